@@ -0,0 +1,247 @@
+// ============================================================================
+// Package activity 用户动态：把领域事件记录成用户可见的活动，读取时合并同类活动
+// ============================================================================
+//
+// 【和审计日志、Webhook 的区别】
+//
+// | 对比     | middleware/auditlog          | webhooks                 | activity（本包）                 |
+// |----------|------------------------------|--------------------------|----------------------------------|
+// | 给谁看   | 管理员、合规检查             | 外部系统                 | 用户自己和访问主页的人           |
+// | 记什么   | 每个修改请求的原始内容       | 订阅的事件，原样转发     | 挑选过的事件，不含敏感字段       |
+// | 来源     | 中间件                       | eventbus                 | eventbus                         |
+//
+// 【数据流】
+//
+//	Handler ──Publish──▶ eventbus ──activity.Subscribe──▶ 转换函数（决定记不记、记什么）──▶ activities 表
+//	GET /users/:id/activity ──▶ 按时间倒序读出 ──▶ 合并同类 ──▶ 游标分页
+//
+//	activities
+//	id | user_id | verb | object_type | object_id | data | created_at
+//
+// 【读取时合并】
+//
+// 同一个用户连续的同一种活动，最新一条往前 Window（默认 1 小时）之内的合并成一条：
+//
+//	10:50 post.created #12 ┐
+//	10:20 post.created #11 ├─▶ "created 3 posts"，objects 里是最新的几篇
+//	10:05 post.created #10 ┘
+//	09:58 profile.updated  ───▶ "updated the profile"
+//	09:30 post.created #9  ───▶ 中间隔着别的活动，单独一条
+//
+// 合并规则可以随时调整，不用迁移数据；代价是每次读取要多读一些行，
+// 所以一组最多合并 MaxGroup 条，一页最多读 (page_size+1) × MaxGroup 行。
+//
+// 【分页】
+//
+// page_size 是合并后的条数。游标指向一页最后一组的最后一条原始记录，
+// 下一页从它后面开始合并，翻页时新插入的活动不会让已经返回的组重复出现。
+//
+// 【用法】
+//
+//	feed := activity.New(db, activity.Config{Verbs: map[string]activity.Verb{
+//	    "post.created": {One: "created a post", Many: "created %d posts"},
+//	}})
+//	activity.Subscribe(feed, bus, PostCreated, func(e PostCreatedEvent) (activity.Activity, bool) {
+//	    return activity.Activity{UserID: e.UserID, ObjectType: "post", ObjectID: e.ID,
+//	        Data: map[string]any{"title": e.Title}}, true
+//	})
+//	r.GET("/users/:id/activity", activity.Handler(feed))
+//
+// ============================================================================
+package activity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go-one/eventbus"
+	"go-one/pagination"
+)
+
+// Activity 表 activities 的一行，一次用户可见的活动
+type Activity struct {
+	ID     uint `gorm:"primaryKey;index:idx_activities_feed,priority:3" json:"id"`
+	UserID uint `gorm:"not null;index:idx_activities_feed,priority:1" json:"user_id"`
+	// Verb 活动类型，通常就是事件主题名，如 post.created
+	Verb       string `gorm:"size:64;not null" json:"verb"`
+	ObjectType string `gorm:"size:32" json:"object_type,omitempty"`
+	ObjectID   uint   `json:"object_id,omitempty"`
+	// Data 展示用的少量字段（文章标题、修改了哪些字段），公开可见，不要放邮箱、手机号
+	Data      map[string]any `gorm:"serializer:json;type:text" json:"data,omitempty"`
+	CreatedAt time.Time      `gorm:"not null;index:idx_activities_feed,priority:2" json:"created_at"`
+}
+
+// TableName 指定表名
+func (Activity) TableName() string {
+	return "activities"
+}
+
+// Verb 活动的展示文案，Many 里的 %d 是合并的条数
+type Verb struct {
+	One  string
+	Many string
+}
+
+// Config 活动流配置
+type Config struct {
+	// Verbs 活动类型的文案；没有登记的类型用类型名本身
+	Verbs map[string]Verb
+
+	// Window 合并的时间范围：一组里最早的一条和最新的一条相差不超过 Window，默认 1 小时
+	Window time.Duration
+
+	// MaxGroup 一组最多合并几条，默认 50
+	MaxGroup int
+
+	// Samples 每组带出最新的几条活动（objects），默认 3
+	Samples int
+}
+
+// Store 活动的记录和读取
+type Store struct {
+	db  *gorm.DB
+	cfg Config
+}
+
+// New 创建 Store，表需要事先 AutoMigrate(&activity.Activity{})
+func New(db *gorm.DB, cfg Config) *Store {
+	if cfg.Window <= 0 {
+		cfg.Window = time.Hour
+	}
+	if cfg.MaxGroup <= 0 {
+		cfg.MaxGroup = 50
+	}
+	if cfg.Samples <= 0 {
+		cfg.Samples = 3
+	}
+	return &Store{db: db, cfg: cfg}
+}
+
+// Record 记录一次活动，CreatedAt 为空时取当前时间
+func (s *Store) Record(ctx context.Context, a *Activity) error {
+	if a.UserID == 0 || a.Verb == "" {
+		return fmt.Errorf("activity: user_id and verb are required")
+	}
+	return s.db.WithContext(ctx).Create(a).Error
+}
+
+// Subscribe 把事件总线上的主题记录为活动，返回取消订阅的函数
+//
+// fn 把事件转换成活动，返回 false 表示这个事件不需要让用户看到；
+// Verb 为空时用主题名。写数据库失败由总线重试。
+func Subscribe[T any](s *Store, bus *eventbus.Bus, topic eventbus.Topic[T], fn func(event T) (Activity, bool)) (unsubscribe func()) {
+	opts := eventbus.Options{Name: "activity", Policy: eventbus.PolicyRetry}
+	return eventbus.Subscribe(bus, topic, opts, func(ctx context.Context, event T) error {
+		a, ok := fn(event)
+		if !ok {
+			return nil
+		}
+		if a.Verb == "" {
+			a.Verb = topic.Name()
+		}
+		return s.Record(ctx, &a)
+	})
+}
+
+// ============================================================================
+// 读取与合并
+// ============================================================================
+
+// Object 合并后一组里的一条活动
+type Object struct {
+	Type      string         `json:"type,omitempty"`
+	ID        uint           `json:"id,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Item 合并后的一条动态
+type Item struct {
+	Verb    string `json:"verb"`
+	Summary string `json:"summary"`
+	Count   int    `json:"count"`
+	// Objects 最新的 Samples 条，最新的在前
+	Objects    []Object  `json:"objects"`
+	LatestAt   time.Time `json:"latest_at"`
+	EarliestAt time.Time `json:"earliest_at"`
+
+	last pagination.Cursor // 组里最早的一条，作为下一页的游标
+}
+
+// batchSize 每次从数据库读取的行数
+const batchSize = 100
+
+// summary 按 Verbs 生成文案
+func (s *Store) summary(verb string, n int) string {
+	v, ok := s.cfg.Verbs[verb]
+	switch {
+	case !ok:
+		if n == 1 {
+			return verb
+		}
+		return fmt.Sprintf("%s ×%d", verb, n)
+	case n == 1 || v.Many == "":
+		return v.One
+	default:
+		return fmt.Sprintf(v.Many, n)
+	}
+}
+
+// joins 活动 a（比组里所有活动都早）能否并入 item
+func (s *Store) joins(item *Item, a *Activity) bool {
+	return item.Verb == a.Verb && item.Count < s.cfg.MaxGroup && item.LatestAt.Sub(a.CreatedAt) < s.cfg.Window
+}
+
+// List 用户的动态，最新的在前；size 是合并后的条数，after 为 nil 表示第一页
+func (s *Store) List(ctx context.Context, userID uint, after *pagination.Cursor, size int) (pagination.Page[Item], error) {
+	if size <= 0 {
+		size = pagination.DefaultSize
+	}
+	var (
+		items   []Item
+		current *Item
+	)
+	finish := func() {
+		if current != nil {
+			current.Summary = s.summary(current.Verb, current.Count)
+			items = append(items, *current)
+		}
+	}
+	for {
+		var rows []Activity
+		q := s.db.WithContext(ctx).Where("user_id = ?", userID)
+		if err := pagination.Apply(q, after, batchSize, pagination.Desc).Find(&rows).Error; err != nil {
+			return pagination.Page[Item]{}, err
+		}
+		for i := range rows {
+			a := &rows[i]
+			if current == nil || !s.joins(current, a) {
+				finish()
+				// 第 size+1 组开始了：前面 size 组都已完整，还有下一页
+				if len(items) == size {
+					return pagination.Page[Item]{Items: items, HasMore: true, NextCursor: items[size-1].last.Encode()}, nil
+				}
+				current = &Item{Verb: a.Verb, LatestAt: a.CreatedAt, Objects: []Object{}}
+			}
+			current.Count++
+			current.EarliestAt = a.CreatedAt
+			current.last = pagination.Cursor{CreatedAt: a.CreatedAt, ID: a.ID}
+			if len(current.Objects) < s.cfg.Samples {
+				current.Objects = append(current.Objects, Object{Type: a.ObjectType, ID: a.ObjectID, Data: a.Data, CreatedAt: a.CreatedAt})
+			}
+		}
+		// Apply 多查一条：不够 batchSize+1 说明已经读完
+		if len(rows) <= batchSize {
+			finish()
+			if items == nil {
+				items = []Item{}
+			}
+			return pagination.Page[Item]{Items: items}, nil
+		}
+		last := rows[len(rows)-1]
+		after = &pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
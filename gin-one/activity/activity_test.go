@@ -0,0 +1,188 @@
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/eventbus"
+	"go-one/internal/testdb"
+	"go-one/pagination"
+)
+
+var verbs = map[string]Verb{
+	"post.created":    {One: "created a post", Many: "created %d posts"},
+	"profile.updated": {One: "updated the profile"},
+}
+
+func newTestStore(t *testing.T, cfg Config) *Store {
+	t.Helper()
+	db := testdb.Open(t, &Activity{})
+	cfg.Verbs = verbs
+	return New(db, cfg)
+}
+
+var base = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+// record 按 offset（相对 base 的分钟数）从早到晚写入
+func record(t *testing.T, s *Store, userID uint, verb string, minutes ...int) {
+	t.Helper()
+	for _, m := range minutes {
+		a := &Activity{UserID: userID, Verb: verb, ObjectType: "post", ObjectID: uint(m + 1), CreatedAt: base.Add(time.Duration(m) * time.Minute)}
+		if err := s.Record(context.Background(), a); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// summaries 所有页的 Summary，顺便检查翻页不重复、不遗漏
+func summaries(t *testing.T, s *Store, userID uint, size int) []string {
+	t.Helper()
+	var (
+		out   []string
+		after *pagination.Cursor
+		total int
+	)
+	for {
+		p, err := s.List(context.Background(), userID, after, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(p.Items) > size {
+			t.Fatalf("page has %d items; want at most %d", len(p.Items), size)
+		}
+		for _, it := range p.Items {
+			out = append(out, it.Summary)
+			total += it.Count
+		}
+		if !p.HasMore {
+			break
+		}
+		if after, err = pagination.Decode(p.NextCursor); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var rows int64
+	s.db.Model(&Activity{}).Where("user_id = ?", userID).Count(&rows)
+	if int64(total) != rows {
+		t.Errorf("pages cover %d activities; want %d", total, rows)
+	}
+	return out
+}
+
+func TestAggregate(t *testing.T) {
+	s := newTestStore(t, Config{Window: time.Hour})
+	record(t, s, 1, "post.created", 0)           // 隔着 profile.updated，单独一条
+	record(t, s, 1, "profile.updated", 28)       // 没有 Many 文案
+	record(t, s, 1, "post.created", 30, 40, 50)  // 合并
+	record(t, s, 1, "post.created", 120)         // 超出 Window，单独一条
+	record(t, s, 1, "comment.created", 130, 131) // 没有登记文案
+	record(t, s, 2, "post.created", 55)          // 别的用户
+
+	p, err := s.List(context.Background(), 1, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(p.Items))
+	for i, it := range p.Items {
+		got[i] = it.Summary
+	}
+	want := []string{"comment.created ×2", "created a post", "created 3 posts", "updated the profile", "created a post"}
+	if !slices.Equal(got, want) || p.HasMore {
+		t.Fatalf("List = %q, has_more %v; want %q", got, p.HasMore, want)
+	}
+
+	group := p.Items[2]
+	if group.Count != 3 || len(group.Objects) != 3 || group.Objects[0].ID != 51 ||
+		!group.LatestAt.Equal(base.Add(50*time.Minute)) || !group.EarliestAt.Equal(base.Add(30*time.Minute)) {
+		t.Errorf("group = %+v", group)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	s := newTestStore(t, Config{Window: time.Hour, MaxGroup: 4, Samples: 2})
+	ctx := context.Background()
+
+	// 每页 1 组，组在页之间不会被拆开
+	record(t, s, 1, "post.created", 0, 1, 2)
+	record(t, s, 1, "profile.updated", 3)
+	record(t, s, 1, "post.created", 4, 5)
+	if got := summaries(t, s, 1, 1); !slices.Equal(got, []string{"created 2 posts", "updated the profile", "created 3 posts"}) {
+		t.Errorf("pages = %q", got)
+	}
+
+	// 超过 batchSize 的连续活动：按 MaxGroup 切组，跨批读取也不重复、不遗漏
+	minutes := make([]int, 250)
+	for i := range minutes {
+		minutes[i] = 1000 + i/10 // 每分钟 10 条，ID 区分先后
+	}
+	record(t, s, 3, "post.created", minutes...)
+	got := summaries(t, s, 3, 7)
+	if len(got) != 63 || got[0] != "created 4 posts" || got[62] != "created 2 posts" {
+		t.Errorf("%d groups, first %q, last %q; want 63 groups of 4 with 2 left over", len(got), got[0], got[len(got)-1])
+	}
+
+	first, _ := s.List(ctx, 3, nil, 1)
+	if len(first.Items[0].Objects) != 2 {
+		t.Errorf("samples = %d; want 2", len(first.Items[0].Objects))
+	}
+	if p, _ := s.List(ctx, 99, nil, 10); p.Items == nil || len(p.Items) != 0 || p.HasMore {
+		t.Errorf("empty feed = %+v", p)
+	}
+}
+
+type postCreated struct {
+	ID, UserID uint
+	Title      string
+	Draft      bool
+}
+
+func TestSubscribeAndHandler(t *testing.T) {
+	s := newTestStore(t, Config{})
+	ctx := context.Background()
+	bus := eventbus.New(eventbus.Config{})
+	topic := eventbus.NewTopic[postCreated]("post.created")
+	Subscribe(s, bus, topic, func(e postCreated) (Activity, bool) {
+		return Activity{UserID: e.UserID, ObjectType: "post", ObjectID: e.ID, Data: map[string]any{"title": e.Title}}, !e.Draft
+	})
+	eventbus.Publish(ctx, bus, topic, postCreated{ID: 1, UserID: 7, Title: "hello"})
+	eventbus.Publish(ctx, bus, topic, postCreated{ID: 2, UserID: 7, Title: "draft", Draft: true})
+	eventbus.Publish(ctx, bus, topic, postCreated{ID: 3, UserID: 7, Title: "world"})
+	bus.Close(ctx)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/users/:id/activity", Handler(s))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/7/activity", nil))
+	var body struct {
+		Data    []Item `json:"data"`
+		HasMore bool   `json:"has_more"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if len(body.Data) != 1 || body.Data[0].Summary != "created 2 posts" || body.Data[0].Objects[0].Data["title"] != "world" {
+		t.Errorf("feed = %+v", body.Data)
+	}
+
+	for target, want := range map[string]int{
+		"/users/abc/activity":        http.StatusBadRequest,
+		"/users/7/activity?page=2":   http.StatusBadRequest,
+		"/users/7/activity?cursor=x": http.StatusBadRequest,
+		"/users/7/activity?cursor=":  http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != want {
+			t.Errorf("GET %s = %d; want %d", target, w.Code, want)
+		}
+	}
+}
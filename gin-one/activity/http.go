@@ -0,0 +1,42 @@
+package activity
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/pagination"
+	"go-one/response"
+)
+
+// Handler GET /users/:id/activity，只支持游标分页
+//
+//	?cursor=&page_size=10 → {"data": [...], "next_cursor": "eyJ0Ij...", "has_more": true, "size": 10}
+//
+// 不带 cursor 参数时返回第一页；动态是公开的，调用方需要限制时自己加权限中间件。
+func Handler(s *Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil || id == 0 {
+			response.Error(c, http.StatusBadRequest, "invalid_id", "ID 不合法")
+			return
+		}
+		page, err := pagination.FromQuery(c)
+		if err != nil || page.Mode == pagination.ModeOffset && page.Page != 1 {
+			response.Error(c, http.StatusBadRequest, "invalid_pagination", "动态只支持 cursor / page_size 分页")
+			return
+		}
+		result, err := s.List(c.Request.Context(), uint(id), page.After, page.Size)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "查询动态失败")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"data":        result.Items,
+			"next_cursor": result.NextCursor,
+			"has_more":    result.HasMore,
+			"size":        page.Size,
+		})
+	}
+}
@@ -0,0 +1,414 @@
+// ============================================================================
+// Package app 应用装配：构造函数注入的 Application 与 provider 函数
+// ============================================================================
+//
+// 示例里的全局变量（DB、users map、JWTSecret）写起来快，但测试时没法替换，
+// 初始化顺序也全靠 main 里的代码位置保证。这里把依赖按 wire 的 provider set 思路拆开：
+//
+//	config.Config
+//	  ├─ ProvideLogger       → *logging.Logger   （标准输出 + 轮转文件，停止时关闭文件）
+//	  ├─ ProvideFieldKeys    → *fieldenc.Keyring （加密列的密钥，读写数据库之前注册）
+//	  ├─ ProvideSecrets      → *secrets.Cache    （Vault / 挂载文件，Start 后定期刷新）
+//	  ├─ ProvideDB           → *gorm.DB          （停止时关闭连接池）
+//	  ├─ ProvideCache        → redis.Client
+//	  └─ ProvideLocker(db)   → lock.Locker       （迁移、定时任务的多实例互斥）
+//	       ProvideRepositories(db, cache)        → Repositories
+//	       ProvideServices(repos, passwords)     → Services
+//	       ProvideSeeder(db, passwords)          → *seed.Seeder （cmd 的 seed 命令）
+//
+// 每个 provider 只依赖参数，不读全局变量；New 按依赖顺序调用它们，
+// 和 wire 生成的代码是同一个样子，只是手写、没有代码生成。
+// 需要替换某一层（测试用内存实现、换 Redis 客户端）时，直接调用下层 provider 自己组装。
+//
+// 【生命周期】
+//
+// 需要后台运行或关闭时释放的组件（数据库、任务 worker、事件总线）注册到 Lifecycle：
+//
+//	a.Lifecycle.Append(app.Hook{Name: "worker", OnStart: ..., OnStop: ...})
+//	if err := a.Start(ctx); err != nil { ... }
+//	srv.OnShutdown("app", a.Stop)  // HTTP 请求全部结束后按逆序停止
+//
+// ============================================================================
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go-one/audit"
+	"go-one/auth/password"
+	"go-one/cache/redis"
+	"go-one/config"
+	"go-one/crypto/fieldenc"
+	"go-one/database"
+	"go-one/lock"
+	"go-one/logging"
+	"go-one/model"
+	"go-one/repository"
+	"go-one/secrets"
+	"go-one/seed"
+	"go-one/service"
+)
+
+// Repositories 数据访问层
+type Repositories struct {
+	Users repository.UserRepository
+	Posts repository.PostRepository
+}
+
+// Services 业务逻辑层
+type Services struct {
+	Users *service.UserService
+	Posts *service.PostService
+}
+
+// Application 一个进程里的全部依赖，由 New 装配
+type Application struct {
+	Config *config.Config
+	Logger *slog.Logger
+	// LogLevel 运行时修改日志级别（logging.RegisterLevel）；Options.Logger 不为空时为 nil
+	LogLevel *slog.LevelVar
+	DB       *gorm.DB
+	// FieldKeys 加密列的密钥环，没有配置 fieldenc.keys 时为 nil
+	FieldKeys *fieldenc.Keyring
+	// Secrets 运行时读取的密钥，secrets.provider 为 none 时为 nil
+	Secrets   *secrets.Cache
+	Cache     redis.Client
+	Locker    lock.Locker
+	Passwords *password.Service
+	Repos     Repositories
+	Services  Services
+	Lifecycle *Lifecycle
+}
+
+// Options 覆盖默认 provider 的结果，零值字段使用默认 provider
+type Options struct {
+	Logger *slog.Logger
+	// DB 已打开的连接（测试用内存 SQLite），非 nil 时不连接数据库、也不在停止时关闭它
+	DB *gorm.DB
+	// Cache 换成 go-redis 适配器，默认进程内 MemoryClient
+	Cache redis.Client
+	// Models 自动迁移的模型，默认见 DefaultModels
+	Models []any
+	// Locker 换成 lock.NewRedis，默认见 ProvideLocker
+	Locker lock.Locker
+	// SkipMigrate 不自动迁移：迁移由单独的 migrate 命令或部署任务执行，或者只是列出路由
+	SkipMigrate bool
+	// Lifecycle 和调用方共用的生命周期容器（见 cmd 包），默认新建
+	Lifecycle *Lifecycle
+}
+
+// New 按依赖顺序调用 provider 装配 Application
+// 中途失败时已注册的停止钩子（如关闭数据库）会被执行
+func New(ctx context.Context, cfg *config.Config, opts Options) (*Application, error) {
+	logger := opts.Logger
+	var level *slog.LevelVar
+	var logs *logging.Logger
+	if logger == nil {
+		var err error
+		if logs, err = ProvideLogger(cfg.Log); err != nil {
+			return nil, err
+		}
+		logger, level = logs.Logger, logs.Level
+	}
+	lc := opts.Lifecycle
+	if lc == nil {
+		lc = NewLifecycle(logger)
+	}
+	if logs != nil {
+		// 最先注册、最后关闭，其他组件停止时的日志还能写进文件
+		lc.OnStop("log", func(context.Context) error { return logs.Close() })
+	}
+
+	fieldKeys, err := ProvideFieldKeys(cfg.FieldEnc)
+	if err != nil {
+		lc.Stop(ctx)
+		return nil, err
+	}
+
+	secretCache, err := ProvideSecrets(lc, cfg.Secrets, logger)
+	if err != nil {
+		lc.Stop(ctx)
+		return nil, err
+	}
+	dbCfg := cfg.Database
+	if secretCache != nil {
+		// 密码只在打开连接池时用一次，之后 Vault 里的新密码要等重启才生效
+		if dbCfg.Password, err = secrets.GetOr(ctx, secretCache, "database.password", dbCfg.Password); err != nil {
+			lc.Stop(ctx)
+			return nil, err
+		}
+	}
+
+	db := opts.DB
+	if db == nil {
+		if db, err = ProvideDB(ctx, lc, dbCfg, logger); err != nil {
+			return nil, err
+		}
+	}
+	models := opts.Models
+	if models == nil {
+		models = DefaultModels()
+	}
+	locker := opts.Locker
+	if locker == nil {
+		locker = ProvideLocker(db)
+	}
+	if !opts.SkipMigrate {
+		if err := Migrate(ctx, db, locker, models...); err != nil {
+			lc.Stop(ctx)
+			return nil, err
+		}
+	}
+
+	cache := opts.Cache
+	if cache == nil {
+		cache = ProvideCache()
+	}
+	passwords := ProvidePasswords()
+	repos := ProvideRepositories(db, cache)
+
+	return &Application{
+		Config:    cfg,
+		Logger:    logger,
+		LogLevel:  level,
+		DB:        db,
+		FieldKeys: fieldKeys,
+		Secrets:   secretCache,
+		Cache:     cache,
+		Locker:    locker,
+		Passwords: passwords,
+		Repos:     repos,
+		Services:  ProvideServices(repos, passwords),
+		Lifecycle: lc,
+	}, nil
+}
+
+// DefaultModels Options.Models 为空时迁移的模型：注销用户时在同一个事务里写 audit_logs，
+// 所以审计表也在其中
+func DefaultModels() []any {
+	return []any{&model.User{}, &model.Post{}, &model.Tag{}, &audit.Log{}}
+}
+
+// Start 启动 Lifecycle 中注册的组件
+func (a *Application) Start(ctx context.Context) error {
+	return a.Lifecycle.Start(ctx)
+}
+
+// Stop 按逆序停止所有组件，签名与 server.Hook 相同
+func (a *Application) Stop(ctx context.Context) error {
+	return a.Lifecycle.Stop(ctx)
+}
+
+// ============================================================================
+// Provider
+// ============================================================================
+
+// ProvideLogger 按 log.* 创建输出到标准输出和（或）轮转文件的 Logger，调用方负责 Close
+func ProvideLogger(cfg config.LogConfig) (*logging.Logger, error) {
+	lc := logging.Config{
+		Level:  cfg.Level,
+		Format: cfg.Format,
+		File: logging.FileConfig{
+			Filename:   cfg.File,
+			MaxSize:    int64(cfg.MaxSizeMB) << 20,
+			MaxAge:     cfg.MaxAge,
+			MaxBackups: cfg.MaxBackups,
+			Compress:   cfg.Compress,
+		},
+	}
+	if cfg.Stdout {
+		lc.Stdout = os.Stdout
+	}
+	return logging.New(lc)
+}
+
+// ProvideFieldKeys 按 fieldenc.* 创建密钥环并注册 encrypted 序列化器；没有配置密钥时返回 nil
+func ProvideFieldKeys(cfg config.FieldEncConfig) (*fieldenc.Keyring, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, nil
+	}
+	keys, err := fieldenc.ParseKeys(cfg.Keys)
+	if err != nil {
+		return nil, err
+	}
+	k, err := fieldenc.NewKeyring(keys, cfg.Active)
+	if err != nil {
+		return nil, err
+	}
+	fieldenc.Register(k)
+	return k, nil
+}
+
+// ProvideSecrets 按 secrets.* 创建带缓存的密钥来源，provider 为 none 时返回 nil
+// Lifecycle 启动后每隔 secrets.ttl 刷新一次读过的密钥，OnChange 回调在刷新时执行
+func ProvideSecrets(lc *Lifecycle, cfg config.SecretsConfig, logger *slog.Logger) (*secrets.Cache, error) {
+	var p secrets.Provider
+	switch cfg.Provider {
+	case "none", "":
+		return nil, nil
+	case "env":
+		p = secrets.Env{}
+	case "file":
+		p = secrets.File{Dir: cfg.Dir}
+	case "vault":
+		v, err := secrets.NewVault(secrets.VaultConfig{
+			Addr:      cfg.Vault.Addr,
+			Token:     cfg.Vault.Token,
+			Mount:     cfg.Vault.Mount,
+			Path:      cfg.Vault.Path,
+			Namespace: cfg.Vault.Namespace,
+		})
+		if err != nil {
+			return nil, err
+		}
+		p = v
+	default:
+		return nil, fmt.Errorf("app: unknown secrets provider %q", cfg.Provider)
+	}
+	sc := secrets.NewCache(p, secrets.CacheConfig{TTL: cfg.TTL, Logger: logger})
+
+	var stop context.CancelFunc
+	lc.Append(Hook{
+		Name: "secrets refresh",
+		OnStart: func(context.Context) error {
+			var ctx context.Context
+			ctx, stop = context.WithCancel(context.Background())
+			go sc.Run(ctx, cfg.TTL)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			stop()
+			return nil
+		},
+	})
+	return sc, nil
+}
+
+// ProvideDB 按配置连接数据库，并注册停止时关闭连接池的钩子
+func ProvideDB(ctx context.Context, lc *Lifecycle, cfg config.DatabaseConfig, logger *slog.Logger) (*gorm.DB, error) {
+	dbCfg := database.FromConfig(cfg)
+	dbCfg.Logger = logger
+	// 唯一索引冲突转换为 gorm.ErrDuplicatedKey，repository 据此返回 ErrDuplicate
+	dbCfg.GORM = &gorm.Config{TranslateError: true}
+	db, err := database.Open(ctx, dbCfg)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	lc.OnStop("database", func(context.Context) error { return sqlDB.Close() })
+	return db, nil
+}
+
+// ProvideCache 进程内缓存客户端，多实例部署时换成 go-redis 适配器（见 cache/redis 包注释）
+func ProvideCache() redis.Client {
+	return redis.NewMemoryClient(10000)
+}
+
+// ProvideLocker PostgreSQL 用 advisory lock，不需要额外的基础设施；
+// 其他数据库只有进程内的锁，多实例部署时通过 Options.Locker 换成 lock.NewRedis
+func ProvideLocker(db *gorm.DB) lock.Locker {
+	if db.Dialector.Name() == "postgres" {
+		return lock.NewPostgres(db)
+	}
+	return lock.NewMemory()
+}
+
+// migrateLock 迁移锁的 key 和续期 TTL
+const migrateLock = "app:migrate"
+
+// Migrate 持有迁移锁执行 AutoMigrate
+//
+// 多个实例同时启动时只有一个在迁移，其他实例等它完成后再执行一遍：
+// 表结构已经是最新的，AutoMigrate 只做检查，不会重复 ALTER TABLE。
+// 等待受 ctx 控制，迁移卡住时由调用方的启动超时结束进程。
+func Migrate(ctx context.Context, db *gorm.DB, locker lock.Locker, models ...any) error {
+	lk, err := lock.Wait(ctx, locker, migrateLock, time.Minute, time.Second)
+	if err != nil {
+		return fmt.Errorf("app: acquire migration lock: %w", err)
+	}
+	return lock.Hold(ctx, lk, time.Minute, func(ctx context.Context) error {
+		return db.WithContext(ctx).AutoMigrate(models...)
+	})
+}
+
+// ProvidePasswords 密码哈希服务，新密码用 argon2id，bcrypt 旧哈希登录时自动升级
+func ProvidePasswords() *password.Service {
+	return password.New(password.DefaultArgon2id(), password.DefaultBcrypt())
+}
+
+// ProvideRepositories 用户和文章按 ID 走 cache-aside 缓存
+func ProvideRepositories(db *gorm.DB, client redis.Client) Repositories {
+	users := redis.New[model.User](client, redis.Config{Prefix: "user:v1:", TTL: 5 * time.Minute})
+	// 文章缓存带着作者信息，作者改名后要等过期才更新，所以 TTL 短一些
+	posts := redis.New[model.Post](client, redis.Config{Prefix: "post:v1:", TTL: time.Minute})
+	return Repositories{
+		Users: repository.NewCachedUserRepository(repository.NewUserRepository(db), users),
+		Posts: repository.NewCachedPostRepository(repository.NewPostRepository(db), posts),
+	}
+}
+
+// ProvideServices 业务层只依赖 repository 接口
+func ProvideServices(repos Repositories, passwords service.PasswordHasher) Services {
+	return Services{
+		Users: service.NewUserService(repos.Users, passwords),
+		Posts: service.NewPostService(repos.Posts, repos.Users),
+	}
+}
+
+// seedPassword 批量生成的用户共用的密码
+const seedPassword = "password123"
+
+// ProvideSeeder 默认模型的 fixture 导入和批量生成
+//
+// fixture 里的 password 写明文，导入时哈希；批量生成的用户共用一个预先算好的哈希
+// （密码都是 password123），1 万次 argon2id 要跑好几分钟。生成的文章随机分给
+// id 在 1 到用户数之间的用户，要先生成用户。
+func ProvideSeeder(db *gorm.DB, passwords *password.Service) *seed.Seeder {
+	hash := sync.OnceValues(func() (string, error) { return passwords.Hash(seedPassword) })
+	users := sync.OnceValues(func() (int64, error) {
+		var n int64
+		err := db.Model(&model.User{}).Count(&n).Error
+		return n, err
+	})
+	return seed.New(db,
+		seed.Table{
+			Name:  "users",
+			Model: &model.User{},
+			Prepare: func(row map[string]any) error {
+				plain, ok := row["password"].(string)
+				if !ok {
+					return errors.New("password is required")
+				}
+				h, err := passwords.Hash(plain)
+				row["password"] = h
+				return err
+			},
+			Generate: func(f *seed.Faker, i int) map[string]any {
+				h, _ := hash()
+				return map[string]any{"username": f.Username(i), "email": f.Email(i), "password": h, "age": f.IntRange(18, 80)}
+			},
+		},
+		seed.Table{Name: "tags", Model: &model.Tag{}},
+		seed.Table{
+			Name:      "posts",
+			Model:     &model.Post{},
+			DependsOn: []string{"users"},
+			Generate: func(f *seed.Faker, i int) map[string]any {
+				n, _ := users()
+				return map[string]any{"title": f.Sentence(f.IntRange(3, 8)), "content": f.Paragraph(3), "user_id": f.IntRange(1, int(max(n, 1)))}
+			},
+		},
+		seed.Table{Name: "post_tags", DependsOn: []string{"posts", "tags"}},
+	)
+}
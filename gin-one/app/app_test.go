@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"go-one/config"
+	"go-one/internal/testdb"
+	"go-one/service"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestLifecycleOrder(t *testing.T) {
+	lc := NewLifecycle(discard)
+	var calls []string
+	record := func(s string) func(context.Context) error {
+		return func(context.Context) error { calls = append(calls, s); return nil }
+	}
+	lc.OnStop("db", record("stop db"))
+	lc.Append(Hook{Name: "worker", OnStart: record("start worker"), OnStop: record("stop worker")})
+	lc.Append(Hook{Name: "bus", OnStart: record("start bus"), OnStop: record("stop bus")})
+
+	ctx := context.Background()
+	if err := lc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 第二次 Start 不会重复启动
+	if err := lc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := lc.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 每个钩子只停止一次
+	if err := lc.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"start worker", "start bus", "stop bus", "stop worker", "stop db"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestLifecycleStartFailureRollsBack(t *testing.T) {
+	lc := NewLifecycle(discard)
+	var calls []string
+	boom := errors.New("boom")
+	lc.Append(Hook{
+		Name:    "a",
+		OnStart: func(context.Context) error { calls = append(calls, "start a"); return nil },
+		OnStop:  func(context.Context) error { calls = append(calls, "stop a"); return nil },
+	})
+	lc.Append(Hook{
+		Name:    "b",
+		OnStart: func(context.Context) error { return boom },
+		OnStop:  func(context.Context) error { calls = append(calls, "stop b"); return nil },
+	})
+	lc.Append(Hook{
+		Name:    "c",
+		OnStart: func(context.Context) error { calls = append(calls, "start c"); return nil },
+	})
+
+	err := lc.Start(context.Background())
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+	// b 没启动成功不停止，c 没有启动
+	want := []string{"start a", "stop a"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestLifecycleStopJoinsErrors(t *testing.T) {
+	lc := NewLifecycle(discard)
+	e1, e2 := errors.New("e1"), errors.New("e2")
+	ran := false
+	lc.OnStop("first", func(context.Context) error { ran = true; return nil })
+	lc.OnStop("second", func(context.Context) error { return e1 })
+	lc.OnStop("third", func(context.Context) error { return e2 })
+
+	err := lc.Stop(context.Background())
+	if !errors.Is(err, e1) || !errors.Is(err, e2) {
+		t.Fatalf("err = %v, want e1 and e2", err)
+	}
+	if !ran {
+		t.Fatal("hook after failing hooks did not run")
+	}
+}
+
+func TestNew(t *testing.T) {
+	db := testdb.Open(t)
+
+	ctx := context.Background()
+	a, err := New(ctx, &config.Config{}, Options{Logger: discard, DB: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := a.Services.Users.Create(ctx, service.CreateUserInput{Username: "alice", Email: "alice@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Password == "secret123" {
+		t.Fatal("password stored in plain text")
+	}
+	if _, err := a.Services.Users.Create(ctx, service.CreateUserInput{Username: "alice", Email: "alice@example.com", Password: "x"}); !errors.Is(err, service.ErrUserExists) {
+		t.Fatalf("duplicate err = %v, want ErrUserExists", err)
+	}
+	p, err := a.Services.Posts.Create(ctx, service.CreatePostInput{Title: "hello", UserID: u.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := a.Services.Posts.Get(ctx, p.ID); err != nil || got.Title != "hello" {
+		t.Fatalf("Get = %v, %v", got, err)
+	}
+
+	// 外部传入的 DB 不归 Application 管，Stop 后仍可使用
+	if err := a.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	if err := sqlDB.Ping(); err != nil {
+		t.Fatalf("injected DB closed by Stop: %v", err)
+	}
+}
+
+func TestNewClosesDBOnStop(t *testing.T) {
+	cfg := &config.Config{Database: config.DatabaseConfig{Driver: "sqlite", Name: t.TempDir() + "/app.db"}}
+	ctx := context.Background()
+	a, err := New(ctx, cfg, Options{Logger: discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := a.DB.DB()
+	if err := a.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlDB.Ping(); err == nil {
+		t.Fatal("database still open after Stop")
+	}
+}
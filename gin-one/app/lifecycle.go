@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Hook 生命周期钩子，OnStart / OnStop 都可以为 nil
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+type entry struct {
+	Hook
+	started bool
+}
+
+// Lifecycle 轻量级容器：按注册顺序启动组件，按注册的逆序停止
+//
+// 只有启动成功的钩子会被停止；没有 OnStart 的钩子（如 provider 打开的数据库连接）
+// 注册时就视为已启动。某个 OnStart 失败时，已经启动的组件立即按逆序停止，
+// 不会留下跑了一半的后台 goroutine。
+type Lifecycle struct {
+	logger *slog.Logger
+
+	mu    sync.Mutex
+	hooks []*entry
+}
+
+// NewLifecycle 创建生命周期容器，logger 为 nil 时使用 slog.Default()
+func NewLifecycle(logger *slog.Logger) *Lifecycle {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Lifecycle{logger: logger}
+}
+
+// Append 注册钩子，Start 之后注册的钩子在下一次 Start 时启动
+func (l *Lifecycle) Append(h Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, &entry{Hook: h, started: h.OnStart == nil})
+}
+
+// OnStop 只注册停止函数，等价于 Append(Hook{Name: name, OnStop: fn})
+func (l *Lifecycle) OnStop(name string, fn func(ctx context.Context) error) {
+	l.Append(Hook{Name: name, OnStop: fn})
+}
+
+// Start 按注册顺序执行还没启动的 OnStart，失败时停止已启动的组件并返回错误
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := append([]*entry(nil), l.hooks...)
+	l.mu.Unlock()
+
+	for _, h := range hooks {
+		l.mu.Lock()
+		started := h.started
+		l.mu.Unlock()
+		if started {
+			continue
+		}
+		if err := h.OnStart(ctx); err != nil {
+			err = fmt.Errorf("app: start %s: %w", h.Name, err)
+			return errors.Join(err, l.Stop(ctx))
+		}
+		l.mu.Lock()
+		h.started = true
+		l.mu.Unlock()
+	}
+	return nil
+}
+
+// Stop 按逆序执行已启动钩子的 OnStop，单个失败不影响其他钩子；每个钩子只停止一次
+//
+// 签名与 server.Hook 相同，可以直接注册为关闭钩子：srv.OnShutdown("app", lc.Stop)
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	var stopping []*entry
+	kept := l.hooks[:0]
+	for _, h := range l.hooks {
+		if h.started {
+			stopping = append(stopping, h)
+		} else {
+			kept = append(kept, h)
+		}
+	}
+	l.hooks = kept
+	l.mu.Unlock()
+
+	var errs []error
+	for i := len(stopping) - 1; i >= 0; i-- {
+		h := stopping[i]
+		if h.OnStop == nil {
+			continue
+		}
+		if err := h.OnStop(ctx); err != nil {
+			l.logger.Error("stop hook failed", slog.String("hook", h.Name), slog.Any("error", err))
+			errs = append(errs, fmt.Errorf("app: stop %s: %w", h.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
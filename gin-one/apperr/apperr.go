@@ -0,0 +1,245 @@
+// ============================================================================
+// Package apperr 业务错误分类：错误类别决定 HTTP 状态码，中间件统一写错误响应
+// ============================================================================
+//
+// 【为什么不在 handler 里直接 c.JSON 错误？】
+//
+// 每个 handler 自己 switch 错误、自己拼 gin.H{"error": ...}，结果是：
+// 同一种错误在不同接口返回不同的状态码和格式；err.Error() 原样返回，
+// SQL、文件路径等内部细节泄漏给客户端；新增一种错误要改所有 handler。
+//
+// 改成 handler 只负责「这是什么错误」，中间件负责「怎么响应」：
+//
+//	var errUserNotFound = apperr.NotFound("user_not_found", "用户不存在")
+//
+//	user, err := svc.Get(ctx, id)
+//	if errors.Is(err, service.ErrUserNotFound) {
+//	    err = apperr.Wrap(err, errUserNotFound) // 保留原始错误链，日志里能看到
+//	}
+//	if err != nil {
+//	    _ = c.Error(err)
+//	    return
+//	}
+//
+// 【错误类别】
+//
+// | Kind             | HTTP | 默认错误码         | 典型场景                       |
+// |------------------|------|--------------------|--------------------------------|
+// | KindInvalid      | 400  | invalid_argument   | 参数格式错误、校验失败         |
+// | KindUnauthorized | 401  | unauthorized       | 未登录、token 过期             |
+// | KindForbidden    | 403  | forbidden          | 已登录但没有权限               |
+// | KindNotFound     | 404  | not_found          | 资源不存在                     |
+// | KindConflict     | 409  | conflict           | 唯一键冲突、版本冲突           |
+// | KindInternal     | 500  | internal_error     | 其他所有错误（不返回错误详情） |
+//
+// 错误链里没有 *Error 的错误一律按 KindInternal 处理：
+// 忘记分类的错误宁可返回笼统的 500，也不能把 err.Error() 发给客户端。
+//
+// ============================================================================
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Kind 错误类别，决定 HTTP 状态码
+type Kind uint8
+
+const (
+	KindInternal Kind = iota
+	KindInvalid
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+)
+
+// kinds 每个类别的状态码、默认错误码和默认提示
+var kinds = [...]struct {
+	status  int
+	code    string
+	message string
+}{
+	KindInternal:     {http.StatusInternalServerError, "internal_error", "服务器内部错误，请稍后重试"},
+	KindInvalid:      {http.StatusBadRequest, "invalid_argument", "请求参数不合法"},
+	KindUnauthorized: {http.StatusUnauthorized, "unauthorized", "请先登录"},
+	KindForbidden:    {http.StatusForbidden, "forbidden", "没有权限执行该操作"},
+	KindNotFound:     {http.StatusNotFound, "not_found", "资源不存在"},
+	KindConflict:     {http.StatusConflict, "conflict", "资源冲突"},
+}
+
+// Status 类别对应的 HTTP 状态码，未知类别按 500 处理
+func (k Kind) Status() int {
+	if int(k) >= len(kinds) {
+		return http.StatusInternalServerError
+	}
+	return kinds[k].status
+}
+
+// String 类别的默认错误码
+func (k Kind) String() string {
+	if int(k) >= len(kinds) {
+		return kinds[KindInternal].code
+	}
+	return kinds[k].code
+}
+
+// Error 带类别和错误码的业务错误
+type Error struct {
+	Kind    Kind
+	Code    string            // 机器可读的错误码，如 user_not_found；为空时使用类别的默认错误码
+	Message string            // 返回给客户端的提示；为空时使用类别的默认提示
+	Fields  map[string]string // 字段级错误，如 {"Email": "email"}，放在响应的 data.fields 中
+	Err     error             // 原始错误，只写日志，不返回给客户端
+}
+
+// New 创建指定类别的错误
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Invalid 400 参数错误
+func Invalid(code, message string) *Error { return New(KindInvalid, code, message) }
+
+// Unauthorized 401 未认证
+func Unauthorized(code, message string) *Error { return New(KindUnauthorized, code, message) }
+
+// Forbidden 403 无权限
+func Forbidden(code, message string) *Error { return New(KindForbidden, code, message) }
+
+// NotFound 404 资源不存在
+func NotFound(code, message string) *Error { return New(KindNotFound, code, message) }
+
+// Conflict 409 资源冲突
+func Conflict(code, message string) *Error { return New(KindConflict, code, message) }
+
+// Internal 500 内部错误，message 同样会返回给客户端，不要放内部细节
+func Internal(code, message string) *Error { return New(KindInternal, code, message) }
+
+// ErrorCode 错误码，Code 为空时返回类别的默认错误码
+func (e *Error) ErrorCode() string {
+	if e.Code != "" {
+		return e.Code
+	}
+	return e.Kind.String()
+}
+
+// ErrorMessage 给客户端的提示，Message 为空时返回类别的默认提示
+func (e *Error) ErrorMessage() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if int(e.Kind) >= len(kinds) {
+		return kinds[KindInternal].message
+	}
+	return kinds[e.Kind].message
+}
+
+// Error 包含原始错误，用于日志；客户端看到的是 ErrorMessage
+func (e *Error) Error() string {
+	msg := e.ErrorCode()
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap 返回原始错误，errors.Is(err, sql.ErrNoRows) 之类的判断不受包装影响
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is 类别相同且错误码相同（target 的 Code 为空时只比较类别）即匹配，
+// 所以 *Error 可以当哨兵错误用，Wrap 或 WithField 之后仍能用 errors.Is 判断：
+//
+//	errors.Is(apperr.Wrap(err, errUserNotFound), errUserNotFound) // true
+//	errors.Is(err, apperr.NotFound("", ""))                       // 任意 404 错误
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return t.Kind == e.Kind && (t.Code == "" || t.Code == e.ErrorCode())
+}
+
+// Wrap 用 as 的类别、错误码和提示包装 err，err 为 nil 时返回 nil
+// as 通常是包级的哨兵错误，不会被修改
+func Wrap(err error, as *Error) error {
+	if err == nil {
+		return nil
+	}
+	e := as.clone()
+	e.Err = err
+	return e
+}
+
+// WithField 附加一个字段级错误，err 为 nil 时返回 nil
+//
+// err 本身是 *Error 时复制一份再追加，不修改原错误（它可能是共享的哨兵错误）；
+// 否则包装成 KindInvalid。
+func WithField(err error, field, message string) error {
+	if err == nil {
+		return nil
+	}
+	e, ok := err.(*Error)
+	if ok {
+		e = e.clone()
+	} else {
+		e = &Error{Kind: KindInvalid, Err: err}
+	}
+	if e.Fields == nil {
+		e.Fields = make(map[string]string, 1)
+	}
+	e.Fields[field] = message
+	return e
+}
+
+// FromBinding 把 ShouldBind 系列的错误转换成 KindInvalid
+// 校验失败时每个字段的失败规则放在 Fields 中，如 {"Email": "email", "Age": "gte"}；
+// 字段名是 validator 报告的名字，默认是结构体字段名，注册了 RegisterTagNameFunc 时是 json 名
+func FromBinding(err error) error {
+	if err == nil {
+		return nil
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		// JSON 语法错误、类型不匹配等，细节对客户端有用，但可能很长，只写日志
+		return &Error{Kind: KindInvalid, Code: "malformed_request", Message: "请求格式错误", Err: err}
+	}
+	e := &Error{Kind: KindInvalid, Code: "validation_failed", Err: err, Fields: make(map[string]string, len(verrs))}
+	for _, fe := range verrs {
+		e.Fields[fe.Field()] = fe.Tag()
+	}
+	e.Message = fmt.Sprintf("%d 个字段校验失败", len(verrs))
+	return e
+}
+
+// From 返回错误链中的 *Error；没有时包装成 KindInternal
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Kind: KindInternal, Err: err}
+}
+
+// KindOf 错误的类别，没有分类的错误返回 KindInternal
+func KindOf(err error) Kind {
+	return From(err).Kind
+}
+
+func (e *Error) clone() *Error {
+	c := *e
+	if e.Fields != nil {
+		c.Fields = make(map[string]string, len(e.Fields)+1)
+		for k, v := range e.Fields {
+			c.Fields[k] = v
+		}
+	}
+	return &c
+}
@@ -0,0 +1,275 @@
+package apperr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"go-learning/errtrace"
+
+	"go-one/i18n"
+)
+
+var (
+	errUserNotFound = NotFound("user_not_found", "用户不存在")
+	errNoRows       = errors.New("record not found")
+)
+
+func TestIs(t *testing.T) {
+	wrapped := Wrap(errNoRows, errUserNotFound)
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		want   bool
+	}{
+		{"wrapped matches sentinel", wrapped, errUserNotFound, true},
+		{"original error kept in chain", wrapped, errNoRows, true},
+		{"outer fmt wrapping", fmt.Errorf("get user: %w", wrapped), errUserNotFound, true},
+		{"any not found", wrapped, NotFound("", ""), true},
+		{"different code", wrapped, NotFound("post_not_found", ""), false},
+		{"different kind", wrapped, Conflict("user_not_found", ""), false},
+		{"default code", NotFound("", "x"), NotFound("not_found", ""), true},
+		{"with field keeps identity", WithField(wrapped, "ID", "required"), errUserNotFound, true},
+		{"plain error", errNoRows, errUserNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.want {
+				t.Errorf("errors.Is(%v, %v) = %v; want %v", tt.err, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapAndWithField(t *testing.T) {
+	if Wrap(nil, errUserNotFound) != nil || WithField(nil, "a", "b") != nil || FromBinding(nil) != nil {
+		t.Fatal("wrapping nil should return nil")
+	}
+
+	err := WithField(WithField(errUserNotFound, "ID", "required"), "Name", "max")
+	if len(errUserNotFound.Fields) != 0 || errUserNotFound.Err != nil {
+		t.Fatalf("sentinel was modified: %+v", errUserNotFound)
+	}
+	e := From(err)
+	if e.Kind != KindNotFound || len(e.Fields) != 2 || e.Fields["Name"] != "max" {
+		t.Errorf("From = %+v", e)
+	}
+
+	plain := From(WithField(errNoRows, "ID", "required"))
+	if plain.Kind != KindInvalid || plain.ErrorCode() != "invalid_argument" || !errors.Is(plain, errNoRows) {
+		t.Errorf("WithField on plain error = %+v", plain)
+	}
+
+	if got := Wrap(errNoRows, errUserNotFound).Error(); got != "user_not_found: 用户不存在: record not found" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := KindOf(errNoRows); got != KindInternal {
+		t.Errorf("KindOf(plain) = %v; want internal", got)
+	}
+	if got := Kind(200); got.Status() != http.StatusInternalServerError || got.String() != "internal_error" {
+		t.Errorf("unknown kind = %d %s", got.Status(), got)
+	}
+}
+
+// newRouter 每个路由返回一种错误，模拟 handler 只上报、不写响应
+func newRouter(cfg Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(cfg))
+	r.GET("/missing", func(c *gin.Context) {
+		_ = c.Error(Wrap(errNoRows, errUserNotFound))
+	})
+	r.GET("/conflict", func(c *gin.Context) {
+		_ = c.Error(fmt.Errorf("create: %w", Conflict("", "")))
+	})
+	r.GET("/unauthorized", func(c *gin.Context) { _ = c.Error(Unauthorized("token_expired", "登录已过期")) })
+	r.GET("/forbidden", func(c *gin.Context) { _ = c.Error(Forbidden("", "")) })
+	r.GET("/internal", func(c *gin.Context) {
+		_ = c.Error(errors.New("dial tcp 10.0.0.1:5432: connection refused"))
+	})
+	r.GET("/traced", func(c *gin.Context) {
+		_ = c.Error(errtrace.Wrap(errNoRows))
+	})
+	r.POST("/bind", func(c *gin.Context) {
+		var req struct {
+			Email string `json:"email" binding:"required,email"`
+			Age   int    `json:"age" binding:"gte=0"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			_ = c.Error(FromBinding(err))
+			return
+		}
+		c.JSON(http.StatusOK, req)
+	})
+	r.GET("/written", func(c *gin.Context) {
+		// 中间件只记录错误，响应已经正常写出
+		_ = c.Error(errors.New("ratelimit: store unavailable"))
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	return r
+}
+
+func TestMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	r := newRouter(Config{Logger: slog.New(slog.NewJSONHandler(&logs, nil))})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   string // 响应的 error 字段
+		wantMsg    string
+		wantFields []string
+	}{
+		{"not found", "GET", "/missing", "", 404, "user_not_found", "用户不存在", nil},
+		{"default code and message", "GET", "/conflict", "", 409, "conflict", "资源冲突", nil},
+		{"unauthorized", "GET", "/unauthorized", "", 401, "token_expired", "登录已过期", nil},
+		{"forbidden", "GET", "/forbidden", "", 403, "forbidden", "没有权限执行该操作", nil},
+		{"unclassified error hides detail", "GET", "/internal", "", 500, "internal_error", "服务器内部错误，请稍后重试", nil},
+		{"traced error", "GET", "/traced", "", 500, "internal_error", "服务器内部错误，请稍后重试", nil},
+		{"validation fields", "POST", "/bind", `{"email":"x","age":-1}`, 400, "validation_failed", "2 个字段校验失败", []string{"Email", "Age"}},
+		{"malformed json", "POST", "/bind", `{"email":`, 400, "malformed_request", "请求格式错误", nil},
+		{"no error", "POST", "/bind", `{"email":"a@b.co"}`, 200, "", "", nil},
+		{"already written", "GET", "/written", "", 200, "", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantCode == "" {
+				if strings.Contains(w.Body.String(), `"code":-1`) {
+					t.Errorf("unexpected error response: %s", w.Body)
+				}
+				return
+			}
+			var resp struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+				Error   string `json:"error"`
+				Data    struct {
+					Fields map[string]string `json:"fields"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != -1 || resp.Error != tt.wantCode || resp.Message != tt.wantMsg {
+				t.Errorf("response = %+v; want error %q message %q", resp, tt.wantCode, tt.wantMsg)
+			}
+			if len(resp.Data.Fields) != len(tt.wantFields) {
+				t.Errorf("fields = %v; want %v", resp.Data.Fields, tt.wantFields)
+			}
+			for _, f := range tt.wantFields {
+				if resp.Data.Fields[f] == "" {
+					t.Errorf("field %s missing in %v", f, resp.Data.Fields)
+				}
+			}
+		})
+	}
+
+	// 只有 5xx 写日志，且日志里有原始错误；带调用栈的错误记录出错位置
+	if n := strings.Count(logs.String(), "request failed"); n != 2 {
+		t.Errorf("logged %d errors; want 1: %s", n, logs.String())
+	}
+	if !strings.Contains(logs.String(), "connection refused") {
+		t.Errorf("log does not contain the original error: %s", logs.String())
+	}
+	if !strings.Contains(logs.String(), `"error_source":"apperr/apperr_test.go:`) {
+		t.Errorf("log does not contain the error source: %s", logs.String())
+	}
+}
+
+func TestMiddlewareLocalized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(i18n.Middleware(i18n.NewBundle(i18n.Config{})))
+	r.Use(Middleware(Config{Logger: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))}))
+	// 复用 newRouter 的 handler，前面加上语言协商
+	for _, rt := range newRouter(Config{}).Routes() {
+		r.Handle(rt.Method, rt.Path, rt.HandlerFunc)
+	}
+
+	tests := []struct {
+		name     string
+		lang     string
+		method   string
+		path     string
+		body     string
+		wantMsg  string
+		wantEach map[string]string
+	}{
+		{"translated default", "en-US,en;q=0.9", "GET", "/conflict", "", "Resource conflict", nil},
+		{"no translation keeps source", "en", "GET", "/missing", "", "用户不存在", nil},
+		{"source language keeps handler message", "zh-CN", "GET", "/unauthorized", "", "登录已过期", nil},
+		{"plural one", "en", "POST", "/bind", `{"email":"a@b.co","age":-1}`, "1 field is invalid",
+			map[string]string{"Age": "Age must be greater than or equal to 0"}},
+		{"plural other", "en", "POST", "/bind", `{"email":"x","age":-1}`, "2 fields are invalid",
+			map[string]string{"Email": "Email must be a valid email address", "Age": "Age must be greater than or equal to 0"}},
+		{"source language messages", "zh", "POST", "/bind", `{}`, "1 个字段校验失败",
+			map[string]string{"Email": "Email 不能为空"}},
+		{"unsupported language uses source", "fr", "POST", "/bind", `{}`, "1 个字段校验失败",
+			map[string]string{"Email": "Email 不能为空"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Accept-Language", tt.lang)
+			r.ServeHTTP(w, req)
+			var resp struct {
+				Message string `json:"message"`
+				Data    struct {
+					Fields   map[string]string `json:"fields"`
+					Messages map[string]string `json:"messages"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Message != tt.wantMsg {
+				t.Errorf("message = %q; want %q", resp.Message, tt.wantMsg)
+			}
+			if len(resp.Data.Messages) != len(tt.wantEach) {
+				t.Errorf("messages = %v; want %v", resp.Data.Messages, tt.wantEach)
+			}
+			for f, want := range tt.wantEach {
+				if resp.Data.Messages[f] != want {
+					t.Errorf("messages[%s] = %q; want %q", f, resp.Data.Messages[f], want)
+				}
+				if resp.Data.Fields[f] == "" {
+					t.Errorf("fields[%s] missing: %v", f, resp.Data.Fields)
+				}
+			}
+		})
+	}
+}
+
+func TestShowDetail(t *testing.T) {
+	r := newRouter(Config{ShowDetail: true, Logger: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/internal", nil))
+	if !strings.Contains(w.Body.String(), "connection refused") {
+		t.Errorf("ShowDetail response = %s; want original error", w.Body)
+	}
+
+	// 4xx 的 message 是给用户看的，不受 ShowDetail 影响
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if strings.Contains(w.Body.String(), "record not found") {
+		t.Errorf("4xx response leaked the cause: %s", w.Body)
+	}
+}
@@ -0,0 +1,109 @@
+package apperr
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"go-one/i18n"
+	"go-one/middleware/logger"
+	"go-one/response"
+)
+
+// Config 错误处理中间件配置
+type Config struct {
+	// Logger 为空时使用 logger.FromContext(c)，即日志中间件提供的请求级 Logger
+	Logger *slog.Logger
+
+	// ShowDetail 5xx 响应的 message 改为原始错误，只应在开发环境开启
+	ShowDetail bool
+}
+
+// DefaultMiddleware 使用默认配置
+func DefaultMiddleware() gin.HandlerFunc {
+	return Middleware(Config{})
+}
+
+// Middleware 创建错误处理中间件
+//
+// handler 通过 c.Error(err) 上报错误后直接 return，不写响应；
+// 中间件在 c.Next() 之后取最后一个错误，按类别写统一响应：
+//
+//	404 {"code": -1, "message": "用户不存在", "error": "user_not_found"}
+//	400 {"code": -1, "message": "1 个字段校验失败", "error": "validation_failed",
+//	     "data": {"fields": {"Email": "email"}}}
+//
+// 经过 i18n.Middleware 时 message 按请求的语言翻译（见 response.Localize），
+// 校验失败还会在 data.messages 里给出每个字段的提示，fields 里的规则名不变：
+//
+//	400 {"code": -1, "message": "1 field is invalid", "error": "validation_failed",
+//	     "data": {"fields": {"Email": "email"}, "messages": {"Email": "Email must be a valid email address"}}}
+//
+// 已经写过响应时不再处理：其他中间件（限流、幂等）只用 c.Error 记录内部错误，
+// 响应已经由 handler 正常写出。5xx 错误连同原始错误链写入日志，
+// 错误带调用栈（go-learning/errtrace）时一并记录出错位置。
+func Middleware(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		e := From(err)
+		status := e.Kind.Status()
+
+		body := response.Response{
+			Code:    response.CodeError,
+			Message: response.Localize(c, e.ErrorCode(), e.ErrorMessage(), "count", len(e.Fields)),
+			Error:   e.ErrorCode(),
+		}
+		if len(e.Fields) > 0 {
+			data := gin.H{"fields": e.Fields}
+			if messages := fieldMessages(i18n.FromContext(c), e.Err); messages != nil {
+				data["messages"] = messages
+			}
+			body.Data = data
+		}
+
+		if status >= http.StatusInternalServerError {
+			log := cfg.Logger
+			if log == nil {
+				log = logger.FromContext(c)
+			}
+			attrs := []slog.Attr{
+				slog.String("method", c.Request.Method),
+				slog.String("path", c.Request.URL.Path),
+				slog.String("error", err.Error()),
+			}
+			attrs = append(attrs, logger.StackAttrs(err, true)...)
+			log.LogAttrs(c.Request.Context(), slog.LevelError, "request failed", attrs...)
+			if cfg.ShowDetail {
+				body.Message = err.Error()
+			}
+		}
+
+		c.AbortWithStatusJSON(status, body)
+	}
+}
+
+// fieldMessages 校验失败时每个字段的提示（validation.<规则>），没有经过 i18n.Middleware 时为 nil
+func fieldMessages(loc *i18n.Localizer, err error) map[string]string {
+	var verrs validator.ValidationErrors
+	if loc == nil || !errors.As(err, &verrs) {
+		return nil
+	}
+	messages := make(map[string]string, len(verrs))
+	for _, fe := range verrs {
+		args := []any{"field", fe.Field(), "param", fe.Param()}
+		msg, ok := loc.Lookup("validation."+fe.Tag(), args...)
+		if !ok {
+			msg = loc.T("validation.default", args...)
+		}
+		messages[fe.Field()] = msg
+	}
+	return messages
+}
@@ -0,0 +1,85 @@
+// ============================================================================
+// Package audit 审计日志
+// ============================================================================
+//
+// 【记录什么？】
+//
+// 谁（Actor）在什么时候对哪条记录（Entity + EntityID）做了什么（Action），
+// 以及改了哪些内容（Changes，JSON）。
+//
+// 【为什么要传 tx？】
+//
+// 审计记录必须和业务修改在同一个事务里：业务回滚时审计也回滚，
+// 不会出现"日志说删了，数据其实还在"的情况。
+//
+// 【两种写法】
+//
+// | 方式                       | 适合                                   | 见       |
+// |----------------------------|----------------------------------------|----------|
+// | db.Use(audit.Plugin{...})  | 通过模型的增删改，自动计算字段 diff    | hooks.go |
+// | audit.Record(ctx, tx, ...) | 批量更新、匿名化等需要自定义内容的操作 | 本文件   |
+//
+// 查询某条记录的审计轨迹：audit.Trail 或 audit.Register 注册的 HTTP 接口（http.go）。
+//
+// ============================================================================
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Log 审计日志表 audit_logs
+type Log struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Actor     string    `gorm:"size:100;index" json:"actor"`
+	Action    string    `gorm:"size:50;index" json:"action"`
+	Entity    string    `gorm:"size:50;index:idx_audit_entity" json:"entity"`
+	EntityID  string    `gorm:"size:50;index:idx_audit_entity" json:"entity_id"`
+	Changes   string    `gorm:"type:text" json:"changes,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (Log) TableName() string {
+	return "audit_logs"
+}
+
+// SystemActor 上下文中没有操作者时使用
+const SystemActor = "system"
+
+type actorKey struct{}
+
+// WithActor 把操作者写入 context，通常在认证中间件之后调用
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext 取出操作者，没有时返回 SystemActor
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
+
+// Record 在 tx 中写入一条审计日志，changes 会序列化成 JSON（可为 nil）
+func Record(ctx context.Context, tx *gorm.DB, action, entity, entityID string, changes any) error {
+	entry := Log{
+		Actor:    ActorFromContext(ctx),
+		Action:   action,
+		Entity:   entity,
+		EntityID: entityID,
+	}
+	if changes != nil {
+		b, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		entry.Changes = string(b)
+	}
+	return tx.WithContext(ctx).Create(&entry).Error
+}
@@ -0,0 +1,224 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+	"go-one/pagination"
+)
+
+type account struct {
+	gorm.Model
+	Name     string
+	Age      int
+	Password string `audit:"redact"`
+	Token    string `audit:"-"`
+}
+
+type note struct {
+	ID   uint
+	Body string
+}
+
+func newTestDB(t *testing.T, migrateLog bool) *gorm.DB {
+	t.Helper()
+	db := testdb.Open(t)
+
+	models := []any{&account{}, &note{}}
+	if migrateLog {
+		models = append(models, &Log{})
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(Plugin{Tables: []string{"accounts", "audit_logs"}}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func logsOf(t *testing.T, db *gorm.DB) []Log {
+	t.Helper()
+	var logs []Log
+	if err := db.Order("id").Find(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+	return logs
+}
+
+func diffOf(t *testing.T, l Log) map[string]Change {
+	t.Helper()
+	d, err := l.Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestPlugin(t *testing.T) {
+	db := newTestDB(t, true)
+	ctx := WithActor(context.Background(), "alice")
+
+	acc := account{Name: "tom", Age: 20, Password: "h1", Token: "secret"}
+	if err := db.WithContext(ctx).Create(&acc).Error; err != nil {
+		t.Fatal(err)
+	}
+	db.WithContext(ctx).Model(&acc).Updates(map[string]any{"age": 21, "name": "tom", "password": "h2"})
+	db.Model(&acc).Update("age", 21) // 值没变，不记录
+	db.Delete(&acc)
+	db.Create(&note{Body: "not audited"})
+	db.Model(&account{}).Where("age > ?", 0).Update("name", "batch") // 没有主键，不记录
+
+	logs := logsOf(t, db)
+	tests := []struct {
+		action string
+		actor  string
+		want   map[string]Change
+	}{
+		{"create", "alice", map[string]Change{
+			"id":       {nil, float64(acc.ID)},
+			"name":     {nil, "tom"},
+			"age":      {nil, float64(20)},
+			"password": {nil, Redacted},
+		}},
+		{"update", "alice", map[string]Change{
+			"age":      {float64(20), float64(21)},
+			"password": {Redacted, Redacted},
+		}},
+		{"delete", SystemActor, map[string]Change{
+			"id":       {float64(acc.ID), nil},
+			"name":     {"tom", nil},
+			"age":      {float64(21), nil},
+			"password": {Redacted, nil},
+		}},
+	}
+	if len(logs) != len(tests) {
+		t.Fatalf("got %d logs; want %d: %+v", len(logs), len(tests), logs)
+	}
+	for i, tt := range tests {
+		l := logs[i]
+		if l.Action != tt.action || l.Actor != tt.actor || l.Entity != "accounts" || l.EntityID != "1" {
+			t.Errorf("log[%d] = %s %s %s/%s; want %s %s accounts/1",
+				i, l.Action, l.Actor, l.Entity, l.EntityID, tt.action, tt.actor)
+		}
+		got := diffOf(t, l)
+		if len(got) != len(tt.want) {
+			t.Errorf("log[%d] changes = %v; want %v", i, got, tt.want)
+			continue
+		}
+		for k, w := range tt.want {
+			if got[k] != w {
+				t.Errorf("log[%d] changes[%s] = %v; want %v", i, k, got[k], w)
+			}
+		}
+	}
+}
+
+func TestPluginBatchCreate(t *testing.T) {
+	db := newTestDB(t, true)
+	db.Create(&[]account{{Name: "a"}, {Name: "b"}, {Name: "c"}})
+	if got := len(logsOf(t, db)); got != 3 {
+		t.Errorf("batch create wrote %d logs; want 3", got)
+	}
+}
+
+func TestPluginRollback(t *testing.T) {
+	// audit_logs 不存在，写日志失败，业务写入也必须回滚
+	db := newTestDB(t, false)
+	if err := db.Create(&account{Name: "tom"}).Error; err == nil {
+		t.Fatal("Create succeeded; want audit error")
+	}
+	var count int64
+	db.Model(&account{}).Count(&count)
+	if count != 0 {
+		t.Errorf("accounts = %d after failed audit; want 0", count)
+	}
+}
+
+func TestTrail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t, true)
+	acc := account{Name: "v0"}
+	db.Create(&acc)
+	for _, name := range []string{"v1", "v2", "v3", "v4"} {
+		db.Model(&acc).Update("name", name)
+	}
+	db.Create(&account{Name: "other"})
+
+	var actions []string
+	var after *pagination.Cursor
+	for {
+		page, err := Trail(context.Background(), db, "accounts", "1", after, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range page.Items {
+			actions = append(actions, l.Action)
+		}
+		if !page.HasMore {
+			break
+		}
+		after, _ = pagination.Decode(page.NextCursor)
+	}
+	if got, want := strings.Join(actions, ","), "update,update,update,update,create"; got != want {
+		t.Errorf("trail actions = %s; want %s", got, want)
+	}
+
+	r := gin.New()
+	Register(r.Group("/audit"), db)
+	tests := []struct {
+		path      string
+		wantCode  int
+		wantItems int
+	}{
+		{"/audit/accounts/1?page_size=3", http.StatusOK, 3},
+		{"/audit/accounts/2", http.StatusOK, 1},
+		{"/audit/posts/1", http.StatusOK, 0},
+		{"/audit/accounts/1?cursor=bad", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.wantCode {
+			t.Errorf("GET %s = %d; want %d", tt.path, w.Code, tt.wantCode)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var body struct {
+			Data struct {
+				Items []Log `json:"items"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if len(body.Data.Items) != tt.wantItems {
+			t.Errorf("GET %s items = %d; want %d", tt.path, len(body.Data.Items), tt.wantItems)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(func(c *gin.Context) string { return c.GetHeader("X-User") }))
+	r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, ActorFromContext(c.Request.Context())) })
+
+	for header, want := range map[string]string{"bob": "bob", "": SystemActor} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-User", header)
+		r.ServeHTTP(w, req)
+		if w.Body.String() != want {
+			t.Errorf("actor with X-User=%q = %q; want %q", header, w.Body.String(), want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package audit
+
+import (
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"go-one/diff"
+)
+
+// ============================================================================
+// GORM 插件：自动记录增删改
+// ============================================================================
+//
+// 【用法】
+//
+//	db.Use(audit.Plugin{Tables: []string{"users", "posts"}})
+//
+// 之后通过模型对这些表的增删改都会在同一个事务里写审计日志（批量创建每行一条）：
+//
+// | 操作                         | Action | Changes                         |
+// |------------------------------|--------|---------------------------------|
+// | db.Create(&user)             | create | 新建记录的非零字段，old 为 null |
+// | db.Model(&user).Updates(...) | update | 只包含值有变化的字段            |
+// | db.Delete(&user)（含软删除） | delete | 删除前的非零字段，new 为 null   |
+//
+// 【diff 怎么算】
+//
+// 更新前按主键查一次旧值（before_update），更新后再查一次新值（after_update），
+// 用 diff.Structs 逐个字段比较（time.Time 按时刻比较，时区不同不算变化）。无论用 Save、Updates(struct) 还是 Updates(map) 都能得到准确结果，
+// 代价是每次更新多两次按主键的查询。
+//
+// 【不会记录的情况】
+//
+// - db.Table("users").Where(...).Updates(...) 这种没有模型或没有主键的批量更新、删除，
+//   需要在业务代码里调用 Record（见 repository.Anonymize）
+// - CreatedAt / UpdatedAt 这类自动时间戳字段
+//
+// 【敏感字段】
+//
+//	Password string `audit:"redact"` // 只记录"改过了"，值替换为 [REDACTED]
+//	Token    string `audit:"-"`      // 完全不记录
+//
+// 审计日志写入失败时，原操作返回错误并回滚。
+//
+
+// Redacted 敏感字段在审计日志中的占位值
+const Redacted = "[REDACTED]"
+
+// Change 字段的旧值和新值，创建时 Old 为 null，删除时 New 为 null
+type Change struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// Plugin GORM 审计插件
+type Plugin struct {
+	// Tables 需要审计的表名
+	Tables []string
+}
+
+// Name 实现 gorm.Plugin
+func (Plugin) Name() string {
+	return "audit"
+}
+
+// Initialize 实现 gorm.Plugin，注册回调
+func (p Plugin) Initialize(db *gorm.DB) error {
+	h := &hooks{tables: map[string]bool{}}
+	for _, t := range p.Tables {
+		h.tables[t] = true
+	}
+	// 审计表本身永远不审计，否则写日志会递归触发
+	delete(h.tables, Log{}.TableName())
+
+	// after 回调必须排在提交之前，写日志失败才能让整个事务回滚
+	const commit = "gorm:commit_or_rollback_transaction"
+	cb := db.Callback()
+	steps := []error{
+		cb.Create().After("gorm:create").Before(commit).Register("audit:after_create", h.afterCreate),
+		cb.Update().Before("gorm:update").Register("audit:before_update", h.loadOld),
+		cb.Update().After("gorm:update").Before(commit).Register("audit:after_update", h.afterUpdate),
+		cb.Delete().Before("gorm:delete").Register("audit:before_delete", h.loadOld),
+		cb.Delete().After("gorm:delete").Before(commit).Register("audit:after_delete", h.afterDelete),
+	}
+	for _, err := range steps {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const oldKey = "audit:old"
+
+type hooks struct {
+	tables map[string]bool
+}
+
+// target 当前语句是否需要审计，返回主键字段
+func (h *hooks) target(db *gorm.DB) (*schema.Field, bool) {
+	st := db.Statement
+	if db.Error != nil || st.Schema == nil || !h.tables[st.Table] {
+		return nil, false
+	}
+	pk := st.Schema.PrioritizedPrimaryField
+	return pk, pk != nil
+}
+
+// load 在同一个连接（事务）里按主键重新查询一行
+func load(db *gorm.DB, pk *schema.Field, id any) (reflect.Value, error) {
+	st := db.Statement
+	row := reflect.New(st.Schema.ModelType)
+	err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).
+		Unscoped().
+		Table(st.Table).
+		Where(clause.Eq{Column: clause.Column{Name: pk.DBName}, Value: id}).
+		Take(row.Interface()).Error
+	return row.Elem(), err
+}
+
+// record 在当前事务中写审计日志，失败时让原操作报错
+func record(db *gorm.DB, action string, id any, changes map[string]Change) {
+	// NewDB 只在第一次链式调用时生成新的 Statement，先调用 Table，
+	// 否则 Record 里的 WithContext 会复制当前语句（表名、Schema 都是业务表）
+	tx := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Table(Log{}.TableName())
+	if err := Record(db.Statement.Context, tx, action, db.Statement.Table, fmt.Sprint(id), changes); err != nil {
+		db.AddError(fmt.Errorf("audit: %w", err))
+	}
+}
+
+func (h *hooks) afterCreate(db *gorm.DB) {
+	pk, ok := h.target(db)
+	if !ok {
+		return
+	}
+	st := db.Statement
+	each(st.ReflectValue, func(v reflect.Value) {
+		id, zero := pk.ValueOf(st.Context, v)
+		if zero {
+			return
+		}
+		record(db, "create", id, changesOf(st, reflect.Value{}, v))
+	})
+}
+
+// loadOld 更新、删除前保存旧值，只处理带主键的单条记录
+func (h *hooks) loadOld(db *gorm.DB) {
+	pk, ok := h.target(db)
+	st := db.Statement
+	if !ok || st.ReflectValue.Kind() != reflect.Struct {
+		return
+	}
+	id, zero := pk.ValueOf(st.Context, st.ReflectValue)
+	if zero {
+		return
+	}
+	old, err := load(db, pk, id)
+	if err != nil {
+		// 记录不存在时原操作也不会影响任何行，不需要审计
+		return
+	}
+	db.InstanceSet(oldKey, old)
+}
+
+func (h *hooks) afterUpdate(db *gorm.DB) {
+	pk, ok := h.target(db)
+	v, found := db.InstanceGet(oldKey)
+	if !ok || !found || db.RowsAffected == 0 {
+		return
+	}
+	old := v.(reflect.Value)
+	id, _ := pk.ValueOf(db.Statement.Context, old)
+	cur, err := load(db, pk, id)
+	if err != nil {
+		db.AddError(fmt.Errorf("audit: reload %s %v: %w", db.Statement.Table, id, err))
+		return
+	}
+	if changes := changesOf(db.Statement, old, cur); len(changes) > 0 {
+		record(db, "update", id, changes)
+	}
+}
+
+func (h *hooks) afterDelete(db *gorm.DB) {
+	pk, ok := h.target(db)
+	v, found := db.InstanceGet(oldKey)
+	if !ok || !found || db.RowsAffected == 0 {
+		return
+	}
+	old := v.(reflect.Value)
+	id, _ := pk.ValueOf(db.Statement.Context, old)
+	record(db, "delete", id, changesOf(db.Statement, old, reflect.Value{}))
+}
+
+// each 对单个结构体或切片中的每个元素调用 fn
+func each(v reflect.Value, fn func(reflect.Value)) {
+	switch v.Kind() {
+	case reflect.Struct:
+		fn(v)
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			fn(reflect.Indirect(v.Index(i)))
+		}
+	}
+}
+
+// changesOf 用 diff.Structs 逐字段比较，old / cur 为零值 reflect.Value 时表示不存在（创建 / 删除），
+// 和模型的零值比较，结果只包含另一边的非零字段，减少噪音
+func changesOf(st *gorm.Statement, old, cur reflect.Value) map[string]Change {
+	zero := reflect.Zero(st.Schema.ModelType)
+	a, b := old, cur
+	if !a.IsValid() {
+		a = zero
+	}
+	if !b.IsValid() {
+		b = zero
+	}
+	fields, err := diff.Structs(a.Interface(), b.Interface(), diff.Options{Name: func(sf reflect.StructField) string {
+		f := st.Schema.LookUpField(sf.Name)
+		if f == nil || f.Name != sf.Name || f.DBName == "" || f.AutoCreateTime > 0 || f.AutoUpdateTime > 0 || f.Tag.Get("audit") == "-" {
+			return "" // 关联、自动时间戳和 audit:"-" 不记录
+		}
+		return f.DBName
+	}})
+	if err != nil {
+		return nil
+	}
+
+	changes := make(map[string]Change, len(fields))
+	for _, fc := range fields {
+		c := Change{Old: fc.Old, New: fc.New}
+		if f := st.Schema.LookUpField(fc.Field); f != nil && f.Tag.Get("audit") == "redact" {
+			c.Old, c.New = Redacted, Redacted
+		}
+		if !old.IsValid() {
+			c.Old = nil
+		}
+		if !cur.IsValid() {
+			c.New = nil
+		}
+		changes[fc.Field] = c
+	}
+	return changes
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/pagination"
+	"go-one/response"
+)
+
+// Middleware 把操作者写入请求 context，actor 返回空字符串时记为 SystemActor
+//
+//	r.Use(audit.Middleware(func(c *gin.Context) string { return c.GetString("username") }))
+//
+// 之后 service / repository 只要把 c.Request.Context() 传给 GORM，
+// 插件和 Record 就能取到操作者。
+func Middleware(actor func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if a := actor(c); a != "" {
+			c.Request = c.Request.WithContext(WithActor(c.Request.Context(), a))
+		}
+		c.Next()
+	}
+}
+
+func logCursor(l Log) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: l.CreatedAt, ID: l.ID}
+}
+
+// Trail 查询一条记录的审计轨迹，最新的在前，游标分页
+func Trail(ctx context.Context, db *gorm.DB, entity, entityID string, after *pagination.Cursor, limit int) (pagination.Page[Log], error) {
+	var logs []Log
+	query := db.WithContext(ctx).Where("entity = ? AND entity_id = ?", entity, entityID)
+	if err := pagination.Apply(query, after, limit, pagination.Desc).Find(&logs).Error; err != nil {
+		return pagination.Page[Log]{}, err
+	}
+	return pagination.NewPage(logs, limit, logCursor), nil
+}
+
+// Register 注册审计查询接口：GET /:entity/:id?cursor=&page_size=
+// 审计日志包含修改前后的数据，group 上应该有管理员权限校验
+func Register(group *gin.RouterGroup, db *gorm.DB) {
+	group.GET("/:entity/:id", func(c *gin.Context) {
+		req, err := pagination.FromQuery(c)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid_pagination", err.Error())
+			return
+		}
+		page, err := Trail(c.Request.Context(), db, c.Param("entity"), c.Param("id"), req.After, req.Size)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "查询审计日志失败")
+			return
+		}
+		response.Success(c, gin.H{
+			"items":       page.Items,
+			"next_cursor": page.NextCursor,
+			"has_more":    page.HasMore,
+		})
+	})
+}
+
+// Diff 解析插件写入的 Changes（字段名 → Change）
+func (l Log) Diff() (map[string]Change, error) {
+	changes := map[string]Change{}
+	if l.Changes == "" {
+		return changes, nil
+	}
+	if err := json.Unmarshal([]byte(l.Changes), &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
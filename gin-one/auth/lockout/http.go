@@ -0,0 +1,48 @@
+package lockout
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// Reject 锁定中的登录请求：429 + Retry-After，JWT 登录和 Session 登录共用
+func Reject(c *gin.Context, st Status) {
+	retry := max(int(math.Ceil(st.RetryAfter.Seconds())), 1)
+	c.Header("Retry-After", strconv.Itoa(retry))
+	response.Abort(c, http.StatusTooManyRequests, "account_locked", "登录失败次数过多，请稍后重试")
+}
+
+// RequireCaptcha 需要验证码但请求里没有或校验失败：428，客户端显示验证码后重新提交
+func RequireCaptcha(c *gin.Context) {
+	response.Abort(c, http.StatusPreconditionRequired, "captcha_required", "请完成验证码后重新登录")
+}
+
+// RegisterAdmin 注册管理接口，调用方负责加管理员权限中间件
+//
+//	GET    /            仍然有效的失败记录，锁定中的在前；?username= 只看一个用户
+//	DELETE /:username   解锁：删除这个用户名的记录；?ip= 只删一个 IP 的
+func RegisterAdmin(group *gin.RouterGroup, g *Guard) {
+	group.GET("", func(c *gin.Context) {
+		list, err := g.List(c.Request.Context(), c.Query("username"))
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "查询失败")
+			return
+		}
+		response.Success(c, list)
+	})
+
+	group.DELETE("/:username", func(c *gin.Context) {
+		username, ip := c.Param("username"), c.Query("ip")
+		n, err := g.Clear(c.Request.Context(), username, ip)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "操作失败")
+			return
+		}
+		response.Success(c, gin.H{"username": key(username), "ip": ip, "cleared": n})
+	})
+}
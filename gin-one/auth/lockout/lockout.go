@@ -0,0 +1,438 @@
+// ============================================================================
+// Package lockout 登录防暴力破解：失败计数、临时锁定、要求验证码
+// ============================================================================
+//
+// 【和 ratelimit 的分工】
+//
+// | 维度           | 谁来挡                                   | 挡住什么                       |
+// |----------------|------------------------------------------|--------------------------------|
+// | IP             | ratelimit（/login 每分钟 5 次）          | 一个 IP 换着用户名猜           |
+// | 用户名 + IP    | lockout（本包）                          | 一个 IP 对同一账号持续猜密码   |
+// | 用户名         | lockout（本包），只要求验证码            | 很多 IP 分散猜同一个账号       |
+//
+// 只按用户名锁定（不管 IP）会被反过来利用：任何人输错几次密码就能把别人的账号锁住。
+// 按用户名 + IP 计数，真正的用户换个网络、或者在自己的设备上照常登录。
+//
+// 但只按用户名 + IP 计数时，每换一个 IP（僵尸网络、或者伪造转发头）计数就从零开始，
+// 永远到不了锁定。所以再按用户名在所有 IP 上计数（ip 为 AnyIP 的记录），
+// 达到 AccountCaptchaAfter 后这个账号从任何 IP 登录都要验证码；只要求验证码、不锁定，
+// 恶意输错密码锁不住别人的账号。
+//
+// 【状态变化】
+//
+//	失败次数（Window 内）  0 ─ ... ─ CaptchaAfter ─ ... ─ MaxFailures
+//	                       正常        要求验证码           锁定 BaseLockout × 2^(第几次锁定-1)，最长 MaxLockout
+//
+//	锁定开始时失败次数清零；解锁后仍要验证码，再失败 MaxFailures 次锁定时间翻倍。
+//	登录成功删除记录；ResetAfter 内没有再被锁定，锁定次数清零。
+//
+// 用户名不存在时同样计数：响应和真实账号一致，不能借锁定判断用户名是否存在。
+//
+//	login_lockouts（ip = * 的一行是这个用户名在所有 IP 上的失败，登录成功也不删除，滑出窗口后失效）
+//	id | username | ip | failed_at | lockouts | locked_until | expires_at | updated_at
+//
+// 【用法】
+//
+//	guard := lockout.New(db, lockout.Config{MaxFailures: 5, CaptchaAfter: 3})
+//
+//	st, err := guard.Check(ctx, username, ip)  // 锁定中返回 ErrLocked
+//	if st.CaptchaRequired { 校验请求里的验证码 }
+//	if 密码错误 { st, err = guard.Fail(ctx, username, ip) } else { guard.Succeed(ctx, username, ip) }
+//
+//	lockout.RegisterAdmin(admin.Group("/lockouts"), guard)
+//	go guard.Sweep(ctx, time.Hour)
+//
+// ============================================================================
+package lockout
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"go-one/lock"
+
+	"go-learning/clock"
+)
+
+// 错误定义
+var (
+	ErrLocked = errors.New("lockout: too many failed attempts, try again later")
+)
+
+// AnyIP 用户名在所有 IP 上的失败记录用的 IP 列值
+const AnyIP = "*"
+
+// Entry 表 login_lockouts 的一行，一个用户名 + IP 的失败记录；IP 为 AnyIP 时是用户名在所有 IP 上的失败
+type Entry struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	Username string `gorm:"size:100;not null;uniqueIndex:idx_login_lockouts_key,priority:1" json:"username"`
+	IP       string `gorm:"size:45;not null;uniqueIndex:idx_login_lockouts_key,priority:2" json:"ip"`
+	// FailedAt Window 内每次失败的时间（滑动窗口日志），最多 MaxFailures 条（AnyIP 行最多 AccountCaptchaAfter 条）
+	FailedAt []time.Time `gorm:"serializer:json;type:text" json:"failed_at"`
+	// Lockouts 连续被锁定的次数，决定下一次锁定多久
+	Lockouts    int        `gorm:"not null;default:0" json:"lockouts"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	// ExpiresAt 过了这个时间记录不再影响登录，Purge 删除
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Entry) TableName() string {
+	return "login_lockouts"
+}
+
+// Config 锁定策略
+type Config struct {
+	// MaxFailures Window 内失败多少次锁定，默认 5
+	MaxFailures int
+
+	// Window 失败次数的滑动窗口，默认 15 分钟
+	Window time.Duration
+
+	// CaptchaAfter Window 内失败多少次后要求验证码，默认 3；大于等于 MaxFailures 时只在解锁后要求
+	CaptchaAfter int
+
+	// AccountCaptchaAfter Window 内这个用户名在所有 IP 上一共失败多少次后，从任何 IP 登录都要求验证码；
+	// 不锁定。默认 MaxFailures × 2
+	AccountCaptchaAfter int
+
+	// BaseLockout 第一次锁定的时长，之后每次翻倍，默认 1 分钟
+	BaseLockout time.Duration
+
+	// MaxLockout 锁定时长上限，默认 1 小时
+	MaxLockout time.Duration
+
+	// ResetAfter 最后一次锁定结束后多久没有再被锁定，锁定次数清零，默认 24 小时
+	ResetAfter time.Duration
+
+	// Logger 锁定和后台清理日志，默认 slog.Default()
+	Logger *slog.Logger
+
+	// Locker 多实例部署时 Sweep 每一轮只由一个实例执行，nil 表示不加锁
+	Locker lock.Locker
+
+	// Clock 默认 clock.Real；测试传 clock.NewFake
+	Clock clock.Clock
+}
+
+// Guard 登录失败计数和锁定，状态在数据库里，多个实例共享
+type Guard struct {
+	db     *gorm.DB
+	cfg    Config
+	logger *slog.Logger
+	clock  clock.Clock
+}
+
+// New 创建 Guard，表需要事先 AutoMigrate(&lockout.Entry{})
+func New(db *gorm.DB, cfg Config) *Guard {
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = 5
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 15 * time.Minute
+	}
+	if cfg.CaptchaAfter <= 0 {
+		cfg.CaptchaAfter = 3
+	}
+	if cfg.AccountCaptchaAfter <= 0 {
+		cfg.AccountCaptchaAfter = cfg.MaxFailures * 2
+	}
+	if cfg.BaseLockout <= 0 {
+		cfg.BaseLockout = time.Minute
+	}
+	if cfg.MaxLockout < cfg.BaseLockout {
+		cfg.MaxLockout = max(time.Hour, cfg.BaseLockout)
+	}
+	if cfg.ResetAfter <= 0 {
+		cfg.ResetAfter = 24 * time.Hour
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Guard{db: db, cfg: cfg, logger: cfg.Logger, clock: clock.OrReal(cfg.Clock)}
+}
+
+// Status 一个用户名 + IP 当前的状态，登录接口据此决定是否要验证码、是否直接拒绝
+type Status struct {
+	// Failures Window 内的失败次数
+	Failures int `json:"failures"`
+	// Remaining 再失败几次锁定；锁定中为 0
+	Remaining int `json:"remaining"`
+	// CaptchaRequired 下一次登录要带验证码：这个 IP 失败太多，或者这个用户名在所有 IP 上失败太多
+	CaptchaRequired bool `json:"captcha_required"`
+	// LockedUntil 锁定结束时间，没有锁定时为 nil
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	// RetryAfter 锁定中时多久后可以重试，用于 Retry-After 响应头
+	RetryAfter time.Duration `json:"-"`
+}
+
+// Locked 是否锁定中
+func (s Status) Locked() bool {
+	return s.LockedUntil != nil
+}
+
+// maxUsername username 列的长度，登录请求里的用户名没有长度限制，超出部分截掉
+const maxUsername = 100
+
+// key 用户名统一小写：Admin 和 admin 是同一个账号，不能换大小写绕过计数
+func key(username string) string {
+	name := strings.ToLower(strings.TrimSpace(username))
+	if len(name) > maxUsername {
+		name = strings.ToValidUTF8(name[:maxUsername], "")
+	}
+	return name
+}
+
+// prune 去掉滑出窗口的失败记录，锁定已结束的清掉 LockedUntil，过了 ResetAfter 的锁定次数清零
+func (g *Guard) prune(e *Entry, now time.Time) {
+	start := now.Add(-g.cfg.Window)
+	kept := e.FailedAt[:0]
+	for _, t := range e.FailedAt {
+		if t.After(start) {
+			kept = append(kept, t)
+		}
+	}
+	e.FailedAt = kept
+	if e.LockedUntil != nil && !now.Before(*e.LockedUntil) {
+		if now.Sub(*e.LockedUntil) >= g.cfg.ResetAfter {
+			e.Lockouts = 0
+		}
+		// 锁定次数还要用来计算下一次锁定时长，结束时间保留到 ResetAfter 之后
+		if e.Lockouts == 0 {
+			e.LockedUntil = nil
+		}
+	}
+}
+
+// status 由已经 prune 过的记录计算状态
+func (g *Guard) status(e *Entry, now time.Time) Status {
+	st := Status{
+		Failures:        len(e.FailedAt),
+		Remaining:       max(g.cfg.MaxFailures-len(e.FailedAt), 0),
+		CaptchaRequired: len(e.FailedAt) >= g.cfg.CaptchaAfter || e.Lockouts > 0,
+	}
+	if e.LockedUntil != nil && now.Before(*e.LockedUntil) {
+		until := *e.LockedUntil
+		st.LockedUntil = &until
+		st.Remaining = 0
+		st.RetryAfter = until.Sub(now)
+	}
+	return st
+}
+
+// expiresAt 记录不再影响登录的时间：最后一次失败滑出窗口，且锁定次数已经清零
+func (g *Guard) expiresAt(e *Entry) time.Time {
+	var at time.Time
+	if n := len(e.FailedAt); n > 0 {
+		at = e.FailedAt[n-1].Add(g.cfg.Window)
+	}
+	if e.LockedUntil != nil {
+		if t := e.LockedUntil.Add(g.cfg.ResetAfter); t.After(at) {
+			at = t
+		}
+	}
+	return at
+}
+
+// lockDuration 第 n 次锁定的时长
+func (g *Guard) lockDuration(n int) time.Duration {
+	d := g.cfg.BaseLockout
+	for i := 1; i < n && d < g.cfg.MaxLockout; i++ {
+		d *= 2
+	}
+	return min(d, g.cfg.MaxLockout)
+}
+
+// accountCaptcha 用户名在所有 IP 上的失败是否已经要求验证码，account 已经 prune 过
+func (g *Guard) accountCaptcha(account *Entry) bool {
+	return len(account.FailedAt) >= g.cfg.AccountCaptchaAfter
+}
+
+// Check 登录前调用，只读不写；锁定中时返回 ErrLocked，Status 里有解锁时间
+func (g *Guard) Check(ctx context.Context, username, ip string) (Status, error) {
+	now := g.clock.Now()
+	var rows []Entry
+	err := g.db.WithContext(ctx).
+		Where("username = ? AND ip IN ? AND expires_at > ?", key(username), []string{ip, AnyIP}, now).
+		Find(&rows).Error
+	if err != nil {
+		return Status{}, err
+	}
+	var e, account Entry
+	for _, row := range rows {
+		if row.IP == ip {
+			e = row
+		} else {
+			account = row
+		}
+	}
+	g.prune(&e, now)
+	g.prune(&account, now)
+	st := g.status(&e, now)
+	st.CaptchaRequired = st.CaptchaRequired || g.accountCaptcha(&account)
+	if st.Locked() {
+		return st, ErrLocked
+	}
+	return st, nil
+}
+
+// Fail 记录一次失败，达到 MaxFailures 时开始锁定并返回 ErrLocked；
+// 同时计入用户名在所有 IP 上的失败，达到 AccountCaptchaAfter 时要求验证码
+//
+// 行锁（SELECT ... FOR UPDATE）保证并发的失败请求都被计入，不会互相覆盖。
+// 两行总是先锁用户名 + IP、再锁 AnyIP，并发的事务不会死锁。
+func (g *Guard) Fail(ctx context.Context, username, ip string) (Status, error) {
+	name := key(username)
+	var st Status
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := g.clock.Now()
+		e, err := g.lockEntry(tx, name, ip, now)
+		if err != nil {
+			return err
+		}
+		// 过期的记录 prune 之后和新记录一样；锁定中的失败不再计数，也不延长锁定
+		g.prune(e, now)
+		if st = g.status(e, now); st.Locked() {
+			return nil
+		}
+		e.FailedAt = append(e.FailedAt, now)
+		if len(e.FailedAt) >= g.cfg.MaxFailures {
+			e.Lockouts++
+			until := now.Add(g.lockDuration(e.Lockouts))
+			e.LockedUntil = &until
+			e.FailedAt = e.FailedAt[:0]
+			g.logger.Warn("login locked", "username", name, "ip", ip, "lockouts", e.Lockouts, "until", until)
+		}
+		st = g.status(e, now)
+		if err := g.save(tx, e); err != nil {
+			return err
+		}
+
+		account, err := g.lockEntry(tx, name, AnyIP, now)
+		if err != nil {
+			return err
+		}
+		g.prune(account, now)
+		wasRequired := g.accountCaptcha(account)
+		// 只需要知道最近 AccountCaptchaAfter 次是否都在窗口内，更早的不用保存
+		account.FailedAt = append(account.FailedAt, now)
+		if n := len(account.FailedAt) - g.cfg.AccountCaptchaAfter; n > 0 {
+			account.FailedAt = account.FailedAt[n:]
+		}
+		if g.accountCaptcha(account) {
+			st.CaptchaRequired = true
+			if !wasRequired {
+				g.logger.Warn("login captcha required for all ips", "username", name, "failures", len(account.FailedAt))
+			}
+		}
+		return g.save(tx, account)
+	})
+	if err != nil {
+		return Status{}, err
+	}
+	if st.Locked() {
+		return st, ErrLocked
+	}
+	return st, nil
+}
+
+// lockEntry 加行锁读取记录；第一次失败时先插入空记录，之后统一走加锁读取
+func (g *Guard) lockEntry(tx *gorm.DB, name, ip string, now time.Time) (*Entry, error) {
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&Entry{Username: name, IP: ip, FailedAt: []time.Time{}, ExpiresAt: now}).Error
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("username = ? AND ip = ?", name, ip).
+		Take(&e).Error
+	return &e, err
+}
+
+// save 写回 prune 和计数之后的记录
+func (g *Guard) save(tx *gorm.DB, e *Entry) error {
+	e.ExpiresAt = g.expiresAt(e)
+	return tx.Model(e).Select("failed_at", "lockouts", "locked_until", "expires_at", "updated_at").Updates(e).Error
+}
+
+// Succeed 登录成功，删除这个 IP 的失败记录（包括锁定次数）；
+// 用户名在所有 IP 上的记录保留到滑出窗口，否则攻击者混进一次成功登录就能清零
+func (g *Guard) Succeed(ctx context.Context, username, ip string) error {
+	return g.db.WithContext(ctx).Where("username = ? AND ip = ?", key(username), ip).Delete(&Entry{}).Error
+}
+
+// List 仍然有效的记录，锁定中的在前，其余按最近更新排序；username 不为空时只看这个用户
+func (g *Guard) List(ctx context.Context, username string) ([]Entry, error) {
+	now := g.clock.Now()
+	q := g.db.WithContext(ctx).Where("expires_at > ?", now)
+	if username != "" {
+		q = q.Where("username = ?", key(username))
+	}
+	var list []Entry
+	err := q.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:  "CASE WHEN locked_until > ? THEN 0 ELSE 1 END, updated_at DESC",
+		Vars: []any{now},
+	}}).Find(&list).Error
+	return list, err
+}
+
+// Clear 管理员解锁：删除用户名的记录，ip 为空时删除这个用户名的全部记录（包括 AnyIP）；返回删除数量
+func (g *Guard) Clear(ctx context.Context, username, ip string) (int64, error) {
+	q := g.db.WithContext(ctx).Where("username = ?", key(username))
+	if ip != "" {
+		q = q.Where("ip = ?", ip)
+	}
+	res := q.Delete(&Entry{})
+	return res.RowsAffected, res.Error
+}
+
+// Purge 删除已经不影响登录的记录，返回删除数量
+func (g *Guard) Purge(ctx context.Context) (int64, error) {
+	res := g.db.WithContext(ctx).Where("expires_at <= ?", g.clock.Now()).Delete(&Entry{})
+	return res.RowsAffected, res.Error
+}
+
+// Sweep 每隔 interval 执行一次 Purge，直到 ctx 取消
+func (g *Guard) Sweep(ctx context.Context, interval time.Duration) {
+	ticker := g.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if err := g.sweep(ctx, interval); err != nil && ctx.Err() == nil {
+				g.logger.Error("purge login lockouts", "error", err)
+			}
+		}
+	}
+}
+
+// sweepLock Sweep 的锁，其他实例持有时这一轮跳过
+const sweepLock = "lockout:sweep"
+
+func (g *Guard) sweep(ctx context.Context, interval time.Duration) error {
+	purge := func(ctx context.Context) error {
+		n, err := g.Purge(ctx)
+		if n > 0 {
+			g.logger.Info("purged expired login lockouts", "count", n)
+		}
+		return err
+	}
+	if g.cfg.Locker == nil {
+		return purge(ctx)
+	}
+	err := lock.Run(ctx, g.cfg.Locker, sweepLock, interval, purge)
+	if errors.Is(err, lock.ErrNotAcquired) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,230 @@
+package lockout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/internal/testdb"
+
+	"go-learning/clock"
+)
+
+func newTestGuard(t *testing.T) (*Guard, *clock.Fake) {
+	t.Helper()
+	db := testdb.Open(t, &Entry{})
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := New(db, Config{
+		MaxFailures:  5,
+		Window:       15 * time.Minute,
+		CaptchaAfter: 3,
+		BaseLockout:  time.Minute,
+		MaxLockout:   10 * time.Minute,
+		ResetAfter:   time.Hour,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Clock:        clk,
+	})
+	return g, clk
+}
+
+func TestFailAndLock(t *testing.T) {
+	g, clk := newTestGuard(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		failures int
+		captcha  bool
+		locked   bool
+	}{
+		{1, false, false},
+		{2, false, false},
+		{3, true, false},
+		{4, true, false},
+		{5, true, true},
+	}
+	for _, tt := range tests {
+		st, err := g.Fail(ctx, "Alice", "10.0.0.1")
+		if st.CaptchaRequired != tt.captcha || st.Locked() != tt.locked || errors.Is(err, ErrLocked) != tt.locked {
+			t.Fatalf("failure %d: %+v, %v; want captcha=%v locked=%v", tt.failures, st, err, tt.captcha, tt.locked)
+		}
+		clk.Advance(time.Second)
+	}
+
+	// 大小写不同是同一个账号；别的 IP 不受影响
+	st, err := g.Check(ctx, "alice", "10.0.0.1")
+	if !errors.Is(err, ErrLocked) || st.RetryAfter != 59*time.Second {
+		t.Errorf("Check = %+v, %v; want locked for the rest of 1m", st, err)
+	}
+	if st, err := g.Check(ctx, "alice", "10.0.0.2"); err != nil || st.Failures != 0 || st.CaptchaRequired {
+		t.Errorf("other ip = %+v, %v", st, err)
+	}
+
+	// 锁定中的失败不计数，也不延长锁定
+	g.Fail(ctx, "alice", "10.0.0.1")
+	clk.Advance(time.Minute)
+	st, err = g.Check(ctx, "alice", "10.0.0.1")
+	if err != nil || st.Failures != 0 || !st.CaptchaRequired || st.Remaining != 5 {
+		t.Errorf("after lockout = %+v, %v; want unlocked, captcha still required", st, err)
+	}
+
+	// 第二次锁定时间翻倍，第五次起不超过 MaxLockout
+	for _, want := range []time.Duration{2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute} {
+		var st Status
+		for range 5 {
+			st, _ = g.Fail(ctx, "alice", "10.0.0.1")
+		}
+		if st.RetryAfter != want {
+			t.Errorf("lockout = %v; want %v", st.RetryAfter, want)
+		}
+		clk.Advance(st.RetryAfter)
+	}
+
+	// ResetAfter 内没有再被锁定，从头计算
+	clk.Advance(time.Hour)
+	if st, err := g.Check(ctx, "alice", "10.0.0.1"); err != nil || st.CaptchaRequired {
+		t.Errorf("after reset = %+v, %v", st, err)
+	}
+	for range 5 {
+		st, _ = g.Fail(ctx, "alice", "10.0.0.1")
+	}
+	if st.RetryAfter != time.Minute {
+		t.Errorf("lockout after reset = %v; want 1m", st.RetryAfter)
+	}
+}
+
+func TestWindowAndSucceed(t *testing.T) {
+	g, clk := newTestGuard(t)
+	ctx := context.Background()
+
+	// 滑出窗口的失败不算
+	for range 4 {
+		g.Fail(ctx, "bob", "10.0.0.1")
+		clk.Advance(5 * time.Minute)
+	}
+	st, err := g.Fail(ctx, "bob", "10.0.0.1")
+	if err != nil || st.Failures != 3 {
+		t.Errorf("sliding window = %+v, %v; want 3 failures in the last 15m", st, err)
+	}
+
+	if err := g.Succeed(ctx, "BOB", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := g.Check(ctx, "bob", "10.0.0.1"); st.Failures != 0 || st.CaptchaRequired {
+		t.Errorf("after success = %+v", st)
+	}
+}
+
+func TestAccountCaptcha(t *testing.T) {
+	g, clk := newTestGuard(t) // MaxFailures 5，AccountCaptchaAfter 默认 10
+	ctx := context.Background()
+
+	// 每个 IP 只失败 2 次，单个 IP 永远到不了验证码和锁定，但用户名上一共失败了 10 次
+	ip := func(i int) string { return fmt.Sprintf("203.0.113.%d", i) }
+	for i := range 5 {
+		for range 2 {
+			st, err := g.Fail(ctx, "alice", ip(i))
+			if err != nil || st.Locked() {
+				t.Fatalf("Fail from %s = %+v, %v; want not locked", ip(i), st, err)
+			}
+		}
+		clk.Advance(time.Second)
+	}
+	// 新 IP 第一次来也要验证码，但不锁定
+	st, err := g.Check(ctx, "Alice", "198.51.100.1")
+	if err != nil || !st.CaptchaRequired || st.Locked() || st.Failures != 0 {
+		t.Errorf("Check from new ip = %+v, %v; want captcha required, not locked", st, err)
+	}
+	if st, _ := g.Check(ctx, "bob", "198.51.100.1"); st.CaptchaRequired {
+		t.Error("other username requires captcha")
+	}
+
+	// 登录成功不清零用户名上的计数，滑出窗口后恢复
+	if err := g.Succeed(ctx, "alice", ip(0)); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := g.Check(ctx, "alice", ip(0)); !st.CaptchaRequired {
+		t.Error("Succeed cleared the account-wide failures")
+	}
+	clk.Advance(15 * time.Minute)
+	if st, _ := g.Check(ctx, "alice", "198.51.100.1"); st.CaptchaRequired {
+		t.Errorf("after window = %+v; want no captcha", st)
+	}
+
+	// 窗口内只差一次时不要求
+	for i := range 9 {
+		g.Fail(ctx, "carol", ip(i))
+	}
+	if st, _ := g.Check(ctx, "carol", "198.51.100.1"); st.CaptchaRequired {
+		t.Error("9 failures should not require captcha")
+	}
+	if st, _ := g.Fail(ctx, "carol", ip(9)); !st.CaptchaRequired {
+		t.Error("10th failure should require captcha")
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	g, clk := newTestGuard(t)
+	ctx := context.Background()
+	for range 5 {
+		g.Fail(ctx, "alice", "10.0.0.1")
+	}
+	g.Fail(ctx, "alice", "10.0.0.2")
+	g.Fail(ctx, "bob", "10.0.0.1")
+	clk.Advance(time.Second)
+	g.Fail(ctx, "carol", "10.0.0.3")
+
+	// 每个用户名还有一条 AnyIP 记录
+	list, err := g.List(ctx, "")
+	if err != nil || len(list) != 7 || list[0].Username != "alice" || list[0].IP != "10.0.0.1" || list[1].Username != "carol" {
+		t.Fatalf("List = %+v, %v; want locked first, then most recent", list, err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterAdmin(r.Group("/admin/lockouts"), g)
+	do := func(method, target string) map[string]any {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s = %d", method, target, w.Code)
+		}
+		var body struct {
+			Data any `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if m, ok := body.Data.(map[string]any); ok {
+			return m
+		}
+		return map[string]any{"len": float64(len(body.Data.([]any)))}
+	}
+	if got := do(http.MethodGet, "/admin/lockouts?username=ALICE"); got["len"] != 3.0 {
+		t.Errorf("filter by username = %v", got)
+	}
+	if got := do(http.MethodDelete, "/admin/lockouts/Alice?ip=10.0.0.1"); got["cleared"] != 1.0 {
+		t.Errorf("clear one ip = %v", got)
+	}
+	if _, err := g.Check(ctx, "alice", "10.0.0.1"); err != nil {
+		t.Errorf("still locked after clear: %v", err)
+	}
+	if got := do(http.MethodDelete, "/admin/lockouts/bob"); got["cleared"] != 2.0 {
+		t.Errorf("clear all ips = %v", got)
+	}
+
+	// 过期的记录不再列出，Purge 删除
+	clk.Advance(15 * time.Minute)
+	if list, _ := g.List(ctx, ""); len(list) != 0 {
+		t.Errorf("expired entries listed: %+v", list)
+	}
+	if n, err := g.Purge(ctx); err != nil || n != 4 {
+		t.Errorf("Purge = %d, %v; want 4", n, err)
+	}
+}
@@ -0,0 +1,171 @@
+// ============================================================================
+// Package onetime 一次性 Token：找回密码、邮箱验证链接
+// ============================================================================
+//
+// 【和 Refresh Token 的区别】
+//
+// | 对比项   | refresh.Token                  | onetime.Token                        |
+// |----------|--------------------------------|--------------------------------------|
+// | 有效期   | 7 天                           | 30 分钟（重置密码）～ 1 天（验证邮箱）|
+// | 使用次数 | 每次刷新轮换                   | 只能用一次，用完写 used_at            |
+// | 发给谁   | 登录的客户端                   | 邮件里的链接，谁拿到链接谁就能用      |
+//
+// 邮件会经过很多系统（邮件服务商、转发、浏览器历史），所以 Token 必须：
+//
+//  1. 只存 SHA-256 摘要：数据库泄露后拿不到可用的链接（同 refresh 包）
+//  2. 有效期短：链接被翻出来时已经过期
+//  3. 只能用一次：Consume 用 WHERE used_at IS NULL 条件更新，并发点两次只有一次成功
+//  4. 新的作废旧的：重新申请时同一用户、同一用途的旧 Token 全部作废
+//
+//	one_time_tokens
+//	id | user_id | purpose | token_hash | expires_at | used_at | created_at
+//
+// 【用法】
+//
+//	raw, _, err := tokens.Issue(ctx, user.ID, onetime.PasswordReset, 30*time.Minute)
+//	mailer.Send(user.Email, "https://example.com/reset-password?token="+raw)
+//
+//	tok, err := tokens.Consume(ctx, req.Token, onetime.PasswordReset)
+//	// 成功后更新 tok.UserID 的密码
+//
+// ============================================================================
+package onetime
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go-learning/clock"
+)
+
+// 错误定义
+var (
+	ErrInvalid = errors.New("onetime: invalid token")
+	ErrExpired = errors.New("onetime: token expired")
+	ErrUsed    = errors.New("onetime: token already used")
+)
+
+// Purpose Token 用途，重置密码的 Token 不能拿来验证邮箱，反之亦然
+type Purpose string
+
+const (
+	PasswordReset Purpose = "password_reset"
+	EmailVerify   Purpose = "email_verify"
+)
+
+// Token 表 one_time_tokens 的一行
+type Token struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index:idx_one_time_tokens_user_purpose,priority:1" json:"user_id"`
+	Purpose   Purpose    `gorm:"size:32;not null;index:idx_one_time_tokens_user_purpose,priority:2" json:"purpose"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (Token) TableName() string {
+	return "one_time_tokens"
+}
+
+// Store 一次性 Token 存储
+type Store struct {
+	db    *gorm.DB
+	clock clock.Clock
+}
+
+// New 创建存储，表需要事先 AutoMigrate(&onetime.Token{})
+func New(db *gorm.DB) *Store {
+	return &Store{db: db, clock: clock.Real}
+}
+
+// Hash Token 摘要，与表里的 token_hash 比较
+func Hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRaw() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Issue 签发 ttl 内有效的 Token，同一用户、同一用途之前未使用的 Token 同时作废
+// 返回的原始 Token 只出现这一次，放进邮件链接后就不再保存
+func (s *Store) Issue(ctx context.Context, userID uint, purpose Purpose, ttl time.Duration) (string, *Token, error) {
+	raw, err := newRaw()
+	if err != nil {
+		return "", nil, err
+	}
+	now := s.clock.Now()
+	t := &Token{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: Hash(raw),
+		ExpiresAt: now.Add(ttl),
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 作废的 Token 也记为已使用，Consume 时返回 ErrUsed
+		if err := tx.Model(&Token{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(t).Error
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return raw, t, nil
+}
+
+// Consume 校验并用掉 Token：不存在或用途不符返回 ErrInvalid，过期返回 ErrExpired，
+// 已经用过（或被新 Token 作废）返回 ErrUsed
+func (s *Store) Consume(ctx context.Context, raw string, purpose Purpose) (*Token, error) {
+	if raw == "" {
+		return nil, ErrInvalid
+	}
+	db := s.db.WithContext(ctx)
+	var t Token
+	err := db.Where("token_hash = ? AND purpose = ?", Hash(raw), purpose).Take(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.UsedAt != nil {
+		return &t, ErrUsed
+	}
+	now := s.clock.Now()
+	if !now.Before(t.ExpiresAt) {
+		return &t, ErrExpired
+	}
+	// 条件更新：同一个链接并发提交两次，只有一次 RowsAffected 为 1
+	res := db.Model(&Token{}).Where("id = ? AND used_at IS NULL", t.ID).Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return &t, ErrUsed
+	}
+	t.UsedAt = &now
+	return &t, nil
+}
+
+// Purge 删除已过期的行，返回删除数量
+func (s *Store) Purge(ctx context.Context) (int64, error) {
+	res := s.db.WithContext(ctx).Where("expires_at <= ?", s.clock.Now()).Delete(&Token{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,122 @@
+package onetime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-one/internal/testdb"
+
+	"go-learning/clock"
+)
+
+func newTestStore(t *testing.T) (*Store, *clock.Fake) {
+	t.Helper()
+	db := testdb.Open(t, &Token{})
+	s := New(db)
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s.clock = clk
+	return s, clk
+}
+
+func TestIssueConsume(t *testing.T) {
+	s, clk := newTestStore(t)
+	ctx := context.Background()
+
+	raw, tok, err := s.Issue(ctx, 1, PasswordReset, 30*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.TokenHash == raw || tok.TokenHash != Hash(raw) {
+		t.Errorf("TokenHash = %q; want sha256 of raw token", tok.TokenHash)
+	}
+
+	// 用途不符、不存在
+	if _, err := s.Consume(ctx, raw, EmailVerify); !errors.Is(err, ErrInvalid) {
+		t.Errorf("wrong purpose: err = %v; want ErrInvalid", err)
+	}
+	if _, err := s.Consume(ctx, "nope", PasswordReset); !errors.Is(err, ErrInvalid) {
+		t.Errorf("unknown token: err = %v; want ErrInvalid", err)
+	}
+
+	clk.Advance(29 * time.Minute)
+	got, err := s.Consume(ctx, raw, PasswordReset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != 1 || got.UsedAt == nil {
+		t.Errorf("token = %+v; want user 1, used", got)
+	}
+	if _, err := s.Consume(ctx, raw, PasswordReset); !errors.Is(err, ErrUsed) {
+		t.Errorf("second use: err = %v; want ErrUsed", err)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	s, clk := newTestStore(t)
+	ctx := context.Background()
+	raw, _, _ := s.Issue(ctx, 1, EmailVerify, time.Hour)
+
+	clk.Advance(time.Hour)
+	if _, err := s.Consume(ctx, raw, EmailVerify); !errors.Is(err, ErrExpired) {
+		t.Errorf("err = %v; want ErrExpired", err)
+	}
+	if n, err := s.Purge(ctx); err != nil || n != 1 {
+		t.Errorf("Purge = %d, %v; want 1", n, err)
+	}
+}
+
+func TestIssueInvalidatesPrevious(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+	first, _, _ := s.Issue(ctx, 1, PasswordReset, time.Hour)
+	verify, _, _ := s.Issue(ctx, 1, EmailVerify, time.Hour)
+	other, _, _ := s.Issue(ctx, 2, PasswordReset, time.Hour)
+	second, _, _ := s.Issue(ctx, 1, PasswordReset, time.Hour)
+
+	if _, err := s.Consume(ctx, first, PasswordReset); !errors.Is(err, ErrUsed) {
+		t.Errorf("old token: err = %v; want ErrUsed", err)
+	}
+	// 其他用途、其他用户的 Token 不受影响
+	for name, tc := range map[string]struct {
+		raw     string
+		purpose Purpose
+	}{
+		"new":   {second, PasswordReset},
+		"other": {other, PasswordReset},
+		"email": {verify, EmailVerify},
+	} {
+		if _, err := s.Consume(ctx, tc.raw, tc.purpose); err != nil {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestConsumeConcurrent(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+	raw, _, _ := s.Issue(ctx, 1, PasswordReset, time.Hour)
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+		ok int
+	)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Consume(ctx, raw, PasswordReset); err == nil {
+				mu.Lock()
+				ok++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if ok != 1 {
+		t.Errorf("successful consumes = %d; want 1", ok)
+	}
+}
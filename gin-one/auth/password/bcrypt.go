@@ -0,0 +1,52 @@
+package password
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt bcrypt 算法
+// Cost 每加 1 耗时翻倍，建议让单次哈希耗时在 100ms~300ms 之间
+type Bcrypt struct {
+	Cost int
+}
+
+// DefaultBcrypt cost=12，普通服务器上约 250ms
+func DefaultBcrypt() Bcrypt {
+	return Bcrypt{Cost: 12}
+}
+
+// Hash 实现 Hasher
+// bcrypt 只使用密码的前 72 字节，更长的密码直接返回错误，避免静默截断
+func (b Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify 实现 Hasher，bcrypt.CompareHashAndPassword 内部使用恒定时间比较
+func (b Bcrypt) Verify(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrInvalidHash
+	}
+	return true, nil
+}
+
+// NeedsRehash 实现 Hasher
+func (b Bcrypt) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != b.Cost
+}
+
+// Identify 实现 Hasher，$2a$ / $2b$ / $2y$ 都是 bcrypt
+func (Bcrypt) Identify(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
@@ -0,0 +1,128 @@
+// ============================================================================
+// Package password 密码哈希与校验
+// ============================================================================
+//
+// 【为什么不能明文 / MD5】
+//
+// 数据库泄露后，明文密码直接可用；MD5/SHA 太快，GPU 每秒可以尝试上百亿次。
+// 密码哈希必须「故意很慢」并且带随机盐：
+//
+// | 算法     | 特点                                        | 格式示例                       |
+// |----------|---------------------------------------------|--------------------------------|
+// | bcrypt   | 老牌可靠，只有 cost 一个参数，密码最长 72 字节 | $2a$12$<salt+hash>             |
+// | argon2id | 密码学竞赛冠军，可调内存占用，抗 GPU/ASIC     | $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash> |
+//
+// 哈希字符串里自带算法和参数，所以不同算法、不同参数的哈希可以共存。
+//
+// 【登录时自动升级】
+//
+// 提高 cost 或从 bcrypt 迁移到 argon2id 时，旧哈希不需要批量处理：
+// 用户下次登录、密码校验通过后，Verify 返回用新参数计算的哈希，调用方保存即可。
+//
+//	ok, newHash, err := passwords.Verify(user.PasswordHash, req.Password)
+//	if ok && newHash != "" {
+//		db.Model(&user).Update("password_hash", newHash)
+//	}
+//
+// 【防止用户名枚举】
+//
+// 用户不存在时也要花同样的时间，否则响应时间会暴露哪些用户名存在：
+//
+//	if !exists {
+//		passwords.VerifyDummy(req.Password)
+//		return 401
+//	}
+//
+// ============================================================================
+package password
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// 错误定义
+var (
+	ErrInvalidHash      = errors.New("password: invalid hash format")
+	ErrUnknownAlgorithm = errors.New("password: unknown hash algorithm")
+)
+
+// Hasher 一种密码哈希算法
+type Hasher interface {
+	// Hash 计算密码哈希，每次调用使用新的随机盐
+	Hash(password string) (string, error)
+	// Verify 以恒定时间比较密码和哈希，密码错误返回 false, nil
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash 哈希使用的参数和当前配置不同时返回 true
+	NeedsRehash(hash string) bool
+	// Identify 哈希字符串是否由本算法生成
+	Identify(hash string) bool
+}
+
+// Service 用首选算法生成新哈希，同时能校验所有已知算法的旧哈希
+type Service struct {
+	preferred Hasher
+	hashers   []Hasher
+
+	dummyOnce sync.Once
+	dummy     string
+}
+
+// New 创建密码服务，preferred 用于新密码和自动升级，legacy 只用于校验旧哈希
+//
+//	// 新用户用 argon2id，老用户的 bcrypt 哈希登录后自动迁移
+//	passwords := password.New(password.DefaultArgon2id(), password.DefaultBcrypt())
+func New(preferred Hasher, legacy ...Hasher) *Service {
+	return &Service{
+		preferred: preferred,
+		hashers:   append([]Hasher{preferred}, legacy...),
+	}
+}
+
+// Hash 用首选算法计算哈希
+func (s *Service) Hash(password string) (string, error) {
+	return s.preferred.Hash(password)
+}
+
+// Verify 校验密码
+// 校验通过且哈希需要升级（算法或参数变化）时，newHash 为新哈希，否则为空
+func (s *Service) Verify(hash, password string) (ok bool, newHash string, err error) {
+	i, err := s.identify(hash)
+	if err != nil {
+		return false, "", err
+	}
+	ok, err = s.hashers[i].Verify(hash, password)
+	if err != nil || !ok {
+		return false, "", err
+	}
+	// hashers[0] 是首选算法，其他都是旧算法
+	if i != 0 || s.preferred.NeedsRehash(hash) {
+		// 升级失败不影响本次登录，下次再试
+		if newHash, err = s.preferred.Hash(password); err != nil {
+			return true, "", nil
+		}
+	}
+	return true, newHash, nil
+}
+
+// VerifyDummy 对一个固定的哈希做一次校验并丢弃结果
+// 用户不存在时调用，让响应时间和「用户存在但密码错误」一致
+func (s *Service) VerifyDummy(password string) {
+	s.dummyOnce.Do(func() {
+		s.dummy, _ = s.preferred.Hash("dummy-password-for-timing")
+	})
+	s.preferred.Verify(s.dummy, password)
+}
+
+func (s *Service) identify(hash string) (int, error) {
+	if !strings.HasPrefix(hash, "$") {
+		return -1, ErrInvalidHash
+	}
+	for i, h := range s.hashers {
+		if h.Identify(hash) {
+			return i, nil
+		}
+	}
+	return -1, ErrUnknownAlgorithm
+}
@@ -0,0 +1,133 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 测试用低成本参数，生产环境使用 Default*
+var (
+	fastBcrypt   = Bcrypt{Cost: bcrypt.MinCost}
+	fastArgon2id = Argon2id{Memory: 1024, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}
+)
+
+func TestHashers(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher Hasher
+		prefix string
+	}{
+		{"bcrypt", fastBcrypt, "$2a$04$"},
+		{"argon2id", fastArgon2id, "$argon2id$v=19$m=1024,t=1,p=1$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := tt.hasher.Hash("s3cret!")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Errorf("Hash() = %q; want prefix %q", hash, tt.prefix)
+			}
+			if !tt.hasher.Identify(hash) {
+				t.Errorf("Identify(%q) = false", hash)
+			}
+
+			// 同一密码两次哈希结果不同（随机盐）
+			hash2, _ := tt.hasher.Hash("s3cret!")
+			if hash == hash2 {
+				t.Error("two hashes of the same password are equal; salt not random")
+			}
+
+			if ok, err := tt.hasher.Verify(hash, "s3cret!"); !ok || err != nil {
+				t.Errorf("Verify(correct) = %v, %v; want true, nil", ok, err)
+			}
+			if ok, err := tt.hasher.Verify(hash, "wrong"); ok || err != nil {
+				t.Errorf("Verify(wrong) = %v, %v; want false, nil", ok, err)
+			}
+			if tt.hasher.NeedsRehash(hash) {
+				t.Error("NeedsRehash() = true for hash with current params")
+			}
+		})
+	}
+}
+
+func TestArgon2idInvalidHash(t *testing.T) {
+	tests := []string{
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=0,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$",
+	}
+	for _, hash := range tests {
+		if _, err := fastArgon2id.Verify(hash, "x"); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("Verify(%q) error = %v; want ErrInvalidHash", hash, err)
+		}
+	}
+}
+
+func TestServiceVerify(t *testing.T) {
+	bcryptHash, _ := fastBcrypt.Hash("s3cret!")
+	oldBcryptHash, _ := Bcrypt{Cost: bcrypt.MinCost + 1}.Hash("s3cret!")
+	argonHash, _ := fastArgon2id.Hash("s3cret!")
+	oldArgonHash, _ := Argon2id{Memory: 512, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}.Hash("s3cret!")
+
+	tests := []struct {
+		name      string
+		svc       *Service
+		hash      string
+		password  string
+		wantOK    bool
+		wantNew   string // 新哈希的前缀，空表示不需要升级
+		wantError error
+	}{
+		{"current params", New(fastArgon2id, fastBcrypt), argonHash, "s3cret!", true, "", nil},
+		{"wrong password", New(fastArgon2id, fastBcrypt), argonHash, "nope", false, "", nil},
+		{"legacy algorithm migrates", New(fastArgon2id, fastBcrypt), bcryptHash, "s3cret!", true, "$argon2id$", nil},
+		{"legacy wrong password", New(fastArgon2id, fastBcrypt), bcryptHash, "nope", false, "", nil},
+		{"argon2 params changed", New(fastArgon2id), oldArgonHash, "s3cret!", true, "$argon2id$v=19$m=1024,", nil},
+		{"bcrypt cost changed", New(fastBcrypt), oldBcryptHash, "s3cret!", true, "$2a$04$", nil},
+		{"algorithm not configured", New(fastArgon2id), bcryptHash, "s3cret!", false, "", ErrUnknownAlgorithm},
+		{"plaintext in database", New(fastArgon2id), "s3cret!", "s3cret!", false, "", ErrInvalidHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, newHash, err := tt.svc.Verify(tt.hash, tt.password)
+			if ok != tt.wantOK || !errors.Is(err, tt.wantError) {
+				t.Fatalf("Verify() = %v, _, %v; want %v, _, %v", ok, err, tt.wantOK, tt.wantError)
+			}
+			if tt.wantNew == "" {
+				if newHash != "" {
+					t.Errorf("newHash = %q; want empty", newHash)
+				}
+				return
+			}
+			if !strings.HasPrefix(newHash, tt.wantNew) {
+				t.Fatalf("newHash = %q; want prefix %q", newHash, tt.wantNew)
+			}
+			// 升级后的哈希可以直接用于下次登录，且不再需要升级
+			ok, again, err := tt.svc.Verify(newHash, tt.password)
+			if !ok || again != "" || err != nil {
+				t.Errorf("Verify(newHash) = %v, %q, %v; want true, \"\", nil", ok, again, err)
+			}
+		})
+	}
+}
+
+func TestBcryptTooLong(t *testing.T) {
+	if _, err := fastBcrypt.Hash(strings.Repeat("a", 73)); err == nil {
+		t.Error("Hash(73 bytes) error = nil; want error instead of silent truncation")
+	}
+}
+
+func TestVerifyDummy(t *testing.T) {
+	svc := New(fastArgon2id)
+	svc.VerifyDummy("anything")
+	if svc.dummy == "" || !fastArgon2id.Identify(svc.dummy) {
+		t.Errorf("dummy hash = %q; want argon2id hash", svc.dummy)
+	}
+}
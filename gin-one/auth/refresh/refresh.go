@@ -0,0 +1,400 @@
+// ============================================================================
+// Package refresh Refresh Token 持久化与撤销
+// ============================================================================
+//
+// 【为什么要存数据库】
+//
+// JWT 签发后无法撤销，Access Token 靠短有效期兜底；
+// Refresh Token 有效期长（默认 7 天），必须能在服务端作废：
+//
+// | 场景                   | 做法                         |
+// |------------------------|------------------------------|
+// | 用户登出               | Revoke 当前 Refresh Token     |
+// | 修改密码 / 账号被盗    | RevokeAll 撤销该用户所有设备  |
+// | 刷新 Access Token      | Rotate：旧的作废，签发新的    |
+// | 已轮换的 Token 又被使用 | 撤销整个 Token 家族，ErrReused |
+// | 过期数据               | Sweep 后台定期删除            |
+//
+// 【存什么】
+//
+// Refresh Token 是 32 字节随机串，表里只存 SHA-256 摘要：
+// 数据库泄露后拿到的摘要无法直接用来换 Token。
+// 随机串熵足够高，不需要 bcrypt 这种慢哈希。
+//
+//	refresh_tokens
+//	id | user_id | family_id | token_hash | device | ip | expires_at | revoked | revoked_at | replaced_by | last_used_at | created_at
+//
+// 【重复使用检测】
+//
+// 一次登录签发的 Token 和之后轮换出来的 Token 属于同一个家族（family_id）。
+// 轮换后旧 Token 记下 replaced_by，正常的客户端不会再用它；再次出现说明 Token 被复制了：
+//
+//	登录 ──▶ T1 ──刷新──▶ T2 ──刷新──▶ T3        （合法客户端）
+//	          └── 攻击者偷到 T1 后刷新 ──▶ ErrReused，T1..T3 全部撤销
+//
+// 分不清哪一方是攻击者，所以整个家族作废，双方都要重新登录；同时发布 Reused 事件，
+// 安全日志、通知用户等订阅者据此处理。客户端收到 ErrReused 对应的错误码时应当清掉本地 Token、跳转登录页。
+//
+// 网络重试可能让合法客户端把同一个 Token 提交两次，Config.ReuseInterval 内的重复使用只返回 ErrRevoked。
+//
+// 【用法】
+//
+//	tokens := refresh.New(db, refresh.Config{TTL: 7 * 24 * time.Hour})
+//	raw, _, err := tokens.Issue(ctx, user.ID, c.Request.UserAgent(), c.ClientIP())
+//	go tokens.Sweep(ctx, time.Hour) // 多实例部署时 Config.Locker 保证每轮只有一个实例清理
+//
+//	eventbus.Subscribe(bus, refresh.Reused, eventbus.Options{Name: "security"},
+//	    func(ctx context.Context, e refresh.ReuseEvent) error { ... })
+//
+// ============================================================================
+package refresh
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go-one/eventbus"
+	"go-one/lock"
+
+	"go-learning/clock"
+)
+
+// 错误定义
+var (
+	ErrInvalid = errors.New("refresh: invalid token")
+	ErrExpired = errors.New("refresh: token expired")
+	ErrRevoked = errors.New("refresh: token revoked")
+	ErrReused  = errors.New("refresh: rotated token reused, token family revoked")
+)
+
+// Reused 检测到已轮换的 Token 被再次使用时发布的安全事件
+var Reused = eventbus.NewTopic[ReuseEvent]("auth.refresh_token_reused")
+
+// ReuseEvent 重复使用事件
+type ReuseEvent struct {
+	UserID   uint   `json:"user_id"`
+	FamilyID string `json:"family_id"`
+	// TokenID 被重复使用的 Token
+	TokenID uint `json:"token_id"`
+	// Device、IP 这一次（重复使用的）请求的设备和 IP
+	Device string `json:"device"`
+	IP     string `json:"ip"`
+	// Revoked 撤销的 Token 数
+	Revoked int64     `json:"revoked"`
+	At      time.Time `json:"at"`
+}
+
+// Token 表 refresh_tokens 的一行，对应一个登录设备
+type Token struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID   string     `gorm:"size:32;index" json:"family_id"` // 同一次登录轮换出来的 Token 共用
+	TokenHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Device     string     `gorm:"size:255" json:"device"` // User-Agent
+	IP         string     `gorm:"size:45" json:"ip"`
+	ExpiresAt  time.Time  `gorm:"not null;index" json:"expires_at"`
+	Revoked    bool       `gorm:"not null;default:false" json:"revoked"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy *uint      `json:"-"` // 轮换出来的新 Token ID，有值说明已经用过
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (Token) TableName() string {
+	return "refresh_tokens"
+}
+
+// Config 存储配置
+type Config struct {
+	// TTL Refresh Token 有效期，默认 7 天
+	TTL time.Duration
+
+	// Logger 后台清理日志，默认 slog.Default()
+	Logger *slog.Logger
+
+	// Locker 多实例部署时 Sweep 每一轮只由一个实例执行，nil 表示不加锁
+	Locker lock.Locker
+
+	// Clock 判断过期和 Sweep 定时用，默认 clock.Real；测试传 clock.NewFake
+	Clock clock.Clock
+
+	// ReuseInterval 轮换后这段时间内旧 Token 再次出现时当作客户端重试，只返回 ErrRevoked；
+	// 默认 0：任何重复使用都撤销整个家族
+	ReuseInterval time.Duration
+
+	// Bus 发布 Reused 事件，nil 时只记录日志
+	Bus *eventbus.Bus
+}
+
+// Store Refresh Token 存储
+type Store struct {
+	db     *gorm.DB
+	ttl    time.Duration
+	logger *slog.Logger
+	locker lock.Locker
+	clock  clock.Clock
+	reuse  time.Duration
+	bus    *eventbus.Bus
+}
+
+// New 创建存储，表需要事先 AutoMigrate(&refresh.Token{})
+func New(db *gorm.DB, cfg Config) *Store {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 7 * 24 * time.Hour
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Store{
+		db: db, ttl: cfg.TTL, logger: cfg.Logger, locker: cfg.Locker, clock: clock.OrReal(cfg.Clock),
+		reuse: cfg.ReuseInterval, bus: cfg.Bus,
+	}
+}
+
+// Hash Token 摘要，与表里的 token_hash 比较
+func Hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRaw() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func newFamily() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Issue 为用户签发新的 Refresh Token，开始一个新的家族，返回的原始 Token 只出现这一次
+func (s *Store) Issue(ctx context.Context, userID uint, device, ip string) (string, *Token, error) {
+	family, err := newFamily()
+	if err != nil {
+		return "", nil, err
+	}
+	return s.issue(s.db.WithContext(ctx), userID, family, device, ip)
+}
+
+func (s *Store) issue(tx *gorm.DB, userID uint, family, device, ip string) (string, *Token, error) {
+	raw, err := newRaw()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(device) > 255 {
+		device = device[:255]
+	}
+	t := &Token{
+		UserID:    userID,
+		FamilyID:  family,
+		TokenHash: Hash(raw),
+		Device:    device,
+		IP:        ip,
+		ExpiresAt: s.clock.Now().Add(s.ttl),
+	}
+	if err := tx.Create(t).Error; err != nil {
+		return "", nil, err
+	}
+	return raw, t, nil
+}
+
+// Validate 检查 Token 是否存在、未过期、未撤销
+func (s *Store) Validate(ctx context.Context, raw string) (*Token, error) {
+	return s.find(s.db.WithContext(ctx), raw)
+}
+
+func (s *Store) find(tx *gorm.DB, raw string) (*Token, error) {
+	var t Token
+	err := tx.Where("token_hash = ?", Hash(raw)).Take(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Revoked {
+		return &t, ErrRevoked
+	}
+	if !s.clock.Now().Before(t.ExpiresAt) {
+		return &t, ErrExpired
+	}
+	return &t, nil
+}
+
+// Rotate 用旧 Token 换新 Token：旧的立即撤销，新 Token 记录本次请求的设备信息，家族不变
+//
+// 撤销用条件更新（WHERE revoked = false），同一个 Token 并发刷新时只有一个成功，
+// 另一个得到 ErrRevoked。已经轮换过的 Token 再次出现时撤销整个家族，返回 ErrReused。
+func (s *Store) Rotate(ctx context.Context, raw, device, ip string) (string, *Token, error) {
+	var (
+		newRaw string
+		next   *Token
+		reused *ReuseEvent
+	)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		old, err := s.find(tx, raw)
+		now := s.clock.Now()
+		if errors.Is(err, ErrRevoked) && old.ReplacedBy != nil {
+			if s.reuse > 0 && old.RevokedAt != nil && now.Sub(*old.RevokedAt) < s.reuse {
+				return ErrRevoked
+			}
+			n, err := s.revokeFamily(tx, old)
+			if err != nil {
+				return err
+			}
+			reused = &ReuseEvent{
+				UserID: old.UserID, FamilyID: old.FamilyID, TokenID: old.ID,
+				Device: device, IP: ip, Revoked: n, At: now,
+			}
+			// 撤销要提交，不能返回错误让事务回滚
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		res := tx.Model(&Token{}).
+			Where("id = ? AND revoked = ?", old.ID, false).
+			Updates(map[string]any{"revoked": true, "revoked_at": now, "last_used_at": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRevoked
+		}
+		family := old.FamilyID
+		if family == "" {
+			// 加上 family_id 之前签发的 Token，从这次轮换开始一个家族
+			if family, err = newFamily(); err != nil {
+				return err
+			}
+		}
+		if newRaw, next, err = s.issue(tx, old.UserID, family, device, ip); err != nil {
+			return err
+		}
+		return tx.Model(&Token{}).Where("id = ?", old.ID).Update("replaced_by", next.ID).Error
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if reused != nil {
+		s.reported(ctx, *reused)
+		return "", nil, ErrReused
+	}
+	return newRaw, next, nil
+}
+
+// revokeFamily 撤销 Token 所在家族里还没撤销的 Token；没有家族的旧数据撤销用户的所有 Token
+func (s *Store) revokeFamily(tx *gorm.DB, t *Token) (int64, error) {
+	q := tx.Model(&Token{}).Where("user_id = ? AND revoked = ?", t.UserID, false)
+	if t.FamilyID != "" {
+		q = q.Where("family_id = ?", t.FamilyID)
+	}
+	res := q.Updates(map[string]any{"revoked": true, "revoked_at": s.clock.Now()})
+	return res.RowsAffected, res.Error
+}
+
+// reported 记录日志并发布 Reused 事件，发布失败不影响返回 ErrReused
+func (s *Store) reported(ctx context.Context, e ReuseEvent) {
+	s.logger.Warn("refresh token reused, family revoked",
+		"user_id", e.UserID, "family_id", e.FamilyID, "token_id", e.TokenID, "ip", e.IP, "revoked", e.Revoked)
+	if s.bus == nil {
+		return
+	}
+	if err := eventbus.Publish(ctx, s.bus, Reused, e); err != nil {
+		s.logger.Error("publish refresh token reuse event", "error", err)
+	}
+}
+
+// Revoke 撤销单个 Token，Token 不存在返回 ErrInvalid，重复撤销不报错
+func (s *Store) Revoke(ctx context.Context, raw string) error {
+	db := s.db.WithContext(ctx)
+	var t Token
+	err := db.Select("id").Where("token_hash = ?", Hash(raw)).Take(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalid
+	}
+	if err != nil {
+		return err
+	}
+	return db.Model(&Token{}).
+		Where("id = ? AND revoked = ?", t.ID, false).
+		Updates(map[string]any{"revoked": true, "revoked_at": s.clock.Now()}).Error
+}
+
+// RevokeAll 撤销用户所有未撤销的 Token（所有设备下线），返回撤销的数量
+func (s *Store) RevokeAll(ctx context.Context, userID uint) (int64, error) {
+	res := s.db.WithContext(ctx).Model(&Token{}).
+		Where("user_id = ? AND revoked = ?", userID, false).
+		Updates(map[string]any{"revoked": true, "revoked_at": s.clock.Now()})
+	return res.RowsAffected, res.Error
+}
+
+// Active 用户当前有效的 Token（已登录的设备），最近签发的在前
+func (s *Store) Active(ctx context.Context, userID uint) ([]Token, error) {
+	var list []Token
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND revoked = ? AND expires_at > ?", userID, false, s.clock.Now()).
+		Order("id DESC").
+		Find(&list).Error
+	return list, err
+}
+
+// Purge 删除已过期的行，返回删除数量
+// 已撤销但未过期的行暂时保留，用于排查"已撤销的 Token 又被使用"
+func (s *Store) Purge(ctx context.Context) (int64, error) {
+	res := s.db.WithContext(ctx).Where("expires_at <= ?", s.clock.Now()).Delete(&Token{})
+	return res.RowsAffected, res.Error
+}
+
+// Sweep 每隔 interval 执行一次 Purge，直到 ctx 取消
+// 通常 go tokens.Sweep(ctx, time.Hour)，服务关闭时取消 ctx
+func (s *Store) Sweep(ctx context.Context, interval time.Duration) {
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if err := s.sweep(ctx, interval); err != nil && ctx.Err() == nil {
+				s.logger.Error("purge refresh tokens", "error", err)
+			}
+		}
+	}
+}
+
+// sweepLock Sweep 的锁，其他实例持有时这一轮跳过
+const sweepLock = "refresh:sweep"
+
+func (s *Store) sweep(ctx context.Context, interval time.Duration) error {
+	purge := func(ctx context.Context) error {
+		n, err := s.Purge(ctx)
+		if n > 0 {
+			s.logger.Info("purged expired refresh tokens", "count", n)
+		}
+		return err
+	}
+	if s.locker == nil {
+		return purge(ctx)
+	}
+	err := lock.Run(ctx, s.locker, sweepLock, interval, purge)
+	if errors.Is(err, lock.ErrNotAcquired) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,310 @@
+package refresh
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"go-one/eventbus"
+	"go-one/internal/testdb"
+	"go-one/lock"
+
+	"go-learning/clock"
+)
+
+func newTestStore(t *testing.T) (*Store, *clock.Fake) {
+	t.Helper()
+	db := testdb.Open(t, &Token{})
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := New(db, Config{TTL: time.Hour, Logger: slog.New(slog.NewTextHandler(io.Discard, nil)), Clock: clk})
+	return s, clk
+}
+
+func TestIssueValidate(t *testing.T) {
+	s, clk := newTestStore(t)
+	ctx := context.Background()
+
+	raw, tok, err := s.Issue(ctx, 1, "curl/8.0", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.TokenHash == raw || tok.TokenHash != Hash(raw) {
+		t.Errorf("TokenHash = %q; want sha256 of raw token", tok.TokenHash)
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		advance time.Duration
+		want    error
+	}{
+		{"valid", raw, 0, nil},
+		{"unknown", "not-a-token", 0, ErrInvalid},
+		{"expired", raw, time.Hour, ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := clk.Now()
+			defer clk.Set(saved)
+			clk.Advance(tt.advance)
+
+			got, err := s.Validate(ctx, tt.raw)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Validate() error = %v; want %v", err, tt.want)
+			}
+			if err == nil && (got.UserID != 1 || got.Device != "curl/8.0") {
+				t.Errorf("Validate() = %+v; want user 1 on curl/8.0", got)
+			}
+		})
+	}
+}
+
+func TestRotate(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+
+	raw, _, _ := s.Issue(ctx, 7, "old", "")
+	next, tok, err := s.Rotate(ctx, raw, "new", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.UserID != 7 || tok.Device != "new" {
+		t.Errorf("rotated token = %+v; want user 7 on new device", tok)
+	}
+	if _, err := s.Validate(ctx, raw); !errors.Is(err, ErrRevoked) {
+		t.Errorf("old token: error = %v; want ErrRevoked", err)
+	}
+	if _, err := s.Validate(ctx, next); err != nil {
+		t.Errorf("new token: error = %v; want nil", err)
+	}
+	if tok.FamilyID == "" {
+		t.Error("rotated token has no family")
+	}
+	if _, _, err := s.Rotate(ctx, raw, "", ""); !errors.Is(err, ErrReused) {
+		t.Errorf("rotate twice: error = %v; want ErrReused", err)
+	}
+}
+
+func TestReuse(t *testing.T) {
+	s, clk := newTestStore(t)
+	ctx := context.Background()
+	bus := eventbus.New(eventbus.Config{})
+	events := make(chan ReuseEvent, 1)
+	eventbus.Subscribe(bus, Reused, eventbus.Options{Name: "test"}, func(_ context.Context, e ReuseEvent) error {
+		events <- e
+		return nil
+	})
+	s.bus = bus
+
+	// 合法客户端 t1 → t2 → t3，另一台设备单独登录
+	t1, first, _ := s.Issue(ctx, 1, "laptop", "10.0.0.1")
+	t2, _, _ := s.Rotate(ctx, t1, "laptop", "10.0.0.1")
+	t3, _, _ := s.Rotate(ctx, t2, "laptop", "10.0.0.1")
+	phone, _, _ := s.Issue(ctx, 1, "phone", "10.0.0.2")
+
+	// 攻击者拿偷到的 t1 刷新：整个家族撤销，合法客户端手里的 t3 也不能用了
+	if _, _, err := s.Rotate(ctx, t1, "evil", "203.0.113.9"); !errors.Is(err, ErrReused) {
+		t.Fatalf("reuse: error = %v; want ErrReused", err)
+	}
+	if _, err := s.Validate(ctx, t3); !errors.Is(err, ErrRevoked) {
+		t.Errorf("latest token in family: error = %v; want ErrRevoked", err)
+	}
+	if _, err := s.Validate(ctx, phone); err != nil {
+		t.Errorf("other family: error = %v; want nil", err)
+	}
+	select {
+	case e := <-events:
+		if e.UserID != 1 || e.FamilyID != first.FamilyID || e.IP != "203.0.113.9" || e.Revoked != 1 {
+			t.Errorf("event = %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no reuse event")
+	}
+	bus.Close(ctx)
+
+	// 登出撤销的 Token 再用不算重复使用
+	s.Revoke(ctx, phone)
+	if _, _, err := s.Rotate(ctx, phone, "", ""); !errors.Is(err, ErrRevoked) {
+		t.Errorf("revoked by logout: error = %v; want ErrRevoked", err)
+	}
+
+	// ReuseInterval 内当作客户端重试
+	s.reuse = 10 * time.Second
+	a1, _, _ := s.Issue(ctx, 2, "", "")
+	a2, _, _ := s.Rotate(ctx, a1, "", "")
+	clk.Advance(5 * time.Second)
+	if _, _, err := s.Rotate(ctx, a1, "", ""); !errors.Is(err, ErrRevoked) {
+		t.Errorf("retry within interval: error = %v; want ErrRevoked", err)
+	}
+	if _, err := s.Validate(ctx, a2); err != nil {
+		t.Errorf("family revoked on retry: %v", err)
+	}
+	clk.Advance(5 * time.Second)
+	if _, _, err := s.Rotate(ctx, a1, "", ""); !errors.Is(err, ErrReused) {
+		t.Errorf("after interval: error = %v; want ErrReused", err)
+	}
+}
+
+func TestReuseLegacy(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+
+	// 加 family_id 之前签发的 Token：轮换时开始新家族，重复使用时撤销用户的所有 Token
+	old, tok, _ := s.Issue(ctx, 3, "", "")
+	other, _, _ := s.Issue(ctx, 3, "", "")
+	s.db.Model(&Token{}).Where("user_id = ?", 3).Update("family_id", "")
+	next, rotated, err := s.Rotate(ctx, old, "", "")
+	if err != nil || rotated.FamilyID == "" || rotated.FamilyID == tok.FamilyID {
+		t.Fatalf("rotate legacy = %+v, %v", rotated, err)
+	}
+	if _, _, err := s.Rotate(ctx, old, "", ""); !errors.Is(err, ErrReused) {
+		t.Fatalf("reuse legacy: error = %v", err)
+	}
+	for _, raw := range []string{next, other} {
+		if _, err := s.Validate(ctx, raw); !errors.Is(err, ErrRevoked) {
+			t.Errorf("user token after legacy reuse: error = %v; want ErrRevoked", err)
+		}
+	}
+}
+
+func TestRotateConcurrent(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+	raw, _, _ := s.Issue(ctx, 1, "", "")
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+		ok int
+	)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := s.Rotate(ctx, raw, "", ""); err == nil {
+				mu.Lock()
+				ok++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if ok != 1 {
+		t.Errorf("%d concurrent rotations succeeded; want 1", ok)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+
+	a1, _, _ := s.Issue(ctx, 1, "laptop", "")
+	a2, _, _ := s.Issue(ctx, 1, "phone", "")
+	b1, _, _ := s.Issue(ctx, 2, "laptop", "")
+
+	if err := s.Revoke(ctx, a1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Revoke(ctx, a1); err != nil {
+		t.Errorf("Revoke twice: error = %v; want nil", err)
+	}
+	if err := s.Revoke(ctx, "unknown"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Revoke unknown: error = %v; want ErrInvalid", err)
+	}
+
+	active, _ := s.Active(ctx, 1)
+	if len(active) != 1 || active[0].Device != "phone" {
+		t.Errorf("Active(1) = %+v; want only phone", active)
+	}
+
+	n, err := s.RevokeAll(ctx, 1)
+	if err != nil || n != 1 {
+		t.Errorf("RevokeAll(1) = %d, %v; want 1, nil", n, err)
+	}
+	if _, err := s.Validate(ctx, a2); !errors.Is(err, ErrRevoked) {
+		t.Errorf("a2 after RevokeAll: error = %v; want ErrRevoked", err)
+	}
+	if _, err := s.Validate(ctx, b1); err != nil {
+		t.Errorf("other user's token: error = %v; want nil", err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	s, clk := newTestStore(t)
+	ctx := context.Background()
+
+	s.Issue(ctx, 1, "", "")
+	clk.Advance(30 * time.Minute)
+	fresh, _, _ := s.Issue(ctx, 1, "", "")
+	clk.Advance(45 * time.Minute) // 第一个已过期，第二个还剩 15 分钟
+
+	n, err := s.Purge(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("Purge() = %d, %v; want 1, nil", n, err)
+	}
+	if _, err := s.Validate(ctx, fresh); err != nil {
+		t.Errorf("unexpired token: error = %v; want nil", err)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	s, clk := newTestStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.Issue(ctx, 1, "", "")
+	clk.Advance(2 * time.Hour)
+
+	done := make(chan struct{})
+	go func() {
+		s.Sweep(ctx, time.Hour)
+		close(done)
+	}()
+	// 等 Sweep 建好 ticker 再推进时间，不用真的等一小时
+	clk.BlockUntil(1)
+	clk.Advance(time.Hour)
+	deadline := time.After(2 * time.Second)
+	for {
+		var count int64
+		s.db.Model(&Token{}).Count(&count)
+		if count == 0 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("expired token not purged by Sweep")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+}
+
+func TestSweepLocked(t *testing.T) {
+	s, clk := newTestStore(t)
+	s.locker = lock.NewMemory()
+	ctx := context.Background()
+	s.Issue(ctx, 1, "", "")
+	clk.Advance(2 * time.Hour)
+
+	// 其他实例正在清理：这一轮跳过，不算错误
+	other, _ := s.locker.Acquire(ctx, sweepLock, time.Minute)
+	var count int64
+	if err := s.sweep(ctx, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if s.db.Model(&Token{}).Count(&count); count != 1 {
+		t.Fatalf("swept while another instance held the lock, count = %d", count)
+	}
+
+	other.Release(ctx)
+	if err := s.sweep(ctx, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if s.db.Model(&Token{}).Count(&count); count != 0 {
+		t.Errorf("count after sweep = %d", count)
+	}
+}
@@ -0,0 +1,370 @@
+// ============================================================================
+// Package session 服务端会话：Cookie 登录，JWT 之外的另一种认证方式
+// ============================================================================
+//
+// 【和 JWT 的区别】
+//
+// | 对比项     | JWT（5_1 的 /login）                 | Session（5_1 的 /session/login）          |
+// |------------|--------------------------------------|-------------------------------------------|
+// | 客户端保存 | Access Token + Refresh Token         | 只有加密的会话 ID（HttpOnly Cookie）       |
+// | 服务端保存 | Refresh Token 摘要                   | 整个会话（内存 / Redis）                  |
+// | 怎么带上   | Authorization: Bearer，JS 自己加     | 浏览器自动带 Cookie                       |
+// | 登出       | Access Token 到期前仍然有效          | 删除会话，下一个请求立即失效              |
+// | 主要风险   | XSS 偷走 Token                       | CSRF：别的网站让浏览器带着 Cookie 发请求  |
+// | 适合       | 移动端、第三方调用、服务间调用       | 同域的浏览器页面                          |
+//
+// 【Cookie 里的会话 ID 为什么要加密】
+//
+// 会话 ID 本身就是 32 字节随机数，猜不到；再用 AES-GCM 加密是为了：
+//
+//  1. 伪造或篡改的 Cookie 在解密时就被拒绝，不会拿去查存储
+//  2. 存储里的 key 不能直接当 Cookie 用：Redis 的 key 列表泄露了也登录不了
+//
+// Cookie 固定 HttpOnly（JS 读不到）、SameSite=Lax（跨站 POST 不带 Cookie），
+// 生产环境 Secure 必须为 true。
+//
+// 【两个超时】
+//
+//	IdleTimeout      多久没有请求就过期（默认 30 分钟），每个请求顺延
+//	AbsoluteTimeout  从登录算起最长多久（默认 12 小时），一直在用也要重新登录
+//
+// 顺延不是每个请求都写存储：距离上次写入超过 IdleTimeout/10（最多 1 分钟）才写一次。
+//
+// 【CSRF】
+//
+// 登录时为会话生成一个 CSRF Token。页面从 /session/me 或模板里拿到它，
+// 修改数据的请求放在 X-CSRF-Token 头或 csrf_token 表单字段里，VerifyCSRF 校验。
+// 攻击者的页面能让浏览器带上 Cookie，但读不到这个 Token。
+// 服务端渲染的表单用 middleware/csrf：csrf.Config{SessionToken: session.CSRFToken}。
+//
+// 【用法】
+//
+//	sessions, _ := session.New(session.Config{Store: session.NewMemoryStore(0), Secret: secret})
+//	r.Use(sessions.Middleware())
+//
+//	r.POST("/session/login", func(c *gin.Context) {
+//		// 校验密码 ...
+//		sessions.Login(c, user.ID, map[string]string{"role": user.Role}) // 换新的会话 ID，防会话固定
+//	})
+//	me := r.Group("/session", sessions.RequireLogin(), sessions.VerifyCSRF())
+//
+// ============================================================================
+package session
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+
+	"go-learning/clock"
+)
+
+// 错误定义
+var (
+	ErrNotFound      = errors.New("session: not found")
+	ErrInvalidCookie = errors.New("session: invalid cookie")
+)
+
+// contextKey 会话在 gin.Context 中的键
+const contextKey = "session"
+
+// CSRF Token 在请求里的位置
+const (
+	CSRFHeader = "X-CSRF-Token"
+	CSRFField  = "csrf_token"
+)
+
+// Config 会话配置
+type Config struct {
+	// Store 会话存储，必填：单实例用 NewMemoryStore，多实例用 NewRedisStore
+	Store Store
+
+	// Secret 加密 Cookie 的密钥，至少 32 字节
+	Secret []byte
+
+	// CookieName 默认 sid；CookiePath 默认 /
+	CookieName string
+	CookiePath string
+	// Domain 默认为空，只发给当前域名
+	Domain string
+	// Secure Cookie 只通过 HTTPS 发送，生产环境必须为 true
+	Secure bool
+
+	// IdleTimeout 空闲超时，默认 30 分钟
+	IdleTimeout time.Duration
+	// AbsoluteTimeout 绝对超时，默认 12 小时
+	AbsoluteTimeout time.Duration
+
+	// Clock 默认 clock.Real，测试时注入 clock.Fake
+	Clock clock.Clock
+
+	// Logger 记录存储读写失败，默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Manager 会话管理：读写 Cookie、加载和保存会话
+type Manager struct {
+	cfg        Config
+	codec      *codec
+	touchEvery time.Duration
+	clock      clock.Clock
+	logger     *slog.Logger
+}
+
+// New 创建会话管理器
+func New(cfg Config) (*Manager, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("session: Store is required")
+	}
+	if len(cfg.Secret) < 32 {
+		return nil, errors.New("session: secret must be at least 32 bytes")
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "sid"
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 30 * time.Minute
+	}
+	if cfg.AbsoluteTimeout <= 0 {
+		cfg.AbsoluteTimeout = 12 * time.Hour
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	codec, err := newCodec(cfg.Secret, cfg.CookieName)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{
+		cfg:        cfg,
+		codec:      codec,
+		touchEvery: min(cfg.IdleTimeout/10, time.Minute),
+		clock:      clock.OrReal(cfg.Clock),
+		logger:     cfg.Logger,
+	}, nil
+}
+
+// Session 当前请求的会话，只在一个请求内使用，不要并发修改
+type Session struct {
+	id    string
+	data  Data
+	dirty bool
+}
+
+// UserID 登录用户的 ID
+func (s *Session) UserID() uint { return s.data.UserID }
+
+// CSRFToken 这个会话的 CSRF Token，放进页面或响应里给前端
+func (s *Session) CSRFToken() string { return s.data.CSRFToken }
+
+// CreatedAt 登录时间
+func (s *Session) CreatedAt() time.Time { return s.data.CreatedAt }
+
+// Get 读取会话里的值，不存在时返回空字符串
+func (s *Session) Get(key string) string { return s.data.Values[key] }
+
+// Set 写入会话里的值，请求结束时保存
+func (s *Session) Set(key, value string) {
+	if s.data.Values == nil {
+		s.data.Values = make(map[string]string)
+	}
+	s.data.Values[key] = value
+	s.dirty = true
+}
+
+// Delete 删除会话里的值，请求结束时保存
+func (s *Session) Delete(key string) {
+	if _, ok := s.data.Values[key]; ok {
+		delete(s.data.Values, key)
+		s.dirty = true
+	}
+}
+
+// FromContext 取出 Middleware 加载的会话，没有登录时返回 nil
+func FromContext(c *gin.Context) *Session {
+	if v, ok := c.Get(contextKey); ok {
+		if s, ok := v.(*Session); ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// CSRFToken 当前会话的 CSRF Token，没有登录时返回空字符串；
+// 签名和 csrf.Config.SessionToken 相同，用 csrf 中间件的同步令牌模式时直接传进去
+func CSRFToken(c *gin.Context) string {
+	if s := FromContext(c); s != nil {
+		return s.CSRFToken()
+	}
+	return ""
+}
+
+// Middleware 从 Cookie 加载会话放进 gin.Context；Cookie 无效或会话过期时按未登录处理并清掉 Cookie。
+// 请求结束时保存 Set / Delete 的修改，并按需顺延空闲超时。
+func (m *Manager) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s := m.load(c)
+		if s == nil {
+			c.Next()
+			return
+		}
+		c.Set(contextKey, s)
+		c.Next()
+
+		// handler 里 Login / Logout 换掉了会话，它们已经保存过
+		if FromContext(c) != s {
+			return
+		}
+		if now := m.clock.Now(); now.Sub(s.data.LastSeen) >= m.touchEvery {
+			s.data.LastSeen = now
+			s.dirty = true
+		}
+		if s.dirty {
+			m.save(c, s)
+		}
+	}
+}
+
+func (m *Manager) load(c *gin.Context) *Session {
+	value, err := c.Cookie(m.cfg.CookieName)
+	if err != nil || value == "" {
+		return nil
+	}
+	id, err := m.codec.decode(value)
+	if err != nil {
+		m.clearCookie(c)
+		return nil
+	}
+	ctx := c.Request.Context()
+	d, err := m.cfg.Store.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		m.clearCookie(c)
+		return nil
+	}
+	if err != nil {
+		// 存储暂时不可用：这次按未登录处理，但保留 Cookie，恢复后不用重新登录
+		m.logger.Error("session: load failed", slog.Any("error", err))
+		return nil
+	}
+	now := m.clock.Now()
+	if now.Sub(d.LastSeen) > m.cfg.IdleTimeout || now.Sub(d.CreatedAt) > m.cfg.AbsoluteTimeout {
+		if err := m.cfg.Store.Delete(ctx, id); err != nil {
+			m.logger.Error("session: delete expired failed", slog.Any("error", err))
+		}
+		m.clearCookie(c)
+		return nil
+	}
+	return &Session{id: id, data: d}
+}
+
+// ttl 存储里的过期时间：空闲超时和剩余的绝对超时取较短的
+func (m *Manager) ttl(d Data) time.Duration {
+	return min(m.cfg.IdleTimeout, m.cfg.AbsoluteTimeout-d.LastSeen.Sub(d.CreatedAt))
+}
+
+func (m *Manager) save(c *gin.Context, s *Session) {
+	if err := m.cfg.Store.Save(c.Request.Context(), s.id, s.data, m.ttl(s.data)); err != nil {
+		m.logger.Error("session: save failed", slog.Any("error", err))
+		return
+	}
+	s.dirty = false
+}
+
+// Login 为 userID 创建新会话并写 Cookie，values 是随会话保存的附加信息（如角色）。
+// 已有会话时先删除：登录前后的会话 ID 不同，攻击者事先塞给受害者的 ID 登录后作废（会话固定）。
+func (m *Manager) Login(c *gin.Context, userID uint, values map[string]string) (*Session, error) {
+	ctx := c.Request.Context()
+	if old := FromContext(c); old != nil {
+		if err := m.cfg.Store.Delete(ctx, old.id); err != nil {
+			return nil, err
+		}
+	}
+	now := m.clock.Now()
+	s := &Session{id: newToken(), data: Data{
+		UserID:    userID,
+		Values:    values,
+		CSRFToken: newToken(),
+		CreatedAt: now,
+		LastSeen:  now,
+	}}
+	if err := m.cfg.Store.Save(ctx, s.id, s.data, m.ttl(s.data)); err != nil {
+		return nil, err
+	}
+	m.setCookie(c, m.codec.encode(s.id), int(m.cfg.AbsoluteTimeout/time.Second))
+	c.Set(contextKey, s)
+	return s, nil
+}
+
+// Logout 删除当前会话并清掉 Cookie，没有登录时什么也不做
+func (m *Manager) Logout(c *gin.Context) error {
+	s := FromContext(c)
+	if s == nil {
+		return nil
+	}
+	if err := m.cfg.Store.Delete(c.Request.Context(), s.id); err != nil {
+		return err
+	}
+	m.clearCookie(c)
+	c.Set(contextKey, (*Session)(nil))
+	return nil
+}
+
+func (m *Manager) setCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(m.cfg.CookieName, value, maxAge, m.cfg.CookiePath, m.cfg.Domain, m.cfg.Secure, true)
+}
+
+func (m *Manager) clearCookie(c *gin.Context) {
+	m.setCookie(c, "", -1)
+}
+
+// RequireLogin 没有登录时返回 401；登录时把 user_id 和会话里的 role 放进 gin.Context，
+// 和 JWT 中间件设置的键相同，policy、featureflag 等按用户判断的代码不用区分两种登录方式
+func (m *Manager) RequireLogin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s := FromContext(c)
+		if s == nil || s.UserID() == 0 {
+			response.Abort(c, http.StatusUnauthorized, "unauthorized", "请先登录")
+			return
+		}
+		c.Set("user_id", s.UserID())
+		c.Set("role", s.Get("role"))
+		c.Next()
+	}
+}
+
+// VerifyCSRF 校验修改数据的请求（GET / HEAD / OPTIONS 之外）带的 CSRF Token，不一致时返回 403。
+// 要放在 RequireLogin 之后；没有会话的请求不检查，由 RequireLogin 拦截。
+// 只比较原始 Token；服务端渲染表单、要每个表单一个 Token 时用 middleware/csrf 的同步令牌模式。
+func (m *Manager) VerifyCSRF() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		s := FromContext(c)
+		if s == nil {
+			c.Next()
+			return
+		}
+		token := c.GetHeader(CSRFHeader)
+		if token == "" {
+			token = c.PostForm(CSRFField)
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.CSRFToken())) != 1 {
+			response.Abort(c, http.StatusForbidden, "csrf_failed", "CSRF 校验失败，请刷新页面后重试")
+			return
+		}
+		c.Next()
+	}
+}
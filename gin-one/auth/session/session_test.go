@@ -0,0 +1,261 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/cache/redis"
+
+	"go-learning/clock"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+// newTestRouter 登录、读取、修改三个接口，和 5_1 的 /session/* 一样
+func newTestRouter(t *testing.T, store Store) (*gin.Engine, *clock.Fake) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	clk := clock.NewFake(time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC))
+	m, err := New(Config{
+		Store:           store,
+		Secret:          secret,
+		IdleTimeout:     30 * time.Minute,
+		AbsoluteTimeout: 2 * time.Hour,
+		Clock:           clk,
+		Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(m.Middleware())
+	r.POST("/login", func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Query("user"))
+		s, err := m.Login(c, uint(id), map[string]string{"role": "admin"})
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, s.CSRFToken())
+	})
+	r.POST("/logout", func(c *gin.Context) {
+		if err := m.Logout(c); err != nil {
+			c.Status(http.StatusInternalServerError)
+		}
+	})
+	me := r.Group("/me", m.RequireLogin(), m.VerifyCSRF())
+	me.GET("", func(c *gin.Context) {
+		c.String(http.StatusOK, "%v %s %s", c.MustGet("user_id"), c.GetString("role"), FromContext(c).Get("theme"))
+	})
+	me.POST("/theme", func(c *gin.Context) {
+		FromContext(c).Set("theme", c.PostForm("theme"))
+	})
+	return r, clk
+}
+
+type client struct {
+	r      *gin.Engine
+	cookie *http.Cookie
+}
+
+func (cl *client) do(method, path, csrf string, form string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(form))
+	if form != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if csrf != "" {
+		req.Header.Set(CSRFHeader, csrf)
+	}
+	if cl.cookie != nil {
+		req.AddCookie(cl.cookie)
+	}
+	rec := httptest.NewRecorder()
+	cl.r.ServeHTTP(rec, req)
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "sid" {
+			if c.MaxAge < 0 {
+				cl.cookie = nil
+			} else {
+				cl.cookie = c
+			}
+		}
+	}
+	return rec
+}
+
+func TestLoginFlow(t *testing.T) {
+	for name, store := range map[string]Store{
+		"memory": NewMemoryStore(0),
+		"redis":  NewRedisStore(redis.NewMemoryClient(0), ""),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, _ := newTestRouter(t, store)
+			cl := &client{r: r}
+
+			if rec := cl.do(http.MethodGet, "/me", "", ""); rec.Code != http.StatusUnauthorized {
+				t.Fatalf("before login: %d", rec.Code)
+			}
+			rec := cl.do(http.MethodPost, "/login?user=7", "", "")
+			csrf := rec.Body.String()
+			if rec.Code != http.StatusOK || csrf == "" || cl.cookie == nil {
+				t.Fatalf("login: %d %q %v", rec.Code, csrf, cl.cookie)
+			}
+			if c := cl.cookie; !c.HttpOnly || c.SameSite != http.SameSiteLaxMode || c.MaxAge != 7200 {
+				t.Errorf("cookie = %+v; want HttpOnly, SameSite=Lax, Max-Age=7200", c)
+			}
+			if strings.Contains(cl.cookie.Value, ".") {
+				t.Errorf("cookie value %q looks signed, not encrypted", cl.cookie.Value)
+			}
+
+			// 修改数据：没有 Token 或 Token 错误时 403，正确时保存到会话
+			if rec := cl.do(http.MethodPost, "/me/theme", "", "theme=dark"); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "csrf_failed") {
+				t.Errorf("no csrf: %d %s", rec.Code, rec.Body)
+			}
+			if rec := cl.do(http.MethodPost, "/me/theme", "wrong", "theme=dark"); rec.Code != http.StatusForbidden {
+				t.Errorf("bad csrf: %d", rec.Code)
+			}
+			if rec := cl.do(http.MethodPost, "/me/theme", "", "theme=dark&csrf_token="+csrf); rec.Code != http.StatusOK {
+				t.Errorf("form csrf: %d %s", rec.Code, rec.Body)
+			}
+			if rec := cl.do(http.MethodGet, "/me", "", ""); rec.Body.String() != "7 admin dark" {
+				t.Errorf("me = %q; want user 7, role admin, theme dark", rec.Body)
+			}
+
+			stolen := cl.cookie
+			if rec := cl.do(http.MethodPost, "/logout", csrf, ""); rec.Code != http.StatusOK || cl.cookie != nil {
+				t.Fatalf("logout: %d, cookie %v", rec.Code, cl.cookie)
+			}
+			// 登出后旧 Cookie 立即失效（JWT 做不到）
+			cl.cookie = stolen
+			if rec := cl.do(http.MethodGet, "/me", "", ""); rec.Code != http.StatusUnauthorized {
+				t.Errorf("old cookie after logout: %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestFixation(t *testing.T) {
+	store := NewMemoryStore(0)
+	r, _ := newTestRouter(t, store)
+	cl := &client{r: r}
+	cl.do(http.MethodPost, "/login?user=1", "", "")
+	before := cl.cookie
+
+	// 已登录时再次登录换新的会话 ID，旧的作废
+	cl.do(http.MethodPost, "/login?user=2", "", "")
+	if cl.cookie.Value == before.Value {
+		t.Fatal("login reused the cookie")
+	}
+	if rec := cl.do(http.MethodGet, "/me", "", ""); !strings.HasPrefix(rec.Body.String(), "2 ") {
+		t.Errorf("me = %q; want user 2", rec.Body)
+	}
+	cl.cookie = before
+	if rec := cl.do(http.MethodGet, "/me", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("pre-login session still valid: %d", rec.Code)
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	store := NewMemoryStore(0)
+	r, clk := newTestRouter(t, store)
+	cl := &client{r: r}
+	cl.do(http.MethodPost, "/login?user=1", "", "")
+
+	// 每 20 分钟一个请求：空闲超时一直被顺延
+	for i := 0; i < 5; i++ {
+		clk.Advance(20 * time.Minute)
+		if rec := cl.do(http.MethodGet, "/me", "", ""); rec.Code != http.StatusOK {
+			t.Fatalf("request %d after %v: %d", i, 20*time.Minute*time.Duration(i+1), rec.Code)
+		}
+	}
+	// 登录超过 2 小时，一直在用也过期（绝对超时）
+	clk.Advance(21 * time.Minute)
+	if rec := cl.do(http.MethodGet, "/me", "", ""); rec.Code != http.StatusUnauthorized || cl.cookie != nil {
+		t.Errorf("after absolute timeout: %d, cookie %v", rec.Code, cl.cookie)
+	}
+
+	cl.cookie = nil
+	cl.do(http.MethodPost, "/login?user=1", "", "")
+	clk.Advance(31 * time.Minute)
+	if rec := cl.do(http.MethodGet, "/me", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("after idle timeout: %d", rec.Code)
+	}
+}
+
+func TestTamperedCookie(t *testing.T) {
+	r, _ := newTestRouter(t, NewMemoryStore(0))
+	cl := &client{r: r}
+	cl.do(http.MethodPost, "/login?user=1", "", "")
+
+	b := []byte(cl.cookie.Value)
+	b[len(b)/2] ^= 1
+	cl.cookie = &http.Cookie{Name: "sid", Value: string(b)}
+	if rec := cl.do(http.MethodGet, "/me", "", ""); rec.Code != http.StatusUnauthorized || cl.cookie != nil {
+		t.Errorf("tampered cookie: %d, cookie %v", rec.Code, cl.cookie)
+	}
+}
+
+func TestCodec(t *testing.T) {
+	c, err := newCodec(secret, "sid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := c.encode("id-1"), c.encode("id-1")
+	if a == b {
+		t.Error("same ID encrypted to the same value")
+	}
+	if id, err := c.decode(a); err != nil || id != "id-1" {
+		t.Errorf("decode = %q, %v", id, err)
+	}
+	// 换了 Cookie 名就解不开
+	other, _ := newCodec(secret, "other")
+	if _, err := other.decode(a); !errors.Is(err, ErrInvalidCookie) {
+		t.Errorf("other cookie name: err = %v", err)
+	}
+	if _, err := c.decode("short"); !errors.Is(err, ErrInvalidCookie) {
+		t.Errorf("short: err = %v", err)
+	}
+}
+
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range map[string]Store{
+		"memory": NewMemoryStore(0),
+		"redis":  NewRedisStore(redis.NewMemoryClient(0), "s:"),
+	} {
+		d := Data{UserID: 1, Values: map[string]string{"k": "v"}}
+		if err := store.Save(ctx, "a", d, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		d.Values["k"] = "changed"
+		got, err := store.Get(ctx, "a")
+		if err != nil || got.UserID != 1 || got.Values["k"] != "v" {
+			t.Errorf("%s: Get = %+v, %v; want a copy of the saved data", name, got, err)
+		}
+		if err := store.Delete(ctx, "a"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: after Delete err = %v; want ErrNotFound", name, err)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Config{Secret: secret}); err == nil {
+		t.Error("missing Store accepted")
+	}
+	if _, err := New(Config{Store: NewMemoryStore(0), Secret: []byte("short")}); err == nil {
+		t.Error("short secret accepted")
+	}
+}
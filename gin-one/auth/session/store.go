@@ -0,0 +1,111 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"time"
+
+	"go-one/cache"
+	"go-one/cache/redis"
+)
+
+// Data 服务端保存的会话内容
+type Data struct {
+	UserID    uint              `json:"user_id"`
+	Values    map[string]string `json:"values,omitempty"`
+	CSRFToken string            `json:"csrf_token"`
+	CreatedAt time.Time         `json:"created_at"` // 绝对超时从这里算起
+	LastSeen  time.Time         `json:"last_seen"`  // 空闲超时从这里算起
+}
+
+func (d Data) clone() Data {
+	d.Values = maps.Clone(d.Values)
+	return d
+}
+
+// Store 会话存储，ID 是明文会话 ID（Cookie 里是加密后的）
+type Store interface {
+	// Get 读取会话，不存在或已过期时返回 ErrNotFound
+	Get(ctx context.Context, id string) (Data, error)
+	// Save 写入会话，ttl 后自动删除
+	Save(ctx context.Context, id string, d Data, ttl time.Duration) error
+	// Delete 删除会话，不存在不算错误
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryStore 进程内存储，用于开发环境和测试；多实例部署时会话不共享，换成 RedisStore
+type MemoryStore struct {
+	c *cache.Cache[string, Data]
+}
+
+// NewMemoryStore 创建进程内存储，最多保存 maxEntries 个会话（0 表示不限），满了淘汰最久没用的
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{c: cache.New[string, Data](cache.Config{MaxEntries: maxEntries})}
+}
+
+// Get 实现 Store
+func (m *MemoryStore) Get(_ context.Context, id string) (Data, error) {
+	d, ok := m.c.Get(id)
+	if !ok {
+		return Data{}, ErrNotFound
+	}
+	return d.clone(), nil
+}
+
+// Save 实现 Store
+func (m *MemoryStore) Save(_ context.Context, id string, d Data, ttl time.Duration) error {
+	m.c.SetWithTTL(id, d.clone(), ttl)
+	return nil
+}
+
+// Delete 实现 Store
+func (m *MemoryStore) Delete(_ context.Context, id string) error {
+	m.c.Delete(id)
+	return nil
+}
+
+// RedisStore 基于 cache/redis.Client 的存储，会话以 JSON 保存在 prefix+ID 下，过期交给 Redis 的 TTL
+type RedisStore struct {
+	client redis.Client
+	prefix string
+}
+
+// NewRedisStore 创建 Redis 存储，prefix 为空时用 "session:"
+func NewRedisStore(client redis.Client, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "session:"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Get 实现 Store
+func (r *RedisStore) Get(ctx context.Context, id string) (Data, error) {
+	var d Data
+	b, err := r.client.Get(ctx, r.prefix+id)
+	if errors.Is(err, redis.ErrMiss) {
+		return d, ErrNotFound
+	}
+	if err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal(b, &d); err != nil {
+		return d, err
+	}
+	return d, nil
+}
+
+// Save 实现 Store
+func (r *RedisStore) Save(ctx context.Context, id string, d Data, ttl time.Duration) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.prefix+id, b, ttl)
+}
+
+// Delete 实现 Store
+func (r *RedisStore) Delete(ctx context.Context, id string) error {
+	return r.client.Del(ctx, r.prefix+id)
+}
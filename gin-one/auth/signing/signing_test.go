@@ -0,0 +1,251 @@
+package signing
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-learning/clock"
+	"go-learning/httpclient"
+)
+
+var (
+	reportKey = []byte("report-service-key-0123456789abcdef")
+	oldKey    = []byte("report-service-old-0123456789abcdef")
+)
+
+func newServer(t *testing.T, clk clock.Clock) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	v, err := New(Config{
+		Keys:        map[string][]byte{"report": reportKey, "report-old": oldKey},
+		MaxBodySize: 64,
+		Clock:       clk,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	internal := r.Group("/internal", v.Middleware())
+	internal.Any("/echo", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, CallerFromContext(c)+":"+string(body))
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func do(t *testing.T, client *http.Client, req *http.Request) (int, string) {
+	t.Helper()
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestTransport(t *testing.T) {
+	srv := newServer(t, nil)
+	client := &http.Client{Transport: Transport(NewSigner("report", reportKey), nil)}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/internal/echo?async=1", strings.NewReader(`{"n":1}`))
+	if code, body := do(t, client, req); code != http.StatusOK || body != `report:{"n":1}` {
+		t.Errorf("signed POST = %d %s", code, body)
+	}
+	// 调用方的请求没有被改动
+	if req.Header.Get(HeaderSignature) != "" {
+		t.Error("Transport modified the caller's request")
+	}
+	// 没有 GetBody 的请求体（普通 io.Reader、io.Pipe）先读进内存，签名后照常发出去
+	var sent *http.Request
+	capture := &http.Client{Transport: Transport(NewSigner("report", reportKey), tamper{http.DefaultTransport, func(r *http.Request) { sent = r }})}
+	for name, body := range map[string]io.Reader{
+		"reader": struct{ io.Reader }{strings.NewReader("plain reader")},
+		"closer": io.NopCloser(strings.NewReader("stream")),
+	} {
+		req, _ = http.NewRequest(http.MethodPut, srv.URL+"/internal/echo", body)
+		if req.GetBody != nil || req.ContentLength != 0 {
+			t.Fatalf("%s: NewRequest set GetBody/ContentLength; test needs a body without them", name)
+		}
+		want := "report:" + map[string]string{"reader": "plain reader", "closer": "stream"}[name]
+		if code, got := do(t, capture, req); code != http.StatusOK || got != want {
+			t.Errorf("%s: signed body = %d %q; want %q", name, code, got, want)
+		}
+		if sent.GetBody == nil || sent.ContentLength != int64(len(want)-len("report:")) {
+			t.Errorf("%s: sent GetBody %v, ContentLength %d", name, sent.GetBody != nil, sent.ContentLength)
+		}
+		if req.GetBody != nil {
+			t.Errorf("%s: Transport modified the caller's request", name)
+		}
+	}
+
+	// 轮换期间旧密钥 ID 仍然有效
+	old := &http.Client{Transport: Transport(NewSigner("report-old", oldKey), nil)}
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/internal/echo", nil)
+	if code, body := do(t, old, req); code != http.StatusOK || body != "report-old:" {
+		t.Errorf("old key = %d %s", code, body)
+	}
+
+	// 未签名的请求
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/internal/echo", nil)
+	if code, body := do(t, http.DefaultClient, req); code != http.StatusUnauthorized || !strings.Contains(body, `"error":"invalid_signature"`) {
+		t.Errorf("unsigned = %d %s", code, body)
+	}
+}
+
+// tamper 签名之后改动请求，模拟中间人
+type tamper struct {
+	base http.RoundTripper
+	fn   func(r *http.Request)
+}
+
+func (t tamper) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.fn(r)
+	return t.base.RoundTrip(r)
+}
+
+func TestVerify(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	srv := newServer(t, clk)
+	signer := NewSigner("report", reportKey)
+
+	tests := []struct {
+		name   string
+		signer *Signer
+		fn     func(r *http.Request)
+		want   int
+	}{
+		{"valid", signer, func(*http.Request) {}, http.StatusOK},
+		{"body changed", signer, func(r *http.Request) {
+			r.Body, r.GetBody = io.NopCloser(strings.NewReader("amount=9")), nil
+		}, http.StatusUnauthorized},
+		{"query changed", signer, func(r *http.Request) { r.URL.RawQuery = "amount=9999" }, http.StatusUnauthorized},
+		{"method changed", signer, func(r *http.Request) { r.Method = http.MethodDelete }, http.StatusUnauthorized},
+		{"unknown key id", signer, func(r *http.Request) { r.Header.Set(HeaderKeyID, "billing") }, http.StatusUnauthorized},
+		{"wrong secret", NewSigner("report", oldKey), func(*http.Request) {}, http.StatusUnauthorized},
+		{"missing timestamp", signer, func(r *http.Request) { r.Header.Del(HeaderTimestamp) }, http.StatusUnauthorized},
+		{"body too large", signer, func(r *http.Request) {
+			r.Body, r.GetBody = io.NopCloser(strings.NewReader(strings.Repeat("x", 100))), nil
+			r.ContentLength = 100
+		}, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{Transport: Transport(tt.signer, tamper{http.DefaultTransport, tt.fn})}
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+"/internal/echo?amount=1", strings.NewReader("amount=1"))
+			if code, body := do(t, client, req); code != tt.want {
+				t.Errorf("code = %d; want %d (%s)", code, tt.want, body)
+			}
+		})
+	}
+
+	// 时间戳超出 5 分钟窗口：服务端时钟比签名时间快 / 慢
+	for _, d := range []time.Duration{6 * time.Minute, -6 * time.Minute} {
+		clk.Advance(d)
+		client := &http.Client{Transport: Transport(signer, nil)}
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/internal/echo", nil)
+		if code, _ := do(t, client, req); code != http.StatusUnauthorized {
+			t.Errorf("skew %v: code = %d; want 401", d, code)
+		}
+		clk.Advance(-d)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1767225600, 0))
+	v, err := New(Config{Keys: map[string][]byte{"report": reportKey}, Clock: clk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := NewSigner("report", reportKey)
+	signer.clock = clk
+	req := httptest.NewRequest(http.MethodPost, "/internal/notify", strings.NewReader("hi"))
+	if err := signer.Sign(req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get(HeaderTimestamp) != "1767225600" {
+		t.Errorf("timestamp = %s", req.Header.Get(HeaderTimestamp))
+	}
+	if caller, err := v.Verify(req, []byte("hi")); err != nil || caller != "report" {
+		t.Fatalf("Verify = %q, %v", caller, err)
+	}
+	if _, err := v.Verify(req, []byte("hi!")); err != ErrInvalidSignature {
+		t.Errorf("changed body: %v", err)
+	}
+	clk.Advance(5*time.Minute + time.Second)
+	if _, err := v.Verify(req, []byte("hi")); err != ErrExpired {
+		t.Errorf("stale: %v", err)
+	}
+	req.Header.Set(HeaderKeyID, "billing")
+	if _, err := v.Verify(req, []byte("hi")); err != ErrUnknownKey {
+		t.Errorf("unknown key: %v", err)
+	}
+	req.Header.Del(HeaderSignature)
+	if _, err := v.Verify(req, []byte("hi")); err != ErrMissingSignature {
+		t.Errorf("missing: %v", err)
+	}
+
+	for name, cfg := range map[string]Config{
+		"no keys":   {},
+		"short key": {Keys: map[string][]byte{"report": []byte("short")}},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestHTTPClientRetry(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	srv := newServer(t, clk)
+
+	// 第一次尝试 503；重试前服务端时钟走了 10 分钟，重试的签名要用新的时间戳
+	var calls atomic.Int32
+	signer := NewSigner("report", reportKey)
+	signer.clock = clk
+	flaky := tamper{http.DefaultTransport, func(r *http.Request) {
+		if calls.Add(1) == 1 {
+			r.URL.Path = "/unavailable"
+		}
+	}}
+	base := &flakyTransport{base: Transport(signer, flaky), clk: clk}
+	client := httpclient.New(httpclient.Config{Transport: base, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPut, srv.URL+"/internal/echo", strings.NewReader("retry"))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(b) != "report:retry" || calls.Load() != 2 {
+		t.Errorf("retry = %d %s after %d calls", resp.StatusCode, b, calls.Load())
+	}
+}
+
+// flakyTransport 把 404 换成 503 让 httpclient 重试，并在每次失败后拨快时钟
+type flakyTransport struct {
+	base http.RoundTripper
+	clk  *clock.Fake
+}
+
+func (f *flakyTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := f.base.RoundTrip(r)
+	if err == nil && resp.StatusCode == http.StatusNotFound {
+		resp.StatusCode = http.StatusServiceUnavailable
+		f.clk.Advance(10 * time.Minute)
+	}
+	return resp, err
+}
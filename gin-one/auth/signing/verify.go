@@ -0,0 +1,127 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+
+	"go-learning/clock"
+)
+
+// callerKey 验证通过的密钥 ID 在 gin.Context 中的键
+const callerKey = "signing.caller"
+
+// Config 被调用方配置
+type Config struct {
+	// Keys 密钥 ID → 密钥，至少一个，每个至少 32 字节
+	Keys map[string][]byte
+	// Tolerance 时间戳允许的偏差（两个方向），默认 5 分钟
+	Tolerance time.Duration
+	// MaxBodySize 请求体上限，验签要读完整个请求体，默认 1MB
+	MaxBodySize int64
+	// Clock 默认 clock.Real，测试时注入 clock.Fake
+	Clock clock.Clock
+	// Logger 记录被拒绝的请求，默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Verifier 校验签名的中间件
+type Verifier struct {
+	cfg    Config
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// New 创建校验器，没有密钥或密钥太短时返回错误
+func New(cfg Config) (*Verifier, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, errors.New("signing: at least one key is required")
+	}
+	for id, key := range cfg.Keys {
+		if len(key) < 32 {
+			return nil, fmt.Errorf("signing: key %q must be at least 32 bytes", id)
+		}
+	}
+	if cfg.Tolerance <= 0 {
+		cfg.Tolerance = 5 * time.Minute
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 1 << 20
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Verifier{cfg: cfg, clock: clock.OrReal(cfg.Clock), logger: cfg.Logger}, nil
+}
+
+// Verify 校验请求的签名，body 是完整的请求体；返回调用方的密钥 ID
+func (v *Verifier) Verify(req *http.Request, body []byte) (string, error) {
+	keyID, ts, sig := req.Header.Get(HeaderKeyID), req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderSignature)
+	if keyID == "" || ts == "" || sig == "" {
+		return "", ErrMissingSignature
+	}
+	secret, ok := v.cfg.Keys[keyID]
+	if !ok {
+		return "", ErrUnknownKey
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, mac(secret, req.Method, req.URL, ts, body)) {
+		return "", ErrInvalidSignature
+	}
+	// 先验签再看时间：签名不对的请求不告诉对方时间窗口
+	if d := v.clock.Now().Sub(time.Unix(unix, 0)); d > v.cfg.Tolerance || d < -v.cfg.Tolerance {
+		return "", ErrExpired
+	}
+	return keyID, nil
+}
+
+// Middleware 读取请求体并验签，失败时 401；请求体读完后放回去，handler 照常绑定
+func (v *Verifier) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, v.cfg.MaxBodySize))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					response.Abort(c, http.StatusRequestEntityTooLarge, "body_too_large", "request body too large")
+					return
+				}
+				response.Abort(c, http.StatusBadRequest, "malformed_request", "failed to read request body")
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		caller, err := v.Verify(c.Request, body)
+		if err != nil {
+			v.logger.Warn("signing: rejected request",
+				"key_id", c.GetHeader(HeaderKeyID), "method", c.Request.Method, "path", c.Request.URL.Path, "error", err)
+			response.Abort(c, http.StatusUnauthorized, "invalid_signature", "invalid request signature")
+			return
+		}
+		c.Set(callerKey, caller)
+		c.Next()
+	}
+}
+
+// CallerFromContext 验签通过的调用方密钥 ID，没有经过中间件时返回空字符串
+func CallerFromContext(c *gin.Context) string {
+	return c.GetString(callerKey)
+}
@@ -0,0 +1,251 @@
+// ============================================================================
+// Package batcher 攒批写入：记录先进内存队列，凑够 Size 条或每隔 Interval 一次性写出
+// ============================================================================
+//
+// 【为什么】
+//
+// 请求日志、审计日志这类"只写不读、晚一秒落库没关系"的数据，每个请求一条 INSERT 时，
+// 数据库的往返和事务提交开销远大于写入本身；攒成一批用 CreateInBatches 写，
+// 一条多行 INSERT 代替几百条单行 INSERT，请求路径上只剩一次入队。
+//
+// 【流程】
+//
+//	Add ──▶ 队列（Capacity）──▶ 后台 goroutine 攒批 ──▶ flush(batch)
+//	          │ 满了                    ├── 凑够 Size 条
+//	          ▼                         ├── 距上次写出超过 Interval
+//	       ErrFull（Dropped +1）        └── Close：写出剩下的全部
+//
+// 【取舍】
+//
+// | 情况                  | 结果                                                    |
+// |-----------------------|---------------------------------------------------------|
+// | 写入速度跟不上        | 队列满后 Add 立即返回 ErrFull，不阻塞请求；Dropped 计数 |
+// | flush 返回错误        | 这一批丢弃并记日志，Failed 计数；不重试，避免越积越多   |
+// | 进程正常退出          | Close 写出队列里剩下的记录（server.OnShutdown 里调用）  |
+// | 进程崩溃 / 被 kill -9 | 队列里还没写出的记录丢失                                |
+//
+// 一条都不能丢的数据（资金流水、audit 包的数据变更记录）不要用批量写入，和业务在同一个事务里同步写。
+//
+// 【用法】
+//
+//	logs := batcher.NewGORM[*RequestLog](db, batcher.Config{Size: 200, Interval: time.Second})
+//	srv.OnShutdown("request logs", logs.Close)
+//	if err := logs.Add(&RequestLog{...}); err != nil { ... } // 只有队列满或已关闭时出错
+//
+// ============================================================================
+package batcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 错误定义
+var (
+	ErrFull   = errors.New("batcher: queue is full")
+	ErrClosed = errors.New("batcher: closed")
+)
+
+// Config 批量写入配置
+type Config struct {
+	// Size 凑够多少条写一次，也是 CreateInBatches 每条 INSERT 的行数，默认 100
+	Size int
+
+	// Interval 最长多久写一次，队列里不够 Size 条也写出，默认 1 秒
+	Interval time.Duration
+
+	// Capacity 队列容量，超出后 Add 返回 ErrFull，默认 Size 的 10 倍
+	Capacity int
+
+	// Logger 记录写入失败，默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Stats 统计，数值从创建起累计
+type Stats struct {
+	Added   uint64 `json:"added"`   // 成功入队的记录数
+	Flushed uint64 `json:"flushed"` // 写入成功的记录数
+	Failed  uint64 `json:"failed"`  // flush 出错丢弃的记录数
+	Dropped uint64 `json:"dropped"` // 队列满或已关闭被拒绝的记录数
+	Batches uint64 `json:"batches"` // flush 调用次数
+	Pending int    `json:"pending"` // 当前队列里等待写出的记录数
+}
+
+// Batcher 并发安全的批量写入器
+type Batcher[T any] struct {
+	cfg   Config
+	flush func(ctx context.Context, batch []T) error
+	queue chan T
+
+	// mu 保证 Close 之后不会再有记录入队：Add 持读锁入队，Close 持写锁设置 closed
+	mu     sync.RWMutex
+	closed bool
+
+	ctx    context.Context // flush 使用，Close 超时后取消正在进行的写入
+	cancel context.CancelFunc
+	quit   chan struct{}
+	done   chan struct{}
+
+	added, flushed, failed, dropped, batches atomic.Uint64
+}
+
+func (c Config) withDefaults() Config {
+	if c.Size <= 0 {
+		c.Size = 100
+	}
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+	if c.Capacity <= 0 {
+		c.Capacity = c.Size * 10
+	}
+	if c.Logger == nil {
+		c.Logger = slog.Default()
+	}
+	return c
+}
+
+// New 创建批量写入器并启动后台 goroutine，flush 只在这一个 goroutine 里调用
+func New[T any](flush func(ctx context.Context, batch []T) error, cfg Config) *Batcher[T] {
+	cfg = cfg.withDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Batcher[T]{
+		cfg:    cfg,
+		flush:  flush,
+		queue:  make(chan T, cfg.Capacity),
+		ctx:    ctx,
+		cancel: cancel,
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// NewGORM 用 CreateInBatches 写入 T 对应的表
+func NewGORM[T any](db *gorm.DB, cfg Config) *Batcher[T] {
+	cfg = cfg.withDefaults()
+	return New(func(ctx context.Context, batch []T) error {
+		return db.WithContext(ctx).CreateInBatches(batch, cfg.Size).Error
+	}, cfg)
+}
+
+// Add 入队，不阻塞；队列满返回 ErrFull，Close 之后返回 ErrClosed
+func (b *Batcher[T]) Add(item T) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		b.dropped.Add(1)
+		return ErrClosed
+	}
+	select {
+	case b.queue <- item:
+		b.added.Add(1)
+		return nil
+	default:
+		b.dropped.Add(1)
+		return ErrFull
+	}
+}
+
+// Close 停止接收新记录，写出队列里剩下的记录后返回
+//
+// ctx 到期时取消正在进行的写入并返回 ctx.Err()，剩下的记录丢弃。可以重复调用。
+func (b *Batcher[T]) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.quit)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+// Stats 统计快照
+func (b *Batcher[T]) Stats() Stats {
+	return Stats{
+		Added:   b.added.Load(),
+		Flushed: b.flushed.Load(),
+		Failed:  b.failed.Load(),
+		Dropped: b.dropped.Load(),
+		Batches: b.batches.Load(),
+		Pending: len(b.queue),
+	}
+}
+
+func (b *Batcher[T]) run() {
+	defer close(b.done)
+	defer b.cancel()
+	ticker := time.NewTicker(b.cfg.Interval)
+	defer ticker.Stop()
+
+	batch := make([]T, 0, b.cfg.Size)
+	write := func() {
+		if len(batch) == 0 {
+			return
+		}
+		b.write(batch)
+		// 不复用底层数组：flush 可能还持有 batch（比如 GORM 回填 ID 后交给别处）
+		batch = make([]T, 0, b.cfg.Size)
+	}
+
+	for {
+		select {
+		case item := <-b.queue:
+			batch = append(batch, item)
+			if len(batch) >= b.cfg.Size {
+				write()
+				ticker.Reset(b.cfg.Interval)
+			}
+		case <-ticker.C:
+			write()
+		case <-b.quit:
+			// Close 之后不会再有入队，取空队列即可
+			for {
+				select {
+				case item := <-b.queue:
+					batch = append(batch, item)
+					if len(batch) >= b.cfg.Size {
+						write()
+					}
+				default:
+					write()
+					return
+				}
+			}
+		}
+	}
+}
+
+// write 调用 flush，panic 按失败处理，后台 goroutine 不能因为一批数据退出
+func (b *Batcher[T]) write(batch []T) {
+	b.batches.Add(1)
+	err := func() (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = fmt.Errorf("panic: %v", v)
+			}
+		}()
+		return b.flush(b.ctx, batch)
+	}()
+	if err != nil {
+		b.failed.Add(uint64(len(batch)))
+		b.cfg.Logger.Error("batcher: flush failed", slog.Int("records", len(batch)), slog.Any("error", err))
+		return
+	}
+	b.flushed.Add(uint64(len(batch)))
+}
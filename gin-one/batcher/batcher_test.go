@@ -0,0 +1,178 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"go-one/internal/testdb"
+)
+
+var quiet = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// recorder 记录每一批的大小
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]int
+	flushed chan struct{}
+}
+
+func newRecorder() *recorder {
+	return &recorder{flushed: make(chan struct{}, 100)}
+}
+
+func (r *recorder) flush(_ context.Context, batch []int) error {
+	r.mu.Lock()
+	r.batches = append(r.batches, batch)
+	r.mu.Unlock()
+	r.flushed <- struct{}{}
+	return nil
+}
+
+func (r *recorder) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sizes []int
+	for _, b := range r.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func (r *recorder) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-r.flushed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("flush not called")
+	}
+}
+
+func TestFlushOnSize(t *testing.T) {
+	rec := newRecorder()
+	b := New(rec.flush, Config{Size: 3, Interval: time.Hour, Logger: quiet})
+	for i := range 7 {
+		if err := b.Add(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec.wait(t)
+	rec.wait(t)
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 两批凑满，剩下的 1 条在 Close 时写出
+	if got := rec.sizes(); len(got) != 3 || got[0] != 3 || got[1] != 3 || got[2] != 1 {
+		t.Errorf("batches = %v", got)
+	}
+	if s := b.Stats(); s.Added != 7 || s.Flushed != 7 || s.Batches != 3 || s.Pending != 0 {
+		t.Errorf("stats = %+v", s)
+	}
+	if err := b.Add(8); !errors.Is(err, ErrClosed) {
+		t.Errorf("add after close err = %v", err)
+	}
+	if err := b.Close(context.Background()); err != nil {
+		t.Errorf("second close err = %v", err)
+	}
+}
+
+func TestFlushOnInterval(t *testing.T) {
+	rec := newRecorder()
+	b := New(rec.flush, Config{Size: 100, Interval: 20 * time.Millisecond, Logger: quiet})
+	defer b.Close(context.Background())
+	b.Add(1)
+	b.Add(2)
+	rec.wait(t)
+	if got := rec.sizes(); len(got) != 1 || got[0] != 2 {
+		t.Errorf("batches = %v", got)
+	}
+}
+
+func TestDropWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	b := New(func(ctx context.Context, batch []int) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release // 模拟数据库卡住
+		return nil
+	}, Config{Size: 1, Capacity: 2, Interval: time.Hour, Logger: quiet})
+
+	b.Add(0) // 被后台取走，卡在 flush 里
+	<-started
+	b.Add(1)
+	b.Add(2)
+	if err := b.Add(3); !errors.Is(err, ErrFull) {
+		t.Errorf("add to full queue err = %v", err)
+	}
+	if s := b.Stats(); s.Dropped != 1 || s.Pending != 2 {
+		t.Errorf("stats = %+v", s)
+	}
+	close(release)
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.Stats(); s.Flushed != 3 {
+		t.Errorf("flushed = %d", s.Flushed)
+	}
+}
+
+func TestFlushErrors(t *testing.T) {
+	calls := 0
+	b := New(func(ctx context.Context, batch []int) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database down")
+		}
+		panic("boom")
+	}, Config{Size: 2, Interval: time.Hour, Logger: quiet})
+	for i := range 4 {
+		b.Add(i)
+	}
+	b.Close(context.Background())
+	if s := b.Stats(); s.Failed != 4 || s.Flushed != 0 || s.Batches != 2 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestCloseTimeout(t *testing.T) {
+	b := New(func(ctx context.Context, batch []int) error {
+		<-ctx.Done() // 写入一直不返回，直到被取消
+		return ctx.Err()
+	}, Config{Size: 1, Interval: time.Hour, Logger: quiet})
+	b.Add(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("close err = %v", err)
+	}
+}
+
+type requestLog struct {
+	ID   uint
+	Path string
+}
+
+func TestGORM(t *testing.T) {
+	db := testdb.Open(t, &requestLog{})
+
+	b := NewGORM[*requestLog](db, Config{Size: 10, Interval: time.Hour, Logger: quiet})
+	for range 25 {
+		b.Add(&requestLog{Path: "/users"})
+	}
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	db.Model(&requestLog{}).Count(&n)
+	if s := b.Stats(); n != 25 || s.Batches != 3 {
+		t.Errorf("rows = %d, stats = %+v", n, s)
+	}
+}
@@ -0,0 +1,272 @@
+// ============================================================================
+// Package cache 泛型进程内缓存：TTL、LRU 淘汰、命中统计、加载去重
+// ============================================================================
+//
+// 【为什么不用 map + RWMutex？】
+//
+// go-with-ai-one/12_concurrency.go 里的 Cache 只是加锁的 map：
+//
+// | 问题       | 后果                                 | 本包的做法                            |
+// |------------|--------------------------------------|---------------------------------------|
+// | 不过期     | 数据永远是旧的                       | 每个条目有过期时间，读到过期的当作未命中 |
+// | 不限大小   | key 无限增长直到 OOM                 | 超过 MaxEntries 淘汰最久未使用的（LRU） |
+// | 缓存击穿   | 热 key 过期瞬间 N 个请求同时查数据库 | GetOrLoad 同一个 key 只有一个加载者     |
+// | 看不到效果 | 不知道命中率                         | Stats 统计命中、未命中、淘汰、加载次数  |
+//
+// 【锁：为什么是分片的 Mutex 而不是 RWMutex？】
+//
+// LRU 的 Get 要把条目移到链表头部，读也是写，RWMutex 的读锁帮不上忙。
+// 所有 goroutine 抢一把锁时吞吐上不去，所以按 key 的哈希分成 Shards 个分片，
+// 每个分片一把锁、一个独立的 LRU；不同分片的 key 互不阻塞。
+// 代价是淘汰是分片内的近似 LRU：每个分片最多 MaxEntries/Shards 个条目。
+// 两种锁的对比见 cache_test.go 中的基准测试：
+//
+//	go test ./cache -bench . -benchmem
+//
+// 【用法】
+//
+//	users := cache.New[uint, *model.User](cache.Config{MaxEntries: 10000, TTL: time.Minute})
+//	u, err := users.GetOrLoad(ctx, id, func(ctx context.Context, id uint) (*model.User, error) {
+//	    return repo.GetByID(ctx, id) // 同一个 id 并发未命中时只查一次数据库
+//	})
+//	users.Delete(id) // 更新或删除用户后失效
+//
+// 加载失败不缓存，下一次调用会重新加载。
+//
+// ============================================================================
+package cache
+
+import (
+	"container/list"
+	"context"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config 缓存配置
+type Config struct {
+	// MaxEntries 最多缓存的条目数，0 表示不限制
+	MaxEntries int
+
+	// TTL 默认过期时间，0 表示不过期；SetWithTTL 可以单独指定
+	TTL time.Duration
+
+	// Shards 分片数，默认 16；1 表示整个缓存一把锁
+	Shards int
+}
+
+// Stats 缓存统计，数值从创建起累计
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"` // 因容量淘汰的条目数，不含过期
+	Loads     uint64 `json:"loads"`     // GetOrLoad 实际调用 loader 的次数
+	Shared    uint64 `json:"shared"`    // 等待别的 goroutine 加载结果、没有自己调用 loader 的次数
+}
+
+// HitRatio 命中率，没有访问时为 0
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time // 零值表示不过期
+}
+
+type shard[K comparable, V any] struct {
+	mu    sync.Mutex
+	ll    *list.List // 最近使用的在前
+	items map[K]*list.Element
+	max   int
+}
+
+// Cache 并发安全的泛型缓存
+type Cache[K comparable, V any] struct {
+	shards []*shard[K, V]
+	seed   maphash.Seed
+	ttl    time.Duration
+	now    func() time.Time
+
+	flightMu sync.Mutex
+	flights  map[K]*call[V]
+
+	hits, misses, evictions, loads, shared atomic.Uint64
+}
+
+// New 创建缓存
+func New[K comparable, V any](cfg Config) *Cache[K, V] {
+	if cfg.Shards <= 0 {
+		cfg.Shards = 16
+	}
+	if cfg.MaxEntries > 0 && cfg.Shards > cfg.MaxEntries {
+		cfg.Shards = cfg.MaxEntries
+	}
+	c := &Cache[K, V]{
+		shards:  make([]*shard[K, V], cfg.Shards),
+		seed:    maphash.MakeSeed(),
+		ttl:     cfg.TTL,
+		now:     time.Now,
+		flights: make(map[K]*call[V]),
+	}
+	per := 0
+	if cfg.MaxEntries > 0 {
+		per = (cfg.MaxEntries + cfg.Shards - 1) / cfg.Shards
+	}
+	for i := range c.shards {
+		c.shards[i] = &shard[K, V]{ll: list.New(), items: make(map[K]*list.Element), max: per}
+	}
+	return c
+}
+
+func (c *Cache[K, V]) shard(key K) *shard[K, V] {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+	return c.shards[maphash.Comparable(c.seed, key)%uint64(len(c.shards))]
+}
+
+// Get 读取缓存，过期的条目当作未命中并删除
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	s := c.shard(key)
+	s.mu.Lock()
+	el, ok := s.items[key]
+	if ok {
+		e := el.Value.(*entry[K, V])
+		if e.expires.IsZero() || c.now().Before(e.expires) {
+			s.ll.MoveToFront(el)
+			s.mu.Unlock()
+			c.hits.Add(1)
+			return e.value, true
+		}
+		s.ll.Remove(el)
+		delete(s.items, key)
+	}
+	s.mu.Unlock()
+	c.misses.Add(1)
+	var zero V
+	return zero, false
+}
+
+// Set 写入缓存，使用默认 TTL
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL 写入缓存并指定过期时间，ttl 为 0 表示不过期
+// 同一个 key 正在进行的 GetOrLoad 加载结果不会再覆盖这次写入
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.invalidate(&key)
+	c.set(key, value, ttl)
+}
+
+func (c *Cache[K, V]) set(key K, value V, ttl time.Duration) {
+	e := &entry[K, V]{key: key, value: value}
+	if ttl > 0 {
+		e.expires = c.now().Add(ttl)
+	}
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		el.Value = e
+		s.ll.MoveToFront(el)
+		return
+	}
+	s.items[key] = s.ll.PushFront(e)
+	if s.max > 0 && s.ll.Len() > s.max {
+		oldest := s.ll.Back()
+		s.ll.Remove(oldest)
+		delete(s.items, oldest.Value.(*entry[K, V]).key)
+		c.evictions.Add(1)
+	}
+}
+
+// Delete 删除条目，数据变更后调用使缓存失效
+// 同一个 key 正在进行的 GetOrLoad 加载的是变更前的数据，结果不会写入缓存
+func (c *Cache[K, V]) Delete(key K) {
+	c.invalidate(&key)
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		s.ll.Remove(el)
+		delete(s.items, key)
+	}
+}
+
+// Clear 清空所有条目，统计不清零；正在进行的加载结果都不会写入缓存
+func (c *Cache[K, V]) Clear() {
+	c.invalidate(nil)
+	for _, s := range c.shards {
+		s.mu.Lock()
+		s.ll.Init()
+		clear(s.items)
+		s.mu.Unlock()
+	}
+}
+
+// Len 当前条目数，包括已过期但还没被清理的
+func (c *Cache[K, V]) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += s.ll.Len()
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// DeleteExpired 清理所有过期条目，返回清理的数量
+// 过期条目在 Get 时也会被删除，这里清理的是过期后再没人访问、一直占着内存的
+func (c *Cache[K, V]) DeleteExpired() int {
+	now := c.now()
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for el := s.ll.Back(); el != nil; {
+			prev := el.Prev()
+			e := el.Value.(*entry[K, V])
+			if !e.expires.IsZero() && !now.Before(e.expires) {
+				s.ll.Remove(el)
+				delete(s.items, e.key)
+				n++
+			}
+			el = prev
+		}
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Run 每隔 interval 清理一次过期条目，阻塞直到 ctx 取消
+func (c *Cache[K, V]) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.DeleteExpired()
+		}
+	}
+}
+
+// Stats 统计快照
+func (c *Cache[K, V]) Stats() Stats {
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Loads:     c.loads.Load(),
+		Shared:    c.shared.Load(),
+	}
+}
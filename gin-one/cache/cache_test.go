@@ -0,0 +1,362 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetSetTTL(t *testing.T) {
+	c := New[string, int](Config{TTL: time.Minute})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, 10*time.Second)
+	c.SetWithTTL("forever", 3, 0)
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		key     string
+		want    int
+		wantOK  bool
+	}{
+		{"fresh", 0, "a", 1, true},
+		{"missing", 0, "x", 0, false},
+		{"custom ttl expired", 10 * time.Second, "b", 0, false},
+		{"default ttl alive", 0, "a", 1, true},
+		{"default ttl expired", 50 * time.Second, "a", 0, false},
+		{"no ttl", 24 * time.Hour, "forever", 3, true},
+	}
+	for _, tt := range tests {
+		now = now.Add(tt.advance)
+		got, ok := c.Get(tt.key)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: Get(%q) = %d, %v; want %d, %v", tt.name, tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	if s := c.Stats(); s.Hits != 3 || s.Misses != 3 {
+		t.Errorf("stats = %+v; want 3 hits, 3 misses", s)
+	}
+	// 过期的条目在 Get 时已经删除
+	if n := c.Len(); n != 1 {
+		t.Errorf("Len = %d; want 1", n)
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	c := New[string, int](Config{MaxEntries: 2, Shards: 1})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a") // a 变成最近使用
+	c.Set("c", 3)
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("Get(%q) ok = %v; want %v", key, ok, want)
+		}
+	}
+	if s := c.Stats(); s.Evictions != 1 {
+		t.Errorf("evictions = %d; want 1", s.Evictions)
+	}
+
+	// 覆盖已有 key 不算新增，不触发淘汰
+	c.Set("c", 30)
+	if v, _ := c.Get("c"); v != 30 || c.Len() != 2 {
+		t.Errorf("overwrite: c = %d, len = %d", v, c.Len())
+	}
+}
+
+func TestShardedCapacity(t *testing.T) {
+	c := New[int, int](Config{MaxEntries: 64, Shards: 8})
+	for i := range 1000 {
+		c.Set(i, i)
+	}
+	if n := c.Len(); n > 64 {
+		t.Fatalf("Len = %d; want <= 64", n)
+	}
+}
+
+func TestDeleteExpired(t *testing.T) {
+	c := New[int, string](Config{TTL: time.Second})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	for i := range 10 {
+		c.Set(i, "v")
+	}
+	c.SetWithTTL(100, "keep", time.Hour)
+	now = now.Add(time.Second)
+
+	if n := c.DeleteExpired(); n != 10 {
+		t.Fatalf("DeleteExpired = %d; want 10", n)
+	}
+	if _, ok := c.Get(100); !ok || c.Len() != 1 {
+		t.Fatalf("unexpired entry lost, len = %d", c.Len())
+	}
+
+	c.Delete(100)
+	c.Set(1, "x")
+	c.Clear()
+	if c.Len() != 0 {
+		t.Fatalf("Len after Clear = %d", c.Len())
+	}
+}
+
+func TestGetOrLoadDedup(t *testing.T) {
+	c := New[string, int](Config{})
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context, key string) (int, error) {
+		calls.Add(1)
+		<-release
+		return len(key), nil
+	}
+
+	const n = 50
+	var wg sync.WaitGroup
+	results := make([]int, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = c.GetOrLoad(context.Background(), "hello", load)
+		}()
+	}
+	// 等所有 goroutine 都进入等待，再放行加载
+	for c.Stats().Shared+c.Stats().Loads < n {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("loader calls = %d; want 1", calls.Load())
+	}
+	for i, v := range results {
+		if v != 5 {
+			t.Fatalf("results[%d] = %d; want 5", i, v)
+		}
+	}
+	// 加载结果已写入缓存
+	if _, err := c.GetOrLoad(context.Background(), "hello", load); err != nil || calls.Load() != 1 {
+		t.Fatalf("cached GetOrLoad called loader again")
+	}
+}
+
+func TestGetOrLoadErrors(t *testing.T) {
+	c := New[int, string](Config{})
+	ctx := context.Background()
+	errDB := errors.New("db down")
+
+	// 按顺序执行：失败和 panic 都不写缓存，第三次重新加载成功
+	tests := []struct {
+		name    string
+		load    Loader[int, string]
+		want    string
+		wantErr bool
+	}{
+		{"error not cached", func(context.Context, int) (string, error) { return "", errDB }, "", true},
+		{"panic becomes error", func(context.Context, int) (string, error) { panic("boom") }, "", true},
+		{"retry succeeds", func(_ context.Context, k int) (string, error) { return strconv.Itoa(k), nil }, "7", false},
+	}
+	for _, tt := range tests {
+		v, err := c.GetOrLoad(ctx, 7, tt.load)
+		if (err != nil) != tt.wantErr || v != tt.want {
+			t.Errorf("%s: GetOrLoad = %q, %v; want %q, err %v", tt.name, v, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGetOrLoadWaiterCancel(t *testing.T) {
+	c := New[string, int](Config{})
+	release := make(chan struct{})
+	defer close(release)
+	go c.GetOrLoad(context.Background(), "k", func(context.Context, string) (int, error) {
+		<-release
+		return 1, nil
+	})
+	for c.Stats().Loads == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetOrLoad(ctx, "k", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("waiter err = %v; want deadline exceeded", err)
+	}
+}
+
+func TestGetOrLoadInvalidate(t *testing.T) {
+	c := New[string, string](Config{})
+	ctx := context.Background()
+	release := make(chan struct{})
+	done := make(chan string)
+	go func() {
+		v, _ := c.GetOrLoad(ctx, "k", func(context.Context, string) (string, error) {
+			<-release
+			return "old", nil // 加载期间数据被改了
+		})
+		done <- v
+	}()
+	for c.Stats().Loads == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	c.Delete("k")
+	close(release)
+	if v := <-done; v != "old" {
+		t.Fatalf("caller got %q; want old", v)
+	}
+	if v, ok := c.Get("k"); ok {
+		t.Fatalf("stale %q cached after Delete during load", v)
+	}
+
+	// 加载期间 Set 的新值不会被加载结果覆盖
+	release = make(chan struct{})
+	go c.GetOrLoad(ctx, "k", func(context.Context, string) (string, error) {
+		<-release
+		return "old", nil
+	})
+	for c.Stats().Loads < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	c.Set("k", "new")
+	close(release)
+	for {
+		c.flightMu.Lock()
+		n := len(c.flights)
+		c.flightMu.Unlock()
+		if n == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if v, _ := c.Get("k"); v != "new" {
+		t.Fatalf("Get = %q; want new", v)
+	}
+}
+
+func TestGetOrLoadLeaderCancel(t *testing.T) {
+	c := New[string, int](Config{})
+	release := make(chan struct{})
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := c.GetOrLoad(leaderCtx, "k", func(ctx context.Context, _ string) (int, error) {
+			<-release
+			return 1, ctx.Err()
+		})
+		leaderErr <- err
+	}()
+	for c.Stats().Loads == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	waiter := make(chan int)
+	go func() {
+		v, _ := c.GetOrLoad(context.Background(), "k", nil)
+		waiter <- v
+	}()
+	for c.Stats().Shared == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 发起加载的客户端断开：它自己立即返回，加载继续，等待者拿到结果
+	cancel()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("leader err = %v; want canceled", err)
+	}
+	close(release)
+	if v := <-waiter; v != 1 {
+		t.Fatalf("waiter got %d; want 1", v)
+	}
+	if v, ok := c.Get("k"); !ok || v != 1 {
+		t.Fatalf("Get = %d, %v; want cached 1", v, ok)
+	}
+}
+
+func TestHitRatio(t *testing.T) {
+	tests := []struct {
+		s    Stats
+		want float64
+	}{
+		{Stats{}, 0},
+		{Stats{Hits: 3, Misses: 1}, 0.75},
+	}
+	for _, tt := range tests {
+		if got := tt.s.HitRatio(); got != tt.want {
+			t.Errorf("HitRatio(%+v) = %v; want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+// ============================================================================
+// 基准测试：RWMutex map vs 单锁 LRU vs 分片 LRU
+// ============================================================================
+//
+// go test ./cache -bench . -benchmem -cpu 1,8
+//
+// rwMap 没有淘汰和过期，只是对照基线（12_concurrency.go 的写法）；
+// 负载为 90% 读 10% 写。单核时分片只多了一次哈希，核数越多，
+// 单锁 LRU 的争用越明显，分片版本的优势越大。
+
+type rwMap struct {
+	mu   sync.RWMutex
+	data map[int]int
+}
+
+func (m *rwMap) Get(k int) (int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.data[k]
+	return v, ok
+}
+
+func (m *rwMap) Set(k, v int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[k] = v
+}
+
+type store interface {
+	Get(int) (int, bool)
+	Set(int, int)
+}
+
+func benchmarkMixed(b *testing.B, s store) {
+	const keys = 1 << 14
+	for i := range keys {
+		s.Set(i, i)
+	}
+	var seq atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(seq.Add(1)) * 1000 // 每个 goroutine 从不同位置开始，避免步调一致地抢同一个分片
+		for pb.Next() {
+			k := (i * 7919) % keys
+			if i%10 == 0 {
+				s.Set(k, i)
+			} else {
+				s.Get(k)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkRWMutexMap(b *testing.B) {
+	benchmarkMixed(b, &rwMap{data: make(map[int]int)})
+}
+
+func BenchmarkCacheSingleLock(b *testing.B) {
+	benchmarkMixed(b, New[int, int](Config{MaxEntries: 1 << 15, Shards: 1}))
+}
+
+func BenchmarkCacheSharded(b *testing.B) {
+	benchmarkMixed(b, New[int, int](Config{MaxEntries: 1 << 15, Shards: 32}))
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+)
+
+// ============================================================================
+// 加载去重（singleflight）
+// ============================================================================
+//
+// 热 key 过期的瞬间，100 个请求同时未命中：
+//
+//	没有去重：100 次数据库查询，数据库被打满（缓存击穿）
+//	有去重：  1 个 goroutine 查询，另外 99 个等它的结果
+//
+// golang.org/x/sync/singleflight 的 key 只能是 string，这里按缓存的 K 直接去重。
+//
+// 【加载期间数据变了】
+//
+// 加载者读到的是数据库的旧值，这时另一个请求更新了数据并 Delete 了 key。
+// 如果加载完照常写缓存，旧值会一直留到 TTL 过期。所以 Delete / Clear / Set
+// 会把这个 key 正在进行的加载标记为作废，作废的结果照常返回给调用者，但不写缓存。
+//
+// 【谁的 ctx？】
+//
+// 加载是所有调用者共享的，不能因为第一个调用者的客户端断开就让其他人都拿到 context.Canceled。
+// load 在单独的 goroutine 里用 context.WithoutCancel(ctx) 执行（保留 ctx 里的值），
+// 每个调用者（包括发起加载的）只在自己的 ctx 取消时提前返回。
+//
+
+// Loader 缓存未命中时加载数据
+type Loader[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+type call[V any] struct {
+	done        chan struct{}
+	value       V
+	err         error
+	invalidated bool // 加载期间 key 被删除或覆盖，结果不写缓存（flightMu 保护）
+}
+
+// GetOrLoad 命中直接返回；未命中时调用 load 并写入缓存
+//
+// 同一个 key 并发未命中时只有第一个调用者发起 load，所有调用者等待同一个结果；
+// 任何调用者的 ctx 取消时它立即返回 ctx.Err()，不影响正在进行的加载。
+// load 返回错误时不写缓存，所有等待者得到同一个错误。
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load Loader[K, V]) (V, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+
+	c.flightMu.Lock()
+	f, ok := c.flights[key]
+	if ok {
+		c.shared.Add(1)
+	} else {
+		f = &call[V]{done: make(chan struct{})}
+		c.flights[key] = f
+		c.loads.Add(1)
+		go c.load(context.WithoutCancel(ctx), key, f, load)
+	}
+	c.flightMu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// load 执行加载，没有作废时写入缓存，最后唤醒所有等待者
+func (c *Cache[K, V]) load(ctx context.Context, key K, f *call[V], load Loader[K, V]) {
+	func() {
+		// load panic 时也要唤醒等待者，否则它们会永远阻塞
+		defer func() {
+			if r := recover(); r != nil {
+				f.err = fmt.Errorf("cache: loader panic: %v", r)
+			}
+		}()
+		f.value, f.err = load(ctx, key)
+	}()
+
+	// 检查作废和写缓存都在 flightMu 里，Delete 先标记作废再删条目，不会漏掉
+	c.flightMu.Lock()
+	if f.err == nil && !f.invalidated {
+		c.set(key, f.value, c.ttl)
+	}
+	delete(c.flights, key)
+	c.flightMu.Unlock()
+	close(f.done)
+}
+
+// invalidate 把 key 正在进行的加载标记为作废；key 为 nil 时作废全部
+func (c *Cache[K, V]) invalidate(key *K) {
+	c.flightMu.Lock()
+	defer c.flightMu.Unlock()
+	if key == nil {
+		for _, f := range c.flights {
+			f.invalidated = true
+		}
+		return
+	}
+	if f, ok := c.flights[*key]; ok {
+		f.invalidated = true
+	}
+}
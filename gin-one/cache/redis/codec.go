@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ============================================================================
+// 序列化
+// ============================================================================
+//
+// | Codec   | 体积 | 可读性            | 说明                                   |
+// |---------|------|-------------------|----------------------------------------|
+// | JSON    | 大   | redis-cli 直接看  | 默认，和 API 响应一致                  |
+// | Msgpack | 小   | 二进制            | 字段多、数字多的实体能省 20%~40% 内存  |
+//
+// 两种都按 json 标签取字段名：json:"-" 的字段（如 User.Password）不会写进 Redis，
+// 所以缓存里的实体只能用于展示，校验密码等操作必须查数据库。
+// 切换 Codec 时要同时换 Prefix，否则新代码会读到旧格式的数据。
+//
+
+// Codec 序列化方式
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// 内置的序列化方式
+var (
+	JSON    Codec = jsonCodec{}
+	Msgpack Codec = msgpackCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.SetOmitEmpty(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
@@ -0,0 +1,42 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"go-one/cache"
+)
+
+// MemoryClient 进程内实现的 Client，用于开发环境和测试，多实例之间不共享
+type MemoryClient struct {
+	c *cache.Cache[string, []byte]
+}
+
+// NewMemoryClient 创建进程内客户端，最多保存 maxEntries 个 key（0 表示不限）
+func NewMemoryClient(maxEntries int) *MemoryClient {
+	return &MemoryClient{c: cache.New[string, []byte](cache.Config{MaxEntries: maxEntries})}
+}
+
+// Get 实现 Client
+func (m *MemoryClient) Get(_ context.Context, key string) ([]byte, error) {
+	b, ok := m.c.Get(key)
+	if !ok {
+		return nil, ErrMiss
+	}
+	return bytes.Clone(b), nil
+}
+
+// Set 实现 Client
+func (m *MemoryClient) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.c.SetWithTTL(key, bytes.Clone(value), ttl)
+	return nil
+}
+
+// Del 实现 Client
+func (m *MemoryClient) Del(_ context.Context, keys ...string) error {
+	for _, k := range keys {
+		m.c.Delete(k)
+	}
+	return nil
+}
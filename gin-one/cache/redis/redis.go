@@ -0,0 +1,221 @@
+// ============================================================================
+// Package redis 基于 Redis 的 cache-aside 缓存层
+// ============================================================================
+//
+// 【cache-aside（旁路缓存）】
+//
+//	读：先查 Redis → 命中直接返回
+//	             → 未命中查数据库，写回 Redis（带 TTL）
+//	写：先更新数据库 → 再删除 Redis 中的 key（不是更新）
+//
+// 为什么写时删除而不是更新？两个并发写 A、B 更新数据库的顺序是 A→B，
+// 更新缓存的顺序可能是 B→A，缓存里留下旧值 A 直到过期。删除没有这个问题，
+// 下一次读会从数据库加载最新值。
+//
+// 【缓存雪崩 / 击穿】
+//
+// | 问题 | 场景                               | 本包的做法                              |
+// |------|------------------------------------|-----------------------------------------|
+// | 击穿 | 热 key 过期瞬间大量请求打到数据库  | 同一个 key 进程内只有一个加载者（singleflight） |
+// | 雪崩 | 同一批写入的 key 在同一时刻过期    | TTL 加 ±Jitter 的随机抖动               |
+// | 宕机 | Redis 不可用                       | 记录日志后直接查数据库（fail-open）     |
+//
+// singleflight 只在进程内去重，N 个实例最多 N 个并发加载，对数据库来说已经足够。
+//
+// 【不直接依赖 go-redis】
+//
+// 和 middleware/ratelimit 一样，只要求客户端实现 Client 的三个方法，用 go-redis 时这样适配：
+//
+//	type goRedisClient struct{ rdb *goredis.Client }
+//
+//	func (c goRedisClient) Get(ctx context.Context, key string) ([]byte, error) {
+//		b, err := c.rdb.Get(ctx, key).Bytes()
+//		if errors.Is(err, goredis.Nil) {
+//			return nil, redis.ErrMiss
+//		}
+//		return b, err
+//	}
+//
+//	func (c goRedisClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+//		return c.rdb.Set(ctx, key, value, ttl).Err()
+//	}
+//
+//	func (c goRedisClient) Del(ctx context.Context, keys ...string) error {
+//		return c.rdb.Del(ctx, keys...).Err()
+//	}
+//
+// 开发环境没有 Redis 时用 NewMemoryClient()。
+//
+// ============================================================================
+package redis
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// 错误定义
+var (
+	ErrMiss = errors.New("redis: cache miss")
+)
+
+// Client Redis 客户端的最小接口
+type Client interface {
+	// Get 读取 key，不存在时返回 ErrMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set 写入 key，ttl 为 0 表示不过期
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Del 删除 key，不存在不算错误
+	Del(ctx context.Context, keys ...string) error
+}
+
+// Config 缓存配置
+type Config struct {
+	// Prefix 加在所有 key 前面，如 "user:"；不同实体、不同版本的结构体用不同前缀
+	Prefix string
+
+	// TTL 过期时间，默认 5 分钟
+	TTL time.Duration
+
+	// Jitter TTL 随机抖动比例，默认 0.1（即 TTL ±10%），负数表示不抖动
+	Jitter float64
+
+	// Codec 序列化方式，默认 JSON
+	Codec Codec
+
+	// Logger 记录 Redis 故障，默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Stats 缓存统计
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Errors uint64 `json:"errors"` // Redis 读写失败或数据无法反序列化
+}
+
+// Cache 某一类实体的缓存，V 为实体类型（如 model.User）
+type Cache[V any] struct {
+	client Client
+	cfg    Config
+	group  singleflight.Group
+
+	hits, misses, errors atomic.Uint64
+}
+
+// New 创建缓存
+func New[V any](client Client, cfg Config) *Cache[V] {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 5 * time.Minute
+	}
+	if cfg.Jitter == 0 {
+		cfg.Jitter = 0.1
+	}
+	if cfg.Codec == nil {
+		cfg.Codec = JSON
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Cache[V]{client: client, cfg: cfg}
+}
+
+func (c *Cache[V]) key(id string) string {
+	return c.cfg.Prefix + id
+}
+
+// ttl 加随机抖动，避免同一批 key 同时过期
+func (c *Cache[V]) ttl() time.Duration {
+	if c.cfg.Jitter <= 0 {
+		return c.cfg.TTL
+	}
+	spread := float64(c.cfg.TTL) * c.cfg.Jitter
+	return c.cfg.TTL + time.Duration((rand.Float64()*2-1)*spread)
+}
+
+// Get 读取缓存，未命中返回 ErrMiss
+func (c *Cache[V]) Get(ctx context.Context, id string) (*V, error) {
+	data, err := c.client.Get(ctx, c.key(id))
+	if err != nil {
+		return nil, err
+	}
+	v := new(V)
+	if err := c.cfg.Codec.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Set 写入缓存
+func (c *Cache[V]) Set(ctx context.Context, id string, v *V) error {
+	data, err := c.cfg.Codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, c.key(id), data, c.ttl())
+}
+
+// Delete 删除缓存，数据库更新成功后调用
+func (c *Cache[V]) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = c.key(id)
+	}
+	return c.client.Del(ctx, keys...)
+}
+
+// GetOrLoad 命中直接返回，未命中调用 load 并写回缓存
+//
+// Redis 故障或缓存数据损坏时只记日志，照常调用 load；load 的错误原样返回且不缓存。
+// 同一个 id 的并发未命中只调用一次 load，等待者的 ctx 取消时立即返回。
+// 并发未命中的调用者拿到的是同一个指针，不要修改返回值。
+func (c *Cache[V]) GetOrLoad(ctx context.Context, id string, load func(ctx context.Context) (*V, error)) (*V, error) {
+	v, err := c.Get(ctx, id)
+	switch {
+	case err == nil:
+		c.hits.Add(1)
+		return v, nil
+	case errors.Is(err, ErrMiss):
+		c.misses.Add(1)
+	default:
+		c.errors.Add(1)
+		c.cfg.Logger.Warn("cache read failed", "key", c.key(id), "error", err)
+	}
+
+	ch := c.group.DoChan(id, func() (any, error) {
+		// 加载不随第一个调用者的请求取消而中断，等待中的其他请求还要用结果
+		lctx := context.WithoutCancel(ctx)
+		v, err := load(lctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Set(lctx, id, v); err != nil {
+			c.errors.Add(1)
+			c.cfg.Logger.Warn("cache write failed", "key", c.key(id), "error", err)
+		}
+		return v, nil
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*V), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Stats 统计快照
+func (c *Cache[V]) Stats() Stats {
+	return Stats{Hits: c.hits.Load(), Misses: c.misses.Load(), Errors: c.errors.Load()}
+}
@@ -0,0 +1,168 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type item struct {
+	ID     uint      `json:"id"`
+	Name   string    `json:"name"`
+	Secret string    `json:"-"`
+	At     time.Time `json:"at"`
+}
+
+// brokenClient 模拟 Redis 宕机
+type brokenClient struct{}
+
+var errDown = errors.New("connection refused")
+
+func (brokenClient) Get(context.Context, string) ([]byte, error)              { return nil, errDown }
+func (brokenClient) Set(context.Context, string, []byte, time.Duration) error { return errDown }
+func (brokenClient) Del(context.Context, ...string) error                     { return errDown }
+
+func TestCodecs(t *testing.T) {
+	in := item{ID: 7, Name: "alice", Secret: "hash", At: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name  string
+		codec Codec
+	}{
+		{"json", JSON},
+		{"msgpack", Msgpack},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New[item](NewMemoryClient(0), Config{Codec: tt.codec})
+			ctx := context.Background()
+			if err := c.Set(ctx, "7", &in); err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.Get(ctx, "7")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != in.ID || got.Name != in.Name || !got.At.Equal(in.At) {
+				t.Fatalf("round trip = %+v; want %+v", got, in)
+			}
+			// json:"-" 的字段不写进缓存
+			if got.Secret != "" {
+				t.Fatalf("secret leaked into cache")
+			}
+		})
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	client := NewMemoryClient(0)
+	c := New[item](client, Config{Prefix: "item:"})
+	ctx := context.Background()
+	loads := 0
+	load := func(context.Context) (*item, error) {
+		loads++
+		return &item{ID: 1, Name: "a"}, nil
+	}
+
+	for range 3 {
+		got, err := c.GetOrLoad(ctx, "1", load)
+		if err != nil || got.Name != "a" {
+			t.Fatalf("GetOrLoad = %+v, %v", got, err)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("loads = %d; want 1", loads)
+	}
+	if _, err := client.Get(ctx, "item:1"); err != nil {
+		t.Fatalf("key not written with prefix: %v", err)
+	}
+	if s := c.Stats(); s.Hits != 2 || s.Misses != 1 {
+		t.Fatalf("stats = %+v", s)
+	}
+
+	// 删除后重新加载
+	if err := c.Delete(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	c.GetOrLoad(ctx, "1", load)
+	if loads != 2 {
+		t.Fatalf("loads after delete = %d; want 2", loads)
+	}
+
+	// 加载失败不缓存
+	errNotFound := errors.New("not found")
+	for range 2 {
+		_, err := c.GetOrLoad(ctx, "404", func(context.Context) (*item, error) { return nil, errNotFound })
+		if !errors.Is(err, errNotFound) {
+			t.Fatalf("err = %v", err)
+		}
+	}
+	if _, err := c.Get(ctx, "404"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("error was cached: %v", err)
+	}
+}
+
+func TestGetOrLoadFailOpen(t *testing.T) {
+	c := New[item](brokenClient{}, Config{})
+	got, err := c.GetOrLoad(context.Background(), "1", func(context.Context) (*item, error) {
+		return &item{Name: "from db"}, nil
+	})
+	if err != nil || got.Name != "from db" {
+		t.Fatalf("GetOrLoad with Redis down = %+v, %v", got, err)
+	}
+	if s := c.Stats(); s.Errors != 2 { // 读失败 + 写回失败
+		t.Fatalf("errors = %d; want 2", s.Errors)
+	}
+}
+
+func TestGetOrLoadStampede(t *testing.T) {
+	c := New[item](NewMemoryClient(0), Config{})
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) (*item, error) {
+		loads.Add(1)
+		<-release
+		return &item{Name: "hot"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := c.GetOrLoad(context.Background(), "hot", load); err != nil || got.Name != "hot" {
+				t.Errorf("GetOrLoad = %+v, %v", got, err)
+			}
+		}()
+	}
+	for c.Stats().Misses < 20 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("loads = %d; want 1", n)
+	}
+}
+
+func TestTTLJitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		jitter   float64
+		min, max time.Duration
+	}{
+		{"default 10%", 0, 90 * time.Second, 110 * time.Second},
+		{"disabled", -1, 100 * time.Second, 100 * time.Second},
+		{"50%", 0.5, 50 * time.Second, 150 * time.Second},
+	}
+	for _, tt := range tests {
+		c := New[item](nil, Config{TTL: 100 * time.Second, Jitter: tt.jitter})
+		for range 100 {
+			if d := c.ttl(); d < tt.min || d > tt.max {
+				t.Fatalf("%s: ttl = %v; want [%v, %v]", tt.name, d, tt.min, tt.max)
+			}
+		}
+	}
+}
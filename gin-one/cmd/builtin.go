@@ -0,0 +1,474 @@
+package cmd
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/app"
+	"go-one/crypto/fieldenc"
+	"go-one/loadgen"
+	"go-one/openapi"
+	"go-one/rbac"
+	"go-one/server"
+	"go-one/service"
+)
+
+// ============================================================================
+// 内置命令
+// ============================================================================
+
+// serve 启动 HTTP 服务，收到 SIGINT / SIGTERM 后优雅关闭
+//
+// 多实例部署时通常先单独跑一次 migrate，服务用 -migrate=false 启动，
+// 滚动发布时新旧版本不会同时改表结构。
+func serve() Command {
+	var addr string
+	var migrate bool
+	return Command{
+		Name:    "serve",
+		Summary: "启动 HTTP 服务（默认命令）",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&addr, "addr", "", "监听地址，默认 server.addr 配置")
+			fs.BoolVar(&migrate, "migrate", true, "启动前自动迁移")
+		},
+		Run: func(ctx context.Context, env *Env) error {
+			env.migrate, env.serving = migrate, true
+			r, _, err := env.router(ctx)
+			if err != nil {
+				return err
+			}
+			srvCfg := server.FromConfig(env.Config.Server)
+			if addr != "" {
+				srvCfg.Addr = addr
+			}
+			// 最后注册、最先停止：进行中的请求结束后才关闭数据库和 worker
+			// （DrainTimeout 到了 server 强制断开连接，handler 可能还在执行）
+			env.Lifecycle.OnStop("in-flight requests", env.InFlight.Wait)
+			// 先启动后台组件再接受请求；关闭时反过来，请求都结束后再停止
+			if err := env.Lifecycle.Start(ctx); err != nil {
+				return err
+			}
+			srv := server.New(r, srvCfg)
+			srv.OnDrain(env.InFlight.Drain)
+			srv.OnShutdown("app", env.Lifecycle.Stop)
+			return srv.RunContext(ctx)
+		},
+	}
+}
+
+// migrate 持有迁移锁执行 AutoMigrate，完成后退出
+func migrate() Command {
+	return Command{
+		Name:    "migrate",
+		Summary: "执行数据库迁移后退出",
+		Run: func(ctx context.Context, env *Env) error {
+			if _, err := env.App(ctx); err != nil {
+				return err
+			}
+			fmt.Fprintln(env.Stdout, "migrated")
+			return nil
+		},
+	}
+}
+
+// rotateFieldKeys 把加密列里明文和旧版本的值用当前密钥版本重新加密（见 crypto/fieldenc）
+//
+//	APP_FIELDENC_KEYS=1:旧,2:新 app rotate-field-keys
+//
+// 处理 Program.Options.Models（默认 app.DefaultModels）里带 serializer:encrypted 的模型；
+// 服务不用停，可以重复执行，打印的 ROTATED 为 0 后才能从配置里删掉旧版本。
+func rotateFieldKeys() Command {
+	var batch int
+	return Command{
+		Name:    "rotate-field-keys",
+		Summary: "用当前密钥版本重新加密敏感列",
+		Flags: func(fs *flag.FlagSet) {
+			fs.IntVar(&batch, "batch", 500, "每批读取的行数")
+		},
+		Run: func(ctx context.Context, env *Env) error {
+			a, err := env.App(ctx)
+			if err != nil {
+				return err
+			}
+			if a.FieldKeys == nil {
+				return ErrNoFieldKeys
+			}
+			models := env.program.Options.Models
+			if models == nil {
+				models = app.DefaultModels()
+			}
+			fmt.Fprintf(env.Stdout, "active key version: v%d\n", a.FieldKeys.Active())
+			tw := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "TABLE\tSCANNED\tROTATED")
+			for _, m := range models {
+				res, err := fieldenc.Rotate(ctx, a.DB, a.FieldKeys, m, batch)
+				if err != nil {
+					tw.Flush()
+					return fmt.Errorf("rotate-field-keys: %T: %w", m, err)
+				}
+				if res.Scanned > 0 {
+					fmt.Fprintf(tw, "%s\t%d\t%d\n", res.Table, res.Scanned, res.Rotated)
+				}
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+// seed 导入 fixture、批量生成数据，参数见 seed.Seeder.Run：
+//
+//	app seed examples/fixtures/ users=10000 posts=50000
+//
+// 没有设置 Program.Seed 时用 app.ProvideSeeder（默认模型）
+func seed() Command {
+	return Command{
+		Name:    "seed",
+		Summary: "导入 fixture / 批量生成数据：seed <文件或目录>... <表名>=<数量>...",
+		Run: func(ctx context.Context, env *Env) error {
+			if env.program.Seed != nil {
+				return env.program.Seed(ctx, env)
+			}
+			if len(env.Args) == 0 {
+				return fmt.Errorf("%w: seed <fixture file or dir>... <table>=<count>...", ErrNoArgs)
+			}
+			a, err := env.App(ctx)
+			if err != nil {
+				return err
+			}
+			if err := app.ProvideSeeder(a.DB, a.Passwords).Run(ctx, env.Args); err != nil {
+				return err
+			}
+			fmt.Fprintln(env.Stdout, "seeded")
+			return nil
+		},
+	}
+}
+
+// createAdminUser 创建用户并在 user_roles 表里授予角色（见 rbac 包）
+//
+// 不传 -password 时生成随机密码，只打印这一次；密码不要写进 shell 历史。
+func createAdminUser() Command {
+	var username, email, password, role string
+	return Command{
+		Name:    "create-admin-user",
+		Summary: "创建管理员账号",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&username, "username", "admin", "用户名")
+			fs.StringVar(&email, "email", "", "邮箱")
+			fs.StringVar(&password, "password", "", "密码，为空时生成随机密码")
+			fs.StringVar(&role, "role", "admin", "授予的角色")
+		},
+		Run: func(ctx context.Context, env *Env) error {
+			a, err := env.App(ctx)
+			if err != nil {
+				return err
+			}
+			if err := app.Migrate(ctx, a.DB, a.Locker, &rbac.UserRole{}); err != nil {
+				return err
+			}
+			generated := password == ""
+			if generated {
+				b := make([]byte, 12)
+				rand.Read(b)
+				password = base64.RawURLEncoding.EncodeToString(b)
+			}
+			u, err := a.Services.Users.Create(ctx, service.CreateUserInput{Username: username, Email: email, Password: password})
+			if errors.Is(err, service.ErrUserExists) {
+				return fmt.Errorf("create-admin-user: %s: %w", username, err)
+			}
+			if err != nil {
+				return err
+			}
+			if err := rbac.NewGormStore(a.DB).Add(ctx, u.ID, role); err != nil {
+				return fmt.Errorf("create-admin-user: grant %s: %w", role, err)
+			}
+			fmt.Fprintf(env.Stdout, "created user %s (id=%d) with role %s\n", u.Username, u.ID, role)
+			if generated {
+				fmt.Fprintf(env.Stdout, "password: %s\n", password)
+			}
+			return nil
+		},
+	}
+}
+
+// routesList 按路径列出注册的路由和 handler，不连接外部服务、不迁移
+func routesList() Command {
+	return Command{
+		Name:    "routes-list",
+		Summary: "列出所有路由",
+		Run: func(ctx context.Context, env *Env) error {
+			env.migrate = false
+			r, _, err := env.router(ctx)
+			if err != nil {
+				return err
+			}
+			routes := r.Routes()
+			slices.SortFunc(routes, func(a, b gin.RouteInfo) int {
+				return cmp.Or(strings.Compare(a.Path, b.Path), strings.Compare(a.Method, b.Method))
+			})
+			tw := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "METHOD\tPATH\tHANDLER")
+			for _, rt := range routes {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", rt.Method, rt.Path, rt.Handler)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+// openapiDump 输出 Router 返回的 OpenAPI 文档，CI 里和仓库中的文件比较就能发现没提交的接口变更
+func openapiDump() Command {
+	var out string
+	return Command{
+		Name:    "openapi-dump",
+		Summary: "输出 OpenAPI 文档",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&out, "o", "", "输出文件，默认标准输出")
+		},
+		Run: func(ctx context.Context, env *Env) error {
+			env.migrate = false
+			_, doc, err := env.router(ctx)
+			if err != nil {
+				return err
+			}
+			if doc == nil {
+				return ErrNoOpenAPI
+			}
+			data, err := doc.JSON()
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, data, "", "  "); err != nil {
+				return err
+			}
+			buf.WriteByte('\n')
+			if out == "" {
+				_, err = buf.WriteTo(env.Stdout)
+				return err
+			}
+			return os.WriteFile(out, buf.Bytes(), 0o644)
+		},
+	}
+}
+
+// contractTest 契约测试（见 openapi 包）：路由都有文档，按文档 example 调用每个接口，
+// 响应的状态码和结构与文档一致；发现问题时返回 ErrContractDrift，CI 里以状态码 1 失败
+//
+// 请求在进程内直接交给路由处理，不监听端口；会真的执行创建、删除，要对测试库运行。
+func contractTest() Command {
+	var token, ignore, run string
+	return Command{
+		Name:    "contract-test",
+		Summary: "按 OpenAPI 文档调用每个接口，检查路由、状态码和响应结构与文档一致",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&token, "token", "", "需要认证的接口带上的 Bearer token")
+			fs.StringVar(&ignore, "ignore", "/openapi.json,/docs", "不需要文档的路由，逗号分隔，支持 * 通配符")
+			fs.StringVar(&run, "run", "", "只调用 \"方法 路径\" 匹配这个正则的接口，如 \"GET /api/v1/users\"")
+		},
+		Run: func(ctx context.Context, env *Env) error {
+			filter, err := regexp.Compile(run)
+			if err != nil {
+				return fmt.Errorf("contract-test: -run: %w", err)
+			}
+			r, doc, err := env.router(ctx)
+			if err != nil {
+				return err
+			}
+			if doc == nil {
+				return ErrNoOpenAPI
+			}
+
+			problems := doc.CheckRoutes(r.Routes(), strings.Split(ignore, ",")...)
+			for _, p := range problems {
+				fmt.Fprintf(env.Stdout, "FAIL  %s\n", p)
+			}
+			routes := map[string]bool{}
+			for _, rt := range r.Routes() {
+				routes[rt.Method+" "+rt.Path] = true
+			}
+			for _, s := range doc.Samples() {
+				// 只写了文档没有路由的接口（doc.Add 登记的反向代理等）不调用
+				if !routes[s.Method+" "+s.Path] || !filter.MatchString(s.Method+" "+s.Path) {
+					continue
+				}
+				req := httptest.NewRequestWithContext(ctx, s.Method, s.Target, bytes.NewReader(s.Body))
+				if s.Body != nil {
+					req.Header.Set("Content-Type", "application/json")
+				}
+				if s.Auth && token != "" {
+					req.Header.Set("Authorization", "Bearer "+token)
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				found := doc.CheckResponse(s.Method, s.Path, w.Code, w.Header().Get("Content-Type"), w.Body.Bytes())
+				result := "PASS"
+				if len(found) > 0 {
+					result = "FAIL"
+				}
+				fmt.Fprintf(env.Stdout, "%s  %-6s %s -> %d\n", result, s.Method, s.Target, w.Code)
+				for _, p := range found {
+					fmt.Fprintf(env.Stdout, "      %s\n", p.Message)
+				}
+				problems = append(problems, found...)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("%w: %d problems", ErrContractDrift, len(problems))
+			}
+			fmt.Fprintln(env.Stdout, "ok")
+			return nil
+		},
+	}
+}
+
+// loadgen 压测一个接口（见 loadgen 包），目标是完整 URL 或路径；路径发到本机的 server.addr：
+//
+//	app loadgen -c 50 -duration 10s /api/v1/users
+//	app loadgen -X POST -H "Content-Type: application/json" -d '{"username":"x"}' http://staging/api/v1/users
+//	app loadgen -ramp 1,2,4,8,16,32,64 -max-p99 100ms /api/v1/users
+//
+// 不调用 Router、不连数据库，压的是另一个进程里正在运行的服务。
+func loadGen() Command {
+	var (
+		cfg     loadgen.Config
+		ramp    loadgen.Ramp
+		body    string
+		headers = http.Header{}
+		levels  string
+	)
+	return Command{
+		Name:    "loadgen",
+		Summary: "压测一个接口，报告延迟分位数、错误率和吞吐量；-ramp 逐级加并发找饱和点",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&cfg.Method, "X", http.MethodGet, "请求方法")
+			fs.StringVar(&body, "d", "", "请求体")
+			fs.Func("H", "请求头，如 \"Authorization: Bearer $TOKEN\"，可以重复", func(v string) error {
+				name, value, ok := strings.Cut(v, ":")
+				if !ok {
+					return fmt.Errorf("header %q: want \"Name: value\"", v)
+				}
+				headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+				return nil
+			})
+			fs.IntVar(&cfg.Concurrency, "c", 10, "并发数")
+			fs.DurationVar(&cfg.Duration, "duration", 10*time.Second, "压测时长；爬坡时是每一级的时长")
+			fs.IntVar(&cfg.Requests, "n", 0, "总请求数，大于 0 时代替 -duration")
+			fs.DurationVar(&cfg.Timeout, "timeout", 10*time.Second, "单个请求的超时")
+			fs.StringVar(&levels, "ramp", "", "逐级使用的并发数，逗号分隔，如 1,2,4,8,16；设置后忽略 -c")
+			fs.DurationVar(&ramp.MaxP99, "max-p99", 0, "爬坡时 p99 超过它就认为饱和，0 不检查")
+			fs.Float64Var(&ramp.MaxErrorRate, "max-errors", 0.01, "爬坡时错误率超过它就认为饱和")
+		},
+		Run: func(ctx context.Context, env *Env) error {
+			if len(env.Args) != 1 {
+				return fmt.Errorf("%w: loadgen [flags] <url or path>", ErrNoArgs)
+			}
+			cfg.URL = env.Args[0]
+			if strings.HasPrefix(cfg.URL, "/") {
+				host := env.Config.Server.Addr
+				if strings.HasPrefix(host, ":") {
+					host = "localhost" + host
+				}
+				cfg.URL = "http://" + host + cfg.URL
+			}
+			if body != "" {
+				cfg.Body = []byte(body)
+			}
+			if len(headers) > 0 {
+				cfg.Header = headers
+			}
+
+			if levels == "" {
+				res, err := loadgen.Run(ctx, cfg)
+				if res.Requests > 0 {
+					printLoad(env.Stdout, cfg, res)
+				}
+				if errors.Is(err, context.Canceled) {
+					return nil // Ctrl+C 提前结束，已经输出了完成的部分
+				}
+				return err
+			}
+			for _, s := range strings.Split(levels, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(s))
+				if err != nil || n <= 0 {
+					return fmt.Errorf("loadgen: -ramp: invalid concurrency %q", s)
+				}
+				ramp.Levels = append(ramp.Levels, n)
+			}
+			fmt.Fprintf(env.Stdout, "%s %s  %v per level\n", cfg.Method, cfg.URL, cfg.Duration)
+			// 每一级跑完就输出一行，不用等全部结束，所以用固定列宽而不是 tabwriter
+			const row = "%11s %9s %10s %7s %9s %9s %9s\n"
+			fmt.Fprintf(env.Stdout, row, "CONCURRENCY", "REQUESTS", "REQ/S", "ERRORS", "P50", "P95", "P99")
+			report, err := loadgen.RunRamp(ctx, cfg, ramp, func(r loadgen.Result) {
+				fmt.Fprintf(env.Stdout, row, strconv.Itoa(r.Concurrency), strconv.Itoa(r.Requests), fmt.Sprintf("%.1f", r.Throughput()),
+					fmt.Sprintf("%.2f%%", r.ErrorRate()*100), ms(r.P50), ms(r.P95), ms(r.P99))
+			})
+			if err != nil {
+				return err
+			}
+			switch {
+			case !report.Saturated:
+				fmt.Fprintln(env.Stdout, "not saturated, try higher -ramp levels")
+			case report.Best.Requests == 0:
+				fmt.Fprintf(env.Stdout, "saturated at the first level: %s\n", report.Reason)
+			default:
+				fmt.Fprintf(env.Stdout, "saturation: concurrency %d, %.1f req/s, p99 %s (next level: %s)\n",
+					report.Best.Concurrency, report.Best.Throughput(), ms(report.Best.P99), report.Reason)
+			}
+			return nil
+		},
+	}
+}
+
+// printLoad 输出一次压测的结果
+func printLoad(w io.Writer, cfg loadgen.Config, r loadgen.Result) {
+	fmt.Fprintf(w, "%s %s  concurrency %d  %v\n", cfg.Method, cfg.URL, r.Concurrency, r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "requests  %d  %.1f/s\n", r.Requests, r.Throughput())
+	fmt.Fprintf(w, "errors    %d  %.2f%%\n", r.Errors, r.ErrorRate()*100)
+	fmt.Fprintf(w, "latency   mean %s  p50 %s  p95 %s  p99 %s  max %s\n", ms(r.Mean), ms(r.P50), ms(r.P95), ms(r.P99), ms(r.Max))
+	fmt.Fprint(w, "status   ")
+	for _, code := range slices.Sorted(maps.Keys(r.Status)) {
+		if code == 0 {
+			fmt.Fprintf(w, " error=%d", r.Status[code])
+		} else {
+			fmt.Fprintf(w, " %d=%d", code, r.Status[code])
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// ms 延迟统一按毫秒输出，方便对齐和比较
+func ms(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 2, 64) + "ms"
+}
+
+// router 调用 Program.Router；serve 以外的命令关掉 gin 的 debug 输出，不混进命令的输出
+func (e *Env) router(ctx context.Context) (*gin.Engine, *openapi.Builder, error) {
+	if e.program.Router == nil {
+		return nil, nil, ErrNoRouter
+	}
+	if !e.serving {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	return e.program.Router(ctx, e)
+}
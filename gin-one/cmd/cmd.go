@@ -0,0 +1,257 @@
+// ============================================================================
+// Package cmd 示例程序的子命令：serve、migrate、rotate-field-keys、seed、create-admin-user、routes-list、openapi-dump、contract-test、loadgen
+// ============================================================================
+//
+// main 只负责启动 HTTP 服务时，迁移、造数据、建管理员账号都要另写脚本，
+// 脚本里再把配置加载、数据库连接复制一遍。这里让同一个二进制带上运维命令，
+// 和服务共用配置加载（config）与依赖装配（app）：
+//
+//	app [配置参数] [命令] [命令参数]
+//
+//	app                                     # 等同于 app serve
+//	app -config prod.yaml serve -migrate=false
+//	app -database.driver postgres migrate
+//	APP_FIELDENC_KEYS=1:...,2:... app rotate-field-keys  # 加密列换成新的密钥版本
+//	app create-admin-user -username root -email root@example.com
+//	app seed examples/fixtures/ users=10000
+//	app routes-list
+//	app openapi-dump -o openapi.json
+//	app contract-test -token $TOKEN         # 响应和文档不一致时退出码为 1
+//	app loadgen -ramp 1,2,4,8,16 /api/v1/users  # 压测另一个进程里运行的服务
+//
+// 配置参数（-config、-server.addr 等，见 config 包）写在命令名之前，命令参数写在之后。
+//
+// 【用法】
+//
+//	func main() {
+//		cmd.Program{
+//			Router: func(ctx context.Context, env *cmd.Env) (*gin.Engine, *openapi.Builder, error) {
+//				a, err := env.App(ctx)  // 需要数据库时才装配，纯网关不用连数据库
+//				...
+//				return r, doc, nil
+//			},
+//		}.Main()
+//	}
+//
+// 【生命周期】
+//
+// Env.Lifecycle 在所有命令间共用，也传给 app.New：Router 里把后台组件（gRPC 服务、
+// 任务 worker）注册到它上面，serve 启动服务前 Start、收到信号后按逆序 Stop；
+// routes-list / openapi-dump / contract-test 同样调用 Router，但不 Start，不会监听端口或启动 worker。
+//
+// Env.InFlight 统计进行中的请求，Router 里 r.Use(env.InFlight.Middleware()) 接入：serve 开始关闭时
+// 拒绝新请求（503 + Retry-After），Lifecycle 停止时先等进行中的请求结束，再关闭数据库。
+//
+// ============================================================================
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/app"
+	"go-one/config"
+	"go-one/middleware/drain"
+	"go-one/openapi"
+)
+
+// 错误定义
+var (
+	ErrUnknownCommand = errors.New("cmd: unknown command")
+	ErrNoRouter       = errors.New("cmd: Program.Router is not set")
+	ErrNoArgs         = errors.New("cmd: missing arguments")
+	ErrNoOpenAPI      = errors.New("cmd: router has no OpenAPI document")
+	ErrContractDrift  = errors.New("cmd: API does not match its OpenAPI document")
+	ErrNoFieldKeys    = errors.New("cmd: fieldenc.keys is not configured")
+)
+
+// Command 一个子命令
+type Command struct {
+	Name    string
+	Summary string // 一行说明，help 里显示
+
+	// Flags 注册命令自己的参数，可以为 nil
+	Flags func(fs *flag.FlagSet)
+
+	// Run 参数解析完成后执行
+	Run func(ctx context.Context, env *Env) error
+}
+
+// Program 一个示例程序
+type Program struct {
+	// Name 用法里显示的程序名，默认 os.Args[0]
+	Name string
+
+	// Options 传给 app.New，测试时换成内存 SQLite
+	Options app.Options
+
+	// Router 注册路由，doc 为 nil 时 openapi-dump 不可用；serve、routes-list、openapi-dump 使用
+	Router func(ctx context.Context, env *Env) (r *gin.Engine, doc *openapi.Builder, err error)
+
+	// Seed 替换 seed 命令的默认实现（app.ProvideSeeder 处理命令参数）
+	Seed func(ctx context.Context, env *Env) error
+
+	// Commands 额外的子命令，和内置命令同名时替换内置命令
+	Commands []Command
+
+	// Stdout / Stderr 默认 os.Stdout / os.Stderr
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Env 命令执行时的环境
+type Env struct {
+	Config    *config.Config
+	Args      []string // 命令参数之后的位置参数
+	Stdout    io.Writer
+	Lifecycle *app.Lifecycle
+	// InFlight 进行中请求计数，健康检查（/healthz、/readyz、/health）不计数也不拒绝
+	InFlight *drain.Tracker
+
+	program *Program
+	stderr  io.Writer
+	migrate bool // App 是否执行自动迁移，routes-list 等只读命令关闭
+	serving bool // serve 的日志按配置写标准输出，其他命令写标准错误，不混进命令的输出
+	app     *app.Application
+}
+
+// App 第一次调用时用 Program.Options 装配 Application，之后返回同一个
+func (e *Env) App(ctx context.Context) (*app.Application, error) {
+	if e.app != nil {
+		return e.app, nil
+	}
+	opts := e.program.Options
+	opts.SkipMigrate = opts.SkipMigrate || !e.migrate
+	opts.Lifecycle = e.Lifecycle
+	if opts.Logger == nil && !e.serving {
+		opts.Logger = slog.New(slog.NewTextHandler(e.stderr, nil))
+	}
+	a, err := app.New(ctx, e.Config, opts)
+	if err != nil {
+		return nil, err
+	}
+	e.app = a
+	return a, nil
+}
+
+// Main 解析 os.Args 执行命令，收到 SIGINT / SIGTERM 时取消 ctx，出错时以状态码 1 退出
+func (p Program) Main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err := p.Run(ctx, os.Args[1:])
+	stop()
+	if err != nil {
+		fmt.Fprintln(p.stderr(), err)
+		os.Exit(1)
+	}
+}
+
+// Run 执行 args 指定的命令；-h / help 打印用法后返回 nil
+func (p Program) Run(ctx context.Context, args []string) error {
+	loader, err := config.NewLoader(config.Options{Args: args})
+	if errors.Is(err, config.ErrHelp) {
+		p.usage()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rest := loader.Args()
+	name := "serve"
+	if len(rest) > 0 {
+		name, rest = rest[0], rest[1:]
+	}
+	if name == "help" {
+		p.usage()
+		return nil
+	}
+	commands := p.commands()
+	i := slices.IndexFunc(commands, func(c Command) bool { return c.Name == name })
+	if i < 0 {
+		p.usage()
+		return fmt.Errorf("%w: %s", ErrUnknownCommand, name)
+	}
+	c := commands[i]
+
+	fs := flag.NewFlagSet(p.name()+" "+c.Name, flag.ContinueOnError)
+	fs.SetOutput(p.stderr())
+	if c.Flags != nil {
+		c.Flags(fs)
+	}
+	if err := fs.Parse(rest); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	env := &Env{
+		Config:    loader.Get(),
+		Args:      fs.Args(),
+		Stdout:    p.stdout(),
+		Lifecycle: app.NewLifecycle(p.Options.Logger),
+		InFlight:  drain.New(drain.Config{SkipPaths: []string{"/healthz", "/readyz", "/health"}}),
+		program:   &p,
+		stderr:    p.stderr(),
+		migrate:   true,
+	}
+	// serve 已经在关闭钩子里停止过，Lifecycle 不会重复停止同一个组件
+	defer env.Lifecycle.Stop(context.WithoutCancel(ctx))
+	return c.Run(ctx, env)
+}
+
+// commands 内置命令加上 Program.Commands，同名的后者替换前者
+func (p *Program) commands() []Command {
+	commands := []Command{serve(), migrate(), rotateFieldKeys(), seed(), createAdminUser(), routesList(), openapiDump(), contractTest(), loadGen()}
+	for _, c := range p.Commands {
+		if i := slices.IndexFunc(commands, func(b Command) bool { return b.Name == c.Name }); i >= 0 {
+			commands[i] = c
+		} else {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+func (p *Program) usage() {
+	w := p.stderr()
+	fmt.Fprintf(w, "用法: %s [配置参数] [命令] [命令参数]\n\n命令:\n", p.name())
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range p.commands() {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.Name, c.Summary)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n配置参数见 %s -h，命令参数见 %s <命令> -h\n", p.name(), p.name())
+}
+
+func (p *Program) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return os.Args[0]
+}
+
+func (p *Program) stdout() io.Writer {
+	if p.Stdout != nil {
+		return p.Stdout
+	}
+	return os.Stdout
+}
+
+func (p *Program) stderr() io.Writer {
+	if p.Stderr != nil {
+		return p.Stderr
+	}
+	return os.Stderr
+}
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/app"
+	"go-one/crypto/fieldenc"
+	"go-one/internal/testdb"
+	"go-one/middleware/drain"
+	"go-one/model"
+	"go-one/openapi"
+	"go-one/rbac"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.Open(t)
+}
+
+// newTestProgram 用内存数据库的程序，Router 注册两个带文档的接口
+func newTestProgram(t *testing.T, db *gorm.DB) (*Program, *bytes.Buffer) {
+	t.Helper()
+	t.Chdir(t.TempDir()) // 不读取仓库里的 config.yaml
+	var out bytes.Buffer
+	return &Program{
+		Name:    "demo",
+		Options: app.Options{DB: db},
+		Router: func(ctx context.Context, env *Env) (*gin.Engine, *openapi.Builder, error) {
+			if _, err := env.App(ctx); err != nil {
+				return nil, nil, err
+			}
+			r := gin.New()
+			doc := openapi.New(openapi.Config{Title: "demo"})
+			doc.Register(r, http.MethodGet, "/users", openapi.Op{Summary: "用户列表"}, func(*gin.Context) {})
+			doc.Register(r, http.MethodPost, "/users", openapi.Op{Summary: "创建用户"}, func(*gin.Context) {})
+			r.GET("/health", func(*gin.Context) {})
+			return r, doc, nil
+		},
+		Stdout: &out,
+		Stderr: &bytes.Buffer{},
+	}, &out
+}
+
+func TestRoutesListAndOpenAPIDump(t *testing.T) {
+	db := newTestDB(t)
+	p, out := newTestProgram(t, db)
+	ctx := context.Background()
+
+	if err := p.Run(ctx, []string{"routes-list"}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "METHOD") ||
+		!strings.HasPrefix(lines[1], "GET     /health") || !strings.HasPrefix(lines[3], "POST    /users") {
+		t.Fatalf("routes-list =\n%s", out)
+	}
+	// 只读命令不迁移
+	if db.Migrator().HasTable(&model.User{}) {
+		t.Error("routes-list migrated the database")
+	}
+
+	file := filepath.Join(t.TempDir(), "openapi.json")
+	if err := p.Run(ctx, []string{"openapi-dump", "-o", file}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(file)
+	var spec struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil || len(spec.Paths["/users"]) != 2 {
+		t.Errorf("openapi-dump = %s, %v", data, err)
+	}
+
+	p.Router = nil
+	if err := p.Run(ctx, []string{"routes-list"}); !errors.Is(err, ErrNoRouter) {
+		t.Errorf("no router err = %v", err)
+	}
+}
+
+func TestContractTest(t *testing.T) {
+	p, out := newTestProgram(t, newTestDB(t))
+	ctx := context.Background()
+
+	// /health 没有文档
+	err := p.Run(ctx, []string{"contract-test"})
+	if !errors.Is(err, ErrContractDrift) || !strings.Contains(out.String(), "FAIL  GET /health: route is not documented") {
+		t.Errorf("err = %v, out =\n%s", err, out)
+	}
+	if !strings.Contains(out.String(), "PASS  POST   /users -> 200") {
+		t.Errorf("out =\n%s", out)
+	}
+
+	out.Reset()
+	if err := p.Run(ctx, []string{"contract-test", "-ignore", "/health", "-run", "^GET "}); err != nil || strings.Contains(out.String(), "POST") {
+		t.Errorf("err = %v, out =\n%s", err, out)
+	}
+}
+
+func TestLoadgen(t *testing.T) {
+	p, out := newTestProgram(t, newTestDB(t))
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ping" || r.Header.Get("X-Test") != "1" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	// 路径发到 server.addr
+	err := p.Run(ctx, []string{"-server.addr", srv.Listener.Addr().String(), "loadgen", "-n", "20", "-c", "2", "-H", "X-Test: 1", "/ping"})
+	if err != nil || !strings.Contains(out.String(), "requests  20") || !strings.Contains(out.String(), "status    200=20") {
+		t.Errorf("err = %v, out =\n%s", err, out)
+	}
+
+	out.Reset()
+	if err := p.Run(ctx, []string{"loadgen", "-ramp", "1,2", "-duration", "20ms", srv.URL + "/ping"}); err != nil ||
+		!strings.Contains(out.String(), "saturated at the first level: error rate 100.00%") {
+		t.Errorf("err = %v, out =\n%s", err, out)
+	}
+
+	for _, args := range [][]string{{"loadgen"}, {"loadgen", "-ramp", "1,x", "/ping"}, {"loadgen", "-H", "bad", "/ping"}} {
+		if err := p.Run(ctx, args); err == nil {
+			t.Errorf("%v: no error", args)
+		}
+	}
+}
+
+func TestCreateAdminUser(t *testing.T) {
+	db := newTestDB(t)
+	p, out := newTestProgram(t, db)
+	ctx := context.Background()
+
+	if err := p.Run(ctx, []string{"create-admin-user", "-username", "root", "-email", "root@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "created user root (id=1) with role admin") || !strings.Contains(out.String(), "password: ") {
+		t.Errorf("output = %s", out)
+	}
+	roles, err := rbac.NewGormStore(db).List(ctx)
+	if err != nil || len(roles) != 1 || roles[0] != (rbac.Assignment{UserID: 1, Role: "admin"}) {
+		t.Errorf("roles = %v, %v", roles, err)
+	}
+
+	err = p.Run(ctx, []string{"create-admin-user", "-username", "root", "-password", "secret123"})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("duplicate err = %v", err)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	p, out := newTestProgram(t, newTestDB(t))
+	ctx := context.Background()
+
+	if err := p.Run(ctx, []string{"nope"}); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("unknown err = %v", err)
+	}
+	if err := p.Run(ctx, []string{"seed"}); !errors.Is(err, ErrNoArgs) {
+		t.Errorf("seed err = %v", err)
+	}
+	if err := p.Run(ctx, []string{"help"}); err != nil {
+		t.Errorf("help err = %v", err)
+	}
+
+	// 配置参数在命令名之前，位置参数在命令参数之后
+	var gotAddr string
+	var gotArgs []string
+	p.Seed = func(_ context.Context, env *Env) error {
+		gotAddr, gotArgs = env.Config.Server.Addr, env.Args
+		return nil
+	}
+	if err := p.Run(ctx, []string{"-server.addr", ":9999", "seed", "users", "posts"}); err != nil {
+		t.Fatal(err)
+	}
+	if gotAddr != ":9999" || strings.Join(gotArgs, ",") != "users,posts" {
+		t.Errorf("seed env addr=%q args=%v", gotAddr, gotArgs)
+	}
+
+	// 同名命令替换内置命令
+	p.Commands = []Command{{Name: "migrate", Run: func(_ context.Context, env *Env) error {
+		_, err := env.Stdout.Write([]byte("custom"))
+		return err
+	}}}
+	out.Reset()
+	if err := p.Run(ctx, []string{"migrate"}); err != nil || out.String() != "custom" {
+		t.Errorf("custom migrate = %q, %v", out, err)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	db := newTestDB(t)
+	p, out := newTestProgram(t, db)
+	fixture := filepath.Join(t.TempDir(), "dev.yaml")
+	os.WriteFile(fixture, []byte("posts: [{title: Hello, user_id: 1}]\nusers: [{username: alice, email: a@example.com, password: secret123}]\n"), 0o644)
+
+	// 默认用 app.ProvideSeeder：fixture 之后再生成 3 个用户、5 篇文章
+	if err := p.Run(context.Background(), []string{"seed", fixture, "posts=5", "users=3"}); err != nil {
+		t.Fatal(err)
+	}
+	var users, posts int64
+	db.Model(&model.User{}).Count(&users)
+	db.Model(&model.Post{}).Count(&posts)
+	if users != 4 || posts != 6 || !strings.Contains(out.String(), "seeded") {
+		t.Errorf("users = %d, posts = %d, out = %q", users, posts, out)
+	}
+	var alice model.User
+	db.First(&alice, 1)
+	if alice.Username != "alice" || alice.Password == "secret123" {
+		t.Errorf("alice = %+v", alice)
+	}
+}
+
+type contact struct {
+	ID    uint
+	Phone string `gorm:"size:255;serializer:encrypted"`
+}
+
+func TestRotateFieldKeys(t *testing.T) {
+	db := newTestDB(t)
+	p, out := newTestProgram(t, db)
+	p.Options.Models = []any{&contact{}}
+	t.Cleanup(func() { fieldenc.Register(nil) })
+	ctx := context.Background()
+
+	if err := p.Run(ctx, []string{"rotate-field-keys"}); !errors.Is(err, ErrNoFieldKeys) {
+		t.Errorf("without keys: %v", err)
+	}
+	// 上线加密之前写入的明文
+	db.Exec("INSERT INTO contacts (phone) VALUES ('13800138000'), ('')")
+
+	key := "1:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	out.Reset()
+	if err := p.Run(ctx, []string{"-fieldenc.keys", key, "rotate-field-keys", "-batch", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "active key version: v1") || !strings.Contains(out.String(), "contacts  2        1") {
+		t.Errorf("output =\n%s", out)
+	}
+	var stored string
+	db.Table("contacts").Select("phone").Where("id = 1").Scan(&stored)
+	var c contact
+	db.First(&c, 1)
+	if !strings.HasPrefix(stored, "enc:v1:") || c.Phone != "13800138000" {
+		t.Errorf("stored = %q, read = %q", stored, c.Phone)
+	}
+}
+
+func TestServe(t *testing.T) {
+	db := newTestDB(t)
+	p, _ := newTestProgram(t, db)
+	var started, stopped atomic.Bool
+	var inflight *drain.Tracker
+	router := p.Router
+	p.Router = func(ctx context.Context, env *Env) (*gin.Engine, *openapi.Builder, error) {
+		inflight = env.InFlight
+		env.Lifecycle.Append(app.Hook{
+			Name:    "worker",
+			OnStart: func(context.Context) error { started.Store(true); return nil },
+			OnStop:  func(context.Context) error { stopped.Store(true); return nil },
+		})
+		return router(ctx, env)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx, []string{"serve", "-addr", "127.0.0.1:0", "-migrate=false"}) }()
+	deadline := time.Now().Add(2 * time.Second)
+	for !started.Load() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !started.Load() || !stopped.Load() {
+		t.Errorf("started %v, stopped %v", started.Load(), stopped.Load())
+	}
+	// 关闭时开始排空，之后到达的请求返回 503；OnDrain 在单独的 goroutine 里执行
+	for deadline := time.Now().Add(time.Second); !inflight.Draining() && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !inflight.Draining() {
+		t.Error("serve did not drain in-flight tracker on shutdown")
+	}
+	if db.Migrator().HasTable(&model.User{}) {
+		t.Error("serve -migrate=false migrated the database")
+	}
+
+	if err := p.Run(context.Background(), []string{"migrate"}); err != nil || !db.Migrator().HasTable(&model.User{}) {
+		t.Errorf("migrate err = %v", err)
+	}
+}
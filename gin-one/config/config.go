@@ -0,0 +1,551 @@
+// ============================================================================
+// Package config 类型化配置加载
+// ============================================================================
+//
+// 示例里的 JWT 密钥、数据库 DSN、上传限制原本都是写死的常量，
+// 这里统一从「默认值 → 配置文件 → 环境变量 → 命令行」加载到一个 Config 结构体。
+//
+// 【优先级】(从高到低，与 examples/4_3_config_logging.go 一致)
+//
+// | 来源       | 示例                                  |
+// |------------|---------------------------------------|
+// | 命令行     | -server.addr=:9090                    |
+// | 环境变量   | APP_SERVER_ADDR=:9090                 |
+// | 配置文件   | server: {addr: ":9090"}               |
+// | 默认值     | :8080                                 |
+//
+// 环境变量名 = 前缀 + 键名大写，"." 换成 "_"：jwt.secret → APP_JWT_SECRET
+//
+// 【必填项】
+//
+// 所有字段都有校验规则，加载失败时一次列出全部错误。
+// jwt.secret 在 release 模式下必填（配置了 secrets.provider 时从密钥来源读取）；开发模式未配置时自动生成随机密钥。
+//
+// 【配置文件】
+//
+//	server:
+//	  addr: ":443"
+//	  mode: release
+//	  tls:
+//	    autocert_domains: [app.example.com]  # 或 cert_file / key_file
+//	    http_addr: ":80"                     # HTTP → HTTPS 跳转和 ACME 验证
+//	  trusted_proxies: [10.0.0.0/8]          # 负载均衡的网段，只相信它们转发的客户端 IP
+//	  public_url: https://app.example.com    # 订阅源里的绝对链接
+//	database:
+//	  driver: mysql
+//	  host: db.internal
+//	  user: app
+//	  name: app          # 密码用环境变量 APP_DATABASE_PASSWORD，不要写进文件
+//	jwt:
+//	  secret: "至少 32 字节"
+//	  access_ttl: 2h
+//	upload:
+//	  max_file_size: 10485760
+//	  download_rate: 1048576   # 每个下载连接 1MB/s
+//	storage:
+//	  driver: s3         # 默认 local，存到 storage.local.dir
+//	  s3:
+//	    endpoint: http://127.0.0.1:9000
+//	    bucket: uploads
+//	    path_style: true # 密钥用 APP_STORAGE_S3_ACCESS_KEY / APP_STORAGE_S3_SECRET_KEY
+//	scanner:
+//	  driver: clamav     # 默认 none，不扫描
+//	  clamav:
+//	    addr: 127.0.0.1:3310
+//	grpc:
+//	  addr: ":9090"
+//	  upstream: users.internal:9090  # 只做 JSON 网关，不启动本地 gRPC 服务
+//	oauth:
+//	  redirect_base: https://app.example.com
+//	  github:
+//	    client_id: Iv1.abc   # 密钥用 APP_OAUTH_GITHUB_CLIENT_SECRET
+//	signing:
+//	  key_id: internal     # 密钥用 APP_SIGNING_SECRET，和调用方共享
+//	fieldenc:
+//	  active: 2            # 默认用最大的版本；密钥用 APP_FIELDENC_KEYS=1:base64,2:base64
+//	secrets:
+//	  provider: vault      # jwt.secret、database.password 从 Vault 读取，定期刷新
+//	  vault:
+//	    addr: https://vault.internal:8200
+//	    path: app/prod     # 令牌用 APP_SECRETS_VAULT_TOKEN
+//
+// 【用法】
+//
+//	cfg, err := config.Load(config.Options{})
+//
+// 需要热加载时用 Loader，见 watch.go
+//
+// ============================================================================
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+)
+
+// Config 应用配置
+type Config struct {
+	Server   ServerConfig   `mapstructure:"server"`
+	Database DatabaseConfig `mapstructure:"database"`
+	JWT      JWTConfig      `mapstructure:"jwt"`
+	Upload   UploadConfig   `mapstructure:"upload"`
+	Storage  StorageConfig  `mapstructure:"storage"`
+	Scanner  ScannerConfig  `mapstructure:"scanner"`
+	GRPC     GRPCConfig     `mapstructure:"grpc"`
+	Log      LogConfig      `mapstructure:"log"`
+	OAuth    OAuthConfig    `mapstructure:"oauth"`
+	Signing  SigningConfig  `mapstructure:"signing"`
+	FieldEnc FieldEncConfig `mapstructure:"fieldenc"`
+	Secrets  SecretsConfig  `mapstructure:"secrets"`
+}
+
+type ServerConfig struct {
+	Addr         string        `mapstructure:"addr" validate:"required"`
+	Mode         string        `mapstructure:"mode" validate:"oneof=debug release test"`
+	ReadTimeout  time.Duration `mapstructure:"read_timeout" validate:"gte=0"`
+	WriteTimeout time.Duration `mapstructure:"write_timeout" validate:"gte=0"`
+	IdleTimeout  time.Duration `mapstructure:"idle_timeout" validate:"gte=0"`
+	// HTTP2 auto：HTTPS 上自动协商；off：只用 HTTP/1.1；h2c：明文端口也接受 HTTP/2
+	HTTP2 string          `mapstructure:"http2" validate:"oneof=auto off h2c"`
+	TLS   ServerTLSConfig `mapstructure:"tls"`
+	// TrustedProxies 可信代理的 IP 或 CIDR，只相信它们转发的 X-Forwarded-For / X-Forwarded-Proto，见 realip、secure
+	TrustedProxies []string `mapstructure:"trusted_proxies" validate:"dive,cidr|ip"`
+	// PublicURL 对外的协议 + 域名，订阅源等需要绝对链接的地方用它拼接，不读请求的 Host 头
+	PublicURL string `mapstructure:"public_url" validate:"required,url"`
+}
+
+// ServerTLSConfig HTTPS，见 server.TLSConfig；cert_file 和 autocert_domains 都为空时只提供明文 HTTP
+type ServerTLSConfig struct {
+	CertFile         string   `mapstructure:"cert_file" validate:"required_with=KeyFile"`
+	KeyFile          string   `mapstructure:"key_file" validate:"required_with=CertFile"`
+	AutocertDomains  []string `mapstructure:"autocert_domains" validate:"dive,hostname"`
+	AutocertCacheDir string   `mapstructure:"autocert_cache_dir"`
+	AutocertEmail    string   `mapstructure:"autocert_email" validate:"omitempty,email"`
+	// HTTPAddr 跳转和 ACME 验证的明文端口，"-" 表示不监听
+	HTTPAddr         string        `mapstructure:"http_addr"`
+	HTTPReadTimeout  time.Duration `mapstructure:"http_read_timeout" validate:"gte=0"`
+	HTTPWriteTimeout time.Duration `mapstructure:"http_write_timeout" validate:"gte=0"`
+	HTTPIdleTimeout  time.Duration `mapstructure:"http_idle_timeout" validate:"gte=0"`
+}
+
+// DatabaseConfig 数据库配置，DSN 为空时由 database.BuildDSN 用连接字段拼接
+type DatabaseConfig struct {
+	Driver   string `mapstructure:"driver" validate:"oneof=sqlite mysql postgres"`
+	DSN      string `mapstructure:"dsn"`  // 完整连接串，非空时忽略下面的连接字段
+	Host     string `mapstructure:"host"` // mysql / postgres 必填，见 Validate
+	Port     int    `mapstructure:"port" validate:"gte=0,lte=65535"`
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+	Name     string `mapstructure:"name" validate:"required_without=DSN"` // 数据库名，sqlite 为文件路径
+
+	// 连接池，0 表示使用驱动默认值（database.DefaultPool）
+	MaxOpenConns    int           `mapstructure:"max_open_conns" validate:"gte=0"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns" validate:"gte=0"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime" validate:"gte=0"`
+
+	ConnectRetries int           `mapstructure:"connect_retries" validate:"gte=0"`
+	ConnectBackoff time.Duration `mapstructure:"connect_backoff" validate:"gte=0"`
+
+	// Replicas 只读副本的完整 DSN，驱动与主库相同；为空时不做读写分离
+	Replicas []string `mapstructure:"replicas" validate:"dive,required"`
+}
+
+type JWTConfig struct {
+	Secret     string        `mapstructure:"secret" validate:"omitempty,min=32"` // release 模式必填，见 Validate
+	AccessTTL  time.Duration `mapstructure:"access_ttl" validate:"gt=0"`
+	RefreshTTL time.Duration `mapstructure:"refresh_ttl" validate:"gtfield=AccessTTL"`
+}
+
+type UploadConfig struct {
+	MaxFileSize int64 `mapstructure:"max_file_size" validate:"gt=0"` // 单文件上限（字节）
+	MaxBodySize int64 `mapstructure:"max_body_size" validate:"gtefield=MaxFileSize"`
+	// DownloadRate 单个下载连接的限速（字节/秒），0 表示不限
+	DownloadRate int64 `mapstructure:"download_rate" validate:"gte=0"`
+}
+
+// StorageConfig 文件存储后端，见 storage.Open
+type StorageConfig struct {
+	Driver string `mapstructure:"driver" validate:"oneof=local s3"`
+	// Secret 本地存储下载链接的签名密钥，未配置时自动生成：重启后旧链接失效，
+	// 多实例之间互相不认对方签的链接，生产环境应该配置
+	Secret string       `mapstructure:"secret"`
+	Local  LocalStorage `mapstructure:"local"`
+	S3     S3Storage    `mapstructure:"s3"`
+}
+
+type LocalStorage struct {
+	Dir     string `mapstructure:"dir" validate:"required"`
+	BaseURL string `mapstructure:"base_url" validate:"required,startswith=/"` // 签名下载链接的路由前缀
+}
+
+// S3Storage S3 兼容存储（AWS S3 / MinIO），driver=s3 时 endpoint、bucket、密钥必填
+type S3Storage struct {
+	Endpoint  string `mapstructure:"endpoint" validate:"omitempty,url"`
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	PathStyle bool   `mapstructure:"path_style"` // MinIO 需要 true
+}
+
+// ScannerConfig 上传文件病毒扫描，见 scanner.Open
+type ScannerConfig struct {
+	Driver        string        `mapstructure:"driver" validate:"oneof=none clamav"`
+	QuarantineDir string        `mapstructure:"quarantine_dir" validate:"required"` // 感染文件移到这里，不提供下载
+	ClamAV        ClamAVScanner `mapstructure:"clamav"`
+}
+
+type ClamAVScanner struct {
+	Addr    string        `mapstructure:"addr" validate:"required"` // clamd 的 TCP 地址
+	Timeout time.Duration `mapstructure:"timeout" validate:"gt=0"`
+}
+
+// GRPCConfig gRPC 服务，见 grpcapi 包
+type GRPCConfig struct {
+	Addr     string `mapstructure:"addr" validate:"required"`
+	Upstream string `mapstructure:"upstream"` // 非空时只做 JSON 网关，请求转发到这个 gRPC 地址
+}
+
+// OAuthConfig 第三方登录，client_id 为空的提供方不启用，见 oauth 包
+type OAuthConfig struct {
+	// RedirectBase 回调地址前缀（对外的协议 + 域名），回调为 RedirectBase/auth/oauth/<provider>/callback
+	RedirectBase string      `mapstructure:"redirect_base" validate:"required,url"`
+	Google       OAuthClient `mapstructure:"google"`
+	GitHub       OAuthClient `mapstructure:"github"`
+}
+
+// OAuthClient 在提供方控制台注册应用后得到的凭据，密钥用环境变量注入
+type OAuthClient struct {
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret" validate:"required_with=ClientID"`
+}
+
+// SigningConfig 内部服务调用的 HMAC 请求签名，见 auth/signing；secret 为空时不启用
+type SigningConfig struct {
+	// KeyID 共享密钥的名字，调用方放在 X-Signature-Key 里
+	KeyID     string        `mapstructure:"key_id" validate:"required"`
+	Secret    string        `mapstructure:"secret" validate:"omitempty,min=32"`
+	Tolerance time.Duration `mapstructure:"tolerance" validate:"gte=0"`
+}
+
+// FieldEncConfig 敏感列加密的主密钥，见 crypto/fieldenc；keys 为空时不启用
+type FieldEncConfig struct {
+	// Keys 每项为 "版本号:base64(32 字节密钥)"，轮换期间新旧版本同时保留
+	Keys []string `mapstructure:"keys" validate:"dive,required"`
+	// Active 加密新值用的版本，0 表示 Keys 里最大的版本
+	Active uint32 `mapstructure:"active"`
+}
+
+// SecretsConfig 运行时读取的密钥来源，见 secrets 包；provider 为 none 时只用上面的配置值
+type SecretsConfig struct {
+	Provider string        `mapstructure:"provider" validate:"oneof=none env file vault"`
+	TTL      time.Duration `mapstructure:"ttl" validate:"gt=0"` // 缓存有效期，也是后台刷新的间隔
+	Dir      string        `mapstructure:"dir" validate:"required"`
+	Vault    VaultSecrets  `mapstructure:"vault"`
+}
+
+// VaultSecrets HashiCorp Vault KV v2，provider 为 vault 时 addr 和 token 必填
+type VaultSecrets struct {
+	Addr      string `mapstructure:"addr" validate:"omitempty,url"`
+	Token     string `mapstructure:"token"`
+	Mount     string `mapstructure:"mount" validate:"required"`
+	Path      string `mapstructure:"path"`
+	Namespace string `mapstructure:"namespace"`
+}
+
+type LogConfig struct {
+	Level  string `mapstructure:"level" validate:"oneof=debug info warn error"`
+	Format string `mapstructure:"format" validate:"oneof=json text"`
+	Stdout bool   `mapstructure:"stdout"`
+	// File 为空时不写文件；Stdout 和 File 至少要有一个
+	File       string        `mapstructure:"file" validate:"required_without=Stdout"`
+	MaxSizeMB  int           `mapstructure:"max_size_mb" validate:"gte=1"`
+	MaxAge     time.Duration `mapstructure:"max_age" validate:"gte=0"`
+	MaxBackups int           `mapstructure:"max_backups" validate:"gte=0"`
+	Compress   bool          `mapstructure:"compress"`
+}
+
+// keys 所有配置项及默认值，同时用来注册命令行参数
+// viper 只会为已知的键读取环境变量，所以没有默认值的必填项也要列在这里
+var keys = []struct {
+	name  string
+	value any
+	usage string
+}{
+	{"server.addr", ":8080", "监听地址"},
+	{"server.mode", "debug", "运行模式 debug/release/test"},
+	{"server.read_timeout", 10 * time.Second, "读超时"},
+	{"server.write_timeout", 30 * time.Second, "写超时"},
+	{"server.idle_timeout", 60 * time.Second, "keep-alive 连接空闲超时"},
+	{"server.http2", "auto", "HTTP/2 模式 auto/off/h2c"},
+	{"server.tls.cert_file", "", "TLS 证书文件（PEM），为空时不启用 HTTPS"},
+	{"server.tls.key_file", "", "TLS 私钥文件（PEM）"},
+	{"server.tls.autocert_domains", []string{}, "自动申请证书的域名，逗号分隔，与 cert_file 二选一"},
+	{"server.tls.autocert_cache_dir", "./certs", "自动证书缓存目录"},
+	{"server.tls.autocert_email", "", "ACME 账号联系邮箱"},
+	{"server.tls.http_addr", ":80", "HTTPS 时跳转和 ACME 验证的明文端口，- 表示不监听"},
+	{"server.tls.http_read_timeout", 5 * time.Second, "明文端口读超时"},
+	{"server.tls.http_write_timeout", 10 * time.Second, "明文端口写超时"},
+	{"server.tls.http_idle_timeout", 30 * time.Second, "明文端口空闲超时"},
+	{"server.trusted_proxies", []string{}, "可信代理的 IP 或 CIDR，逗号分隔"},
+	{"server.public_url", "http://localhost:8080", "对外访问地址（协议 + 域名），用于生成绝对链接"},
+	{"database.driver", "sqlite", "数据库驱动"},
+	{"database.dsn", "", "完整连接串，设置后忽略 host/user/name 等字段"},
+	{"database.host", "", "数据库主机（mysql/postgres）"},
+	{"database.port", 0, "数据库端口，0 表示驱动默认端口"},
+	{"database.user", "", "数据库用户名"},
+	{"database.password", "", "数据库密码"},
+	{"database.name", "test.db", "数据库名，sqlite 为文件路径"},
+	{"database.max_open_conns", 0, "最大打开连接数，0 表示驱动默认值"},
+	{"database.max_idle_conns", 0, "最大空闲连接数，0 表示驱动默认值"},
+	{"database.conn_max_lifetime", time.Duration(0), "连接最大存活时间，0 表示驱动默认值"},
+	{"database.connect_retries", 3, "启动时连接失败的重试次数"},
+	{"database.connect_backoff", time.Second, "第一次重试的等待上限，之后每次翻倍（实际等待随机）"},
+	{"database.replicas", []string{}, "只读副本 DSN，逗号分隔"},
+	{"jwt.secret", "", "JWT 签名密钥（至少 32 字节，release 模式必填）"},
+	{"jwt.access_ttl", 2 * time.Hour, "Access Token 有效期"},
+	{"jwt.refresh_ttl", 7 * 24 * time.Hour, "Refresh Token 有效期"},
+	{"upload.max_file_size", int64(10 << 20), "单文件大小上限（字节）"},
+	{"upload.max_body_size", int64(50 << 20), "请求体大小上限（字节）"},
+	{"upload.download_rate", int64(0), "单个下载连接限速（字节/秒），0 不限"},
+	{"storage.driver", "local", "文件存储后端 local/s3"},
+	{"storage.secret", "", "本地存储下载链接签名密钥，为空时自动生成"},
+	{"storage.local.dir", "./uploads", "本地存储目录"},
+	{"storage.local.base_url", "/files", "本地存储下载路由前缀"},
+	{"storage.s3.endpoint", "", "S3 服务地址，如 http://127.0.0.1:9000"},
+	{"storage.s3.region", "us-east-1", "S3 区域"},
+	{"storage.s3.bucket", "", "S3 存储桶"},
+	{"storage.s3.access_key", "", "S3 Access Key"},
+	{"storage.s3.secret_key", "", "S3 Secret Key"},
+	{"storage.s3.path_style", false, "使用路径风格 URL（MinIO 需要）"},
+	{"scanner.driver", "none", "上传文件扫描 none/clamav"},
+	{"scanner.quarantine_dir", "./quarantine", "感染文件隔离目录"},
+	{"scanner.clamav.addr", "127.0.0.1:3310", "clamd TCP 地址"},
+	{"scanner.clamav.timeout", 30 * time.Second, "单个文件扫描超时"},
+	{"grpc.addr", ":9090", "gRPC 监听地址"},
+	{"grpc.upstream", "", "网关模式：JSON 请求转发到这个 gRPC 地址，不启动本地 gRPC 服务"},
+	{"log.level", "info", "日志级别"},
+	{"log.format", "json", "日志格式 json/text"},
+	{"log.stdout", true, "日志输出到标准输出"},
+	{"log.file", "", "日志文件路径，如 logs/app.log，为空时不写文件"},
+	{"log.max_size_mb", 100, "单个日志文件大小上限（MB），超过后轮转"},
+	{"log.max_age", time.Duration(0), "轮转出的日志文件保留时间，0 表示不按时间删除"},
+	{"log.max_backups", 0, "最多保留的轮转文件个数，0 表示不限"},
+	{"log.compress", false, "gzip 压缩轮转出的日志文件"},
+	{"oauth.redirect_base", "http://localhost:8080", "OAuth 回调地址前缀（对外的协议 + 域名）"},
+	{"oauth.google.client_id", "", "Google OAuth Client ID，为空时不启用"},
+	{"oauth.google.client_secret", "", "Google OAuth Client Secret"},
+	{"oauth.github.client_id", "", "GitHub OAuth Client ID，为空时不启用"},
+	{"oauth.github.client_secret", "", "GitHub OAuth Client Secret"},
+	{"signing.key_id", "internal", "内部服务调用签名的密钥 ID"},
+	{"signing.secret", "", "内部服务调用签名的共享密钥，至少 32 字节，为空时不启用"},
+	{"signing.tolerance", 5 * time.Minute, "签名时间戳允许的偏差"},
+	{"fieldenc.keys", []string{}, "敏感列加密主密钥，版本号:base64，逗号分隔，为空时不启用"},
+	{"fieldenc.active", uint32(0), "加密新值用的密钥版本，0 表示最大的版本"},
+	{"secrets.provider", "none", "密钥来源 none/env/file/vault"},
+	{"secrets.ttl", 5 * time.Minute, "密钥缓存有效期和刷新间隔"},
+	{"secrets.dir", "/run/secrets", "file：密钥文件所在目录，文件名即密钥名（如 jwt.secret）"},
+	{"secrets.vault.addr", "", "vault：服务地址，如 https://vault.internal:8200"},
+	{"secrets.vault.token", "", "vault：访问令牌"},
+	{"secrets.vault.mount", "secret", "vault：KV v2 挂载路径"},
+	{"secrets.vault.path", "", "vault：本应用的路径前缀，如 app/prod"},
+	{"secrets.vault.namespace", "", "vault：命名空间（企业版）"},
+}
+
+// Options 加载选项
+type Options struct {
+	// File 配置文件路径，可被 -config 参数覆盖
+	// 为空时依次查找 ./config.yaml、./configs/config.yaml，都不存在则不读文件
+	File string
+
+	// EnvPrefix 环境变量前缀，默认 APP
+	EnvPrefix string
+
+	// Args 命令行参数，nil 时使用 os.Args[1:]
+	Args []string
+
+	// Logger 热加载日志，默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// ErrHelp 命令行带 -h / -help，用法已经打印
+var ErrHelp = flag.ErrHelp
+
+// Load 加载并校验配置
+func Load(opts Options) (*Config, error) {
+	l, err := NewLoader(opts)
+	if err != nil {
+		return nil, err
+	}
+	return l.Get(), nil
+}
+
+// source 解析命令行后确定下来的输入，热加载时复用，只重新读文件和环境变量
+type source struct {
+	file      string
+	envPrefix string
+	flags     map[string]string
+	devSecret string // 开发模式下自动生成的 JWT 密钥，热加载时保持不变
+
+	devStorageSecret string // 同上，本地存储的签名密钥，不和 JWT 共用
+
+	args []string // 参数之后的位置参数，如子命令名
+}
+
+func parseSource(opts Options) (*source, error) {
+	args := opts.Args
+	if args == nil {
+		args = os.Args[1:]
+	}
+	// 与 13_stdlib.go 的 flag 演示相同，但用独立的 FlagSet，不污染全局 flag.CommandLine
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	file := fs.String("config", opts.File, "配置文件路径")
+	for _, k := range keys {
+		fs.String(k.name, fmt.Sprint(k.value), k.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	src := &source{file: *file, envPrefix: opts.EnvPrefix, flags: map[string]string{}, args: fs.Args()}
+	if src.envPrefix == "" {
+		src.envPrefix = "APP"
+	}
+	src.devSecret = randomHex(32)
+	src.devStorageSecret = randomHex(32)
+	// 只记录显式传入的参数，否则参数的默认值会盖过环境变量和配置文件
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			src.flags[f.Name] = f.Value.String()
+		}
+	})
+	if src.file == "" {
+		for _, p := range []string{"config.yaml", "configs/config.yaml"} {
+			if _, err := os.Stat(p); err == nil {
+				src.file = p
+				break
+			}
+		}
+	}
+	return src, nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// load 每次都用新的 viper 实例，不依赖全局状态，热加载时也不会残留旧值
+func (s *source) load() (*Config, error) {
+	v := viper.New()
+	for _, k := range keys {
+		v.SetDefault(k.name, k.value)
+	}
+
+	if s.file != "" {
+		v.SetConfigFile(s.file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("config: read %s: %w", s.file, err)
+		}
+	}
+
+	v.SetEnvPrefix(s.envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	for name, value := range s.flags {
+		v.Set(name, value)
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := Validate(&cfg); err != nil {
+		return nil, err
+	}
+	// 非 release 模式没配密钥时生成随机密钥，示例可以直接 go run；重启后已签发的 Token 失效
+	if cfg.JWT.Secret == "" {
+		cfg.JWT.Secret = s.devSecret
+	}
+	if cfg.Storage.Secret == "" {
+		cfg.Storage.Secret = s.devStorageSecret
+	}
+	return &cfg, nil
+}
+
+var validate = func() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// 错误信息里使用配置键名（jwt.secret），而不是 Go 字段名
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		return f.Tag.Get("mapstructure")
+	})
+	// 生产环境不允许使用自动生成的密钥：多实例之间不一致，重启后所有 Token 失效
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		cfg := sl.Current().Interface().(Config)
+		if cfg.Server.Mode == "release" && cfg.JWT.Secret == "" && cfg.Secrets.Provider == "none" {
+			sl.ReportError(cfg.JWT.Secret, "jwt.secret", "Secret", "required", "")
+		}
+		// 没有完整 DSN 时，mysql / postgres 必须知道连哪台主机
+		if db := cfg.Database; db.DSN == "" && db.Driver != "sqlite" && db.Host == "" {
+			sl.ReportError(db.Host, "database.host", "Host", "required", "")
+		}
+		// 证书文件和自动证书二选一（autocert_domains 默认是空切片，excluded_with 会把它当作已设置）
+		if tc := cfg.Server.TLS; tc.CertFile != "" && len(tc.AutocertDomains) > 0 {
+			sl.ReportError(tc.CertFile, "server.tls.cert_file", "CertFile", "excluded_with", "autocert_domains")
+		}
+		if st := cfg.Storage; st.Driver == "s3" {
+			for _, f := range []struct{ key, field, value string }{
+				{"storage.s3.endpoint", "Endpoint", st.S3.Endpoint},
+				{"storage.s3.bucket", "Bucket", st.S3.Bucket},
+				{"storage.s3.access_key", "AccessKey", st.S3.AccessKey},
+				{"storage.s3.secret_key", "SecretKey", st.S3.SecretKey},
+			} {
+				if f.value == "" {
+					sl.ReportError(f.value, f.key, f.field, "required", "")
+				}
+			}
+		}
+		if vs := cfg.Secrets.Vault; cfg.Secrets.Provider == "vault" {
+			if vs.Addr == "" {
+				sl.ReportError(vs.Addr, "secrets.vault.addr", "Addr", "required", "")
+			}
+			if vs.Token == "" {
+				sl.ReportError(vs.Token, "secrets.vault.token", "Token", "required", "")
+			}
+		}
+	}, Config{})
+	return v
+}()
+
+// Validate 校验配置，一次返回所有不合法的字段
+func Validate(cfg *Config) error {
+	err := validate.Struct(cfg)
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	msgs := make([]string, len(verrs))
+	for i, e := range verrs {
+		// Namespace 形如 Config.jwt.secret，去掉根类型名
+		key := e.Namespace()[strings.IndexByte(e.Namespace(), '.')+1:]
+		if e.Param() != "" {
+			msgs[i] = fmt.Sprintf("%s: %s=%s", key, e.Tag(), e.Param())
+		} else {
+			msgs[i] = fmt.Sprintf("%s: %s", key, e.Tag())
+		}
+	}
+	return fmt.Errorf("config: invalid values: %s", strings.Join(msgs, "; "))
+}
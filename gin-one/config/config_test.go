@@ -0,0 +1,266 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	// 先写临时文件再改名，和编辑器、ConfigMap 的替换方式一样
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPriority(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, file, `
+server:
+  addr: ":7000"
+  mode: release
+database:
+  dsn: "file.db"
+jwt:
+  secret: "`+testSecret+`"
+  access_ttl: 30m
+log:
+  level: warn
+`)
+	t.Setenv("TEST_DATABASE_DSN", "env.db")
+	t.Setenv("TEST_LOG_LEVEL", "debug")
+
+	cfg, err := Load(Options{
+		File:      file,
+		EnvPrefix: "TEST",
+		Args:      []string{"-log.level=error"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"default", cfg.Upload.MaxFileSize, int64(10 << 20)},
+		{"default duration", cfg.JWT.RefreshTTL, 7 * 24 * time.Hour},
+		{"file", cfg.Server.Addr, ":7000"},
+		{"file duration", cfg.JWT.AccessTTL, 30 * time.Minute},
+		{"env over file", cfg.Database.DSN, "env.db"},
+		{"flag over env", cfg.Log.Level, "error"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v; want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("APP_JWT_SECRET", testSecret)
+	t.Setenv("APP_UPLOAD_MAX_FILE_SIZE", "1024")
+	t.Setenv("APP_DATABASE_REPLICAS", "r1.db,r2.db")
+
+	cfg, err := Load(Options{Args: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Upload.MaxFileSize != 1024 {
+		t.Errorf("MaxFileSize = %d; want 1024", cfg.Upload.MaxFileSize)
+	}
+	if got := cfg.Database.Replicas; len(got) != 2 || got[1] != "r2.db" {
+		t.Errorf("Replicas = %q; want [r1.db r2.db]", got)
+	}
+	if cfg.Server.Addr != ":8080" {
+		t.Errorf("Addr = %q; want :8080", cfg.Server.Addr)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{"missing secret in release", map[string]string{"APP_SERVER_MODE": "release"}, []string{"jwt.secret: required"}},
+		{"short secret", map[string]string{"APP_JWT_SECRET": "short"}, []string{"jwt.secret: min=32"}},
+		{"mysql without host", map[string]string{"APP_DATABASE_DRIVER": "mysql"}, []string{"database.host: required"}},
+		{
+			"s3 without credentials",
+			map[string]string{"APP_STORAGE_DRIVER": "s3", "APP_STORAGE_S3_BUCKET": "uploads"},
+			[]string{"storage.s3.endpoint: required", "storage.s3.access_key: required", "storage.s3.secret_key: required"},
+		},
+		{"unknown storage driver", map[string]string{"APP_STORAGE_DRIVER": "ftp"}, []string{"storage.driver: oneof"}},
+		{"unknown scanner driver", map[string]string{"APP_SCANNER_DRIVER": "virustotal"}, []string{"scanner.driver: oneof"}},
+		{
+			"cert file and autocert together",
+			map[string]string{
+				"APP_SERVER_TLS_CERT_FILE":        "cert.pem",
+				"APP_SERVER_TLS_KEY_FILE":         "key.pem",
+				"APP_SERVER_TLS_AUTOCERT_DOMAINS": "app.example.com",
+			},
+			[]string{"server.tls.cert_file: excluded_with=autocert_domains"},
+		},
+		{"cert without key", map[string]string{"APP_SERVER_TLS_CERT_FILE": "cert.pem"}, []string{"server.tls.key_file: required_with"}},
+		{"unknown http2 mode", map[string]string{"APP_SERVER_HTTP2": "yes"}, []string{"server.http2: oneof"}},
+		{"vault without addr and token", map[string]string{"APP_SECRETS_PROVIDER": "vault"}, []string{"secrets.vault.addr: required", "secrets.vault.token: required"}},
+		{"unknown secrets provider", map[string]string{"APP_SECRETS_PROVIDER": "aws"}, []string{"secrets.provider: oneof"}},
+		{"invalid trusted proxy", map[string]string{"APP_SERVER_TRUSTED_PROXIES": "10.0.0.0/8,proxy.internal"}, []string{"server.trusted_proxies[1]: cidr|ip"}},
+		{"public url without scheme", map[string]string{"APP_SERVER_PUBLIC_URL": "app.example.com"}, []string{"server.public_url: url"}},
+		{
+			"several errors",
+			map[string]string{
+				"APP_JWT_SECRET":           testSecret,
+				"APP_SERVER_MODE":          "prod",
+				"APP_JWT_REFRESH_TTL":      "1m",
+				"APP_UPLOAD_MAX_BODY_SIZE": "1",
+			},
+			[]string{"server.mode: oneof", "jwt.refresh_ttl: gtfield", "upload.max_body_size: gtefield"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := Load(Options{Args: []string{}})
+			if err == nil {
+				t.Fatal("Load() error = nil; want validation error")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q; want it to contain %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestDevSecret(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	l, err := NewLoader(Options{Args: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, storageSecret := l.Get().JWT.Secret, l.Get().Storage.Secret
+	if len(secret) != 64 {
+		t.Fatalf("generated secret %q; want 64 hex chars", secret)
+	}
+	if err := l.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Get().JWT.Secret; got != secret {
+		t.Errorf("secret changed after reload: %q -> %q", secret, got)
+	}
+	if got := l.Get().Storage.Secret; got != storageSecret || got == secret {
+		t.Errorf("storage secret = %q; want stable and different from jwt secret", got)
+	}
+}
+
+func TestFlags(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	_, err := Load(Options{Args: []string{"-h"}})
+	if !errors.Is(err, ErrHelp) {
+		t.Errorf("-h: error = %v; want ErrHelp", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "custom.yaml")
+	writeFile(t, file, "jwt:\n  secret: "+testSecret+"\nserver:\n  addr: \":9000\"\n")
+	cfg, err := Load(Options{Args: []string{"-config", file}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Addr != ":9000" {
+		t.Errorf("Addr = %q; want :9000", cfg.Server.Addr)
+	}
+
+	// 子命令和它的参数不被配置参数解析
+	l, err := NewLoader(Options{Args: []string{"-server.addr", ":9001", "migrate", "-dry-run"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(l.Args(), " "); got != "migrate -dry-run" || l.Get().Server.Addr != ":9001" {
+		t.Errorf("Args = %q, Addr = %q", got, l.Get().Server.Addr)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, file, "jwt:\n  secret: "+testSecret+"\nlog:\n  level: info\n")
+
+	l, err := NewLoader(Options{
+		File:   file,
+		Args:   []string{},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan string, 10)
+	l.OnChange(func(old, cur *Config) {
+		changes <- old.Log.Level + "->" + cur.Log.Level
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Watch(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Watch() = %v", err)
+		}
+	}()
+	time.Sleep(50 * time.Millisecond) // 等待 watcher 就绪
+
+	writeFile(t, file, "jwt:\n  secret: "+testSecret+"\nlog:\n  level: debug\n")
+	select {
+	case got := <-changes:
+		if got != "info->debug" {
+			t.Errorf("change = %q; want info->debug", got)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no reload after file change")
+	}
+
+	// 非法配置被拒绝，保留旧配置
+	writeFile(t, file, "jwt:\n  secret: short\nlog:\n  level: warn\n")
+	select {
+	case got := <-changes:
+		t.Errorf("unexpected change %q for invalid config", got)
+	case <-time.After(500 * time.Millisecond):
+	}
+	if got := l.Get().Log.Level; got != "debug" {
+		t.Errorf("Log.Level = %q; want debug (previous config)", got)
+	}
+}
+
+func TestWatchWithoutFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("APP_JWT_SECRET", testSecret)
+	l, err := NewLoader(Options{Args: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Watch(context.Background()); err == nil {
+		t.Error("Watch() without file = nil; want error")
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ============================================================================
+// 热加载
+// ============================================================================
+//
+// 【用法】
+//
+//	loader, err := config.NewLoader(config.Options{})
+//	loader.OnChange(func(old, cur *config.Config) {
+//		if old.Log.Level != cur.Log.Level { ... }
+//	})
+//	go loader.Watch(ctx)
+//
+//	// 处理请求时读取最新配置
+//	limit := loader.Get().Upload.MaxFileSize
+//
+// 【注意】
+//
+// 1. 新配置校验失败时保留旧配置，只记日志，不会让服务带着坏配置运行
+// 2. 监听的是文件所在目录：vim 等编辑器、K8s ConfigMap 都是「写新文件再改名」，
+//    直接监听文件会在第一次替换后丢失
+// 3. server.addr、database.dsn 等启动时使用的配置，改了也要重启才生效，
+//    适合热加载的是日志级别、上传限制这类每次请求都会读取的值
+//
+
+// debounce 编辑器保存一次常常触发多个事件，合并成一次加载
+const debounce = 100 * time.Millisecond
+
+// Loader 持有当前配置，支持热加载
+type Loader struct {
+	src    *source
+	logger *slog.Logger
+	cur    atomic.Pointer[Config]
+
+	mu        sync.Mutex
+	listeners []func(old, cur *Config)
+}
+
+// NewLoader 解析命令行并完成首次加载
+func NewLoader(opts Options) (*Loader, error) {
+	src, err := parseSource(opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := src.load()
+	if err != nil {
+		return nil, err
+	}
+	l := &Loader{src: src, logger: opts.Logger}
+	if l.logger == nil {
+		l.logger = slog.Default()
+	}
+	l.cur.Store(cfg)
+	return l, nil
+}
+
+// Get 返回当前配置，调用方不要修改返回值
+func (l *Loader) Get() *Config {
+	return l.cur.Load()
+}
+
+// File 实际使用的配置文件路径，没有配置文件时为空
+func (l *Loader) File() string {
+	return l.src.file
+}
+
+// Args 配置参数之后的位置参数：app -config prod.yaml migrate -dry-run 返回 [migrate -dry-run]
+func (l *Loader) Args() []string {
+	return l.src.args
+}
+
+// OnChange 注册配置变化回调，只在重新加载成功且内容有变化时调用
+func (l *Loader) OnChange(fn func(old, cur *Config)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listeners = append(l.listeners, fn)
+}
+
+// Reload 立即重新加载，失败时保留当前配置
+// 除了文件监听，也可以在收到 SIGHUP 时手动调用
+func (l *Loader) Reload() error {
+	cfg, err := l.src.load()
+	if err != nil {
+		return err
+	}
+	old := l.cur.Swap(cfg)
+	if reflect.DeepEqual(old, cfg) {
+		return nil
+	}
+
+	l.mu.Lock()
+	listeners := append([]func(old, cur *Config){}, l.listeners...)
+	l.mu.Unlock()
+	for _, fn := range listeners {
+		fn(old, cfg)
+	}
+	return nil
+}
+
+// Watch 监听配置文件变化并自动重新加载，阻塞到 ctx 取消
+func (l *Loader) Watch(ctx context.Context) error {
+	if l.src.file == "" {
+		return errors.New("config: no config file to watch")
+	}
+	file, err := filepath.Abs(l.src.file)
+	if err != nil {
+		return err
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := w.Add(filepath.Dir(file)); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(ev.Name) == file && !ev.Has(fsnotify.Chmod) {
+				timer.Reset(debounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			l.logger.Warn("config watcher error", "error", err)
+		case <-timer.C:
+			if err := l.Reload(); err != nil {
+				l.logger.Error("config reload failed, keeping previous config", "file", file, "error", err)
+				continue
+			}
+			l.logger.Info("config reloaded", "file", file)
+		}
+	}
+}
@@ -0,0 +1,479 @@
+// ============================================================================
+// Package crudgen 管理后台 CRUD 脚手架：用反射读取 GORM 模型的字段和标签，一次注册五个接口
+// ============================================================================
+//
+// 【用法】
+//
+//	tags := crudgen.New(db, crudgen.Config[model.Tag]{})
+//	crudgen.Register(admin.Group("/tags"), tags)
+//
+//	GET    /tags?page=1&page_size=20&name=go&sort=-id&q=lang  列表：过滤、搜索、排序、分页
+//	GET    /tags/:id                                          单条
+//	POST   /tags                                              创建
+//	PATCH  /tags/:id                                          部分更新，只改请求体里出现的字段
+//	DELETE /tags/:id                                          删除（嵌入 gorm.Model 时为软删除）
+//
+// 【字段规则】
+//
+// New 时用 reflect 遍历模型字段（含嵌入的 gorm.Model），结合 GORM 解析出的列信息和标签决定每个字段能做什么：
+//
+// | 来源                                   | 效果                                               |
+// |----------------------------------------|----------------------------------------------------|
+// | json 标签                              | 请求体、过滤参数、排序参数都用 json 名，"-" 不暴露 |
+// | 主键、created_at / updated_at、deleted_at | 只读，请求体里出现时返回 read_only               |
+// | 关联字段（Posts []Post 等）            | 只随 Preload 输出，不能写入                        |
+// | binding:"required,max=50"              | 创建和更新后用 gin 的校验器整体校验                |
+// | crud:"readonly"                        | 只读                                               |
+// | crud:"filter"                          | ?name=go 精确匹配，?status=a,b 为 IN               |
+// | crud:"sort"                            | ?sort=name,-id，主键总是可以排序                   |
+// | crud:"search"                          | ?q= 对这些字符串字段做 LIKE 模糊匹配               |
+//
+// 标签写错（不认识的选项、filter 放在不支持的类型上、字段名和保留参数冲突）在 New 时 panic，
+// 启动就能发现，而不是等到某个请求才报错。
+//
+// 【为什么不直接 ShouldBindJSON(&row)】
+//
+// 直接绑定到模型，客户端传 {"id": 1, "created_at": "..."} 就能改主键和时间戳，
+// 更新时也分不清「没传」和「传了零值」。这里先解码成 map[string]json.RawMessage，
+// 逐个字段检查是否可写再赋值，更新时只 Select 出现过的列，零值也能写进去。
+//
+// 通过模型执行 Create / Updates / Delete，audit.Plugin 会照常记录审计日志。
+//
+// ============================================================================
+package crudgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 错误定义
+var (
+	ErrNotFound = errors.New("crudgen: record not found")
+	ErrConflict = errors.New("crudgen: duplicate key")
+	ErrInUse    = errors.New("crudgen: record is still referenced")
+	ErrInvalid  = errors.New("crudgen: invalid input")
+)
+
+// ValidationError 字段级错误，Fields 为 json 字段名 → 失败原因（binding 规则名或 unknown / read_only / type）
+// errors.Is(err, ErrInvalid) 为 true
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	names := slices.Sorted(maps.Keys(e.Fields))
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + ": " + e.Fields[name]
+	}
+	return "crudgen: invalid fields: " + strings.Join(parts, ", ")
+}
+
+func (e *ValidationError) Unwrap() error { return ErrInvalid }
+
+// 列表查询的保留参数，不能用作 filter 字段名
+var reserved = []string{"page", "page_size", "cursor", "sort", "q"}
+
+// Config 资源配置，钩子和写操作在同一个事务里执行，返回错误即取消
+type Config[T any] struct {
+	// Preload 查询时预加载的关联，如 []string{"User"}
+	Preload []string
+
+	// DefaultSort 没有 ?sort 时的排序，格式同 ?sort，默认按主键倒序（最新的在前）
+	DefaultSort string
+
+	// BeforeSave 创建和更新时，赋值并通过 binding 校验后调用，用来做跨字段或查库的业务校验
+	BeforeSave func(tx *gorm.DB, row *T) error
+
+	// BeforeDelete 删除前调用，通常用来检查是否还被引用，被引用时返回 ErrInUse
+	BeforeDelete func(tx *gorm.DB, row *T) error
+}
+
+// field 一个数据库列对应的字段
+type field struct {
+	name     string // Go 字段名
+	json     string // 请求 / 响应中的名字
+	column   string
+	typ      reflect.Type
+	writable bool
+	filter   bool
+	sort     bool
+	search   bool
+}
+
+// Resource 某个模型的 CRUD
+type Resource[T any] struct {
+	db     *gorm.DB
+	cfg    Config[T]
+	pk     *field
+	fields map[string]*field // json 名 → 字段
+	search []*field
+	sort   []order
+}
+
+// order 一个排序项
+type order struct {
+	column string
+	desc   bool
+}
+
+// New 解析模型 T 的字段，T 必须是结构体且只有一个主键；标签写错时 panic
+func New[T any](db *gorm.DB, cfg Config[T]) *Resource[T] {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("crudgen: %s is not a struct", t))
+	}
+	s, err := schema.Parse(new(T), &sync.Map{}, db.NamingStrategy)
+	if err != nil {
+		panic(fmt.Sprintf("crudgen: parse %s: %v", t, err))
+	}
+	if len(s.PrimaryFields) != 1 {
+		panic(fmt.Sprintf("crudgen: %s must have exactly one primary key", t))
+	}
+
+	r := &Resource[T]{db: db, cfg: cfg, fields: make(map[string]*field)}
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() || sf.Anonymous {
+			continue
+		}
+		gf := s.LookUpField(sf.Name)
+		if gf == nil || gf.DBName == "" || gf.Name != sf.Name {
+			continue // 关联、gorm:"-" 等不对应列的字段
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		f := &field{name: sf.Name, json: name, column: gf.DBName, typ: sf.Type}
+		f.writable = !gf.PrimaryKey && gf.AutoCreateTime == 0 && gf.AutoUpdateTime == 0 &&
+			sf.Type != reflect.TypeFor[gorm.DeletedAt]()
+		for opt := range strings.SplitSeq(sf.Tag.Get("crud"), ",") {
+			switch strings.TrimSpace(opt) {
+			case "":
+			case "readonly":
+				f.writable = false
+			case "filter":
+				if _, err := parseValue(f.typ, ""); errors.Is(err, errUnsupported) {
+					panic(fmt.Sprintf("crudgen: %s.%s: filter is not supported on %s", t, sf.Name, f.typ))
+				}
+				if slices.Contains(reserved, name) {
+					panic(fmt.Sprintf("crudgen: %s.%s: %q is a reserved query parameter", t, sf.Name, name))
+				}
+				f.filter = true
+			case "sort":
+				f.sort = true
+			case "search":
+				if indirect(f.typ).Kind() != reflect.String {
+					panic(fmt.Sprintf("crudgen: %s.%s: search requires a string field", t, sf.Name))
+				}
+				f.search = true
+				r.search = append(r.search, f)
+			default:
+				panic(fmt.Sprintf("crudgen: %s.%s: unknown crud option %q", t, sf.Name, opt))
+			}
+		}
+		if gf.PrimaryKey {
+			f.sort = true
+			r.pk = f
+		}
+		r.fields[name] = f
+	}
+	if r.pk == nil {
+		panic(fmt.Sprintf("crudgen: %s: primary key is hidden by json:\"-\"", t))
+	}
+
+	if cfg.DefaultSort == "" {
+		cfg.DefaultSort = "-" + r.pk.json
+	}
+	if r.sort, err = r.parseSort(cfg.DefaultSort); err != nil {
+		panic(fmt.Sprintf("crudgen: %s: DefaultSort: %v", t, err))
+	}
+	return r
+}
+
+// ============================================================================
+// 查询
+// ============================================================================
+
+// ListQuery 列表条件，通常由 HTTP 查询参数解析而来
+type ListQuery struct {
+	Filter map[string][]string // json 名 → 取值，多个值为 IN
+	Search string              // 对 crud:"search" 字段模糊匹配
+	Sort   string              // 如 "name,-id"，为空时用 DefaultSort
+	Offset int
+	Limit  int
+}
+
+// List 查询一页和总数
+func (r *Resource[T]) List(ctx context.Context, q ListQuery) ([]T, int64, error) {
+	db := r.db.WithContext(ctx).Model(new(T))
+	for name, values := range q.Filter {
+		f, ok := r.fields[name]
+		if !ok || !f.filter {
+			return nil, 0, &ValidationError{Fields: map[string]string{name: "not_filterable"}}
+		}
+		args := make([]any, 0, len(values))
+		for _, s := range values {
+			v, err := parseValue(f.typ, s)
+			if err != nil {
+				return nil, 0, &ValidationError{Fields: map[string]string{name: "type"}}
+			}
+			args = append(args, v)
+		}
+		db = db.Where(clause.IN{Column: clause.Column{Name: f.column}, Values: args})
+	}
+	if q.Search != "" && len(r.search) > 0 {
+		kw := "%" + likeEscaper.Replace(q.Search) + "%"
+		conds := make([]clause.Expression, len(r.search))
+		for i, f := range r.search {
+			conds[i] = clause.Expr{SQL: `? LIKE ? ESCAPE '\'`, Vars: []any{clause.Column{Name: f.column}, kw}}
+		}
+		db = db.Where(clause.Or(conds...))
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	orders := r.sort
+	if q.Sort != "" {
+		var err error
+		if orders, err = r.parseSort(q.Sort); err != nil {
+			return nil, 0, err
+		}
+	}
+	for _, o := range orders {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: o.column}, Desc: o.desc})
+	}
+	rows := []T{}
+	err := r.preload(db).Offset(q.Offset).Limit(q.Limit).Find(&rows).Error
+	return rows, total, err
+}
+
+// parseSort 解析 "name,-id"；最后总是按主键排序，排序字段有重复值时翻页结果仍然稳定
+func (r *Resource[T]) parseSort(s string) ([]order, error) {
+	var orders []order
+	hasPK := false
+	for item := range strings.SplitSeq(s, ",") {
+		item = strings.TrimSpace(item)
+		name, desc := strings.CutPrefix(item, "-")
+		f, ok := r.fields[name]
+		if !ok || !f.sort {
+			return nil, &ValidationError{Fields: map[string]string{"sort": "not_sortable: " + item}}
+		}
+		orders = append(orders, order{column: f.column, desc: desc})
+		hasPK = hasPK || f == r.pk
+	}
+	if !hasPK {
+		orders = append(orders, order{column: r.pk.column})
+	}
+	return orders, nil
+}
+
+// Get 按主键查询，id 是路径参数的原始字符串
+func (r *Resource[T]) Get(ctx context.Context, id string) (*T, error) {
+	return r.take(r.db.WithContext(ctx), id)
+}
+
+func (r *Resource[T]) take(tx *gorm.DB, id string) (*T, error) {
+	v, err := parseValue(r.pk.typ, id)
+	if err != nil {
+		return nil, ErrNotFound // 格式不对的 ID 不可能存在
+	}
+	row := new(T)
+	err = r.preload(tx).Where(clause.Eq{Column: clause.Column{Name: r.pk.column}, Value: v}).Take(row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return row, err
+}
+
+func (r *Resource[T]) preload(db *gorm.DB) *gorm.DB {
+	for _, name := range r.cfg.Preload {
+		db = db.Preload(name)
+	}
+	return db
+}
+
+// ============================================================================
+// 写入
+// ============================================================================
+
+// Create 用请求体的字段创建记录，返回创建后的数据（含数据库生成的主键和默认值）
+func (r *Resource[T]) Create(ctx context.Context, body map[string]json.RawMessage) (*T, error) {
+	row := new(T)
+	var id string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := r.assign(row, body); err != nil {
+			return err
+		}
+		if err := r.validate(tx, row); err != nil {
+			return err
+		}
+		if err := translate(tx.Omit(clause.Associations).Create(row).Error); err != nil {
+			return err
+		}
+		id = fmt.Sprint(reflect.ValueOf(row).Elem().FieldByName(r.pk.name).Interface())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, id)
+}
+
+// Update 只更新请求体里出现的字段，零值（""、0、false）也会写入
+func (r *Resource[T]) Update(ctx context.Context, id string, body map[string]json.RawMessage) (*T, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		row, err := r.take(tx, id)
+		if err != nil {
+			return err
+		}
+		columns, err := r.assign(row, body)
+		if err != nil {
+			return err
+		}
+		if err := r.validate(tx, row); err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			return nil
+		}
+		return translate(tx.Model(row).Select(columns).Omit(clause.Associations).Updates(row).Error)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, id)
+}
+
+// Delete 删除记录；模型嵌入了 gorm.Model 时是软删除，可以配合 trash 包恢复
+func (r *Resource[T]) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		row, err := r.take(tx, id)
+		if err != nil {
+			return err
+		}
+		if r.cfg.BeforeDelete != nil {
+			if err := r.cfg.BeforeDelete(tx, row); err != nil {
+				return err
+			}
+		}
+		return translate(tx.Delete(row).Error)
+	})
+}
+
+// assign 把请求体的字段逐个解码到 row，返回赋值过的列；所有字段的错误一起返回
+func (r *Resource[T]) assign(row *T, body map[string]json.RawMessage) ([]string, error) {
+	v := reflect.ValueOf(row).Elem()
+	columns := make([]string, 0, len(body))
+	errs := make(map[string]string)
+	for name, raw := range body {
+		f, ok := r.fields[name]
+		switch {
+		case !ok:
+			errs[name] = "unknown"
+		case !f.writable:
+			errs[name] = "read_only"
+		default:
+			if err := json.Unmarshal(raw, v.FieldByName(f.name).Addr().Interface()); err != nil {
+				errs[name] = "type"
+				continue
+			}
+			columns = append(columns, f.column)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &ValidationError{Fields: errs}
+	}
+	return columns, nil
+}
+
+// validate 用 gin 的校验器检查 binding 标签，再调用 BeforeSave
+func (r *Resource[T]) validate(tx *gorm.DB, row *T) error {
+	if err := binding.Validator.ValidateStruct(row); err != nil {
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) {
+			return err
+		}
+		errs := make(map[string]string, len(verrs))
+		for _, fe := range verrs {
+			errs[r.jsonName(fe.StructField())] = fe.Tag()
+		}
+		return &ValidationError{Fields: errs}
+	}
+	if r.cfg.BeforeSave != nil {
+		return r.cfg.BeforeSave(tx, row)
+	}
+	return nil
+}
+
+// jsonName Go 字段名对应的 json 名，找不到时原样返回
+func (r *Resource[T]) jsonName(goName string) string {
+	for _, f := range r.fields {
+		if f.name == goName {
+			return f.json
+		}
+	}
+	return goName
+}
+
+// translate 把 GORM 的约束错误转换为包内错误，需要 gorm.Config{TranslateError: true}
+func translate(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return fmt.Errorf("%w: %v", ErrInUse, err)
+	}
+	return err
+}
+
+// likeEscaper 转义 LIKE 通配符，用户输入的 % 和 _ 按普通字符匹配
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+var errUnsupported = errors.New("unsupported type")
+
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
+
+// parseValue 把查询参数 / 路径参数按字段类型转换，只支持字符串、整数、浮点数和布尔
+func parseValue(t reflect.Type, s string) (any, error) {
+	switch t = indirect(t); t.Kind() {
+	case reflect.String:
+		return s, nil
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(s, 10, t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(s, 10, t.Bits())
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, t.Bits())
+	}
+	return nil, errUnsupported
+}
@@ -0,0 +1,270 @@
+package crudgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+)
+
+type article struct {
+	gorm.Model
+	Title    string `gorm:"uniqueIndex" json:"title" binding:"required,max=20" crud:"filter,sort,search"`
+	Status   string `json:"status" binding:"omitempty,oneof=draft published" crud:"filter"`
+	Featured bool   `json:"featured" crud:"filter"`
+	Views    int    `json:"views" crud:"sort,readonly"`
+	Secret   string `json:"-"`
+	Comments []comment
+}
+
+type comment struct {
+	ID        uint
+	ArticleID uint
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.ForeignKeys(t, &article{}, &comment{})
+}
+
+func body(s string) map[string]json.RawMessage {
+	var m map[string]json.RawMessage
+	json.Unmarshal([]byte(s), &m)
+	return m
+}
+
+func TestFields(t *testing.T) {
+	r := New(newTestDB(t), Config[article]{})
+	tests := []struct {
+		name                             string
+		exists, writable, filter, search bool
+	}{
+		{"ID", true, false, false, false},
+		{"CreatedAt", true, false, false, false},
+		{"DeletedAt", true, false, false, false},
+		{"title", true, true, true, true},
+		{"views", true, false, false, false},
+		{"featured", true, true, true, false},
+		{"Secret", false, false, false, false},
+		{"Comments", false, false, false, false},
+	}
+	for _, tt := range tests {
+		f, ok := r.fields[tt.name]
+		if ok != tt.exists {
+			t.Errorf("%s exists = %v", tt.name, ok)
+			continue
+		}
+		if ok && (f.writable != tt.writable || f.filter != tt.filter || f.search != tt.search) {
+			t.Errorf("%s = %+v", tt.name, f)
+		}
+	}
+}
+
+func TestNewPanics(t *testing.T) {
+	type badOption struct {
+		ID   uint
+		Name string `crud:"filtr"`
+	}
+	type reservedName struct {
+		ID   uint
+		Page int `json:"page" crud:"filter"`
+	}
+	type searchInt struct {
+		ID  uint
+		Age int `crud:"search"`
+	}
+	db := newTestDB(t)
+	for name, fn := range map[string]func(){
+		"unknown option": func() { New(db, Config[badOption]{}) },
+		"reserved":       func() { New(db, Config[reservedName]{}) },
+		"search int":     func() { New(db, Config[searchInt]{}) },
+		"bad sort":       func() { New(db, Config[article]{DefaultSort: "status"}) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("New did not panic")
+				}
+			}()
+			fn()
+		})
+	}
+}
+
+func TestCreateAndUpdate(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	saved := 0
+	r := New(db, Config[article]{
+		BeforeSave: func(tx *gorm.DB, a *article) error {
+			saved++
+			return nil
+		},
+	})
+
+	var verr *ValidationError
+	_, err := r.Create(ctx, body(`{"ID": 9, "views": 100, "status": "gone", "extra": 1, "featured": "yes"}`))
+	if !errors.As(err, &verr) || !errors.Is(err, ErrInvalid) {
+		t.Fatalf("err = %v", err)
+	}
+	want := map[string]string{"ID": "read_only", "views": "read_only", "extra": "unknown", "featured": "type"}
+	if len(verr.Fields) != len(want) {
+		t.Errorf("fields = %v, want %v", verr.Fields, want)
+	}
+	for k, v := range want {
+		if verr.Fields[k] != v {
+			t.Errorf("fields[%s] = %q, want %q", k, verr.Fields[k], v)
+		}
+	}
+
+	// binding 标签的错误用 json 名报告
+	_, err = r.Create(ctx, body(`{"status": "gone"}`))
+	if !errors.As(err, &verr) || verr.Fields["title"] != "required" || verr.Fields["status"] != "oneof" {
+		t.Errorf("binding err = %v", err)
+	}
+
+	a, err := r.Create(ctx, body(`{"title": "hello", "status": "draft", "featured": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID == 0 || a.Title != "hello" || !a.Featured || saved != 1 {
+		t.Errorf("created = %+v, saved %d", a, saved)
+	}
+	if _, err := r.Create(ctx, body(`{"title": "hello"}`)); !errors.Is(err, ErrConflict) {
+		t.Errorf("duplicate err = %v", err)
+	}
+
+	// 只更新出现的字段，false 也能写入
+	db.Model(&article{}).Where("id = ?", a.ID).Update("views", 7)
+	a, err = r.Update(ctx, "1", body(`{"featured": false}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Featured || a.Title != "hello" || a.Status != "draft" || a.Views != 7 {
+		t.Errorf("updated = %+v", a)
+	}
+	if _, err := r.Update(ctx, "1", body(`{"title": ""}`)); !errors.As(err, &verr) || verr.Fields["title"] != "required" {
+		t.Errorf("update to empty title err = %v", err)
+	}
+	if _, err := r.Update(ctx, "42", body(`{}`)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update missing err = %v", err)
+	}
+	if _, err := r.Get(ctx, "abc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get non-numeric id err = %v", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	r := New(db, Config[article]{
+		BeforeDelete: func(tx *gorm.DB, a *article) error {
+			var n int64
+			tx.Model(&comment{}).Where("article_id = ?", a.ID).Count(&n)
+			if n > 0 {
+				return ErrInUse
+			}
+			return nil
+		},
+	})
+	db.Create(&article{Title: "a", Comments: []comment{{}}})
+	db.Create(&article{Title: "b"})
+
+	if err := r.Delete(ctx, "1"); !errors.Is(err, ErrInUse) {
+		t.Errorf("delete referenced err = %v", err)
+	}
+	if err := r.Delete(ctx, "2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(ctx, "2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get deleted err = %v", err)
+	}
+	var n int64
+	db.Unscoped().Model(&article{}).Where("id = 2").Count(&n)
+	if n != 1 {
+		t.Error("gorm.Model should be soft deleted")
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+	for i, title := range []string{"go tips", "rust 101", "go_generics", "gin", "gorm"} {
+		db.Create(&article{Title: title, Status: "published", Views: 10 * i, Featured: i%2 == 0})
+	}
+	db.Model(&article{}).Where("title = ?", "gin").Update("status", "draft")
+
+	router := gin.New()
+	Register(router.Group("/articles"), New(db, Config[article]{}))
+
+	do := func(method, path, reqBody string) (int, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(reqBody))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+	titles := func(resp string) string {
+		var out struct {
+			Data struct {
+				Items []article `json:"items"`
+				Total int       `json:"total"`
+			} `json:"data"`
+		}
+		json.Unmarshal([]byte(resp), &out)
+		s := make([]string, len(out.Data.Items))
+		for i, a := range out.Data.Items {
+			s[i] = a.Title
+		}
+		return strings.Join(s, "|") + "/" + strconv.Itoa(out.Data.Total)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "gorm|gin|go_generics|rust 101|go tips/5"},
+		{"?page_size=2&page=2", "go_generics|rust 101/5"},
+		{"?status=draft", "gin/1"},
+		{"?status=draft,published&featured=true&sort=title", "go tips|go_generics|gorm/3"},
+		{"?q=go_", "go_generics/1"}, // _ 按普通字符匹配
+		{"?sort=-views&page_size=2", "gorm|gin/5"},
+		{"?views=10&_t=1", "gorm|gin|go_generics|rust 101|go tips/5"}, // 不可过滤的参数忽略
+	}
+	for _, tt := range tests {
+		code, resp := do("GET", "/articles"+tt.query, "")
+		if got := titles(resp); code != 200 || got != tt.want {
+			t.Errorf("GET %s = %d %s, want %s", tt.query, code, got, tt.want)
+		}
+	}
+
+	errTests := []struct {
+		method, path, body string
+		code               int
+		want               string
+	}{
+		{"GET", "/articles?sort=status", "", 400, "not_sortable"},
+		{"GET", "/articles?featured=maybe", "", 400, `"featured":"type"`},
+		{"GET", "/articles?cursor=", "", 400, "invalid_pagination"},
+		{"GET", "/articles/99", "", 404, "not_found"},
+		{"POST", "/articles", `[1]`, 400, "malformed_request"},
+		{"POST", "/articles", `{"title": "gin"}`, 409, "duplicate"},
+		{"POST", "/articles", `{"title": "a very long title that exceeds"}`, 400, `"title":"max"`},
+		{"POST", "/articles", `{"title": "echo"}`, 200, `"title":"echo"`},
+		{"PATCH", "/articles/4", `{"status": "published"}`, 200, `"status":"published"`},
+		{"DELETE", "/articles/4", "", 200, `"deleted":true`},
+		{"DELETE", "/articles/4", "", 404, "not_found"},
+	}
+	for _, tt := range errTests {
+		if code, resp := do(tt.method, tt.path, tt.body); code != tt.code || !strings.Contains(resp, tt.want) {
+			t.Errorf("%s %s = %d %s, want %d %s", tt.method, tt.path, code, resp, tt.code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package crudgen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/pagination"
+	"go-one/response"
+)
+
+// Register 在 group 上注册五个接口，调用方负责加管理员权限中间件
+//
+//	GET    /      列表，响应 {"items": [...], "total": 35, "page": 1, "size": 10}
+//	GET    /:id   单条
+//	POST   /      创建
+//	PATCH  /:id   部分更新
+//	DELETE /:id   删除
+//
+// 校验失败返回 400，字段错误放在 data.fields，格式和 apperr 中间件一致：
+//
+//	{"code": -1, "message": "2 个字段校验失败", "error": "validation_failed",
+//	 "data": {"fields": {"name": "required", "id": "read_only"}}}
+func Register[T any](group *gin.RouterGroup, r *Resource[T]) {
+	group.GET("", func(c *gin.Context) {
+		page, err := pagination.FromQuery(c)
+		if err != nil || page.Mode != pagination.ModeOffset {
+			response.Error(c, http.StatusBadRequest, "invalid_pagination", "只支持 page / page_size 分页")
+			return
+		}
+		q := ListQuery{
+			Filter: make(map[string][]string),
+			Search: c.Query("q"),
+			Sort:   c.Query("sort"),
+			Offset: page.Offset(),
+			Limit:  page.Size,
+		}
+		// 只取可过滤字段的参数，其他未知参数忽略（前端常带 _t 之类的防缓存参数）
+		for name, f := range r.fields {
+			if v, ok := c.GetQuery(name); ok && f.filter {
+				q.Filter[name] = strings.Split(v, ",")
+			}
+		}
+		rows, total, err := r.List(c.Request.Context(), q)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, gin.H{"items": rows, "total": total, "page": page.Page, "size": page.Size})
+	})
+
+	group.GET("/:id", func(c *gin.Context) {
+		row, err := r.Get(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, row)
+	})
+
+	group.POST("", func(c *gin.Context) {
+		body, ok := decodeBody(c)
+		if !ok {
+			return
+		}
+		row, err := r.Create(c.Request.Context(), body)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, row)
+	})
+
+	group.PATCH("/:id", func(c *gin.Context) {
+		body, ok := decodeBody(c)
+		if !ok {
+			return
+		}
+		row, err := r.Update(c.Request.Context(), c.Param("id"), body)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, row)
+	})
+
+	group.DELETE("/:id", func(c *gin.Context) {
+		id := c.Param("id")
+		if err := r.Delete(c.Request.Context(), id); err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, gin.H{"id": id, "deleted": true})
+	})
+}
+
+// decodeBody 请求体必须是 JSON 对象
+func decodeBody(c *gin.Context) (map[string]json.RawMessage, bool) {
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil || body == nil {
+		response.Error(c, http.StatusBadRequest, "malformed_request", "请求体必须是 JSON 对象")
+		return nil, false
+	}
+	return body, true
+}
+
+// abort 按错误类型选择状态码，钩子返回的其他错误按 500 处理
+func abort(c *gin.Context, err error) {
+	var verr *ValidationError
+	switch {
+	case errors.As(err, &verr):
+		c.JSON(http.StatusBadRequest, response.Response{
+			Code:    response.CodeError,
+			Message: fmt.Sprintf("%d 个字段校验失败", len(verr.Fields)),
+			Error:   "validation_failed",
+			Data:    gin.H{"fields": verr.Fields},
+		})
+	case errors.Is(err, ErrNotFound):
+		response.Error(c, http.StatusNotFound, "not_found", "记录不存在")
+	case errors.Is(err, ErrConflict):
+		response.Error(c, http.StatusConflict, "duplicate", "已存在相同唯一键的记录")
+	case errors.Is(err, ErrInUse):
+		response.Error(c, http.StatusConflict, "in_use", "记录仍被其他数据引用，无法删除")
+	case errors.Is(err, ErrInvalid):
+		response.Error(c, http.StatusBadRequest, "invalid_argument", err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, "internal_error", "操作失败")
+	}
+}
@@ -0,0 +1,228 @@
+// ============================================================================
+// Package fieldenc 敏感字段加密存储（手机号、身份证号）
+// ============================================================================
+//
+// 【为什么要加密列？】
+//
+// 数据库备份被拷走、只读账号泄露、DBA 查问题时看到的都是明文。
+// 加密之后库里只有密文，密钥在配置（环境变量 / 密钥管理服务）里，
+// 拿到库不等于拿到数据。
+//
+// 【信封加密】
+//
+//	主密钥 KEK（配置里，按版本号区分）
+//	   │ 加密
+//	   ▼
+//	数据密钥 DEK（每个值随机生成一个）
+//	   │ AES-256-GCM 加密，附加数据 = 表名.列名
+//	   ▼
+//	库里存的值：enc:v2:base64(DEK 的 nonce ‖ 加密后的 DEK ‖ 值的 nonce ‖ 密文)
+//
+// | 设计                 | 原因                                                        |
+// |----------------------|-------------------------------------------------------------|
+// | 每个值一个 DEK       | 同一个 KEK 加密的数据量很小，不会接近 GCM 随机 nonce 的上限 |
+// | 值里带 KEK 版本号    | 轮换后新旧密文共存，读的时候按版本号找密钥                  |
+// | 表名.列名作附加数据  | 把 phone 的密文复制到 id_card 列，解密直接失败              |
+// | 没有 enc: 前缀的值   | 按明文返回：已有数据可以先上线再用 Rotate 批量加密          |
+//
+// 密文每个值多约 130 字节，列宽要放宽（手机号 size:255）。
+// 加密列不能用 WHERE phone = ? 查询，也不能建有意义的索引；需要按手机号查找时，
+// 另加一列 HMAC(手机号) 做等值查询。
+//
+// 【用法】
+//
+//	keys, _ := fieldenc.ParseKeys(cfg.FieldEnc.Keys)      // ["1:base64...", "2:base64..."]
+//	ring, _ := fieldenc.NewKeyring(keys, cfg.FieldEnc.Active)
+//	fieldenc.Register(ring)                                // 在迁移和查询之前注册
+//
+//	type Contact struct {
+//		ID     uint
+//		Phone  string `gorm:"size:255;serializer:encrypted"`
+//		IDCard string `gorm:"size:255;serializer:encrypted"`
+//	}
+//
+// 写入时自动加密，查询时自动解密，业务代码看到的始终是明文。
+//
+// 【轮换主密钥】
+//
+//  1. 配置里追加新版本（APP_FIELDENC_KEYS=1:旧,2:新），重启后新值用版本 2 加密
+//  2. 运行 rotate-field-keys（Rotate）把旧版本和明文的值用版本 2 重新加密
+//  3. 确认没有旧版本的值后，从配置里删掉版本 1
+//
+// ============================================================================
+package fieldenc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 错误定义
+var (
+	ErrNoKeys        = errors.New("fieldenc: at least one key is required")
+	ErrUnknownKey    = errors.New("fieldenc: value encrypted with an unknown key version")
+	ErrDecrypt       = errors.New("fieldenc: decryption failed")
+	ErrNotRegistered = errors.New("fieldenc: no keyring registered")
+)
+
+// prefix 加密值的前缀，后面是 v<版本号>:
+const prefix = "enc:"
+
+// dekLabel 加密 DEK 时的附加数据
+const dekLabel = "fieldenc data key"
+
+// Keyring 按版本号保存的主密钥，可以并发使用
+type Keyring struct {
+	keys   map[uint32]cipher.AEAD
+	active uint32
+}
+
+// ParseKeys 解析配置里的密钥列表，每项为 "版本号:base64(32 字节密钥)"
+func ParseKeys(specs []string) (map[uint32][]byte, error) {
+	keys := make(map[uint32][]byte, len(specs))
+	for _, spec := range specs {
+		ver, encoded, ok := strings.Cut(strings.TrimSpace(spec), ":")
+		if !ok {
+			return nil, errors.New("fieldenc: key must be \"<version>:<base64 key>\"")
+		}
+		v, err := strconv.ParseUint(ver, 10, 32)
+		if err != nil || v == 0 {
+			return nil, fmt.Errorf("fieldenc: key version %q must be a positive integer", ver)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("fieldenc: key version %d: %w", v, err)
+		}
+		if _, dup := keys[uint32(v)]; dup {
+			return nil, fmt.Errorf("fieldenc: duplicate key version %d", v)
+		}
+		keys[uint32(v)] = key
+	}
+	return keys, nil
+}
+
+// NewKeyring 创建密钥环，每个密钥 32 字节（AES-256）；active 为 0 时用最大的版本号加密新值
+func NewKeyring(keys map[uint32][]byte, active uint32) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	k := &Keyring{keys: make(map[uint32]cipher.AEAD, len(keys))}
+	for ver, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("fieldenc: key version %d must be 32 bytes, got %d", ver, len(key))
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[ver] = aead
+		if active == 0 && ver > k.active {
+			k.active = ver
+		}
+	}
+	if active != 0 {
+		if _, ok := keys[active]; !ok {
+			return nil, fmt.Errorf("fieldenc: active key version %d is not configured", active)
+		}
+		k.active = active
+	}
+	return k, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Active 加密新值用的版本号
+func (k *Keyring) Active() uint32 {
+	return k.active
+}
+
+// Encrypt 用当前版本加密，aad 是附加数据（通常为 表名.列名），解密时必须相同
+func (k *Keyring) Encrypt(plaintext []byte, aad string) (string, error) {
+	kek := k.keys[k.active]
+	dek := make([]byte, 32)
+	rand.Read(dek)
+	data, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+
+	// DEK 的 nonce ‖ 加密后的 DEK ‖ 值的 nonce ‖ 密文
+	out := make([]byte, kek.NonceSize(), kek.NonceSize()+len(dek)+kek.Overhead()+data.NonceSize()+len(plaintext)+data.Overhead())
+	rand.Read(out)
+	out = kek.Seal(out, out, dek, []byte(dekLabel))
+	nonce := make([]byte, data.NonceSize())
+	rand.Read(nonce)
+	out = append(out, nonce...)
+	out = data.Seal(out, nonce, plaintext, []byte(aad))
+	return prefix + "v" + strconv.FormatUint(uint64(k.active), 10) + ":" + base64.RawStdEncoding.EncodeToString(out), nil
+}
+
+// Decrypt 解密 Encrypt 的结果；没有 enc: 前缀的值按明文原样返回
+func (k *Keyring) Decrypt(stored, aad string) ([]byte, error) {
+	ver, payload, ok := parse(stored)
+	if !ok {
+		return []byte(stored), nil
+	}
+	kek, ok := k.keys[ver]
+	if !ok {
+		return nil, fmt.Errorf("%w: v%d", ErrUnknownKey, ver)
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(payload)
+	n := kek.NonceSize()
+	wrapped := n + 32 + kek.Overhead()
+	if err != nil || len(raw) < wrapped+n {
+		return nil, ErrDecrypt
+	}
+	dek, err := kek.Open(nil, raw[:n], raw[n:wrapped], []byte(dekLabel))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	data, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+	rest := raw[wrapped:]
+	plaintext, err := data.Open(nil, rest[:data.NonceSize()], rest[data.NonceSize():], []byte(aad))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// NeedsRotation 存储的值是否要重新加密：明文，或者不是当前版本；空值不加密
+func (k *Keyring) NeedsRotation(stored string) bool {
+	if stored == "" {
+		return false
+	}
+	ver, _, ok := parse(stored)
+	return !ok || ver != k.active
+}
+
+// parse 拆出 enc:v<版本号>:<payload>
+func parse(stored string) (ver uint32, payload string, ok bool) {
+	rest, ok := strings.CutPrefix(stored, prefix+"v")
+	if !ok {
+		return 0, "", false
+	}
+	v, payload, ok := strings.Cut(rest, ":")
+	if !ok {
+		return 0, "", false
+	}
+	n, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		return 0, "", false
+	}
+	return uint32(n), payload, true
+}
@@ -0,0 +1,236 @@
+package fieldenc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+)
+
+var (
+	key1 = bytes.Repeat([]byte{1}, 32)
+	key2 = bytes.Repeat([]byte{2}, 32)
+)
+
+type contact struct {
+	gorm.Model
+	Name   string
+	Phone  string  `gorm:"size:255;serializer:encrypted"`
+	IDCard *string `gorm:"size:255;serializer:encrypted"`
+	Note   []byte  `gorm:"serializer:encrypted"`
+}
+
+func newKeyring(t *testing.T, keys map[uint32][]byte) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(keys, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// useKeyring 注册密钥环，测试结束后恢复成没有密钥的序列化器
+func useKeyring(t *testing.T, k *Keyring) {
+	Register(k)
+	t.Cleanup(func() { Register(nil) })
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.Open(t, &contact{})
+}
+
+// raw 不经过序列化器读出库里存的值
+func raw(t *testing.T, db *gorm.DB, id uint, column string) string {
+	t.Helper()
+	var v string
+	if err := db.Table("contacts").Select(column).Where("id = ?", id).Scan(&v).Error; err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestKeyring(t *testing.T) {
+	k := newKeyring(t, map[uint32][]byte{1: key1, 2: key2})
+	if k.Active() != 2 {
+		t.Errorf("Active = %d; want highest version 2", k.Active())
+	}
+
+	a, err := k.Encrypt([]byte("13800138000"), "contacts.phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := k.Encrypt([]byte("13800138000"), "contacts.phone")
+	if !strings.HasPrefix(a, "enc:v2:") || a == b {
+		t.Errorf("Encrypt = %s, %s; want distinct enc:v2: values", a, b)
+	}
+	if plain, err := k.Decrypt(a, "contacts.phone"); err != nil || string(plain) != "13800138000" {
+		t.Errorf("Decrypt = %q, %v", plain, err)
+	}
+	// 密文挪到别的列
+	if _, err := k.Decrypt(a, "contacts.id_card"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("other column: %v", err)
+	}
+	// 密文被改动
+	tampered := a[:len(a)-2] + "AA"
+	if _, err := k.Decrypt(tampered, "contacts.phone"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("tampered: %v", err)
+	}
+	// 明文原样返回
+	if plain, err := k.Decrypt("13800138000", "contacts.phone"); err != nil || string(plain) != "13800138000" {
+		t.Errorf("plaintext = %q, %v", plain, err)
+	}
+
+	// 只有旧密钥的实例解不开新版本
+	old := newKeyring(t, map[uint32][]byte{1: key1})
+	if _, err := old.Decrypt(a, "contacts.phone"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("unknown version: %v", err)
+	}
+	v1, _ := old.Encrypt([]byte("x"), "t.c")
+	for stored, want := range map[string]bool{v1: true, a: false, "plain": true, "": false} {
+		if got := k.NeedsRotation(stored); got != want {
+			t.Errorf("NeedsRotation(%.10q) = %v; want %v", stored, got, want)
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys([]string{"1:" + base64.StdEncoding.EncodeToString(key1), " 2:" + base64.StdEncoding.EncodeToString(key2)})
+	if err != nil || !bytes.Equal(keys[1], key1) || !bytes.Equal(keys[2], key2) {
+		t.Fatalf("ParseKeys = %v, %v", keys, err)
+	}
+	if k, err := NewKeyring(keys, 1); err != nil || k.Active() != 1 {
+		t.Errorf("NewKeyring(active=1) = %v, %v", k, err)
+	}
+
+	for _, specs := range [][]string{
+		{base64.StdEncoding.EncodeToString(key1)},
+		{"0:" + base64.StdEncoding.EncodeToString(key1)},
+		{"1:not base64!"},
+		{"1:" + base64.StdEncoding.EncodeToString(key1), "1:" + base64.StdEncoding.EncodeToString(key2)},
+	} {
+		if _, err := ParseKeys(specs); err == nil {
+			t.Errorf("ParseKeys(%q) accepted", specs)
+		}
+	}
+	for name, keys := range map[string]map[uint32][]byte{
+		"no keys":   nil,
+		"short key": {1: []byte("short")},
+	} {
+		if _, err := NewKeyring(keys, 0); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	if _, err := NewKeyring(map[uint32][]byte{1: key1}, 3); err == nil {
+		t.Error("missing active version accepted")
+	}
+}
+
+func TestSerializer(t *testing.T) {
+	db := newTestDB(t)
+
+	// 没有注册密钥：有值的加密列读写都报错，空值照常
+	if err := db.Create(&contact{Name: "empty"}).Error; err != nil {
+		t.Errorf("create without keys and values: %v", err)
+	}
+	if err := db.Create(&contact{Phone: "13800138000"}).Error; !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("create without keys: %v", err)
+	}
+
+	useKeyring(t, newKeyring(t, map[uint32][]byte{1: key1}))
+	idCard := "110101199003077777"
+	c := contact{Name: "alice", Phone: "13800138000", IDCard: &idCard, Note: []byte("vip")}
+	if err := db.Create(&c).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, column := range []string{"phone", "id_card", "note"} {
+		if v := raw(t, db, c.ID, column); !strings.HasPrefix(v, "enc:v1:") {
+			t.Errorf("%s stored as %q", column, v)
+		}
+	}
+
+	var got contact
+	if err := db.First(&got, c.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Phone != c.Phone || got.IDCard == nil || *got.IDCard != idCard || string(got.Note) != "vip" {
+		t.Errorf("read back = %+v", got)
+	}
+
+	// 空值不加密，nil 仍然是 NULL
+	empty := contact{Name: "bob"}
+	db.Create(&empty)
+	var back contact
+	db.First(&back, empty.ID)
+	if raw(t, db, empty.ID, "phone") != "" || back.Phone != "" || back.IDCard != nil || back.Note != nil {
+		t.Errorf("empty values = %+v", back)
+	}
+
+	// 密文被挪到另一行的另一列
+	db.Exec("UPDATE contacts SET id_card = phone WHERE id = ?", c.ID)
+	if err := db.First(&got, c.ID).Error; !errors.Is(err, ErrDecrypt) {
+		t.Errorf("swapped column: %v", err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	// 上线前的明文数据
+	db.Exec("INSERT INTO contacts (name, phone, id_card) VALUES ('legacy', '13900139000', NULL)")
+	useKeyring(t, newKeyring(t, map[uint32][]byte{1: key1}))
+	for i := range 5 {
+		db.Create(&contact{Name: "v1", Phone: "1380013800" + string(rune('0'+i))})
+	}
+	deleted := contact{Name: "deleted", Phone: "13700137000"}
+	db.Create(&deleted)
+	db.Delete(&deleted)
+
+	// 追加版本 2：新写入用 v2，旧数据仍然能读
+	k2 := newKeyring(t, map[uint32][]byte{1: key1, 2: key2})
+	useKeyring(t, k2)
+	var all []contact
+	if err := db.Order("id").Find(&all).Error; err != nil || len(all) != 6 || all[0].Phone != "13900139000" {
+		t.Fatalf("mixed read = %+v, %v", all, err)
+	}
+	db.Create(&contact{Name: "v2", Phone: "13600136000"})
+
+	res, err := Rotate(ctx, db, k2, &contact{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 8 行（含软删除），明文 1 + v1 6 个值，v2 和空值不动
+	if res.Table != "contacts" || res.Scanned != 8 || res.Rotated != 7 {
+		t.Errorf("Rotate = %+v", res)
+	}
+	for id := uint(1); id <= 8; id++ {
+		if v := raw(t, db, id, "phone"); !strings.HasPrefix(v, "enc:v2:") {
+			t.Errorf("id %d phone = %.12q", id, v)
+		}
+	}
+	if err := db.Unscoped().Order("id").Find(&all).Error; err != nil || all[0].Phone != "13900139000" || all[6].Phone != "13700137000" {
+		t.Errorf("after rotate = %+v, %v", all, err)
+	}
+
+	// 再跑一次没有要改的；只剩版本 2 的实例可以读全部数据
+	if res, _ := Rotate(ctx, db, k2, &contact{}, 0); res.Rotated != 0 {
+		t.Errorf("second Rotate = %+v", res)
+	}
+	useKeyring(t, newKeyring(t, map[uint32][]byte{2: key2}))
+	if err := db.Unscoped().Find(&all).Error; err != nil {
+		t.Errorf("v2 only: %v", err)
+	}
+
+	// 没有加密列的模型
+	type plain struct{ ID uint }
+	if res, err := Rotate(ctx, db, k2, &plain{}, 0); err != nil || res.Scanned != 0 {
+		t.Errorf("no encrypted columns = %+v, %v", res, err)
+	}
+}
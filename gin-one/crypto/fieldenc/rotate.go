@@ -0,0 +1,131 @@
+package fieldenc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// RotateResult 一张表的轮换结果
+type RotateResult struct {
+	Table   string
+	Scanned int // 扫描的行数
+	Rotated int // 重新加密的值的个数
+}
+
+// Rotate 把模型里所有 serializer:encrypted 的列用当前版本重新加密，明文的旧数据一并加密
+//
+// 按主键分批读取原始值（不经过序列化器），只改写明文和旧版本的值；
+// 更新带上 "列 = 旧值" 条件，读取之后被业务改写过的行跳过（新值已经是当前版本）。
+// 包括软删除的行。可以重复执行，中断后再跑一次即可。
+func Rotate(ctx context.Context, db *gorm.DB, k *Keyring, model any, batchSize int) (RotateResult, error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return RotateResult{}, err
+	}
+	sch := stmt.Schema
+	result := RotateResult{Table: sch.Table}
+
+	var fields []*schema.Field
+	for _, f := range sch.Fields {
+		if f.TagSettings["SERIALIZER"] == Name && f.DBName != "" {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return result, nil
+	}
+	pk := sch.PrioritizedPrimaryField
+	if pk == nil {
+		return result, fmt.Errorf("fieldenc: %s has no primary key", sch.Table)
+	}
+
+	columns := []clause.Column{{Name: pk.DBName}}
+	for _, f := range fields {
+		columns = append(columns, clause.Column{Name: f.DBName})
+	}
+
+	var last any
+	for {
+		q := db.WithContext(ctx).Table(sch.Table).Clauses(clause.Select{Columns: columns}).
+			Order(clause.OrderByColumn{Column: clause.Column{Name: pk.DBName}}).Limit(batchSize)
+		if last != nil {
+			q = q.Where(clause.Gt{Column: clause.Column{Name: pk.DBName}, Value: last})
+		}
+		batch, err := readBatch(q, len(fields))
+		if err != nil {
+			return result, err
+		}
+		for _, row := range batch {
+			result.Scanned++
+			last = row.id
+
+			updates := map[string]any{}
+			where := []clause.Expression{clause.Eq{Column: clause.Column{Name: pk.DBName}, Value: row.id}}
+			for i, f := range fields {
+				old := row.values[i]
+				if !old.Valid || !k.NeedsRotation(old.String) {
+					continue
+				}
+				plain, err := k.Decrypt(old.String, aad(f))
+				if err != nil {
+					return result, fmt.Errorf("%w (%s, %s = %v)", err, aad(f), pk.DBName, row.id)
+				}
+				enc, err := k.Encrypt(plain, aad(f))
+				if err != nil {
+					return result, err
+				}
+				updates[f.DBName] = enc
+				where = append(where, clause.Eq{Column: clause.Column{Name: f.DBName}, Value: old.String})
+			}
+			if len(updates) == 0 {
+				continue
+			}
+			res := db.WithContext(ctx).Table(sch.Table).Clauses(clause.Where{Exprs: where}).UpdateColumns(updates)
+			if res.Error != nil {
+				return result, res.Error
+			}
+			if res.RowsAffected > 0 {
+				result.Rotated += len(updates)
+			}
+		}
+		if len(batch) < batchSize {
+			return result, nil
+		}
+	}
+}
+
+type rawRow struct {
+	id     any
+	values []sql.NullString
+}
+
+// readBatch 读出一批原始值，读完关闭游标后再更新（SQLite 不能边读边写）
+func readBatch(q *gorm.DB, n int) ([]rawRow, error) {
+	rows, err := q.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batch []rawRow
+	for rows.Next() {
+		row := rawRow{values: make([]sql.NullString, n)}
+		dest := []any{&row.id}
+		for i := range row.values {
+			dest = append(dest, &row.values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		batch = append(batch, row)
+	}
+	return batch, rows.Err()
+}
@@ -0,0 +1,107 @@
+package fieldenc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"gorm.io/gorm/schema"
+)
+
+// Name 字段标签里的序列化器名字：gorm:"serializer:encrypted"
+const Name = "encrypted"
+
+// keyring 当前的密钥环
+//
+// GORM 解析模型时把序列化器实例缓存在 schema 里，之后再注册新实例不会生效，
+// 所以序列化器每次读写时从这里取密钥环。
+var keyring atomic.Pointer[Keyring]
+
+// init 没配置密钥时模型照常解析和迁移，读写有值的加密列才报 ErrNotRegistered
+func init() {
+	schema.RegisterSerializer(Name, Serializer{})
+}
+
+// Register 设置加密列使用的密钥环，要在第一次读写加密列之前调用；传 nil 取消
+func Register(k *Keyring) {
+	keyring.Store(k)
+}
+
+// Serializer GORM 序列化器，支持 string、*string、[]byte 字段
+//
+// 空字符串和 nil 原样存储，不加密：否则"没填手机号"也会变成一段密文。
+type Serializer struct{}
+
+// aad 附加数据：表名.列名
+func aad(field *schema.Field) string {
+	return field.Schema.Table + "." + field.DBName
+}
+
+// Scan 查询时解密
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	fieldValue := reflect.New(field.FieldType)
+	var stored string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		stored = v
+	case []byte:
+		stored = string(v)
+	default:
+		return fmt.Errorf("fieldenc: %s: unsupported database type %T", aad(field), dbValue)
+	}
+
+	if stored != "" {
+		k := keyring.Load()
+		if k == nil {
+			return ErrNotRegistered
+		}
+		plain, err := k.Decrypt(stored, aad(field))
+		if err != nil {
+			return fmt.Errorf("%w (%s)", err, aad(field))
+		}
+		switch p := fieldValue.Interface().(type) {
+		case *string:
+			*p = string(plain)
+		case **string:
+			str := string(plain)
+			*p = &str
+		case *[]byte:
+			*p = plain
+		default:
+			return fmt.Errorf("fieldenc: %s: unsupported field type %s", aad(field), field.FieldType)
+		}
+	}
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+// Value 写入时加密
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue any) (any, error) {
+	var plain []byte
+	switch v := fieldValue.(type) {
+	case string:
+		plain = []byte(v)
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		plain = []byte(*v)
+	case []byte:
+		if v == nil {
+			return nil, nil
+		}
+		plain = v
+	default:
+		return nil, fmt.Errorf("fieldenc: %s: unsupported field type %T", aad(field), fieldValue)
+	}
+	if len(plain) == 0 {
+		return "", nil
+	}
+	k := keyring.Load()
+	if k == nil {
+		return nil, ErrNotRegistered
+	}
+	return k.Encrypt(plain, aad(field))
+}
@@ -0,0 +1,369 @@
+// ============================================================================
+// Package csvimport 流式 CSV 导入：逐行解析、逐行校验、分批写入、汇总报告
+// ============================================================================
+//
+// 【为什么不先 ReadAll】
+//
+// 10 万行的文件 ReadAll 要把所有行同时放进内存，解析完才开始写库。
+// 这里用 bufio 包一层、csv.Reader 逐行读，每攒够 BatchSize 行交给 Insert 写一批，
+// 内存占用只和批大小有关，和文件大小无关。
+//
+// 【表头映射】
+//
+// 第一行是表头，按 csv 标签对应结构体字段，列的顺序随意，多余的列忽略：
+//
+//	type UserRow struct {
+//	    Username string `csv:"username" binding:"required,min=3"`
+//	    Email    string `csv:"email" binding:"required,email"`
+//	    Age      int    `csv:"age" binding:"gte=0,lte=150"`
+//	}
+//
+// 支持 string、整数、无符号整数、浮点数和 bool；空单元格保持零值。
+// 校验复用 gin 的 binding 标签（binding.Validator），错误路径用列名：line[3].email。
+//
+// 【三种结果】
+//
+// | 结果     | 原因                                         | 报告里         |
+// |----------|----------------------------------------------|----------------|
+// | created  | Insert 写入成功                              | created 计数   |
+// | skipped  | Insert 对该行返回 ErrSkip（如用户名已存在）  | skipped 计数   |
+// | failed   | 列数不对、类型转换失败、校验失败、行级错误   | errors 逐行列出 |
+//
+// Insert 返回的整体错误（数据库断开）中止导入，已经提交的批次不回滚，
+// 报告里的计数就是已提交的部分，重新导入时已存在的行会被跳过。
+//
+// ============================================================================
+package csvimport
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"go-learning/multierr"
+)
+
+// 错误定义
+var (
+	// ErrSkip Insert 对某一行返回它表示跳过（已存在等），不计为失败
+	ErrSkip = errors.New("csvimport: row skipped")
+	// ErrTooManyRows 超过 Config.MaxRows
+	ErrTooManyRows = errors.New("csvimport: too many rows")
+	// ErrMissingColumn 表头缺少必需的列
+	ErrMissingColumn = errors.New("csvimport: missing column")
+)
+
+// Row 一行解析结果，Line 是文件中的行号（表头是第 1 行）
+type Row[T any] struct {
+	Line  int
+	Value T
+}
+
+// InsertFunc 写入一批行，返回与 batch 等长的逐行错误（nil 为创建成功，ErrSkip 为跳过）
+// 返回的第二个值非 nil 时中止整个导入
+type InsertFunc[T any] func(ctx context.Context, batch []Row[T]) ([]error, error)
+
+// Config 导入配置，零值字段使用默认值
+type Config struct {
+	BatchSize int // 每批写入的行数，默认 500
+	MaxRows   int // 数据行上限（不含表头），默认 10000，超过时中止
+	MaxErrors int // 报告里最多保留的行错误，默认 100，超出的只计数
+	// Required 表头必须包含的列，缺少时在读第一行数据之前就返回 ErrMissingColumn
+	Required []string
+	// Comma 分隔符，默认逗号
+	Comma rune
+}
+
+func (c Config) withDefaults() Config {
+	if c.BatchSize <= 0 {
+		c.BatchSize = 500
+	}
+	if c.MaxRows <= 0 {
+		c.MaxRows = 10000
+	}
+	if c.MaxErrors <= 0 {
+		c.MaxErrors = 100
+	}
+	if c.Comma == 0 {
+		c.Comma = ','
+	}
+	return c
+}
+
+// Report 导入结果汇总
+type Report struct {
+	Rows    int              `json:"rows"` // 读到的数据行数
+	Created int              `json:"created"`
+	Skipped int              `json:"skipped"`
+	Failed  int              `json:"failed"`
+	Errors  *multierr.Errors `json:"errors"` // 最多 MaxErrors 条，路径为 line[行号].列名
+}
+
+func (r *Report) fail(max int, line int, err error) {
+	r.Failed++
+	if r.Errors.Len() < max {
+		r.Errors.Add(multierr.Index("line", line), err)
+	}
+}
+
+// Import 从 r 逐行读取 CSV，校验后每 BatchSize 行调用一次 insert
+//
+// 返回的报告在出错时也非 nil，包含出错之前已经处理的行
+func Import[T any](ctx context.Context, r io.Reader, cfg Config, insert InsertFunc[T]) (*Report, error) {
+	cfg = cfg.withDefaults()
+	report := &Report{Errors: &multierr.Errors{}}
+
+	fields, err := columns(reflect.TypeFor[T]())
+	if err != nil {
+		return report, err
+	}
+
+	cr := csv.NewReader(bufio.NewReaderSize(r, 64<<10))
+	cr.Comma = cfg.Comma
+	cr.FieldsPerRecord = -1 // 列数不一致按行报错，而不是中止整个文件
+	cr.ReuseRecord = true
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return report, fmt.Errorf("%w: empty file", ErrMissingColumn)
+	}
+	if err != nil {
+		return report, fmt.Errorf("csvimport: read header: %w", err)
+	}
+	index, err := mapHeader(header, fields, cfg.Required)
+	if err != nil {
+		return report, err
+	}
+	width := len(header)
+
+	batch := make([]Row[T], 0, cfg.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		rowErrs, err := insert(ctx, batch)
+		if err != nil {
+			return err
+		}
+		if len(rowErrs) != len(batch) {
+			return fmt.Errorf("csvimport: insert returned %d results for %d rows", len(rowErrs), len(batch))
+		}
+		for i, rowErr := range rowErrs {
+			switch {
+			case rowErr == nil:
+				report.Created++
+			case errors.Is(rowErr, ErrSkip):
+				report.Skipped++
+			default:
+				report.fail(cfg.MaxErrors, batch[i].Line, rowErr)
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			// 引号不匹配等格式错误：记下这一行，继续读下一行
+			report.Rows++
+			report.fail(cfg.MaxErrors, perr.StartLine, perr.Err)
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("csvimport: read: %w", err)
+		}
+		if blank(record) {
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		report.Rows++
+		if report.Rows > cfg.MaxRows {
+			report.Rows--
+			return report, fmt.Errorf("%w: more than %d", ErrTooManyRows, cfg.MaxRows)
+		}
+		if len(record) != width {
+			report.fail(cfg.MaxErrors, line, fmt.Errorf("expected %d fields, got %d", width, len(record)))
+			continue
+		}
+
+		var v T
+		if err := decode(reflect.ValueOf(&v).Elem(), record, fields, index); err != nil {
+			report.fail(cfg.MaxErrors, line, err)
+			continue
+		}
+		if err := validate(&v, fields); err != nil {
+			report.fail(cfg.MaxErrors, line, err)
+			continue
+		}
+		batch = append(batch, Row[T]{Line: line, Value: v})
+		if len(batch) == cfg.BatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, flush()
+}
+
+// blank 空行（csv.Reader 已经跳过完全空白的行，这里处理 ",,," 这种只有分隔符的行）
+func blank(record []string) bool {
+	for _, s := range record {
+		if strings.TrimSpace(s) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ============================================================================
+// 结构体映射
+// ============================================================================
+
+type field struct {
+	name  string // csv 列名
+	index int    // 结构体字段下标
+}
+
+// columns 解析 csv 标签，没有标签的字段不参与导入
+func columns(t reflect.Type) ([]field, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("csvimport: %s is not a struct", t)
+	}
+	var fields []field
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("csv"), ",")
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		switch f.Type.Kind() {
+		case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return nil, fmt.Errorf("csvimport: field %s: unsupported type %s", f.Name, f.Type)
+		}
+		fields = append(fields, field{name: name, index: i})
+	}
+	return fields, nil
+}
+
+// mapHeader 返回每个结构体字段对应的列下标，表头没有的字段为 -1
+// 列名不区分大小写，去掉 Excel 导出时常见的 UTF-8 BOM
+func mapHeader(header []string, fields []field, required []string) ([]int, error) {
+	pos := make(map[string]int, len(header))
+	for i, h := range header {
+		if i == 0 {
+			h = strings.TrimPrefix(h, "\ufeff")
+		}
+		pos[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, name := range required {
+		if _, ok := pos[strings.ToLower(name)]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingColumn, name)
+		}
+	}
+	index := make([]int, len(fields))
+	for i, f := range fields {
+		if p, ok := pos[strings.ToLower(f.name)]; ok {
+			index[i] = p
+		} else {
+			index[i] = -1
+		}
+	}
+	return index, nil
+}
+
+// decode 按列填充结构体，类型转换错误按列名收集
+func decode(v reflect.Value, record []string, fields []field, index []int) error {
+	var errs multierr.Errors
+	for i, f := range fields {
+		if index[i] < 0 {
+			continue
+		}
+		s := strings.TrimSpace(record[index[i]])
+		if s == "" {
+			continue
+		}
+		if err := setField(v.Field(f.index), s); err != nil {
+			errs.Add(f.name, err)
+		}
+	}
+	return errs.Err()
+}
+
+func setField(fv reflect.Value, s string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", s)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		fv.SetFloat(f)
+	}
+	return nil
+}
+
+// validate 用 gin 的 binding.Validator 校验，错误路径换成 csv 列名
+func validate(v any, fields []field) error {
+	if binding.Validator == nil {
+		return nil
+	}
+	err := binding.Validator.ValidateStruct(v)
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	t := reflect.TypeOf(v).Elem()
+	names := make(map[string]string, len(fields))
+	for _, f := range fields {
+		names[t.Field(f.index).Name] = f.name
+	}
+	var errs multierr.Errors
+	for _, fe := range verrs {
+		name, ok := names[fe.StructField()]
+		if !ok {
+			name = fe.StructField()
+		}
+		errs.Add(name, fmt.Errorf("failed on %q", fe.Tag()))
+	}
+	return errs.Err()
+}
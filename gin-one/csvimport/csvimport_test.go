@@ -0,0 +1,183 @@
+package csvimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-learning/multierr"
+)
+
+type userRow struct {
+	Username string `csv:"username" binding:"required,min=3"`
+	Email    string `csv:"email" binding:"required,email"`
+	Age      int    `csv:"age" binding:"gte=0,lte=150"`
+	Admin    bool   `csv:"admin"`
+	Note     string // 没有 csv 标签，不参与导入
+}
+
+// recorder 记录每批收到的行，用户名为 taken 的行返回 ErrSkip
+type recorder struct {
+	batches [][]Row[userRow]
+	taken   map[string]bool
+	fail    error
+}
+
+func (r *recorder) insert(_ context.Context, batch []Row[userRow]) ([]error, error) {
+	if r.fail != nil {
+		return nil, r.fail
+	}
+	r.batches = append(r.batches, append([]Row[userRow](nil), batch...))
+	errs := make([]error, len(batch))
+	for i, row := range batch {
+		if r.taken[row.Value.Username] {
+			errs[i] = ErrSkip
+		}
+	}
+	return errs, nil
+}
+
+func paths(errs *multierr.Errors) []string {
+	var out []string
+	for _, e := range errs.Errors() {
+		out = append(out, e.Path)
+	}
+	return out
+}
+
+func TestImport(t *testing.T) {
+	// 列顺序和结构体不同，多余的 extra 列忽略，表头带 BOM
+	input := "\ufeffEmail,username,age,extra,admin\n" +
+		"alice@example.com,alice,30,x,true\n" +
+		"bob@example.com,bob,,x,\n" + // 空单元格为零值
+		"\n" + // 空行跳过
+		"not-an-email,carol,20,x,false\n" + // 校验失败
+		"dave@example.com,dave,abc,x,false\n" + // 类型错误
+		"erin@example.com,erin,40\n" + // 列数不对
+		"frank@example.com,frank,50,x,false\n" +
+		"taken@example.com,taken,1,x,false\n"
+
+	rec := &recorder{taken: map[string]bool{"taken": true}}
+	report, err := Import(context.Background(), strings.NewReader(input), Config{BatchSize: 2}, rec.insert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rows != 7 || report.Created != 3 || report.Skipped != 1 || report.Failed != 3 {
+		t.Fatalf("report = %+v; want rows 7, created 3, skipped 1, failed 3", report)
+	}
+	want := []string{"line[5].email", "line[6].age", "line[7]"}
+	if got := paths(report.Errors); !reflect.DeepEqual(got, want) {
+		t.Errorf("error paths = %v; want %v", got, want)
+	}
+
+	// 每批最多 2 行，行号对应文件里的行
+	if len(rec.batches) != 2 {
+		t.Fatalf("batches = %d; want 2", len(rec.batches))
+	}
+	first := rec.batches[0]
+	if first[0].Line != 2 || first[1].Line != 3 {
+		t.Errorf("lines = %d, %d; want 2, 3", first[0].Line, first[1].Line)
+	}
+	wantAlice := userRow{Username: "alice", Email: "alice@example.com", Age: 30, Admin: true}
+	if first[0].Value != wantAlice {
+		t.Errorf("row = %+v; want %+v", first[0].Value, wantAlice)
+	}
+}
+
+func TestImportQuotedFields(t *testing.T) {
+	input := "username,email\n" +
+		"\"ali,ce\",alice@example.com\n" +
+		"\"multi\nline\",ml@example.com\n" +
+		"bob,bob@example.com\n"
+	rec := &recorder{}
+	report, err := Import(context.Background(), strings.NewReader(input), Config{}, rec.insert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Created != 3 {
+		t.Fatalf("report = %+v; want 3 created", report)
+	}
+	rows := rec.batches[0]
+	if rows[0].Value.Username != "ali,ce" || rows[1].Value.Username != "multi\nline" {
+		t.Errorf("usernames = %q, %q", rows[0].Value.Username, rows[1].Value.Username)
+	}
+	// 跨行的字段之后，行号仍然对应文件位置
+	if rows[2].Line != 5 {
+		t.Errorf("bob line = %d; want 5", rows[2].Line)
+	}
+}
+
+func TestImportMissingColumn(t *testing.T) {
+	rec := &recorder{}
+	_, err := Import(context.Background(), strings.NewReader("username\nalice\n"),
+		Config{Required: []string{"username", "email"}}, rec.insert)
+	if !errors.Is(err, ErrMissingColumn) || !strings.Contains(err.Error(), "email") {
+		t.Fatalf("err = %v; want ErrMissingColumn for email", err)
+	}
+	if len(rec.batches) != 0 {
+		t.Error("insert called despite missing column")
+	}
+
+	if _, err := Import(context.Background(), strings.NewReader(""), Config{}, rec.insert); !errors.Is(err, ErrMissingColumn) {
+		t.Errorf("empty file: err = %v; want ErrMissingColumn", err)
+	}
+}
+
+func TestImportLimits(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("username,email\n")
+	for i := range 5 {
+		fmt.Fprintf(&b, "user%d,bad%d\n", i, i)
+	}
+
+	rec := &recorder{}
+	report, err := Import(context.Background(), strings.NewReader(b.String()), Config{MaxErrors: 2}, rec.insert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 失败计数完整，错误明细只保留前 2 条
+	if report.Failed != 5 || report.Errors.Len() != 2 {
+		t.Errorf("failed = %d, errors = %d; want 5, 2", report.Failed, report.Errors.Len())
+	}
+
+	_, err = Import(context.Background(), strings.NewReader(b.String()), Config{MaxRows: 3}, rec.insert)
+	if !errors.Is(err, ErrTooManyRows) {
+		t.Errorf("err = %v; want ErrTooManyRows", err)
+	}
+}
+
+func TestImportInsertError(t *testing.T) {
+	boom := errors.New("database is down")
+	rec := &recorder{fail: boom}
+	input := "username,email\nalice,alice@example.com\n"
+	report, err := Import(context.Background(), strings.NewReader(input), Config{}, rec.insert)
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v; want %v", err, boom)
+	}
+	if report == nil || report.Rows != 1 || report.Created != 0 {
+		t.Errorf("report = %+v; want 1 row read, none created", report)
+	}
+}
+
+func TestImportCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Import(ctx, strings.NewReader("username,email\nalice,a@example.com\n"), Config{}, (&recorder{}).insert)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v; want context.Canceled", err)
+	}
+}
+
+func TestImportUnsupportedField(t *testing.T) {
+	type bad struct {
+		Tags []string `csv:"tags"`
+	}
+	_, err := Import(context.Background(), strings.NewReader("tags\n"), Config{},
+		func(context.Context, []Row[bad]) ([]error, error) { return nil, nil })
+	if err == nil || !strings.Contains(err.Error(), "unsupported type") {
+		t.Errorf("err = %v; want unsupported type", err)
+	}
+}
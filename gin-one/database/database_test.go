@@ -0,0 +1,170 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestBuildDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    string
+		wantErr error
+	}{
+		{"dsn wins", Config{Driver: MySQL, DSN: "raw", Host: "ignored"}, "raw", nil},
+		{"sqlite default", Config{Name: "test.db"}, "test.db?_foreign_keys=1", nil},
+		{"sqlite existing query", Config{Name: "file::memory:?cache=shared"}, "file::memory:?cache=shared&_foreign_keys=1", nil},
+		{"sqlite override param", Config{Name: "a.db", Params: map[string]string{"_foreign_keys": "0"}}, "a.db?_foreign_keys=0", nil},
+		{
+			"postgres escapes password",
+			Config{Driver: Postgres, Host: "pg", User: "app", Password: "p@ss:w/rd?", Name: "shop"},
+			"postgres://app:p%40ss%3Aw%2Frd%3F@pg:5432/shop?sslmode=disable",
+			nil,
+		},
+		{
+			"postgres ipv6",
+			Config{Driver: Postgres, Host: "::1", Port: 6432, Name: "n", Params: map[string]string{"sslmode": "require"}},
+			"postgres://[::1]:6432/n?sslmode=require",
+			nil,
+		},
+		{"mysql missing host", Config{Driver: MySQL, Name: "n"}, "", ErrIncomplete},
+		{"sqlite missing name", Config{}, "", ErrIncomplete},
+		{"unknown driver", Config{Driver: "oracle", Name: "n"}, "", ErrUnknownDriver},
+	}
+	for _, tt := range tests {
+		got, err := BuildDSN(tt.cfg)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: BuildDSN error = %v; want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: BuildDSN = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// mysql 参数顺序不固定，解析回 mysql.Config 比较
+func TestBuildDSNMySQL(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want mysql.Config
+	}{
+		{
+			"escapes password",
+			Config{Driver: MySQL, Host: "db", User: "app", Password: "p@ss:w/rd?", Name: "shop"},
+			mysql.Config{User: "app", Passwd: "p@ss:w/rd?", Addr: "db:3306", DBName: "shop", ParseTime: true},
+		},
+		{
+			"custom port and params",
+			Config{Driver: MySQL, Host: "db", Port: 3307, User: "u", Name: "n", Params: map[string]string{"parseTime": "false"}},
+			mysql.Config{User: "u", Addr: "db:3307", DBName: "n"},
+		},
+	}
+	for _, tt := range tests {
+		dsn, err := BuildDSN(tt.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			t.Errorf("%s: ParseDSN(%q): %v", tt.name, dsn, err)
+			continue
+		}
+		if got.User != tt.want.User || got.Passwd != tt.want.Passwd || got.Addr != tt.want.Addr ||
+			got.DBName != tt.want.DBName || got.ParseTime != tt.want.ParseTime {
+			t.Errorf("%s: BuildDSN = %q, parsed %+v; want %+v", tt.name, dsn, got, tt.want)
+		}
+		if got.Params["charset"] != "utf8mb4" || got.Loc != time.Local {
+			t.Errorf("%s: BuildDSN = %q; want charset=utf8mb4 and loc=Local", tt.name, dsn)
+		}
+	}
+}
+
+func TestPoolDefaults(t *testing.T) {
+	tests := []struct {
+		driver string
+		in     Pool
+		want   Pool
+	}{
+		{MySQL, Pool{}, DefaultPool(MySQL)},
+		{Postgres, Pool{MaxOpenConns: 5}, Pool{MaxOpenConns: 5, MaxIdleConns: 5, ConnMaxLifetime: 30 * time.Minute, ConnMaxIdleTime: 5 * time.Minute}},
+		{SQLite, Pool{MaxIdleConns: 2}, Pool{MaxOpenConns: 10, MaxIdleConns: 2}},
+	}
+	for _, tt := range tests {
+		if got := tt.in.withDefaults(tt.driver); got != tt.want {
+			t.Errorf("%s: withDefaults(%+v) = %+v; want %+v", tt.driver, tt.in, got, tt.want)
+		}
+	}
+}
+
+func quiet() *gorm.Config {
+	return &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+}
+
+func TestOpenSQLite(t *testing.T) {
+	db, err := Open(context.Background(), Config{
+		Name: filepath.Join(t.TempDir(), "app.db"),
+		Pool: Pool{MaxOpenConns: 3},
+		GORM: quiet(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	if got := sqlDB.Stats().MaxOpenConnections; got != 3 {
+		t.Errorf("MaxOpenConnections = %d; want 3", got)
+	}
+	var fk int
+	db.Raw("PRAGMA foreign_keys").Scan(&fk)
+	if fk != 1 {
+		t.Errorf("foreign_keys = %d; want 1", fk)
+	}
+}
+
+func TestOpenRetry(t *testing.T) {
+	// 目录不存在，每次 Ping 都失败
+	bad := Config{
+		Name:    filepath.Join(t.TempDir(), "missing", "app.db"),
+		Retries: 2,
+		Backoff: time.Millisecond,
+		GORM:    quiet(),
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	var logs bytes.Buffer
+	bad.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	_, err := Open(context.Background(), bad)
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Fatalf("Open error = %v; want failure after 3 attempts", err)
+	}
+	// 两次重试各记录一条日志（等待时间是随机的，不检查耗时）
+	if n := strings.Count(logs.String(), "retrying"); n != 2 {
+		t.Errorf("logged %d retries; want 2\n%s", n, logs.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bad.Retries, bad.Backoff = 10, time.Hour
+	if _, err := Open(ctx, bad); !errors.Is(err, context.Canceled) {
+		t.Errorf("Open with canceled ctx error = %v; want context.Canceled", err)
+	}
+
+	if _, err := Open(context.Background(), Config{Driver: "oracle", Name: "x"}); !errors.Is(err, ErrUnknownDriver) {
+		t.Errorf("Open unknown driver error = %v; want ErrUnknownDriver", err)
+	}
+}
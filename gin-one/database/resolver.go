@@ -0,0 +1,293 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// 读写分离
+// ============================================================================
+//
+// 【路由规则】
+//
+// | 语句                                          | 去向                                   |
+// |-----------------------------------------------|----------------------------------------|
+// | Create / Update / Delete / Exec               | 主库                                   |
+// | 事务中的任何语句                              | 主库（同一个事务连接）                 |
+// | SELECT ... FOR UPDATE（clause.Locking）       | 主库                                   |
+// | 非 SELECT 开头的 Raw 语句                     | 主库                                   |
+// | ctx 经过 UsePrimary                           | 主库                                   |
+// | 同一请求里已经写过（粘主库）                  | 主库                                   |
+// | 其他查询（Find / First / Count / Raw SELECT） | 健康的从库轮询，没有健康从库时回落主库 |
+//
+// 【为什么写后要粘主库？】
+//
+// 主从复制有延迟（通常几毫秒到几秒）。创建用户后立即查询，
+// 从库可能还没有这条记录，用户看到"刚创建的数据不见了"。
+// StickyMiddleware 给每个请求一个会话，会话里写过一次，之后的读都走主库。
+// 只在同一个请求内有效；跨请求的"读自己的写"需要客户端带标记（如写后几秒内的 Cookie）。
+//
+// 【健康检查】
+//
+// Run 定期 Ping 每个从库，连续失败 FailThreshold 次摘除，Ping 成功一次立即恢复。
+//
+// 【用法】
+//
+//	r := database.NewResolver(database.ResolverConfig{
+//	    Replicas: []database.Replica{{Name: "replica-1", DB: replicaSQLDB}},
+//	})
+//	db.Use(r)
+//	go r.Run(ctx, 10*time.Second)
+//	engine.Use(database.StickyMiddleware())
+//
+
+// Replica 只读副本
+type Replica struct {
+	Name string
+	DB   *sql.DB
+}
+
+// ResolverConfig 读写分离配置
+type ResolverConfig struct {
+	Replicas []Replica
+
+	// FailThreshold 连续 Ping 失败多少次后摘除，默认 3
+	FailThreshold int
+
+	// PingTimeout 单次 Ping 超时，默认 1s
+	PingTimeout time.Duration
+
+	// Logger 记录摘除和恢复，默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// ReplicaStatus 从库状态
+type ReplicaStatus struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+}
+
+type replica struct {
+	Replica
+	healthy  atomic.Bool
+	failures int // 只在 Check 中访问，由 checkMu 保护
+}
+
+// Resolver GORM 插件，把读请求路由到从库
+type Resolver struct {
+	cfg      ResolverConfig
+	primary  gorm.ConnPool
+	replicas []*replica
+	next     atomic.Uint64
+	checkMu  sync.Mutex
+}
+
+// NewResolver 创建读写分离插件，所有从库初始为健康
+func NewResolver(cfg ResolverConfig) *Resolver {
+	if cfg.FailThreshold <= 0 {
+		cfg.FailThreshold = 3
+	}
+	if cfg.PingTimeout <= 0 {
+		cfg.PingTimeout = time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	r := &Resolver{cfg: cfg}
+	for _, rep := range cfg.Replicas {
+		rr := &replica{Replica: rep}
+		rr.healthy.Store(true)
+		r.replicas = append(r.replicas, rr)
+	}
+	return r
+}
+
+// Name 实现 gorm.Plugin
+func (r *Resolver) Name() string {
+	return "database:resolver"
+}
+
+// Initialize 实现 gorm.Plugin，当前连接池作为主库
+func (r *Resolver) Initialize(db *gorm.DB) error {
+	r.primary = db.ConnPool
+	cb := db.Callback()
+	steps := []error{
+		cb.Query().Before("gorm:query").Register("resolver:query", r.routeRead),
+		cb.Row().Before("gorm:row").Register("resolver:row", r.routeRead),
+		cb.Create().Before("gorm:create").Register("resolver:create", r.routeWrite),
+		cb.Update().Before("gorm:update").Register("resolver:update", r.routeWrite),
+		cb.Delete().Before("gorm:delete").Register("resolver:delete", r.routeWrite),
+		cb.Raw().Before("gorm:raw").Register("resolver:raw", r.routeWrite),
+	}
+	for _, err := range steps {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inTransaction 事务中的语句必须留在事务连接上
+func inTransaction(db *gorm.DB) bool {
+	_, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+// routeWrite 写操作走主库，并让当前请求粘在主库上
+// 即使之前同一个 Statement 被路由到了从库（链式调用复用），这里也会切回主库
+func (r *Resolver) routeWrite(db *gorm.DB) {
+	markWritten(db.Statement.Context)
+	if !inTransaction(db) {
+		db.Statement.ConnPool = r.primary
+	}
+}
+
+func (r *Resolver) routeRead(db *gorm.DB) {
+	if inTransaction(db) {
+		return
+	}
+	if r.readFromPrimary(db) {
+		db.Statement.ConnPool = r.primary
+		return
+	}
+	if rep := r.pick(); rep != nil {
+		db.Statement.ConnPool = rep.DB
+		return
+	}
+	db.Statement.ConnPool = r.primary
+}
+
+func (r *Resolver) readFromPrimary(db *gorm.DB) bool {
+	st := db.Statement
+	if _, locking := st.Clauses["FOR"]; locking {
+		return true
+	}
+	if forcedPrimary(st.Context) {
+		return true
+	}
+	// Raw("UPDATE ... RETURNING").Scan 这类原生语句也会走 Row 回调
+	if raw := strings.TrimSpace(st.SQL.String()); raw != "" && !strings.HasPrefix(strings.ToUpper(raw), "SELECT") {
+		markWritten(st.Context)
+		return true
+	}
+	return false
+}
+
+// pick 轮询选择健康的从库，全部不健康时返回 nil
+func (r *Resolver) pick() *replica {
+	n := len(r.replicas)
+	start := r.next.Add(1)
+	for i := range n {
+		rep := r.replicas[(int(start)+i)%n]
+		if rep.healthy.Load() {
+			return rep
+		}
+	}
+	return nil
+}
+
+// Check Ping 所有从库一次，更新健康状态
+func (r *Resolver) Check(ctx context.Context) {
+	r.checkMu.Lock()
+	defer r.checkMu.Unlock()
+	for _, rep := range r.replicas {
+		pctx, cancel := context.WithTimeout(ctx, r.cfg.PingTimeout)
+		err := rep.DB.PingContext(pctx)
+		cancel()
+
+		if err == nil {
+			rep.failures = 0
+			if !rep.healthy.Swap(true) {
+				r.cfg.Logger.Info("replica recovered", "replica", rep.Name)
+			}
+			continue
+		}
+		rep.failures++
+		if rep.failures >= r.cfg.FailThreshold && rep.healthy.Swap(false) {
+			r.cfg.Logger.Warn("replica evicted", "replica", rep.Name, "failures", rep.failures, "error", err)
+		}
+	}
+}
+
+// Run 每隔 interval 检查一次从库，阻塞直到 ctx 取消
+func (r *Resolver) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Check(ctx)
+		}
+	}
+}
+
+// Status 所有从库的健康状态
+func (r *Resolver) Status() []ReplicaStatus {
+	out := make([]ReplicaStatus, len(r.replicas))
+	for i, rep := range r.replicas {
+		out[i] = ReplicaStatus{Name: rep.Name, Healthy: rep.healthy.Load()}
+	}
+	return out
+}
+
+// ============================================================================
+// 请求级别的主库粘滞
+// ============================================================================
+
+type (
+	sessionKey struct{}
+	primaryKey struct{}
+)
+
+type session struct {
+	written atomic.Bool
+}
+
+// WithSession 开启一个读写会话：会话中写过之后，后续读都走主库
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// UsePrimary 强制 ctx 中的所有查询走主库，如"下单后立即查询订单"
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func markWritten(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		s.written.Store(true)
+	}
+}
+
+func forcedPrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	if ctx.Value(primaryKey{}) != nil {
+		return true
+	}
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	return ok && s.written.Load()
+}
+
+// StickyMiddleware 为每个请求开启读写会话，见 WithSession
+func StickyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(WithSession(c.Request.Context()))
+		c.Next()
+	}
+}
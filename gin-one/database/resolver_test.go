@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type node struct {
+	ID   uint
+	Name string
+}
+
+// openNode 打开一个 SQLite 文件并写入一行 name，用来分辨查询落在哪个库
+func openNode(t *testing.T, path, name string) *gorm.DB {
+	t.Helper()
+	db, err := Open(context.Background(), Config{Name: path, GORM: quiet()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	db.AutoMigrate(&node{})
+	db.Create(&node{Name: name})
+	return db
+}
+
+func newResolverDB(t *testing.T, threshold int, replicas ...Replica) (*gorm.DB, *Resolver) {
+	t.Helper()
+	dir := t.TempDir()
+	primary := openNode(t, filepath.Join(dir, "primary.db"), "primary")
+	r := NewResolver(ResolverConfig{
+		Replicas:      replicas,
+		FailThreshold: threshold,
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := primary.Use(r); err != nil {
+		t.Fatal(err)
+	}
+	return primary, r
+}
+
+func replicaNode(t *testing.T, name string) Replica {
+	t.Helper()
+	db := openNode(t, filepath.Join(t.TempDir(), name+".db"), name)
+	sqlDB, _ := db.DB()
+	return Replica{Name: name, DB: sqlDB}
+}
+
+// firstName 第一行的 name 即查询落到的库
+func firstName(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	var n node
+	if err := db.Order("id").First(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n.Name
+}
+
+func TestResolverRouting(t *testing.T) {
+	db, _ := newResolverDB(t, 3, replicaNode(t, "replica"))
+	bg := context.Background()
+
+	tests := []struct {
+		name string
+		run  func() string
+		want string
+	}{
+		{"plain read", func() string { return firstName(t, db) }, "replica"},
+		{"raw select", func() string {
+			var name string
+			db.Raw("SELECT name FROM nodes ORDER BY id LIMIT 1").Scan(&name)
+			return name
+		}, "replica"},
+		{"use primary", func() string { return firstName(t, db.WithContext(UsePrimary(bg))) }, "primary"},
+		{"inside transaction", func() string {
+			var got string
+			db.Transaction(func(tx *gorm.DB) error {
+				got = firstName(t, tx)
+				return nil
+			})
+			return got
+		}, "primary"},
+		{"read without session after write", func() string {
+			db.WithContext(bg).Create(&node{Name: "x"})
+			return firstName(t, db.WithContext(bg))
+		}, "replica"},
+	}
+	for _, tt := range tests {
+		if got := tt.run(); got != tt.want {
+			t.Errorf("%s: served by %q; want %q", tt.name, got, tt.want)
+		}
+	}
+
+	var count int64
+	db.WithContext(UsePrimary(bg)).Model(&node{}).Count(&count)
+	if count != 2 {
+		t.Errorf("primary rows = %d; want 2 (writes must go to primary)", count)
+	}
+}
+
+func TestResolverSticky(t *testing.T) {
+	db, _ := newResolverDB(t, 3, replicaNode(t, "replica"))
+	ctx := WithSession(context.Background())
+
+	if got := firstName(t, db.WithContext(ctx)); got != "replica" {
+		t.Errorf("before write: served by %q; want replica", got)
+	}
+	db.WithContext(ctx).Create(&node{Name: "new"})
+	if got := firstName(t, db.WithContext(ctx)); got != "primary" {
+		t.Errorf("after write: served by %q; want primary", got)
+	}
+	if got := firstName(t, db.WithContext(WithSession(context.Background()))); got != "replica" {
+		t.Errorf("new session: served by %q; want replica", got)
+	}
+}
+
+func TestResolverRoundRobin(t *testing.T) {
+	db, _ := newResolverDB(t, 3, replicaNode(t, "a"), replicaNode(t, "b"))
+	seen := map[string]int{}
+	for range 4 {
+		seen[firstName(t, db)]++
+	}
+	if seen["a"] != 2 || seen["b"] != 2 {
+		t.Errorf("reads per replica = %v; want 2 each", seen)
+	}
+}
+
+func TestResolverEviction(t *testing.T) {
+	// 目录不存在时 Ping 失败，创建目录后恢复
+	dir := filepath.Join(t.TempDir(), "later")
+	flaky, err := sql.Open("sqlite3", filepath.Join(dir, "replica.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer flaky.Close()
+	db, r := newResolverDB(t, 2, Replica{Name: "flaky", DB: flaky})
+	ctx := context.Background()
+
+	r.Check(ctx)
+	if !r.Status()[0].Healthy {
+		t.Error("evicted after 1 failure; want threshold 2")
+	}
+	r.Check(ctx)
+	if r.Status()[0].Healthy {
+		t.Fatal("still healthy after 2 failures")
+	}
+	if got := firstName(t, db); got != "primary" {
+		t.Errorf("no healthy replica: served by %q; want primary", got)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	r.Check(ctx)
+	if !r.Status()[0].Healthy {
+		t.Error("not recovered after successful ping")
+	}
+}
@@ -0,0 +1,222 @@
+// ============================================================================
+// Package diagnostics 运行时诊断接口：pprof、goroutine 快照、内存 / GC 统计、锁和阻塞采样开关
+// ============================================================================
+//
+// go-with-ai-one/12_concurrency.go 在命令行里打印过 NumGoroutine、GOMAXPROCS，
+// 线上服务没有终端，这些信息要通过接口取：
+//
+// | 接口                     | 内容                                    | 典型用途                         |
+// |--------------------------|-----------------------------------------|----------------------------------|
+// | GET  /pprof/             | pprof 索引页                            | 浏览器查看有哪些 profile         |
+// | GET  /pprof/profile      | CPU profile（?seconds=10）              | CPU 飙高时找热点函数             |
+// | GET  /pprof/heap         | 堆内存 profile                          | 内存持续增长时找分配点           |
+// | GET  /pprof/trace        | 执行跟踪（?seconds=5）                  | 调度延迟、GC 停顿                |
+// | GET  /pprof/:name        | goroutine / allocs / block / mutex ...  | 其他内置 profile                 |
+// | GET  /goroutines         | 所有 goroutine 的完整调用栈（文本）     | 请求卡住、goroutine 泄漏         |
+// | GET  /runtime            | goroutine 数、堆大小、GC 次数和停顿     | 快速看一眼，不用下载 profile     |
+// | GET  /profiling          | 当前锁 / 阻塞采样率                     |                                  |
+// | PUT  /profiling          | 开关锁 / 阻塞采样                       | 排查锁竞争前打开，排查完关闭     |
+//
+// 【用法】
+//
+//	debug := r.Group("/debug", JWTAuthMiddleware(), RoleMiddleware("admin"))
+//	diagnostics.Register(debug)
+//
+//	curl -o cpu.out "http://localhost:8080/debug/pprof/profile?seconds=10" -H "Authorization: Bearer <token>"
+//	go tool pprof -http :9090 cpu.out
+//
+// 【为什么必须加权限】
+//
+// goroutine 调用栈里有函数参数、SQL、请求路径；heap profile 能看出业务逻辑；
+// CPU profile 和 trace 在采样期间拖慢整个进程。
+// 不要挂在公开路由上，也不要 import _ "net/http/pprof"：它注册在 http.DefaultServeMux，
+// 一旦有人用 DefaultServeMux 起了 server 就直接暴露了。
+//
+// ============================================================================
+package diagnostics
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	rpprof "runtime/pprof"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// startedAt 进程启动时间，用于计算 uptime
+var startedAt = time.Now()
+
+// blockRate runtime 只提供 SetBlockProfileRate，没有读取当前值的函数，这里记一份
+var blockRate atomic.Int64
+
+// Register 在 group 上注册诊断接口，调用方负责加认证和管理员权限中间件
+func Register(group *gin.RouterGroup) {
+	p := group.Group("/pprof")
+	{
+		p.GET("/", gin.WrapF(pprof.Index))
+		p.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		p.GET("/profile", gin.WrapF(pprof.Profile))
+		p.GET("/symbol", gin.WrapF(pprof.Symbol))
+		p.POST("/symbol", gin.WrapF(pprof.Symbol))
+		p.GET("/trace", gin.WrapF(pprof.Trace))
+		p.GET("/:name", profile)
+	}
+
+	group.GET("/goroutines", goroutines)
+	group.GET("/runtime", runtimeStats)
+	group.GET("/profiling", getProfiling)
+	group.PUT("/profiling", setProfiling)
+}
+
+// profile 内置的命名 profile；pprof.Index 按固定前缀 /debug/pprof/ 取名字，
+// 挂在其他路径下时取不到，所以单独注册
+func profile(c *gin.Context) {
+	name := c.Param("name")
+	if rpprof.Lookup(name) == nil {
+		response.Error(c, http.StatusNotFound, "profile_not_found", "没有这个 profile: "+name)
+		return
+	}
+	pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+}
+
+// goroutines 所有 goroutine 的调用栈
+//
+//	?debug=2（默认）每个 goroutine 单独列出，带状态和阻塞时长，如 "[chan receive, 12 minutes]"
+//	?debug=1        相同调用栈合并计数，goroutine 很多时先看这个
+func goroutines(c *gin.Context) {
+	level := 2
+	if c.Query("debug") == "1" {
+		level = 1
+	}
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Status(http.StatusOK)
+	_ = rpprof.Lookup("goroutine").WriteTo(c.Writer, level)
+}
+
+// RuntimeStats 运行时快照
+type RuntimeStats struct {
+	GoVersion    string  `json:"go_version"`
+	Uptime       string  `json:"uptime"`
+	NumCPU       int     `json:"num_cpu"`
+	GOMAXPROCS   int     `json:"gomaxprocs"`
+	NumGoroutine int     `json:"num_goroutine"`
+	NumCgoCall   int64   `json:"num_cgo_call"`
+	Memory       Memory  `json:"memory"`
+	GC           GCStats `json:"gc"`
+}
+
+// Memory 内存统计，单位字节
+type Memory struct {
+	HeapAlloc   uint64 `json:"heap_alloc"`   // 堆上存活对象（和未回收的垃圾）
+	HeapInuse   uint64 `json:"heap_inuse"`   // 堆上正在使用的 span
+	HeapObjects uint64 `json:"heap_objects"` // 堆上对象数
+	StackInuse  uint64 `json:"stack_inuse"`  // goroutine 栈
+	Sys         uint64 `json:"sys"`          // 从操作系统申请的总量，接近进程 RSS
+	TotalAlloc  uint64 `json:"total_alloc"`  // 累计分配，增长速度反映分配压力
+}
+
+// GCStats GC 统计
+type GCStats struct {
+	NumGC         uint32  `json:"num_gc"`
+	NextGC        uint64  `json:"next_gc"`         // 堆达到这个大小时触发下一次 GC
+	LastGC        string  `json:"last_gc"`         // 上一次 GC 的时间，从未 GC 时为空
+	PauseTotal    string  `json:"pause_total"`     // 累计 STW 停顿
+	LastPause     string  `json:"last_pause"`      // 最近一次 STW 停顿
+	CPUFraction   float64 `json:"cpu_fraction"`    // GC 占用的 CPU 比例
+	MemoryLimitMB int64   `json:"memory_limit_mb"` // GOMEMLIMIT，-1 表示没有设置
+}
+
+// ReadRuntimeStats 采集运行时快照
+//
+// runtime.ReadMemStats 会短暂 stop the world，不要在热路径上调用。
+func ReadRuntimeStats() RuntimeStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	s := RuntimeStats{
+		GoVersion:    runtime.Version(),
+		Uptime:       time.Since(startedAt).Round(time.Second).String(),
+		NumCPU:       runtime.NumCPU(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0), // 0 表示只查询
+		NumGoroutine: runtime.NumGoroutine(),
+		NumCgoCall:   runtime.NumCgoCall(),
+		Memory: Memory{
+			HeapAlloc:   m.HeapAlloc,
+			HeapInuse:   m.HeapInuse,
+			HeapObjects: m.HeapObjects,
+			StackInuse:  m.StackInuse,
+			Sys:         m.Sys,
+			TotalAlloc:  m.TotalAlloc,
+		},
+		GC: GCStats{
+			NumGC:       m.NumGC,
+			NextGC:      m.NextGC,
+			PauseTotal:  time.Duration(m.PauseTotalNs).String(),
+			CPUFraction: m.GCCPUFraction,
+		},
+	}
+	if m.NumGC > 0 {
+		s.GC.LastGC = time.Unix(0, int64(m.LastGC)).Format(time.RFC3339)
+		s.GC.LastPause = time.Duration(m.PauseNs[(m.NumGC+255)%256]).String() // 环形缓冲区中最新的一个
+	}
+
+	// SetMemoryLimit 传入负数时只返回当前值，不做修改
+	s.GC.MemoryLimitMB = -1
+	if limit := debug.SetMemoryLimit(-1); limit != int64(^uint64(0)>>1) {
+		s.GC.MemoryLimitMB = limit >> 20
+	}
+	return s
+}
+
+func runtimeStats(c *gin.Context) {
+	response.Success(c, ReadRuntimeStats())
+}
+
+// Profiling 锁 / 阻塞采样率
+//
+// MutexFraction：平均每 N 次锁竞争采样 1 次，0 表示关闭
+// BlockRate：    阻塞每 N 纳秒采样 1 次，1 表示全部记录，0 表示关闭
+//
+// 两者默认关闭：采样有开销，BlockRate=1 时每次 channel 阻塞都要记录调用栈。
+// 打开后 /pprof/mutex、/pprof/block 才有数据。
+type Profiling struct {
+	MutexFraction *int `json:"mutex_fraction" binding:"omitempty,gte=0"`
+	BlockRate     *int `json:"block_rate" binding:"omitempty,gte=0"`
+}
+
+func currentProfiling() gin.H {
+	return gin.H{
+		"mutex_fraction": runtime.SetMutexProfileFraction(-1), // 负数只查询
+		"block_rate":     blockRate.Load(),
+	}
+}
+
+func getProfiling(c *gin.Context) {
+	response.Success(c, currentProfiling())
+}
+
+// setProfiling 只修改传入的字段
+//
+//	curl -X PUT /debug/profiling -d '{"mutex_fraction":5,"block_rate":10000}'  # 打开
+//	curl -X PUT /debug/profiling -d '{"mutex_fraction":0,"block_rate":0}'      # 关闭
+func setProfiling(c *gin.Context) {
+	var req Profiling
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid_request", "采样率必须是非负整数")
+		return
+	}
+	if req.MutexFraction != nil {
+		runtime.SetMutexProfileFraction(*req.MutexFraction)
+	}
+	if req.BlockRate != nil {
+		runtime.SetBlockProfileRate(*req.BlockRate)
+		blockRate.Store(int64(*req.BlockRate))
+	}
+	response.Success(c, currentProfiling())
+}
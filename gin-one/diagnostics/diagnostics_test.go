@@ -0,0 +1,102 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	Register(r.Group("/debug"))
+	return r
+}
+
+func TestEndpoints(t *testing.T) {
+	r := newRouter()
+	tests := []struct {
+		name, method, path string
+		wantCode           int
+		wantBody           string
+	}{
+		{"pprof index", "GET", "/debug/pprof/", 200, "Types of profiles available"},
+		{"named profile", "GET", "/debug/pprof/heap?debug=1", 200, "heap profile"},
+		{"goroutine profile", "GET", "/debug/pprof/goroutine?debug=1", 200, "goroutine profile"},
+		{"unknown profile", "GET", "/debug/pprof/nope", 404, "profile_not_found"},
+		{"cmdline", "GET", "/debug/pprof/cmdline", 200, ""},
+		{"goroutine dump", "GET", "/debug/goroutines", 200, "goroutine "},
+		{"goroutine dump grouped", "GET", "/debug/goroutines?debug=1", 200, "goroutine profile: total"},
+		{"runtime stats", "GET", "/debug/runtime", 200, `"num_goroutine"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("%s %s = %d %.200s; want %d containing %q", tt.method, tt.path, rec.Code, rec.Body, tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRuntimeStats(t *testing.T) {
+	runtime.GC()
+	s := ReadRuntimeStats()
+	if s.NumGoroutine < 1 || s.GOMAXPROCS < 1 || s.Memory.HeapAlloc == 0 || s.Memory.Sys == 0 {
+		t.Errorf("stats = %+v", s)
+	}
+	if s.GC.NumGC == 0 || s.GC.LastGC == "" || s.GC.LastPause == "" {
+		t.Errorf("gc stats after runtime.GC() = %+v", s.GC)
+	}
+}
+
+func TestProfiling(t *testing.T) {
+	r := newRouter()
+	t.Cleanup(func() {
+		runtime.SetMutexProfileFraction(0)
+		runtime.SetBlockProfileRate(0)
+		blockRate.Store(0)
+	})
+
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  int
+		wantMutex float64
+		wantBlock float64
+	}{
+		{"enable both", `{"mutex_fraction":5,"block_rate":10000}`, 200, 5, 10000},
+		{"partial update keeps block rate", `{"mutex_fraction":0}`, 200, 0, 10000},
+		{"negative rejected", `{"block_rate":-1}`, 400, 0, 10000},
+		{"invalid json", `{`, 400, 0, 10000},
+		{"disable", `{"block_rate":0}`, 200, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/debug/profiling", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("PUT = %d %s; want %d", rec.Code, rec.Body, tt.wantCode)
+			}
+
+			// 无论修改成功与否，GET 返回的都是实际生效的值
+			rec = httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/profiling", nil))
+			var resp struct {
+				Data map[string]float64 `json:"data"`
+			}
+			_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+			if resp.Data["mutex_fraction"] != tt.wantMutex || resp.Data["block_rate"] != tt.wantBlock {
+				t.Errorf("profiling = %v; want mutex %v block %v", resp.Data, tt.wantMutex, tt.wantBlock)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+// ============================================================================
+// Package diff 结构体比较：用反射逐字段比较两个结构体（或结构体和一组更新），得到有序的变更列表
+// ============================================================================
+//
+// 【两种用法】
+//
+//	changes, _ := diff.Structs(before, after, diff.Options{})
+//	// [{Field: "age", Old: 20, New: 21}, {Field: "address.city", Old: "北京", New: "上海"}]
+//
+//	changes, _ := diff.Updates(user, map[string]any{"age": 21, "status": "active"}, diff.Options{})
+//	// 只返回和 user 当前值不同的字段；status 没变就不在结果里
+//
+// 第二种用于 PATCH：请求里没变的字段不写数据库，全都没变时连 UPDATE 都不用发，
+// updated_at 不会被刷新，客户端缓存的 ETag 仍然有效。
+//
+// 【比较规则】
+//
+// | 字段类型                                     | 处理                                          |
+// |----------------------------------------------|-----------------------------------------------|
+// | 嵌套结构体                                   | 递归比较，路径用 . 连接：address.city         |
+// | 匿名嵌入（gorm.Model）                       | 展开到外层，路径里没有 Model                  |
+// | 指针                                         | 解引用后比较，nil 的 Old / New 为 nil         |
+// | time.Time 等有 Equal 方法的类型              | 用 Equal 比较：同一时刻不同时区不算变化       |
+// | 实现 json.Marshaler / driver.Valuer 的结构体 | 当作一个值整体比较（gorm.DeletedAt 不会拆开） |
+// | 切片、map、interface、自引用的结构体         | reflect.DeepEqual                             |
+// | 未导出字段                                   | 跳过                                          |
+//
+// 字段名默认取 json 标签（json:"-" 跳过），没有标签时用字段名；
+// 审计日志用 Options.Name 改成数据库列名（见 audit 包）。
+//
+// ============================================================================
+package diff
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// 错误定义
+var (
+	ErrNotStruct    = errors.New("diff: value is not a struct")
+	ErrTypeMismatch = errors.New("diff: values have different types")
+	ErrUnknownField = errors.New("diff: unknown field")
+)
+
+// Change 一个字段的旧值和新值
+type Change struct {
+	Field string `json:"field"` // 字段路径，如 age、address.city
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// Changes 变更列表，按结构体字段定义的顺序排列
+type Changes []Change
+
+// Get 按路径查找
+func (cs Changes) Get(field string) (Change, bool) {
+	for _, c := range cs {
+		if c.Field == field {
+			return c, true
+		}
+	}
+	return Change{}, false
+}
+
+// Fields 变化的字段路径
+func (cs Changes) Fields() []string {
+	fields := make([]string, len(cs))
+	for i, c := range cs {
+		fields[i] = c.Field
+	}
+	return fields
+}
+
+// Map 字段路径 → 新值，可以直接交给 db.Model(&row).Updates(changes.Map())
+func (cs Changes) Map() map[string]any {
+	m := make(map[string]any, len(cs))
+	for _, c := range cs {
+		m[c.Field] = c.New
+	}
+	return m
+}
+
+// Options 比较选项
+type Options struct {
+	// Name 字段在路径中的名字，返回 "" 跳过该字段（嵌套结构体整个跳过）
+	// 默认取 json 标签名，没有时用字段名，json:"-" 跳过
+	Name func(f reflect.StructField) string
+}
+
+func (o Options) name(f reflect.StructField) string {
+	if o.Name != nil {
+		return o.Name(f)
+	}
+	return JSONName(f)
+}
+
+// JSONName 默认的字段命名：json 标签名，没有时用字段名，json:"-" 返回 ""
+func JSONName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
+
+// Structs 比较同一类型的两个结构体（或结构体指针），返回有变化的字段
+func Structs(old, new any, opts Options) (Changes, error) {
+	a, err := structValue(old)
+	if err != nil {
+		return nil, err
+	}
+	b, err := structValue(new)
+	if err != nil {
+		return nil, err
+	}
+	if a.Type() != b.Type() {
+		return nil, fmt.Errorf("%w: %s and %s", ErrTypeMismatch, a.Type(), b.Type())
+	}
+	var changes Changes
+	walk(a.Type(), opts, func(p path) {
+		if p.node {
+			return
+		}
+		x, y := p.get(a), p.get(b)
+		if !equal(x, y) {
+			changes = append(changes, Change{Field: p.name, Old: iface(x), New: iface(y)})
+		}
+	})
+	return changes, nil
+}
+
+// Updates 比较结构体 cur 的当前值和 updates（字段路径 → 新值），返回会产生变化的字段
+//
+// updates 的值可以是指针（解引用后比较，nil 表示设为空），类型不同但可以无损转换时
+// （JSON 解析出的 float64 对 int 字段、string 对自定义字符串类型）按字段类型比较。
+// 不认识的路径返回 ErrUnknownField。
+func Updates(cur any, updates map[string]any, opts Options) (Changes, error) {
+	v, err := structValue(cur)
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]path, len(updates))
+	var order []string
+	walk(v.Type(), opts, func(p path) {
+		if _, ok := updates[p.name]; ok {
+			paths[p.name] = p
+			order = append(order, p.name)
+		}
+	})
+	for name := range updates {
+		if _, ok := paths[name]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownField, name)
+		}
+	}
+
+	var changes Changes
+	for _, name := range order {
+		p := paths[name]
+		old := p.get(v)
+		next := convert(reflect.ValueOf(updates[name]), p.typ)
+		if !equal(old, next) {
+			changes = append(changes, Change{Field: name, Old: iface(old), New: iface(next)})
+		}
+	}
+	return changes, nil
+}
+
+func structValue(x any) (reflect.Value, error) {
+	v := reflect.ValueOf(x)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%w: %T", ErrNotStruct, x)
+	}
+	return v, nil
+}
+
+// ============================================================================
+// 遍历
+// ============================================================================
+
+// path 一个字段：从外层结构体到它的字段下标，每一段之后可能隔着指针
+type path struct {
+	name  string
+	index [][]int
+	typ   reflect.Type // 解引用后的字段类型
+	node  bool         // 需要递归比较的嵌套结构体，自身不直接比较
+}
+
+// get 取出字段的值，途中遇到 nil 指针返回无效的 reflect.Value；指针字段返回解引用后的值
+func (p path) get(v reflect.Value) reflect.Value {
+	for _, idx := range p.index {
+		v = indirect(v)
+		if !v.IsValid() {
+			return v
+		}
+		v = v.FieldByIndex(idx)
+	}
+	return indirect(v)
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func deref(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// walk 按定义顺序报告每个字段，嵌套结构体先报告自身（node）再报告子字段
+func walk(t reflect.Type, opts Options, fn func(path)) {
+	var visit func(t reflect.Type, prefix string, base [][]int, last []int)
+	seen := map[reflect.Type]bool{t: true} // 当前路径上的结构体，遇到自引用（链表、树）时整体比较
+	visit = func(t reflect.Type, prefix string, base [][]int, last []int) {
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			idx := append(slices.Clone(last), i)
+			ft := deref(sf.Type)
+			nested := ft.Kind() == reflect.Struct && !leaf(ft) && !seen[ft]
+
+			if sf.Anonymous && nested && sf.Tag.Get("json") == "" {
+				// 嵌入的结构体展开到外层；隔着指针时另起一段
+				seen[ft] = true
+				if sf.Type.Kind() == reflect.Pointer {
+					visit(ft, prefix, append(slices.Clone(base), idx), nil)
+				} else {
+					visit(ft, prefix, base, idx)
+				}
+				delete(seen, ft)
+				continue
+			}
+			name := opts.name(sf)
+			if name == "" {
+				continue
+			}
+			index := append(slices.Clone(base), idx)
+			fn(path{name: prefix + name, index: index, typ: ft, node: nested})
+			if nested {
+				seen[ft] = true
+				visit(ft, prefix+name+".", index, nil)
+				delete(seen, ft)
+			}
+		}
+	}
+	visit(t, "", nil, nil)
+}
+
+// ============================================================================
+// 比较
+// ============================================================================
+
+var (
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+	valuer        = reflect.TypeFor[driver.Valuer]()
+)
+
+// leaf 结构体有自己的序列化方式时当作一个值，不拆开比较
+func leaf(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	pt := reflect.PointerTo(t)
+	for _, it := range []reflect.Type{jsonMarshaler, textMarshaler, valuer} {
+		if t.Implements(it) || pt.Implements(it) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal 无效值表示 nil；有 Equal(T) bool 方法时用它，否则 DeepEqual
+func equal(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	if m, ok := a.Type().MethodByName("Equal"); ok {
+		mt := m.Type
+		if mt.NumIn() == 2 && mt.In(1) == a.Type() && mt.NumOut() == 1 && mt.Out(0).Kind() == reflect.Bool {
+			return m.Func.Call([]reflect.Value{a, b})[0].Bool()
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// convert 把更新值转换成字段类型；只接受能无损转回去的转换，1.5 不会被当成 int 的 1
+func convert(v reflect.Value, t reflect.Type) reflect.Value {
+	v = indirect(v)
+	if !v.IsValid() || v.Type() == t || !v.Type().ConvertibleTo(t) {
+		return v
+	}
+	c := v.Convert(t)
+	if c.Type().ConvertibleTo(v.Type()) && reflect.DeepEqual(c.Convert(v.Type()).Interface(), v.Interface()) {
+		return c
+	}
+	return v
+}
+
+func iface(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
@@ -0,0 +1,130 @@
+package diff
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type address struct {
+	City   string `json:"city"`
+	Street string `json:"street"`
+}
+
+type status string
+
+type profile struct {
+	gorm.Model
+	Name     string     `json:"name"`
+	Age      int        `json:"age"`
+	Status   status     `json:"status"`
+	Nickname *string    `json:"nickname"`
+	Home     address    `json:"home"`
+	Work     *address   `json:"work"`
+	Birthday time.Time  `json:"birthday"`
+	Tags     []string   `json:"tags"`
+	Secret   string     `json:"-"`
+	Parent   *profile   `json:"parent"`
+	LastSeen *time.Time `json:"last_seen"`
+	internal int
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestStructs(t *testing.T) {
+	day := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	old := profile{
+		Name: "tom", Age: 20, Status: "active", Nickname: ptr("t"),
+		Home:     address{City: "北京", Street: "长安街"},
+		Birthday: day, Tags: []string{"a"}, Secret: "x", internal: 1,
+	}
+	old.ID = 1
+	cur := old
+	cur.Age = 21
+	cur.Nickname = ptr("t") // 不同指针，相同的值
+	cur.Home.City = "上海"
+	cur.Work = &address{City: "深圳"}
+	cur.Birthday = day.In(time.FixedZone("CST", 8*3600)) // 同一时刻
+	cur.Tags = []string{"a", "b"}
+	cur.Secret = "y"
+	cur.internal = 2
+	cur.DeletedAt = gorm.DeletedAt{Time: day, Valid: true}
+
+	changes, err := Structs(old, &cur, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Changes{
+		{"DeletedAt", gorm.DeletedAt{}, cur.DeletedAt},
+		{"age", 20, 21},
+		{"home.city", "北京", "上海"},
+		{"work.city", nil, "深圳"},
+		{"work.street", nil, ""},
+		{"tags", []string{"a"}, []string{"a", "b"}},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes =\n%v\nwant\n%v", changes, want)
+	}
+
+	if changes, _ := Structs(cur, cur, Options{}); len(changes) != 0 {
+		t.Errorf("same value changes = %v", changes)
+	}
+	if _, err := Structs(cur, address{}, Options{}); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("mismatch err = %v", err)
+	}
+	if _, err := Structs(1, 2, Options{}); !errors.Is(err, ErrNotStruct) {
+		t.Errorf("not struct err = %v", err)
+	}
+}
+
+func TestStructsCustomName(t *testing.T) {
+	old, cur := profile{Name: "a", Age: 1}, profile{Name: "b", Age: 2}
+	changes, _ := Structs(old, cur, Options{Name: func(f reflect.StructField) string {
+		if f.Name == "Age" {
+			return ""
+		}
+		return strings.ToUpper(f.Name)
+	}})
+	if got := changes.Fields(); !reflect.DeepEqual(got, []string{"NAME"}) {
+		t.Errorf("fields = %v", got)
+	}
+}
+
+func TestUpdates(t *testing.T) {
+	cur := profile{Name: "tom", Age: 20, Status: "active", Home: address{City: "北京"}}
+	seen := time.Now()
+
+	changes, err := Updates(&cur, map[string]any{
+		"name":      "tom",           // 没变
+		"age":       float64(20),     // JSON 数字，按 int 比较，没变
+		"status":    "banned",        // string → status
+		"nickname":  ptr("tommy"),    // 指针解引用
+		"home.city": "北京",            // 嵌套路径，没变
+		"last_seen": &seen,           // nil → 时间
+		"work":      (*address)(nil), // nil → nil，没变
+	}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := changes.Fields(); !reflect.DeepEqual(got, []string{"status", "nickname", "last_seen"}) {
+		t.Errorf("fields = %v", got)
+	}
+	if c, _ := changes.Get("status"); c.Old != status("active") || c.New != status("banned") {
+		t.Errorf("status change = %+v", c)
+	}
+	if m := changes.Map(); m["nickname"] != "tommy" {
+		t.Errorf("map = %v", m)
+	}
+
+	// 有损转换不算相等
+	if changes, _ := Updates(cur, map[string]any{"age": 20.5}, Options{}); len(changes) != 1 {
+		t.Errorf("20.5 vs 20 changes = %v", changes)
+	}
+	if _, err := Updates(cur, map[string]any{"password": "x"}, Options{}); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("unknown field err = %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+// ============================================================================
+// Package download 文件下载：Range 断点续传、ETag 缓存、限速
+// ============================================================================
+//
+// 【Range 请求】
+//
+//	GET /download/big.zip          Range: bytes=1048576-
+//	206 Partial Content            Content-Range: bytes 1048576-5242879/5242880
+//
+// | Range 头         | 含义                     | 响应                               |
+// |------------------|--------------------------|------------------------------------|
+// | 无               | 整个文件                 | 200，Accept-Ranges: bytes          |
+// | bytes=0-499      | 前 500 字节              | 206                                |
+// | bytes=500-       | 从 500 到末尾            | 206                                |
+// | bytes=-500       | 最后 500 字节            | 206                                |
+// | bytes=9999-      | 超出文件大小             | 416，Content-Range: bytes */大小   |
+// | bytes=0-1,5-9    | 多个范围                 | 忽略 Range，200 返回整个文件       |
+//
+// 多个范围要用 multipart/byteranges 响应，下载工具断点续传用不到，按 RFC 9110 直接忽略。
+//
+// 【为什么不用 http.ServeContent？】
+//
+// ServeContent 需要 io.ReadSeeker，本地文件可以，S3 对象不行（Seek 意味着重新发请求）。
+// 这里先用 Ranger.Stat 拿到大小和 ETag，解析出范围后再用 GetRange 只取需要的部分，
+// S3 后端由 S3 自己处理 Range，不会把整个对象拉到应用服务器。
+// 没实现 Ranger 的 Blob 退化为整个文件下载，响应 Accept-Ranges: none。
+//
+// 【ETag 与续传安全】
+//
+//	If-None-Match: "abc"   和当前 ETag 相同 → 304，不传内容
+//	If-Range: "abc"        和当前 ETag 相同才按 Range 返回，否则返回整个文件
+//
+// 下载到一半文件被替换时，If-Range 保证客户端不会把新文件的后半段拼到旧文件的前半段上。
+//
+// 【限速】
+//
+// Options.Rate 限制单个连接的速度（字节/秒），每写出 Rate/10 字节检查一次进度，
+// 超前就睡一会儿。这是每个连接单独限速，限制总带宽需要所有连接共享一个令牌桶。
+//
+// ============================================================================
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"go-one/pools"
+	"go-one/storage"
+)
+
+// 错误定义
+var (
+	// ErrInvalidRange Range 头格式不对或包含多个范围，按没有 Range 处理
+	ErrInvalidRange = errors.New("download: invalid range")
+	// ErrUnsatisfiable 范围超出文件大小，响应 416
+	ErrUnsatisfiable = errors.New("download: range not satisfiable")
+)
+
+// Options 下载选项
+type Options struct {
+	Rate       int64  // 单个连接限速（字节/秒），0 表示不限
+	Attachment bool   // 加 Content-Disposition: attachment，浏览器弹出保存对话框
+	Filename   string // 保存时的文件名，默认取 key 的最后一段
+}
+
+// ParseRange 解析单个 bytes 范围，返回起始位置和长度
+func ParseRange(header string, size int64) (start, length int64, err error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, ErrInvalidRange
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, ErrInvalidRange
+	}
+
+	if first == "" {
+		// bytes=-N：最后 N 个字节
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, ErrInvalidRange
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, ErrUnsatisfiable
+		}
+		n = min(n, size)
+		return size - n, n, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, ErrInvalidRange
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, ErrInvalidRange
+		}
+		end = min(end, size-1)
+	}
+	if start >= size {
+		return 0, 0, ErrUnsatisfiable
+	}
+	return start, end - start + 1, nil
+}
+
+// Serve 把 key 对应的对象写到响应里，支持 GET 和 HEAD
+//
+// 在写响应之前失败时返回错误（storage.ErrNotFound、storage.ErrInvalidKey 等），
+// 由调用方决定错误响应的格式；已经开始传输后的错误（通常是客户端断开）只能中断连接，也会返回。
+func Serve(w http.ResponseWriter, r *http.Request, b storage.Blob, key string, opt Options) error {
+	ranger, ok := b.(storage.Ranger)
+	if !ok {
+		return serveWhole(w, r, b, key, opt)
+	}
+
+	info, err := ranger.Stat(r.Context(), key)
+	if err != nil {
+		return err
+	}
+	h := w.Header()
+	h.Set("Accept-Ranges", "bytes")
+	if info.ETag != "" {
+		h.Set("ETag", info.ETag)
+	}
+	if !info.ModTime.IsZero() {
+		h.Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	setContentHeaders(h, key, opt)
+
+	if info.ETag != "" && etagMatch(r.Header.Get("If-None-Match"), info.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	start, length, status := int64(0), info.Size, http.StatusOK
+	if rh := r.Header.Get("Range"); rh != "" && rangeStillValid(r, info) {
+		s, n, err := ParseRange(rh, info.Size)
+		switch {
+		case errors.Is(err, ErrUnsatisfiable):
+			h.Set("Content-Range", fmt.Sprintf("bytes */%d", info.Size))
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return nil
+		case err == nil:
+			start, length, status = s, n, http.StatusPartialContent
+			h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", s, s+n-1, info.Size))
+		}
+		// ErrInvalidRange：忽略 Range，返回整个文件
+	}
+	h.Set("Content-Length", strconv.FormatInt(length, 10))
+
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return nil
+	}
+	rc, err := ranger.GetRange(r.Context(), key, start, length)
+	if err != nil {
+		h.Del("Content-Length")
+		h.Del("Content-Range")
+		return err
+	}
+	defer rc.Close()
+
+	w.WriteHeader(status)
+	_, err = pools.Copy(throttle(r.Context(), w, opt.Rate), rc)
+	return err
+}
+
+// serveWhole 不支持 Range 的后端：整个文件流式返回，长度未知
+func serveWhole(w http.ResponseWriter, r *http.Request, b storage.Blob, key string, opt Options) error {
+	rc, err := b.Get(r.Context(), key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	w.Header().Set("Accept-Ranges", "none")
+	setContentHeaders(w.Header(), key, opt)
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	_, err = pools.Copy(throttle(r.Context(), w, opt.Rate), rc)
+	return err
+}
+
+func setContentHeaders(h http.Header, key string, opt Options) {
+	ct := mime.TypeByExtension(path.Ext(key))
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	h.Set("Content-Type", ct)
+	if opt.Attachment {
+		name := opt.Filename
+		if name == "" {
+			name = path.Base(key)
+		}
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	}
+}
+
+// etagMatch If-None-Match 可以是 * 或逗号分隔的多个 ETag，弱比较（忽略 W/ 前缀）
+func etagMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// rangeStillValid 处理 If-Range：值可以是 ETag（强比较）或时间，和当前文件一致才按 Range 返回
+func rangeStillValid(r *http.Request, info storage.Info) bool {
+	ir := r.Header.Get("If-Range")
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, `"`) {
+		return ir == info.ETag
+	}
+	t, err := http.ParseTime(ir)
+	return err == nil && !info.ModTime.IsZero() && info.ModTime.Unix() <= t.Unix()
+}
+
+// throttle 按 rate 字节/秒限速，rate <= 0 时原样返回
+func throttle(ctx context.Context, w io.Writer, rate int64) io.Writer {
+	if rate <= 0 {
+		return w
+	}
+	return newThrottledWriter(ctx, w, rate)
+}
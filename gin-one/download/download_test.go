@@ -0,0 +1,175 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-one/storage"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header        string
+		size          int64
+		start, length int64
+		err           error
+	}{
+		{"bytes=0-499", 1000, 0, 500, nil},
+		{"bytes=500-", 1000, 500, 500, nil},
+		{"bytes=-200", 1000, 800, 200, nil},
+		{"bytes=-2000", 1000, 0, 1000, nil},
+		{"bytes=900-5000", 1000, 900, 100, nil},
+		{"bytes= 10-19", 1000, 10, 10, nil},
+		{"bytes=1000-", 1000, 0, 0, ErrUnsatisfiable},
+		{"bytes=-0", 1000, 0, 0, ErrUnsatisfiable},
+		{"bytes=0-", 0, 0, 0, ErrUnsatisfiable},
+		{"bytes=0-1,5-9", 1000, 0, 0, ErrInvalidRange},
+		{"bytes=9-5", 1000, 0, 0, ErrInvalidRange},
+		{"items=0-5", 1000, 0, 0, ErrInvalidRange},
+		{"bytes=abc", 1000, 0, 0, ErrInvalidRange},
+	}
+	for _, tt := range tests {
+		start, length, err := ParseRange(tt.header, tt.size)
+		if !errors.Is(err, tt.err) || start != tt.start || length != tt.length {
+			t.Errorf("ParseRange(%q, %d) = %d, %d, %v; want %d, %d, %v",
+				tt.header, tt.size, start, length, err, tt.start, tt.length, tt.err)
+		}
+	}
+}
+
+// getOnly 只实现 Blob，不实现 Ranger
+type getOnly struct{ storage.Blob }
+
+func TestServe(t *testing.T) {
+	local, err := storage.NewLocal(t.TempDir(), "/files", []byte("k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "0123456789abcdefghij"
+	local.Put(context.Background(), "docs/a.txt", strings.NewReader(content), "")
+	info, _ := local.Stat(context.Background(), "docs/a.txt")
+
+	tests := []struct {
+		name       string
+		blob       storage.Blob
+		method     string
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{"whole file", local, "GET", nil, 200, content,
+			map[string]string{"Accept-Ranges": "bytes", "Content-Length": "20", "ETag": info.ETag, "Content-Type": "text/plain; charset=utf-8"}},
+		{"range", local, "GET", map[string]string{"Range": "bytes=10-14"}, 206, "abcde",
+			map[string]string{"Content-Range": "bytes 10-14/20", "Content-Length": "5"}},
+		{"resume from offset", local, "GET", map[string]string{"Range": "bytes=15-"}, 206, "fghij", nil},
+		{"suffix", local, "GET", map[string]string{"Range": "bytes=-3"}, 206, "hij", nil},
+		{"unsatisfiable", local, "GET", map[string]string{"Range": "bytes=20-"}, 416, "",
+			map[string]string{"Content-Range": "bytes */20"}},
+		{"multiple ranges ignored", local, "GET", map[string]string{"Range": "bytes=0-1,4-5"}, 200, content, nil},
+		{"not modified", local, "GET", map[string]string{"If-None-Match": `"other", ` + info.ETag}, 304, "", nil},
+		{"if-range matches", local, "GET", map[string]string{"Range": "bytes=0-0", "If-Range": info.ETag}, 206, "0", nil},
+		{"if-range stale", local, "GET", map[string]string{"Range": "bytes=0-0", "If-Range": `"old"`}, 200, content, nil},
+		{"head", local, "HEAD", map[string]string{"Range": "bytes=0-9"}, 206, "",
+			map[string]string{"Content-Length": "10"}},
+		{"no ranger", getOnly{local}, "GET", map[string]string{"Range": "bytes=0-0"}, 200, content,
+			map[string]string{"Accept-Ranges": "none"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/download/docs/a.txt", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			if err := Serve(w, r, tt.blob, "docs/a.txt", Options{}); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.wantStatus || w.Body.String() != tt.wantBody {
+				t.Fatalf("got %d %q; want %d %q", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+			for k, v := range tt.wantHeader {
+				if got := w.Header().Get(k); got != v {
+					t.Errorf("header %s = %q; want %q", k, got, v)
+				}
+			}
+		})
+	}
+
+	// 不存在的文件在写响应之前返回错误
+	w := httptest.NewRecorder()
+	err = Serve(w, httptest.NewRequest("GET", "/", nil), local, "missing.txt", Options{Attachment: true})
+	if !errors.Is(err, storage.ErrNotFound) || w.Body.Len() != 0 {
+		t.Fatalf("missing file err = %v, body %q", err, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	Serve(w, httptest.NewRequest("GET", "/", nil), local, "docs/a.txt", Options{Attachment: true, Filename: "报告.txt"})
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment; filename*=utf-8''") {
+		t.Fatalf("Content-Disposition = %q", cd)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	var buf bytes.Buffer
+	var slept time.Duration
+	clock := time.Unix(0, 0)
+	tw := newThrottledWriter(context.Background(), &buf, 1000)
+	tw.start, tw.now = clock, func() time.Time { return clock.Add(slept) }
+	tw.sleep = func(_ context.Context, d time.Duration) error {
+		slept += d
+		return nil
+	}
+
+	// 1000 字节/秒写 2500 字节，应该用 2.5 秒，每次最多写 100 字节
+	n, err := io.Copy(tw, strings.NewReader(strings.Repeat("x", 2500)))
+	if err != nil || n != 2500 || buf.Len() != 2500 {
+		t.Fatalf("copy = %d, %v", n, err)
+	}
+	if slept != 2500*time.Millisecond {
+		t.Fatalf("slept %v; want 2.5s", slept)
+	}
+	if tw.chunk != 100 {
+		t.Fatalf("chunk = %d; want 100", tw.chunk)
+	}
+
+	// 客户端断开时停止
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tw = newThrottledWriter(ctx, io.Discard, 10)
+	if _, err := tw.Write(make([]byte, 100)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v; want context.Canceled", err)
+	}
+}
+
+func TestServeThrottled(t *testing.T) {
+	local, _ := storage.NewLocal(t.TempDir(), "/files", []byte("k"))
+	local.Put(context.Background(), "a.bin", bytes.NewReader(make([]byte, 300)), "")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Serve(w, r, local, "a.bin", Options{Rate: 1000})
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if len(b) != 300 {
+		t.Fatalf("read %d bytes", len(b))
+	}
+	// 每 100 字节一批，第三批在 200ms 时写出
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("300 bytes at 1000 B/s took %v; want >= 200ms", elapsed)
+	}
+}
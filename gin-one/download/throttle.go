@@ -0,0 +1,70 @@
+package download
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// throttledWriter 限速写入：按已写字节数算出"应该用掉的时间"，写得太快就等
+type throttledWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	rate    int64 // 字节/秒
+	chunk   int   // 每次写出的字节数，约 100ms 的量
+	start   time.Time
+	written int64
+	now     func() time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+func newThrottledWriter(ctx context.Context, w io.Writer, rate int64) *throttledWriter {
+	return &throttledWriter{
+		ctx:   ctx,
+		w:     w,
+		rate:  rate,
+		chunk: int(max(rate/10, 1)),
+		start: time.Now(),
+		now:   time.Now,
+		sleep: sleepContext,
+	}
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	total := 0
+	for len(p) > 0 {
+		n := min(len(p), t.chunk)
+		written, err := t.w.Write(p[:n])
+		total += written
+		t.written += int64(written)
+		if err != nil {
+			return total, err
+		}
+		// 及时推给客户端，否则数据堆在缓冲区里，客户端看到的是一顿一顿的
+		if f, ok := t.w.(http.Flusher); ok {
+			f.Flush()
+		}
+		p = p[n:]
+
+		due := time.Duration(float64(t.written) / float64(t.rate) * float64(time.Second))
+		if wait := due - t.now().Sub(t.start); wait > 0 {
+			if err := t.sleep(t.ctx, wait); err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
+
+// sleepContext 等待 d，客户端断开（ctx 取消）时立即返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,332 @@
+// ============================================================================
+// Package eventbus 进程内事件总线：有类型的主题、异步订阅者、按主题有序投递
+// ============================================================================
+//
+// 【和 outbox 的区别】
+//
+// | 对比         | outbox                               | eventbus                                 |
+// |--------------|--------------------------------------|------------------------------------------|
+// | 事件存哪里   | 数据库表，和业务同一事务提交         | 内存队列，进程退出时未处理的事件丢失     |
+// | 投递保证     | 至少一次，可以跨进程（Kafka）        | 最多一次，只在本进程内                   |
+// | 适合         | 下游系统必须收到的领域事件           | 审计、缓存失效、通知等尽力而为的副作用   |
+//
+// 必须送达的副作用在 handler 里再入队到 jobs / outbox，总线只负责扇出。
+//
+// 【用法】
+//
+//	var UserCreated = eventbus.NewTopic[UserCreatedEvent]("user.created")
+//
+//	bus := eventbus.New(eventbus.Config{})
+//	eventbus.Subscribe(bus, UserCreated, eventbus.Options{Name: "audit"},
+//	    func(ctx context.Context, e UserCreatedEvent) error { ... }) // e 已经是具体类型，不需要断言
+//
+//	eventbus.Publish(ctx, bus, UserCreated, UserCreatedEvent{ID: u.ID})
+//
+// 【投递模型】
+//
+//	Publish ──▶ topic 锁 ──▶ 订阅者 A 的队列（Buffer）──▶ goroutine A ──▶ handler
+//	                     └─▶ 订阅者 B 的队列（Buffer）──▶ goroutine B ──▶ handler
+//
+// 每个订阅者一个 goroutine，按入队顺序逐个处理，慢订阅者不影响其他订阅者。
+// 同一主题的 Publish 在锁内依次入队，并发发布时所有订阅者看到的顺序都一样。
+// 队列满时 Publish 阻塞（背压），直到有空位或 ctx 取消。
+//
+// 【handler 出错】
+//
+// | Policy      | 行为                                          | 适合             |
+// |-------------|-----------------------------------------------|------------------|
+// | PolicyLog   | 记录错误日志，继续处理下一个事件（默认）      | 审计、统计       |
+// | PolicyRetry | 退避重试 MaxRetries 次，仍失败再记录错误日志  | 调用外部服务     |
+// | PolicyDrop  | 直接丢弃，不记日志                            | 缓存失效         |
+//
+// handler panic 按返回错误处理，不会让 goroutine 退出。
+//
+// ============================================================================
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+)
+
+// 错误定义
+var (
+	ErrClosed = errors.New("eventbus: bus closed")
+)
+
+// Topic 有类型的主题，T 是事件类型；同名主题必须使用同一个 T
+type Topic[T any] struct {
+	name string
+}
+
+// NewTopic 创建主题，通常声明为包级变量
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{name: name}
+}
+
+// Name 主题名
+func (t Topic[T]) Name() string {
+	return t.name
+}
+
+// Policy handler 返回错误时的处理方式
+type Policy int
+
+const (
+	PolicyLog   Policy = iota // 记录日志，继续处理下一个事件
+	PolicyRetry               // 退避重试，用完次数后记录日志
+	PolicyDrop                // 静默丢弃
+)
+
+// Config 总线配置
+type Config struct {
+	// Buffer 订阅者队列的默认长度，默认 64
+	Buffer int
+
+	// Logger 默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Options 订阅选项
+type Options struct {
+	// Name 订阅者名称，用于日志，如 "audit"、"cache"
+	Name string
+
+	// Buffer 队列长度，默认 Config.Buffer
+	Buffer int
+
+	// Policy 出错时的处理方式，默认 PolicyLog
+	Policy Policy
+
+	// MaxRetries PolicyRetry 的最多重试次数，默认 3
+	// RetryDelay 第一次重试前的等待时间，之后翻倍，默认 100ms
+	MaxRetries int
+	RetryDelay time.Duration
+}
+
+// Bus 事件总线，零值不可用，用 New 创建
+type Bus struct {
+	cfg    Config
+	logger *slog.Logger
+
+	mu     sync.Mutex // 保护 topics、closed
+	topics map[string]*topic
+	closed bool
+
+	wg sync.WaitGroup // 订阅者 goroutine
+}
+
+// topic 一个主题的订阅者
+type topic struct {
+	mu     sync.Mutex // 串行化同一主题的发布，保证所有订阅者看到的顺序一致
+	subs   []*subscriber
+	closed bool
+}
+
+type subscriber struct {
+	opts   Options
+	queue  chan envelope
+	handle func(ctx context.Context, event any) error
+}
+
+type envelope struct {
+	ctx   context.Context
+	topic string
+	event any
+}
+
+// New 创建总线
+func New(cfg Config) *Bus {
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = 64
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Bus{
+		cfg:    cfg,
+		logger: cfg.Logger,
+		topics: make(map[string]*topic),
+	}
+}
+
+// topic 返回主题，不存在时创建；总线已关闭返回 nil
+func (b *Bus) topic(name string) *topic {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// Subscribe 订阅主题，返回取消订阅的函数
+//
+// 取消订阅后不再接收新事件，已经在队列里的事件仍会处理完。
+// 总线已关闭时不做任何事，返回的函数也是空操作。
+func Subscribe[T any](b *Bus, t Topic[T], opts Options, fn func(ctx context.Context, event T) error) (unsubscribe func()) {
+	if opts.Buffer <= 0 {
+		opts.Buffer = b.cfg.Buffer
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = 100 * time.Millisecond
+	}
+	s := &subscriber{
+		opts:  opts,
+		queue: make(chan envelope, opts.Buffer),
+		handle: func(ctx context.Context, event any) error {
+			return fn(ctx, event.(T))
+		},
+	}
+
+	tp := b.topic(t.name)
+	if tp == nil {
+		return func() {}
+	}
+	tp.mu.Lock()
+	if tp.closed {
+		tp.mu.Unlock()
+		return func() {}
+	}
+	tp.subs = append(tp.subs, s)
+	b.wg.Add(1)
+	tp.mu.Unlock()
+
+	go b.loop(s)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			tp.mu.Lock()
+			defer tp.mu.Unlock()
+			if tp.closed {
+				return // Close 已经关闭了队列
+			}
+			if i := slices.Index(tp.subs, s); i >= 0 {
+				tp.subs = slices.Delete(tp.subs, i, i+1)
+				close(s.queue)
+			}
+		})
+	}
+}
+
+// Publish 把事件放入主题所有订阅者的队列，不等待处理完成
+//
+// handler 收到的 ctx 保留 ctx 中的值（请求 ID、操作者等），但不会随 ctx 取消：
+// 请求结束后事件仍会被处理。
+// 队列满时阻塞，ctx 取消时返回 ctx.Err()，此时排在前面的订阅者可能已经收到事件。
+func Publish[T any](ctx context.Context, b *Bus, t Topic[T], event T) error {
+	tp := b.topic(t.name)
+	if tp == nil {
+		return ErrClosed
+	}
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	if tp.closed {
+		return ErrClosed
+	}
+	env := envelope{ctx: context.WithoutCancel(ctx), topic: t.name, event: event}
+	for _, s := range tp.subs {
+		select {
+		case s.queue <- env:
+		default:
+			// 队列满：阻塞等待并记录，持续出现说明订阅者处理不过来
+			b.logger.Warn("eventbus: subscriber queue full", "topic", t.name, "subscriber", s.opts.Name)
+			select {
+			case s.queue <- env:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+// loop 订阅者的 goroutine，队列关闭后退出
+func (b *Bus) loop(s *subscriber) {
+	defer b.wg.Done()
+	for env := range s.queue {
+		b.deliver(s, env)
+	}
+}
+
+// deliver 按 Policy 处理一个事件
+func (b *Bus) deliver(s *subscriber, env envelope) {
+	err := b.call(s, env)
+	if err == nil {
+		return
+	}
+	switch s.opts.Policy {
+	case PolicyDrop:
+		return
+	case PolicyRetry:
+		delay := s.opts.RetryDelay
+		for i := 1; i <= s.opts.MaxRetries && err != nil; i++ {
+			time.Sleep(delay)
+			delay *= 2
+			err = b.call(s, env)
+		}
+		if err == nil {
+			return
+		}
+	}
+	b.logger.Error("eventbus: handler failed", "topic", env.topic, "subscriber", s.opts.Name, "error", err)
+}
+
+// call 调用 handler，panic 转成错误
+func (b *Bus) call(s *subscriber, env envelope) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			b.logger.Error("eventbus: handler panic", "topic", env.topic, "subscriber", s.opts.Name,
+				"panic", v, "stack", string(debug.Stack()))
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return s.handle(env.ctx, env.event)
+}
+
+// Close 停止接收新事件，等待队列中的事件处理完；ctx 到期时不再等待，返回 ctx.Err()
+//
+// 关闭后 Publish 返回 ErrClosed，Subscribe 不做任何事。
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, tp := range b.topics {
+			tp.mu.Lock()
+			tp.closed = true
+			for _, s := range tp.subs {
+				close(s.queue)
+			}
+			tp.subs = nil
+			tp.mu.Unlock()
+		}
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
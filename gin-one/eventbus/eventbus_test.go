@@ -0,0 +1,271 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type userCreated struct {
+	ID   int
+	Name string
+}
+
+var userTopic = NewTopic[userCreated]("user.created")
+
+func newTestBus() *Bus {
+	return New(Config{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+}
+
+func closeBus(t *testing.T, b *Bus) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFanOutTyped(t *testing.T) {
+	b := newTestBus()
+	var mu sync.Mutex
+	got := map[string][]string{}
+	for _, name := range []string{"audit", "cache", "notify"} {
+		Subscribe(b, userTopic, Options{Name: name}, func(ctx context.Context, e userCreated) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got[name] = append(got[name], e.Name)
+			return nil
+		})
+	}
+	// 其他主题的订阅者收不到
+	other := NewTopic[string]("post.created")
+	var otherCalls atomic.Int32
+	Subscribe(b, other, Options{}, func(context.Context, string) error {
+		otherCalls.Add(1)
+		return nil
+	})
+
+	for _, name := range []string{"alice", "bob"} {
+		if err := Publish(context.Background(), b, userTopic, userCreated{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	closeBus(t, b)
+
+	for _, name := range []string{"audit", "cache", "notify"} {
+		if !slices.Equal(got[name], []string{"alice", "bob"}) {
+			t.Errorf("%s got %v", name, got[name])
+		}
+	}
+	if otherCalls.Load() != 0 {
+		t.Error("subscriber of another topic should not be called")
+	}
+}
+
+func TestOrderingAcrossPublishers(t *testing.T) {
+	b := newTestBus()
+	var mu sync.Mutex
+	seen := make([][]int, 3)
+	for i := range seen {
+		Subscribe(b, userTopic, Options{Buffer: 4}, func(ctx context.Context, e userCreated) error {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[i] = append(seen[i], e.ID)
+			return nil
+		})
+	}
+
+	var wg sync.WaitGroup
+	for p := 0; p < 8; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				_ = Publish(context.Background(), b, userTopic, userCreated{ID: p*1000 + i})
+			}
+		}(p)
+	}
+	wg.Wait()
+	closeBus(t, b)
+
+	if len(seen[0]) != 400 {
+		t.Fatalf("delivered %d; want 400", len(seen[0]))
+	}
+	// 并发发布时所有订阅者看到同样的顺序
+	for i := 1; i < len(seen); i++ {
+		if !slices.Equal(seen[0], seen[i]) {
+			t.Fatalf("subscriber %d saw a different order", i)
+		}
+	}
+	// 同一个发布者的事件保持发布顺序
+	last := map[int]int{}
+	for _, id := range seen[0] {
+		p, i := id/1000, id%1000
+		if prev, ok := last[p]; ok && i <= prev {
+			t.Fatalf("publisher %d: %d delivered after %d", p, i, prev)
+		}
+		last[p] = i
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    Policy
+		failures  int32 // handler 前几次调用返回错误
+		panics    bool
+		wantCalls int32
+	}{
+		{"log does not retry", PolicyLog, 1, false, 2},
+		{"drop does not retry", PolicyDrop, 1, false, 2},
+		{"retry until success", PolicyRetry, 2, false, 4},
+		{"retry gives up after max", PolicyRetry, 100, false, 8},
+		{"panic is an error", PolicyRetry, 1, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBus()
+			var calls atomic.Int32
+			Subscribe(b, userTopic, Options{Policy: tt.policy, MaxRetries: 3, RetryDelay: time.Millisecond},
+				func(ctx context.Context, e userCreated) error {
+					if calls.Add(1) <= tt.failures {
+						if tt.panics {
+							panic("boom")
+						}
+						return errors.New("fail")
+					}
+					return nil
+				})
+			// 第一个事件失败后订阅者继续处理第二个
+			_ = Publish(context.Background(), b, userTopic, userCreated{ID: 1})
+			_ = Publish(context.Background(), b, userTopic, userCreated{ID: 2})
+			closeBus(t, b)
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d; want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestSlowSubscriberDoesNotBlockOthers(t *testing.T) {
+	b := newTestBus()
+	release := make(chan struct{})
+	Subscribe(b, userTopic, Options{Name: "slow", Buffer: 10}, func(context.Context, userCreated) error {
+		<-release
+		return nil
+	})
+	fast := make(chan int, 10)
+	Subscribe(b, userTopic, Options{Name: "fast"}, func(_ context.Context, e userCreated) error {
+		fast <- e.ID
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		_ = Publish(context.Background(), b, userTopic, userCreated{ID: i})
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-fast:
+		case <-time.After(time.Second):
+			t.Fatal("fast subscriber blocked by slow one")
+		}
+	}
+	close(release)
+	closeBus(t, b)
+}
+
+func TestBackpressure(t *testing.T) {
+	b := newTestBus()
+	release := make(chan struct{})
+	Subscribe(b, userTopic, Options{Buffer: 1}, func(context.Context, userCreated) error {
+		<-release
+		return nil
+	})
+
+	// 第一个被 handler 取走，第二个占满队列，第三个阻塞
+	_ = Publish(context.Background(), b, userTopic, userCreated{ID: 1})
+	_ = Publish(context.Background(), b, userTopic, userCreated{ID: 2})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	deadline := time.Now().Add(time.Second)
+	var err error
+	for time.Now().Before(deadline) {
+		if err = Publish(ctx, b, userTopic, userCreated{ID: 3}); err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v; want deadline exceeded", err)
+	}
+	close(release)
+	closeBus(t, b)
+}
+
+func TestContextValues(t *testing.T) {
+	type key struct{}
+	b := newTestBus()
+	got := make(chan any, 1)
+	Subscribe(b, userTopic, Options{}, func(ctx context.Context, e userCreated) error {
+		got <- ctx.Value(key{})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "req-1"))
+	_ = Publish(ctx, b, userTopic, userCreated{})
+	cancel() // 请求结束不影响事件处理
+	closeBus(t, b)
+	if v := <-got; v != "req-1" {
+		t.Errorf("ctx value = %v; want req-1", v)
+	}
+}
+
+func TestUnsubscribeAndClose(t *testing.T) {
+	b := newTestBus()
+	var calls atomic.Int32
+	unsubscribe := Subscribe(b, userTopic, Options{}, func(context.Context, userCreated) error {
+		calls.Add(1)
+		return nil
+	})
+
+	_ = Publish(context.Background(), b, userTopic, userCreated{})
+	unsubscribe()
+	unsubscribe() // 重复调用无影响
+	_ = Publish(context.Background(), b, userTopic, userCreated{})
+	closeBus(t, b)
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %d; want 1", got)
+	}
+	if err := Publish(context.Background(), b, userTopic, userCreated{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("publish after close = %v; want ErrClosed", err)
+	}
+	Subscribe(b, userTopic, Options{}, func(context.Context, userCreated) error { return nil })()
+	closeBus(t, b) // 重复关闭无影响
+}
+
+func TestCloseTimeout(t *testing.T) {
+	b := newTestBus()
+	release := make(chan struct{})
+	defer close(release)
+	Subscribe(b, userTopic, Options{}, func(context.Context, userCreated) error {
+		<-release
+		return nil
+	})
+	_ = Publish(context.Background(), b, userTopic, userCreated{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close = %v; want deadline exceeded", err)
+	}
+}
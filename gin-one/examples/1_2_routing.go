@@ -7,9 +7,14 @@
 package main
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+
+	"go-one/middleware/realip"
+	"go-one/middleware/versioning"
+	"go-one/server"
 )
 
 // ============================================================================
@@ -216,10 +221,17 @@ func main() {
 		}
 	}
 
-	// API v2 版本 (演示版本演进)
-	v2 := r.Group("/api/v2")
+	// API 版本演进
+	//
+	// 再建一个 /api/v2 路由组的话，v2 只改了 GET /users，也要把 v1 的其他路由复制一遍，
+	// 漏掉的接口在 v2 下就是 404。versioning 只注册一次路径，按请求头选择版本：
+	//   X-API-Version: 2  或  Accept: application/vnd.api.v2+json
+	// 某个接口没有 v2 实现时沿用 v1；请求不支持的版本返回 406
+	versions := versioning.New(versioning.Config{Versions: []int{1, 2}})
+	api := r.Group("/api")
 	{
-		v2.GET("/users", listUsersV2) // 新版本可能返回不同的数据结构
+		versions.Handle(api, http.MethodGet, "/users", versioning.Handlers{1: listUsersV1, 2: listUsersV2})
+		versions.Handle(api, http.MethodGet, "/users/:id", versioning.Handlers{1: getUserV1}) // v2 沿用 v1
 	}
 
 	// ========================================================================
@@ -280,7 +292,14 @@ func main() {
 	// 八、获取完整 URL 信息
 	// ========================================================================
 
-	r.GET("/debug/request", func(c *gin.Context) {
+	// 客户端 IP：c.ClientIP() 默认信任所有代理，谁都能用 X-Forwarded-For 换一个 IP；
+	// realip 只相信负载均衡网段转发的头，直接访问时用 TCP 连接的地址
+	ips, err := realip.New(realip.Config{TrustedProxies: []string{"10.0.0.0/8"}})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r.GET("/debug/request", ips.Middleware(), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			// 请求路径
 			"path": c.Request.URL.Path, // /debug/request
@@ -290,8 +309,12 @@ func main() {
 			"query_string": c.Request.URL.RawQuery, // foo=bar
 			// 请求方法
 			"method": c.Request.Method, // GET
-			// 客户端 IP
-			"client_ip": c.ClientIP(),
+			// 客户端 IP（可信代理之外的第一跳）
+			"client_ip": realip.FromContext(c),
+			// gin 的结果：直接采用 X-Forwarded-For 的第一个地址，可以伪造
+			"gin_client_ip": c.ClientIP(),
+			// TCP 连接的对端地址
+			"remote_addr": c.Request.RemoteAddr,
 			// 匹配的路由模式
 			"full_path": c.FullPath(), // /debug/request
 		})
@@ -320,7 +343,10 @@ func main() {
 	})
 
 	// 启动服务器
-	r.Run(":8080")
+	// 收到 Ctrl+C / SIGTERM 后等待进行中的请求完成再退出
+	if err := server.Run(r, ":8080"); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // ============================================================================
@@ -426,7 +452,17 @@ func authMiddleware() gin.HandlerFunc {
 // # 路由组测试
 // curl http://localhost:8080/api/v1/users
 // curl http://localhost:8080/api/v1/users/123
-// curl http://localhost:8080/api/v2/users
+//
+// # 版本协商（同一个路径，按请求头选择版本）
+// curl -i http://localhost:8080/api/users                                          # 默认 v1
+// curl -i http://localhost:8080/api/users -H "X-API-Version: 2"                    # v2
+// curl -i http://localhost:8080/api/users -H "Accept: application/vnd.api.v2+json" # v2
+// curl -i http://localhost:8080/api/users/123 -H "X-API-Version: 2"                # 没有 v2 实现，沿用 v1
+// curl -i http://localhost:8080/api/users -H "X-API-Version: 3"                    # 406
+//
+// # 客户端 IP：直接访问时伪造的 X-Forwarded-For 骗得过 c.ClientIP()，骗不过 realip
+// curl -H "X-Forwarded-For: 6.6.6.6" http://localhost:8080/debug/request
+// # {"client_ip":"127.0.0.1","gin_client_ip":"6.6.6.6","remote_addr":"127.0.0.1:54321",...}
 //
 // # 认证测试
 // curl http://localhost:8080/admin/dashboard  # 401
@@ -464,6 +500,14 @@ func authMiddleware() gin.HandlerFunc {
 //    /files/*filepath 匹配 /files/a/b/c
 //    c.Param("filepath") 返回 "/a/b/c" (注意有斜杠)
 //
+// 6. 【按请求头区分版本却没有 Vary】
+//    同一个 URL 的 v1、v2 响应不同，CDN / 浏览器缓存只按 URL 缓存会串版本
+//    响应要带 Vary: Accept, X-API-Version（versioning 会自动加上）
+//
+// 7. 【直接用 c.ClientIP() 做限流或审计】
+//    gin 默认信任所有代理，客户端自己带 X-Forwarded-For 就能换 IP
+//    用 realip 配置可信代理网段，取 IP 用 realip.FromContext(c)
+//
 // ============================================================================
 
 // ============================================================================
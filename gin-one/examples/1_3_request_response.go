@@ -7,9 +7,12 @@
 package main
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+
+	"go-one/server"
 )
 
 // ============================================================================
@@ -341,7 +344,10 @@ func main() {
 		})
 	})
 
-	r.Run(":8080")
+	// 收到 Ctrl+C / SIGTERM 后等待进行中的请求完成再退出
+	if err := server.Run(r, ":8080"); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // ============================================================================
@@ -7,11 +7,17 @@
 package main
 
 import (
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+
+	"go-one/formats"
+	"go-one/server"
 )
 
 // ============================================================================
@@ -25,7 +31,7 @@ import (
 // 2. Path 参数: /user/:id
 // 3. Form 表单: application/x-www-form-urlencoded 或 multipart/form-data
 // 4. JSON Body: application/json
-// 5. XML Body: application/xml
+// 5. XML / YAML / TOML Body: application/xml、application/yaml、application/toml
 // 6. Header: 请求头
 //
 // 【绑定的本质】
@@ -50,6 +56,8 @@ import (
 // | ShouldBind          | 自动检测                    | 根据 Content-Type 自动选择 |
 // | ShouldBindJSON      | Body                       | application/json         |
 // | ShouldBindXML       | Body                       | application/xml          |
+// | ShouldBindYAML      | Body                       | application/yaml         |
+// | ShouldBindTOML      | Body                       | application/toml         |
 // | ShouldBindQuery     | URL Query                  | -                        |
 // | ShouldBindUri       | Path 参数                   | -                        |
 // | ShouldBindHeader    | Request Header             | -                        |
@@ -108,6 +116,16 @@ type UpdateUserRequest struct {
 	Age   *int    `json:"age" form:"age"`
 }
 
+// Profile 多格式绑定示例：四种格式的字段名标签写在一起，校验规则只写一次
+// XML 用属性表示 id，tags 包一层 <tags><tag>...</tag></tags>
+type Profile struct {
+	XMLName xml.Name `json:"-" xml:"profile" yaml:"-" toml:"-"`
+	ID      int      `json:"id" xml:"id,attr" yaml:"id" toml:"id"`
+	Name    string   `json:"name" xml:"name" yaml:"name" toml:"name" binding:"required"`
+	Email   string   `json:"email" xml:"email" yaml:"email" toml:"email" binding:"required,email"`
+	Tags    []string `json:"tags" xml:"tags>tag" yaml:"tags" toml:"tags"`
+}
+
 func main() {
 	r := gin.Default()
 
@@ -352,11 +370,88 @@ func main() {
 	})
 
 	// ========================================================================
-	// 九、自定义绑定器 (实现 binding.Binding 接口)
+	// 九、XML / YAML / TOML 绑定
 	// ========================================================================
-	// 这是高级用法，通常不需要，了解即可
+	//
+	// 和 ShouldBindJSON 一样，解码后执行 binding 标签的校验；
+	// 字段名按各自的标签匹配（xml / yaml / toml），YAML 没有 yaml 标签时用 json 标签
+
+	r.POST("/profiles/xml", func(c *gin.Context) {
+		var p Profile
+		if err := c.ShouldBindXML(&p); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.XML(http.StatusOK, p)
+	})
 
-	r.Run(":8080")
+	r.POST("/profiles/yaml", func(c *gin.Context) {
+		var p Profile
+		if err := c.ShouldBindYAML(&p); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.YAML(http.StatusOK, p)
+	})
+
+	// ========================================================================
+	// 十、自定义绑定器 (实现 binding.Binding 接口)
+	// ========================================================================
+	//
+	// binding.Binding 只有两个方法：Name() 和 Bind(*http.Request, any)；
+	// 再实现 BindBody([]byte, any) 就是 binding.BindingBody，可以用于 ShouldBindBodyWith。
+	//
+	// Gin 自带的 binding.TOML 会忽略结构体里没有的键，拼错的键悄悄变成零值。
+	// formats.StrictTOML 是一个自定义实现：多余的键直接报错，其余行为和自带的一样。
+
+	r.POST("/profiles/toml", func(c *gin.Context) {
+		var p Profile
+		if err := c.ShouldBindWith(&p, formats.StrictTOML); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.TOML(http.StatusOK, p)
+	})
+
+	// ========================================================================
+	// 十一、按 Content-Type 绑定、按 Accept 响应（内容协商）
+	// ========================================================================
+	//
+	// formats.Bind 按 Content-Type 选择 JSON / XML / YAML / TOML 绑定器，
+	// 其他类型返回 415（c.ShouldBind 会回落到表单绑定，结果是一堆 required 错误，不好排查）；
+	// formats.Render 按 Accept 选择响应格式，都不接受时返回 406
+
+	r.POST("/profiles", func(c *gin.Context) {
+		var p Profile
+		if err := formats.Bind(c, &p); err != nil {
+			if errors.Is(err, formats.ErrUnsupportedMediaType) {
+				c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		formats.Render(c, http.StatusCreated, p)
+	})
+
+	r.GET("/profiles/:id", func(c *gin.Context) {
+		var uri UserUri
+		if err := c.ShouldBindUri(&uri); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		formats.Render(c, http.StatusOK, Profile{
+			ID:    uri.ID,
+			Name:  "张三",
+			Email: "test@example.com",
+			Tags:  []string{"go", "gin"},
+		})
+	})
+
+	// 收到 Ctrl+C / SIGTERM 后等待进行中的请求完成再退出
+	if err := server.Run(r, ":8080"); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // ============================================================================
@@ -406,6 +501,27 @@ func main() {
 //   -H "Content-Type: application/json" \
 //   -d '{"age":30}'
 //
+// # XML / YAML 绑定
+// curl -X POST http://localhost:8080/profiles/xml \
+//   -H "Content-Type: application/xml" \
+//   -d '<profile id="1"><name>张三</name><email>test@example.com</email><tags><tag>go</tag></tags></profile>'
+// curl -X POST http://localhost:8080/profiles/yaml \
+//   -H "Content-Type: application/yaml" \
+//   --data-binary $'name: 张三\nemail: test@example.com\ntags: [go, gin]\n'
+//
+// # 自定义 TOML 绑定器：拼错的键返回 400
+// curl -X POST http://localhost:8080/profiles/toml \
+//   -H "Content-Type: application/toml" \
+//   --data-binary $'name = "张三"\nemial = "test@example.com"\n'
+//
+// # 内容协商：YAML 请求，TOML 响应
+// curl -X POST http://localhost:8080/profiles \
+//   -H "Content-Type: application/yaml" -H "Accept: application/toml" \
+//   --data-binary $'name: 张三\nemail: test@example.com\n'
+// curl -H "Accept: application/xml" http://localhost:8080/profiles/1
+// curl -i -H "Accept: image/png" http://localhost:8080/profiles/1   # 406
+// curl -i -X POST http://localhost:8080/profiles -H "Content-Type: text/plain" -d 'x'   # 415
+//
 // ============================================================================
 
 // ============================================================================
@@ -442,6 +558,13 @@ func main() {
 //    JSON Body 必须设置 Content-Type: application/json
 //    否则 ShouldBindJSON 可能绑定失败
 //
+// 8. 【各格式的字段名标签互不通用】
+//    只写 json:"nick_name" 时，XML / TOML 仍按字段名 NickName 匹配，请求里的 nick_name 被忽略
+//    YAML 是例外：没有 yaml 标签时会用 json 标签
+//
+// 9. 【XML 不能编码 map】
+//    c.XML(200, map[string]any{...}) 报错（gin.H 除外），协商响应的数据要用带 xml 标签的结构体
+//
 // ============================================================================
 
 // ============================================================================
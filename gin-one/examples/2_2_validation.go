@@ -7,14 +7,24 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+
+	"go-one/operation"
+	"go-one/receipt"
+	"go-one/server"
+	"go-one/storage"
 )
 
 // ============================================================================
@@ -288,9 +298,92 @@ func toSnakeCase(s string) string {
 	return strings.ToLower(result.String())
 }
 
+// ============================================================================
+// 订单与支付回执
+// ============================================================================
+//
+// 支付成功后创建订单，并启动一个异步操作生成 PDF 回执：
+//
+//	POST /payments             → {"order_id": "ord_1", "receipt_operation": "op_xxx"}
+//	GET  /operations/op_xxx    → 轮询生成进度（失败会自动重试）
+//	GET  /orders/ord_1         → 回执生成后返回 receipt_url（10 分钟有效的签名链接）
+//
+
+// Order 订单（内存存储，仅作演示）
+type Order struct {
+	ID               string         `json:"id"`
+	Status           string         `json:"status"`
+	PaymentMethod    string         `json:"payment_method"`
+	Items            []receipt.Item `json:"-"`
+	Total            string         `json:"total"`
+	PaidAt           time.Time      `json:"paid_at"`
+	ReceiptOperation string         `json:"receipt_operation"`
+	ReceiptKey       string         `json:"-"`
+}
+
+var (
+	ordersMu sync.RWMutex
+	orders   = make(map[string]*Order)
+
+	receiptOps  = operation.NewManager(operation.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second})
+	receiptBlob storage.Blob
+)
+
+// createPaidOrder 保存订单并异步生成回执
+func createPaidOrder(method string, items []receipt.Item) *Order {
+	ordersMu.Lock()
+	order := &Order{
+		ID:            fmt.Sprintf("ord_%d", len(orders)+1),
+		Status:        "paid",
+		PaymentMethod: method,
+		Items:         items,
+		PaidAt:        time.Now(),
+	}
+	rc := &receipt.Receipt{
+		Number:        "R-" + strings.TrimPrefix(order.ID, "ord_"),
+		OrderID:       order.ID,
+		Merchant:      "Gin Demo Shop",
+		Customer:      "guest",
+		PaymentMethod: method,
+		Currency:      "CNY",
+		PaidAt:        order.PaidAt,
+		Items:         items,
+	}
+	order.Total = receipt.FormatAmount(rc.Total())
+	orders[order.ID] = order
+	ordersMu.Unlock()
+
+	op := receiptOps.Start("receipt:"+order.ID, func(ctx context.Context) (any, error) {
+		data, err := receipt.Render(rc)
+		if err != nil {
+			return nil, operation.Permanent(err) // 排版错误重试也没用
+		}
+		key := receipt.Key(order.ID)
+		if err := receiptBlob.Put(ctx, key, bytes.NewReader(data), "application/pdf"); err != nil {
+			return nil, err // 存储错误可能是暂时的，交给 operation 重试
+		}
+
+		ordersMu.Lock()
+		order.ReceiptKey = key
+		ordersMu.Unlock()
+		return gin.H{"order_id": order.ID, "key": key}, nil
+	})
+
+	ordersMu.Lock()
+	order.ReceiptOperation = op.ID
+	ordersMu.Unlock()
+	return order
+}
+
 func main() {
 	r := gin.Default()
 
+	local, err := storage.NewLocal("./data/files", "/files", []byte("change-me-in-production"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	receiptBlob = local
+
 	// ========================================================================
 	// 注册自定义校验器
 	// ========================================================================
@@ -387,6 +480,12 @@ func main() {
 	// 四、条件校验示例
 	// ========================================================================
 
+	type PaymentItem struct {
+		Name      string `json:"name" binding:"required,max=100"`
+		Quantity  int    `json:"quantity" binding:"required,gte=1"`
+		UnitPrice int64  `json:"unit_price" binding:"required,gt=0"` // 单位：分
+	}
+
 	type PaymentRequest struct {
 		// 支付方式
 		PaymentMethod string `json:"payment_method" binding:"required,oneof=credit_card bank_transfer alipay wechat"`
@@ -400,6 +499,9 @@ func main() {
 		// 银行信息 - 仅当 payment_method=bank_transfer 时必填
 		BankAccount string `json:"bank_account"`
 		BankName    string `json:"bank_name"`
+
+		// 商品明细 - 可选，dive 会逐个校验数组元素
+		Items []PaymentItem `json:"items" binding:"omitempty,dive"`
 	}
 
 	r.POST("/payments", func(c *gin.Context) {
@@ -462,12 +564,52 @@ func main() {
 			return
 		}
 
+		items := make([]receipt.Item, 0, len(req.Items))
+		for _, it := range req.Items {
+			items = append(items, receipt.Item{Name: it.Name, Quantity: it.Quantity, UnitPrice: it.UnitPrice})
+		}
+		if len(items) == 0 {
+			items = append(items, receipt.Item{Name: "Demo item", Quantity: 1, UnitPrice: 9900})
+		}
+		order := createPaidOrder(req.PaymentMethod, items)
+
 		c.JSON(http.StatusOK, gin.H{
 			"code":    0,
 			"message": "支付请求已提交",
+			"data": gin.H{
+				"order_id":          order.ID,
+				"receipt_operation": order.ReceiptOperation,
+			},
 		})
 	})
 
+	// 查询订单：回执生成完成后附带签名下载链接
+	r.GET("/orders/:id", func(c *gin.Context) {
+		ordersMu.RLock()
+		order, ok := orders[c.Param("id")]
+		var snapshot Order
+		if ok {
+			snapshot = *order
+		}
+		ordersMu.RUnlock()
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "订单不存在"})
+			return
+		}
+
+		links := gin.H{"receipt_operation": "/operations/" + snapshot.ReceiptOperation}
+		if snapshot.ReceiptKey != "" {
+			url, err := receiptBlob.SignedURL(c.Request.Context(), snapshot.ReceiptKey, 10*time.Minute)
+			if err == nil {
+				links["receipt_url"] = url
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 0, "data": snapshot, "links": links})
+	})
+
+	r.GET("/operations/:id", receiptOps.Handler())
+	r.GET("/files/*key", local.Handler())
+
 	// ========================================================================
 	// 五、展示所有校验规则
 	// ========================================================================
@@ -488,7 +630,12 @@ func main() {
 		})
 	})
 
-	r.Run(":8080")
+	srv := server.New(r, server.Config{Addr: ":8080"})
+	// 退出前取消等待重试的回执任务，并等待正在生成的回执写完
+	srv.OnShutdown("receipt operations", receiptOps.Shutdown)
+	if err := srv.Run(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // ============================================================================
@@ -569,6 +716,18 @@ func main() {
 //     "payment_method": "credit_card"
 //   }'
 //
+// # 支付接口 - 带商品明细（单价单位：分），返回 order_id 和 receipt_operation
+// curl -X POST http://localhost:8080/payments \
+//   -H "Content-Type: application/json" \
+//   -d '{
+//     "payment_method": "alipay",
+//     "items": [{"name": "Go Book", "quantity": 2, "unit_price": 4950}]
+//   }'
+//
+// # 查询回执生成进度 / 订单（links.receipt_url 即 PDF 下载地址）
+// curl http://localhost:8080/operations/<operation_id>
+// curl http://localhost:8080/orders/ord_1
+//
 // ============================================================================
 
 // ============================================================================
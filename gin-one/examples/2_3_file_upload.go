@@ -2,23 +2,47 @@
 // 2.3 文件上传与下载
 // ============================================================================
 // 运行方式: go run examples/2_3_file_upload.go
-// 测试前先创建目录: mkdir -p uploads
+// 上传限制可在 config.yaml 中配置（upload.max_file_size 支持热加载）
+// 存储后端由 storage.driver 决定，默认存到本地 ./uploads，改成 s3 即可切到 S3 / MinIO
+// 去重上传的文件元数据存在 database.* 配置的数据库（默认 SQLite test.db）
+// 上传的文件先经过 scanner.driver 指定的病毒扫描（默认 none），感染文件返回 422
 // ============================================================================
 
 package main
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"mime/multipart"
 	"net/http"
-	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"go-one/config"
+	"go-one/database"
+	"go-one/download"
+	"go-one/files"
+	"go-one/imageproc"
+	"go-one/quota"
+	"go-one/scanner"
+	"go-one/server"
+	"go-one/storage"
+	"go-one/upload"
+
+	"go-learning/conc"
 )
 
 // ============================================================================
@@ -43,24 +67,51 @@ import (
 //
 // 1. c.FormFile("file") - 获取文件头信息 (不读取内容)
 // 2. file.Open() - 打开文件流
-// 3. io.Copy() - 流式写入目标文件
+// 3. blob.Put() - 流式写入存储后端
 //
 // 【为什么用流式处理？】
 //
 // 大文件如果全部读入内存会导致 OOM
 // 流式处理: 边读边写，内存占用恒定
 //
+// 【存储后端】
+//
+// handler 只和 storage.Blob 打交道，不知道文件落在哪里：
+//
+// | storage.driver | 实现            | 文件地址                                  |
+// |----------------|-----------------|-------------------------------------------|
+// | local（默认）  | storage.Local   | /files/<key>?expires=...&sig=...          |
+// | s3             | storage.S3      | S3 / MinIO 预签名链接，浏览器直接下载     |
+//
+// 对象用 key 标识（如 images/2024/05/01/ab12cd34_1714550400.jpg），
+// 返回给客户端的是 SignedURL 生成的临时链接，私有文件不会被随意访问。
+//
+// 【病毒扫描】
+//
+// 写入存储后、把 key 返回给客户端之前调用 guard.Check：
+// 感染文件移到 scanner.quarantine_dir 并从存储删除，响应 422 和扫描结果；
+// clamd 不可用时同样删除文件，响应 503，让客户端稍后重试。
+//
 // ============================================================================
 
-const (
-	// 上传目录
-	UploadDir = "./uploads"
-	// 单文件最大 10MB
-	MaxFileSize = 10 << 20 // 10MB
-	// 请求体最大 50MB (多文件上传)
-	MaxBodySize = 50 << 20 // 50MB
+// 上传配置来自 config 包（upload.max_file_size / upload.max_body_size / storage.*）
+var (
+	conf *config.Loader
+	// 存储后端，启动时按 storage.driver 创建
+	blob storage.Blob
+	// 上传后扫描，感染文件移到隔离目录
+	guard *scanner.Guard
 )
 
+// 临时链接有效期
+const urlTTL = time.Hour
+
+// MaxFileSize 单文件大小上限，默认 10MB
+// 每次请求读取最新配置，修改 config.yaml 后无需重启即可生效
+func MaxFileSize() int64 {
+	return conf.Get().Upload.MaxFileSize
+}
+
 // 允许的文件类型
 var AllowedImageTypes = map[string]bool{
 	"image/jpeg": true,
@@ -83,14 +134,109 @@ func generateID() string {
 	return hex.EncodeToString(b)
 }
 
+// saveUpload 把 multipart 文件写入存储后端
+func saveUpload(ctx context.Context, key string, fh *multipart.FileHeader) error {
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return blob.Put(ctx, key, f, fh.Header.Get("Content-Type"))
+}
+
+// scanned 扫描刚写入的 key，不通过时写好错误响应并返回 false
+func scanned(c *gin.Context, key string) bool {
+	v, err := guard.Check(c.Request.Context(), blob, key)
+	return scanOK(c, v, err)
+}
+
+// scanOK 把扫描结果转换成响应：感染 422，扫描服务不可用 503
+func scanOK(c *gin.Context, v scanner.Verdict, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, scanner.ErrInfected):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "infected",
+			"message": "文件未通过安全扫描",
+			"verdict": v,
+		})
+	case errors.Is(err, scanner.ErrUnavailable), errors.Is(err, scanner.ErrScan):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "scan_unavailable",
+			"message": "文件扫描暂时不可用，请稍后重试",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "scan failed"})
+	}
+	return false
+}
+
+// fileURL 生成文件的临时访问链接，失败时返回空字符串
+func fileURL(ctx context.Context, key string) string {
+	u, err := blob.SignedURL(ctx, key, urlTTL)
+	if err != nil {
+		return ""
+	}
+	return u
+}
+
 func main() {
+	var err error
+	conf, err = config.NewLoader(config.Options{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	blob, err = storage.Open(conf.Get().Storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if conf.File() != "" {
+		go conf.Watch(context.Background())
+	}
+
+	// 病毒扫描：默认不扫描，scanner.driver=clamav 时连接 clamd
+	// 隔离目录是单独的本地目录，没有任何下载路由
+	av, err := scanner.Open(conf.Get().Scanner)
+	if err != nil {
+		log.Fatal(err)
+	}
+	quarantine, err := storage.NewLocal(conf.Get().Scanner.QuarantineDir, "", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	guard = scanner.NewGuard(av, quarantine, scanner.Config{})
+
+	// 文件元数据表，去重上传使用（默认 SQLite test.db，见 database.*）
+	dbCfg := database.FromConfig(conf.Get().Database)
+	dbCfg.GORM = &gorm.Config{Logger: logger.Default.LogMode(logger.Warn)}
+	db, err := database.Open(context.Background(), dbCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := db.AutoMigrate(&files.File{}, &imageproc.Variant{}, &quota.Counter{}); err != nil {
+		log.Fatal(err)
+	}
+	dedup := files.New(db, blob, files.Config{})
+
+	// 图片后台处理：生成 100x100 / 300x300 缩略图，记录到 image_variants 表
+	images := imageproc.New(blob, db, imageproc.Config{})
+
 	r := gin.Default()
 
-	// 设置请求体大小限制
-	r.MaxMultipartMemory = MaxBodySize
+	// 设置请求体大小限制（默认 50MB，多文件上传）
+	r.MaxMultipartMemory = conf.Get().Upload.MaxBodySize
 
-	// 确保上传目录存在
-	os.MkdirAll(UploadDir, 0755)
+	// 每个 API Key 每月的额度：请求数和上传字节数，超出返回 402（需要升级套餐）
+	// 不带 X-API-Key 的请求不计量；/quota 在中间件之前注册，额度用完后也能查询
+	q := quota.New(quota.Config{
+		Store:  quota.NewGormStore(db),
+		Limits: quota.Limits{quota.Requests: 1000, quota.UploadBytes: 100 << 20},
+		Status: http.StatusPaymentRequired,
+	})
+	byKey := quota.ByHeader("X-API-Key")
+	r.GET("/quota", quota.Handler(q, byKey))
+	r.Use(quota.Middleware(q, byKey), quota.LimitUpload(q, byKey))
 
 	// ========================================================================
 	// 一、单文件上传 (基础版)
@@ -107,19 +253,23 @@ func main() {
 			return
 		}
 
-		// 保存文件 (使用原文件名，不推荐用于生产环境)
-		dst := filepath.Join(UploadDir, file.Filename)
-		if err := c.SaveUploadedFile(file, dst); err != nil {
+		// 保存文件 (使用原文件名，不推荐用于生产环境，同名文件会被覆盖)
+		key := filepath.Base(file.Filename)
+		if err := saveUpload(c.Request.Context(), key, file); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "save_failed",
 				"message": "文件保存失败",
 			})
 			return
 		}
+		if !scanned(c, key) {
+			return
+		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"message":  "上传成功",
 			"filename": file.Filename,
+			"key":      key,
 			"size":     file.Size,
 		})
 	})
@@ -140,10 +290,10 @@ func main() {
 		defer file.Close()
 
 		// 1. 文件大小校验
-		if header.Size > MaxFileSize {
+		if header.Size > MaxFileSize() {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":   "file_too_large",
-				"message": fmt.Sprintf("文件大小不能超过 %dMB", MaxFileSize/(1<<20)),
+				"message": fmt.Sprintf("文件大小不能超过 %dMB", MaxFileSize()/(1<<20)),
 			})
 			return
 		}
@@ -196,44 +346,52 @@ func main() {
 			strings.ToLower(ext),
 		)
 
-		// 4. 按日期分目录存储
-		dateDir := time.Now().Format("2006/01/02")
-		fullDir := filepath.Join(UploadDir, "images", dateDir)
-		os.MkdirAll(fullDir, 0755)
+		// 4. 按日期分目录存储（key 用 "/" 分隔，和操作系统无关）
+		key := path.Join("images", time.Now().Format("2006/01/02"), newFilename)
 
-		dst := filepath.Join(fullDir, newFilename)
-
-		// 5. 流式保存文件
-		out, err := os.Create(dst)
+		// 5. 去掉 EXIF（GPS 坐标、设备信息）后再写入存储，Content-Type 用检测出的真实类型
+		// 大小已经校验过，整张图读进内存没有问题
+		data, err := io.ReadAll(file)
+		if err == nil {
+			data, err = imageproc.StripMetadata(data, 90)
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "create_failed",
-				"message": "无法创建文件",
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_image",
+				"message": "图片文件已损坏",
 			})
 			return
 		}
-		defer out.Close()
-
-		_, err = io.Copy(out, file)
-		if err != nil {
+		if err := blob.Put(c.Request.Context(), key, bytes.NewReader(data), contentType); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "write_failed",
 				"message": "文件写入失败",
 			})
 			return
 		}
+		if !scanned(c, key) {
+			return
+		}
 
-		// 6. 返回文件访问路径
-		relativePath := filepath.Join("images", dateDir, newFilename)
+		// 6. 扫描通过后，缩略图交给后台生成，响应里先给出将来的地址
+		// 队列满时不影响上传结果，只是没有缩略图
+		processing := images.Submit(key) == nil
+		variants := gin.H{}
+		for _, v := range images.Plan(key) {
+			variants[v.Name] = gin.H{"key": v.Key, "width": v.Width, "height": v.Height, "url": fileURL(c.Request.Context(), v.Key)}
+		}
 
+		// 7. 返回 key 和临时访问链接
 		c.JSON(http.StatusOK, gin.H{
 			"message":       "上传成功",
 			"original_name": header.Filename,
 			"saved_name":    newFilename,
-			"size":          header.Size,
+			"size":          len(data),
 			"content_type":  contentType,
-			"path":          relativePath,
-			"url":           fmt.Sprintf("/files/%s", relativePath),
+			"key":           key,
+			"url":           fileURL(c.Request.Context(), key),
+			"variants":      variants,
+			"processing":    processing,
 		})
 	})
 
@@ -269,17 +427,24 @@ func main() {
 			return
 		}
 
-		var results []gin.H
-		var errors []gin.H
+		// 每个文件的处理结果按下标写入，各 goroutine 互不干扰，不需要加锁
+		type outcome struct {
+			ok   bool
+			info gin.H
+		}
+		outcomes := make([]outcome, len(files))
+
+		// 保存和病毒扫描都是 IO，并发处理；最多 4 个同时进行，
+		// 10 个大文件不会一下子占满存储后端的连接和扫描引擎
+		err = conc.ForEach(c.Request.Context(), files, 4, func(ctx context.Context, i int, file *multipart.FileHeader) error {
+			fail := func(reason string) {
+				outcomes[i] = outcome{info: gin.H{"filename": file.Filename, "error": reason}}
+			}
 
-		for _, file := range files {
 			// 校验单个文件大小
-			if file.Size > MaxFileSize {
-				errors = append(errors, gin.H{
-					"filename": file.Filename,
-					"error":    "文件过大",
-				})
-				continue
+			if file.Size > MaxFileSize() {
+				fail("文件过大")
+				return nil
 			}
 
 			// 生成新文件名
@@ -290,23 +455,44 @@ func main() {
 				ext,
 			)
 
-			dst := filepath.Join(UploadDir, "batch", newFilename)
-			os.MkdirAll(filepath.Dir(dst), 0755)
+			key := path.Join("batch", newFilename)
 
 			// 保存文件
-			if err := c.SaveUploadedFile(file, dst); err != nil {
-				errors = append(errors, gin.H{
-					"filename": file.Filename,
-					"error":    "保存失败",
-				})
-				continue
+			if err := saveUpload(ctx, key, file); err != nil {
+				fail("保存失败")
+				return nil
+			}
+			// 感染的文件算作失败，不影响同一批的其他文件
+			if v, err := guard.Check(ctx, blob, key); err != nil {
+				fail("未通过安全扫描")
+				outcomes[i].info["verdict"] = v
+				return nil
 			}
 
-			results = append(results, gin.H{
+			outcomes[i] = outcome{ok: true, info: gin.H{
 				"original_name": file.Filename,
 				"saved_name":    newFilename,
+				"key":           key,
 				"size":          file.Size,
-			})
+			}}
+			// 单个文件失败不返回 error：返回 error 会取消同一批中其他文件的上传
+			return nil
+		})
+		if err != nil {
+			// 只有客户端断开（请求 ctx 取消）时才会走到这里，没有人接收响应了
+			log.Printf("multiple upload aborted: %v", err)
+			return
+		}
+
+		// 按上传顺序整理结果
+		var results []gin.H
+		var errors []gin.H
+		for _, o := range outcomes {
+			if o.ok {
+				results = append(results, o.info)
+			} else {
+				errors = append(errors, o.info)
+			}
 		}
 
 		c.JSON(http.StatusOK, gin.H{
@@ -319,8 +505,10 @@ func main() {
 	})
 
 	// ========================================================================
-	// 四、大文件流式上传 (分块读取)
+	// 四、大文件流式上传
 	// ========================================================================
+	//
+	// 文件不经过内存：本地存储边读边写临时文件，S3 直接把文件流作为请求体。
 
 	r.POST("/upload/stream", func(c *gin.Context) {
 		file, header, err := c.Request.FormFile("file")
@@ -336,45 +524,23 @@ func main() {
 			time.Now().Unix(),
 			filepath.Ext(header.Filename),
 		)
-		dst := filepath.Join(UploadDir, "large", newFilename)
-		os.MkdirAll(filepath.Dir(dst), 0755)
+		key := path.Join("large", newFilename)
 
-		// 创建目标文件
-		out, err := os.Create(dst)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
+		// 流式写入存储后端
+		if err := blob.Put(c.Request.Context(), key, file, "application/octet-stream"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "write failed"})
 			return
 		}
-		defer out.Close()
-
-		// 流式写入 (32KB 缓冲区)
-		buffer := make([]byte, 32*1024)
-		var totalBytes int64
-
-		for {
-			n, err := file.Read(buffer)
-			if n > 0 {
-				written, writeErr := out.Write(buffer[:n])
-				if writeErr != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "write failed"})
-					return
-				}
-				totalBytes += int64(written)
-			}
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "read failed"})
-				return
-			}
+		if !scanned(c, key) {
+			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"message":       "上传成功",
 			"original_name": header.Filename,
 			"saved_name":    newFilename,
-			"size":          totalBytes,
+			"key":           key,
+			"size":          header.Size,
 		})
 	})
 
@@ -382,76 +548,63 @@ func main() {
 	// 五、文件下载
 	// ========================================================================
 
-	// 直接下载 (小文件)
-	r.GET("/download/:filename", func(c *gin.Context) {
-		filename := c.Param("filename")
-
-		// 安全检查：防止路径遍历攻击
-		filename = filepath.Base(filename)
-		filePath := filepath.Join(UploadDir, filename)
+	// 经过应用服务器下载：适合需要鉴权、记录下载次数的场景
+	// key 可以带目录，如 /download/images/2024/05/01/xxx.jpg
+	// 支持 Range 断点续传和 ETag 缓存，upload.download_rate 限制单个连接的速度
+	r.GET("/download/*key", func(c *gin.Context) {
+		key := strings.TrimPrefix(c.Param("key"), "/")
 
-		// 检查文件是否存在
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		// 安全检查：Blob 实现会拒绝绝对路径和 .. 片段，防止路径遍历攻击
+		err := download.Serve(c.Writer, c.Request, blob, key, download.Options{
+			Rate:       conf.Get().Upload.DownloadRate,
+			Attachment: true,
+		})
+		switch {
+		case err == nil:
+		case c.Writer.Written():
+			// 已经开始传输（通常是客户端断开），只能中断，下次带 Range 续传
+			log.Printf("download %s interrupted: %v", key, err)
+		case errors.Is(err, storage.ErrNotFound):
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "not_found",
 				"message": "文件不存在",
 			})
-			return
+		case errors.Is(err, storage.ErrInvalidKey):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_key"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "read failed"})
+		}
+	})
+	r.HEAD("/download/*key", func(c *gin.Context) {
+		key := strings.TrimPrefix(c.Param("key"), "/")
+		if err := download.Serve(c.Writer, c.Request, blob, key, download.Options{}); err != nil && !c.Writer.Written() {
+			c.Status(http.StatusNotFound)
 		}
-
-		// 设置下载文件名
-		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-		c.File(filePath)
 	})
 
-	// 流式下载 (大文件)
-	r.GET("/download/stream/:filename", func(c *gin.Context) {
-		filename := filepath.Base(c.Param("filename"))
-		filePath := filepath.Join(UploadDir, filename)
-
-		// 打开文件
-		file, err := os.Open(filePath)
+	// 获取临时下载链接：客户端拿到链接后直接下载，流量不经过应用服务器
+	r.GET("/links/*key", func(c *gin.Context) {
+		key := strings.TrimPrefix(c.Param("key"), "/")
+		u, err := blob.SignedURL(c.Request.Context(), key, urlTTL)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_key"})
 			return
 		}
-		defer file.Close()
-
-		// 获取文件信息
-		stat, _ := file.Stat()
-
-		// 设置响应头
-		c.Header("Content-Type", "application/octet-stream")
-		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-		c.Header("Content-Length", fmt.Sprintf("%d", stat.Size()))
-
-		// 流式传输
-		c.Stream(func(w io.Writer) bool {
-			buffer := make([]byte, 32*1024)
-			for {
-				n, err := file.Read(buffer)
-				if n > 0 {
-					w.Write(buffer[:n])
-				}
-				if err == io.EOF {
-					return false
-				}
-				if err != nil {
-					return false
-				}
-			}
+		c.JSON(http.StatusOK, gin.H{
+			"url":        u,
+			"expires_in": int(urlTTL.Seconds()),
 		})
 	})
 
 	// ========================================================================
-	// 六、静态文件服务 (访问已上传的文件)
+	// 六、临时链接的文件服务 (访问已上传的文件)
 	// ========================================================================
 
-	// 静态文件服务
-	r.Static("/files", UploadDir)
-
-	// 或者使用 StaticFS 自定义配置
-	// r.StaticFS("/assets", http.Dir(UploadDir))
+	// 本地存储需要自己处理 SignedURL 生成的链接，校验签名和过期时间；
+	// S3 的预签名链接指向对象存储，不需要这个路由
+	if local, ok := blob.(*storage.Local); ok {
+		r.GET(conf.Get().Storage.Local.BaseURL+"/*key", local.Handler())
+	}
 
 	// ========================================================================
 	// 七、带表单数据的文件上传
@@ -483,35 +636,144 @@ func main() {
 			return
 		}
 
-		// 保存文件
+		// 保存文件，同一用户的头像覆盖旧文件
 		ext := filepath.Ext(file.Filename)
-		newFilename := fmt.Sprintf("avatar_%d%s", form.UserID, ext)
-		dst := filepath.Join(UploadDir, "avatars", newFilename)
-		os.MkdirAll(filepath.Dir(dst), 0755)
+		key := path.Join("avatars", fmt.Sprintf("avatar_%d%s", form.UserID, ext))
 
-		if err := c.SaveUploadedFile(file, dst); err != nil {
+		if err := saveUpload(c.Request.Context(), key, file); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "save failed"})
 			return
 		}
+		if !scanned(c, key) {
+			return
+		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"message":     "头像上传成功",
 			"user_id":     form.UserID,
 			"description": form.Description,
-			"avatar_url":  fmt.Sprintf("/files/avatars/%s", newFilename),
+			"avatar_key":  key,
+			"avatar_url":  fileURL(c.Request.Context(), key),
+		})
+	})
+
+	// ========================================================================
+	// 八、分片上传 (断点续传)
+	// ========================================================================
+	//
+	// 大文件切成 5MB 的块逐个上传，会话和分片都存在 blob 里，
+	// 网络中断或服务重启后查询 missing 只补传缺失的块，最后合并并校验 SHA-256。
+	// 合并后的文件放在 large/ 下，和 /upload/stream 上传的文件在一起。
+	// 注意：合并后的文件没有经过 guard 扫描，需要时在 complete 之后调用 guard.Check。
+
+	chunked := upload.NewManager(blob, upload.Config{KeyPrefix: "large"})
+	upload.Register(r.Group("/upload/sessions"), chunked)
+
+	// ========================================================================
+	// 九、按内容去重上传
+	// ========================================================================
+	//
+	// 边读边算 SHA-256，相同内容只存一份：第二次上传同一个文件直接返回已有记录，
+	// duplicate 为 true。元数据（哈希、大小、类型、原始文件名、上传者、key）存在 files 表。
+
+	r.POST("/upload/dedup", func(c *gin.Context) {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file_required", "message": "请选择要上传的文件"})
+			return
+		}
+		defer file.Close()
+		if header.Size > MaxFileSize() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file_too_large"})
+			return
+		}
+		// 示例没有登录，上传者从表单读取，实际项目从 JWT 中取
+		owner, _ := strconv.ParseUint(c.PostForm("user_id"), 10, 32)
+
+		// 写入后还要建 files 记录，所以在写入之前扫描临时文件
+		v, err := guard.CheckFile(c.Request.Context(), file, path.Join("dedup", generateID()+filepath.Ext(header.Filename)))
+		if !scanOK(c, v, err) {
+			return
+		}
+
+		f, duplicate, err := dedup.Save(c.Request.Context(), file, header.Filename, uint(owner))
+		if errors.Is(err, files.ErrEmpty) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "empty_file"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "save failed"})
+			return
+		}
+		status := http.StatusCreated
+		if duplicate {
+			status = http.StatusOK
+		}
+		c.JSON(status, gin.H{
+			"file":      f,
+			"duplicate": duplicate,
+			"url":       fileURL(c.Request.Context(), f.Path),
 		})
 	})
 
-	r.Run(":8080")
+	r.GET("/upload/files/:id", func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+		f, err := dedup.Get(c.Request.Context(), uint(id))
+		if errors.Is(err, files.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "文件不存在"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"file": f, "url": fileURL(c.Request.Context(), f.Path)})
+	})
+
+	// 查询已经生成的缩略图，列表为空说明还在处理（或处理失败，见日志）
+	r.GET("/upload/variants/*key", func(c *gin.Context) {
+		key := strings.TrimPrefix(c.Param("key"), "/")
+		variants, err := images.Variants(c.Request.Context(), key)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+			return
+		}
+		items := make([]gin.H, 0, len(variants))
+		for _, v := range variants {
+			items = append(items, gin.H{"variant": v, "url": fileURL(c.Request.Context(), v.Key)})
+		}
+		c.JSON(http.StatusOK, gin.H{"key": key, "variants": items})
+	})
+
+	srv := server.New(r, server.Config{Addr: conf.Get().Server.Addr})
+
+	// 图片处理 worker 随服务启动；关闭时等正在处理的图片完成
+	procCtx, stopProc := context.WithCancel(context.Background())
+	procDone := make(chan struct{})
+	go func() {
+		images.Run(procCtx)
+		close(procDone)
+	}()
+	srv.OnShutdown("image processor", func(ctx context.Context) error {
+		stopProc()
+		select {
+		case <-procDone:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	// 收到 Ctrl+C / SIGTERM 后等待进行中的请求完成再退出
+	if err := srv.Run(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // ============================================================================
 // 测试命令
 // ============================================================================
 //
-// # 先创建上传目录
-// mkdir -p uploads
-//
 // # 创建测试文件
 // echo "test content" > test.txt
 // # 或使用真实图片文件
@@ -520,9 +782,10 @@ func main() {
 // curl -X POST http://localhost:8080/upload/simple \
 //   -F "file=@test.txt"
 //
-// # 单文件上传 (生产版，上传图片)
+// # 单文件上传 (生产版，上传图片，去掉 EXIF 并在后台生成缩略图)
 // curl -X POST http://localhost:8080/upload/image \
 //   -F "file=@/path/to/image.jpg"
+// curl http://localhost:8080/upload/variants/<返回的 key>
 //
 // # 多文件上传
 // curl -X POST http://localhost:8080/upload/multiple \
@@ -535,11 +798,53 @@ func main() {
 //   -F "description=我的头像" \
 //   -F "avatar=@/path/to/avatar.jpg"
 //
-// # 文件下载
-// curl -O http://localhost:8080/download/test.txt
-//
-// # 访问静态文件
-// curl http://localhost:8080/files/test.txt
+// # 文件下载（经过应用服务器）
+// curl -OJ http://localhost:8080/download/test.txt
+//
+// # 断点续传：只取一部分 / 从本地已有文件的末尾继续下载
+// curl -i -r 0-99 http://localhost:8080/download/test.txt      # 206 Content-Range: bytes 0-99/...
+// curl -C - -o test.txt http://localhost:8080/download/test.txt
+//
+// # ETag 缓存：带上次响应的 ETag，文件没变返回 304
+// curl -i -H 'If-None-Match: "<ETag>"' http://localhost:8080/download/test.txt
+//
+// # 获取临时链接，再用返回的 url 下载（本地存储是 /files/...?expires=...&sig=...）
+// curl http://localhost:8080/links/test.txt
+// curl "http://localhost:8080/files/test.txt?expires=...&sig=..."
+//
+// # 分片上传：创建会话 → 上传分片（可乱序、可重传）→ 合并
+// head -c 12000000 /dev/urandom > big.bin && split -b 5242880 -d -a 1 big.bin part.
+// curl -X POST http://localhost:8080/upload/sessions -H "Content-Type: application/json" \
+//   -d "{\"filename\":\"big.bin\",\"size\":12000000,\"sha256\":\"$(sha256sum big.bin | cut -d' ' -f1)\"}"
+// curl -X PUT --data-binary @part.0 http://localhost:8080/upload/sessions/<id>/chunks/0
+// curl -X PUT --data-binary @part.2 http://localhost:8080/upload/sessions/<id>/chunks/2
+// curl http://localhost:8080/upload/sessions/<id>          # missing: [1]
+// curl -X PUT --data-binary @part.1 http://localhost:8080/upload/sessions/<id>/chunks/1
+// curl -X POST http://localhost:8080/upload/sessions/<id>/complete
+//
+// # 去重上传：同一个文件传两次，第二次 duplicate=true，id 相同
+// curl -X POST http://localhost:8080/upload/dedup -F "user_id=1" -F "file=@test.txt"
+// curl -X POST http://localhost:8080/upload/dedup -F "user_id=2" -F "file=@test.txt"
+// curl http://localhost:8080/upload/files/1
+//
+// # 病毒扫描：启动 clamd 后用 EICAR 测试文件验证，返回 422 和 verdict，文件移到 ./quarantine
+// docker run -d -p 3310:3310 clamav/clamav
+// APP_SCANNER_DRIVER=clamav go run examples/2_3_file_upload.go
+// curl -o eicar.txt https://secure.eicar.org/eicar.com.txt
+// curl -X POST http://localhost:8080/upload/stream -F "file=@eicar.txt"
+//
+// # 月度额度：带 X-API-Key 的请求计入请求数和上传字节数，响应头 X-Quota-Remaining 是剩余请求数
+// curl -i -X POST http://localhost:8080/upload/simple -H "X-API-Key: demo" -F "file=@test.txt"
+// curl http://localhost:8080/quota -H "X-API-Key: demo"
+// head -c 110000000 /dev/zero > huge.bin   # 超过 100MB 上传额度，返回 402 quota_exceeded
+// curl -i -X POST http://localhost:8080/upload/stream -H "X-API-Key: demo" -F "file=@huge.bin"
+//
+// # 切换到 MinIO（先创建 uploads 存储桶），handler 代码不用改
+// docker run -d -p 9000:9000 minio/minio server /data
+// APP_STORAGE_DRIVER=s3 APP_STORAGE_S3_ENDPOINT=http://127.0.0.1:9000 \
+//   APP_STORAGE_S3_BUCKET=uploads APP_STORAGE_S3_PATH_STYLE=true \
+//   APP_STORAGE_S3_ACCESS_KEY=minioadmin APP_STORAGE_S3_SECRET_KEY=minioadmin \
+//   go run examples/2_3_file_upload.go
 //
 // ============================================================================
 
@@ -567,7 +872,7 @@ func main() {
 //
 // 4. 【路径遍历攻击】
 //    下载文件时要验证路径
-//    filename = filepath.Base(filename) // 只保留文件名
+//    storage.ValidKey 拒绝以 / 开头和包含 .. 的 key，Blob 的每个实现都会检查
 //
 // 5. 【大文件处理】
 //    大文件应该用流式处理，不要一次性读入内存
@@ -578,9 +883,11 @@ func main() {
 //    使用 UUID 或时间戳确保文件名唯一
 //
 // 7. 【磁盘空间】
-//    生产环境要监控磁盘空间
-//    定期清理过期文件
-//    考虑使用对象存储 (S3, OSS)
+//    本地存储要监控磁盘空间，定期清理过期文件
+//    多实例部署时本地磁盘不共享，要改用对象存储 (storage.driver: s3)
+//
+// 8. 【签名密钥】
+//    storage.secret 为空时每次启动随机生成，重启后旧链接全部失效
 //
 // ============================================================================
 
@@ -589,13 +896,14 @@ func main() {
 // ============================================================================
 //
 // 1. 【使用对象存储】
-//    - 阿里云 OSS
+//    - 阿里云 OSS / 腾讯云 COS（S3 兼容接口）
 //    - AWS S3
 //    - MinIO (自建)
 //    优势: 高可用、CDN 加速、自动扩容
+//    配置 storage.driver: s3 即可，见 storage/s3.go
 //
 // 2. 【文件元数据存储】
-//    将文件信息存入数据库:
+//    将文件信息存入数据库（见第九节和 files 包）:
 //    - 原始文件名
 //    - 存储路径
 //    - 文件大小
@@ -611,9 +919,11 @@ func main() {
 //    - 自动生成缩略图
 //    - 去除 EXIF 信息 (隐私)
 //    - 压缩优化
+//    见 /upload/image 和 imageproc 包；WebP 编码需要 cgo 库，通过 imageproc.Format 注入
 //
 // 5. 【断点续传】
-//    大文件支持分块上传和断点续传
+//    大文件支持分块上传和断点续传，见第八节和 upload 包
+//    S3 原生的 Multipart Upload 可以省掉合并时的两次读取，但只能用在 S3 后端
 //
 // ============================================================================
 
@@ -621,14 +931,14 @@ func main() {
 // 练习题
 // ============================================================================
 //
-// 1. 实现文件去重:
-//    - 计算文件 MD5
-//    - 相同 MD5 的文件不重复存储
-//    - 返回已有文件的路径
+// 1. 给去重上传加引用表:
+//    - files 表一份内容一条记录，第一个上传者的文件名会被所有人看到
+//    - 新建 user_files(user_id, file_id, name)，每个用户保留自己的文件名
+//    - 删除时引用计数归零才删除对象
 //
-// 2. 实现图片上传自动缩略图:
-//    - 上传图片后自动生成 100x100、300x300 缩略图
-//    - 返回原图和缩略图的 URL
+// 2. 缩略图失败重试:
+//    - 现在 worker 处理失败只记日志
+//    - 参考 operation 包的指数退避，失败的图片重新入队，超过次数后记录到数据库
 //
 // 3. 实现文件上传进度:
 //    - 使用 SSE 或 WebSocket 推送上传进度
@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"go-one/server"
 )
 
 // ============================================================================
@@ -212,7 +214,10 @@ func main() {
 	log.Println("  curl http://localhost:8080/public/info")
 	log.Println("  curl -H 'Authorization: Bearer token' http://localhost:8080/api/profile")
 
-	r.Run(":8080")
+	// 收到 Ctrl+C / SIGTERM 后等待进行中的请求完成再退出
+	if err := server.Run(r, ":8080"); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // ============================================================================
@@ -7,14 +7,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"go-one/middleware/bodylimit"
+	"go-one/middleware/compress"
+	"go-one/middleware/logger"
+	"go-one/middleware/recovery"
+	"go-one/server"
 )
 
 // ============================================================================
@@ -220,6 +228,94 @@ func main() {
 		})
 	}
 
+	// ========================================================================
+	// 七、结构化日志（log/slog）
+	// ========================================================================
+
+	// JSON 日志 + 采样 + 脱敏，实现见 middleware/logger
+	slogGroup := r.Group("/slog")
+	slogGroup.Use(logger.New(logger.Config{
+		// /slog/hot 每 10 次请求只记录 1 次（4xx/5xx 和超过 1 秒的慢请求始终记录）
+		Sampling:   map[string]int{"/slog/hot": 10},
+		LogHeaders: true,
+		Redact:     true, // Authorization、?password= 等输出为 [REDACTED]，?email= 等掩码为 a***@example.com
+	}))
+	{
+		slogGroup.GET("/test", func(c *gin.Context) {
+			// 请求级 Logger，自动带上 request_id
+			// 默认 Logger 开启脱敏时，email / phone 属性同样掩码
+			logger.FromContext(c).Info("handling slog test", slog.String("email", c.Query("email")))
+			c.JSON(http.StatusOK, gin.H{"message": "slog logger test"})
+		})
+		slogGroup.GET("/hot", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"message": "sampled"})
+		})
+	}
+
+	// ========================================================================
+	// 八、结构化 Recovery（统一响应 + 堆栈日志 + 上报）
+	// ========================================================================
+
+	// 先挂日志中间件，Recovery 才能拿到带 request_id 的 Logger，日志也能记录到 500
+	recoverGroup := r.Group("/recover")
+	recoverGroup.Use(
+		logger.New(logger.Config{}),
+		recovery.New(recovery.Config{
+			// 生产环境可以换成 Sentry 客户端；这里只打印一行
+			Reporter: recovery.ReporterFunc(func(ctx context.Context, e recovery.Event) {
+				log.Printf("[REPORT] %s %s panic=%v", e.Method, e.Route, e.Value)
+			}),
+		}),
+	)
+	{
+		// 返回 {"code":-1,"message":"服务器内部错误，请稍后重试","error":"internal_error"}
+		recoverGroup.GET("/test", func(c *gin.Context) {
+			panic("something went wrong!")
+		})
+
+		// 模拟客户端断开：只记录一条 WARN，不打印堆栈、不上报
+		recoverGroup.GET("/abort", func(c *gin.Context) {
+			panic(http.ErrAbortHandler)
+		})
+	}
+
+	// ========================================================================
+	// 九、响应压缩与请求体大小限制
+	// ========================================================================
+
+	// 小于 1KB 的响应、图片等已压缩的类型不压缩，实现见 middleware/compress
+	// 请求体超过 1KB 返回 413（统一错误格式），实现见 middleware/bodylimit
+	limitGroup := r.Group("/compress")
+	limitGroup.Use(compress.New(compress.Config{}), bodylimit.New(1<<10))
+	{
+		// 约 5KB 的 JSON，带 Accept-Encoding: gzip 时压缩到几百字节
+		limitGroup.GET("/large", func(c *gin.Context) {
+			items := make([]gin.H, 100)
+			for i := range items {
+				items[i] = gin.H{"id": i, "name": fmt.Sprintf("item-%d", i), "status": "active"}
+			}
+			c.JSON(http.StatusOK, gin.H{"items": items})
+		})
+
+		limitGroup.GET("/small", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"message": "too small to compress"})
+		})
+
+		limitGroup.POST("/upload", func(c *gin.Context) {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				// 没有 Content-Length 的超大请求体在读取时报错
+				if bodylimit.IsTooLarge(err) {
+					c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "body too large"})
+					return
+				}
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"received": len(body)})
+		})
+	}
+
 	// ========================================================================
 	// 测试路由
 	// ========================================================================
@@ -247,8 +343,14 @@ func main() {
 	log.Println("  curl http://localhost:8080/panic/test")
 	log.Println("  curl http://localhost:8080/status/404")
 	log.Println("  curl http://localhost:8080/prod/test")
+	log.Println("  curl 'http://localhost:8080/slog/test?password=123&email=alice@example.com' -H 'Authorization: Bearer xxx'")
+	log.Println("  curl http://localhost:8080/recover/test")
+	log.Println("  curl -sI -H 'Accept-Encoding: gzip' http://localhost:8080/compress/large")
 
-	r.Run(":8080")
+	// 收到 Ctrl+C / SIGTERM 后等待进行中的请求完成再退出
+	if err := server.Run(r, ":8080"); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // ============================================================================
@@ -355,6 +457,24 @@ func ProductionLogger() gin.HandlerFunc {
 // # 生产级日志
 // curl http://localhost:8080/prod/test
 //
+// # 结构化日志（password 与 Authorization 会被脱敏，email 掩码）
+// curl 'http://localhost:8080/slog/test?password=123&email=alice@example.com' -H 'Authorization: Bearer xxx'
+//
+// # 采样（连续请求 20 次只输出 2 条日志）
+// for i in {1..20}; do curl -s http://localhost:8080/slog/hot > /dev/null; done
+//
+// # 结构化 Recovery（统一 JSON 响应，堆栈写入 slog，并调用 Reporter）
+// curl http://localhost:8080/recover/test
+//
+// # 响应压缩（看 Content-Encoding: gzip 和 Vary: Accept-Encoding）
+// curl -s -D - -o /dev/null -H 'Accept-Encoding: gzip' http://localhost:8080/compress/large
+// curl --compressed http://localhost:8080/compress/large      # curl 自动解压
+// curl -s -D - -H 'Accept-Encoding: gzip' http://localhost:8080/compress/small  # 太小，不压缩
+//
+// # 请求体大小限制（超过 1KB 返回 413）
+// curl -X POST -d 'hello' http://localhost:8080/compress/upload
+// head -c 2048 /dev/zero | curl -X POST --data-binary @- http://localhost:8080/compress/upload
+//
 // ============================================================================
 
 // ============================================================================
@@ -381,6 +501,15 @@ func ProductionLogger() gin.HandlerFunc {
 //    SkipPaths 是精确匹配，不支持通配符
 //    /health 和 /health/ 是不同的路径
 //
+// 6. 【压缩中间件的顺序】
+//    压缩中间件要挂在会写响应的中间件（Recovery、超时）外层，
+//    否则它们绕过压缩直接写出的响应会和已压缩的数据混在一起；
+//    SSE 等流式响应每次 Flush 都会立即压缩发送，不会被缓冲卡住
+//
+// 7. 【ShouldBindJSON 不限制大小】
+//    Go 不限制请求体大小，一个超大 POST 就能把内存吃满；
+//    bodylimit 先看 Content-Length，chunked 请求用 http.MaxBytesReader 在读取时截断
+//
 // ============================================================================
 
 // ============================================================================
@@ -16,6 +16,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"go-one/server"
 )
 
 // ============================================================================
@@ -137,7 +139,10 @@ func main() {
 	log.Println("  curl -H 'Authorization: Bearer <token>' http://localhost:8080/api/profile")
 	log.Println("  for i in {1..15}; do curl http://localhost:8080/limited/test; done")
 
-	r.Run(":8080")
+	// 收到 Ctrl+C / SIGTERM 后等待进行中的请求完成再退出
+	if err := server.Run(r, ":8080"); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // ============================================================================
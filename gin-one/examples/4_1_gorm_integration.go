@@ -2,21 +2,73 @@
 // 4.1 GORM 集成与 CRUD
 // ============================================================================
 // 运行方式: go run examples/4_1_gorm_integration.go
-// 需要先安装: go get -u gorm.io/gorm gorm.io/driver/sqlite
+// 指定数据库: go run examples/4_1_gorm_integration.go -database.name=app.db
+// 使用 MySQL: APP_DATABASE_PASSWORD=secret go run examples/4_1_gorm_integration.go
+//            -database.driver=mysql -database.host=127.0.0.1 -database.user=root -database.name=app
+// 需要先安装: go get -u gorm.io/gorm gorm.io/driver/sqlite gorm.io/driver/mysql gorm.io/driver/postgres
 // ============================================================================
 
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/sqlite"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+
+	"go-one/activity"
+	"go-one/app"
+	"go-one/apperr"
+	"go-one/audit"
+	"go-one/auth/password"
+	"go-one/cache"
+	"go-one/cache/redis"
+	"go-one/config"
+	"go-one/crudgen"
+	"go-one/crypto/fieldenc"
+	"go-one/csvimport"
+	"go-one/database"
+	"go-one/diff"
+	"go-one/eventbus"
+	"go-one/feed"
+	"go-one/health"
+	"go-one/i18n"
+	"go-one/jobs"
+	"go-one/lock"
+	"go-one/mapper"
+	"go-one/middleware/etag"
+	"go-one/middleware/idempotency"
+	"go-one/model"
+	"go-one/outbox"
+	"go-one/pagination"
+	"go-one/policy"
+	"go-one/pools"
+	"go-one/publicapi"
+	"go-one/render"
+	"go-one/repository"
+	"go-one/resilience"
+	"go-one/server"
+	"go-one/service"
+	"go-one/tracing"
+	"go-one/trash"
+	"go-one/webhooks"
+	"go-one/webhooks/inbound"
+
+	"go-learning/httpclient"
+	"go-learning/multierr"
 )
 
 // ============================================================================
@@ -40,95 +92,181 @@ import (
 // ============================================================================
 // 模型定义
 // ============================================================================
+//
+// User / Post / Tag / Comment 定义在 model 包（字段和标签说明见 model/model.go），
+// repository、service 和本示例共用同一份定义。
+// 这里声明别名，下面的高级查询、事务演示可以直接写 User{}、Post{}
+
+type (
+	User    = model.User
+	Post    = model.Post
+	Tag     = model.Tag
+	Comment = model.Comment
+)
 
-// User 用户模型
-type User struct {
-	// gorm.Model 包含 ID, CreatedAt, UpdatedAt, DeletedAt
-	// type Model struct {
-	//     ID        uint           `gorm:"primaryKey"`
-	//     CreatedAt time.Time
-	//     UpdatedAt time.Time
-	//     DeletedAt gorm.DeletedAt `gorm:"index"`
-	// }
-	gorm.Model
+// Contact 用户的联系方式，手机号和身份证号加密存储（见 crypto/fieldenc）
+// 库里是 enc:v1:... 的密文，读出来自动解密；加密列不能用 WHERE phone = ? 查询
+type Contact struct {
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	Phone     string    `gorm:"size:255;serializer:encrypted" json:"phone"`
+	IDCard    string    `gorm:"size:255;serializer:encrypted" json:"id_card"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
 
-	// 用户名：唯一索引，非空
-	Username string `gorm:"uniqueIndex;not null;size:50" json:"username"`
+func (Contact) TableName() string { return "user_contacts" }
 
-	// 邮箱：唯一索引
-	Email string `gorm:"uniqueIndex;size:100" json:"email"`
+// ============================================================================
+// 全局数据库连接
+// ============================================================================
 
-	// 密码：不返回给前端
-	Password string `gorm:"not null" json:"-"`
+var DB *gorm.DB
+
+// Replicas 读写分离插件，没有配置 database.replicas 时为 nil
+var Replicas *database.Resolver
 
-	// 年龄：默认值
-	Age int `gorm:"default:0" json:"age"`
+// Locker 多实例互斥：迁移和定时清理同一时刻只有一个实例执行
+// PostgreSQL 用 advisory lock，SQLite / MySQL 是进程内的锁，多实例时换成 lock.NewRedis
+var Locker lock.Locker
 
-	// 状态：枚举
-	Status string `gorm:"type:varchar(20);default:'active'" json:"status"`
+// FieldKeys 加密列的密钥环，没有配置 fieldenc.keys 时为 nil，联系方式接口不注册
+var FieldKeys *fieldenc.Keyring
 
-	// 关联：一个用户有多篇文章
-	Posts []Post `gorm:"foreignKey:UserID" json:"posts,omitempty"`
+// Events 事务发件箱的发布器，TransactionDemo 提交后通知它立即发布
+var Events *outbox.Relay
+
+// WelcomeEmail 注册欢迎邮件任务的 payload
+type WelcomeEmail struct {
+	UserID uint   `json:"user_id"`
+	Email  string `json:"email"`
 }
 
-// Post 文章模型
-type Post struct {
-	gorm.Model
-	Title   string `gorm:"not null;size:200" json:"title"`
-	Content string `gorm:"type:text" json:"content"`
-	UserID  uint   `gorm:"index" json:"user_id"`
+// SendWelcome 欢迎邮件任务，失败最多执行 3 次后进死信表
+var SendWelcome = jobs.Task[WelcomeEmail]{Name: "email.welcome", MaxAttempts: 3}
+
+// Jobs 任务队列的 worker，TransactionDemo 提交后通知它立即领取
+var Jobs *jobs.Worker
 
-	// 属于某个用户
-	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+// ImportUsersPayload 异步导入任务的 payload：上传的 CSV 先存成临时文件，worker 读完后删除
+type ImportUsersPayload struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
 }
 
-// TableName 自定义表名
-func (User) TableName() string {
-	return "users"
+// ImportUsers 异步导入用户任务；重复执行时已导入的行会被跳过，所以可以安全重试
+var ImportUsers = jobs.Task[ImportUsersPayload]{Name: "users.import", MaxAttempts: 2}
+
+// importReports 异步导入的结果，GET /users/import/:id 查询
+// 只保存在本进程内存里，多实例部署时要写到数据库或 Redis
+var importReports = cache.New[string, *csvimport.Report](cache.Config{MaxEntries: 1000, TTL: time.Hour})
+
+// UserCreatedEvent 用户创建后发布的进程内事件，也是 Webhook 请求体的 data
+type UserCreatedEvent struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
 }
 
-func (Post) TableName() string {
-	return "posts"
+// PostCreatedEvent 文章创建后发布的进程内事件，也是 Webhook 请求体的 data
+type PostCreatedEvent struct {
+	ID     uint   `json:"id"`
+	UserID uint   `json:"user_id"`
+	Title  string `json:"title"`
 }
 
-// ============================================================================
-// 全局数据库连接
-// ============================================================================
+// UserUpdatedEvent 用户资料修改后发布的进程内事件，只带字段名，不带新旧值
+type UserUpdatedEvent struct {
+	ID     uint     `json:"id"`
+	Fields []string `json:"fields"`
+}
 
-var DB *gorm.DB
+// 进程内事件主题，订阅者在 main 中注册
+var (
+	UserCreated = eventbus.NewTopic[UserCreatedEvent]("user.created")
+	UserUpdated = eventbus.NewTopic[UserUpdatedEvent]("user.updated")
+	PostCreated = eventbus.NewTopic[PostCreatedEvent]("post.created")
+)
 
-// InitDB 初始化数据库
-func InitDB() error {
-	var err error
+// Bus 进程内事件总线，Handler 创建成功后发布事件，审计、缓存失效、通知各自订阅
+var Bus *eventbus.Bus
+
+// Activities 用户动态，订阅 post.created / user.updated
+var Activities *activity.Store
+
+// Hooks 出站 Webhook，订阅 user.created / post.created 并投递给管理员登记的端点
+var Hooks *webhooks.Dispatcher
+
+// GitHubPushEvent GitHub push 事件里用到的字段，完整结构见 GitHub 文档
+type GitHubPushEvent struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Pusher struct {
+		Name string `json:"name"`
+	} `json:"pusher"`
+	Commits []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+	} `json:"commits"`
+}
+
+// passwords 密码哈希服务，新用户使用 argon2id
+var passwords = password.New(password.DefaultArgon2id(), password.DefaultBcrypt())
 
-	// SQLite 连接（开发环境）
-	// 生产环境换成 MySQL/PostgreSQL
-	DB, err = gorm.Open(sqlite.Open("test.db"), &gorm.Config{
+// InitDB 初始化数据库，驱动和连接参数来自配置 database.*（默认 SQLite test.db）
+func InitDB(ctx context.Context, cfg config.DatabaseConfig) error {
+	dbCfg := database.FromConfig(cfg)
+	dbCfg.GORM = &gorm.Config{
 		// 日志配置
 		Logger: logger.Default.LogMode(logger.Info),
 		// 禁用默认事务（提升性能）
 		// SkipDefaultTransaction: true,
 		// 预编译语句缓存
 		PrepareStmt: true,
-	})
+		// 唯一索引冲突等错误转换为 gorm.ErrDuplicatedKey，repository 据此返回 ErrDuplicate
+		TranslateError: true,
+	}
+
+	// 按 database.driver 选择 SQLite / MySQL / PostgreSQL，安全拼接 DSN，
+	// 连接池使用驱动默认值（database.DefaultPool），数据库没就绪时退避重试
+	var err error
+	DB, err = database.Open(ctx, dbCfg)
 	if err != nil {
 		return err
 	}
 
-	// 获取底层 *sql.DB 配置连接池
-	sqlDB, err := DB.DB()
+	// 读写分离：配置了只读副本时，查询路由到从库，写操作和事务留在主库
+	// SQLite 没有复制，可以复制一份数据库文件观察路由：
+	//   cp test.db replica.db && go run examples/4_1_gorm_integration.go -database.replicas=replica.db
+	//   之后新建的用户在 GET /users 里看不到（读走副本），但 POST 同一请求内的查询走主库
+	if len(cfg.Replicas) > 0 {
+		replicas, err := database.OpenReplicas(ctx, dbCfg, cfg.Replicas)
+		if err != nil {
+			return err
+		}
+		Replicas = database.NewResolver(database.ResolverConfig{Replicas: replicas})
+		if err := DB.Use(Replicas); err != nil {
+			return err
+		}
+	}
+
+	// 自动迁移（开发环境使用，生产环境用 migrate 工具；只在主库执行，从库靠复制同步表结构）
+	// 持有迁移锁执行：多个实例同时启动时依次迁移，不会同时 ALTER 同一张表
+	Locker = app.ProvideLocker(DB)
+	err = app.Migrate(ctx, DB, Locker, &User{}, &Post{}, &Tag{}, &Comment{}, &Contact{}, &audit.Log{}, &outbox.Event{}, &outbox.Processed{},
+		&jobs.Job{}, &jobs.DeadJob{}, &webhooks.Endpoint{}, &webhooks.Delivery{}, &activity.Activity{})
 	if err != nil {
 		return err
 	}
 
-	// 连接池配置
-	sqlDB.SetMaxIdleConns(10)           // 最大空闲连接数
-	sqlDB.SetMaxOpenConns(100)          // 最大打开连接数
-	sqlDB.SetConnMaxLifetime(time.Hour) // 连接最大存活时间
+	// 审计插件：users / posts 的增删改自动写 audit_logs，和业务在同一个事务里
+	if err := DB.Use(audit.Plugin{Tables: []string{"users", "posts"}}); err != nil {
+		return err
+	}
 
-	// 自动迁移（开发环境使用，生产环境用 migrate 工具）
-	err = DB.AutoMigrate(&User{}, &Post{})
-	if err != nil {
+	// 每条 SQL 一个 span，db.WithContext(ctx) 时挂在请求 span 下面
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
 		return err
 	}
 
@@ -137,35 +275,224 @@ func InitDB() error {
 }
 
 func main() {
+	cfg, err := config.Load(config.Options{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 链路追踪：设置 OTEL_EXPORTER_OTLP_ENDPOINT 后导出到 Jaeger / Tempo，没有设置时只生成 trace ID
+	// 要在 InitDB 之前初始化，GORM 插件注册时就能拿到全局 TracerProvider
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{ServiceName: "gorm-example"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 加密列的密钥要在读写数据库之前注册
+	// 生成密钥：APP_FIELDENC_KEYS=1:$(openssl rand -base64 32) go run examples/4_1_gorm_integration.go
+	if FieldKeys, err = app.ProvideFieldKeys(cfg.FieldEnc); err != nil {
+		log.Fatal(err)
+	}
+
 	// 初始化数据库
-	if err := InitDB(); err != nil {
+	if err := InitDB(context.Background(), cfg.Database); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// 依赖注入：repository → service → handler，全部通过构造函数传入
+	// 用户和文章详情走 cache-aside 缓存，service 和 handler 不需要改动；
+	// 本示例用进程内的 MemoryClient 代替 Redis，接入 go-redis 的适配方法见 cache/redis 包注释
+	cacheClient := redis.NewMemoryClient(10000)
+	userCache := redis.New[User](cacheClient, redis.Config{Prefix: "user:v1:", TTL: 5 * time.Minute})
+	// 文章缓存带着作者信息，作者改名后要等过期才更新，所以 TTL 短一些
+	postCache := redis.New[Post](cacheClient, redis.Config{Prefix: "post:v1:", TTL: time.Minute, Codec: redis.Msgpack})
+	userRepo := repository.NewCachedUserRepository(repository.NewUserRepository(DB), userCache)
+	postRepo := repository.NewCachedPostRepository(repository.NewPostRepository(DB), postCache)
+	userHandler := NewUserHandler(service.NewUserService(userRepo, passwords))
+	postHandler := NewPostHandler(service.NewPostService(postRepo, userRepo), cfg.Server.PublicURL)
+	// 评论增删后删除文章缓存（postRepo 带缓存），GET /posts/:id 里的 comment_count 立即更新
+	commentHandler := NewCommentHandler(service.NewCommentService(repository.NewCommentRepository(DB), postRepo, userRepo))
+
+	// 用户动态的文案，%d 是合并的条数；事件订阅在下面创建事件总线时注册
+	Activities = activity.New(DB, activity.Config{Verbs: map[string]activity.Verb{
+		"post.created":    {One: "发布了文章", Many: "发布了 %d 篇文章"},
+		"profile.updated": {One: "更新了资料", Many: "更新了 %d 次资料"},
+	}})
+	userActivity := activity.Handler(Activities)
+
+	userTrash := trash.New(DB, trash.Config[User]{
+		// 注销时个人信息已被匿名化，恢复只能找回账号本身，用户名 / 邮箱需要管理员重新设置
+		BeforeRestore: func(tx *gorm.DB, u *User) error {
+			return tx.Unscoped().Model(u).Update("status", "active").Error
+		},
+		// 自定义 DSN 时 SQLite 可能没开外键检查，先查文章，给出明确的错误而不是留下孤儿数据
+		BeforePurge: func(tx *gorm.DB, u *User) error {
+			var n int64
+			if err := tx.Model(&Post{}).Where("user_id = ?", u.ID).Count(&n).Error; err != nil {
+				return err
+			}
+			if n > 0 {
+				return trash.ErrInUse
+			}
+			return nil
+		},
+	})
+
 	r := gin.Default()
 
+	// 按 Accept-Language 选择语言：协商到英文时错误提示和字段校验提示换成英文目录，
+	// 中文（源语言）和不支持的语言保持代码里的原文。内置目录只有通用错误码，
+	// 业务错误码在这里补充；实际项目放在 go:embed 的 locales/en.json 里用 bundle.Load 加载
+	bundle := i18n.NewBundle(i18n.Config{})
+	if err := bundle.AddMessages("en", map[string]any{
+		"errors": map[string]any{
+			"user_not_found":   "User not found",
+			"version_conflict": "The user was modified by someone else, fetch the latest version and retry",
+		},
+	}); err != nil {
+		log.Fatal(err)
+	}
+	r.Use(i18n.Middleware(bundle))
+
+	// handler 通过 c.Error 上报的错误在这里统一转换为 {code, message, error} 响应
+	// 未分类的错误返回 500 并写日志，不把数据库错误等内部细节返回给客户端
+	r.Use(apperr.DefaultMiddleware())
+
+	// 每个请求一个 server span，响应头 X-Trace-Id 带上 trace ID
+	r.Use(tracing.Middleware())
+
+	// 每个请求一个读写会话：同一请求里写过之后，后续查询都走主库，避免读到复制延迟前的旧数据
+	r.Use(database.StickyMiddleware())
+
+	// 操作者写入 context，审计日志据此记录 actor
+	// 本示例没有登录，用 X-User 请求头模拟；实际项目从 JWT 中取用户名
+	r.Use(audit.Middleware(func(c *gin.Context) string { return c.GetHeader("X-User") }))
+
 	// ========================================================================
 	// 用户 CRUD 接口
 	// ========================================================================
 
-	users := r.Group("/users")
+	// 创建接口支持 Idempotency-Key：客户端超时后带同一个 key 重试，只会创建一条记录
+	// 本示例没有登录，key 按客户端 IP 隔离；多实例部署时 Store 换成 idempotency.NewRedisStore
+	idem := idempotency.New(idempotency.Config{})
+
+	// GET 的 JSON 响应自动带弱 ETag，客户端带 If-None-Match 再请求时内容没变返回 304
+	// GET /users/:id 用 UpdatedAt 显式设置 ETag，命中时连序列化都省掉
+	conditional := etag.New(etag.Config{})
+
+	users := r.Group("/users", conditional)
 	{
-		users.POST("", CreateUser)      // 创建用户
-		users.GET("", ListUsers)        // 用户列表
-		users.GET("/:id", GetUser)      // 获取用户
-		users.PUT("/:id", UpdateUser)   // 更新用户
-		users.DELETE("/:id", DeleteUser) // 删除用户
+		users.POST("", idem, userHandler.Create)            // 创建用户
+		users.POST("/batch", idem, userHandler.BatchCreate) // 批量创建，逐行返回错误
+		users.POST("/import", userHandler.Import)           // CSV 导入，?async=true 交给任务队列
+		users.GET("/import/:id", userHandler.ImportStatus)  // 异步导入的结果
+		users.GET("", userHandler.List)                     // 用户列表
+		users.GET("/:id", userHandler.Get)                  // 获取用户
+		users.PUT("/:id", userHandler.Update)               // 更新用户（只改请求体里出现的字段，和 PATCH 相同）
+		users.PATCH("/:id", userHandler.Update)             // 部分更新，响应里带字段级的变化
+		users.DELETE("/:id", userHandler.Delete)            // 删除用户
+		users.GET("/:id/activity", userActivity)            // 用户动态，游标分页，同类活动合并
+	}
+
+	// 全量导出：游标逐行读取、逐条编码写出，不经过 ETag 中间件（它会缓冲响应体）
+	// email 带 view:"admin,self" 标签，只有管理员导出的结果里有；本示例没有登录，用 X-Role 请求头模拟
+	exporter := render.New(render.Options{
+		Subject:  func(c *gin.Context) policy.Subject { return policy.Subject{Role: c.GetHeader("X-Role")} },
+		Location: time.UTC,
+	})
+	// Accept: application/x-ndjson 时每行一个用户，客户端可以边收边处理，不用等整个数组结束
+	r.GET("/users/export", func(c *gin.Context) {
+		q := DB.WithContext(c.Request.Context()).Model(&model.User{}).Order("id")
+		if c.NegotiateFormat(gin.MIMEJSON, render.NDJSONType) == render.NDJSONType {
+			render.NDJSON(exporter, c, render.Rows[model.User](q))
+			return
+		}
+		render.Stream(exporter, c, render.Rows[model.User](q))
+	})
+
+	// ========================================================================
+	// 回收站（软删除的用户）
+	// ========================================================================
+	// GET /users/trash 不会和 GET /users/:id 冲突，gin 优先匹配静态路径
+	//
+	// curl http://localhost:8080/users/trash
+	// curl -X POST http://localhost:8080/users/1/restore
+	// curl -X DELETE http://localhost:8080/users/1/purge
+
+	trash.Register(users, userTrash)
+
+	// ========================================================================
+	// 联系方式（敏感字段加密存储）
+	// ========================================================================
+	// 没有配置 fieldenc.keys 时不注册。库里看到的是密文：
+	//   sqlite3 test.db "select phone from user_contacts"   # enc:v1:...
+	//
+	// curl -X PUT http://localhost:8080/users/1/contact -d '{"phone":"13800138000","id_card":"110101199003077777"}'
+	// curl http://localhost:8080/users/1/contact
+	//
+	// 轮换密钥：追加版本 2 重启，新写入用 v2；再把旧数据重新加密（或 go-one/cmd 的 rotate-field-keys 命令）
+	//   APP_FIELDENC_KEYS=1:<旧>,2:$(openssl rand -base64 32) go run examples/4_1_gorm_integration.go
+	//   curl -X POST http://localhost:8080/admin/field-keys/rotate
+
+	if FieldKeys != nil {
+		users.GET("/:id/contact", GetContact)
+		users.PUT("/:id/contact", PutContact)
+		r.POST("/admin/field-keys/rotate", RotateFieldKeys) // 生产环境要加管理员权限中间件
+	} else {
+		log.Println("fieldenc.keys not configured, /users/:id/contact disabled")
 	}
 
 	// ========================================================================
 	// 文章接口（演示关联）
 	// ========================================================================
 
-	posts := r.Group("/posts")
+	posts := r.Group("/posts", conditional)
 	{
-		posts.POST("", CreatePost)
-		posts.GET("", ListPosts)
-		posts.GET("/:id", GetPost)
+		posts.POST("", idem, postHandler.Create)
+		posts.GET("", postHandler.List)
+		posts.GET("/:id", postHandler.Get)
+		posts.POST("/:id/tags", postHandler.AttachTags)         // 加标签，不存在的自动创建
+		posts.DELETE("/:id/tags/:name", postHandler.DetachTags) // 去掉一个标签
+		posts.POST("/:id/comments", commentHandler.Create)      // 发表评论，parent_id 回复另一条评论
+		posts.GET("/:id/comments", commentHandler.Thread)       // 楼中楼，?depth= 展开的层数
+	}
+	r.GET("/tags/popular", postHandler.PopularTags) // 文章数最多的标签
+	r.DELETE("/comments/:id", commentHandler.Delete)
+
+	// ========================================================================
+	// 标签管理（crudgen 按模型的字段和标签生成 CRUD 接口）
+	// ========================================================================
+	// name 带 crud:"filter,sort,search"，id 是主键只读；post_tags 里还有引用的标签不能删除
+	//
+	// curl "http://localhost:8080/admin/tags?q=go&sort=name&page_size=20"
+	// curl -X POST http://localhost:8080/admin/tags -H "Content-Type: application/json" -d '{"name":"golang"}'
+	// curl -X PATCH http://localhost:8080/admin/tags/1 -H "Content-Type: application/json" -d '{"name":"go"}'
+	// curl -X DELETE http://localhost:8080/admin/tags/1
+
+	tags := crudgen.New(DB, crudgen.Config[Tag]{
+		DefaultSort: "name",
+		BeforeDelete: func(tx *gorm.DB, t *Tag) error {
+			var n int64
+			if err := tx.Table("post_tags").Where("tag_id = ?", t.ID).Count(&n).Error; err != nil {
+				return err
+			}
+			if n > 0 {
+				return crudgen.ErrInUse
+			}
+			return nil
+		},
+	})
+	crudgen.Register(r.Group("/admin/tags"), tags) // 生产环境要加管理员权限中间件
+
+	// ========================================================================
+	// 订阅源（Atom / RSS）
+	// ========================================================================
+
+	feeds := r.Group("/feeds")
+	{
+		feeds.GET("/posts.atom", postHandler.Feed(feed.FormatAtom))
+		feeds.GET("/posts.rss", postHandler.Feed(feed.FormatRSS))
+		feeds.GET("/tags/:tag/posts.atom", postHandler.Feed(feed.FormatAtom))
+		feeds.GET("/tags/:tag/posts.rss", postHandler.Feed(feed.FormatRSS))
 	}
 
 	// ========================================================================
@@ -180,7 +507,269 @@ func main() {
 
 	r.POST("/transaction", TransactionDemo)
 
-	r.Run(":8080")
+	// ========================================================================
+	// 审计轨迹
+	// ========================================================================
+	// 审计日志包含修改前后的数据，实际项目要加管理员权限（见 rbac）
+	//
+	// curl -X PUT -H "X-User: alice" http://localhost:8080/users/1 -d '{"age":30}'
+	// curl http://localhost:8080/audit/users/1
+	// curl "http://localhost:8080/audit/users/1?cursor=&page_size=5"
+
+	audit.Register(r.Group("/audit"), DB)
+
+	// ========================================================================
+	// 公开 API（匿名只读，与上面的内部接口完全隔离）
+	// ========================================================================
+	// 按 IP 限流（突发 + 每日额度）、响应缓存、过滤扫描器 User-Agent，不返回邮箱等个人信息
+	//
+	// curl -A demo http://localhost:8080/public/v1/posts?page=1&limit=10
+	// curl -A demo http://localhost:8080/public/v1/posts/1
+	// curl -A demo "http://localhost:8080/public/v1/search?q=gin"
+	// curl -A sqlmap http://localhost:8080/public/v1/posts   # 403
+
+	publicapi.Register(r.Group("/public/v1"), publicapi.Config{DB: DB})
+
+	srv := server.New(r, server.FromConfig(cfg.Server))
+
+	// ========================================================================
+	// 健康检查：/healthz 存活，/readyz 就绪（检查数据库、磁盘、goroutine 数量）
+	// ========================================================================
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	checks := health.New(health.Config{TTL: 5 * time.Second})
+	checks.Register("database", health.DB(sqlDB))
+	checks.Register("disk", health.DiskSpace(".", 100<<20)) // SQLite 文件所在磁盘至少留 100MB
+	checks.Register("goroutines", health.Goroutines(10000))
+	checks.Register("server", health.Ready(srv.Ready), health.NoCache()) // 关闭过程中立即返回 503
+	checks.Mount(r)
+
+	// 从库健康检查：连续 Ping 失败的从库被摘除，读请求回落到其他从库或主库
+	// curl http://localhost:8080/replicas
+	if Replicas != nil {
+		checkCtx, stopCheck := context.WithCancel(context.Background())
+		go Replicas.Run(checkCtx, 10*time.Second)
+		srv.OnShutdown("replica checker", func(context.Context) error {
+			stopCheck()
+			return nil
+		})
+		r.GET("/replicas", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"replicas": Replicas.Status()})
+		})
+	}
+
+	// 事务发件箱：Relay 轮询 outbox_events 发布到进程内总线，换成 outbox.KafkaPublisher 即可投递到 Kafka
+	// 订阅者按幂等键去重，Relay 重发同一事件时日志只打印一次
+	bus := outbox.NewMemoryBus()
+	bus.Subscribe("*", func(ctx context.Context, msg outbox.Message) error {
+		_, err := outbox.ProcessOnce(ctx, DB, "event-log", msg.Key, func(tx *gorm.DB) error {
+			log.Printf("event %s aggregate=%s payload=%s", msg.Topic, msg.AggregateID, msg.Payload)
+			return nil
+		})
+		return err
+	})
+	Events = outbox.NewRelay(DB, bus, outbox.Config{Locker: Locker})
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	go Events.Run(relayCtx)
+	srv.OnShutdown("outbox relay", func(context.Context) error {
+		stopRelay()
+		return nil
+	})
+	r.GET("/outbox/pending", func(c *gin.Context) {
+		n, err := Events.Pending(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"pending": n})
+	})
+
+	// 任务队列：任务和用户在同一个事务里入队，worker 失败重试，3 次后进死信表
+	// curl -X POST http://localhost:8080/transaction
+	// curl http://localhost:8080/admin/jobs/stats
+	// curl "http://localhost:8080/admin/jobs/dead?page=1&page_size=10"
+	// curl -X POST http://localhost:8080/admin/jobs/dead/1/requeue
+	// curl -X DELETE http://localhost:8080/admin/jobs/dead/1
+	Jobs = jobs.NewWorker(DB, jobs.Config{Concurrency: 2})
+	jobs.Handle(Jobs, SendWelcome, func(ctx context.Context, p WelcomeEmail) error {
+		if strings.HasSuffix(p.Email, "@example.com") {
+			// 模拟邮件服务拒收：重试无意义，直接进死信表
+			return jobs.Permanent(fmt.Errorf("mailbox %s does not exist", p.Email))
+		}
+		log.Printf("welcome email sent to %s (user %d)", p.Email, p.UserID)
+		return nil
+	})
+	// CSV 异步导入：worker 读临时文件、分批写入，结果放进 importReports
+	// curl -X POST "http://localhost:8080/users/import?async=true" -F file=@users.csv
+	// curl http://localhost:8080/users/import/<id>
+	importUsers := service.NewUserService(userRepo, passwords)
+	jobs.Handle(Jobs, ImportUsers, func(ctx context.Context, p ImportUsersPayload) error {
+		f, err := os.Open(p.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			return jobs.Permanent(err) // 文件已被上一次执行删除
+		}
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		report, err := importUserCSV(ctx, importUsers, f)
+		if err != nil && !errors.Is(err, csvimport.ErrMissingColumn) && !errors.Is(err, csvimport.ErrTooManyRows) {
+			return err // 数据库故障等，保留文件等待重试
+		}
+		importReports.Set(p.ID, report)
+		f.Close()
+		os.Remove(p.Path)
+		log.Printf("import %s: created=%d skipped=%d failed=%d", p.ID, report.Created, report.Skipped, report.Failed)
+		return nil
+	})
+	// 下游依赖各自的并发上限、超时和熔断
+	// curl http://localhost:8080/admin/resilience
+	guards := resilience.NewRegistry(nil)
+	// 出站 Webhook：投递任务要在 worker 启动前注册
+	// 所有投递最多 8 个并发；连接失败、超时连续 10 次（出口网络出问题，不是某个端点）时暂停全部投递
+	Hooks = webhooks.New(DB, Jobs, webhooks.Config{Guard: guards.New(resilience.Config{
+		Name: "webhooks", MaxConcurrent: 8, Breaker: httpclient.BreakerConfig{FailureThreshold: 10},
+	})})
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	jobsDone := make(chan struct{})
+	go func() {
+		Jobs.Run(jobsCtx)
+		close(jobsDone)
+	}()
+	srv.OnShutdown("job worker", func(ctx context.Context) error {
+		stopJobs()
+		select { // 等正在执行的任务结束
+		case <-jobsDone:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	jobs.Register(r.Group("/admin/jobs"), DB) // 生产环境要加管理员权限中间件
+	r.GET("/admin/resilience", resilience.Handler(guards))
+
+	// 进程内事件总线：创建用户 / 文章后扇出到审计、缓存失效、通知，互不阻塞
+	// 关闭钩子按注册的逆序执行：先排空总线（通知订阅者还要入队任务），再停 worker
+	// curl -X POST http://localhost:8080/users -d '{"username":"bob","email":"bob@mail.test","password":"12345678"}'
+	Bus = eventbus.New(eventbus.Config{})
+	eventbus.Subscribe(Bus, UserCreated, eventbus.Options{Name: "audit"}, func(ctx context.Context, e UserCreatedEvent) error {
+		log.Printf("[audit] user.created id=%d username=%s actor=%q", e.ID, e.Username, audit.ActorFromContext(ctx))
+		return nil
+	})
+	eventbus.Subscribe(Bus, PostCreated, eventbus.Options{Name: "audit"}, func(ctx context.Context, e PostCreatedEvent) error {
+		log.Printf("[audit] post.created id=%d user_id=%d actor=%q", e.ID, e.UserID, audit.ActorFromContext(ctx))
+		return nil
+	})
+	// 文章数统计缓存了 10 秒，新用户或新文章出现后立即失效
+	eventbus.Subscribe(Bus, UserCreated, eventbus.Options{Name: "cache", Policy: eventbus.PolicyDrop}, func(context.Context, UserCreatedEvent) error {
+		postCountCache.Clear()
+		return nil
+	})
+	eventbus.Subscribe(Bus, PostCreated, eventbus.Options{Name: "cache", Policy: eventbus.PolicyDrop}, func(context.Context, PostCreatedEvent) error {
+		postCountCache.Clear()
+		return nil
+	})
+	// 欢迎邮件必须送达，这里只负责入队，重试交给任务队列；入队失败（数据库抖动）由总线重试几次
+	eventbus.Subscribe(Bus, UserCreated, eventbus.Options{Name: "notify", Policy: eventbus.PolicyRetry}, func(ctx context.Context, e UserCreatedEvent) error {
+		if _, err := SendWelcome.Enqueue(ctx, DB, WelcomeEmail{UserID: e.ID, Email: e.Email}); err != nil {
+			return err
+		}
+		Jobs.Notify()
+		return nil
+	})
+	eventbus.Subscribe(Bus, PostCreated, eventbus.Options{Name: "notify"}, func(ctx context.Context, e PostCreatedEvent) error {
+		log.Printf("[notify] followers of user %d: new post %q", e.UserID, e.Title)
+		return nil
+	})
+	// 同样的事件转发为 Webhook：订阅者只写投递记录并入队，发送和重试在 worker 里
+	webhooks.Subscribe(Hooks, Bus, UserCreated)
+	webhooks.Subscribe(Hooks, Bus, PostCreated)
+	// 用户动态：发文章、改资料记一条，GET /users/:id/activity 读取时合并
+	activity.Subscribe(Activities, Bus, PostCreated, func(e PostCreatedEvent) (activity.Activity, bool) {
+		return activity.Activity{UserID: e.UserID, ObjectType: "post", ObjectID: e.ID, Data: map[string]any{"title": e.Title}}, true
+	})
+	activity.Subscribe(Activities, Bus, UserUpdated, func(e UserUpdatedEvent) (activity.Activity, bool) {
+		return activity.Activity{UserID: e.ID, Verb: "profile.updated", ObjectType: "user", ObjectID: e.ID, Data: map[string]any{"fields": e.Fields}}, true
+	})
+	srv.OnShutdown("event bus", Bus.Close)
+	webhooks.Register(r.Group("/admin/webhooks"), Hooks) // 生产环境要加管理员权限中间件
+
+	// 入站 Webhook：GitHub 仓库设置里 Payload URL 填 /webhooks/github，Content type 选 application/json
+	// 验签后按 X-GitHub-Delivery 去重，push 事件解析成 GitHubPushEvent，其他事件返回 ignored
+	githubSecret := os.Getenv("GITHUB_WEBHOOK_SECRET")
+	if githubSecret == "" {
+		githubSecret = "dev-secret"
+	}
+	github := inbound.New(inbound.Config{Provider: inbound.GitHub(githubSecret)})
+	inbound.On(github, "push", func(ctx context.Context, e inbound.Event[GitHubPushEvent]) error {
+		p := e.Payload
+		log.Printf("[github] %s pushed %d commit(s) to %s %s (delivery %s)",
+			p.Pusher.Name, len(p.Commits), p.Repository.FullName, p.Ref, e.ID)
+		return nil
+	})
+	r.POST("/webhooks/github", github.Handle)
+
+	// 演示用的接收方：?fail=1 返回 500 观察重试和熔断，?gone=1 返回 410 让端点停用
+	r.POST("/webhooks/demo-receiver", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		log.Printf("[webhook] %s %s signature=%s body=%s", c.GetHeader(webhooks.HeaderEvent),
+			c.GetHeader(webhooks.HeaderID), c.GetHeader(webhooks.HeaderSignature), body)
+		switch {
+		case c.Query("fail") != "":
+			c.Status(http.StatusInternalServerError)
+		case c.Query("gone") != "":
+			c.Status(http.StatusGone)
+		default:
+			c.Status(http.StatusNoContent)
+		}
+	})
+
+	// 实体缓存命中率：连续 GET /users/1 两次，user.hits 增加
+	r.GET("/cache/stats", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user": userCache.Stats(), "post": postCache.Stats()})
+	})
+
+	// 跨服务链路：用带 tracing.Transport 的客户端调用"另一个服务"（这里是本服务自己），
+	// 请求头带上 traceparent，三个 span（本接口 → 出站请求 → /posts）在同一条链路上
+	// 出站请求同时经过 resilience.Transport：下游变慢时最多占 10 个并发，5 秒超时，连续失败后熔断
+	// curl -i http://localhost:8080/tracing/chain
+	traced := &http.Client{Transport: resilience.Transport(
+		guards.New(resilience.Config{Name: "posts-service", Timeout: 5 * time.Second}),
+		tracing.Transport(nil),
+	)}
+	r.GET("/tracing/chain", func(c *gin.Context) {
+		req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet,
+			"http://"+c.Request.Host+"/posts?page=1&page_size=5", nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		resp, err := traced.Do(req)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		resp.Body.Close()
+		c.JSON(http.StatusOK, gin.H{
+			"trace_id":          tracing.TraceID(c.Request.Context()),
+			"downstream_status": resp.StatusCode,
+			"downstream_trace":  resp.Header.Get(tracing.TraceIDHeader), // 和 trace_id 相同
+		})
+	})
+
+	// 退出前导出缓冲中的 span
+	srv.OnShutdown("tracing", shutdownTracing)
+
+	// 所有请求处理完后再关闭数据库连接
+	srv.OnShutdown("database", func(ctx context.Context) error {
+		return sqlDB.Close()
+	})
+	if err := srv.Run(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // ============================================================================
@@ -194,175 +783,490 @@ type CreateUserRequest struct {
 	Age      int    `json:"age" binding:"gte=0,lte=150"`
 }
 
+// BatchCreateUsersRequest 批量创建用户
+// 不加 dive：逐行单独校验，一行不合法不影响其他行，错误按行返回
+type BatchCreateUsersRequest struct {
+	Users []CreateUserRequest `json:"users" binding:"required,min=1,max=100"`
+}
+
+// ImportUserRow CSV 导入的一行，表头为 username,email,password,age（age 可省略）
+type ImportUserRow struct {
+	Username string `csv:"username" binding:"required,min=3,max=50"`
+	Email    string `csv:"email" binding:"required,email"`
+	Password string `csv:"password" binding:"required,min=6"`
+	Age      int    `csv:"age" binding:"gte=0,lte=150"`
+}
+
 type UpdateUserRequest struct {
 	Username *string `json:"username" binding:"omitempty,min=3,max=50"`
 	Email    *string `json:"email" binding:"omitempty,email"`
 	Age      *int    `json:"age" binding:"omitempty,gte=0,lte=150"`
 	Status   *string `json:"status" binding:"omitempty,oneof=active inactive banned"`
+
+	// Version GET 时拿到的 version，带上后基于旧数据的修改返回 409，不会覆盖别人的修改
+	Version *uint `json:"version" binding:"omitempty,gte=1"`
+}
+
+// ContactRequest 设置联系方式，空字符串表示清空
+type ContactRequest struct {
+	Phone  string `json:"phone" binding:"omitempty,numeric,min=6,max=20"`
+	IDCard string `json:"id_card" binding:"omitempty,alphanum,max=18"`
 }
 
+// ListUsersQuery 用户列表过滤条件，分页参数（page / page_size / cursor）由 pagination.FromQuery 解析
 type ListUsersQuery struct {
-	Page     int    `form:"page,default=1"`
-	PageSize int    `form:"page_size,default=10"`
-	Status   string `form:"status"`
-	Keyword  string `form:"keyword"`
+	Status  string `form:"status"`
+	Keyword string `form:"keyword"`
 }
 
+// ============================================================================
+// 分层：Handler → Service → Repository
+// ============================================================================
+//
+// 【改造前】Handler 直接用全局 DB 写查询，业务规则（密码哈希、作者是否存在）
+//          和 HTTP 细节混在一起，测试必须起数据库
+//
+// 【改造后】
+//
+//	UserHandler  只处理 HTTP：绑定参数、把错误映射成状态码
+//	    ↓
+//	service.UserService  业务规则，依赖 repository 接口（测试时换成内存实现）
+//	    ↓
+//	repository.UserRepository  只管读写数据库，每个查询都带 context
+//
+// 依赖在 main 中通过构造函数逐层传入，没有全局变量。
+// 下面的「高级查询」「事务」两节是 GORM 语法演示，仍然直接使用 DB。
+
+// parseID 解析路径参数 :id
+func parseID(c *gin.Context) (uint, bool) {
+	var uri struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		_ = c.Error(apperr.Wrap(err, errInvalidID))
+		return 0, false
+	}
+	return uri.ID, true
+}
+
+// ============================================================================
+// 错误定义
+// ============================================================================
+// handler 用 c.Error 上报，apperr 中间件按类别写统一响应，例如：
+// 404 {"code": -1, "message": "用户不存在", "error": "user_not_found"}
+
+var (
+	errInvalidID     = apperr.Invalid("invalid_id", "ID 必须是正整数")
+	errInvalidPage   = apperr.Invalid("invalid_page", "分页参数不合法")
+	errUserNotFound  = apperr.NotFound("user_not_found", "用户不存在")
+	errUserExists    = apperr.Conflict("user_exists", "用户名或邮箱已被使用")
+	errUserModified  = apperr.Conflict("version_conflict", "用户已被他人修改，请重新获取最新数据和 version 后再提交")
+	errPostNotFound  = apperr.NotFound("post_not_found", "文章不存在")
+	errUnknownAuthor = apperr.Invalid("unknown_author", "作者不存在")
+	errInvalidParent = apperr.Invalid("invalid_parent", "回复的评论不存在或不属于这篇文章")
+	errNoComment     = apperr.NotFound("comment_not_found", "评论不存在")
+	errNoContact     = apperr.NotFound("contact_not_found", "没有设置联系方式")
+)
+
 // ============================================================================
 // 用户 CRUD Handler
 // ============================================================================
 
-// CreateUser 创建用户
-func CreateUser(c *gin.Context) {
+// UserHandler 用户接口
+type UserHandler struct {
+	users *service.UserService
+}
+
+// NewUserHandler 创建用户接口
+func NewUserHandler(users *service.UserService) *UserHandler {
+	return &UserHandler{users: users}
+}
+
+// userError 把 service 错误转换为 apperr 交给错误处理中间件，
+// 没有列出的错误（数据库故障等）按 500 处理，不会把 err.Error() 返回给客户端
+func userError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		err = apperr.Wrap(err, errUserNotFound)
+	case errors.Is(err, service.ErrUserExists):
+		err = apperr.Wrap(err, errUserExists)
+	case errors.Is(err, service.ErrVersionConflict):
+		// 不自动重试：客户端的修改基于旧数据，要由客户端重新 GET、合并后再提交
+		err = apperr.WithField(apperr.Wrap(err, errUserModified), "version", "stale")
+	}
+	_ = c.Error(err)
+}
+
+// Create 创建用户
+func (h *UserHandler) Create(c *gin.Context) {
 	var req CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = c.Error(apperr.FromBinding(err))
 		return
 	}
 
-	user := User{
-		Username: req.Username,
-		Email:    req.Email,
-		Password: req.Password, // 实际应该加密
-		Age:      req.Age,
+	// 字段同名，用 mapper 复制；密码哈希在 service 中完成，明文密码不落库
+	var in service.CreateUserInput
+	if err := mapper.Copy(&in, req); err != nil {
+		_ = c.Error(err)
+		return
 	}
-
-	// Create 创建记录
-	result := DB.Create(&user)
-	if result.Error != nil {
-		// 处理唯一键冲突
-		c.JSON(http.StatusConflict, gin.H{"error": "username or email already exists"})
+	user, err := h.users.Create(c.Request.Context(), in)
+	if err != nil {
+		userError(c, err)
 		return
 	}
 
+	// 事件只通知订阅者，发布失败不影响创建结果
+	event := UserCreatedEvent{ID: user.ID, Username: user.Username, Email: user.Email}
+	if err := eventbus.Publish(c.Request.Context(), Bus, UserCreated, event); err != nil {
+		log.Printf("publish %s: %v", UserCreated.Name(), err)
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "user created",
 		"user":    user,
 	})
 }
 
-// ListUsers 用户列表
-func ListUsers(c *gin.Context) {
-	var query ListUsersQuery
-	if err := c.ShouldBindQuery(&query); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// BatchCreate 批量创建用户，每行独立创建，部分失败时其他行照常创建
+//
+//	POST /users/batch {"users": [{...}, {...}]}
+//	→ 200 {"created": 1, "failed": 1, "users": [...],
+//	       "errors": [{"path": "users[1].email", "error": "failed on \"email\""}]}
+//
+// 行级错误（校验失败、用户名重复）放在 errors 中；数据库故障等其他错误中止整个批次，
+// 已经创建的行不回滚，返回 500（需要全部成功或全部失败时改成一个事务）
+func (h *UserHandler) BatchCreate(c *gin.Context) {
+	var req BatchCreateUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.FromBinding(err))
 		return
 	}
 
-	var users []User
-	var total int64
-
-	// 构建查询
-	db := DB.Model(&User{})
+	var rowErrs multierr.Errors
+	created := make([]*model.User, 0, len(req.Users))
+	for i := range req.Users {
+		row := &req.Users[i]
+		path := multierr.Index("users", i)
+		if err := binding.Validator.ValidateStruct(row); err != nil {
+			rowErrs.Add(path, fieldErrors(err))
+			continue
+		}
+		user, err := h.users.Create(c.Request.Context(), service.CreateUserInput{
+			Username: row.Username,
+			Email:    row.Email,
+			Password: row.Password,
+			Age:      row.Age,
+		})
+		if errors.Is(err, service.ErrUserExists) {
+			rowErrs.Add(path, err)
+			continue
+		}
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		created = append(created, user)
 
-	// 条件过滤
-	if query.Status != "" {
-		db = db.Where("status = ?", query.Status)
-	}
-	if query.Keyword != "" {
-		db = db.Where("username LIKE ? OR email LIKE ?",
-			"%"+query.Keyword+"%", "%"+query.Keyword+"%")
+		event := UserCreatedEvent{ID: user.ID, Username: user.Username, Email: user.Email}
+		if err := eventbus.Publish(c.Request.Context(), Bus, UserCreated, event); err != nil {
+			log.Printf("publish %s: %v", UserCreated.Name(), err)
+		}
 	}
 
-	// 统计总数
-	db.Count(&total)
+	c.JSON(http.StatusOK, gin.H{
+		"created": len(created),
+		"failed":  len(req.Users) - len(created),
+		"users":   created,
+		"errors":  &rowErrs,
+	})
+}
 
-	// 分页查询
-	offset := (query.Page - 1) * query.PageSize
-	db.Offset(offset).Limit(query.PageSize).Find(&users)
+// ============================================================================
+// CSV 批量导入
+// ============================================================================
+//
+// 同步模式直接返回报告；文件大时（每行都要做一次 argon2 哈希）用 ?async=true：
+//
+//	POST /users/import               -F file=@users.csv 或 Content-Type: text/csv 直接发文件内容
+//	→ 200 {"rows": 4, "created": 2, "skipped": 1, "failed": 1,
+//	       "errors": [{"path": "line[3].email", "error": "failed on \"email\""}]}
+//
+//	POST /users/import?async=true    → 202 {"id": "...", "status_url": "/users/import/..."}
+//	GET  /users/import/:id           → 202 还在处理 / 200 报告
+//
+// 每 200 行一个事务；用户名或邮箱已存在的行计为 skipped，导入中断后重新上传同一个文件是安全的
 
-	c.JSON(http.StatusOK, gin.H{
-		"data":  users,
-		"total": total,
-		"page":  query.Page,
-		"size":  query.PageSize,
+var (
+	errImportFile   = apperr.Invalid("invalid_import", "需要上传 CSV 文件（表单字段 file 或 text/csv 请求体）")
+	errImportFormat = apperr.Invalid("invalid_csv", "CSV 表头缺少必需的列")
+	errImportSize   = apperr.Invalid("import_too_large", "单次导入最多 10000 行")
+)
+
+// importUserCSV 流式解析 CSV 并分批创建用户，同步接口和异步任务共用
+func importUserCSV(ctx context.Context, users *service.UserService, r io.Reader) (*csvimport.Report, error) {
+	cfg := csvimport.Config{BatchSize: 200, Required: []string{"username", "email", "password"}}
+	return csvimport.Import(ctx, r, cfg, func(ctx context.Context, batch []csvimport.Row[ImportUserRow]) ([]error, error) {
+		in := make([]service.CreateUserInput, len(batch))
+		for i, row := range batch {
+			in[i] = service.CreateUserInput{
+				Username: row.Value.Username,
+				Email:    row.Value.Email,
+				Password: row.Value.Password,
+				Age:      row.Value.Age,
+			}
+		}
+		created, err := users.CreateBatch(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		rowErrs := make([]error, len(batch))
+		for i, user := range created {
+			if user == nil {
+				rowErrs[i] = csvimport.ErrSkip
+				continue
+			}
+			event := UserCreatedEvent{ID: user.ID, Username: user.Username, Email: user.Email}
+			if err := eventbus.Publish(ctx, Bus, UserCreated, event); err != nil {
+				log.Printf("publish %s: %v", UserCreated.Name(), err)
+			}
+		}
+		return rowErrs, nil
 	})
 }
 
-// GetUser 获取单个用户
-func GetUser(c *gin.Context) {
-	id := c.Param("id")
+// importBody 上传的文件：multipart 的 file 字段，或者 text/csv 请求体
+func importBody(c *gin.Context) (io.ReadCloser, error) {
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		return fh.Open()
+	}
+	if c.ContentType() != "text/csv" {
+		return nil, fmt.Errorf("unsupported content type %q", c.ContentType())
+	}
+	return c.Request.Body, nil
+}
 
-	var user User
-	// First 查询第一条，未找到返回 ErrRecordNotFound
-	result := DB.First(&user, id)
+// Import CSV 批量导入用户
+func (h *UserHandler) Import(c *gin.Context) {
+	body, err := importBody(c)
+	if err != nil {
+		_ = c.Error(apperr.Wrap(err, errImportFile))
+		return
+	}
+	defer body.Close()
 
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	if c.Query("async") == "true" {
+		h.importAsync(c, body)
 		return
 	}
 
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+	report, err := importUserCSV(c.Request.Context(), h.users, body)
+	switch {
+	case errors.Is(err, csvimport.ErrMissingColumn):
+		_ = c.Error(apperr.Wrap(err, errImportFormat))
+	case errors.Is(err, csvimport.ErrTooManyRows):
+		_ = c.Error(apperr.Wrap(err, errImportSize))
+	case err != nil:
+		// 已提交的批次不回滚，日志里带上已处理的数量方便排查
+		log.Printf("import aborted after %d rows (created %d): %v", report.Rows, report.Created, err)
+		_ = c.Error(err)
+	default:
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// importAsync 把上传内容存成临时文件后入队，立即返回 202
+func (h *UserHandler) importAsync(c *gin.Context, body io.Reader) {
+	f, err := os.CreateTemp("", "users-import-*.csv")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if _, err := pools.Copy(f, body); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		_ = c.Error(err)
+		return
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	id := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(f.Name()), "users-import-"), ".csv")
+	if _, err := ImportUsers.Enqueue(c.Request.Context(), DB, ImportUsersPayload{ID: id, Path: f.Name()}); err != nil {
+		os.Remove(f.Name())
+		_ = c.Error(err)
+		return
+	}
+	Jobs.Notify()
+	c.JSON(http.StatusAccepted, gin.H{"id": id, "status_url": "/users/import/" + id})
 }
 
-// UpdateUser 更新用户
-func UpdateUser(c *gin.Context) {
-	id := c.Param("id")
+// ImportStatus 异步导入结果，还没处理完时返回 202
+func (h *UserHandler) ImportStatus(c *gin.Context) {
+	report, ok := importReports.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusAccepted, gin.H{"status": "pending"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
 
-	// 先检查用户是否存在
-	var user User
-	if err := DB.First(&user, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+// fieldErrors 把校验错误转成按 json 字段名的多错误，如 email: failed on "email"
+func fieldErrors(err error) error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	var errs multierr.Errors
+	for _, fe := range verrs {
+		errs.Add(jsonName(reflect.TypeOf(CreateUserRequest{}), fe.StructField()), fmt.Errorf("failed on %q", fe.Tag()))
+	}
+	return errs.Err()
+}
+
+// jsonName 结构体字段的 json 名，没有 json 标签时用字段名
+func jsonName(t reflect.Type, field string) string {
+	f, ok := t.FieldByName(field)
+	if !ok {
+		return field
+	}
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return field
+}
+
+// List 用户列表，支持页码和游标两种分页
+//
+//	?page=2&page_size=10  → {"data": [...], "total": 35, "page": 2, "size": 10}
+//	?cursor=&page_size=10 → {"data": [...], "next_cursor": "eyJ0Ij...", "has_more": true, "size": 10}
+func (h *UserHandler) List(c *gin.Context) {
+	var query ListUsersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = c.Error(apperr.FromBinding(err))
+		return
+	}
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		_ = c.Error(apperr.Wrap(err, errInvalidPage))
+		return
+	}
+
+	in := service.ListUsersInput{
+		Page:     page.Page,
+		PageSize: page.Size,
+		Status:   query.Status,
+		Keyword:  query.Keyword,
+	}
+	if page.Mode == pagination.ModeCursor {
+		// 下一页带上 next_cursor 和同样的过滤条件
+		result, err := h.users.Scroll(c.Request.Context(), in, page.After)
+		if err != nil {
+			userError(c, err)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusOK, gin.H{
+			"data":        result.Items,
+			"next_cursor": result.NextCursor,
+			"has_more":    result.HasMore,
+			"size":        page.Size,
+		})
 		return
 	}
 
-	var req UpdateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	users, total, err := h.users.List(c.Request.Context(), in)
+	if err != nil {
+		userError(c, err)
 		return
 	}
 
-	// 构建更新 map（只更新非空字段）
-	updates := make(map[string]interface{})
-	if req.Username != nil {
-		updates["username"] = *req.Username
-	}
-	if req.Email != nil {
-		updates["email"] = *req.Email
+	c.JSON(http.StatusOK, gin.H{
+		"data":  users,
+		"total": total,
+		"page":  page.Page,
+		"size":  page.Size,
+	})
+}
+
+// Get 获取单个用户
+func (h *UserHandler) Get(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
 	}
-	if req.Age != nil {
-		updates["age"] = *req.Age
+	user, err := h.users.Get(c.Request.Context(), id)
+	if err != nil {
+		userError(c, err)
+		return
 	}
-	if req.Status != nil {
-		updates["status"] = *req.Status
+	// 任何更新都会刷新 UpdatedAt，用它做 ETag 不需要先序列化响应体
+	if etag.Check(c, etag.FromTime(user.UpdatedAt)) {
+		return
 	}
+	c.JSON(http.StatusOK, user)
+}
 
-	// Updates 更新多个字段
-	if err := DB.Model(&user).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// Update 更新用户
+func (h *UserHandler) Update(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.FromBinding(err))
 		return
 	}
 
-	// 重新查询返回最新数据
-	DB.First(&user, id)
+	// 指针字段深拷贝，没传的字段仍是 nil
+	var in service.UpdateUserInput
+	if err := mapper.Copy(&in, req); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	user, changes, err := h.users.Update(c.Request.Context(), id, in)
+	if err != nil {
+		userError(c, err)
+		return
+	}
 
+	// changes 只包含真正变化的字段：[{"field": "age", "old": 20, "new": 21}]
+	// 全都没变时为空数组，数据库没有写入，updated_at 和 ETag 都不变
+	if changes == nil {
+		changes = diff.Changes{}
+	}
+	if len(changes) > 0 {
+		event := UserUpdatedEvent{ID: user.ID, Fields: changes.Fields()}
+		if err := eventbus.Publish(c.Request.Context(), Bus, UserUpdated, event); err != nil {
+			log.Printf("publish %s: %v", UserUpdated.Name(), err)
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"message": "user updated",
 		"user":    user,
+		"changes": changes,
 	})
 }
 
-// DeleteUser 删除用户（软删除）
-func DeleteUser(c *gin.Context) {
-	id := c.Param("id")
-
-	// Delete 软删除（设置 deleted_at）
-	result := DB.Delete(&User{}, id)
-
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+// Delete 删除用户（匿名化 + 软删除）
+// 用户名改为 deleted_user_<id>、邮箱替换为摘要，文章保留并仍关联到该用户
+func (h *UserHandler) Delete(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.users.Delete(c.Request.Context(), id); err != nil {
+		userError(c, err)
 		return
 	}
-
 	c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
 }
 
@@ -371,67 +1275,395 @@ func DeleteUser(c *gin.Context) {
 // ============================================================================
 
 type CreatePostRequest struct {
-	Title   string `json:"title" binding:"required"`
-	Content string `json:"content"`
-	UserID  uint   `json:"user_id" binding:"required"`
+	Title   string   `json:"title" binding:"required"`
+	Content string   `json:"content"`
+	UserID  uint     `json:"user_id" binding:"required"`
+	Tags    []string `json:"tags" binding:"omitempty,max=10,dive,min=1,max=50"`
+}
+
+// PostHandler 文章接口
+type PostHandler struct {
+	posts     *service.PostService
+	publicURL string // 对外地址，订阅源的绝对链接用它拼接
+}
+
+// NewPostHandler 创建文章接口，publicURL 为对外的协议 + 域名（server.public_url）
+func NewPostHandler(posts *service.PostService, publicURL string) *PostHandler {
+	return &PostHandler{posts: posts, publicURL: strings.TrimSuffix(publicURL, "/")}
 }
 
-// CreatePost 创建文章
-func CreatePost(c *gin.Context) {
+// Create 创建文章，标签不存在时自动创建
+func (h *PostHandler) Create(c *gin.Context) {
 	var req CreatePostRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = c.Error(apperr.FromBinding(err))
 		return
 	}
 
-	// 检查用户是否存在
-	var user User
-	if err := DB.First(&user, req.UserID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user not found"})
-		return
-	}
-
-	post := Post{
+	post, err := h.posts.Create(c.Request.Context(), service.CreatePostInput{
 		Title:   req.Title,
 		Content: req.Content,
 		UserID:  req.UserID,
+		Tags:    req.Tags,
+	})
+	if errors.Is(err, service.ErrUserNotFound) {
+		// 作者是请求体里的字段，不存在属于参数错误而不是 404
+		err = apperr.WithField(apperr.Wrap(err, errUnknownAuthor), "UserID", "exists")
+	}
+	if err != nil {
+		_ = c.Error(err)
+		return
 	}
 
-	DB.Create(&post)
+	event := PostCreatedEvent{ID: post.ID, UserID: post.UserID, Title: post.Title}
+	if err := eventbus.Publish(c.Request.Context(), Bus, PostCreated, event); err != nil {
+		log.Printf("publish %s: %v", PostCreated.Name(), err)
+	}
 
 	c.JSON(http.StatusCreated, post)
 }
 
-// ListPosts 文章列表（带关联用户）
-func ListPosts(c *gin.Context) {
-	var posts []Post
+// List 文章列表，最新的在前（带关联用户和标签，Preload 在 repository 中完成）
+// 分页参数与用户列表相同，?tag= 只看该标签的文章
+func (h *PostHandler) List(c *gin.Context) {
+	page, err := pagination.FromQuery(c)
+	if err != nil {
+		_ = c.Error(apperr.Wrap(err, errInvalidPage))
+		return
+	}
+	tag := c.Query("tag")
 
-	// Preload 预加载关联数据
-	DB.Preload("User").Find(&posts)
+	if page.Mode == pagination.ModeCursor {
+		result, err := h.posts.Scroll(c.Request.Context(), tag, page.After, page.Size)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"data":        result.Items,
+			"next_cursor": result.NextCursor,
+			"has_more":    result.HasMore,
+			"size":        page.Size,
+		})
+		return
+	}
 
-	c.JSON(http.StatusOK, posts)
+	posts, total, err := h.posts.List(c.Request.Context(), tag, page.Page, page.Size)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":  posts,
+		"total": total,
+		"page":  page.Page,
+		"size":  page.Size,
+	})
+}
+
+// Get 获取文章详情
+func (h *PostHandler) Get(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	post, err := h.posts.Get(c.Request.Context(), id)
+	if errors.Is(err, service.ErrPostNotFound) {
+		err = apperr.Wrap(err, errPostNotFound)
+	}
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, post)
 }
 
-// GetPost 获取文章详情
-func GetPost(c *gin.Context) {
-	id := c.Param("id")
+// AttachTagsRequest 给文章加标签的请求体
+type AttachTagsRequest struct {
+	Tags []string `json:"tags" binding:"required,min=1,max=10,dive,min=1,max=50"`
+}
+
+// AttachTags 给文章加标签，已有的忽略；响应是文章现在的全部标签
+func (h *PostHandler) AttachTags(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req AttachTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.FromBinding(err))
+		return
+	}
+	tags, err := h.posts.AttachTags(c.Request.Context(), id, req.Tags)
+	h.respondTags(c, tags, err)
+}
 
-	var post Post
-	// Preload 预加载用户信息
-	result := DB.Preload("User").First(&post, id)
+// DetachTags 去掉文章的一个标签，文章没有这个标签时什么也不做
+func (h *PostHandler) DetachTags(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	tags, err := h.posts.DetachTags(c.Request.Context(), id, []string{c.Param("name")})
+	h.respondTags(c, tags, err)
+}
 
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+func (h *PostHandler) respondTags(c *gin.Context, tags []Tag, err error) {
+	if errors.Is(err, service.ErrPostNotFound) {
+		err = apperr.Wrap(err, errPostNotFound)
+	}
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
 
-	c.JSON(http.StatusOK, post)
+// PopularTagsQuery 热门标签的查询参数
+type PopularTagsQuery struct {
+	Limit int `form:"limit,default=20" binding:"min=1,max=100"`
+}
+
+// PopularTags 文章数最多的标签，每个标签带文章数
+func (h *PostHandler) PopularTags(c *gin.Context) {
+	var q PopularTagsQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		_ = c.Error(apperr.FromBinding(err))
+		return
+	}
+	tags, err := h.posts.PopularTags(c.Request.Context(), q.Limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tags})
+}
+
+// ============================================================================
+// 评论 Handler
+// ============================================================================
+
+// CreateCommentRequest 发表评论请求
+type CreateCommentRequest struct {
+	UserID   uint   `json:"user_id" binding:"required"`
+	ParentID *uint  `json:"parent_id"`
+	Body     string `json:"body" binding:"required,max=2000"`
+}
+
+// ThreadQuery 评论树的查询参数，depth 不传时默认展开 3 层
+type ThreadQuery struct {
+	Depth int `form:"depth" binding:"omitempty,min=1,max=10"`
+}
+
+// CommentHandler 评论接口
+type CommentHandler struct {
+	comments *service.CommentService
+}
+
+// NewCommentHandler 创建评论接口
+func NewCommentHandler(comments *service.CommentService) *CommentHandler {
+	return &CommentHandler{comments: comments}
+}
+
+// commentError 把 service 错误转换为 apperr
+func commentError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrPostNotFound):
+		return apperr.Wrap(err, errPostNotFound)
+	case errors.Is(err, service.ErrCommentNotFound):
+		return apperr.Wrap(err, errNoComment)
+	case errors.Is(err, service.ErrUserNotFound):
+		return apperr.WithField(apperr.Wrap(err, errUnknownAuthor), "UserID", "exists")
+	case errors.Is(err, service.ErrInvalidParent):
+		return apperr.WithField(apperr.Wrap(err, errInvalidParent), "ParentID", "exists")
+	}
+	return err
+}
+
+// Create 发表评论
+func (h *CommentHandler) Create(c *gin.Context) {
+	postID, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.FromBinding(err))
+		return
+	}
+	comment, err := h.comments.Create(c.Request.Context(), service.CreateCommentInput{
+		PostID:   postID,
+		UserID:   req.UserID,
+		ParentID: req.ParentID,
+		Body:     req.Body,
+	})
+	if err != nil {
+		_ = c.Error(commentError(err))
+		return
+	}
+	c.JSON(http.StatusCreated, comment)
+}
+
+// Thread 文章的评论树，树在 service 里用一次查询的结果组装
+func (h *CommentHandler) Thread(c *gin.Context) {
+	postID, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var q ThreadQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		_ = c.Error(apperr.FromBinding(err))
+		return
+	}
+	thread, err := h.comments.Thread(c.Request.Context(), postID, q.Depth)
+	if err != nil {
+		_ = c.Error(commentError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": thread})
+}
+
+// Delete 删除评论（软删除）
+func (h *CommentHandler) Delete(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.comments.Delete(c.Request.Context(), id); err != nil {
+		_ = c.Error(commentError(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ============================================================================
+// 订阅源 Handler
+// ============================================================================
+
+// feedSize 订阅源返回的最近文章数
+const feedSize = 20
+
+// Feed 最近文章订阅源，带 :tag 参数时只包含该标签的文章
+func (h *PostHandler) Feed(format feed.Format) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tag := c.Param("tag")
+
+		posts, err := h.posts.Recent(c.Request.Context(), tag, feedSize)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		// 不用 c.Request.Host：可以伪造，且订阅源会被缓存
+		base := h.publicURL
+		title := "最新文章"
+		if tag != "" {
+			title = fmt.Sprintf("标签 %s 的最新文章", tag)
+		}
+
+		f := &feed.Feed{
+			ID:       base + "/feeds/posts",
+			Title:    title,
+			Link:     base + "/posts",
+			SelfLink: base + c.Request.URL.Path,
+		}
+		for _, p := range posts {
+			entry := &feed.Entry{
+				ID:        fmt.Sprintf("%s/posts/%d", base, p.ID),
+				Title:     p.Title,
+				Link:      fmt.Sprintf("%s/posts/%d", base, p.ID),
+				Author:    p.User.Username,
+				Content:   p.Content, // 渲染时会清洗 HTML
+				Published: p.CreatedAt,
+				Updated:   p.UpdatedAt,
+			}
+			for _, t := range p.Tags {
+				entry.Categories = append(entry.Categories, t.Name)
+			}
+			f.Entries = append(f.Entries, entry)
+		}
+
+		feed.Serve(c, f, format)
+	}
+}
+
+// ============================================================================
+// 联系方式 Handler（加密列）
+// ============================================================================
+//
+// Handler 里读写的都是明文，加解密在 GORM 序列化器里完成。
+// 数据库备份、只读账号、慢查询日志里只有密文；密钥只在配置（环境变量）里。
+
+// GetContact 读取联系方式，序列化器自动解密
+func GetContact(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var contact Contact
+	err := DB.WithContext(c.Request.Context()).First(&contact, "user_id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		_ = c.Error(apperr.Wrap(err, errNoContact))
+		return
+	}
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, contact)
+}
+
+// PutContact 设置联系方式，写入时用当前版本的密钥加密
+func PutContact(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req ContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.FromBinding(err))
+		return
+	}
+	ctx := c.Request.Context()
+	if err := DB.WithContext(ctx).Select("id").First(&User{}, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = apperr.Wrap(err, errUserNotFound)
+		}
+		_ = c.Error(err)
+		return
+	}
+	contact := Contact{UserID: id, Phone: req.Phone, IDCard: req.IDCard}
+	if err := DB.WithContext(ctx).Save(&contact).Error; err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, contact)
+}
+
+// RotateFieldKeys 把明文和旧版本的密文用当前版本重新加密，可以重复调用
+func RotateFieldKeys(c *gin.Context) {
+	res, err := fieldenc.Rotate(c.Request.Context(), DB, FieldKeys, &Contact{}, 500)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"active": FieldKeys.Active(), "table": res.Table, "scanned": res.Scanned, "rotated": res.Rotated})
 }
 
 // ============================================================================
 // 高级查询演示
 // ============================================================================
 
+// postCountCache 缓存 Join 统计结果 10 秒：统计页被频繁刷新时，
+// 10 秒内只查一次数据库，缓存过期瞬间的并发请求也只有一个去查（GetOrLoad 去重）
+var postCountCache = cache.New[string, []UserPostCount](cache.Config{MaxEntries: 1, TTL: 10 * time.Second})
+
+// UserPostCount 每个用户的文章数
+type UserPostCount struct {
+	Username  string
+	PostCount int64
+}
+
 // AdvancedQuery 高级查询示例
 func AdvancedQuery(c *gin.Context) {
 	// 1. Select 指定字段
@@ -455,17 +1687,21 @@ func AdvancedQuery(c *gin.Context) {
 	var usersWithPosts []User
 	DB.Where("id IN (?)", subQuery).Find(&usersWithPosts)
 
-	// 5. Join 查询
-	type UserPostCount struct {
-		Username  string
-		PostCount int64
+	// 5. Join 查询（结果缓存 10 秒）
+	userPostCounts, err := postCountCache.GetOrLoad(c.Request.Context(), "all",
+		func(ctx context.Context, _ string) ([]UserPostCount, error) {
+			var rows []UserPostCount
+			err := DB.WithContext(ctx).Model(&User{}).
+				Select("users.username, count(posts.id) as post_count").
+				Joins("LEFT JOIN posts ON posts.user_id = users.id").
+				Group("users.id").
+				Scan(&rows).Error
+			return rows, err
+		})
+	if err != nil {
+		_ = c.Error(err)
+		return
 	}
-	var userPostCounts []UserPostCount
-	DB.Model(&User{}).
-		Select("users.username, count(posts.id) as post_count").
-		Joins("LEFT JOIN posts ON posts.user_id = users.id").
-		Group("users.id").
-		Scan(&userPostCounts)
 
 	c.JSON(http.StatusOK, gin.H{
 		"usernames":        usernames,
@@ -473,6 +1709,7 @@ func AdvancedQuery(c *gin.Context) {
 		"status_stats":     results,
 		"users_with_posts": usersWithPosts,
 		"user_post_counts": userPostCounts,
+		"cache_stats":      postCountCache.Stats(),
 	})
 }
 
@@ -481,29 +1718,53 @@ func AdvancedQuery(c *gin.Context) {
 // ============================================================================
 
 // TransactionDemo 事务示例
+// 领域事件通过 outbox.Add 写进同一个事务：事务回滚时事件也不存在，不会发出"幽灵事件"
 func TransactionDemo(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// 哈希计算较慢，放在事务外面，避免长时间占用连接
+	hash, err := passwords.Hash("123456")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
 	// 方式一：自动事务
-	err := DB.Transaction(func(tx *gorm.DB) error {
+	err = DB.Transaction(func(tx *gorm.DB) error {
 		// 创建用户
-		user := User{Username: "tx_user", Email: "tx@example.com", Password: "123456"}
+		user := User{Username: "tx_user", Email: "tx@example.com", Password: hash}
 		if err := tx.Create(&user).Error; err != nil {
 			return err // 返回错误会自动回滚
 		}
+		event := gin.H{"id": user.ID, "username": user.Username}
+		if _, err := outbox.Add(ctx, tx, "user.created", user.ID, event); err != nil {
+			return err
+		}
+		if _, err := SendWelcome.Enqueue(ctx, tx, WelcomeEmail{UserID: user.ID, Email: user.Email}); err != nil {
+			return err
+		}
 
 		// 创建文章
 		post := Post{Title: "Transaction Post", UserID: user.ID}
 		if err := tx.Create(&post).Error; err != nil {
 			return err
 		}
+		event = gin.H{"id": post.ID, "title": post.Title, "user_id": user.ID}
+		if _, err := outbox.Add(ctx, tx, "post.created", post.ID, event); err != nil {
+			return err
+		}
 
 		// 返回 nil 自动提交
 		return nil
 	})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
+	// 提交后唤醒 Relay；即使这里崩溃，事件也已落库，下次轮询照样发布
+	Events.Notify()
+	Jobs.Notify()
 
 	// 方式二：手动事务
 	// tx := DB.Begin()
@@ -530,33 +1791,118 @@ func TransactionDemo(c *gin.Context) {
 //   -H "Content-Type: application/json" \
 //   -d '{"username":"zhangsan","email":"zhangsan@example.com","password":"123456","age":25}'
 //
-// # 用户列表
+// # 带 Idempotency-Key 重试：第二次直接返回第一次的响应（Idempotent-Replayed: true），不会重复创建
+// curl -i -X POST http://localhost:8080/users -H "Idempotency-Key: 7c1e0a52" \
+//   -H "Content-Type: application/json" \
+//   -d '{"username":"lisi","email":"lisi@example.com","password":"123456","age":30}'
+// # 同一个 key 换了请求体：422
+//
+// # 英文错误提示：message 为 "3 fields are invalid"，data.messages 给出每个字段的提示
+// curl -X POST http://localhost:8080/users -H "Accept-Language: en-US,en;q=0.9" \
+//   -H "Content-Type: application/json" -d '{"username":"ab","email":"x"}'
+//
+// # 用户列表（页码分页）
 // curl "http://localhost:8080/users?page=1&page_size=10&keyword=zhang"
 //
-// # 获取用户
+// # 用户列表（游标分页：第一页传空 cursor，之后传上一页返回的 next_cursor）
+// curl "http://localhost:8080/users?cursor=&page_size=2"
+// curl "http://localhost:8080/users?cursor=<next_cursor>&page_size=2"
+//
+// # 流式导出全部用户（普通调用方看不到 email）
+// curl http://localhost:8080/users/export
+// curl http://localhost:8080/users/export -H "X-Role: admin"
+// curl -N http://localhost:8080/users/export -H "Accept: application/x-ndjson" | jq -c '{id, username}'
+//
+// # 获取用户（第二次起命中缓存，PUT / DELETE 后自动失效）
 // curl http://localhost:8080/users/1
+// curl http://localhost:8080/cache/stats
+//
+// # 条件 GET（ETag 来自 UpdatedAt；带上次的 ETag 返回 304，PUT 更新后又返回 200）
+// curl -i http://localhost:8080/users/1
+// curl -i http://localhost:8080/users/1 -H 'If-None-Match: W/"<etag>"'
+// # 列表没有显式 ETag，由中间件对 JSON 响应体计算
+// curl -i http://localhost:8080/users
 //
-// # 更新用户
-// curl -X PUT http://localhost:8080/users/1 \
+// # 更新用户（响应里的 changes 列出真正变化的字段；再发一次同样的请求 changes 为空，ETag 不变）
+// curl -X PATCH http://localhost:8080/users/1 \
 //   -H "Content-Type: application/json" \
 //   -d '{"age":26,"status":"active"}'
 //
+// # 用户动态（发文章、改资料后出现；连续同类活动合并成一条，如 "发布了 3 篇文章"）
+// # page_size 是合并后的条数，翻页传上一页返回的 next_cursor
+// curl "http://localhost:8080/users/1/activity?cursor=&page_size=5"
+// curl "http://localhost:8080/users/1/activity?cursor=<next_cursor>&page_size=5"
+//
+// # 乐观锁：带上 GET 拿到的 version，期间被别人改过（version 已变）返回 409：
+// # {"code":-1,"message":"用户已被他人修改，请重新获取最新数据和 version 后再提交",
+// #  "error":"version_conflict","data":{"fields":{"version":"stale"}}}
+// # 客户端重新 GET，把自己的修改合并到最新数据上，带新的 version 再 PATCH
+// curl -X PATCH http://localhost:8080/users/1 \
+//   -H "Content-Type: application/json" \
+//   -d '{"age":27,"version":1}'
+//
+// # 健康检查（/readyz 返回每项检查的状态和耗时）
+// curl http://localhost:8080/healthz
+// curl http://localhost:8080/readyz
+//
 // # 删除用户
 // curl -X DELETE http://localhost:8080/users/1
+// # 删除后文章仍在，作者显示为 deleted_user_1
+// curl http://localhost:8080/posts
 //
 // # 创建文章
 // curl -X POST http://localhost:8080/posts \
 //   -H "Content-Type: application/json" \
-//   -d '{"title":"Hello GORM","content":"GORM is great!","user_id":1}'
+//   -d '{"title":"Hello GORM","content":"GORM is great!","user_id":1,"tags":["go","gorm"]}'
 //
-// # 文章列表（带用户信息）
+// # 文章列表（带用户和标签，最新的在前，同样支持 page / cursor；标签是整页一次预加载，不是每篇一次）
 // curl http://localhost:8080/posts
+// curl "http://localhost:8080/posts?cursor=&page_size=5"
+// curl "http://localhost:8080/posts?tag=gorm"
+//
+// # 给文章加 / 去标签（响应是文章现在的全部标签）
+// curl -X POST http://localhost:8080/posts/1/tags -H "Content-Type: application/json" -d '{"tags":["sql","go"]}'
+// curl -X DELETE http://localhost:8080/posts/1/tags/sql
+//
+// # 热门标签（一条 GROUP BY 查询，带文章数）
+// curl "http://localhost:8080/tags/popular?limit=10"
 //
-// # 高级查询
+// # 评论（parent_id 回复另一条评论；GET /posts/1 里的 comment_count 由评论的钩子维护）
+// curl -X POST http://localhost:8080/posts/1/comments -H "Content-Type: application/json" -d '{"user_id":1,"body":"沙发"}'
+// curl -X POST http://localhost:8080/posts/1/comments -H "Content-Type: application/json" -d '{"user_id":1,"parent_id":1,"body":"回复"}'
+// # 楼中楼：一次查出全部评论在 Go 里组装，超过 depth 层的回复挂到上一层并带 reply_to
+// curl "http://localhost:8080/posts/1/comments?depth=2"
+// # 删除后仍有回复的评论显示为 [deleted]，没有回复的不再显示
+// curl -X DELETE http://localhost:8080/comments/1
+//
+// # 订阅源（第二次请求带上 ETag 会返回 304）
+// curl -i http://localhost:8080/feeds/posts.atom
+// curl -i http://localhost:8080/feeds/posts.rss
+// curl -i http://localhost:8080/feeds/tags/go/posts.atom
+// curl -i http://localhost:8080/feeds/posts.atom -H 'If-None-Match: "<etag>"'
+//
+// # 高级查询（连续请求两次，cache_stats.hits 增加）
 // curl http://localhost:8080/advanced/query
 //
-// # 事务
+// # 事务（同时写入 user.created / post.created 两个发件箱事件，服务日志里能看到发布）
 // curl -X POST http://localhost:8080/transaction
+// curl http://localhost:8080/outbox/pending
+//
+// # 出站 Webhook（登记端点时返回的 secret 只出现这一次，接收方用它校验 X-Webhook-Signature）
+// curl -X POST http://localhost:8080/admin/webhooks/endpoints \
+//   -H "Content-Type: application/json" \
+//   -d '{"url":"http://localhost:8080/webhooks/demo-receiver","events":["user.created","post.created"]}'
+// curl -X POST http://localhost:8080/users -d '{"username":"carol","email":"carol@mail.test","password":"12345678"}'
+// curl "http://localhost:8080/admin/webhooks/deliveries?page=1&page_size=10&endpoint_id=1"
+// curl -X POST http://localhost:8080/admin/webhooks/deliveries/1/redeliver
+// curl -X POST http://localhost:8080/admin/webhooks/endpoints/1/rotate-secret
+// curl -X POST http://localhost:8080/admin/webhooks/endpoints/1/enable      # 清除熔断状态
+//
+// # 入站 GitHub Webhook（签名用 GITHUB_WEBHOOK_SECRET，默认 dev-secret；同一个 Delivery 重发返回 duplicate）
+// body='{"ref":"refs/heads/main","repository":{"full_name":"me/app"},"pusher":{"name":"me"},"commits":[{"id":"abc","message":"fix"}]}'
+// sig=$(printf '%s' "$body" | openssl dgst -sha256 -hmac dev-secret | cut -d' ' -f2)
+// curl -X POST http://localhost:8080/webhooks/github -H "X-GitHub-Event: push" \
+//   -H "X-GitHub-Delivery: 72d3162e-cc78-11e3-81ab-4c9367dc0958" -H "X-Hub-Signature-256: sha256=$sig" -d "$body"
 //
 // ============================================================================
 
@@ -572,6 +1918,8 @@ func TransactionDemo(c *gin.Context) {
 //    默认查询会自动加 WHERE deleted_at IS NULL
 //    查询已删除记录: DB.Unscoped().Find(&users)
 //    永久删除: DB.Unscoped().Delete(&user)
+//    恢复: DB.Unscoped().Model(&user).Update("deleted_at", nil)
+//    这几种操作已封装在 trash 包，见上面的回收站接口
 //
 // 3. 【Update 零值问题】
 //    Save: 更新所有字段（包括零值）
@@ -591,6 +1939,27 @@ func TransactionDemo(c *gin.Context) {
 // 6. 【事务并发】
 //    同一事务中的操作是串行的
 //    不要在事务中做耗时操作（如调用外部 API）
+//    事务里也不要直接发消息：回滚后消息已经发出去了，用 outbox.Add 写发件箱
+//
+// 7. 【用 UpdatedAt 做 ETag】
+//    只有通过 GORM 更新才会刷新 UpdatedAt；直接写 SQL 或 UpdateColumn 不会，
+//    ETag 不变，客户端会一直拿到 304 的旧数据
+//
+// 8. 【任务至少执行一次】
+//    handler 执行时间超过 VisibilityTimeout，或 worker 崩溃，任务会被再领取一次
+//    发邮件、扣款这类任务要按业务唯一键去重，不能假设只执行一次
+//
+// 9. 【事件总线不是消息队列】
+//    eventbus 的事件在内存里，进程退出时队列中没处理的事件直接丢失
+//    必须送达的副作用（发邮件）在订阅者里入队到 jobs，或者在事务里写 outbox
+//
+// 10. 【链路断开】
+//     查询用 DB.First(...) 而不是 DB.WithContext(c.Request.Context()).First(...)，
+//     SQL 的 span 不在请求下面，而是各自成为一条新的 trace
+//
+// 11. 【Webhook 接收方要去重】
+//     投递是"至少一次"：接收方处理成功但响应超时，发送方会重试
+//     按 X-Webhook-Id 去重，并在校验签名时检查时间戳，拒绝重放的旧请求
 //
 // ============================================================================
 
@@ -598,17 +1967,17 @@ func TransactionDemo(c *gin.Context) {
 // 练习题
 // ============================================================================
 //
-// 1. 实现用户密码加密存储:
-//    - 创建时用 bcrypt 加密
-//    - 使用 GORM Hook (BeforeCreate)
+// 1. 改用 GORM Hook 处理密码:
+//    - UserService.Create 里手动调用 passwords.Hash，改为在 model.User 的 BeforeCreate 中完成
+//    - 注意区分「明文」和「已经是哈希」，避免重复哈希
 //
 // 2. 实现文章标签多对多关联:
 //    - Post has many Tags
 //    - Tag has many Posts
 //    - 中间表 post_tags
 //
-// 3. 实现用户行为日志:
-//    - 使用 GORM Hook (AfterCreate, AfterUpdate, AfterDelete)
-//    - 记录操作类型、操作时间、操作人
+// 3. 给审计接口加权限:
+//    - 参考 5_1 接入 JWT，audit.Middleware 从 token 中取用户名
+//    - /audit 用 rbac 的 RequirePermission("audit:read") 保护
 //
 // ============================================================================
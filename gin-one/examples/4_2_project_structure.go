@@ -436,6 +436,25 @@ func SetupRouter(
 //
 // ============================================================================
 
+// ============================================================================
+// 不用代码生成：app 包的 provider set
+// ============================================================================
+//
+// wire 生成的代码就是按依赖顺序调用一串构造函数。go-one/app 把这串调用手写出来：
+//
+//	a, err := app.New(ctx, cfg, app.Options{})
+//	// 内部依次调用 ProvideLogger → ProvideDB → ProvideCache
+//	//            → ProvideRepositories → ProvideServices
+//	grpcSrv := grpcapi.NewServer(a.Services.Users, ...)
+//
+// 需要后台运行的组件注册到 a.Lifecycle（OnStart 按顺序启动、OnStop 按逆序停止），
+// 关闭时交给 server：srv.OnShutdown("app", a.Stop)
+//
+// 测试时用 app.Options{DB: 内存 SQLite} 替换数据库，不需要改任何全局变量，
+// 完整用法见 examples/7_1_grpc_service.go 和 app/app_test.go。
+//
+// ============================================================================
+
 // ============================================================================
 // 易错点总结
 // ============================================================================
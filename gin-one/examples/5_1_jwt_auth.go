@@ -2,19 +2,67 @@
 // 5.1 JWT 身份认证实战
 // ============================================================================
 // 运行方式: go run examples/5_1_jwt_auth.go
-// 需要先安装: go get -u github.com/golang-jwt/jwt/v5
+// 生产模式: APP_SERVER_MODE=release APP_JWT_SECRET=<至少 32 字节> go run examples/5_1_jwt_auth.go
+// 密钥文件: APP_SECRETS_PROVIDER=file APP_SECRETS_DIR=./secrets APP_SECRETS_TTL=10s go run examples/5_1_jwt_auth.go
+// 需要先安装: go get -u github.com/golang-jwt/jwt/v5 gorm.io/gorm gorm.io/driver/sqlite
 // ============================================================================
 
 package main
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"go-one/app"
+	"go-one/auth/lockout"
+	"go-one/auth/onetime"
+	"go-one/auth/password"
+	"go-one/auth/refresh"
+	"go-one/auth/session"
+	"go-one/auth/signing"
+	"go-one/batcher"
+	"go-one/config"
+	"go-one/database"
+	"go-one/diagnostics"
+	"go-one/eventbus"
+	"go-one/featureflag"
+	"go-one/health"
+	"go-one/i18n"
+	"go-one/logging"
+	"go-one/mask"
+	"go-one/middleware/auditlog"
+	"go-one/middleware/bodylimit"
+	"go-one/middleware/cors"
+	"go-one/middleware/csrf"
+	"go-one/middleware/drain"
+	"go-one/middleware/ratelimit"
+	"go-one/middleware/realip"
+	"go-one/middleware/secure"
+	"go-one/oauth"
+	"go-one/policy"
+	"go-one/qr"
+	"go-one/rbac"
+	"go-one/resilience"
+	"go-one/secrets"
+	"go-one/serializer"
+	"go-one/server"
 )
 
 // ============================================================================
@@ -61,11 +109,17 @@ import (
 // 配置
 // ============================================================================
 
+// 启动时由 config 包加载（jwt.secret / jwt.access_ttl / jwt.refresh_ttl），
+// 生产环境通过 APP_JWT_SECRET 注入密钥，不要写进代码或配置文件
+//
+// 配置了 secrets.provider（Vault、Kubernetes Secret 卷）时 jwt.secret 从那里读取并定期刷新：
+// JWTKeys 换成新密钥签发，旧密钥在 Access Token 有效期内仍能验证，轮换不用重启。
+// Session、CSRF、OAuth state 的密钥不支持轮换，用启动时的 JWTSecret。
 var (
-	// 【重要】生产环境必须从配置/环境变量读取
-	JWTSecret          = []byte("your-super-secret-key-must-be-at-least-32-bytes")
-	AccessTokenExpire  = 2 * time.Hour  // Access Token 有效期
-	RefreshTokenExpire = 7 * 24 * time.Hour // Refresh Token 有效期
+	JWTSecret          []byte
+	JWTKeys            *secrets.Rotating
+	AccessTokenExpire  time.Duration // Access Token 有效期，默认 2h
+	RefreshTokenExpire time.Duration // Refresh Token 有效期，默认 7 天
 )
 
 // ============================================================================
@@ -84,8 +138,9 @@ type CustomClaims struct {
 // Token 生成
 // ============================================================================
 
-// GenerateToken 生成 JWT Token
-func GenerateToken(userID uint, username, role string) (accessToken, refreshToken string, err error) {
+// GenerateToken 生成 Access Token
+// Refresh Token 不是 JWT，由 refresh.Store 签发随机串并存入 refresh_tokens 表，可以随时撤销
+func GenerateToken(userID uint, username, role string) (string, error) {
 	// 创建 Access Token
 	accessClaims := CustomClaims{
 		UserID:   userID,
@@ -101,28 +156,7 @@ func GenerateToken(userID uint, username, role string) (accessToken, refreshToke
 	}
 
 	accessTokenObj := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessToken, err = accessTokenObj.SignedString(JWTSecret)
-	if err != nil {
-		return "", "", err
-	}
-
-	// 创建 Refresh Token（只包含 UserID）
-	refreshClaims := CustomClaims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTokenExpire)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   "refresh_token",
-		},
-	}
-
-	refreshTokenObj := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshToken, err = refreshTokenObj.SignedString(JWTSecret)
-	if err != nil {
-		return "", "", err
-	}
-
-	return accessToken, refreshToken, nil
+	return accessTokenObj.SignedString(JWTKeys.Current())
 }
 
 // ParseToken 解析 JWT Token
@@ -132,7 +166,12 @@ func ParseToken(tokenString string) (*CustomClaims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return JWTSecret, nil
+		// 当前密钥和轮换前的密钥依次尝试
+		var keys jwt.VerificationKeySet
+		for _, k := range JWTKeys.Verification() {
+			keys.Keys = append(keys.Keys, k)
+		}
+		return keys, nil
 	})
 
 	if err != nil {
@@ -214,6 +253,7 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 }
 
 // RoleMiddleware 角色权限中间件
+// 只比较 JWT 里的角色名，适合"只有管理员"这类固定规则；按资源和动作授权见 rbac 包
 func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role := c.GetString("role")
@@ -238,42 +278,628 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	}
 }
 
+// RequireVerified 邮箱未验证的用户不能访问敏感接口（两步验证、修改内容等），放在 JWTAuthMiddleware 之后
+// 每次请求按 user_id 查一次用户：验证状态可能在 Token 签发之后才改变，不能放进 Claims
+func RequireVerified() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := findUserByID(c.GetUint("user_id"))
+		if user == nil || user.VerifiedAt == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"error":   "email_not_verified",
+				"message": "Please verify your email address first",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
 // ============================================================================
 // 模拟用户数据
 // ============================================================================
 
 type User struct {
+	ID           uint       `json:"id"`
+	Username     string     `json:"username"`
+	Email        string     `json:"email" view:"admin,self" mask:"email"` // 只有管理员和本人看得到，见 /api/users/:id；列表里掩码
+	PasswordHash string     `json:"-"`                                    // 只存哈希，不返回
+	Role         string     `json:"role"`
+	TOTPSecret   string     `json:"-"`                             // 两步验证密钥
+	VerifiedAt   *time.Time `json:"verified_at" view:"admin,self"` // 邮箱验证时间，nil 表示未验证
+}
+
+// OwnerID 实现 serializer.Owner：用户本人即为 self
+func (u User) OwnerID() uint { return u.ID }
+
+// 密码服务：新密码用 argon2id，旧的 bcrypt 哈希登录成功后自动升级
+var passwords = password.New(password.DefaultArgon2id(), password.DefaultBcrypt())
+
+// 模拟数据库，密码哈希在 seedUsers 中生成
+// 注册接口会并发写入，读写都要经过 usersMu
+var (
+	usersMu sync.RWMutex
+	users   = map[string]*User{
+		"admin": {ID: 1, Username: "admin", Email: "admin@example.com", Role: "admin"},
+		"user":  {ID: 2, Username: "user", Email: "user@example.com", Role: "user"},
+	}
+)
+
+// seedUsers 生成演示账号的密码哈希
+// admin 故意使用 bcrypt，模拟迁移前的老数据：第一次登录后会被升级为 argon2id
+func seedUsers() error {
+	adminHash, err := password.DefaultBcrypt().Hash("admin123")
+	if err != nil {
+		return err
+	}
+	userHash, err := passwords.Hash("user123")
+	if err != nil {
+		return err
+	}
+	users["admin"].PasswordHash = adminHash
+	users["user"].PasswordHash = userHash
+	// 演示账号视为已验证邮箱，新注册的用户要点击验证链接
+	now := time.Now()
+	users["admin"].VerifiedAt = &now
+	users["user"].VerifiedAt = &now
+	return nil
+}
+
+// rbacPolicyYAML 角色权限定义（实际项目放在配置文件里，用 rbac.LoadPolicyFile 加载）
+// JWT 中的 role 是用户的基础角色，管理员还可以通过 /admin/rbac 接口额外分配角色
+const rbacPolicyYAML = `
+roles:
+  user:
+    permissions: [posts:read, posts:write, profile:read]
+  editor:
+    inherits: [user]
+    permissions: [posts:delete, users:read]
+  admin:
+    permissions: ["*"]
+`
+
+// featureFlagsYAML 功能开关的初始定义，管理员通过 /admin/flags 修改后保存在 feature_flags 表，
+// 重启时表里的同名 flag 覆盖这里的定义
+const featureFlagsYAML = `
+flags:
+  - key: new-profile-page
+    description: 新版个人资料页，先给 30% 的用户
+    type: bool
+    enabled: true
+    default: false
+    variants:
+      - {value: true, weight: 30}
+`
+
+// pagesHTML Session 登录后的设置页和公开的留言表单，演示服务端渲染表单的 CSRF 隐藏字段
+const pagesHTML = `
+{{define "profile"}}<!doctype html>
+<title>Profile</title>
+<p>Hello {{.Username}}, theme: {{.Theme}}</p>
+<form method="post" action="/session/profile">{{.CSRFField}}
+  <select name="theme"><option>light</option><option>dark</option></select>
+  <button>Save</button>
+</form>
+<form method="post" action="/session/logout">{{.CSRFField}}<button>Logout</button></form>
+{{end}}
+{{define "feedback"}}<!doctype html>
+<title>Feedback</title>
+{{if .Thanks}}<p>Thanks!</p>{{end}}
+<form method="post" action="/feedback">{{.CSRFField}}
+  <textarea name="message"></textarea>
+  <button>Send</button>
+</form>
+{{end}}
+`
+
+// Post 文章（演示资源级授权）
+type Post struct {
 	ID       uint   `json:"id"`
-	Username string `json:"username"`
-	Password string `json:"-"` // 不返回密码
-	Role     string `json:"role"`
+	Title    string `json:"title"`
+	AuthorID uint   `json:"author_id"`
+}
+
+// OwnerID 实现 policy.Owned：作者即所有者
+func (p *Post) OwnerID() uint { return p.AuthorID }
+
+var (
+	postsMu sync.RWMutex
+	posts   = map[string]*Post{
+		"1": {ID: 1, Title: "Admin announcement", AuthorID: 1},
+		"2": {ID: 2, Title: "Hello from user", AuthorID: 2},
+	}
+)
+
+// loadPost 按路径参数加载文章，供 policy.Ownership 使用
+func loadPost(c *gin.Context) (*Post, error) {
+	postsMu.RLock()
+	defer postsMu.RUnlock()
+	if p, ok := posts[c.Param("id")]; ok {
+		return p, nil
+	}
+	return nil, policy.ErrNotFound
 }
 
-// 模拟数据库
-var users = map[string]*User{
-	"admin": {ID: 1, Username: "admin", Password: "admin123", Role: "admin"},
-	"user":  {ID: 2, Username: "user", Password: "user123", Role: "user"},
+// loadUser 按路径参数加载用户，供 policy.Ownership 使用；ID 格式错误时不查询，返回 400
+func loadUser(c *gin.Context) (*User, error) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return nil, policy.ErrInvalidID
+	}
+	user := findUserByID(id)
+	if user == nil {
+		return nil, policy.ErrNotFound
+	}
+	return user, nil
 }
 
-// Token 黑名单（生产环境应该用 Redis）
+// 头像（生产环境应该放对象存储，见 2_3_file_upload.go）
+const (
+	maxAvatarSize = 2 << 20
+	// multipartOverhead 请求体里除文件内容之外的部分：boundary、每个 part 的头、其他字段
+	// 请求体上限要留出这部分，正好 2MB 的头像才不会被 413；文件本身的大小另外精确检查
+	multipartOverhead = 64 << 10
+)
+
+var (
+	avatarsMu sync.RWMutex
+	avatars   = map[uint]avatar{}
+)
+
+type avatar struct {
+	contentType string
+	data        []byte
+}
+
+// avatarTypes 允许的头像格式，按文件内容判断，不信任客户端的 Content-Type
+var avatarTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Access Token 黑名单（生产环境应该用 Redis）
+// Refresh Token 的撤销记录在数据库里，见 refresh.Store
 var tokenBlacklist = make(map[string]bool)
 
+// findUser 按用户名查找用户（实际应该从数据库查询）
+func findUser(username string) (*User, bool) {
+	usersMu.RLock()
+	defer usersMu.RUnlock()
+	u, ok := users[username]
+	return u, ok
+}
+
+// findUserByID 按 ID 查找用户
+func findUserByID(id uint) *User {
+	usersMu.RLock()
+	defer usersMu.RUnlock()
+	for _, u := range users {
+		if u.ID == id {
+			return u
+		}
+	}
+	return nil
+}
+
+// findUserByEmail 按邮箱查找用户，不区分大小写
+func findUserByEmail(email string) *User {
+	usersMu.RLock()
+	defer usersMu.RUnlock()
+	for _, u := range users {
+		if strings.EqualFold(u.Email, email) {
+			return u
+		}
+	}
+	return nil
+}
+
+// authenticate 校验用户名和密码，JWT 登录和 Session 登录共用
+// 用户不存在时也做一次哈希校验，响应时间一致，无法据此判断用户名是否存在
+func authenticate(username, plain string) (*User, bool) {
+	user, exists := findUser(username)
+	if !exists {
+		passwords.VerifyDummy(plain)
+		return nil, false
+	}
+	verified, newHash, err := passwords.Verify(user.PasswordHash, plain)
+	if err != nil {
+		log.Printf("verify password for %s: %v", user.Username, err)
+	}
+	if newHash != "" {
+		// 哈希算法或参数已更新，用这次登录的明文密码重新计算并保存
+		user.PasswordHash = newHash
+		log.Printf("password hash upgraded for %s", user.Username)
+	}
+	return user, verified
+}
+
+// demoCaptcha 演示用的验证码答案
+const demoCaptcha = "demo-captcha"
+
+// verifyCaptcha 校验登录请求里的验证码
+// 生产环境把前端拿到的 token 发给 hCaptcha / reCAPTCHA / Turnstile 的 siteverify 接口校验
+func verifyCaptcha(token string) bool {
+	return hmac.Equal([]byte(token), []byte(demoCaptcha))
+}
+
+// errUserExists 用户名或邮箱已被注册
+var errUserExists = errors.New("username or email already registered")
+
+// createUser 分配 ID 并保存，用户名或邮箱重复时返回 errUserExists
+func createUser(u *User) error {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+	var maxID uint
+	for _, existing := range users {
+		if existing.Username == u.Username || strings.EqualFold(existing.Email, u.Email) {
+			return errUserExists
+		}
+		maxID = max(maxID, existing.ID)
+	}
+	u.ID = maxID + 1
+	users[u.Username] = u
+	return nil
+}
+
+// ============================================================================
+// 邮件（示例用）
+// ============================================================================
+
+// 一次性链接的有效期：重置密码的链接能直接改密码，有效期要短
+const (
+	ResetTokenTTL  = 30 * time.Minute
+	VerifyTokenTTL = 24 * time.Hour
+)
+
+// frontendURL 邮件里的链接指向前端页面，页面再把 token POST 给接口
+const frontendURL = "http://localhost:3000"
+
+// mails 邮件模板：按请求的 Accept-Language 选择语言，本示例的文本以英文为源语言，
+// 中文没有的模板会用英文的。实际项目把模板放在 go:embed 的 locales/<语言>/*.tmpl 里用 Load 加载
+var mails = newMailBundle()
+
+func newMailBundle() *i18n.Bundle {
+	b := i18n.NewBundle(i18n.Config{Default: "en"})
+	must := func(err error) {
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	must(b.AddMessages("en", map[string]any{
+		"mail": map[string]any{
+			"hours":   map[string]any{"one": "{count} hour", "other": "{count} hours"},
+			"minutes": map[string]any{"one": "{count} minute", "other": "{count} minutes"},
+		},
+	}))
+	must(b.AddMessages("zh", map[string]any{
+		"mail": map[string]any{"hours": "{count} 小时", "minutes": "{count} 分钟"},
+	}))
+	must(b.AddTemplate("en", `
+{{define "verify_email.subject"}}Verify your email{{end}}
+{{define "verify_email.body"}}Hi {{.Name}}, open this link within {{t "mail.hours" "count" .Hours}} to verify your email: {{.Link}}{{end}}
+{{define "reset_password.subject"}}Reset your password{{end}}
+{{define "reset_password.body"}}Hi {{.Name}}, open this link within {{t "mail.minutes" "count" .Minutes}} to set a new password: {{.Link}}
+If you did not request this, ignore this email.{{end}}`))
+	must(b.AddTemplate("zh", `
+{{define "verify_email.subject"}}验证你的邮箱{{end}}
+{{define "verify_email.body"}}{{.Name}}，你好：请在 {{t "mail.hours" "count" .Hours}}内打开链接完成邮箱验证：{{.Link}}{{end}}
+{{define "reset_password.subject"}}重置密码{{end}}
+{{define "reset_password.body"}}{{.Name}}，你好：请在 {{t "mail.minutes" "count" .Minutes}}内打开链接设置新密码：{{.Link}}
+如果不是你本人操作，请忽略这封邮件。{{end}}`))
+	return b
+}
+
+// mailGuard 邮件服务的并发上限、超时和熔断，main 里创建（要用配置好的日志）
+// 邮件服务挂了时注册、找回密码照常返回，只是这封邮件没发出，用户可以重新申请
+var mailGuard *resilience.Guard
+
+// sendMail 用模板 name 的 .subject / .body 按请求的语言生成邮件
+// 示例只打印到日志，实际项目交给 jobs 队列异步发送（见 jobs 包注释）
+func sendMail(c *gin.Context, to, name string, data map[string]any) {
+	loc := mails.For(c.GetHeader("Accept-Language"))
+	subject, err := loc.Render(name+".subject", data)
+	if err != nil {
+		log.Printf("render mail %s: %v", name, err)
+		return
+	}
+	body, err := loc.Render(name+".body", data)
+	if err != nil {
+		log.Printf("render mail %s: %v", name, err)
+		return
+	}
+	err = mailGuard.Do(c.Request.Context(), func(ctx context.Context) error {
+		// 这里换成 SMTP / 邮件服务商的 API 调用，必须使用 ctx 才能被超时打断
+		log.Printf("mail to=%s lang=%s subject=%q\n%s", to, loc.Language(), subject, body)
+		return nil
+	})
+	if err != nil {
+		log.Printf("send mail %s to %s: %v", name, to, err)
+	}
+}
+
+// verifyMail 验证邮件的模板参数
+func verifyMail(user *User, raw string) map[string]any {
+	return map[string]any{
+		"Name":  user.Username,
+		"Hours": int(VerifyTokenTTL / time.Hour),
+		"Link":  frontendURL + "/verify-email?token=" + raw,
+	}
+}
+
+// ============================================================================
+// 第三方登录（示例用的用户存储适配）
+// ============================================================================
+
+// oauthUsers 让 oauth.Links.Resolve 能查找和创建本示例的用户
+type oauthUsers struct{}
+
+func (oauthUsers) FindByEmail(_ context.Context, email string) (uint, bool, error) {
+	if u := findUserByEmail(email); u != nil {
+		return u.ID, true, nil
+	}
+	return 0, false, nil
+}
+
+// Create 第三方登录创建的用户没有密码，只能用第三方登录（或者走找回密码设置一个）
+// 用户名取邮箱 @ 前面的部分，重复时加数字后缀
+func (oauthUsers) Create(_ context.Context, id oauth.Identity) (uint, error) {
+	base, _, _ := strings.Cut(id.Email, "@")
+	u := &User{Email: id.Email, Role: "user"}
+	if id.EmailVerified {
+		now := time.Now()
+		u.VerifiedAt = &now
+	}
+	for i := 0; i < 100; i++ {
+		u.Username = base
+		if i > 0 {
+			u.Username = fmt.Sprintf("%s%d", base, i)
+		}
+		err := createUser(u)
+		if err == nil {
+			return u.ID, nil
+		}
+		if findUserByEmail(id.Email) != nil {
+			return 0, err
+		}
+	}
+	return 0, errUserExists
+}
+
+// oauthStateKey 从 JWT 密钥派生签名 state Cookie 的密钥，两种签名不共用同一个密钥
+func oauthStateKey(secret []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte("oauth state cookie"))
+	return h.Sum(nil)
+}
+
+// linkError 一次性链接校验失败：过期、已用过、不存在都对应"链接无效"，只是提示不同
+func linkError(c *gin.Context, err error) {
+	message := "Invalid or expired link"
+	switch {
+	case errors.Is(err, onetime.ErrExpired):
+		message = "Link has expired, please request a new one"
+	case errors.Is(err, onetime.ErrUsed):
+		message = "Link has already been used"
+	case !errors.Is(err, onetime.ErrInvalid):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify link"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": message})
+}
+
+// userIDParam 解析路径参数 :id
+func userIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	return uint(id), err == nil
+}
+
 // ============================================================================
 // 主程序
 // ============================================================================
 
 func main() {
+	cfg, err := config.Load(config.Options{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// log.file 不为空时同时写入轮转的日志文件，级别可以通过 /debug/loglevel 临时调整
+	logs, err := app.ProvideLogger(cfg.Log)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logs.Logger)
+	JWTSecret = []byte(cfg.JWT.Secret)
+	AccessTokenExpire = cfg.JWT.AccessTTL
+	RefreshTokenExpire = cfg.JWT.RefreshTTL
+
+	// 密钥来源：secrets.provider 为 none 时 JWTKeys 固定为 jwt.secret
+	//   mkdir secrets && openssl rand -hex 32 > secrets/jwt.secret
+	//   启动后再执行一次 openssl rand -hex 32 > secrets/jwt.secret，10 秒内日志出现 "secrets: value changed"，
+	//   新登录的 Token 用新密钥签名，之前的 Token 在 jwt.access_ttl 内仍然有效
+	lc := app.NewLifecycle(logs.Logger)
+	JWTKeys = secrets.Fixed(JWTSecret)
+	secretCache, err := app.ProvideSecrets(lc, cfg.Secrets, logs.Logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if secretCache != nil {
+		if JWTKeys, err = secretCache.Watch(context.Background(), "jwt.secret", AccessTokenExpire); err != nil {
+			log.Fatal(err)
+		}
+		JWTSecret = JWTKeys.Current()
+		// 数据库密码只在打开连接池时读取一次
+		if cfg.Database.Password, err = secrets.GetOr(context.Background(), secretCache, "database.password", cfg.Database.Password); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := lc.Start(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := seedUsers(); err != nil {
+		log.Fatal(err)
+	}
+
+	// 下游依赖（邮件服务、第三方登录提供方）各自的并发上限、超时和熔断，状态见 /debug/resilience
+	guards := resilience.NewRegistry(logs.Logger)
+	mailGuard = guards.New(resilience.Config{Name: "smtp", MaxConcurrent: 4, Timeout: 10 * time.Second})
+	// 提供方卡住时不能占满回调请求：最多 20 个并发，连续失败 5 次后直接返回错误，30 秒后试探
+	oauthHTTP := &http.Client{Transport: resilience.Transport(guards.New(resilience.Config{
+		Name: "oauth", MaxConcurrent: 20, Timeout: 10 * time.Second,
+	}), nil)}
+
+	// Refresh Token 存在 refresh_tokens 表里，登出、改密码时可以撤销
+	dbCfg := database.FromConfig(cfg.Database)
+	dbCfg.GORM = &gorm.Config{Logger: logger.Default.LogMode(logger.Warn)}
+	db, err := database.Open(context.Background(), dbCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// 多实例部署时迁移和每小时的 Refresh Token 清理只由一个实例执行
+	locker := app.ProvideLocker(db)
+	if err := app.Migrate(context.Background(), db, locker, &refresh.Token{}, &rbac.UserRole{}, &onetime.Token{},
+		&oauth.Link{}, &auditlog.Entry{}, &featureflag.Flag{}, &lockout.Entry{}); err != nil {
+		log.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// 进程内事件总线：Refresh Token 被重复使用（可能被盗）等安全事件
+	bus := eventbus.New(eventbus.Config{Logger: logs.Logger})
+	eventbus.Subscribe(bus, refresh.Reused, eventbus.Options{Name: "security log"},
+		func(ctx context.Context, e refresh.ReuseEvent) error {
+			// 实际项目里还要通知用户"账号在别处使用，已强制下线"，并推送到安全告警系统
+			logs.Logger.WarnContext(ctx, "security event", "type", refresh.Reused.Name(),
+				"user_id", e.UserID, "family_id", e.FamilyID, "ip", e.IP, "device", e.Device, "revoked", e.Revoked)
+			return nil
+		})
+	tokens := refresh.New(db, refresh.Config{TTL: RefreshTokenExpire, Locker: locker, Bus: bus})
+	// 找回密码、邮箱验证的一次性链接，表里只存摘要
+	links := onetime.New(db)
+	// 第三方账号和本地用户的关联
+	identities := oauth.NewLinks(db)
+	// 登录失败计数：同一用户名 + IP 15 分钟内失败 3 次要验证码，5 次锁定 1 分钟，再次锁定时间翻倍
+	// 同一用户名在所有 IP 上 15 分钟内一共失败 10 次，从任何 IP 登录都要验证码
+	guard := lockout.New(db, lockout.Config{Locker: locker})
+
+	// 权限：角色定义来自 YAML，额外分配的角色保存在 user_roles 表
+	rbacPolicy, err := rbac.ParsePolicy([]byte(rbacPolicyYAML))
+	if err != nil {
+		log.Fatal(err)
+	}
+	perms, err := rbac.New(context.Background(), rbac.Config{Policy: rbacPolicy, Store: rbac.NewGormStore(db)})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 功能开关：定义来自 YAML，管理员修改后写 feature_flags 表并通过 SSE 推送
+	flagDefs, err := featureflag.ParseYAML([]byte(featureFlagsYAML))
+	if err != nil {
+		log.Fatal(err)
+	}
+	flags, err := featureflag.New(context.Background(), featureflag.Config{Flags: flagDefs, Store: featureflag.NewGormStore(db)})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 后台每小时清理一次过期的 Refresh Token 和登录失败记录，服务关闭时停止
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	go tokens.Sweep(sweepCtx, time.Hour)
+	go guard.Sweep(sweepCtx, time.Hour)
+
 	r := gin.Default()
+	// 本机 Nginx 加上配置里的负载均衡网段：只相信它们转发的客户端 IP 和原始协议
+	proxies := append([]string{"127.0.0.1", "::1"}, cfg.Server.TrustedProxies...)
+	// 真实客户端 IP：最先解析，登录限流、访问日志、Refresh Token 记录的 IP 都用它
+	// 只看 Nginx 用 proxy_add_x_forwarded_for 设置的 X-Forwarded-For，客户端自己带的 Forwarded 不看
+	ips, err := realip.New(realip.Config{TrustedProxies: proxies})
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.Use(ips.Middleware())
+	// 进行中请求计数：关闭时新请求返回 503 + Retry-After，已有的请求结束后才关闭数据库
+	inflight := drain.New(drain.Config{SkipPaths: []string{"/healthz", "/readyz"}})
+	r.Use(inflight.Middleware())
+	// 安全响应头：nosniff、X-Frame-Options、Referrer-Policy、CSP，HTTPS 响应带 HSTS；
+	// 生产模式 HTTP 请求跳转 HTTPS。TLS 在本机 Nginx 终止时它转发的 X-Forwarded-Proto 才可信
+	headers, err := secure.New(secure.Config{
+		RedirectHTTPS:  cfg.Server.Mode == "release",
+		TrustedProxies: proxies,
+		CSP:            secure.DefaultCSP(),
+		SkipPaths:      []string{"/healthz", "/readyz"},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.Use(headers.Middleware())
+
+	// 限流状态存储，多实例部署时换成 ratelimit.NewRedisStore
+	limitStore := ratelimit.NewMemoryStore()
 
 	// ========================================================================
 	// 公开接口
 	// ========================================================================
 
+	// 登录：按 IP 限流，每分钟最多 5 次（滑动窗口，防暴力破解）
+	loginLimit := ratelimit.New(ratelimit.Config{
+		Algorithm: ratelimit.SlidingWindow(5, time.Minute),
+		Store:     limitStore,
+		KeyFunc:   ratelimit.ByIP,
+		Prefix:    "login:",
+	})
+
+	// login 校验密码并记录失败次数，JWT 登录和 Session 登录共用；失败时已经写好响应
+	//   锁定中：429 + Retry-After，不校验密码（猜对了也不放行）
+	//   需要验证码但没带或不对：428 captcha_required，不计入失败次数
+	//   密码错误：401，响应里的 captcha_required 告诉客户端下次要显示验证码
+	login := func(c *gin.Context, username, plain, captcha string) (*User, bool) {
+		ctx, ip := c.Request.Context(), realip.FromContext(c)
+		st, err := guard.Check(ctx, username, ip)
+		if errors.Is(err, lockout.ErrLocked) {
+			lockout.Reject(c, st)
+			return nil, false
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+			return nil, false
+		}
+		if st.CaptchaRequired && !verifyCaptcha(captcha) {
+			lockout.RequireCaptcha(c)
+			return nil, false
+		}
+
+		user, ok := authenticate(username, plain)
+		if !ok {
+			st, err := guard.Fail(ctx, username, ip)
+			if errors.Is(err, lockout.ErrLocked) {
+				lockout.Reject(c, st)
+				return nil, false
+			}
+			if err != nil {
+				log.Printf("record failed login for %s: %v", username, err)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":             401,
+				"message":          "Invalid username or password",
+				"captcha_required": st.CaptchaRequired,
+			})
+			return nil, false
+		}
+		if err := guard.Succeed(ctx, username, ip); err != nil {
+			log.Printf("clear failed logins for %s: %v", username, err)
+		}
+		return user, true
+	}
+
 	// 登录
-	r.POST("/login", func(c *gin.Context) {
+	r.POST("/login", loginLimit, func(c *gin.Context) {
 		var req struct {
 			Username string `json:"username" binding:"required"`
 			Password string `json:"password" binding:"required"`
+			Captcha  string `json:"captcha"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -282,17 +908,18 @@ func main() {
 		}
 
 		// 验证用户
-		user, exists := users[req.Username]
-		if !exists || user.Password != req.Password {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "Invalid username or password",
-			})
+		user, ok := login(c, req.Username, req.Password, req.Captcha)
+		if !ok {
 			return
 		}
 
-		// 生成 Token
-		accessToken, refreshToken, err := GenerateToken(user.ID, user.Username, user.Role)
+		// 生成 Token，Refresh Token 记录登录设备
+		accessToken, err := GenerateToken(user.ID, user.Username, user.Role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		refreshToken, _, err := tokens.Issue(c.Request.Context(), user.ID, c.Request.UserAgent(), realip.FromContext(c))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 			return
@@ -310,6 +937,9 @@ func main() {
 		})
 	})
 
+	// 二维码生成（短链接等公开内容），缓存最近 256 张图片；otpauth URI 会被拒绝，见 /api/2fa/setup
+	r.GET("/qr", qr.Handler(qr.NewCache(256)))
+
 	// 刷新 Token
 	r.POST("/refresh", func(c *gin.Context) {
 		var req struct {
@@ -321,91 +951,458 @@ func main() {
 			return
 		}
 
-		// 检查是否在黑名单
-		if tokenBlacklist[req.RefreshToken] {
+		// 旧 Token 作废并签发新 Token（轮换），已撤销、已过期的 Token 不能再用
+		// 已经轮换过的 Token 又被提交：可能被盗，这次登录的所有 Token 全部作废，
+		// 客户端看到 refresh_token_reused 时清掉本地 Token，跳转登录页
+		refreshToken, record, err := tokens.Rotate(c.Request.Context(), req.RefreshToken, c.Request.UserAgent(), realip.FromContext(c))
+		if err != nil {
+			errCode, message := "invalid_refresh_token", "Invalid refresh token"
+			switch {
+			case errors.Is(err, refresh.ErrReused):
+				errCode, message = "refresh_token_reused", "Refresh token reuse detected, please log in again"
+			case errors.Is(err, refresh.ErrRevoked):
+				errCode, message = "refresh_token_revoked", "Token has been revoked"
+			case errors.Is(err, refresh.ErrExpired):
+				errCode, message = "refresh_token_expired", "Token has expired"
+			case !errors.Is(err, refresh.ErrInvalid):
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+				return
+			}
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
-				"message": "Token has been revoked",
+				"message": message,
+				"error":   errCode,
 			})
 			return
 		}
 
-		// 解析 Refresh Token
-		claims, err := ParseToken(req.RefreshToken)
-		if err != nil {
+		user := findUserByID(record.UserID)
+		if user == nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
-				"message": "Invalid refresh token",
+				"message": "User not found",
 			})
 			return
 		}
 
-		// 检查 Token 类型
-		if claims.Subject != "refresh_token" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "Invalid token type",
-			})
+		accessToken, err := GenerateToken(user.ID, user.Username, user.Role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 			return
 		}
 
-		// 获取用户信息（实际应该从数据库查询）
-		var user *User
-		for _, u := range users {
-			if u.ID == claims.UserID {
-				user = u
-				break
-			}
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "Token refreshed",
+			"data": gin.H{
+				"access_token":  accessToken,
+				"refresh_token": refreshToken,
+				"token_type":    "Bearer",
+				"expires_in":    AccessTokenExpire.Seconds(),
+			},
+		})
+	})
+
+	// ========================================================================
+	// 注册、邮箱验证、找回密码
+	// ========================================================================
+	//
+	// 这几个接口会发邮件，按 IP 限流，防止被用来给别人的邮箱刷邮件
+
+	authGroup := r.Group("/auth", ratelimit.New(ratelimit.Config{
+		Algorithm: ratelimit.SlidingWindow(5, time.Minute),
+		Store:     limitStore,
+		KeyFunc:   ratelimit.ByIP,
+		Prefix:    "auth-mail:",
+	}))
+
+	// 注册：账号立即可以登录，但敏感接口（RequireVerified）要等邮箱验证之后
+	authGroup.POST("/signup", func(c *gin.Context) {
+		var req struct {
+			Username string `json:"username" binding:"required,alphanum,min=3,max=32"`
+			Email    string `json:"email" binding:"required,email,max=100"`
+			Password string `json:"password" binding:"required,min=8,max=72"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		hash, err := passwords.Hash(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+			return
+		}
+		user := &User{Username: req.Username, Email: req.Email, PasswordHash: hash, Role: "user"}
+		if err := createUser(user); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"code": 409, "message": err.Error()})
+			return
+		}
+		raw, _, err := links.Issue(c.Request.Context(), user.ID, onetime.EmailVerify, VerifyTokenTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue verification token"})
+			return
 		}
+		sendMail(c, user.Email, "verify_email", verifyMail(user, raw))
+		c.JSON(http.StatusCreated, gin.H{"code": 0, "message": "Verification email sent", "data": user})
+	})
 
+	// 验证邮箱：用 POST 而不是让邮件里的链接直接 GET 接口，
+	// 邮件客户端和安全网关会预先访问链接，GET 接口会在用户点击之前就把 Token 用掉
+	authGroup.POST("/verify-email", func(c *gin.Context) {
+		var req struct {
+			Token string `json:"token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tok, err := links.Consume(c.Request.Context(), req.Token, onetime.EmailVerify)
+		if err != nil {
+			linkError(c, err)
+			return
+		}
+		user := findUserByID(tok.UserID)
 		if user == nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "User not found",
-			})
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid or expired link"})
 			return
 		}
+		now := time.Now()
+		usersMu.Lock()
+		if user.VerifiedAt == nil {
+			user.VerifiedAt = &now
+		}
+		usersMu.Unlock()
+		c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Email verified"})
+	})
+
+	// 忘记密码：不管邮箱是否注册都返回同样的响应，不能用来探测哪些邮箱注册过
+	authGroup.POST("/forgot-password", func(c *gin.Context) {
+		var req struct {
+			Email string `json:"email" binding:"required,email"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if user := findUserByEmail(req.Email); user != nil {
+			// 新链接签发后，之前发出的重置链接全部作废
+			raw, _, err := links.Issue(c.Request.Context(), user.ID, onetime.PasswordReset, ResetTokenTTL)
+			if err != nil {
+				log.Printf("issue reset token for user %d: %v", user.ID, err)
+			} else {
+				sendMail(c, user.Email, "reset_password", map[string]any{
+					"Name":    user.Username,
+					"Minutes": int(ResetTokenTTL / time.Minute),
+					"Link":    frontendURL + "/reset-password?token=" + raw,
+				})
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "If the email is registered, a reset link has been sent",
+		})
+	})
 
-		// 生成新的 Token
-		accessToken, refreshToken, err := GenerateToken(user.ID, user.Username, user.Role)
+	// 重置密码：链接只能用一次；改密码后所有设备的 Refresh Token 作废，强制重新登录
+	authGroup.POST("/reset-password", func(c *gin.Context) {
+		var req struct {
+			Token       string `json:"token" binding:"required"`
+			NewPassword string `json:"new_password" binding:"required,min=8,max=72"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tok, err := links.Consume(c.Request.Context(), req.Token, onetime.PasswordReset)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			linkError(c, err)
+			return
+		}
+		user := findUserByID(tok.UserID)
+		if user == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid or expired link"})
+			return
+		}
+		hash, err := passwords.Hash(req.NewPassword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+			return
+		}
+		usersMu.Lock()
+		user.PasswordHash = hash
+		// 能收到重置邮件说明邮箱是本人的，顺便视为已验证
+		if user.VerifiedAt == nil {
+			now := time.Now()
+			user.VerifiedAt = &now
+		}
+		usersMu.Unlock()
+		if _, err := tokens.RevokeAll(c.Request.Context(), user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
 			return
 		}
+		c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Password has been reset, please log in again"})
+	})
+
+	// ========================================================================
+	// 第三方登录（Google / GitHub）
+	// ========================================================================
+	//
+	// 配置了 client_id 的提供方才启用，如：
+	//   APP_OAUTH_GITHUB_CLIENT_ID=... APP_OAUTH_GITHUB_CLIENT_SECRET=... go run examples/5_1_jwt_auth.go
+	// 提供方控制台登记的回调地址：http://localhost:8080/auth/oauth/github/callback
+
+	var providers []oauth.Provider
+	callback := func(name string) string { return cfg.OAuth.RedirectBase + "/auth/oauth/" + name + "/callback" }
+	if c := cfg.OAuth.Google; c.ClientID != "" {
+		providers = append(providers, oauth.Google(oauth.Client{
+			ClientID: c.ClientID, ClientSecret: c.ClientSecret, RedirectURL: callback("google"), HTTPClient: oauthHTTP,
+		}))
+	}
+	if c := cfg.OAuth.GitHub; c.ClientID != "" {
+		providers = append(providers, oauth.GitHub(oauth.Client{
+			ClientID: c.ClientID, ClientSecret: c.ClientSecret, RedirectURL: callback("github"), HTTPClient: oauthHTTP,
+		}))
+	}
+
+	social, err := oauth.New(oauth.Config{
+		Providers: providers,
+		Secret:    oauthStateKey(JWTSecret),
+		Secure:    cfg.Server.Mode == "release",
+		// 回调通过校验后：创建或关联本地用户，然后和密码登录一样签发本站的 Token
+		OnLogin: func(c *gin.Context, id oauth.Identity) {
+			userID, created, err := identities.Resolve(c.Request.Context(), id, oauthUsers{})
+			switch {
+			case errors.Is(err, oauth.ErrEmailNotVerified):
+				c.JSON(http.StatusConflict, gin.H{
+					"code":    409,
+					"message": "An account with this email already exists, log in with password to link it",
+				})
+				return
+			case errors.Is(err, oauth.ErrNoEmail):
+				c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "The provider did not return an email address"})
+				return
+			case err != nil:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+				return
+			}
+			user := findUserByID(userID)
+			if user == nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+				return
+			}
+			accessToken, err := GenerateToken(user.ID, user.Username, user.Role)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+				return
+			}
+			refreshToken, _, err := tokens.Issue(c.Request.Context(), user.ID, c.Request.UserAgent(), realip.FromContext(c))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+				return
+			}
+			// 实际项目一般重定向回前端页面，Token 放在 HttpOnly Cookie 里，而不是直接返回 JSON
+			c.JSON(http.StatusOK, gin.H{
+				"code":    0,
+				"message": "Login successful",
+				"data": gin.H{
+					"access_token":  accessToken,
+					"refresh_token": refreshToken,
+					"token_type":    "Bearer",
+					"expires_in":    AccessTokenExpire.Seconds(),
+					"provider":      id.Provider,
+					"new_user":      created,
+				},
+			})
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	oauthGroup := r.Group("/auth/oauth")
+	// 登录页据此显示哪些第三方登录按钮
+	oauthGroup.GET("/providers", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"code": 0, "data": social.Providers()})
+	})
+	oauth.Register(oauthGroup, social)
+
+	// ========================================================================
+	// Session 登录：同样的账号，换成服务端会话 + Cookie，和上面的 JWT 流程对比
+	// ========================================================================
+
+	// 会话保存在进程内存里，重启后要重新登录；多实例部署时换成
+	// session.NewRedisStore(app.ProvideCache(), "")，所有实例共用同一份会话
+	sessions, err := session.New(session.Config{
+		Store:  session.NewMemoryStore(10000),
+		Secret: JWTSecret,
+		Secure: cfg.Server.Mode == "release",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// 同步令牌：Token 保存在会话里，每个表单拿到的是加了随机掩码的不同字符串
+	sessionCSRF, err := csrf.New(csrf.Config{SessionToken: session.CSRFToken})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// 登录请求还没有会话，同步令牌模式放行，由密码校验和限流保护
+	sessionGroup := r.Group("/session", sessions.Middleware(), sessionCSRF.Middleware())
+	r.SetHTMLTemplate(template.Must(template.New("pages").Parse(pagesHTML)))
 
-		// 将旧的 Refresh Token 加入黑名单
-		tokenBlacklist[req.RefreshToken] = true
+	// 和 /login 同一个限流器和失败计数：两个入口加起来每分钟 5 次，不能换个接口继续猜密码
+	sessionGroup.POST("/login", loginLimit, func(c *gin.Context) {
+		var req struct {
+			Username string `json:"username" binding:"required"`
+			Password string `json:"password" binding:"required"`
+			Captcha  string `json:"captcha"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		user, ok := login(c, req.Username, req.Password, req.Captcha)
+		if !ok {
+			return
+		}
 
+		// 会话 ID 在 Set-Cookie 里（HttpOnly，JS 读不到），响应体只有 CSRF Token
+		s, err := sessions.Login(c, user.ID, map[string]string{"role": user.Role})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{
 			"code":    0,
-			"message": "Token refreshed",
+			"message": "Login successful",
+			"data":    gin.H{"csrf_token": s.CSRFToken()},
+		})
+	})
+
+	// RequireLogin 设置的 user_id / role 和 JWT 中间件相同，RoleMiddleware 等可以直接复用
+	sessionAuth := sessionGroup.Group("", sessions.RequireLogin())
+	sessionAuth.GET("/me", func(c *gin.Context) {
+		s := session.FromContext(c)
+		user := findUserByID(s.UserID())
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "User not found"})
+			return
+		}
+		// 页面刷新后从这里重新拿 CSRF Token
+		c.JSON(http.StatusOK, gin.H{
+			"code": 0,
 			"data": gin.H{
-				"access_token":  accessToken,
-				"refresh_token": refreshToken,
-				"token_type":    "Bearer",
-				"expires_in":    AccessTokenExpire.Seconds(),
+				"user_id":    user.ID,
+				"username":   user.Username,
+				"role":       user.Role,
+				"login_at":   s.CreatedAt(),
+				"csrf_token": csrf.Token(c),
 			},
 		})
 	})
+	// 登出立即生效：会话删除后同一个 Cookie 再来就是 401，不需要黑名单
+	sessionAuth.POST("/logout", func(c *gin.Context) {
+		if err := sessions.Logout(c); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Logout successful"})
+	})
+	sessionAuth.GET("/admin", RoleMiddleware("admin"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Hello admin (session)"})
+	})
+	// 服务端渲染的设置页：表单里的隐藏字段由 csrf.TemplateField 生成
+	sessionAuth.GET("/profile", func(c *gin.Context) {
+		s := session.FromContext(c)
+		c.HTML(http.StatusOK, "profile", gin.H{
+			"Username":  findUserByID(s.UserID()).Username,
+			"Theme":     s.Get("theme"),
+			"CSRFField": csrf.TemplateField(c),
+		})
+	})
+	sessionAuth.POST("/profile", func(c *gin.Context) {
+		session.FromContext(c).Set("theme", c.PostForm("theme"))
+		c.Redirect(http.StatusSeeOther, "/session/profile")
+	})
+
+	// 不需要登录的留言表单：没有会话，用双重提交 Cookie；release 模式下 Secure，Cookie 带 __Host- 前缀
+	feedbackCSRF, err := csrf.New(csrf.Config{Secret: JWTSecret, Secure: cfg.Server.Mode == "release"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	feedback := r.Group("/feedback", feedbackCSRF.Middleware())
+	feedback.GET("", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "feedback", gin.H{"Thanks": c.Query("sent") != "", "CSRFField": csrf.TemplateField(c)})
+	})
+	feedback.POST("", func(c *gin.Context) {
+		log.Printf("feedback received: %d bytes", len(c.PostForm("message")))
+		c.Redirect(http.StatusSeeOther, "/feedback?sent=1")
+	})
+
+	// ========================================================================
+	// 内部服务接口：HMAC 请求签名，不用 JWT
+	// ========================================================================
+
+	// 报表、通知等内部服务用共享密钥签名后调用，配置 APP_SIGNING_SECRET 后启用。
+	// 调用方（Go）：httpclient.New(httpclient.Config{Transport: signing.Transport(signing.NewSigner(keyID, secret), nil)})
+	if cfg.Signing.Secret != "" {
+		verifier, err := signing.New(signing.Config{
+			Keys:      map[string][]byte{cfg.Signing.KeyID: []byte(cfg.Signing.Secret)},
+			Tolerance: cfg.Signing.Tolerance,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		internal := r.Group("/internal", verifier.Middleware())
+		internal.GET("/users/:id", func(c *gin.Context) {
+			id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+			user := findUserByID(uint(id))
+			if user == nil {
+				c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "User not found"})
+				return
+			}
+			log.Printf("internal call from %s: user %d", signing.CallerFromContext(c), user.ID)
+			c.JSON(http.StatusOK, gin.H{"code": 0, "data": gin.H{"id": user.ID, "username": user.Username, "email": user.Email, "role": user.Role}})
+		})
+	}
 
 	// ========================================================================
 	// 需要认证的接口
 	// ========================================================================
 
 	authorized := r.Group("/api")
+	// 公开 API：允许前端开发环境和所有子域名，前端需要读取限流头
+	// 必须在 JWT 中间件之前注册，否则预检请求（不带 Token）会被 401
+	cors.Register(authorized, cors.Policy{
+		AllowOrigins:  []string{"http://localhost:3000", "https://*.example.com"},
+		ExposeHeaders: []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After"},
+	})
 	authorized.Use(JWTAuthMiddleware())
+	// 按用户限流：每秒 10 个请求，允许突发 20 个（令牌桶）
+	authorized.Use(ratelimit.New(ratelimit.Config{
+		Algorithm: ratelimit.TokenBucket(10, 20),
+		Store:     limitStore,
+		KeyFunc:   ratelimit.ByUser,
+		Prefix:    "api:",
+	}))
+	// 按当前用户 ID 求值，要在 JWT 中间件之后
+	authorized.Use(flags.Middleware())
 	{
 		// 获取当前用户信息
 		authorized.GET("/me", func(c *gin.Context) {
 			claims, _ := c.Get("claims")
 			customClaims := claims.(*CustomClaims)
 
+			verified := false
+			if user := findUserByID(customClaims.UserID); user != nil {
+				verified = user.VerifiedAt != nil
+			}
+
 			c.JSON(http.StatusOK, gin.H{
 				"code": 0,
 				"data": gin.H{
-					"user_id":  customClaims.UserID,
-					"username": customClaims.Username,
-					"role":     customClaims.Role,
+					"user_id":        customClaims.UserID,
+					"username":       customClaims.Username,
+					"role":           customClaims.Role,
+					"email_verified": verified,
 				},
 			})
 		})
@@ -419,20 +1416,227 @@ func main() {
 				tokenBlacklist[parts[1]] = true
 			}
 
+			// 同时撤销本设备的 Refresh Token（可选）
+			var req struct {
+				RefreshToken string `json:"refresh_token"`
+			}
+			if c.ShouldBindJSON(&req) == nil && req.RefreshToken != "" {
+				if err := tokens.Revoke(c.Request.Context(), req.RefreshToken); err != nil && !errors.Is(err, refresh.ErrInvalid) {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+					return
+				}
+			}
+
 			c.JSON(http.StatusOK, gin.H{
 				"code":    0,
 				"message": "Logout successful",
 			})
 		})
 
+		// 重新发送验证邮件，之前的验证链接作废
+		authorized.POST("/resend-verification", func(c *gin.Context) {
+			user := findUserByID(c.GetUint("user_id"))
+			if user == nil {
+				c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "User not found"})
+				return
+			}
+			if user.VerifiedAt != nil {
+				c.JSON(http.StatusConflict, gin.H{"code": 409, "message": "Email already verified"})
+				return
+			}
+			raw, _, err := links.Issue(c.Request.Context(), user.ID, onetime.EmailVerify, VerifyTokenTTL)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue verification token"})
+				return
+			}
+			sendMail(c, user.Email, "verify_email", verifyMail(user, raw))
+			c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Verification email sent"})
+		})
+
+		// 关联的第三方账号
+		authorized.GET("/oauth/links", func(c *gin.Context) {
+			list, err := identities.List(c.Request.Context(), c.GetUint("user_id"))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list linked accounts"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"code": 0, "data": list})
+		})
+
+		// 已登录的设备
+		authorized.GET("/sessions", func(c *gin.Context) {
+			list, err := tokens.Active(c.Request.Context(), c.GetUint("user_id"))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"code": 0, "data": list})
+		})
+
+		// 所有设备下线（怀疑账号被盗时使用），已签发的 Access Token 在过期前仍然有效
+		authorized.DELETE("/sessions", func(c *gin.Context) {
+			n, err := tokens.RevokeAll(c.Request.Context(), c.GetUint("user_id"))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"code": 0, "data": gin.H{"revoked": n}})
+		})
+
+		// 两步验证预配：生成密钥，返回 otpauth URI 和二维码图片
+		// 二维码以 data: URI 随响应返回，不给 /qr 地址：密钥放进 URL 会进日志、浏览器历史和缓存
+		authorized.POST("/2fa/setup", RequireVerified(), func(c *gin.Context) {
+			user, exists := findUser(c.GetString("username"))
+			if !exists {
+				c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "User not found"})
+				return
+			}
+
+			secret, err := qr.NewTOTPSecret()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+				return
+			}
+			uri := qr.ProvisioningURI("gin-app", user.Username, secret)
+			image, err := qr.DataURI(uri, qr.Options{Size: 256, Level: "M"})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+				return
+			}
+			usersMu.Lock()
+			user.TOTPSecret = secret
+			usersMu.Unlock()
+
+			c.Header("Cache-Control", "no-store")
+			c.JSON(http.StatusOK, gin.H{
+				"code": 0,
+				"data": gin.H{
+					"secret":      secret, // 无法扫码时手动输入
+					"otpauth_uri": uri,
+					"qr_image":    image, // <img src="...">
+				},
+			})
+		})
+
 		// 普通用户和管理员都可以访问
+		// 前端启动时拉取自己看到的 flag 值，再订阅 /api/flags/stream，收到 flag.updated 后重新拉取
+		featureflag.Register(authorized.Group("/flags"), flags)
+
 		authorized.GET("/profile", func(c *gin.Context) {
+			layout := "v1"
+			if featureflag.FromContext(c).Bool("new-profile-page", false) {
+				layout = "v2"
+			}
 			c.JSON(http.StatusOK, gin.H{
 				"code":    0,
 				"message": "Profile data",
 				"user_id": c.GetUint("user_id"),
+				"layout":  layout,
 			})
 		})
+
+		// 用户资料：同一个 User 按调用方输出不同字段
+		// 其他人只看到 id / username / role，本人和管理员还能看到 email / verified_at
+		authorized.GET("/users/:id", func(c *gin.Context) {
+			id, ok := userIDParam(c)
+			user := findUserByID(id)
+			if !ok || user == nil {
+				c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "User not found"})
+				return
+			}
+			usersMu.RLock()
+			u := *user
+			usersMu.RUnlock()
+			serializer.Success(c, u)
+		})
+
+		// 编辑、删除文章：只有作者本人或管理员可以操作
+		// 文章不存在 404；非作者返回 403 {"error":"forbidden","reason":"not_owner"}
+		authorized.PUT("/posts/:id", RequireVerified(), policy.Ownership(loadPost), func(c *gin.Context) {
+			var req struct {
+				Title string `json:"title" binding:"required"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+				return
+			}
+			post := policy.Resource[*Post](c) // 中间件已加载，不用再查一次
+			postsMu.Lock()
+			post.Title = req.Title
+			out := *post
+			postsMu.Unlock()
+			c.JSON(http.StatusOK, gin.H{"code": 0, "data": out})
+		})
+
+		authorized.DELETE("/posts/:id", RequireVerified(), policy.Ownership(loadPost), func(c *gin.Context) {
+			post := policy.Resource[*Post](c)
+			// 管理员删除别人的文章留一条日志，作者删自己的不用
+			if access, _ := policy.AccessFrom(c); !access.Owner {
+				logs.WarnContext(c.Request.Context(), "post deleted by admin",
+					"post_id", post.ID, "author_id", post.AuthorID, "admin_id", c.GetUint("user_id"))
+			}
+			postsMu.Lock()
+			delete(posts, c.Param("id"))
+			postsMu.Unlock()
+			c.Status(http.StatusNoContent)
+		})
+
+		// 上传头像：只有本人或管理员可以修改，最大 2MB
+		// 顺序：先限制请求体，再检查所有权，最后才解析 multipart
+		authorized.PUT("/users/:id/avatar", bodylimit.New(maxAvatarSize+multipartOverhead), policy.Ownership(loadUser), func(c *gin.Context) {
+			file, err := c.FormFile("avatar")
+			if err != nil {
+				if bodylimit.IsTooLarge(err) {
+					c.JSON(http.StatusRequestEntityTooLarge, gin.H{"code": 413, "message": "Avatar must be at most 2MB"})
+					return
+				}
+				c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Missing avatar file"})
+				return
+			}
+			if file.Size > maxAvatarSize {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"code": 413, "message": "Avatar must be at most 2MB"})
+				return
+			}
+			f, err := file.Open()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read avatar"})
+				return
+			}
+			defer f.Close()
+			data, err := io.ReadAll(f)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read avatar"})
+				return
+			}
+			contentType := http.DetectContentType(data)
+			if !avatarTypes[contentType] {
+				c.JSON(http.StatusUnsupportedMediaType, gin.H{"code": 415, "message": "Avatar must be a PNG, JPEG, GIF or WebP image"})
+				return
+			}
+
+			user := policy.Resource[*User](c)
+			avatarsMu.Lock()
+			avatars[user.ID] = avatar{contentType: contentType, data: data}
+			avatarsMu.Unlock()
+			c.JSON(http.StatusOK, gin.H{"code": 0, "data": gin.H{
+				"user_id":      user.ID,
+				"content_type": contentType,
+				"size":         len(data),
+			}})
+		})
+
+		// 头像是公开资料，登录用户都可以看
+		authorized.GET("/users/:id/avatar", func(c *gin.Context) {
+			id, _ := userIDParam(c)
+			avatarsMu.RLock()
+			a, ok := avatars[id]
+			avatarsMu.RUnlock()
+			if !ok {
+				c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "Avatar not found"})
+				return
+			}
+			c.Data(http.StatusOK, a.contentType, a.data)
+		})
 	}
 
 	// ========================================================================
@@ -440,30 +1644,111 @@ func main() {
 	// ========================================================================
 
 	admin := r.Group("/admin")
+	// 管理后台：只允许后台域名，并允许携带 Cookie
+	cors.Register(admin, cors.Policy{
+		AllowOrigins:     []string{"https://admin.example.com"},
+		AllowMethods:     []string{"GET", "PUT", "DELETE"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
 	admin.Use(JWTAuthMiddleware())
-	admin.Use(RoleMiddleware("admin"))
+	// 管理操作写入 request_audit_logs：请求体和响应体脱敏后保存，GET 默认不记录
+	// 攒批写入，请求不等数据库；最多晚 1 秒落库，退出时由 OnShutdown 写出剩下的
+	auditSink := auditlog.NewBatchSink(db, batcher.Config{Size: 100, Interval: time.Second})
+	admin.Use(auditlog.New(auditlog.Config{
+		Sink:            auditSink,
+		CaptureRequest:  true,
+		CaptureResponse: true,
+		RedactPaths:     []string{"data[*].email"}, // 响应里的用户邮箱属于个人信息
+		Actor:           func(c *gin.Context) string { return c.GetString("username") },
+	}))
 	{
-		admin.GET("/users", func(c *gin.Context) {
+		// 每个接口声明需要的权限，哪些角色拥有这些权限由 rbacPolicyYAML 决定
+		admin.GET("/users", perms.RequirePermission("users:read"), func(c *gin.Context) {
 			var userList []User
+			usersMu.RLock()
 			for _, u := range users {
 				userList = append(userList, *u)
 			}
+			usersMu.RUnlock()
+			// 列表页截图、导出最容易外泄，邮箱掩码为 a***@example.com；完整邮箱到 /api/users/:id 查看
+			if err := mask.Struct(&userList); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to mask users"})
+				return
+			}
 			c.JSON(http.StatusOK, gin.H{
 				"code": 0,
 				"data": userList,
 			})
 		})
 
-		admin.DELETE("/users/:id", func(c *gin.Context) {
+		// 撤销指定用户的所有 Refresh Token，强制其重新登录
+		admin.DELETE("/users/:id/tokens", perms.RequirePermission("users:manage"), func(c *gin.Context) {
+			id, ok := userIDParam(c)
+			if !ok || findUserByID(id) == nil {
+				c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "User not found"})
+				return
+			}
+			n, err := tokens.RevokeAll(c.Request.Context(), id)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"code": 0, "data": gin.H{"revoked": n}})
+		})
+
+		admin.DELETE("/users/:id", perms.RequirePermission("users:delete"), func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{
 				"code":    0,
 				"message": "User deleted (simulated)",
 			})
 		})
+
+		// 功能开关的定义：灰度比例、总开关
+		featureflag.RegisterAdmin(admin.Group("/flags", RoleMiddleware("admin")), flags)
+
+		// 角色分配：固定只允许 admin，避免拥有 roles:manage 的人给自己授予更高的角色
+		rbac.RegisterAdmin(admin.Group("/rbac", RoleMiddleware("admin")), perms)
+
+		// 登录锁定：查看失败记录，用户确认是本人后解锁
+		lockout.RegisterAdmin(admin.Group("/lockouts", perms.RequirePermission("users:manage")), guard)
+
+		// 最近的管理操作，供合规检查
+		admin.GET("/audit-logs", RoleMiddleware("admin"), func(c *gin.Context) {
+			var entries []auditlog.Entry
+			q := db.WithContext(c.Request.Context()).Order("id DESC").Limit(50)
+			if actor := c.Query("actor"); actor != "" {
+				q = q.Where("actor = ?", actor)
+			}
+			if err := q.Find(&entries).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to load audit logs"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"code": 0, "data": entries})
+		})
+		// 批量写入的统计：dropped / failed 不为 0 说明数据库跟不上，有审计记录丢失
+		admin.GET("/audit-logs/stats", RoleMiddleware("admin"), func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"code": 0, "data": auditSink.Stats()})
+		})
 	}
 
+	// ========================================================================
+	// 运行时诊断：pprof、goroutine 快照、内存 / GC 统计，只允许管理员
+	// ========================================================================
+	// 调用栈和 heap profile 会暴露内部实现，CPU profile 采样期间拖慢整个进程，
+	// 所以和管理接口一样先认证再检查角色，不要挂在公开路由上
+
+	debugGroup := r.Group("/debug", JWTAuthMiddleware(), RoleMiddleware("admin"))
+	diagnostics.Register(debugGroup)
+	// 线上排查时临时打开 debug，到期自动恢复；修改记录带上操作的管理员
+	logging.RegisterLevel(debugGroup, logs.Level, logs.Logger)
+	// 各下游依赖的熔断状态、正在进行的调用数、失败 / 超时 / 拒绝次数
+	debugGroup.GET("/resilience", resilience.Handler(guards))
+	// 各路由进行中的请求数，关闭卡住时看是哪些请求没结束
+	debugGroup.GET("/inflight", inflight.Handler())
+
 	// 打印测试说明
-	println("Server starting on :8080")
+	println("Server starting on " + cfg.Server.Addr)
 	println("")
 	println("Test accounts:")
 	println("  admin / admin123 (role: admin)")
@@ -477,10 +1762,48 @@ func main() {
 	println("# Access protected resource")
 	println(`curl http://localhost:8080/api/me -H "Authorization: Bearer <access_token>"`)
 	println("")
+	println("# Session login (cookie instead of token)")
+	println(`curl -c cookies.txt -X POST http://localhost:8080/session/login -H "Content-Type: application/json" -d '{"username":"admin","password":"admin123"}'`)
+	println(`curl -b cookies.txt http://localhost:8080/session/me`)
+	println("")
 	println("# Admin only")
 	println(`curl http://localhost:8080/admin/users -H "Authorization: Bearer <access_token>"`)
-
-	r.Run(":8080")
+	println("")
+	println("# Runtime diagnostics (admin only)")
+	println(`curl http://localhost:8080/debug/runtime -H "Authorization: Bearer <access_token>"`)
+	println(`curl http://localhost:8080/debug/resilience -H "Authorization: Bearer <access_token>"`)
+	println(`curl -X POST http://localhost:8080/debug/loglevel -H "Authorization: Bearer <access_token>" -H "Content-Type: application/json" -d '{"level":"debug","duration":"10m"}'`)
+
+	srv := server.New(r, server.FromConfig(cfg.Server))
+
+	// 关闭顺序与注册相反：先停清理任务、写出排队的审计记录，再关数据库，最后关日志文件
+	srv.OnShutdown("log", func(context.Context) error { return logs.Close() })
+	srv.OnShutdown("secrets", lc.Stop)
+	srv.OnShutdown("database", func(context.Context) error { return sqlDB.Close() })
+	srv.OnShutdown("audit log", auditSink.Close)
+	// 等订阅者处理完已经发布的安全事件
+	srv.OnShutdown("event bus", bus.Close)
+	// flag 推送是 SSE 长连接，关闭开始时断开，否则要等到关闭超时
+	srv.OnDrain(flags.Broker().Close)
+	srv.OnDrain(inflight.Drain)
+	srv.OnShutdown("token sweeper", func(context.Context) error {
+		stopSweep()
+		return nil
+	})
+	// 最后注册、最先执行：排空超时后 server 强制断开连接，handler 可能还在用数据库
+	srv.OnShutdown("in-flight requests", inflight.Wait)
+
+	// 健康检查
+	checks := health.New(health.Config{})
+	checks.Register("database", health.DB(sqlDB))
+	checks.Register("goroutines", health.Goroutines(10000))
+	checks.Register("server", health.Ready(srv.Ready), health.NoCache())
+	checks.Mount(r)
+
+	// 收到 Ctrl+C / SIGTERM 后等待进行中的请求完成再退出
+	if err := srv.Run(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // ============================================================================
@@ -501,18 +1824,208 @@ func main() {
 //   -H "Content-Type: application/json" \
 //   -d '{"refresh_token":"<refresh_token>"}'
 //
-// # 登出
-// curl -X POST http://localhost:8080/api/logout \
+// # 再提交一次刷新前的旧 Token（模拟被盗）：401 refresh_token_reused，
+// # 刚换到的新 Token 也一起作废，日志里出现 "security event"
+// curl -X POST http://localhost:8080/refresh \
+//   -H "Content-Type: application/json" \
+//   -d '{"refresh_token":"<旧的 refresh_token>"}'
+//
+// # 两步验证预配（返回 qr_image，data: URI 可以直接放进 <img src>）
+// curl -X POST http://localhost:8080/api/2fa/setup \
 //   -H "Authorization: Bearer <access_token>"
 //
+// # 二维码（PNG / SVG）
+// curl -o qr.png "http://localhost:8080/qr?data=https://example.com/s/abc&size=300&level=Q"
+// curl "http://localhost:8080/qr?data=hello&format=svg"
+// curl -i "http://localhost:8080/qr?data=otpauth://totp/x?secret=ABC"  # 400，密钥不能放进 URL
+//
+// # 登出（带上 refresh_token 时一并撤销）
+// curl -X POST http://localhost:8080/api/logout \
+//   -H "Authorization: Bearer <access_token>" \
+//   -H "Content-Type: application/json" -d '{"refresh_token":"<refresh_token>"}'
+//
+// # 查看已登录设备 / 所有设备下线
+// curl http://localhost:8080/api/sessions -H "Authorization: Bearer <access_token>"
+// curl -X DELETE http://localhost:8080/api/sessions -H "Authorization: Bearer <access_token>"
+//
+// # 注册：日志里打印验证链接 mail to=... link=http://localhost:3000/verify-email?token=...
+// curl -X POST http://localhost:8080/auth/signup \
+//   -H "Content-Type: application/json" \
+//   -d '{"username":"carol","email":"carol@example.com","password":"carol1234"}'
+//
+// # 未验证邮箱：可以登录，但两步验证、编辑文章返回 403 email_not_verified
+// curl -X POST http://localhost:8080/api/2fa/setup -H "Authorization: Bearer <carol_access_token>"
+//
+// # 用日志里的 token 验证邮箱（第二次提交返回 "Link has already been used"）
+// curl -X POST http://localhost:8080/auth/verify-email \
+//   -H "Content-Type: application/json" -d '{"token":"<token>"}'
+//
+// # 重新发送验证邮件（旧链接作废）
+// curl -X POST http://localhost:8080/api/resend-verification -H "Authorization: Bearer <carol_access_token>"
+//
+// # 忘记密码：注册过和没注册过的邮箱返回相同的响应，只有前者会在日志里打印链接
+// curl -X POST http://localhost:8080/auth/forgot-password \
+//   -H "Content-Type: application/json" -d '{"email":"user@example.com"}'
+// curl -X POST http://localhost:8080/auth/forgot-password \
+//   -H "Content-Type: application/json" -d '{"email":"nobody@example.com"}'
+// # 邮件按 Accept-Language 选择语言，日志里是中文的主题和正文（"请在 30 分钟内……"）
+// curl -X POST http://localhost:8080/auth/forgot-password -H "Accept-Language: zh-CN,zh;q=0.9" \
+//   -H "Content-Type: application/json" -d '{"email":"user@example.com"}'
+//
+// # 重置密码：成功后该用户所有 Refresh Token 失效，要用新密码重新登录
+// curl -X POST http://localhost:8080/auth/reset-password \
+//   -H "Content-Type: application/json" -d '{"token":"<token>","new_password":"newpass123"}'
+//
+// # 第三方登录：先在 GitHub 创建 OAuth App，回调地址填 http://localhost:8080/auth/oauth/github/callback
+// APP_OAUTH_GITHUB_CLIENT_ID=<id> APP_OAUTH_GITHUB_CLIENT_SECRET=<secret> go run examples/5_1_jwt_auth.go
+// curl http://localhost:8080/auth/oauth/providers            # ["github"]
+// curl -i http://localhost:8080/auth/oauth/github/login      # 302 到 GitHub，带 state 和 code_challenge
+// # 浏览器打开 http://localhost:8080/auth/oauth/github/login，授权后回调返回本站的 access_token
+// # GitHub 主邮箱和 user@example.com 相同且已验证时，登录的是已有的 user 账号
+// curl http://localhost:8080/api/oauth/links -H "Authorization: Bearer <access_token>"
+//
+// # 登录失败：第 3 次起响应带 "captcha_required": true，之后要带验证码（演示值 demo-captcha），否则 428
+// curl -X POST http://localhost:8080/login -H "Content-Type: application/json" -d '{"username":"user","password":"wrong"}'
+// curl -X POST http://localhost:8080/login -H "Content-Type: application/json" \
+//   -d '{"username":"user","password":"wrong","captcha":"demo-captcha"}'
+// # 15 分钟内第 5 次失败后锁定 1 分钟：429 account_locked + Retry-After，密码对了也不放行；再次锁定 2 分钟、4 分钟……
+// # （/login 同时按 IP 限流每分钟 5 次，连续试时等一分钟再发，否则先收到 rate_limit_exceeded）
+// # 管理员查看和解锁（只解锁一个 IP 时加 ?ip=127.0.0.1）
+// curl http://localhost:8080/admin/lockouts -H "Authorization: Bearer <admin_access_token>"
+// curl -X DELETE http://localhost:8080/admin/lockouts/user -H "Authorization: Bearer <admin_access_token>"
+//
+// # Session 登录：和 JWT 对比，客户端只保存 Cookie，不用自己加 Authorization 头
+// curl -i -c cookies.txt -X POST http://localhost:8080/session/login \
+//   -H "Content-Type: application/json" -d '{"username":"admin","password":"admin123"}'
+// # Set-Cookie: sid=<加密的会话 ID>; Max-Age=43200; HttpOnly; SameSite=Lax，响应体里是 csrf_token
+// curl -b cookies.txt http://localhost:8080/session/me
+// curl -b cookies.txt http://localhost:8080/session/admin
+// # 修改数据的请求要带 CSRF Token，没有时 403 csrf_failed
+// curl -i -b cookies.txt -X POST http://localhost:8080/session/logout
+// curl -b cookies.txt -X POST http://localhost:8080/session/logout -H "X-CSRF-Token: <csrf_token>"
+// # 登出后同一个 Cookie 立即失效（JWT 的 Access Token 要等到过期）
+// curl -i -b cookies.txt http://localhost:8080/session/me
+//
+// # 服务端渲染的表单：浏览器登录后打开 http://localhost:8080/session/profile，
+// # 每次刷新隐藏字段 csrf_token 的值都不同（掩码），都能通过校验；不带时 403 csrf_failed
+// curl -b cookies.txt http://localhost:8080/session/profile
+// curl -i -b cookies.txt -X POST http://localhost:8080/session/profile -d "theme=dark&csrf_token=<页面里的值>"
+//
+// # 安全响应头：X-Content-Type-Options、X-Frame-Options、Referrer-Policy、Content-Security-Policy
+// curl -s -D - -o /dev/null http://localhost:8080/feedback
+// # 生产模式 HTTP 跳转 HTTPS（301，POST 为 308）；本机 Nginx 转发的 X-Forwarded-Proto: https 不跳转、带 HSTS
+// APP_SERVER_MODE=release APP_JWT_SECRET=<至少 32 字节> go run examples/5_1_jwt_auth.go
+// curl -s -D - -o /dev/null http://localhost:8080/feedback
+// curl -s -D - -o /dev/null http://localhost:8080/feedback -H "X-Forwarded-Proto: https"
+//
+// # 登录限流按真实客户端 IP：本机代理转发的 X-Forwarded-For 从右往左找第一个不可信的地址，
+// # 客户端在左边伪造的 6.6.6.6 不影响限流 key（第 6 次起 429）
+// for i in 1 2 3 4 5 6; do curl -s -o /dev/null -w "%{http_code} " -X POST http://localhost:8080/login \
+//   -H "X-Forwarded-For: 6.6.6.$i, 9.9.9.9" -H "Content-Type: application/json" -d '{"username":"x","password":"y"}'; done
+//
+// # 内部服务接口：HMAC 签名代替 JWT，签名覆盖方法、路径和查询串、时间戳、请求体摘要
+// APP_SIGNING_SECRET=0123456789abcdef0123456789abcdef go run examples/5_1_jwt_auth.go
+// ts=$(date +%s); body_hash=$(printf "" | sha256sum | cut -d" " -f1)
+// sig=$(printf "GET\n/internal/users/1\n%s\n%s" "$ts" "$body_hash" | openssl dgst -sha256 -hmac 0123456789abcdef0123456789abcdef | sed "s/.* //")
+// curl -i http://localhost:8080/internal/users/1 -H "X-Signature-Key: internal" -H "X-Signature-Timestamp: $ts" -H "X-Signature: $sig"
+// curl -i http://localhost:8080/internal/users/2 -H "X-Signature-Key: internal" -H "X-Signature-Timestamp: $ts" -H "X-Signature: $sig"   # 换了路径：401
+//
+// # 应用自己终止 TLS：证书文件（或 APP_SERVER_TLS_AUTOCERT_DOMAINS=app.example.com 自动申请），
+// # HTTPS 上自动协商 HTTP/2，明文端口只做跳转
+// openssl req -x509 -newkey ec -pkeyopt ec_paramgen_curve:P-256 -nodes -days 7 -subj /CN=localhost \
+//   -addext subjectAltName=DNS:localhost -keyout key.pem -out cert.pem
+// APP_SERVER_ADDR=:8443 APP_SERVER_TLS_CERT_FILE=cert.pem APP_SERVER_TLS_KEY_FILE=key.pem \
+//   APP_SERVER_TLS_HTTP_ADDR=:8080 go run examples/5_1_jwt_auth.go
+// curl -sk -o /dev/null -w "%{http_version}\n" https://localhost:8443/healthz   # 2
+// curl -s -D - -o /dev/null http://localhost:8080/feedback                     # 301 → https://localhost:8443/feedback
+// # TLS 在 Envoy 终止、代理到应用也用 HTTP/2（h2c）
+// APP_SERVER_HTTP2=h2c go run examples/5_1_jwt_auth.go
+// curl -s --http2-prior-knowledge -o /dev/null -w "%{http_version}\n" http://localhost:8080/healthz   # 2
+//
+// # 不登录的留言表单用双重提交 Cookie：GET 时下发 csrf_token Cookie（release 模式下是 __Host-csrf_token），POST 时表单值要和 Cookie 对上
+// curl -c form.txt http://localhost:8080/feedback
+// curl -i -b form.txt -X POST http://localhost:8080/feedback -d "message=hi&csrf_token=<页面里的值>"
+// curl -i -X POST http://localhost:8080/feedback -d "message=hi"   # 403
+//
+// # 伪造回调（没有 state Cookie）：400 invalid_state
+// curl -i "http://localhost:8080/auth/oauth/github/callback?code=x&state=y"
+//
+// # 用户资料：查看自己（id=2）带 email，查看别人（id=1）没有；admin 查看任何人都带 email
+// curl http://localhost:8080/api/users/2 -H "Authorization: Bearer <user_access_token>"
+// curl http://localhost:8080/api/users/1 -H "Authorization: Bearer <user_access_token>"
+//
+// # 管理员强制某个用户重新登录
+// curl -X DELETE http://localhost:8080/admin/users/2/tokens \
+//   -H "Authorization: Bearer <admin_access_token>"
+//
 // # 管理员接口（需要 admin 角色）
 // curl http://localhost:8080/admin/users \
 //   -H "Authorization: Bearer <admin_access_token>"
 //
-// # 用普通用户访问管理员接口（会返回 403）
+// # 管理操作审计：上面的撤销、角色分配都会记录，请求体里的 password 等字段已脱敏
+// curl "http://localhost:8080/admin/audit-logs?actor=admin" \
+//   -H "Authorization: Bearer <admin_access_token>"
+//
+// # 审计记录批量写入的统计（入队、写出、丢弃数）
+// curl http://localhost:8080/admin/audit-logs/stats \
+//   -H "Authorization: Bearer <admin_access_token>"
+//
+// # 登录限流（第 6 次返回 429 + Retry-After）
+// for i in {1..6}; do curl -i -X POST http://localhost:8080/login \
+//   -H "Content-Type: application/json" -d '{"username":"admin","password":"x"}'; done
+//
+// # 用普通用户访问管理员接口（会返回 403，reason=permission_required）
 // curl http://localhost:8080/admin/users \
 //   -H "Authorization: Bearer <user_access_token>"
 //
+// # 给 user（id=2）分配 editor 角色后，同一个 Token 立即可以访问 /admin/users
+// curl -X PUT http://localhost:8080/admin/rbac/users/2/roles/editor \
+//   -H "Authorization: Bearer <admin_access_token>"
+// curl http://localhost:8080/admin/rbac/roles -H "Authorization: Bearer <admin_access_token>"
+//
+// # 编辑文章：user 只能改自己的文章（id=2），改 id=1 返回 403 + reason
+// curl -X PUT http://localhost:8080/api/posts/1 \
+//   -H "Authorization: Bearer <user_access_token>" \
+//   -H "Content-Type: application/json" -d '{"title":"hacked"}'
+// curl -X DELETE http://localhost:8080/api/posts/2 -H "Authorization: Bearer <user_access_token>"
+// curl -X DELETE http://localhost:8080/api/posts/9 -H "Authorization: Bearer <user_access_token>"  # 404
+//
+// # 上传头像：user 只能改自己的（id=2），改 id=1 返回 403；admin 可以改任何人的
+// curl -X PUT http://localhost:8080/api/users/2/avatar \
+//   -H "Authorization: Bearer <user_access_token>" -F "avatar=@/path/to/avatar.png"
+// curl http://localhost:8080/api/users/2/avatar -H "Authorization: Bearer <user_access_token>" -o avatar.png
+//
+// # CORS 预检：/api 允许子域名（204），/admin 拒绝非后台域名（403）
+// curl -i -X OPTIONS http://localhost:8080/api/me \
+//   -H "Origin: https://app.example.com" -H "Access-Control-Request-Method: GET"
+// curl -i -X OPTIONS http://localhost:8080/admin/users/1 \
+//   -H "Origin: https://app.example.com" -H "Access-Control-Request-Method: DELETE"
+//
+// # 功能开关：当前用户看到的值，和实时推送（另开一个终端保持连接）
+// curl http://localhost:8080/api/flags -H "Authorization: Bearer <user_access_token>"
+// curl -N http://localhost:8080/api/flags/stream -H "Authorization: Bearer <user_access_token>"
+//
+// # 管理员把新版资料页灰度扩大到 100%，stream 终端立即收到 flag.updated
+// curl -X PUT http://localhost:8080/admin/flags/new-profile-page \
+//   -H "Authorization: Bearer <admin_access_token>" -H "Content-Type: application/json" \
+//   -d '{"type":"bool","enabled":true,"default":false,"variants":[{"value":true,"weight":100}]}'
+// curl http://localhost:8080/admin/flags -H "Authorization: Bearer <admin_access_token>"
+//
+// # 运行时诊断（需要 admin 角色，user Token 返回 403）
+// curl http://localhost:8080/debug/runtime -H "Authorization: Bearer <admin_access_token>"
+// curl "http://localhost:8080/debug/goroutines?debug=1" -H "Authorization: Bearer <admin_access_token>"
+// curl -X PUT http://localhost:8080/debug/profiling -H "Authorization: Bearer <admin_access_token>" \
+//   -H "Content-Type: application/json" -d '{"mutex_fraction":5,"block_rate":10000}'
+// curl -o cpu.out "http://localhost:8080/debug/pprof/profile?seconds=10" -H "Authorization: Bearer <admin_access_token>"
+// go tool pprof -http :9090 cpu.out
+//
+// # 临时调整日志级别（10 分钟后恢复；不带 duration 时一直保持）
+// curl -X POST http://localhost:8080/debug/loglevel -H "Authorization: Bearer <admin_access_token>" \
+//   -H "Content-Type: application/json" -d '{"level":"debug","duration":"10m"}'
+// curl http://localhost:8080/debug/loglevel -H "Authorization: Bearer <admin_access_token>"
+// # 同时写入轮转的日志文件
+// APP_LOG_FILE=logs/app.log APP_LOG_MAX_SIZE_MB=50 APP_LOG_MAX_BACKUPS=7 APP_LOG_COMPRESS=true go run examples/5_1_jwt_auth.go
+//
 // ============================================================================
 
 // ============================================================================
@@ -546,6 +2059,45 @@ func main() {
 //    JWT 的 Payload 只是 Base64 编码，不是加密
 //    不要存敏感信息（如密码）
 //
+// 7. 【pprof 和超时、认证】
+//    CPU profile 默认采样 30 秒，等于 server 的 WriteTimeout（30s），pprof 直接返回错误，
+//    要带 ?seconds=10 这样小于 WriteTimeout 的值
+//    go tool pprof 不能带 Authorization 头，先用 curl 下载 profile 文件再分析
+//
+// 8. 【重置链接可以反复使用】
+//    Token 只校验签名和有效期（比如直接用 JWT），链接泄露后在有效期内谁都能改密码
+//    用 onetime 包：数据库里只存摘要，Consume 条件更新 used_at，只能成功一次；
+//    重新申请时旧链接作废；重置成功后 RevokeAll 让已登录的设备全部下线
+//
+// 9. 【忘记密码接口泄露注册信息】
+//    "该邮箱未注册" 和 "邮件已发送" 两种响应，可以用来批量探测哪些邮箱注册过
+//    无论是否注册都返回同样的提示，并按 IP 限流
+//
+// 10. 【GET 验证链接被提前消费】
+//    邮件安全网关会预先访问邮件里的链接，GET 接口在用户点击之前 Token 就被用掉了
+//    链接指向前端页面，页面再 POST /auth/verify-email
+//
+// 11. 【第三方登录按邮箱自动关联】
+//    提供方没有验证过的邮箱谁都能填，按邮箱直接关联等于让别人登录你的账号
+//    只有 email_verified 为 true 时才关联已有用户，之后按 (provider, subject) 识别，不再看邮箱
+//
+// 12. 【回调不校验 state】
+//    攻击者把自己账号的 code 发给受害者，受害者登录进攻击者的账号（登录 CSRF）
+//    state 放在签名 Cookie 里，回调时比较；再加 PKCE，截获的 code 没有 code_verifier 换不到 Token
+//
+// 13. 【审计日志里存了明文密码】
+//    管理员重置别人密码时请求体里就是新密码，原样落库后审计表成了最大的泄露源
+//    auditlog 默认按字段名脱敏，业务特有的敏感字段用 RedactFields / RedactPaths 补充；
+//    超过 MaxBodySize 被截断的 JSON 无法解析，整个请求体不记录
+//
+// 14. 【灰度用随机数】
+//    rand.Intn(100) < 30 每次请求结果不同，同一个用户刷新页面新旧版本来回跳
+//    featureflag 按 flag key + 用户 ID 哈希分桶，同一个用户总是同一个结果，扩大比例时已有的用户不会掉出去
+//
+// 15. 【Session 登录后不换会话 ID】
+//    攻击者先拿到一个会话 ID 塞给受害者，受害者登录后攻击者用同一个 ID 就是登录状态（会话固定）
+//    sessions.Login 总是生成新 ID 并删除旧会话；Cookie 自动带上，修改数据的请求还要校验 CSRF Token
+//
 // ============================================================================
 
 // ============================================================================
@@ -554,11 +2106,16 @@ func main() {
 //
 // 1. 实现 Token 黑名单存储到 Redis
 //
-// 2. 实现多设备登录管理:
-//    - 记录每个 Token 的设备信息
-//    - 支持踢出指定设备
+// 2. 完善多设备登录管理:
+//    - refresh_tokens 表已记录每个设备的 User-Agent 和 IP
+//    - 增加 DELETE /api/sessions/:id，只踢出指定设备
+//
+// 3. 已登录用户在设置页主动关联 Google / GitHub（identities.Link），以及解除关联
+//    （只剩一种登录方式时不允许解除）
+//
+// 4. 后台定期调用 links.Purge 清理过期的一次性 Token（参考 tokens.Sweep）
 //
-// 3. 实现 Token 自动续期:
+// 5. 实现 Token 自动续期:
 //    - Access Token 快过期时自动刷新
 //    - 返回新 Token 在响应头中
 //
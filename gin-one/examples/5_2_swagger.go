@@ -1,32 +1,29 @@
 // ============================================================================
-// 5.2 Swagger 接口文档自动生成
+// 5.2 OpenAPI 接口文档（运行时生成）
 // ============================================================================
-// 运行方式:
-//   1. 安装 swag: go install github.com/swaggo/swag/cmd/swag@latest
-//   2. 生成文档: swag init -g examples/5_2_swagger.go
-//   3. 运行服务: go run examples/5_2_swagger.go
-//   4. 访问文档: http://localhost:8080/swagger/index.html
+// 运行方式: go run examples/5_2_swagger.go
+// 访问文档: http://localhost:8080/docs
+// 文档 JSON: http://localhost:8080/openapi.json
+// 导出文档: go run examples/5_2_swagger.go openapi-dump -o openapi.json
 //
-// 需要先安装:
-//   go get -u github.com/swaggo/gin-swagger
-//   go get -u github.com/swaggo/files
+// 不需要安装 swag，也不需要 swag init：文档在启动时由 go-one/openapi 包生成
 // ============================================================================
 
 package main
 
 import (
+	"context"
+	"log"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
-	// 导入 swagger 相关包
-	// swaggerFiles "github.com/swaggo/files"
-	// ginSwagger "github.com/swaggo/gin-swagger"
-	// _ "your-project/docs" // 导入生成的 docs 包
+
+	"go-one/cmd"
+	"go-one/openapi"
 )
 
 // ============================================================================
-// Swagger 核心概念
+// OpenAPI 核心概念
 // ============================================================================
 //
 // 【什么是 Swagger/OpenAPI？】
@@ -38,116 +35,117 @@ import (
 // 3. 服务端桩代码
 // 4. API 测试工具
 //
-// 【swag 工具工作原理】
-//
-// 1. 在代码中写注释
-// 2. swag init 扫描注释
-// 3. 生成 docs/swagger.json
-// 4. gin-swagger 读取 JSON 展示 UI
-//
-// ============================================================================
-//
-// 【常用注释格式】
-//
-// @Summary      简短描述
-// @Description  详细描述
-// @Tags         标签（用于分组）
-// @Accept       接受的 MIME 类型
-// @Produce      返回的 MIME 类型
-// @Param        参数名 位置 类型 必填 "描述"
-// @Success      状态码 {数据类型} 模型 "描述"
-// @Failure      状态码 {数据类型} 模型 "描述"
-// @Router       路径 [方法]
-// @Security     安全方案名
+// 【两种生成方式】
+//
+// swag 注释（常见做法）：
+//  1. 在 handler 上方写 @Summary、@Param、@Router 等注释
+//  2. swag init 扫描源码，生成 docs/swagger.json 和 docs.go
+//  3. gin-swagger 读取生成的文件展示 UI
+//
+// 运行时生成（本示例）：
+//  1. 注册路由时同时传入接口说明 openapi.Op
+//  2. openapi 包用反射读取结构体的 json / binding / example / doc 标签
+//  3. /openapi.json 返回文档，/docs 返回 Swagger UI 页面
+//
+// | 对比项         | swag 注释                            | 运行时生成                         |
+// |----------------|--------------------------------------|------------------------------------|
+// | 路由路径       | @Router 手写，改了路由容易忘记改注释  | 和路由注册是同一次调用             |
+// | 参数校验规则   | 注释里再写一遍 minLength 等           | 读 binding 标签，和校验一致        |
+// | 泛型响应       | Response{data=User} 特殊语法          | Response[User]，普通的 Go 泛型     |
+// | 字段描述       | 字段后面的注释                       | doc 标签（注释在运行时读不到）     |
+// | 生成步骤       | 每次修改后 swag init，生成的文件要提交 | 无                                 |
+//
+// 【swag 注释和 Op 的对应关系】
+//
+// | swag 注释                                   | openapi.Op                          |
+// |---------------------------------------------|-------------------------------------|
+// | @Summary / @Description / @Tags             | Summary / Description / Tags        |
+// | @Param id path int true "用户ID"            | Path: UserURI{}（uri 标签）          |
+// | @Param page query int false "页码"          | Query: ListUsersQuery{}（form 标签） |
+// | @Param request body CreateUserRequest true  | Body: CreateUserRequest{}           |
+// | @Success 200 {object} Response{data=User}   | Responses: {200: Response[User]{}}  |
+// | @Security BearerAuth                        | Auth: true                          |
+// | @Router /users/{id} [get]                   | Register 的 method 和 path 参数      |
 //
 // ============================================================================
 
-// ============================================================================
-// API 通用信息（必须在 main 函数前定义）
-// ============================================================================
-
-// @title           Gin Learning API
-// @version         1.0
-// @description     Gin 框架学习项目 API 文档
-// @termsOfService  http://swagger.io/terms/
-
-// @contact.name   API Support
-// @contact.url    http://www.example.com/support
-// @contact.email  support@example.com
-
-// @license.name  Apache 2.0
-// @license.url   http://www.apache.org/licenses/LICENSE-2.0.html
-
-// @host      localhost:8080
-// @BasePath  /api/v1
-
-// @securityDefinitions.apikey  BearerAuth
-// @in                          header
-// @name                        Authorization
-// @description                 Bearer Token 认证，格式: Bearer {token}
-
 // ============================================================================
 // 数据模型定义
 // ============================================================================
+//
+// 字段描述写在 doc 标签里，示例值写在 example 标签里，
+// 校验规则只写一次（binding 标签），同时用于 ShouldBind 和文档。
 
 // User 用户模型
-// @Description 用户信息
 type User struct {
-	ID        uint   `json:"id" example:"1"`                            // 用户ID
-	Username  string `json:"username" example:"zhangsan"`               // 用户名
-	Email     string `json:"email" example:"zhangsan@example.com"`      // 邮箱
-	CreatedAt string `json:"created_at" example:"2024-01-15T10:30:00Z"` // 创建时间
+	ID        uint   `json:"id" doc:"用户ID" example:"1"`
+	Username  string `json:"username" doc:"用户名" example:"zhangsan"`
+	Email     string `json:"email" doc:"邮箱" example:"zhangsan@example.com"`
+	CreatedAt string `json:"created_at" doc:"创建时间" example:"2024-01-15T10:30:00Z"`
 }
 
 // CreateUserRequest 创建用户请求
-// @Description 创建用户的请求参数
 type CreateUserRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=50" example:"zhangsan"` // 用户名
-	Email    string `json:"email" binding:"required,email" example:"zhangsan@example.com"` // 邮箱
-	Password string `json:"password" binding:"required,min=6" example:"password123"`       // 密码
+	Username string `json:"username" binding:"required,min=3,max=50" doc:"用户名" example:"zhangsan"`
+	Email    string `json:"email" binding:"required,email" doc:"邮箱" example:"zhangsan@example.com"`
+	Password string `json:"password" binding:"required,min=6" doc:"密码" example:"password123"`
 }
 
 // UpdateUserRequest 更新用户请求
 type UpdateUserRequest struct {
-	Username string `json:"username,omitempty" example:"lisi"` // 用户名
-	Email    string `json:"email,omitempty" example:"lisi@example.com"` // 邮箱
+	Username string `json:"username,omitempty" binding:"omitempty,min=3,max=50" doc:"用户名" example:"lisi"`
+	Email    string `json:"email,omitempty" binding:"omitempty,email" doc:"邮箱" example:"lisi@example.com"`
 }
 
-// Response 通用响应
-type Response struct {
-	Code    int         `json:"code" example:"0"`       // 状态码
-	Message string      `json:"message" example:"成功"` // 消息
-	Data    interface{} `json:"data,omitempty"`         // 数据
+// UserURI 路径参数 /users/:id
+type UserURI struct {
+	ID uint `uri:"id" binding:"required" doc:"用户ID" example:"1"`
+}
+
+// ListUsersQuery 用户列表查询参数
+type ListUsersQuery struct {
+	Page    int    `form:"page" binding:"omitempty,min=1" doc:"页码，默认 1" example:"1"`
+	Size    int    `form:"size" binding:"omitempty,min=1,max=100" doc:"每页数量，默认 10" example:"10"`
+	Keyword string `form:"keyword" doc:"搜索关键字"`
+}
+
+// Response 通用响应，T 是 data 的类型
+//
+// 用泛型而不是 interface{}：文档生成器能知道每个接口 data 的具体结构
+type Response[T any] struct {
+	Code    int    `json:"code" doc:"状态码，0 表示成功" example:"0"`
+	Message string `json:"message" doc:"消息" example:"成功"`
+	Data    T      `json:"data,omitempty"`
 }
 
 // ErrorResponse 错误响应
 type ErrorResponse struct {
-	Code    int    `json:"code" example:"400"`    // 错误码
-	Message string `json:"message" example:"参数错误"` // 错误信息
-	Error   string `json:"error,omitempty" example:"username is required"` // 错误详情
+	Code    int    `json:"code" doc:"错误码" example:"400"`
+	Message string `json:"message" doc:"错误信息" example:"参数错误"`
+	Error   string `json:"error,omitempty" doc:"错误详情" example:"username is required"`
 }
 
 // PaginatedResponse 分页响应
-type PaginatedResponse struct {
-	Code    int         `json:"code" example:"0"`
-	Message string      `json:"message" example:"成功"`
-	Data    interface{} `json:"data"`
-	Total   int64       `json:"total" example:"100"`
-	Page    int         `json:"page" example:"1"`
-	Size    int         `json:"size" example:"10"`
+type PaginatedResponse[T any] struct {
+	Code    int    `json:"code" example:"0"`
+	Message string `json:"message" example:"成功"`
+	Data    []T    `json:"data"`
+	Total   int64  `json:"total" doc:"总数" example:"100"`
+	Page    int    `json:"page" example:"1"`
+	Size    int    `json:"size" example:"10"`
 }
 
 // LoginRequest 登录请求
 type LoginRequest struct {
-	Username string `json:"username" binding:"required" example:"admin"`    // 用户名
-	Password string `json:"password" binding:"required" example:"admin123"` // 密码
+	Username string `json:"username" binding:"required" doc:"用户名" example:"admin"`
+	Password string `json:"password" binding:"required" doc:"密码" example:"admin123"`
 }
 
 // LoginResponse 登录响应
 type LoginResponse struct {
-	AccessToken  string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIs..."`  // Access Token
-	RefreshToken string `json:"refresh_token" example:"eyJhbGciOiJIUzI1NiIs..."` // Refresh Token
-	ExpiresIn    int    `json:"expires_in" example:"7200"`                        // 过期时间(秒)
+	AccessToken  string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIs..."`
+	RefreshToken string `json:"refresh_token" example:"eyJhbGciOiJIUzI1NiIs..."`
+	ExpiresIn    int    `json:"expires_in" doc:"过期时间(秒)" example:"7200"`
 }
 
 // ============================================================================
@@ -160,34 +158,28 @@ var userList = []User{
 }
 
 // ============================================================================
-// Handler 函数（带 Swagger 注释）
+// Handler 函数（不需要文档注释，文档在注册路由时给出）
 // ============================================================================
 
-// Login godoc
-// @Summary      用户登录
-// @Description  使用用户名密码登录，获取 JWT Token
-// @Tags         认证
-// @Accept       json
-// @Produce      json
-// @Param        request  body      LoginRequest  true  "登录参数"
-// @Success      200      {object}  Response{data=LoginResponse}  "登录成功"
-// @Failure      400      {object}  ErrorResponse  "参数错误"
-// @Failure      401      {object}  ErrorResponse  "用户名或密码错误"
-// @Router       /auth/login [post]
+func badRequest(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, ErrorResponse{Code: 400, Message: "参数错误", Error: err.Error()})
+}
+
+func notFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, ErrorResponse{Code: 404, Message: "用户不存在"})
+}
+
+// Login 用户登录
 func Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Code:    400,
-			Message: "参数错误",
-			Error:   err.Error(),
-		})
+		badRequest(c, err)
 		return
 	}
 
 	// 模拟验证
 	if req.Username == "admin" && req.Password == "admin123" {
-		c.JSON(http.StatusOK, Response{
+		c.JSON(http.StatusOK, Response[LoginResponse]{
 			Code:    0,
 			Message: "登录成功",
 			Data: LoginResponse{
@@ -205,92 +197,46 @@ func Login(c *gin.Context) {
 	})
 }
 
-// GetUsers godoc
-// @Summary      获取用户列表
-// @Description  获取所有用户的列表，支持分页
-// @Tags         用户管理
-// @Accept       json
-// @Produce      json
-// @Param        page   query     int     false  "页码"       default(1)
-// @Param        size   query     int     false  "每页数量"   default(10)
-// @Param        keyword query   string  false  "搜索关键字"
-// @Success      200    {object}  PaginatedResponse{data=[]User}  "成功"
-// @Failure      500    {object}  ErrorResponse  "服务器错误"
-// @Security     BearerAuth
-// @Router       /users [get]
+// GetUsers 用户列表
 func GetUsers(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
+	q := ListUsersQuery{Page: 1, Size: 10}
+	if err := c.ShouldBindQuery(&q); err != nil {
+		badRequest(c, err)
+		return
+	}
 
-	c.JSON(http.StatusOK, PaginatedResponse{
+	c.JSON(http.StatusOK, PaginatedResponse[User]{
 		Code:    0,
 		Message: "成功",
 		Data:    userList,
 		Total:   int64(len(userList)),
-		Page:    page,
-		Size:    size,
+		Page:    q.Page,
+		Size:    q.Size,
 	})
 }
 
-// GetUser godoc
-// @Summary      获取用户详情
-// @Description  根据用户 ID 获取用户详细信息
-// @Tags         用户管理
-// @Accept       json
-// @Produce      json
-// @Param        id   path      int  true  "用户ID"
-// @Success      200  {object}  Response{data=User}  "成功"
-// @Failure      400  {object}  ErrorResponse  "参数错误"
-// @Failure      404  {object}  ErrorResponse  "用户不存在"
-// @Security     BearerAuth
-// @Router       /users/{id} [get]
+// GetUser 用户详情
 func GetUser(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Code:    400,
-			Message: "参数错误",
-			Error:   "id must be a number",
-		})
+	var uri UserURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		badRequest(c, err)
 		return
 	}
 
 	for _, user := range userList {
-		if user.ID == uint(id) {
-			c.JSON(http.StatusOK, Response{
-				Code:    0,
-				Message: "成功",
-				Data:    user,
-			})
+		if user.ID == uri.ID {
+			c.JSON(http.StatusOK, Response[User]{Code: 0, Message: "成功", Data: user})
 			return
 		}
 	}
-
-	c.JSON(http.StatusNotFound, ErrorResponse{
-		Code:    404,
-		Message: "用户不存在",
-	})
+	notFound(c)
 }
 
-// CreateUser godoc
-// @Summary      创建用户
-// @Description  创建新用户
-// @Tags         用户管理
-// @Accept       json
-// @Produce      json
-// @Param        request  body      CreateUserRequest  true  "用户信息"
-// @Success      201      {object}  Response{data=User}  "创建成功"
-// @Failure      400      {object}  ErrorResponse  "参数错误"
-// @Security     BearerAuth
-// @Router       /users [post]
+// CreateUser 创建用户
 func CreateUser(c *gin.Context) {
 	var req CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Code:    400,
-			Message: "参数错误",
-			Error:   err.Error(),
-		})
+		badRequest(c, err)
 		return
 	}
 
@@ -302,90 +248,53 @@ func CreateUser(c *gin.Context) {
 	}
 	userList = append(userList, newUser)
 
-	c.JSON(http.StatusCreated, Response{
-		Code:    0,
-		Message: "创建成功",
-		Data:    newUser,
-	})
+	c.JSON(http.StatusCreated, Response[User]{Code: 0, Message: "创建成功", Data: newUser})
 }
 
-// UpdateUser godoc
-// @Summary      更新用户
-// @Description  根据 ID 更新用户信息
-// @Tags         用户管理
-// @Accept       json
-// @Produce      json
-// @Param        id       path      int                true  "用户ID"
-// @Param        request  body      UpdateUserRequest  true  "更新内容"
-// @Success      200      {object}  Response{data=User}  "更新成功"
-// @Failure      400      {object}  ErrorResponse  "参数错误"
-// @Failure      404      {object}  ErrorResponse  "用户不存在"
-// @Security     BearerAuth
-// @Router       /users/{id} [put]
+// UpdateUser 更新用户
 func UpdateUser(c *gin.Context) {
-	id, _ := strconv.Atoi(c.Param("id"))
-
+	var uri UserURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		badRequest(c, err)
+		return
+	}
 	var req UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Code:    400,
-			Message: "参数错误",
-		})
+		badRequest(c, err)
 		return
 	}
 
 	for i, user := range userList {
-		if user.ID == uint(id) {
+		if user.ID == uri.ID {
 			if req.Username != "" {
 				userList[i].Username = req.Username
 			}
 			if req.Email != "" {
 				userList[i].Email = req.Email
 			}
-			c.JSON(http.StatusOK, Response{
-				Code:    0,
-				Message: "更新成功",
-				Data:    userList[i],
-			})
+			c.JSON(http.StatusOK, Response[User]{Code: 0, Message: "更新成功", Data: userList[i]})
 			return
 		}
 	}
-
-	c.JSON(http.StatusNotFound, ErrorResponse{
-		Code:    404,
-		Message: "用户不存在",
-	})
+	notFound(c)
 }
 
-// DeleteUser godoc
-// @Summary      删除用户
-// @Description  根据 ID 删除用户
-// @Tags         用户管理
-// @Accept       json
-// @Produce      json
-// @Param        id   path      int  true  "用户ID"
-// @Success      200  {object}  Response  "删除成功"
-// @Failure      404  {object}  ErrorResponse  "用户不存在"
-// @Security     BearerAuth
-// @Router       /users/{id} [delete]
+// DeleteUser 删除用户
 func DeleteUser(c *gin.Context) {
-	id, _ := strconv.Atoi(c.Param("id"))
+	var uri UserURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		badRequest(c, err)
+		return
+	}
 
 	for i, user := range userList {
-		if user.ID == uint(id) {
+		if user.ID == uri.ID {
 			userList = append(userList[:i], userList[i+1:]...)
-			c.JSON(http.StatusOK, Response{
-				Code:    0,
-				Message: "删除成功",
-			})
+			c.JSON(http.StatusOK, Response[any]{Code: 0, Message: "删除成功"})
 			return
 		}
 	}
-
-	c.JSON(http.StatusNotFound, ErrorResponse{
-		Code:    404,
-		Message: "用户不存在",
-	})
+	notFound(c)
 }
 
 // ============================================================================
@@ -393,91 +302,121 @@ func DeleteUser(c *gin.Context) {
 // ============================================================================
 
 func main() {
+	// openapi-dump 不启动服务直接输出文档，CI 里可以和提交的文件比较
+	cmd.Program{Router: router}.Main()
+}
+
+// router 注册路由和文档；这个示例的数据在内存里，不调用 env.App，不连接数据库
+func router(context.Context, *cmd.Env) (*gin.Engine, *openapi.Builder, error) {
 	r := gin.Default()
 
+	// 相当于 swag 的 @title、@version、@description、@securityDefinitions
+	doc := openapi.New(openapi.Config{
+		Title:       "Gin Learning API",
+		Version:     "1.0",
+		Description: "Gin 框架学习项目 API 文档",
+		BearerAuth:  true,
+	})
+
 	// ========================================================================
-	// Swagger 路由
+	// API 路由：注册路由的同时写文档
 	// ========================================================================
 
-	// 取消下面的注释以启用 Swagger UI
-	// r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	v1 := r.Group("/api/v1")
+	// 请求先按文档校验，参数类型不对、请求体多了文档之外的字段直接返回 400，到不了 handler
+	v1.Use(doc.ValidateRequests(openapi.ValidationConfig{Strict: true}))
+
+	doc.Register(v1, http.MethodPost, "/auth/login", openapi.Op{
+		Summary:     "用户登录",
+		Description: "使用用户名密码登录，获取 JWT Token",
+		Tags:        []string{"认证"},
+		Body:        LoginRequest{},
+		Responses: map[int]any{
+			200: Response[LoginResponse]{},
+			400: openapi.ValidationError{},
+			401: ErrorResponse{},
+		},
+	}, Login)
+
+	users := []string{"用户管理"}
+	doc.Register(v1, http.MethodGet, "/users", openapi.Op{
+		Summary:   "获取用户列表",
+		Tags:      users,
+		Query:     ListUsersQuery{},
+		Responses: map[int]any{200: PaginatedResponse[User]{}, 400: openapi.ValidationError{}},
+		Auth:      true,
+	}, GetUsers)
+	doc.Register(v1, http.MethodGet, "/users/:id", openapi.Op{
+		Summary:   "获取用户详情",
+		Tags:      users,
+		Path:      UserURI{},
+		Responses: map[int]any{200: Response[User]{}, 400: openapi.ValidationError{}, 404: ErrorResponse{}},
+		Auth:      true,
+	}, GetUser)
+	doc.Register(v1, http.MethodPost, "/users", openapi.Op{
+		Summary:   "创建用户",
+		Tags:      users,
+		Body:      CreateUserRequest{},
+		Responses: map[int]any{201: Response[User]{}, 400: openapi.ValidationError{}},
+		Auth:      true,
+	}, CreateUser)
+	doc.Register(v1, http.MethodPut, "/users/:id", openapi.Op{
+		Summary:   "更新用户",
+		Tags:      users,
+		Path:      UserURI{},
+		Body:      UpdateUserRequest{},
+		Responses: map[int]any{200: Response[User]{}, 400: openapi.ValidationError{}, 404: ErrorResponse{}},
+		Auth:      true,
+	}, UpdateUser)
+	doc.Register(v1, http.MethodDelete, "/users/:id", openapi.Op{
+		Summary:   "删除用户",
+		Tags:      users,
+		Path:      UserURI{},
+		Responses: map[int]any{200: Response[any]{}, 404: ErrorResponse{}},
+		Auth:      true,
+	}, DeleteUser)
 
 	// ========================================================================
-	// API 路由
+	// 文档路由（不写进文档本身）
 	// ========================================================================
 
-	// 认证接口
-	r.POST("/api/v1/auth/login", Login)
-
-	// 用户接口
-	v1 := r.Group("/api/v1")
-	{
-		v1.GET("/users", GetUsers)
-		v1.GET("/users/:id", GetUser)
-		v1.POST("/users", CreateUser)
-		v1.PUT("/users/:id", UpdateUser)
-		v1.DELETE("/users/:id", DeleteUser)
-	}
+	r.GET("/openapi.json", doc.Handler())
+	r.GET("/docs", openapi.UI("/openapi.json"))
 
-	// 打印说明
-	println("Server starting on :8080")
-	println("")
-	println("Swagger UI: http://localhost:8080/swagger/index.html")
-	println("(需要先运行 swag init 生成文档)")
-	println("")
-	println("生成文档命令:")
-	println("  swag init -g examples/5_2_swagger.go")
-	println("")
-	println("测试命令:")
-	println(`  curl http://localhost:8080/api/v1/users`)
-	println(`  curl http://localhost:8080/api/v1/users/1`)
-
-	r.Run(":8080")
+	log.Println("Swagger UI: http://localhost:8080/docs")
+	return r, doc, nil
 }
 
 // ============================================================================
-// Swag 命令
+// 测试命令
 // ============================================================================
 //
-// # 安装 swag
-// go install github.com/swaggo/swag/cmd/swag@latest
-//
-// # 生成文档
-// swag init -g examples/5_2_swagger.go -o docs
-//
-// # 格式化注释
-// swag fmt
-//
-// ============================================================================
-
-// ============================================================================
-// 常用注释格式示例
-// ============================================================================
-//
-// 【路径参数】
-// @Param   id   path   int   true   "用户ID"
-//
-// 【查询参数】
-// @Param   page   query   int   false   "页码"   default(1)
-// @Param   keyword   query   string   false   "搜索关键字"
-//
-// 【请求体】
-// @Param   request   body   CreateUserRequest   true   "请求体"
+// # 查看文档
+// curl -s http://localhost:8080/openapi.json | jq '.paths | keys'
+// curl -s http://localhost:8080/openapi.json | jq '.components.schemas.CreateUserRequest'
+// open http://localhost:8080/docs
 //
-// 【表单参数】
-// @Param   file   formData   file   true   "上传文件"
+// # 调用接口
+// curl http://localhost:8080/api/v1/users?page=1&size=10
+// curl http://localhost:8080/api/v1/users/1
+// curl -X POST http://localhost:8080/api/v1/users \
+//   -H "Content-Type: application/json" \
+//   -d '{"username":"lisi","email":"lisi@example.com","password":"secret123"}'
 //
-// 【请求头】
-// @Param   Authorization   header   string   true   "Bearer Token"
+// # 不启动服务，直接输出文档 / 列出路由
+// go run examples/5_2_swagger.go openapi-dump -o openapi.json
+// go run examples/5_2_swagger.go routes-list
 //
-// 【枚举类型】
-// @Param   status   query   string   false   "状态"   Enums(active, inactive)
+// # 契约测试：按文档里的 example 调用每个接口，状态码没声明、响应多了文档之外的字段时退出码为 1
+// go run examples/5_2_swagger.go contract-test
+// go run examples/5_2_swagger.go contract-test -run "^GET "
 //
-// 【嵌套对象响应】
-// @Success   200   {object}   Response{data=User}   "成功"
+// # 压测：服务运行时在另一个终端执行，路径发到 server.addr
+// go run examples/5_2_swagger.go loadgen -c 50 -duration 10s /api/v1/users/1
+// go run examples/5_2_swagger.go loadgen -ramp 1,2,4,8,16,32,64 -max-p99 50ms /api/v1/users
 //
-// 【数组响应】
-// @Success   200   {object}   Response{data=[]User}   "成功"
+// # 用文档生成客户端（openapi-generator）
+// openapi-generator-cli generate -i http://localhost:8080/openapi.json -g typescript-fetch -o ./client
 //
 // ============================================================================
 
@@ -485,26 +424,29 @@ func main() {
 // 易错点总结
 // ============================================================================
 //
-// 1. 【注释必须紧贴函数】
-//    注释和函数之间不能有空行
+// 1. 【字段注释不会出现在文档里】
+//    反射只能读到类型和标签，读不到源码注释
+//    需要在文档里显示的描述写在 doc 标签里
 //
 // 2. 【类型名大小写】
-//    Swagger 通过反射读取类型
-//    类型必须是导出的（首字母大写）
+//    反射读不到未导出的字段，json:"-" 的字段也会跳过
+//    文档里的字段和 encoding/json 实际输出的字段一致
 //
-// 3. 【example 标签】
-//    结构体字段的 example 标签用于文档展示
-//    example:"张三" 不是 example:"张三"
+// 3. 【用 interface{} 作为 data】
+//    文档只能显示"任意值"
+//    用泛型 Response[User]，文档里会生成 Response_User
 //
-// 4. 【嵌套类型】
-//    使用 Response{data=User} 表示泛型响应
+// 4. 【绕过 Register 直接注册路由】
+//    v1.GET(...) 注册的接口不会出现在文档里
+//    需要文档的接口都通过 doc.Register 注册
 //
-// 5. 【数组类型】
-//    使用 []User 或 Response{data=[]User}
+// 5. 【生产环境暴露文档】
+//    /docs 和 /openapi.json 会暴露所有接口和字段
+//    对外服务在 release 模式下不注册，或放在认证中间件后面
 //
-// 6. 【安全定义】
-//    securityDefinitions 在文件顶部定义
-//    @Security 在每个需要认证的接口添加
+// 6. 【Swagger UI 加载不出来】
+//    UI 的 JS/CSS 从 CDN 加载，内网环境需要把 swagger-ui-dist 放到本地
+//    /openapi.json 本身不依赖外网
 //
 // ============================================================================
 
@@ -512,10 +454,10 @@ func main() {
 // 练习题
 // ============================================================================
 //
-// 1. 为项目中的所有接口添加 Swagger 注释
+// 1. 为 4_1_gorm_integration.go 的所有接口注册文档
 //
-// 2. 添加文件上传接口的文档
+// 2. 添加文件上传接口的文档（multipart/form-data，需要扩展 Op）
 //
-// 3. 实现分组标签（Tags）来组织 API
+// 3. 用 v1.GET 直接注册一个接口，运行 contract-test 看它报告哪个路由没有文档
 //
 // ============================================================================
@@ -0,0 +1,360 @@
+// ============================================================================
+// 6.1 WebSocket 聊天室
+// ============================================================================
+// 运行方式: go run examples/6_1_websocket_chat.go
+// 生产模式: APP_SERVER_MODE=release APP_JWT_SECRET=<至少 32 字节> go run examples/6_1_websocket_chat.go
+// 连接管理（Hub、房间、心跳、背压、JWT 握手）在 go-one/ws 包里，这里只写聊天协议
+// ============================================================================
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"go-one/config"
+	"go-one/response"
+	"go-one/server"
+	"go-one/ws"
+)
+
+// ============================================================================
+// WebSocket 核心概念
+// ============================================================================
+//
+// 【HTTP 升级】
+//
+// WebSocket 连接从一个普通的 HTTP GET 开始，服务端同意后切换协议，之后同一个 TCP 连接双向收发消息：
+//
+//	GET /ws?token=eyJ... HTTP/1.1
+//	Connection: Upgrade
+//	Upgrade: websocket
+//	Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==
+//
+//	HTTP/1.1 101 Switching Protocols
+//	Upgrade: websocket
+//	Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=
+//
+// 认证必须在升级之前完成：升级之后就不能再返回 401 了。
+//
+// 【和 REST 的区别】
+//
+// | 对比项       | REST                     | WebSocket                          |
+// |--------------|--------------------------|------------------------------------|
+// | 方向         | 客户端请求，服务端响应   | 双方随时发送                       |
+// | 连接         | 每个请求独立             | 长连接，服务端要管理连接状态       |
+// | 负载均衡     | 随便转发                 | 连接固定在一个实例上               |
+// | 部署/重启    | 等请求结束即可           | 要通知客户端重连                   |
+//
+// 【聊天协议】
+//
+// 消息都是 JSON，type 区分类型：
+//
+//	→ {"type":"join","room":"golang"}
+//	→ {"type":"say","room":"golang","text":"大家好"}         房间广播
+//	→ {"type":"dm","to":"2","text":"私聊"}                   私信，发给用户 2 的所有连接
+//	→ {"type":"leave","room":"golang"}
+//	← {"type":"message","room":"golang","from":"1","name":"alice","text":"大家好","at":"..."}
+//	← {"type":"dm","from":"1","name":"alice","text":"私聊","at":"..."}
+//	← {"type":"presence","room":"golang","from":"1","name":"alice","text":"joined"}
+//	← {"type":"error","text":"..."}
+//
+// ============================================================================
+
+// Message 客户端和服务端之间的消息
+type Message struct {
+	Type string    `json:"type"`
+	Room string    `json:"room,omitempty"`
+	To   string    `json:"to,omitempty"`
+	From string    `json:"from,omitempty"`
+	Name string    `json:"name,omitempty"`
+	Text string    `json:"text,omitempty"`
+	At   time.Time `json:"at,omitzero"`
+}
+
+// 单条消息和房间名的长度限制，防止一条消息刷屏
+const (
+	maxTextLen = 2000
+	maxRoomLen = 64
+)
+
+// Chat 聊天协议，处理客户端发来的消息
+type Chat struct {
+	hub *ws.Hub
+}
+
+// encode Message 只有字符串和时间字段，序列化不会失败
+func encode(m Message) []byte {
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// reply 给单个连接回消息
+func reply(c *ws.Conn, m Message) {
+	c.Send(encode(m))
+}
+
+// OnMessage 收到客户端消息，在连接的读循环里调用
+func (ch *Chat) OnMessage(c *ws.Conn, data []byte) {
+	var m Message
+	if err := json.Unmarshal(data, &m); err != nil {
+		reply(c, Message{Type: "error", Text: "消息必须是 JSON"})
+		return
+	}
+	if len(m.Text) > maxTextLen || len(m.Room) > maxRoomLen {
+		reply(c, Message{Type: "error", Text: "消息太长"})
+		return
+	}
+
+	// 发送者身份来自握手时的 JWT，不信任客户端自己填的 from
+	out := Message{From: c.Identity.UserID, Name: c.Identity.Name, At: time.Now()}
+
+	switch m.Type {
+	case "join":
+		if m.Room == "" {
+			reply(c, Message{Type: "error", Text: "room 不能为空"})
+			return
+		}
+		ch.hub.Join(c, m.Room)
+		out.Type, out.Room, out.Text = "presence", m.Room, "joined"
+		ch.hub.Broadcast(m.Room, encode(out), nil)
+
+	case "leave":
+		ch.hub.Leave(c, m.Room)
+		out.Type, out.Room, out.Text = "presence", m.Room, "left"
+		ch.hub.Broadcast(m.Room, encode(out), nil)
+
+	case "say":
+		// 只能往自己加入的房间发消息
+		if !joined(c, m.Room) {
+			reply(c, Message{Type: "error", Text: "先加入房间 " + m.Room})
+			return
+		}
+		out.Type, out.Room, out.Text = "message", m.Room, m.Text
+		ch.hub.Broadcast(m.Room, encode(out), nil)
+
+	case "dm":
+		out.Type, out.To, out.Text = "dm", m.To, m.Text
+		if ch.hub.SendToUser(m.To, encode(out)) == 0 {
+			reply(c, Message{Type: "error", Text: "用户 " + m.To + " 不在线"})
+			return
+		}
+		// 回显给自己的所有连接（包括其他标签页），多端看到的聊天记录一致
+		ch.hub.SendToUser(c.Identity.UserID, encode(out))
+
+	default:
+		reply(c, Message{Type: "error", Text: "未知的消息类型 " + m.Type})
+	}
+}
+
+func joined(c *ws.Conn, room string) bool {
+	for _, r := range c.Rooms() {
+		if r == room {
+			return true
+		}
+	}
+	return false
+}
+
+// OnDisconnect 通知连接所在的房间；调用时连接还在房间里，但已经收不到消息
+func (ch *Chat) OnDisconnect(c *ws.Conn) {
+	log.Printf("ws disconnected: conn=%s user=%s", c.ID, c.Identity.UserID)
+	for _, room := range c.Rooms() {
+		ch.hub.Broadcast(room, encode(Message{
+			Type: "presence", Room: room, From: c.Identity.UserID, Name: c.Identity.Name, Text: "left", At: time.Now(),
+		}), c)
+	}
+}
+
+// ============================================================================
+// Token（示例用）
+// ============================================================================
+
+// issueToken 签发和 5_1_jwt_auth.go 相同格式的 Access Token
+// 这里为了方便测试直接按参数签发，实际项目在登录接口验证密码后签发
+func issueToken(secret []byte, userID uint, username string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id":  userID,
+		"username": username,
+		"sub":      "access_token",
+		"iat":      now.Unix(),
+		"exp":      now.Add(ttl).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+func main() {
+	cfg, err := config.Load(config.Options{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	secret := []byte(cfg.JWT.Secret)
+
+	chat := &Chat{}
+	hub := ws.NewHub(ws.Config{
+		Authenticate: ws.JWT(secret),
+		OnMessage:    chat.OnMessage,
+		OnConnect: func(c *ws.Conn) {
+			log.Printf("ws connected: conn=%s user=%s", c.ID, c.Identity.UserID)
+		},
+		OnDisconnect: chat.OnDisconnect,
+		// 开发时前端跑在别的端口，放行所有 Origin；生产环境只放行自己的域名
+		CheckOrigin: func(r *http.Request) bool { return cfg.Server.Mode != "release" },
+	})
+	chat.hub = hub
+
+	r := gin.Default()
+
+	// ========================================================================
+	// 一、获取测试 Token（仅 debug 模式）
+	// ========================================================================
+
+	if cfg.Server.Mode != "release" {
+		r.GET("/token", func(c *gin.Context) {
+			id, err := strconv.ParseUint(c.Query("user_id"), 10, 32)
+			if err != nil || id == 0 || c.Query("username") == "" {
+				response.Error(c, http.StatusBadRequest, "invalid_request", "需要 user_id 和 username")
+				return
+			}
+			tok, err := issueToken(secret, uint(id), c.Query("username"), cfg.JWT.AccessTTL)
+			if err != nil {
+				response.Error(c, http.StatusInternalServerError, "sign_failed", err.Error())
+				return
+			}
+			response.Success(c, gin.H{"token": tok})
+		})
+	}
+
+	// ========================================================================
+	// 二、WebSocket 入口
+	// ========================================================================
+
+	// 认证失败返回 401 JSON，成功后请求一直阻塞到连接断开
+	r.GET("/ws", hub.Handler())
+
+	// ========================================================================
+	// 三、从 HTTP 推送消息
+	// ========================================================================
+	//
+	// 其他服务（或管理后台）通过 REST 接口往房间里发通知，和 WebSocket 消息走同一个 Hub。
+
+	r.POST("/rooms/:room/announce", func(c *gin.Context) {
+		var req struct {
+			Text string `json:"text" binding:"required,max=2000"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		n := hub.Broadcast(c.Param("room"), encode(Message{
+			Type: "message", Room: c.Param("room"), Name: "system", Text: req.Text, At: time.Now(),
+		}), nil)
+		response.Success(c, gin.H{"delivered": n})
+	})
+
+	r.GET("/stats", func(c *gin.Context) {
+		response.Success(c, gin.H{"connections": hub.Count()})
+	})
+
+	srv := server.New(r, server.Config{Addr: cfg.Server.Addr})
+
+	// Shutdown 不等待已升级的连接，主动发 1001 让客户端重连到其他实例
+	srv.OnShutdown("websocket hub", func(context.Context) error {
+		hub.Close()
+		return nil
+	})
+
+	if err := srv.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// ============================================================================
+// 测试命令
+// ============================================================================
+//
+// # 安装命令行客户端: https://github.com/vi/websocat
+//
+// # 取两个用户的 Token
+// TOKEN1=$(curl -s "http://localhost:8080/token?user_id=1&username=alice" | jq -r .data.token)
+// TOKEN2=$(curl -s "http://localhost:8080/token?user_id=2&username=bob" | jq -r .data.token)
+//
+// # 没有 Token：握手被拒绝，HTTP 401
+// curl -i -H "Connection: Upgrade" -H "Upgrade: websocket" \
+//   -H "Sec-WebSocket-Version: 13" -H "Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==" \
+//   http://localhost:8080/ws
+//
+// # 终端 1: bob 加入房间
+// websocat "ws://localhost:8080/ws?token=$TOKEN2"
+// {"type":"join","room":"golang"}
+//
+// # 终端 2: alice 加入房间并发言、私聊 bob
+// websocat "ws://localhost:8080/ws?token=$TOKEN1"
+// {"type":"join","room":"golang"}
+// {"type":"say","room":"golang","text":"大家好"}
+// {"type":"dm","to":"2","text":"私聊"}
+//
+// # 终端 3: 从 HTTP 发公告
+// curl -X POST http://localhost:8080/rooms/golang/announce \
+//   -H "Content-Type: application/json" -d '{"text":"服务将在 5 分钟后重启"}'
+//
+// # 浏览器控制台（Token 放在子协议里，不会出现在访问日志中）
+// const ws = new WebSocket("ws://localhost:8080/ws", ["bearer", TOKEN])
+// ws.onmessage = e => console.log(JSON.parse(e.data))
+// ws.onopen = () => ws.send(JSON.stringify({type: "join", room: "golang"}))
+//
+// # Ctrl+C 关闭服务：websocat 收到 1001 Going Away
+//
+// ============================================================================
+
+// ============================================================================
+// 易错点总结
+// ============================================================================
+//
+// 1. 【并发写连接】
+//    gorilla/websocket 不支持多个 goroutine 同时写
+//    ws 包让每个连接只有一个写循环，其他地方只往队列里放消息
+//
+// 2. 【没有心跳】
+//    客户端断网后服务端迟迟发现不了，连接和内存一直泄漏
+//    ws 包定时 ping，超过 PongTimeout 没有数据就断开
+//
+// 3. 【慢客户端拖垮广播】
+//    广播时阻塞等待某个客户端，整个房间都卡住
+//    发送队列有上限，满了就断开这个客户端
+//
+// 4. 【信任客户端的身份字段】
+//    消息里的 from 可以随便填
+//    发送者只看握手时 JWT 认证出的身份
+//
+// 5. 【CheckOrigin 全部放行】
+//    浏览器会带上 Cookie 发起跨站 WebSocket（CSWSH 攻击）
+//    生产环境只允许自己的域名
+//
+// 6. 【多实例部署】
+//    Hub 只知道本实例的连接，用户 A 和 B 连在不同实例上收不到对方的消息
+//    需要用 Redis Pub/Sub 等在实例之间转发
+//
+// ============================================================================
+
+// ============================================================================
+// 练习题
+// ============================================================================
+//
+// 1. 加入房间时推送最近 50 条历史消息（存 Redis List）
+//
+// 2. 用 Redis Pub/Sub 让 Hub 支持多实例：Broadcast 先发布到频道，每个实例订阅后转发给本地连接
+//
+// 3. 实现"正在输入"提示：客户端发 typing，服务端广播给房间，3 秒内不重复发送
+//
+// 4. 统计每个房间的在线人数，每 10 秒广播一次
+//
+// ============================================================================
@@ -0,0 +1,344 @@
+// ============================================================================
+// 6.2 Server-Sent Events 通知推送
+// ============================================================================
+// 运行方式: go run examples/6_2_sse_notifications.go
+// 生产模式: APP_SERVER_MODE=release APP_JWT_SECRET=<至少 32 字节> go run examples/6_2_sse_notifications.go
+// 订阅管理（断线补发、按用户推送、心跳、慢客户端）在 go-one/sse 包里，这里只写业务接口
+// 站内通知（落库、未读数、标记已读）在 go-one/notification 包里，数据库见 database.*（默认 SQLite test.db）
+// ============================================================================
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"go-one/config"
+	"go-one/database"
+	"go-one/notification"
+	"go-one/response"
+	"go-one/server"
+	"go-one/sse"
+	"go-one/ws"
+)
+
+// ============================================================================
+// SSE 核心概念
+// ============================================================================
+//
+// 【一个不结束的 HTTP 响应】
+//
+// 客户端发一个普通 GET，服务端不断往响应里写事件，连接一直不关：
+//
+//	GET /events?token=eyJ... HTTP/1.1
+//	Accept: text/event-stream
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: text/event-stream
+//
+//	retry: 3000
+//
+//	id: 1
+//	event: notification
+//	data: {"title":"订单已发货"}
+//
+//	: ping
+//
+// 【浏览器端】
+//
+//	const es = new EventSource("/events?token=" + token)
+//	es.addEventListener("notification", e => show(JSON.parse(e.data)))
+//	es.addEventListener("reset", () => reloadAll())      // 离线太久，补发不完整
+//
+// EventSource 断线后自动重连，并带上收到的最后一个 id（Last-Event-ID 头），
+// 服务端从缓冲区补发这之后的事件。
+//
+// 【什么时候用 SSE】
+//
+// | 场景                     | 选择       | 原因                                 |
+// |--------------------------|------------|--------------------------------------|
+// | 站内通知、订单状态       | SSE        | 只需要服务端推送，自带重连和补发     |
+// | 任务进度、日志流         | SSE        | 同上，curl 就能调试                  |
+// | 聊天、协同编辑           | WebSocket  | 客户端也要频繁发消息                 |
+// | 很久才有一次变化         | 轮询       | 不值得维持长连接                     |
+//
+// 【推送 + 落库】
+//
+// SSE 只能送达此刻在线的用户。需要"登录后还能看到"的站内通知先写 notifications 表，
+// 用户在线时再推一个 notification 事件；离线的用户打开页面时查 GET /notifications：
+//
+//	svc.Notify(ctx, userID, "order.shipped", payload)
+//	  ├─ INSERT notifications (user_id, type, payload, read_at = NULL)
+//	  └─ broker.Online(userID) ? broker.PublishTo(userID, "notification", 通知 JSON)
+//
+// ============================================================================
+
+// Notification 推送给前端的通知
+type Notification struct {
+	Title string    `json:"title"`
+	Body  string    `json:"body,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// ============================================================================
+// Token（示例用）
+// ============================================================================
+
+// issueToken 签发和 5_1_jwt_auth.go 相同格式的 Access Token
+// 这里为了方便测试直接按参数签发，实际项目在登录接口验证密码后签发
+func issueToken(secret []byte, userID uint, username string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id":  userID,
+		"username": username,
+		"sub":      "access_token",
+		"iat":      now.Unix(),
+		"exp":      now.Add(ttl).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+func main() {
+	cfg, err := config.Load(config.Options{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	secret := []byte(cfg.JWT.Secret)
+
+	// 认证和 WebSocket 共用 ws.JWT，同一个 Token 两边都能用
+	broker := sse.NewBroker(sse.Config{
+		Authenticate: ws.JWT(secret),
+		BufferSize:   1000,
+		Heartbeat:    15 * time.Second,
+	})
+
+	dbCfg := database.FromConfig(cfg.Database)
+	dbCfg.GORM = &gorm.Config{Logger: logger.Default.LogMode(logger.Warn)}
+	db, err := database.Open(context.Background(), dbCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := db.AutoMigrate(&notification.Notification{}); err != nil {
+		log.Fatal(err)
+	}
+	// 同时挂着 WebSocket 时再加一个 notification.WebSocket(hub)
+	notifications := notification.New(db, notification.Config{
+		Pushers: []notification.Pusher{notification.SSE(broker)},
+	})
+
+	r := gin.Default()
+
+	// ========================================================================
+	// 一、获取测试 Token（仅 debug 模式）
+	// ========================================================================
+
+	if cfg.Server.Mode != "release" {
+		r.GET("/token", func(c *gin.Context) {
+			id, err := strconv.ParseUint(c.Query("user_id"), 10, 32)
+			if err != nil || id == 0 || c.Query("username") == "" {
+				response.Error(c, http.StatusBadRequest, "invalid_request", "需要 user_id 和 username")
+				return
+			}
+			tok, err := issueToken(secret, uint(id), c.Query("username"), cfg.JWT.AccessTTL)
+			if err != nil {
+				response.Error(c, http.StatusInternalServerError, "sign_failed", err.Error())
+				return
+			}
+			response.Success(c, gin.H{"token": tok})
+		})
+	}
+
+	// ========================================================================
+	// 二、订阅入口
+	// ========================================================================
+
+	// 认证失败返回 401 JSON；成功后请求一直保持，直到客户端断开或服务关闭
+	// 注意不要挂 gzip 之类会缓冲响应的中间件，事件会积在缓冲区里发不出去
+	r.GET("/events", broker.Handler())
+
+	// ========================================================================
+	// 三、发布事件
+	// ========================================================================
+	//
+	// 业务代码在状态变化时调用 Publish / PublishTo，这里用 HTTP 接口模拟。
+	// 实际项目中这两个接口应该只对内部服务或管理员开放。
+
+	type notifyRequest struct {
+		Title string `json:"title" binding:"required,max=200"`
+		Body  string `json:"body" binding:"max=2000"`
+	}
+
+	// 广播给所有在线用户，如系统公告
+	r.POST("/notify", func(c *gin.Context) {
+		var req notifyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		id, err := broker.Publish("notification", Notification{Title: req.Title, Body: req.Body, At: time.Now()})
+		if err != nil {
+			response.Error(c, http.StatusServiceUnavailable, "unavailable", err.Error())
+			return
+		}
+		response.Success(c, gin.H{"id": id})
+	})
+
+	// 发给一个用户：先存进通知表，在线时推送（他打开的所有页面都会收到）
+	r.POST("/users/:id/notify", func(c *gin.Context) {
+		uid, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil || uid == 0 {
+			response.Error(c, http.StatusBadRequest, "invalid_id", "ID 不合法")
+			return
+		}
+		var req notifyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		n, err := notifications.Notify(c.Request.Context(), uint(uid), "message",
+			Notification{Title: req.Title, Body: req.Body, At: time.Now()})
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "保存通知失败")
+			return
+		}
+		response.Success(c, n)
+	})
+
+	// ========================================================================
+	// 四、通知列表和已读
+	// ========================================================================
+
+	// 和 /events 用同一个 Token，放在 Authorization: Bearer 头里
+	me := r.Group("/notifications", func(c *gin.Context) {
+		id, err := ws.ParseJWT(secret, ws.Token(c.Request))
+		if err != nil {
+			response.Abort(c, http.StatusUnauthorized, "unauthorized", err.Error())
+			return
+		}
+		c.Set("user_id", id.UserID)
+	})
+	notification.Register(me, notifications, func(c *gin.Context) (uint, bool) {
+		id, err := strconv.ParseUint(c.GetString("user_id"), 10, 32)
+		return uint(id), err == nil && id != 0
+	})
+
+	r.GET("/stats", func(c *gin.Context) {
+		response.Success(c, gin.H{"subscribers": broker.Subscribers()})
+	})
+
+	srv := server.New(r, server.Config{Addr: cfg.Server.Addr})
+
+	// Shutdown 会等待所有进行中的请求，SSE 请求不会自己结束；
+	// 关闭一开始就断开订阅者，否则要等到关闭超时
+	srv.OnDrain(broker.Close)
+
+	if err := srv.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// ============================================================================
+// 测试命令
+// ============================================================================
+//
+// # 取两个用户的 Token
+// TOKEN1=$(curl -s "http://localhost:8080/token?user_id=1&username=alice" | jq -r .data.token)
+// TOKEN2=$(curl -s "http://localhost:8080/token?user_id=2&username=bob" | jq -r .data.token)
+//
+// # 没有 Token：401
+// curl -i http://localhost:8080/events
+//
+// # 终端 1、2: 两个用户订阅（-N 关闭 curl 的输出缓冲）
+// curl -N "http://localhost:8080/events?token=$TOKEN1"
+// curl -N "http://localhost:8080/events?token=$TOKEN2"
+//
+// # 终端 3: 广播，两个终端都收到
+// curl -X POST http://localhost:8080/notify \
+//   -H "Content-Type: application/json" -d '{"title":"系统将在 5 分钟后维护"}'
+//
+// # 只发给 bob：存进通知表，bob 在线，终端 2 收到 event: notification
+// curl -X POST http://localhost:8080/users/2/notify \
+//   -H "Content-Type: application/json" -d '{"title":"订单已发货","body":"单号 SF123"}'
+//
+// # 发给离线的用户 3：没有推送，只落库
+// curl -X POST http://localhost:8080/users/3/notify \
+//   -H "Content-Type: application/json" -d '{"title":"你有一条新评论"}'
+//
+// # bob 的通知列表、未读数、标记已读
+// curl -H "Authorization: Bearer $TOKEN2" "http://localhost:8080/notifications?unread=true&page_size=20"
+// curl -H "Authorization: Bearer $TOKEN2" http://localhost:8080/notifications/unread-count
+// curl -X POST -H "Authorization: Bearer $TOKEN2" http://localhost:8080/notifications/1/read
+// curl -X POST -H "Authorization: Bearer $TOKEN2" http://localhost:8080/notifications/read-all
+//
+// # alice 标记 bob 的通知：404
+// curl -X POST -H "Authorization: Bearer $TOKEN1" http://localhost:8080/notifications/1/read
+//
+// # 断线补发：停掉终端 1，再发几条通知，然后带上最后收到的 id 重连
+// curl -N -H "Last-Event-ID: 1" "http://localhost:8080/events?token=$TOKEN1"
+//
+// # ID 比服务端最新的还大（模拟服务重启过）：收到 event: reset
+// curl -N -H "Last-Event-ID: 999" "http://localhost:8080/events?token=$TOKEN1"
+//
+// # 浏览器控制台
+// const es = new EventSource("/events?token=" + TOKEN)
+// es.addEventListener("notification", e => console.log(e.lastEventId, JSON.parse(e.data)))
+//
+// # Ctrl+C 关闭服务：curl 立即断开，不用等关闭超时
+//
+// ============================================================================
+
+// ============================================================================
+// 易错点总结
+// ============================================================================
+//
+// 1. 【响应被缓冲】
+//    事件写进缓冲区没有 Flush，客户端迟迟收不到
+//    sse 包每批事件后 Flush；Nginx 要关闭 proxy_buffering（或响应头 X-Accel-Buffering: no）
+//
+// 2. 【WriteTimeout 断开长连接】
+//    http.Server 的 WriteTimeout 对 SSE 同样生效，30 秒后连接被强制关闭
+//    sse 包用 http.ResponseController 清除这个请求的写超时
+//
+// 3. 【代理空闲超时】
+//    很久没有事件时，代理认为连接空闲并断开
+//    定时发送注释行 ": ping" 作为心跳
+//
+// 4. 【重连后丢事件】
+//    断线期间发布的事件客户端永远收不到
+//    事件带递增 id，重连时按 Last-Event-ID 从缓冲区补发，补发不完整时发 reset
+//
+// 5. 【关闭服务卡住】
+//    Shutdown 等待所有请求结束，而 SSE 请求永远不会自己结束
+//    用 srv.OnDrain(broker.Close) 在关闭开始时断开订阅者
+//
+// 6. 【HTTP/1.1 连接数限制】
+//    浏览器对同一个域名最多 6 个 HTTP/1.1 连接，每个标签页占一个
+//    用 HTTP/2（多路复用）或者在多个标签页之间共享一个 EventSource（SharedWorker）
+//
+// 7. 【只推不存】
+//    用户离线时 PublishTo 的事件没人收，补发缓冲区也只保留最近一段，通知就丢了
+//    先写 notifications 表再推送（notification.Service.Notify），离线用户登录后查列表
+//
+// ============================================================================
+
+// ============================================================================
+// 练习题
+// ============================================================================
+//
+// 1. 把事件存进 Redis Stream，实现多实例部署和服务重启后的补发
+//
+// 2. 订单状态变化时推送 order.updated 事件，前端收到后只刷新这个订单
+//
+// 3. 支持订阅指定主题：/events?topics=orders,system，只推送这些类型的事件
+//
+// 4. 对比 6.1 的 WebSocket：用 SSE + 普通 POST 接口实现一个聊天室，各有什么优缺点
+//
+// ============================================================================
@@ -0,0 +1,277 @@
+// ============================================================================
+// 7.1 gRPC 服务与 JSON 网关
+// ============================================================================
+// 运行方式: go run examples/7_1_grpc_service.go
+// 只做网关: go run examples/7_1_grpc_service.go -server.addr=:8081 -grpc.upstream=localhost:9090
+// 运维命令: go run examples/7_1_grpc_service.go help
+// 接口定义在 grpcapi/proto/user/v1/user.proto，实现和网关在 go-one/grpcapi 包里
+// ============================================================================
+
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"go-one/app"
+	"go-one/cmd"
+	"go-one/grpcapi"
+	"go-one/grpcapi/userpb"
+	"go-one/openapi"
+	"go-one/response"
+)
+
+// ============================================================================
+// gRPC 核心概念
+// ============================================================================
+//
+// 【和 REST 的区别】
+//
+// | 对比项     | REST + JSON                    | gRPC                                    |
+// |------------|--------------------------------|-----------------------------------------|
+// | 接口定义   | 文档（OpenAPI），可能和代码不一致 | .proto 文件，客户端和服务端代码都由它生成 |
+// | 编码       | JSON 文本                      | protobuf 二进制，更小更快               |
+// | 传输       | HTTP/1.1 或 HTTP/2             | 只能 HTTP/2                             |
+// | 错误       | HTTP 状态码                    | 16 个 gRPC 状态码（NotFound、AlreadyExists...） |
+// | 浏览器     | 直接调用                       | 不能直接调用，需要网关或 gRPC-Web       |
+// | 适合       | 对外开放的接口                 | 服务之间的内部调用                      |
+//
+// 【一份业务逻辑，两种入口】
+//
+//	curl (JSON) → :8080 Gin 网关 ─┐ gRPC 客户端
+//	grpcurl     ─────────────────→ :9090 gRPC 服务 → service.UserService → 数据库
+//
+// 网关把 JSON 转成 protobuf 再调用 gRPC，错误码反向转换（NotFound → 404）。
+// 业务规则（密码哈希、只更新允许的字段、用户名重复检查）只在 service 包里写一次。
+//
+// 【metadata】
+//
+// gRPC 的 metadata 相当于 HTTP 头，token 放在 authorization 里：
+//
+//	grpcurl -H "authorization: Bearer eyJ..." ...
+//
+// 网关会把 HTTP 请求的 Authorization 头转成 metadata，认证只在 gRPC 拦截器里做一次。
+//
+// ============================================================================
+
+// issueToken 签发和 5_1_jwt_auth.go 相同格式的 Access Token
+// 这里为了方便测试直接按参数签发，实际项目在登录接口验证密码后签发
+func issueToken(secret []byte, userID uint, username string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id":  userID,
+		"username": username,
+		"sub":      "access_token",
+		"iat":      now.Unix(),
+		"exp":      now.Add(ttl).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// dialTarget 监听地址 ":9090" 转成客户端能连接的 "localhost:9090"
+func dialTarget(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return net.JoinHostPort("localhost", port)
+	}
+	return addr
+}
+
+func main() {
+	// serve（默认）、migrate、create-admin-user、routes-list 等子命令，见 go-one/cmd
+	cmd.Program{Router: router}.Main()
+}
+
+// router 装配 gRPC 服务和 JSON 网关
+//
+// gRPC 服务注册到 env.Lifecycle，serve 时才监听端口；routes-list 同样调用 router，
+// 只注册路由、不启动服务。
+func router(ctx context.Context, env *cmd.Env) (*gin.Engine, *openapi.Builder, error) {
+	cfg := env.Config
+	secret := []byte(cfg.JWT.Secret)
+
+	// ========================================================================
+	// 一、gRPC 服务（配置了 grpc.upstream 时跳过，只做网关，也不连接数据库）
+	// ========================================================================
+
+	var grpcSrv *grpc.Server
+	target := cfg.GRPC.Upstream
+	if target == "" {
+		// 依赖由 app 包的 provider 按顺序装配：配置 → 数据库 → 缓存 → repository → service
+		// 数据库打开时 TranslateError 已开启，用户名重复时返回 AlreadyExists 而不是 Internal
+		application, err := env.App(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// 和 4_1_gorm_integration.go 完全相同的 service，只是换了一个入口
+		grpcSrv = grpcapi.NewServer(application.Services.Users, grpcapi.Config{
+			Authenticate: grpcapi.JWT(secret),
+			// 注册不需要登录
+			Public: []string{userpb.UserService_CreateUser_FullMethodName},
+			// 开发时打开反射，grpcurl 不用指定 .proto 文件
+			Reflection: cfg.Server.Mode != "release",
+		})
+		target = dialTarget(cfg.GRPC.Addr)
+	}
+
+	// ========================================================================
+	// 二、JSON 网关
+	// ========================================================================
+	//
+	// grpc.NewClient 不会立即连接，第一次调用时才建立连接，断开后自动重连。
+	// 内网示例不加密；跨机房要用 credentials.NewTLS。
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 关闭钩子按注册的逆序执行，HTTP 请求都结束后：先停 gRPC，再关客户端连接，最后关数据库
+	env.Lifecycle.OnStop("grpc client", func(context.Context) error {
+		return conn.Close()
+	})
+	if grpcSrv != nil {
+		env.Lifecycle.Append(app.Hook{
+			Name: "grpc",
+			OnStart: func(context.Context) error {
+				lis, err := net.Listen("tcp", cfg.GRPC.Addr)
+				if err != nil {
+					return err
+				}
+				go func() {
+					if err := grpcSrv.Serve(lis); err != nil {
+						log.Printf("grpc server stopped: %v", err)
+					}
+				}()
+				log.Printf("gRPC listening on %s", cfg.GRPC.Addr)
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				return grpcapi.GracefulStop(ctx, grpcSrv)
+			},
+		})
+	}
+
+	r := gin.Default()
+	// 进行中请求计数：serve 关闭时新请求返回 503，等网关请求都结束后才停止 gRPC 服务和数据库
+	r.Use(env.InFlight.Middleware())
+	grpcapi.RegisterGateway(r.Group("/v1"), userpb.NewUserServiceClient(conn))
+
+	if cfg.Server.Mode != "release" {
+		r.GET("/token", func(c *gin.Context) {
+			id, err := strconv.ParseUint(c.Query("user_id"), 10, 32)
+			if err != nil || id == 0 || c.Query("username") == "" {
+				response.Error(c, http.StatusBadRequest, "invalid_request", "需要 user_id 和 username")
+				return
+			}
+			tok, err := issueToken(secret, uint(id), c.Query("username"), cfg.JWT.AccessTTL)
+			if err != nil {
+				response.Error(c, http.StatusInternalServerError, "sign_failed", err.Error())
+				return
+			}
+			response.Success(c, gin.H{"token": tok})
+		})
+		// 各路由进行中的请求数：curl http://localhost:8080/inflight
+		r.GET("/inflight", env.InFlight.Handler())
+	}
+
+	// 网关接口由 protobuf 定义，没有 OpenAPI 文档
+	return r, nil, nil
+}
+
+// ============================================================================
+// 测试命令
+// ============================================================================
+//
+// # 通过 JSON 网关注册（CreateUser 不需要 token）
+// curl -X POST http://localhost:8080/v1/users \
+//   -H "Content-Type: application/json" \
+//   -d '{"username":"alice","email":"alice@example.com","password":"secret123","age":20}'
+//
+// # 其他接口需要 token
+// TOKEN=$(curl -s "http://localhost:8080/token?user_id=1&username=alice" | jq -r .data.token)
+// curl http://localhost:8080/v1/users/1 -H "Authorization: Bearer $TOKEN"
+// curl "http://localhost:8080/v1/users?page=1&page_size=10" -H "Authorization: Bearer $TOKEN"
+// curl -X PATCH http://localhost:8080/v1/users/1 -H "Authorization: Bearer $TOKEN" \
+//   -H "Content-Type: application/json" -d '{"age":0}'
+//
+// # 错误码转换：NotFound → 404，AlreadyExists → 409，InvalidArgument → 400
+// curl -i http://localhost:8080/v1/users/999 -H "Authorization: Bearer $TOKEN"
+//
+// # 直接调用 gRPC（安装 grpcurl: go install github.com/fullstorydev/grpcurl/cmd/grpcurl@latest）
+// grpcurl -plaintext localhost:9090 list
+// grpcurl -plaintext localhost:9090 describe user.v1.UserService
+// grpcurl -plaintext -H "authorization: Bearer $TOKEN" -d '{"id": 1}' \
+//   localhost:9090 user.v1.UserService/GetUser
+// grpcurl -plaintext -d '{"username":"al","email":"bad","password":"x"}' \
+//   localhost:9090 user.v1.UserService/CreateUser      # InvalidArgument，带逐字段错误
+// grpcurl -plaintext localhost:9090 grpc.health.v1.Health/Check
+//
+// # 子命令：配置参数写在命令名之前，命令参数写在之后
+// go run examples/7_1_grpc_service.go help
+// go run examples/7_1_grpc_service.go migrate
+// go run examples/7_1_grpc_service.go create-admin-user -username root -email root@example.com
+// go run examples/7_1_grpc_service.go seed examples/fixtures/              # alice、bob 和两篇文章，密码 secret123
+// go run examples/7_1_grpc_service.go seed users=10000 posts=50000         # 性能测试数据，密码都是 password123
+// go run examples/7_1_grpc_service.go routes-list
+// go run examples/7_1_grpc_service.go -grpc.addr=:9091 serve -migrate=false
+//
+// # 只做网关：另开一个进程，转发到上面的 gRPC 服务
+// go run examples/7_1_grpc_service.go -server.addr=:8081 -grpc.upstream=localhost:9090
+// curl http://localhost:8081/v1/users/1 -H "Authorization: Bearer $TOKEN"
+//
+// ============================================================================
+
+// ============================================================================
+// 易错点总结
+// ============================================================================
+//
+// 1. 【修改已发布的字段编号】
+//    protobuf 按编号而不是名字编码，改编号或复用删掉的编号，旧客户端会读到错误的数据
+//    只能新增字段；删除的字段用 reserved 占住编号
+//
+// 2. 【零值和"没有设置"分不清】
+//    proto3 的 int32 age = 0 和不传没有区别，更新接口无法把年龄改成 0
+//    需要区分时用 optional，生成的 Go 字段是 *int32
+//
+// 3. 【64 位整数变成字符串】
+//    protojson 把 int64 / uint64 编码成 "1"（JavaScript 的 number 放不下 64 位整数）
+//    前端要按字符串处理 id
+//
+// 4. 【把内部错误原样返回】
+//    fmt.Errorf 返回的错误会变成 Unknown，并把数据库地址等细节发给客户端
+//    已知错误转成对应的状态码，其他错误记日志，只返回 Internal
+//
+// 5. 【没有超时】
+//    客户端不设 deadline，服务端卡住时调用方永远等下去
+//    调用时用 context.WithTimeout；网关直接用 HTTP 请求的 context，客户端断开时调用也会取消
+//
+// 6. 【每次调用都 Dial】
+//    gRPC 连接是长连接，可以被并发复用
+//    进程里建一个 ClientConn 共用，关闭时再 Close
+//
+// ============================================================================
+
+// ============================================================================
+// 练习题
+// ============================================================================
+//
+// 1. 给 ListUsers 加一个服务端流式版本 StreamUsers，逐条返回所有用户
+//
+// 2. 实现 PostService 的 gRPC 接口，CreatePost 的作者取自 token 里的用户（grpcapi.IdentityFrom）
+//
+// 3. 写一个拦截器，按方法统计调用次数和耗时，暴露在 /metrics
+//
+// 4. 把 Gin 和 gRPC 放在同一个端口：按 Content-Type: application/grpc 分流（需要 h2c）
+//
+// ============================================================================
@@ -0,0 +1,398 @@
+// ============================================================================
+// Package featureflag 功能开关：按用户 ID 哈希分桶做百分比灰度，支持布尔 / 字符串 / 数值变体
+// ============================================================================
+//
+// 【和配置项的区别】
+//
+// | 对比         | config                         | featureflag                                  |
+// |--------------|--------------------------------|----------------------------------------------|
+// | 粒度         | 整个进程一个值                 | 每个用户一个值（灰度 20% 的用户看到新功能）  |
+// | 修改         | 改文件 / 环境变量，热加载      | 管理接口修改，立即生效并通过 SSE 推给前端    |
+// | 典型用途     | 数据库地址、超时时间           | 新功能灰度、A/B 测试、紧急关闭某个功能       |
+//
+// 【定义】
+//
+//	flags:
+//	  - key: new-checkout
+//	    type: bool
+//	    enabled: true
+//	    default: false
+//	    variants:
+//	      - {value: true, weight: 20}        # 20% 的用户得到 true，其余得到 default
+//	  - key: checkout-button
+//	    type: string
+//	    enabled: true
+//	    default: blue
+//	    variants:
+//	      - {value: green, weight: 50}
+//	      - {value: orange, weight: 25}    # 50% green、25% orange、25% blue
+//
+// enabled: false 是总开关：所有人得到 default，出问题时一键关闭。
+//
+// 【分桶】
+//
+//	bucket = fnv32a(flag key + "/" + 用户 ID) % 100
+//
+//	 0 ─────── 50 ───── 75 ──────── 100
+//	 │  green   │ orange │   default  │
+//
+// 同一个用户对同一个 flag 总是落在同一个桶，刷新页面不会忽然变来变去；
+// 权重按顺序累加，灰度从 20% 调到 50% 时原来那 20% 的用户仍在里面。
+// key 参与哈希，不同 flag 的灰度人群互相独立。匿名用户没有 ID，总是得到 default。
+//
+// 【在 Handler 里使用】
+//
+//	r.Use(JWTAuthMiddleware(), flags.Middleware())
+//
+//	if featureflag.FromContext(c).Bool("new-checkout", false) { ... }
+//
+// Middleware 在请求开始时取一份快照，同一个请求里多次读取结果一致，即使中途修改了 flag。
+//
+// ============================================================================
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-one/sse"
+)
+
+// 错误定义
+var (
+	ErrNotFound = errors.New("featureflag: flag not found")
+	ErrInvalid  = errors.New("featureflag: invalid flag")
+)
+
+// Type flag 的值类型
+type Type string
+
+const (
+	TypeBool   Type = "bool"
+	TypeString Type = "string"
+	TypeNumber Type = "number" // JSON 数字，Go 里统一为 float64
+)
+
+// Variant 一个变体：Weight% 的用户得到 Value
+type Variant struct {
+	Value  any `yaml:"value" json:"value"`
+	Weight int `yaml:"weight" json:"weight"` // 0-100
+}
+
+// Flag 一个功能开关，也是表 feature_flags 的一行
+type Flag struct {
+	Key         string    `gorm:"column:flag_key;primaryKey;size:100" yaml:"key" json:"key"` // key 是 MySQL 保留字
+	Description string    `gorm:"size:200" yaml:"description" json:"description,omitempty"`
+	Type        Type      `gorm:"size:10;not null" yaml:"type" json:"type"`
+	Enabled     bool      `gorm:"not null" yaml:"enabled" json:"enabled"`
+	Default     any       `gorm:"type:text;serializer:json" yaml:"default" json:"default"`
+	Variants    []Variant `gorm:"type:text;serializer:json" yaml:"variants" json:"variants,omitempty"`
+	UpdatedAt   time.Time `yaml:"-" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Flag) TableName() string {
+	return "feature_flags"
+}
+
+var keyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,99}$`)
+
+// Validate 检查 key、类型、值和权重，并把数值统一为 float64
+// YAML 解析出的整数是 int、JSON 是 float64，统一之后比较和输出才一致
+func (f *Flag) Validate() error {
+	if !keyPattern.MatchString(f.Key) {
+		return fmt.Errorf("%w: key %q must be lowercase letters, digits, '.', '_' or '-'", ErrInvalid, f.Key)
+	}
+	var err error
+	if f.Default, err = f.Type.normalize(f.Default); err != nil {
+		return fmt.Errorf("%w: %s default: %v", ErrInvalid, f.Key, err)
+	}
+	total := 0
+	for i := range f.Variants {
+		v := &f.Variants[i]
+		if v.Value, err = f.Type.normalize(v.Value); err != nil {
+			return fmt.Errorf("%w: %s variant %d: %v", ErrInvalid, f.Key, i, err)
+		}
+		if v.Weight < 0 || v.Weight > 100 {
+			return fmt.Errorf("%w: %s variant %d: weight must be 0-100", ErrInvalid, f.Key, i)
+		}
+		total += v.Weight
+	}
+	if total > 100 {
+		return fmt.Errorf("%w: %s: variant weights add up to %d%%", ErrInvalid, f.Key, total)
+	}
+	return nil
+}
+
+func (t Type) normalize(v any) (any, error) {
+	switch t {
+	case TypeBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case TypeString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case TypeNumber:
+		switch n := v.(type) {
+		case float64:
+			return n, nil
+		case float32:
+			return float64(n), nil
+		case int:
+			return float64(n), nil
+		case int64:
+			return float64(n), nil
+		case uint64:
+			return float64(n), nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %q", t)
+	}
+	return nil, fmt.Errorf("%v (%T) is not a %s", v, v, t)
+}
+
+// Evaluate 计算 subject（通常是用户 ID）看到的值
+func (f *Flag) Evaluate(subject string) any {
+	if !f.Enabled || subject == "" || len(f.Variants) == 0 {
+		return f.Default
+	}
+	b := Bucket(f.Key, subject)
+	cum := 0
+	for _, v := range f.Variants {
+		cum += v.Weight
+		if b < cum {
+			return v.Value
+		}
+	}
+	return f.Default
+}
+
+// Bucket subject 在 flag key 下的桶号，0-99
+func Bucket(key, subject string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	h.Write([]byte("/"))
+	h.Write([]byte(subject))
+	return int(h.Sum32() % 100)
+}
+
+// ============================================================================
+// Service：保存 flag、修改后通知订阅者
+// ============================================================================
+
+// SSE 事件类型
+const (
+	EventUpdated = "flag.updated" // data 为 Flag
+	EventDeleted = "flag.deleted" // data 为 {"key": "..."}
+)
+
+// Config Service 配置
+type Config struct {
+	// Flags 初始 flag，通常来自 ParseYAML / LoadFile
+	Flags []Flag
+
+	// Store 持久化，nil 时只保存在内存中；Store 里已有的同名 flag 覆盖 Flags
+	Store Store
+
+	// Broker 修改后推送 EventUpdated / EventDeleted，默认新建一个不认证的 Broker
+	Broker *sse.Broker
+}
+
+// Service 功能开关服务
+//
+// 读多写少：flag 集合是不可变的 map，修改时复制一份再原子替换，
+// 求值不加锁，请求拿到的快照不会被后来的修改影响。
+type Service struct {
+	store  Store
+	broker *sse.Broker
+
+	mu    sync.Mutex // 串行化修改
+	flags atomic.Pointer[map[string]*Flag]
+}
+
+// New 创建 Service，加载 Store 中已有的 flag
+func New(ctx context.Context, cfg Config) (*Service, error) {
+	if cfg.Broker == nil {
+		cfg.Broker = sse.NewBroker(sse.Config{})
+	}
+	s := &Service{store: cfg.Store, broker: cfg.Broker}
+	flags := make(map[string]*Flag, len(cfg.Flags))
+	add := func(f Flag) error {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+		flags[f.Key] = &f
+		return nil
+	}
+	for _, f := range cfg.Flags {
+		if err := add(f); err != nil {
+			return nil, err
+		}
+	}
+	if s.store != nil {
+		stored, err := s.store.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("featureflag: load flags: %w", err)
+		}
+		for _, f := range stored {
+			if err := add(f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	s.flags.Store(&flags)
+	return s, nil
+}
+
+// snapshot 当前 flag 集合，只读
+func (s *Service) snapshot() map[string]*Flag {
+	return *s.flags.Load()
+}
+
+// List 所有 flag，按 key 排序
+func (s *Service) List() []Flag {
+	snap := s.snapshot()
+	list := make([]Flag, 0, len(snap))
+	for _, key := range slices.Sorted(maps.Keys(snap)) {
+		list = append(list, *snap[key])
+	}
+	return list
+}
+
+// Get 按 key 查询
+func (s *Service) Get(key string) (Flag, error) {
+	f, ok := s.snapshot()[key]
+	if !ok {
+		return Flag{}, ErrNotFound
+	}
+	return *f, nil
+}
+
+// Set 创建或替换 flag，先写 Store 再更新内存，最后推送 EventUpdated
+func (s *Service) Set(ctx context.Context, f Flag) (Flag, error) {
+	if err := f.Validate(); err != nil {
+		return Flag{}, err
+	}
+	f.UpdatedAt = time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store != nil {
+		if err := s.store.Save(ctx, &f); err != nil {
+			return Flag{}, err
+		}
+	}
+	next := maps.Clone(s.snapshot())
+	next[f.Key] = &f
+	s.flags.Store(&next)
+	s.broker.Publish(EventUpdated, f)
+	return f, nil
+}
+
+// Delete 删除 flag，之后求值都返回调用方给的 fallback
+func (s *Service) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.snapshot()[key]; !ok {
+		return ErrNotFound
+	}
+	if s.store != nil {
+		if err := s.store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	next := maps.Clone(s.snapshot())
+	delete(next, key)
+	s.flags.Store(&next)
+	s.broker.Publish(EventDeleted, map[string]string{"key": key})
+	return nil
+}
+
+// Broker 推送修改的 SSE Broker
+func (s *Service) Broker() *sse.Broker {
+	return s.broker
+}
+
+// For 取当前快照，返回 subject 的求值器；subject 为空表示匿名
+func (s *Service) For(subject string) *Evaluator {
+	return &Evaluator{flags: s.snapshot(), subject: subject}
+}
+
+// ForUser For 的便捷形式，userID 为 0 表示匿名
+func (s *Service) ForUser(userID uint) *Evaluator {
+	if userID == 0 {
+		return s.For("")
+	}
+	return s.For(strconv.FormatUint(uint64(userID), 10))
+}
+
+// ============================================================================
+// Evaluator：一个用户在一份快照上的求值
+// ============================================================================
+
+// Evaluator 求值器；flag 不存在或类型不对时返回调用方给的 fallback
+// 零值可用，所有方法都返回 fallback
+type Evaluator struct {
+	flags   map[string]*Flag
+	subject string
+}
+
+// Value 原始值，flag 不存在时 ok 为 false
+func (e *Evaluator) Value(key string) (v any, ok bool) {
+	if e == nil {
+		return nil, false
+	}
+	f, ok := e.flags[key]
+	if !ok {
+		return nil, false
+	}
+	return f.Evaluate(e.subject), true
+}
+
+// Bool 布尔 flag
+func (e *Evaluator) Bool(key string, fallback bool) bool {
+	return get(e, key, fallback)
+}
+
+// String 字符串 flag
+func (e *Evaluator) String(key, fallback string) string {
+	return get(e, key, fallback)
+}
+
+// Number 数值 flag
+func (e *Evaluator) Number(key string, fallback float64) float64 {
+	return get(e, key, fallback)
+}
+
+// All 所有 flag 的值，给前端一次拿全
+func (e *Evaluator) All() map[string]any {
+	out := make(map[string]any)
+	if e == nil {
+		return out
+	}
+	for key, f := range e.flags {
+		out[key] = f.Evaluate(e.subject)
+	}
+	return out
+}
+
+func get[T any](e *Evaluator, key string, fallback T) T {
+	v, ok := e.Value(key)
+	if !ok {
+		return fallback
+	}
+	if t, ok := v.(T); ok {
+		return t
+	}
+	return fallback
+}
@@ -0,0 +1,230 @@
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+)
+
+const flagsYAML = `
+flags:
+  - key: new-checkout
+    type: bool
+    enabled: true
+    default: false
+    variants:
+      - {value: true, weight: 20}
+  - key: checkout-button
+    type: string
+    enabled: true
+    default: blue
+    variants:
+      - {value: green, weight: 50}
+      - {value: orange, weight: 25}
+  - key: page-size
+    type: number
+    enabled: false
+    default: 20
+    variants:
+      - {value: 50, weight: 100}
+`
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.Open(t, &Flag{})
+}
+
+func newService(t *testing.T, store Store) *Service {
+	t.Helper()
+	flags, err := ParseYAML([]byte(flagsYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(context.Background(), Config{Flags: flags, Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRolloutDistribution(t *testing.T) {
+	s := newService(t, nil)
+	counts := map[string]int{}
+	onCheckout := 0
+	for id := uint(1); id <= 10000; id++ {
+		e := s.ForUser(id)
+		counts[e.String("checkout-button", "")]++
+		if e.Bool("new-checkout", false) {
+			onCheckout++
+		}
+	}
+	within := func(got, want int) bool { return got > want-300 && got < want+300 }
+	if !within(onCheckout, 2000) {
+		t.Errorf("new-checkout on for %d/10000 users, want ~2000", onCheckout)
+	}
+	for color, want := range map[string]int{"green": 5000, "orange": 2500, "blue": 2500} {
+		if !within(counts[color], want) {
+			t.Errorf("%s = %d, want ~%d", color, counts[color], want)
+		}
+	}
+}
+
+func TestRolloutIsStable(t *testing.T) {
+	s := newService(t, nil)
+	var before []uint
+	for id := uint(1); id <= 1000; id++ {
+		if s.ForUser(id).Bool("new-checkout", false) {
+			before = append(before, id)
+		}
+	}
+
+	// 灰度从 20% 扩大到 50%：原来的用户仍然在里面
+	f, _ := s.Get("new-checkout")
+	f.Variants = []Variant{{Value: true, Weight: 50}}
+	if _, err := s.Set(context.Background(), f); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range before {
+		if !s.ForUser(id).Bool("new-checkout", false) {
+			t.Fatalf("user %d dropped out after widening the rollout", id)
+		}
+	}
+}
+
+func TestEvaluator(t *testing.T) {
+	s := newService(t, nil)
+	e := s.ForUser(42)
+
+	if got := e.Number("page-size", 10); got != 20 {
+		t.Errorf("disabled flag = %v, want default 20", got)
+	}
+	if got := s.ForUser(0).Bool("new-checkout", true); got {
+		t.Error("anonymous user should get the default")
+	}
+	if got := e.Bool("missing", true); !got {
+		t.Error("missing flag should return fallback")
+	}
+	if got := e.Bool("checkout-button", true); !got {
+		t.Error("type mismatch should return fallback")
+	}
+	var zero *Evaluator
+	if got := zero.String("checkout-button", "x"); got != "x" || len(zero.All()) != 0 {
+		t.Error("nil evaluator should return fallbacks")
+	}
+
+	// 快照：取出求值器之后的修改不影响它
+	s.Delete(context.Background(), "checkout-button")
+	if _, ok := e.Value("checkout-button"); !ok {
+		t.Error("snapshot changed after Delete")
+	}
+	if _, ok := s.ForUser(42).Value("checkout-button"); ok {
+		t.Error("new snapshot still has deleted flag")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		flag Flag
+	}{
+		{"bad key", Flag{Key: "Bad Key", Type: TypeBool, Default: false}},
+		{"unknown type", Flag{Key: "a", Type: "date", Default: "x"}},
+		{"wrong default", Flag{Key: "a", Type: TypeBool, Default: "yes"}},
+		{"wrong variant", Flag{Key: "a", Type: TypeNumber, Default: 1, Variants: []Variant{{Value: "2", Weight: 10}}}},
+		{"over 100", Flag{Key: "a", Type: TypeString, Default: "x", Variants: []Variant{{"y", 60}, {"z", 50}}}},
+		{"negative", Flag{Key: "a", Type: TypeString, Default: "x", Variants: []Variant{{"y", -1}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.flag.Validate(); !errors.Is(err, ErrInvalid) {
+				t.Errorf("err = %v, want ErrInvalid", err)
+			}
+		})
+	}
+	if _, err := ParseYAML([]byte("flags:\n  - {key: a, type: bool, default: true}\n  - {key: a, type: bool, default: false}\n")); !errors.Is(err, ErrInvalid) {
+		t.Errorf("duplicate key err = %v", err)
+	}
+}
+
+func TestGormStore(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	s := newService(t, NewGormStore(db))
+	f, _ := s.Get("page-size")
+	f.Enabled = true
+	if _, err := s.Set(ctx, f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Set(ctx, Flag{Key: "banner", Type: TypeString, Enabled: true, Default: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "banner"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新创建：数据库里的 page-size 覆盖 YAML，banner 已删除
+	s2 := newService(t, NewGormStore(db))
+	if got := s2.ForUser(1).Number("page-size", 0); got != 50 {
+		t.Errorf("page-size = %v, want 50 from the store", got)
+	}
+	if _, err := s2.Get("banner"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("banner err = %v", err)
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := newService(t, nil)
+	r := gin.New()
+	api := r.Group("/flags", func(c *gin.Context) {
+		if id, err := strconv.Atoi(c.GetHeader("X-User")); err == nil {
+			c.Set("user_id", uint(id))
+		}
+	}, s.Middleware())
+	Register(api, s)
+	RegisterAdmin(r.Group("/admin/flags"), s)
+
+	do := func(method, path, body string, header ...string) (int, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if len(header) > 0 {
+			req.Header.Set("X-User", header[0])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	code, body := do("GET", "/flags", "", "7")
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	json.Unmarshal([]byte(body), &resp)
+	if code != 200 || len(resp.Data) != 3 || resp.Data["page-size"] != float64(20) {
+		t.Errorf("evaluate = %d %s", code, body)
+	}
+
+	if code, body := do("PUT", "/admin/flags/dark-mode", `{"type":"bool","enabled":true,"default":false,"variants":[{"value":true,"weight":100}]}`); code != 200 || !strings.Contains(body, `"key":"dark-mode"`) {
+		t.Errorf("put = %d %s", code, body)
+	}
+	if _, body := do("GET", "/flags", "", "7"); !strings.Contains(body, `"dark-mode":true`) {
+		t.Errorf("after put: %s", body)
+	}
+	if code, _ := do("PUT", "/admin/flags/dark-mode", `{"type":"bool","default":"no"}`); code != 400 {
+		t.Errorf("invalid put = %d", code)
+	}
+	if code, _ := do("DELETE", "/admin/flags/nope", ""); code != 404 {
+		t.Errorf("delete missing = %d", code)
+	}
+	if code, body := do("GET", "/admin/flags", ""); code != 200 || strings.Index(body, "checkout-button") > strings.Index(body, "dark-mode") {
+		t.Errorf("list = %d %s", code, body)
+	}
+}
@@ -0,0 +1,100 @@
+package featureflag
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/policy"
+	"go-one/response"
+)
+
+// contextKey 求值器在 gin.Context 中的键
+const contextKey = "featureflag"
+
+// Middleware 为每个请求取一份快照，按当前用户（JWT 中间件设置的 user_id）创建求值器
+// 要放在认证中间件之后，否则所有请求都按匿名用户求值
+func (s *Service) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(contextKey, s.ForUser(policy.SubjectFromContext(c).UserID))
+		c.Next()
+	}
+}
+
+// FromContext 取出求值器；没有经过 Middleware 时返回零值求值器，所有方法返回 fallback
+func FromContext(c *gin.Context) *Evaluator {
+	if v, ok := c.Get(contextKey); ok {
+		if e, ok := v.(*Evaluator); ok {
+			return e
+		}
+	}
+	return &Evaluator{}
+}
+
+// Register 注册给客户端用的接口，group 要经过 Middleware
+//
+//	GET /        当前用户看到的所有 flag 值
+//	GET /stream  SSE：flag 修改时推送 flag.updated / flag.deleted，收到后重新拉取 GET /
+func Register(group *gin.RouterGroup, s *Service) {
+	group.GET("", func(c *gin.Context) {
+		response.Success(c, FromContext(c).All())
+	})
+	group.GET("/stream", s.broker.Handler())
+}
+
+// RegisterAdmin 注册管理接口，调用方负责加管理员权限中间件
+//
+//	GET    /          所有 flag 定义
+//	GET    /:key      单个 flag
+//	PUT    /:key      创建或替换（请求体为 Flag，key 以路径为准）
+//	DELETE /:key      删除
+func RegisterAdmin(group *gin.RouterGroup, s *Service) {
+	group.GET("", func(c *gin.Context) {
+		response.Success(c, s.List())
+	})
+
+	group.GET("/:key", func(c *gin.Context) {
+		f, err := s.Get(c.Param("key"))
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, f)
+	})
+
+	group.PUT("/:key", func(c *gin.Context) {
+		var f Flag
+		if err := c.ShouldBindJSON(&f); err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		f.Key = c.Param("key")
+		saved, err := s.Set(c.Request.Context(), f)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, saved)
+	})
+
+	group.DELETE("/:key", func(c *gin.Context) {
+		key := c.Param("key")
+		if err := s.Delete(c.Request.Context(), key); err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, gin.H{"key": key, "deleted": true})
+	})
+}
+
+func abort(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		response.Error(c, http.StatusNotFound, "flag_not_found", "flag 不存在")
+	case errors.Is(err, ErrInvalid):
+		response.Error(c, http.StatusBadRequest, "invalid_flag", err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, "internal_error", "操作失败")
+	}
+}
@@ -0,0 +1,77 @@
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.yaml.in/yaml/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ============================================================================
+// 加载与存储
+// ============================================================================
+
+// ParseYAML 解析 YAML 定义（格式见包注释），逐个校验
+func ParseYAML(data []byte) ([]Flag, error) {
+	var doc struct {
+		Flags []Flag `yaml:"flags"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	seen := make(map[string]bool, len(doc.Flags))
+	for i := range doc.Flags {
+		f := &doc.Flags[i]
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[f.Key] {
+			return nil, fmt.Errorf("%w: duplicate key %q", ErrInvalid, f.Key)
+		}
+		seen[f.Key] = true
+	}
+	return doc.Flags, nil
+}
+
+// LoadFile 从 YAML 文件加载
+func LoadFile(path string) ([]Flag, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("featureflag: %w", err)
+	}
+	return ParseYAML(data)
+}
+
+// Store flag 的持久化
+type Store interface {
+	List(ctx context.Context) ([]Flag, error)
+	Save(ctx context.Context, f *Flag) error
+	Delete(ctx context.Context, key string) error
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 使用 feature_flags 表，需要事先 AutoMigrate(&featureflag.Flag{})
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) List(ctx context.Context) ([]Flag, error) {
+	var flags []Flag
+	err := s.db.WithContext(ctx).Order("flag_key").Find(&flags).Error
+	return flags, err
+}
+
+// Save 按 key upsert
+func (s *gormStore) Save(ctx context.Context, f *Flag) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(f).Error
+}
+
+func (s *gormStore) Delete(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("flag_key = ?", key).Delete(&Flag{}).Error
+}
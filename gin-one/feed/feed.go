@@ -0,0 +1,296 @@
+// ============================================================================
+// Package feed 生成 Atom / RSS 订阅源
+// ============================================================================
+//
+// 【两种格式】
+//
+// | 格式    | 规范            | Content-Type                      |
+// |---------|-----------------|-----------------------------------|
+// | Atom    | RFC 4287        | application/atom+xml; charset=utf-8 |
+// | RSS 2.0 | rssboard.org    | application/rss+xml; charset=utf-8  |
+//
+// 同一份 Feed/Entry 数据可以同时渲染成两种格式，
+// 由 Handler 层根据路由后缀（.atom / .rss）选择。
+//
+// 【用法】
+//
+//	f := &feed.Feed{Title: "最新文章", Link: cfg.Server.PublicURL + "/posts"}
+//	f.Entries = append(f.Entries, &feed.Entry{...})
+//	feed.Serve(c, f, feed.FormatAtom)
+//
+// 链接用配置的对外地址拼接，不要用请求的 Host 头：Host 可以被客户端伪造，
+// 订阅源又会被代理和阅读器缓存，伪造的链接会发给所有订阅者；在 TLS 终止代理后面协议也不对。
+//
+// ============================================================================
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"time"
+)
+
+// Format 订阅源格式
+type Format string
+
+const (
+	FormatAtom Format = "atom"
+	FormatRSS  Format = "rss"
+)
+
+// ContentType 返回格式对应的 MIME 类型
+func (f Format) ContentType() string {
+	switch f {
+	case FormatRSS:
+		return "application/rss+xml; charset=utf-8"
+	default:
+		return "application/atom+xml; charset=utf-8"
+	}
+}
+
+// ErrEmptyTitle 订阅源标题为空
+var ErrEmptyTitle = errors.New("feed: title is required")
+
+// Feed 订阅源（格式无关的中间表示）
+type Feed struct {
+	ID          string // Atom 要求的全局唯一 ID，为空时使用 Link
+	Title       string
+	Link        string // 网站/列表页地址
+	SelfLink    string // 订阅源自身地址
+	Description string
+	Author      string
+	Updated     time.Time // 为空时取所有条目中最新的时间
+	Entries     []*Entry
+}
+
+// Entry 订阅条目
+type Entry struct {
+	ID         string
+	Title      string
+	Link       string
+	Author     string
+	Content    string // HTML 内容，渲染前会经过 Sanitize
+	Published  time.Time
+	Updated    time.Time
+	Categories []string
+}
+
+// LastModified 返回订阅源的最后修改时间
+// 【用途】生成 Last-Modified 响应头、Atom <updated> 字段
+func (f *Feed) LastModified() time.Time {
+	latest := f.Updated
+	for _, e := range f.Entries {
+		if t := e.modified(); t.After(latest) {
+			latest = t
+		}
+	}
+	return latest.UTC().Truncate(time.Second)
+}
+
+func (e *Entry) modified() time.Time {
+	if e.Updated.After(e.Published) {
+		return e.Updated
+	}
+	return e.Published
+}
+
+// ============================================================================
+// Atom (RFC 4287)
+// ============================================================================
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  *atomPerson `xml:"author,omitempty"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Content    *atomContent   `xml:"content,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Atom 渲染为 Atom 1.0 文档
+func (f *Feed) Atom() ([]byte, error) {
+	if f.Title == "" {
+		return nil, ErrEmptyTitle
+	}
+
+	doc := atomFeed{
+		ID:      firstNonEmpty(f.ID, f.Link),
+		Title:   f.Title,
+		Updated: formatAtomTime(f.LastModified()),
+		Links:   []atomLink{{Rel: "alternate", Href: f.Link}},
+	}
+	if f.SelfLink != "" {
+		doc.Links = append(doc.Links, atomLink{Rel: "self", Type: FormatAtom.ContentType(), Href: f.SelfLink})
+	}
+	if f.Author != "" {
+		doc.Author = &atomPerson{Name: f.Author}
+	}
+
+	for _, e := range f.Entries {
+		entry := atomEntry{
+			ID:      firstNonEmpty(e.ID, e.Link),
+			Title:   e.Title,
+			Updated: formatAtomTime(e.modified()),
+			Links:   []atomLink{{Rel: "alternate", Href: e.Link}},
+		}
+		if !e.Published.IsZero() {
+			entry.Published = formatAtomTime(e.Published)
+		}
+		if e.Author != "" {
+			entry.Author = &atomPerson{Name: e.Author}
+		}
+		for _, term := range e.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: term})
+		}
+		if e.Content != "" {
+			// type="html"：内容按转义后的 HTML 文本输出
+			entry.Content = &atomContent{Type: "html", Body: Sanitize(e.Content)}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return marshal(doc)
+}
+
+// ============================================================================
+// RSS 2.0
+// ============================================================================
+
+type rssDoc struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr,omitempty"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      *atomLink `xml:"atom:link,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	Author      string   `xml:"author,omitempty"`
+	Categories  []string `xml:"category"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+	Description string   `xml:"description,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS 渲染为 RSS 2.0 文档
+func (f *Feed) RSS() ([]byte, error) {
+	if f.Title == "" {
+		return nil, ErrEmptyTitle
+	}
+
+	doc := rssDoc{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title: f.Title,
+			Link:  f.Link,
+			// description 是 RSS 2.0 的必填字段
+			Description:   firstNonEmpty(f.Description, f.Title),
+			LastBuildDate: formatRSSTime(f.LastModified()),
+		},
+	}
+	if f.SelfLink != "" {
+		doc.AtomNS = "http://www.w3.org/2005/Atom"
+		doc.Channel.AtomLink = &atomLink{Rel: "self", Type: FormatRSS.ContentType(), Href: f.SelfLink}
+	}
+
+	for _, e := range f.Entries {
+		item := rssItem{
+			Title:      e.Title,
+			Link:       e.Link,
+			GUID:       rssGUID{IsPermaLink: e.ID == "", Value: firstNonEmpty(e.ID, e.Link)},
+			Author:     e.Author,
+			Categories: e.Categories,
+		}
+		if !e.Published.IsZero() {
+			item.PubDate = formatRSSTime(e.Published)
+		}
+		if e.Content != "" {
+			item.Description = Sanitize(e.Content)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+
+	return marshal(doc)
+}
+
+// ============================================================================
+// 辅助函数
+// ============================================================================
+
+func marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// formatAtomTime Atom 使用 RFC 3339 时间格式
+func formatAtomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatRSSTime RSS 使用 RFC 822（RFC1123Z 是其四位年份的兼容写法）
+func formatRSSTime(t time.Time) string {
+	return t.UTC().Format(time.RFC1123Z)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,257 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 更新 golden 文件: go test ./feed -update
+var update = flag.Bool("update", false, "update golden files")
+
+func sampleFeed() *Feed {
+	published := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	return &Feed{
+		Title:       "最新文章",
+		Link:        "http://localhost:8080/posts",
+		SelfLink:    "http://localhost:8080/feeds/posts.atom",
+		Description: "Gin 学习笔记",
+		Author:      "gin-app",
+		Entries: []*Entry{
+			{
+				ID:         "tag:localhost,2024:posts/2",
+				Title:      "Hello GORM",
+				Link:       "http://localhost:8080/posts/2",
+				Author:     "zhangsan",
+				Content:    `<p>GORM is <b>great</b>!</p><script>alert(1)</script>`,
+				Published:  published.Add(time.Hour),
+				Updated:    published.Add(2 * time.Hour),
+				Categories: []string{"go", "gorm"},
+			},
+			{
+				ID:        "tag:localhost,2024:posts/1",
+				Title:     "Hello Gin",
+				Link:      "http://localhost:8080/posts/1",
+				Author:    "lisi",
+				Content:   `<a href="javascript:alert(1)" onclick="x()">link</a>`,
+				Published: published,
+			},
+		},
+	}
+}
+
+// assertGolden 比较输出与 testdata 下的 golden 文件
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s mismatch:\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+// ============================================================================
+// 【结构校验】
+// ============================================================================
+// 用最小结构体反序列化，检查规范中的必填元素
+// Atom: feed/id, feed/title, feed/updated, entry/id, entry/title, entry/updated
+// RSS:  channel/title, channel/link, channel/description, item 需有 title 或 description
+// ============================================================================
+
+func validateAtom(t *testing.T, data []byte) {
+	t.Helper()
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Title   string   `xml:"title"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Title   string `xml:"title"`
+			Updated string `xml:"updated"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid atom xml: %v", err)
+	}
+	if doc.ID == "" || doc.Title == "" {
+		t.Errorf("atom feed missing id/title")
+	}
+	if _, err := time.Parse(time.RFC3339, doc.Updated); err != nil {
+		t.Errorf("atom feed updated = %q; want RFC 3339", doc.Updated)
+	}
+	for i, e := range doc.Entries {
+		if e.ID == "" || e.Title == "" {
+			t.Errorf("entry[%d] missing id/title", i)
+		}
+		if _, err := time.Parse(time.RFC3339, e.Updated); err != nil {
+			t.Errorf("entry[%d] updated = %q; want RFC 3339", i, e.Updated)
+		}
+	}
+}
+
+func validateRSS(t *testing.T, data []byte) {
+	t.Helper()
+	var doc struct {
+		XMLName xml.Name `xml:"rss"`
+		Version string   `xml:"version,attr"`
+		Channel struct {
+			Title string `xml:"title"`
+			// atom:link 的本地名也是 link，需要按命名空间区分
+			Links []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:"link"`
+			Description string `xml:"description"`
+			Items       []struct {
+				Title       string `xml:"title"`
+				Description string `xml:"description"`
+				PubDate     string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid rss xml: %v", err)
+	}
+	if doc.Version != "2.0" {
+		t.Errorf("rss version = %q; want 2.0", doc.Version)
+	}
+	ch := doc.Channel
+	link := ""
+	for _, l := range ch.Links {
+		if l.XMLName.Space == "" {
+			link = l.Value
+		}
+	}
+	if ch.Title == "" || link == "" || ch.Description == "" {
+		t.Errorf("rss channel missing title/link/description")
+	}
+	for i, it := range ch.Items {
+		if it.Title == "" && it.Description == "" {
+			t.Errorf("item[%d] needs title or description", i)
+		}
+		if it.PubDate != "" {
+			if _, err := time.Parse(time.RFC1123Z, it.PubDate); err != nil {
+				t.Errorf("item[%d] pubDate = %q; want RFC 822", i, it.PubDate)
+			}
+		}
+	}
+}
+
+func TestAtomGolden(t *testing.T) {
+	got, err := sampleFeed().Atom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validateAtom(t, got)
+	assertGolden(t, "posts.atom.golden", got)
+}
+
+func TestRSSGolden(t *testing.T) {
+	f := sampleFeed()
+	f.SelfLink = "http://localhost:8080/feeds/posts.rss"
+	got, err := f.RSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validateRSS(t, got)
+	assertGolden(t, "posts.rss.golden", got)
+}
+
+func TestEmptyTitle(t *testing.T) {
+	if _, err := (&Feed{}).Atom(); err != ErrEmptyTitle {
+		t.Errorf("Atom() error = %v; want %v", err, ErrEmptyTitle)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+	}{
+		{"keeps allowed tags", "<p>hi <b>there</b></p>", "<p>hi <b>there</b></p>"},
+		{"drops script with body", "a<script>alert(1)</script>b", "ab"},
+		{"strips unknown tags", "<div><span>text</span></div>", "text"},
+		{"drops event attributes", `<a href="https://x.io" onclick="x()">x</a>`, `<a href="https://x.io">x</a>`},
+		{"drops javascript urls", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"escapes text", "1 < 2 & 3", "1 &lt; 2 &amp; 3"},
+		{"nested dropped tags", "<style>a{}<script>x</script></style>ok", "ok"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.input); got != tt.want {
+				t.Errorf("Sanitize(%q) = %q; want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeConditional(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/feed.atom", func(c *gin.Context) { Serve(c, sampleFeed(), FormatAtom) })
+	r.GET("/feed.rss", func(c *gin.Context) { Serve(c, sampleFeed(), FormatRSS) })
+
+	// 首次请求：200 + 缓存头
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/feed.atom", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d; want 200", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+		t.Errorf("Content-Type = %q; want application/atom+xml", ct)
+	}
+	etag := w.Header().Get("ETag")
+	lastModified := w.Header().Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("missing ETag/Last-Modified headers")
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"matching etag", "If-None-Match", etag, http.StatusNotModified},
+		{"weak etag", "If-None-Match", "W/" + etag, http.StatusNotModified},
+		{"stale etag", "If-None-Match", `"stale"`, http.StatusOK},
+		{"not modified since", "If-Modified-Since", lastModified, http.StatusNotModified},
+		{"modified since", "If-Modified-Since", "Mon, 01 Jan 2024 00:00:00 GMT", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/feed.atom", nil)
+			req.Header.Set(tt.header, tt.value)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d; want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/feed.rss", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/rss+xml") {
+		t.Errorf("Content-Type = %q; want application/rss+xml", ct)
+	}
+}
@@ -0,0 +1,80 @@
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// HTTP 输出（条件请求）
+// ============================================================================
+//
+// 【为什么订阅源需要缓存头？】
+//
+// 阅读器会周期性轮询订阅源（通常每 15~60 分钟），
+// 内容没变时返回 304 可以省掉绝大部分带宽。
+//
+// 【判断顺序】(RFC 7232)
+//
+// 1. 有 If-None-Match 时只比较 ETag
+// 2. 否则比较 If-Modified-Since 与 Last-Modified
+//
+// ============================================================================
+
+// Serve 渲染订阅源并写入响应，支持 ETag / Last-Modified 条件请求
+func Serve(c *gin.Context, f *Feed, format Format) {
+	var (
+		body []byte
+		err  error
+	)
+	if format == FormatRSS {
+		body, err = f.RSS()
+	} else {
+		body, err = f.Atom()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	modified := f.LastModified()
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=300")
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.Format(http.TimeFormat))
+	}
+
+	if notModified(c.Request, etag, modified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, format.ContentType(), body)
+}
+
+// notModified 判断是否可以返回 304
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !modified.After(t)
+	}
+	return false
+}
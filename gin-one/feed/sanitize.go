@@ -0,0 +1,137 @@
+package feed
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ============================================================================
+// HTML 清洗
+// ============================================================================
+//
+// 【为什么要清洗？】
+//
+// 文章内容由用户提交，订阅源会被第三方阅读器渲染。
+// 不清洗的话，<script>、onerror=... 等内容会变成存储型 XSS。
+//
+// 【策略：白名单】
+//
+// 1. 只保留白名单中的标签和属性，其余标签去掉（保留文本）
+// 2. <script>/<style> 等标签连同内容一起丢弃
+// 3. href/src 只允许 http、https、mailto 协议
+//
+// ============================================================================
+
+// allowedTags 允许的标签及其可保留的属性
+var allowedTags = map[string][]string{
+	"p": nil, "br": nil, "hr": nil,
+	"b": nil, "i": nil, "em": nil, "strong": nil, "u": nil, "s": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"ul": nil, "ol": nil, "li": nil,
+	"blockquote": nil, "code": nil, "pre": nil,
+	"a":   {"href", "title"},
+	"img": {"src", "alt", "title"},
+}
+
+// droppedTags 连同内容一起丢弃的标签
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"embed": true, "noscript": true, "template": true,
+}
+
+// allowedSchemes 链接允许的协议
+var allowedSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// Sanitize 按白名单清洗 HTML 片段
+func Sanitize(input string) string {
+	var out bytes.Buffer
+	z := html.NewTokenizer(strings.NewReader(input))
+	skipDepth := 0 // 处于被丢弃标签内部时 > 0
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// io.EOF 表示正常结束；其他错误时返回已清洗的部分
+			return out.String()
+		}
+
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[tok.Data] {
+				if tt == html.StartTagToken {
+					skipDepth++
+				}
+				continue
+			}
+			if skipDepth > 0 {
+				continue
+			}
+			attrs, ok := allowedTags[tok.Data]
+			if !ok {
+				continue
+			}
+			tok.Attr = filterAttrs(tok.Attr, attrs)
+			out.WriteString(tok.String())
+
+		case html.EndTagToken:
+			if droppedTags[tok.Data] {
+				if skipDepth > 0 {
+					skipDepth--
+				}
+				continue
+			}
+			if skipDepth > 0 {
+				continue
+			}
+			if _, ok := allowedTags[tok.Data]; ok {
+				out.WriteString(tok.String())
+			}
+
+		case html.TextToken:
+			if skipDepth == 0 {
+				out.WriteString(html.EscapeString(tok.Data))
+			}
+		}
+		// 注释、DOCTYPE 一律丢弃
+	}
+}
+
+// filterAttrs 只保留白名单属性，并校验链接协议
+func filterAttrs(attrs []html.Attribute, allowed []string) []html.Attribute {
+	var kept []html.Attribute
+	for _, a := range attrs {
+		if !contains(allowed, a.Key) {
+			continue
+		}
+		if (a.Key == "href" || a.Key == "src") && !safeURL(a.Val) {
+			continue
+		}
+		kept = append(kept, html.Attribute{Key: a.Key, Val: a.Val})
+	}
+	return kept
+}
+
+// safeURL 拒绝 javascript:、data: 等危险协议；相对路径视为安全
+func safeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		return true
+	}
+	return allowedSchemes[strings.ToLower(u.Scheme)]
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+// ============================================================================
+// Package files 按内容哈希去重的文件存储
+// ============================================================================
+//
+// 【去重原理】
+//
+// 文件内容决定 SHA-256，内容相同哈希就相同。上传时边读边算哈希，
+// 查 files 表里有没有同样的哈希：有就直接返回已有记录，不再写第二份。
+//
+//	上传 → 边读边算 SHA-256，同时写入本地临时文件
+//	     → files 表按 sha256 查询
+//	     → 已存在：删除临时文件，返回已有记录（Duplicate = true）
+//	     → 不存在：临时文件写入 Blob（key 由哈希决定），插入记录
+//
+// 为什么要先写临时文件？哈希要读完整个文件才知道，
+// 而 Blob 没有重命名操作，不能先写到临时 key 再挪到哈希对应的 key。
+//
+// 【对象 key】
+//
+//	<Prefix>/ab/abcdef0123...（完整哈希，前两位做目录，避免单个目录文件过多）
+//
+// 【并发上传同一个文件】
+//
+// 两个请求同时发现哈希不存在，都会写同一个 key（内容相同，覆盖无害），
+// 插入记录时 sha256 唯一索引只让一个成功，另一个 ON CONFLICT DO NOTHING 后重新查询，
+// 两个请求拿到的是同一条记录。
+//
+// 【注意】
+//
+// 去重是全局的：记录里的 OriginalName / OwnerID 是第一次上传时的值，
+// 需要"每个用户一份自己的文件名"时，再加一张引用表（user_id, file_id, name）。
+//
+// ============================================================================
+package files
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"go-one/pools"
+	"go-one/storage"
+)
+
+// 错误定义
+var (
+	ErrNotFound = errors.New("files: file not found")
+	ErrEmpty    = errors.New("files: empty file")
+)
+
+// File 文件元数据表 files，一份内容一条记录
+type File struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SHA256       string    `gorm:"size:64;not null;uniqueIndex" json:"sha256"`
+	Size         int64     `gorm:"not null" json:"size"`
+	MimeType     string    `gorm:"size:100" json:"mime_type"`
+	OriginalName string    `gorm:"size:255" json:"original_name"`
+	OwnerID      uint      `gorm:"index" json:"owner_id"`
+	Path         string    `gorm:"size:255;not null" json:"path"` // Blob 中的 key
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (File) TableName() string {
+	return "files"
+}
+
+// Config 去重存储配置
+type Config struct {
+	Prefix string // 对象 key 前缀，默认 objects
+	TmpDir string // 临时文件目录，默认 os.TempDir()
+}
+
+// Store 按内容哈希去重的文件存储
+type Store struct {
+	db   *gorm.DB
+	blob storage.Blob
+	cfg  Config
+}
+
+// New 创建去重存储，调用方负责 AutoMigrate(&files.File{})
+func New(db *gorm.DB, blob storage.Blob, cfg Config) *Store {
+	if cfg.Prefix == "" {
+		cfg.Prefix = "objects"
+	}
+	return &Store{db: db, blob: blob, cfg: cfg}
+}
+
+// Key 哈希对应的对象 key
+func (s *Store) Key(sum string) string {
+	return path.Join(s.cfg.Prefix, sum[:2], sum)
+}
+
+// Save 保存文件，内容已存在时返回已有记录，duplicate 为 true
+func (s *Store) Save(ctx context.Context, r io.Reader, name string, ownerID uint) (f *File, duplicate bool, err error) {
+	tmp, err := os.CreateTemp(s.cfg.TmpDir, "dedup-*")
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	// 一次读取同时完成：写临时文件、算哈希、留下开头 512 字节判断类型
+	h := sha256.New()
+	head := &headBuffer{limit: 512}
+	size, err := pools.Copy(io.MultiWriter(tmp, h, head), r)
+	if err != nil {
+		return nil, false, err
+	}
+	if size == 0 {
+		return nil, false, ErrEmpty
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	if existing, err := s.FindByHash(ctx, sum); err == nil {
+		return existing, true, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, false, err
+	}
+
+	mime := http.DetectContentType(head.buf)
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+	key := s.Key(sum)
+	if err := s.blob.Put(ctx, key, tmp, mime); err != nil {
+		return nil, false, err
+	}
+
+	f = &File{SHA256: sum, Size: size, MimeType: mime, OriginalName: path.Base(name), OwnerID: ownerID, Path: key}
+	res := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(f)
+	if res.Error != nil {
+		return nil, false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		// 并发上传的另一个请求先插入了
+		existing, err := s.FindByHash(ctx, sum)
+		return existing, true, err
+	}
+	return f, false, nil
+}
+
+// Get 按 ID 查询
+func (s *Store) Get(ctx context.Context, id uint) (*File, error) {
+	return s.first(ctx, "id = ?", id)
+}
+
+// FindByHash 按 SHA-256（小写十六进制）查询
+func (s *Store) FindByHash(ctx context.Context, sum string) (*File, error) {
+	return s.first(ctx, "sha256 = ?", sum)
+}
+
+func (s *Store) first(ctx context.Context, query string, arg any) (*File, error) {
+	var f File
+	err := s.db.WithContext(ctx).Where(query, arg).First(&f).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Open 读取文件内容，调用方负责 Close
+func (s *Store) Open(ctx context.Context, f *File) (io.ReadCloser, error) {
+	return s.blob.Get(ctx, f.Path)
+}
+
+// headBuffer 只保留写入内容的前 limit 个字节
+type headBuffer struct {
+	buf   []byte
+	limit int
+}
+
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if n := b.limit - len(b.buf); n > 0 {
+		b.buf = append(b.buf, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,113 @@
+package files
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-one/internal/testdb"
+	"go-one/storage"
+)
+
+func newStore(t *testing.T) *Store {
+	t.Helper()
+	db := testdb.Open(t, &File{})
+	blob, err := storage.NewLocal(t.TempDir(), "/files", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(db, blob, Config{TmpDir: t.TempDir()})
+}
+
+func TestSaveDedup(t *testing.T) {
+	ctx := context.Background()
+	s := newStore(t)
+	content := "%PDF-1.4 hello"
+	sum := sha256.Sum256([]byte(content))
+
+	tests := []struct {
+		name      string
+		content   string
+		filename  string
+		owner     uint
+		duplicate bool
+		wantName  string
+	}{
+		{"first upload", content, "report.pdf", 1, false, "report.pdf"},
+		{"same content, other name and owner", content, "copy.pdf", 2, true, "report.pdf"},
+		{"different content", "plain text", "a.txt", 2, false, "a.txt"},
+	}
+	var firstID uint
+	for _, tt := range tests {
+		f, dup, err := s.Save(ctx, strings.NewReader(tt.content), tt.filename, tt.owner)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if dup != tt.duplicate || f.OriginalName != tt.wantName {
+			t.Fatalf("%s: dup = %v, name = %q; want %v, %q", tt.name, dup, f.OriginalName, tt.duplicate, tt.wantName)
+		}
+		if firstID == 0 {
+			firstID = f.ID
+			if f.SHA256 != hex.EncodeToString(sum[:]) || f.MimeType != "application/pdf" || f.Size != int64(len(content)) {
+				t.Fatalf("metadata = %+v", f)
+			}
+		} else if tt.duplicate && f.ID != firstID {
+			t.Fatalf("%s: id = %d; want existing %d", tt.name, f.ID, firstID)
+		}
+	}
+
+	var count int64
+	s.db.Model(&File{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("rows = %d; want 2", count)
+	}
+
+	f, _ := s.Get(ctx, firstID)
+	rc, err := s.Open(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if got, _ := io.ReadAll(rc); string(got) != content {
+		t.Fatalf("content = %q", got)
+	}
+}
+
+func TestSaveConcurrentDuplicates(t *testing.T) {
+	s := newStore(t)
+	var wg sync.WaitGroup
+	ids := make([]uint, 8)
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, _, err := s.Save(context.Background(), strings.NewReader("same bytes"), "x.txt", 1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ids[i] = f.ID
+		}()
+	}
+	wg.Wait()
+	for _, id := range ids {
+		if id != ids[0] {
+			t.Fatalf("ids = %v; want all equal", ids)
+		}
+	}
+}
+
+func TestSaveErrors(t *testing.T) {
+	s := newStore(t)
+	if _, _, err := s.Save(context.Background(), strings.NewReader(""), "empty", 1); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("empty err = %v; want ErrEmpty", err)
+	}
+	if _, err := s.Get(context.Background(), 42); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get err = %v; want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,124 @@
+// ============================================================================
+// Package formats 多格式请求绑定与内容协商响应（JSON / XML / YAML / TOML）
+// ============================================================================
+//
+// 【Gin 自带的绑定】
+//
+// | Content-Type                          | 绑定器          | 读取的标签 |
+// |---------------------------------------|-----------------|------------|
+// | application/json                      | binding.JSON    | json       |
+// | application/xml、text/xml             | binding.XML     | xml        |
+// | application/yaml、application/x-yaml  | binding.YAML    | yaml，没有时用 json |
+// | application/toml                      | binding.TOML    | toml       |
+//
+// 所有绑定器解码后都会执行 binding 标签的校验，四种格式的校验规则写一次即可。
+// 注意字段名标签各管各的：只写了 json:"user_name" 时，XML 和 TOML 仍然按字段名 UserName 匹配。
+//
+// 【自定义绑定器：StrictTOML】
+//
+// binding.TOML 遇到结构体里没有的键直接忽略，配置类接口里拼错的键（tiemout = 5）
+// 会悄悄变成零值。StrictTOML 实现同一个 binding.BindingBody 接口，
+// 多余的键返回 *toml.StrictMissingError，其余行为（校验、BindBody）不变：
+//
+//	c.ShouldBindWith(&req, formats.StrictTOML)
+//	c.ShouldBindBodyWith(&req, formats.StrictTOML) // Body 需要读两次时
+//
+// 【内容协商】
+//
+// Render 按 Accept 从 Offered 中选择响应格式，没有 Accept 时返回 JSON，
+// 都不接受时返回 406；响应头带 Vary: Accept，缓存按 Accept 区分。
+//
+// ============================================================================
+package formats
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/pelletier/go-toml/v2"
+
+	"go-one/response"
+)
+
+// ErrUnsupportedMediaType 请求体的 Content-Type 不是支持的格式
+var ErrUnsupportedMediaType = errors.New("formats: unsupported media type")
+
+// Offered Render 支持的响应格式，第一个为默认格式
+var Offered = []string{binding.MIMEJSON, binding.MIMEXML, binding.MIMEYAML2, binding.MIMETOML}
+
+// StrictTOML 拒绝未知键的 TOML 绑定器
+var StrictTOML binding.BindingBody = strictTOML{}
+
+type strictTOML struct{}
+
+func (strictTOML) Name() string { return "toml-strict" }
+
+func (strictTOML) Bind(req *http.Request, obj any) error {
+	if req == nil || req.Body == nil {
+		return errors.New("formats: invalid request")
+	}
+	return decodeTOML(req.Body, obj)
+}
+
+func (strictTOML) BindBody(body []byte, obj any) error {
+	return decodeTOML(bytes.NewReader(body), obj)
+}
+
+func decodeTOML(r io.Reader, obj any) error {
+	dec := toml.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(obj); err != nil {
+		return err
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// For 按 Content-Type 选择请求体绑定器，TOML 使用 StrictTOML
+// 不支持的类型返回 ErrUnsupportedMediaType，而不是像 binding.Default 那样回落到表单
+func For(contentType string) (binding.BindingBody, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrUnsupportedMediaType
+	}
+	switch mediaType {
+	case binding.MIMEJSON:
+		return binding.JSON, nil
+	case binding.MIMEXML, binding.MIMEXML2:
+		return binding.XML, nil
+	case binding.MIMEYAML, binding.MIMEYAML2:
+		return binding.YAML, nil
+	case binding.MIMETOML:
+		return StrictTOML, nil
+	}
+	return nil, ErrUnsupportedMediaType
+}
+
+// Bind 按请求的 Content-Type 绑定请求体并校验
+func Bind(c *gin.Context, obj any) error {
+	b, err := For(c.GetHeader("Content-Type"))
+	if err != nil {
+		return err
+	}
+	return c.ShouldBindWith(obj, b)
+}
+
+// Render 按 Accept 选择 JSON / XML / YAML / TOML 写响应，都不接受时返回 406
+//
+// XML 不能直接编码 map（gin.H 除外），data 要用带 xml 标签的结构体
+func Render(c *gin.Context, code int, data any) {
+	c.Header("Vary", "Accept")
+	if c.NegotiateFormat(Offered...) == "" {
+		response.Abort(c, http.StatusNotAcceptable, "not_acceptable",
+			"supported formats: application/json, application/xml, application/yaml, application/toml")
+		return
+	}
+	c.Negotiate(code, gin.Negotiate{Offered: Offered, Data: data})
+}
@@ -0,0 +1,181 @@
+package formats
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// profile 四种格式的标签写在一起；Nickname 只有 json 标签
+type profile struct {
+	XMLName  xml.Name `json:"-" xml:"profile" yaml:"-" toml:"-"`
+	ID       int      `json:"id" xml:"id,attr" yaml:"id" toml:"id"`
+	Name     string   `json:"name" xml:"name" yaml:"name" toml:"name" binding:"required"`
+	Email    string   `json:"email" xml:"email" yaml:"email" toml:"email" binding:"required,email"`
+	Tags     []string `json:"tags" xml:"tags>tag" yaml:"tags" toml:"tags"`
+	Nickname string   `json:"nick_name"`
+}
+
+func bindBody(t *testing.T, contentType, body string) (profile, error) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", contentType)
+	var p profile
+	err := Bind(c, &p)
+	return p, err
+}
+
+func TestBindFormats(t *testing.T) {
+	tests := []struct {
+		name, contentType, body string
+	}{
+		{"json", "application/json", `{"id":7,"name":"alice","email":"a@example.com","tags":["go","gin"]}`},
+		{"xml", "application/xml; charset=utf-8",
+			`<profile id="7"><name>alice</name><email>a@example.com</email><tags><tag>go</tag><tag>gin</tag></tags></profile>`},
+		{"text xml", "text/xml",
+			`<profile id="7"><name>alice</name><email>a@example.com</email><tags><tag>go</tag><tag>gin</tag></tags></profile>`},
+		{"yaml", "application/yaml", "id: 7\nname: alice\nemail: a@example.com\ntags: [go, gin]\n"},
+		{"x-yaml", "application/x-yaml", "id: 7\nname: alice\nemail: a@example.com\ntags:\n  - go\n  - gin\n"},
+		{"toml", "application/toml", "id = 7\nname = \"alice\"\nemail = \"a@example.com\"\ntags = [\"go\", \"gin\"]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := bindBody(t, tt.contentType, tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.ID != 7 || p.Name != "alice" || p.Email != "a@example.com" || strings.Join(p.Tags, ",") != "go,gin" {
+				t.Errorf("got %+v", p)
+			}
+		})
+	}
+}
+
+// binding 标签对所有格式生效
+func TestBindValidatesEveryFormat(t *testing.T) {
+	bodies := map[string]string{
+		"application/json": `{"name":"alice","email":"bad"}`,
+		"application/xml":  `<profile><name>alice</name><email>bad</email></profile>`,
+		"application/yaml": "name: alice\nemail: bad\n",
+		"application/toml": "name = \"alice\"\nemail = \"bad\"\n",
+	}
+	for ct, body := range bodies {
+		_, err := bindBody(t, ct, body)
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) || verrs[0].Field() != "Email" {
+			t.Errorf("%s: err = %v; want Email validation error", ct, err)
+		}
+	}
+}
+
+// 每种格式只看自己的标签：nick_name 只在 json 标签里，YAML 没有 yaml 标签时也认 json 标签
+func TestFieldNameTags(t *testing.T) {
+	cases := []struct {
+		contentType, body, want string
+	}{
+		{"application/json", `{"name":"a","email":"a@example.com","nick_name":"al"}`, "al"},
+		{"application/yaml", "name: a\nemail: a@example.com\nnick_name: al\n", "al"},
+		{"application/xml", `<profile><name>a</name><email>a@example.com</email><nick_name>al</nick_name></profile>`, ""},
+		{"application/xml", `<profile><name>a</name><email>a@example.com</email><Nickname>al</Nickname></profile>`, "al"},
+	}
+	for _, tc := range cases {
+		p, err := bindBody(t, tc.contentType, tc.body)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.contentType, err)
+		}
+		if p.Nickname != tc.want {
+			t.Errorf("%s %s: Nickname = %q; want %q", tc.contentType, tc.body, p.Nickname, tc.want)
+		}
+	}
+}
+
+func TestStrictTOML(t *testing.T) {
+	body := "name = \"alice\"\nemail = \"a@example.com\"\nemial = \"typo\"\n"
+
+	// Gin 自带的 TOML 绑定器忽略拼错的键
+	var loose profile
+	if err := binding.TOML.BindBody([]byte(body), &loose); err != nil {
+		t.Fatalf("binding.TOML: %v", err)
+	}
+
+	var strict profile
+	err := StrictTOML.BindBody([]byte(body), &strict)
+	var missing *toml.StrictMissingError
+	if !errors.As(err, &missing) {
+		t.Fatalf("StrictTOML err = %v; want *toml.StrictMissingError", err)
+	}
+
+	// 通过 ShouldBindBodyWith 可以对同一个 Body 绑定两次
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name = \"bob\"\nemail = \"b@example.com\"\n"))
+	var a, b profile
+	if err := c.ShouldBindBodyWith(&a, StrictTOML); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ShouldBindBodyWith(&b, StrictTOML); err != nil {
+		t.Fatal(err)
+	}
+	if a.Name != "bob" || b.Name != "bob" {
+		t.Errorf("names = %q, %q; want bob twice", a.Name, b.Name)
+	}
+}
+
+func TestBindUnsupportedMediaType(t *testing.T) {
+	for _, ct := range []string{"", "text/plain", "application/x-www-form-urlencoded", "not a media type"} {
+		if _, err := bindBody(t, ct, "name=alice"); !errors.Is(err, ErrUnsupportedMediaType) {
+			t.Errorf("%q: err = %v; want ErrUnsupportedMediaType", ct, err)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/p", func(c *gin.Context) {
+		Render(c, http.StatusOK, profile{ID: 7, Name: "alice", Email: "a@example.com", Tags: []string{"go"}})
+	})
+
+	tests := []struct {
+		accept, wantType, wantBody string
+		wantCode                   int
+	}{
+		{"", "application/json", `"name":"alice"`, http.StatusOK},
+		{"application/json", "application/json", `"tags":["go"]`, http.StatusOK},
+		{"application/xml", "application/xml", `<profile id="7"><name>alice</name>`, http.StatusOK},
+		{"text/html, application/yaml;q=0.9", "application/yaml", "name: alice", http.StatusOK},
+		{"application/toml", "application/toml", "name = 'alice'", http.StatusOK},
+		{"*/*", "application/json", `"id":7`, http.StatusOK},
+		{"image/png", "application/json", "not_acceptable", http.StatusNotAcceptable},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/p", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.wantCode {
+			t.Errorf("Accept %q: status = %d; want %d", tt.accept, w.Code, tt.wantCode)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.wantType) {
+			t.Errorf("Accept %q: Content-Type = %q; want %s", tt.accept, ct, tt.wantType)
+		}
+		if !strings.Contains(w.Body.String(), tt.wantBody) {
+			t.Errorf("Accept %q: body = %s; want to contain %s", tt.accept, w.Body, tt.wantBody)
+		}
+		if w.Header().Get("Vary") != "Accept" {
+			t.Errorf("Accept %q: Vary = %q", tt.accept, w.Header().Get("Vary"))
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"go-one/grpcapi/userpb"
+	"go-one/response"
+)
+
+// JSON 字段名用 proto 里的 snake_case（create_time），和其他 Gin 接口一致；
+// 零值字段也输出，前端不用区分"没有这个字段"和"值为 0"
+var (
+	marshalJSON   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	unmarshalJSON = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// forwardHeaders 转发给 gRPC 服务的请求头，gRPC 端按 metadata 读取
+var forwardHeaders = []string{"Authorization", "X-Request-ID"}
+
+// RegisterGateway 在 rg 下注册 /users JSON 接口，每个请求转成一次 gRPC 调用
+//
+//	POST   /users      CreateUser
+//	GET    /users      ListUsers   ?page=&page_size=&page_token=&status=&keyword=
+//	GET    /users/:id  GetUser
+//	PATCH  /users/:id  UpdateUser  只修改请求体中出现的字段
+//	DELETE /users/:id  DeleteUser
+func RegisterGateway(rg gin.IRouter, client userpb.UserServiceClient) {
+	g := &gateway{client: client}
+	users := rg.Group("/users")
+	users.POST("", g.create)
+	users.GET("", g.list)
+	users.GET("/:id", g.get)
+	users.PATCH("/:id", g.update)
+	users.DELETE("/:id", g.delete)
+}
+
+type gateway struct {
+	client userpb.UserServiceClient
+}
+
+func (g *gateway) create(c *gin.Context) {
+	var req userpb.CreateUserRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	user, err := g.client.CreateUser(outgoing(c), &req)
+	reply(c, user, err)
+}
+
+func (g *gateway) get(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	user, err := g.client.GetUser(outgoing(c), &userpb.GetUserRequest{Id: id})
+	reply(c, user, err)
+}
+
+func (g *gateway) list(c *gin.Context) {
+	req := &userpb.ListUsersRequest{
+		PageToken: c.Query("page_token"),
+		Status:    c.Query("status"),
+		Keyword:   c.Query("keyword"),
+	}
+	for name, dst := range map[string]*int32{"page": &req.Page, "page_size": &req.PageSize} {
+		v := c.Query(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid_argument", "invalid "+name)
+			return
+		}
+		*dst = int32(n)
+	}
+	resp, err := g.client.ListUsers(outgoing(c), req)
+	reply(c, resp, err)
+}
+
+func (g *gateway) update(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	var req userpb.UpdateUserRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	req.Id = id // 以路径为准，忽略请求体里的 id
+	user, err := g.client.UpdateUser(outgoing(c), &req)
+	reply(c, user, err)
+}
+
+func (g *gateway) delete(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	_, err := g.client.DeleteUser(outgoing(c), &userpb.DeleteUserRequest{Id: id})
+	reply(c, nil, err)
+}
+
+// outgoing 把需要的请求头放进 gRPC metadata，客户端断开时 gRPC 调用也会取消
+//
+// 要用 c.Request.Context()：gin.Context 默认不会把 Done、Value 转给请求的 context
+func outgoing(c *gin.Context) context.Context {
+	md := metadata.MD{}
+	for _, h := range forwardHeaders {
+		if v := c.GetHeader(h); v != "" {
+			md.Set(h, v)
+		}
+	}
+	return metadata.NewOutgoingContext(c.Request.Context(), md)
+}
+
+func bindJSON(c *gin.Context, m proto.Message) bool {
+	body, err := io.ReadAll(c.Request.Body)
+	if err == nil {
+		err = unmarshalJSON.Unmarshal(body, m)
+	}
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid_argument", "invalid JSON body")
+		return false
+	}
+	return true
+}
+
+func pathID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.Error(c, http.StatusBadRequest, "invalid_argument", "invalid id")
+		return 0, false
+	}
+	return id, true
+}
+
+// reply 成功时用 protojson 编码放进 data，失败时按 gRPC 状态码选择 HTTP 状态码
+func reply(c *gin.Context, m proto.Message, err error) {
+	if err != nil {
+		st := status.Convert(err)
+		code, ok := httpCodes[st.Code()]
+		if !ok {
+			code = httpCodes[codes.Internal]
+		}
+		response.Error(c, code.status, code.name, st.Message())
+		return
+	}
+	if m == nil {
+		response.Success(c, nil)
+		return
+	}
+	b, err := marshalJSON.Marshal(m)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "internal", "encode response failed")
+		return
+	}
+	response.Success(c, json.RawMessage(b))
+}
+
+// httpCodes gRPC 状态码到 HTTP 状态码和错误码的对应关系，和 grpc-gateway 的约定一致
+var httpCodes = map[codes.Code]struct {
+	status int
+	name   string
+}{
+	codes.InvalidArgument:    {http.StatusBadRequest, "invalid_argument"},
+	codes.FailedPrecondition: {http.StatusBadRequest, "failed_precondition"},
+	codes.OutOfRange:         {http.StatusBadRequest, "out_of_range"},
+	codes.Unauthenticated:    {http.StatusUnauthorized, "unauthenticated"},
+	codes.PermissionDenied:   {http.StatusForbidden, "permission_denied"},
+	codes.NotFound:           {http.StatusNotFound, "not_found"},
+	codes.AlreadyExists:      {http.StatusConflict, "already_exists"},
+	codes.Aborted:            {http.StatusConflict, "aborted"},
+	codes.ResourceExhausted:  {http.StatusTooManyRequests, "resource_exhausted"},
+	codes.Canceled:           {499, "canceled"}, // 客户端断开，Nginx 的约定
+	codes.Unimplemented:      {http.StatusNotImplemented, "unimplemented"},
+	codes.Unavailable:        {http.StatusServiceUnavailable, "unavailable"},
+	codes.DeadlineExceeded:   {http.StatusGatewayTimeout, "deadline_exceeded"},
+	codes.Internal:           {http.StatusInternalServerError, "internal"},
+}
+
+// HTTPStatus gRPC 状态码对应的 HTTP 状态码，未列出的按 500 处理
+func HTTPStatus(code codes.Code) int {
+	if c, ok := httpCodes[code]; ok {
+		return c.status
+	}
+	return http.StatusInternalServerError
+}
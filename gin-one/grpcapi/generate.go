@@ -0,0 +1,8 @@
+package grpcapi
+
+// 需要 protoc、protoc-gen-go 和 protoc-gen-go-grpc：
+//
+//	go install google.golang.org/protobuf/cmd/protoc-gen-go@v1.36.11
+//	go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@v1.5.1
+//
+//go:generate protoc -I proto --go_out=. --go_opt=module=go-one/grpcapi --go-grpc_out=. --go-grpc_opt=module=go-one/grpcapi user/v1/user.proto
@@ -0,0 +1,456 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"go-one/diff"
+	"go-one/grpcapi/userpb"
+	"go-one/model"
+	"go-one/pagination"
+	"go-one/repository"
+	"go-one/service"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+// fakeUsers 内存版 repository.UserRepository
+type fakeUsers struct {
+	mu    sync.Mutex
+	users map[uint]*model.User
+	next  uint
+	fail  error // 非 nil 时所有方法返回这个错误
+}
+
+func (f *fakeUsers) Create(_ context.Context, u *model.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail != nil {
+		return f.fail
+	}
+	for _, x := range f.users {
+		if x.Username == u.Username || x.Email == u.Email {
+			return repository.ErrDuplicate
+		}
+	}
+	f.next++
+	u.ID, u.Status, u.CreatedAt, u.UpdatedAt = f.next, "active", time.Now(), time.Now()
+	f.users[u.ID] = u
+	return nil
+}
+
+func (f *fakeUsers) CreateBatch(ctx context.Context, users []*model.User) ([]bool, error) {
+	created := make([]bool, len(users))
+	for i, u := range users {
+		err := f.Create(ctx, u)
+		if err != nil && !errors.Is(err, repository.ErrDuplicate) {
+			return nil, err
+		}
+		created[i] = err == nil
+	}
+	return created, nil
+}
+
+func (f *fakeUsers) Get(_ context.Context, id uint) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail != nil {
+		return nil, f.fail
+	}
+	u, ok := f.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return u, nil
+}
+
+func (f *fakeUsers) Exists(ctx context.Context, id uint) (bool, error) {
+	_, err := f.Get(ctx, id)
+	return err == nil, nil
+}
+
+func (f *fakeUsers) List(_ context.Context, filter repository.UserFilter) ([]model.User, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []model.User
+	for id := uint(1); id <= f.next; id++ {
+		if u, ok := f.users[id]; ok {
+			out = append(out, *u)
+		}
+	}
+	total := int64(len(out))
+	out = out[min(filter.Offset, len(out)):]
+	return out[:min(filter.Limit, len(out))], total, nil
+}
+
+func (f *fakeUsers) Scroll(ctx context.Context, filter repository.UserFilter, after *pagination.Cursor) (pagination.Page[model.User], error) {
+	all, _, _ := f.List(ctx, repository.UserFilter{Limit: 1 << 30})
+	var rows []model.User
+	for _, u := range all {
+		if after == nil || u.ID > after.ID {
+			rows = append(rows, u)
+		}
+	}
+	rows = rows[:min(filter.Limit+1, len(rows))]
+	return pagination.NewPage(rows, filter.Limit, func(u model.User) pagination.Cursor { return pagination.Of(u.Model) }), nil
+}
+
+func (f *fakeUsers) Update(_ context.Context, id, _ uint, fields map[string]any) (*model.User, diff.Changes, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[id]
+	if !ok {
+		return nil, nil, repository.ErrNotFound
+	}
+	for k, v := range fields {
+		switch k {
+		case "username":
+			u.Username = v.(string)
+		case "email":
+			u.Email = v.(string)
+		case "age":
+			u.Age = v.(int)
+		case "status":
+			u.Status = v.(string)
+		}
+	}
+	return u, nil, nil
+}
+
+func (f *fakeUsers) Anonymize(_ context.Context, id uint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.users[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(f.users, id)
+	return nil
+}
+
+type plainHasher struct{}
+
+func (plainHasher) Hash(pw string) (string, error) { return "hashed:" + pw, nil }
+
+// newClient 用 bufconn 在内存里启动 gRPC 服务，返回客户端
+func newClient(t *testing.T, repo *fakeUsers) userpb.UserServiceClient {
+	t.Helper()
+	srv := NewServer(service.NewUserService(repo, plainHasher{}), Config{
+		Authenticate: JWT(secret),
+		Public:       []string{userpb.UserService_CreateUser_FullMethodName},
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return userpb.NewUserServiceClient(conn)
+}
+
+func newRepo() *fakeUsers {
+	return &fakeUsers{users: make(map[uint]*model.User)}
+}
+
+func token(t *testing.T, exp time.Duration) string {
+	t.Helper()
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1, "username": "alice", "exp": time.Now().Add(exp).Unix(),
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func withToken(t *testing.T) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token(t, time.Hour))
+}
+
+func TestUserServer(t *testing.T) {
+	repo := newRepo()
+	client := newClient(t, repo)
+	ctx := withToken(t)
+
+	alice, err := client.CreateUser(ctx, &userpb.CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "secret1", Age: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.GetId() == 0 || alice.GetStatus() != "active" || alice.GetCreateTime() == nil {
+		t.Fatalf("created user = %v", alice)
+	}
+	if repo.users[1].Password != "hashed:secret1" {
+		t.Fatal("password not hashed by service")
+	}
+
+	tests := []struct {
+		name string
+		call func() (proto.Message, error)
+		code codes.Code
+	}{
+		{"create duplicate", func() (proto.Message, error) {
+			return client.CreateUser(ctx, &userpb.CreateUserRequest{Username: "alice", Email: "a2@example.com", Password: "secret1"})
+		}, codes.AlreadyExists},
+		{"create invalid", func() (proto.Message, error) {
+			return client.CreateUser(ctx, &userpb.CreateUserRequest{Username: "al", Email: "bad", Password: "secret1"})
+		}, codes.InvalidArgument},
+		{"get", func() (proto.Message, error) {
+			return client.GetUser(ctx, &userpb.GetUserRequest{Id: alice.GetId()})
+		}, codes.OK},
+		{"get missing", func() (proto.Message, error) {
+			return client.GetUser(ctx, &userpb.GetUserRequest{Id: 99})
+		}, codes.NotFound},
+		{"get without id", func() (proto.Message, error) {
+			return client.GetUser(ctx, &userpb.GetUserRequest{})
+		}, codes.InvalidArgument},
+		{"update invalid status", func() (proto.Message, error) {
+			return client.UpdateUser(ctx, &userpb.UpdateUserRequest{Id: alice.GetId(), Status: proto.String("gone")})
+		}, codes.InvalidArgument},
+		{"list bad token", func() (proto.Message, error) {
+			return client.ListUsers(ctx, &userpb.ListUsersRequest{PageToken: "???"})
+		}, codes.InvalidArgument},
+		{"delete missing", func() (proto.Message, error) {
+			return client.DeleteUser(ctx, &userpb.DeleteUserRequest{Id: 99})
+		}, codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.call()
+			if got := status.Code(err); got != tt.code {
+				t.Fatalf("code = %v; want %v (%v)", got, tt.code, err)
+			}
+		})
+	}
+
+	t.Run("field violations", func(t *testing.T) {
+		_, err := client.CreateUser(ctx, &userpb.CreateUserRequest{Username: "al", Email: "bad", Password: "secret1"})
+		var fields []string
+		for _, d := range status.Convert(err).Details() {
+			if br, ok := d.(*errdetails.BadRequest); ok {
+				for _, v := range br.GetFieldViolations() {
+					fields = append(fields, v.GetField())
+				}
+			}
+		}
+		if strings.Join(fields, ",") != "username,email" {
+			t.Fatalf("violations = %v", fields)
+		}
+	})
+
+	t.Run("update zero value", func(t *testing.T) {
+		// optional 字段：设置为 0 也会更新，没设置的字段不变
+		u, err := client.UpdateUser(ctx, &userpb.UpdateUserRequest{Id: alice.GetId(), Age: proto.Int32(0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.GetAge() != 0 || u.GetEmail() != "alice@example.com" {
+			t.Fatalf("updated = %v", u)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		for _, name := range []string{"bob", "carol"} {
+			client.CreateUser(ctx, &userpb.CreateUserRequest{Username: name, Email: name + "@example.com", Password: "secret1"})
+		}
+		page, err := client.ListUsers(ctx, &userpb.ListUsersRequest{Page: 1, PageSize: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.GetUsers()) != 2 || page.GetTotalSize() != 3 {
+			t.Fatalf("page = %v", page)
+		}
+
+		var names []string
+		req := &userpb.ListUsersRequest{PageSize: 2}
+		for {
+			resp, err := client.ListUsers(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, u := range resp.GetUsers() {
+				names = append(names, u.GetUsername())
+			}
+			if resp.GetNextPageToken() == "" {
+				break
+			}
+			req.PageToken = resp.GetNextPageToken()
+		}
+		if strings.Join(names, ",") != "alice,bob,carol" {
+			t.Fatalf("scrolled %v", names)
+		}
+	})
+
+	t.Run("internal error hidden", func(t *testing.T) {
+		repo.fail = errors.New("dial tcp 10.0.0.5:3306: connection refused")
+		defer func() { repo.fail = nil }()
+		_, err := client.GetUser(ctx, &userpb.GetUserRequest{Id: 1})
+		if st := status.Convert(err); st.Code() != codes.Internal || st.Message() != "internal error" {
+			t.Fatalf("status = %v", st)
+		}
+	})
+}
+
+func TestAuth(t *testing.T) {
+	client := newClient(t, newRepo())
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{"no token", context.Background(), codes.Unauthenticated},
+		{"expired", metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token(t, -time.Minute)), codes.Unauthenticated},
+		{"not bearer", metadata.AppendToOutgoingContext(context.Background(), "authorization", token(t, time.Hour)), codes.Unauthenticated},
+		{"valid", withToken(t), codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.GetUser(tt.ctx, &userpb.GetUserRequest{Id: 1})
+			if got := status.Code(err); got != tt.code {
+				t.Fatalf("code = %v; want %v", got, tt.code)
+			}
+		})
+	}
+
+	// 注册接口不需要 token
+	if _, err := client.CreateUser(context.Background(), &userpb.CreateUserRequest{Username: "alice", Email: "a@example.com", Password: "secret1"}); err != nil {
+		t.Fatalf("public method: %v", err)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	intercept := Recovery(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	_, err := intercept(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/x/Y"}, func(context.Context, any) (any, error) {
+		panic("boom")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("code = %v; want Internal", status.Code(err))
+	}
+}
+
+func TestGateway(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterGateway(r, newClient(t, newRepo()))
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	auth := "Bearer " + token(t, time.Hour)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		auth   string
+		status int
+		want   string // 响应体里应该包含的内容
+	}{
+		{"create", http.MethodPost, "/users", `{"username":"alice","email":"alice@example.com","password":"secret1","age":20}`, "", 200, `"username":"alice"`},
+		{"create invalid json", http.MethodPost, "/users", `{"username":`, "", 400, `"error":"invalid_argument"`},
+		{"create invalid field", http.MethodPost, "/users", `{"username":"al","email":"x@example.com","password":"secret1"}`, "", 400, `invalid username`},
+		{"create duplicate", http.MethodPost, "/users", `{"username":"alice","email":"b@example.com","password":"secret1"}`, "", 409, `"error":"already_exists"`},
+		{"get without token", http.MethodGet, "/users/1", "", "", 401, `"error":"unauthenticated"`},
+		{"get", http.MethodGet, "/users/1", "", auth, 200, `"create_time":"`},
+		{"get missing", http.MethodGet, "/users/99", "", auth, 404, `"error":"not_found"`},
+		{"get bad id", http.MethodGet, "/users/abc", "", auth, 400, `invalid id`},
+		{"patch", http.MethodPatch, "/users/1", `{"age":0,"id":"99"}`, auth, 200, `"id":"1"`},
+		{"patch emits zero", http.MethodGet, "/users/1", "", auth, 200, `"age":0`},
+		{"list", http.MethodGet, "/users?page=1&page_size=10", "", auth, 200, `"total_size":"1"`},
+		{"list bad page", http.MethodGet, "/users?page=x", "", auth, 400, `invalid page`},
+		{"delete", http.MethodDelete, "/users/1", "", auth, 200, `"code":0`},
+		{"deleted", http.MethodGet, "/users/1", "", auth, 404, `not_found`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var body json.RawMessage
+			json.NewDecoder(resp.Body).Decode(&body)
+			if resp.StatusCode != tt.status || !strings.Contains(string(body), tt.want) {
+				t.Fatalf("got %d %s; want %d containing %s", resp.StatusCode, body, tt.status, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{codes.NotFound, 404},
+		{codes.AlreadyExists, 409},
+		{codes.Unauthenticated, 401},
+		{codes.Unavailable, 503},
+		{codes.DataLoss, 500},
+	}
+	for _, tt := range tests {
+		if got := HTTPStatus(tt.code); got != tt.want {
+			t.Errorf("HTTPStatus(%v) = %d; want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestGracefulStop(t *testing.T) {
+	release := make(chan struct{})
+	srv := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		// 模拟一个很慢的调用，Stop 取消 ctx 后返回
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return h(ctx, req)
+	}))
+	userpb.RegisterUserServiceServer(srv, NewUserServer(service.NewUserService(newRepo(), plainHasher{}), nil))
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	conn, _ := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	defer conn.Close()
+	defer close(release)
+
+	go userpb.NewUserServiceClient(conn).GetUser(context.Background(), &userpb.GetUserRequest{Id: 1})
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := GracefulStop(ctx, srv); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v; want DeadlineExceeded", err)
+	}
+}
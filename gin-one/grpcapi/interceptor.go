@@ -0,0 +1,100 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"go-one/ws"
+)
+
+// Authenticator 校验 token，返回错误时请求以 Unauthenticated 结束
+type Authenticator func(ctx context.Context, token string) (ws.Identity, error)
+
+// JWT 和 ws.JWT 相同的校验规则，同一个 Access Token 可以调用 HTTP、WebSocket 和 gRPC 接口
+func JWT(secret []byte) Authenticator {
+	return func(_ context.Context, token string) (ws.Identity, error) {
+		return ws.ParseJWT(secret, token)
+	}
+}
+
+type identityKey struct{}
+
+// IdentityFrom 取出 Auth 拦截器认证的身份
+func IdentityFrom(ctx context.Context) (ws.Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(ws.Identity)
+	return id, ok
+}
+
+// Token 从 metadata 的 authorization: Bearer <token> 中取 token
+// gRPC 的 metadata 键都是小写，对应 HTTP/2 头部
+func Token(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if tok, ok := strings.CutPrefix(v, "Bearer "); ok {
+			return strings.TrimSpace(tok)
+		}
+	}
+	return ""
+}
+
+// Auth 认证拦截器，public 中的方法（全名，如 /user.v1.UserService/CreateUser）不需要 token
+func Auth(authn Authenticator, public ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if slices.Contains(public, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		id, err := authn(ctx, Token(ctx))
+		if err != nil {
+			// 不把具体原因（过期、签名错误）告诉客户端
+			return nil, status.Error(codes.Unauthenticated, "invalid or missing token")
+		}
+		return handler(context.WithValue(ctx, identityKey{}, id), req)
+	}
+}
+
+// Logging 每个调用一条日志，Internal 等服务端错误用 Error 级别
+func Logging(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+
+		level := slog.LevelInfo
+		switch code {
+		case codes.OK, codes.NotFound, codes.AlreadyExists, codes.InvalidArgument, codes.Unauthenticated,
+			codes.PermissionDenied, codes.FailedPrecondition, codes.Canceled:
+		default:
+			level = slog.LevelError
+		}
+		attrs := []any{"method", info.FullMethod, "code", code.String(), "latency", time.Since(start)}
+		if id, ok := IdentityFrom(ctx); ok {
+			attrs = append(attrs, "user", id.UserID)
+		}
+		logger.Log(ctx, level, "grpc request", attrs...)
+		return resp, err
+	}
+}
+
+// Recovery handler panic 时返回 Internal，不让整个进程退出
+//
+// 和 Gin 的 Recovery 中间件一样，它要在拦截器链的最外层
+func Recovery(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				logger.ErrorContext(ctx, "grpc panic recovered", "method", info.FullMethod, "panic", p, "stack", string(debug.Stack()))
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,333 @@
+// ============================================================================
+// Package grpcapi 用户服务的 gRPC 接口和 JSON 网关
+// ============================================================================
+//
+// 【结构】
+//
+//	gRPC 客户端 ──────────────────────────────→ ┐
+//	                                             │ 拦截器（恢复 → 日志 → 认证）
+//	HTTP JSON → Gin → 网关 → UserServiceClient → ┘      ↓
+//	                                             UserServer → service.UserService → repository
+//
+// 业务规则只在 service 包里写一次：UserServer 只做 protobuf 和 service 参数之间的转换，
+// 网关只做 JSON 和 protobuf 之间的转换。接口定义见 proto/user/v1/user.proto，
+// userpb 是 protoc 生成的代码，不要手改。
+//
+// 【错误映射】
+//
+// | service 错误        | gRPC 状态码       | 网关 HTTP 状态码 |
+// |---------------------|-------------------|------------------|
+// | 参数校验失败        | InvalidArgument   | 400              |
+// | 缺少或无效的 token  | Unauthenticated   | 401              |
+// | ErrUserNotFound     | NotFound          | 404              |
+// | ErrUserExists       | AlreadyExists     | 409              |
+// | 其他                | Internal          | 500              |
+//
+// Internal 只返回 "internal error"，原始错误写日志，不暴露给客户端。
+// 参数校验失败时带 errdetails.BadRequest，客户端可以逐个字段显示错误。
+//
+// 【网关模式】
+//
+// | 部署方式         | gRPC 服务 | JSON 网关转发到        |
+// |------------------|-----------|------------------------|
+// | 单进程（默认）   | 本进程    | 本进程的 gRPC 端口     |
+// | 只做网关         | 不启动    | grpc.upstream 配置的地址 |
+//
+// 网关总是通过 gRPC 客户端调用，而不是直接调 UserServer：
+// 两种部署方式走同一条路径，认证、日志等拦截器对 JSON 请求同样生效。
+//
+// ============================================================================
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"go-one/grpcapi/userpb"
+	"go-one/model"
+	"go-one/pagination"
+	"go-one/service"
+)
+
+// Config gRPC 服务配置
+type Config struct {
+	// Authenticate 校验 metadata 中的 Bearer token，nil 表示不认证
+	Authenticate Authenticator
+
+	// Public 不需要认证的方法全名，如 userpb.UserService_CreateUser_FullMethodName（注册）
+	Public []string
+
+	// Reflection 注册反射服务，grpcurl 等工具不需要 .proto 文件就能调用；生产环境一般关闭
+	Reflection bool
+
+	// Logger 默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// NewServer 创建注册了用户服务和健康检查的 gRPC 服务，拦截器顺序：恢复 → 日志 → 认证
+//
+//	srv := grpcapi.NewServer(users, grpcapi.Config{Authenticate: grpcapi.JWT(secret)})
+//	lis, _ := net.Listen("tcp", ":9090")
+//	go srv.Serve(lis)
+//	defer srv.GracefulStop()
+func NewServer(users *service.UserService, cfg Config) *grpc.Server {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	interceptors := []grpc.UnaryServerInterceptor{Recovery(cfg.Logger), Logging(cfg.Logger)}
+	if cfg.Authenticate != nil {
+		// 健康检查给负载均衡器用，不带 token
+		public := append([]string{healthpb.Health_Check_FullMethodName}, cfg.Public...)
+		interceptors = append(interceptors, Auth(cfg.Authenticate, public...))
+	}
+
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	userpb.RegisterUserServiceServer(srv, NewUserServer(users, cfg.Logger))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	if cfg.Reflection {
+		reflection.Register(srv)
+	}
+	return srv
+}
+
+// GracefulStop 停止接收新调用并等待进行中的调用结束，ctx 到期后强制关闭并返回 ctx.Err()
+//
+// grpc.Server.GracefulStop 本身没有超时，和 server.OnShutdown 一起用：
+//
+//	srv.OnShutdown("grpc", func(ctx context.Context) error { return grpcapi.GracefulStop(ctx, grpcSrv) })
+func GracefulStop(ctx context.Context, srv *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		srv.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// UserServer 实现 userpb.UserServiceServer，业务逻辑交给 service.UserService
+type UserServer struct {
+	userpb.UnimplementedUserServiceServer
+
+	users    *service.UserService
+	validate *validator.Validate
+	logger   *slog.Logger
+}
+
+// NewUserServer 创建用户服务，logger 为 nil 时使用 slog.Default()
+func NewUserServer(users *service.UserService, logger *slog.Logger) *UserServer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &UserServer{users: users, validate: validator.New(), logger: logger}
+}
+
+// 校验规则和 Gin 接口的 binding 标签保持一致（见 examples/4_1_gorm_integration.go）
+type createRules struct {
+	Username string `validate:"required,min=3,max=50"`
+	Email    string `validate:"required,email"`
+	Password string `validate:"required,min=6"`
+	Age      int32  `validate:"gte=0,lte=150"`
+}
+
+type updateRules struct {
+	ID       uint64  `validate:"required"`
+	Username *string `validate:"omitempty,min=3,max=50"`
+	Email    *string `validate:"omitempty,email"`
+	Age      *int32  `validate:"omitempty,gte=0,lte=150"`
+	Status   *string `validate:"omitempty,oneof=active inactive banned"`
+}
+
+// CreateUser 创建用户
+func (s *UserServer) CreateUser(ctx context.Context, req *userpb.CreateUserRequest) (*userpb.User, error) {
+	if err := s.check(createRules{req.GetUsername(), req.GetEmail(), req.GetPassword(), req.GetAge()}); err != nil {
+		return nil, err
+	}
+	user, err := s.users.Create(ctx, service.CreateUserInput{
+		Username: req.GetUsername(),
+		Email:    req.GetEmail(),
+		Password: req.GetPassword(),
+		Age:      int(req.GetAge()),
+	})
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+	return toProto(user), nil
+}
+
+// GetUser 获取用户
+func (s *UserServer) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.User, error) {
+	id, err := userID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.users.Get(ctx, id)
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+	return toProto(user), nil
+}
+
+// ListUsers page > 0 时页码分页，否则游标分页
+func (s *UserServer) ListUsers(ctx context.Context, req *userpb.ListUsersRequest) (*userpb.ListUsersResponse, error) {
+	if req.GetPage() < 0 || req.GetPageSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page and page_size must not be negative")
+	}
+	in := service.ListUsersInput{
+		Page:     int(req.GetPage()),
+		PageSize: int(req.GetPageSize()),
+		Status:   req.GetStatus(),
+		Keyword:  req.GetKeyword(),
+	}
+
+	if req.GetPage() > 0 {
+		users, total, err := s.users.List(ctx, in)
+		if err != nil {
+			return nil, s.toStatus(ctx, err)
+		}
+		return &userpb.ListUsersResponse{Users: toProtoList(users), TotalSize: total}, nil
+	}
+
+	after, err := pagination.Decode(req.GetPageToken())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+	}
+	page, err := s.users.Scroll(ctx, in, after)
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+	return &userpb.ListUsersResponse{Users: toProtoList(page.Items), NextPageToken: page.NextCursor}, nil
+}
+
+// UpdateUser 只更新设置了的字段
+func (s *UserServer) UpdateUser(ctx context.Context, req *userpb.UpdateUserRequest) (*userpb.User, error) {
+	if err := s.check(updateRules{req.GetId(), req.Username, req.Email, req.Age, req.Status}); err != nil {
+		return nil, err
+	}
+	in := service.UpdateUserInput{Username: req.Username, Email: req.Email, Status: req.Status}
+	if req.Age != nil {
+		age := int(req.GetAge())
+		in.Age = &age
+	}
+	user, _, err := s.users.Update(ctx, uint(req.GetId()), in)
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+	return toProto(user), nil
+}
+
+// DeleteUser 注销用户
+func (s *UserServer) DeleteUser(ctx context.Context, req *userpb.DeleteUserRequest) (*emptypb.Empty, error) {
+	id, err := userID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.Delete(ctx, id); err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func userID(id uint64) (uint, error) {
+	if id == 0 {
+		return 0, status.Error(codes.InvalidArgument, "id is required")
+	}
+	return uint(id), nil
+}
+
+// check 校验失败时返回 InvalidArgument，附带每个字段的错误
+func (s *UserServer) check(rules any) error {
+	err := s.validate.Struct(rules)
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	br := &errdetails.BadRequest{}
+	for _, fe := range verrs {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       snake(fe.Field()),
+			Description: fmt.Sprintf("failed on %q", fe.Tag()),
+		})
+	}
+	msg := fmt.Sprintf("invalid %s", br.FieldViolations[0].Field)
+	if len(br.FieldViolations) > 1 {
+		msg += fmt.Sprintf(" and %d more", len(br.FieldViolations)-1)
+	}
+	st, _ := status.New(codes.InvalidArgument, msg).WithDetails(br)
+	return st.Err()
+}
+
+// snake 规则结构体的字段名转成 proto 字段名：Username → username，ID → id
+func snake(field string) string {
+	if field == "ID" {
+		return "id"
+	}
+	out := make([]byte, 0, len(field)+2)
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c >= 'A' && c <= 'Z' {
+			if i > 0 {
+				out = append(out, '_')
+			}
+			c += 'a' - 'A'
+		}
+		out = append(out, c)
+	}
+	return string(out)
+}
+
+// toStatus service 错误转成 gRPC 状态码，未知错误只写日志
+func (s *UserServer) toStatus(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrUserExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, service.ErrVersionConflict):
+		// Aborted：读-改-写冲突，客户端应从读取开始重试
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	s.logger.ErrorContext(ctx, "grpcapi: internal error", "error", err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+func toProto(u *model.User) *userpb.User {
+	return &userpb.User{
+		Id:         uint64(u.ID),
+		Username:   u.Username,
+		Email:      u.Email,
+		Age:        int32(u.Age),
+		Status:     u.Status,
+		CreateTime: timestamppb.New(u.CreatedAt),
+		UpdateTime: timestamppb.New(u.UpdatedAt),
+	}
+}
+
+func toProtoList(users []model.User) []*userpb.User {
+	out := make([]*userpb.User, len(users))
+	for i := range users {
+		out[i] = toProto(&users[i])
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// ============================================================================
+// 内置检查项
+// ============================================================================
+
+// DB 数据库连通性检查
+//
+//	sqlDB, _ := gormDB.DB()
+//	h.Register("database", health.DB(sqlDB))
+func DB(db *sql.DB) Checker {
+	return CheckerFunc(db.PingContext)
+}
+
+// Pinger 任何带 Ping 方法的客户端，go-redis 可以这样适配：
+//
+//	health.Ping(health.PingFunc(func(ctx context.Context) error {
+//		return rdb.Ping(ctx).Err()
+//	}))
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingFunc 函数适配器
+type PingFunc func(ctx context.Context) error
+
+// Ping 实现 Pinger
+func (f PingFunc) Ping(ctx context.Context) error { return f(ctx) }
+
+// Ping 用 Pinger 做连通性检查（Redis、消息队列等）
+func Ping(p Pinger) Checker {
+	return CheckerFunc(p.Ping)
+}
+
+// Goroutines goroutine 数量超过 max 时失败，常用来发现 goroutine 泄漏
+func Goroutines(max int) Checker {
+	return CheckerFunc(func(context.Context) error {
+		if n := runtime.NumGoroutine(); n > max {
+			return fmt.Errorf("too many goroutines: %d > %d", n, max)
+		}
+		return nil
+	})
+}
+
+// Ready 把一个布尔状态变成检查项，如 server.Server.Ready
+// 建议配合 NoCache 注册，状态变化能立即反映到 /readyz
+func Ready(ready func() bool) Checker {
+	return CheckerFunc(func(context.Context) error {
+		if !ready() {
+			return errors.New("not ready")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,15 @@
+//go:build !(linux || darwin)
+
+package health
+
+import (
+	"context"
+	"errors"
+)
+
+// DiskSpace 当前平台不支持，检查始终失败以提醒配置错误
+func DiskSpace(path string, minFree uint64) Checker {
+	return CheckerFunc(func(context.Context) error {
+		return errors.New("disk space check is not supported on this platform")
+	})
+}
@@ -0,0 +1,25 @@
+//go:build linux || darwin
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+)
+
+// DiskSpace path 所在文件系统的可用空间少于 minFree 字节时失败
+func DiskSpace(path string, minFree uint64) Checker {
+	return CheckerFunc(func(context.Context) error {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(path, &st); err != nil {
+			return err
+		}
+		// Bavail 是非 root 用户可用的块数，比 Bfree 更贴近应用实际能用的空间
+		free := uint64(st.Bavail) * uint64(st.Bsize)
+		if free < minFree {
+			return fmt.Errorf("low disk space on %s: %d MiB free, want >= %d MiB", path, free>>20, minFree>>20)
+		}
+		return nil
+	})
+}
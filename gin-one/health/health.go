@@ -0,0 +1,226 @@
+// ============================================================================
+// Package health 健康检查与就绪探针
+// ============================================================================
+//
+// 【两个探针的区别】
+//
+// | 接口     | K8s 探针        | 失败后果               | 检查内容                 |
+// |----------|-----------------|------------------------|--------------------------|
+// | /healthz | livenessProbe   | 重启容器               | 只看进程是否还能响应     |
+// | /readyz  | readinessProbe  | 从 Service 摘除，不重启 | 数据库、Redis、磁盘等依赖 |
+//
+// 数据库挂了不应该导致所有 Pod 被反复重启，所以依赖检查只放在 /readyz。
+//
+// 【缓存】
+//
+// 探针每几秒调用一次，多个副本 × 多个探针会给数据库带来不必要的压力。
+// 每个检查的结果缓存 TTL（默认 5s），过期后下一次请求才重新执行。
+//
+// 【输出】
+//
+//	{
+//	  "status": "fail",
+//	  "checks": {
+//	    "database": {"status": "ok", "latency_ms": 0.42},
+//	    "redis":    {"status": "fail", "latency_ms": 2000.1, "error": "context deadline exceeded"}
+//	  },
+//	  "checked_at": "2024-01-01T00:00:00Z"
+//	}
+//
+// ============================================================================
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 检查状态
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Checker 依赖检查，返回 nil 表示健康
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc 函数适配器
+type CheckerFunc func(ctx context.Context) error
+
+// Check 实现 Checker
+func (f CheckerFunc) Check(ctx context.Context) error { return f(ctx) }
+
+// Result 单项检查结果
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	Cached    bool    `json:"cached,omitempty"`
+}
+
+// Report 整体检查结果
+type Report struct {
+	Status    string            `json:"status"`
+	Checks    map[string]Result `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+// Config 默认配置
+type Config struct {
+	TTL     time.Duration // 结果缓存时间，默认 5s
+	Timeout time.Duration // 单项检查超时，默认 2s
+}
+
+// Option 单项检查的配置
+type Option func(*check)
+
+// WithTimeout 覆盖默认超时
+func WithTimeout(d time.Duration) Option {
+	return func(c *check) { c.timeout = d }
+}
+
+// WithTTL 覆盖默认缓存时间
+func WithTTL(d time.Duration) Option {
+	return func(c *check) { c.ttl = d }
+}
+
+// NoCache 每次都重新检查，适合开销极小的检查（如内存中的状态位）
+func NoCache() Option {
+	return func(c *check) { c.ttl = 0 }
+}
+
+type check struct {
+	name    string
+	checker Checker
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu       sync.Mutex
+	last     Result
+	lastTime time.Time
+}
+
+// Health 检查注册表
+type Health struct {
+	cfg    Config
+	mu     sync.RWMutex
+	checks []*check
+	now    func() time.Time
+}
+
+// New 创建检查注册表
+func New(cfg Config) *Health {
+	if cfg.TTL == 0 {
+		cfg.TTL = 5 * time.Second
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	return &Health{cfg: cfg, now: time.Now}
+}
+
+// Register 注册一项就绪检查
+func (h *Health) Register(name string, checker Checker, opts ...Option) {
+	c := &check{name: name, checker: checker, timeout: h.cfg.Timeout, ttl: h.cfg.TTL}
+	for _, opt := range opts {
+		opt(c)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, c)
+}
+
+// Check 并发执行所有检查（命中缓存的直接返回）
+func (h *Health) Check(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := append([]*check(nil), h.checks...)
+	h.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:    StatusOK,
+		Checks:    make(map[string]Result, len(checks)),
+		CheckedAt: h.now().UTC(),
+	}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// run 执行单项检查；同一项检查同一时间只会执行一次，并发请求等待同一个结果
+func (h *Health) run(ctx context.Context, c *check) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ttl > 0 && !c.lastTime.IsZero() && h.now().Sub(c.lastTime) < c.ttl {
+		res := c.last
+		res.Cached = true
+		return res
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.checker.Check(ctx)
+	res := Result{
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+
+	c.last, c.lastTime = res, h.now()
+	return res
+}
+
+// ============================================================================
+// HTTP 接口
+// ============================================================================
+
+// LivenessHandler /healthz：进程能响应就返回 200，不检查依赖
+func (h *Health) LivenessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": StatusOK})
+	}
+}
+
+// ReadinessHandler /readyz：所有检查通过返回 200，否则 503
+func (h *Health) ReadinessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := h.Check(c.Request.Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	}
+}
+
+// Mount 注册 /healthz 和 /readyz
+func (h *Health) Mount(r gin.IRoutes) {
+	r.GET("/healthz", h.LivenessHandler())
+	r.GET("/readyz", h.ReadinessHandler())
+}
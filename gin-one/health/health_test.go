@@ -0,0 +1,99 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 检查在 goroutine 中执行，但 Check 返回前会等待全部完成，这里不需要加锁
+	var dbErr error
+	dbCalls := 0
+
+	h := New(Config{TTL: time.Minute, Timeout: 50 * time.Millisecond})
+	now := time.Unix(1700000000, 0)
+	h.now = func() time.Time { return now }
+
+	h.Register("database", CheckerFunc(func(ctx context.Context) error {
+		dbCalls++
+		return dbErr
+	}))
+	h.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done() // 超时后返回
+		return ctx.Err()
+	}), WithTimeout(10*time.Millisecond), NoCache())
+	h.Register("goroutines", Goroutines(1<<20))
+
+	r := gin.New()
+	h.Mount(r)
+
+	get := func(path string) (int, Report) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var rep Report
+		json.Unmarshal(w.Body.Bytes(), &rep)
+		return w.Code, rep
+	}
+
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz = %d; want 200", code)
+	}
+
+	code, rep := get("/readyz")
+	if code != http.StatusServiceUnavailable || rep.Status != StatusFail {
+		t.Fatalf("/readyz = %d %s; want 503 fail", code, rep.Status)
+	}
+	if got := rep.Checks["slow"]; got.Status != StatusFail || got.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("slow = %+v; want deadline exceeded", got)
+	}
+	if got := rep.Checks["database"]; got.Status != StatusOK || got.Cached {
+		t.Errorf("database = %+v; want ok, not cached", got)
+	}
+
+	// TTL 内再次请求命中缓存，即使数据库已经出错
+	dbErr = errors.New("connection refused")
+	_, rep = get("/readyz")
+	if got := rep.Checks["database"]; got.Status != StatusOK || !got.Cached || dbCalls != 1 {
+		t.Errorf("database = %+v calls=%d; want cached ok, 1 call", got, dbCalls)
+	}
+
+	// 过期后重新检查
+	now = now.Add(time.Minute)
+	_, rep = get("/readyz")
+	if got := rep.Checks["database"]; got.Status != StatusFail || got.Error != "connection refused" {
+		t.Errorf("database = %+v; want fail: connection refused", got)
+	}
+}
+
+func TestReadyChecker(t *testing.T) {
+	var ready atomic.Bool
+	h := New(Config{})
+	h.Register("server", Ready(ready.Load), NoCache())
+
+	if rep := h.Check(context.Background()); rep.Status != StatusFail {
+		t.Errorf("status = %s; want fail", rep.Status)
+	}
+	ready.Store(true)
+	if rep := h.Check(context.Background()); rep.Status != StatusOK {
+		t.Errorf("status = %s; want ok", rep.Status)
+	}
+}
+
+func TestDiskSpace(t *testing.T) {
+	if err := DiskSpace(t.TempDir(), 1).Check(context.Background()); err != nil {
+		t.Errorf("DiskSpace(1 byte) = %v; want nil", err)
+	}
+	if err := DiskSpace(t.TempDir(), 1<<62).Check(context.Background()); err == nil {
+		t.Error("DiskSpace(4 EiB) = nil; want error")
+	}
+}
@@ -0,0 +1,31 @@
+package i18n
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// contextKey Localizer 在 gin.Context 中的键
+const contextKey = "i18n"
+
+// Middleware 按 Accept-Language 协商语言，Localizer 放进 gin.Context
+//
+// 响应带上 Content-Language，并声明 Vary: Accept-Language，缓存按语言分别保存
+func Middleware(b *Bundle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l := b.For(c.GetHeader("Accept-Language"))
+		c.Set(contextKey, l)
+		c.Header("Content-Language", l.tag.String())
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// FromContext 取出 Localizer；没有经过 Middleware 时返回 nil，方法照常可以调用（返回源文本）
+func FromContext(c *gin.Context) *Localizer {
+	if v, ok := c.Get(contextKey); ok {
+		if l, ok := v.(*Localizer); ok {
+			return l
+		}
+	}
+	return nil
+}
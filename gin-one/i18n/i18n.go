@@ -0,0 +1,366 @@
+// ============================================================================
+// Package i18n 接口提示的多语言：语言目录、Accept-Language 协商、复数、邮件模板
+// ============================================================================
+//
+// 代码里的提示（apperr 的默认提示、response.Error 的 message）都是中文，
+// 海外用户看到的也是中文。这里按请求的 Accept-Language 选一种语言，
+// 错误响应、字段校验提示和邮件按这种语言输出；代码里的中文仍然是源文本，不用改。
+//
+// 【语言目录】
+//
+// 一种语言一个文件，文件名就是语言标签（en.json、zh.toml、pt-BR.json），
+// 嵌套的键用点号拼起来，值里的 {name} 按参数替换：
+//
+//	{
+//	  "errors": {
+//	    "user_not_found": "User not found",
+//	    "validation_failed": {"one": "{count} field is invalid", "other": "{count} fields are invalid"}
+//	  },
+//	  "validation": {"min": "{field} must be at least {param} characters"}
+//	}
+//
+// 值是一个只包含 zero / one / two / few / many / other 的对象时为复数消息，
+// 按参数 count 和语言的 CLDR 规则选择（英语 1 用 one；中文只有 other；俄语还有 few / many）。
+//
+// 本包内置 locales/ 下的中英文目录（errors.<apperr 默认错误码>、validation.<校验规则>），
+// 应用的目录后加载，同名的键覆盖内置的。
+//
+// 【约定的键】
+//
+// | 键                   | 使用方                                | 参数            |
+// |----------------------|---------------------------------------|-----------------|
+// | errors.<错误码>      | response.Error / Abort、apperr 中间件 | count（字段数） |
+// | validation.<规则>    | apperr 中间件的 data.messages         | field, param    |
+// | validation.default   | 没有对应规则的翻译时                  | field, param    |
+//
+// 【用法】
+//
+//	//go:embed locales
+//	var locales embed.FS
+//
+//	bundle := i18n.NewBundle(i18n.Config{})
+//	if err := bundle.Load(locales, "locales"); err != nil { ... }
+//	r.Use(i18n.Middleware(bundle))
+//
+//	loc := i18n.FromContext(c)
+//	loc.T("cart.items", "count", 3)  // "3 items"
+//
+// 【源语言】
+//
+// Config.Default（默认 zh）是代码里文本的语言。请求协商到源语言时，
+// response 和 apperr 保留代码里的 message（handler 写的"token 已过期"比目录里通用的"请先登录"更具体）；
+// 其他语言在目录里有对应错误码时替换，没有时仍然返回源文本。
+//
+// ============================================================================
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/pelletier/go-toml/v2"
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// 错误定义
+var (
+	ErrFormat   = errors.New("i18n: unsupported catalog format")
+	ErrLanguage = errors.New("i18n: invalid language tag")
+	ErrTemplate = errors.New("i18n: template not found")
+)
+
+//go:embed locales
+var builtin embed.FS
+
+// Config Bundle 的配置
+type Config struct {
+	// Default 源语言，也是协商不到时使用的语言，默认 zh
+	Default string
+}
+
+// message 一条消息；plural 不为 nil 时按 count 选择
+type message struct {
+	text   string
+	plural map[plural.Form]string
+}
+
+// Bundle 所有语言的目录和邮件模板；启动时加载，之后可以并发使用
+type Bundle struct {
+	mu        sync.RWMutex
+	def       language.Tag
+	tags      []language.Tag // tags[0] 是源语言
+	matcher   language.Matcher
+	catalogs  map[language.Tag]map[string]message
+	templates map[language.Tag]*template.Template
+}
+
+// NewBundle 创建 Bundle 并加载内置目录；Default 不是合法的语言标签时 panic
+func NewBundle(cfg Config) *Bundle {
+	if cfg.Default == "" {
+		cfg.Default = "zh"
+	}
+	def := language.MustParse(cfg.Default)
+	b := &Bundle{
+		def:       def,
+		tags:      []language.Tag{def},
+		catalogs:  map[language.Tag]map[string]message{},
+		templates: map[language.Tag]*template.Template{},
+	}
+	b.matcher = language.NewMatcher(b.tags)
+	if err := b.Load(builtin, "locales"); err != nil {
+		panic(err) // 内置目录在测试里检查过
+	}
+	return b
+}
+
+// Load 加载 dir 下的目录文件（<语言>.json、<语言>.toml）和邮件模板（<语言>/*.tmpl，见 Render）
+func (b *Bundle) Load(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("i18n: %w", err)
+	}
+	for _, e := range entries {
+		name := path.Join(dir, e.Name())
+		if e.IsDir() {
+			if err := b.loadTemplates(fsys, name, e.Name()); err != nil {
+				return err
+			}
+			continue
+		}
+		ext := path.Ext(e.Name())
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("i18n: %w", err)
+		}
+		var messages map[string]any
+		switch ext {
+		case ".json":
+			err = json.Unmarshal(data, &messages)
+		case ".toml":
+			err = toml.Unmarshal(data, &messages)
+		default:
+			return fmt.Errorf("%w: %s", ErrFormat, name)
+		}
+		if err != nil {
+			return fmt.Errorf("i18n: parse %s: %w", name, err)
+		}
+		if err := b.AddMessages(strings.TrimSuffix(e.Name(), ext), messages); err != nil {
+			return fmt.Errorf("%w (%s)", err, name)
+		}
+	}
+	return nil
+}
+
+// AddMessages 添加一种语言的消息，结构和目录文件相同；已有的键被覆盖
+func (b *Bundle) AddMessages(lang string, messages map[string]any) error {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrLanguage, lang)
+	}
+	flat := map[string]message{}
+	if err := flatten("", messages, flat); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	catalog := b.catalogs[tag]
+	if catalog == nil {
+		catalog = map[string]message{}
+		b.catalogs[tag] = catalog
+		b.addTagLocked(tag)
+	}
+	for k, m := range flat {
+		catalog[k] = m
+	}
+	return nil
+}
+
+// addTagLocked 新的语言参与协商
+func (b *Bundle) addTagLocked(tag language.Tag) {
+	for _, t := range b.tags {
+		if t == tag {
+			return
+		}
+	}
+	b.tags = append(b.tags, tag)
+	b.matcher = language.NewMatcher(b.tags)
+}
+
+// forms 目录里复数分类的写法
+var forms = map[string]plural.Form{
+	"zero": plural.Zero, "one": plural.One, "two": plural.Two,
+	"few": plural.Few, "many": plural.Many, "other": plural.Other,
+}
+
+// flatten 嵌套的键拼成 errors.user_not_found；只含复数分类的对象是一条复数消息
+func flatten(prefix string, in map[string]any, out map[string]message) error {
+	for k, v := range in {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case string:
+			out[key] = message{text: v}
+		case map[string]any:
+			if m, ok := pluralMessage(v); ok {
+				out[key] = m
+				continue
+			}
+			if err := flatten(key, v, out); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: %s must be a string or an object", ErrFormat, key)
+		}
+	}
+	return nil
+}
+
+func pluralMessage(v map[string]any) (message, bool) {
+	if _, ok := v["other"].(string); !ok {
+		return message{}, false
+	}
+	m := message{plural: make(map[plural.Form]string, len(v))}
+	for k, text := range v {
+		form, ok := forms[k]
+		s, isString := text.(string)
+		if !ok || !isString {
+			return message{}, false
+		}
+		m.plural[form] = s
+	}
+	m.text = m.plural[plural.Other]
+	return m, true
+}
+
+// Languages 参与协商的语言，第一个是源语言
+func (b *Bundle) Languages() []language.Tag {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]language.Tag(nil), b.tags...)
+}
+
+// For 按偏好顺序选一种语言，参数可以是 Accept-Language 头（"en-US,en;q=0.9"）或语言标签
+func (b *Bundle) For(prefs ...string) *Localizer {
+	b.mu.RLock()
+	_, i := language.MatchStrings(b.matcher, prefs...)
+	tag := b.tags[i]
+	b.mu.RUnlock()
+	return &Localizer{b: b, tag: tag}
+}
+
+// Localizer 一种语言的翻译，由 Bundle.For 或 Middleware 创建
+//
+// 方法可以在 nil 上调用（没有经过 Middleware）：T 返回 key，Translate 返回 fallback
+type Localizer struct {
+	b   *Bundle
+	tag language.Tag
+}
+
+// Language 协商出的语言
+func (l *Localizer) Language() language.Tag {
+	if l == nil {
+		return language.Und
+	}
+	return l.tag
+}
+
+// IsSource 协商出的是源语言，代码里的文本不需要翻译
+func (l *Localizer) IsSource() bool {
+	return l == nil || l.tag == l.b.def
+}
+
+// Lookup 依次在协商出的语言、源语言的目录中查找 key；args 是键值对，如 "count", 3, "name", "Alice"
+func (l *Localizer) Lookup(key string, args ...any) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+	l.b.mu.RLock()
+	m, ok := l.b.catalogs[l.tag][key]
+	tag := l.tag
+	if !ok {
+		m, ok = l.b.catalogs[l.b.def][key]
+		tag = l.b.def
+	}
+	l.b.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	return m.format(tag, args), true
+}
+
+// T 翻译 key，找不到时返回 key 本身，页面上一眼能看出漏了哪条
+func (l *Localizer) T(key string, args ...any) string {
+	if s, ok := l.Lookup(key, args...); ok {
+		return s
+	}
+	return key
+}
+
+// Translate fallback 是代码里写的源语言文本：协商到源语言，或者当前语言的目录里没有 key 时，
+// 返回 fallback（同样替换参数）；不会退回源语言的目录
+func (l *Localizer) Translate(key, fallback string, args ...any) string {
+	if !l.IsSource() {
+		l.b.mu.RLock()
+		m, ok := l.b.catalogs[l.tag][key]
+		l.b.mu.RUnlock()
+		if ok {
+			return m.format(l.tag, args)
+		}
+	}
+	if len(args) == 0 {
+		return fallback
+	}
+	return interpolate(fallback, args)
+}
+
+func (m message) format(tag language.Tag, args []any) string {
+	text := m.text
+	if m.plural != nil {
+		if s, ok := m.plural[pluralForm(tag, args)]; ok {
+			text = s
+		}
+	}
+	return interpolate(text, args)
+}
+
+// pluralForm 按参数 count 的 CLDR 复数分类；没有 count 或不是整数时为 other
+func pluralForm(tag language.Tag, args []any) plural.Form {
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] != "count" {
+			continue
+		}
+		n, err := strconv.Atoi(fmt.Sprint(args[i+1]))
+		if err != nil {
+			return plural.Other
+		}
+		if n < 0 {
+			n = -n
+		}
+		return plural.Cardinal.MatchPlural(tag, n, 0, 0, 0, 0)
+	}
+	return plural.Other
+}
+
+// interpolate 把 {name} 替换为参数值；没有对应参数的占位符原样保留
+func interpolate(text string, args []any) string {
+	if len(args) < 2 || !strings.Contains(text, "{") {
+		return text
+	}
+	pairs := make([]string, 0, len(args))
+	for i := 0; i+1 < len(args); i += 2 {
+		pairs = append(pairs, "{"+fmt.Sprint(args[i])+"}", fmt.Sprint(args[i+1]))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
@@ -0,0 +1,161 @@
+package i18n
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// app 应用的目录：JSON、TOML 各一种语言，外加英文邮件模板
+var app = fstest.MapFS{
+	"locales/en.json": {Data: []byte(`{
+		"cart": {"items": {"one": "{count} item", "other": "{count} items"}},
+		"greeting": "Hello, {name}!",
+		"errors": {"not_found": "Nothing here"}
+	}`)},
+	"locales/ru.toml": {Data: []byte(`
+[cart.items]
+one = "{count} товар"
+few = "{count} товара"
+many = "{count} товаров"
+other = "{count} товара"
+`)},
+	"locales/en/mail.tmpl": {Data: []byte(`{{define "welcome.subject"}}Welcome, {{.Name}}{{end}}` +
+		`{{define "welcome.body"}}{{t "cart.items" "count" .Items}} waiting{{end}}`)},
+}
+
+func newBundle(t *testing.T) *Bundle {
+	t.Helper()
+	b := NewBundle(Config{})
+	if err := b.Load(app, "locales"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddMessages("zh", map[string]any{"cart": map[string]any{"items": map[string]any{"other": "{count} 件商品"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddTemplate("zh", `{{define "welcome.subject"}}欢迎，{{.Name}}{{end}}{{define "welcome.footer"}}退订{{end}}`); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestNegotiate(t *testing.T) {
+	b := newBundle(t)
+	tests := []struct {
+		prefs []string
+		want  string
+	}{
+		{[]string{"en-US,en;q=0.9"}, "en"},
+		{[]string{"de-DE,ru;q=0.8,en;q=0.5"}, "ru"},
+		{[]string{"zh-CN"}, "zh"},
+		{[]string{"fr"}, "zh"}, // 不支持的语言退回源语言
+		{[]string{""}, "zh"},
+		{[]string{"", "en"}, "en"},
+	}
+	for _, tt := range tests {
+		if got := b.For(tt.prefs...).Language().String(); got != tt.want {
+			t.Errorf("For(%q) = %s; want %s", tt.prefs, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	b := newBundle(t)
+	en, ru, zh := b.For("en"), b.For("ru"), b.For("zh")
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"interpolate", en.T("greeting", "name", "Alice"), "Hello, Alice!"},
+		{"plural one", en.T("cart.items", "count", 1), "1 item"},
+		{"plural other", en.T("cart.items", "count", 5), "5 items"},
+		{"plural few", ru.T("cart.items", "count", 3), "3 товара"},
+		{"plural many", ru.T("cart.items", "count", 11), "11 товаров"},
+		{"chinese has only other", zh.T("cart.items", "count", 1), "1 件商品"},
+		{"falls back to source catalog", ru.T("validation.required", "field", "email"), "email 不能为空"},
+		{"builtin overridden by app", en.T("errors.not_found"), "Nothing here"},
+		{"missing key", en.T("nope"), "nope"},
+		{"translate other language", en.Translate("errors.conflict", "资源冲突"), "Resource conflict"},
+		{"translate missing keeps source", ru.Translate("errors.conflict", "资源冲突"), "资源冲突"},
+		{"translate source language", zh.Translate("errors.unauthorized", "登录已过期"), "登录已过期"},
+		{"translate plural", en.Translate("errors.validation_failed", "", "count", 1), "1 field is invalid"},
+		{"nil localizer", (*Localizer)(nil).Translate("errors.conflict", "{n} 冲突", "n", 2), "2 冲突"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q; want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	b := NewBundle(Config{})
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want error
+	}{
+		{"format", fstest.MapFS{"l/en.yaml": {Data: []byte("a: b")}}, ErrFormat},
+		{"language", fstest.MapFS{"l/not a tag.json": {Data: []byte(`{}`)}}, ErrLanguage},
+		{"value type", fstest.MapFS{"l/en.json": {Data: []byte(`{"a": 1}`)}}, ErrFormat},
+	}
+	for _, tt := range tests {
+		if err := b.Load(tt.fsys, "l"); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v; want %v", tt.name, err, tt.want)
+		}
+	}
+	if err := b.Load(fstest.MapFS{"l/en.json": {Data: []byte(`{`)}}, "l"); err == nil {
+		t.Error("want parse error")
+	}
+}
+
+func TestRender(t *testing.T) {
+	b := newBundle(t)
+	data := map[string]any{"Name": "Alice", "Items": 2}
+
+	tests := []struct {
+		lang, name, want string
+	}{
+		{"en", "welcome.subject", "Welcome, Alice"},
+		{"en", "welcome.body", "2 items waiting"},
+		{"zh", "welcome.subject", "欢迎，Alice"},
+		{"en", "welcome.footer", "退订"}, // 英文没有这个模板，用源语言的
+	}
+	for _, tt := range tests {
+		got, err := b.For(tt.lang).Render(tt.name, data)
+		if err != nil || got != tt.want {
+			t.Errorf("Render(%s, %s) = %q, %v; want %q", tt.lang, tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := b.For("en").Render("missing", data); !errors.Is(err, ErrTemplate) {
+		t.Errorf("missing template err = %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(newBundle(t)))
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, FromContext(c).T("cart.items", "count", 1))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "en-GB,en;q=0.8")
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "1 item" || w.Header().Get("Content-Language") != "en" || w.Header().Get("Vary") != "Accept-Language" {
+		t.Errorf("response = %q %v", w.Body, w.Header())
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if l := FromContext(c); l != nil || l.T("greeting") != "greeting" {
+		t.Errorf("FromContext without middleware = %v", l)
+	}
+}
@@ -0,0 +1,97 @@
+package i18n
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+
+	"golang.org/x/text/language"
+)
+
+// 邮件模板
+//
+// 每种语言一组 text/template，用 define 命名，主题和正文分开定义：
+//
+//	locales/en/mail.tmpl
+//	{{define "reset_password.subject"}}Reset your password{{end}}
+//	{{define "reset_password.body"}}Hi {{.Name}},
+//	open the link below within {{t "duration.minutes" "count" .Minutes}}:
+//	{{.Link}}{{end}}
+//
+// 模板里的 t 就是 Localizer.T，短语可以和接口提示共用目录。
+// 当前语言没有某个模板时用源语言的，漏翻的邮件至少能发出去。
+
+// placeholder 解析时 t 还没有绑定到具体语言，执行前由 Render 替换
+var placeholder = template.FuncMap{"t": func(key string, _ ...any) string { return key }}
+
+// AddTemplate 把 text 中 define 的模板加入 lang 的模板集，同名的覆盖
+func (b *Bundle) AddTemplate(lang, text string) error {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrLanguage, lang)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := b.templateSetLocked(tag).Parse(text); err != nil {
+		return fmt.Errorf("i18n: %w", err)
+	}
+	return nil
+}
+
+// loadTemplates 解析 dir 下的 *.tmpl，目录名是语言
+func (b *Bundle) loadTemplates(fsys fs.FS, dir, lang string) error {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return fmt.Errorf("%w: %q (%s)", ErrLanguage, lang, dir)
+	}
+	files, err := fs.Glob(fsys, path.Join(dir, "*.tmpl"))
+	if err != nil || len(files) == 0 {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := b.templateSetLocked(tag).ParseFS(fsys, files...); err != nil {
+		return fmt.Errorf("i18n: %w", err)
+	}
+	return nil
+}
+
+func (b *Bundle) templateSetLocked(tag language.Tag) *template.Template {
+	set := b.templates[tag]
+	if set == nil {
+		set = template.New(tag.String()).Funcs(placeholder)
+		b.templates[tag] = set
+		b.addTagLocked(tag)
+	}
+	return set
+}
+
+// Render 用协商出的语言执行模板 name，没有时用源语言的模板
+func (l *Localizer) Render(name string, data any) (string, error) {
+	if l == nil {
+		return "", fmt.Errorf("%w: %s", ErrTemplate, name)
+	}
+	l.b.mu.RLock()
+	set := l.b.templates[l.tag]
+	if set == nil || set.Lookup(name) == nil {
+		set = l.b.templates[l.b.def]
+	}
+	if set == nil || set.Lookup(name) == nil {
+		l.b.mu.RUnlock()
+		return "", fmt.Errorf("%w: %s", ErrTemplate, name)
+	}
+	// 模板集是共享的，t 要绑定到这个请求的语言，所以每次复制一份
+	set, err := set.Clone()
+	l.b.mu.RUnlock()
+	if err != nil {
+		return "", fmt.Errorf("i18n: %w", err)
+	}
+
+	var sb strings.Builder
+	if err := set.Funcs(template.FuncMap{"t": l.T}).ExecuteTemplate(&sb, name, data); err != nil {
+		return "", fmt.Errorf("i18n: %w", err)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,327 @@
+// ============================================================================
+// Package imageproc 图片上传后处理：缩略图、去除 EXIF、WebP 转换
+// ============================================================================
+//
+// 【流程】
+//
+//	上传请求 → StripMetadata 去掉 EXIF（同步，原图存储前完成）
+//	        → blob.Put 原图
+//	        → Processor.Submit 放进队列，立即返回 Plan 给出的缩略图地址
+//	后台 worker → 读原图 → 按 EXIF 方向摆正 → 逐个尺寸居中裁剪缩放 → 编码写入 blob
+//	            → 记录到 image_variants 表
+//
+// 缩略图是异步生成的：响应里的地址在处理完成前访问会 404，
+// 客户端可以先显示原图，或者查询 Variants 确认已经生成。
+//
+// 【为什么去 EXIF 要同步？】
+//
+// 手机照片的 EXIF 里有 GPS 坐标、设备型号、拍摄时间。
+// 原图一旦带着 EXIF 写进存储就可能被下载，所以必须在写入前去掉，不能等后台任务。
+//
+// 【缩略图 key】
+//
+//	原图   images/2024/05/01/ab12_1714550400.jpg
+//	缩略图 images/2024/05/01/ab12_1714550400_thumb.jpg     100x100
+//	       images/2024/05/01/ab12_1714550400_medium.jpg    300x300
+//
+// 配置了 WebP 编码器时缩略图扩展名是 .webp。
+//
+// 【WebP】
+//
+// 标准库和 golang.org/x/image 只能解码 WebP，不能编码；编码需要 cgo 库，
+// 通过 Format 接口注入，没有配置时缩略图和原图格式一致（JPEG 仍是 JPEG，其他转 PNG）：
+//
+//	type webpFormat struct{}
+//	func (webpFormat) Encode(w io.Writer, img image.Image) error {
+//	    return webp.Encode(w, img, &webp.Options{Quality: 80}) // github.com/chai2010/webp
+//	}
+//	func (webpFormat) ContentType() string { return "image/webp" }
+//	func (webpFormat) Ext() string         { return ".webp" }
+//
+//	p := imageproc.New(blob, db, imageproc.Config{Format: webpFormat{}})
+//
+// ============================================================================
+package imageproc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // 注册 GIF 解码器
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	_ "golang.org/x/image/webp" // 注册 WebP 解码器
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"go-one/storage"
+)
+
+// 错误定义
+var (
+	ErrQueueFull   = errors.New("imageproc: queue is full")
+	ErrTooLarge    = errors.New("imageproc: image dimensions too large")
+	ErrUnsupported = errors.New("imageproc: unsupported image format")
+)
+
+// Size 缩略图尺寸，居中裁剪到 Width x Height
+type Size struct {
+	Name   string // 拼进 key 的后缀，如 thumb
+	Width  int
+	Height int
+}
+
+// DefaultSizes 默认生成 100x100 和 300x300 两种缩略图
+var DefaultSizes = []Size{
+	{Name: "thumb", Width: 100, Height: 100},
+	{Name: "medium", Width: 300, Height: 300},
+}
+
+// Format 缩略图的编码格式
+type Format interface {
+	Encode(w io.Writer, img image.Image) error
+	ContentType() string
+	Ext() string
+}
+
+// JPEG 返回指定质量（1~100）的 JPEG 格式
+func JPEG(quality int) Format {
+	return jpegFormat{quality: quality}
+}
+
+// PNG 无损格式，GIF / PNG / WebP 原图没有配置 Format 时使用
+var PNG Format = pngFormat{}
+
+type jpegFormat struct{ quality int }
+
+func (f jpegFormat) Encode(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: f.quality})
+}
+func (jpegFormat) ContentType() string { return "image/jpeg" }
+func (jpegFormat) Ext() string         { return ".jpg" }
+
+type pngFormat struct{}
+
+func (pngFormat) Encode(w io.Writer, img image.Image) error { return png.Encode(w, img) }
+func (pngFormat) ContentType() string                       { return "image/png" }
+func (pngFormat) Ext() string                               { return ".png" }
+
+// Config 处理器配置
+type Config struct {
+	Sizes     []Size        // 缩略图尺寸，默认 DefaultSizes
+	Format    Format        // 缩略图格式，nil 表示和原图一致
+	Quality   int           // JPEG 质量，默认 85
+	MaxPixels int           // 原图最大像素数，防止解压炸弹，默认 4000 万
+	Workers   int           // 后台 worker 数，默认 CPU 核数
+	Queue     int           // 队列长度，满了 Submit 返回 ErrQueueFull，默认 100
+	Timeout   time.Duration // 单张图片处理超时，默认 1 分钟
+	// Logger 默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Variant 缩略图记录表 image_variants
+type Variant struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	Source    string    `gorm:"size:255;not null;uniqueIndex:idx_image_variant" json:"-"` // 原图 key
+	Name      string    `gorm:"size:50;not null;uniqueIndex:idx_image_variant" json:"name"`
+	Key       string    `gorm:"size:255;not null" json:"key"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	MimeType  string    `gorm:"size:50" json:"mime_type"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (Variant) TableName() string {
+	return "image_variants"
+}
+
+// Processor 后台图片处理器
+type Processor struct {
+	blob   storage.Blob
+	db     *gorm.DB
+	cfg    Config
+	logger *slog.Logger
+	jobs   chan string
+}
+
+// New 创建处理器，db 为 nil 时不记录缩略图；调用方负责 AutoMigrate(&imageproc.Variant{})
+func New(blob storage.Blob, db *gorm.DB, cfg Config) *Processor {
+	if len(cfg.Sizes) == 0 {
+		cfg.Sizes = DefaultSizes
+	}
+	if cfg.Quality <= 0 || cfg.Quality > 100 {
+		cfg.Quality = 85
+	}
+	if cfg.MaxPixels <= 0 {
+		cfg.MaxPixels = 40_000_000
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.Queue <= 0 {
+		cfg.Queue = 100
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Minute
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Processor{blob: blob, db: db, cfg: cfg, logger: cfg.Logger, jobs: make(chan string, cfg.Queue)}
+}
+
+// format 缩略图格式：配置优先，否则 JPEG 原图出 JPEG，其他出 PNG
+func (p *Processor) format(sourceKey string) Format {
+	if p.cfg.Format != nil {
+		return p.cfg.Format
+	}
+	switch strings.ToLower(path.Ext(sourceKey)) {
+	case ".jpg", ".jpeg":
+		return JPEG(p.cfg.Quality)
+	}
+	return PNG
+}
+
+// Plan 原图对应的缩略图（只有 Name、Key、Width、Height），不读取图片
+func (p *Processor) Plan(sourceKey string) []Variant {
+	base := strings.TrimSuffix(sourceKey, path.Ext(sourceKey))
+	ext := p.format(sourceKey).Ext()
+	variants := make([]Variant, len(p.cfg.Sizes))
+	for i, s := range p.cfg.Sizes {
+		variants[i] = Variant{
+			Source: sourceKey,
+			Name:   s.Name,
+			Key:    base + "_" + s.Name + ext,
+			Width:  s.Width,
+			Height: s.Height,
+		}
+	}
+	return variants
+}
+
+// Submit 把原图放进处理队列，队列满时返回 ErrQueueFull 而不是阻塞请求
+func (p *Processor) Submit(sourceKey string) error {
+	select {
+	case p.jobs <- sourceKey:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run 启动 worker 处理队列，阻塞到 ctx 取消；正在处理的图片会处理完再返回，
+// 队列里剩下的丢弃（原图已经保存，之后可以重新 Submit）
+func (p *Processor) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range p.cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case key := <-p.jobs:
+					p.handle(context.WithoutCancel(ctx), key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *Processor) handle(ctx context.Context, key string) {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	variants, err := p.Process(ctx, key)
+	if err != nil {
+		p.logger.Error("process image", "key", key, "error", err)
+		return
+	}
+	p.logger.Info("image processed", "key", key, "variants", len(variants), "duration", time.Since(start))
+}
+
+// Process 同步生成原图的全部缩略图并记录，worker 和测试都用它
+func (p *Processor) Process(ctx context.Context, sourceKey string) ([]Variant, error) {
+	rc, err := p.blob.Get(ctx, sourceKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := p.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	format := p.format(sourceKey)
+	variants := p.Plan(sourceKey)
+	for i, s := range p.cfg.Sizes {
+		var buf bytes.Buffer
+		if err := format.Encode(&buf, thumbnail(img, s.Width, s.Height)); err != nil {
+			return nil, fmt.Errorf("imageproc: encode %s: %w", s.Name, err)
+		}
+		v := &variants[i]
+		v.MimeType = format.ContentType()
+		v.Size = int64(buf.Len())
+		if err := p.blob.Put(ctx, v.Key, &buf, v.MimeType); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.db != nil {
+		// 重新处理同一张图时覆盖旧记录
+		err := p.db.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "source"}, {Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"key", "width", "height", "mime_type", "size"}),
+		}).Create(&variants).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return variants, nil
+}
+
+// decode 先读尺寸拒绝过大的图片，再完整解码并按 EXIF 方向摆正
+func (p *Processor) decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if cfg.Width*cfg.Height > p.cfg.MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	return orient(img, Orientation(data)), nil
+}
+
+// Variants 查询原图已经生成的缩略图，没有记录说明还在处理或处理失败
+func (p *Processor) Variants(ctx context.Context, sourceKey string) ([]Variant, error) {
+	var variants []Variant
+	if p.db == nil {
+		return variants, nil
+	}
+	err := p.db.WithContext(ctx).Where("source = ?", sourceKey).Order("id").Find(&variants).Error
+	return variants, err
+}
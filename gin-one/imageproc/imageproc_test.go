@@ -0,0 +1,264 @@
+package imageproc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+
+	"go-one/internal/testdb"
+	"go-one/storage"
+)
+
+// testImage 左半红右半蓝，方便检查旋转方向
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			c := color.RGBA{255, 0, 0, 255}
+			if x >= w/2 {
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// withEXIF 在 SOI 后插入只含 Orientation 的 APP1 段
+func withEXIF(t *testing.T, jpg []byte, orientation uint16) []byte {
+	t.Helper()
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)                       // 1 个条目
+	tiff = append(tiff, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01) // 0x0112 SHORT x1
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0) // 值补齐 + 下一个 IFD 偏移
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+
+	seg := []byte{0xFF, 0xE1}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+	seg = append(seg, payload...)
+	return append(append([]byte{0xFF, 0xD8}, seg...), jpg[2:]...)
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOrientation(t *testing.T) {
+	plain := encodeJPEG(t, testImage(40, 20))
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", plain, 1},
+		{"rotate 90", withEXIF(t, plain, 6), 6},
+		{"rotate 180", withEXIF(t, plain, 3), 3},
+		{"invalid value", withEXIF(t, plain, 9), 1},
+		{"png", []byte("\x89PNG\r\n\x1a\n"), 1},
+	}
+	for _, tt := range tests {
+		if got := Orientation(tt.data); got != tt.want {
+			t.Errorf("%s: Orientation = %d; want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStripJPEG(t *testing.T) {
+	plain := encodeJPEG(t, testImage(40, 20))
+
+	// 方向为 1：字节级去除，压缩数据原样保留
+	out, err := StripMetadata(withEXIF(t, plain, 1), 85)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, plain) {
+		t.Fatalf("stripped jpeg differs from original without exif (%d vs %d bytes)", len(out), len(plain))
+	}
+
+	// 方向为 6：转正后重新编码，宽高互换，左边的红色转到上边
+	out, err = StripMetadata(withEXIF(t, plain, 6), 85)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Orientation(out) != 1 || bytes.Contains(out, []byte("Exif")) {
+		t.Fatal("exif still present")
+	}
+	img, err := jpeg.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+		t.Fatalf("size = %v; want 20x40", b.Size())
+	}
+	if r, _, bl, _ := img.At(10, 5).RGBA(); r < bl {
+		t.Fatalf("top should be red after rotating 90° clockwise")
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, testImage(8, 8))
+	data := buf.Bytes()
+
+	// 在 IEND 前插入 tEXt 块
+	text := []byte("tEXtComment\x00secret location")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)-4))
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(text))
+	iend := len(data) - 12
+	withText := append(append(append([]byte{}, data[:iend]...), chunk...), data[iend:]...)
+	if _, err := png.Decode(bytes.NewReader(withText)); err != nil {
+		t.Fatalf("test png invalid: %v", err)
+	}
+
+	out, err := StripMetadata(withText, 85)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Fatal("tEXt chunk not removed")
+	}
+}
+
+func TestOrient(t *testing.T) {
+	src := testImage(4, 2) // 左红右蓝
+	tests := []struct {
+		o          int
+		w, h       int
+		redX, redY int // 原来左上角的红色像素移到哪里
+	}{
+		{2, 4, 2, 3, 0},
+		{3, 4, 2, 3, 1},
+		{4, 4, 2, 0, 1},
+		{5, 2, 4, 0, 0},
+		{6, 2, 4, 1, 0},
+		{7, 2, 4, 1, 3},
+		{8, 2, 4, 0, 3},
+	}
+	for _, tt := range tests {
+		got := orient(src, tt.o)
+		if b := got.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("orient %d: size %v; want %dx%d", tt.o, b.Size(), tt.w, tt.h)
+			continue
+		}
+		if r, _, _, _ := got.At(tt.redX, tt.redY).RGBA(); r == 0 {
+			t.Errorf("orient %d: pixel (%d,%d) not red", tt.o, tt.redX, tt.redY)
+		}
+	}
+}
+
+func newProcessor(t *testing.T, cfg Config) (*Processor, storage.Blob) {
+	t.Helper()
+	db := testdb.Open(t, &Variant{})
+	blob, err := storage.NewLocal(t.TempDir(), "/files", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(blob, db, cfg), blob
+}
+
+func TestProcess(t *testing.T) {
+	ctx := context.Background()
+	p, blob := newProcessor(t, Config{})
+	key := "images/2024/05/01/a.jpg"
+	blob.Put(ctx, key, bytes.NewReader(encodeJPEG(t, testImage(400, 300))), "image/jpeg")
+
+	variants, err := p.Process(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"images/2024/05/01/a_thumb.jpg": 100, "images/2024/05/01/a_medium.jpg": 300}
+	for _, v := range variants {
+		rc, err := blob.Get(ctx, v.Key)
+		if err != nil {
+			t.Fatalf("variant %s: %v", v.Key, err)
+		}
+		cfg, err := jpeg.DecodeConfig(rc)
+		rc.Close()
+		if err != nil || cfg.Width != want[v.Key] || cfg.Height != want[v.Key] {
+			t.Fatalf("variant %s = %dx%d, %v", v.Key, cfg.Width, cfg.Height, err)
+		}
+	}
+
+	// 重新处理不会产生重复记录
+	p.Process(ctx, key)
+	recorded, err := p.Variants(ctx, key)
+	if err != nil || len(recorded) != 2 || recorded[0].MimeType != "image/jpeg" || recorded[0].Size == 0 {
+		t.Fatalf("Variants = %+v, %v", recorded, err)
+	}
+}
+
+func TestProcessErrors(t *testing.T) {
+	ctx := context.Background()
+	p, blob := newProcessor(t, Config{MaxPixels: 100})
+	var buf bytes.Buffer
+	png.Encode(&buf, testImage(20, 20))
+	blob.Put(ctx, "big.png", &buf, "image/png")
+	blob.Put(ctx, "note.txt", bytes.NewReader([]byte("not an image")), "text/plain")
+
+	tests := []struct {
+		key  string
+		want error
+	}{
+		{"big.png", ErrTooLarge},
+		{"note.txt", ErrUnsupported},
+		{"missing.png", storage.ErrNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := p.Process(ctx, tt.key); !errors.Is(err, tt.want) {
+			t.Errorf("Process(%s) err = %v; want %v", tt.key, err, tt.want)
+		}
+	}
+}
+
+func TestRunAndSubmit(t *testing.T) {
+	p, blob := newProcessor(t, Config{Workers: 2, Queue: 1, Sizes: []Size{{Name: "s", Width: 10, Height: 10}}})
+	var buf bytes.Buffer
+	png.Encode(&buf, testImage(30, 30))
+	blob.Put(context.Background(), "a.png", &buf, "image/png")
+
+	if plan := p.Plan("a.png"); len(plan) != 1 || plan[0].Key != "a_s.png" {
+		t.Fatalf("Plan = %+v", plan)
+	}
+	// 没有 worker 时队列长度为 1，第二个放不进去
+	if err := p.Submit("a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Submit("a.png"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Submit err = %v; want ErrQueueFull", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		v, _ := p.Variants(context.Background(), "a.png")
+		if len(v) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("variant not generated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
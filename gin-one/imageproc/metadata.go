@@ -0,0 +1,225 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"image/jpeg"
+)
+
+// ============================================================================
+// 元数据
+// ============================================================================
+//
+// 【JPEG 结构】
+//
+//	FFD8 | FFE0 len APP0(JFIF) | FFE1 len APP1(EXIF/XMP) | ... | FFDA SOS 压缩数据 ... | FFD9
+//
+// 去 EXIF 只需要跳过 APP1 / APP13 / COM 段，其余字节原样复制，不重新压缩、不损失画质。
+// APP2（ICC 色彩配置）和 APP14（Adobe 颜色变换）要保留，去掉会让颜色显示不对。
+//
+// 【方向】
+//
+// 手机竖着拍的照片像素其实是横着存的，靠 EXIF Orientation 告诉看图软件要转 90 度。
+// 去掉 EXIF 后照片就"躺倒"了，所以 Orientation 不是 1 时先按方向转正再重新编码。
+//
+// 【PNG】
+//
+// PNG 由 长度 | 类型 | 数据 | CRC 的块组成，去掉 eXIf、tEXt、zTXt、iTXt、tIME 块即可。
+//
+
+// jpegDropped 去掉的 JPEG 段
+var jpegDropped = map[byte]bool{
+	0xE1: true, // APP1：EXIF、XMP
+	0xED: true, // APP13：IPTC、Photoshop
+	0xFE: true, // COM：注释
+}
+
+// pngDropped 去掉的 PNG 块
+var pngDropped = map[string]bool{
+	"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true,
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// StripMetadata 去掉 JPEG / PNG 中的 EXIF 等元数据，其他格式原样返回
+//
+// JPEG 带方向信息时会转正后以 quality 重新编码，其余情况都是无损的字节级处理。
+func StripMetadata(data []byte, quality int) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		if o := Orientation(data); o > 1 {
+			img, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			var buf bytes.Buffer
+			err = jpeg.Encode(&buf, orient(img, o), &jpeg.Options{Quality: quality})
+			return buf.Bytes(), err
+		}
+		return stripJPEG(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return stripPNG(data)
+	}
+	return data, nil
+}
+
+// jpegSegments 依次回调 SOS 之前的每个段，seg 包含标记和长度；返回 SOS 开始的位置
+func jpegSegments(data []byte, fn func(marker byte, seg []byte)) (int, error) {
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 0, ErrUnsupported
+		}
+		marker := data[i+1]
+		if marker == 0xFF { // 填充字节
+			i++
+			continue
+		}
+		if marker == 0xDA {
+			return i, nil
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		if n < 2 || i+2+n > len(data) {
+			return 0, ErrUnsupported
+		}
+		fn(marker, data[i:i+2+n])
+		i += 2 + n
+	}
+	return 0, ErrUnsupported
+}
+
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	sos, err := jpegSegments(data, func(marker byte, seg []byte) {
+		if !jpegDropped[marker] {
+			out = append(out, seg...)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[sos:]...), nil
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	for i := len(pngSignature); i < len(data); {
+		if i+12 > len(data) {
+			return nil, ErrUnsupported
+		}
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + n
+		if n < 0 || end > len(data) {
+			return nil, ErrUnsupported
+		}
+		if !pngDropped[string(data[i+4:i+8])] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
+
+// Orientation 读取 JPEG EXIF 中的方向（1~8），没有或解析失败时返回 1
+func Orientation(data []byte) int {
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return 1
+	}
+	o := 1
+	jpegSegments(data, func(marker byte, seg []byte) {
+		if marker == 0xE1 && bytes.HasPrefix(seg[4:], []byte("Exif\x00\x00")) {
+			if v := exifOrientation(seg[10:]); v > 0 {
+				o = v
+			}
+		}
+	})
+	return o
+}
+
+// exifOrientation 解析 TIFF 结构，在 IFD0 中查找 0x0112 标签
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := range count {
+		e := ifd + 2 + i*12
+		if e+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[e:]) == 0x0112 {
+			if v := int(order.Uint16(tiff[e+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// orient 按 EXIF 方向转正图片
+//
+// | 值 | 含义                 | 像素 (x, y) 移到            |
+// |----|----------------------|-----------------------------|
+// | 2  | 水平翻转             | (w-1-x, y)                  |
+// | 3  | 旋转 180°            | (w-1-x, h-1-y)              |
+// | 4  | 垂直翻转             | (x, h-1-y)                  |
+// | 5  | 转置                 | (y, x)                      |
+// | 6  | 顺时针 90°           | (h-1-y, x)                  |
+// | 7  | 反转置               | (h-1-y, w-1-x)              |
+// | 8  | 逆时针 90°           | (y, w-1-x)                  |
+func orient(img image.Image, o int) image.Image {
+	if o <= 1 || o > 8 {
+		return img
+	}
+	b := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := range h {
+		for x := range w {
+			var dx, dy int
+			switch o {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[src.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
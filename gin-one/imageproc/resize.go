@@ -0,0 +1,30 @@
+package imageproc
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// thumbnail 居中裁剪到目标宽高比后缩放到 width x height
+//
+// 例如 400x300 的图片生成 100x100 缩略图：先取中间 300x300，再缩小到 100x100，
+// 不会拉伸变形。CatmullRom 插值比最近邻慢，但缩小时不会出现锯齿。
+func thumbnail(img image.Image, width, height int) image.Image {
+	b := img.Bounds()
+	crop := b
+	// 比较 w/h 和 width/height，交叉相乘避免浮点误差
+	if b.Dx()*height > b.Dy()*width {
+		w := b.Dy() * width / height
+		crop.Min.X = b.Min.X + (b.Dx()-w)/2
+		crop.Max.X = crop.Min.X + w
+	} else {
+		h := b.Dx() * height / width
+		crop.Min.Y = b.Min.Y + (b.Dy()-h)/2
+		crop.Max.Y = crop.Min.Y + h
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+	return dst
+}
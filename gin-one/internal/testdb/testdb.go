@@ -0,0 +1,61 @@
+// ============================================================================
+// Package testdb 测试用的内存 SQLite 数据库
+// ============================================================================
+//
+// 各个包的测试都要一个独立、用完即弃的数据库，这里统一打开方式：
+//
+//	db := testdb.Open(t, &Job{}, &DeadJob{})
+//
+// 【为什么限制为一个连接？】
+//
+// "file::memory:" 的每个连接都是一个独立的空库，连接池开第二个连接时
+// 迁移过的表就"消失"了，所以 SetMaxOpenConns(1)。
+//
+// 【和 testutil 的区别】
+//
+// testutil 启动整个 app（路由、服务、共用库 + 每个测试一个事务），会导入 app；
+// 被 app 导入的包（jobs、audit、repository ...）在测试里用它会形成导入环。
+// testdb 只依赖 gorm 和 SQLite 驱动，任何包都能用。
+//
+// ============================================================================
+package testdb
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open 打开一个内存 SQLite 库并迁移 models，测试结束时关闭
+//
+// 日志静默；TranslateError 打开，唯一索引冲突返回 gorm.ErrDuplicatedKey
+func Open(t testing.TB, models ...any) *gorm.DB {
+	t.Helper()
+	return open(t, "file::memory:", models)
+}
+
+// ForeignKeys 同 Open，另外打开 SQLite 外键约束，孤儿记录会直接报错
+func ForeignKeys(t testing.TB, models ...any) *gorm.DB {
+	t.Helper()
+	return open(t, "file::memory:?_foreign_keys=1", models)
+}
+
+func open(t testing.TB, dsn string, models []any) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/pagination"
+	"go-one/response"
+)
+
+// Register 在 group 上注册任务管理接口，调用方负责加管理员权限中间件
+//
+//	GET    /stats                      各状态任务数
+//	GET    /dead?page=1&page_size=10   死信列表
+//	POST   /dead/:id/requeue           重新入队
+//	DELETE /dead/:id                   丢弃
+func Register(group *gin.RouterGroup, db *gorm.DB) {
+	group.GET("/stats", func(c *gin.Context) {
+		s, err := GetStats(c.Request.Context(), db)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "统计任务失败")
+			return
+		}
+		response.Success(c, s)
+	})
+
+	group.GET("/dead", func(c *gin.Context) {
+		req, err := pagination.FromQuery(c)
+		if err != nil || req.Mode != pagination.ModeOffset {
+			response.Error(c, http.StatusBadRequest, "invalid_pagination", "死信列表只支持 page / page_size 分页")
+			return
+		}
+		rows, total, err := ListDead(c.Request.Context(), db, req.Offset(), req.Size)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "查询死信失败")
+			return
+		}
+		response.Success(c, gin.H{"items": rows, "total": total, "page": req.Page, "size": req.Size})
+	})
+
+	group.POST("/dead/:id/requeue", func(c *gin.Context) {
+		id, ok := deadID(c)
+		if !ok {
+			return
+		}
+		job, err := Requeue(c.Request.Context(), db, id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, job)
+	})
+
+	group.DELETE("/dead/:id", func(c *gin.Context) {
+		id, ok := deadID(c)
+		if !ok {
+			return
+		}
+		if err := Discard(c.Request.Context(), db, id); err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, gin.H{"id": id, "discarded": true})
+	})
+}
+
+func deadID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		response.Error(c, http.StatusBadRequest, "invalid_id", "ID 不合法")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func abort(c *gin.Context, err error) {
+	if errors.Is(err, ErrNotFound) {
+		response.Error(c, http.StatusNotFound, "dead_job_not_found", "死信不存在")
+		return
+	}
+	response.Error(c, http.StatusInternalServerError, "internal_error", "操作失败")
+}
@@ -0,0 +1,255 @@
+// ============================================================================
+// Package jobs 持久化任务队列：数据库表存任务，worker 领取执行，失败重试，多次失败进死信表
+// ============================================================================
+//
+// 【和 go func() 的区别】
+//
+// | 问题                       | go func()                  | jobs                                        |
+// |----------------------------|----------------------------|---------------------------------------------|
+// | 进程重启                   | 没执行完的任务丢失         | 任务在表里，重启后继续                      |
+// | 任务失败                   | 只能打日志                 | 指数退避重试，超过次数进死信表可人工重放    |
+// | 并发量                     | 无上限                     | Concurrency 个 worker（worker pool）        |
+// | 多实例部署                 | 每个实例各跑各的           | 条件更新领取，同一任务只有一个实例执行      |
+// | 和业务数据一致             | 事务回滚了任务已经发出     | Enqueue 传事务 tx，回滚时任务也不存在       |
+//
+// 【定义任务】
+//
+//	type WelcomeEmail struct{ UserID uint; Email string }
+//	var SendWelcome = jobs.Task[WelcomeEmail]{Name: "email.welcome", MaxAttempts: 3}
+//
+//	// 生产者：和创建用户在同一个事务里
+//	SendWelcome.Enqueue(ctx, tx, WelcomeEmail{UserID: u.ID, Email: u.Email})
+//
+//	// 消费者：payload 已经反序列化成 WelcomeEmail
+//	jobs.Handle(worker, SendWelcome, func(ctx context.Context, p WelcomeEmail) error { ... })
+//
+// 【任务的生命周期】
+//
+//	Enqueue ──▶ jobs 表（run_at = 现在）
+//	              │ worker 领取：run_at 推迟 VisibilityTimeout，attempts + 1
+//	              ▼
+//	          执行 handler ──成功──▶ 删除
+//	              │
+//	              失败 ──attempts < MaxAttempts──▶ run_at = 现在 + 退避，等待下次领取
+//	              │
+//	              └──达到 MaxAttempts 或 Permanent(err)──▶ 移到 jobs_dead 表
+//
+// 【可见性超时】
+//
+// 领取时把 run_at 推迟 VisibilityTimeout，其他 worker 在这段时间内看不到这个任务。
+// worker 崩溃时任务不会丢：超时后 run_at 已经到期，会被重新领取。
+// 所以任务是"至少执行一次"，handler 要能承受重复执行（如按业务唯一键去重）。
+//
+// ============================================================================
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 错误定义
+var (
+	ErrNotFound = errors.New("jobs: dead job not found")
+)
+
+// DefaultQueue 没有指定队列时使用
+const DefaultQueue = "default"
+
+// Job 表 jobs 的一行：等待执行、正在执行或等待重试的任务
+type Job struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Queue       string    `gorm:"size:50;not null;index:idx_jobs_due,priority:1" json:"queue"`
+	Type        string    `gorm:"size:100;not null" json:"type"`
+	Payload     string    `gorm:"type:text;not null" json:"payload"` // JSON
+	Attempts    int       `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int       `gorm:"not null;default:0" json:"max_attempts"` // 0 表示用 worker 的 Config.MaxAttempts
+	LastError   string    `gorm:"size:500" json:"last_error,omitempty"`
+	RunAt       time.Time `gorm:"not null;index:idx_jobs_due,priority:2" json:"run_at"` // 到期后可以被领取
+	LockedBy    string    `gorm:"size:32;not null;default:''" json:"-"`                 // 领取时生成的令牌，空表示没有 worker 在执行
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Job) TableName() string {
+	return "jobs"
+}
+
+// DeadJob 表 jobs_dead：重试用完或永久失败的任务，等待人工处理
+type DeadJob struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	JobID      uint      `gorm:"not null;index" json:"job_id"` // 原 jobs.id
+	Queue      string    `gorm:"size:50;not null" json:"queue"`
+	Type       string    `gorm:"size:100;not null;index" json:"type"`
+	Payload    string    `gorm:"type:text;not null" json:"payload"`
+	Attempts   int       `gorm:"not null" json:"attempts"`
+	LastError  string    `gorm:"size:500" json:"last_error"`
+	EnqueuedAt time.Time `gorm:"not null" json:"enqueued_at"`
+	FailedAt   time.Time `gorm:"not null;index" json:"failed_at"`
+}
+
+// TableName 指定表名
+func (DeadJob) TableName() string {
+	return "jobs_dead"
+}
+
+// ============================================================================
+// 任务定义与入队
+// ============================================================================
+
+// Task 一种任务的定义，T 是 payload 类型，必须能 JSON 序列化
+type Task[T any] struct {
+	// Name 任务类型，如 "email.welcome"，写入 jobs.type，worker 按它找 handler
+	Name string
+
+	// Queue 队列名，默认 DefaultQueue；慢任务放单独的队列，由单独的 worker 处理，避免堵住快任务
+	Queue string
+
+	// MaxAttempts 最多执行次数（含第一次），0 表示用 worker 的 Config.MaxAttempts
+	MaxAttempts int
+}
+
+// Enqueue 立即入队
+//
+// db 传事务 tx 时任务和业务数据一起提交或回滚；传 DB 时立即写入。
+func (t Task[T]) Enqueue(ctx context.Context, db *gorm.DB, payload T) (*Job, error) {
+	return t.EnqueueAt(ctx, db, payload, time.Now())
+}
+
+// EnqueueIn delay 之后执行，如"注册 24 小时后发送引导邮件"
+func (t Task[T]) EnqueueIn(ctx context.Context, db *gorm.DB, payload T, delay time.Duration) (*Job, error) {
+	return t.EnqueueAt(ctx, db, payload, time.Now().Add(delay))
+}
+
+// EnqueueAt 在 runAt 之后执行
+func (t Task[T]) EnqueueAt(ctx context.Context, db *gorm.DB, payload T, runAt time.Time) (*Job, error) {
+	if t.Name == "" {
+		return nil, errors.New("jobs: Task.Name must not be empty")
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: marshal %s: %w", t.Name, err)
+	}
+	job := &Job{
+		Queue:       t.queue(),
+		Type:        t.Name,
+		Payload:     string(b),
+		MaxAttempts: t.MaxAttempts,
+		RunAt:       runAt,
+	}
+	if err := db.WithContext(ctx).Create(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (t Task[T]) queue() string {
+	if t.Queue == "" {
+		return DefaultQueue
+	}
+	return t.Queue
+}
+
+// ============================================================================
+// 死信管理
+// ============================================================================
+
+// ListDead 死信列表，最近失败的在前
+func ListDead(ctx context.Context, db *gorm.DB, offset, limit int) ([]DeadJob, int64, error) {
+	var total int64
+	if err := db.WithContext(ctx).Model(&DeadJob{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var rows []DeadJob
+	err := db.WithContext(ctx).Order("failed_at DESC, id DESC").Offset(offset).Limit(limit).Find(&rows).Error
+	return rows, total, err
+}
+
+// Requeue 把死信放回 jobs 表立即执行，执行次数从 0 开始重新计算
+// 通常在修复了导致失败的 bug 或下游恢复之后调用
+func Requeue(ctx context.Context, db *gorm.DB, id uint) (*Job, error) {
+	var job *Job
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		dead, err := takeDead(tx, id)
+		if err != nil {
+			return err
+		}
+		job = &Job{
+			Queue:     dead.Queue,
+			Type:      dead.Type,
+			Payload:   dead.Payload,
+			LastError: dead.LastError,
+			RunAt:     time.Now(),
+		}
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		return tx.Delete(dead).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Discard 删除死信，不再执行
+func Discard(ctx context.Context, db *gorm.DB, id uint) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		dead, err := takeDead(tx, id)
+		if err != nil {
+			return err
+		}
+		return tx.Delete(dead).Error
+	})
+}
+
+func takeDead(tx *gorm.DB, id uint) (*DeadJob, error) {
+	var dead DeadJob
+	err := tx.Where("id = ?", id).Take(&dead).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return &dead, err
+}
+
+// Stats 各状态的任务数，用于监控积压
+type Stats struct {
+	Ready     int64 `json:"ready"`     // 已到期，等待领取
+	Running   int64 `json:"running"`   // 已被领取，可见性超时还没到
+	Scheduled int64 `json:"scheduled"` // 延迟任务或等待重试
+	Dead      int64 `json:"dead"`
+}
+
+// GetStats 统计任务数
+func GetStats(ctx context.Context, db *gorm.DB) (Stats, error) {
+	var s Stats
+	now := time.Now()
+	db = db.WithContext(ctx)
+	if err := db.Model(&Job{}).Where("run_at <= ?", now).Count(&s.Ready).Error; err != nil {
+		return s, err
+	}
+	if err := db.Model(&Job{}).Where("run_at > ? AND locked_by <> ''", now).Count(&s.Running).Error; err != nil {
+		return s, err
+	}
+	if err := db.Model(&Job{}).Where("run_at > ? AND locked_by = ''", now).Count(&s.Scheduled).Error; err != nil {
+		return s, err
+	}
+	err := db.Model(&DeadJob{}).Count(&s.Dead).Error
+	return s, err
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,436 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+
+	"go-learning/clock"
+)
+
+type email struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+}
+
+var sendEmail = Task[email]{Name: "email.send", MaxAttempts: 3}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.Open(t, &Job{}, &DeadJob{})
+}
+
+// newTestWorker 时间可控的 Worker，返回它的假时钟
+func newTestWorker(db *gorm.DB, cfg Config) (*Worker, *clock.Fake) {
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Now())
+	cfg.Clock = clk
+	return NewWorker(db, cfg), clk
+}
+
+func count(t *testing.T, db *gorm.DB, model any) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Model(model).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestEnqueueInTransaction(t *testing.T) {
+	tests := []struct {
+		name     string
+		rollback bool
+		want     int64
+	}{
+		{"commit", false, 1},
+		{"rollback", true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			_ = db.Transaction(func(tx *gorm.DB) error {
+				if _, err := sendEmail.Enqueue(context.Background(), tx, email{To: "a@example.com"}); err != nil {
+					t.Fatal(err)
+				}
+				if tt.rollback {
+					return errors.New("rollback")
+				}
+				return nil
+			})
+			if got := count(t, db, &Job{}); got != tt.want {
+				t.Errorf("jobs = %d; want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutcomes(t *testing.T) {
+	tests := []struct {
+		name     string
+		task     Task[email]
+		payload  string // 非空时直接写入原始 payload
+		handler  func(context.Context, email) error
+		runs     int           // RunOnce 的次数
+		step     time.Duration // 每次 RunOnce 后推进的时间
+		wantJobs int64
+		wantDead int64
+		wantRuns int32
+	}{
+		{
+			name:    "success deletes job",
+			task:    sendEmail,
+			handler: func(context.Context, email) error { return nil },
+			runs:    1, wantJobs: 0, wantDead: 0, wantRuns: 1,
+		},
+		{
+			name:    "failure waits for backoff",
+			task:    sendEmail,
+			handler: func(context.Context, email) error { return errors.New("smtp down") },
+			runs:    3, step: 0, wantJobs: 1, wantDead: 0, wantRuns: 1,
+		},
+		{
+			name:    "retries until max attempts then dead",
+			task:    sendEmail,
+			handler: func(context.Context, email) error { return errors.New("smtp down") },
+			runs:    5, step: time.Hour, wantJobs: 0, wantDead: 1, wantRuns: 3,
+		},
+		{
+			name:    "worker default max attempts",
+			task:    Task[email]{Name: "email.send"},
+			handler: func(context.Context, email) error { return errors.New("smtp down") },
+			runs:    8, step: time.Hour, wantJobs: 0, wantDead: 1, wantRuns: 5,
+		},
+		{
+			name:    "permanent error goes straight to dead",
+			task:    sendEmail,
+			handler: func(context.Context, email) error { return Permanent(errors.New("invalid address")) },
+			runs:    3, step: time.Hour, wantJobs: 0, wantDead: 1, wantRuns: 1,
+		},
+		{
+			name:    "bad payload is permanent",
+			task:    sendEmail,
+			payload: `{"to": 1}`,
+			handler: func(context.Context, email) error { return nil },
+			runs:    3, step: time.Hour, wantJobs: 0, wantDead: 1, wantRuns: 0,
+		},
+		{
+			name:    "panic is retried",
+			task:    sendEmail,
+			handler: func(context.Context, email) error { panic("boom") },
+			runs:    2, step: time.Hour, wantJobs: 1, wantDead: 0, wantRuns: 2,
+		},
+		{
+			name:    "recovers after failure",
+			task:    sendEmail,
+			handler: failTimes(1),
+			runs:    3, step: time.Hour, wantJobs: 0, wantDead: 0, wantRuns: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			w, clk := newTestWorker(db, Config{})
+			var runs atomic.Int32
+			Handle(w, sendEmail, func(ctx context.Context, e email) error {
+				runs.Add(1)
+				return tt.handler(ctx, e)
+			})
+
+			ctx := context.Background()
+			job, err := tt.task.EnqueueAt(ctx, db, email{To: "a@example.com"}, w.clock.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.payload != "" {
+				db.Model(job).Update("payload", tt.payload)
+			}
+			for i := 0; i < tt.runs; i++ {
+				if _, err := w.RunOnce(ctx); err != nil {
+					t.Fatal(err)
+				}
+				clk.Advance(tt.step)
+			}
+
+			if got := count(t, db, &Job{}); got != tt.wantJobs {
+				t.Errorf("jobs = %d; want %d", got, tt.wantJobs)
+			}
+			if got := count(t, db, &DeadJob{}); got != tt.wantDead {
+				t.Errorf("dead = %d; want %d", got, tt.wantDead)
+			}
+			if got := runs.Load(); got != tt.wantRuns {
+				t.Errorf("runs = %d; want %d", got, tt.wantRuns)
+			}
+		})
+	}
+}
+
+func failTimes(n int32) func(context.Context, email) error {
+	var calls atomic.Int32
+	return func(context.Context, email) error {
+		if calls.Add(1) <= n {
+			return errors.New("temporary")
+		}
+		return nil
+	}
+}
+
+func TestDeadJobFields(t *testing.T) {
+	db := newTestDB(t)
+	w, _ := newTestWorker(db, Config{})
+	Handle(w, sendEmail, func(context.Context, email) error { return Permanent(errors.New("mailbox does not exist")) })
+
+	job, _ := sendEmail.EnqueueAt(context.Background(), db, email{To: "x@example.com"}, w.clock.Now())
+	_, _ = w.RunOnce(context.Background())
+
+	var dead DeadJob
+	if err := db.First(&dead).Error; err != nil {
+		t.Fatal(err)
+	}
+	var p email
+	_ = json.Unmarshal([]byte(dead.Payload), &p)
+	if dead.JobID != job.ID || dead.Type != "email.send" || dead.Attempts != 1 ||
+		dead.LastError != "mailbox does not exist" || p.To != "x@example.com" {
+		t.Errorf("dead job = %+v", dead)
+	}
+}
+
+func TestVisibilityTimeout(t *testing.T) {
+	db := newTestDB(t)
+	w, clk := newTestWorker(db, Config{VisibilityTimeout: time.Minute})
+	ctx := context.Background()
+	_, _ = sendEmail.EnqueueAt(ctx, db, email{}, w.clock.Now())
+
+	first, err := w.claim(ctx, 10)
+	if err != nil || len(first) != 1 {
+		t.Fatalf("first claim = %d, %v", len(first), err)
+	}
+	// 模拟 worker 崩溃：领取后没有结果
+	if again, _ := w.claim(ctx, 10); len(again) != 0 {
+		t.Fatal("claimed job should be invisible")
+	}
+
+	clk.Advance(2 * time.Minute)
+	second, _ := w.claim(ctx, 10)
+	if len(second) != 1 || second[0].Attempts != 2 {
+		t.Fatalf("reclaim after timeout = %+v", second)
+	}
+
+	// 第一个 worker 迟到的结果不能覆盖第二个 worker 的领取
+	Handle(w, sendEmail, func(context.Context, email) error { return nil })
+	w.process(ctx, first[0])
+	if got := count(t, db, &Job{}); got != 1 {
+		t.Errorf("late result deleted the job: jobs = %d", got)
+	}
+	w.process(ctx, second[0])
+	if got := count(t, db, &Job{}); got != 0 {
+		t.Errorf("jobs = %d; want 0", got)
+	}
+}
+
+func TestQueuesAndDelay(t *testing.T) {
+	db := newTestDB(t)
+	w, clk := newTestWorker(db, Config{Queues: []string{"mail"}})
+	ctx := context.Background()
+	mail := Task[email]{Name: "email.send", Queue: "mail"}
+
+	_, _ = sendEmail.EnqueueAt(ctx, db, email{}, w.clock.Now())                // default 队列，不归这个 worker
+	_, _ = mail.EnqueueAt(ctx, db, email{}, w.clock.Now().Add(10*time.Minute)) // 延迟任务
+
+	if jobs, _ := w.claim(ctx, 10); len(jobs) != 0 {
+		t.Fatalf("claimed %d; want none", len(jobs))
+	}
+	clk.Advance(10 * time.Minute)
+	if jobs, _ := w.claim(ctx, 10); len(jobs) != 1 || jobs[0].Queue != "mail" {
+		t.Fatalf("claimed %+v; want the mail job", jobs)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	w := NewWorker(nil, Config{Backoff: time.Second, MaxBackoff: 10 * time.Second})
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := w.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v; want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRunConcurrency(t *testing.T) {
+	db := newTestDB(t)
+	w := NewWorker(db, Config{
+		Concurrency:  3,
+		PollInterval: 10 * time.Millisecond,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	var running, peak, done atomic.Int32
+	Handle(w, sendEmail, func(context.Context, email) error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		done.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	const total = 12
+	for i := 0; i < total; i++ {
+		_, _ = sendEmail.Enqueue(ctx, db, email{})
+	}
+	finished := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(finished)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for done.Load() < total {
+		select {
+		case <-deadline:
+			t.Fatalf("done = %d; want %d", done.Load(), total)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-finished
+
+	if p := peak.Load(); p > 3 {
+		t.Errorf("peak concurrency = %d; want <= 3", p)
+	}
+	if got := count(t, db, &Job{}); got != 0 {
+		t.Errorf("jobs = %d; want 0", got)
+	}
+}
+
+func TestRunDelayed(t *testing.T) {
+	db := newTestDB(t)
+	w, clk := newTestWorker(db, Config{PollInterval: time.Minute})
+	ran := make(chan string, 1)
+	Handle(w, sendEmail, func(_ context.Context, e email) error {
+		ran <- e.To
+		return nil
+	})
+	_, _ = sendEmail.EnqueueAt(context.Background(), db, email{To: "later@example.com"}, clk.Now().Add(time.Minute))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// 等 Run 建好轮询 ticker；任务还没到 run_at，不会执行
+	clk.BlockUntil(1)
+	select {
+	case to := <-ran:
+		t.Fatalf("delayed job %s ran before run_at", to)
+	default:
+	}
+
+	// 假时间走一个轮询周期，到点的任务被领取执行，不用真的等一分钟
+	clk.Advance(time.Minute)
+	select {
+	case to := <-ran:
+		if to != "later@example.com" {
+			t.Errorf("ran %s", to)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("delayed job not run after Advance")
+	}
+}
+
+func TestAdminHTTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+	w, clk := newTestWorker(db, Config{})
+	Handle(w, sendEmail, func(context.Context, email) error { return Permanent(errors.New("bad")) })
+	ctx := context.Background()
+	_, _ = sendEmail.EnqueueAt(ctx, db, email{To: "a@example.com"}, w.clock.Now())
+	_, _ = w.RunOnce(ctx)
+
+	var dead DeadJob
+	db.First(&dead)
+
+	r := gin.New()
+	Register(r.Group("/admin/jobs"), db)
+
+	tests := []struct {
+		name, method, path string
+		wantCode           int
+		wantBody           string
+	}{
+		{"stats", "GET", "/admin/jobs/stats", 200, `"dead":1`},
+		{"list dead", "GET", "/admin/jobs/dead?page=1&page_size=10", 200, `"total":1`},
+		{"cursor not supported", "GET", "/admin/jobs/dead?cursor=", 400, "invalid_pagination"},
+		{"invalid id", "POST", "/admin/jobs/dead/abc/requeue", 400, "invalid_id"},
+		{"requeue missing", "POST", "/admin/jobs/dead/999/requeue", 404, "dead_job_not_found"},
+		{"requeue", "POST", "/admin/jobs/dead/" + itoa(dead.ID) + "/requeue", 200, `"type":"email.send"`},
+		{"requeue twice", "POST", "/admin/jobs/dead/" + itoa(dead.ID) + "/requeue", 404, "dead_job_not_found"},
+		{"stats after requeue", "GET", "/admin/jobs/stats", 200, `"ready":1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("%s %s = %d %s; want %d containing %s", tt.method, tt.path, rec.Code, rec.Body, tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+
+	// 重新入队的任务从第 1 次开始计数
+	var job Job
+	db.First(&job)
+	if job.Attempts != 0 || job.LastError != "bad" {
+		t.Errorf("requeued job = %+v", job)
+	}
+
+	// 丢弃：Requeue 用的是真实时间，先把 worker 的时钟拨过去
+	clk.Advance(time.Minute)
+	_, _ = w.RunOnce(ctx)
+	dead = DeadJob{} // First 会把已有的主键当作查询条件
+	db.First(&dead)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/admin/jobs/dead/"+itoa(dead.ID), nil))
+	if rec.Code != 200 || count(t, db, &DeadJob{}) != 0 {
+		t.Errorf("discard = %d %s", rec.Code, rec.Body)
+	}
+}
+
+func itoa(id uint) string {
+	b, _ := json.Marshal(id)
+	return string(b)
+}
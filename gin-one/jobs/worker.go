@@ -0,0 +1,356 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go-learning/clock"
+)
+
+// ============================================================================
+// Worker：领取并执行任务
+// ============================================================================
+//
+// 【worker pool】
+//
+// 一个 goroutine 负责领取，最多 Concurrency 个 goroutine 同时执行。
+// 只领取空闲 worker 数量的任务：领取了却排队等待执行，可见性超时会白白流逝。
+//
+//	         ┌─────────── 空闲槽位 ───────────┐
+//	claim ──▶│ job ─▶ worker 1                │
+//	         │ job ─▶ worker 2                │
+//	         │ ...    worker N（Concurrency） │
+//	         └────────────────────────────────┘
+//
+// 【优雅关闭】
+//
+// ctx 取消后不再领取新任务，正在执行的任务继续执行完（最多 VisibilityTimeout），Run 才返回。
+//
+// ============================================================================
+
+// Config Worker 配置
+type Config struct {
+	// Queues 处理哪些队列，默认 []string{DefaultQueue}
+	Queues []string
+
+	// Concurrency 同时执行的任务数，默认 4
+	Concurrency int
+
+	// PollInterval 没有任务时的轮询间隔，默认 1s；Notify 可以提前唤醒
+	PollInterval time.Duration
+
+	// VisibilityTimeout 领取后其他 worker 看不到任务的时间，也是 handler 的执行超时，默认 5 分钟
+	// 要大于 handler 的最长执行时间，否则任务还在执行就会被别的 worker 再领取一次
+	VisibilityTimeout time.Duration
+
+	// MaxAttempts 任务没有指定 MaxAttempts 时的最多执行次数，默认 5
+	MaxAttempts int
+
+	// Backoff 第一次重试的等待时间，之后翻倍，最长 MaxBackoff；默认 1s / 10m
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// Logger 默认 slog.Default()
+	Logger *slog.Logger
+
+	// Clock 领取、重试退避和轮询用的时钟，默认 clock.Real；测试传 clock.NewFake
+	Clock clock.Clock
+}
+
+// handlerFunc 反序列化 payload 并调用用户的 handler
+type handlerFunc func(ctx context.Context, payload []byte) error
+
+// Worker 从 jobs 表领取任务执行
+type Worker struct {
+	db       *gorm.DB
+	cfg      Config
+	handlers map[string]handlerFunc
+	wake     chan struct{}
+	logger   *slog.Logger
+	clock    clock.Clock
+}
+
+// NewWorker 创建 Worker，表需要事先 AutoMigrate(&jobs.Job{}, &jobs.DeadJob{})
+func NewWorker(db *gorm.DB, cfg Config) *Worker {
+	if len(cfg.Queues) == 0 {
+		cfg.Queues = []string{DefaultQueue}
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = 5 * time.Minute
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 10 * time.Minute
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Worker{
+		db:       db,
+		cfg:      cfg,
+		handlers: make(map[string]handlerFunc),
+		wake:     make(chan struct{}, 1),
+		logger:   cfg.Logger,
+		clock:    clock.OrReal(cfg.Clock),
+	}
+}
+
+// Handle 注册任务的处理函数，必须在 Run 之前调用；同一个任务重复注册时 panic
+//
+// 返回错误时按退避重试；返回 Permanent(err) 时不再重试，直接进死信表。
+func Handle[T any](w *Worker, t Task[T], fn func(ctx context.Context, payload T) error) {
+	if _, ok := w.handlers[t.Name]; ok {
+		panic("jobs: duplicate handler for " + t.Name)
+	}
+	w.handlers[t.Name] = func(ctx context.Context, raw []byte) error {
+		var payload T
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			// 格式不对的 payload 重试多少次都一样
+			return Permanent(fmt.Errorf("decode payload: %w", err))
+		}
+		return fn(ctx, payload)
+	}
+}
+
+// permanentError 不需要重试的错误
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 标记错误不需要重试，如参数不合法、关联的数据已经删除
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Notify 入队后调用，让 Worker 立即领取而不是等下一次轮询；不会阻塞
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// claim 领取最多 limit 个到期的任务
+// 和 outbox 一样用条件更新防止多个 worker 领到同一个任务：更新时再检查一次 run_at，
+// 被别人先领走（run_at 已推迟）的行更新不到
+func (w *Worker) claim(ctx context.Context, limit int) ([]Job, error) {
+	now := w.clock.Now()
+	var due []Job
+	err := w.db.WithContext(ctx).
+		Where("queue IN ? AND run_at <= ?", w.cfg.Queues, now).
+		Order("run_at, id").Limit(limit).
+		Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+	claimed := due[:0]
+	for _, job := range due {
+		token, err := newToken()
+		if err != nil {
+			return nil, err
+		}
+		res := w.db.WithContext(ctx).Model(&Job{}).
+			Where("id = ? AND run_at <= ? AND attempts = ?", job.ID, now, job.Attempts).
+			Updates(map[string]any{
+				"run_at":    now.Add(w.cfg.VisibilityTimeout),
+				"attempts":  job.Attempts + 1,
+				"locked_by": token,
+			})
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			job.Attempts++
+			job.LockedBy = token
+			claimed = append(claimed, job)
+		}
+	}
+	return claimed, nil
+}
+
+// backoff 第 attempts 次失败后的等待时间
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.cfg.Backoff
+	for i := 1; i < attempts && d < w.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, w.cfg.MaxBackoff)
+}
+
+// process 执行一个任务并记录结果
+// 结果写入条件带上领取令牌：执行超过可见性超时、任务已被别人重新领取时，不覆盖别人的状态
+func (w *Worker) process(ctx context.Context, job Job) {
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.VisibilityTimeout)
+	defer cancel()
+
+	err := w.run(ctx, job)
+	// 记录结果不受 handler 超时影响
+	saveCtx := context.WithoutCancel(ctx)
+	mine := w.db.WithContext(saveCtx).Model(&Job{}).Where("id = ? AND locked_by = ?", job.ID, job.LockedBy)
+
+	if err == nil {
+		if res := mine.Delete(&Job{}); res.Error != nil {
+			w.logger.Error("jobs: delete finished job", "id", job.ID, "type", job.Type, "error", res.Error)
+		} else if res.RowsAffected == 0 {
+			w.logger.Warn("jobs: job finished after visibility timeout", "id", job.ID, "type", job.Type)
+		}
+		return
+	}
+
+	msg := err.Error()
+	if len(msg) > 500 {
+		msg = msg[:500]
+	}
+	maxAttempts := job.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = w.cfg.MaxAttempts
+	}
+	var permanent *permanentError
+	if errors.As(err, &permanent) || job.Attempts >= maxAttempts {
+		w.bury(saveCtx, job, msg)
+		return
+	}
+
+	delay := w.backoff(job.Attempts)
+	res := mine.Updates(map[string]any{
+		"run_at":     w.clock.Now().Add(delay),
+		"last_error": msg,
+		"locked_by":  "",
+	})
+	if res.Error != nil {
+		w.logger.Error("jobs: record failure", "id", job.ID, "type", job.Type, "error", res.Error)
+		return
+	}
+	w.logger.Warn("jobs: job failed, will retry",
+		"id", job.ID, "type", job.Type, "attempts", job.Attempts, "retry_in", delay, "error", err)
+}
+
+// run 调用 handler，panic 按失败处理
+func (w *Worker) run(ctx context.Context, job Job) (err error) {
+	h, ok := w.handlers[job.Type]
+	if !ok {
+		// 可能是新版本的任务、旧版本的 worker 还在跑，按普通失败重试，等新版本上线
+		return fmt.Errorf("no handler registered for %q", job.Type)
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			w.logger.Error("jobs: handler panic", "id", job.ID, "type", job.Type, "panic", v, "stack", string(debug.Stack()))
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return h(ctx, []byte(job.Payload))
+}
+
+// bury 把任务移到死信表
+func (w *Worker) bury(ctx context.Context, job Job, msg string) {
+	moved := false
+	err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND locked_by = ?", job.ID, job.LockedBy).Delete(&Job{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error // 已被别人重新领取，交给对方处理
+		}
+		moved = true
+		return tx.Create(&DeadJob{
+			JobID:      job.ID,
+			Queue:      job.Queue,
+			Type:       job.Type,
+			Payload:    job.Payload,
+			Attempts:   job.Attempts,
+			LastError:  msg,
+			EnqueuedAt: job.CreatedAt,
+			FailedAt:   w.clock.Now(),
+		}).Error
+	})
+	if err != nil {
+		w.logger.Error("jobs: move job to dead letter", "id", job.ID, "type", job.Type, "error", err)
+		return
+	}
+	if !moved {
+		return
+	}
+	w.logger.Error("jobs: job moved to dead letter", "id", job.ID, "type", job.Type, "attempts", job.Attempts, "error", msg)
+}
+
+// RunOnce 领取一批（最多 Concurrency 个）任务并依次执行，返回执行的数量；测试和命令行工具使用
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	jobs, err := w.claim(ctx, w.cfg.Concurrency)
+	if err != nil {
+		return 0, err
+	}
+	for _, job := range jobs {
+		w.process(ctx, job)
+	}
+	return len(jobs), nil
+}
+
+// Run 持续领取并执行任务，阻塞直到 ctx 取消且正在执行的任务都结束
+func (w *Worker) Run(ctx context.Context) {
+	slots := make(chan struct{}, w.cfg.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// 正在执行的任务不随 ctx 取消，执行完再退出
+	jobCtx := context.WithoutCancel(ctx)
+	done := make(chan struct{}, w.cfg.Concurrency) // 有任务完成时唤醒领取
+	poll := w.clock.NewTicker(w.cfg.PollInterval)
+	defer poll.Stop()
+
+	for {
+		if free := cap(slots) - len(slots); free > 0 {
+			jobs, err := w.claim(ctx, free)
+			if err != nil && ctx.Err() == nil {
+				w.logger.Error("jobs: claim", "error", err)
+			}
+			for _, job := range jobs {
+				slots <- struct{}{}
+				wg.Add(1)
+				go func(job Job) {
+					defer wg.Done()
+					defer func() {
+						<-slots
+						select {
+						case done <- struct{}{}:
+						default:
+						}
+					}()
+					w.process(jobCtx, job)
+				}(job)
+			}
+			// 领满了说明可能还有积压，有空闲槽位就立即再领
+			if len(jobs) == free {
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C():
+		case <-w.wake:
+		case <-done:
+		}
+	}
+}
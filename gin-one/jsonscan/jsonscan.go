@@ -0,0 +1,312 @@
+// ============================================================================
+// Package jsonscan 不解析整个 JSON，直接扫描字节取出少数几个已知字段
+// ============================================================================
+//
+// 【为什么】
+//
+// Webhook 分发只需要 type 和 id，但 Stripe 的事件体动辄几十 KB，json.Unmarshal 到结构体
+// 要扫描整个文档、为每个字段分配；用 jsonscan 找到目标字段就停，跳过的部分不分配内存：
+//
+//	typ, err := jsonscan.GetString(body, "type")         // "invoice.paid"
+//	obj, err := jsonscan.Get(body, "data", "object")     // 原始字节，交给 handler 再解析
+//	sha, err := jsonscan.GetString(body, "commits", "0", "id")
+//
+// 【路径】
+//
+// | 当前值 | 路径段               | 说明                                            |
+// |--------|----------------------|-------------------------------------------------|
+// | 对象   | 键名                 | 按原样比较；重复的键取第一个（json 取最后一个） |
+// | 数组   | 十进制下标 "0"、"12" | 越界返回 ErrNotFound                            |
+// | 其他   | 任意                 | ErrNotFound                                     |
+//
+// 【限制】
+//
+// 只检查扫描经过的部分是否合法：目标字段后面的内容即使损坏也不会报错。
+// 用在已验签、来源可信的请求体上；不可信的输入仍然用 encoding/json 完整解析。
+//
+// ============================================================================
+package jsonscan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// 错误定义
+var (
+	ErrNotFound = errors.New("jsonscan: path not found")
+	ErrSyntax   = errors.New("jsonscan: invalid JSON")
+	ErrType     = errors.New("jsonscan: unexpected value type")
+)
+
+// Get 返回 path 指向的值的原始字节（字符串带引号），是 data 的子切片，不分配内存
+func Get(data []byte, path ...string) ([]byte, error) {
+	i := skipSpace(data, 0)
+	for _, key := range path {
+		if i >= len(data) {
+			return nil, syntaxError(i)
+		}
+		var err error
+		switch data[i] {
+		case '{':
+			i, err = findKey(data, i, key)
+		case '[':
+			i, err = findIndex(data, i, key)
+		default:
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	end, err := skipValue(data, i)
+	if err != nil {
+		return nil, err
+	}
+	return data[i:end], nil
+}
+
+// GetString 取字符串字段；只为返回值分配一次，含转义字符时交给 encoding/json 解码
+func GetString(data []byte, path ...string) (string, error) {
+	raw, err := Get(data, path...)
+	if err != nil {
+		return "", err
+	}
+	if raw[0] != '"' {
+		return "", fmt.Errorf("%w: want string, got %s", ErrType, kind(raw[0]))
+	}
+	s := raw[1 : len(raw)-1]
+	for _, c := range s {
+		if c == '\\' {
+			var out string
+			if err := json.Unmarshal(raw, &out); err != nil {
+				return "", fmt.Errorf("%w: %v", ErrSyntax, err)
+			}
+			return out, nil
+		}
+	}
+	return string(s), nil
+}
+
+// GetInt 取整数字段，小数或超出 int64 范围时返回错误
+func GetInt(data []byte, path ...string) (int64, error) {
+	raw, err := Get(data, path...)
+	if err != nil {
+		return 0, err
+	}
+	if raw[0] != '-' && (raw[0] < '0' || raw[0] > '9') {
+		return 0, fmt.Errorf("%w: want number, got %s", ErrType, kind(raw[0]))
+	}
+	return strconv.ParseInt(string(raw), 10, 64)
+}
+
+// GetBool 取布尔字段
+func GetBool(data []byte, path ...string) (bool, error) {
+	raw, err := Get(data, path...)
+	if err != nil {
+		return false, err
+	}
+	switch raw[0] {
+	case 't':
+		return true, nil
+	case 'f':
+		return false, nil
+	}
+	return false, fmt.Errorf("%w: want bool, got %s", ErrType, kind(raw[0]))
+}
+
+func kind(c byte) string {
+	switch c {
+	case '"':
+		return "string"
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case 't', 'f':
+		return "bool"
+	case 'n':
+		return "null"
+	}
+	return "number"
+}
+
+func syntaxError(offset int) error {
+	return fmt.Errorf("%w at offset %d", ErrSyntax, offset)
+}
+
+// ============================================================================
+// 扫描
+// ============================================================================
+
+// findKey data[i] 是 '{'，返回 key 对应的值的起始位置
+func findKey(data []byte, i int, key string) (int, error) {
+	i = skipSpace(data, i+1)
+	if i < len(data) && data[i] == '}' {
+		return 0, ErrNotFound
+	}
+	for {
+		if i >= len(data) || data[i] != '"' {
+			return 0, syntaxError(i)
+		}
+		end, escaped, err := scanString(data, i)
+		if err != nil {
+			return 0, err
+		}
+		match := keyEqual(data[i:end], escaped, key)
+		i = skipSpace(data, end)
+		if i >= len(data) || data[i] != ':' {
+			return 0, syntaxError(i)
+		}
+		i = skipSpace(data, i+1)
+		if match {
+			return i, nil
+		}
+		if i, err = skipValue(data, i); err != nil {
+			return 0, err
+		}
+		i = skipSpace(data, i)
+		if i >= len(data) {
+			return 0, syntaxError(i)
+		}
+		switch data[i] {
+		case ',':
+			i = skipSpace(data, i+1)
+		case '}':
+			return 0, ErrNotFound
+		default:
+			return 0, syntaxError(i)
+		}
+	}
+}
+
+// keyEqual raw 是带引号的键；string(raw) == key 的比较编译器不会分配
+func keyEqual(raw []byte, escaped bool, key string) bool {
+	if !escaped {
+		return string(raw[1:len(raw)-1]) == key
+	}
+	var s string
+	return json.Unmarshal(raw, &s) == nil && s == key
+}
+
+// findIndex data[i] 是 '['，返回第 key 个元素的起始位置
+func findIndex(data []byte, i int, key string) (int, error) {
+	n, err := strconv.Atoi(key)
+	if err != nil || n < 0 {
+		return 0, ErrNotFound
+	}
+	i = skipSpace(data, i+1)
+	if i < len(data) && data[i] == ']' {
+		return 0, ErrNotFound
+	}
+	for ; ; n-- {
+		if n == 0 {
+			return i, nil
+		}
+		if i, err = skipValue(data, i); err != nil {
+			return 0, err
+		}
+		i = skipSpace(data, i)
+		if i >= len(data) {
+			return 0, syntaxError(i)
+		}
+		switch data[i] {
+		case ',':
+			i = skipSpace(data, i+1)
+		case ']':
+			return 0, ErrNotFound
+		default:
+			return 0, syntaxError(i)
+		}
+	}
+}
+
+func skipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scanString data[i] 是 '"'，返回结束引号之后的位置，escaped 表示其中有转义
+func scanString(data []byte, i int) (end int, escaped bool, err error) {
+	for j := i + 1; j < len(data); j++ {
+		switch data[j] {
+		case '\\':
+			escaped = true
+			j++
+		case '"':
+			return j + 1, escaped, nil
+		}
+	}
+	return 0, false, syntaxError(i)
+}
+
+// skipValue 返回从 i 开始的值之后的位置
+func skipValue(data []byte, i int) (int, error) {
+	if i >= len(data) {
+		return 0, syntaxError(i)
+	}
+	switch c := data[i]; {
+	case c == '"':
+		end, _, err := scanString(data, i)
+		return end, err
+	case c == '{' || c == '[':
+		return skipContainer(data, i)
+	case c == 't':
+		return literal(data, i, "true")
+	case c == 'f':
+		return literal(data, i, "false")
+	case c == 'n':
+		return literal(data, i, "null")
+	case c == '-' || (c >= '0' && c <= '9'):
+		j := i + 1
+		for j < len(data) && isNumberByte(data[j]) {
+			j++
+		}
+		return j, nil
+	}
+	return 0, syntaxError(i)
+}
+
+// skipContainer 跳过对象或数组：只数括号深度，字符串里的括号不算
+func skipContainer(data []byte, i int) (int, error) {
+	depth := 0
+	for j := i; j < len(data); j++ {
+		switch data[j] {
+		case '"':
+			end, _, err := scanString(data, j)
+			if err != nil {
+				return 0, err
+			}
+			j = end - 1
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return j + 1, nil
+			}
+		}
+	}
+	return 0, syntaxError(i)
+}
+
+func literal(data []byte, i int, lit string) (int, error) {
+	end := i + len(lit)
+	if end > len(data) || string(data[i:end]) != lit {
+		return 0, syntaxError(i)
+	}
+	return end, nil
+}
+
+func isNumberByte(c byte) bool {
+	return (c >= '0' && c <= '9') || c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-'
+}
@@ -0,0 +1,137 @@
+package jsonscan
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const event = `{
+	"id": "evt_1",
+	"object": "event",
+	"data": {"object": {"id": "in_1", "note": "a \"quoted\" } brace", "lines": [1, {"x": [2]}, 3]}},
+	"livemode": false,
+	"pending_webhooks": 2,
+	"type": "invoice.paid",
+	"type": "ignored duplicate",
+	"esc\u0061ped": "yes",
+	"commits": [{"id": "c1"}, {"id": "c2"}],
+	"unicode": "café"
+}`
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		path []string
+		want string
+		err  error
+	}{
+		{[]string{"id"}, `"evt_1"`, nil},
+		{[]string{"data", "object", "id"}, `"in_1"`, nil},
+		{[]string{"data", "object", "lines"}, `[1, {"x": [2]}, 3]`, nil},
+		{[]string{"data", "object", "lines", "1", "x", "0"}, `2`, nil},
+		{[]string{"livemode"}, `false`, nil},
+		{[]string{"type"}, `"invoice.paid"`, nil}, // 重复的键取第一个
+		{[]string{"escaped"}, `"yes"`, nil},
+		{[]string{"commits", "1", "id"}, `"c2"`, nil},
+		{nil, event, nil},
+		{[]string{"missing"}, "", ErrNotFound},
+		{[]string{"commits", "2"}, "", ErrNotFound},
+		{[]string{"commits", "x"}, "", ErrNotFound},
+		{[]string{"id", "x"}, "", ErrNotFound},
+	}
+	for _, tt := range tests {
+		got, err := Get([]byte(event), tt.path...)
+		if string(got) != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Get(%v) = %s, %v; want %s, %v", tt.path, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestTyped(t *testing.T) {
+	data := []byte(event)
+	if s, err := GetString(data, "data", "object", "note"); err != nil || s != `a "quoted" } brace` {
+		t.Errorf("escaped string = %q, %v", s, err)
+	}
+	if s, _ := GetString(data, "unicode"); s != "café" {
+		t.Errorf("unicode = %q", s)
+	}
+	if n, err := GetInt(data, "pending_webhooks"); err != nil || n != 2 {
+		t.Errorf("int = %d, %v", n, err)
+	}
+	if b, err := GetBool(data, "livemode"); err != nil || b {
+		t.Errorf("bool = %v, %v", b, err)
+	}
+	if _, err := GetString(data, "livemode"); !errors.Is(err, ErrType) {
+		t.Errorf("type err = %v", err)
+	}
+	if _, err := GetInt(data, "id"); !errors.Is(err, ErrType) {
+		t.Errorf("int type err = %v", err)
+	}
+}
+
+func TestSyntax(t *testing.T) {
+	for _, in := range []string{
+		``,
+		`{`,
+		`{"a" 1}`,
+		`{"a": 1 "b": 2}`,
+		`{"a": "unterminated}`,
+		`{"a": tru, "b": 1}`,
+		`{"a": [1, 2`,
+		`[1 2]`,
+	} {
+		if _, err := Get([]byte(in), "b"); !errors.Is(err, ErrSyntax) && !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) err = %v", in, err)
+		} else if err == nil {
+			t.Errorf("Get(%q) succeeded", in)
+		}
+	}
+	if _, err := Get([]byte(`{"a": tru}`), "a"); !errors.Is(err, ErrSyntax) {
+		t.Errorf("bad literal err = %v", err)
+	}
+}
+
+func TestAllocs(t *testing.T) {
+	data := []byte(event)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := Get(data, "data", "object", "lines", "1"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Get allocs = %v, want 0", allocs)
+	}
+}
+
+// ============================================================================
+// Benchmark：从 Stripe 事件里取 type，payload 越大差距越大
+// ============================================================================
+
+var bigEvent = []byte(`{"id": "evt_1", "data": {"object": {"lines": [` +
+	strings.Repeat(`{"id": "il_1", "amount": 1000, "description": "line item", "metadata": {"k": "v"}},`, 200) +
+	`{}]}}, "type": "invoice.paid"}`)
+
+func BenchmarkUnmarshal(b *testing.B) {
+	b.SetBytes(int64(len(bigEvent)))
+	b.ReportAllocs()
+	for b.Loop() {
+		var env struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(bigEvent, &env); err != nil || env.Type != "invoice.paid" {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetString(b *testing.B) {
+	b.SetBytes(int64(len(bigEvent)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if typ, err := GetString(bigEvent, "type"); err != nil || typ != "invoice.paid" {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// ============================================================================
+// Package loadgen 压测：固定并发打一个接口，统计延迟分位数、错误率和吞吐量；逐级加并发找饱和点
+// ============================================================================
+//
+// 请求由 Concurrency 个并发槽位循环发出（go-learning/conc 的 Group.SetLimit），
+// 一个请求结束才发下一个（闭环压测），所以吞吐量 ≈ 并发数 / 平均延迟。
+// HTTP 客户端用 go-learning/httpclient，但关掉重试和熔断：压测要看到每一个失败，
+// 重试会把失败藏起来，熔断器会替服务端挡掉请求，两者都会让结果比实际好看。
+//
+// 【指标】
+// | 字段          | 含义                                                            |
+// |---------------|-----------------------------------------------------------------|
+// | Requests      | 发出的请求数（ctx 取消时中断的请求不算）                        |
+// | Errors        | 网络错误、超时和 4xx / 5xx 响应；按状态码的明细见 Status        |
+// | P50/P95/P99   | 收到响应的请求的延迟分位数（含读完响应体），网络错误不计入      |
+// | Throughput()  | Requests / Elapsed，每秒请求数                                  |
+//
+// 401 / 429 也算错误：token 过期、被限流时压测结果没有意义，要在报告里看得出来。
+//
+// 【爬坡找饱和点】
+// RunRamp 按 Levels 依次加并发（默认 1, 2, 4 ... 256），每一级跑 Config.Duration，
+// 出现下面任一情况就停止，饱和点是此前吞吐量最高的一级：
+//
+//  1. 错误率超过 MaxErrorRate（默认 1%）
+//  2. p99 超过 MaxP99（默认不检查）
+//  3. 并发加上去了，吞吐量比目前最好的一级增加不到 MinGain（默认 5%）：
+//     服务端已经处理不过来，多出来的请求只是在排队，延迟变长
+//
+// 【用法】
+//
+//	res, err := loadgen.Run(ctx, loadgen.Config{URL: "http://localhost:8080/api/v1/users", Concurrency: 50})
+//	fmt.Println(res.P99, res.ErrorRate(), res.Throughput())
+//
+//	report, err := loadgen.RunRamp(ctx, cfg, loadgen.Ramp{MaxP99: 200 * time.Millisecond}, nil)
+//	fmt.Println(report.Best.Concurrency, report.Reason)
+//
+// 命令行见 cmd 包的 loadgen 命令：
+//
+//	go run examples/5_2_swagger.go loadgen -c 50 -duration 10s /api/v1/users
+//	go run examples/5_2_swagger.go loadgen -ramp 1,2,4,8,16,32,64 -max-p99 100ms /api/v1/users
+//
+// 压测端和服务端在同一台机器上时会抢 CPU，测到的饱和点偏低，只适合比较改动前后的差别。
+// ============================================================================
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"go-learning/conc"
+	"go-learning/httpclient"
+)
+
+// 错误定义
+var (
+	ErrNoURL      = errors.New("loadgen: URL is required")
+	ErrInvalidURL = errors.New("loadgen: URL must be absolute http(s)")
+)
+
+// Config 一次压测的配置
+type Config struct {
+	// Method 默认 GET
+	Method string
+
+	// URL 目标地址，必须是完整的 http(s) URL
+	URL string
+
+	// Body 每个请求发送的请求体，可以为 nil
+	Body []byte
+
+	// Header 每个请求带上的请求头，如 Authorization、Content-Type
+	Header http.Header
+
+	// Concurrency 同时进行的请求数，默认 10
+	Concurrency int
+
+	// Duration 压测时长，默认 10 秒；Requests > 0 时以请求数为准
+	Duration time.Duration
+
+	// Requests 总请求数，0 表示按 Duration 计时
+	Requests int
+
+	// Timeout 单个请求的超时（含读完响应体），默认 10 秒
+	Timeout time.Duration
+
+	// Transport 默认是按 Concurrency 设置了空闲连接数的 http.Transport；
+	// http.DefaultTransport 每个主机只保留 2 个空闲连接，高并发时大部分请求都在建新连接
+	Transport http.RoundTripper
+}
+
+func (cfg *Config) setDefaults() error {
+	if cfg.URL == "" {
+		return ErrNoURL
+	}
+	if u, err := url.Parse(cfg.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidURL, cfg.URL)
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodGet
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 10
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = 10 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return nil
+}
+
+// Result 一次压测的结果
+type Result struct {
+	Concurrency int
+	Requests    int
+	Errors      int
+	Status      map[int]int // 状态码 → 次数，0 表示没有收到响应（网络错误、超时）
+	Elapsed     time.Duration
+
+	Mean, P50, P95, P99, Max time.Duration
+}
+
+// ErrorRate 错误请求的比例，0 ~ 1
+func (r Result) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Requests)
+}
+
+// Throughput 每秒完成的请求数
+func (r Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+// Run 按 cfg 压测一次；ctx 取消时停止发请求，返回已完成部分的结果和 ctx 的错误
+func Run(ctx context.Context, cfg Config) (Result, error) {
+	if err := cfg.setDefaults(); err != nil {
+		return Result{}, err
+	}
+	transport := cfg.Transport
+	if transport == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.MaxIdleConns = cfg.Concurrency
+		t.MaxIdleConnsPerHost = cfg.Concurrency
+		defer t.CloseIdleConnections()
+		transport = t
+	}
+	client := httpclient.New(httpclient.Config{
+		Timeout:    cfg.Timeout,
+		MaxRetries: -1,
+		Breaker:    httpclient.NewBreaker(httpclient.BreakerConfig{FailureThreshold: math.MaxInt}),
+		Transport:  transport,
+	})
+
+	rec := &recorder{status: map[int]int{}}
+	var g conc.Group
+	g.SetLimit(cfg.Concurrency)
+	start := time.Now()
+	deadline := start.Add(cfg.Duration)
+	for i := 0; ctx.Err() == nil; i++ {
+		if cfg.Requests > 0 && i >= cfg.Requests || cfg.Requests <= 0 && !time.Now().Before(deadline) {
+			break
+		}
+		// 并发槽位都占满时 Go 阻塞，直到有请求结束
+		g.Go(func() error {
+			rec.add(ctx, send(ctx, client, &cfg))
+			return nil
+		})
+	}
+	g.Wait()
+
+	res := rec.result(time.Since(start))
+	res.Concurrency = cfg.Concurrency
+	return res, ctx.Err()
+}
+
+// sample 一个请求的结果
+type sample struct {
+	status  int // 0 表示没有收到响应
+	latency time.Duration
+	err     error
+}
+
+func send(ctx context.Context, client *httpclient.Client, cfg *Config) sample {
+	req, err := http.NewRequestWithContext(ctx, cfg.Method, cfg.URL, bytes.NewReader(cfg.Body))
+	if err != nil {
+		return sample{err: err}
+	}
+	if cfg.Header != nil {
+		req.Header = cfg.Header.Clone()
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return sample{latency: time.Since(start), err: err}
+	}
+	// 读完响应体再计时，连接也才能放回连接池复用
+	_, err = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return sample{status: resp.StatusCode, latency: time.Since(start), err: err}
+}
+
+// recorder 并发收集样本
+type recorder struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	status    map[int]int
+	errors    int
+}
+
+func (r *recorder) add(ctx context.Context, s sample) {
+	if s.err != nil && ctx.Err() != nil {
+		return // 压测被取消时中断的请求，不是服务端的问题
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s.err != nil {
+		r.status[0]++
+		r.errors++
+		return
+	}
+	r.status[s.status]++
+	if s.status >= http.StatusBadRequest {
+		r.errors++
+	}
+	r.latencies = append(r.latencies, s.latency)
+}
+
+func (r *recorder) result(elapsed time.Duration) Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := Result{Errors: r.errors, Status: r.status, Elapsed: elapsed}
+	for _, n := range r.status {
+		res.Requests += n
+	}
+	if len(r.latencies) == 0 {
+		return res
+	}
+	slices.Sort(r.latencies)
+	var sum time.Duration
+	for _, d := range r.latencies {
+		sum += d
+	}
+	res.Mean = sum / time.Duration(len(r.latencies))
+	res.P50 = percentile(r.latencies, 0.50)
+	res.P95 = percentile(r.latencies, 0.95)
+	res.P99 = percentile(r.latencies, 0.99)
+	res.Max = r.latencies[len(r.latencies)-1]
+	return res
+}
+
+// percentile 最近秩法：sorted 中至少 p 比例的值不大于返回值
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
@@ -0,0 +1,169 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	var n, inflight, peak atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cur := inflight.Add(1)
+		defer inflight.Add(-1)
+		for p := peak.Load(); cur > p && !peak.CompareAndSwap(p, cur); p = peak.Load() {
+		}
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || string(body) != `{"a":1}` || r.Header.Get("Authorization") != "Bearer x" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		time.Sleep(time.Millisecond)
+		if n.Add(1)%10 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	res, err := Run(context.Background(), Config{
+		Method:      http.MethodPost,
+		URL:         srv.URL,
+		Body:        []byte(`{"a":1}`),
+		Header:      http.Header{"Authorization": {"Bearer x"}},
+		Concurrency: 4,
+		Requests:    100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Requests != 100 || res.Errors != 10 || res.Status[200] != 90 || res.Status[503] != 10 {
+		t.Errorf("result = %+v", res)
+	}
+	if res.ErrorRate() != 0.1 || res.Throughput() <= 0 || res.Concurrency != 4 {
+		t.Errorf("error rate = %v, throughput = %v", res.ErrorRate(), res.Throughput())
+	}
+	if res.P50 < time.Millisecond || res.P50 > res.P95 || res.P95 > res.P99 || res.P99 > res.Max {
+		t.Errorf("latencies = %v %v %v %v", res.P50, res.P95, res.P99, res.Max)
+	}
+	if peak.Load() > 4 {
+		t.Errorf("peak concurrency = %d, want <= 4", peak.Load())
+	}
+}
+
+func TestRunDurationAndErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+	}))
+	url := srv.URL
+	res, err := Run(context.Background(), Config{URL: url, Concurrency: 2, Duration: 50 * time.Millisecond})
+	if err != nil || res.Requests == 0 || res.Errors != 0 || res.Elapsed < 50*time.Millisecond {
+		t.Fatalf("result = %+v, %v", res, err)
+	}
+
+	// 服务端关闭后都是网络错误，不计入延迟
+	srv.Close()
+	res, err = Run(context.Background(), Config{URL: url, Requests: 5})
+	if err != nil || res.Requests != 5 || res.Errors != 5 || res.Status[0] != 5 || res.P99 != 0 {
+		t.Errorf("result = %+v, %v", res, err)
+	}
+
+	for _, u := range []string{"", "/api/v1/users", "ftp://example.com"} {
+		if _, err := Run(context.Background(), Config{URL: u}); !errors.Is(err, ErrNoURL) && !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("URL %q: err = %v", u, err)
+		}
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res, err := Run(ctx, Config{URL: srv.URL, Concurrency: 3, Duration: time.Minute})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+	// 取消时中断的请求不算错误
+	if res.Errors != 0 || res.Requests != 0 {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestRunRamp(t *testing.T) {
+	// 最多同时处理 2 个请求，多出来的返回 503
+	sem := make(chan struct{}, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			time.Sleep(2 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	var steps []int
+	report, err := RunRamp(context.Background(), Config{URL: srv.URL, Duration: 100 * time.Millisecond},
+		Ramp{Levels: []int{1, 2, 8, 16}, MinGain: -1},
+		func(r Result) { steps = append(steps, r.Concurrency) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Saturated || report.Best.Concurrency != 2 || len(report.Steps) != 3 || len(steps) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	if !strings.HasPrefix(report.Reason, "error rate") {
+		t.Errorf("reason = %q", report.Reason)
+	}
+}
+
+func TestSaturated(t *testing.T) {
+	ramp := Ramp{MaxP99: 100 * time.Millisecond, MaxErrorRate: 0.01, MinGain: 0.05}
+	best := Result{Concurrency: 8, Requests: 1000, Elapsed: time.Second}
+
+	tests := []struct {
+		res  Result
+		want string
+	}{
+		{Result{Concurrency: 16, Requests: 2000, Elapsed: time.Second, P99: 50 * time.Millisecond}, ""},
+		{Result{Concurrency: 16, Requests: 1040, Elapsed: time.Second}, "throughput 1040.0/s at concurrency 16, 1000.0/s at 8"},
+		{Result{Concurrency: 16, Requests: 2000, Errors: 21, Elapsed: time.Second}, "error rate 1.05% > 1.00%"},
+		{Result{Concurrency: 16, Requests: 2000, Elapsed: time.Second, P99: 120 * time.Millisecond}, "p99 120ms > 100ms"},
+	}
+	for _, tt := range tests {
+		if got := ramp.saturated(tt.res, best); got != tt.want {
+			t.Errorf("saturated(%+v) = %q, want %q", tt.res, got, tt.want)
+		}
+	}
+	// 第一级没有可比较的吞吐量
+	if got := ramp.saturated(Result{Requests: 10, Elapsed: time.Second}, Result{}); got != "" {
+		t.Errorf("first level = %q", got)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var d []time.Duration
+	for i := 1; i <= 100; i++ {
+		d = append(d, time.Duration(i))
+	}
+	for p, want := range map[float64]time.Duration{0.5: 50, 0.95: 95, 0.99: 99, 1: 100, 0: 1} {
+		if got := percentile(d, p); got != want {
+			t.Errorf("percentile(%v) = %v, want %v", p, got, want)
+		}
+	}
+}
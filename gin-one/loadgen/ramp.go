@@ -0,0 +1,79 @@
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Ramp 逐级加并发的配置，每一级的时长、请求内容见 Config
+type Ramp struct {
+	// Levels 依次使用的并发数，默认 1, 2, 4 ... 256
+	Levels []int
+
+	// MaxP99 p99 超过它就认为饱和，0 表示不检查
+	MaxP99 time.Duration
+
+	// MaxErrorRate 错误率超过它就认为饱和，默认 0.01；小于 0 表示不检查
+	MaxErrorRate float64
+
+	// MinGain 吞吐量比目前最好的一级增加不到这个比例就认为饱和，默认 0.05；小于 0 表示不检查
+	MinGain float64
+}
+
+// Report 爬坡的结果
+type Report struct {
+	Steps     []Result // 实际跑过的每一级，包括触发饱和的那一级
+	Best      Result   // 饱和前吞吐量最高的一级；第一级就超限时为零值
+	Saturated bool     // 是否在 Levels 跑完之前找到了饱和点
+	Reason    string   // 饱和的原因，如 "p99 120ms > 100ms"
+}
+
+// RunRamp 按 ramp.Levels 依次压测，饱和时停止；onStep 在每一级结束后调用，可以为 nil
+func RunRamp(ctx context.Context, cfg Config, ramp Ramp, onStep func(Result)) (Report, error) {
+	if err := cfg.setDefaults(); err != nil {
+		return Report{}, err
+	}
+	if len(ramp.Levels) == 0 {
+		ramp.Levels = []int{1, 2, 4, 8, 16, 32, 64, 128, 256}
+	}
+	if ramp.MaxErrorRate == 0 {
+		ramp.MaxErrorRate = 0.01
+	}
+	if ramp.MinGain == 0 {
+		ramp.MinGain = 0.05
+	}
+
+	var report Report
+	for _, level := range ramp.Levels {
+		cfg.Concurrency = level
+		res, err := Run(ctx, cfg)
+		if err != nil {
+			return report, err
+		}
+		report.Steps = append(report.Steps, res)
+		if onStep != nil {
+			onStep(res)
+		}
+		if reason := ramp.saturated(res, report.Best); reason != "" {
+			report.Saturated, report.Reason = true, reason
+			return report, nil
+		}
+		report.Best = res
+	}
+	return report, nil
+}
+
+// saturated 和目前最好的一级比较，返回饱和的原因；没有饱和时返回空字符串
+func (ramp *Ramp) saturated(res, best Result) string {
+	if ramp.MaxErrorRate >= 0 && res.ErrorRate() > ramp.MaxErrorRate {
+		return fmt.Sprintf("error rate %.2f%% > %.2f%%", res.ErrorRate()*100, ramp.MaxErrorRate*100)
+	}
+	if ramp.MaxP99 > 0 && res.P99 > ramp.MaxP99 {
+		return fmt.Sprintf("p99 %v > %v", res.P99, ramp.MaxP99)
+	}
+	if ramp.MinGain >= 0 && best.Requests > 0 && res.Throughput() < best.Throughput()*(1+ramp.MinGain) {
+		return fmt.Sprintf("throughput %.1f/s at concurrency %d, %.1f/s at %d", res.Throughput(), res.Concurrency, best.Throughput(), best.Concurrency)
+	}
+	return ""
+}
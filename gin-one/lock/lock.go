@@ -0,0 +1,145 @@
+// ============================================================================
+// Package lock 分布式锁：多实例部署时保证同一时刻只有一个实例执行某段逻辑
+// ============================================================================
+//
+// 【为什么】
+//
+// 同一个服务部署 N 个实例，启动时每个实例都会跑数据库迁移，每小时每个实例都会清理一次
+// 过期数据。迁移并发执行会互相踩（两个实例同时 ALTER TABLE），清理并发执行是 N 倍的无用功。
+// 需要一把所有实例都能看到的锁：
+//
+//	err := lock.Run(ctx, locker, "refresh:purge", time.Minute, func(ctx context.Context) error {
+//	    _, err := tokens.Purge(ctx)
+//	    return err
+//	})
+//	if errors.Is(err, lock.ErrNotAcquired) { ... } // 其他实例正在执行，这次跳过
+//
+// 【实现】
+//
+// | 实现        | 加锁                              | 过期                             | 适用                     |
+// |-------------|-----------------------------------|----------------------------------|--------------------------|
+// | NewRedis    | SET key token NX PX ttl           | TTL 到期自动释放，Renew 延长     | 已有 Redis 的多实例部署  |
+// | NewPostgres | pg_try_advisory_lock(hash(key))   | 持有连接断开即释放，TTL 不起作用 | 只有 PostgreSQL 的部署   |
+// | NewMemory   | 进程内 map                        | TTL 到期自动释放                 | 单实例、开发环境、测试   |
+//
+// 传多个 Redis 客户端时是简化版 RedLock：在多数节点上加锁成功才算持有，
+// 单个 Redis 节点宕机或主从切换丢了锁，不会让两个实例同时持有。
+//
+// 【TTL 和续期】
+//
+// 持有者崩溃时锁必须能被别人拿到，所以 Redis 锁带 TTL；执行时间可能超过 TTL 的任务
+// 要定期 Renew。Run / Hold 每隔 ttl/3 自动续期，续期失败（锁已过期被别人拿走）时
+// 取消传给 fn 的 ctx，fn 应该尽快停下，返回 ErrLost。
+//
+// 锁只能降低并发执行的概率（GC 停顿、网络分区都可能让两个实例短暂地都认为自己持有锁），
+// 被保护的操作本身仍然要能承受重复执行：迁移是幂等的，清理过期数据重复执行也没关系。
+//
+// ============================================================================
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// 错误定义
+var (
+	ErrNotAcquired = errors.New("lock: held by another owner")
+	ErrLost        = errors.New("lock: lost or expired")
+)
+
+// Locker 创建锁的后端
+type Locker interface {
+	// Acquire 尝试加锁，不等待；已被其他持有者占用时返回 ErrNotAcquired
+	Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+}
+
+// Lock 已持有的锁
+type Lock interface {
+	// Key 加锁时的 key
+	Key() string
+	// Renew 把过期时间重置为 ttl 之后；锁已过期或被别人拿走时返回 ErrLost
+	Renew(ctx context.Context, ttl time.Duration) error
+	// Release 释放锁，只释放自己持有的；锁已过期时返回 ErrLost
+	Release(ctx context.Context) error
+}
+
+// Wait 每隔 interval 重试一次 Acquire，直到加锁成功或 ctx 取消
+// 用在"必须执行、但不能同时执行"的场景，如启动时的数据库迁移
+func Wait(ctx context.Context, l Locker, key string, ttl, interval time.Duration) (Lock, error) {
+	for {
+		lk, err := l.Acquire(ctx, key, ttl)
+		if !errors.Is(err, ErrNotAcquired) {
+			return lk, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Run 尝试加锁，成功后执行 fn，期间自动续期，结束后释放
+// 锁被占用时不执行 fn，返回 ErrNotAcquired；用在"其他实例执行了就可以跳过"的定时任务
+func Run(ctx context.Context, l Locker, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	lk, err := l.Acquire(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+	return Hold(ctx, lk, ttl, fn)
+}
+
+// Hold 持有 lk 执行 fn：每隔 ttl/3 续期一次，fn 返回后释放
+//
+// 续期失败时取消传给 fn 的 ctx，fn 因此返回的错误替换为 ErrLost。
+// 释放用不随 ctx 取消的 context，服务关闭时也会把锁还回去。
+func Hold(ctx context.Context, lk Lock, ttl time.Duration, fn func(ctx context.Context) error) error {
+	fnCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		ticker := time.NewTicker(max(ttl/3, time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				// 网络错误也按丢锁处理：无法确认仍然持有时，宁可停下
+				if err := lk.Renew(fnCtx, ttl); err != nil {
+					if ctx.Err() == nil {
+						cancel(ErrLost)
+					}
+					return
+				}
+			}
+		}
+	}()
+
+	err := fn(fnCtx)
+	close(done)
+	<-renewed
+
+	releaseErr := lk.Release(context.WithoutCancel(ctx))
+	if cause := context.Cause(fnCtx); errors.Is(cause, ErrLost) {
+		return ErrLost
+	}
+	if err != nil {
+		return err
+	}
+	return releaseErr
+}
+
+// newToken 每次加锁的随机令牌，Renew / Release 时比较，防止释放掉别人后来加上的锁
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
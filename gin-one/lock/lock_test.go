@@ -0,0 +1,289 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// clock 可以手动拨动的时钟
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func TestMemory(t *testing.T) {
+	clk := &clock{now: time.Unix(0, 0)}
+	m := NewMemory()
+	m.now = clk.Now
+	testLocker(t, m, clk)
+}
+
+func TestRedis(t *testing.T) {
+	clk := &clock{now: time.Unix(0, 0)}
+	r := NewRedis("lock:", newFakeRedis(clk))
+	r.now = clk.Now
+	testLocker(t, r, clk)
+}
+
+// testLocker 两种实现共同的行为：互斥、过期、续期、只释放自己的锁
+func testLocker(t *testing.T, l Locker, clk *clock) {
+	ctx := context.Background()
+	a, err := l.Acquire(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Acquire(ctx, "job", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("second acquire err = %v", err)
+	}
+	if _, err := l.Acquire(ctx, "other", time.Minute); err != nil {
+		t.Fatalf("other key err = %v", err)
+	}
+
+	// 续期后原来的过期时间到了也还持有
+	clk.Advance(50 * time.Second)
+	if err := a.Renew(ctx, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(50 * time.Second)
+	if _, err := l.Acquire(ctx, "job", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("acquire after renew err = %v", err)
+	}
+
+	// 过期后别人拿到锁，原持有者续期、释放都返回 ErrLost，不会删掉别人的锁
+	clk.Advance(time.Minute)
+	b, err := l.Acquire(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatalf("acquire after expiry err = %v", err)
+	}
+	if err := a.Renew(ctx, time.Minute); !errors.Is(err, ErrLost) {
+		t.Errorf("stale renew err = %v", err)
+	}
+	if err := a.Release(ctx); !errors.Is(err, ErrLost) {
+		t.Errorf("stale release err = %v", err)
+	}
+	if _, err := l.Acquire(ctx, "job", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("stale release freed the new owner's lock: %v", err)
+	}
+
+	if err := b.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Acquire(ctx, "job", time.Minute); err != nil {
+		t.Errorf("acquire after release err = %v", err)
+	}
+}
+
+// fakeRedis 按脚本模拟 SET NX PX / 比较后 PEXPIRE / 比较后 DEL
+type fakeRedis struct {
+	mu   sync.Mutex
+	clk  *clock
+	down bool
+	data map[string]fakeEntry
+}
+
+type fakeEntry struct {
+	value   string
+	expires time.Time
+}
+
+func newFakeRedis(clk *clock) *fakeRedis {
+	return &fakeRedis{clk: clk, data: map[string]fakeEntry{}}
+}
+
+func (f *fakeRedis) Eval(_ context.Context, script string, keys []string, args ...any) (any, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return nil, errors.New("connection refused")
+	}
+	now := f.clk.Now()
+	e, ok := f.data[keys[0]]
+	if ok && !now.Before(e.expires) {
+		delete(f.data, keys[0])
+		ok = false
+	}
+	token := args[0].(string)
+	switch script {
+	case acquireScript:
+		if ok {
+			return int64(0), nil
+		}
+		f.data[keys[0]] = fakeEntry{token, now.Add(time.Duration(args[1].(int64)) * time.Millisecond)}
+		return int64(1), nil
+	case renewScript:
+		if !ok || e.value != token {
+			return int64(0), nil
+		}
+		f.data[keys[0]] = fakeEntry{token, now.Add(time.Duration(args[1].(int64)) * time.Millisecond)}
+		return int64(1), nil
+	case releaseScript:
+		if !ok || e.value != token {
+			return int64(0), nil
+		}
+		delete(f.data, keys[0])
+		return int64(1), nil
+	}
+	panic("unknown script")
+}
+
+func TestRedisQuorum(t *testing.T) {
+	ctx := context.Background()
+	clk := &clock{now: time.Unix(0, 0)}
+	nodes := []*fakeRedis{newFakeRedis(clk), newFakeRedis(clk), newFakeRedis(clk)}
+	r := NewRedis("lock:", nodes[0], nodes[1], nodes[2])
+	r.now = clk.Now
+
+	// 一个节点宕机，另外两个是多数，仍然可以加锁
+	nodes[2].down = true
+	lk, err := r.Acquire(ctx, "migrate", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 另一个竞争者只在宕机恢复的那个节点上成功，不是多数，拿不到锁，也不会留下残余
+	nodes[2].down = false
+	if _, err := r.Acquire(ctx, "migrate", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("minority acquire err = %v", err)
+	}
+	if _, ok := nodes[2].data["lock:migrate"]; ok {
+		t.Error("failed acquire left a key on node 2")
+	}
+	if err := lk.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个节点宕机：既拿不到多数也无法确定，返回节点错误而不是 ErrNotAcquired
+	nodes[0].down, nodes[1].down = true, true
+	if _, err := r.Acquire(ctx, "migrate", time.Minute); err == nil || errors.Is(err, ErrNotAcquired) {
+		t.Errorf("acquire with 2 nodes down err = %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	// 持有期间其他执行者跳过
+	var ran atomic.Int32
+	err := Run(ctx, m, "purge", time.Minute, func(ctx context.Context) error {
+		ran.Add(1)
+		if err := Run(ctx, m, "purge", time.Minute, func(context.Context) error {
+			ran.Add(1)
+			return nil
+		}); !errors.Is(err, ErrNotAcquired) {
+			t.Errorf("nested run err = %v", err)
+		}
+		return nil
+	})
+	if err != nil || ran.Load() != 1 {
+		t.Fatalf("run = %v, ran %d", err, ran.Load())
+	}
+
+	// 结束后释放
+	if err := Run(ctx, m, "purge", time.Minute, func(context.Context) error { return nil }); err != nil {
+		t.Errorf("run after release err = %v", err)
+	}
+
+	// fn 的错误原样返回，锁同样释放
+	boom := errors.New("boom")
+	if err := Run(ctx, m, "purge", time.Minute, func(context.Context) error { return boom }); !errors.Is(err, boom) {
+		t.Errorf("fn err = %v", err)
+	}
+	if _, err := m.Acquire(ctx, "purge", time.Minute); err != nil {
+		t.Errorf("lock not released after fn error: %v", err)
+	}
+}
+
+func TestHoldRenews(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	// 执行时间是 ttl 的好几倍，靠续期一直持有
+	err := Run(ctx, m, "slow", 30*time.Millisecond, func(ctx context.Context) error {
+		select {
+		case <-time.After(120 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if err != nil {
+		t.Fatalf("slow run err = %v", err)
+	}
+}
+
+// lostLock 续期总是失败，模拟锁被别人拿走
+type lostLock struct{ released atomic.Bool }
+
+func (l *lostLock) Key() string                                { return "lost" }
+func (l *lostLock) Renew(context.Context, time.Duration) error { return ErrLost }
+func (l *lostLock) Release(context.Context) error {
+	l.released.Store(true)
+	return ErrLost
+}
+
+func TestHoldLost(t *testing.T) {
+	lk := &lostLock{}
+	err := Hold(context.Background(), lk, 15*time.Millisecond, func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			t.Error("fn ctx not cancelled after losing the lock")
+			return nil
+		}
+	})
+	if !errors.Is(err, ErrLost) || !lk.released.Load() {
+		t.Errorf("hold err = %v, released %v", err, lk.released.Load())
+	}
+}
+
+func TestWait(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	first, _ := m.Acquire(ctx, "migrate", time.Minute)
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		first.Release(ctx)
+	}()
+	lk, err := Wait(ctx, m, "migrate", time.Minute, 5*time.Millisecond)
+	if err != nil || lk.Key() != "migrate" {
+		t.Fatalf("wait = %v, %v", lk, err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := Wait(timeout, m, "migrate", time.Minute, 5*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait timeout err = %v", err)
+	}
+}
+
+func TestAdvisoryKey(t *testing.T) {
+	seen := map[int64]bool{}
+	for i := range 1000 {
+		k := advisoryKey("job:" + strconv.Itoa(i))
+		if seen[k] {
+			t.Fatalf("collision at %d", i)
+		}
+		seen[k] = true
+	}
+	if advisoryKey("migrate") != advisoryKey("migrate") {
+		t.Error("advisoryKey not stable")
+	}
+}
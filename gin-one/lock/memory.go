@@ -0,0 +1,78 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// 内存实现
+// ============================================================================
+//
+// 只在一个进程内互斥，多实例部署时每个实例各有一份，等于没有锁；
+// 用于单实例部署、开发环境和测试。
+
+// Memory 进程内的锁
+type Memory struct {
+	mu   sync.Mutex
+	held map[string]memoryEntry
+	now  func() time.Time
+}
+
+type memoryEntry struct {
+	token   string
+	expires time.Time
+}
+
+// NewMemory 创建进程内的锁
+func NewMemory() *Memory {
+	return &Memory{held: make(map[string]memoryEntry), now: time.Now}
+}
+
+// Acquire 实现 Locker
+func (m *Memory) Acquire(_ context.Context, key string, ttl time.Duration) (Lock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if e, ok := m.held[key]; ok && now.Before(e.expires) {
+		return nil, ErrNotAcquired
+	}
+	lk := &memoryLock{m: m, key: key, token: newToken()}
+	m.held[key] = memoryEntry{token: lk.token, expires: now.Add(ttl)}
+	return lk, nil
+}
+
+type memoryLock struct {
+	m     *Memory
+	key   string
+	token string
+}
+
+func (l *memoryLock) Key() string { return l.key }
+
+func (l *memoryLock) Renew(_ context.Context, ttl time.Duration) error {
+	l.m.mu.Lock()
+	defer l.m.mu.Unlock()
+	if !l.ownedLocked() {
+		return ErrLost
+	}
+	l.m.held[l.key] = memoryEntry{token: l.token, expires: l.m.now().Add(ttl)}
+	return nil
+}
+
+func (l *memoryLock) Release(context.Context) error {
+	l.m.mu.Lock()
+	defer l.m.mu.Unlock()
+	if !l.ownedLocked() {
+		return ErrLost
+	}
+	delete(l.m.held, l.key)
+	return nil
+}
+
+// ownedLocked 调用方持有 m.mu；过期的锁即使还在 map 里也不算持有
+func (l *memoryLock) ownedLocked() bool {
+	e, ok := l.m.held[l.key]
+	return ok && e.token == l.token && l.m.now().Before(e.expires)
+}
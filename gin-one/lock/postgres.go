@@ -0,0 +1,97 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"hash/fnv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// PostgreSQL advisory lock 实现
+// ============================================================================
+//
+// pg_try_advisory_lock 是会话级的锁：谁加的锁谁释放，连接断开时自动释放。
+// 所以每把锁从连接池里借出一个专用连接，一直占用到 Release：
+//
+//	持有者崩溃 → TCP 断开 → PostgreSQL 结束会话 → 锁自动释放
+//
+// 不需要 TTL，Acquire 和 Renew 的 ttl 参数被忽略；Renew 只检查连接是否还活着，
+// 连接断了说明锁已经随会话释放。每持有一把锁连接池里就少一个可用连接，
+// 不适合大量、长时间的锁。key 用 FNV-1a 哈希成 advisory lock 需要的 bigint，
+// 不同的 key 哈希冲突时会互相阻塞，但不会同时持有。
+
+// Postgres 基于 advisory lock 的锁
+type Postgres struct {
+	db *gorm.DB
+}
+
+// NewPostgres 创建 advisory lock，db 必须是 PostgreSQL 连接
+func NewPostgres(db *gorm.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// advisoryKey key 的 64 位哈希
+func advisoryKey(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// Acquire 实现 Locker
+func (p *Postgres) Acquire(ctx context.Context, key string, _ time.Duration) (Lock, error) {
+	sqlDB, err := p.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id := advisoryKey(key)
+	var ok bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", id).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !ok {
+		conn.Close()
+		return nil, ErrNotAcquired
+	}
+	return &postgresLock{conn: conn, key: key, id: id}, nil
+}
+
+type postgresLock struct {
+	conn *sql.Conn
+	key  string
+	id   int64
+}
+
+func (l *postgresLock) Key() string { return l.key }
+
+func (l *postgresLock) Renew(ctx context.Context, _ time.Duration) error {
+	if err := l.conn.PingContext(ctx); err != nil {
+		return ErrLost
+	}
+	return nil
+}
+
+func (l *postgresLock) Release(ctx context.Context) error {
+	var ok bool
+	err := l.conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", l.id).Scan(&ok)
+	if err != nil {
+		// 解锁失败时不能把连接还回连接池：会话还在，锁也还在。
+		// Raw 返回 driver.ErrBadConn，database/sql 会关闭这个连接而不是复用
+		_ = l.conn.Raw(func(any) error { return driver.ErrBadConn })
+		l.conn.Close()
+		return err
+	}
+	l.conn.Close()
+	if !ok {
+		return ErrLost
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-one/rediseval"
+)
+
+// ============================================================================
+// Redis 实现
+// ============================================================================
+//
+// 加锁是 SET key token NX PX ttl；续期和释放先比较 token 再 PEXPIRE / DEL，
+// 比较和修改必须在同一个 Lua 脚本里，否则 GET 之后锁恰好过期被别人拿走，DEL 删掉的是别人的锁。
+//
+// 客户端接口和 go-redis 的适配方法见 rediseval。
+//
+// 【多个节点（RedLock-lite）】
+//
+// 传入 N 个互相独立的 Redis（不是同一个集群的主从）时，在超过半数的节点上加锁成功，
+// 且加锁耗时加上时钟漂移小于 ttl，才算持有；否则立即释放已加上的部分。
+// 和完整的 RedLock 相比没有失败后的随机等待重试，重试交给 Wait。
+
+// 脚本统一返回整数：nil 回复在 go-redis 里是 redis.Nil 错误
+const (
+	acquireScript = `
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  return 1
+end
+return 0
+`
+	renewScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`
+	releaseScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+)
+
+// clockDrift 各节点时钟和网络延迟的余量：ttl 的 1% 加 2ms（RedLock 论文的取值）
+func clockDrift(ttl time.Duration) time.Duration {
+	return ttl/100 + 2*time.Millisecond
+}
+
+// Redis 基于一个或多个 Redis 节点的锁
+type Redis struct {
+	clients []rediseval.Client
+	prefix  string
+	now     func() time.Time
+}
+
+// NewRedis 创建 Redis 锁，所有 key 加上 prefix（如 "lock:"）；至少需要一个客户端
+func NewRedis(prefix string, clients ...rediseval.Client) *Redis {
+	if len(clients) == 0 {
+		panic("lock: NewRedis needs at least one client")
+	}
+	return &Redis{clients: clients, prefix: prefix, now: time.Now}
+}
+
+func (r *Redis) quorum() int {
+	return len(r.clients)/2 + 1
+}
+
+// Acquire 实现 Locker
+func (r *Redis) Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	lk := &redisLock{r: r, key: key, token: newToken()}
+	start := r.now()
+	ok, errs := r.each(ctx, acquireScript, lk, ttl.Milliseconds())
+	if ok >= r.quorum() && r.now().Sub(start)+clockDrift(ttl) < ttl {
+		return lk, nil
+	}
+
+	// 没有拿到多数，或者加锁太慢、剩余有效期不够：释放已加上的部分
+	_, _ = r.each(context.WithoutCancel(ctx), releaseScript, lk)
+	if ok+len(errs) < r.quorum() || len(errs) == 0 {
+		return nil, ErrNotAcquired
+	}
+	return nil, errors.Join(errs...)
+}
+
+// each 在每个节点上执行脚本，返回回复 1 的节点数和出错的节点的错误
+func (r *Redis) each(ctx context.Context, script string, lk *redisLock, args ...any) (int, []error) {
+	ok := 0
+	var errs []error
+	for i, c := range r.clients {
+		reply, err := c.Eval(ctx, script, []string{r.prefix + lk.key}, append([]any{lk.token}, args...)...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("lock: redis node %d: %w", i, err))
+			continue
+		}
+		n, isInt := reply.(int64)
+		if !isInt {
+			errs = append(errs, fmt.Errorf("lock: redis node %d: unexpected reply %v", i, reply))
+			continue
+		}
+		if n == 1 {
+			ok++
+		}
+	}
+	return ok, errs
+}
+
+type redisLock struct {
+	r     *Redis
+	key   string
+	token string
+}
+
+func (l *redisLock) Key() string { return l.key }
+
+func (l *redisLock) Renew(ctx context.Context, ttl time.Duration) error {
+	return l.result(l.r.each(ctx, renewScript, l, ttl.Milliseconds()))
+}
+
+func (l *redisLock) Release(ctx context.Context) error {
+	return l.result(l.r.each(ctx, releaseScript, l))
+}
+
+// result 多数节点成功才算成功；否则有节点出错时返回错误，全都正常回复说明锁已不属于自己
+func (l *redisLock) result(ok int, errs []error) error {
+	if ok >= l.r.quorum() {
+		return nil
+	}
+	if len(errs) > 0 {
+		return errors.Join(append([]error{ErrLost}, errs...)...)
+	}
+	return ErrLost
+}
@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// LevelRequest POST /loglevel 的请求体
+type LevelRequest struct {
+	Level string `json:"level" binding:"required" example:"debug"`
+	// Duration 多久后恢复到修改前的级别，如 "10m"；为空时一直保持，忘了改回去 debug 日志会一直刷
+	Duration string `json:"duration" example:"10m"`
+}
+
+// LevelStatus GET / POST /loglevel 的响应
+type LevelStatus struct {
+	Level string `json:"level"`
+	// Until 临时级别恢复的时间，没有临时级别时为空
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// RegisterLevel 在 group 上注册 GET / POST /loglevel，调用方负责加认证和管理员权限中间件
+//
+// 修改记录在 logger 上（Warn 级别，调到 error 也能看到是谁改的），user_id 取认证中间件写入的值
+func RegisterLevel(group gin.IRoutes, level *slog.LevelVar, logger *slog.Logger) {
+	lc := &levelControl{level: level, logger: logger}
+	group.GET("/loglevel", func(c *gin.Context) {
+		response.Success(c, lc.status())
+	})
+	group.POST("/loglevel", lc.set)
+}
+
+// levelControl 保存临时级别的恢复定时器
+type levelControl struct {
+	level  *slog.LevelVar
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	timer  *time.Timer
+	until  time.Time
+	revert slog.Level // 临时级别结束后恢复的级别
+}
+
+func (lc *levelControl) status() LevelStatus {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	s := LevelStatus{Level: levelName(lc.level.Level())}
+	if lc.timer != nil {
+		until := lc.until
+		s.Until = &until
+	}
+	return s
+}
+
+func (lc *levelControl) set(c *gin.Context) {
+	var req LevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid_request", "需要 level")
+		return
+	}
+	level, err := ParseLevel(req.Level)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid_level", "level 只能是 debug / info / warn / error")
+		return
+	}
+	var d time.Duration
+	if req.Duration != "" {
+		if d, err = time.ParseDuration(req.Duration); err != nil || d <= 0 {
+			response.Error(c, http.StatusBadRequest, "invalid_duration", "duration 格式如 30s、10m、1h")
+			return
+		}
+	}
+
+	lc.mu.Lock()
+	old := lc.level.Level()
+	// 已经有临时级别时，恢复到最初的级别，而不是上一个临时级别
+	revert := old
+	if lc.timer != nil {
+		lc.timer.Stop()
+		lc.timer = nil
+		revert = lc.revert
+	}
+	lc.level.Set(level)
+	if d > 0 {
+		lc.revert, lc.until = revert, time.Now().Add(d)
+		lc.timer = time.AfterFunc(d, func() { lc.expire(revert) })
+	}
+	lc.mu.Unlock()
+
+	if lc.logger != nil {
+		lc.logger.Warn("log level changed",
+			slog.String("from", levelName(old)), slog.String("to", levelName(level)),
+			slog.String("duration", req.Duration), slog.Any("user_id", c.Value("user_id")))
+	}
+	response.Success(c, lc.status())
+}
+
+// expire 临时级别到期，恢复修改前的级别
+func (lc *levelControl) expire(revert slog.Level) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.timer = nil
+	lc.level.Set(revert)
+	if lc.logger != nil {
+		lc.logger.Warn("log level restored", slog.String("level", levelName(revert)))
+	}
+}
+
+func levelName(l slog.Level) string {
+	return strings.ToLower(l.String())
+}
@@ -0,0 +1,142 @@
+// ============================================================================
+// Package logging 应用日志的输出端：标准输出 + 按大小轮转的文件、运行时调整级别
+// ============================================================================
+//
+// app.ProvideLogger 原来只往标准输出写 JSON。容器里这样就够了（收集器读 stdout），
+// 但直接部署在虚拟机上时日志要落盘，落盘就要轮转，否则一个文件写满磁盘；
+// 线上排查问题时还想临时打开 debug，不能为此重启进程。
+//
+// | 组件          | 作用                                                              |
+// |---------------|-------------------------------------------------------------------|
+// | File          | io.Writer，超过 MaxSize 时改名为 app-<时间>.log 并新建文件；     |
+// |               | 后台按 MaxBackups / MaxAge 删除旧文件，Compress 时 gzip 压缩      |
+// | Fanout        | 把一条记录交给多个 slog.Handler（标准输出一份、文件一份）         |
+// | LevelVar      | 所有输出端共用一个级别，修改后立即对所有 Logger 生效              |
+// | RegisterLevel | GET / POST /loglevel 查看、修改级别，可以指定多久后自动恢复       |
+//
+// File 只是一个 io.Writer，不关心内容格式：JSON Lines 审计日志、CSV 导出同样可以用它轮转。
+//
+// 【用法】
+//
+//	l, err := logging.New(logging.Config{
+//		Level:  "info",
+//		Format: "json",
+//		Stdout: os.Stdout,
+//		File:   logging.FileConfig{Filename: "logs/app.log", MaxSize: 100 << 20, MaxBackups: 7, Compress: true},
+//	})
+//	defer l.Close()
+//	slog.SetDefault(l.Logger)
+//
+//	debug := r.Group("/debug", JWTAuthMiddleware(), RoleMiddleware("admin"))
+//	logging.RegisterLevel(debug, l.Level, l.Logger)
+//
+//	curl -X POST http://localhost:8080/debug/loglevel -H "Authorization: Bearer <token>" \
+//	  -d '{"level":"debug","duration":"10m"}'
+//
+// 【轮转的时机】
+//
+// 写入前检查：当前文件加上这次写入会超过 MaxSize 时先轮转，所以一条日志不会被拆到两个文件里。
+// 单条超过 MaxSize 的记录照样写入一个新文件。进程重启后接着写已有的文件（追加），不会每次启动都轮转。
+//
+// ============================================================================
+package logging
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// 错误定义
+var (
+	ErrNoOutput = errors.New("logging: neither Stdout nor File.Filename is set")
+	ErrLevel    = errors.New("logging: unknown level")
+)
+
+// Config 日志配置
+type Config struct {
+	// Level debug / info / warn / error，默认 info
+	Level string
+
+	// Format json / text，默认 json；标准输出和文件使用相同格式
+	Format string
+
+	// Stdout 标准输出一份，通常是 os.Stdout；为 nil 时只写文件
+	Stdout io.Writer
+
+	// File Filename 不为空时同时写入轮转的日志文件
+	File FileConfig
+
+	// AddSource 记录调用位置（文件:行号）
+	AddSource bool
+}
+
+// Logger 配置好输出端的 slog.Logger，Level 可以在运行时修改
+type Logger struct {
+	*slog.Logger
+	Level *slog.LevelVar
+
+	file *File
+}
+
+// New 按配置创建 Logger；写文件时调用方负责在退出前 Close
+func New(cfg Config) (*Logger, error) {
+	level := new(slog.LevelVar)
+	if cfg.Level != "" {
+		l, err := ParseLevel(cfg.Level)
+		if err != nil {
+			return nil, err
+		}
+		level.Set(l)
+	}
+
+	l := &Logger{Level: level}
+	opts := &slog.HandlerOptions{Level: level, AddSource: cfg.AddSource}
+	var handlers []slog.Handler
+	if cfg.Stdout != nil {
+		handlers = append(handlers, newHandler(cfg.Format, cfg.Stdout, opts))
+	}
+	if cfg.File.Filename != "" {
+		f, err := OpenFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		l.file = f
+		handlers = append(handlers, newHandler(cfg.Format, f, opts))
+	}
+	if len(handlers) == 0 {
+		return nil, ErrNoOutput
+	}
+	l.Logger = slog.New(Fanout(handlers...))
+	return l, nil
+}
+
+// Close 关闭日志文件，等待后台的压缩和清理结束；没有文件时什么也不做
+func (l *Logger) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// File 日志文件，没有配置文件时为 nil；可以用来在收到 SIGHUP 时手动 Rotate
+func (l *Logger) File() *File {
+	return l.file
+}
+
+func newHandler(format string, w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	if strings.EqualFold(format, "text") {
+		return slog.NewTextHandler(w, opts)
+	}
+	return slog.NewJSONHandler(w, opts)
+}
+
+// ParseLevel debug / info / warn / error（不区分大小写，也接受 INFO+2 这种 slog 写法）
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, errors.Join(ErrLevel, err)
+	}
+	return l, nil
+}
@@ -0,0 +1,281 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clock 每次调用前进 1 秒，连续轮转的文件名不会重复
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time {
+	c.t = c.t.Add(time.Second)
+	return c.t
+}
+
+func openTest(t *testing.T, cfg FileConfig) (*File, *clock) {
+	t.Helper()
+	clk := &clock{t: time.Date(2026, 10, 15, 7, 59, 59, 0, time.UTC)}
+	f, err := openFile(cfg, clk.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f, clk
+}
+
+func files(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestFileRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	f, _ := openTest(t, FileConfig{Filename: filepath.Join(dir, "app.log"), MaxSize: 10})
+
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "a very long line\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	want := []string{
+		"app-2026-10-15T08-00-01.000.log", // aaaa bbbb
+		"app-2026-10-15T08-00-02.000.log", // cccc
+		"app.log",                         // 超过 MaxSize 的单条照样写入
+	}
+	if got := files(t, dir); !slices.Equal(got, want) {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	if got := read(t, filepath.Join(dir, want[0])); got != "aaaa\nbbbb\n" {
+		t.Errorf("first backup = %q", got)
+	}
+	if got := read(t, filepath.Join(dir, "app.log")); got != "a very long line\n" {
+		t.Errorf("current = %q", got)
+	}
+	if _, err := f.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("write after close err = %v", err)
+	}
+}
+
+func TestFileAppendsOnReopen(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "logs", "app.log")
+	for _, line := range []string{"one\n", "two\n"} {
+		f, err := OpenFile(FileConfig{Filename: name, MaxSize: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(line))
+		f.Close()
+	}
+	if got := read(t, name); got != "one\ntwo\n" {
+		t.Errorf("content = %q", got)
+	}
+}
+
+func TestFileMill(t *testing.T) {
+	t.Run("max backups", func(t *testing.T) {
+		dir := t.TempDir()
+		f, _ := openTest(t, FileConfig{Filename: filepath.Join(dir, "app.log"), MaxBackups: 2})
+		// 不是轮转出的文件，不能删
+		os.WriteFile(filepath.Join(dir, "app-error.log"), nil, 0o644)
+		for range 4 {
+			f.Write([]byte("x\n"))
+			f.Rotate()
+		}
+		f.Close()
+
+		want := []string{"app-2026-10-15T08-00-03.000.log", "app-2026-10-15T08-00-04.000.log", "app-error.log", "app.log"}
+		if got := files(t, dir); !slices.Equal(got, want) {
+			t.Fatalf("files = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("max age", func(t *testing.T) {
+		dir := t.TempDir()
+		f, clk := openTest(t, FileConfig{Filename: filepath.Join(dir, "app.log"), MaxAge: time.Hour})
+		f.Rotate() // 08:00:01
+		f.wg.Wait()
+		clk.t = clk.t.Add(2 * time.Hour)
+		f.Rotate() // 10:00:02，08:00:01 已经过期
+		f.Close()
+
+		want := []string{"app-2026-10-15T10-00-02.000.log", "app.log"}
+		if got := files(t, dir); !slices.Equal(got, want) {
+			t.Fatalf("files = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("compress", func(t *testing.T) {
+		dir := t.TempDir()
+		f, _ := openTest(t, FileConfig{Filename: filepath.Join(dir, "app.log"), Compress: true, MaxBackups: 1})
+		f.Write([]byte("first\n"))
+		f.Rotate()
+		f.wg.Wait()
+		// 模拟上次压缩到一半退出：.log 和残缺的 .gz 同时存在
+		os.WriteFile(filepath.Join(dir, "app-2026-10-15T07-00-00.000.log"), []byte("old\n"), 0o644)
+		os.WriteFile(filepath.Join(dir, "app-2026-10-15T07-00-00.000.log.gz"), []byte("broken"), 0o644)
+		f.Write([]byte("second\n"))
+		f.Rotate()
+		f.Close()
+
+		// 残缺的两份算一个备份，超出 MaxBackups 一起删除
+		want := []string{"app-2026-10-15T08-00-02.000.log.gz", "app.log"}
+		if got := files(t, dir); !slices.Equal(got, want) {
+			t.Fatalf("files = %v, want %v", got, want)
+		}
+		gz, err := os.Open(filepath.Join(dir, want[0]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gz.Close()
+		zr, err := gzip.NewReader(gz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := io.ReadAll(zr); string(b) != "second\n" {
+			t.Errorf("decompressed = %q", b)
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Config{}); !errors.Is(err, ErrNoOutput) {
+		t.Errorf("no output err = %v", err)
+	}
+	if _, err := New(Config{Level: "verbose", Stdout: io.Discard}); !errors.Is(err, ErrLevel) {
+		t.Errorf("bad level err = %v", err)
+	}
+
+	dir := t.TempDir()
+	var stdout bytes.Buffer
+	l, err := New(Config{Level: "warn", Stdout: &stdout, File: FileConfig{Filename: filepath.Join(dir, "app.log")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.With("svc", "api").Info("dropped")
+	l.With("svc", "api").Warn("kept", "n", 1)
+	l.Level.Set(slog.LevelDebug)
+	l.Debug("now visible")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file := read(t, filepath.Join(dir, "app.log"))
+	if stdout.String() != file {
+		t.Errorf("stdout and file differ:\n%s\n%s", stdout.String(), file)
+	}
+	lines := strings.Split(strings.TrimSpace(file), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	var rec map[string]any
+	json.Unmarshal([]byte(lines[0]), &rec)
+	if rec["msg"] != "kept" || rec["svc"] != "api" || rec["n"] != 1.0 {
+		t.Errorf("record = %v", rec)
+	}
+}
+
+// failing 模拟磁盘满
+type failing struct{}
+
+func (failing) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestFanout(t *testing.T) {
+	var info, debug bytes.Buffer
+	h := Fanout(
+		slog.NewTextHandler(failing{}, nil),
+		slog.NewTextHandler(&info, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		slog.NewTextHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	)
+	logger := slog.New(h).WithGroup("req").With("id", 7)
+	logger.Debug("d")
+	logger.Info("i")
+
+	if strings.Contains(info.String(), "msg=d") || !strings.Contains(info.String(), "msg=i req.id=7") {
+		t.Errorf("info sink = %q", info.String())
+	}
+	if !strings.Contains(debug.String(), "msg=d req.id=7") || !strings.Contains(debug.String(), "msg=i") {
+		t.Errorf("debug sink = %q", debug.String())
+	}
+	if err := h.Handle(t.Context(), slog.NewRecord(time.Now(), slog.LevelInfo, "x", 0)); err == nil {
+		t.Error("want error from failing sink")
+	}
+}
+
+func TestRegisterLevel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	level := new(slog.LevelVar)
+	r := gin.New()
+	g := r.Group("/debug", func(c *gin.Context) { c.Set("user_id", uint(1)) })
+	RegisterLevel(g, level, slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	do := func(method, body string) (int, LevelStatus) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/debug/loglevel", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(rec, req)
+		var resp struct{ Data LevelStatus }
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	for _, body := range []string{`{}`, `{"level":"verbose"}`, `{"level":"debug","duration":"soon"}`, `{"level":"debug","duration":"-1m"}`} {
+		if code, _ := do(http.MethodPost, body); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", body, code)
+		}
+	}
+
+	code, s := do(http.MethodPost, `{"level":"DEBUG"}`)
+	if code != http.StatusOK || s.Level != "debug" || s.Until != nil || level.Level() != slog.LevelDebug {
+		t.Fatalf("set = %d %+v", code, s)
+	}
+	if !strings.Contains(logs.String(), `"from":"info","to":"debug"`) || !strings.Contains(logs.String(), `"user_id":1`) {
+		t.Errorf("audit log = %s", logs.String())
+	}
+
+	// 连续设置临时级别，到期后恢复到第一次临时修改之前的级别
+	do(http.MethodPost, `{"level":"error","duration":"1h"}`)
+	_, s = do(http.MethodPost, `{"level":"warn","duration":"30ms"}`)
+	if s.Level != "warn" || s.Until == nil {
+		t.Fatalf("temporary = %+v", s)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, s = do(http.MethodGet, ""); s.Level != "debug" || s.Until != nil {
+		t.Errorf("after expiry = %+v", s)
+	}
+}
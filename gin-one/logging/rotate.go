@@ -0,0 +1,269 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileConfig 轮转文件的配置
+type FileConfig struct {
+	// Filename 当前写入的文件，如 logs/app.log；目录不存在时自动创建
+	Filename string
+
+	// MaxSize 单个文件的最大字节数，默认 100MB
+	MaxSize int64
+
+	// MaxAge 轮转出的文件保留多久（按文件名里的时间），0 表示不按时间删除
+	MaxAge time.Duration
+
+	// MaxBackups 最多保留几个轮转出的文件，0 表示不限
+	MaxBackups int
+
+	// Compress 轮转出的文件在后台 gzip 压缩为 .gz
+	Compress bool
+}
+
+// backupTimeFormat 轮转文件名里的时间（UTC），按字典序排序就是时间顺序；不用冒号，Windows 也能用
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// File 按大小轮转的日志文件，可以并发写入
+type File struct {
+	cfg FileConfig
+	now func() time.Time // 只在持有 mu 时调用；测试时替换
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+
+	millMu sync.Mutex     // 同一时间只有一个清理 / 压缩在跑
+	wg     sync.WaitGroup // Close 等待后台清理结束
+}
+
+// OpenFile 打开（追加）或创建日志文件，并在后台清理一次过期的旧文件
+func OpenFile(cfg FileConfig) (*File, error) {
+	return openFile(cfg, time.Now)
+}
+
+func openFile(cfg FileConfig, now func() time.Time) (*File, error) {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 100 << 20
+	}
+	f := &File{cfg: cfg, now: now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	f.startMill(f.now())
+	return f, nil
+}
+
+func (f *File) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.cfg.Filename), 0o755); err != nil {
+		return fmt.Errorf("logging: %w", err)
+	}
+	file, err := os.OpenFile(f.cfg.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("logging: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("logging: %w", err)
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// Write 写入一条记录，当前文件放不下时先轮转
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.size > 0 && f.size+int64(len(p)) > f.cfg.MaxSize {
+		if err := f.rotateLocked(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate 立即轮转，如收到 SIGHUP 时；当前文件为空时也会轮转
+func (f *File) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.rotateLocked()
+}
+
+func (f *File) rotateLocked() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("logging: %w", err)
+	}
+	now := f.now()
+	if err := os.Rename(f.cfg.Filename, f.backupName(now)); err != nil {
+		return fmt.Errorf("logging: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.startMill(now)
+	return nil
+}
+
+// Close 关闭文件并等待后台的清理 / 压缩结束
+func (f *File) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	err := f.file.Close()
+	f.mu.Unlock()
+	f.wg.Wait()
+	return err
+}
+
+// backupName logs/app.log → logs/app-2026-10-15T08-30-00.000.log
+func (f *File) backupName(t time.Time) string {
+	dir, prefix, ext := f.parts()
+	return filepath.Join(dir, prefix+t.UTC().Format(backupTimeFormat)+ext)
+}
+
+// parts 目录、轮转文件名的前缀（app-）和扩展名（.log）
+func (f *File) parts() (dir, prefix, ext string) {
+	dir = filepath.Dir(f.cfg.Filename)
+	base := filepath.Base(f.cfg.Filename)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+func (f *File) startMill(now time.Time) {
+	if f.cfg.MaxAge <= 0 && f.cfg.MaxBackups <= 0 && !f.cfg.Compress {
+		return
+	}
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.millMu.Lock()
+		defer f.millMu.Unlock()
+		// 在后台运行，没有调用方可以返回错误；也不能写进日志本身（可能正是磁盘满了）
+		if err := f.mill(now); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
+}
+
+// backup 一个轮转出的文件；压缩到一半进程退出时 .log 和 .gz 同时存在，算作同一个
+type backup struct {
+	at    time.Time
+	paths []string
+	gz    bool // 只有 .gz，已经压缩完成
+}
+
+// backups 按时间从新到旧列出轮转出的文件
+func (f *File) backups() ([]*backup, error) {
+	dir, prefix, ext := f.parts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byTime := map[time.Time]*backup{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp, gz := strings.CutSuffix(strings.TrimPrefix(name, prefix), ".gz")
+		stamp, ok := strings.CutSuffix(stamp, ext)
+		at, err := time.Parse(backupTimeFormat, stamp)
+		if !ok || err != nil {
+			continue // 不是轮转出的文件，如 app-error.log
+		}
+		b := byTime[at]
+		if b == nil {
+			b = &backup{at: at, gz: true}
+			byTime[at] = b
+		}
+		b.paths = append(b.paths, filepath.Join(dir, name))
+		b.gz = b.gz && gz
+	}
+	out := make([]*backup, 0, len(byTime))
+	for _, b := range byTime {
+		out = append(out, b)
+	}
+	slices.SortFunc(out, func(a, b *backup) int { return b.at.Compare(a.at) })
+	return out, nil
+}
+
+// mill 删除超出 MaxBackups 或早于 MaxAge 的文件，压缩剩下的未压缩文件
+func (f *File) mill(now time.Time) error {
+	list, err := f.backups()
+	if err != nil {
+		return fmt.Errorf("logging: list backups: %w", err)
+	}
+	for i, b := range list {
+		expired := f.cfg.MaxBackups > 0 && i >= f.cfg.MaxBackups ||
+			f.cfg.MaxAge > 0 && now.Sub(b.at) > f.cfg.MaxAge
+		if expired {
+			for _, p := range b.paths {
+				if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+					return fmt.Errorf("logging: remove backup: %w", err)
+				}
+			}
+			continue
+		}
+		if f.cfg.Compress && !b.gz {
+			if err := compress(b.paths); err != nil {
+				return fmt.Errorf("logging: compress backup: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// compress 把未压缩的文件写成同名 .gz 后删除原文件；上次没压缩完的 .gz 直接覆盖
+func compress(paths []string) error {
+	src := ""
+	for _, p := range paths {
+		if !strings.HasSuffix(p, ".gz") {
+			src = p
+		}
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(src+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
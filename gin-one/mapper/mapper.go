@@ -0,0 +1,621 @@
+// ============================================================================
+// Package mapper 结构体映射：用反射把请求结构体复制到模型（或反过来），省掉逐字段赋值
+// ============================================================================
+//
+// 【用法】
+//
+//	var in service.UpdateUserInput
+//	if err := mapper.Copy(&in, req); err != nil { ... }
+//
+//	dto, err := mapper.Map[UserDTO](user)   // 目标是新值时更方便
+//	dtos, err := mapper.Map[[]UserDTO](users)
+//
+// 【字段匹配】
+//
+// 按字段名匹配，mapper:"name" 标签改名，mapper:"-" 跳过；两边都可以写标签。
+// 匿名嵌入的结构体（gorm.Model）展开到外层，外层同名字段优先。
+// 只在一边出现的字段不处理，目标里原有的值保留。
+//
+// 【类型转换】
+//
+// | 源 → 目标                         | 处理                                               |
+// |-----------------------------------|----------------------------------------------------|
+// | 相同类型                          | 直接赋值；指针、切片、map 深拷贝，不和源共享内存   |
+// | string ↔ int / uint / float / bool | strconv 解析 / 格式化，解析失败返回错误            |
+// | 数字 → 数字                       | 溢出（300 → int8）、小数 → 整数丢精度时返回错误    |
+// | 底层类型相同（string → Status）   | 类型转换                                           |
+// | *T → T                            | 解引用；nil 时目标保持不变（PATCH 请求没传的字段） |
+// | T → *T                            | 分配新值                                           |
+// | time.Time ↔ string                | RFC 3339                                           |
+// | 结构体 → 结构体                   | 按上面的规则逐字段映射                             |
+// | 切片 / 数组 → 切片，map → map      | 逐个元素转换                                       |
+// | Register 注册的类型对             | 调用注册的函数，优先于以上规则                     |
+//
+// 字段名相同但类型无法转换时返回 ErrUnsupported，而不是悄悄跳过：多半是结构体改了一边忘了另一边。
+//
+// 【性能】
+//
+// 每一对（目标类型，源类型）第一次映射时把字段匹配和转换规则编译成函数，缓存在 sync.Map 里，
+// 之后只剩 FieldByIndex 和赋值。benchmark（见 mapper_test.go）：手写赋值约 10 倍快，
+// 对请求处理来说 mapper 的开销（百纳秒级）可以忽略，但不要在百万行的循环里用。
+//
+// ============================================================================
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 错误定义
+var (
+	ErrInvalidTarget = errors.New("mapper: dst must be a non-nil pointer")
+	ErrUnsupported   = errors.New("mapper: unsupported conversion")
+	ErrOverflow      = errors.New("mapper: value out of range")
+)
+
+// Copy 把 src 映射到 dst 指向的值，dst 必须是非 nil 指针
+//
+// 出错时 dst 可能已经写入了部分字段。
+func Copy(dst, src any) error {
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Pointer || dv.IsNil() {
+		return fmt.Errorf("%w, got %T", ErrInvalidTarget, dst)
+	}
+	sv := reflect.ValueOf(src)
+	if !sv.IsValid() {
+		return nil // src 是 nil，没有可复制的
+	}
+	p := lookup(dv.Type().Elem(), sv.Type())
+	if p.err != nil {
+		return p.err
+	}
+	if err := p.fn(dv.Elem(), sv); err != nil {
+		return fmt.Errorf("mapper: %w", err)
+	}
+	return nil
+}
+
+// Map 把 src 映射成新的 D
+func Map[D any](src any) (D, error) {
+	var d D
+	err := Copy(&d, src)
+	return d, err
+}
+
+// Register 注册 S → D 的转换函数，优先于内置规则；在启动时、第一次 Copy 之前调用
+//
+//	mapper.Register(func(d decimal.Decimal) (string, error) { return d.String(), nil })
+func Register[S, D any](fn func(S) (D, error)) {
+	buildMu.Lock()
+	defer buildMu.Unlock()
+	converters[pair{reflect.TypeFor[D](), reflect.TypeFor[S]()}] = func(dst, src reflect.Value) error {
+		v, err := fn(src.Interface().(S))
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(&v).Elem())
+		return nil
+	}
+	plans.Clear() // 已编译的计划可能用到了旧规则
+}
+
+func init() {
+	Register(func(t time.Time) (string, error) { return t.Format(time.RFC3339Nano), nil })
+	Register(func(s string) (time.Time, error) { return time.Parse(time.RFC3339Nano, s) })
+}
+
+// ============================================================================
+// 编译缓存
+// ============================================================================
+
+// convFunc 把 src 转换后写入 dst，dst 可寻址
+type convFunc func(dst, src reflect.Value) error
+
+type pair struct {
+	dst, src reflect.Type
+}
+
+// plan 一对类型的转换函数；自引用的类型（树、链表）在编译完成前就会被引用，所以存指针
+type plan struct {
+	fn  convFunc
+	err error
+}
+
+// call 间接调用，编译递归类型时 fn 还没有赋值
+func (p *plan) call(dst, src reflect.Value) error {
+	if p.err != nil {
+		return p.err
+	}
+	return p.fn(dst, src)
+}
+
+var (
+	plans      sync.Map // pair → *plan
+	buildMu    sync.Mutex
+	converters = map[pair]convFunc{}
+)
+
+func lookup(dt, st reflect.Type) *plan {
+	if p, ok := plans.Load(pair{dt, st}); ok {
+		return p.(*plan)
+	}
+	buildMu.Lock()
+	defer buildMu.Unlock()
+	return build(dt, st, make(map[pair]*plan))
+}
+
+// build 编译一对类型，building 记录本次编译中还没完成的类型对，遇到时直接返回（处理自引用）
+func build(dt, st reflect.Type, building map[pair]*plan) *plan {
+	k := pair{dt, st}
+	if p, ok := plans.Load(k); ok {
+		return p.(*plan)
+	}
+	if p, ok := building[k]; ok {
+		return p
+	}
+	p := &plan{}
+	building[k] = p
+	p.fn, p.err = compile(dt, st, building)
+	delete(building, k)
+	plans.Store(k, p)
+	return p
+}
+
+func compile(dt, st reflect.Type, building map[pair]*plan) (convFunc, error) {
+	if fn, ok := converters[pair{dt, st}]; ok {
+		return fn, nil
+	}
+	if dt == st && !deep(dt) {
+		return func(dst, src reflect.Value) error {
+			dst.Set(src)
+			return nil
+		}, nil
+	}
+
+	switch {
+	case st.Kind() == reflect.Pointer && dt.Kind() == reflect.Pointer:
+		elem := build(dt.Elem(), st.Elem(), building)
+		return func(dst, src reflect.Value) error {
+			if src.IsNil() {
+				dst.SetZero()
+				return nil
+			}
+			v := reflect.New(dt.Elem())
+			if err := elem.call(v.Elem(), src.Elem()); err != nil {
+				return err
+			}
+			dst.Set(v)
+			return nil
+		}, elem.err
+	case st.Kind() == reflect.Pointer:
+		elem := build(dt, st.Elem(), building)
+		return func(dst, src reflect.Value) error {
+			if src.IsNil() {
+				return nil
+			}
+			return elem.call(dst, src.Elem())
+		}, elem.err
+	case dt.Kind() == reflect.Pointer:
+		elem := build(dt.Elem(), st, building)
+		return func(dst, src reflect.Value) error {
+			v := reflect.New(dt.Elem())
+			if err := elem.call(v.Elem(), src); err != nil {
+				return err
+			}
+			dst.Set(v)
+			return nil
+		}, elem.err
+	case dt.Kind() == reflect.Interface:
+		if !st.AssignableTo(dt) {
+			break
+		}
+		return func(dst, src reflect.Value) error {
+			dst.Set(src)
+			return nil
+		}, nil
+	case dt.Kind() == reflect.Slice && (st.Kind() == reflect.Slice || st.Kind() == reflect.Array):
+		return sliceConv(dt, st, building)
+	case dt.Kind() == reflect.Map && st.Kind() == reflect.Map:
+		return mapConv(dt, st, building)
+	case dt.Kind() == reflect.Struct && st.Kind() == reflect.Struct:
+		return structConv(dt, st, building)
+	}
+	if fn := scalarConv(dt, st); fn != nil {
+		return fn, nil
+	}
+	return nil, &unsupportedError{src: st, dst: dt}
+}
+
+// unsupportedError 编译时发现的无法转换的字段，field 是从最外层结构体开始的路径
+type unsupportedError struct {
+	field    string
+	src, dst reflect.Type
+}
+
+func (e *unsupportedError) Error() string {
+	if e.field == "" {
+		return fmt.Sprintf("%v: %s → %s", ErrUnsupported, e.src, e.dst)
+	}
+	return fmt.Sprintf("%v: %s: %s → %s", ErrUnsupported, e.field, e.src, e.dst)
+}
+
+func (e *unsupportedError) Unwrap() error { return ErrUnsupported }
+
+// deep 类型的值是否需要逐层复制；有未导出字段的结构体（time.Time）无法逐字段复制，整体赋值
+func deep(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		return true
+	case reflect.Array:
+		return deep(t.Elem())
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if !t.Field(i).IsExported() {
+				return false
+			}
+		}
+		for i := range t.NumField() {
+			if deep(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sliceConv(dt, st reflect.Type, building map[pair]*plan) (convFunc, error) {
+	elem := build(dt.Elem(), st.Elem(), building)
+	if elem.err != nil {
+		return nil, elem.err
+	}
+	direct := dt.Elem() == st.Elem() && !deep(dt.Elem())
+	return func(dst, src reflect.Value) error {
+		if src.Kind() == reflect.Slice && src.IsNil() {
+			dst.SetZero()
+			return nil
+		}
+		n := src.Len()
+		out := reflect.MakeSlice(dt, n, n)
+		if direct {
+			reflect.Copy(out, src)
+		} else {
+			for i := range n {
+				if err := elem.call(out.Index(i), src.Index(i)); err != nil {
+					return fmt.Errorf("[%d]: %w", i, err)
+				}
+			}
+		}
+		dst.Set(out)
+		return nil
+	}, nil
+}
+
+func mapConv(dt, st reflect.Type, building map[pair]*plan) (convFunc, error) {
+	key := build(dt.Key(), st.Key(), building)
+	if key.err != nil {
+		return nil, key.err
+	}
+	elem := build(dt.Elem(), st.Elem(), building)
+	if elem.err != nil {
+		return nil, elem.err
+	}
+	return func(dst, src reflect.Value) error {
+		if src.IsNil() {
+			dst.SetZero()
+			return nil
+		}
+		out := reflect.MakeMapWithSize(dt, src.Len())
+		k, v := reflect.New(dt.Key()).Elem(), reflect.New(dt.Elem()).Elem()
+		for it := src.MapRange(); it.Next(); {
+			k.SetZero()
+			v.SetZero()
+			if err := key.call(k, it.Key()); err != nil {
+				return fmt.Errorf("[%v]: %w", it.Key(), err)
+			}
+			if err := elem.call(v, it.Value()); err != nil {
+				return fmt.Errorf("[%v]: %w", it.Key(), err)
+			}
+			out.SetMapIndex(k, v)
+		}
+		dst.Set(out)
+		return nil
+	}, nil
+}
+
+// ============================================================================
+// 结构体
+// ============================================================================
+
+// field 结构体里可映射的字段，嵌入结构体的字段已展开
+type field struct {
+	name  string // 匹配用的名字：mapper 标签或字段名
+	index []int
+	typ   reflect.Type
+	depth int
+}
+
+// step 一个字段的复制
+type step struct {
+	name     string
+	dst, src []int
+	conv     *plan
+}
+
+func structConv(dt, st reflect.Type, building map[pair]*plan) (convFunc, error) {
+	srcFields := make(map[string]field)
+	for _, f := range fields(st) {
+		srcFields[f.name] = f
+	}
+	var steps []step
+	for _, df := range fields(dt) {
+		sf, ok := srcFields[df.name]
+		if !ok {
+			continue
+		}
+		conv := build(df.typ, sf.typ, building)
+		if conv.err != nil {
+			e := *conv.err.(*unsupportedError)
+			e.field = strings.TrimSuffix(df.name+"."+e.field, ".")
+			return nil, &e
+		}
+		steps = append(steps, step{name: df.name, dst: df.index, src: sf.index, conv: conv})
+	}
+	return func(dst, src reflect.Value) error {
+		for _, s := range steps {
+			v, err := src.FieldByIndexErr(s.src)
+			if err != nil {
+				continue // 源里嵌入的指针是 nil，没有这个字段的值
+			}
+			if err := s.conv.call(fieldAlloc(dst, s.dst), v); err != nil {
+				return fmt.Errorf("%s: %w", s.name, err)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// fieldAlloc 按下标取字段，途中的 nil 嵌入指针先分配
+func fieldAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fields 导出字段，匿名嵌入的结构体展开；同名时外层优先，同一层重名的都丢弃（和 encoding/json 一致）
+func fields(t reflect.Type) []field {
+	var all []field
+	var visit func(t reflect.Type, index []int, depth int, seen map[reflect.Type]bool)
+	visit = func(t reflect.Type, index []int, depth int, seen map[reflect.Type]bool) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("mapper")
+			if tag == "-" {
+				continue
+			}
+			idx := append(append([]int(nil), index...), i)
+			ft := sf.Type
+			if sf.Anonymous && tag == "" {
+				switch {
+				case ft.Kind() == reflect.Struct:
+					visit(ft, idx, depth+1, seen)
+					continue
+				case ft.Kind() == reflect.Pointer && ft.Elem().Kind() == reflect.Struct && sf.IsExported():
+					visit(ft.Elem(), idx, depth+1, seen) // 目标里是 nil 时由 fieldAlloc 分配
+					continue
+				}
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			name := tag
+			if name == "" {
+				name = sf.Name
+			}
+			all = append(all, field{name: name, index: idx, typ: ft, depth: depth})
+		}
+	}
+	visit(t, nil, 0, make(map[reflect.Type]bool))
+
+	// 同名字段保留最浅的一个
+	best := make(map[string]int)
+	dup := make(map[string]bool)
+	for i, f := range all {
+		j, ok := best[f.name]
+		switch {
+		case !ok || f.depth < all[j].depth:
+			best[f.name] = i
+			dup[f.name] = false
+		case f.depth == all[j].depth:
+			dup[f.name] = true
+		}
+	}
+	var out []field
+	for i, f := range all {
+		if best[f.name] == i && !dup[f.name] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// ============================================================================
+// 标量
+// ============================================================================
+
+func scalarConv(dt, st reflect.Type) convFunc {
+	dk, sk := dt.Kind(), st.Kind()
+	switch {
+	case sk == reflect.String && dk == reflect.String,
+		sk == reflect.Bool && dk == reflect.Bool:
+		return func(dst, src reflect.Value) error {
+			dst.Set(src.Convert(dt))
+			return nil
+		}
+	case sk == reflect.String && isNumber(dk):
+		return func(dst, src reflect.Value) error {
+			return parseNumber(dst, strings.TrimSpace(src.String()))
+		}
+	case sk == reflect.String && dk == reflect.Bool:
+		return func(dst, src reflect.Value) error {
+			b, err := strconv.ParseBool(strings.TrimSpace(src.String()))
+			if err != nil {
+				return err
+			}
+			dst.SetBool(b)
+			return nil
+		}
+	case isNumber(sk) && dk == reflect.String:
+		return func(dst, src reflect.Value) error {
+			dst.SetString(formatNumber(src))
+			return nil
+		}
+	case sk == reflect.Bool && dk == reflect.String:
+		return func(dst, src reflect.Value) error {
+			dst.SetString(strconv.FormatBool(src.Bool()))
+			return nil
+		}
+	case isNumber(sk) && isNumber(dk):
+		return func(dst, src reflect.Value) error {
+			return setNumber(dst, src)
+		}
+	}
+	return nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	return isInt(k) || isUint(k) || k == reflect.Float32 || k == reflect.Float64
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func formatNumber(v reflect.Value) string {
+	switch k := v.Kind(); {
+	case isInt(k):
+		return strconv.FormatInt(v.Int(), 10)
+	case isUint(k):
+		return strconv.FormatUint(v.Uint(), 10)
+	default:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	}
+}
+
+func parseNumber(dst reflect.Value, s string) error {
+	bits := dst.Type().Bits()
+	switch k := dst.Kind(); {
+	case isInt(k):
+		n, err := strconv.ParseInt(s, 10, bits)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(n)
+	case isUint(k):
+		n, err := strconv.ParseUint(s, 10, bits)
+		if err != nil {
+			return err
+		}
+		dst.SetUint(n)
+	default:
+		f, err := strconv.ParseFloat(s, bits)
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(f)
+	}
+	return nil
+}
+
+// rangeError 数字超出目标类型的范围
+type rangeError struct {
+	value any
+	typ   reflect.Type
+}
+
+func (e *rangeError) Error() string {
+	return fmt.Sprintf("%v out of range for %s", e.value, e.typ)
+}
+
+func (e *rangeError) Unwrap() error { return ErrOverflow }
+
+// setNumber 数字之间转换，超出目标范围或小数转整数丢精度时返回 ErrOverflow
+func setNumber(dst, src reflect.Value) error {
+	sk, dk := src.Kind(), dst.Kind()
+	switch {
+	case isInt(sk):
+		n := src.Int()
+		switch {
+		case isInt(dk):
+			if dst.OverflowInt(n) {
+				return &rangeError{n, dst.Type()}
+			}
+			dst.SetInt(n)
+		case isUint(dk):
+			if n < 0 || dst.OverflowUint(uint64(n)) {
+				return &rangeError{n, dst.Type()}
+			}
+			dst.SetUint(uint64(n))
+		default:
+			dst.SetFloat(float64(n))
+		}
+	case isUint(sk):
+		n := src.Uint()
+		switch {
+		case isInt(dk):
+			if n > math.MaxInt64 || dst.OverflowInt(int64(n)) {
+				return &rangeError{n, dst.Type()}
+			}
+			dst.SetInt(int64(n))
+		case isUint(dk):
+			if dst.OverflowUint(n) {
+				return &rangeError{n, dst.Type()}
+			}
+			dst.SetUint(n)
+		default:
+			dst.SetFloat(float64(n))
+		}
+	default:
+		f := src.Float()
+		switch {
+		case isInt(dk):
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || dst.OverflowInt(int64(f)) {
+				return &rangeError{f, dst.Type()}
+			}
+			dst.SetInt(int64(f))
+		case isUint(dk):
+			if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 || dst.OverflowUint(uint64(f)) {
+				return &rangeError{f, dst.Type()}
+			}
+			dst.SetUint(uint64(f))
+		default:
+			if dst.OverflowFloat(f) {
+				return &rangeError{f, dst.Type()}
+			}
+			dst.SetFloat(f)
+		}
+	}
+	return nil
+}
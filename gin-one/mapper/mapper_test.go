@@ -0,0 +1,277 @@
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type status string
+
+type user struct {
+	gorm.Model
+	Username string
+	Email    string
+	Age      int
+	Status   status
+	Tags     []string
+	Address  *address
+	Password string
+}
+
+type address struct {
+	City string
+}
+
+type userDTO struct {
+	ID        string // uint → string
+	CreatedAt string // time.Time → RFC 3339
+	Username  string `mapper:"Username"`
+	Mail      string `mapper:"Email"`
+	Age       *int
+	Status    string
+	Tags      []string
+	Address   addressDTO
+	Password  string `mapper:"-"`
+}
+
+type addressDTO struct {
+	City string
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestCopy(t *testing.T) {
+	day := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	u := user{
+		Username: "tom", Email: "tom@example.com", Age: 20, Status: "active",
+		Tags: []string{"a"}, Address: &address{City: "北京"}, Password: "hash",
+	}
+	u.ID, u.CreatedAt = 7, day
+
+	dto, err := Map[userDTO](&u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := userDTO{
+		ID: "7", CreatedAt: "2024-05-06T07:08:09Z", Username: "tom", Mail: "tom@example.com",
+		Age: ptr(20), Status: "active", Tags: []string{"a"}, Address: addressDTO{City: "北京"},
+	}
+	if !reflect.DeepEqual(dto, want) {
+		t.Errorf("dto = %+v\nwant %+v", dto, want)
+	}
+
+	// 深拷贝：改源不影响目标
+	u.Tags[0] = "changed"
+	if dto.Tags[0] != "a" {
+		t.Error("slice shared with source")
+	}
+
+	// 反方向：string → uint / time.Time，*int → int
+	var back user
+	if err := Copy(&back, dto); err != nil {
+		t.Fatal(err)
+	}
+	if back.ID != 7 || !back.CreatedAt.Equal(day) || back.Email != "tom@example.com" || back.Age != 20 ||
+		back.Status != "active" || back.Address == nil || back.Address.City != "北京" || back.Password != "" {
+		t.Errorf("back = %+v", back)
+	}
+}
+
+func TestCopyPatch(t *testing.T) {
+	type patch struct {
+		Username *string
+		Age      *string
+	}
+	u := user{Username: "tom", Age: 20, Email: "tom@example.com"}
+
+	// nil 指针不修改目标，只在一边出现的字段保留原值
+	if err := Copy(&u, patch{Age: ptr("21")}); err != nil {
+		t.Fatal(err)
+	}
+	if u.Username != "tom" || u.Age != 21 || u.Email != "tom@example.com" {
+		t.Errorf("patched = %+v", u)
+	}
+}
+
+func TestCopyErrors(t *testing.T) {
+	type small struct{ Age int8 }
+	type chanField struct{ Tags chan int }
+	type wrapper struct{ Inner chanField }
+
+	tests := []struct {
+		name     string
+		dst, src any
+		err      error
+		contains string
+	}{
+		{"not pointer", user{}, user{}, ErrInvalidTarget, ""},
+		{"nil pointer", (*user)(nil), user{}, ErrInvalidTarget, ""},
+		{"parse", &user{}, struct{ Age string }{"abc"}, nil, "Age: strconv.ParseInt"},
+		{"overflow", &small{}, user{Age: 300}, ErrOverflow, "Age: 300 out of range for int8"},
+		{"fraction", &small{}, struct{ Age float64 }{1.5}, ErrOverflow, ""},
+		{"negative uint", &struct{ ID uint }{}, struct{ ID int }{-1}, ErrOverflow, ""},
+		{"unsupported", &user{}, chanField{}, ErrUnsupported, "Tags: chan int → []string"},
+		{"nested path", &struct{ Inner user }{}, wrapper{}, ErrUnsupported, "Inner.Tags"},
+	}
+	for _, tt := range tests {
+		err := Copy(tt.dst, tt.src)
+		if err == nil || (tt.err != nil && !errors.Is(err, tt.err)) || !strings.Contains(err.Error(), tt.contains) {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+}
+
+func TestCopyContainers(t *testing.T) {
+	type node struct {
+		Name     string
+		Children []*node
+	}
+	tree := &node{Name: "root", Children: []*node{{Name: "a"}, {Name: "b", Children: []*node{{Name: "c"}}}}}
+	got, err := Map[*node](tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, tree) || got.Children[1] == tree.Children[1] {
+		t.Errorf("tree copy = %+v", got)
+	}
+
+	m, err := Map[map[string]int](map[string]string{"a": "1", "b": "2"})
+	if err != nil || !reflect.DeepEqual(m, map[string]int{"a": 1, "b": 2}) {
+		t.Errorf("map = %v, %v", m, err)
+	}
+	s, err := Map[[]string]([3]int{1, 2, 3})
+	if err != nil || !reflect.DeepEqual(s, []string{"1", "2", "3"}) {
+		t.Errorf("array = %v, %v", s, err)
+	}
+	if s, _ := Map[[]int]([]int(nil)); s != nil {
+		t.Errorf("nil slice = %v", s)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	type cents int64
+	type price struct{ Amount cents }
+	type priceDTO struct{ Amount string }
+	t.Cleanup(func() {
+		buildMu.Lock()
+		delete(converters, pair{reflect.TypeFor[string](), reflect.TypeFor[cents]()})
+		buildMu.Unlock()
+		plans.Clear()
+	})
+
+	// 注册前按数字格式化，注册后使用自定义格式，已缓存的计划失效
+	if dto, _ := Map[priceDTO](price{1999}); dto.Amount != "1999" {
+		t.Errorf("before register = %q", dto.Amount)
+	}
+	Register(func(c cents) (string, error) { return fmt.Sprintf("%d.%02d", c/100, c%100), nil })
+	if dto, _ := Map[priceDTO](price{1999}); dto.Amount != "19.99" {
+		t.Errorf("after register = %q", dto.Amount)
+	}
+}
+
+func TestFieldsEmbedded(t *testing.T) {
+	type base struct {
+		ID   uint
+		Name string
+	}
+	type other struct {
+		Name string
+	}
+	type outer struct {
+		base
+		*other
+		Name string // 外层同名字段优先
+	}
+	var got []string
+	for _, f := range fields(reflect.TypeFor[outer]()) {
+		got = append(got, f.name)
+	}
+	if !reflect.DeepEqual(got, []string{"ID", "Name"}) {
+		t.Errorf("fields = %v", got)
+	}
+
+	// 目标里的 nil 嵌入指针在写入时分配
+	type Base struct {
+		Name string
+	}
+	type target struct {
+		*Base
+	}
+	var dst target
+	if err := Copy(&dst, base{ID: 1, Name: "x"}); err != nil || dst.Base == nil || dst.Name != "x" {
+		t.Errorf("embedded pointer = %+v, %v", dst.Base, err)
+	}
+}
+
+// ============================================================================
+// Benchmark：手写赋值 vs mapper（编译结果已缓存）
+// ============================================================================
+
+type createRequest struct {
+	Username string
+	Email    string
+	Password string
+	Age      int
+	Tags     []string
+}
+
+type createInput struct {
+	Username string
+	Email    string
+	Password string
+	Age      int
+	Tags     []string
+}
+
+var benchReq = createRequest{Username: "tom", Email: "tom@example.com", Password: "secret", Age: 20, Tags: []string{"a", "b"}}
+
+func BenchmarkHandwritten(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		in := createInput{
+			Username: benchReq.Username,
+			Email:    benchReq.Email,
+			Password: benchReq.Password,
+			Age:      benchReq.Age,
+			Tags:     append([]string(nil), benchReq.Tags...),
+		}
+		_ = in
+	}
+}
+
+func BenchmarkCopy(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		var in createInput
+		if err := Copy(&in, benchReq); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCopyConvert(b *testing.B) {
+	type row struct {
+		ID        uint
+		Age       int
+		CreatedAt time.Time
+	}
+	type dto struct {
+		ID        string
+		Age       *int
+		CreatedAt string
+	}
+	r := row{ID: 42, Age: 20, CreatedAt: time.Now()}
+	b.ReportAllocs()
+	for b.Loop() {
+		var d dto
+		if err := Copy(&d, r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
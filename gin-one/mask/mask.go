@@ -0,0 +1,301 @@
+// ============================================================================
+// Package mask 数据掩码：日志和接口输出里保留可辨认的一部分，隐藏其余部分
+// ============================================================================
+//
+// 【掩码和脱敏的区别】
+//
+// 脱敏（middleware/logger、middleware/auditlog）把整个值换成 [REDACTED]，用于密码、Token；
+// 掩码保留一部分，客服和运维还能对上号，但拿到日志的人没法直接用：
+//
+// | 规则    | 原值                  | 掩码后                |
+// |---------|-----------------------|-----------------------|
+// | email   | alice@example.com     | a***@example.com      |
+// | phone   | 13812345678           | 138****5678           |
+// | card    | 4111 1111 1111 1234   | **** **** **** 1234   |
+// | name    | 张三丰                | 张**                  |
+// | id      | 110101199001011234    | 110***********1234    |
+// | all     | 任意                  | ***                   |
+//
+// 分隔符（空格、-、+）原样保留，只替换数字和字母，格式仍然可读。
+//
+// 【结构体标签】
+//
+//	type User struct {
+//	    Email string `json:"email" mask:"email"`
+//	    Phone string `json:"phone" mask:"phone"`
+//	}
+//
+//	list := slices.Clone(users) // Struct 原地修改，先复制
+//	mask.Struct(&list)
+//
+// 嵌套结构体、指针、切片、数组、map 里的结构体指针都会处理；
+// 支持 string、*string、[]string 字段。Register 注册自定义规则。
+//
+// 【日志】
+//
+//	slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+//	    ReplaceAttr: mask.ReplaceAttr(mask.DefaultFields),
+//	}))
+//
+// 之后 slog.String("email", u.Email) 输出 a***@example.com，调用方不用记得每次掩码。
+//
+// ============================================================================
+package mask
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// ErrUnknownRule mask 标签引用了未注册的规则
+	ErrUnknownRule = errors.New("mask: unknown rule")
+	// ErrNotPointer Struct 的参数不是非 nil 指针
+	ErrNotPointer = errors.New("mask: Struct requires a non-nil pointer")
+)
+
+// Func 掩码规则
+type Func func(string) string
+
+var (
+	rulesMu sync.RWMutex
+	rules   = map[string]Func{
+		"email": Email,
+		"phone": Phone,
+		"card":  Card,
+		"name":  Name,
+		"id":    ID,
+		"all":   All,
+	}
+)
+
+// DefaultFields 常见字段名到规则的映射，用于 ReplaceAttr 和日志中间件
+var DefaultFields = map[string]string{
+	"email":       "email",
+	"phone":       "phone",
+	"mobile":      "phone",
+	"card_number": "card",
+	"id_number":   "id",
+}
+
+// Register 注册自定义规则，同名规则会被覆盖，通常在 init 中调用
+func Register(name string, fn Func) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	rules[name] = fn
+}
+
+// Rule 按名字取规则
+func Rule(name string) (Func, bool) {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	fn, ok := rules[name]
+	return fn, ok
+}
+
+// ============================================================================
+// 内置规则
+// ============================================================================
+
+// Email 保留用户名首字符和完整域名：alice@example.com → a***@example.com
+func Email(s string) string {
+	local, domain, ok := strings.Cut(s, "@")
+	if !ok || local == "" {
+		return Default(s)
+	}
+	first, _ := utf8.DecodeRuneInString(local)
+	return string(first) + "***@" + domain
+}
+
+// Phone 保留后 4 位，11 位及以上再保留前 3 位：13812345678 → 138****5678
+func Phone(s string) string {
+	n := countAlnum(s)
+	if n <= 4 {
+		return maskAlnum(s, 0, 0)
+	}
+	head := 0
+	if n >= 11 {
+		head = 3
+	}
+	return maskAlnum(s, head, 4)
+}
+
+// Card 只保留后 4 位：4111 1111 1111 1234 → **** **** **** 1234
+func Card(s string) string {
+	if countAlnum(s) < 8 {
+		return maskAlnum(s, 0, 0)
+	}
+	return maskAlnum(s, 0, 4)
+}
+
+// Name 只保留第一个字：张三丰 → 张**，Alice → A****
+func Name(s string) string {
+	return maskAlnum(s, 1, 0)
+}
+
+// ID 证件号保留前 3 位和后 4 位，不足 10 位时只保留首位
+func ID(s string) string {
+	if countAlnum(s) < 10 {
+		return maskAlnum(s, 1, 0)
+	}
+	return maskAlnum(s, 3, 4)
+}
+
+// All 整个替换为 ***，不暴露长度
+func All(string) string {
+	return "***"
+}
+
+// Default 没有专门规则时使用：保留首尾各一个字符
+func Default(s string) string {
+	if countAlnum(s) <= 2 {
+		return maskAlnum(s, 0, 0)
+	}
+	return maskAlnum(s, 1, 1)
+}
+
+func isMaskable(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func countAlnum(s string) int {
+	n := 0
+	for _, r := range s {
+		if isMaskable(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// maskAlnum 保留前 head 个和后 tail 个字母数字，中间的替换为 *，其余字符原样保留
+func maskAlnum(s string, head, tail int) string {
+	total := countAlnum(s)
+	var b strings.Builder
+	b.Grow(len(s))
+	i := 0
+	for _, r := range s {
+		if !isMaskable(r) {
+			b.WriteRune(r)
+			continue
+		}
+		if i < head || i >= total-tail {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('*')
+		}
+		i++
+	}
+	return b.String()
+}
+
+// ============================================================================
+// 结构体
+// ============================================================================
+
+// Struct 按 mask 标签原地修改 ptr 指向的值，ptr 可以是结构体、切片、map 等的指针
+func Struct(ptr any) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return ErrNotPointer
+	}
+	return walk(v.Elem(), 0)
+}
+
+func walk(v reflect.Value, depth int) error {
+	if depth > 64 {
+		return nil // 循环引用，已经处理过的部分不再重复
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			return walk(v.Elem(), depth+1)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			if !sf.IsExported() && !sf.Anonymous {
+				continue
+			}
+			fv := v.Field(i)
+			if rule := sf.Tag.Get("mask"); rule != "" {
+				if err := apply(fv, rule); err != nil {
+					return fmt.Errorf("%w: field %s.%s", err, t.Name(), sf.Name)
+				}
+				continue
+			}
+			if err := walk(fv, depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if err := walk(v.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// map 的值不可寻址，只处理值为指针的 map
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := walk(iter.Value(), depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func apply(v reflect.Value, name string) error {
+	fn, ok := Rule(name)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownRule, name)
+	}
+	if !v.CanSet() && v.Kind() != reflect.Pointer {
+		return nil
+	}
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(fn(v.String()))
+	case v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.String:
+		if !v.IsNil() {
+			v.Elem().SetString(fn(v.Elem().String()))
+		}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		for i := range v.Len() {
+			v.Index(i).SetString(fn(v.Index(i).String()))
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// slog
+// ============================================================================
+
+// ReplaceAttr 返回 slog.HandlerOptions.ReplaceAttr：属性名（不区分大小写）命中 fields 时按规则掩码
+// 只处理字符串值，分组里的属性按自己的名字匹配
+func ReplaceAttr(fields map[string]string) func(groups []string, a slog.Attr) slog.Attr {
+	lookup := make(map[string]Func, len(fields))
+	for field, name := range fields {
+		if fn, ok := Rule(name); ok {
+			lookup[strings.ToLower(field)] = fn
+		}
+	}
+	return func(_ []string, a slog.Attr) slog.Attr {
+		if a.Value.Kind() != slog.KindString {
+			return a
+		}
+		if fn, ok := lookup[strings.ToLower(a.Key)]; ok {
+			a.Value = slog.StringValue(fn(a.Value.String()))
+		}
+		return a
+	}
+}
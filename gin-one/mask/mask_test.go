@@ -0,0 +1,124 @@
+package mask
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   Func
+		in   string
+		want string
+	}{
+		{"email", Email, "alice@example.com", "a***@example.com"},
+		{"email unicode", Email, "张三@example.com", "张***@example.com"},
+		{"email without at", Email, "alice", "a***e"},
+		{"phone", Phone, "13812345678", "138****5678"},
+		{"phone with separators", Phone, "+86 138-1234-5678", "+86 1**-****-5678"},
+		{"phone short", Phone, "12345", "*2345"},
+		{"phone tiny", Phone, "1234", "****"},
+		{"card", Card, "4111 1111 1111 1234", "**** **** **** 1234"},
+		{"card too short", Card, "1234567", "*******"},
+		{"name", Name, "张三丰", "张**"},
+		{"name latin", Name, "Alice", "A****"},
+		{"id", ID, "110101199001011234", "110***********1234"},
+		{"all", All, "secret", "***"},
+		{"default", Default, "ab", "**"},
+		{"empty", Email, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fn(tt.in); got != tt.want {
+				t.Errorf("%s(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+type PaymentCard struct {
+	Number string `mask:"card"`
+	Brand  string
+}
+
+type Account struct {
+	Email   string                  `json:"email" mask:"email"`
+	Phone   *string                 `mask:"phone"`
+	Aliases []string                `mask:"name"`
+	Cards   []PaymentCard           // 切片里的结构体
+	Primary *PaymentCard            // 指针
+	ByName  map[string]*PaymentCard // map 的值是指针
+	Note    string
+	secret  string `mask:"all"`
+}
+
+func TestStruct(t *testing.T) {
+	phone := "13812345678"
+	accounts := []Account{{
+		Email:   "alice@example.com",
+		Phone:   &phone,
+		Aliases: []string{"Alice", "Ally"},
+		Cards:   []PaymentCard{{Number: "4111111111111234", Brand: "visa"}},
+		Primary: &PaymentCard{Number: "5500000000000004"},
+		ByName:  map[string]*PaymentCard{"work": {Number: "340000000000009"}},
+		Note:    "keep",
+	}}
+	if err := Struct(&accounts); err != nil {
+		t.Fatal(err)
+	}
+	a := accounts[0]
+	checks := map[string][2]string{
+		"email":   {a.Email, "a***@example.com"},
+		"phone":   {*a.Phone, "138****5678"},
+		"alias":   {a.Aliases[1], "A***"},
+		"card":    {a.Cards[0].Number, "************1234"},
+		"brand":   {a.Cards[0].Brand, "visa"},
+		"primary": {a.Primary.Number, "************0004"},
+		"map":     {a.ByName["work"].Number, "***********0009"},
+		"note":    {a.Note, "keep"},
+	}
+	for name, c := range checks {
+		if c[0] != c[1] {
+			t.Errorf("%s = %q, want %q", name, c[0], c[1])
+		}
+	}
+}
+
+func TestStructErrors(t *testing.T) {
+	if err := Struct(Account{}); !errors.Is(err, ErrNotPointer) {
+		t.Errorf("non-pointer err = %v", err)
+	}
+	type bad struct {
+		V string `mask:"nope"`
+	}
+	if err := Struct(&bad{V: "x"}); !errors.Is(err, ErrUnknownRule) {
+		t.Errorf("unknown rule err = %v", err)
+	}
+
+	Register("upper", strings.ToUpper)
+	type custom struct {
+		V string `mask:"upper"`
+	}
+	c := custom{V: "abc"}
+	if err := Struct(&c); err != nil || c.V != "ABC" {
+		t.Errorf("custom = %q, %v", c.V, err)
+	}
+}
+
+func TestReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: ReplaceAttr(DefaultFields)}))
+	log.Info("signup", slog.String("Email", "alice@example.com"), slog.Group("user", slog.String("phone", "13812345678")),
+		slog.Int("mobile", 1), slog.String("name", "alice"))
+
+	out := buf.String()
+	for _, want := range []string{"Email=a***@example.com", "user.phone=138****5678", "mobile=1", "name=alice"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log %q missing %q", out, want)
+		}
+	}
+}
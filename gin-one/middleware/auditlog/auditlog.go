@@ -0,0 +1,275 @@
+// ============================================================================
+// Package auditlog 合规审计用的请求日志：记录谁调用了什么接口、带了什么参数、得到什么结果
+// ============================================================================
+//
+// 【和访问日志、数据审计的区别】
+//
+// | 包                  | 记录                                   | 用途                         |
+// |---------------------|----------------------------------------|------------------------------|
+// | middleware/logger   | 每个请求一行，不含请求体，可以采样     | 排查问题、监控               |
+// | audit               | 数据行的字段 diff，和业务同一个事务    | 某条记录被谁改过             |
+// | middleware/auditlog | 管理操作的完整请求 / 响应体（脱敏后）  | 合规检查：管理员做了什么操作 |
+//
+// 【请求体捕获】
+//
+// 只捕获 JSON、表单和文本，文件上传等二进制内容只记录不捕获。
+// 超过 MaxBodySize 的部分不读进内存：请求体读出前 MaxBodySize 字节后拼回去交给 handler，
+// 响应体只复制前 MaxBodySize 字节。
+//
+// 被截断的 JSON 解析不了，也就没法按字段脱敏，这种情况整个请求体不记录，只标记 truncated，
+// 宁可少记也不能把密码写进审计日志。
+//
+// 【脱敏规则】
+//
+// | 规则                     | 示例                         | 匹配                                   |
+// |--------------------------|------------------------------|----------------------------------------|
+// | 字段名（任意层级）       | password、card_number        | 名字相同的键，不区分大小写             |
+// | JSON 路径                | payment.card.number          | 从根开始的完整路径                     |
+// | 路径通配                 | items[*].cvv、*.ssn          | [*] 匹配任意数组元素，* 匹配任意键      |
+//
+// 查询参数和表单按字段名脱敏。被脱敏的值替换为 "[REDACTED]"，JSON 结构保持不变。
+//
+// 【写到哪里】
+//
+// Sink 接口只有一个 Write 方法，内置 JSON Lines 文件（FileSink）和数据库表（DBSink、BatchSink）。
+// FileSink / DBSink 同步写入：审计记录不会因为队列满了就丢，代价是 sink 的耗时算在请求里，
+// 所以只挂在管理接口上，不要挂在全局。
+// BatchSink 只入队、后台攒批写入，请求不等数据库；队列满或进程崩溃时会丢记录，
+// 丢弃数见 Stats().Dropped，合规要求一条都不能丢时用 DBSink。
+//
+// ============================================================================
+package auditlog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/audit"
+	"go-one/middleware/realip"
+)
+
+// 默认脱敏的字段名
+var DefaultRedactFields = []string{
+	"password", "old_password", "new_password", "token", "access_token", "refresh_token",
+	"secret", "client_secret", "card_number", "cvv", "authorization",
+}
+
+// Redacted 被脱敏的值
+const Redacted = "[REDACTED]"
+
+// Entry 一条审计记录，DBSink 写入 request_audit_logs 表
+type Entry struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	RequestID         string    `gorm:"size:64;index" json:"request_id,omitempty"`
+	Actor             string    `gorm:"size:100;index" json:"actor"`
+	Method            string    `gorm:"size:10" json:"method"`
+	Path              string    `gorm:"size:255" json:"path"`
+	Route             string    `gorm:"size:255;index" json:"route"`
+	Query             string    `gorm:"size:1000" json:"query,omitempty"`
+	Status            int       `json:"status"`
+	LatencyMS         int64     `json:"latency_ms"`
+	ClientIP          string    `gorm:"size:45" json:"client_ip"`
+	UserAgent         string    `gorm:"size:255" json:"user_agent,omitempty"`
+	RequestBody       string    `gorm:"type:text" json:"request_body,omitempty"`
+	RequestTruncated  bool      `json:"request_truncated,omitempty"`
+	ResponseBody      string    `gorm:"type:text" json:"response_body,omitempty"`
+	ResponseTruncated bool      `json:"response_truncated,omitempty"`
+	CreatedAt         time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (Entry) TableName() string {
+	return "request_audit_logs"
+}
+
+// Config 中间件配置
+type Config struct {
+	// Sink 审计记录写到哪里，必填
+	Sink Sink
+
+	// Methods 记录哪些方法，默认 POST / PUT / PATCH / DELETE（只读请求不算"操作"）
+	Methods []string
+
+	// CaptureRequest / CaptureResponse 是否记录请求体 / 响应体
+	CaptureRequest  bool
+	CaptureResponse bool
+	// MaxBodySize 每个方向最多记录的字节数，默认 4KB
+	MaxBodySize int
+
+	// RedactFields 按字段名脱敏，为空时使用 DefaultRedactFields
+	RedactFields []string
+	// RedactPaths 按 JSON 路径脱敏，如 "payment.card.number"、"items[*].cvv"
+	RedactPaths []string
+
+	// Actor 取操作者，默认 audit.ActorFromContext（配合 audit.Middleware 使用）
+	Actor func(*gin.Context) string
+	// RequestIDKey 请求 ID 在 Context 中的键，默认 "request_id"
+	RequestIDKey string
+
+	// Logger 记录 sink 写入失败，默认 slog.Default()
+	Logger *slog.Logger
+}
+
+func (c Config) withDefaults() Config {
+	if len(c.Methods) == 0 {
+		c.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	if c.MaxBodySize <= 0 {
+		c.MaxBodySize = 4 << 10
+	}
+	if len(c.RedactFields) == 0 {
+		c.RedactFields = DefaultRedactFields
+	}
+	if c.Actor == nil {
+		c.Actor = func(c *gin.Context) string { return audit.ActorFromContext(c.Request.Context()) }
+	}
+	if c.RequestIDKey == "" {
+		c.RequestIDKey = "request_id"
+	}
+	if c.Logger == nil {
+		c.Logger = slog.Default()
+	}
+	return c
+}
+
+// New 创建审计日志中间件，放在认证中间件之后，操作者才取得到
+func New(cfg Config) gin.HandlerFunc {
+	if cfg.Sink == nil {
+		panic("auditlog: Config.Sink is required")
+	}
+	cfg = cfg.withDefaults()
+	redactor := NewRedactor(cfg.RedactFields, cfg.RedactPaths)
+
+	return func(c *gin.Context) {
+		if !slices.Contains(cfg.Methods, c.Request.Method) {
+			c.Next()
+			return
+		}
+		start := time.Now()
+
+		var reqBody capture
+		if cfg.CaptureRequest && c.Request.Body != nil && textual(c.ContentType()) {
+			reqBody = peekBody(c.Request, cfg.MaxBodySize)
+		}
+		var rec *recorder
+		if cfg.CaptureResponse {
+			rec = &recorder{ResponseWriter: c.Writer, max: cfg.MaxBodySize}
+			c.Writer = rec
+		}
+
+		c.Next()
+
+		e := &Entry{
+			RequestID: c.GetString(cfg.RequestIDKey),
+			Actor:     cfg.Actor(c),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Route:     c.FullPath(),
+			Query:     redactor.Query(c.Request.URL.RawQuery),
+			Status:    c.Writer.Status(),
+			LatencyMS: time.Since(start).Milliseconds(),
+			ClientIP:  realip.FromContext(c),
+			UserAgent: truncate(c.Request.UserAgent(), 255),
+		}
+		e.RequestBody, e.RequestTruncated = redactor.body(c.ContentType(), reqBody)
+		if rec != nil && textual(mediaType(c.Writer.Header().Get("Content-Type"))) {
+			e.ResponseBody, e.ResponseTruncated = redactor.body(
+				mediaType(c.Writer.Header().Get("Content-Type")),
+				capture{data: rec.buf.Bytes(), truncated: rec.truncated})
+		}
+
+		// 客户端断开不应该让审计记录丢失
+		ctx := context.WithoutCancel(c.Request.Context())
+		if err := cfg.Sink.Write(ctx, e); err != nil {
+			cfg.Logger.Error("auditlog: write entry failed",
+				slog.String("method", e.Method), slog.String("path", e.Path),
+				slog.String("actor", e.Actor), slog.Any("error", err))
+		}
+	}
+}
+
+// ============================================================================
+// 请求体 / 响应体捕获
+// ============================================================================
+
+type capture struct {
+	data      []byte
+	truncated bool
+}
+
+// peekBody 读出前 max 字节，剩下的部分原样留给 handler
+func peekBody(r *http.Request, max int) capture {
+	buf := make([]byte, max+1)
+	n, err := io.ReadFull(r.Body, buf)
+	buf = buf[:n]
+	// ReadFull 读满 max+1 字节说明请求体超过上限；EOF / ErrUnexpectedEOF 表示已经读完
+	truncated := err == nil
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(buf), r.Body), Closer: r.Body}
+	if truncated {
+		return capture{data: buf[:max], truncated: true}
+	}
+	return capture{data: buf}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// recorder 写给客户端的同时复制前 max 字节
+type recorder struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	w.keep(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recorder) WriteString(s string) (int, error) {
+	w.keep([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *recorder) keep(b []byte) {
+	room := w.max - w.buf.Len()
+	if len(b) > room {
+		b, w.truncated = b[:max(room, 0)], true
+	}
+	w.buf.Write(b)
+}
+
+// textual 只捕获可读的内容，上传的文件、图片不进审计日志
+func textual(mediaType string) bool {
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/x-www-form-urlencoded",
+		strings.HasPrefix(mediaType, "text/"):
+		return true
+	}
+	return false
+}
+
+func mediaType(contentType string) string {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	return mt
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
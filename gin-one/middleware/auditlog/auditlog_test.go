@@ -0,0 +1,260 @@
+package auditlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/audit"
+	"go-one/batcher"
+	"go-one/internal/testdb"
+)
+
+// memSink 收集写入的记录
+type memSink struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func (s *memSink) Write(_ context.Context, e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, *e)
+	return nil
+}
+
+func (s *memSink) last(t *testing.T) Entry {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.entries) == 0 {
+		t.Fatal("no entry written")
+	}
+	return s.entries[len(s.entries)-1]
+}
+
+// newRouter /admin/users 回显请求体，handler 同时校验请求体没有被中间件读坏
+func newRouter(cfg Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(audit.Middleware(func(c *gin.Context) string { return c.GetHeader("X-User") }))
+	r.Use(New(cfg))
+	echo := func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusOK, c.ContentType(), body)
+	}
+	r.POST("/admin/users", echo)
+	r.GET("/admin/users", echo)
+	r.POST("/admin/upload", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusCreated, gin.H{"size": len(body)})
+	})
+	return r
+}
+
+func do(r http.Handler, method, target, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-User", "admin")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCapture(t *testing.T) {
+	sink := &memSink{}
+	r := newRouter(Config{Sink: sink, CaptureRequest: true, CaptureResponse: true})
+
+	body := `{"name":"alice","password":"s3cret","profile":{"Card_Number":"4111"},"id":12345678901234567890}`
+	w := do(r, "POST", "/admin/users?token=abc&page=1", "application/json; charset=utf-8", body)
+	if w.Body.String() != body {
+		t.Fatalf("handler saw %q, want original body", w.Body.String())
+	}
+
+	e := sink.last(t)
+	if e.Actor != "admin" || e.Method != "POST" || e.Route != "/admin/users" || e.Status != 200 {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.Query != "page=1&token=[REDACTED]" {
+		t.Errorf("query = %q", e.Query)
+	}
+	want := `{"id":12345678901234567890,"name":"alice","password":"[REDACTED]","profile":{"Card_Number":"[REDACTED]"}}`
+	if e.RequestBody != want {
+		t.Errorf("request body = %s\nwant %s", e.RequestBody, want)
+	}
+	if e.ResponseBody != want {
+		t.Errorf("response body = %s\nwant %s", e.ResponseBody, want)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	sink := &memSink{}
+	r := newRouter(Config{Sink: sink, CaptureRequest: true})
+
+	do(r, "GET", "/admin/users", "application/json", "")
+	if len(sink.entries) != 0 {
+		t.Fatalf("GET should not be recorded, got %d entries", len(sink.entries))
+	}
+
+	// 二进制内容记录请求，但不捕获请求体，也不影响 handler 读取
+	w := do(r, "POST", "/admin/upload", "application/octet-stream", "\x00\x01\x02")
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"size":3`) {
+		t.Fatalf("upload = %d %s", w.Code, w.Body.String())
+	}
+	if e := sink.last(t); e.RequestBody != "" || e.ResponseBody != "" {
+		t.Errorf("entry = %+v, want no bodies", e)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	sink := &memSink{}
+	r := newRouter(Config{Sink: sink, CaptureRequest: true, CaptureResponse: true, MaxBodySize: 16})
+
+	// JSON 截断后没法脱敏，整个丢弃；handler 仍然拿到完整请求体
+	long := `{"name":"alice","password":"s3cret"}`
+	if w := do(r, "POST", "/admin/users", "application/json", long); w.Body.String() != long {
+		t.Fatalf("handler saw %q", w.Body.String())
+	}
+	e := sink.last(t)
+	if e.RequestBody != "" || !e.RequestTruncated || e.ResponseBody != "" || !e.ResponseTruncated {
+		t.Errorf("json entry = %+v", e)
+	}
+
+	// 表单截断后仍按字段名脱敏
+	do(r, "POST", "/admin/users", "application/x-www-form-urlencoded", "password=s3cret-and-more&name=bob")
+	e = sink.last(t)
+	if e.RequestBody != "password=[REDACTED]" || !e.RequestTruncated {
+		t.Errorf("form entry = %+v", e)
+	}
+
+	// 刚好等于上限不算截断
+	exact := `{"name":"alice"}`
+	do(r, "POST", "/admin/users", "application/json", exact)
+	if e = sink.last(t); e.RequestBody != exact || e.RequestTruncated {
+		t.Errorf("exact entry = %+v", e)
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor([]string{"password"}, []string{"payment.card.number", "items[*].cvv", "*.ssn", "list[1]"})
+	tests := []struct {
+		in, want string
+	}{
+		{`{"password":"x","nested":{"PASSWORD":"y"}}`, `{"nested":{"PASSWORD":"[REDACTED]"},"password":"[REDACTED]"}`},
+		{`{"payment":{"card":{"number":"4111","brand":"visa"}},"card":{"number":"keep"}}`,
+			`{"card":{"number":"keep"},"payment":{"card":{"brand":"visa","number":"[REDACTED]"}}}`},
+		{`{"items":[{"cvv":"1"},{"cvv":"2","sku":"a"}]}`, `{"items":[{"cvv":"[REDACTED]"},{"cvv":"[REDACTED]","sku":"a"}]}`},
+		{`{"user":{"ssn":"1"},"admin":{"ssn":"2"},"ssn":"top"}`, `{"admin":{"ssn":"[REDACTED]"},"ssn":"top","user":{"ssn":"[REDACTED]"}}`},
+		{`{"list":[1,2,3]}`, `{"list":[1,"[REDACTED]",3]}`},
+		{`[{"password":"x","note":"<b>&</b>"}]`, `[{"note":"<b>&</b>","password":"[REDACTED]"}]`},
+	}
+	for _, tt := range tests {
+		got, err := r.JSON([]byte(tt.in))
+		if err != nil {
+			t.Fatalf("JSON(%s): %v", tt.in, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("JSON(%s)\n got %s\nwant %s", tt.in, got, tt.want)
+		}
+	}
+	if _, err := r.JSON([]byte(`{"password":`)); err == nil {
+		t.Error("invalid JSON should fail")
+	}
+
+	got, err := r.Marshal(struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}{"bob", "x"})
+	if err != nil || string(got) != `{"password":"[REDACTED]","user":"bob"}` {
+		t.Errorf("Marshal = %s, %v", got, err)
+	}
+}
+
+func TestSinks(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "audit.log")
+		sink, err := OpenFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := newRouter(Config{Sink: sink, CaptureRequest: true})
+		do(r, "POST", "/admin/users", "application/json", `{"a":1}`)
+		do(r, "POST", "/admin/users", "application/json", `{"a":2}`)
+		if err := sink.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		data, _ := os.ReadFile(path)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("got %d lines: %s", len(lines), data)
+		}
+		var e Entry
+		if err := json.Unmarshal([]byte(lines[1]), &e); err != nil || e.RequestBody != `{"a":2}` || e.CreatedAt.IsZero() {
+			t.Errorf("line = %s, err = %v", lines[1], err)
+		}
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+			t.Errorf("perm = %v", info.Mode().Perm())
+		}
+	})
+
+	t.Run("db", func(t *testing.T) {
+		db := testdb.Open(t, &Entry{})
+
+		r := newRouter(Config{Sink: NewDBSink(db), CaptureRequest: true})
+		do(r, "POST", "/admin/users", "application/json", `{"password":"x"}`)
+
+		var e Entry
+		if err := db.Take(&e).Error; err != nil {
+			t.Fatal(err)
+		}
+		if e.Actor != "admin" || e.RequestBody != `{"password":"[REDACTED]"}` {
+			t.Errorf("row = %+v", e)
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		db := testdb.Open(t, &Entry{})
+
+		sink := NewBatchSink(db, batcher.Config{Size: 10, Interval: time.Hour})
+		r := newRouter(Config{Sink: sink})
+		for range 3 {
+			do(r, "POST", "/admin/users", "application/json", `{}`)
+		}
+		var n int64
+		if db.Model(&Entry{}).Count(&n); n != 0 {
+			t.Errorf("rows before close = %d", n) // 没凑够一批也没到时间，还在队列里
+		}
+		if err := sink.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if db.Model(&Entry{}).Count(&n); n != 3 || sink.Stats().Flushed != 3 {
+			t.Errorf("rows after close = %d, stats = %+v", n, sink.Stats())
+		}
+	})
+
+	t.Run("write error is logged", func(t *testing.T) {
+		var logs bytes.Buffer
+		sink := SinkFunc(func(context.Context, *Entry) error { return errors.New("disk full") })
+		r := newRouter(Config{Sink: sink, Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+		if w := do(r, "POST", "/admin/users", "application/json", `{}`); w.Code != http.StatusOK {
+			t.Fatalf("status = %d", w.Code)
+		}
+		if !strings.Contains(logs.String(), "disk full") {
+			t.Errorf("logs = %s", logs.String())
+		}
+	})
+}
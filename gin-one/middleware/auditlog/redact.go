@@ -0,0 +1,171 @@
+package auditlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Redactor 按字段名和 JSON 路径脱敏，创建后只读，可以并发使用
+type Redactor struct {
+	fields map[string]struct{}
+	paths  [][]string
+}
+
+// NewRedactor 创建脱敏器，fields 不区分大小写匹配任意层级的键，paths 规则见包注释
+func NewRedactor(fields, paths []string) *Redactor {
+	r := &Redactor{fields: make(map[string]struct{}, len(fields))}
+	for _, f := range fields {
+		r.fields[strings.ToLower(f)] = struct{}{}
+	}
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p != "" {
+			r.paths = append(r.paths, parsePath(p))
+		}
+	}
+	return r
+}
+
+var indexRe = regexp.MustCompile(`\[(\*|\d+)\]`)
+
+// parsePath "items[*].cvv" → [items * cvv]，"items[0].cvv" → [items 0 cvv]
+func parsePath(p string) []string {
+	p = indexRe.ReplaceAllString(p, ".$1")
+	return strings.Split(strings.TrimPrefix(p, "."), ".")
+}
+
+// JSON 脱敏 JSON 文档，data 不是合法 JSON 时返回错误
+func (r *Redactor) JSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // 保持数字原样，大整数 ID 不会变成 1.2e+18
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return marshal(r.walk(v, nil))
+}
+
+// Marshal 把任意值（结构体按 json 标签）编码为 JSON 并脱敏，用于在审计日志里记录业务对象
+func (r *Redactor) Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return r.JSON(data)
+}
+
+func (r *Redactor) walk(v any, path []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			p := append(path[:len(path):len(path)], k)
+			if r.matchField(k) || r.matchPath(p) {
+				v[k] = Redacted
+			} else {
+				v[k] = r.walk(child, p)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			p := append(path[:len(path):len(path)], strconv.Itoa(i))
+			if r.matchPath(p) {
+				v[i] = Redacted
+			} else {
+				v[i] = r.walk(child, p)
+			}
+		}
+	}
+	return v
+}
+
+func (r *Redactor) matchField(name string) bool {
+	_, ok := r.fields[strings.ToLower(name)]
+	return ok
+}
+
+func (r *Redactor) matchPath(path []string) bool {
+	for _, rule := range r.paths {
+		if len(rule) == len(path) && slices.EqualFunc(rule, path, func(want, got string) bool {
+			return want == "*" || strings.EqualFold(want, got)
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+// body 按内容类型脱敏捕获到的请求体 / 响应体，返回要记录的内容和是否截断
+func (r *Redactor) body(mediaType string, body capture) (string, bool) {
+	if len(body.data) == 0 {
+		return "", body.truncated
+	}
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		if body.truncated {
+			return "", true // 截断的 JSON 没法按字段脱敏，整个丢弃
+		}
+		out, err := r.JSON(body.data)
+		if err != nil {
+			return "", false
+		}
+		return string(out), false
+	case mediaType == "application/x-www-form-urlencoded":
+		// 截断只会影响最后一个字段：键被截断时值也不在了，值被截断时仍按键名脱敏
+		return r.Query(string(body.data)), body.truncated
+	default:
+		return string(body.data), body.truncated
+	}
+}
+
+// Query 按字段名脱敏查询参数
+func (r *Redactor) Query(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return Redacted
+	}
+	return r.form(values)
+}
+
+func (r *Redactor) form(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		redact := r.matchField(k)
+		for _, v := range values[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(k))
+			b.WriteByte('=')
+			if redact {
+				b.WriteString(Redacted) // 不转义，日志里一眼能看出被脱敏
+			} else {
+				b.WriteString(url.QueryEscape(v))
+			}
+		}
+	}
+	return b.String()
+}
+
+// marshal 不转义 <>&，审计日志给人看
+func marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,118 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go-one/batcher"
+)
+
+// Sink 审计记录的去处，Write 在请求的 goroutine 里同步调用，需要并发安全
+type Sink interface {
+	Write(ctx context.Context, e *Entry) error
+}
+
+// SinkFunc 函数适配为 Sink
+type SinkFunc func(ctx context.Context, e *Entry) error
+
+func (f SinkFunc) Write(ctx context.Context, e *Entry) error { return f(ctx, e) }
+
+// ============================================================================
+// JSON Lines
+// ============================================================================
+
+// WriterSink 每条记录一行 JSON，交给日志采集（Filebeat、Vector）转存
+type WriterSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewWriterSink 写到任意 io.Writer
+func NewWriterSink(w io.Writer) *WriterSink {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &WriterSink{w: w, enc: enc}
+}
+
+// OpenFile 以追加方式打开审计日志文件，权限 0600：里面是管理员的操作记录
+func OpenFile(path string) (*WriterSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterSink(f), nil
+}
+
+func (s *WriterSink) Write(_ context.Context, e *Entry) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 一次 Encode 一次 Write，O_APPEND 下多进程写同一个文件也不会交错
+	return s.enc.Encode(e)
+}
+
+// Close 底层是文件时关闭文件
+func (s *WriterSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// ============================================================================
+// 数据库
+// ============================================================================
+
+// DBSink 写入 request_audit_logs 表，表需要事先 AutoMigrate(&auditlog.Entry{})
+type DBSink struct {
+	db *gorm.DB
+}
+
+// NewDBSink 创建数据库 sink，审计库和业务库可以是不同的连接
+func NewDBSink(db *gorm.DB) *DBSink {
+	return &DBSink{db: db}
+}
+
+func (s *DBSink) Write(ctx context.Context, e *Entry) error {
+	return s.db.WithContext(ctx).Create(e).Error
+}
+
+// BatchSink 攒批写入 request_audit_logs：请求里只入队，后台凑够一批再一条多行 INSERT
+//
+// 队列满时 Write 返回 batcher.ErrFull，这条记录丢弃（中间件记一条错误日志）；
+// 退出前调用 Close 写出剩下的记录。
+type BatchSink struct {
+	b *batcher.Batcher[*Entry]
+}
+
+// NewBatchSink 创建批量写入的数据库 sink
+func NewBatchSink(db *gorm.DB, cfg batcher.Config) *BatchSink {
+	return &BatchSink{b: batcher.NewGORM[*Entry](db, cfg)}
+}
+
+func (s *BatchSink) Write(_ context.Context, e *Entry) error {
+	// 写入时间以请求为准，不是批量落库的时间
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	return s.b.Add(e)
+}
+
+// Close 写出队列里剩下的记录，注册为 server.OnShutdown 钩子
+func (s *BatchSink) Close(ctx context.Context) error {
+	return s.b.Close(ctx)
+}
+
+// Stats 入队、写出、丢弃的记录数
+func (s *BatchSink) Stats() batcher.Stats {
+	return s.b.Stats()
+}
@@ -0,0 +1,96 @@
+// ============================================================================
+// Package bodylimit 请求体大小限制中间件
+// ============================================================================
+//
+// 【为什么需要？】
+//
+// Go 的 http.Server 不限制请求体大小（只限制请求头 MaxHeaderBytes）。
+// c.ShouldBindJSON 会把整个请求体读进内存，一个 1GB 的 POST 就能让服务 OOM。
+//
+// 【两道检查】
+//
+// | 情况                                   | 处理                                              |
+// |----------------------------------------|---------------------------------------------------|
+// | Content-Length 大于上限                | 不读请求体，直接 413                              |
+// | 没有 Content-Length（chunked）或谎报   | 用 http.MaxBytesReader 包装，读到上限时返回错误   |
+//
+// 第二种情况下报错的是 handler 里的读取（如 ShouldBindJSON），
+// handler 可以用 IsTooLarge(err) 判断后自己返回 413；
+// handler 没有写响应时，中间件在 c.Next() 之后补上统一格式的 413。
+//
+// 【使用】
+//
+//	api.Use(bodylimit.New(1 << 20))                         // 普通接口 1MB
+//	upload.POST("/avatar", bodylimit.New(10<<20), handler)  // 上传接口单独放宽
+//
+// ============================================================================
+package bodylimit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// New 创建中间件，max 为请求体最大字节数，必须大于 0
+func New(max int64) gin.HandlerFunc {
+	if max <= 0 {
+		panic(fmt.Sprintf("bodylimit: max must be positive, got %d", max))
+	}
+	msg := fmt.Sprintf("request body must be at most %d bytes", max)
+
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > max {
+			// 客户端还在发送请求体，让 net/http 响应后关闭连接，不再读剩下的数据
+			c.Header("Connection", "close")
+			response.Abort(c, http.StatusRequestEntityTooLarge, "body_too_large", msg)
+			return
+		}
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		body := &limitedBody{ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, max)}
+		c.Request.Body = body
+		c.Next()
+
+		// handler 没有写响应、也没有设置状态码，说明它没处理读取错误
+		if body.exceeded && !c.Writer.Written() && c.Writer.Status() == http.StatusOK {
+			response.Abort(c, http.StatusRequestEntityTooLarge, "body_too_large", msg)
+		}
+	}
+}
+
+// IsTooLarge err 是否因为请求体超过上限
+//
+//	if err := c.ShouldBindJSON(&req); err != nil {
+//	    if bodylimit.IsTooLarge(err) {
+//	        response.Error(c, 413, "body_too_large", "请求体过大")
+//	        return
+//	    }
+//	    ...
+//	}
+func IsTooLarge(err error) bool {
+	var e *http.MaxBytesError
+	return errors.As(err, &e)
+}
+
+// limitedBody 记录读取时是否超过了上限
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && IsTooLarge(err) {
+		b.exceeded = true
+	}
+	return n, err
+}
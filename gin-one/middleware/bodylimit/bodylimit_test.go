@@ -0,0 +1,76 @@
+package bodylimit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(New(8))
+	// /read 只读取不处理错误，由中间件补 413；/echo 自己处理错误
+	r.POST("/read", func(c *gin.Context) {
+		_, _ = io.ReadAll(c.Request.Body)
+	})
+	r.POST("/echo", func(c *gin.Context) {
+		b, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			status := http.StatusBadRequest
+			if IsTooLarge(err) {
+				status = http.StatusTeapot
+			}
+			c.Status(status)
+			return
+		}
+		c.String(http.StatusOK, string(b))
+	})
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		chunked bool // 不带 Content-Length
+		want    int
+	}{
+		{"within limit", "/echo", "12345678", false, 200},
+		{"content-length too large", "/echo", "123456789", false, 413},
+		{"chunked within limit", "/echo", "1234", true, 200},
+		{"chunked too large, handler handles", "/echo", "123456789", true, http.StatusTeapot},
+		{"chunked too large, middleware responds", "/read", "123456789", true, 413},
+		{"empty body", "/echo", "", false, 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d; want %d (%s)", w.Code, tt.want, w.Body)
+			}
+			if tt.want == 413 && !strings.Contains(w.Body.String(), `"body_too_large"`) {
+				t.Errorf("body = %s; want unified error envelope", w.Body)
+			}
+			if tt.want == 200 && w.Body.String() != tt.body {
+				t.Errorf("body = %q; want %q", w.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestInvalidMaxPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for max 0")
+		}
+	}()
+	New(0)
+}
@@ -0,0 +1,353 @@
+// ============================================================================
+// Package compress gzip / deflate 响应压缩中间件
+// ============================================================================
+//
+// 【什么时候压缩】
+//
+// | 条件                                   | 原因                                        |
+// |----------------------------------------|---------------------------------------------|
+// | Accept-Encoding 包含 gzip 或 deflate   | 客户端能解压                                |
+// | Content-Type 在白名单中                | JPEG、PNG、ZIP 已经压缩过，再压只浪费 CPU   |
+// | 响应体不小于 MinLength                 | 几十字节的响应压缩后反而更大                |
+// | 状态码不是 204 / 206 / 304             | 没有响应体，或 Range 响应按原始字节计算位置 |
+// | handler 没有设置 Content-Encoding      | 已经压缩过                                  |
+// | 不是 WebSocket 升级请求                | 连接会被接管                                |
+//
+// 【实现】
+//
+// 包装 gin.ResponseWriter，先缓冲前 MinLength 字节：
+// 缓冲满了再决定是否压缩，handler 结束时还没满就原样输出。
+// handler 调用 Flush（SSE、流式下载）时立即决定，之后边压缩边发送。
+//
+// 【易错点】
+//
+//   - HTTP 的 "deflate" 是 zlib 格式（RFC 1950），不是 compress/flate 的裸 DEFLATE 流
+//   - 压缩后 Content-Length 失效，要删掉；强 ETag 要改成弱 ETag（字节不同了）
+//   - 响应要带 Vary: Accept-Encoding，否则 CDN 会把压缩的响应给不支持的客户端
+//
+// ============================================================================
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Config 压缩配置
+type Config struct {
+	// Level 压缩级别，1（最快）~ 9（最小），默认 gzip.DefaultCompression（6）
+	Level int
+
+	// MinLength 小于这个长度的响应不压缩，默认 1024 字节
+	MinLength int
+
+	// ContentTypes 需要压缩的类型，默认 DefaultContentTypes
+	// "text/*" 匹配所有 text 类型
+	ContentTypes []string
+
+	// Skip 返回 true 时不压缩，如已经由 Nginx 压缩的路径
+	Skip func(c *gin.Context) bool
+}
+
+// DefaultContentTypes 默认压缩的类型：文本类，不含图片、视频、压缩包
+var DefaultContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/x-ndjson",
+	"application/problem+json",
+	"image/svg+xml",
+}
+
+// 支持的编码，按服务端偏好排序（q 值相同时选前面的）
+var encodings = []string{"gzip", "deflate"}
+
+// New 创建压缩中间件
+func New(cfg Config) gin.HandlerFunc {
+	if cfg.Level == 0 {
+		cfg.Level = gzip.DefaultCompression
+	}
+	if cfg.Level < gzip.HuffmanOnly || cfg.Level > gzip.BestCompression {
+		panic("compress: invalid Level " + strconv.Itoa(cfg.Level))
+	}
+	if cfg.MinLength == 0 {
+		cfg.MinLength = 1024
+	}
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = DefaultContentTypes
+	}
+	p := &pools{level: cfg.Level}
+
+	return func(c *gin.Context) {
+		if cfg.Skip != nil && cfg.Skip(c) {
+			c.Next()
+			return
+		}
+		// 不论是否压缩，响应都随 Accept-Encoding 变化
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiate(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead || isUpgrade(c.Request) {
+			c.Next()
+			return
+		}
+
+		w := &writer{ResponseWriter: c.Writer, cfg: &cfg, pools: p, encoding: encoding}
+		c.Writer = w
+		defer w.close()
+		c.Next()
+	}
+}
+
+// negotiate 按 Accept-Encoding 的 q 值选择编码，都不可用时返回 ""
+//
+//	"gzip, deflate"          → gzip
+//	"deflate, gzip;q=0.5"    → deflate
+//	"gzip;q=0, *"            → deflate
+//	"identity"               → ""
+func negotiate(header string) string {
+	if header == "" {
+		return ""
+	}
+	q := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		weight := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				weight = f
+			}
+		}
+		if name == "*" {
+			wildcard = weight
+		} else {
+			q[name] = weight
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range encodings {
+		weight, ok := q[enc]
+		if !ok {
+			weight = max(wildcard, 0)
+		}
+		if weight > bestQ {
+			best, bestQ = enc, weight
+		}
+	}
+	return best
+}
+
+func isUpgrade(r *http.Request) bool {
+	return r.Header.Get("Upgrade") != "" &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// allowed Content-Type 是否在白名单中
+func (cfg *Config) allowed(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range cfg.ContentTypes {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mt, prefix+"/") {
+				return true
+			}
+		} else if mt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================
+// 压缩器复用
+// ============================================================================
+//
+// gzip.Writer 内部有几百 KB 的缓冲区，每个请求新建一个会给 GC 很大压力
+
+type resetWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+type pools struct {
+	level int
+	gzip  sync.Pool
+	zlib  sync.Pool
+}
+
+func (p *pools) get(encoding string, dst io.Writer) resetWriter {
+	pool := &p.gzip
+	if encoding == "deflate" {
+		pool = &p.zlib
+	}
+	if w, ok := pool.Get().(resetWriter); ok {
+		w.Reset(dst)
+		return w
+	}
+	var w resetWriter
+	if encoding == "deflate" {
+		w, _ = zlib.NewWriterLevel(dst, p.level) // 级别已经在 New 中校验过
+	} else {
+		w, _ = gzip.NewWriterLevel(dst, p.level)
+	}
+	return w
+}
+
+func (p *pools) put(encoding string, w resetWriter) {
+	w.Reset(io.Discard) // 不再引用这次请求的连接
+	if encoding == "deflate" {
+		p.zlib.Put(w)
+	} else {
+		p.gzip.Put(w)
+	}
+}
+
+// ============================================================================
+// ResponseWriter 包装
+// ============================================================================
+
+type writer struct {
+	gin.ResponseWriter
+	cfg      *Config
+	pools    *pools
+	encoding string
+
+	buf     []byte      // 决定之前缓冲的数据
+	decided bool        // 是否已经决定压缩与否
+	enc     resetWriter // 压缩时非 nil
+	size    int         // handler 写入的未压缩字节数
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	w.size += len(b)
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.cfg.MinLength {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Size handler 写入的字节数（压缩前），日志中间件记录的是这个值
+func (w *writer) Size() int {
+	if w.size == 0 && !w.ResponseWriter.Written() {
+		return -1
+	}
+	return w.size
+}
+
+// Written 缓冲中有数据也算已写入，避免 gin 以为 handler 没有响应
+func (w *writer) Written() bool {
+	return w.size > 0 || w.ResponseWriter.Written()
+}
+
+// Flush 流式响应：立即决定是否压缩，把已有数据发出去
+func (w *writer) Flush() {
+	if !w.decided {
+		// 流式响应的总长度未知，不看 MinLength
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Hijack 接管连接前先把缓冲的数据发出去
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !w.decided {
+		w.decided = true
+		if len(w.buf) > 0 {
+			if _, err := w.ResponseWriter.Write(w.buf); err != nil {
+				return nil, nil, err
+			}
+			w.buf = nil
+		}
+	}
+	return w.ResponseWriter.Hijack()
+}
+
+// decide 根据状态码、响应头和已缓冲的数据决定是否压缩，然后写出缓冲
+// bigEnough 为 false 表示响应体小于 MinLength，不值得压缩
+func (w *writer) decide(bigEnough bool) error {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		// net/http 会根据第一次写入的内容推断类型，压缩后推断的就是 gzip 了，这里提前推断
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if bigEnough && w.shouldCompress() {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		w.enc = w.pools.get(w.encoding, w.ResponseWriter)
+		_, err := w.enc.Write(w.buf)
+		w.buf = nil
+		return err
+	}
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *writer) shouldCompress() bool {
+	switch w.Status() {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified:
+		return false
+	}
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	return w.cfg.allowed(h.Get("Content-Type"))
+}
+
+// close handler 结束：没有决定过就按已有数据决定，然后结束压缩流
+func (w *writer) close() {
+	if !w.decided {
+		_ = w.decide(len(w.buf) >= w.cfg.MinLength)
+	}
+	if w.enc != nil {
+		_ = w.enc.Close()
+		w.pools.put(w.encoding, w.enc)
+		w.enc = nil
+	}
+}
@@ -0,0 +1,170 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip, deflate, br", "gzip"},
+		{"deflate, gzip;q=0.5", "deflate"},
+		{"GZIP", "gzip"},
+		{"gzip;q=0", ""},
+		{"gzip;q=0, *", "deflate"},
+		{"*", "gzip"},
+		{"identity", ""},
+		{"br", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiate(tt.header); got != tt.want {
+			t.Errorf("negotiate(%q) = %q; want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+var big = strings.Repeat("hello compress ", 200) // 3000 字节
+
+func newRouter(cfg Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(New(cfg))
+	r.GET("/text", func(c *gin.Context) { c.String(http.StatusOK, big) })
+	r.GET("/small", func(c *gin.Context) { c.String(http.StatusOK, "hi") })
+	r.GET("/png", func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(big)) })
+	r.GET("/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "br")
+		c.String(http.StatusOK, big)
+	})
+	r.GET("/etag", func(c *gin.Context) {
+		c.Header("ETag", `"v1"`)
+		c.String(http.StatusOK, big)
+	})
+	r.GET("/detect", func(c *gin.Context) { _, _ = c.Writer.Write([]byte("<html>" + big)) })
+	r.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		_, _ = c.Writer.WriteString("data: 1\n\n")
+		c.Writer.Flush()
+	})
+	return r
+}
+
+func decode(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+	var r io.Reader
+	var err error
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(body)
+	case "deflate":
+		r, err = zlib.NewReader(body)
+	default:
+		r = body
+	}
+	if err != nil {
+		t.Fatalf("decode %s: %v", encoding, err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decode %s: %v", encoding, err)
+	}
+	return string(b)
+}
+
+func TestCompress(t *testing.T) {
+	r := newRouter(Config{})
+	tests := []struct {
+		name, path, accept string
+		wantEncoding       string
+		wantBody           string
+	}{
+		{"gzip", "/text", "gzip", "gzip", big},
+		{"deflate", "/text", "deflate", "deflate", big},
+		{"no accept-encoding", "/text", "", "", big},
+		{"below min length", "/small", "gzip", "", "hi"},
+		{"content type not allowed", "/png", "gzip", "", big},
+		{"already encoded", "/encoded", "gzip", "br", big},
+		{"detect content type", "/detect", "gzip", "gzip", "<html>" + big},
+		{"flush compresses small stream", "/stream", "gzip", "gzip", "data: 1\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q; want %q", got, tt.wantEncoding)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q; want Accept-Encoding", got)
+			}
+			encoding := tt.wantEncoding
+			if encoding == "br" {
+				encoding = "" // handler 自己写的，测试里原样比较
+			}
+			if got := decode(t, encoding, w.Body); got != tt.wantBody {
+				t.Errorf("body = %.40q...; want %.40q...", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCompressHeaders(t *testing.T) {
+	r := newRouter(Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/etag", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get("ETag"); got != `W/"v1"` {
+		t.Errorf("ETag = %q; want weak", got)
+	}
+	if got := w.Header().Get("Content-Length"); got != "" {
+		t.Errorf("Content-Length = %q; want removed", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/detect", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Errorf("Content-Type = %q; want detected text/html", got)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	r := newRouter(Config{MinLength: 1, ContentTypes: []string{"image/png"}})
+	for path, want := range map[string]string{"/small": "", "/png": "gzip"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get("Content-Encoding"); got != want {
+			t.Errorf("%s: Content-Encoding = %q; want %q", path, got, want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid Level")
+		}
+	}()
+	New(Config{Level: 10})
+}
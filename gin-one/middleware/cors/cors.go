@@ -0,0 +1,224 @@
+// ============================================================================
+// Package cors 可配置的 CORS 中间件，支持按路由组挂载不同策略
+// ============================================================================
+//
+// 【为什么要按路由组区分？】
+//
+// 公开接口（/api）通常允许多个前端域名访问，而管理后台（/admin）
+// 只应该允许后台域名，并且需要携带 Cookie。一个全局 CORS 配置
+// 要么太松（后台暴露给所有域名），要么太紧（公开接口用不了）。
+//
+// 【用法】
+//
+//	public := r.Group("/api")
+//	cors.Register(public, cors.Policy{AllowOrigins: []string{"https://*.example.com"}})
+//
+//	admin := r.Group("/admin")
+//	cors.Register(admin, cors.Policy{
+//		AllowOrigins:     []string{"https://admin.example.com"},
+//		AllowCredentials: true,
+//	})
+//
+// 【Origin 匹配规则】
+//
+//	"*"                        任意来源（不能与 AllowCredentials 同时使用）
+//	"https://example.com"      精确匹配
+//	"https://*.example.com"    匹配任意一级或多级子域名，不匹配 example.com 本身
+//
+// ============================================================================
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Policy 一组 CORS 规则
+type Policy struct {
+	AllowOrigins     []string
+	AllowMethods     []string      // 默认 GET, POST, PUT, PATCH, DELETE, HEAD
+	AllowHeaders     []string      // 默认 Origin, Content-Type, Accept, Authorization, X-Request-ID
+	ExposeHeaders    []string      // 允许前端 JS 读取的响应头
+	AllowCredentials bool          // 是否允许携带 Cookie / Authorization
+	MaxAge           time.Duration // 预检结果缓存时间，默认 12 小时
+}
+
+// 默认值
+var (
+	DefaultAllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"}
+	DefaultAllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"}
+	DefaultMaxAge       = 12 * time.Hour
+)
+
+// compiled 预处理后的策略，避免每次请求都拼接字符串
+type compiled struct {
+	anyOrigin   bool
+	exact       map[string]bool
+	wildcards   [][2]string // {前缀, 后缀}，如 {"https://", ".example.com"}
+	methods     string
+	headers     map[string]bool
+	headerList  string
+	expose      string
+	credentials bool
+	maxAge      string
+}
+
+func compile(p Policy) *compiled {
+	if len(p.AllowMethods) == 0 {
+		p.AllowMethods = DefaultAllowMethods
+	}
+	if len(p.AllowHeaders) == 0 {
+		p.AllowHeaders = DefaultAllowHeaders
+	}
+	if p.MaxAge == 0 {
+		p.MaxAge = DefaultMaxAge
+	}
+
+	c := &compiled{
+		exact:       make(map[string]bool),
+		headers:     make(map[string]bool),
+		methods:     strings.Join(p.AllowMethods, ", "),
+		headerList:  strings.Join(p.AllowHeaders, ", "),
+		expose:      strings.Join(p.ExposeHeaders, ", "),
+		credentials: p.AllowCredentials,
+		maxAge:      strconv.Itoa(int(p.MaxAge.Seconds())),
+	}
+
+	for _, o := range p.AllowOrigins {
+		o = strings.ToLower(strings.TrimSuffix(o, "/"))
+		switch {
+		case o == "*":
+			c.anyOrigin = true
+		case strings.Contains(o, "*"):
+			prefix, suffix, _ := strings.Cut(o, "*")
+			c.wildcards = append(c.wildcards, [2]string{prefix, suffix})
+		default:
+			c.exact[o] = true
+		}
+	}
+	for _, h := range p.AllowHeaders {
+		c.headers[strings.ToLower(h)] = true
+	}
+
+	// 规范禁止 Allow-Origin: * 与 Allow-Credentials: true 同时出现
+	// 回显任意 Origin 又等于对所有网站开放带 Cookie 的访问，这里直接拒绝这种配置
+	if c.anyOrigin && c.credentials {
+		panic("cors: AllowOrigins \"*\" cannot be combined with AllowCredentials")
+	}
+	return c
+}
+
+// allowOrigin 判断 Origin 是否在白名单中
+func (p *compiled) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.exact[origin] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		// 通配部分至少一个字符，且不能跨越协议/端口
+		if len(origin) > len(w[0])+len(w[1]) &&
+			strings.HasPrefix(origin, w[0]) && strings.HasSuffix(origin, w[1]) {
+			middle := origin[len(w[0]) : len(origin)-len(w[1])]
+			if !strings.ContainsAny(middle, "/:") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowHeaders 预检请求中申请的头是否都被允许
+func (p *compiled) allowHeaders(requested string) bool {
+	for _, h := range strings.Split(requested, ",") {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h != "" && !p.headers[h] {
+			return false
+		}
+	}
+	return true
+}
+
+// New 根据策略创建中间件
+func New(policy Policy) gin.HandlerFunc {
+	p := compile(policy)
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		h := c.Writer.Header()
+
+		// 响应内容随 Origin 变化，必须告诉缓存
+		h.Add("Vary", "Origin")
+
+		// 非跨域请求（同源或服务端调用）直接放行
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		preflight := c.Request.Method == http.MethodOptions &&
+			c.GetHeader("Access-Control-Request-Method") != ""
+
+		if !p.allowOrigin(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			// 简单请求不带 CORS 头，由浏览器拦截响应
+			c.Next()
+			return
+		}
+
+		if p.anyOrigin {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if p.credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			if req := c.GetHeader("Access-Control-Request-Headers"); req != "" && !p.allowHeaders(req) {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			h.Set("Access-Control-Allow-Methods", p.methods)
+			h.Set("Access-Control-Allow-Headers", p.headerList)
+			h.Set("Access-Control-Max-Age", p.maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if p.expose != "" {
+			h.Set("Access-Control-Expose-Headers", p.expose)
+		}
+		c.Next()
+	}
+}
+
+// Register 把策略挂到路由组上，并为该组注册 OPTIONS 兜底路由
+//
+// 【为什么需要兜底路由？】
+//
+// Gin 只有在路由匹配成功后才会执行路由组中间件。
+// 组内没有注册 OPTIONS 方法时，预检请求直接 404，CORS 中间件根本不会运行。
+//
+// 【注意】
+//  1. 必须在注册组内路由和认证中间件之前调用，否则预检请求会先被认证拦截
+//  2. 同一个前缀只能 Register 一次，且各组前缀不能互相包含（如 / 与 /admin）
+func Register(group *gin.RouterGroup, policy Policy) {
+	group.Use(New(policy))
+	group.OPTIONS("/*cors", func(c *gin.Context) {
+		// 非预检的 OPTIONS 请求
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,89 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAllowOrigin(t *testing.T) {
+	p := compile(Policy{AllowOrigins: []string{"https://example.com", "https://*.example.com"}})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://example.com", true},
+		{"https://EXAMPLE.com", true},
+		{"https://app.example.com", true},
+		{"https://a.b.example.com", true},
+		{"http://app.example.com", false},       // 协议不同
+		{"https://evil.com", false},             // 不在白名单
+		{"https://example.com.evil.com", false}, // 后缀伪造
+		{"https://evil.com:443/.example.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := p.allowOrigin(tt.origin); got != tt.want {
+			t.Errorf("allowOrigin(%q) = %v; want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestCredentialsWithWildcardPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for * with credentials")
+		}
+	}()
+	New(Policy{AllowOrigins: []string{"*"}, AllowCredentials: true})
+}
+
+func TestGroupPolicies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	public := r.Group("/api")
+	Register(public, Policy{AllowOrigins: []string{"*"}})
+	public.GET("/posts", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	admin := r.Group("/admin")
+	Register(admin, Policy{AllowOrigins: []string{"https://admin.example.com"}, AllowCredentials: true})
+	admin.Use(func(c *gin.Context) { c.AbortWithStatus(http.StatusUnauthorized) }) // 模拟认证
+	admin.DELETE("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		origin     string
+		wantStatus int
+		wantOrigin string
+	}{
+		{"public simple", http.MethodGet, "/api/posts", "https://any.site", http.StatusOK, "*"},
+		{"admin preflight ok", http.MethodOptions, "/admin/users/1", "https://admin.example.com", http.StatusNoContent, "https://admin.example.com"},
+		{"admin preflight denied", http.MethodOptions, "/admin/users/1", "https://any.site", http.StatusForbidden, ""},
+		{"admin request from other origin", http.MethodDelete, "/admin/users/1", "https://any.site", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d; want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Allow-Origin = %q; want %q", got, tt.wantOrigin)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// ============================================================================
+// Package csrf 跨站请求伪造防护：双重提交 Cookie / 同步令牌两种模式
+// ============================================================================
+//
+// 【什么时候需要】
+//
+// 浏览器给跨站发起的请求自动带上 Cookie。只要登录状态放在 Cookie 里（session 包、
+// 服务端渲染的表单），别的网站就能让已登录用户的浏览器提交修改数据的请求。
+// 攻击者能让浏览器带上 Cookie，但读不到本站页面里的 Token，所以要求请求再带一个 Token。
+//
+// 只用 Authorization: Bearer 的 JWT 接口（/api、/admin）不需要：浏览器不会自动加这个头，
+// 跨站请求要加自定义头必须先通过 CORS 预检。这些路由组放进 Exempt，或者干脆不挂这个中间件。
+//
+// 【两种模式】
+//
+// | 模式         | Token 保存在                   | 校验                              | 适合                      |
+// |--------------|--------------------------------|-----------------------------------|---------------------------|
+// | 同步令牌     | 服务端会话（session 包）       | 请求里的 Token == 会话里的 Token  | 已经有服务端会话          |
+// | 双重提交     | 签名的 Cookie（__Host- 前缀）  | 请求里的 Token == Cookie 里的值   | 无会话的表单（留言、订阅）|
+//
+// Config.SessionToken 不为空时用同步令牌模式，否则用双重提交。
+//
+// 【双重提交防种 Cookie】
+//
+// 兄弟子域名（或同站的明文 HTTP 页面）能写入本域名的 Cookie：攻击者先种一个自己知道的值，
+// 再提交同样的 Token 就能通过校验。两道防线各管一部分：
+//
+// | 防线               | 挡住                                   | 挡不住                                        |
+// |--------------------|----------------------------------------|-----------------------------------------------|
+// | __Host- 前缀       | 任何子域名、明文页面写入这个 Cookie    | Secure=false（开发环境）时不能用前缀          |
+// | HMAC 签名          | 攻击者自己编的值                       | 从本站拿到的合法 Cookie 原样种进来            |
+//
+// 签名不绑定客户端，合法的值谁都能从本站拿一个，所以真正防种的是 __Host- 前缀：
+// Secure 为 true 且 CookiePath 为 / 时默认 Cookie 名为 __Host-csrf_token，浏览器只接受
+// 本站 HTTPS 响应设置的、不带 Domain 的这个 Cookie。开发环境 Secure=false 时没有这层保护。
+//
+// 【每个表单一个 Token】
+//
+// Token(c) 每次调用返回不同的字符串（随机掩码 XOR 真实 Token），校验时先去掉掩码。
+// 页面里每个表单的 Token 都不一样，HTTPS 压缩侧信道（BREACH）猜不出固定的值。
+// 会话里保存的原始 Token（如 session 的 CSRFToken()）也能通过校验。
+//
+// 【用法】
+//
+//	// 同步令牌：挂在 session 中间件之后
+//	protect, _ := csrf.New(csrf.Config{SessionToken: session.CSRFToken})
+//	web := r.Group("/session", sessions.Middleware(), protect.Middleware())
+//
+//	// 模板里每个表单放一个隐藏字段
+//	c.HTML(http.StatusOK, "profile", gin.H{"CSRFField": csrf.TemplateField(c)})
+//	// <form method="post">{{ .CSRFField }} ...</form>
+//
+//	// 前端 JS：读 Cookie（双重提交）或接口返回的 Token，放进 X-CSRF-Token 头
+//
+// 校验失败返回统一的 403 信封：{"code":-1,"message":"CSRF 校验失败...","error":"csrf_failed"}
+//
+// ============================================================================
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// contextKey 当前请求的真实 Token 在 gin.Context 中的键
+const contextKey = "csrf"
+
+// 默认的 Token 位置
+const (
+	DefaultHeader = "X-CSRF-Token"
+	DefaultField  = "csrf_token"
+	DefaultCookie = "csrf_token"
+	// HostCookie Secure 时默认的 Cookie 名，__Host- 前缀让子域名无法写入
+	HostCookie = hostPrefix + DefaultCookie
+)
+
+const hostPrefix = "__Host-"
+
+// Config 配置
+type Config struct {
+	// SessionToken 返回服务端会话里的 Token，没有会话时返回空字符串；不为空时用同步令牌模式
+	SessionToken func(c *gin.Context) string
+
+	// Secret 双重提交模式签名 Cookie 的密钥，至少 32 字节；同步令牌模式不需要
+	Secret []byte
+
+	// HeaderName 默认 X-CSRF-Token；FieldName 表单字段，默认 csrf_token
+	HeaderName string
+	FieldName  string
+
+	// CookieName 双重提交模式的 Cookie，Secure 时默认 __Host-csrf_token，否则 csrf_token；CookiePath 默认 /
+	// Cookie 不设 HttpOnly，前端 JS 要读出来放进请求头（注意名字随 Secure 变化）
+	// 自己指定 __Host- 开头的名字时必须 Secure 且 CookiePath 为 /
+	CookieName string
+	CookiePath string
+	// Secure Cookie 只通过 HTTPS 发送并启用 __Host- 前缀，生产环境必须为 true
+	Secure bool
+
+	// Exempt 不检查的路径前缀，如只用 Bearer Token 的 "/api/"；全局挂载时用
+	Exempt []string
+}
+
+// Protection CSRF 防护
+type Protection struct {
+	cfg Config
+	key []byte
+}
+
+// New 创建 CSRF 防护
+func New(cfg Config) (*Protection, error) {
+	if cfg.SessionToken == nil && len(cfg.Secret) < 32 {
+		return nil, errors.New("csrf: secret must be at least 32 bytes in double-submit mode")
+	}
+	if cfg.HeaderName == "" {
+		cfg.HeaderName = DefaultHeader
+	}
+	if cfg.FieldName == "" {
+		cfg.FieldName = DefaultField
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = DefaultCookie
+		if cfg.Secure && cfg.CookiePath == "/" {
+			cfg.CookieName = HostCookie
+		}
+	}
+	if strings.HasPrefix(cfg.CookieName, hostPrefix) && (!cfg.Secure || cfg.CookiePath != "/") {
+		return nil, errors.New("csrf: __Host- cookies require Secure and path /")
+	}
+	p := &Protection{cfg: cfg}
+	if cfg.SessionToken == nil {
+		// 从 Secret 派生专用密钥，同一个 Secret 用在别处也不会共用密钥
+		h := hmac.New(sha256.New, cfg.Secret)
+		h.Write([]byte("csrf cookie"))
+		p.key = h.Sum(nil)
+	}
+	return p, nil
+}
+
+// Middleware 把当前请求的 Token 放进 gin.Context（Token / TemplateField 读取），
+// 修改数据的请求（GET / HEAD / OPTIONS / TRACE 之外）校验请求带的 Token
+func (p *Protection) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, prefix := range p.cfg.Exempt {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		var real string
+		if p.cfg.SessionToken != nil {
+			real = p.cfg.SessionToken(c)
+		} else {
+			real = p.cookieToken(c)
+		}
+		if real != "" {
+			c.Set(contextKey, real)
+		}
+
+		if safe(c.Request.Method) {
+			c.Next()
+			return
+		}
+		// 同步令牌模式没有会话就没有登录状态可以被冒用，交给认证中间件处理
+		if real == "" && p.cfg.SessionToken != nil {
+			c.Next()
+			return
+		}
+		sent := c.GetHeader(p.cfg.HeaderName)
+		if sent == "" {
+			sent = c.PostForm(p.cfg.FieldName)
+		}
+		if real == "" || !verify(sent, real) {
+			response.Abort(c, http.StatusForbidden, "csrf_failed", "CSRF 校验失败，请刷新页面后重试")
+			return
+		}
+		c.Next()
+	}
+}
+
+// cookieToken 读取并校验双重提交 Cookie；没有或签名不对时签发新的，
+// 这次请求如果要修改数据仍然失败（请求里的 Token 不可能和新 Cookie 一致）
+func (p *Protection) cookieToken(c *gin.Context) string {
+	if v, err := c.Cookie(p.cfg.CookieName); err == nil && p.validCookie(v) {
+		return v
+	}
+	v := p.newCookie()
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(p.cfg.CookieName, v, 0, p.cfg.CookiePath, "", p.cfg.Secure, false)
+	if safe(c.Request.Method) {
+		return v
+	}
+	return ""
+}
+
+// safe 不修改数据的方法，不校验 Token
+func safe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// Token 当前请求的 Token，加了随机掩码，每次调用结果不同；放进表单或返回给前端。
+// 没有挂 Middleware 或同步令牌模式下没有会话时返回空字符串
+func Token(c *gin.Context) string {
+	real := c.GetString(contextKey)
+	if real == "" {
+		return ""
+	}
+	return mask(real)
+}
@@ -0,0 +1,187 @@
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func serve(r *gin.Engine, method, path string, form url.Values, header http.Header, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	var body *strings.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	} else {
+		body = strings.NewReader("")
+	}
+	req := httptest.NewRequest(method, path, body)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func cookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestDoubleSubmit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	p, err := New(Config{Secret: secret, Exempt: []string{"/api/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(p.Middleware())
+	r.GET("/form", func(c *gin.Context) { c.String(http.StatusOK, string(TemplateField(c))) })
+	r.POST("/form", func(c *gin.Context) { c.String(http.StatusOK, "saved") })
+	r.POST("/api/posts", func(c *gin.Context) { c.String(http.StatusOK, "jwt") })
+
+	// 第一次打开表单：签发 Cookie，页面里有隐藏字段
+	rec := serve(r, http.MethodGet, "/form", nil, nil)
+	ck := cookie(rec, DefaultCookie)
+	if ck == nil || ck.HttpOnly {
+		t.Fatalf("cookie = %+v; want readable csrf cookie", ck)
+	}
+	field := rec.Body.String()
+	if !strings.Contains(field, `name="csrf_token"`) {
+		t.Fatalf("field = %s", field)
+	}
+	token := field[strings.Index(field, `value="`)+7 : strings.LastIndex(field, `"`)]
+
+	tests := []struct {
+		name    string
+		form    url.Values
+		header  http.Header
+		cookies []*http.Cookie
+		want    int
+	}{
+		{"masked form token", url.Values{"csrf_token": {token}}, nil, []*http.Cookie{ck}, http.StatusOK},
+		{"raw cookie value in header (SPA)", nil, http.Header{"X-Csrf-Token": {ck.Value}}, []*http.Cookie{ck}, http.StatusOK},
+		{"missing token", url.Values{}, nil, []*http.Cookie{ck}, http.StatusForbidden},
+		{"wrong token", url.Values{"csrf_token": {"x" + token[1:]}}, nil, []*http.Cookie{ck}, http.StatusForbidden},
+		{"no cookie", url.Values{"csrf_token": {token}}, nil, nil, http.StatusForbidden},
+		// 攻击者自己编的值：和 Token 一致，但没有本站签名
+		{"planted cookie", nil, http.Header{"X-Csrf-Token": {"planted.sig"}},
+			[]*http.Cookie{{Name: DefaultCookie, Value: "planted.sig"}}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(r, http.MethodPost, "/form", tt.form, tt.header, tt.cookies...)
+			if rec.Code != tt.want {
+				t.Fatalf("code = %d; want %d (%s)", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want == http.StatusForbidden && !strings.Contains(rec.Body.String(), `"error":"csrf_failed"`) {
+				t.Errorf("body = %s; want unified csrf_failed envelope", rec.Body)
+			}
+		})
+	}
+
+	// 只用 Bearer Token 的接口不检查
+	if rec := serve(r, http.MethodPost, "/api/posts", nil, http.Header{"Authorization": {"Bearer x"}}); rec.Code != http.StatusOK {
+		t.Errorf("exempt path = %d", rec.Code)
+	}
+}
+
+func TestHostCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	p, err := New(Config{Secret: secret, Secure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(p.Middleware())
+	r.GET("/form", func(c *gin.Context) { c.String(http.StatusOK, Token(c)) })
+	r.POST("/form", func(c *gin.Context) { c.String(http.StatusOK, "saved") })
+
+	rec := serve(r, http.MethodGet, "/form", nil, nil)
+	ck := cookie(rec, HostCookie)
+	if ck == nil || !ck.Secure || ck.Path != "/" || ck.Domain != "" {
+		t.Fatalf("cookie = %+v; want Secure host-only __Host-csrf_token on /", ck)
+	}
+	token := rec.Body.String()
+	if rec := serve(r, http.MethodPost, "/form", url.Values{"csrf_token": {token}}, nil, ck); rec.Code != http.StatusOK {
+		t.Errorf("own cookie: code = %d", rec.Code)
+	}
+
+	// 兄弟子域名从本站拿到一个签名合法的值，只能种成不带前缀的 Cookie（浏览器拒绝它写 __Host-），不被读取
+	planted := &http.Cookie{Name: DefaultCookie, Value: ck.Value}
+	h := http.Header{"X-Csrf-Token": {ck.Value}}
+	if rec := serve(r, http.MethodPost, "/form", nil, h, planted); rec.Code != http.StatusForbidden {
+		t.Errorf("planted signed cookie: code = %d; want 403", rec.Code)
+	}
+
+	for _, cfg := range []Config{
+		{Secret: secret, CookieName: HostCookie},
+		{Secret: secret, CookieName: HostCookie, Secure: true, CookiePath: "/forms"},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) accepted an invalid __Host- cookie", cfg)
+		}
+	}
+	// 不是 / 路径时不能用前缀，回退到普通名字
+	if p, err := New(Config{Secret: secret, Secure: true, CookiePath: "/forms"}); err != nil || p.cfg.CookieName != DefaultCookie {
+		t.Errorf("CookiePath /forms: name = %v, %v", p, err)
+	}
+}
+
+func TestSynchronizer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	p, err := New(Config{SessionToken: func(c *gin.Context) string { return c.GetHeader("X-Test-Session") }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(p.Middleware())
+	r.GET("/token", func(c *gin.Context) { c.String(http.StatusOK, Token(c)) })
+	r.POST("/save", func(c *gin.Context) { c.String(http.StatusOK, "saved") })
+
+	session := http.Header{"X-Test-Session": {"session-token"}}
+	a := serve(r, http.MethodGet, "/token", nil, session).Body.String()
+	b := serve(r, http.MethodGet, "/token", nil, session).Body.String()
+	if a == "" || a == b {
+		t.Fatalf("tokens %q, %q; want a different token per form", a, b)
+	}
+	for _, sent := range []string{a, b, "session-token"} {
+		h := http.Header{"X-Test-Session": {"session-token"}, "X-Csrf-Token": {sent}}
+		if rec := serve(r, http.MethodPost, "/save", nil, h); rec.Code != http.StatusOK {
+			t.Errorf("token %q: code = %d", sent, rec.Code)
+		}
+	}
+	h := http.Header{"X-Test-Session": {"other-session"}, "X-Csrf-Token": {a}}
+	if rec := serve(r, http.MethodPost, "/save", nil, h); rec.Code != http.StatusForbidden {
+		t.Errorf("token from another session: code = %d", rec.Code)
+	}
+
+	// 没有会话时不检查，交给认证中间件
+	if rec := serve(r, http.MethodPost, "/save", nil, nil); rec.Code != http.StatusOK {
+		t.Errorf("no session: code = %d", rec.Code)
+	}
+	if got := serve(r, http.MethodGet, "/token", nil, nil).Body.String(); got != "" {
+		t.Errorf("Token without session = %q", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Error("double-submit without secret accepted")
+	}
+}
@@ -0,0 +1,81 @@
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TemplateField 每个表单一个的隐藏字段，放进 html/template 的数据里：
+//
+//	c.HTML(http.StatusOK, "profile", gin.H{"CSRFField": csrf.TemplateField(c)})
+//	<form method="post" action="/profile">{{ .CSRFField }} ...</form>
+//
+// 字段名固定为 DefaultField；改了 Config.FieldName 时自己用 Token(c) 拼
+func TemplateField(c *gin.Context) template.HTML {
+	token := Token(c)
+	if token == "" {
+		return ""
+	}
+	// Token 是 base64url，没有需要转义的字符
+	return template.HTML(`<input type="hidden" name="` + DefaultField + `" value="` + token + `">`)
+}
+
+// mask 输出 base64(pad || pad XOR token)，pad 每次随机
+func mask(token string) string {
+	b := make([]byte, 2*len(token))
+	pad, masked := b[:len(token)], b[len(token):]
+	rand.Read(pad)
+	for i := range masked {
+		masked[i] = pad[i] ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// verify 比较请求带的 Token 和真实 Token；请求里的可以是 Token(c) 加了掩码的，也可以是原始值
+func verify(sent, real string) bool {
+	if sent == "" {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(sent), []byte(real)) == 1 {
+		return true
+	}
+	b, err := base64.RawURLEncoding.DecodeString(sent)
+	if err != nil || len(b) != 2*len(real) {
+		return false
+	}
+	pad, masked := b[:len(real)], b[len(real):]
+	for i := range masked {
+		masked[i] ^= pad[i]
+	}
+	return subtle.ConstantTimeCompare(masked, []byte(real)) == 1
+}
+
+// newCookie 双重提交的 Cookie 值：base64(随机数).base64(HMAC)
+func (p *Protection) newCookie() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	body := base64.RawURLEncoding.EncodeToString(b)
+	return body + "." + base64.RawURLEncoding.EncodeToString(p.mac(body))
+}
+
+func (p *Protection) validCookie(v string) bool {
+	body, sig, ok := strings.Cut(v, ".")
+	if !ok {
+		return false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	return err == nil && hmac.Equal(mac, p.mac(body))
+}
+
+func (p *Protection) mac(body string) []byte {
+	h := hmac.New(sha256.New, p.key)
+	h.Write([]byte(body))
+	return h.Sum(nil)
+}
@@ -0,0 +1,199 @@
+// ============================================================================
+// Package drain 进行中请求计数与关闭时的请求排空
+// ============================================================================
+//
+// 【为什么 http.Server.Shutdown 不够】
+//
+// Shutdown 关闭监听、等空闲连接关闭，但有三个缺口：
+//
+// | 情况                                       | 没有 drain 时                                      |
+// |--------------------------------------------|----------------------------------------------------|
+// | keep-alive 连接上 Shutdown 之后到达的请求  | 照常执行，可能在数据库关闭之后才跑到查库那一步     |
+// | DrainTimeout 到了还没结束的请求            | server 强制关闭连接，handler 还在跑，关闭钩子照常执行 |
+// | 运维想知道"还在处理什么"                   | 只能看日志猜                                       |
+//
+// Tracker 给这几件事补上：
+//
+//	Middleware  每个请求按路由模板（c.FullPath()）计数，结束时减一
+//	Drain       开始排空：之后到达的请求直接 503 + Retry-After + Connection: close，
+//	            客户端或负载均衡换一个实例重试
+//	Wait(ctx)   阻塞到进行中的请求数为 0，关闭数据库之前调用
+//	InFlight    进行中的请求总数（gauge），Handler 按路由输出
+//
+// 【接入 server 和 Lifecycle】
+//
+//	inflight := drain.New(drain.Config{SkipPaths: []string{"/healthz", "/ready"}})
+//	r.Use(inflight.Middleware())                     // 放在最前面，其他中间件的耗时也算在内
+//	srv.OnDrain(inflight.Drain)                       // 开始关闭时拒绝新请求
+//	srv.OnShutdown("database", closeDB)
+//	srv.OnShutdown("in-flight", inflight.Wait)         // 最后注册、最先执行：请求结束后才关数据库
+//
+// cmd 的 serve 命令已经这样接好，Router 里 r.Use(env.InFlight.Middleware()) 即可。
+//
+// SSE / WebSocket 这类长连接一直算在进行中，要用 OnDrain 通知它们退出（见 server 包），
+// 否则 Wait 会等到超时。健康检查放进 SkipPaths：存活探针在排空期间仍要返回 200。
+//
+// ============================================================================
+package drain
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// unmatched 没有匹配到路由的请求（404）计在这个名字下
+const unmatched = "(unmatched)"
+
+// Config 配置
+type Config struct {
+	// RetryAfter 排空期间 503 响应的 Retry-After，默认 5 秒
+	RetryAfter time.Duration
+
+	// SkipPaths 不计数、排空期间也不拒绝的路径（如 /healthz）
+	SkipPaths []string
+}
+
+// Tracker 进行中请求计数，可以并发使用
+type Tracker struct {
+	retryAfter string
+	skip       map[string]bool
+	draining   atomic.Bool
+
+	mu     sync.Mutex
+	total  int64
+	routes map[string]int64
+	idle   []chan struct{} // Wait 的等待者，total 回到 0 时关闭
+}
+
+// New 创建 Tracker
+func New(cfg Config) *Tracker {
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = 5 * time.Second
+	}
+	skip := make(map[string]bool, len(cfg.SkipPaths))
+	for _, p := range cfg.SkipPaths {
+		skip[p] = true
+	}
+	return &Tracker{
+		retryAfter: strconv.Itoa(int((cfg.RetryAfter + time.Second - 1) / time.Second)),
+		skip:       skip,
+		routes:     make(map[string]int64),
+	}
+}
+
+// Middleware 计数中间件；Drain 之后拒绝新请求
+func (t *Tracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if t.skip[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+		if t.draining.Load() {
+			c.Header("Retry-After", t.retryAfter)
+			c.Header("Connection", "close")
+			response.Abort(c, http.StatusServiceUnavailable, "shutting_down", "server is shutting down, retry later")
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = unmatched
+		}
+		t.add(route, 1)
+		defer t.add(route, -1)
+		c.Next()
+	}
+}
+
+func (t *Tracker) add(route string, delta int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total += delta
+	if n := t.routes[route] + delta; n > 0 {
+		t.routes[route] = n
+	} else {
+		delete(t.routes, route)
+	}
+	if t.total == 0 {
+		for _, ch := range t.idle {
+			close(ch)
+		}
+		t.idle = nil
+	}
+}
+
+// Drain 开始排空，之后到达的请求返回 503；可以重复调用，签名与 server.OnDrain 相同
+func (t *Tracker) Drain() {
+	t.draining.Store(true)
+}
+
+// Draining 是否已经开始排空
+func (t *Tracker) Draining() bool {
+	return t.draining.Load()
+}
+
+// InFlight 进行中的请求总数
+func (t *Tracker) InFlight() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+// Routes 每个路由进行中的请求数，只含不为 0 的路由
+func (t *Tracker) Routes() map[string]int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	routes := make(map[string]int64, len(t.routes))
+	for k, v := range t.routes {
+		routes[k] = v
+	}
+	return routes
+}
+
+// Wait 阻塞到进行中的请求数为 0；ctx 先结束时返回错误，带上还没结束的请求数
+// 签名与 server.Hook、Lifecycle 的 OnStop 相同
+func (t *Tracker) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	if t.total == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	t.idle = append(t.idle, ch)
+	t.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("drain: %d requests still in flight %v: %w", t.InFlight(), t.Routes(), ctx.Err())
+	}
+}
+
+// Stats Handler 的响应
+type Stats struct {
+	Draining bool             `json:"draining"`
+	InFlight int64            `json:"in_flight"`
+	Routes   map[string]int64 `json:"routes"`
+}
+
+// Handler 输出排空状态和各路由进行中的请求数，挂在管理员路由下
+func (t *Tracker) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t.mu.Lock()
+		stats := Stats{Draining: t.draining.Load(), InFlight: t.total, Routes: make(map[string]int64, len(t.routes))}
+		for k, v := range t.routes {
+			stats.Routes[k] = v
+		}
+		t.mu.Unlock()
+		response.Success(c, stats)
+	}
+}
@@ -0,0 +1,110 @@
+package drain
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTracker(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tr := New(Config{RetryAfter: 1500 * time.Millisecond, SkipPaths: []string{"/healthz"}})
+	r := gin.New()
+	r.Use(tr.Middleware())
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	r.GET("/slow/:id", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	r.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/stats", tr.Handler())
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	done := make(chan int, 2)
+	for _, id := range []string{"1", "2"} {
+		go func() { done <- serve("/slow/" + id).Code }()
+		<-entered
+	}
+	if got := tr.InFlight(); got != 2 {
+		t.Fatalf("InFlight = %d; want 2", got)
+	}
+	if got := tr.Routes(); got["/slow/:id"] != 2 || len(got) != 1 {
+		t.Errorf("Routes = %v; want /slow/:id: 2", got)
+	}
+	// 统计接口自己也在进行中
+	if rec := serve("/stats"); !strings.Contains(rec.Body.String(), `"in_flight":3`) || !strings.Contains(rec.Body.String(), `"/slow/:id":2`) {
+		t.Errorf("stats = %s", rec.Body)
+	}
+
+	// 没有请求时 Wait 立即返回；有请求时等到超时并报告剩余数量
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := tr.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "2 requests") {
+		t.Errorf("Wait = %v; want deadline with 2 requests", err)
+	}
+
+	tr.Drain()
+	rec := serve("/slow/3")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "2" || rec.Header().Get("Connection") != "close" {
+		t.Errorf("during drain: %d %v", rec.Code, rec.Header())
+	}
+	if !strings.Contains(rec.Body.String(), "shutting_down") && !strings.Contains(rec.Body.String(), "shutting down") {
+		t.Errorf("body = %s", rec.Body)
+	}
+	if rec := serve("/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("skipped path during drain = %d; want 200", rec.Code)
+	}
+
+	waited := make(chan error)
+	go func() { waited <- tr.Wait(context.Background()) }()
+	close(release)
+	for range 2 {
+		if code := <-done; code != http.StatusOK {
+			t.Errorf("in-flight request = %d; want 200 (drain must not cut it off)", code)
+		}
+	}
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Errorf("Wait = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return after requests finished")
+	}
+	if tr.InFlight() != 0 || len(tr.Routes()) != 0 || !tr.Draining() {
+		t.Errorf("after drain: in_flight=%d routes=%v draining=%v", tr.InFlight(), tr.Routes(), tr.Draining())
+	}
+	if err := tr.Wait(context.Background()); err != nil {
+		t.Errorf("Wait with nothing in flight = %v", err)
+	}
+}
+
+func TestUnmatched(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tr := New(Config{})
+	r := gin.New()
+	r.Use(tr.Middleware())
+	var seen int64
+	r.NoRoute(func(c *gin.Context) {
+		seen = tr.Routes()[unmatched]
+		c.Status(http.StatusNotFound)
+	})
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nope", nil))
+	if rec.Code != http.StatusNotFound || seen != 1 {
+		t.Errorf("code = %d, %s = %d; want 404 counted once", rec.Code, unmatched, seen)
+	}
+}
@@ -0,0 +1,225 @@
+// ============================================================================
+// Package etag JSON 接口的 ETag 与条件 GET（If-None-Match → 304）
+// ============================================================================
+//
+// 【流程】
+//
+//	第一次：GET /users/1              → 200，ETag: W/"5d41402abc4b2a76"
+//	之后：  GET /users/1
+//	        If-None-Match: W/"5d41402abc4b2a76"
+//	                                  → 304，没有响应体
+//
+// 客户端（浏览器、带缓存的 HTTP 客户端）用本地缓存的响应体，省掉传输和解析。
+//
+// 【两种 ETag】
+//
+// | 方式                          | 做法                                          | 省掉了什么                 |
+// |-------------------------------|-----------------------------------------------|----------------------------|
+// | 中间件自动计算（默认）        | 缓冲响应体，取 SHA-256 前 8 字节              | 只省带宽，handler 照常执行 |
+// | handler 显式设置              | etag.Check(c, etag.FromTime(u.UpdatedAt))     | 还省掉序列化和后续查询     |
+//
+// handler 设置了 ETag 响应头时，中间件不再计算，直接用它比较 If-None-Match。
+//
+// 【为什么是弱 ETag（W/ 前缀）】
+//
+// 强 ETag 表示"字节完全相同"，Range 请求依赖它。JSON 的字段顺序、空白、压缩都可能改变字节，
+// 而语义不变，所以用弱 ETag；compress 中间件压缩后也会把强 ETag 改成弱 ETag。
+//
+// 【不处理的情况】
+//
+//   - 非 GET / HEAD 请求
+//   - 状态码不是 200
+//   - 响应体超过 MaxSize（只透传，不缓冲、不计算）
+//   - 调用了 Flush 的流式响应
+//
+// ============================================================================
+package etag
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Config ETag 中间件配置
+type Config struct {
+	// MaxSize 最多缓冲的响应体字节数，默认 1MB
+	// 超过后直接透传，不计算 ETag：大响应缓冲在内存里的代价比省下的带宽更高
+	MaxSize int
+}
+
+// New 创建中间件
+func New(cfg Config) gin.HandlerFunc {
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = 1 << 20
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+		w := &writer{ResponseWriter: c.Writer, max: cfg.MaxSize}
+		c.Writer = w
+		// handler panic 时丢弃缓冲，recovery 中间件的错误响应直接写出
+		defer w.discard()
+		c.Next()
+		w.finish(c.Request)
+	}
+}
+
+// FromTime 用实体的更新时间生成弱 ETag
+//
+// 同一个 URL 对应同一个实体，更新时间变了内容才会变；精度到纳秒，1 秒内的两次更新也能区分。
+func FromTime(t time.Time) string {
+	return `W/"` + strconv.FormatInt(t.UnixNano(), 36) + `"`
+}
+
+// FromBytes 用内容摘要生成弱 ETag
+func FromBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return `W/"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// Check 设置 ETag 响应头，与 If-None-Match 匹配时写 304 并返回 true，handler 应直接 return
+//
+//	user, err := svc.Get(ctx, id)
+//	...
+//	if etag.Check(c, etag.FromTime(user.UpdatedAt)) {
+//	    return
+//	}
+//	c.JSON(http.StatusOK, user)
+func Check(c *gin.Context, tag string) bool {
+	c.Header("ETag", tag)
+	if Match(c.GetHeader("If-None-Match"), tag) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// Match If-None-Match 是否包含 tag，按弱比较（忽略 W/ 前缀），"*" 匹配任意值
+func Match(ifNoneMatch, tag string) bool {
+	if ifNoneMatch == "" || tag == "" {
+		return false
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSON application/json 和 application/*+json（如 problem+json）
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || (strings.HasPrefix(mt, "application/") && strings.HasSuffix(mt, "+json"))
+}
+
+// ============================================================================
+// ResponseWriter 包装
+// ============================================================================
+
+type writer struct {
+	gin.ResponseWriter
+	buf         bytes.Buffer
+	max         int
+	passthrough bool // 超过 MaxSize 或 Flush 后不再缓冲
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.buf.Len()+len(b) > w.max {
+		if err := w.stopBuffering(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+func (w *writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written 缓冲中有数据也算已写入
+func (w *writer) Written() bool {
+	return w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// Size 包括还在缓冲中的数据
+func (w *writer) Size() int {
+	if w.passthrough || w.buf.Len() == 0 {
+		return w.ResponseWriter.Size()
+	}
+	return w.buf.Len()
+}
+
+// Flush 流式响应：不再缓冲，也不计算 ETag
+func (w *writer) Flush() {
+	if !w.passthrough {
+		_ = w.stopBuffering()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *writer) stopBuffering() error {
+	w.passthrough = true
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+func (w *writer) discard() {
+	if !w.passthrough {
+		w.passthrough = true
+		w.buf.Reset()
+	}
+}
+
+// finish handler 结束后：计算 ETag、比较 If-None-Match，然后写出缓冲
+func (w *writer) finish(r *http.Request) {
+	if w.passthrough {
+		return
+	}
+	w.passthrough = true // 之后外层中间件的写入直接透传
+	h := w.Header()
+	if w.Status() == http.StatusOK && !w.ResponseWriter.Written() {
+		tag := h.Get("ETag")
+		if tag == "" && w.buf.Len() > 0 && isJSON(h.Get("Content-Type")) {
+			tag = FromBytes(w.buf.Bytes())
+			h.Set("ETag", tag)
+		}
+		if Match(r.Header.Get("If-None-Match"), tag) {
+			// 304 不带响应体，描述响应体的头也要去掉
+			h.Del("Content-Type")
+			h.Del("Content-Length")
+			w.buf.Reset()
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			w.ResponseWriter.WriteHeaderNow()
+			return
+		}
+	}
+	if w.buf.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
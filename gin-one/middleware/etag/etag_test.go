@@ -0,0 +1,130 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		header, tag string
+		want        bool
+	}{
+		{`W/"abc"`, `W/"abc"`, true},
+		{`"abc"`, `W/"abc"`, true}, // 弱比较
+		{`W/"abc"`, `"abc"`, true},
+		{`"x", W/"abc"`, `W/"abc"`, true},
+		{`*`, `W/"abc"`, true},
+		{`W/"abd"`, `W/"abc"`, false},
+		{``, `W/"abc"`, false},
+		{`W/"abc"`, ``, false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.header, tt.tag); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v; want %v", tt.header, tt.tag, got, tt.want)
+		}
+	}
+}
+
+var updatedAt = time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC)
+
+func newRouter(cfg Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		defer func() {
+			if recover() != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			}
+		}()
+		c.Next()
+	})
+	r.Use(New(cfg))
+	r.GET("/json", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"id": 1}) })
+	r.GET("/text", func(c *gin.Context) { c.String(http.StatusOK, "hello") })
+	r.GET("/missing", func(c *gin.Context) { c.JSON(http.StatusNotFound, gin.H{"error": "not found"}) })
+	r.GET("/large", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"data": strings.Repeat("x", 100)}) })
+	r.GET("/explicit", func(c *gin.Context) {
+		if Check(c, FromTime(updatedAt)) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": 1})
+	})
+	r.GET("/header", func(c *gin.Context) {
+		c.Header("ETag", `"v1"`)
+		c.String(http.StatusOK, "plain")
+	})
+	r.GET("/panic", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"partial": true})
+		panic("boom")
+	})
+	r.POST("/json", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"id": 1}) })
+	return r
+}
+
+func do(r http.Handler, method, path, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestETag(t *testing.T) {
+	r := newRouter(Config{MaxSize: 64})
+	jsonTag := FromBytes([]byte(`{"id":1}`))
+
+	tests := []struct {
+		name, method, path, ifNoneMatch string
+		wantCode                        int
+		wantETag                        string
+	}{
+		{"json gets etag", "GET", "/json", "", 200, jsonTag},
+		{"json not modified", "GET", "/json", jsonTag, 304, jsonTag},
+		{"json stale etag", "GET", "/json", `W/"old"`, 200, jsonTag},
+		{"non-json skipped", "GET", "/text", "", 200, ""},
+		{"non-200 skipped", "GET", "/missing", "", 404, ""},
+		{"over max size skipped", "GET", "/large", "", 200, ""},
+		{"post skipped", "POST", "/json", "", 200, ""},
+		{"explicit etag", "GET", "/explicit", "", 200, FromTime(updatedAt)},
+		{"explicit not modified", "GET", "/explicit", FromTime(updatedAt), 304, FromTime(updatedAt)},
+		{"handler header any type", "GET", "/header", `"v1"`, 304, `"v1"`},
+		{"panic discards buffer", "GET", "/panic", "", 500, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(r, tt.method, tt.path, tt.ifNoneMatch)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d; want %d (%s)", w.Code, tt.wantCode, w.Body)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %q; want %q", got, tt.wantETag)
+			}
+			if tt.wantCode == 304 {
+				if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+					t.Errorf("304 body = %q, Content-Type = %q; want empty", w.Body, w.Header().Get("Content-Type"))
+				}
+			}
+		})
+	}
+}
+
+func TestBodyPreserved(t *testing.T) {
+	r := newRouter(Config{MaxSize: 64})
+	for _, path := range []string{"/json", "/large"} {
+		w := do(r, "GET", path, "")
+		if !strings.HasPrefix(w.Body.String(), "{") || !strings.HasSuffix(w.Body.String(), "}") {
+			t.Errorf("%s body = %q", path, w.Body)
+		}
+	}
+	if w := do(r, "GET", "/panic", ""); w.Body.String() != `{"error":"internal"}` {
+		t.Errorf("panic body = %q; want only the recovery response", w.Body)
+	}
+}
@@ -0,0 +1,286 @@
+// ============================================================================
+// Package idempotency Idempotency-Key 中间件：重试的 POST / PATCH 只执行一次
+// ============================================================================
+//
+// 【为什么需要？】
+//
+// 客户端发出"创建订单"后网络超时，它不知道服务端有没有执行成功。
+// 直接重试可能创建两个订单，不重试又可能一个都没有。
+// 客户端为每个操作生成一个唯一 key（如 UUID），重试时带上同一个 key：
+//
+//	POST /orders
+//	Idempotency-Key: 5f1b8c9e-...
+//
+// 服务端第一次执行并保存响应，之后带同一个 key 的请求直接返回保存的响应。
+//
+// 【处理流程】
+//
+// | 情况                                 | 结果                                           |
+// |--------------------------------------|------------------------------------------------|
+// | 第一次出现的 key                     | 执行 handler，保存状态码、响应头、响应体       |
+// | key 已完成，请求体相同               | 返回保存的响应，带 Idempotent-Replayed: true   |
+// | key 已完成，请求体或 URL 不同        | 422：同一个 key 不能用于不同的请求             |
+// | key 还在执行中（并发重试）           | 409，带 Retry-After                            |
+// | handler 返回 5xx 或 panic            | 不保存，删除记录，客户端可以用同一个 key 重试  |
+// | 没有 Idempotency-Key                 | 直接执行（Required 为 true 时返回 400）        |
+//
+// 4xx 响应也会保存：参数错误的请求重试多少次都是同样的错误，修改参数后应换一个 key。
+//
+// 【key 的范围】
+//
+// 存储 key 是 "用户 + Idempotency-Key"，不同用户碰巧用了同一个 key 也不会拿到别人的响应。
+//
+// ============================================================================
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/middleware/realip"
+	"go-one/response"
+)
+
+// Record 保存的一次响应
+type Record struct {
+	// Fingerprint 请求方法、路径和请求体的 SHA-256，用于发现 key 被用在了不同的请求上
+	Fingerprint string `json:"fingerprint"`
+
+	// Done 为 false 表示 handler 还在执行
+	Done bool `json:"done"`
+
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// Store 保存 key 对应的记录，每个方法都必须是原子操作
+type Store interface {
+	// Begin 占住 key：key 不存在时写入一条进行中的记录（lockTTL 后过期）并返回 nil, nil；
+	// 已存在时返回已有的记录（可能还在进行中）
+	Begin(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (*Record, error)
+
+	// Complete 保存响应，ttl 后过期
+	Complete(ctx context.Context, key string, rec *Record, ttl time.Duration) error
+
+	// Release 删除记录，之后同一个 key 可以重新执行
+	Release(ctx context.Context, key string) error
+}
+
+// Config 中间件配置
+type Config struct {
+	// Store 默认 NewMemoryStore()，多实例部署用 NewRedisStore
+	Store Store
+
+	// TTL 保存响应的时间，默认 24 小时；客户端的重试都应该在这个时间内完成
+	TTL time.Duration
+
+	// LockTTL 进行中记录的过期时间，默认 1 分钟
+	// 进程在执行中崩溃时，记录到期后客户端才能重试；应大于 handler 的最长执行时间
+	LockTTL time.Duration
+
+	// Header 默认 "Idempotency-Key"
+	Header string
+
+	// Methods 需要处理的方法，默认 POST、PATCH
+	Methods []string
+
+	// UserFunc 返回当前用户，用来隔离不同用户的 key
+	// 默认读取 JWT 中间件写入的 user_id，未登录时按客户端 IP
+	UserFunc func(c *gin.Context) string
+
+	// Required 为 true 时没有 Idempotency-Key 的请求返回 400
+	Required bool
+
+	// MaxBodySize 计算指纹时最多读取的请求体大小，默认 1MB，超过时返回 413
+	MaxBodySize int64
+}
+
+const maxKeyLength = 255
+
+// New 创建中间件
+//
+// 挂在认证中间件之后（UserFunc 需要用户信息），handler 之前：
+//
+//	orders.POST("", idempotency.New(idempotency.Config{Store: store}), createOrder)
+func New(cfg Config) gin.HandlerFunc {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.LockTTL == 0 {
+		cfg.LockTTL = time.Minute
+	}
+	if cfg.Header == "" {
+		cfg.Header = "Idempotency-Key"
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	if cfg.UserFunc == nil {
+		cfg.UserFunc = byUser
+	}
+	if cfg.MaxBodySize == 0 {
+		cfg.MaxBodySize = 1 << 20
+	}
+
+	return func(c *gin.Context) {
+		if !slices.Contains(cfg.Methods, c.Request.Method) {
+			c.Next()
+			return
+		}
+		key := c.GetHeader(cfg.Header)
+		switch {
+		case key == "" && cfg.Required:
+			response.Abort(c, http.StatusBadRequest, "idempotency_key_required", cfg.Header+" header is required")
+			return
+		case key == "":
+			c.Next()
+			return
+		case len(key) > maxKeyLength:
+			response.Abort(c, http.StatusBadRequest, "invalid_idempotency_key", fmt.Sprintf("%s must be at most %d characters", cfg.Header, maxKeyLength))
+			return
+		}
+
+		fp, ok := fingerprint(c, cfg.MaxBodySize)
+		if !ok {
+			return
+		}
+
+		ctx := c.Request.Context()
+		storeKey := cfg.UserFunc(c) + ":" + key
+		rec, err := cfg.Store.Begin(ctx, storeKey, fp, cfg.LockTTL)
+		if err != nil {
+			// 存储故障时拒绝请求（fail-closed）：放行就可能重复扣款，宁可让客户端稍后重试
+			_ = c.Error(fmt.Errorf("idempotency: %w", err))
+			response.Abort(c, http.StatusServiceUnavailable, "idempotency_unavailable", "请稍后重试")
+			return
+		}
+		if rec != nil {
+			replay(c, rec, fp, cfg.Header)
+			return
+		}
+
+		rw := &recorder{ResponseWriter: c.Writer}
+		c.Writer = rw
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			// handler panic：删除记录让客户端重试，panic 继续交给 recovery 中间件
+			_ = cfg.Store.Release(context.WithoutCancel(ctx), storeKey)
+		}()
+
+		c.Next()
+
+		// 客户端可能已经断开，保存响应不受请求 context 取消的影响
+		saveCtx := context.WithoutCancel(ctx)
+		status := rw.Status()
+		if status >= http.StatusInternalServerError {
+			completed = true
+			_ = cfg.Store.Release(saveCtx, storeKey)
+			return
+		}
+		err = cfg.Store.Complete(saveCtx, storeKey, &Record{
+			Fingerprint: fp,
+			Done:        true,
+			Status:      status,
+			Header:      storedHeader(rw.Header()),
+			Body:        rw.body.Bytes(),
+		}, cfg.TTL)
+		completed = true
+		if err != nil {
+			_ = c.Error(fmt.Errorf("idempotency: save response: %w", err))
+			_ = cfg.Store.Release(saveCtx, storeKey)
+		}
+	}
+}
+
+// replay 处理 key 已存在的情况
+func replay(c *gin.Context, rec *Record, fp, header string) {
+	switch {
+	case !rec.Done:
+		c.Header("Retry-After", "1")
+		response.Abort(c, http.StatusConflict, "request_in_progress", "a request with the same "+header+" is still being processed")
+	case rec.Fingerprint != fp:
+		response.Abort(c, http.StatusUnprocessableEntity, "idempotency_key_reused", header+" was already used for a different request")
+	default:
+		h := c.Writer.Header()
+		for k, vs := range rec.Header {
+			h[k] = slices.Clone(vs)
+		}
+		h.Set("Idempotent-Replayed", "true")
+		c.Status(rec.Status)
+		_, _ = c.Writer.Write(rec.Body)
+		c.Abort()
+	}
+}
+
+// fingerprint 读取请求体计算指纹，再放回去给 handler 使用
+func fingerprint(c *gin.Context, limit int64) (string, bool) {
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+		if err != nil {
+			response.Abort(c, http.StatusBadRequest, "invalid_body", "read request body failed")
+			return "", false
+		}
+		if int64(len(body)) > limit {
+			response.Abort(c, http.StatusRequestEntityTooLarge, "body_too_large", "request body too large")
+			return "", false
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	h := sha256.New()
+	// 查询参数也算请求的一部分：?amount=1 和 ?amount=100 不是同一个请求
+	h.Write([]byte(c.Request.Method + " " + c.Request.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// skipHeaders 不保存的响应头：每次响应都应该重新生成
+var skipHeaders = []string{"Date", "Set-Cookie", "X-Request-Id", "Content-Length"}
+
+func storedHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for _, k := range skipHeaders {
+		out.Del(k)
+	}
+	return out
+}
+
+func byUser(c *gin.Context) string {
+	if id, ok := c.Get("user_id"); ok {
+		return fmt.Sprintf("user:%v", id)
+	}
+	return "ip:" + realip.FromContext(c)
+}
+
+// recorder 在写给客户端的同时保留一份响应体
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
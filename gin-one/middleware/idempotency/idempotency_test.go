@@ -0,0 +1,282 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRouter POST /orders 每次执行 calls 加一，返回 201 和执行次数
+func newRouter(cfg Config, calls *atomic.Int32) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		defer func() {
+			if recover() != nil {
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}
+		}()
+		c.Next()
+	})
+	r.Use(New(cfg))
+	handler := func(c *gin.Context) {
+		n := calls.Add(1)
+		var body struct{ Item string }
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		switch body.Item {
+		case "fail":
+			c.Status(http.StatusInternalServerError)
+			return
+		case "panic":
+			panic("boom")
+		}
+		c.Header("X-Order", body.Item)
+		c.JSON(http.StatusCreated, gin.H{"item": body.Item, "call": n})
+	}
+	r.POST("/orders", handler)
+	r.PUT("/orders", handler)
+	return r
+}
+
+func do(r http.Handler, method, key, user, body string) *httptest.ResponseRecorder {
+	return doTarget(r, method, "/orders", key, user, body)
+}
+
+func doTarget(r http.Handler, method, target, key, user, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	if user != "" {
+		req.RemoteAddr = user + ":1234"
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestReplay(t *testing.T) {
+	type step struct {
+		method, key, user, body string
+		wantCode                int
+		wantReplay              bool
+		wantCalls               int32
+	}
+	tests := []struct {
+		name  string
+		cfg   Config
+		steps []step
+	}{
+		{"replay same request", Config{}, []step{
+			{"POST", "k1", "", `{"item":"a"}`, 201, false, 1},
+			{"POST", "k1", "", `{"item":"a"}`, 201, true, 1},
+		}},
+		{"different body", Config{}, []step{
+			{"POST", "k1", "", `{"item":"a"}`, 201, false, 1},
+			{"POST", "k1", "", `{"item":"b"}`, 422, false, 1},
+		}},
+		{"new key runs again", Config{}, []step{
+			{"POST", "k1", "", `{"item":"a"}`, 201, false, 1},
+			{"POST", "k2", "", `{"item":"a"}`, 201, false, 2},
+		}},
+		{"keys scoped per user", Config{}, []step{
+			{"POST", "k1", "10.0.0.1", `{"item":"a"}`, 201, false, 1},
+			{"POST", "k1", "10.0.0.2", `{"item":"a"}`, 201, false, 2},
+		}},
+		{"no key", Config{}, []step{
+			{"POST", "", "", `{"item":"a"}`, 201, false, 1},
+			{"POST", "", "", `{"item":"a"}`, 201, false, 2},
+		}},
+		{"key required", Config{Required: true}, []step{
+			{"POST", "", "", `{"item":"a"}`, 400, false, 0},
+		}},
+		{"method not covered", Config{}, []step{
+			{"PUT", "k1", "", `{"item":"a"}`, 201, false, 1},
+			{"PUT", "k1", "", `{"item":"a"}`, 201, false, 2},
+		}},
+		{"4xx is stored", Config{}, []step{
+			{"POST", "k1", "", `not json`, 400, false, 1},
+			{"POST", "k1", "", `not json`, 400, true, 1},
+		}},
+		{"5xx is released", Config{}, []step{
+			{"POST", "k1", "", `{"item":"fail"}`, 500, false, 1},
+			{"POST", "k1", "", `{"item":"fail"}`, 500, false, 2},
+		}},
+		{"panic is released", Config{}, []step{
+			{"POST", "k1", "", `{"item":"panic"}`, 500, false, 1},
+			{"POST", "k1", "", `{"item":"panic"}`, 500, false, 2},
+		}},
+		{"key too long", Config{}, []step{
+			{"POST", strings.Repeat("k", 256), "", `{"item":"a"}`, 400, false, 0},
+		}},
+		{"body too large", Config{MaxBodySize: 4}, []step{
+			{"POST", "k1", "", `{"item":"a"}`, 413, false, 0},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			r := newRouter(tt.cfg, &calls)
+			var first string
+			for i, s := range tt.steps {
+				w := do(r, s.method, s.key, s.user, s.body)
+				if w.Code != s.wantCode {
+					t.Fatalf("step %d: status = %d; want %d (%s)", i, w.Code, s.wantCode, w.Body)
+				}
+				if replayed := w.Header().Get("Idempotent-Replayed") == "true"; replayed != s.wantReplay {
+					t.Errorf("step %d: replayed = %v; want %v", i, replayed, s.wantReplay)
+				}
+				if got := calls.Load(); got != s.wantCalls {
+					t.Errorf("step %d: calls = %d; want %d", i, got, s.wantCalls)
+				}
+				if s.wantReplay && w.Body.String() != first {
+					t.Errorf("step %d: replayed body = %s; want %s", i, w.Body, first)
+				}
+				if i == 0 {
+					first = w.Body.String()
+				}
+			}
+		})
+	}
+}
+
+func TestFingerprintQuery(t *testing.T) {
+	var calls atomic.Int32
+	r := newRouter(Config{}, &calls)
+	if w := doTarget(r, "POST", "/orders?qty=1", "k1", "", `{"item":"a"}`); w.Code != http.StatusCreated {
+		t.Fatalf("first: status = %d; want 201", w.Code)
+	}
+	if w := doTarget(r, "POST", "/orders?qty=1", "k1", "", `{"item":"a"}`); w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("same query: want replay")
+	}
+	if w := doTarget(r, "POST", "/orders?qty=100", "k1", "", `{"item":"a"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different query: status = %d; want 422", w.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d; want 1", calls.Load())
+	}
+}
+
+func TestReplayHeaders(t *testing.T) {
+	var calls atomic.Int32
+	r := newRouter(Config{}, &calls)
+	do(r, "POST", "k1", "", `{"item":"a"}`)
+	w := do(r, "POST", "k1", "", `{"item":"a"}`)
+	if got := w.Header().Get("X-Order"); got != "a" {
+		t.Errorf("X-Order = %q; want a", got)
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	started, release := make(chan struct{}), make(chan struct{})
+	r := gin.New()
+	r.POST("/orders", New(Config{}), func(c *gin.Context) {
+		close(started)
+		<-release
+		c.Status(http.StatusCreated)
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		do(r, "POST", "k1", "", `{}`)
+	}()
+	<-started
+
+	w := do(r, "POST", "k1", "", `{}`)
+	if w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" {
+		t.Errorf("concurrent duplicate = %d, Retry-After %q; want 409", w.Code, w.Header().Get("Retry-After"))
+	}
+	close(release)
+	wg.Wait()
+
+	if w := do(r, "POST", "k1", "", `{}`); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("after completion = %d; want replayed 201", w.Code)
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	if rec, _ := s.Begin(ctx, "k", "fp", time.Minute); rec != nil {
+		t.Fatal("first Begin should acquire")
+	}
+	if rec, _ := s.Begin(ctx, "k", "fp", time.Minute); rec == nil || rec.Done {
+		t.Fatalf("second Begin = %+v; want in-flight record", rec)
+	}
+
+	// 进行中的记录到期后可以重新占住（进程崩溃的情况）
+	now = now.Add(2 * time.Minute)
+	if rec, _ := s.Begin(ctx, "k", "fp", time.Minute); rec != nil {
+		t.Fatal("expired lock should be acquired again")
+	}
+
+	_ = s.Complete(ctx, "k", &Record{Fingerprint: "fp", Done: true, Status: 201}, time.Hour)
+	if rec, _ := s.Begin(ctx, "k", "fp", time.Minute); rec == nil || rec.Status != 201 {
+		t.Fatalf("completed record = %+v", rec)
+	}
+	now = now.Add(2 * time.Hour)
+	if rec, _ := s.Begin(ctx, "k", "fp", time.Minute); rec != nil {
+		t.Fatal("expired record should be acquired again")
+	}
+}
+
+// fakeRedis 按脚本模拟 SET NX / SET / DEL，不处理过期
+type fakeRedis struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func (f *fakeRedis) Eval(_ context.Context, script string, keys []string, args ...any) (any, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch script {
+	case beginScript:
+		if v, ok := f.data[keys[0]]; ok {
+			return v, nil
+		}
+		f.data[keys[0]] = args[0].(string)
+		return "", nil
+	case completeScript:
+		f.data[keys[0]] = args[0].(string)
+		return "OK", nil
+	case releaseScript:
+		delete(f.data, keys[0])
+		return int64(1), nil
+	}
+	panic("unknown script")
+}
+
+func TestRedisStore(t *testing.T) {
+	fake := &fakeRedis{data: map[string]string{}}
+	var calls atomic.Int32
+	r := newRouter(Config{Store: NewRedisStore(fake, "idem:")}, &calls)
+
+	first := do(r, "POST", "k1", "", `{"item":"a"}`)
+	again := do(r, "POST", "k1", "", `{"item":"a"}`)
+	if again.Code != 201 || again.Body.String() != first.Body.String() || calls.Load() != 1 {
+		t.Errorf("replay = %d %s, calls %d", again.Code, again.Body, calls.Load())
+	}
+	if _, ok := fake.data["idem:ip:192.0.2.1:k1"]; !ok {
+		t.Errorf("keys = %v", fake.data)
+	}
+}
@@ -0,0 +1,156 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-one/rediseval"
+)
+
+// ============================================================================
+// 内存存储
+// ============================================================================
+//
+// 适合单实例部署。多实例时重试可能落到另一个实例上，那里没有记录，
+// 请求会被再执行一次，这种情况请使用 RedisStore。
+//
+
+// MemoryStore 并发安全的内存存储
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]memoryRecord
+	lastSweep time.Time
+
+	// now 可替换的时钟，测试用
+	now func() time.Time
+}
+
+type memoryRecord struct {
+	rec     Record
+	expires time.Time
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]memoryRecord), now: time.Now}
+}
+
+// Begin 实现 Store
+func (s *MemoryStore) Begin(_ context.Context, key, fingerprint string, lockTTL time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+
+	if r, ok := s.records[key]; ok && now.Before(r.expires) {
+		rec := r.rec
+		return &rec, nil
+	}
+	s.records[key] = memoryRecord{rec: Record{Fingerprint: fingerprint}, expires: now.Add(lockTTL)}
+	return nil, nil
+}
+
+// Complete 实现 Store
+func (s *MemoryStore) Complete(_ context.Context, key string, rec *Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = memoryRecord{rec: *rec, expires: s.now().Add(ttl)}
+	return nil
+}
+
+// Release 实现 Store
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// sweep 定期清理过期记录（调用方已持有锁）
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for k, r := range s.records {
+		if !now.Before(r.expires) {
+			delete(s.records, k)
+		}
+	}
+}
+
+// ============================================================================
+// Redis 存储
+// ============================================================================
+//
+// 记录编码成 JSON 保存在一个字符串 key 里，过期交给 Redis 的 PX。
+// Begin 用 SET NX 占住 key，多个实例同时收到重试时只有一个能执行。
+//
+// 客户端接口和 go-redis 的适配方法见 rediseval。
+//
+
+// RedisStore 基于 Redis 的共享存储
+type RedisStore struct {
+	client rediseval.Client
+	prefix string
+}
+
+// NewRedisStore 创建 Redis 存储，所有 key 加上 prefix（如 "idempotency:"）
+func NewRedisStore(client rediseval.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// beginScript 占住成功时返回空字符串（nil 回复在 go-redis 里是 redis.Nil 错误），否则返回已有记录
+const beginScript = `
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  return ''
+end
+return redis.call('GET', KEYS[1])
+`
+
+const completeScript = `return redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])`
+
+const releaseScript = `return redis.call('DEL', KEYS[1])`
+
+// Begin 实现 Store
+func (s *RedisStore) Begin(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (*Record, error) {
+	pending, err := json.Marshal(Record{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+	reply, err := s.client.Eval(ctx, beginScript, []string{s.prefix + key}, string(pending), lockTTL.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected redis reply: %v", reply)
+	}
+	if data == "" {
+		return nil, nil
+	}
+	var rec Record
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// Complete 实现 Store
+func (s *RedisStore) Complete(ctx context.Context, key string, rec *Record, ttl time.Duration) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Eval(ctx, completeScript, []string{s.prefix + key}, string(data), ttl.Milliseconds())
+	return err
+}
+
+// Release 实现 Store
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	_, err := s.client.Eval(ctx, releaseScript, []string{s.prefix + key})
+	return err
+}
@@ -0,0 +1,327 @@
+// ============================================================================
+// Package logger 基于 log/slog 的结构化访问日志中间件
+// ============================================================================
+//
+// 【与 gin.Logger() 的区别】
+//
+// | 特性     | gin.Logger()   | logger.New()                      |
+// |----------|----------------|-----------------------------------|
+// | 格式     | 纯文本         | JSON（slog.JSONHandler）          |
+// | 日志级别 | 无             | 5xx=ERROR, 4xx=WARN, 其他=INFO    |
+// | 采样     | 不支持         | 按路由 1/N 采样，错误和慢请求全记 |
+// | 脱敏     | 不支持         | Authorization、password 等字段    |
+// | 掩码     | 不支持         | email、phone 等参数保留部分字符   |
+//
+// 【输出示例】
+//
+//	{"time":"...","level":"INFO","msg":"request","method":"GET","path":"/ping",
+//	 "route":"/ping","status":200,"latency":"1.2ms","latency_ms":1,
+//	 "client_ip":"127.0.0.1","request_id":"..."}
+//
+// 【出错位置】
+//
+// c.Error 上报的错误带调用栈（go-learning/errtrace）时，额外记录出错位置，
+// 5xx 还会记录完整调用栈，不用再靠错误消息全文搜索代码：
+//
+//	"error":"connection refused","error_source":"repository/user.go:58",
+//	"error_stack":["/src/repository/user.go:58 go-one/repository.(*userRepository).Get", ...]
+//
+// ============================================================================
+package logger
+
+import (
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-learning/errtrace"
+
+	"go-one/mask"
+	"go-one/middleware/realip"
+)
+
+// contextKey 请求级 Logger 在 gin.Context 中的键
+const contextKey = "logger"
+
+// Config 日志中间件配置
+type Config struct {
+	// Logger 输出目标，默认 JSON 格式写到 stdout
+	Logger *slog.Logger
+
+	// SkipPaths 完全不记录的路径（如 /healthz）
+	SkipPaths []string
+
+	// Sampling 按路由采样：key 为路由模板（c.FullPath()），value 为 N 表示每 N 条记录 1 条
+	// 【注意】状态码 >= 400 和耗时达到 SlowThreshold 的请求不参与采样，始终记录
+	Sampling map[string]int
+
+	// SlowThreshold 慢请求阈值，默认 1 秒
+	SlowThreshold time.Duration
+
+	// RequestIDKey 请求 ID 在 Context 中的键，默认 "request_id"
+	RequestIDKey string
+
+	// LogHeaders 是否记录请求头
+	LogHeaders bool
+
+	// Redact 是否开启脱敏
+	Redact bool
+
+	// RedactHeaders 需要脱敏的请求头（不区分大小写），为空时使用 DefaultRedactHeaders
+	RedactHeaders []string
+
+	// RedactParams 需要脱敏的查询参数（不区分大小写），为空时使用 DefaultRedactParams
+	RedactParams []string
+
+	// MaskParams 需要掩码的查询参数（不区分大小写）到 mask 规则名的映射，为空时使用 mask.DefaultFields
+	// 开启 Redact 且使用默认 Logger 时，Handler 通过 FromContext 记录的同名属性也会掩码
+	MaskParams map[string]string
+}
+
+// 默认脱敏字段
+var (
+	DefaultRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+	DefaultRedactParams  = []string{"password", "passwd", "token", "access_token", "refresh_token", "secret"}
+)
+
+// redacted 脱敏后的占位值
+const redacted = "[REDACTED]"
+
+// Default 使用默认配置（开启脱敏）创建中间件
+func Default() gin.HandlerFunc {
+	return New(Config{Redact: true})
+}
+
+// New 创建结构化日志中间件
+func New(cfg Config) gin.HandlerFunc {
+	if len(cfg.MaskParams) == 0 {
+		cfg.MaskParams = mask.DefaultFields
+	}
+	if cfg.Logger == nil {
+		opts := &slog.HandlerOptions{}
+		if cfg.Redact {
+			opts.ReplaceAttr = mask.ReplaceAttr(cfg.MaskParams)
+		}
+		cfg.Logger = slog.New(slog.NewJSONHandler(os.Stdout, opts))
+	}
+	if cfg.RequestIDKey == "" {
+		cfg.RequestIDKey = "request_id"
+	}
+	if cfg.SlowThreshold <= 0 {
+		cfg.SlowThreshold = time.Second
+	}
+	if len(cfg.RedactHeaders) == 0 {
+		cfg.RedactHeaders = DefaultRedactHeaders
+	}
+	if len(cfg.RedactParams) == 0 {
+		cfg.RedactParams = DefaultRedactParams
+	}
+
+	skip := make(map[string]bool, len(cfg.SkipPaths))
+	for _, p := range cfg.SkipPaths {
+		skip[p] = true
+	}
+	sampler := newSampler(cfg.Sampling)
+
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if skip[path] {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+
+		// 请求级 Logger：后续 Handler 可通过 FromContext 取出，自动带上 request_id
+		reqLogger := cfg.Logger
+		if id := c.GetString(cfg.RequestIDKey); id != "" {
+			reqLogger = reqLogger.With(slog.String("request_id", id))
+		}
+		c.Set(contextKey, reqLogger)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		route := c.FullPath()
+		latency := time.Since(start)
+		if status < 400 && latency < cfg.SlowThreshold && !sampler.keep(route) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", path),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.String("latency", latency.String()),
+			slog.Int64("latency_ms", latency.Milliseconds()),
+			slog.String("client_ip", realip.FromContext(c)),
+			slog.Int("body_size", c.Writer.Size()),
+		}
+
+		// RequestID 中间件可能在本中间件之后执行，这里再取一次
+		if id := c.GetString(cfg.RequestIDKey); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		if query := c.Request.URL.RawQuery; query != "" {
+			if cfg.Redact {
+				query = redactQuery(query, cfg.RedactParams, cfg.MaskParams)
+			}
+			attrs = append(attrs, slog.String("query", query))
+		}
+		if cfg.LogHeaders {
+			attrs = append(attrs, headerAttrs(c, cfg))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+			for _, e := range c.Errors {
+				if st := StackAttrs(e.Err, status >= 500); len(st) > 0 {
+					attrs = append(attrs, st...) // 只记录第一个带调用栈的错误
+					break
+				}
+			}
+		}
+
+		cfg.Logger.LogAttrs(c.Request.Context(), levelFor(status), "request", attrs...)
+	}
+}
+
+// FromContext 取出请求级 Logger，未经过中间件时返回 slog.Default()
+func FromContext(c *gin.Context) *slog.Logger {
+	if v, ok := c.Get(contextKey); ok {
+		if l, ok := v.(*slog.Logger); ok {
+			return l
+		}
+	}
+	return slog.Default()
+}
+
+// StackAttrs 错误带调用栈（errtrace）时返回 error_source，full 为 true 时再加上 error_stack；
+// 没有调用栈时返回 nil。recovery、apperr 等写错误日志的中间件也用它
+func StackAttrs(err error, full bool) []slog.Attr {
+	frames := errtrace.StackOf(err)
+	if len(frames) == 0 {
+		return nil
+	}
+	attrs := []slog.Attr{slog.String("error_source", errtrace.Source(err))}
+	if full {
+		stack := make([]string, len(frames))
+		for i, f := range frames {
+			stack[i] = f.String()
+		}
+		attrs = append(attrs, slog.Any("error_stack", stack))
+	}
+	return attrs
+}
+
+// levelFor 根据状态码选择日志级别
+func levelFor(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// ============================================================================
+// 脱敏
+// ============================================================================
+
+func headerAttrs(c *gin.Context, cfg Config) slog.Attr {
+	attrs := make([]any, 0, len(c.Request.Header))
+	for name, values := range c.Request.Header {
+		value := strings.Join(values, ", ")
+		if cfg.Redact && containsFold(cfg.RedactHeaders, name) {
+			value = redacted
+		}
+		attrs = append(attrs, slog.String(name, value))
+	}
+	return slog.Group("headers", attrs...)
+}
+
+// redactQuery 替换敏感查询参数的值，掩码参数保留部分字符
+func redactQuery(raw string, params []string, masks map[string]string) string {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return redacted
+	}
+	for key, vs := range values {
+		if containsFold(params, key) {
+			values[key] = []string{redacted}
+			continue
+		}
+		if fn, ok := maskFor(masks, key); ok {
+			for i, v := range vs {
+				vs[i] = fn(v)
+			}
+		}
+	}
+	// Encode 会转义 [ ] 和掩码用的 *，这里还原以便阅读
+	return queryUnescaper.Replace(values.Encode())
+}
+
+var queryUnescaper = strings.NewReplacer(url.QueryEscape(redacted), redacted, "%2A", "*")
+
+func maskFor(masks map[string]string, key string) (mask.Func, bool) {
+	for field, rule := range masks {
+		if strings.EqualFold(field, key) {
+			return mask.Rule(rule)
+		}
+	}
+	return nil, false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================
+// 采样
+// ============================================================================
+//
+// 【为什么要采样？】
+//
+// 高频接口（如 /ping、列表页）每秒可能有上千次请求，
+// 全量记录会让日志系统成本飙升，而正常请求的日志价值很低。
+// 采样保留 1/N 的成功请求，失败请求和慢请求始终全量记录。
+//
+
+type sampler struct {
+	rates    map[string]uint64
+	counters sync.Map // route -> *atomic.Uint64
+}
+
+func newSampler(rates map[string]int) *sampler {
+	s := &sampler{rates: make(map[string]uint64, len(rates))}
+	for route, n := range rates {
+		if n > 1 {
+			s.rates[route] = uint64(n)
+		}
+	}
+	return s
+}
+
+// keep 判断本次请求是否记录：每 N 次记录第 1 次
+func (s *sampler) keep(route string) bool {
+	n, ok := s.rates[route]
+	if !ok {
+		return true
+	}
+	v, _ := s.counters.LoadOrStore(route, new(atomic.Uint64))
+	count := v.(*atomic.Uint64).Add(1)
+	return count%n == 1
+}
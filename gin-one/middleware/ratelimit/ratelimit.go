@@ -0,0 +1,225 @@
+// ============================================================================
+// Package ratelimit 限流中间件
+// ============================================================================
+//
+// 【三个可替换的部分】
+//
+//	Algorithm  限流算法：TokenBucket（令牌桶）/ SlidingWindow（滑动窗口日志）
+//	Store      状态存储：MemoryStore（单机）/ RedisStore（多实例共享）
+//	KeyFunc    限流维度：ByIP（按客户端 IP）/ ByUser（按 JWT 中的 user_id）
+//
+// 【算法对比】
+//
+// | 算法         | 突发流量       | 精确度           | 内存占用           |
+// |--------------|---------------|------------------|--------------------|
+// | 令牌桶       | 允许 burst 个  | 平均速率精确     | 每个 key 两个数字  |
+// | 滑动窗口日志 | 不允许         | 任意窗口内精确   | 每个 key N 个时间戳 |
+//
+// 【响应头】
+//
+//	X-RateLimit-Limit      窗口内允许的请求数
+//	X-RateLimit-Remaining  剩余可用次数
+//	X-RateLimit-Reset      额度完全恢复的时间（Unix 秒）
+//	Retry-After            被限流时，多少秒后可以重试
+//
+// ============================================================================
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/middleware/realip"
+)
+
+// Result 一次限流判断的结果
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // 多久后额度完全恢复
+	RetryAfter time.Duration // 被拒绝时多久后可重试
+}
+
+// Store 限流状态存储，每个方法都必须是原子操作
+type Store interface {
+	// TakeToken 令牌桶：补充令牌后尝试取走一个，返回剩余令牌数（可为小数）
+	TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (allowed bool, tokens float64, err error)
+
+	// LogRequest 滑动窗口：清理过期记录后尝试记录本次请求
+	// 返回窗口内请求数（含本次）和最早一条记录的时间
+	LogRequest(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (allowed bool, count int, oldest time.Time, err error)
+}
+
+// Algorithm 限流算法
+type Algorithm interface {
+	Take(ctx context.Context, store Store, key string, now time.Time) (Result, error)
+}
+
+// ============================================================================
+// 令牌桶
+// ============================================================================
+
+type tokenBucket struct {
+	rate  float64 // 每秒补充的令牌数
+	burst int     // 桶容量
+}
+
+// TokenBucket 令牌桶算法：每秒补充 rate 个令牌，最多积累 burst 个
+func TokenBucket(rate float64, burst int) Algorithm {
+	return tokenBucket{rate: rate, burst: burst}
+}
+
+func (a tokenBucket) Take(ctx context.Context, store Store, key string, now time.Time) (Result, error) {
+	allowed, tokens, err := store.TakeToken(ctx, "tb:"+key, a.rate, a.burst, now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	res := Result{
+		Allowed:    allowed,
+		Limit:      a.burst,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: seconds((float64(a.burst) - tokens) / a.rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / a.rate)
+	}
+	return res, nil
+}
+
+// ============================================================================
+// 滑动窗口日志
+// ============================================================================
+
+type slidingWindow struct {
+	limit  int
+	window time.Duration
+}
+
+// SlidingWindow 滑动窗口日志算法：任意 window 时间内最多 limit 次请求
+func SlidingWindow(limit int, window time.Duration) Algorithm {
+	return slidingWindow{limit: limit, window: window}
+}
+
+func (a slidingWindow) Take(ctx context.Context, store Store, key string, now time.Time) (Result, error) {
+	allowed, count, oldest, err := store.LogRequest(ctx, "sw:"+key, a.limit, a.window, now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// 最早的记录滑出窗口时，才会空出一个名额
+	reset := oldest.Add(a.window).Sub(now)
+	res := Result{
+		Allowed:    allowed,
+		Limit:      a.limit,
+		Remaining:  max(a.limit-count, 0),
+		ResetAfter: reset,
+	}
+	if !allowed {
+		res.RetryAfter = reset
+	}
+	return res, nil
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+// ============================================================================
+// 限流维度
+// ============================================================================
+
+// KeyFunc 从请求中提取限流 key
+type KeyFunc func(c *gin.Context) string
+
+// ByIP 按客户端 IP 限流；在代理后面时先挂 realip 中间件，否则伪造 X-Forwarded-For 就能绕过
+func ByIP(c *gin.Context) string {
+	return "ip:" + realip.FromContext(c)
+}
+
+// ByUser 按 JWT 认证中间件写入的 user_id 限流，未登录时退化为按 IP
+// 【注意】必须挂在 JWT 中间件之后
+func ByUser(c *gin.Context) string {
+	if id, ok := c.Get("user_id"); ok {
+		return fmt.Sprintf("user:%v", id)
+	}
+	return ByIP(c)
+}
+
+// ============================================================================
+// 中间件
+// ============================================================================
+
+// Config 限流中间件配置
+type Config struct {
+	Algorithm Algorithm // 必填
+	Store     Store     // 默认 NewMemoryStore()
+	KeyFunc   KeyFunc   // 默认 ByIP
+	Prefix    string    // key 前缀，多个限流器共用一个 Store 时用于区分
+
+	// Skip 返回 true 时跳过限流（如内网 IP、管理员）
+	Skip func(c *gin.Context) bool
+
+	// now 可替换的时钟，测试用
+	now func() time.Time
+}
+
+// New 创建限流中间件
+func New(cfg Config) gin.HandlerFunc {
+	if cfg.Algorithm == nil {
+		panic("ratelimit: Config.Algorithm is required")
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = ByIP
+	}
+	if cfg.now == nil {
+		cfg.now = time.Now
+	}
+
+	return func(c *gin.Context) {
+		if cfg.Skip != nil && cfg.Skip(c) {
+			c.Next()
+			return
+		}
+
+		now := cfg.now()
+		res, err := cfg.Algorithm.Take(c.Request.Context(), cfg.Store, cfg.Prefix+cfg.KeyFunc(c), now)
+		if err != nil {
+			// 存储故障时放行（fail-open），避免 Redis 宕机导致全站不可用
+			_ = c.Error(fmt.Errorf("ratelimit: %w", err))
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(res.ResetAfter).Unix(), 10))
+
+		if !res.Allowed {
+			retry := int(math.Ceil(res.RetryAfter.Seconds()))
+			h.Set("Retry-After", strconv.Itoa(max(retry, 1)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":       "rate_limit_exceeded",
+				"message":     "请求过于频繁，请稍后重试",
+				"retry_after": max(retry, 1),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTokenBucket(t *testing.T) {
+	store := NewMemoryStore()
+	alg := TokenBucket(1, 3) // 每秒 1 个，突发 3 个
+	now := time.Unix(1700000000, 0)
+	ctx := context.Background()
+
+	// 桶初始是满的：前 3 次放行，第 4 次拒绝
+	for i := 0; i < 3; i++ {
+		res, _ := alg.Take(ctx, store, "k", now)
+		if !res.Allowed {
+			t.Fatalf("request %d rejected; want allowed", i+1)
+		}
+	}
+	res, _ := alg.Take(ctx, store, "k", now)
+	if res.Allowed {
+		t.Fatal("4th request allowed; want rejected")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v; want 1s", res.RetryAfter)
+	}
+
+	// 1 秒后补充 1 个令牌
+	res, _ = alg.Take(ctx, store, "k", now.Add(time.Second))
+	if !res.Allowed || res.Remaining != 0 {
+		t.Errorf("after refill: allowed=%v remaining=%d; want true, 0", res.Allowed, res.Remaining)
+	}
+
+	// 不同 key 互不影响
+	if res, _ := alg.Take(ctx, store, "other", now); !res.Allowed {
+		t.Error("other key rejected; want allowed")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	store := NewMemoryStore()
+	alg := SlidingWindow(2, time.Minute)
+	now := time.Unix(1700000000, 0)
+	ctx := context.Background()
+
+	tests := []struct {
+		offset    time.Duration
+		allowed   bool
+		remaining int
+	}{
+		{0, true, 1},
+		{10 * time.Second, true, 0},
+		{30 * time.Second, false, 0},
+		{61 * time.Second, true, 0}, // 第一条滑出窗口
+	}
+
+	for _, tt := range tests {
+		res, err := alg.Take(ctx, store, "k", now.Add(tt.offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != tt.allowed || res.Remaining != tt.remaining {
+			t.Errorf("at +%v: allowed=%v remaining=%d; want %v, %d",
+				tt.offset, res.Allowed, res.Remaining, tt.allowed, tt.remaining)
+		}
+	}
+}
+
+// TestMemoryStoreRetention 慢速率和长窗口的 key 空闲超过 10 分钟也不能被清理成满额
+func TestMemoryStoreRetention(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Unix(1700000000, 0)
+	ctx := context.Background()
+
+	bucket := TokenBucket(1.0/3600, 2) // 每小时 1 个
+	window := SlidingWindow(2, time.Hour)
+	for i := 0; i < 2; i++ {
+		bucket.Take(ctx, store, "k", now)
+		window.Take(ctx, store, "k", now)
+	}
+
+	later := now.Add(11 * time.Minute)
+	if res, _ := bucket.Take(ctx, store, "k", later); res.Allowed {
+		t.Error("token bucket reset after 11 idle minutes; want rejected")
+	}
+	if res, _ := window.Take(ctx, store, "k", later); res.Allowed {
+		t.Error("sliding window reset after 11 idle minutes; want rejected")
+	}
+
+	// 桶补满、记录滑出窗口后才清理
+	store.sweep(now.Add(90 * time.Minute))
+	if len(store.buckets) != 1 || len(store.windows) != 0 {
+		t.Errorf("after 90m: %d buckets, %d windows; want 1, 0", len(store.buckets), len(store.windows))
+	}
+	store.sweep(now.Add(3 * time.Hour))
+	if len(store.buckets) != 0 {
+		t.Errorf("after 3h: %d buckets; want 0", len(store.buckets))
+	}
+}
+
+func TestMiddlewareHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Unix(1700000000, 0)
+	r := gin.New()
+	r.Use(New(Config{
+		Algorithm: SlidingWindow(1, time.Minute),
+		now:       func() time.Time { return now },
+	}))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("first status = %d; want 200", w.Code)
+	}
+	if got := w.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("X-RateLimit-Remaining = %q; want 0", got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second status = %d; want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q; want 60", got)
+	}
+	if got := w.Header().Get("X-RateLimit-Reset"); got != "1700000060" {
+		t.Errorf("X-RateLimit-Reset = %q; want 1700000060", got)
+	}
+}
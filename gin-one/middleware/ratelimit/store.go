@@ -0,0 +1,247 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-one/rediseval"
+)
+
+// ============================================================================
+// 内存存储
+// ============================================================================
+//
+// 适合单实例部署。多实例时每个实例各自计数，实际限额会变成 N 倍，
+// 这种情况请使用 RedisStore。
+//
+
+// 【什么时候可以清理一个 key？】
+//
+// 只有当它的状态和"从没出现过"等价时才能删，否则删除就等于把额度重置为满：
+//
+//	令牌桶    桶已补满：now >= full（full = 最后一次访问 + 补满所需时间）
+//	滑动窗口  最新一条记录已滑出窗口：now - times[末尾] >= window
+//
+// 这和 RedisStore 的 PEXPIRE 取值一致（burst/rate 与 window）。
+// 固定的空闲时长不行：日限额的桶空闲 10 分钟后远没补满，删掉就白送一整天的额度。
+//
+
+// MemoryStore 并发安全的内存存储
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucketState
+	windows   map[string]*windowState
+	lastSweep time.Time
+}
+
+type bucketState struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // 按当前速率补满的时间，之后可以清理
+}
+
+type windowState struct {
+	times  []time.Time // 按时间升序
+	window time.Duration
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucketState),
+		windows: make(map[string]*windowState),
+	}
+}
+
+// TakeToken 实现 Store
+func (s *MemoryStore) TakeToken(_ context.Context, key string, rate float64, burst int, now time.Time) (bool, float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucketState{tokens: float64(burst), last: now}
+		s.buckets[key] = b
+	}
+
+	// 按流逝时间补充令牌，不超过桶容量
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(float64(burst), b.tokens+elapsed*rate)
+	}
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second)))
+	return allowed, b.tokens, nil
+}
+
+// LogRequest 实现 Store
+func (s *MemoryStore) LogRequest(_ context.Context, key string, limit int, window time.Duration, now time.Time) (bool, int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	w, ok := s.windows[key]
+	if !ok {
+		w = &windowState{}
+		s.windows[key] = w
+	}
+	w.window = window
+
+	// 丢弃滑出窗口的记录
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(w.times) && !w.times[i].After(cutoff) {
+		i++
+	}
+	w.times = w.times[i:]
+
+	allowed := len(w.times) < limit
+	if allowed {
+		w.times = append(w.times, now)
+	}
+
+	oldest := now
+	if len(w.times) > 0 {
+		oldest = w.times[0]
+	}
+	return allowed, len(w.times), oldest, nil
+}
+
+// sweep 定期清理已恢复到初始状态的 key（调用方已持有锁）
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for k, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, k)
+		}
+	}
+	for k, w := range s.windows {
+		if len(w.times) == 0 || now.Sub(w.times[len(w.times)-1]) >= w.window {
+			delete(s.windows, k)
+		}
+	}
+}
+
+// ============================================================================
+// Redis 存储
+// ============================================================================
+//
+// 状态读写用 Lua 脚本保证原子性，客户端接口和 go-redis 的适配方法见 rediseval。
+//
+
+// RedisStore 基于 Redis 的共享存储
+type RedisStore struct {
+	client rediseval.Client
+	prefix string
+}
+
+// NewRedisStore 创建 Redis 存储，所有 key 加上 prefix（如 "ratelimit:"）
+func NewRedisStore(client rediseval.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// tokenBucketScript 令牌数用字符串返回，避免 Lua number 转换成 Redis 整数时丢失小数
+const tokenBucketScript = `
+local rate  = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now   = tonumber(ARGV[3])
+local state  = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts     = tonumber(state[2]) or now
+if now > ts then
+  tokens = math.min(burst, tokens + (now - ts) / 1000 * rate)
+end
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`
+
+// slidingWindowScript 用有序集合保存请求时间戳，score 为毫秒时间
+const slidingWindowScript = `
+local limit  = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now    = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], 0, now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+  redis.call('ZADD', KEYS[1], now, ARGV[4])
+  count = count + 1
+  allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], window)
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {allowed, count, oldest[2] or tostring(now)}
+`
+
+// TakeToken 实现 Store
+func (s *RedisStore) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (bool, float64, error) {
+	reply, err := s.client.Eval(ctx, tokenBucketScript, []string{s.prefix + key}, rate, burst, now.UnixMilli())
+	if err != nil {
+		return false, 0, err
+	}
+	values, err := replySlice(reply, 2)
+	if err != nil {
+		return false, 0, err
+	}
+	tokens, err := strconv.ParseFloat(fmt.Sprint(values[1]), 64)
+	if err != nil {
+		return false, 0, err
+	}
+	return toInt(values[0]) == 1, tokens, nil
+}
+
+// LogRequest 实现 Store
+func (s *RedisStore) LogRequest(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (bool, int, time.Time, error) {
+	// member 需要唯一，同一毫秒的多个请求才不会互相覆盖
+	member := strconv.FormatInt(now.UnixNano(), 36)
+	reply, err := s.client.Eval(ctx, slidingWindowScript, []string{s.prefix + key}, limit, window.Milliseconds(), now.UnixMilli(), member)
+	if err != nil {
+		return false, 0, time.Time{}, err
+	}
+	values, err := replySlice(reply, 3)
+	if err != nil {
+		return false, 0, time.Time{}, err
+	}
+	oldestMs, err := strconv.ParseFloat(fmt.Sprint(values[2]), 64)
+	if err != nil {
+		return false, 0, time.Time{}, err
+	}
+	return toInt(values[0]) == 1, toInt(values[1]), time.UnixMilli(int64(oldestMs)), nil
+}
+
+func replySlice(reply any, n int) ([]any, error) {
+	values, ok := reply.([]any)
+	if !ok || len(values) != n {
+		return nil, fmt.Errorf("unexpected redis reply: %v", reply)
+	}
+	return values, nil
+}
+
+func toInt(v any) int {
+	switch n := v.(type) {
+	case int64:
+		return int(n)
+	case int:
+		return n
+	default:
+		i, _ := strconv.Atoi(fmt.Sprint(v))
+		return i
+	}
+}
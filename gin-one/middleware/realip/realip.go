@@ -0,0 +1,245 @@
+// ============================================================================
+// Package realip 可信代理与真实客户端 IP
+// ============================================================================
+//
+// 【为什么不直接用 c.ClientIP()？】
+//
+// gin 默认信任所有代理：任何人发一个 X-Forwarded-For: 1.2.3.4 就能换一个 IP，
+// 按 IP 限流形同虚设，审计日志里记的也是伪造的地址。
+// 这些头只有经过自己的代理时才可信，而且只有代理追加的那部分可信：
+//
+//	客户端 1.2.3.4（伪造 X-Forwarded-For: 6.6.6.6）──► Nginx 10.0.0.5 ──► 应用
+//	应用收到：RemoteAddr = 10.0.0.5，X-Forwarded-For: 6.6.6.6, 1.2.3.4
+//
+// 从右往左看：10.0.0.5 是可信代理，它说的上一跳 1.2.3.4 不是可信代理，
+// 那就是真实客户端；再往左的 6.6.6.6 是客户端自己写的，不看。
+//
+// 【只看代理设置的那一个头】
+//
+// 代理只会追加或覆盖自己设置的头，别的转发头原样透传：Nginx 追加 X-Forwarded-For 时，
+// 客户端自己带的 Forwarded 也会到达应用。按顺序依次查看多个头时，排在前面的那个
+// 就由客户端决定，所以 Config.Header 只指定一个，默认 X-Forwarded-For。
+//
+// | 情况                             | 结果                                        |
+// |----------------------------------|---------------------------------------------|
+// | 直连地址不在 TrustedProxies 里   | 直连地址，忽略转发头                        |
+// | X-Forwarded-For                  | 从右往左找第一个不可信的地址                |
+// | Forwarded（RFC 7239）            | 取 for= 列表，同上                          |
+// | X-Real-IP                        | 单个地址，直接采用                          |
+// | 头不存在或含无法解析的地址       | 直连地址（不会去看别的头）                  |
+// | 整条链都是可信代理               | 最左边的地址                                |
+//
+// 【使用】
+//
+//	ips, err := realip.New(realip.Config{TrustedProxies: []string{"10.0.0.0/8"}}) // 代理设置 X-Forwarded-For
+//	ips, err := realip.New(realip.Config{TrustedProxies: cidrs, Header: realip.HeaderXRealIP})
+//	r.Use(ips.Middleware()) // 放在最前面，限流、审计日志都在它之后
+//
+//	ip := realip.FromContext(c)
+//
+// 限流（ratelimit.ByIP）、幂等键、访问日志、审计日志、panic 日志都用 FromContext 取 IP；
+// 没有挂中间件时退回 c.ClientIP()。
+//
+// ============================================================================
+package realip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contextKey 解析出的客户端 IP 在 gin.Context 中的键
+const contextKey = "realip.client_ip"
+
+// 支持的转发头
+const (
+	HeaderForwarded     = "Forwarded"
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderXRealIP       = "X-Real-IP"
+)
+
+// Config 配置
+type Config struct {
+	// TrustedProxies 可信代理的 IP 或 CIDR；为空时不看转发头，直接用直连地址
+	TrustedProxies []string
+	// Header 可信代理设置的转发头，只看这一个，默认 X-Forwarded-For
+	Header string
+}
+
+// Resolver 客户端 IP 解析器
+type Resolver struct {
+	proxies Proxies
+	header  string
+}
+
+// New 创建解析器，TrustedProxies 格式错误或 Header 不支持时返回错误
+func New(cfg Config) (*Resolver, error) {
+	if cfg.Header == "" {
+		cfg.Header = HeaderXForwardedFor
+	}
+	proxies, err := ParseProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("realip: %w", err)
+	}
+	h := http.CanonicalHeaderKey(cfg.Header)
+	switch h {
+	case HeaderForwarded, HeaderXForwardedFor, http.CanonicalHeaderKey(HeaderXRealIP):
+	default:
+		return nil, fmt.Errorf("realip: unsupported header %q", cfg.Header)
+	}
+	return &Resolver{proxies: proxies, header: h}, nil
+}
+
+// Proxies 可信代理网段；secure 判断 X-Forwarded-Proto 是否可信时也用它
+type Proxies []netip.Prefix
+
+// ParseProxies 解析可信代理列表，每项是 CIDR（"10.0.0.0/8"）或单个 IP（"::1"）
+func ParseProxies(list []string) (Proxies, error) {
+	var out Proxies
+	for _, s := range list {
+		p, err := parsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", s, err)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// Contains addr 是否属于可信代理
+func (ps Proxies) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range ps {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrefix 解析 "10.0.0.0/8" 或单个 IP
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		return p.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// Middleware 解析客户端 IP 存入 gin.Context
+func (r *Resolver) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(contextKey, r.ClientIP(c.Request))
+		c.Next()
+	}
+}
+
+// ClientIP 按 Config 解析请求的客户端 IP
+func (r *Resolver) ClientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	remote, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	remote = remote.Unmap()
+	if !r.proxies.Contains(remote) {
+		return remote.String()
+	}
+	values := req.Header.Values(r.header)
+	if len(values) == 0 {
+		return remote.String()
+	}
+	var chain []string
+	switch r.header {
+	case HeaderForwarded:
+		chain = forwardedFor(values)
+	case HeaderXForwardedFor:
+		chain = splitList(values)
+	default:
+		// X-Real-IP 是代理直接写的单个地址
+		chain = values[len(values)-1:]
+	}
+	if ip, ok := r.walk(chain); ok {
+		return ip.String()
+	}
+	return remote.String()
+}
+
+// walk 从右往左跳过可信代理，返回第一个不可信的地址；遇到无法解析的地址时放弃
+func (r *Resolver) walk(chain []string) (netip.Addr, bool) {
+	var ip netip.Addr
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(chain[i])
+		if !ok {
+			return netip.Addr{}, false
+		}
+		ip = addr
+		if !r.proxies.Contains(ip) {
+			break
+		}
+	}
+	return ip, ip.IsValid()
+}
+
+// splitList 合并多行头并按逗号拆分，如 "1.2.3.4, 10.0.0.1"
+func splitList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			out = append(out, strings.TrimSpace(s))
+		}
+	}
+	return out
+}
+
+// forwardedFor 取 Forwarded 头每一段的 for=，如 `for=1.2.3.4;proto=https, for="[2001:db8::1]:4711"`；
+// 缺少 for= 的段记为空字符串，walk 遇到时放弃
+func forwardedFor(values []string) []string {
+	var out []string
+	for _, elem := range splitList(values) {
+		var v string
+		for _, pair := range strings.Split(elem, ";") {
+			k, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(k, "for") {
+				v = strings.Trim(val, `"`)
+				break
+			}
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// parseAddr 解析 "1.2.3.4"、"1.2.3.4:80"、"[::1]:80"、"::1"；
+// unknown、_hidden 等隐藏地址返回 false
+func parseAddr(s string) (netip.Addr, bool) {
+	if addr, err := netip.ParseAddr(strings.Trim(s, "[]")); err == nil {
+		return addr.Unmap(), true
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		if addr, err := netip.ParseAddr(host); err == nil {
+			return addr.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// FromContext 中间件解析出的客户端 IP；没有挂中间件时返回 c.ClientIP()
+func FromContext(c *gin.Context) string {
+	if ip := c.GetString(contextKey); ip != "" {
+		return ip
+	}
+	return c.ClientIP()
+}
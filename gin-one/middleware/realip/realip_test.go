@@ -0,0 +1,122 @@
+package realip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientIP(t *testing.T) {
+	r, err := New(Config{TrustedProxies: []string{"10.0.0.0/8", "::1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		remote string
+		header http.Header
+		want   string
+	}{
+		{"direct client", "203.0.113.9:5000", nil, "203.0.113.9"},
+		{"untrusted client spoofing", "203.0.113.9:5000", http.Header{"X-Forwarded-For": {"6.6.6.6"}}, "203.0.113.9"},
+		{"trusted proxy", "10.0.0.5:5000", http.Header{"X-Forwarded-For": {"1.2.3.4"}}, "1.2.3.4"},
+		{"spoofed entry left of client", "10.0.0.5:5000", http.Header{"X-Forwarded-For": {"6.6.6.6, 1.2.3.4"}}, "1.2.3.4"},
+		{"chain of trusted proxies", "10.0.0.5:5000", http.Header{"X-Forwarded-For": {"1.2.3.4, 10.0.0.7", "10.0.0.6"}}, "1.2.3.4"},
+		{"all hops trusted", "10.0.0.5:5000", http.Header{"X-Forwarded-For": {"10.0.0.8, 10.0.0.7"}}, "10.0.0.8"},
+		// Nginx 追加了 X-Forwarded-For，客户端自己带的 Forwarded / X-Real-IP 被原样转发过来
+		{"client forwarded next to trusted xff", "10.0.0.5:5000", http.Header{
+			"Forwarded":       {"for=6.6.6.6"},
+			"X-Real-Ip":       {"7.7.7.7"},
+			"X-Forwarded-For": {"1.2.3.4"},
+		}, "1.2.3.4"},
+		{"client forwarded without xff", "10.0.0.5:5000", http.Header{"Forwarded": {"for=6.6.6.6"}}, "10.0.0.5"},
+		{"garbage xff does not fall back", "10.0.0.5:5000", http.Header{
+			"X-Forwarded-For": {"1.2.3.4, not-an-ip"},
+			"X-Real-Ip":       {"5.6.7.8"},
+		}, "10.0.0.5"},
+		{"ipv4-mapped remote", "[::ffff:10.0.0.5]:5000", http.Header{"X-Forwarded-For": {"1.2.3.4"}}, "1.2.3.4"},
+		{"trusted proxy without headers", "10.0.0.5:5000", nil, "10.0.0.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.ClientIP(request(tt.remote, tt.header)); got != tt.want {
+				t.Errorf("ClientIP = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func request(remote string, header http.Header) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remote
+	if header != nil {
+		req.Header = header
+	}
+	return req
+}
+
+func TestHeader(t *testing.T) {
+	proxies := []string{"10.0.0.0/8", "::1"}
+	spoofed := http.Header{"X-Forwarded-For": {"6.6.6.6"}, "Forwarded": {"for=6.6.6.6"}, "X-Real-Ip": {"6.6.6.6"}}
+	tests := []struct {
+		header string
+		remote string
+		set    http.Header
+		want   string
+	}{
+		// 代理只设置 X-Real-IP：客户端自己带的 X-Forwarded-For、Forwarded 不看
+		{"x-real-ip", "10.0.0.5:5000", http.Header{"X-Real-Ip": {"1.2.3.4"}}, "1.2.3.4"},
+		{"Forwarded", "10.0.0.5:5000", http.Header{"Forwarded": {`for=1.2.3.4;proto=https, for="10.0.0.7:8080"`}}, "1.2.3.4"},
+		{"Forwarded", "[::1]:5000", http.Header{"Forwarded": {`for="[2001:db8:cafe::17]:4711"`}}, "2001:db8:cafe::17"},
+		{"Forwarded", "10.0.0.5:5000", http.Header{"Forwarded": {"for=unknown"}}, "10.0.0.5"},
+	}
+	for _, tt := range tests {
+		r, err := New(Config{TrustedProxies: proxies, Header: tt.header})
+		if err != nil {
+			t.Fatal(err)
+		}
+		header := spoofed.Clone()
+		for k, v := range tt.set {
+			header[k] = v
+		}
+		if got := r.ClientIP(request(tt.remote, header)); got != tt.want {
+			t.Errorf("%s %v: ClientIP = %s; want %s", tt.header, tt.set, got, tt.want)
+		}
+	}
+
+	for _, cfg := range []Config{
+		{TrustedProxies: []string{"10.0.0.0/33"}},
+		{TrustedProxies: []string{"proxy.internal"}},
+		{Header: "CF-Connecting-IP"},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) accepted", cfg)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	res, err := New(Config{TrustedProxies: []string{"10.0.0.0/8"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/raw", func(c *gin.Context) { c.String(http.StatusOK, FromContext(c)) })
+	g := r.Group("/", res.Middleware())
+	g.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, FromContext(c)) })
+
+	// 没挂中间件时退回 gin 的 ClientIP：默认信任所有代理，伪造的头直接生效
+	for path, want := range map[string]string{"/ip": "203.0.113.9", "/raw": "6.6.6.6"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "203.0.113.9:5000"
+		req.Header.Set("X-Forwarded-For", "6.6.6.6")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Body.String() != want {
+			t.Errorf("%s: ip = %s; want %s", path, rec.Body, want)
+		}
+	}
+}
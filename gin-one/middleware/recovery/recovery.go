@@ -0,0 +1,178 @@
+// ============================================================================
+// Package recovery panic 恢复中间件
+// ============================================================================
+//
+// 【与 gin.Recovery() 的区别】
+//
+// | 能力               | gin.Recovery()        | recovery.New()                     |
+// |--------------------|-----------------------|------------------------------------|
+// | 响应格式           | 空 500                | 统一 Response JSON                 |
+// | 堆栈输出           | 文本写到 stderr       | 结构化日志（带 request_id）        |
+// | 错误上报           | 需要自己写 handle     | Reporter 接口（Sentry 风格）       |
+// | 客户端断开         | 识别 broken pipe      | 识别 broken pipe / reset，不告警    |
+// | 出错位置           | 需要自己读堆栈        | 日志 source 字段（panic 的那一行）  |
+//
+// 【为什么要区分客户端断开？】
+//
+// 客户端提前关闭连接时，写响应会得到 EPIPE / ECONNRESET。
+// 这不是服务端 bug，打印堆栈、发告警只会制造噪音；连接已断也没法再写响应。
+//
+// 【挂载位置】
+//
+// 放在日志中间件之后、业务中间件之前，这样日志中间件能记录到 500 状态码，
+// 而其他中间件里的 panic 也能被捕获：
+//
+//	r.Use(logger.New(...), recovery.New(recovery.Config{...}))
+//
+// ============================================================================
+package recovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/middleware/logger"
+	"go-one/middleware/realip"
+	"go-one/response"
+
+	"go-learning/errtrace"
+)
+
+// Event 一次 panic 的上报内容
+type Event struct {
+	Value     any
+	Stack     []byte
+	Method    string
+	Path      string
+	Route     string
+	ClientIP  string
+	RequestID string
+	Time      time.Time
+}
+
+// Reporter 错误上报接口，如 Sentry、钉钉机器人
+// Report 在请求 goroutine 中同步调用，耗时操作应自行异步处理
+type Reporter interface {
+	Report(ctx context.Context, e Event)
+}
+
+// ReporterFunc 函数适配器
+type ReporterFunc func(ctx context.Context, e Event)
+
+// Report 实现 Reporter
+func (f ReporterFunc) Report(ctx context.Context, e Event) { f(ctx, e) }
+
+// Config 恢复中间件配置
+type Config struct {
+	// Logger 为空时使用 logger.FromContext(c)，即日志中间件提供的请求级 Logger
+	Logger *slog.Logger
+
+	// Reporter 可选，客户端断开的情况不会上报
+	Reporter Reporter
+
+	// ShowDetail 在响应中返回 panic 内容，只应在开发环境开启
+	ShowDetail bool
+
+	// RequestIDKey 从 gin.Context 读取请求 ID 的 key，默认 "request_id"
+	RequestIDKey string
+}
+
+// Default 使用默认配置
+func Default() gin.HandlerFunc {
+	return New(Config{})
+}
+
+// New 创建恢复中间件
+func New(cfg Config) gin.HandlerFunc {
+	if cfg.RequestIDKey == "" {
+		cfg.RequestIDKey = "request_id"
+	}
+
+	return func(c *gin.Context) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			log := cfg.Logger
+			if log == nil {
+				log = logger.FromContext(c)
+			}
+			ctx := c.Request.Context()
+
+			if brokenConnection(rec) {
+				// 连接已断开，不写响应、不打印堆栈
+				log.LogAttrs(ctx, slog.LevelWarn, "client connection closed",
+					slog.String("method", c.Request.Method),
+					slog.String("path", c.Request.URL.Path),
+					slog.Any("error", rec),
+				)
+				if err, ok := rec.(error); ok {
+					_ = c.Error(err)
+				}
+				c.Abort()
+				return
+			}
+
+			// 必须在这个 defer 里创建：此时调用栈还没展开，errtrace 能定位到 panic 的那一行
+			err := errtrace.Errorf("panic: %v", rec)
+			stack := debug.Stack()
+			log.LogAttrs(ctx, slog.LevelError, "panic recovered",
+				slog.String("method", c.Request.Method),
+				slog.String("path", c.Request.URL.Path),
+				slog.Any("panic", rec),
+				slog.String("source", errtrace.Source(err)),
+				slog.String("stack", string(stack)),
+			)
+			_ = c.Error(err)
+
+			if cfg.Reporter != nil {
+				cfg.Reporter.Report(ctx, Event{
+					Value:     rec,
+					Stack:     stack,
+					Method:    c.Request.Method,
+					Path:      c.Request.URL.Path,
+					Route:     c.FullPath(),
+					ClientIP:  realip.FromContext(c),
+					RequestID: c.GetString(cfg.RequestIDKey),
+					Time:      time.Now(),
+				})
+			}
+
+			// Handler 已经开始写响应时，状态码和部分 body 已发出，无法再改
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+
+			message := "服务器内部错误，请稍后重试"
+			if cfg.ShowDetail {
+				message = fmt.Sprint(rec)
+			}
+			response.Abort(c, http.StatusInternalServerError, "internal_error", message)
+		}()
+
+		c.Next()
+	}
+}
+
+// brokenConnection 判断 panic 是否由客户端断开连接引起
+func brokenConnection(rec any) bool {
+	err, ok := rec.(error)
+	if !ok {
+		return false
+	}
+	// http.ErrAbortHandler 是标准库约定的"静默中止"信号
+	return errors.Is(err, http.ErrAbortHandler) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
@@ -0,0 +1,128 @@
+package recovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	brokenPipe := &net.OpError{Op: "write", Net: "tcp", Err: &os.SyscallError{Syscall: "write", Err: syscall.EPIPE}}
+
+	tests := []struct {
+		name       string
+		panicValue any
+		written    bool
+		showDetail bool
+		wantStatus int
+		wantBody   *response.Response
+		wantReport bool
+		wantLog    string
+	}{
+		{
+			name:       "string panic",
+			panicValue: "boom",
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   &response.Response{Code: response.CodeError, Message: "服务器内部错误，请稍后重试", Error: "internal_error"},
+			wantReport: true,
+			wantLog:    `"level":"ERROR","msg":"panic recovered"`,
+		},
+		{
+			name:       "show detail",
+			panicValue: fmt.Errorf("db down"),
+			showDetail: true,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   &response.Response{Code: response.CodeError, Message: "db down", Error: "internal_error"},
+			wantReport: true,
+			wantLog:    `"stack":"goroutine`,
+		},
+		{
+			name:       "source points at panic",
+			panicValue: "nil map",
+			wantStatus: http.StatusInternalServerError,
+			wantReport: true,
+			wantLog:    `"source":"recovery/recovery_test.go:`,
+		},
+		{
+			name:       "already written",
+			panicValue: "late",
+			written:    true,
+			wantStatus: http.StatusAccepted,
+			wantReport: true,
+		},
+		{
+			name:       "broken pipe",
+			panicValue: brokenPipe,
+			wantStatus: http.StatusOK, // 什么都没写
+			wantLog:    `"level":"WARN","msg":"client connection closed"`,
+		},
+		{
+			name:       "abort handler",
+			panicValue: http.ErrAbortHandler,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			var reported []Event
+
+			r := gin.New()
+			r.Use(New(Config{
+				Logger:     slog.New(slog.NewJSONHandler(&logs, nil)),
+				ShowDetail: tt.showDetail,
+				Reporter: ReporterFunc(func(_ context.Context, e Event) {
+					reported = append(reported, e)
+				}),
+			}))
+			r.GET("/panic", func(c *gin.Context) {
+				if tt.written {
+					c.Status(http.StatusAccepted)
+					c.Writer.WriteHeaderNow()
+				}
+				panic(tt.panicValue)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d; want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != nil {
+				var got response.Response
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatalf("body %q: %v", w.Body.String(), err)
+				}
+				if got != *tt.wantBody {
+					t.Errorf("body = %+v; want %+v", got, *tt.wantBody)
+				}
+			}
+			if (len(reported) == 1) != tt.wantReport {
+				t.Errorf("reported %d events; want report=%v", len(reported), tt.wantReport)
+			}
+			if len(reported) == 1 && (reported[0].Route != "/panic" || len(reported[0].Stack) == 0) {
+				t.Errorf("event = %+v; want route /panic with stack", reported[0])
+			}
+			if tt.wantLog != "" && !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("log %q does not contain %q", logs.String(), tt.wantLog)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+// ============================================================================
+// Package secure 安全响应头、HSTS 与 HTTP → HTTPS 跳转
+// ============================================================================
+//
+// 【每个响应都带的头】
+//
+// | 响应头                     | 默认值                           | 防什么                                         |
+// |----------------------------|----------------------------------|------------------------------------------------|
+// | Strict-Transport-Security  | max-age=31536000（仅 HTTPS 响应）| 之后一年浏览器只用 HTTPS 访问，防 SSL 剥离     |
+// | X-Content-Type-Options     | nosniff                          | 浏览器把上传的 .txt 当脚本执行（MIME 嗅探）    |
+// | X-Frame-Options            | DENY                             | 页面被别的网站用 iframe 嵌入（点击劫持）       |
+// | Referrer-Policy            | strict-origin-when-cross-origin  | 完整 URL（带 Token 的链接）通过 Referer 泄露   |
+// | Content-Security-Policy    | 用 CSP 构造，默认不发            | XSS 注入的脚本、外部资源被加载执行             |
+//
+// HSTS 只在 HTTPS 响应里发：规范要求浏览器忽略 HTTP 响应里的 HSTS，
+// 开发环境用 http://localhost 访问时也不会被"锁"成 HTTPS。
+//
+// 【HTTPS 判断和反向代理】
+//
+// TLS 通常在 Nginx / 负载均衡终止，到应用的是 HTTP，r.TLS 为 nil。代理用
+// X-Forwarded-Proto: https 或 Forwarded: proto=https 告诉应用原始协议。
+// 这两个头谁都能伪造，只在直连地址属于 TrustedProxies 时才相信：
+//
+//	客户端 ──HTTPS──► 10.0.0.5 (Nginx) ──HTTP + X-Forwarded-Proto: https──► 应用
+//	TrustedProxies: ["10.0.0.0/8"]  → 按 HTTPS 处理，发 HSTS，不跳转
+//	同一个请求直接打到应用          → 不在 TrustedProxies 里，忽略头，按 HTTP 处理
+//
+// 【跳转】
+//
+// RedirectHTTPS 为 true 时 HTTP 请求跳转到同一路径的 HTTPS：GET / HEAD 用 301，
+// 其他方法用 308（保持方法和请求体）。负载均衡的健康检查通常走 HTTP，放进 SkipPaths。
+//
+// 【用法】
+//
+//	headers, err := secure.New(secure.Config{
+//		RedirectHTTPS:  cfg.Server.Mode == "release",
+//		TrustedProxies: []string{"10.0.0.0/8"},
+//		CSP: secure.NewCSP().
+//			Add("default-src", secure.Self).
+//			Add("script-src", secure.Self, secure.Nonce).
+//			Add("frame-ancestors", secure.None),
+//		SkipPaths: []string{"/healthz"},
+//	})
+//	r.Use(headers.Middleware())
+//
+//	// 模板里的内联脚本带上本次请求的 nonce
+//	c.HTML(200, "page", gin.H{"Nonce": secure.NonceFromContext(c)})
+//	// <script nonce="{{.Nonce}}">...</script>
+//
+// ============================================================================
+package secure
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/middleware/realip"
+)
+
+// nonceKey 本次请求的 CSP nonce 在 gin.Context 中的键
+const nonceKey = "secure.nonce"
+
+// Config 配置
+type Config struct {
+	// HSTSMaxAge 默认 365 天；小于 0 时不发 HSTS
+	HSTSMaxAge time.Duration
+	// HSTSIncludeSubdomains 子域名也只用 HTTPS，确认所有子域名都支持 HTTPS 后再打开
+	HSTSIncludeSubdomains bool
+	// HSTSPreload 申请加入浏览器的 HSTS 预加载列表（要求 max-age 至少一年且 includeSubDomains）
+	HSTSPreload bool
+
+	// FrameOptions 默认 DENY；允许同源嵌入时用 SAMEORIGIN，"-" 表示不发
+	FrameOptions string
+	// ReferrerPolicy 默认 strict-origin-when-cross-origin，"-" 表示不发
+	ReferrerPolicy string
+
+	// CSP 为 nil 时不发 Content-Security-Policy
+	CSP *CSP
+	// CSPReportOnly 只报告不拦截（Content-Security-Policy-Report-Only），上线新策略前先观察
+	CSPReportOnly bool
+
+	// RedirectHTTPS HTTP 请求跳转到 HTTPS，生产环境打开
+	RedirectHTTPS bool
+	// HTTPSHost 跳转目标的主机（可以带端口），默认用请求的 Host 去掉端口
+	HTTPSHost string
+
+	// TrustedProxies 可信代理的 IP 或 CIDR，只有直连地址在这里面时才看 X-Forwarded-Proto / Forwarded
+	TrustedProxies []string
+
+	// SkipPaths 不跳转的路径（负载均衡的 HTTP 健康检查）；安全头照常设置
+	SkipPaths []string
+}
+
+// Headers 安全响应头中间件
+type Headers struct {
+	cfg     Config
+	hsts    string
+	csp     string
+	nonce   bool
+	proxies realip.Proxies
+	skip    map[string]bool
+}
+
+// New 创建中间件，TrustedProxies 格式错误时返回错误
+func New(cfg Config) (*Headers, error) {
+	if cfg.HSTSMaxAge == 0 {
+		cfg.HSTSMaxAge = 365 * 24 * time.Hour
+	}
+	if cfg.FrameOptions == "" {
+		cfg.FrameOptions = "DENY"
+	}
+	if cfg.ReferrerPolicy == "" {
+		cfg.ReferrerPolicy = "strict-origin-when-cross-origin"
+	}
+	h := &Headers{cfg: cfg, skip: make(map[string]bool, len(cfg.SkipPaths))}
+	if cfg.HSTSMaxAge > 0 {
+		h.hsts = "max-age=" + strconv.FormatInt(int64(cfg.HSTSMaxAge/time.Second), 10)
+		if cfg.HSTSIncludeSubdomains {
+			h.hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			h.hsts += "; preload"
+		}
+	}
+	if cfg.CSP != nil {
+		h.csp = cfg.CSP.String()
+		h.nonce = strings.Contains(h.csp, Nonce)
+	}
+	proxies, err := realip.ParseProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("secure: %w", err)
+	}
+	h.proxies = proxies
+	for _, p := range cfg.SkipPaths {
+		h.skip[p] = true
+	}
+	return h, nil
+}
+
+// Middleware 设置安全头，按需跳转 HTTPS
+func (h *Headers) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		https := h.IsHTTPS(c.Request)
+		if !https && h.cfg.RedirectHTTPS && !h.skip[c.Request.URL.Path] {
+			h.redirect(c)
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		if h.cfg.FrameOptions != "-" {
+			header.Set("X-Frame-Options", h.cfg.FrameOptions)
+		}
+		if h.cfg.ReferrerPolicy != "-" {
+			header.Set("Referrer-Policy", h.cfg.ReferrerPolicy)
+		}
+		if https && h.hsts != "" {
+			header.Set("Strict-Transport-Security", h.hsts)
+		}
+		if h.csp != "" {
+			csp := h.csp
+			if h.nonce {
+				nonce := newNonce()
+				c.Set(nonceKey, nonce)
+				csp = strings.ReplaceAll(csp, Nonce, "'nonce-"+nonce+"'")
+			}
+			name := "Content-Security-Policy"
+			if h.cfg.CSPReportOnly {
+				name = "Content-Security-Policy-Report-Only"
+			}
+			header.Set(name, csp)
+		}
+		c.Next()
+	}
+}
+
+func (h *Headers) redirect(c *gin.Context) {
+	host := h.cfg.HTTPSHost
+	if host == "" {
+		host = c.Request.Host
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+	}
+	code := http.StatusMovedPermanently
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+	c.Redirect(code, "https://"+host+c.Request.URL.RequestURI())
+	c.Abort()
+}
+
+// IsHTTPS 请求是否经 HTTPS 到达：直连 TLS，或者可信代理转发时声明的原始协议是 https
+func (h *Headers) IsHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	if !h.fromTrustedProxy(r) {
+		return false
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		// 多层代理时是逗号分隔的列表，第一个是客户端到最外层代理的协议
+		first, _, _ := strings.Cut(proto, ",")
+		return strings.EqualFold(strings.TrimSpace(first), "https")
+	}
+	return forwardedProto(r.Header.Get("Forwarded")) == "https"
+}
+
+func (h *Headers) fromTrustedProxy(r *http.Request) bool {
+	if len(h.proxies) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	return h.proxies.Contains(addr)
+}
+
+// forwardedProto 取 RFC 7239 Forwarded 头第一段的 proto，如 `for=1.2.3.4;proto=https, for=10.0.0.1`
+func forwardedProto(v string) string {
+	first, _, _ := strings.Cut(v, ",")
+	for _, pair := range strings.Split(first, ";") {
+		k, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && strings.EqualFold(k, "proto") {
+			return strings.ToLower(strings.Trim(val, `"`))
+		}
+	}
+	return ""
+}
+
+// NonceFromContext 本次请求的 CSP nonce，放进内联 <script nonce="..."> 里；CSP 没有用 Nonce 时返回空字符串
+func NonceFromContext(c *gin.Context) string {
+	return c.GetString(nonceKey)
+}
+
+func newNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
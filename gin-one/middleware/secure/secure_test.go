@@ -0,0 +1,150 @@
+package secure
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRouter(t *testing.T, cfg Config) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	h, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(h.Middleware())
+	r.Any("/*path", func(c *gin.Context) { c.String(http.StatusOK, NonceFromContext(c)) })
+	return r
+}
+
+func serve(r *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHeaders(t *testing.T) {
+	r := newRouter(t, Config{HSTSIncludeSubdomains: true})
+
+	rec := serve(r, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	h := rec.Header()
+	if h.Get("X-Content-Type-Options") != "nosniff" || h.Get("X-Frame-Options") != "DENY" ||
+		h.Get("Referrer-Policy") != "strict-origin-when-cross-origin" {
+		t.Errorf("headers = %v", h)
+	}
+	if h.Get("Strict-Transport-Security") != "" || h.Get("Content-Security-Policy") != "" {
+		t.Errorf("HSTS over HTTP or CSP without config: %v", h)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	req.TLS = &tls.ConnectionState{}
+	if got := serve(r, req).Header().Get("Strict-Transport-Security"); got != "max-age=31536000; includeSubDomains" {
+		t.Errorf("HSTS = %q", got)
+	}
+
+	r = newRouter(t, Config{HSTSMaxAge: -1, FrameOptions: "-", ReferrerPolicy: "no-referrer"})
+	rec = serve(r, req)
+	if h := rec.Header(); h.Get("Strict-Transport-Security") != "" || h.Get("X-Frame-Options") != "" || h.Get("Referrer-Policy") != "no-referrer" {
+		t.Errorf("disabled headers = %v", h)
+	}
+}
+
+func TestCSP(t *testing.T) {
+	csp := DefaultCSP().Add("script-src", Self, Nonce).Add("img-src", Self, "data:").Add("img-src", "data:", "https://cdn.example.com").Add("upgrade-insecure-requests")
+	want := "default-src 'self'; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'; " +
+		"script-src 'self' 'nonce-{nonce}'; img-src 'self' data: https://cdn.example.com; upgrade-insecure-requests"
+	if got := csp.String(); got != want {
+		t.Fatalf("CSP =\n%s\nwant\n%s", got, want)
+	}
+
+	r := newRouter(t, Config{CSP: csp})
+	a := serve(r, httptest.NewRequest(http.MethodGet, "/", nil))
+	b := serve(r, httptest.NewRequest(http.MethodGet, "/", nil))
+	nonce := a.Body.String()
+	if nonce == "" || nonce == b.Body.String() {
+		t.Fatalf("nonces %q, %q; want a fresh nonce per request", nonce, b.Body)
+	}
+	if got := a.Header().Get("Content-Security-Policy"); !strings.Contains(got, "script-src 'self' 'nonce-"+nonce+"'") {
+		t.Errorf("CSP header = %q; want the nonce from the context", got)
+	}
+
+	r = newRouter(t, Config{CSP: NewCSP().Add("default-src", Self), CSPReportOnly: true})
+	rec := serve(r, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Header().Get("Content-Security-Policy-Report-Only") != "default-src 'self'" || rec.Body.String() != "" {
+		t.Errorf("report-only: %v, nonce %q", rec.Header(), rec.Body)
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	r := newRouter(t, Config{RedirectHTTPS: true, SkipPaths: []string{"/healthz"}})
+
+	rec := serve(r, httptest.NewRequest(http.MethodGet, "http://example.com:8080/a?b=1", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://example.com/a?b=1" {
+		t.Errorf("GET: %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := serve(r, httptest.NewRequest(http.MethodPost, "http://example.com/a", nil)); rec.Code != http.StatusPermanentRedirect {
+		t.Errorf("POST: %d; want 308", rec.Code)
+	}
+	if rec := serve(r, httptest.NewRequest(http.MethodGet, "http://example.com/healthz", nil)); rec.Code != http.StatusOK {
+		t.Errorf("skipped path: %d", rec.Code)
+	}
+
+	r = newRouter(t, Config{RedirectHTTPS: true, HTTPSHost: "secure.example.com:8443"})
+	if rec := serve(r, httptest.NewRequest(http.MethodGet, "http://example.com/x", nil)); rec.Header().Get("Location") != "https://secure.example.com:8443/x" {
+		t.Errorf("HTTPSHost: %s", rec.Header().Get("Location"))
+	}
+}
+
+func TestIsHTTPS(t *testing.T) {
+	h, err := New(Config{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.5", "::1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		remote string
+		header http.Header
+		want   bool
+	}{
+		{"trusted proxy, X-Forwarded-Proto", "10.1.2.3:5000", http.Header{"X-Forwarded-Proto": {"https"}}, true},
+		{"trusted proxy, chained proto", "10.1.2.3:5000", http.Header{"X-Forwarded-Proto": {"https, http"}}, true},
+		{"trusted proxy, http", "10.1.2.3:5000", http.Header{"X-Forwarded-Proto": {"http"}}, false},
+		{"trusted single IP, Forwarded", "192.168.1.5:5000", http.Header{"Forwarded": {`for=1.2.3.4;proto="https", for=10.0.0.1`}}, true},
+		{"trusted IPv6 loopback", "[::1]:5000", http.Header{"X-Forwarded-Proto": {"HTTPS"}}, true},
+		{"untrusted client spoofing", "203.0.113.9:5000", http.Header{"X-Forwarded-Proto": {"https"}}, false},
+		{"trusted proxy, no header", "10.1.2.3:5000", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			req.Header = tt.header
+			if req.Header == nil {
+				req.Header = http.Header{}
+			}
+			if got := h.IsHTTPS(req); got != tt.want {
+				t.Errorf("IsHTTPS = %v; want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := New(Config{TrustedProxies: []string{"not-an-ip"}}); err == nil {
+		t.Error("invalid proxy accepted")
+	}
+
+	// 可信代理转发的 HTTPS 请求不跳转，带 HSTS
+	r := newRouter(t, Config{RedirectHTTPS: true, TrustedProxies: []string{"10.0.0.0/8"}, HSTSMaxAge: time.Hour})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	if rec := serve(r, req); rec.Code != http.StatusOK || rec.Header().Get("Strict-Transport-Security") != "max-age=3600" {
+		t.Errorf("behind proxy: %d %v", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,256 @@
+// ============================================================================
+// Package versioning API 版本协商：同一个路径，按请求头选择不同版本的 handler
+// ============================================================================
+//
+// 【为什么不用 /api/v1、/api/v2 路由组？】
+//
+// 路径里带版本时，v2 只改了一个接口，也要把 v1 的所有路由在 v2 组里再注册一遍；
+// 忘记注册的接口在 v2 下就是 404。版本协商只注册一次路径，每个路径按版本挂 handler：
+//
+//	registry.Handle(api, "GET", "/users", versioning.Handlers{1: listUsersV1, 2: listUsersV2})
+//	registry.Handle(api, "GET", "/users/:id", versioning.Handlers{1: getUser})
+//
+// 请求 v2 时 /users 用 listUsersV2，/users/:id 没有 v2 实现，沿用 v1 的 getUser。
+//
+// 【客户端怎么指定版本】
+//
+// | 方式                                    | 例子                                      |
+// |-----------------------------------------|-------------------------------------------|
+// | X-API-Version 请求头（优先）            | X-API-Version: 2（也接受 v2）             |
+// | Accept 媒体类型                         | Accept: application/vnd.api.v2+json       |
+// | 都没有                                  | 使用 Config.Default                       |
+//
+// 【响应】
+//
+// | 情况                                  | 结果                                                |
+// |---------------------------------------|-----------------------------------------------------|
+// | 版本号格式错误                        | 400 invalid_version                                 |
+// | 版本不在 Config.Versions 中           | 406 unsupported_version                             |
+// | 该接口在请求的版本还不存在            | 406 unsupported_version（如 v1 请求 v2 才加的接口） |
+// | 成功                                  | X-API-Version 响应头返回实际使用的版本              |
+//
+// 响应总是带 Vary: Accept, X-API-Version，否则 CDN 会把 v1 的响应缓存给 v2 的请求。
+// 已弃用的版本额外带 Deprecation: true，提醒客户端升级。
+//
+// ============================================================================
+package versioning
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// 错误定义
+var (
+	errInvalid     = errors.New("invalid API version")
+	errUnsupported = errors.New("unsupported API version")
+)
+
+// Config 版本协商配置
+type Config struct {
+	// Versions 支持的版本，如 []int{1, 2}，必填
+	Versions []int
+
+	// Default 请求没有指定版本时使用，默认 Versions 中最小的
+	// 新增版本时老客户端不用改动；想让默认跟随最新版本时设置为最大的版本
+	Default int
+
+	// Vendor Accept 媒体类型中的厂商名，默认 "api"，即 application/vnd.api.v2+json
+	Vendor string
+
+	// Header 指定版本的请求头，默认 "X-API-Version"
+	Header string
+
+	// Deprecated 已弃用的版本，响应带 Deprecation: true
+	Deprecated []int
+}
+
+// Handlers 版本号 → handler
+type Handlers map[int]gin.HandlerFunc
+
+// Registry 按 (路由, 版本) 选择 handler
+type Registry struct {
+	versions   []int
+	def        int
+	mediaType  string // application/vnd.api.v
+	header     string
+	deprecated map[int]bool
+	supported  string // "1, 2"，用于错误信息
+}
+
+const contextKey = "api_version"
+
+// New 创建版本注册表，Versions 为空或 Default 不在 Versions 中时 panic（配置错误应在启动时发现）
+func New(cfg Config) *Registry {
+	if len(cfg.Versions) == 0 {
+		panic("versioning: Versions must not be empty")
+	}
+	versions := slices.Clone(cfg.Versions)
+	slices.Sort(versions)
+	if cfg.Default == 0 {
+		cfg.Default = versions[0]
+	}
+	if !slices.Contains(versions, cfg.Default) {
+		panic(fmt.Sprintf("versioning: Default %d is not in Versions", cfg.Default))
+	}
+	if cfg.Vendor == "" {
+		cfg.Vendor = "api"
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-API-Version"
+	}
+
+	reg := &Registry{
+		versions:   versions,
+		def:        cfg.Default,
+		mediaType:  "application/vnd." + strings.ToLower(cfg.Vendor) + ".v",
+		header:     cfg.Header,
+		deprecated: make(map[int]bool),
+	}
+	for _, v := range cfg.Deprecated {
+		reg.deprecated[v] = true
+	}
+	names := make([]string, len(versions))
+	for i, v := range versions {
+		names[i] = strconv.Itoa(v)
+	}
+	reg.supported = strings.Join(names, ", ")
+	return reg
+}
+
+// Middleware 协商版本并保存到 gin.Context，版本无效时中止请求
+//
+// 挂在路由组上后，组内普通 handler 也可以用 From(c) 读取版本，做小范围的差异处理。
+// 不挂这个中间件时，Handle 注册的路由会自己协商。
+func (reg *Registry) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := reg.resolve(c); !ok {
+			return
+		}
+		c.Next()
+	}
+}
+
+// Handle 在 r 上注册一个路由，请求时按协商出的版本选择 handler
+//
+// 选择不大于请求版本的最高版本：v3 请求在只有 {1, 2} 的路由上使用 v2 的 handler。
+// 没有可用版本时返回 406。
+func (reg *Registry) Handle(r gin.IRoutes, method, path string, handlers Handlers) {
+	if len(handlers) == 0 {
+		panic("versioning: no handlers for " + method + " " + path)
+	}
+	versions := make([]int, 0, len(handlers))
+	for v := range handlers {
+		if !slices.Contains(reg.versions, v) {
+			panic(fmt.Sprintf("versioning: %s %s registers unsupported version %d", method, path, v))
+		}
+		versions = append(versions, v)
+	}
+	slices.Sort(versions)
+
+	r.Handle(method, path, func(c *gin.Context) {
+		v, ok := reg.resolve(c)
+		if !ok {
+			return
+		}
+		// 从高到低找第一个不大于 v 的版本
+		for i := len(versions) - 1; i >= 0; i-- {
+			if versions[i] <= v {
+				handlers[versions[i]](c)
+				return
+			}
+		}
+		response.Abort(c, http.StatusNotAcceptable, "unsupported_version",
+			fmt.Sprintf("%s %s is not available in API version %d", method, path, v))
+	})
+}
+
+// From 返回协商出的版本，没有经过 Middleware 或 Handle 时返回 0
+func From(c *gin.Context) int {
+	return c.GetInt(contextKey)
+}
+
+// resolve 取已经协商过的版本，或者协商并写响应头；失败时已经写好错误响应
+func (reg *Registry) resolve(c *gin.Context) (int, bool) {
+	if v := From(c); v != 0 {
+		return v, true
+	}
+
+	// 不论协商结果如何，缓存都要按这两个头区分
+	c.Writer.Header().Add("Vary", "Accept")
+	c.Writer.Header().Add("Vary", reg.header)
+
+	v, err := reg.negotiate(c.Request)
+	if err != nil {
+		code := http.StatusBadRequest
+		errCode := "invalid_version"
+		if errors.Is(err, errUnsupported) {
+			code = http.StatusNotAcceptable
+			errCode = "unsupported_version"
+		}
+		response.Abort(c, code, errCode, err.Error()+", supported versions: "+reg.supported)
+		return 0, false
+	}
+
+	c.Set(contextKey, v)
+	c.Header(reg.header, strconv.Itoa(v))
+	if reg.deprecated[v] {
+		c.Header("Deprecation", "true")
+	}
+	return v, true
+}
+
+// negotiate 请求头优先，其次 Accept，都没有时用默认版本
+func (reg *Registry) negotiate(r *http.Request) (int, error) {
+	if h := strings.TrimSpace(r.Header.Get(reg.header)); h != "" {
+		v, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(h), "v"))
+		if err != nil || v <= 0 {
+			return 0, errInvalid
+		}
+		if !slices.Contains(reg.versions, v) {
+			return 0, errUnsupported
+		}
+		return v, nil
+	}
+
+	// Accept 可能列出多个版本：选 q 值最高的受支持版本，q 相同时按出现顺序
+	best, bestQ, sawVendor := 0, 0.0, false
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		typ, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || !strings.HasPrefix(typ, reg.mediaType) {
+			continue
+		}
+		sawVendor = true
+		num, ok := strings.CutSuffix(typ[len(reg.mediaType):], "+json")
+		v, err := strconv.Atoi(num)
+		if !ok || err != nil || !slices.Contains(reg.versions, v) {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = v, q
+		}
+	}
+	switch {
+	case best != 0:
+		return best, nil
+	case sawVendor:
+		// 客户端明确要了某个版本，但都不支持
+		return 0, errUnsupported
+	}
+	return reg.def, nil
+}
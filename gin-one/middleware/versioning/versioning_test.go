@@ -0,0 +1,170 @@
+package versioning
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNegotiate(t *testing.T) {
+	reg := New(Config{Versions: []int{2, 1, 3}})
+
+	tests := []struct {
+		name    string
+		header  string
+		accept  string
+		want    int
+		wantErr error
+	}{
+		{"default", "", "", 1, nil},
+		{"plain json", "", "application/json", 1, nil},
+		{"header", "2", "", 2, nil},
+		{"header with v", "V3", "", 3, nil},
+		{"header wins", "1", "application/vnd.api.v2+json", 1, nil},
+		{"accept", "", "application/vnd.api.v2+json", 2, nil},
+		{"accept case", "", "Application/VND.API.V3+JSON", 3, nil},
+		{"accept q", "", "application/vnd.api.v2+json;q=0.5, application/vnd.api.v3+json;q=0.9", 3, nil},
+		{"accept skips unsupported", "", "application/vnd.api.v9+json, application/vnd.api.v2+json;q=0.1", 2, nil},
+		{"accept other vendor", "", "application/vnd.github.v3+json", 1, nil},
+		{"header not number", "two", "", 0, errInvalid},
+		{"header zero", "0", "", 0, errInvalid},
+		{"header unsupported", "4", "", 0, errUnsupported},
+		{"accept unsupported", "", "application/vnd.api.v4+json", 0, errUnsupported},
+		{"accept q zero", "", "application/vnd.api.v2+json;q=0", 0, errUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("X-API-Version", tt.header)
+			}
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			got, err := reg.negotiate(r)
+			if got != tt.want || err != tt.wantErr {
+				t.Errorf("negotiate = %d, %v; want %d, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg := New(Config{Versions: []int{1, 2, 3}, Deprecated: []int{1}})
+	named := func(name string) gin.HandlerFunc {
+		return func(c *gin.Context) { c.String(http.StatusOK, name) }
+	}
+
+	r := gin.New()
+	api := r.Group("/api")
+	reg.Handle(api, http.MethodGet, "/users", Handlers{1: named("list-v1"), 2: named("list-v2")})
+	reg.Handle(api, http.MethodGet, "/users/:id", Handlers{1: named("get-v1")})
+	reg.Handle(api, http.MethodGet, "/reports", Handlers{3: named("reports-v3")})
+
+	tests := []struct {
+		name        string
+		path        string
+		version     string
+		wantCode    int
+		wantBody    string
+		wantVersion string
+	}{
+		{"default v1", "/api/users", "", 200, "list-v1", "1"},
+		{"v2", "/api/users", "2", 200, "list-v2", "2"},
+		{"v3 falls back to v2", "/api/users", "3", 200, "list-v2", "3"},
+		{"only v1 route", "/api/users/7", "3", 200, "get-v1", "3"},
+		{"added in v3", "/api/reports", "3", 200, "reports-v3", "3"},
+		{"not yet in v2", "/api/reports", "2", 406, "", "2"},
+		{"unsupported", "/api/users", "9", 406, "", ""},
+		{"invalid", "/api/users", "x", 400, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.version != "" {
+				req.Header.Set("X-API-Version", tt.version)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d; want %d (%s)", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q; want %q", w.Body, tt.wantBody)
+			}
+			if got := w.Header().Get("X-API-Version"); got != tt.wantVersion {
+				t.Errorf("X-API-Version = %q; want %q", got, tt.wantVersion)
+			}
+			if got := w.Header().Values("Vary"); len(got) != 2 {
+				t.Errorf("Vary = %v", got)
+			}
+			if dep := w.Header().Get("Deprecation") == "true"; dep != (tt.wantVersion == "1") {
+				t.Errorf("Deprecation = %q", w.Header().Get("Deprecation"))
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg := New(Config{Versions: []int{1, 2}, Default: 2, Vendor: "shop"})
+
+	r := gin.New()
+	api := r.Group("/api", reg.Middleware())
+	api.GET("/ping", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"version": From(c)}) })
+	reg.Handle(api, http.MethodGet, "/users", Handlers{1: func(c *gin.Context) { c.String(200, "v1") }})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/ping", nil))
+	if w.Code != 200 || w.Body.String() != `{"version":2}` {
+		t.Errorf("default = %d %s", w.Code, w.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set("Accept", "application/vnd.shop.v1+json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 200 || w.Body.String() != "v1" {
+		t.Errorf("vendor accept = %d %s", w.Code, w.Body)
+	}
+	// 中间件和 Handle 都协商时只写一次响应头
+	if got := w.Header().Values("Vary"); len(got) != 2 {
+		t.Errorf("Vary = %v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/ping", nil)
+	req.Header.Set("X-API-Version", "3")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("unsupported = %d; want 406", w.Code)
+	}
+}
+
+func TestConfigPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{"no versions", func() { New(Config{}) }},
+		{"bad default", func() { New(Config{Versions: []int{1, 2}, Default: 3}) }},
+		{"unknown handler version", func() {
+			New(Config{Versions: []int{1}}).Handle(gin.New(), http.MethodGet, "/", Handlers{2: func(*gin.Context) {}})
+		}},
+		{"no handlers", func() { New(Config{Versions: []int{1}}).Handle(gin.New(), http.MethodGet, "/", nil) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			tt.fn()
+		})
+	}
+}
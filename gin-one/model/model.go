@@ -0,0 +1,136 @@
+// ============================================================================
+// Package model 数据库模型
+// ============================================================================
+//
+// 从 examples/4_1_gorm_integration.go 抽出来，repository、service 和示例共用同一份定义。
+// 模型只描述表结构，不包含查询逻辑：查询在 repository，业务规则在 service。
+// 例外是 Comment 的钩子：posts.comment_count 必须和评论在同一个事务里增减，
+// 放在钩子里，无论从哪里创建、删除评论计数都不会漏。
+//
+// | 表        | 模型    | 关联                                               |
+// |-----------|---------|----------------------------------------------------|
+// | users     | User    | 一对多 Post                                        |
+// | posts     | Post    | 属于 User，多对多 Tag（post_tags），一对多 Comment |
+// | tags      | Tag     | 多对多 Post                                        |
+// | comments  | Comment | 属于 Post，parent_id 指向回复的评论                |
+//
+// ============================================================================
+package model
+
+import "gorm.io/gorm"
+
+// User 用户模型
+type User struct {
+	// gorm.Model 包含 ID, CreatedAt, UpdatedAt, DeletedAt
+	// type Model struct {
+	//     ID        uint           `gorm:"primaryKey"`
+	//     CreatedAt time.Time
+	//     UpdatedAt time.Time
+	//     DeletedAt gorm.DeletedAt `gorm:"index"`
+	// }
+	gorm.Model
+
+	// 用户名：唯一索引，非空
+	Username string `gorm:"uniqueIndex;not null;size:50" json:"username"`
+
+	// 邮箱：唯一索引；serializer / render 输出时只有管理员和本人看得到
+	Email string `gorm:"uniqueIndex;size:100" json:"email" view:"admin,self"`
+
+	// 密码哈希（argon2id / bcrypt），不返回给前端，审计日志中只记录"改过"
+	Password string `gorm:"not null" json:"-" audit:"redact"`
+
+	// 年龄：默认值
+	Age int `gorm:"default:0" json:"age"`
+
+	// 状态：枚举
+	Status string `gorm:"type:varchar(20);default:'active'" json:"status"`
+
+	// 版本号：乐观锁，每次更新加 1（见 optlock 包）；审计日志里不记录
+	Version uint `gorm:"not null;default:1" json:"version" audit:"-"`
+
+	// 关联：一个用户有多篇文章
+	Posts []Post `gorm:"foreignKey:UserID" json:"posts,omitempty"`
+}
+
+// Post 文章模型
+type Post struct {
+	gorm.Model
+	Title   string `gorm:"not null;size:200" json:"title"`
+	Content string `gorm:"type:text" json:"content"`
+	UserID  uint   `gorm:"index" json:"user_id"`
+
+	// 属于某个用户
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+
+	// 多对多：文章标签，中间表 post_tags
+	Tags []Tag `gorm:"many2many:post_tags;" json:"tags,omitempty"`
+
+	// 评论数（不含已删除的），由 Comment 的钩子维护，不要直接修改
+	CommentCount int64 `gorm:"not null;default:0" json:"comment_count"`
+}
+
+// Tag 标签模型
+type Tag struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// binding / crud 标签给 crudgen 生成的管理接口用：创建和更新时校验，列表可按名字过滤、排序、搜索
+	Name string `gorm:"uniqueIndex;not null;size:50" json:"name" binding:"required,max=50" crud:"filter,sort,search"`
+}
+
+// Comment 文章评论，ParentID 为空是顶层评论，否则是对另一条评论的回复
+//
+// 删除是软删除：有回复的评论删除后仍在楼中楼里占位，显示为 [deleted]
+type Comment struct {
+	gorm.Model
+	PostID   uint  `gorm:"not null;index" json:"post_id"`
+	UserID   uint  `gorm:"not null;index" json:"user_id"`
+	ParentID *uint `gorm:"index" json:"parent_id"`
+	// 评论内容；没有 size 限制，长度在 service / handler 校验
+	Body string `gorm:"type:text;not null" json:"body"`
+}
+
+// AfterCreate 文章评论数加 1，和插入评论在同一个事务里
+func (c *Comment) AfterCreate(tx *gorm.DB) error {
+	return tx.Table("posts").Where("id = ?", c.PostID).
+		UpdateColumn("comment_count", gorm.Expr("comment_count + 1")).Error
+}
+
+// AfterDelete 文章评论数减 1；并发删除同一条评论时只有真正删掉的那次减
+//
+// 删除时要传完整的评论（至少带 PostID），只带主键时无法知道是哪篇文章
+func (c *Comment) AfterDelete(tx *gorm.DB) error {
+	if tx.Statement.RowsAffected == 0 {
+		return nil
+	}
+	return tx.Table("posts").Where("id = ? AND comment_count > 0", c.PostID).
+		UpdateColumn("comment_count", gorm.Expr("comment_count - 1")).Error
+}
+
+// TagCount 标签和使用它的文章数，聚合查询的结果，不是表
+type TagCount struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Posts int64  `json:"posts"`
+}
+
+// TableName 自定义表名
+func (User) TableName() string {
+	return "users"
+}
+
+func (Post) TableName() string {
+	return "posts"
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
+
+func (Comment) TableName() string {
+	return "comments"
+}
+
+// OwnerID 实现 serializer.Owner，用户本人可以看到 view:"self" 的字段
+func (u User) OwnerID() uint {
+	return u.ID
+}
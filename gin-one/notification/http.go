@@ -0,0 +1,100 @@
+package notification
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/pagination"
+	"go-one/response"
+)
+
+// Register 注册当前用户的通知接口，currentUser 取出已认证的用户 ID，
+// 返回 false 时响应 401。和 5_1_jwt_auth.go 的中间件搭配：
+//
+//	notification.Register(api.Group("/notifications"), svc, func(c *gin.Context) (uint, bool) {
+//	    id := c.GetUint("user_id")
+//	    return id, id != 0
+//	})
+func Register(group *gin.RouterGroup, s *Service, currentUser func(*gin.Context) (uint, bool)) {
+	user := func(c *gin.Context) (uint, bool) {
+		id, ok := currentUser(c)
+		if !ok {
+			response.Error(c, http.StatusUnauthorized, "unauthorized", "请先登录")
+		}
+		return id, ok
+	}
+
+	group.GET("", func(c *gin.Context) {
+		uid, ok := user(c)
+		if !ok {
+			return
+		}
+		req, err := pagination.FromQuery(c)
+		if err != nil || (req.Mode == pagination.ModeOffset && c.Query("page") != "") {
+			response.Error(c, http.StatusBadRequest, "invalid_pagination", "通知列表只支持 cursor / page_size 分页")
+			return
+		}
+		unread, _ := strconv.ParseBool(c.Query("unread"))
+		page, err := s.List(c.Request.Context(), uid, Query{UnreadOnly: unread, After: req.After, Limit: req.Size})
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "查询通知失败")
+			return
+		}
+		response.Success(c, gin.H{
+			"items":       page.Items,
+			"next_cursor": page.NextCursor,
+			"has_more":    page.HasMore,
+		})
+	})
+
+	group.GET("/unread-count", func(c *gin.Context) {
+		uid, ok := user(c)
+		if !ok {
+			return
+		}
+		n, err := s.UnreadCount(c.Request.Context(), uid)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "查询未读数失败")
+			return
+		}
+		response.Success(c, gin.H{"unread": n})
+	})
+
+	group.POST("/:id/read", func(c *gin.Context) {
+		uid, ok := user(c)
+		if !ok {
+			return
+		}
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil || id == 0 {
+			response.Error(c, http.StatusBadRequest, "invalid_id", "ID 不合法")
+			return
+		}
+		n, err := s.MarkRead(c.Request.Context(), uid, uint(id))
+		switch {
+		case errors.Is(err, ErrNotFound):
+			// 别人的通知也返回 404，不暴露 ID 是否存在
+			response.Error(c, http.StatusNotFound, "not_found", "通知不存在")
+		case err != nil:
+			response.Error(c, http.StatusInternalServerError, "internal_error", "标记已读失败")
+		default:
+			response.Success(c, n)
+		}
+	})
+
+	group.POST("/read-all", func(c *gin.Context) {
+		uid, ok := user(c)
+		if !ok {
+			return
+		}
+		n, err := s.MarkAllRead(c.Request.Context(), uid)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "标记已读失败")
+			return
+		}
+		response.Success(c, gin.H{"updated": n})
+	})
+}
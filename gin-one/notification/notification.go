@@ -0,0 +1,230 @@
+// ============================================================================
+// Package notification 站内通知：先落库，用户在线时再实时推送
+// ============================================================================
+//
+// 【为什么要落库】
+//
+// sse / ws 只负责"此刻在线"的连接：用户离线时 PublishTo 的事件没人收，
+// SSE 的环形缓冲区也只保留最近一段。站内通知要能在登录后查看、标记已读，
+// 所以每条通知先写进 notifications 表，再尝试推送：
+//
+//	Notify ──► INSERT notifications ──► 在线？──► SSE PublishTo / ws SendToUser
+//	                                      └─ 否：等用户打开通知列表时查询
+//
+// 推送失败不影响 Notify 的结果，通知已经在表里，客户端刷新列表就能看到。
+//
+// 【表结构】
+//
+// | 列         | 说明                                          |
+// |------------|-----------------------------------------------|
+// | user_id    | 接收者，和 created_at、id 一起建索引          |
+// | type       | 通知类型，如 order.shipped、comment.reply     |
+// | payload    | JSON，前端按 type 解释                         |
+// | read_at    | 已读时间，NULL 表示未读                        |
+//
+// 【接口】
+//
+//	GET  /notifications?unread=true&cursor=&page_size=   最新的在前，游标分页
+//	GET  /notifications/unread-count                     角标数字
+//	POST /notifications/:id/read                         标记一条已读
+//	POST /notifications/read-all                         全部已读
+//
+// 所有操作都带 user_id 条件，用户只能看到和修改自己的通知。
+//
+// ============================================================================
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go-one/pagination"
+)
+
+// 错误定义
+var (
+	ErrNotFound    = errors.New("notification: not found")
+	ErrInvalidType = errors.New("notification: invalid type")
+)
+
+// Notification 表 notifications 的一行
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index:idx_notifications_user_created,priority:1" json:"user_id"`
+	Type      string     `gorm:"size:100;not null" json:"type"`
+	Payload   string     `gorm:"type:text;not null" json:"-"` // JSON，见 MarshalJSON
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `gorm:"index:idx_notifications_user_created,priority:2" json:"created_at"`
+}
+
+// TableName 指定表名
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// MarshalJSON payload 按原始 JSON 输出，而不是转义后的字符串
+func (n Notification) MarshalJSON() ([]byte, error) {
+	type plain Notification
+	payload := json.RawMessage(n.Payload)
+	if n.Payload == "" {
+		payload = json.RawMessage("null")
+	}
+	return json.Marshal(struct {
+		plain
+		Payload json.RawMessage `json:"payload"`
+		Read    bool            `json:"read"`
+	}{plain(n), payload, n.ReadAt != nil})
+}
+
+// Pusher 把通知推给在线用户，用户不在线时返回 false
+type Pusher interface {
+	Push(n *Notification) (delivered bool, err error)
+}
+
+// Config 通知服务配置
+type Config struct {
+	// Pushers 实时推送渠道，每条通知依次尝试所有渠道（SSE 和 WebSocket 可以同时开）
+	Pushers []Pusher
+	// Logger 记录推送失败，默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Service 通知服务
+type Service struct {
+	db     *gorm.DB
+	cfg    Config
+	logger *slog.Logger
+}
+
+// New 创建通知服务，调用方负责 AutoMigrate(&notification.Notification{})
+func New(db *gorm.DB, cfg Config) *Service {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{db: db, cfg: cfg, logger: logger}
+}
+
+// Notify 保存一条通知并推送给在线的接收者
+//
+// payload 是 string、[]byte 或 json.RawMessage 时按 JSON 原样保存，其他类型编码为 JSON。
+// 推送失败只记日志，返回的错误只表示保存失败。
+func (s *Service) Notify(ctx context.Context, userID uint, typ string, payload any) (*Notification, error) {
+	if typ == "" || strings.ContainsAny(typ, "\r\n") {
+		return nil, ErrInvalidType
+	}
+	data, err := encode(payload)
+	if err != nil {
+		return nil, fmt.Errorf("notification: encode payload: %w", err)
+	}
+	n := &Notification{UserID: userID, Type: typ, Payload: string(data)}
+	if err := s.db.WithContext(ctx).Create(n).Error; err != nil {
+		return nil, err
+	}
+	s.push(n)
+	return n, nil
+}
+
+func encode(payload any) ([]byte, error) {
+	var data []byte
+	switch v := payload.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	default:
+		return json.Marshal(v)
+	}
+	if !json.Valid(data) {
+		return nil, errors.New("payload is not valid JSON")
+	}
+	return data, nil
+}
+
+func (s *Service) push(n *Notification) {
+	for _, p := range s.cfg.Pushers {
+		if _, err := p.Push(n); err != nil {
+			s.logger.Warn("notification: push failed",
+				slog.Uint64("user_id", uint64(n.UserID)), slog.String("type", n.Type), slog.Any("error", err))
+		}
+	}
+}
+
+// Query 列表查询条件
+type Query struct {
+	UnreadOnly bool
+	After      *pagination.Cursor // 上一页最后一条，nil 表示第一页
+	Limit      int
+}
+
+func notificationCursor(n Notification) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: n.CreatedAt, ID: n.ID}
+}
+
+// List 用户的通知，最新的在前，游标分页
+func (s *Service) List(ctx context.Context, userID uint, q Query) (pagination.Page[Notification], error) {
+	if q.Limit <= 0 {
+		q.Limit = pagination.DefaultSize
+	}
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	if q.UnreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	var rows []Notification
+	if err := pagination.Apply(query, q.After, q.Limit, pagination.Desc).Find(&rows).Error; err != nil {
+		return pagination.Page[Notification]{}, err
+	}
+	return pagination.NewPage(rows, q.Limit, notificationCursor), nil
+}
+
+// UnreadCount 未读通知数
+func (s *Service) UnreadCount(ctx context.Context, userID uint) (int64, error) {
+	var n int64
+	err := s.db.WithContext(ctx).Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
+
+// MarkRead 把一条通知标记为已读，不存在或不属于该用户时返回 ErrNotFound
+// 已经读过的通知保持原来的 read_at，重复调用不报错
+func (s *Service) MarkRead(ctx context.Context, userID, id uint) (*Notification, error) {
+	var n Notification
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&n).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if n.ReadAt != nil {
+			return nil
+		}
+		now := time.Now()
+		if err := tx.Model(&n).Update("read_at", now).Error; err != nil {
+			return err
+		}
+		n.ReadAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// MarkAllRead 把用户所有未读通知标记为已读，返回更新的条数
+func (s *Service) MarkAllRead(ctx context.Context, userID uint) (int64, error) {
+	res := s.db.WithContext(ctx).Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return res.RowsAffected, res.Error
+}
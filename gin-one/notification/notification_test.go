@@ -0,0 +1,226 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.Open(t, &Notification{})
+}
+
+// fakeBroker 记录 PublishTo 的调用，online 里的用户视为在线
+type fakeBroker struct {
+	online    map[string]bool
+	published []string
+}
+
+func (b *fakeBroker) Online(userID string) bool { return b.online[userID] }
+
+func (b *fakeBroker) PublishTo(userID, typ string, data any) (uint64, error) {
+	b.published = append(b.published, userID+" "+typ+" "+string(data.([]byte)))
+	return uint64(len(b.published)), nil
+}
+
+type fakeHub struct {
+	online map[string]bool
+	sent   []string
+}
+
+func (h *fakeHub) Online(userID string) bool { return h.online[userID] }
+
+func (h *fakeHub) SendToUser(userID string, msg []byte) int {
+	h.sent = append(h.sent, userID+" "+string(msg))
+	return 1
+}
+
+type failingPusher struct{}
+
+func (failingPusher) Push(*Notification) (bool, error) { return false, errors.New("boom") }
+
+func TestNotifyFanOut(t *testing.T) {
+	db := newTestDB(t)
+	broker := &fakeBroker{online: map[string]bool{"1": true}}
+	hub := &fakeHub{online: map[string]bool{"1": true, "2": true}}
+	s := New(db, Config{Pushers: []Pusher{ssePusher{broker}, wsPusher{hub}, failingPusher{}}})
+	ctx := context.Background()
+
+	n, err := s.Notify(ctx, 1, "order.shipped", map[string]any{"order_id": 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.ID == 0 || n.Payload != `{"order_id":7}` {
+		t.Fatalf("notification = %+v", n)
+	}
+	// 用户 3 不在线：只落库，不推送
+	if _, err := s.Notify(ctx, 3, "comment.reply", `{"post_id":1}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Notify(ctx, 2, "system", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(broker.published) != 1 || !strings.HasPrefix(broker.published[0], `1 notification {"id":1,`) ||
+		!strings.Contains(broker.published[0], `"payload":{"order_id":7}`) {
+		t.Errorf("sse published = %q", broker.published)
+	}
+	if len(hub.sent) != 2 || !strings.HasPrefix(hub.sent[0], `1 {"type":"notification","data":{"id":1,`) ||
+		!strings.HasPrefix(hub.sent[1], "2 ") || !strings.Contains(hub.sent[1], `"payload":null`) {
+		t.Errorf("ws sent = %q", hub.sent)
+	}
+
+	var count int64
+	db.Model(&Notification{}).Count(&count)
+	if count != 3 {
+		t.Errorf("rows = %d; want 3", count)
+	}
+}
+
+func TestNotifyInvalid(t *testing.T) {
+	s := New(newTestDB(t), Config{})
+	ctx := context.Background()
+	if _, err := s.Notify(ctx, 1, "", nil); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("err = %v; want ErrInvalidType", err)
+	}
+	if _, err := s.Notify(ctx, 1, "bad\ntype", nil); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("err = %v; want ErrInvalidType", err)
+	}
+	if _, err := s.Notify(ctx, 1, "x", "not json"); err == nil {
+		t.Error("invalid JSON payload accepted")
+	}
+}
+
+func TestListAndMarkRead(t *testing.T) {
+	s := New(newTestDB(t), Config{})
+	ctx := context.Background()
+	var ids []uint
+	for i := range 5 {
+		n, err := s.Notify(ctx, 1, "t", map[string]int{"i": i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, n.ID)
+	}
+	other, _ := s.Notify(ctx, 2, "t", nil)
+
+	page, err := s.List(ctx, 1, Query{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 3 || !page.HasMore || page.Items[0].ID != ids[4] {
+		t.Fatalf("page = %+v", page)
+	}
+	after := notificationCursor(page.Items[2])
+	page, _ = s.List(ctx, 1, Query{After: &after, Limit: 3})
+	if len(page.Items) != 2 || page.HasMore || page.Items[1].ID != ids[0] {
+		t.Fatalf("second page = %+v", page)
+	}
+
+	n, err := s.MarkRead(ctx, 1, ids[0])
+	if err != nil || n.ReadAt == nil {
+		t.Fatalf("MarkRead = %+v, %v", n, err)
+	}
+	first := *n.ReadAt
+	// 重复标记保持原来的已读时间
+	if n, _ := s.MarkRead(ctx, 1, ids[0]); !n.ReadAt.Equal(first) {
+		t.Errorf("read_at changed: %v -> %v", first, n.ReadAt)
+	}
+	// 别人的通知
+	if _, err := s.MarkRead(ctx, 1, other.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v; want ErrNotFound", err)
+	}
+
+	if c, _ := s.UnreadCount(ctx, 1); c != 4 {
+		t.Errorf("unread = %d; want 4", c)
+	}
+	page, _ = s.List(ctx, 1, Query{UnreadOnly: true, Limit: 10})
+	if len(page.Items) != 4 {
+		t.Errorf("unread items = %d; want 4", len(page.Items))
+	}
+
+	if updated, err := s.MarkAllRead(ctx, 1); err != nil || updated != 4 {
+		t.Errorf("MarkAllRead = %d, %v; want 4", updated, err)
+	}
+	if c, _ := s.UnreadCount(ctx, 1); c != 0 {
+		t.Errorf("unread = %d; want 0", c)
+	}
+	if c, _ := s.UnreadCount(ctx, 2); c != 1 {
+		t.Errorf("user 2 unread = %d; want 1", c)
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := New(newTestDB(t), Config{})
+	ctx := context.Background()
+	mine, _ := s.Notify(ctx, 1, "t", `{"a":1}`)
+	theirs, _ := s.Notify(ctx, 2, "t", nil)
+
+	r := gin.New()
+	Register(r.Group("/notifications"), s, func(c *gin.Context) (uint, bool) {
+		id, err := strconv.ParseUint(c.GetHeader("X-User"), 10, 32)
+		return uint(id), err == nil
+	})
+	do := func(method, path, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	id := func(n *Notification) string { return strconv.FormatUint(uint64(n.ID), 10) }
+
+	tests := []struct {
+		method, path, user string
+		want               int
+	}{
+		{http.MethodGet, "/notifications", "", http.StatusUnauthorized},
+		{http.MethodGet, "/notifications?page=2", "1", http.StatusBadRequest},
+		{http.MethodPost, "/notifications/" + id(theirs) + "/read", "1", http.StatusNotFound},
+		{http.MethodPost, "/notifications/abc/read", "1", http.StatusBadRequest},
+		{http.MethodPost, "/notifications/" + id(mine) + "/read", "1", http.StatusOK},
+		{http.MethodPost, "/notifications/read-all", "2", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := do(tt.method, tt.path, tt.user); w.Code != tt.want {
+			t.Errorf("%s %s = %d; want %d", tt.method, tt.path, w.Code, tt.want)
+		}
+	}
+
+	w := do(http.MethodGet, "/notifications", "1")
+	var body struct {
+		Data struct {
+			Items []struct {
+				ID      uint            `json:"id"`
+				Payload json.RawMessage `json:"payload"`
+				Read    bool            `json:"read"`
+			} `json:"items"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	items := body.Data.Items
+	if len(items) != 1 || string(items[0].Payload) != `{"a":1}` || !items[0].Read {
+		t.Errorf("items = %+v", items)
+	}
+
+	w = do(http.MethodGet, "/notifications/unread-count", "2")
+	if !strings.Contains(w.Body.String(), `"unread":0`) {
+		t.Errorf("unread-count body = %s", w.Body.String())
+	}
+}
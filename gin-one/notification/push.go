@@ -0,0 +1,73 @@
+package notification
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"go-one/sse"
+	"go-one/ws"
+)
+
+// EventType SSE 事件类型 / WebSocket 消息的 type 字段
+const EventType = "notification"
+
+// sseBroker sse.Broker 中用到的方法，测试时可以替换
+type sseBroker interface {
+	Online(userID string) bool
+	PublishTo(userID, typ string, data any) (uint64, error)
+}
+
+// wsHub ws.Hub 中用到的方法
+type wsHub interface {
+	Online(userID string) bool
+	SendToUser(userID string, msg []byte) int
+}
+
+// ssePusher 通过 SSE 推送，事件类型为 notification，data 是通知的 JSON
+type ssePusher struct{ b sseBroker }
+
+// SSE 用户有 /events 订阅时推送
+//
+//	es.addEventListener("notification", e => prepend(JSON.parse(e.data)))
+func SSE(b *sse.Broker) Pusher {
+	return ssePusher{b: b}
+}
+
+func (p ssePusher) Push(n *Notification) (bool, error) {
+	uid := strconv.FormatUint(uint64(n.UserID), 10)
+	// 不在线时不进 Broker 的补发缓冲区：重连后客户端本来就要重新拉列表
+	if !p.b.Online(uid) {
+		return false, nil
+	}
+	data, err := json.Marshal(n)
+	if err != nil {
+		return false, err
+	}
+	if _, err := p.b.PublishTo(uid, EventType, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// wsPusher 通过 WebSocket 推送 {"type":"notification","data":{...}}
+type wsPusher struct{ h wsHub }
+
+// WebSocket 用户有 WebSocket 连接时推送
+func WebSocket(h *ws.Hub) Pusher {
+	return wsPusher{h: h}
+}
+
+func (p wsPusher) Push(n *Notification) (bool, error) {
+	uid := strconv.FormatUint(uint64(n.UserID), 10)
+	if !p.h.Online(uid) {
+		return false, nil
+	}
+	msg, err := json.Marshal(struct {
+		Type string        `json:"type"`
+		Data *Notification `json:"data"`
+	}{EventType, n})
+	if err != nil {
+		return false, err
+	}
+	return p.h.SendToUser(uid, msg) > 0, nil
+}
@@ -0,0 +1,106 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// githubProvider GitHub 只有 OAuth2，没有 ID Token：拿 access_token 调用 API 取用户和邮箱
+// 没有 nonce，重放由 state + PKCE 防御
+type githubProvider struct {
+	client   Client
+	endpoint Endpoint
+	apiURL   string
+	hc       *http.Client
+}
+
+// GitHub 用 GitHub 账号登录，在 Settings → Developer settings → OAuth Apps 中创建应用
+// Scopes 为空时使用 read:user user:email
+func GitHub(c Client) Provider {
+	if len(c.Scopes) == 0 {
+		c.Scopes = []string{"read:user", "user:email"}
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = defaultHTTPClient()
+	}
+	return &githubProvider{
+		client: c,
+		endpoint: Endpoint{
+			AuthURL:  "https://github.com/login/oauth/authorize",
+			TokenURL: "https://github.com/login/oauth/access_token",
+		},
+		apiURL: "https://api.github.com",
+		hc:     c.HTTPClient,
+	}
+}
+
+func (p *githubProvider) Name() string { return "github" }
+
+func (p *githubProvider) AuthCodeURL(state, _, challenge string) string {
+	return authCodeURL(p.endpoint, p.client, state, challenge, nil)
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code, verifier, _ string) (Identity, error) {
+	tok, err := exchange(ctx, p.hc, p.endpoint, p.client, code, verifier)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := p.get(ctx, tok.AccessToken, "/user", &user); err != nil {
+		return Identity{}, err
+	}
+	if user.ID == 0 {
+		return Identity{}, fmt.Errorf("oauth: github: empty user id")
+	}
+	id := Identity{
+		Provider:  "github",
+		Subject:   strconv.FormatInt(user.ID, 10), // login 可以改名，id 不变
+		Name:      user.Name,
+		AvatarURL: user.AvatarURL,
+	}
+	if id.Name == "" {
+		id.Name = user.Login
+	}
+
+	// /user 里的 email 是公开邮箱，可能为空也不保证已验证；从 /user/emails 取已验证的主邮箱
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, tok.AccessToken, "/user/emails", &emails); err != nil {
+		return Identity{}, err
+	}
+	for _, e := range emails {
+		if e.Primary {
+			id.Email, id.EmailVerified = e.Email, e.Verified
+			break
+		}
+	}
+	return id, nil
+}
+
+func (p *githubProvider) get(ctx context.Context, accessToken, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	status, err := doJSON(p.hc, req, v)
+	if err != nil {
+		return fmt.Errorf("oauth: github %s: %w", path, err)
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("oauth: github %s: status %d", path, status)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package oauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// Config 登录流程配置
+type Config struct {
+	Providers []Provider
+
+	// Secret 签名 state Cookie 的 HMAC 密钥，至少 32 字节
+	Secret []byte
+
+	// CookieName 默认 oauth_state；CookiePath 默认 /，必须覆盖回调路径
+	CookieName string
+	CookiePath string
+	// Secure Cookie 只通过 HTTPS 发送，生产环境必须为 true
+	Secure bool
+	// TTL 从跳转授权页到回调的最长时间，默认 10 分钟
+	TTL time.Duration
+
+	// OnLogin 回调校验通过后调用，负责创建或关联本地用户（见 Links.Resolve）、签发本站 Token 并写响应
+	OnLogin func(c *gin.Context, id Identity)
+
+	// Logger 记录换 Token 失败的原因，默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Flow 授权码 + PKCE 登录流程，state 保存在签名 Cookie 里，服务端无状态
+type Flow struct {
+	cfg       Config
+	providers map[string]Provider
+	logger    *slog.Logger
+	now       func() time.Time
+}
+
+// New 创建登录流程
+func New(cfg Config) (*Flow, error) {
+	if len(cfg.Secret) < 32 {
+		return nil, errors.New("oauth: secret must be at least 32 bytes")
+	}
+	if cfg.OnLogin == nil {
+		return nil, errors.New("oauth: OnLogin is required")
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "oauth_state"
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 10 * time.Minute
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	f := &Flow{cfg: cfg, providers: make(map[string]Provider, len(cfg.Providers)), logger: cfg.Logger, now: time.Now}
+	for _, p := range cfg.Providers {
+		f.providers[p.Name()] = p
+	}
+	return f, nil
+}
+
+// Providers 已启用的提供方名字，登录页据此显示按钮
+func (f *Flow) Providers() []string {
+	names := make([]string, 0, len(f.cfg.Providers))
+	for _, p := range f.cfg.Providers {
+		names = append(names, p.Name())
+	}
+	return names
+}
+
+// Register 注册登录入口和回调：
+//
+//	GET /:provider/login     跳转到提供方授权页
+//	GET /:provider/callback  提供方回调，成功后调用 OnLogin
+//
+// 回调地址 = 对外域名 + group 前缀 + /<provider>/callback，要和提供方控制台里登记的一致
+func Register(group *gin.RouterGroup, f *Flow) {
+	group.GET("/:provider/login", f.Begin)
+	group.GET("/:provider/callback", f.Callback)
+}
+
+// flowState 跳转前生成、回调时核对的随机值
+type flowState struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	Expires  int64  `json:"e"`
+}
+
+// Begin 生成 state、nonce、code_verifier，写入签名 Cookie 后跳转到授权页
+func (f *Flow) Begin(c *gin.Context) {
+	p, ok := f.providers[c.Param("provider")]
+	if !ok {
+		response.Error(c, http.StatusNotFound, "unknown_provider", "不支持的登录方式")
+		return
+	}
+	st := flowState{Provider: p.Name(), Expires: f.now().Add(f.cfg.TTL).Unix()}
+	for _, v := range []*string{&st.State, &st.Nonce, &st.Verifier} {
+		s, err := randomString()
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "生成登录状态失败")
+			return
+		}
+		*v = s
+	}
+	f.setCookie(c, f.sign(st), int(f.cfg.TTL.Seconds()))
+	c.Redirect(http.StatusFound, p.AuthCodeURL(st.State, st.Nonce, Challenge(st.Verifier)))
+}
+
+// Callback 核对 state，用 code + code_verifier 换 Token，校验通过后交给 OnLogin
+func (f *Flow) Callback(c *gin.Context) {
+	p, ok := f.providers[c.Param("provider")]
+	if !ok {
+		response.Error(c, http.StatusNotFound, "unknown_provider", "不支持的登录方式")
+		return
+	}
+	cookie, _ := c.Cookie(f.cfg.CookieName)
+	// 无论成败都删除 Cookie，同一个 state 只能用一次
+	f.setCookie(c, "", -1)
+
+	if e := c.Query("error"); e != "" {
+		if e == "access_denied" {
+			response.Error(c, http.StatusForbidden, "access_denied", "已取消授权")
+		} else {
+			response.Error(c, http.StatusBadRequest, "oauth_error", e)
+		}
+		return
+	}
+	st, err := f.verify(cookie)
+	if err != nil || st.Provider != p.Name() ||
+		subtle.ConstantTimeCompare([]byte(st.State), []byte(c.Query("state"))) != 1 {
+		response.Error(c, http.StatusBadRequest, "invalid_state", "登录已过期或来源不可信，请重新登录")
+		return
+	}
+	code := c.Query("code")
+	if code == "" {
+		response.Error(c, http.StatusBadRequest, "invalid_request", "缺少 code")
+		return
+	}
+
+	id, err := p.Exchange(c.Request.Context(), code, st.Verifier, st.Nonce)
+	if err != nil {
+		f.logger.Warn("oauth: exchange failed", slog.String("provider", p.Name()), slog.Any("error", err))
+		response.Error(c, http.StatusBadGateway, "oauth_failed", "第三方登录失败，请重试")
+		return
+	}
+	f.cfg.OnLogin(c, id)
+}
+
+func (f *Flow) setCookie(c *gin.Context, value string, maxAge int) {
+	// SameSite=Lax：从提供方跳回来是顶级 GET 导航，Lax 会带上 Cookie，Strict 不会
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(f.cfg.CookieName, value, maxAge, f.cfg.CookiePath, "", f.cfg.Secure, true)
+}
+
+// sign 编码为 base64(JSON).base64(HMAC)
+func (f *Flow) sign(st flowState) string {
+	payload, _ := json.Marshal(st)
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(f.mac(body))
+}
+
+func (f *Flow) mac(body string) []byte {
+	h := hmac.New(sha256.New, f.cfg.Secret)
+	h.Write([]byte(body))
+	return h.Sum(nil)
+}
+
+func (f *Flow) verify(cookie string) (flowState, error) {
+	var st flowState
+	body, sig, ok := strings.Cut(cookie, ".")
+	if !ok {
+		return st, ErrInvalidState
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, f.mac(body)) {
+		return st, ErrInvalidState
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil || json.Unmarshal(payload, &st) != nil {
+		return st, ErrInvalidState
+	}
+	if f.now().Unix() > st.Expires || st.State == "" {
+		return st, ErrInvalidState
+	}
+	return st, nil
+}
@@ -0,0 +1,121 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAlreadyLinked 第三方账号已经关联了另一个本地用户
+var ErrAlreadyLinked = errors.New("oauth: identity already linked to another user")
+
+// Link 表 oauth_identities 的一行：一个第三方账号关联到一个本地用户
+// 一个本地用户可以关联多个第三方账号（Google 和 GitHub 都能登录同一个账号）
+type Link struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Provider  string    `gorm:"size:32;not null;uniqueIndex:idx_oauth_identities_provider_subject" json:"provider"`
+	Subject   string    `gorm:"size:255;not null;uniqueIndex:idx_oauth_identities_provider_subject" json:"-"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Email     string    `gorm:"size:255" json:"email"` // 关联时提供方返回的邮箱，只用于展示
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (Link) TableName() string {
+	return "oauth_identities"
+}
+
+// Users 本地用户存储，由应用实现
+type Users interface {
+	// FindByEmail 按邮箱查找本地用户（不区分大小写），不存在时 found 为 false
+	FindByEmail(ctx context.Context, email string) (userID uint, found bool, err error)
+	// Create 用第三方身份创建本地用户（没有密码），id.EmailVerified 决定是否视为已验证邮箱
+	Create(ctx context.Context, id Identity) (userID uint, err error)
+}
+
+// Links 第三方账号关联
+type Links struct {
+	db *gorm.DB
+}
+
+// NewLinks 创建关联存储，表需要事先 AutoMigrate(&oauth.Link{})
+func NewLinks(db *gorm.DB) *Links {
+	return &Links{db: db}
+}
+
+// Find 查找已关联的本地用户，没有关联时返回 gorm.ErrRecordNotFound
+func (l *Links) Find(ctx context.Context, provider, subject string) (*Link, error) {
+	var link Link
+	err := l.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).Take(&link).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Resolve 创建或关联本地用户，规则见包注释，created 表示新建了用户
+func (l *Links) Resolve(ctx context.Context, id Identity, users Users) (userID uint, created bool, err error) {
+	link, err := l.Find(ctx, id.Provider, id.Subject)
+	if err == nil {
+		return link.UserID, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, err
+	}
+
+	if id.Email == "" {
+		return 0, false, ErrNoEmail
+	}
+	existing, found, err := users.FindByEmail(ctx, id.Email)
+	if err != nil {
+		return 0, false, err
+	}
+	switch {
+	case found && !id.EmailVerified:
+		// 任何人都能在提供方填一个别人的邮箱，未验证就关联等于把账号交给他
+		return 0, false, ErrEmailNotVerified
+	case found:
+		userID = existing
+	default:
+		if userID, err = users.Create(ctx, id); err != nil {
+			return 0, false, err
+		}
+		created = true
+	}
+	if err := l.Link(ctx, userID, id); err != nil {
+		return 0, false, err
+	}
+	return userID, created, nil
+}
+
+// Link 把第三方账号关联到用户，已关联到同一用户时不报错，关联到其他用户时返回 ErrAlreadyLinked
+// 已登录用户在设置页主动关联时直接调用
+func (l *Links) Link(ctx context.Context, userID uint, id Identity) error {
+	err := l.db.WithContext(ctx).Create(&Link{
+		Provider: id.Provider,
+		Subject:  id.Subject,
+		UserID:   userID,
+		Email:    id.Email,
+	}).Error
+	if err == nil {
+		return nil
+	}
+	// 唯一索引冲突：并发的第一次登录，或者已经关联过
+	existing, findErr := l.Find(ctx, id.Provider, id.Subject)
+	if findErr != nil {
+		return err
+	}
+	if existing.UserID != userID {
+		return ErrAlreadyLinked
+	}
+	return nil
+}
+
+// List 用户关联的第三方账号
+func (l *Links) List(ctx context.Context, userID uint) ([]Link, error) {
+	var links []Link
+	err := l.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&links).Error
+	return links, err
+}
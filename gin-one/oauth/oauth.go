@@ -0,0 +1,218 @@
+// ============================================================================
+// Package oauth 第三方登录：OAuth2 授权码 + PKCE，OIDC ID Token 校验，创建或关联本地用户
+// ============================================================================
+//
+// 【授权码流程】
+//
+//	浏览器                     本服务                               提供方（Google / GitHub）
+//	  │ GET /auth/oauth/google/login │                                        │
+//	  │─────────────────────────────►│ 生成 state、nonce、code_verifier       │
+//	  │   302 + Set-Cookie(签名)     │ 写进签名 Cookie                        │
+//	  │◄─────────────────────────────│                                        │
+//	  │ 302 到授权页（state、nonce、code_challenge）────────────────────────►│
+//	  │                              │                       用户同意授权     │
+//	  │ GET /callback?code=...&state=... ◄────────────────────────────────────│
+//	  │─────────────────────────────►│ 校验 state == Cookie 里的 state        │
+//	  │                              │ POST token（code + code_verifier）────►│
+//	  │                              │◄──── access_token / id_token ──────────│
+//	  │                              │ 校验 id_token 签名、aud、iss、nonce    │
+//	  │                              │ 创建或关联本地用户，签发本站 JWT       │
+//	  │◄─────────────────────────────│                                        │
+//
+// 【三个随机值各防什么】
+//
+// | 值            | 放在哪里                         | 防御                                         |
+// |---------------|----------------------------------|----------------------------------------------|
+// | state         | 授权 URL + Cookie，回调时比较    | 登录 CSRF：攻击者把自己的 code 塞给受害者    |
+// | nonce         | 授权 URL + Cookie，ID Token 里   | ID Token 重放：拿别处截获的 ID Token 来登录  |
+// | code_verifier | 只在 Cookie，授权 URL 里是摘要   | code 被截获（日志、Referer）后换不到 Token   |
+//
+// 三个值都放在 HMAC 签名的 HttpOnly Cookie 里，服务端不用存会话，多实例也能直接用；
+// Cookie 10 分钟过期，回调之后立即删除，同一个 state 不能用两次。
+//
+// 【创建还是关联】
+//
+// oauth_identities 表记录 (provider, subject) → user_id，见 Links.Resolve：
+//
+// | 情况                                           | 处理                                 |
+// |------------------------------------------------|--------------------------------------|
+// | 这个第三方账号已经关联过                       | 登录关联的用户                       |
+// | 提供方确认邮箱已验证，且本地有同邮箱的用户     | 关联到该用户                         |
+// | 邮箱未验证，但本地有同邮箱的用户               | 拒绝（ErrEmailNotVerified），防接管  |
+// | 本地没有同邮箱的用户                           | 创建新用户并关联                     |
+//
+// 关联只认 subject（提供方的用户 ID），不认邮箱：用户在提供方改了邮箱也能登录。
+//
+// ============================================================================
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 错误定义
+var (
+	ErrUnknownProvider  = errors.New("oauth: unknown provider")
+	ErrInvalidState     = errors.New("oauth: invalid state")
+	ErrInvalidNonce     = errors.New("oauth: invalid nonce")
+	ErrInvalidIDToken   = errors.New("oauth: invalid id token")
+	ErrAccessDenied     = errors.New("oauth: access denied by user")
+	ErrNoEmail          = errors.New("oauth: provider returned no email")
+	ErrEmailNotVerified = errors.New("oauth: email not verified by provider")
+)
+
+// Identity 提供方返回的用户身份
+type Identity struct {
+	Provider      string `json:"provider"`
+	Subject       string `json:"subject"` // 提供方的用户 ID，不会变
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	AvatarURL     string `json:"avatar_url,omitempty"`
+}
+
+// Provider 一个第三方登录提供方
+type Provider interface {
+	// Name 路由里的名字，如 google、github
+	Name() string
+	// AuthCodeURL 授权页地址，challenge 是 code_verifier 的 S256 摘要
+	AuthCodeURL(state, nonce, challenge string) string
+	// Exchange 用 code 和 code_verifier 换 Token 并取出用户身份，OIDC 提供方同时校验 nonce
+	Exchange(ctx context.Context, code, verifier, nonce string) (Identity, error)
+}
+
+// Client 在提供方注册应用得到的凭据
+type Client struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // 必须和提供方控制台里登记的回调地址完全一致
+	Scopes       []string
+
+	// HTTPClient 调用提供方接口，默认 10 秒超时；要加熔断、并发上限时传 resilience.Transport 包过的客户端
+	HTTPClient *http.Client
+}
+
+// Endpoint 授权和换 Token 的地址
+type Endpoint struct {
+	AuthURL  string
+	TokenURL string
+}
+
+// ============================================================================
+// PKCE（RFC 7636）
+// ============================================================================
+
+// randomString 32 字节随机数的 base64url 编码，43 个字符，满足 code_verifier 的长度要求
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge code_verifier 的 S256 摘要：BASE64URL(SHA256(verifier))
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ============================================================================
+// 授权码换 Token
+// ============================================================================
+
+// authCodeURL 拼接授权页地址，extra 是提供方特有的参数（如 OIDC 的 nonce）
+func authCodeURL(ep Endpoint, c Client, state, challenge string, extra url.Values) string {
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.ClientID},
+		"redirect_uri":          {c.RedirectURL},
+		"scope":                 {strings.Join(c.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	for k, v := range extra {
+		q[k] = v
+	}
+	sep := "?"
+	if strings.Contains(ep.AuthURL, "?") {
+		sep = "&"
+	}
+	return ep.AuthURL + sep + q.Encode()
+}
+
+// tokenResponse 换 Token 接口的响应（RFC 6749 5.1），OIDC 多一个 id_token
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+	ErrorDesc   string `json:"error_description"`
+}
+
+// exchange POST token 接口，client_secret 放在表单里（Google、GitHub 都支持）
+func exchange(ctx context.Context, hc *http.Client, ep Endpoint, c Client, code, verifier string) (*tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.RedirectURL},
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// GitHub 默认返回表单格式，要求 JSON
+	req.Header.Set("Accept", "application/json")
+
+	var tok tokenResponse
+	status, err := doJSON(hc, req, &tok)
+	if err != nil {
+		return nil, fmt.Errorf("oauth: exchange code: %w", err)
+	}
+	// GitHub 出错时也返回 200，只能看 error 字段
+	if tok.Error != "" || status != http.StatusOK {
+		return nil, fmt.Errorf("oauth: exchange code: status %d: %s %s", status, tok.Error, tok.ErrorDesc)
+	}
+	if tok.AccessToken == "" {
+		return nil, errors.New("oauth: exchange code: empty access_token")
+	}
+	return &tok, nil
+}
+
+// doJSON 发送请求并解码 JSON 响应，响应体最多读 1MB
+func doJSON(hc *http.Client, req *http.Request, v any) (int, error) {
+	resp, err := hc.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return resp.StatusCode, fmt.Errorf("status %d: decode response: %w", resp.StatusCode, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// defaultHTTPClient 调用提供方接口的客户端，必须有超时：提供方卡住时不能拖住回调请求
+func defaultHTTPClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second}
+}
@@ -0,0 +1,384 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+// fakeIdP 模拟提供方：授权时记录 challenge 和 nonce，换 Token 时校验 code_verifier
+type fakeIdP struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu       sync.Mutex
+	codes    map[string]authRequest
+	badNonce bool // 签发 nonce 不匹配的 ID Token
+}
+
+type authRequest struct {
+	challenge, nonce string
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &fakeIdP{key: key, codes: map[string]authRequest{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", idp.token)
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("GET /user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer at-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Bad credentials"}`))
+			return
+		}
+		w.Write([]byte(`{"id":42,"login":"octocat","name":"","avatar_url":"https://a/x.png"}`))
+	})
+	mux.HandleFunc("GET /user/emails", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"email":"public@example.com","primary":false,"verified":true},
+			{"email":"octo@example.com","primary":true,"verified":true}]`))
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// authorize 模拟用户在授权页同意，返回发给回调的 code
+func (idp *fakeIdP) authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("auth url without PKCE: %s", authURL)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	code = "code-" + q.Get("state")[:8]
+	idp.codes[code] = authRequest{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	return code, q.Get("state")
+}
+
+func (idp *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	idp.mu.Lock()
+	req, ok := idp.codes[r.Form.Get("code")]
+	delete(idp.codes, r.Form.Get("code"))
+	badNonce := idp.badNonce
+	idp.mu.Unlock()
+	if !ok || Challenge(r.Form.Get("code_verifier")) != req.challenge || r.Form.Get("client_secret") != "s3cret" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+	nonce := req.nonce
+	if badNonce {
+		nonce = "replayed"
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": idp.URL, "aud": "client-1", "sub": "u-100",
+		"exp": time.Now().Add(time.Hour).Unix(), "iat": time.Now().Unix(),
+		"nonce": nonce, "email": "alice@example.com", "email_verified": true, "name": "Alice",
+	})
+	tok.Header["kid"] = "k1"
+	idToken, _ := tok.SignedString(idp.key)
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "at-1", "token_type": "Bearer", "id_token": idToken})
+}
+
+func (idp *fakeIdP) oidc() Provider {
+	return NewOIDC(OIDCConfig{
+		Name:     "fake",
+		Issuers:  []string{idp.URL},
+		Endpoint: Endpoint{AuthURL: idp.URL + "/authorize", TokenURL: idp.URL + "/token"},
+		JWKSURL:  idp.URL + "/jwks",
+		Client:   Client{ClientID: "client-1", ClientSecret: "s3cret", RedirectURL: "http://app/cb"},
+	})
+}
+
+func (idp *fakeIdP) github() Provider {
+	p := GitHub(Client{ClientID: "client-1", ClientSecret: "s3cret", RedirectURL: "http://app/cb"}).(*githubProvider)
+	p.endpoint = Endpoint{AuthURL: idp.URL + "/authorize", TokenURL: idp.URL + "/token"}
+	p.apiURL = idp.URL
+	return p
+}
+
+// newFlow 挂着 Flow 的路由，OnLogin 把身份写进响应
+func newFlow(t *testing.T, providers ...Provider) (*Flow, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	f, err := New(Config{
+		Providers: providers,
+		Secret:    secret,
+		OnLogin:   func(c *gin.Context, id Identity) { c.JSON(http.StatusOK, id) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	Register(r.Group("/auth/oauth"), f)
+	return f, r
+}
+
+func get(r http.Handler, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// begin 访问登录入口，返回授权页地址和 state Cookie
+func begin(t *testing.T, r http.Handler, provider string) (string, *http.Cookie) {
+	t.Helper()
+	w := get(r, "/auth/oauth/"+provider+"/login")
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status = %d", w.Code)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("cookies = %+v; want one HttpOnly SameSite=Lax cookie", cookies)
+	}
+	return w.Header().Get("Location"), cookies[0]
+}
+
+func TestOIDCFlow(t *testing.T) {
+	idp := newFakeIdP(t)
+	_, r := newFlow(t, idp.oidc())
+
+	authURL, cookie := begin(t, r, "fake")
+	if !strings.HasPrefix(authURL, idp.URL+"/authorize?") || !strings.Contains(authURL, "nonce=") {
+		t.Fatalf("auth url = %s", authURL)
+	}
+	code, state := idp.authorize(t, authURL)
+
+	w := get(r, "/auth/oauth/fake/callback?code="+code+"&state="+state, cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback = %d %s", w.Code, w.Body.String())
+	}
+	var id Identity
+	json.Unmarshal(w.Body.Bytes(), &id)
+	want := Identity{Provider: "fake", Subject: "u-100", Email: "alice@example.com", EmailVerified: true, Name: "Alice"}
+	if id != want {
+		t.Errorf("identity = %+v; want %+v", id, want)
+	}
+	// 回调之后 Cookie 被删除
+	if c := w.Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 {
+		t.Errorf("cookie not cleared: %+v", c)
+	}
+}
+
+func TestGitHubFlow(t *testing.T) {
+	idp := newFakeIdP(t)
+	_, r := newFlow(t, idp.github())
+
+	authURL, cookie := begin(t, r, "github")
+	if strings.Contains(authURL, "nonce=") || !strings.Contains(authURL, "scope=read%3Auser+user%3Aemail") {
+		t.Fatalf("auth url = %s", authURL)
+	}
+	code, state := idp.authorize(t, authURL)
+	w := get(r, "/auth/oauth/github/callback?code="+code+"&state="+state, cookie)
+	var id Identity
+	json.Unmarshal(w.Body.Bytes(), &id)
+	// 用主邮箱而不是公开邮箱，name 为空时用 login
+	want := Identity{Provider: "github", Subject: "42", Email: "octo@example.com", EmailVerified: true,
+		Name: "octocat", AvatarURL: "https://a/x.png"}
+	if w.Code != http.StatusOK || id != want {
+		t.Errorf("callback = %d, identity = %+v; want %+v", w.Code, id, want)
+	}
+}
+
+func TestCallbackRejects(t *testing.T) {
+	idp := newFakeIdP(t)
+	f, r := newFlow(t, idp.oidc())
+
+	tests := []struct {
+		name  string
+		setup func() (path string, cookies []*http.Cookie)
+		want  int
+	}{
+		{"unknown provider", func() (string, []*http.Cookie) {
+			return "/auth/oauth/nope/callback", nil
+		}, http.StatusNotFound},
+		{"user denied", func() (string, []*http.Cookie) {
+			_, c := begin(t, r, "fake")
+			return "/auth/oauth/fake/callback?error=access_denied", []*http.Cookie{c}
+		}, http.StatusForbidden},
+		{"no cookie", func() (string, []*http.Cookie) {
+			code, state := idp.authorize(t, must(begin(t, r, "fake")))
+			return "/auth/oauth/fake/callback?code=" + code + "&state=" + state, nil
+		}, http.StatusBadRequest},
+		{"state mismatch (login CSRF)", func() (string, []*http.Cookie) {
+			// 攻击者的 code 和 state，配上受害者浏览器里的 Cookie
+			code, state := idp.authorize(t, must(begin(t, r, "fake")))
+			_, victim := begin(t, r, "fake")
+			return "/auth/oauth/fake/callback?code=" + code + "&state=" + state, []*http.Cookie{victim}
+		}, http.StatusBadRequest},
+		{"tampered cookie", func() (string, []*http.Cookie) {
+			authURL, c := begin(t, r, "fake")
+			code, state := idp.authorize(t, authURL)
+			c.Value = strings.Replace(c.Value, ".", "x.", 1)
+			return "/auth/oauth/fake/callback?code=" + code + "&state=" + state, []*http.Cookie{c}
+		}, http.StatusBadRequest},
+		{"expired", func() (string, []*http.Cookie) {
+			authURL, c := begin(t, r, "fake")
+			code, state := idp.authorize(t, authURL)
+			f.now = func() time.Time { return time.Now().Add(11 * time.Minute) }
+			return "/auth/oauth/fake/callback?code=" + code + "&state=" + state, []*http.Cookie{c}
+		}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.now = time.Now
+			path, cookies := tt.setup()
+			if w := get(r, path, cookies...); w.Code != tt.want {
+				t.Errorf("status = %d; want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func must(authURL string, _ *http.Cookie) string { return authURL }
+
+func TestIDTokenChecks(t *testing.T) {
+	idp := newFakeIdP(t)
+	p := idp.oidc()
+	ctx := context.Background()
+
+	// 拿到 code 之后用错误的 verifier 换 Token（code 被截获）
+	code, _ := idp.authorize(t, p.AuthCodeURL("state-123456", "n1", Challenge("right")))
+	if _, err := p.Exchange(ctx, code, "wrong", "n1"); err == nil {
+		t.Error("exchange with wrong code_verifier succeeded")
+	}
+
+	idp.badNonce = true
+	code, _ = idp.authorize(t, p.AuthCodeURL("state-abcdefg", "n2", Challenge("v")))
+	if _, err := p.Exchange(ctx, code, "v", "n2"); !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("err = %v; want ErrInvalidNonce", err)
+	}
+
+	// audience 不是本应用
+	other := NewOIDC(OIDCConfig{
+		Name: "fake", Issuers: []string{idp.URL}, JWKSURL: idp.URL + "/jwks",
+		Endpoint: Endpoint{AuthURL: idp.URL + "/authorize", TokenURL: idp.URL + "/token"},
+		Client:   Client{ClientID: "someone-else", ClientSecret: "s3cret"},
+	})
+	idp.badNonce = false
+	code, _ = idp.authorize(t, other.AuthCodeURL("state-zzzzzzz", "n3", Challenge("v")))
+	if _, err := other.Exchange(ctx, code, "v", "n3"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("err = %v; want ErrInvalidIDToken", err)
+	}
+}
+
+// ============================================================================
+// 创建或关联
+// ============================================================================
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.Open(t, &Link{})
+}
+
+type fakeUsers struct {
+	byEmail map[string]uint
+	created []Identity
+}
+
+func (u *fakeUsers) FindByEmail(_ context.Context, email string) (uint, bool, error) {
+	id, ok := u.byEmail[strings.ToLower(email)]
+	return id, ok, nil
+}
+
+func (u *fakeUsers) Create(_ context.Context, id Identity) (uint, error) {
+	u.created = append(u.created, id)
+	n := uint(100 + len(u.created))
+	u.byEmail[strings.ToLower(id.Email)] = n
+	return n, nil
+}
+
+func TestResolve(t *testing.T) {
+	links := NewLinks(newTestDB(t))
+	users := &fakeUsers{byEmail: map[string]uint{"alice@example.com": 1}}
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		id          Identity
+		wantUser    uint
+		wantCreated bool
+		wantErr     error
+	}{
+		{"verified email links existing user",
+			Identity{Provider: "google", Subject: "g1", Email: "Alice@example.com", EmailVerified: true}, 1, false, nil},
+		{"linked identity logs in even after email change",
+			Identity{Provider: "google", Subject: "g1", Email: "new@example.com"}, 1, false, nil},
+		{"unverified email cannot take over",
+			Identity{Provider: "github", Subject: "h1", Email: "alice@example.com"}, 0, false, ErrEmailNotVerified},
+		{"new email creates user",
+			Identity{Provider: "github", Subject: "h2", Email: "bob@example.com"}, 101, true, nil},
+		{"no email",
+			Identity{Provider: "github", Subject: "h3"}, 0, false, ErrNoEmail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uid, created, err := links.Resolve(ctx, tt.id, users)
+			if !errors.Is(err, tt.wantErr) || uid != tt.wantUser || created != tt.wantCreated {
+				t.Errorf("Resolve = %d, %v, %v; want %d, %v, %v", uid, created, err, tt.wantUser, tt.wantCreated, tt.wantErr)
+			}
+		})
+	}
+
+	list, _ := links.List(ctx, 1)
+	if len(list) != 1 || list[0].Provider != "google" {
+		t.Errorf("links of user 1 = %+v", list)
+	}
+	// 同一个第三方账号不能再关联给别人，重复关联给自己不报错
+	g1 := Identity{Provider: "google", Subject: "g1"}
+	if err := links.Link(ctx, 2, g1); !errors.Is(err, ErrAlreadyLinked) {
+		t.Errorf("err = %v; want ErrAlreadyLinked", err)
+	}
+	if err := links.Link(ctx, 1, g1); err != nil {
+		t.Errorf("relink to same user: %v", err)
+	}
+}
+
+func TestNewValidates(t *testing.T) {
+	if _, err := New(Config{Secret: []byte("short"), OnLogin: func(*gin.Context, Identity) {}}); err == nil {
+		t.Error("short secret accepted")
+	}
+	if _, err := New(Config{Secret: secret}); err == nil {
+		t.Error("missing OnLogin accepted")
+	}
+}
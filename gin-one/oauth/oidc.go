@@ -0,0 +1,231 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCConfig OpenID Connect 提供方配置
+type OIDCConfig struct {
+	Name     string
+	Issuers  []string // ID Token 的 iss 必须是其中之一
+	Endpoint Endpoint
+	JWKSURL  string // 签名公钥（JWK Set）地址
+	Client   Client
+
+	// HTTPClient 调用提供方接口，默认 Client.HTTPClient，都没有时 10 秒超时
+	HTTPClient *http.Client
+}
+
+// oidcProvider 从 ID Token 取身份，不需要再调用 userinfo 接口
+type oidcProvider struct {
+	cfg  OIDCConfig
+	hc   *http.Client
+	keys *keySet
+}
+
+// NewOIDC 通用 OIDC 提供方，Scopes 为空时使用 openid email profile
+func NewOIDC(cfg OIDCConfig) Provider {
+	if len(cfg.Client.Scopes) == 0 {
+		cfg.Client.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = cfg.Client.HTTPClient
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = defaultHTTPClient()
+	}
+	return &oidcProvider{cfg: cfg, hc: cfg.HTTPClient, keys: &keySet{url: cfg.JWKSURL, hc: cfg.HTTPClient}}
+}
+
+// Google 用 Google 账号登录（OIDC），在 Google Cloud Console → API 和服务 → 凭据 中创建 OAuth 客户端
+func Google(c Client) Provider {
+	return NewOIDC(OIDCConfig{
+		Name:    "google",
+		Issuers: []string{"https://accounts.google.com", "accounts.google.com"},
+		Endpoint: Endpoint{
+			AuthURL:  "https://accounts.google.com/o/oauth2/v2/auth",
+			TokenURL: "https://oauth2.googleapis.com/token",
+		},
+		JWKSURL: "https://www.googleapis.com/oauth2/v3/certs",
+		Client:  c,
+	})
+}
+
+func (p *oidcProvider) Name() string { return p.cfg.Name }
+
+func (p *oidcProvider) AuthCodeURL(state, nonce, challenge string) string {
+	return authCodeURL(p.cfg.Endpoint, p.cfg.Client, state, challenge, url.Values{"nonce": {nonce}})
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code, verifier, nonce string) (Identity, error) {
+	tok, err := exchange(ctx, p.hc, p.cfg.Endpoint, p.cfg.Client, code, verifier)
+	if err != nil {
+		return Identity{}, err
+	}
+	if tok.IDToken == "" {
+		return Identity{}, fmt.Errorf("%w: missing id_token (scope must include openid)", ErrInvalidIDToken)
+	}
+	claims, err := p.verify(ctx, tok.IDToken, nonce)
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{
+		Provider:      p.cfg.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.verified(),
+		Name:          claims.Name,
+		AvatarURL:     claims.Picture,
+	}, nil
+}
+
+// idClaims ID Token 中用到的声明
+type idClaims struct {
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified any    `json:"email_verified"` // 规范是 bool，部分提供方返回字符串 "true"
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+	jwt.RegisteredClaims
+}
+
+func (c *idClaims) verified() bool {
+	switch v := c.EmailVerified.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// verify 校验签名（RS256，按 kid 取公钥）、aud、iss、exp 和 nonce
+func (p *oidcProvider) verify(ctx context.Context, raw, nonce string) (*idClaims, error) {
+	claims := &idClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.keys.get(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithAudience(p.cfg.Client.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if !slices.Contains(p.cfg.Issuers, claims.Issuer) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, claims.Issuer)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, ErrInvalidNonce
+	}
+	return claims, nil
+}
+
+// ============================================================================
+// JWK Set
+// ============================================================================
+
+// keySet 缓存提供方的签名公钥
+//
+// 提供方定期轮换密钥，新的 kid 先出现在 JWKS 里再开始用来签名，
+// 所以遇到不认识的 kid 时重新拉取一次；为了不被伪造的 kid 刷接口，两次拉取至少间隔 1 分钟。
+type keySet struct {
+	url string
+	hc  *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+func (s *keySet) get(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if k, ok := s.keys[kid]; ok {
+		return k, nil
+	}
+	if time.Since(s.fetched) < time.Minute {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	keys, err := s.fetch(ctx)
+	s.fetched = time.Now()
+	if err != nil {
+		return nil, err
+	}
+	s.keys = keys
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// jwk RFC 7517 中 RSA 公钥用到的字段
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (s *keySet) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	status, err := doJSON(s.hc, req, &set)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: status %d", status)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		pub, err := rsaKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+func rsaKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exp := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid modulus or exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
@@ -0,0 +1,300 @@
+package openapi
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 契约测试
+// ============================================================================
+//
+// 路由和文档在 Register 里一起写，但 handler 实际返回什么文档管不到：
+// 响应结构体加了字段却没改 Op.Responses 里的类型、404 分支返回了没声明的结构、
+// 有人绕过 Register 直接 v1.GET(...)。契约测试在 CI 里把这些对一遍：
+//
+//	1. CheckRoutes   路由表里的每个路由都有文档
+//	2. Samples       用文档里的 example 构造每个接口的请求
+//	3. CheckResponse 响应的状态码在文档里声明过，响应体符合 Schema，没有文档之外的字段
+//
+// cmd 包的 contract-test 命令按这个顺序执行，有问题时退出码为 1：
+//
+//	go run examples/5_2_swagger.go contract-test
+//
+// 已有的接口测试里，把 Verify 挂在路由上，每个响应顺便检查一遍：
+//
+//	r.Use(doc.Verify(func(p openapi.Problem) { t.Error(p) }))
+
+// Problem 契约测试发现的一处文档和实现不一致
+type Problem struct {
+	Method  string
+	Path    string // Gin 路径，如 /users/:id
+	Status  int    // 响应的状态码，检查路由时为 0
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Status == 0 {
+		return fmt.Sprintf("%s %s: %s", p.Method, p.Path, p.Message)
+	}
+	return fmt.Sprintf("%s %s %d: %s", p.Method, p.Path, p.Status, p.Message)
+}
+
+// operationLocked 按 Gin 路径找文档里的接口，调用方持有锁
+func (b *Builder) operationLocked(method, ginPath string) *Operation {
+	oasPath, _ := convertPath(ginPath)
+	item := b.doc.Paths[oasPath]
+	if item == nil {
+		return nil
+	}
+	if slot := item.slot(method); slot != nil {
+		return *slot
+	}
+	return nil
+}
+
+// CheckRoutes 找出没有文档的路由；ignore 是不需要文档的路由，
+// 支持 path.Match 通配符，如 /openapi.json、/debug/*
+func (b *Builder) CheckRoutes(routes gin.RoutesInfo, ignore ...string) []Problem {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out []Problem
+	for _, rt := range routes {
+		if slices.ContainsFunc(ignore, func(p string) bool { ok, _ := path.Match(p, rt.Path); return ok }) {
+			continue
+		}
+		if b.operationLocked(rt.Method, rt.Path) == nil {
+			out = append(out, Problem{Method: rt.Method, Path: rt.Path, Message: "route is not documented"})
+		}
+	}
+	slices.SortFunc(out, func(a, b Problem) int {
+		return cmp.Or(strings.Compare(a.Path, b.Path), strings.Compare(a.Method, b.Method))
+	})
+	return out
+}
+
+// CheckResponse 按文档检查一个响应：状态码要声明过，JSON 响应体按 Schema 严格校验（文档之外的字段也算问题）
+//
+// ginPath 是注册时的路径（c.FullPath()），不是请求的 URL
+func (b *Builder) CheckResponse(method, ginPath string, status int, contentType string, body []byte) []Problem {
+	problem := func(format string, args ...any) Problem {
+		return Problem{Method: method, Path: ginPath, Status: status, Message: fmt.Sprintf(format, args...)}
+	}
+
+	b.mu.Lock()
+	op := b.operationLocked(method, ginPath)
+	b.mu.Unlock()
+	if op == nil {
+		return []Problem{problem("route is not documented")}
+	}
+	resp := op.Responses[strconv.Itoa(status)]
+	if resp == nil {
+		resp = op.Responses["default"]
+	}
+	if resp == nil {
+		return []Problem{problem("status is not documented")}
+	}
+	media, ok := resp.Content["application/json"]
+	if !ok || media.Schema == nil {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return []Problem{problem("documented without a body, got %d bytes", len(body))}
+		}
+		return nil
+	}
+	if mt, _, _ := mime.ParseMediaType(contentType); mt != "application/json" {
+		return []Problem{problem("Content-Type is %q, want application/json", contentType)}
+	}
+
+	violations, err := b.ValidateJSON(media.Schema, body, true)
+	if err != nil {
+		return []Problem{problem("invalid JSON body: %v", err)}
+	}
+	out := make([]Problem, len(violations))
+	for i, v := range violations {
+		out[i] = problem("%s", v)
+	}
+	return out
+}
+
+// Verify 检查经过的每个响应，问题交给 report；没有匹配到路由的请求（404）不检查
+//
+// 要缓存整个响应体，只在测试里使用
+func (b *Builder) Verify(report func(Problem)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		if c.FullPath() == "" {
+			return
+		}
+		for _, p := range b.CheckResponse(c.Request.Method, c.FullPath(), w.Status(), w.Header().Get("Content-Type"), w.body.Bytes()) {
+			report(p)
+		}
+	}
+}
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Sample 按文档里的 example 构造的请求
+type Sample struct {
+	Method string
+	Path   string // Gin 路径，和 CheckResponse 的参数相同
+	Target string // 填好路径参数和查询参数的 URL
+	Body   []byte // JSON 请求体，接口没有请求体时为 nil
+	Auth   bool   // 接口需要 Authorization: Bearer
+}
+
+// methodOrder 同一路径上先查询、再创建修改、最后删除，删除不会影响前面的请求
+var methodOrder = []string{
+	http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// Samples 每个文档接口一个请求，按路径排序，同一路径按 GET、POST、PUT、PATCH、DELETE 的顺序
+//
+// 参数和请求体的字段取 example 标签；没有 example 时按类型和约束生成一个合法的值
+// （email 格式、min 长度、enum 的第一个值），所以 example 写得越全，请求越接近真实场景
+func (b *Builder) Samples() []Sample {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g := &sampler{defs: b.schemas.defs}
+
+	var out []Sample
+	for _, oasPath := range slices.Sorted(maps.Keys(b.doc.Paths)) {
+		item := b.doc.Paths[oasPath]
+		for _, method := range methodOrder {
+			op := *item.slot(method)
+			if op == nil {
+				continue
+			}
+			s := Sample{Method: method, Path: ginPath(oasPath), Auth: len(op.Security) > 0}
+			target, query := oasPath, url.Values{}
+			for _, p := range op.Parameters {
+				v := fmt.Sprint(g.value(p.Schema, 0))
+				switch p.In {
+				case "path":
+					target = strings.ReplaceAll(target, "{"+p.Name+"}", url.PathEscape(v))
+				case "query":
+					if p.Required || p.Schema.Example != nil {
+						query.Set(p.Name, v)
+					}
+				}
+			}
+			if len(query) > 0 {
+				target += "?" + query.Encode()
+			}
+			s.Target = target
+			if op.RequestBody != nil {
+				s.Body, _ = json.Marshal(g.value(op.RequestBody.Content["application/json"].Schema, 0))
+			}
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ginPath /users/{id} → /users/:id；通配参数在文档里和普通参数一样，还原不出 *path，这里不区分
+func ginPath(oasPath string) string {
+	segs := strings.Split(oasPath, "/")
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			segs[i] = ":" + seg[1:len(seg)-1]
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+type sampler struct {
+	defs map[string]*Schema
+}
+
+// maxDepth 自引用的类型（评论的 replies）展开几层后停止
+const maxDepth = 4
+
+func (g *sampler) value(s *Schema, depth int) any {
+	if s == nil || depth > maxDepth {
+		return nil
+	}
+	if s.Example != nil {
+		return s.Example
+	}
+	if s.Ref != "" {
+		return g.value(g.defs[strings.TrimPrefix(s.Ref, refPrefix)], depth+1)
+	}
+	if len(s.AllOf) > 0 {
+		return g.value(s.AllOf[0], depth)
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+	switch s.Type {
+	case "object":
+		obj := map[string]any{}
+		for name, prop := range s.Properties {
+			if v := g.value(prop, depth+1); v != nil {
+				obj[name] = v
+			}
+		}
+		return obj
+	case "array":
+		items := []any{}
+		if s.MinItems != nil {
+			for range *s.MinItems {
+				items = append(items, g.value(s.Items, depth+1))
+			}
+		}
+		return items
+	case "string":
+		switch s.Format {
+		case "email":
+			return "user@example.com"
+		case "date-time":
+			return "2024-01-01T00:00:00Z"
+		case "uri":
+			return "https://example.com"
+		case "uuid":
+			return "00000000-0000-4000-8000-000000000000"
+		}
+		v := "string"
+		if s.MinLength != nil && *s.MinLength > len(v) {
+			v = strings.Repeat("x", *s.MinLength)
+		}
+		return v
+	case "integer", "number":
+		n := 1.0 // ID 类的参数从 1 开始
+		if s.Minimum != nil && (n < *s.Minimum || s.ExclusiveMinimum && n == *s.Minimum) {
+			n = *s.Minimum + 1
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			n = *s.Maximum
+		}
+		return n
+	case "boolean":
+		return true
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+// ============================================================================
+// Package openapi 运行时生成 OpenAPI 3.0 文档，不需要 swag init
+// ============================================================================
+//
+// 【和 swag 注释的区别】
+//
+// | 对比项     | swag 注释                           | openapi 包                              |
+// |------------|-------------------------------------|-----------------------------------------|
+// | 接口信息   | 写在 handler 上方的 @Router 等注释  | 注册路由时传 Op                         |
+// | 数据模型   | swag 解析源码，字段注释就是描述     | 反射结构体，描述写在 doc 标签里          |
+// | 校验规则   | 需要再写一遍（minLength 等）         | 直接读 binding / validate 标签          |
+// | 路由路径   | @Router 手写，可能和代码不一致       | 注册路由和写文档是同一次调用            |
+// | 生成步骤   | 每次改完都要 swag init              | 启动时生成，没有生成的文件              |
+//
+// 【用法】
+//
+//	doc := openapi.New(openapi.Config{Title: "Demo API", Version: "1.0", BearerAuth: true})
+//	v1 := r.Group("/api/v1")
+//	doc.Register(v1, http.MethodGet, "/users/:id", openapi.Op{
+//		Summary:   "获取用户",
+//		Tags:      []string{"用户"},
+//		Responses: map[int]any{200: User{}, 404: ErrorResponse{}},
+//		Auth:      true,
+//	}, GetUser)
+//	r.GET("/openapi.json", doc.Handler())
+//	r.GET("/docs", openapi.UI("/openapi.json"))
+//
+// 【从标签到 Schema】
+//
+// | 标签                              | Schema                              |
+// |-----------------------------------|-------------------------------------|
+// | json:"name,omitempty"             | 属性名，"-" 表示跳过                |
+// | binding:"required"                | required                            |
+// | binding:"min=3,max=50"            | minLength / maxLength（字符串）      |
+// | binding:"gte=0,lte=150"           | minimum / maximum                   |
+// | binding:"email"                   | format: email                       |
+// | binding:"oneof=active banned"     | enum                                |
+// | uri:"id" / form:"page"            | 路径参数 / 查询参数的名字           |
+// | doc:"用户名"                      | description                         |
+// | example:"alice"                   | example，按字段类型解析             |
+//
+// 具名结构体放在 components/schemas 里通过 $ref 引用，泛型 Response[User]
+// 变成 Response_User（Response[[]User] 是 Response_List_User），每种 data 类型都有准确的文档。
+//
+// 【契约测试】
+//
+// 文档生成之后反过来检查实现（contract.go）：路由是否都有文档，
+// 响应的状态码和 JSON 是否符合声明的 Schema（validate.go），见 cmd 包的 contract-test 命令。
+//
+// 【请求校验】
+//
+// ValidateRequests 中间件用同一份 Schema 检查进来的请求（request.go）：路径参数、查询参数、
+// JSON 请求体不符合文档时返回 400 和逐项的 violations，Strict 模式下未知字段也拒绝。
+//
+// ============================================================================
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Config 文档信息
+type Config struct {
+	Title       string // 默认 "API"
+	Version     string // 默认 "1.0"
+	Description string
+
+	// Servers 接口地址，为空时 Swagger UI 使用文档所在的地址
+	Servers []string
+
+	// BearerAuth 声明 Bearer token 认证，Op.Auth 为 true 的接口需要它
+	BearerAuth bool
+}
+
+// Op 一个接口的文档
+type Op struct {
+	Summary     string
+	Description string
+	Tags        []string
+	ID          string // operationId，生成客户端 SDK 时作为方法名
+
+	// Path 带 uri 标签的结构体；为 nil 时路径里的参数都按必填的字符串处理
+	Path any
+	// Query 带 form 标签的结构体，和 c.ShouldBindQuery 用同一个类型
+	Query any
+	// Body JSON 请求体，和 c.ShouldBindJSON 用同一个类型
+	Body any
+
+	// Responses 状态码 → 响应体的零值，nil 表示没有响应体
+	Responses map[int]any
+
+	Auth       bool // 需要 Authorization: Bearer
+	Deprecated bool
+
+	// SkipValidation ValidateRequests 不检查这个接口，如文件上传、透传原始请求体
+	SkipValidation bool
+}
+
+// Router 能注册路由并知道自己路径前缀的路由器，*gin.Engine 和 *gin.RouterGroup 都满足
+type Router interface {
+	gin.IRoutes
+	BasePath() string
+}
+
+// Builder 收集接口和模型，生成 OpenAPI 文档，可以并发使用
+type Builder struct {
+	mu      sync.Mutex
+	doc     Document
+	schemas *schemas
+	cached  []byte
+}
+
+const bearerScheme = "BearerAuth"
+
+// New 创建文档
+func New(cfg Config) *Builder {
+	if cfg.Title == "" {
+		cfg.Title = "API"
+	}
+	if cfg.Version == "" {
+		cfg.Version = "1.0"
+	}
+	b := &Builder{
+		doc: Document{
+			OpenAPI: "3.0.3",
+			Info:    Info{Title: cfg.Title, Version: cfg.Version, Description: cfg.Description},
+			Paths:   map[string]*PathItem{},
+		},
+		schemas: newSchemas(),
+	}
+	for _, u := range cfg.Servers {
+		b.doc.Servers = append(b.doc.Servers, Server{URL: u})
+	}
+	if cfg.BearerAuth {
+		b.doc.Components.SecuritySchemes = map[string]SecurityScheme{
+			bearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		}
+	}
+	b.doc.Components.Schemas = b.schemas.defs
+	return b
+}
+
+// Register 在 r 上注册路由，同时把接口写进文档；路由路径和文档路径来自同一个参数，不会不一致
+func (b *Builder) Register(r Router, method, relativePath string, op Op, handlers ...gin.HandlerFunc) {
+	r.Handle(method, relativePath, handlers...)
+	b.Add(method, joinPaths(r.BasePath(), relativePath), op)
+}
+
+// Add 只写文档不注册路由，用于不是 Gin 处理的接口（如反向代理、静态文件）
+//
+// p 使用 Gin 的路径格式：/users/:id、/files/*path
+func (b *Builder) Add(method, p string, op Op) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cached = nil
+
+	oasPath, params := convertPath(p)
+	item := b.doc.Paths[oasPath]
+	if item == nil {
+		item = &PathItem{}
+		b.doc.Paths[oasPath] = item
+	}
+	if slot := item.slot(method); slot != nil {
+		*slot = b.operation(op, params)
+	}
+}
+
+func (b *Builder) operation(op Op, pathParams []string) *Operation {
+	o := &Operation{
+		Tags:        op.Tags,
+		Summary:     op.Summary,
+		Description: op.Description,
+		OperationID: op.ID,
+		Deprecated:  op.Deprecated,
+		Responses:   map[string]*Response{},
+
+		skipValidation: op.SkipValidation,
+	}
+
+	declared := map[string]bool{}
+	for _, p := range b.params(op.Path, "uri", "path") {
+		p.Required = true // 路径参数总是必填
+		declared[p.Name] = true
+		o.Parameters = append(o.Parameters, p)
+	}
+	for _, name := range pathParams {
+		if !declared[name] {
+			o.Parameters = append(o.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	o.Parameters = append(o.Parameters, b.params(op.Query, "form", "query")...)
+
+	if op.Body != nil {
+		o.RequestBody = &RequestBody{Required: true, Content: jsonContent(b.schemas.of(op.Body))}
+	}
+
+	for code, v := range op.Responses {
+		resp := &Response{Description: http.StatusText(code)}
+		if v != nil {
+			resp.Content = jsonContent(b.schemas.of(v))
+		}
+		o.Responses[strconv.Itoa(code)] = resp
+	}
+	if len(o.Responses) == 0 {
+		// responses 是必填字段
+		o.Responses["default"] = &Response{Description: "OK"}
+	}
+
+	if op.Auth {
+		o.Security = []map[string][]string{{bearerScheme: {}}}
+	}
+	return o
+}
+
+// params 把结构体字段转成参数，名字取 tag 标签（uri / form），没有标签的字段跳过
+func (b *Builder) params(v any, tag, in string) []Parameter {
+	if v == nil {
+		return nil
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var out []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		schema, required := b.schemas.field(f)
+		desc := schema.Description
+		schema.Description = "" // 描述放在参数上
+		out = append(out, Parameter{
+			Name:        name,
+			In:          in,
+			Description: desc,
+			Required:    required,
+			Schema:      schema,
+		})
+	}
+	return out
+}
+
+func jsonContent(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+func (p *PathItem) slot(method string) **Operation {
+	switch strings.ToUpper(method) {
+	case http.MethodGet:
+		return &p.Get
+	case http.MethodPut:
+		return &p.Put
+	case http.MethodPost:
+		return &p.Post
+	case http.MethodDelete:
+		return &p.Delete
+	case http.MethodOptions:
+		return &p.Options
+	case http.MethodHead:
+		return &p.Head
+	case http.MethodPatch:
+		return &p.Patch
+	}
+	return nil
+}
+
+// convertPath Gin 路径转成 OpenAPI 路径：/users/:id → /users/{id}，/files/*path → /files/{path}
+func convertPath(p string) (string, []string) {
+	segs := strings.Split(p, "/")
+	var params []string
+	for i, seg := range segs {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') {
+			params = append(params, seg[1:])
+			segs[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
+
+// joinPaths 和 Gin 拼接分组路径的规则一致：保留末尾的 /
+func joinPaths(base, rel string) string {
+	if rel == "" {
+		return base
+	}
+	joined := path.Join(base, rel)
+	if strings.HasSuffix(rel, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+	return joined
+}
+
+// JSON 返回文档的 JSON，结果会缓存到下次 Add
+func (b *Builder) JSON() ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cached == nil {
+		data, err := json.Marshal(&b.doc)
+		if err != nil {
+			return nil, err
+		}
+		b.cached = data
+	}
+	return b.cached, nil
+}
+
+// Handler 返回文档 JSON 的 handler，挂在 /openapi.json
+func (b *Builder) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := b.JSON()
+		if err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+	}
+}
+
+//go:embed ui.html
+var uiFS embed.FS
+
+var uiTemplate = template.Must(template.ParseFS(uiFS, "ui.html"))
+
+// UI 返回 Swagger UI 页面，specURL 是 Handler 挂载的地址
+//
+// 页面本身嵌入在二进制里；swagger-ui 的 JS 和 CSS 从 CDN 加载（固定版本），
+// 内网环境可以把 swagger-ui-dist 下载到本地，用 r.Static 提供并修改 ui.html 里的地址。
+func UI(specURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		_ = uiTemplate.Execute(c.Writer, map[string]string{"SpecURL": specURL})
+	}
+}
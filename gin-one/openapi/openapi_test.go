@@ -0,0 +1,480 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type user struct {
+	ID        uint      `json:"id" example:"1"`
+	Username  string    `json:"username" binding:"required,min=3,max=50" doc:"用户名" example:"alice"`
+	Email     string    `json:"email,omitempty" binding:"omitempty,email"`
+	Age       int       `json:"age" binding:"gte=0,lte=150"`
+	Status    string    `json:"status" binding:"oneof=active banned"`
+	Tags      []string  `json:"tags" binding:"max=5,dive,min=2"`
+	Score     *float64  `json:"score"`
+	CreatedAt time.Time `json:"created_at"`
+	Password  string    `json:"-"`
+	secret    string
+}
+
+type base struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type post struct {
+	base
+	Title   string            `json:"title"`
+	Author  user              `json:"author"`
+	Replies []post            `json:"replies"`
+	Meta    map[string]int    `json:"meta"`
+	Extra   any               `json:"extra"`
+	Raw     json.RawMessage   `json:"raw"`
+	Data    []byte            `json:"data"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+type envelope[T any] struct {
+	Code int `json:"code"`
+	Data T   `json:"data"`
+}
+
+func TestSchemaFromTags(t *testing.T) {
+	s := newSchemas()
+	ref := s.of(user{})
+	if ref.Ref != "#/components/schemas/user" {
+		t.Fatalf("ref = %q", ref.Ref)
+	}
+	obj := s.defs["user"]
+	if !reflect.DeepEqual(obj.Required, []string{"username"}) {
+		t.Errorf("required = %v; want [username]", obj.Required)
+	}
+	for _, name := range []string{"Password", "secret", "-"} {
+		if _, ok := obj.Properties[name]; ok {
+			t.Errorf("property %q should be skipped", name)
+		}
+	}
+
+	tests := []struct {
+		field string
+		check func(*Schema) bool
+	}{
+		{"id", func(p *Schema) bool { return p.Type == "integer" && *p.Minimum == 0 && p.Example == int64(1) }},
+		{"username", func(p *Schema) bool {
+			return p.Type == "string" && *p.MinLength == 3 && *p.MaxLength == 50 && p.Description == "用户名" && p.Example == "alice"
+		}},
+		{"email", func(p *Schema) bool { return p.Format == "email" }},
+		{"age", func(p *Schema) bool { return *p.Minimum == 0 && *p.Maximum == 150 && p.Format == "int64" }},
+		{"status", func(p *Schema) bool { return reflect.DeepEqual(p.Enum, []any{"active", "banned"}) }},
+		{"tags", func(p *Schema) bool { return p.Type == "array" && *p.MaxItems == 5 && *p.Items.MinLength == 2 }},
+		{"score", func(p *Schema) bool { return p.Type == "number" && p.Format == "double" }},
+		{"created_at", func(p *Schema) bool { return p.Type == "string" && p.Format == "date-time" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			p := obj.Properties[tt.field]
+			if p == nil || !tt.check(p) {
+				b, _ := json.Marshal(p)
+				t.Errorf("%s = %s", tt.field, b)
+			}
+		})
+	}
+}
+
+func TestSchemaNesting(t *testing.T) {
+	s := newSchemas()
+	s.of(post{})
+	obj := s.defs["post"]
+
+	// 匿名嵌入的字段展开到外层
+	if obj.Properties["id"] == nil || obj.Properties["created_at"] == nil || obj.Properties["base"] != nil {
+		t.Errorf("embedded fields not flattened: %v", keys(obj.Properties))
+	}
+	// 自引用通过 $ref，不会无限递归
+	if got := obj.Properties["replies"].Items.Ref; got != "#/components/schemas/post" {
+		t.Errorf("replies.items.$ref = %q", got)
+	}
+	if got := obj.Properties["author"].Ref; got != "#/components/schemas/user" || s.defs["user"] == nil {
+		t.Errorf("author.$ref = %q", got)
+	}
+	if got := obj.Properties["meta"].AdditionalProperties.Type; got != "integer" {
+		t.Errorf("meta.additionalProperties.type = %q", got)
+	}
+	if p := obj.Properties["extra"]; p.Type != "" {
+		t.Errorf("extra.type = %q; want any", p.Type)
+	}
+	if p := obj.Properties["raw"]; p.Type != "" {
+		t.Errorf("raw.type = %q; want any", p.Type)
+	}
+	if p := obj.Properties["data"]; p.Type != "string" || p.Format != "byte" {
+		t.Errorf("data = %s/%s; want string/byte", p.Type, p.Format)
+	}
+}
+
+func TestSchemaGenericName(t *testing.T) {
+	s := newSchemas()
+	ref := s.of(envelope[[]user]{})
+	if ref.Ref != "#/components/schemas/envelope_List_user" {
+		t.Fatalf("ref = %q", ref.Ref)
+	}
+	data := s.defs["envelope_List_user"].Properties["data"]
+	if data.Type != "array" || data.Items.Ref != "#/components/schemas/user" {
+		t.Errorf("data = %+v", data)
+	}
+	// 不同类型得到不同的名字
+	if ref := s.of(envelope[user]{}); ref.Ref != "#/components/schemas/envelope_user" {
+		t.Errorf("envelope[user] ref = %q", ref.Ref)
+	}
+	if ref := s.of(envelope[any]{}); ref.Ref != "#/components/schemas/envelope_any" {
+		t.Errorf("envelope[any] ref = %q", ref.Ref)
+	}
+	if len(s.defs) != 4 {
+		t.Errorf("defs = %v", keys(s.defs))
+	}
+}
+
+func TestConvertPath(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string
+		params []string
+	}{
+		{"/users", "/users", nil},
+		{"/users/:id", "/users/{id}", []string{"id"}},
+		{"/users/:id/posts/:post_id", "/users/{id}/posts/{post_id}", []string{"id", "post_id"}},
+		{"/files/*path", "/files/{path}", []string{"path"}},
+	}
+	for _, tt := range tests {
+		got, params := convertPath(tt.in)
+		if got != tt.want || !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("convertPath(%q) = %q, %v; want %q, %v", tt.in, got, params, tt.want, tt.params)
+		}
+	}
+}
+
+type listQuery struct {
+	Page    int    `form:"page" binding:"omitempty,min=1" doc:"页码"`
+	Keyword string `form:"keyword"`
+	Ignored string
+}
+
+type idPath struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+func TestRegister(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	doc := New(Config{Title: "Test", BearerAuth: true})
+	v1 := r.Group("/api/v1")
+
+	doc.Register(v1, http.MethodGet, "/users", Op{
+		Summary:   "list",
+		Query:     listQuery{},
+		Responses: map[int]any{200: envelope[[]user]{}},
+	}, func(c *gin.Context) { c.String(200, "list") })
+	doc.Register(v1, http.MethodGet, "/users/:id", Op{
+		Path:      idPath{},
+		Responses: map[int]any{200: user{}, 404: nil},
+		Auth:      true,
+	}, func(c *gin.Context) { c.String(200, c.Param("id")) })
+	doc.Register(v1, http.MethodPost, "/users/:id/posts/:slug", Op{Body: post{}}, func(c *gin.Context) {})
+
+	// 路由照常注册
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/users/7", nil))
+	if w.Body.String() != "7" {
+		t.Errorf("GET /api/v1/users/7 = %q", w.Body.String())
+	}
+
+	r.GET("/openapi.json", doc.Handler())
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != 200 || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("GET /openapi.json = %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	var got Document
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.OpenAPI != "3.0.3" || got.Info.Title != "Test" || got.Info.Version != "1.0" {
+		t.Errorf("info = %s %+v", got.OpenAPI, got.Info)
+	}
+	if _, ok := got.Components.SecuritySchemes[bearerScheme]; !ok {
+		t.Error("missing bearer security scheme")
+	}
+
+	list := got.Paths["/api/v1/users"].Get
+	if list == nil || len(list.Parameters) != 2 {
+		t.Fatalf("list op = %+v", list)
+	}
+	if p := list.Parameters[0]; p.Name != "page" || p.In != "query" || p.Required || p.Description != "页码" || *p.Schema.Minimum != 1 {
+		t.Errorf("page param = %+v", p)
+	}
+	if ref := list.Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/envelope_List_user" {
+		t.Errorf("list 200 schema = %q", ref)
+	}
+
+	get := got.Paths["/api/v1/users/{id}"].Get
+	if get == nil || len(get.Parameters) != 1 || get.Parameters[0].In != "path" || get.Parameters[0].Schema.Type != "integer" {
+		t.Fatalf("get op = %+v", get)
+	}
+	if len(get.Security) != 1 || list.Security != nil {
+		t.Errorf("security = %v / %v", get.Security, list.Security)
+	}
+	if r := get.Responses["404"]; r == nil || r.Description != "Not Found" || r.Content != nil {
+		t.Errorf("404 = %+v", r)
+	}
+
+	// 没有 Path 结构体时路径参数按字符串处理
+	create := got.Paths["/api/v1/users/{id}/posts/{slug}"].Post
+	if create == nil || len(create.Parameters) != 2 || create.Parameters[1].Name != "slug" || create.Parameters[1].Schema.Type != "string" {
+		t.Fatalf("create op = %+v", create)
+	}
+	if create.RequestBody == nil || create.Responses["default"] == nil {
+		t.Errorf("create op body/responses = %+v / %v", create.RequestBody, create.Responses)
+	}
+	for _, name := range []string{"post", "user", "envelope_List_user"} {
+		if got.Components.Schemas[name] == nil {
+			t.Errorf("missing component %q", name)
+		}
+	}
+}
+
+func TestJSONCache(t *testing.T) {
+	doc := New(Config{})
+	doc.Add(http.MethodGet, "/a", Op{})
+	first, _ := doc.JSON()
+	again, _ := doc.JSON()
+	if &first[0] != &again[0] {
+		t.Error("JSON not cached")
+	}
+	doc.Add(http.MethodGet, "/b", Op{})
+	after, _ := doc.JSON()
+	if !strings.Contains(string(after), `"/b"`) {
+		t.Errorf("cache not invalidated: %s", after)
+	}
+}
+
+func TestUI(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/docs", nil)
+	UI("/api/openapi.json")(c)
+
+	if w.Code != 200 || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("UI = %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if body := w.Body.String(); !strings.Contains(body, `url: "/api/openapi.json"`) {
+		t.Errorf("spec url not in page:\n%s", body)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	doc := New(Config{})
+	ref := doc.schemas.of(envelope[[]user]{})
+
+	ok := `{"code": 0, "data": [{"id": 1, "username": "alice", "email": "a@example.com", "age": 30, "status": "active",
+		"tags": ["go"], "score": null, "created_at": "2026-10-15T08:00:00Z"}]}`
+	if v, err := doc.ValidateJSON(ref, []byte(ok), true); err != nil || len(v) != 0 {
+		t.Fatalf("valid body: %v, %v", v, err)
+	}
+
+	bad := `{"code": 1.5, "extra": true, "data": [{"id": -1, "username": "al", "email": "nope", "age": 200, "status": "gone",
+		"tags": ["go", "x", "ab", "cd", "ef", "gh"], "created_at": "yesterday"}, {"email": ""}]}`
+	v, err := doc.ValidateJSON(ref, []byte(bad), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, x := range v {
+		got = append(got, x.Field+" "+x.Rule)
+	}
+	want := []string{
+		"code type",
+		"data[0].age maximum", "data[0].created_at format", "data[0].email format", "data[0].id minimum",
+		"data[0].status enum", "data[0].tags maxItems", "data[0].tags[1] minLength", "data[0].username minLength",
+		"data[1].username required", // 空 email 不检查格式
+		"extra unknown",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("violations =\n%v\nwant\n%v", got, want)
+	}
+
+	// 非严格模式不管未声明的字段，map 的值按 additionalProperties 校验
+	if v := doc.Validate(ref, map[string]any{"code": 0.0, "extra": 1.0}, false); len(v) != 0 {
+		t.Errorf("non-strict = %v", v)
+	}
+	meta := doc.schemas.of(map[string]int{})
+	if v := doc.Validate(meta, map[string]any{"a": "x"}, true); len(v) != 1 || v[0].Field != "a" || v[0].Rule != "type" {
+		t.Errorf("map = %v", v)
+	}
+}
+
+func TestContract(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	doc := New(Config{})
+	var problems []string
+	r.Use(doc.Verify(func(p Problem) { problems = append(problems, p.String()) }))
+
+	doc.Register(r, http.MethodGet, "/users/:id", Op{
+		Path:      idPath{},
+		Query:     listQuery{},
+		Responses: map[int]any{200: user{}, 404: nil},
+	}, func(c *gin.Context) {
+		switch c.Param("id") {
+		case "1":
+			c.JSON(http.StatusOK, user{ID: 1, Username: "alice", Status: "active"})
+		case "2":
+			c.JSON(http.StatusOK, gin.H{"id": 2, "username": "bob", "status": "active", "nickname": "b"})
+		case "3":
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "boom"})
+		default:
+			c.Status(http.StatusNotFound)
+		}
+	})
+	doc.Register(r, http.MethodPost, "/users", Op{Body: user{}, Responses: map[int]any{201: user{}}}, func(c *gin.Context) {
+		var u user
+		if err := c.ShouldBindJSON(&u); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.JSON(http.StatusCreated, u)
+	})
+	doc.Add(http.MethodGet, "/proxy/*path", Op{})
+	r.GET("/health", func(c *gin.Context) {})
+	r.GET("/debug/vars", func(c *gin.Context) {})
+
+	if got := doc.CheckRoutes(r.Routes(), "/debug/*"); len(got) != 1 || got[0].String() != "GET /health: route is not documented" {
+		t.Errorf("CheckRoutes = %v", got)
+	}
+
+	// example 和约束生成的请求能通过 binding 校验
+	samples := doc.Samples()
+	if len(samples) != 3 {
+		t.Fatalf("samples = %+v", samples)
+	}
+	if s := samples[2]; s.Path != "/users/:id" || s.Target != "/users/1" || s.Body != nil {
+		t.Errorf("get sample = %+v", s)
+	}
+	post := samples[1]
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(post.Method, post.Target, strings.NewReader(string(post.Body)))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("POST %s = %d %s", post.Body, w.Code, w.Body)
+	}
+
+	for _, id := range []string{"1", "2", "3", "4"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/"+id, nil))
+	}
+	// 多出来的字段、没声明的状态码；404 声明了没有响应体
+	if len(problems) != 2 || problems[0] != "GET /users/:id 200: nickname: is not a documented field" ||
+		problems[1] != "GET /users/:id 500: status is not documented" {
+		t.Errorf("problems = %q", problems)
+	}
+}
+
+type tagsQuery struct {
+	IDs []int `form:"ids" binding:"max=3"`
+}
+
+func TestValidateRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	doc := New(Config{})
+	r.Use(doc.ValidateRequests(ValidationConfig{Strict: true}))
+
+	ok := func(c *gin.Context) {
+		var u user
+		if c.Request.ContentLength > 0 && c.ShouldBindJSON(&u) != nil {
+			c.Status(http.StatusTeapot) // 校验过的请求体 handler 仍能读到
+			return
+		}
+		c.String(http.StatusOK, u.Username)
+	}
+	doc.Register(r, http.MethodGet, "/users/:id", Op{Path: idPath{}, Query: listQuery{}}, ok)
+	doc.Register(r, http.MethodGet, "/tags", Op{Query: tagsQuery{}}, ok)
+	doc.Register(r, http.MethodPost, "/users", Op{Body: user{}, Responses: map[int]any{200: nil, 400: ValidationError{}}}, ok)
+	doc.Register(r, http.MethodPost, "/import", Op{Body: user{}, SkipValidation: true}, ok)
+	r.POST("/raw", ok)
+
+	tests := []struct {
+		method, target, body string
+		status               int
+		fields               []string
+	}{
+		{"GET", "/users/1?page=2", "", 200, nil},
+		{"GET", "/users/x?page=0", "", 400, []string{"path.id:type", "query.page:minimum"}},
+		{"GET", "/users/1?page=1.5&page=x", "", 400, []string{"query.page:type"}},
+		{"GET", "/tags?ids=1&ids=2", "", 200, nil},
+		{"GET", "/tags?ids=1&ids=a&ids=3&ids=4", "", 400, []string{"query.ids:maxItems", "query.ids[1]:type"}},
+		{"POST", "/users", `{"username":"alice","status":"active"}`, 200, nil},
+		{"POST", "/users", `{"username":"al","email":"x","status":"gone","nickname":"a"}`, 400,
+			[]string{"body.email:format", "body.nickname:unknown", "body.status:enum", "body.username:minLength"}},
+		{"POST", "/users", ``, 400, []string{"body:required"}},
+		{"POST", "/users", `{`, 400, nil},
+		{"POST", "/users", `[]`, 400, []string{"body:type"}},
+		{"POST", "/import", `{"username":"alice","status":"active","nickname":"a"}`, 200, nil},
+		{"POST", "/raw", `{"username":"alice","status":"active","nickname":"a"}`, 200, nil},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s %s %s = %d %s", tt.method, tt.target, tt.body, w.Code, w.Body)
+			continue
+		}
+		if tt.fields == nil {
+			continue
+		}
+		var resp struct {
+			Error string `json:"error"`
+			Data  struct {
+				Violations []Violation `json:"violations"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		var got []string
+		for _, v := range resp.Data.Violations {
+			got = append(got, v.Field+":"+v.Rule)
+		}
+		if resp.Error != "validation_failed" || !reflect.DeepEqual(got, tt.fields) {
+			t.Errorf("%s %s %s: violations = %v, want %v", tt.method, tt.target, tt.body, got, tt.fields)
+		}
+		// 400 写进文档后，校验失败的响应也符合文档
+		if tt.target == "/users" {
+			if p := doc.CheckResponse(tt.method, "/users", w.Code, w.Header().Get("Content-Type"), w.Body.Bytes()); len(p) > 0 {
+				t.Errorf("%s: %v", tt.body, p)
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("username=alice"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("form body = %d", w.Code)
+	}
+}
+
+func keys[V any](m map[string]V) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
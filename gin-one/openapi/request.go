@@ -0,0 +1,177 @@
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// ============================================================================
+// 请求校验
+// ============================================================================
+//
+// handler 里的 ShouldBind 只认识 binding 标签，文档里写了的 enum、format、
+// 未知字段管不到；反过来，文档写错了也没人发现。ValidateRequests 在请求到达
+// handler 之前按文档检查一遍，和 handler 看到的是同一份 Schema：
+//
+//	v1.Use(doc.ValidateRequests(openapi.ValidationConfig{Strict: true}))
+//
+// 不符合时返回 400，violations 列出每一处问题，字段带上位置前缀：
+//
+//	{"code": -1, "message": "2 个参数校验失败", "error": "validation_failed",
+//	 "data": {"violations": [
+//	   {"field": "query.page", "rule": "type", "message": "must be an integer"},
+//	   {"field": "body.email", "rule": "format", "message": "must be a valid email"}]}}
+//
+// 没有文档的路由直接放行；文件上传、透传原始请求体等不适合校验的接口
+// 注册时设置 Op.SkipValidation。请求体读出来校验后会放回去，handler 照常绑定。
+
+// ValidationConfig 请求校验中间件的配置
+type ValidationConfig struct {
+	// Strict 请求体里出现文档没有的字段时返回 400，拼错的字段名不会被静默忽略
+	Strict bool
+}
+
+// ValidationError ValidateRequests 返回的 400 响应，和 response 包的格式一致；
+// 写进 Op.Responses 的 400，文档里就有 violations 的结构
+type ValidationError struct {
+	Code    int               `json:"code" example:"-1"`
+	Message string            `json:"message" example:"1 个参数校验失败"`
+	Error   string            `json:"error,omitempty" example:"validation_failed"`
+	Data    *ValidationDetail `json:"data,omitempty"`
+}
+
+// ValidationDetail 校验失败的明细
+type ValidationDetail struct {
+	Violations []Violation `json:"violations"`
+}
+
+// ValidateRequests 按文档校验路径参数、查询参数和 JSON 请求体
+//
+// 要在 Register 之前挂到路由组上（Gin 的中间件只作用于之后注册的路由）
+func (b *Builder) ValidateRequests(cfg ValidationConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		b.mu.Lock()
+		op := b.operationLocked(c.Request.Method, c.FullPath())
+		b.mu.Unlock()
+		if op == nil || op.skipValidation {
+			c.Next()
+			return
+		}
+
+		var violations []Violation
+		for _, p := range op.Parameters {
+			violations = append(violations, b.checkParam(c, p)...)
+		}
+		if op.RequestBody != nil {
+			body, err := c.GetRawData()
+			if err != nil {
+				response.Abort(c, http.StatusBadRequest, "malformed_request", "请求格式错误")
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+			switch mt, _, _ := mime.ParseMediaType(c.ContentType()); {
+			case len(bytes.TrimSpace(body)) == 0:
+				if op.RequestBody.Required {
+					violations = append(violations, Violation{Field: "body", Rule: "required", Message: "is required"})
+				}
+			case mt != "" && mt != "application/json":
+				response.Abort(c, http.StatusUnsupportedMediaType, "unsupported_media_type", "请求体只支持 application/json")
+				return
+			default:
+				found, err := b.ValidateJSON(op.RequestBody.Content["application/json"].Schema, body, cfg.Strict)
+				if err != nil {
+					response.Abort(c, http.StatusBadRequest, "malformed_request", "请求格式错误")
+					return
+				}
+				for _, v := range found {
+					v.Field = within("body", v.Field)
+					violations = append(violations, v)
+				}
+			}
+		}
+
+		if len(violations) > 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ValidationError{
+				Code:    response.CodeError,
+				Message: response.Localize(c, "validation_failed", fmt.Sprintf("%d 个参数校验失败", len(violations)), "count", len(violations)),
+				Error:   "validation_failed",
+				Data:    &ValidationDetail{Violations: violations},
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// checkParam 把路径 / 查询参数的字符串按 Schema 的类型转换后校验，字段名为 path.id、query.page
+func (b *Builder) checkParam(c *gin.Context, p Parameter) []Violation {
+	field := p.In + "." + p.Name
+	var raw []string
+	switch p.In {
+	case "path":
+		raw = []string{c.Param(p.Name)}
+	case "query":
+		raw = c.QueryArray(p.Name)
+	default:
+		return nil
+	}
+	if len(raw) == 0 || len(raw) == 1 && raw[0] == "" {
+		if p.Required {
+			return []Violation{{Field: field, Rule: "required", Message: "is required"}}
+		}
+		return nil
+	}
+
+	var v any
+	if p.Schema != nil && p.Schema.Type == "array" {
+		items := make([]any, len(raw))
+		for i, s := range raw {
+			items[i] = coerce(p.Schema.Items, s)
+		}
+		v = items
+	} else {
+		v = coerce(p.Schema, raw[0]) // 和 c.Query 一样，重复的参数取第一个
+	}
+
+	found := b.Validate(p.Schema, v, false)
+	for i := range found {
+		found[i].Field = within(field, found[i].Field)
+	}
+	return found
+}
+
+// within 给 Validate 返回的字段加上位置前缀：根值 → body，[0] → body[0]，email → body.email
+func within(loc, field string) string {
+	if field == "" || field[0] == '[' {
+		return loc + field
+	}
+	return loc + "." + field
+}
+
+// coerce 参数都是字符串，按 Schema 的类型转换成 JSON 解码会得到的值；
+// 转换不了时原样返回字符串，交给 Validate 报告类型错误
+func coerce(s *Schema, raw string) any {
+	if s == nil {
+		return raw
+	}
+	switch s.Type {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(raw); err == nil {
+			return v
+		}
+	}
+	return raw
+}
@@ -0,0 +1,302 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// schemas 按 Go 类型生成 Schema，具名结构体放进 components，返回 $ref
+type schemas struct {
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{defs: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+// of 返回 v 的类型对应的 Schema，v 为 nil 时返回 nil
+func (s *schemas) of(v any) *Schema {
+	if v == nil {
+		return nil
+	}
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *schemas) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// 自定义 JSON 编码的类型无法从字段推断，按 time.Time、字符串或任意值处理
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Minimum: ptr(0.0)}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Minimum: ptr(0.0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json 把 []byte 编码成 base64 字符串
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.define(t)}
+	}
+	// interface、func、chan 等：任意值
+	return &Schema{}
+}
+
+// define 把具名结构体放进 components，返回名字
+//
+// 先占住名字再生成字段，自引用的类型（如树形评论 Replies []Comment）不会无限递归
+func (s *schemas) define(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := s.uniqueName(t)
+	s.names[t] = name
+	s.defs[name] = &Schema{}
+	*s.defs[name] = *s.object(t)
+	return name
+}
+
+// uniqueName 泛型 Page[go-one/model.User] 变成 Page_User，Page[[]User] 变成 Page_List_User；不同包的同名类型加包名区分
+func (s *schemas) uniqueName(t reflect.Type) string {
+	name := typeName(t.Name())
+	if _, taken := s.defs[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	name = typeName(pkg[strings.LastIndex(pkg, "/")+1:] + "_" + t.Name())
+	for i, base := 2, name; ; i++ {
+		if _, taken := s.defs[name]; !taken {
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
+
+// qualified 类型名里带包路径的部分，如 go-one/model.User、main.User
+var qualified = regexp.MustCompile(`[\w\-./]*[./](\w+)`)
+
+func typeName(name string) string {
+	name = strings.ReplaceAll(name, "interface {}", "any")
+	name = qualified.ReplaceAllString(name, "$1")
+	name = strings.ReplaceAll(name, "[]", "List_") // Page[[]User] 和 Page[User] 不重名
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	return strings.Join(words, "_")
+}
+
+// object 按 json 标签生成对象的属性，匿名嵌入的结构体字段展开到外层（和 encoding/json 一致）
+func (s *schemas) object(t reflect.Type) *Schema {
+	obj := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.fields(t, obj)
+	return obj
+}
+
+func (s *schemas) fields(t reflect.Type, obj *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				s.fields(ft, obj)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop, required := s.field(f)
+		obj.Properties[name] = prop
+		if required {
+			obj.Required = append(obj.Required, name)
+		}
+	}
+}
+
+// field 字段的 Schema 加上 binding / validate 标签里的约束，以及 doc、example 标签
+func (s *schemas) field(f reflect.StructField) (*Schema, bool) {
+	prop := s.schema(f.Type)
+	required := applyRules(prop, f.Type, rules(f.Tag))
+
+	if doc := f.Tag.Get("doc"); doc != "" || f.Tag.Get("example") != "" {
+		if prop.Ref != "" {
+			// OpenAPI 3.0 中 $ref 旁边的其他字段会被忽略，用 allOf 包一层
+			prop = &Schema{AllOf: []*Schema{prop}}
+		}
+		prop.Description = doc
+		if ex, ok := f.Tag.Lookup("example"); ok {
+			prop.Example = example(f.Type, ex)
+		}
+	}
+	return prop, required
+}
+
+// rules gin 用 binding 标签，直接用 validator 的代码用 validate 标签
+func rules(tag reflect.StructTag) string {
+	if r, ok := tag.Lookup("binding"); ok {
+		return r
+	}
+	return tag.Get("validate")
+}
+
+// applyRules 把 validator 规则转成 Schema 约束，返回字段是否必填
+//
+//	required          → required
+//	min / max / len   → 字符串 minLength/maxLength，数组 minItems/maxItems，数字 minimum/maximum
+//	gte / lte / gt / lt → minimum/maximum（gt、lt 不含边界）
+//	email / url / uuid → format
+//	oneof=a b c       → enum
+//	dive 之后的规则作用于数组元素
+func applyRules(prop *Schema, t reflect.Type, rule string) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	required := false
+	tokens := strings.Split(rule, ",")
+	for i, tok := range tokens {
+		key, val, _ := strings.Cut(strings.TrimSpace(tok), "=")
+		switch key {
+		case "required":
+			required = true
+		case "dive":
+			if prop.Items != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+				applyRules(prop.Items, t.Elem(), strings.Join(tokens[i+1:], ","))
+			}
+			return required
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				continue
+			}
+			lo, hi := key != "max", key != "min"
+			switch t.Kind() {
+			case reflect.String:
+				setLimits(&prop.MinLength, &prop.MaxLength, int(n), lo, hi)
+			case reflect.Slice, reflect.Array, reflect.Map:
+				setLimits(&prop.MinItems, &prop.MaxItems, int(n), lo, hi)
+			default:
+				setLimits(&prop.Minimum, &prop.Maximum, n, lo, hi)
+			}
+		case "gte", "gt":
+			if n, err := strconv.ParseFloat(val, 64); err == nil {
+				prop.Minimum, prop.ExclusiveMinimum = &n, key == "gt"
+			}
+		case "lte", "lt":
+			if n, err := strconv.ParseFloat(val, 64); err == nil {
+				prop.Maximum, prop.ExclusiveMaximum = &n, key == "lt"
+			}
+		case "email":
+			prop.Format = "email"
+		case "url", "uri":
+			prop.Format = "uri"
+		case "uuid", "uuid4":
+			prop.Format = "uuid"
+		case "oneof":
+			for _, v := range strings.Fields(val) {
+				prop.Enum = append(prop.Enum, example(t, v))
+			}
+		}
+	}
+	return required
+}
+
+func setLimits[T any](min, max **T, n T, lo, hi bool) {
+	if lo {
+		*min = &n
+	}
+	if hi {
+		*max = &n
+	}
+}
+
+// example 按字段类型解析标签里的示例值，解析失败时原样作为字符串
+func example(t reflect.Type, v string) any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() != reflect.Uint8 {
+			var out []any
+			for _, item := range strings.Split(v, ",") {
+				out = append(out, example(t.Elem(), strings.TrimSpace(item)))
+			}
+			return out
+		}
+	}
+	return v
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,222 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ============================================================================
+// Schema 校验
+// ============================================================================
+//
+// 文档里的 Schema 由 Go 类型反射生成，这里反过来检查一个 JSON 值是否符合它：
+// 契约测试检查响应，请求校验中间件检查请求体。只支持生成器会产出的那部分关键字。
+//
+// null 总是通过类型检查：生成器不标 nullable，而 encoding/json 会把
+// nil 指针、nil 切片、nil map 编码成 null，这不算和文档不一致。
+
+// Violation 值不符合 Schema 的一处
+type Violation struct {
+	Field   string `json:"field"`   // 出错的位置，如 data.users[0].email；根值为空
+	Rule    string `json:"rule"`    // 违反的关键字：type、required、enum、minLength、unknown ...
+	Message string `json:"message"` // 给人看的说明
+}
+
+func (v Violation) String() string {
+	if v.Field == "" {
+		return v.Message
+	}
+	return v.Field + ": " + v.Message
+}
+
+// ValidateJSON 解析 data 并按 s 校验；strict 为 true 时 Schema 里没有的字段也算违规
+func (b *Builder) ValidateJSON(s *Schema, data []byte, strict bool) ([]Violation, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return b.Validate(s, v, strict), nil
+}
+
+// Validate 按 s 校验 json.Unmarshal 得到的值（map[string]any、[]any、float64 ...）
+func (b *Builder) Validate(s *Schema, v any, strict bool) []Violation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := &checker{defs: b.schemas.defs, strict: strict}
+	c.check(s, v, "")
+	return c.out
+}
+
+type checker struct {
+	defs   map[string]*Schema
+	strict bool
+	out    []Violation
+}
+
+func (c *checker) fail(field, rule, format string, args ...any) {
+	c.out = append(c.out, Violation{Field: field, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+const refPrefix = "#/components/schemas/"
+
+func (c *checker) check(s *Schema, v any, field string) {
+	if s == nil || v == nil {
+		return
+	}
+	if s.Ref != "" {
+		c.check(c.defs[strings.TrimPrefix(s.Ref, refPrefix)], v, field)
+		return
+	}
+	for _, sub := range s.AllOf {
+		c.check(sub, v, field)
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return equalJSON(e, v) }) {
+		c.fail(field, "enum", "must be one of %v", s.Enum)
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			c.fail(field, "type", "must be an object")
+			return
+		}
+		c.object(s, obj, field)
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			c.fail(field, "type", "must be an array")
+			return
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			c.fail(field, "minItems", "must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(arr) > *s.MaxItems {
+			c.fail(field, "maxItems", "must have at most %d items", *s.MaxItems)
+		}
+		for i, item := range arr {
+			c.check(s.Items, item, field+"["+strconv.Itoa(i)+"]")
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			c.fail(field, "type", "must be a string")
+			return
+		}
+		c.string(s, str, field)
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok || (s.Type == "integer" && n != math.Trunc(n)) {
+			c.fail(field, "type", "must be %s %s", article(s.Type), s.Type)
+			return
+		}
+		c.number(s, n, field)
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			c.fail(field, "type", "must be a boolean")
+		}
+	}
+}
+
+func (c *checker) object(s *Schema, obj map[string]any, field string) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			c.fail(join(field, name), "required", "is required")
+		}
+	}
+	// 按字段名排序，违规的顺序固定，测试和 golden 文件才稳定
+	for _, name := range slices.Sorted(maps.Keys(obj)) {
+		if prop, ok := s.Properties[name]; ok {
+			c.check(prop, obj[name], join(field, name))
+			continue
+		}
+		switch {
+		case s.AdditionalProperties != nil:
+			c.check(s.AdditionalProperties, obj[name], join(field, name))
+		case c.strict && s.Properties != nil:
+			c.fail(join(field, name), "unknown", "is not a documented field")
+		}
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func (c *checker) string(s *Schema, str, field string) {
+	n := utf8.RuneCountInString(str) // binding 的 min / max 也按字符数计算
+	if s.MinLength != nil && n < *s.MinLength {
+		c.fail(field, "minLength", "must be at least %d characters", *s.MinLength)
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		c.fail(field, "maxLength", "must be at most %d characters", *s.MaxLength)
+	}
+	if str == "" {
+		return // 空字符串不检查格式，和 binding:"omitempty,email" 一致
+	}
+	var ok bool
+	switch s.Format {
+	case "email":
+		_, err := mail.ParseAddress(str)
+		ok = err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339Nano, str)
+		ok = err == nil
+	case "uri":
+		u, err := url.Parse(str)
+		ok = err == nil && u.Scheme != ""
+	case "uuid":
+		ok = uuidPattern.MatchString(str)
+	default:
+		return
+	}
+	if !ok {
+		c.fail(field, "format", "must be a valid %s", s.Format)
+	}
+}
+
+func (c *checker) number(s *Schema, n float64, field string) {
+	if s.Minimum != nil && (n < *s.Minimum || s.ExclusiveMinimum && n == *s.Minimum) {
+		c.fail(field, "minimum", "must be %s %v", cmpWord(">", s.ExclusiveMinimum), *s.Minimum)
+	}
+	if s.Maximum != nil && (n > *s.Maximum || s.ExclusiveMaximum && n == *s.Maximum) {
+		c.fail(field, "maximum", "must be %s %v", cmpWord("<", s.ExclusiveMaximum), *s.Maximum)
+	}
+}
+
+func cmpWord(op string, exclusive bool) string {
+	if exclusive {
+		return op
+	}
+	return op + "="
+}
+
+func article(typ string) string {
+	if typ == "integer" {
+		return "an"
+	}
+	return "a"
+}
+
+// join 拼接字段路径：data + email → data.email
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+// equalJSON enum 里是 Go 值（int64、string），v 是 JSON 解码的值（float64、string）
+func equalJSON(a, b any) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
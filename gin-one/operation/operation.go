@@ -0,0 +1,225 @@
+// ============================================================================
+// Package operation 长时间运行操作（Long-Running Operation, LRO）
+// ============================================================================
+//
+// 【什么时候用 LRO？】
+//
+// 生成 PDF、导出报表、转码视频这类耗时任务不适合在请求里同步完成。
+// LRO 模式的做法是：
+//
+//	POST /payments            → 200 {"operation": "op_xxx"}   立即返回
+//	GET  /operations/op_xxx   → {"done": false, "state": "running"}
+//	GET  /operations/op_xxx   → {"done": true,  "response": {...}}
+//
+// 【重试】
+//
+// 任务函数返回错误时按指数退避重试，直到 MaxAttempts 次。
+// 参数错误这类重试也没用的错误，用 Permanent 包装后会立即失败。
+//
+// 【限制】
+//
+// 操作状态只保存在内存中，进程重启后丢失；需要持久化时应换成任务队列。
+//
+// ============================================================================
+package operation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// State 操作状态
+type State string
+
+const (
+	StatePending   State = "pending"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+)
+
+// Operation 操作快照
+type Operation struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	State     State     `json:"state"`
+	Done      bool      `json:"done"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error,omitempty"`
+	Response  any       `json:"response,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Func 任务函数，返回值会放进 Operation.Response
+type Func func(ctx context.Context) (any, error)
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	MaxAttempts    int           // 最多执行次数（含第一次），默认 3
+	InitialBackoff time.Duration // 第一次重试前等待时间，默认 1s，之后每次翻倍
+	MaxBackoff     time.Duration // 等待时间上限，默认 30s
+}
+
+// permanentError 不需要重试的错误
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent 标记错误为不可重试
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// Manager 管理所有操作
+type Manager struct {
+	mu     sync.RWMutex
+	ops    map[string]*Operation
+	policy RetryPolicy
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager 创建操作管理器
+func NewManager(policy RetryPolicy) *Manager {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = time.Second
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 30 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		ops:    make(map[string]*Operation),
+		policy: policy,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start 在后台启动一个操作，立即返回其快照
+func (m *Manager) Start(name string, fn Func) Operation {
+	now := time.Now()
+	op := &Operation{
+		ID:        newID(),
+		Name:      name,
+		State:     StatePending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	m.mu.Lock()
+	m.ops[op.ID] = op
+	snapshot := *op
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.run(op, fn)
+	}()
+	return snapshot
+}
+
+func (m *Manager) run(op *Operation, fn Func) {
+	backoff := m.policy.InitialBackoff
+	for {
+		m.update(op, func() {
+			op.State = StateRunning
+			op.Attempts++
+		})
+
+		resp, err := fn(m.ctx)
+		if err == nil {
+			m.update(op, func() {
+				op.State, op.Done, op.Error, op.Response = StateSucceeded, true, "", resp
+			})
+			return
+		}
+
+		var perm permanentError
+		if errors.As(err, &perm) || op.Attempts >= m.policy.MaxAttempts || m.ctx.Err() != nil {
+			m.update(op, func() {
+				op.State, op.Done, op.Error = StateFailed, true, err.Error()
+			})
+			return
+		}
+
+		// 记录最近一次错误，便于轮询时看到失败原因
+		m.update(op, func() {
+			op.State, op.Error = StatePending, err.Error()
+		})
+
+		select {
+		case <-time.After(backoff):
+		case <-m.ctx.Done():
+		}
+		backoff = min(backoff*2, m.policy.MaxBackoff)
+	}
+}
+
+func (m *Manager) update(op *Operation, fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn()
+	op.UpdatedAt = time.Now()
+}
+
+// Get 查询操作快照
+func (m *Manager) Get(id string) (Operation, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	op, ok := m.ops[id]
+	if !ok {
+		return Operation{}, false
+	}
+	return *op, true
+}
+
+// Shutdown 取消所有正在等待重试的操作，并等待运行中的任务返回
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.cancel()
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Handler 查询操作状态的接口，路由参数名为 id：
+//
+//	r.GET("/operations/:id", ops.Handler())
+func (m *Manager) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op, ok := m.Get(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "操作不存在"})
+			return
+		}
+		c.JSON(http.StatusOK, op)
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "op_" + hex.EncodeToString(b)
+}
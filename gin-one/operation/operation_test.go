@@ -0,0 +1,85 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// wait 轮询直到操作结束
+func wait(t *testing.T, m *Manager, id string) Operation {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if op, _ := m.Get(id); op.Done {
+			return op
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("operation %s did not finish", id)
+	return Operation{}
+}
+
+func TestRetryThenSucceed(t *testing.T) {
+	m := NewManager(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	calls := 0
+	op := m.Start("flaky", func(ctx context.Context) (any, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("temporary")
+		}
+		return "ok", nil
+	})
+
+	got := wait(t, m, op.ID)
+	if got.State != StateSucceeded || got.Attempts != 3 || got.Response != "ok" || got.Error != "" {
+		t.Errorf("got state=%s attempts=%d response=%v error=%q; want succeeded, 3, ok, \"\"",
+			got.State, got.Attempts, got.Response, got.Error)
+	}
+}
+
+func TestFailure(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantAttempts int
+	}{
+		{"exhausted", errors.New("boom"), 2},
+		{"permanent", Permanent(errors.New("bad input")), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+			op := m.Start(tt.name, func(ctx context.Context) (any, error) { return nil, tt.err })
+
+			got := wait(t, m, op.ID)
+			if got.State != StateFailed || got.Attempts != tt.wantAttempts || got.Error != tt.err.Error() {
+				t.Errorf("got state=%s attempts=%d error=%q; want failed, %d, %q",
+					got.State, got.Attempts, got.Error, tt.wantAttempts, tt.err.Error())
+			}
+		})
+	}
+}
+
+func TestShutdownStopsRetrying(t *testing.T) {
+	m := NewManager(RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour})
+	op := m.Start("slow", func(ctx context.Context) (any, error) { return nil, errors.New("temporary") })
+
+	// 等第一次执行结束，进入退避等待
+	for {
+		if got, _ := m.Get(op.ID); got.Attempts == 1 && got.State == StatePending {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m.Get(op.ID); got.State != StateFailed {
+		t.Errorf("state after shutdown = %s; want failed", got.State)
+	}
+}
@@ -0,0 +1,123 @@
+// ============================================================================
+// Package optlock 乐观锁：UPDATE 带上读到的版本号，被别人抢先修改时返回 409
+// ============================================================================
+//
+// 【为什么】
+//
+// 两个管理员同时打开同一个用户的编辑页，A 改了邮箱、B 改了状态，后保存的人
+// 用自己页面上的旧数据覆盖了前一个人的修改，双方都不知道。悲观锁（SELECT ... FOR UPDATE）
+// 要在用户编辑的几分钟里一直持有事务，不现实；乐观锁只在写入那一刻检查：
+//
+//	UPDATE users SET email=?, version=version+1, updated_at=?
+//	 WHERE id=? AND version=?            -- version 是读取时的值
+//
+// 影响 0 行说明读取之后有人改过（或者记录已被删除），由调用方决定重试还是提示用户。
+//
+// 【约定】
+//
+// 模型有一个 Version uint 字段，对应 version 列，新记录从 1 开始：
+//
+//	Version uint `gorm:"not null;default:1" json:"version" audit:"-"`
+//
+// 客户端 GET 时拿到 version，PATCH 时原样带回；服务端比较后才写入。
+//
+// 【重试】
+//
+// | 场景                           | 做法                                           |
+// |--------------------------------|------------------------------------------------|
+// | 用户在页面上编辑（HTTP PATCH） | 返回 409，客户端重新 GET，提示用户合并后再提交 |
+// | 后台任务的读-改-写             | 重新读取、重新计算，有限次数内自动重试         |
+// | 只改单列的计数器               | 不需要乐观锁，用 SET n = n + 1                 |
+//
+// ============================================================================
+package optlock
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// ErrConflict 版本冲突，errors.Is(err, ErrConflict) 对 *ConflictError 成立
+var ErrConflict = errors.New("optlock: version conflict")
+
+// Column 版本号的列名，对应模型的 Version 字段
+const Column = "version"
+
+// ConflictError 版本冲突，带上期望的和数据库里当前的版本号，方便客户端判断要不要重新读取
+type ConflictError struct {
+	Expected uint
+	Current  uint
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("optlock: version conflict: expected %d, current %d", e.Expected, e.Current)
+}
+
+// Is 让 errors.Is(err, ErrConflict) 成立
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+// Check 比较客户端带回的版本号和刚读到的记录，expected 为 0 表示客户端没有带版本号，不检查
+//
+// Update 只能保证"读取到写入之间"没人修改；客户端页面上的数据是更早读到的，
+// 要先用 Check 比较一次，再用 Update 防住读取之后的并发写入。
+func Check(row any, expected uint) error {
+	if cur := uint(version(row).Uint()); expected != 0 && expected != cur {
+		return &ConflictError{Expected: expected, Current: cur}
+	}
+	return nil
+}
+
+// Update 用 row 当前的 Version 做条件更新 fields（列名 → 新值），并把版本号加 1
+//
+// row 是指向模型的指针，主键和 Version 必须已经读出；成功后 row.Version 同步为新版本号。
+// 被抢先修改时返回 *ConflictError，记录已不存在（包括软删除）时返回 gorm.ErrRecordNotFound；
+// 出错时 row 的其他字段可能已被 GORM 赋上 fields 的值，要重新读取，不要再用它。
+func Update(tx *gorm.DB, row any, fields map[string]any) error {
+	v := version(row)
+	expected := uint(v.Uint())
+
+	updates := maps.Clone(fields)
+	if updates == nil {
+		updates = make(map[string]any, 1)
+	}
+	updates[Column] = gorm.Expr(Column + " + 1")
+
+	res := tx.Model(row).Where(Column+" = ?", expected).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return conflict(tx, row, expected)
+	}
+	v.SetUint(uint64(expected) + 1)
+	return nil
+}
+
+// conflict UPDATE 没有影响任何行时查出当前版本号，区分"被修改"和"被删除"
+func conflict(tx *gorm.DB, row any, expected uint) error {
+	// 复制一份再查，只读 version 一列，不改动调用方的 row；主键非零时 GORM 自动加上主键条件
+	fresh := reflect.New(reflect.TypeOf(row).Elem())
+	fresh.Elem().Set(reflect.ValueOf(row).Elem())
+	err := tx.Session(&gorm.Session{NewDB: true}).Select(Column).Take(fresh.Interface()).Error
+	if err != nil {
+		return err
+	}
+	return &ConflictError{Expected: expected, Current: uint(version(fresh.Interface()).Uint())}
+}
+
+// version 返回 row 的 Version 字段；没有这个字段是编程错误，直接 panic
+func version(row any) reflect.Value {
+	v := reflect.ValueOf(row)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("optlock: want pointer to struct, got %T", row))
+	}
+	f := v.Elem().FieldByName("Version")
+	if !f.IsValid() || f.Kind() != reflect.Uint {
+		panic(fmt.Sprintf("optlock: %T has no Version uint field", row))
+	}
+	return f
+}
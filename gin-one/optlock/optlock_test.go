@@ -0,0 +1,91 @@
+package optlock
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+)
+
+type doc struct {
+	gorm.Model
+	Title   string
+	Version uint `gorm:"not null;default:1"`
+}
+
+func newDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.Open(t, &doc{})
+}
+
+func TestUpdate(t *testing.T) {
+	db := newDB(t)
+	d := doc{Title: "draft"}
+	db.Create(&d)
+	if d.Version != 1 {
+		t.Fatalf("version after create = %d", d.Version)
+	}
+
+	// 两个人读到同一个版本
+	var a, b doc
+	db.First(&a, d.ID)
+	db.First(&b, d.ID)
+
+	if err := Update(db, &a, map[string]any{"title": "by a"}); err != nil {
+		t.Fatal(err)
+	}
+	if a.Version != 2 {
+		t.Errorf("a.Version = %d, want 2", a.Version)
+	}
+
+	err := Update(db, &b, map[string]any{"title": "by b"})
+	var ce *ConflictError
+	if !errors.As(err, &ce) || !errors.Is(err, ErrConflict) || ce.Expected != 1 || ce.Current != 2 {
+		t.Fatalf("stale update err = %v", err)
+	}
+	if b.Version != 1 {
+		t.Errorf("b.Version = %d after conflict", b.Version)
+	}
+
+	var got doc
+	db.First(&got, d.ID)
+	if got.Title != "by a" || got.Version != 2 {
+		t.Errorf("row = %q v%d", got.Title, got.Version)
+	}
+}
+
+func TestUpdateDeleted(t *testing.T) {
+	db := newDB(t)
+	d := doc{Title: "draft"}
+	db.Create(&d)
+	db.Delete(&doc{}, d.ID)
+
+	if err := Update(db, &d, map[string]any{"title": "x"}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("update deleted err = %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	d := &doc{Version: 3}
+	if err := Check(d, 0); err != nil {
+		t.Errorf("no version err = %v", err)
+	}
+	if err := Check(d, 3); err != nil {
+		t.Errorf("same version err = %v", err)
+	}
+	var ce *ConflictError
+	if err := Check(d, 2); !errors.As(err, &ce) || ce.Current != 3 {
+		t.Errorf("stale version err = %v", err)
+	}
+}
+
+func TestMissingVersionField(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want panic")
+		}
+	}()
+	Check(&struct{ ID uint }{}, 1)
+}
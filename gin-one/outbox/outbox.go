@@ -0,0 +1,363 @@
+// ============================================================================
+// Package outbox 事务发件箱：业务数据和领域事件在同一个事务里提交
+// ============================================================================
+//
+// 【问题：双写】
+//
+//	tx.Create(&user)      // 1. 写数据库
+//	tx.Commit()
+//	kafka.Publish(event)  // 2. 发消息 —— 进程在这里崩溃，事件永远丢失
+//
+// 反过来先发消息再提交，事务回滚时消费者会收到"不存在的用户"。
+// 数据库和消息队列之间没有分布式事务，两步写总有一步可能失败。
+//
+// 【发件箱】
+//
+//	DB.Transaction(func(tx *gorm.DB) error {
+//	    tx.Create(&user)
+//	    _, err := outbox.Add(ctx, tx, "user.created", userID, payload) // 事件写进 outbox_events
+//	    return err
+//	})
+//
+// 事件和业务数据一起提交或一起回滚；后台 Relay 轮询 outbox_events，
+// 发布成功后标记 published_at，失败按指数退避重试。
+//
+// 【至少一次 + 幂等键】
+//
+// 发布成功、标记之前进程崩溃，重启后同一事件会再发一次。
+// 每个事件有唯一的 Key（幂等键），消费者用 ProcessOnce 记录处理过的 Key，重复的直接跳过。
+//
+// | 保证           | 做法                                               |
+// |----------------|----------------------------------------------------|
+// | 不丢           | 事件和业务同一事务提交，Relay 重试直到成功          |
+// | 不重复处理     | 消费者按 Key 去重（ProcessOnce）                    |
+// | 多实例不重复发 | 领取事件时条件更新 next_attempt_at（租约）          |
+// | 表不无限增长   | Run 定期删除发布超过 Retention 的事件               |
+//
+// 重试会打乱同一聚合的事件顺序，需要顺序的消费者应检查事件中的版本号。
+//
+// ============================================================================
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go-one/lock"
+)
+
+// 错误定义
+var (
+	ErrNoTransaction = errors.New("outbox: Add must be called inside a transaction")
+)
+
+// Event 表 outbox_events 的一行
+type Event struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Key           string     `gorm:"size:32;not null;uniqueIndex" json:"key"` // 幂等键
+	Topic         string     `gorm:"size:100;not null;index" json:"topic"`
+	AggregateID   string     `gorm:"size:64;index" json:"aggregate_id"` // 如用户 ID，Kafka 分区键
+	Payload       string     `gorm:"type:text;not null" json:"payload"` // JSON
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"size:500" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+	PublishedAt   *time.Time `gorm:"index" json:"published_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (Event) TableName() string {
+	return "outbox_events"
+}
+
+// Message 发布给 Publisher 的消息
+type Message struct {
+	Key         string          `json:"key"`
+	Topic       string          `json:"topic"`
+	AggregateID string          `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func (e Event) message() Message {
+	return Message{
+		Key:         e.Key,
+		Topic:       e.Topic,
+		AggregateID: e.AggregateID,
+		Payload:     json.RawMessage(e.Payload),
+		CreatedAt:   e.CreatedAt,
+	}
+}
+
+// Publisher 把消息发到消息队列，返回 nil 表示对方已确认收到
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+func newKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Add 在事务 tx 中写入一个事件，payload 序列化为 JSON
+// tx 必须是 DB.Transaction 回调里的 tx，否则事件和业务数据不在同一个事务里
+func Add(ctx context.Context, tx *gorm.DB, topic string, aggregateID any, payload any) (*Event, error) {
+	if _, ok := tx.Statement.ConnPool.(gorm.TxCommitter); !ok {
+		return nil, ErrNoTransaction
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: marshal %s: %w", topic, err)
+	}
+	key, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+	e := &Event{
+		Key:           key,
+		Topic:         topic,
+		AggregateID:   fmt.Sprint(aggregateID),
+		Payload:       string(b),
+		NextAttemptAt: time.Now(),
+	}
+	if err := tx.WithContext(ctx).Create(e).Error; err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ============================================================================
+// Relay：轮询并发布
+// ============================================================================
+
+// Config Relay 配置
+type Config struct {
+	// Interval 轮询间隔，默认 1s；Notify 可以提前唤醒
+	Interval time.Duration
+
+	// BatchSize 每次最多领取的事件数，默认 100
+	BatchSize int
+
+	// Lease 领取后多久没有结果视为 Relay 崩溃，事件可被重新领取，默认 30s
+	Lease time.Duration
+
+	// Backoff 第一次重试的等待时间，之后翻倍，最长 MaxBackoff；默认 1s / 5m
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// Retention 已发布事件保留多久，默认 7 天
+	Retention time.Duration
+
+	// Locker 多实例部署时每小时的清理只由一个实例执行，nil 表示不加锁；
+	// 发布不需要锁，领取时的租约已经保证同一事件只有一个 Relay 在发
+	Locker lock.Locker
+
+	// Logger 默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Relay 后台发布 outbox_events 中的事件
+type Relay struct {
+	db     *gorm.DB
+	pub    Publisher
+	cfg    Config
+	wake   chan struct{}
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// NewRelay 创建 Relay，表需要事先 AutoMigrate(&outbox.Event{})
+func NewRelay(db *gorm.DB, pub Publisher, cfg Config) *Relay {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 30 * time.Second
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 7 * 24 * time.Hour
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Relay{
+		db:     db,
+		pub:    pub,
+		cfg:    cfg,
+		wake:   make(chan struct{}, 1),
+		logger: cfg.Logger,
+		now:    time.Now,
+	}
+}
+
+// Notify 事务提交后调用，让 Relay 立即发布而不是等下一次轮询；不会阻塞
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// claim 领取到期的事件：把 next_attempt_at 推迟一个租约
+// 条件更新里再检查一次到期，别的实例先领走了（已推迟）就更新不到，保证只有一个实例发布
+func (r *Relay) claim(ctx context.Context) ([]Event, error) {
+	now := r.now()
+	var due []Event
+	err := r.db.WithContext(ctx).
+		Where("published_at IS NULL AND next_attempt_at <= ?", now).
+		Order("id").Limit(r.cfg.BatchSize).
+		Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+	claimed := due[:0]
+	for _, e := range due {
+		res := r.db.WithContext(ctx).Model(&Event{}).
+			Where("id = ? AND next_attempt_at <= ? AND published_at IS NULL", e.ID, now).
+			Update("next_attempt_at", now.Add(r.cfg.Lease))
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			claimed = append(claimed, e)
+		}
+	}
+	return claimed, nil
+}
+
+// backoff 第 attempts 次失败后的等待时间
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.cfg.Backoff
+	for i := 1; i < attempts && d < r.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.cfg.MaxBackoff)
+}
+
+// Flush 发布一批到期的事件，返回发布成功的数量
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	events, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	published := 0
+	for _, e := range events {
+		perr := r.pub.Publish(ctx, e.message())
+		db := r.db.WithContext(ctx).Model(&Event{}).Where("id = ?", e.ID)
+		if perr == nil {
+			if err := db.Update("published_at", r.now()).Error; err != nil {
+				// 已经发出去了，标记失败只会导致租约到期后重发，消费者按 Key 去重
+				return published, err
+			}
+			published++
+			continue
+		}
+
+		attempts := e.Attempts + 1
+		msg := perr.Error()
+		if len(msg) > 500 {
+			msg = msg[:500]
+		}
+		err := db.Updates(map[string]any{
+			"attempts":        attempts,
+			"last_error":      msg,
+			"next_attempt_at": r.now().Add(r.backoff(attempts)),
+		}).Error
+		if err != nil {
+			return published, err
+		}
+		r.logger.Warn("outbox publish failed", "topic", e.Topic, "key", e.Key, "attempts", attempts, "error", perr)
+	}
+	return published, nil
+}
+
+// Purge 删除发布时间早于 Retention 的事件
+func (r *Relay) Purge(ctx context.Context) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Where("published_at IS NOT NULL AND published_at < ?", r.now().Add(-r.cfg.Retention)).
+		Delete(&Event{})
+	return res.RowsAffected, res.Error
+}
+
+// purgeLock 定时清理的锁，其他实例持有时这一轮跳过
+const purgeLock = "outbox:purge"
+
+func (r *Relay) purge(ctx context.Context) error {
+	purge := func(ctx context.Context) error {
+		n, err := r.Purge(ctx)
+		if n > 0 {
+			r.logger.Info("purged published outbox events", "count", n)
+		}
+		return err
+	}
+	if r.cfg.Locker == nil {
+		return purge(ctx)
+	}
+	err := lock.Run(ctx, r.cfg.Locker, purgeLock, time.Minute, purge)
+	if errors.Is(err, lock.ErrNotAcquired) {
+		return nil
+	}
+	return err
+}
+
+// Run 轮询发布，每小时清理一次旧事件，阻塞直到 ctx 取消
+func (r *Relay) Run(ctx context.Context) {
+	poll := time.NewTicker(r.cfg.Interval)
+	defer poll.Stop()
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-purge.C:
+			if err := r.purge(ctx); err != nil && ctx.Err() == nil {
+				r.logger.Error("purge outbox events", "error", err)
+			}
+			continue
+		case <-poll.C:
+		case <-r.wake:
+		}
+		// 一批满了说明还有积压，继续发，不等下一次轮询
+		for {
+			n, err := r.Flush(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.logger.Error("flush outbox", "error", err)
+				}
+				break
+			}
+			if n < r.cfg.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// Pending 未发布的事件数，用于监控积压
+func (r *Relay) Pending(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&Event{}).Where("published_at IS NULL").Count(&n).Error
+	return n, err
+}
@@ -0,0 +1,241 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+)
+
+type account struct {
+	ID   uint
+	Name string
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.Open(t, &account{}, &Event{}, &Processed{})
+}
+
+// createAccount 在事务里创建账号并写入 account.created 事件，fail 为 true 时回滚
+func createAccount(db *gorm.DB, name string, fail bool) error {
+	ctx := context.Background()
+	return db.Transaction(func(tx *gorm.DB) error {
+		a := account{Name: name}
+		if err := tx.Create(&a).Error; err != nil {
+			return err
+		}
+		if _, err := Add(ctx, tx, "account.created", a.ID, map[string]string{"name": name}); err != nil {
+			return err
+		}
+		if fail {
+			return errors.New("boom")
+		}
+		return nil
+	})
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name       string
+		fail       bool
+		wantEvents int64
+	}{
+		{"commit writes event", false, 1},
+		{"rollback drops event", true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			_ = createAccount(db, "alice", tt.fail)
+			var n int64
+			db.Model(&Event{}).Count(&n)
+			if n != tt.wantEvents {
+				t.Fatalf("events = %d, want %d", n, tt.wantEvents)
+			}
+		})
+	}
+
+	t.Run("outside transaction", func(t *testing.T) {
+		db := newTestDB(t)
+		if _, err := Add(context.Background(), db, "x", 1, nil); !errors.Is(err, ErrNoTransaction) {
+			t.Fatalf("err = %v, want ErrNoTransaction", err)
+		}
+	})
+}
+
+// flakyPublisher 前 failures 次失败，之后把消息收下
+type flakyPublisher struct {
+	failures int
+	got      []Message
+}
+
+func (p *flakyPublisher) Publish(_ context.Context, msg Message) error {
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+	p.got = append(p.got, msg)
+	return nil
+}
+
+func TestRelayFlush(t *testing.T) {
+	db := newTestDB(t)
+	for _, name := range []string{"alice", "bob"} {
+		if err := createAccount(db, name, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pub := &flakyPublisher{failures: 1}
+	r := NewRelay(db, pub, Config{Backoff: time.Minute})
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// 第一个事件失败，第二个成功
+	if n, err := r.Flush(ctx); err != nil || n != 1 {
+		t.Fatalf("Flush = %d, %v; want 1", n, err)
+	}
+	var failed Event
+	db.Where("published_at IS NULL").Take(&failed)
+	if failed.Attempts != 1 || failed.LastError == "" {
+		t.Fatalf("failed event = %+v", failed)
+	}
+
+	// 退避期内不重试
+	if n, _ := r.Flush(ctx); n != 0 {
+		t.Fatalf("Flush during backoff = %d, want 0", n)
+	}
+
+	now = now.Add(time.Minute)
+	if n, err := r.Flush(ctx); err != nil || n != 1 {
+		t.Fatalf("Flush after backoff = %d, %v; want 1", n, err)
+	}
+	if pending, _ := r.Pending(ctx); pending != 0 {
+		t.Fatalf("pending = %d, want 0", pending)
+	}
+	if len(pub.got) != 2 || pub.got[0].Key == pub.got[1].Key || pub.got[0].Key == "" {
+		t.Fatalf("published = %+v", pub.got)
+	}
+	if string(pub.got[0].Payload) != `{"name":"bob"}` {
+		t.Fatalf("payload = %s", pub.got[0].Payload)
+	}
+
+	// 发布过的事件保留 Retention 后清理
+	now = now.Add(8 * 24 * time.Hour)
+	if n, err := r.Purge(ctx); err != nil || n != 2 {
+		t.Fatalf("Purge = %d, %v; want 2", n, err)
+	}
+}
+
+func TestRelayClaimLease(t *testing.T) {
+	db := newTestDB(t)
+	if err := createAccount(db, "alice", false); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	a := NewRelay(db, &flakyPublisher{}, Config{Lease: time.Minute})
+	b := NewRelay(db, &flakyPublisher{}, Config{Lease: time.Minute})
+	a.now = func() time.Time { return now }
+	b.now = a.now
+
+	first, err := a.claim(context.Background())
+	if err != nil || len(first) != 1 {
+		t.Fatalf("first claim = %d, %v", len(first), err)
+	}
+	// a 领取后还没有结果，b 领不到
+	if second, _ := b.claim(context.Background()); len(second) != 0 {
+		t.Fatalf("second claim = %d, want 0", len(second))
+	}
+	// 租约过期（a 崩溃），b 可以重新领取
+	now = now.Add(time.Minute)
+	if third, _ := b.claim(context.Background()); len(third) != 1 {
+		t.Fatalf("claim after lease = %d, want 1", len(third))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := NewRelay(nil, nil, Config{Backoff: time.Second, MaxBackoff: 10 * time.Second})
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := r.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryBusAndProcessOnce(t *testing.T) {
+	db := newTestDB(t)
+	bus := NewMemoryBus()
+	ctx := context.Background()
+
+	var handled, all int
+	bus.Subscribe("account.created", func(ctx context.Context, msg Message) error {
+		_, err := ProcessOnce(ctx, db, "welcome-mail", msg.Key, func(tx *gorm.DB) error {
+			handled++
+			return nil
+		})
+		return err
+	})
+	bus.Subscribe("*", func(context.Context, Message) error {
+		all++
+		return nil
+	})
+
+	msg := Message{Key: "k1", Topic: "account.created"}
+	for range 3 { // 至少一次投递：同一条消息重复到达
+		if err := bus.Publish(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bus.Publish(ctx, Message{Key: "k2", Topic: "other"}); err != nil {
+		t.Fatal(err)
+	}
+	if handled != 1 || all != 4 {
+		t.Fatalf("handled = %d, all = %d; want 1, 4", handled, all)
+	}
+
+	// fn 失败时不留下幂等键，重发还会执行
+	calls := 0
+	fail := func(tx *gorm.DB) error { calls++; return errors.New("smtp down") }
+	if _, err := ProcessOnce(ctx, db, "welcome-mail", "k3", fail); err == nil {
+		t.Fatal("want error")
+	}
+	done, err := ProcessOnce(ctx, db, "welcome-mail", "k3", func(tx *gorm.DB) error { calls++; return nil })
+	if err != nil || !done || calls != 2 {
+		t.Fatalf("retry = %v, %v, calls %d", done, err, calls)
+	}
+}
+
+type fakeWriter struct{ msgs []KafkaMessage }
+
+func (w *fakeWriter) WriteMessages(_ context.Context, msgs ...KafkaMessage) error {
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func TestKafkaPublisher(t *testing.T) {
+	w := &fakeWriter{}
+	p := KafkaPublisher{Writer: w, TopicPrefix: "dev."}
+	err := p.Publish(context.Background(), Message{Key: "k1", Topic: "user.created", AggregateID: "7", Payload: []byte(`{}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := w.msgs[0]
+	if m.Topic != "dev.user.created" || string(m.Key) != "7" || string(m.Headers[0].Value) != "k1" {
+		t.Fatalf("kafka message = %+v", m)
+	}
+}
@@ -0,0 +1,147 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ============================================================================
+// 发布端
+// ============================================================================
+
+// Handler 订阅者处理函数，返回错误表示处理失败，Relay 会稍后重发
+type Handler func(ctx context.Context, msg Message) error
+
+// MemoryBus 进程内消息总线，同步调用订阅者，适合单体应用和测试
+type MemoryBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewMemoryBus 创建进程内总线
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe 订阅 topic，topic 为 "*" 时接收所有消息
+func (b *MemoryBus) Subscribe(topic string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], h)
+}
+
+// Publish 实现 Publisher，依次调用订阅者，任一失败则整条消息算失败
+// 重发时已成功的订阅者会再收到一次，所以订阅者要用 ProcessOnce 去重
+func (b *MemoryBus) Publish(ctx context.Context, msg Message) error {
+	b.mu.RLock()
+	hs := append(append([]Handler{}, b.handlers[msg.Topic]...), b.handlers["*"]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, h := range hs {
+		if err := h(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// KafkaHeader Kafka 消息头
+type KafkaHeader struct {
+	Key   string
+	Value []byte
+}
+
+// KafkaMessage 要写入 Kafka 的消息，字段和 segmentio/kafka-go 的 Message 对应
+type KafkaMessage struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers []KafkaHeader
+	Time    time.Time
+}
+
+// KafkaWriter Kafka 客户端的最小接口，kafka-go 的 *kafka.Writer 包一层即可实现
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...KafkaMessage) error
+}
+
+// KafkaPublisher 把消息写入 Kafka
+//
+// 分区键用 AggregateID，同一聚合的事件进同一分区；幂等键放在消息头 idempotency-key 里。
+type KafkaPublisher struct {
+	Writer KafkaWriter
+
+	// TopicPrefix 加在 topic 前面，如 "prod."
+	TopicPrefix string
+}
+
+// Publish 实现 Publisher
+func (p KafkaPublisher) Publish(ctx context.Context, msg Message) error {
+	err := p.Writer.WriteMessages(ctx, KafkaMessage{
+		Topic: p.TopicPrefix + msg.Topic,
+		Key:   []byte(msg.AggregateID),
+		Value: msg.Payload,
+		Headers: []KafkaHeader{
+			{Key: "idempotency-key", Value: []byte(msg.Key)},
+		},
+		Time: msg.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("kafka publish %s: %w", msg.Topic, err)
+	}
+	return nil
+}
+
+// ============================================================================
+// 消费端去重
+// ============================================================================
+
+// Processed 表 outbox_processed：消费者处理过的幂等键
+type Processed struct {
+	Consumer    string    `gorm:"primaryKey;size:100"`
+	Key         string    `gorm:"primaryKey;size:32"`
+	ProcessedAt time.Time `gorm:"not null"`
+}
+
+// TableName 指定表名
+func (Processed) TableName() string {
+	return "outbox_processed"
+}
+
+// ProcessOnce 每个 consumer 对同一个 key 只执行一次 fn
+//
+// 记录幂等键和 fn 在同一个事务里：fn 失败时键不会留下，下次重发还会执行；
+// fn 成功后重复投递的消息直接跳过，返回 (false, nil)。
+// fn 里的写操作必须用参数 tx，否则去重和业务不在同一个事务里。
+func ProcessOnce(ctx context.Context, db *gorm.DB, consumer, key string, fn func(tx *gorm.DB) error) (bool, error) {
+	done := false
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Processed{
+			Consumer:    consumer,
+			Key:         key,
+			ProcessedAt: time.Now(),
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil // 处理过了
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		done = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return done, nil
+}
@@ -0,0 +1,195 @@
+// ============================================================================
+// Package pagination 列表分页：页码（offset）与游标（keyset）两种模式
+// ============================================================================
+//
+// 【offset 分页的问题】
+//
+//	SELECT * FROM users ORDER BY id LIMIT 10 OFFSET 100000
+//
+// 数据库要先扫描并丢弃前 100000 行，页码越大越慢；
+// 翻页期间有新数据插入，还会出现重复或漏掉的记录。
+//
+// 【游标分页】
+//
+// 记住上一页最后一条的 (created_at, id)，下一页从它后面开始：
+//
+//	SELECT * FROM posts
+//	WHERE created_at < '2024-01-01 10:00' OR (created_at = '2024-01-01 10:00' AND id < 42)
+//	ORDER BY created_at DESC, id DESC
+//	LIMIT 11                       -- 多查一条判断是否还有下一页
+//
+// 走 (created_at, id) 索引，第 1 页和第 10000 页一样快。
+// created_at 可能重复，所以必须带上 id 作为第二排序键。
+//
+// | 模式   | 请求                         | 响应                               | 适合             |
+// |--------|------------------------------|------------------------------------|------------------|
+// | offset | ?page=3&page_size=10         | data, total, page, size            | 后台表格、跳页   |
+// | cursor | ?cursor=&page_size=10        | data, next_cursor, has_more, size  | 无限滚动、大表   |
+//
+// 游标对客户端是不透明的字符串（base64 JSON），只表示位置，不包含权限信息；
+// 带 cursor 参数（第一页传空值）即使用游标模式。
+//
+// ============================================================================
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 每页条数
+const (
+	DefaultSize = 10
+	MaxSize     = 100
+)
+
+// 错误定义
+var (
+	ErrInvalidCursor = errors.New("pagination: invalid cursor")
+	ErrInvalidPage   = errors.New("pagination: invalid page or page_size")
+)
+
+// ============================================================================
+// 游标
+// ============================================================================
+
+// Cursor 上一页最后一条记录的位置
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"i"`
+}
+
+// Of 从 gorm.Model 取出游标，模型嵌入了 gorm.Model 时使用：
+//
+//	pagination.NewPage(users, limit, func(u model.User) pagination.Cursor { return pagination.Of(u.Model) })
+func Of(m gorm.Model) Cursor {
+	return Cursor{CreatedAt: m.CreatedAt, ID: m.ID}
+}
+
+// Encode 编码为 URL 安全的字符串
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode 解析游标，空字符串表示第一页，返回 nil
+func Decode(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == 0 || c.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Direction 排序方向
+type Direction int
+
+const (
+	Asc  Direction = iota // 最早的在前
+	Desc                  // 最新的在前
+)
+
+// Apply 在查询上加游标条件、排序和 LIMIT limit+1
+// 表需要有 created_at 和 id 列，建议建立 (created_at, id) 联合索引
+func Apply(db *gorm.DB, after *Cursor, limit int, dir Direction) *gorm.DB {
+	op, order := ">", "created_at, id"
+	if dir == Desc {
+		op, order = "<", "created_at DESC, id DESC"
+	}
+	if after != nil {
+		// 不用 (created_at, id) < (?, ?) 行值比较，兼容更多数据库
+		db = db.Where("(created_at "+op+" ? OR (created_at = ? AND id "+op+" ?))",
+			after.CreatedAt, after.CreatedAt, after.ID)
+	}
+	return db.Order(order).Limit(limit + 1)
+}
+
+// Page 游标分页结果
+type Page[T any] struct {
+	Items      []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// NewPage 用 Apply 查出的最多 limit+1 行构造结果，多出的一行只用来判断 HasMore
+func NewPage[T any](rows []T, limit int, cursor func(T) Cursor) Page[T] {
+	if rows == nil {
+		rows = []T{}
+	}
+	p := Page[T]{Items: rows}
+	if len(rows) > limit {
+		p.Items, p.HasMore = rows[:limit], true
+		p.NextCursor = cursor(p.Items[limit-1]).Encode()
+	}
+	return p
+}
+
+// ============================================================================
+// 请求参数
+// ============================================================================
+
+// Mode 分页模式
+type Mode string
+
+const (
+	ModeOffset Mode = "offset"
+	ModeCursor Mode = "cursor"
+)
+
+// Request 分页参数
+type Request struct {
+	Mode  Mode
+	Page  int     // offset 模式的页码，从 1 开始
+	Size  int     // 每页条数，两种模式共用
+	After *Cursor // cursor 模式下上一页最后一条，nil 表示第一页
+}
+
+// Offset offset 模式下跳过的行数
+func (r Request) Offset() int {
+	return (r.Page - 1) * r.Size
+}
+
+// FromQuery 从查询参数解析分页：page、page_size、cursor
+// page_size 超过 MaxSize 时按 MaxSize 处理
+func FromQuery(c *gin.Context) (Request, error) {
+	r := Request{Mode: ModeOffset, Page: 1, Size: DefaultSize}
+
+	if s := c.Query("page_size"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return r, ErrInvalidPage
+		}
+		r.Size = min(n, MaxSize)
+	}
+
+	if s, ok := c.GetQuery("cursor"); ok {
+		after, err := Decode(s)
+		if err != nil {
+			return r, err
+		}
+		r.Mode, r.After = ModeCursor, after
+		return r, nil
+	}
+
+	if s := c.Query("page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return r, ErrInvalidPage
+		}
+		r.Page = n
+	}
+	return r, nil
+}
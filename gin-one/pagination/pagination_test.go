@@ -0,0 +1,145 @@
+package pagination
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+)
+
+type item struct {
+	gorm.Model
+	Name string
+}
+
+func cursorOf(it item) Cursor { return Of(it.Model) }
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC), ID: 42}
+	got, err := Decode(c.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(c.CreatedAt) || got.ID != c.ID {
+		t.Errorf("Decode(Encode(%v)) = %v", c, got)
+	}
+
+	if got, err := Decode(""); got != nil || err != nil {
+		t.Errorf(`Decode("") = %v, %v; want nil, nil`, got, err)
+	}
+	for _, s := range []string{"!!!", "bm90IGpzb24", "e30"} { // 非 base64、非 JSON、{}
+		if _, err := Decode(s); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Decode(%q) error = %v; want ErrInvalidCursor", s, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	db := testdb.Open(t, &item{})
+
+	// 每 3 条共用一个 created_at，检验 id 作为第二排序键
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 10 {
+		it := item{Name: string(rune('a' + i))}
+		it.CreatedAt = base.Add(time.Duration(i/3) * time.Minute)
+		db.Create(&it)
+	}
+
+	tests := []struct {
+		dir  Direction
+		size int
+		want string
+	}{
+		{Asc, 3, "abcdefghij"},
+		{Asc, 4, "abcdefghij"},
+		{Desc, 3, "jihgfedcba"},
+		{Desc, 10, "jihgfedcba"},
+		{Desc, 20, "jihgfedcba"},
+	}
+	for _, tt := range tests {
+		var (
+			got   string
+			after *Cursor
+			pages int
+			err   error
+		)
+		for {
+			var rows []item
+			if err := Apply(db.Model(&item{}), after, tt.size, tt.dir).Find(&rows).Error; err != nil {
+				t.Fatal(err)
+			}
+			p := NewPage(rows, tt.size, cursorOf)
+			pages++
+			for _, it := range p.Items {
+				got += it.Name
+			}
+			if !p.HasMore {
+				break
+			}
+			if after, err = Decode(p.NextCursor); err != nil {
+				t.Fatal(err)
+			}
+			if pages > 20 {
+				t.Fatal("pagination does not terminate")
+			}
+		}
+		if got != tt.want {
+			t.Errorf("dir=%d size=%d: pages joined = %q; want %q", tt.dir, tt.size, got, tt.want)
+		}
+		if wantPages := (10 + tt.size - 1) / tt.size; pages != wantPages {
+			t.Errorf("dir=%d size=%d: %d pages; want %d", tt.dir, tt.size, pages, wantPages)
+		}
+	}
+}
+
+func TestNewPageEmpty(t *testing.T) {
+	p := NewPage[item](nil, 10, cursorOf)
+	if p.Items == nil || p.HasMore || p.NextCursor != "" {
+		t.Errorf("NewPage(nil) = %+v; want empty non-nil items", p)
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	valid := Cursor{CreatedAt: time.Now(), ID: 5}.Encode()
+
+	tests := []struct {
+		query     string
+		want      Request
+		wantAfter bool
+		wantErr   error
+	}{
+		{"", Request{Mode: ModeOffset, Page: 1, Size: DefaultSize}, false, nil},
+		{"page=3&page_size=20", Request{Mode: ModeOffset, Page: 3, Size: 20}, false, nil},
+		{"page_size=1000", Request{Mode: ModeOffset, Page: 1, Size: MaxSize}, false, nil},
+		{"cursor=", Request{Mode: ModeCursor, Page: 1, Size: DefaultSize}, false, nil},
+		{"cursor=" + valid + "&page_size=5", Request{Mode: ModeCursor, Page: 1, Size: 5}, true, nil},
+		{"page=0", Request{}, false, ErrInvalidPage},
+		{"page_size=abc", Request{}, false, ErrInvalidPage},
+		{"cursor=garbage", Request{}, false, ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/?"+tt.query, nil)
+
+		got, err := FromQuery(c)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("FromQuery(%q) error = %v; want %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got.Mode != tt.want.Mode || got.Page != tt.want.Page || got.Size != tt.want.Size {
+			t.Errorf("FromQuery(%q) = %+v; want %+v", tt.query, got, tt.want)
+		}
+		if (got.After != nil) != tt.wantAfter {
+			t.Errorf("FromQuery(%q).After = %v; want set = %v", tt.query, got.After, tt.wantAfter)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+// ============================================================================
+// Package pdf 极简 PDF 生成器（文本、表格、JPEG 图片）
+// ============================================================================
+//
+// 【为什么不用第三方库？】
+//
+// 回执、发票这类单据只需要"在固定位置写字、画线、贴 Logo"，
+// 几百行代码就能输出合法的 PDF 1.4，不必引入完整的排版引擎。
+//
+// 【PDF 文件结构】
+//
+//	%PDF-1.4                  文件头
+//	1 0 obj ... endobj        对象：目录、页面树、字体、图片、页面、内容流
+//	xref                      交叉引用表：每个对象在文件中的字节偏移
+//	trailer ... %%EOF         指向根对象
+//
+// 【坐标系】
+//
+// PDF 原点在页面左下角，这里统一换算成左上角原点、y 轴向下，
+// 与 HTML/Canvas 的习惯一致。单位为点（pt），1 pt = 1/72 英寸。
+//
+// 【限制】
+//
+//  1. 只内置 Helvetica / Helvetica-Bold，使用 WinAnsi 编码，
+//     中文等字符会被替换成 "?"（嵌入 CJK 字体需要子集化，超出本包范围）
+//  2. 图片只支持 JPEG（DCTDecode 可以直接把原始字节放进 PDF）
+//  3. 输出不含时间戳，同样的输入得到同样的字节，便于测试和缓存
+//
+// ============================================================================
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // 注册 JPEG 解码器，DecodeConfig 才能识别格式
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A4 纸张尺寸（pt）
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Font 内置字体
+type Font string
+
+const (
+	Helvetica     Font = "F1"
+	HelveticaBold Font = "F2"
+)
+
+// ErrUnsupportedImage 不是 JPEG 图片
+var ErrUnsupportedImage = errors.New("pdf: only JPEG images are supported")
+
+// Document 一个 PDF 文档
+type Document struct {
+	Title  string
+	pages  []*Page
+	images []*Image
+}
+
+// New 创建空文档
+func New() *Document {
+	return &Document{}
+}
+
+// Page 一页 A4 纸
+type Page struct {
+	content bytes.Buffer
+	images  []*Image // 本页引用的图片
+}
+
+// Image 已加入文档的 JPEG 图片，可在多个页面重复引用
+type Image struct {
+	name          string // 资源名，如 Im1
+	data          []byte
+	width, height int
+	colorSpace    string
+}
+
+// AddPage 追加一页并返回
+func (d *Document) AddPage() *Page {
+	p := &Page{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// AddJPEG 把 JPEG 图片加入文档
+func (d *Document) AddJPEG(data []byte) (*Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || format != "jpeg" {
+		return nil, ErrUnsupportedImage
+	}
+
+	cs := "/DeviceRGB"
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		cs = "/DeviceGray"
+	case color.CMYKModel:
+		cs = "/DeviceCMYK"
+	}
+
+	img := &Image{
+		name:       "Im" + strconv.Itoa(len(d.images)+1),
+		data:       data,
+		width:      cfg.Width,
+		height:     cfg.Height,
+		colorSpace: cs,
+	}
+	d.images = append(d.images, img)
+	return img, nil
+}
+
+// ============================================================================
+// 绘图
+// ============================================================================
+
+// Text 在 (x, y) 处写一行文字，y 是文字基线位置
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td (%s) Tj ET\n",
+		font, num(size), num(x), num(A4Height-y), escape(s))
+}
+
+// TextRight 右对齐文字，right 为文字右边缘的 x 坐标
+func (p *Page) TextRight(right, y float64, font Font, size float64, s string) {
+	p.Text(right-TextWidth(font, size, s), y, font, size, s)
+}
+
+// Line 画一条直线
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n",
+		num(width), num(x1), num(A4Height-y1), num(x2), num(A4Height-y2))
+}
+
+// Rect 填充矩形，gray 为灰度（0 黑 ~ 1 白）
+func (p *Page) Rect(x, y, w, h, gray float64) {
+	fmt.Fprintf(&p.content, "q %s g %s %s %s %s re f Q\n",
+		num(gray), num(x), num(A4Height-y-h), num(w), num(h))
+}
+
+// Image 在 (x, y) 处绘制图片，(x, y) 是图片左上角
+func (p *Page) Image(img *Image, x, y, w, h float64) {
+	found := false
+	for _, im := range p.images {
+		if im == img {
+			found = true
+			break
+		}
+	}
+	if !found {
+		p.images = append(p.images, img)
+	}
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /%s Do Q\n",
+		num(w), num(h), num(x), num(A4Height-y-h), img.name)
+}
+
+// ============================================================================
+// 表格
+// ============================================================================
+
+// Align 列对齐方式
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+)
+
+// Column 表格列
+type Column struct {
+	Title string
+	Width float64
+	Align Align
+}
+
+// Table 简单表格：表头加粗并带灰色底纹，每行之间画细线
+type Table struct {
+	Columns   []Column
+	Rows      [][]string
+	FontSize  float64 // 默认 10
+	RowHeight float64 // 默认 FontSize * 2
+}
+
+// Table 在 (x, y) 处绘制表格，返回表格底部的 y 坐标，便于继续往下排版
+func (p *Page) Table(x, y float64, t Table) float64 {
+	size := t.FontSize
+	if size == 0 {
+		size = 10
+	}
+	rowH := t.RowHeight
+	if rowH == 0 {
+		rowH = size * 2
+	}
+	var total float64
+	for _, c := range t.Columns {
+		total += c.Width
+	}
+
+	const pad = 4
+	drawRow := func(cells []string, font Font) {
+		baseline := y + rowH/2 + size*0.35 // 文字在行内垂直居中
+		cx := x
+		for i, c := range t.Columns {
+			if i < len(cells) {
+				if c.Align == AlignRight {
+					p.TextRight(cx+c.Width-pad, baseline, font, size, cells[i])
+				} else {
+					p.Text(cx+pad, baseline, font, size, cells[i])
+				}
+			}
+			cx += c.Width
+		}
+		y += rowH
+	}
+
+	header := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = c.Title
+	}
+	p.Rect(x, y, total, rowH, 0.9)
+	drawRow(header, HelveticaBold)
+
+	for _, row := range t.Rows {
+		drawRow(row, Helvetica)
+		p.Line(x, y, x+total, y, 0.5)
+	}
+	return y
+}
+
+// ============================================================================
+// 输出
+// ============================================================================
+
+// WriteTo 把文档写入 w
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	// 对象编号：1 目录，2 页面树，3/4 字体，5 信息，然后是图片，最后每页两个对象（页面 + 内容流）
+	const (
+		catalogID = 1
+		pagesID   = 2
+		fontID    = 3
+		boldID    = 4
+		infoID    = 5
+	)
+	imageID := func(i int) int { return infoID + 1 + i }
+	pageID := func(i int) int { return infoID + 1 + len(d.images) + i*2 }
+
+	var buf bytes.Buffer
+	offsets := []int{0} // 对象 0 是空闲链表头
+	obj := func(id int, body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", id, body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n") // 第二行的高位字节告诉传输工具这是二进制文件
+
+	obj(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageID(i))
+	}
+	obj(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %s %s] >>",
+		strings.Join(kids, " "), len(d.pages), num(A4Width), num(A4Height)))
+
+	obj(fontID, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj(boldID, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	obj(infoID, fmt.Sprintf("<< /Title (%s) /Producer (go-one/pdf) >>", escape(d.Title)))
+
+	for i, img := range d.images {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n",
+			imageID(i), img.width, img.height, img.colorSpace, len(img.data))
+		buf.Write(img.data)
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+
+	for i, p := range d.pages {
+		xobjects := ""
+		if len(p.images) > 0 {
+			refs := make([]string, len(p.images))
+			for j, img := range p.images {
+				for k, di := range d.images {
+					if di == img {
+						refs[j] = fmt.Sprintf("/%s %d 0 R", img.name, imageID(k))
+					}
+				}
+			}
+			xobjects = " /XObject << " + strings.Join(refs, " ") + " >>"
+		}
+		obj(pageID(i), fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >>%s >> /Contents %d 0 R >>",
+			pagesID, fontID, boldID, xobjects, pageID(i)+1))
+
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Length %d >>\nstream\n", pageID(i)+1, p.content.Len())
+		buf.Write(p.content.Bytes())
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+
+	// 交叉引用表每行固定 20 字节："0000000009 00000 n \n"
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets))
+	for _, off := range offsets[1:] {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets), catalogID, infoID, xref)
+
+	return buf.WriteTo(w)
+}
+
+// Bytes 输出为字节切片
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	d.WriteTo(&buf) // 写入 bytes.Buffer 不会失败
+	return buf.Bytes()
+}
+
+// ============================================================================
+// 工具函数
+// ============================================================================
+
+// escape 把字符串转成 WinAnsi 字节并转义 PDF 字符串中的特殊字符
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			// Latin-1 补充区与 WinAnsi 相同，用八进制转义保持文件为 ASCII
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// num 格式化坐标，最多保留两位小数并去掉多余的 0
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,98 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"hello", "hello"},
+		{"a(b)c\\", `a\(b\)c\\`},
+		{"line\nbreak", "line break"},
+		{"café", `caf\351`},
+		{"支付", "??"},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	// "Hi" = H(722) + i(222) = 944/1000 * 10pt
+	if got := TextWidth(Helvetica, 10, "Hi"); got != 9.44 {
+		t.Errorf("TextWidth = %v; want 9.44", got)
+	}
+}
+
+// TestXref 交叉引用表中的每个偏移都必须正好指向 "N 0 obj"
+func TestXref(t *testing.T) {
+	var logo bytes.Buffer
+	if err := jpeg.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 4, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := New()
+	doc.Title = "Test (1)"
+	img, err := doc.AddJPEG(logo.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1 := doc.AddPage()
+	p1.Text(50, 50, HelveticaBold, 20, "Hello")
+	p1.Image(img, 400, 40, 40, 40)
+	p1.Table(50, 100, Table{
+		Columns: []Column{{Title: "A", Width: 100}, {Title: "B", Width: 100, Align: AlignRight}},
+		Rows:    [][]string{{"x", "1.00"}},
+	})
+	doc.AddPage().Text(50, 50, Helvetica, 12, "Page 2")
+
+	out := doc.Bytes()
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("startxref not found")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point to xref table", xref)
+	}
+
+	lines := strings.Split(string(out[xref:]), "\n")
+	count, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+	if count != 11 { // 空闲对象 0 + 5 个固定对象 + 1 张图片 + 2 页 * 2
+		t.Errorf("object count = %d; want 11", count)
+	}
+	for id := 1; id < count; id++ {
+		off, _ := strconv.Atoi(lines[2+id][:10])
+		want := strconv.Itoa(id) + " 0 obj"
+		if !bytes.HasPrefix(out[off:], []byte(want)) {
+			t.Errorf("xref entry %d points to %q; want %q", id, out[off:off+10], want)
+		}
+	}
+
+	if !bytes.Contains(out, []byte("/Title (Test \\(1\\))")) {
+		t.Error("title not escaped")
+	}
+	if !bytes.Equal(out, doc.Bytes()) {
+		t.Error("output is not deterministic")
+	}
+}
+
+func TestAddJPEGRejectsOtherFormats(t *testing.T) {
+	if _, err := New().AddJPEG([]byte("\x89PNG\r\n\x1a\n")); err != ErrUnsupportedImage {
+		t.Errorf("err = %v; want ErrUnsupportedImage", err)
+	}
+}
@@ -0,0 +1,300 @@
+// ============================================================================
+// Package policy 接口级授权策略
+// ============================================================================
+//
+// 【角色检查不够用】
+//
+// RoleMiddleware("admin") 只能回答"这个人是不是管理员"，
+// 回答不了"这个人能不能改这篇文章"——后者还取决于文章是谁写的。
+//
+// 【策略 = 纯函数】
+//
+//	type Policy[R any] func(s Subject, r R) Decision
+//
+// 输入是当前用户和已加载的资源，输出是允许/拒绝及原因。
+// 纯函数不碰数据库、不碰 HTTP，单元测试只需要构造结构体。
+//
+// 【组合】
+//
+//	canEdit := policy.Any(policy.AdminRole[*Post](), policy.Owner(func(p *Post) uint { return p.AuthorID }))
+//	api.PUT("/posts/:id", policy.Authorize(loadPost, canEdit), updatePost)
+//
+// 【所有权】
+//
+// "本人或管理员"是最常见的规则，资源实现 Owned 后直接用 Ownership：
+//
+//	func (p *Post) OwnerID() uint { return p.AuthorID }
+//	api.DELETE("/posts/:id", policy.Ownership(loadPost), deletePost)
+//
+//	func deletePost(c *gin.Context) {
+//	    post := policy.Resource[*Post](c)      // 中间件已加载
+//	    access, _ := policy.AccessFrom(c)      // 中间件已判断：本人还是管理员代为操作
+//	    if !access.Owner { audit(...) }
+//	}
+//
+// 【拒绝响应】
+//
+//	403 {"code": -1, "error": "forbidden", "reason": "not_owner", "message": "..."}
+//
+// reason 是机器可读的拒绝原因，前端据此决定提示文案或隐藏按钮。
+//
+// ============================================================================
+package policy
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// 常用拒绝原因
+const (
+	ReasonUnauthenticated = "unauthenticated"
+	ReasonNotOwner        = "not_owner"
+	ReasonRoleRequired    = "role_required"
+)
+
+// Subject 发起请求的用户
+type Subject struct {
+	UserID uint
+	Role   string
+}
+
+// Authenticated 是否已登录
+func (s Subject) Authenticated() bool {
+	return s.UserID != 0
+}
+
+// SubjectFromContext 读取 JWT 认证中间件写入的 user_id / role
+func SubjectFromContext(c *gin.Context) Subject {
+	var s Subject
+	if v, ok := c.Get("user_id"); ok {
+		switch id := v.(type) {
+		case uint:
+			s.UserID = id
+		case int:
+			s.UserID = uint(id)
+		case float64: // 从 JWT MapClaims 中直接取出的数字
+			s.UserID = uint(id)
+		}
+	}
+	s.Role = c.GetString("role")
+	return s
+}
+
+// Decision 授权结果
+type Decision struct {
+	Allowed bool
+	Reason  string // 拒绝原因，允许时为空
+}
+
+// Allow 允许
+func Allow() Decision {
+	return Decision{Allowed: true}
+}
+
+// Deny 拒绝并给出原因
+func Deny(reason string) Decision {
+	return Decision{Reason: reason}
+}
+
+// Policy 授权策略
+type Policy[R any] func(s Subject, r R) Decision
+
+// ============================================================================
+// 内置策略
+// ============================================================================
+
+// Authenticated 只要求已登录
+func Authenticated[R any]() Policy[R] {
+	return func(s Subject, _ R) Decision {
+		if !s.Authenticated() {
+			return Deny(ReasonUnauthenticated)
+		}
+		return Allow()
+	}
+}
+
+// AdminRole 要求 admin 角色
+func AdminRole[R any]() Policy[R] {
+	return Role[R]("admin")
+}
+
+// Role 要求指定角色之一
+func Role[R any](roles ...string) Policy[R] {
+	return func(s Subject, _ R) Decision {
+		for _, role := range roles {
+			if s.Role == role {
+				return Allow()
+			}
+		}
+		return Deny(ReasonRoleRequired)
+	}
+}
+
+// OwnerOrAdmin 资源所有者本人或管理员；拒绝原因只有 unauthenticated / not_owner
+func OwnerOrAdmin[R any](owner func(R) uint) Policy[R] {
+	isOwner := Owner(owner)
+	return func(s Subject, r R) Decision {
+		if s.Authenticated() && s.Role == "admin" {
+			return Allow()
+		}
+		return isOwner(s, r)
+	}
+}
+
+// Owner 要求当前用户是资源的所有者，owner 从资源中取出所有者 ID
+func Owner[R any](owner func(R) uint) Policy[R] {
+	return func(s Subject, r R) Decision {
+		if !s.Authenticated() {
+			return Deny(ReasonUnauthenticated)
+		}
+		if owner(r) != s.UserID {
+			return Deny(ReasonNotOwner)
+		}
+		return Allow()
+	}
+}
+
+// ============================================================================
+// 组合
+// ============================================================================
+
+// All 所有策略都允许才允许，返回第一个拒绝原因
+func All[R any](policies ...Policy[R]) Policy[R] {
+	return func(s Subject, r R) Decision {
+		for _, p := range policies {
+			if d := p(s, r); !d.Allowed {
+				return d
+			}
+		}
+		return Allow()
+	}
+}
+
+// Any 任一策略允许即允许，全部拒绝时合并原因（如 "role_required,not_owner"）
+func Any[R any](policies ...Policy[R]) Policy[R] {
+	return func(s Subject, r R) Decision {
+		reasons := make([]string, 0, len(policies))
+		for _, p := range policies {
+			d := p(s, r)
+			if d.Allowed {
+				return d
+			}
+			reasons = append(reasons, d.Reason)
+		}
+		return Deny(strings.Join(reasons, ","))
+	}
+}
+
+// ============================================================================
+// 中间件
+// ============================================================================
+
+// Loader 可以返回的错误，其他错误响应 500
+var (
+	// ErrInvalidID 路径参数不是合法的 ID，Loader 在查询之前返回，中间件响应 400
+	ErrInvalidID = errors.New("policy: invalid resource id")
+	// ErrNotFound 找不到资源，中间件响应 404
+	ErrNotFound = errors.New("policy: resource not found")
+)
+
+// Loader 根据请求加载资源（通常读取路径参数查数据库）
+type Loader[R any] func(c *gin.Context) (R, error)
+
+const (
+	resourceKey = "policy.resource"
+	accessKey   = "policy.access"
+)
+
+// Authorize 加载资源并评估策略，通过后资源存入 Context，Handler 用 Resource 取出，避免重复查询
+func Authorize[R any](load Loader[R], p Policy[R]) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := authorize(c, load, p); ok {
+			c.Next()
+		}
+	}
+}
+
+// authorize 加载资源并评估策略，拒绝时写好响应并返回 false
+func authorize[R any](c *gin.Context, load Loader[R], p Policy[R]) (r R, ok bool) {
+	r, err := load(c)
+	if errors.Is(err, ErrInvalidID) {
+		response.Abort(c, http.StatusBadRequest, "invalid_id", "ID 格式错误")
+		return r, false
+	}
+	if errors.Is(err, ErrNotFound) {
+		response.Abort(c, http.StatusNotFound, "not_found", "资源不存在")
+		return r, false
+	}
+	if err != nil {
+		_ = c.Error(err)
+		response.Abort(c, http.StatusInternalServerError, "internal_error", "加载资源失败")
+		return r, false
+	}
+
+	if d := p(SubjectFromContext(c), r); !d.Allowed {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"code":    response.CodeError,
+			"error":   "forbidden",
+			"reason":  d.Reason,
+			"message": "没有权限执行此操作",
+		})
+		return r, false
+	}
+
+	c.Set(resourceKey, r)
+	return r, true
+}
+
+// Resource 取出 Authorize 已加载的资源
+func Resource[R any](c *gin.Context) R {
+	r, _ := c.MustGet(resourceKey).(R)
+	return r
+}
+
+// ============================================================================
+// 所有权
+// ============================================================================
+
+// Owned 有所有者的资源，和 serializer.Owner 是同一个方法，model.User 已经实现
+type Owned interface {
+	OwnerID() uint
+}
+
+// Access Ownership 的检查结果，存入 Context，Handler 不用再判断一次
+type Access struct {
+	Owner bool // 当前用户是资源的所有者
+	Admin bool // 当前用户是管理员；管理员操作自己的资源时两个都为 true
+}
+
+// Ownership 加载资源，只允许所有者本人或管理员：Authorize(load, OwnerOrAdmin(R.OwnerID))，
+// 通过后资源用 Resource、检查结果用 AccessFrom 取出
+//
+// ID 格式错误 400；资源不存在 404；未登录 403 unauthenticated；不是本人也不是管理员 403 not_owner
+func Ownership[R Owned](load Loader[R]) gin.HandlerFunc {
+	owner := func(r R) uint { return r.OwnerID() }
+	p := OwnerOrAdmin(owner)
+	return func(c *gin.Context) {
+		r, ok := authorize(c, load, p)
+		if !ok {
+			return
+		}
+		s := SubjectFromContext(c)
+		c.Set(accessKey, Access{Owner: owner(r) == s.UserID, Admin: s.Role == "admin"})
+		c.Next()
+	}
+}
+
+// AccessFrom 取出 Ownership 的检查结果，请求没有经过 Ownership 时 ok 为 false
+func AccessFrom(c *gin.Context) (a Access, ok bool) {
+	v, ok := c.Get(accessKey)
+	if !ok {
+		return Access{}, false
+	}
+	return v.(Access), true
+}
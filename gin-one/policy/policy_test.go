@@ -0,0 +1,229 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type post struct {
+	ID       uint
+	AuthorID uint
+}
+
+func authorOf(p *post) uint { return p.AuthorID }
+
+func (p *post) OwnerID() uint { return p.AuthorID }
+
+var (
+	alice = Subject{UserID: 1, Role: "user"}
+	bob   = Subject{UserID: 2, Role: "user"}
+	admin = Subject{UserID: 3, Role: "admin"}
+	guest = Subject{}
+)
+
+func TestPolicies(t *testing.T) {
+	p := &post{ID: 10, AuthorID: alice.UserID}
+
+	tests := []struct {
+		name    string
+		policy  Policy[*post]
+		subject Subject
+		want    Decision
+	}{
+		{"authenticated/user", Authenticated[*post](), bob, Allow()},
+		{"authenticated/guest", Authenticated[*post](), guest, Deny(ReasonUnauthenticated)},
+
+		{"admin/admin", AdminRole[*post](), admin, Allow()},
+		{"admin/user", AdminRole[*post](), alice, Deny(ReasonRoleRequired)},
+		{"role/one of", Role[*post]("editor", "user"), bob, Allow()},
+
+		{"owner/owner", Owner(authorOf), alice, Allow()},
+		{"owner/other", Owner(authorOf), bob, Deny(ReasonNotOwner)},
+		{"owner/guest", Owner(authorOf), guest, Deny(ReasonUnauthenticated)},
+
+		{"any/owner", Any(AdminRole[*post](), Owner(authorOf)), alice, Allow()},
+		{"any/admin", Any(AdminRole[*post](), Owner(authorOf)), admin, Allow()},
+		{"any/other", Any(AdminRole[*post](), Owner(authorOf)), bob, Deny("role_required,not_owner")},
+
+		{"owner or admin/owner", OwnerOrAdmin(authorOf), alice, Allow()},
+		{"owner or admin/admin", OwnerOrAdmin(authorOf), admin, Allow()},
+		{"owner or admin/other", OwnerOrAdmin(authorOf), bob, Deny(ReasonNotOwner)},
+		{"owner or admin/guest", OwnerOrAdmin(authorOf), guest, Deny(ReasonUnauthenticated)},
+		{"owner or admin/role without login", OwnerOrAdmin(authorOf), Subject{Role: "admin"}, Deny(ReasonUnauthenticated)},
+
+		{"all/owner but not admin", All(Owner(authorOf), AdminRole[*post]()), alice, Deny(ReasonRoleRequired)},
+		{"all/empty", All[*post](), guest, Allow()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy(tt.subject, p); got != tt.want {
+				t.Errorf("decision = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	posts := map[string]*post{"10": {ID: 10, AuthorID: alice.UserID}}
+
+	load := func(c *gin.Context) (*post, error) {
+		switch id := c.Param("id"); id {
+		case "boom":
+			return nil, errors.New("db down")
+		default:
+			if p, ok := posts[id]; ok {
+				return p, nil
+			}
+			return nil, ErrNotFound
+		}
+	}
+
+	newRouter := func(s Subject) *gin.Engine {
+		r := gin.New()
+		r.Use(func(c *gin.Context) { // 模拟 JWT 中间件
+			if s.Authenticated() {
+				c.Set("user_id", s.UserID)
+				c.Set("role", s.Role)
+			}
+		})
+		r.PUT("/posts/:id", Authorize(load, Any(AdminRole[*post](), Owner(authorOf))), func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"id": Resource[*post](c).ID})
+		})
+		return r
+	}
+
+	tests := []struct {
+		name       string
+		subject    Subject
+		id         string
+		wantStatus int
+		wantReason string
+	}{
+		{"owner", alice, "10", http.StatusOK, ""},
+		{"admin", admin, "10", http.StatusOK, ""},
+		{"other user", bob, "10", http.StatusForbidden, "role_required,not_owner"},
+		{"guest", guest, "10", http.StatusForbidden, "role_required,unauthenticated"},
+		{"missing", alice, "404", http.StatusNotFound, ""},
+		{"loader error", alice, "boom", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newRouter(tt.subject).ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/posts/"+tt.id, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantReason != "" {
+				var body struct {
+					Error  string `json:"error"`
+					Reason string `json:"reason"`
+				}
+				json.Unmarshal(w.Body.Bytes(), &body)
+				if body.Error != "forbidden" || body.Reason != tt.wantReason {
+					t.Errorf("body = %+v; want forbidden/%s", body, tt.wantReason)
+				}
+			}
+		})
+	}
+}
+
+func TestSubjectFromContext(t *testing.T) {
+	tests := []struct {
+		value any
+		want  uint
+	}{
+		{uint(7), 7},
+		{7, 7},
+		{float64(7), 7},
+		{"7", 0},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Set("user_id", tt.value)
+		if got := SubjectFromContext(c).UserID; got != tt.want {
+			t.Errorf("user_id %T(%v) → %d; want %d", tt.value, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	posts := map[string]*post{"10": {ID: 10, AuthorID: alice.UserID}, "11": {ID: 11, AuthorID: admin.UserID}}
+	loads := 0
+	load := func(c *gin.Context) (*post, error) {
+		loads++
+		if _, err := strconv.ParseUint(c.Param("id"), 10, 64); err != nil {
+			return nil, ErrInvalidID
+		}
+		if p, ok := posts[c.Param("id")]; ok {
+			return p, nil
+		}
+		return nil, ErrNotFound
+	}
+
+	newRouter := func(s Subject) *gin.Engine {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			if s.Authenticated() {
+				c.Set("user_id", s.UserID)
+				c.Set("role", s.Role)
+			}
+		})
+		r.DELETE("/posts/:id", Ownership(load), func(c *gin.Context) {
+			access, ok := AccessFrom(c)
+			c.JSON(http.StatusOK, gin.H{"id": Resource[*post](c).ID, "ok": ok, "owner": access.Owner, "admin": access.Admin})
+		})
+		return r
+	}
+
+	tests := []struct {
+		name       string
+		subject    Subject
+		id         string
+		wantStatus int
+		wantBody   string
+	}{
+		{"owner", alice, "10", http.StatusOK, `{"admin":false,"id":10,"ok":true,"owner":true}`},
+		{"admin on someone else's post", admin, "10", http.StatusOK, `{"admin":true,"id":10,"ok":true,"owner":false}`},
+		{"admin on own post", admin, "11", http.StatusOK, `{"admin":true,"id":11,"ok":true,"owner":true}`},
+		{"other user", bob, "10", http.StatusForbidden, ReasonNotOwner},
+		{"guest", guest, "10", http.StatusForbidden, ReasonUnauthenticated},
+		{"missing", alice, "404", http.StatusNotFound, ""},
+		{"invalid id", alice, "abc", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loads = 0
+			w := httptest.NewRecorder()
+			newRouter(tt.subject).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/posts/"+tt.id, nil))
+			if w.Code != tt.wantStatus || loads != 1 {
+				t.Fatalf("status = %d after %d loads; want %d after 1", w.Code, loads, tt.wantStatus)
+			}
+			var body struct {
+				Reason string `json:"reason"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &body)
+			switch {
+			case w.Code == http.StatusOK && w.Body.String() != tt.wantBody:
+				t.Errorf("body = %s; want %s", w.Body, tt.wantBody)
+			case w.Code == http.StatusForbidden && body.Reason != tt.wantBody:
+				t.Errorf("reason = %q; want %q", body.Reason, tt.wantBody)
+			}
+		})
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if _, ok := AccessFrom(c); ok {
+		t.Error("AccessFrom without Ownership: ok = true")
+	}
+}
@@ -0,0 +1,90 @@
+package pools
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+)
+
+// CopyBufferSize Copy 使用的缓冲区大小，和 io.Copy 相同
+const CopyBufferSize = 32 << 10
+
+// Bytes 默认的缓冲区池：4KB（小请求体）、32KB（流式复制）、256KB（大块读写）
+var Bytes = NewBuffers(4<<10, CopyBufferSize, 256<<10)
+
+// Buffers 分级的 []byte 池：每一档容量一个 sync.Pool，Get 按需要的长度选最小的一档
+//
+// 存 *[]byte 而不是 []byte：切片放进 sync.Pool 时装箱会分配 24 字节，指针不会。
+type Buffers struct {
+	sizes []int
+	pools []sync.Pool
+}
+
+// NewBuffers 按容量从小到大分档，sizes 不能为空且必须为正数
+func NewBuffers(sizes ...int) *Buffers {
+	if len(sizes) == 0 {
+		panic("pools: NewBuffers needs at least one size")
+	}
+	sizes = slices.Clone(sizes)
+	slices.Sort(sizes)
+	sizes = slices.Compact(sizes)
+	if sizes[0] <= 0 {
+		panic(fmt.Sprintf("pools: invalid buffer size %d", sizes[0]))
+	}
+	b := &Buffers{sizes: sizes, pools: make([]sync.Pool, len(sizes))}
+	for i, size := range sizes {
+		b.pools[i].New = func() any {
+			buf := make([]byte, size)
+			return &buf
+		}
+	}
+	return b
+}
+
+// Get 取出长度为 n 的缓冲区，容量是能放下 n 的最小一档；内容是上一次使用留下的，不会清零
+//
+// n 超过最大一档时直接分配，Put 时丢弃。
+func (b *Buffers) Get(n int) *[]byte {
+	i, _ := slices.BinarySearch(b.sizes, n)
+	if i == len(b.sizes) {
+		buf := make([]byte, n)
+		return &buf
+	}
+	buf := b.pools[i].Get().(*[]byte)
+	*buf = (*buf)[:n]
+	return buf
+}
+
+// Put 放回缓冲区，容量不是某一档（Get 时超出最大一档、或被 append 扩容过）的丢弃
+func (b *Buffers) Put(buf *[]byte) {
+	c := cap(*buf)
+	if i, ok := slices.BinarySearch(b.sizes, c); ok {
+		*buf = (*buf)[:c]
+		b.pools[i].Put(buf)
+	}
+}
+
+// Copy 和 io.Copy 相同，但缓冲区来自 Bytes
+//
+// src 实现 io.WriterTo 或 dst 实现 io.ReaderFrom 时照常交给它们（bytes.Buffer、
+// http.ResponseWriter 的 sendfile），*os.File 除外：它的 ReadFrom / WriteTo 在对端不是
+// 文件或 socket 时退回 io.Copy，又分配一个 32KB 缓冲区。
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	if wt, ok := src.(io.WriterTo); ok && !isFile(src) {
+		return wt.WriteTo(dst)
+	}
+	if rf, ok := dst.(io.ReaderFrom); ok && !isFile(dst) {
+		return rf.ReadFrom(src)
+	}
+	buf := Bytes.Get(CopyBufferSize)
+	defer Bytes.Put(buf)
+	// 包一层隐藏 *os.File 的 ReadFrom / WriteTo，让 io.CopyBuffer 用我们的缓冲区
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, *buf)
+}
+
+func isFile(x any) bool {
+	_, ok := x.(*os.File)
+	return ok
+}
@@ -0,0 +1,61 @@
+// ============================================================================
+// Package pools 对象池：泛型包装的 sync.Pool 和分级的 []byte 缓冲区池
+// ============================================================================
+//
+// 【用法】
+//
+//	var bufPool = pools.New(
+//		func() *bytes.Buffer { return new(bytes.Buffer) },
+//		func(b *bytes.Buffer) bool { b.Reset(); return b.Cap() <= 64<<10 },
+//	)
+//	buf := bufPool.Get()
+//	defer bufPool.Put(buf)
+//
+//	n, err := pools.Copy(dst, src) // 代替 io.Copy，32KB 缓冲区来自池
+//
+// 【什么时候用】
+//
+// | 场景                                   | 说明                                                   |
+// |----------------------------------------|--------------------------------------------------------|
+// | 每个请求都要的大对象（缓冲区、编码器） | 分配成本高、GC 压力大，放进池里复用                    |
+// | 上传、下载、存储的流式复制             | io.Copy 每次分配 32KB，换成 pools.Copy                 |
+// | 小对象、生命周期跨请求的对象           | 不用池：sync.Pool 本身也有开销                         |
+//
+// 放回池中的对象不能再使用；Put 之前要清理掉上一次请求的数据（reset 函数负责）。
+// sync.Pool 的对象会在 GC 时被回收，池不保证对象一直在，也不限制数量。
+//
+// ============================================================================
+package pools
+
+import "sync"
+
+// Pool 类型安全的 sync.Pool
+//
+// T 应该是指针类型：值类型放进 sync.Pool 时装箱成 interface 会再分配一次，抵消了池的作用。
+type Pool[T any] struct {
+	pool  sync.Pool
+	reset func(T) bool
+}
+
+// New 创建对象池
+//
+// newFn 在池为空时创建新对象；reset 在 Put 时调用，清理对象并返回是否放回，
+// 偶尔的大对象返回 false 丢弃，避免池子长期持有大块内存。reset 为 nil 时总是放回。
+func New[T any](newFn func() T, reset func(T) bool) *Pool[T] {
+	p := &Pool[T]{reset: reset}
+	p.pool.New = func() any { return newFn() }
+	return p
+}
+
+// Get 取出一个对象，池为空时新建
+func (p *Pool[T]) Get() T {
+	return p.pool.Get().(T)
+}
+
+// Put 放回对象，之后调用方不能再使用它
+func (p *Pool[T]) Put(x T) {
+	if p.reset != nil && !p.reset(x) {
+		return
+	}
+	p.pool.Put(x)
+}
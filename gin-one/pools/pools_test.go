@@ -0,0 +1,152 @@
+package pools
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPool(t *testing.T) {
+	created := 0
+	p := New(
+		func() *bytes.Buffer { created++; return new(bytes.Buffer) },
+		func(b *bytes.Buffer) bool { b.Reset(); return b.Cap() <= 1024 },
+	)
+
+	b := p.Get()
+	b.WriteString("hello")
+	p.Put(b)
+	if b.Len() != 0 {
+		t.Error("reset not called on Put")
+	}
+
+	big := p.Get()
+	big.Grow(4096)
+	p.Put(big) // 超过上限，丢弃
+	if created == 0 {
+		t.Error("New not called")
+	}
+}
+
+func TestBuffers(t *testing.T) {
+	b := NewBuffers(1024, 64, 256, 64)
+	if got := b.sizes; len(got) != 3 || got[0] != 64 || got[2] != 1024 {
+		t.Fatalf("sizes = %v", got)
+	}
+
+	tests := []struct {
+		n, len, cap int
+	}{
+		{0, 0, 64},
+		{10, 10, 64},
+		{64, 64, 64},
+		{65, 65, 256},
+		{1024, 1024, 1024},
+		{2000, 2000, 2000}, // 超过最大一档直接分配
+	}
+	for _, tt := range tests {
+		buf := b.Get(tt.n)
+		if len(*buf) != tt.len || cap(*buf) != tt.cap {
+			t.Errorf("Get(%d) len %d cap %d, want %d %d", tt.n, len(*buf), cap(*buf), tt.len, tt.cap)
+		}
+		b.Put(buf)
+	}
+
+	// 放回后按完整容量取出
+	buf := b.Get(10)
+	b.Put(buf)
+	if len(*buf) != 64 {
+		t.Errorf("Put did not restore length: %d", len(*buf))
+	}
+
+	for _, size := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewBuffers(%d) did not panic", size)
+				}
+			}()
+			NewBuffers(size)
+		}()
+	}
+}
+
+func TestCopy(t *testing.T) {
+	data := strings.Repeat("0123456789", 10_000)
+
+	var dst bytes.Buffer
+	n, err := Copy(&dst, struct{ io.Reader }{strings.NewReader(data)})
+	if err != nil || n != int64(len(data)) || dst.String() != data {
+		t.Fatalf("copy = %d, %v", n, err)
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "copy-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if n, err := Copy(f, struct{ io.Reader }{strings.NewReader(data)}); err != nil || n != int64(len(data)) {
+		t.Fatalf("copy to file = %d, %v", n, err)
+	}
+	f.Seek(0, io.SeekStart)
+	dst.Reset()
+	if _, err := Copy(&dst, f); err != nil || dst.String() != data {
+		t.Fatalf("copy from file: %v", err)
+	}
+}
+
+// ============================================================================
+// Benchmark：io.Copy 每次分配 32KB，pools.Copy 复用缓冲区
+// ============================================================================
+
+// 模拟 multipart 文件：没有 WriterTo，io.Copy 只能自己分配缓冲区
+type plainReader struct{ r io.Reader }
+
+func (p plainReader) Read(b []byte) (int, error) { return p.r.Read(b) }
+
+var payload = bytes.Repeat([]byte("x"), 256<<10)
+
+func benchmarkCopy(b *testing.B, fn func(io.Writer, io.Reader) (int64, error)) {
+	f, err := os.CreateTemp(b.TempDir(), "bench-*")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	for b.Loop() {
+		f.Seek(0, io.SeekStart)
+		if _, err := fn(f, plainReader{bytes.NewReader(payload)}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIOCopy(b *testing.B) {
+	benchmarkCopy(b, io.Copy)
+}
+
+func BenchmarkPoolsCopy(b *testing.B) {
+	benchmarkCopy(b, Copy)
+}
+
+func BenchmarkMakeBuffer(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		buf := make([]byte, CopyBufferSize)
+		sink = buf
+	}
+}
+
+func BenchmarkBuffersGet(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		buf := Bytes.Get(CopyBufferSize)
+		sink = *buf
+		Bytes.Put(buf)
+	}
+}
+
+var sink []byte
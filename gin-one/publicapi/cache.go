@@ -0,0 +1,131 @@
+package publicapi
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 响应缓存
+// ============================================================================
+//
+// 以完整 URL（路径 + 查询参数）为 key，只缓存 200 响应。
+// 公开接口不区分用户，所有人看到的内容相同，可以放心共享缓存；
+// 同时设置 Cache-Control: public，让 CDN 和浏览器也能缓存。
+//
+
+type cachedResponse struct {
+	key         string
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+type responseCache struct {
+	ttl   time.Duration
+	size  int
+	mu    sync.Mutex
+	ll    *list.List // 最近使用的在前
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+func newResponseCache(ttl time.Duration, size int) *responseCache {
+	return &responseCache{
+		ttl:   ttl,
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+func (rc *responseCache) get(key string) (*cachedResponse, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	el, ok := rc.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cachedResponse)
+	if rc.now().After(entry.expires) {
+		rc.ll.Remove(el)
+		delete(rc.items, key)
+		return nil, false
+	}
+	rc.ll.MoveToFront(el)
+	return entry, true
+}
+
+func (rc *responseCache) add(entry *cachedResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if el, ok := rc.items[entry.key]; ok {
+		el.Value = entry
+		rc.ll.MoveToFront(el)
+		return
+	}
+	rc.items[entry.key] = rc.ll.PushFront(entry)
+	if rc.ll.Len() > rc.size {
+		oldest := rc.ll.Back()
+		rc.ll.Remove(oldest)
+		delete(rc.items, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+// recorder 在写出响应的同时保留一份副本
+type recorder struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.buf.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.buf.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+func (rc *responseCache) middleware() gin.HandlerFunc {
+	maxAge := "public, max-age=" + strconv.Itoa(int(rc.ttl.Seconds()))
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		key := c.Request.URL.RequestURI()
+		if entry, ok := rc.get(key); ok {
+			c.Header("X-Cache", "HIT")
+			c.Header("Cache-Control", maxAge)
+			c.Data(http.StatusOK, entry.contentType, entry.body)
+			c.Abort()
+			return
+		}
+
+		rec := &recorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Header("X-Cache", "MISS")
+		c.Header("Cache-Control", maxAge)
+		c.Next()
+
+		if rec.Status() == http.StatusOK {
+			rc.add(&cachedResponse{
+				key:         key,
+				contentType: rec.Header().Get("Content-Type"),
+				body:        bytes.Clone(rec.buf.Bytes()),
+				expires:     rc.now().Add(rc.ttl),
+			})
+		}
+	}
+}
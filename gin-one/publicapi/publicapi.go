@@ -0,0 +1,252 @@
+// ============================================================================
+// Package publicapi 匿名只读公开 API
+// ============================================================================
+//
+// 【与内部 API 的区别】
+//
+// | 项目       | /posts（内部）         | /public/v1（公开）                   |
+// |------------|------------------------|--------------------------------------|
+// | 认证       | 按需                   | 不需要                               |
+// | 写操作     | 支持                   | 只读                                 |
+// | 返回字段   | 完整模型（含邮箱等）   | 精简视图，不含任何个人信息           |
+// | 限流       | 无 / 按用户            | 按 IP：短时突发 + 每日额度           |
+// | 缓存       | 无                     | 进程内响应缓存 + Cache-Control       |
+// | 客户端过滤 | 无                     | 拒绝空 User-Agent 和已知扫描器       |
+//
+// 【中间件顺序】
+//
+//	User-Agent 检查 → 突发限流 → 每日额度 → 响应缓存 → Handler
+//
+// 缓存放在限流之后：命中缓存的请求同样消耗额度，否则刷缓存接口也能绕过限流。
+//
+// ============================================================================
+package publicapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/middleware/ratelimit"
+	"go-one/response"
+)
+
+// Config 公开 API 配置，零值字段使用默认值
+type Config struct {
+	DB *gorm.DB // 必填
+
+	// Store 限流存储，默认 ratelimit.NewMemoryStore()
+	Store ratelimit.Store
+
+	// Rate / Burst 每个 IP 的短时限流：每秒 Rate 个请求，最多突发 Burst 个，默认 2 / 10
+	Rate  float64
+	Burst int
+
+	// DailyBudget 每个 IP 每天的请求总量，默认 2000
+	DailyBudget int
+
+	// CacheTTL 响应缓存时间，默认 30s；CacheSize 最多缓存的响应数，默认 1000
+	CacheTTL  time.Duration
+	CacheSize int
+
+	// BlockedAgents User-Agent 黑名单（不区分大小写的子串），默认 DefaultBlockedAgents
+	BlockedAgents []string
+}
+
+// DefaultBlockedAgents 常见扫描器和批量抓取工具
+var DefaultBlockedAgents = []string{"sqlmap", "nikto", "nmap", "masscan", "zgrab", "scrapy", "httrack"}
+
+// 分页与搜索限制
+const (
+	defaultLimit = 20
+	maxLimit     = 50
+	minQueryLen  = 2
+	maxQueryLen  = 100
+)
+
+// Register 在路由组上挂载公开 API
+//
+//	publicapi.Register(r.Group("/public/v1"), publicapi.Config{DB: DB})
+func Register(g *gin.RouterGroup, cfg Config) {
+	if cfg.DB == nil {
+		panic("publicapi: Config.DB is required")
+	}
+	if cfg.Store == nil {
+		cfg.Store = ratelimit.NewMemoryStore()
+	}
+	if cfg.Rate == 0 {
+		cfg.Rate = 2
+	}
+	if cfg.Burst == 0 {
+		cfg.Burst = 10
+	}
+	if cfg.DailyBudget == 0 {
+		cfg.DailyBudget = 2000
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = 30 * time.Second
+	}
+	if cfg.CacheSize == 0 {
+		cfg.CacheSize = 1000
+	}
+	if cfg.BlockedAgents == nil {
+		cfg.BlockedAgents = DefaultBlockedAgents
+	}
+
+	g.Use(
+		filterAgents(cfg.BlockedAgents),
+		ratelimit.New(ratelimit.Config{
+			Algorithm: ratelimit.TokenBucket(cfg.Rate, cfg.Burst),
+			Store:     cfg.Store,
+			Prefix:    "public:burst:",
+		}),
+		// 每日额度也用令牌桶：容量为一天的额度，按天均匀补充，不会在零点集中重置
+		// 存储要保留桶直到补满（最长一天），MemoryStore 和 RedisStore 都是如此，空闲再久也不会重置为满额
+		ratelimit.New(ratelimit.Config{
+			Algorithm: ratelimit.TokenBucket(float64(cfg.DailyBudget)/86400, cfg.DailyBudget),
+			Store:     cfg.Store,
+			Prefix:    "public:daily:",
+		}),
+		newResponseCache(cfg.CacheTTL, cfg.CacheSize).middleware(),
+	)
+
+	h := &handler{db: cfg.DB}
+	g.GET("/posts", h.listPosts)
+	g.GET("/posts/:id", h.getPost)
+	g.GET("/search", h.search)
+}
+
+// filterAgents 拒绝空 User-Agent 和黑名单中的客户端
+// 这只能挡住不加伪装的工具，真正的防护仍然依靠限流
+func filterAgents(blocked []string) gin.HandlerFunc {
+	lower := make([]string, len(blocked))
+	for i, b := range blocked {
+		lower[i] = strings.ToLower(b)
+	}
+	return func(c *gin.Context) {
+		ua := strings.ToLower(strings.TrimSpace(c.Request.UserAgent()))
+		if ua == "" {
+			response.Abort(c, http.StatusForbidden, "user_agent_required", "请求缺少 User-Agent")
+			return
+		}
+		for _, b := range lower {
+			if strings.Contains(ua, b) {
+				response.Abort(c, http.StatusForbidden, "client_blocked", "该客户端已被禁止访问")
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// ============================================================================
+// Handler
+// ============================================================================
+
+// PostView 公开的文章视图，只包含可以公开的字段
+type PostView struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content,omitempty"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type handler struct {
+	db *gorm.DB
+}
+
+// posts 公共查询：关联作者用户名（已注销用户同样显示为 deleted_user_<id>）
+func (h *handler) posts(c *gin.Context) *gorm.DB {
+	return h.db.WithContext(c.Request.Context()).
+		Table("posts").
+		Select("posts.id, posts.title, posts.content, posts.created_at, users.username AS author").
+		Joins("LEFT JOIN users ON users.id = posts.user_id").
+		Where("posts.deleted_at IS NULL")
+}
+
+func (h *handler) listPosts(c *gin.Context) {
+	page, limit := pageParams(c)
+
+	var posts []PostView
+	err := h.posts(c).
+		Order("posts.created_at DESC, posts.id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Scan(&posts).Error
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "internal_error", "查询失败")
+		return
+	}
+	// 列表不返回正文，减小响应体积
+	for i := range posts {
+		posts[i].Content = ""
+	}
+	response.Success(c, gin.H{"items": posts, "page": page, "limit": limit})
+}
+
+func (h *handler) getPost(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid_id", "文章 ID 无效")
+		return
+	}
+
+	var post PostView
+	err = h.posts(c).Where("posts.id = ?", id).Take(&post).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(c, http.StatusNotFound, "not_found", "文章不存在")
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "internal_error", "查询失败")
+		return
+	}
+	response.Success(c, post)
+}
+
+func (h *handler) search(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if n := len([]rune(q)); n < minQueryLen || n > maxQueryLen {
+		response.Error(c, http.StatusBadRequest, "invalid_query", "关键词长度需要在 2~100 个字符之间")
+		return
+	}
+	_, limit := pageParams(c)
+
+	// 转义 LIKE 通配符，防止用户输入 % 造成全表扫描式的匹配
+	pattern := "%" + likeEscaper.Replace(q) + "%"
+	var posts []PostView
+	err := h.posts(c).
+		Where(`posts.title LIKE ? ESCAPE '\' OR posts.content LIKE ? ESCAPE '\'`, pattern, pattern).
+		Order("posts.created_at DESC, posts.id DESC").
+		Limit(limit).
+		Scan(&posts).Error
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "internal_error", "查询失败")
+		return
+	}
+	for i := range posts {
+		posts[i].Content = ""
+	}
+	response.Success(c, gin.H{"items": posts, "query": q})
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// pageParams 解析分页参数，limit 最大 50
+func pageParams(c *gin.Context) (page, limit int) {
+	page, _ = strconv.Atoi(c.Query("page"))
+	limit, _ = strconv.Atoi(c.Query("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultLimit
+	}
+	return page, min(limit, maxLimit)
+}
@@ -0,0 +1,288 @@
+package publicapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+	"go-one/middleware/ratelimit"
+)
+
+type testUser struct {
+	gorm.Model
+	Username string
+	Email    string
+}
+
+func (testUser) TableName() string { return "users" }
+
+type testPost struct {
+	gorm.Model
+	Title   string
+	Content string
+	UserID  uint
+}
+
+func (testPost) TableName() string { return "posts" }
+
+func newTestRouter(t testing.TB, cfg Config) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db := testdb.Open(t, &testUser{}, &testPost{})
+	alice := testUser{Username: "alice", Email: "alice@example.com"}
+	db.Create(&alice)
+	db.Create(&[]testPost{
+		{Title: "Hello Gin", Content: "first post", UserID: alice.ID},
+		{Title: "100% coverage", Content: "second post", UserID: alice.ID},
+	})
+
+	cfg.DB = db
+	r := gin.New()
+	Register(r.Group("/public/v1"), cfg)
+	return r
+}
+
+func get(r http.Handler, path, ua, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	req.RemoteAddr = ip + ":12345"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUserAgentFilter(t *testing.T) {
+	r := newTestRouter(t, Config{})
+
+	tests := []struct {
+		ua   string
+		want int
+	}{
+		{"", http.StatusForbidden},
+		{"sqlmap/1.7", http.StatusForbidden},
+		{"Mozilla/5.0 (compatible; Nikto/2.5)", http.StatusForbidden},
+		{"Mozilla/5.0", http.StatusOK},
+		{"curl/8.0", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := get(r, "/public/v1/posts", tt.ua, "10.0.0.1"); w.Code != tt.want {
+			t.Errorf("UA %q: status = %d; want %d", tt.ua, w.Code, tt.want)
+		}
+	}
+}
+
+func TestPostsHidePrivateFields(t *testing.T) {
+	r := newTestRouter(t, Config{})
+
+	for _, path := range []string{"/public/v1/posts", "/public/v1/posts/1", "/public/v1/search?q=hello"} {
+		w := get(r, path, "test", "10.0.0.1")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d; want 200", path, w.Code)
+		}
+		body := w.Body.String()
+		if strings.Contains(body, "alice@example.com") || strings.Contains(body, "email") {
+			t.Errorf("GET %s leaks email: %s", path, body)
+		}
+		if !strings.Contains(body, `"author":"alice"`) {
+			t.Errorf("GET %s: missing author: %s", path, body)
+		}
+	}
+
+	if w := get(r, "/public/v1/posts/99", "test", "10.0.0.1"); w.Code != http.StatusNotFound {
+		t.Errorf("GET missing post: status = %d; want 404", w.Code)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	r := newTestRouter(t, Config{})
+
+	tests := []struct {
+		q     string
+		code  int
+		count int
+	}{
+		{"hello", http.StatusOK, 1},
+		{"post", http.StatusOK, 2},
+		{"%25", http.StatusBadRequest, 0}, // 单个字符
+		{"0%25", http.StatusOK, 1},        // % 按字面匹配，只命中 "100%"
+		{"%25%25", http.StatusOK, 0},      // 不会变成匹配全部的通配符
+		{"x", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		w := get(r, "/public/v1/search?q="+tt.q, "test", "10.0.0.1")
+		if w.Code != tt.code {
+			t.Errorf("search %q: status = %d; want %d", tt.q, w.Code, tt.code)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		var resp struct {
+			Data struct {
+				Items []PostView `json:"items"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data.Items) != tt.count {
+			t.Errorf("search %q: %d results; want %d", tt.q, len(resp.Data.Items), tt.count)
+		}
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	r := newTestRouter(t, Config{})
+
+	first := get(r, "/public/v1/posts/1", "test", "10.0.0.1")
+	second := get(r, "/public/v1/posts/1", "test", "10.0.0.1")
+	if got := first.Header().Get("X-Cache"); got != "MISS" {
+		t.Errorf("first X-Cache = %q; want MISS", got)
+	}
+	if got := second.Header().Get("X-Cache"); got != "HIT" {
+		t.Errorf("second X-Cache = %q; want HIT", got)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("cached body = %s; want %s", second.Body, first.Body)
+	}
+	if got := second.Header().Get("Cache-Control"); got != "public, max-age=30" {
+		t.Errorf("Cache-Control = %q; want public, max-age=30", got)
+	}
+
+	// 错误响应不缓存
+	get(r, "/public/v1/posts/99", "test", "10.0.0.1")
+	if w := get(r, "/public/v1/posts/99", "test", "10.0.0.1"); w.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("404 was cached")
+	}
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	rc := newResponseCache(time.Minute, 2)
+	now := time.Now()
+	rc.now = func() time.Time { return now }
+
+	rc.add(&cachedResponse{key: "a", expires: now.Add(time.Minute)})
+	rc.add(&cachedResponse{key: "b", expires: now.Add(time.Minute)})
+	rc.get("a") // a 变为最近使用
+	rc.add(&cachedResponse{key: "c", expires: now.Add(time.Minute)})
+
+	if _, ok := rc.get("b"); ok {
+		t.Errorf("b should be evicted")
+	}
+	if _, ok := rc.get("a"); !ok {
+		t.Errorf("a should be kept")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := rc.get("a"); ok {
+		t.Errorf("a should expire")
+	}
+}
+
+// TestLoad 多个 IP 并发压测：每个 IP 最多通过 Burst 个请求，其余 429，且互不影响
+func TestLoad(t *testing.T) {
+	const (
+		ips       = 20
+		perIP     = 30
+		burst     = 5
+		daily     = 1000
+		expectOK  = burst
+		expect429 = perIP - burst
+	)
+	// Rate 很小，测试期间不会补充令牌
+	r := newTestRouter(t, Config{Rate: 0.001, Burst: burst, DailyBudget: daily})
+
+	var (
+		mu     sync.Mutex
+		counts = make(map[string]map[int]int)
+		wg     sync.WaitGroup
+	)
+	for i := range ips {
+		ip := fmt.Sprintf("192.0.2.%d", i+1)
+		counts[ip] = make(map[int]int)
+		for range perIP {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w := get(r, "/public/v1/posts", "loadtest", ip)
+				mu.Lock()
+				counts[ip][w.Code]++
+				mu.Unlock()
+			}()
+		}
+	}
+	wg.Wait()
+
+	for ip, c := range counts {
+		if c[http.StatusOK] != expectOK || c[http.StatusTooManyRequests] != expect429 {
+			t.Errorf("%s: 200=%d 429=%d; want %d/%d", ip, c[http.StatusOK], c[http.StatusTooManyRequests], expectOK, expect429)
+		}
+	}
+}
+
+func TestDailyBudget(t *testing.T) {
+	// 突发额度足够大，只有每日额度生效
+	r := newTestRouter(t, Config{Rate: 1000, Burst: 1000, DailyBudget: 3})
+
+	for i := range 3 {
+		if w := get(r, "/public/v1/posts", "test", "10.0.0.2"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d; want 200", i+1, w.Code)
+		}
+	}
+	if w := get(r, "/public/v1/posts", "test", "10.0.0.2"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("over budget: status = %d; want 429", w.Code)
+	}
+	if w := get(r, "/public/v1/posts", "test", "10.0.0.3"); w.Code != http.StatusOK {
+		t.Errorf("other IP: status = %d; want 200", w.Code)
+	}
+}
+
+// shiftedStore 把存储看到的时间往后拨 offset，模拟客户端空闲一段时间
+type shiftedStore struct {
+	ratelimit.Store
+	offset time.Duration
+}
+
+func (s *shiftedStore) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (bool, float64, error) {
+	return s.Store.TakeToken(ctx, key, rate, burst, now.Add(s.offset))
+}
+
+func TestDailyBudgetSurvivesIdle(t *testing.T) {
+	store := &shiftedStore{Store: ratelimit.NewMemoryStore()}
+	r := newTestRouter(t, Config{Store: store, Rate: 1000, Burst: 1000, DailyBudget: 3})
+
+	for i := range 3 {
+		if w := get(r, "/public/v1/posts", "test", "10.0.0.2"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d; want 200", i+1, w.Code)
+		}
+	}
+	// 空闲 11 分钟只补充了约 0.02 个令牌，额度仍然用尽
+	store.offset = 11 * time.Minute
+	if w := get(r, "/public/v1/posts", "test", "10.0.0.2"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("after 11 idle minutes: status = %d; want 429", w.Code)
+	}
+}
+
+func BenchmarkCachedPost(b *testing.B) {
+	r := newTestRouter(b, Config{Rate: 1e9, Burst: 1 << 30, DailyBudget: 1 << 30})
+	get(r, "/public/v1/posts/1", "bench", "10.0.0.1")
+
+	for b.Loop() {
+		if w := get(r, "/public/v1/posts/1", "bench", "10.0.0.1"); w.Code != http.StatusOK {
+			b.Fatalf("status = %d", w.Code)
+		}
+	}
+}
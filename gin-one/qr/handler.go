@@ -0,0 +1,158 @@
+package qr
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 缓存
+// ============================================================================
+//
+// 同一个短链接/同一个 otpauth URI 会被反复请求，
+// 生成 PNG 需要 RS 纠错编码 + 图片压缩，缓存后可以直接返回字节。
+// 这里用 container/list 实现一个最简单的 LRU。
+//
+
+// Cache 并发安全的 LRU 图片缓存
+type Cache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key  string
+	data []byte
+}
+
+// NewCache 创建最多保存 capacity 张图片的缓存
+func NewCache(capacity int) *Cache {
+	return &Cache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get 读取缓存，命中时移动到队首
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		return el.Value.(*cacheEntry).data, true
+	}
+	return nil, false
+}
+
+// Add 写入缓存，超出容量时淘汰最久未使用的条目
+func (c *Cache) Add(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*cacheEntry).data = data
+		return
+	}
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, data: data})
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// cacheKey 对内容和参数做摘要，同时作为 ETag
+func cacheKey(data string, opts Options) string {
+	sum := sha256.Sum256([]byte(data + "|" + strconv.Itoa(opts.Size) + "|" + opts.Level + "|" + string(opts.Format)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ============================================================================
+// HTTP Handler
+// ============================================================================
+
+// Handler 返回 GET /qr 的处理函数
+//
+// 查询参数：
+//
+//	data   必填，编码内容
+//	size   图片边长，64~1024，默认 256
+//	level  纠错级别 L/M/Q/H，默认 M
+//	format png / svg，默认 png
+//
+// data 是 otpauth:// URI 时返回 400：密钥不能出现在 URL 里，改用 DataURI。
+// cache 为 nil 时不缓存
+func Handler(cache *Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var query struct {
+			Data   string `form:"data"`
+			Size   int    `form:"size"`
+			Level  string `form:"level"`
+			Format string `form:"format"`
+		}
+		if err := c.ShouldBindQuery(&query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "message": err.Error()})
+			return
+		}
+
+		opts, err := Options{Size: query.Size, Level: query.Level, Format: Format(query.Format)}.normalize()
+		switch {
+		case err != nil:
+		case query.Data == "":
+			err = ErrEmptyData
+		case len(query.Data) > MaxDataLen:
+			err = ErrDataTooLong
+		case isSecret(query.Data):
+			err = ErrSecretData
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_qr_params", "message": err.Error()})
+			return
+		}
+
+		key := cacheKey(query.Data, opts)
+		etag := `"` + key + `"`
+		c.Header("ETag", etag)
+		// 相同参数生成的图片永远相同，可以长期缓存
+		c.Header("Cache-Control", "public, max-age=86400, immutable")
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+
+		if cache != nil {
+			if img, ok := cache.Get(key); ok {
+				c.Header("X-Cache", "HIT")
+				c.Data(http.StatusOK, opts.Format.ContentType(), img)
+				return
+			}
+		}
+
+		img, err := Encode(query.Data, opts)
+		if err != nil {
+			c.Header("Cache-Control", "no-store")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "qr_encode_failed", "message": err.Error()})
+			return
+		}
+		if cache != nil {
+			cache.Add(key, img)
+			c.Header("X-Cache", "MISS")
+		}
+		c.Data(http.StatusOK, opts.Format.ContentType(), img)
+	}
+}
+
+// isSecret 内容是否带密钥（otpauth:// URI），不区分大小写
+func isSecret(data string) bool {
+	return len(data) >= len(otpauthScheme) && strings.EqualFold(data[:len(otpauthScheme)], otpauthScheme)
+}
@@ -0,0 +1,71 @@
+package qr
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"net/url"
+	"strconv"
+)
+
+// ============================================================================
+// TOTP 预配 (Provisioning)
+// ============================================================================
+//
+// 【流程】
+//
+// 1. 服务端为用户生成随机密钥（Base32 编码）
+// 2. 拼出 otpauth:// URI，用 DataURI 编码成二维码，随预配接口的响应直接返回
+// 3. 用户用 Google Authenticator 等 App 扫码，App 保存密钥
+// 4. 之后登录时 App 每 30 秒生成一个 6 位验证码
+//
+// URI 格式（Key Uri Format）：
+//
+//	otpauth://totp/{issuer}:{account}?secret=XXX&issuer={issuer}&algorithm=SHA1&digits=6&period=30
+//
+
+// NewTOTPSecret 生成 160 位随机密钥（RFC 4226 推荐长度），Base32 无填充编码
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+// otpauthScheme otpauth URI 的前缀，带这个前缀的内容含密钥
+const otpauthScheme = "otpauth://"
+
+// ProvisioningURI 生成验证器 App 可识别的 otpauth:// URI，含密钥，用 DataURI 生成二维码
+func ProvisioningURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", "6")
+	params.Set("period", "30")
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: params.Encode(),
+	}
+	return u.String()
+}
+
+// ImageURL 生成指向 /qr 接口的图片地址，便于前端直接放进 <img src>；
+// 只用于可以公开的内容（短链接等），otpauth URI 用 DataURI
+func ImageURL(base, data string, opts Options) string {
+	params := url.Values{}
+	params.Set("data", data)
+	if opts.Size != 0 {
+		params.Set("size", strconv.Itoa(opts.Size))
+	}
+	if opts.Level != "" {
+		params.Set("level", opts.Level)
+	}
+	if opts.Format != "" {
+		params.Set("format", string(opts.Format))
+	}
+	return base + "?" + params.Encode()
+}
@@ -0,0 +1,171 @@
+// ============================================================================
+// Package qr 二维码生成（PNG / SVG）
+// ============================================================================
+//
+// 编码部分是对 github.com/skip2/go-qrcode 的薄封装（该库无其他依赖），
+// 本包只负责：参数校验、SVG 输出、结果缓存、HTTP Handler。
+//
+// 【纠错级别】
+//
+// | 级别 | 可恢复比例 | 适用场景                   |
+// |------|-----------|----------------------------|
+// | L    | ~7%       | 屏幕展示、数据量大         |
+// | M    | ~15%      | 默认                       |
+// | Q    | ~25%      | 打印品                     |
+// | H    | ~30%      | 中间要叠加 Logo            |
+//
+// 【典型用途】
+//
+// 1. 短链接：/qr?data=https://s.example.com/abc
+// 2. 两步验证：把 otpauth:// URI 编码成二维码，给验证器 App 扫描
+//
+// 【含密钥的内容不要走 /qr】
+//
+// GET /qr 的内容在查询字符串里，会进访问日志、浏览器历史，图片还会被代理/CDN 和 LRU 缓存。
+// otpauth:// URI 带着 TOTP 密钥，要用 DataURI 生成后直接放进接口响应，/qr 会拒绝这类内容。
+//
+// ============================================================================
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Format 输出格式
+type Format string
+
+const (
+	FormatPNG Format = "png"
+	FormatSVG Format = "svg"
+)
+
+// ContentType 返回格式对应的 MIME 类型
+func (f Format) ContentType() string {
+	if f == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// 尺寸与内容限制
+const (
+	MinSize     = 64
+	MaxSize     = 1024
+	DefaultSize = 256
+	MaxDataLen  = 1024 // 内容越长版本越高，扫码越困难
+)
+
+// 参数错误
+var (
+	ErrEmptyData    = errors.New("qr: data is required")
+	ErrDataTooLong  = fmt.Errorf("qr: data exceeds %d bytes", MaxDataLen)
+	ErrInvalidSize  = fmt.Errorf("qr: size must be between %d and %d", MinSize, MaxSize)
+	ErrInvalidLevel = errors.New("qr: level must be one of L, M, Q, H")
+	ErrInvalidFmt   = errors.New("qr: format must be png or svg")
+	ErrSecretData   = errors.New("qr: otpauth URIs contain secrets and must not be passed in the URL")
+)
+
+// Options 生成参数
+type Options struct {
+	Size   int    // 图片边长（像素），默认 256
+	Level  string // 纠错级别 L/M/Q/H，默认 M
+	Format Format // png / svg，默认 png
+}
+
+// normalize 填充默认值并校验
+func (o Options) normalize() (Options, error) {
+	if o.Size == 0 {
+		o.Size = DefaultSize
+	}
+	if o.Size < MinSize || o.Size > MaxSize {
+		return o, ErrInvalidSize
+	}
+	if o.Level == "" {
+		o.Level = "M"
+	}
+	o.Level = strings.ToUpper(o.Level)
+	if _, ok := levels[o.Level]; !ok {
+		return o, ErrInvalidLevel
+	}
+	if o.Format == "" {
+		o.Format = FormatPNG
+	}
+	if o.Format != FormatPNG && o.Format != FormatSVG {
+		return o, ErrInvalidFmt
+	}
+	return o, nil
+}
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Encode 把 data 编码为二维码图片
+func Encode(data string, opts Options) ([]byte, error) {
+	if data == "" {
+		return nil, ErrEmptyData
+	}
+	if len(data) > MaxDataLen {
+		return nil, ErrDataTooLong
+	}
+	opts, err := opts.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := qrcode.New(data, levels[opts.Level])
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Format == FormatSVG {
+		return renderSVG(code.Bitmap(), opts.Size), nil
+	}
+	return code.PNG(opts.Size)
+}
+
+// DataURI 把 data 编码为 data: URI，可以直接放进 <img src>，图片不经过任何 URL 和缓存
+func DataURI(data string, opts Options) (string, error) {
+	opts, err := opts.normalize()
+	if err != nil {
+		return "", err
+	}
+	img, err := Encode(data, opts)
+	if err != nil {
+		return "", err
+	}
+	return "data:" + opts.Format.ContentType() + ";base64," + base64.StdEncoding.EncodeToString(img), nil
+}
+
+// renderSVG 把模块矩阵渲染为 SVG
+// 【技巧】每一行连续的黑色模块合并成一个 <rect>，体积比逐点输出小很多
+func renderSVG(bitmap [][]bool, size int) []byte {
+	n := len(bitmap)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, n, n)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/>`, n, n)
+	for y, row := range bitmap {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="1"/>`, start, y, x-start)
+		}
+	}
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
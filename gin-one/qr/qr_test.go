@@ -0,0 +1,180 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEncode(t *testing.T) {
+	img, err := Encode("https://example.com/s/abc", Options{Size: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(img))
+	if err != nil {
+		t.Fatalf("not a PNG: %v", err)
+	}
+	if b := decoded.Bounds(); b.Dx() != 128 || b.Dy() != 128 {
+		t.Errorf("PNG size = %dx%d; want 128x128", b.Dx(), b.Dy())
+	}
+
+	svg, err := Encode("hello", Options{Format: FormatSVG, Level: "h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(svg); !strings.HasPrefix(s, `<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256"`) ||
+		!strings.HasSuffix(s, "</svg>") || !strings.Contains(s, `height="1"/>`) {
+		t.Errorf("SVG = %.120s...", s)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		opts Options
+		want error
+	}{
+		{"empty data", "", Options{}, ErrEmptyData},
+		{"data too long", strings.Repeat("a", MaxDataLen+1), Options{}, ErrDataTooLong},
+		{"size too small", "x", Options{Size: MinSize - 1}, ErrInvalidSize},
+		{"size too large", "x", Options{Size: MaxSize + 1}, ErrInvalidSize},
+		{"unknown level", "x", Options{Level: "X"}, ErrInvalidLevel},
+		{"unknown format", "x", Options{Format: "gif"}, ErrInvalidFmt},
+		{"min size", "x", Options{Size: MinSize}, nil},
+		{"max size", "x", Options{Size: MaxSize, Level: "q"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Encode(tt.data, tt.opts); !errors.Is(err, tt.want) {
+				t.Errorf("Encode = %v; want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 160 位 Base32 无填充是 32 个字符
+	if len(secret) != 32 || strings.ContainsAny(secret, "=") {
+		t.Errorf("secret = %q; want 32 Base32 chars without padding", secret)
+	}
+
+	u, err := url.Parse(ProvisioningURI("gin-app", "alice", secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/gin-app:alice" {
+		t.Errorf("URI = %s; want otpauth://totp/gin-app:alice", u)
+	}
+	want := map[string]string{"secret": secret, "issuer": "gin-app", "algorithm": "SHA1", "digits": "6", "period": "30"}
+	q := u.Query()
+	for key, v := range want {
+		if q.Get(key) != v {
+			t.Errorf("%s = %q; want %q", key, q.Get(key), v)
+		}
+	}
+	if !isSecret(u.String()) || !isSecret("OTPAUTH://totp/x") || isSecret("https://example.com") {
+		t.Error("isSecret should match otpauth URIs only")
+	}
+}
+
+func TestDataURI(t *testing.T) {
+	uri, err := DataURI(ProvisioningURI("gin-app", "alice", "JBSWY3DPEHPK3PXP"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, ok := strings.CutPrefix(uri, "data:image/png;base64,")
+	if !ok {
+		t.Fatalf("DataURI = %.40s...; want data:image/png;base64,", uri)
+	}
+	img, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(img)); err != nil {
+		t.Errorf("payload is not a PNG: %v", err)
+	}
+
+	if uri, _ := DataURI("x", Options{Format: FormatSVG}); !strings.HasPrefix(uri, "data:image/svg+xml;base64,") {
+		t.Errorf("SVG DataURI = %.40s...", uri)
+	}
+	if _, err := DataURI("x", Options{Size: 1}); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("DataURI invalid size = %v; want ErrInvalidSize", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/qr", Handler(NewCache(2)))
+
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/qr?data=hello", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" || w.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("first GET = %d %v", w.Code, w.Header())
+	}
+	etag := w.Header().Get("ETag")
+	if w := get("/qr?data=hello", nil); w.Header().Get("X-Cache") != "HIT" || w.Header().Get("ETag") != etag {
+		t.Errorf("second GET X-Cache = %q; want HIT", w.Header().Get("X-Cache"))
+	}
+	if w := get("/qr?data=hello", http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match = %d; want 304", w.Code)
+	}
+	if w := get("/qr?data=hello&format=svg", nil); w.Header().Get("Content-Type") != "image/svg+xml" || w.Header().Get("ETag") == etag {
+		t.Errorf("svg = %v; want a different image", w.Header())
+	}
+
+	secret := url.QueryEscape(ProvisioningURI("gin-app", "alice", "JBSWY3DPEHPK3PXP"))
+	for target, want := range map[string]int{
+		"/qr":                        http.StatusBadRequest,
+		"/qr?data=x&size=10":         http.StatusBadRequest,
+		"/qr?data=x&level=Z":         http.StatusBadRequest,
+		"/qr?data=x&format=gif":      http.StatusBadRequest,
+		"/qr?data=x&size=abc":        http.StatusBadRequest,
+		"/qr?data=" + secret:         http.StatusBadRequest,
+		"/qr?data=x&size=64&level=h": http.StatusOK,
+		"/qr?data=" + strings.Repeat("a", MaxDataLen+1): http.StatusBadRequest,
+	} {
+		w := get(target, nil)
+		if w.Code != want {
+			t.Errorf("GET %.60s = %d; want %d", target, w.Code, want)
+		}
+		if w.Code == http.StatusBadRequest && w.Header().Get("Cache-Control") != "" {
+			t.Errorf("GET %.60s: error response should not be cacheable", target)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache(2)
+	c.Add("a", []byte("1"))
+	c.Add("b", []byte("2"))
+	c.Get("a") // a 变成最近使用
+	c.Add("c", []byte("3"))
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || string(v) != "1" {
+		t.Errorf("a = %q, %v; want kept", v, ok)
+	}
+}
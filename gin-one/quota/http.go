@@ -0,0 +1,168 @@
+package quota
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// ============================================================================
+// 计量维度
+// ============================================================================
+
+// KeyFunc 从请求中取出计量的主体（租户、API Key），返回空字符串时不计量
+type KeyFunc func(c *gin.Context) string
+
+// ByHeader 按请求头计量，如 X-API-Key
+// 存储里保存的是摘要，数据库或 Redis 泄露时拿不到原始 Key
+func ByHeader(name string) KeyFunc {
+	return func(c *gin.Context) string {
+		v := c.GetHeader(name)
+		if v == "" {
+			return ""
+		}
+		sum := sha256.Sum256([]byte(v))
+		return "key:" + hex.EncodeToString(sum[:16])
+	}
+}
+
+// ByUser 按 JWT 认证中间件写入的 user_id 计量，必须挂在 JWT 中间件之后
+func ByUser(c *gin.Context) string {
+	if id, ok := c.Get("user_id"); ok {
+		return fmt.Sprintf("user:%v", id)
+	}
+	return ""
+}
+
+// ============================================================================
+// 中间件
+// ============================================================================
+//
+// 【响应头】
+//
+//	X-Quota-Limit      本月额度（不限时不返回这几个头）
+//	X-Quota-Remaining  本月剩余
+//	X-Quota-Reset      额度恢复的时间（Unix 秒，下个月 1 日 00:00 UTC）
+
+func setHeaders(c *gin.Context, u Usage) {
+	if u.Limit <= 0 {
+		return
+	}
+	h := c.Writer.Header()
+	h.Set("X-Quota-Limit", strconv.FormatInt(u.Limit, 10))
+	h.Set("X-Quota-Remaining", strconv.FormatInt(u.Remaining, 10))
+	h.Set("X-Quota-Reset", strconv.FormatInt(u.Reset.Unix(), 10))
+}
+
+func (q *Quota) reject(c *gin.Context, u Usage) {
+	setHeaders(c, u)
+	c.AbortWithStatusJSON(q.cfg.Status, gin.H{
+		"error":   "quota_exceeded",
+		"message": "本月额度已用完",
+		"metric":  u.Metric,
+		"limit":   u.Limit,
+		"used":    u.Used,
+		"reset":   u.Reset,
+	})
+}
+
+// Middleware 每个请求计入一次 Requests，超出额度时返回 Config.Status
+// 存储故障时放行（fail-open），和 ratelimit 一样不因为计量不可用导致全站不可用
+func Middleware(q *Quota, key KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject := key(c)
+		if subject == "" {
+			c.Next()
+			return
+		}
+		u, err := q.Add(c.Request.Context(), subject, Requests, 1)
+		if errors.Is(err, ErrExceeded) {
+			q.reject(c, u)
+			return
+		}
+		if err != nil {
+			_ = c.Error(err)
+			c.Next()
+			return
+		}
+		setHeaders(c, u)
+		c.Next()
+	}
+}
+
+// LimitUpload 按上传字节数计量
+//
+// 处理前按 Content-Length 判断剩余额度够不够；处理成功（状态码 < 400）后
+// 计入实际读取的请求体字节数（multipart 的分隔和头部也算在内，和流量计费一致）。
+// 没有 Content-Length 的分块上传只能在结束后计入，可能让用量超出额度。
+func LimitUpload(q *Quota, key KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject := key(c)
+		if subject == "" {
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+		u, err := q.Check(ctx, subject, UploadBytes, max(c.Request.ContentLength, 0))
+		if errors.Is(err, ErrExceeded) {
+			q.reject(c, u)
+			return
+		}
+		if err != nil {
+			_ = c.Error(err)
+		}
+
+		body := &countingReader{r: c.Request.Body}
+		c.Request.Body = body
+		c.Next()
+
+		if c.Writer.Status() >= http.StatusBadRequest || body.n == 0 {
+			return
+		}
+		if _, err := q.Add(ctx, subject, UploadBytes, body.n); err != nil && !errors.Is(err, ErrExceeded) {
+			_ = c.Error(err)
+		}
+	}
+}
+
+// countingReader 统计 handler 实际读取的字节数
+type countingReader struct {
+	r io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countingReader) Close() error { return r.r.Close() }
+
+// Handler 查询本月用量
+//
+//	GET /quota → {"subject": "key:…", "usage": [{"metric": "requests", "used": 120, "limit": 10000, ...}]}
+func Handler(q *Quota, key KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject := key(c)
+		if subject == "" {
+			response.Error(c, http.StatusUnauthorized, "unauthorized", "缺少计量主体（API Key 或登录用户）")
+			return
+		}
+		report, err := q.Report(c.Request.Context(), subject)
+		if err != nil {
+			_ = c.Error(err)
+			response.Error(c, http.StatusInternalServerError, "internal_error", "查询用量失败")
+			return
+		}
+		response.Success(c, gin.H{"subject": subject, "usage": report})
+	}
+}
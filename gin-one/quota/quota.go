@@ -0,0 +1,206 @@
+// ============================================================================
+// Package quota 按月计量的用量额度：每个租户 / API Key 每月的请求数、上传字节数
+// ============================================================================
+//
+// 【和 ratelimit 的区别】
+//
+// | 项目     | ratelimit                    | quota                                  |
+// |----------|------------------------------|----------------------------------------|
+// | 目的     | 保护服务，削掉瞬时突发       | 计量用量，按套餐限制总量               |
+// | 时间窗口 | 秒级（令牌桶 / 滑动窗口）    | 自然月（UTC），月初清零                |
+// | 计量项   | 只有请求数                   | 请求数、上传字节数，可以自己加         |
+// | 超出时   | 429，几秒后重试              | 429 或 402（需要升级套餐），下个月恢复 |
+// | 精确度   | 严格                         | 软限制：并发请求可能略微超出额度       |
+//
+// 两者一起用：ratelimit 挡住刷接口，quota 管每月能用多少。
+//
+// 【软限制】
+//
+// 先累加再判断，超出的那次请求被拒绝但同样计入用量（Used 可能大于 Limit），
+// 不需要先读后写的锁，存储只要支持原子加法。上传字节数在请求结束后才知道，
+// 只能在开始前按 Content-Length 预判，所以最后一次上传可能让用量超出额度。
+//
+// 【用法】
+//
+//	q := quota.New(quota.Config{
+//	    Store:  quota.NewGormStore(db),
+//	    Limits: quota.Limits{quota.Requests: 100000, quota.UploadBytes: 10 << 30},
+//	})
+//	byKey := quota.ByHeader("X-API-Key")
+//	r.GET("/quota", quota.Handler(q, byKey)) // 用量查询不计量，额度用完后也能查
+//	api := r.Group("/api", quota.Middleware(q, byKey))
+//	api.POST("/upload", quota.LimitUpload(q, byKey), handler)
+//
+// ============================================================================
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// 错误定义
+var (
+	ErrExceeded = errors.New("quota: exceeded")
+)
+
+// Metric 计量项
+type Metric string
+
+// 内置计量项
+const (
+	Requests    Metric = "requests"     // 请求数，Middleware 计量
+	UploadBytes Metric = "upload_bytes" // 上传字节数，LimitUpload 计量
+)
+
+// Limits 每个计量项每月的上限，不在 map 里或 <= 0 表示不限
+type Limits map[Metric]int64
+
+// Store 用量计数存储，period 是 "2006-01" 格式的月份
+type Store interface {
+	// Add 把 subject 在 period 的 metric 加上 n，返回加之后的累计值；必须是原子操作
+	Add(ctx context.Context, subject string, metric Metric, period string, n int64) (int64, error)
+	// Get 返回 subject 在 period 的全部计数，没有用过的计量项不在 map 里
+	Get(ctx context.Context, subject, period string) (map[Metric]int64, error)
+}
+
+// Config 额度配置
+type Config struct {
+	// Store 计数存储，必填：NewGormStore 或 NewRedisStore
+	Store Store
+
+	// Limits 所有 subject 的默认额度
+	Limits Limits
+
+	// LimitsFor 按 subject 返回额度（不同套餐不同额度），nil 时都用 Limits
+	LimitsFor func(ctx context.Context, subject string) (Limits, error)
+
+	// Status 超出额度时的状态码，默认 429；额度和付费套餐绑定、需要升级时用 402
+	Status int
+
+	// now 可替换的时钟，测试用
+	now func() time.Time
+}
+
+// Quota 额度检查和计量
+type Quota struct {
+	cfg Config
+}
+
+// New 创建额度检查
+func New(cfg Config) *Quota {
+	if cfg.Store == nil {
+		panic("quota: Config.Store is required")
+	}
+	if cfg.Status == 0 {
+		cfg.Status = http.StatusTooManyRequests
+	}
+	if cfg.now == nil {
+		cfg.now = time.Now
+	}
+	return &Quota{cfg: cfg}
+}
+
+// Usage 一个计量项本月的用量
+type Usage struct {
+	Metric    Metric    `json:"metric"`
+	Used      int64     `json:"used"`
+	Limit     int64     `json:"limit"`     // 0 表示不限
+	Remaining int64     `json:"remaining"` // 不限时为 -1
+	Reset     time.Time `json:"reset"`     // 下个月 1 日 00:00 UTC
+}
+
+// Exceeded 用量是否已超出额度
+func (u Usage) Exceeded() bool {
+	return u.Limit > 0 && u.Used > u.Limit
+}
+
+// period 当前月份和下个月开始的时间，统一用 UTC，所有实例、所有时区的用户月初是同一时刻
+func (q *Quota) period() (string, time.Time) {
+	now := q.cfg.now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start.Format("2006-01"), start.AddDate(0, 1, 0)
+}
+
+func (q *Quota) limits(ctx context.Context, subject string) (Limits, error) {
+	if q.cfg.LimitsFor == nil {
+		return q.cfg.Limits, nil
+	}
+	return q.cfg.LimitsFor(ctx, subject)
+}
+
+func usage(metric Metric, used, limit int64, reset time.Time) Usage {
+	u := Usage{Metric: metric, Used: used, Limit: max(limit, 0), Remaining: -1, Reset: reset}
+	if u.Limit > 0 {
+		u.Remaining = max(u.Limit-used, 0)
+	}
+	return u
+}
+
+// Add 计入 n 的用量，返回计入后的用量；计入后超出额度时同时返回 ErrExceeded（用量已经计入）
+func (q *Quota) Add(ctx context.Context, subject string, metric Metric, n int64) (Usage, error) {
+	limits, err := q.limits(ctx, subject)
+	if err != nil {
+		return Usage{}, err
+	}
+	period, reset := q.period()
+	used, err := q.cfg.Store.Add(ctx, subject, metric, period, n)
+	if err != nil {
+		return Usage{}, fmt.Errorf("quota: add %s: %w", metric, err)
+	}
+	u := usage(metric, used, limits[metric], reset)
+	if u.Exceeded() {
+		return u, ErrExceeded
+	}
+	return u, nil
+}
+
+// Check 判断再用 n 是否超出额度，不计入用量；超出时返回 ErrExceeded
+func (q *Quota) Check(ctx context.Context, subject string, metric Metric, n int64) (Usage, error) {
+	limits, err := q.limits(ctx, subject)
+	if err != nil {
+		return Usage{}, err
+	}
+	period, reset := q.period()
+	counts, err := q.cfg.Store.Get(ctx, subject, period)
+	if err != nil {
+		return Usage{}, fmt.Errorf("quota: get usage: %w", err)
+	}
+	u := usage(metric, counts[metric], limits[metric], reset)
+	if u.Limit > 0 && u.Used+n > u.Limit {
+		return u, ErrExceeded
+	}
+	return u, nil
+}
+
+// Report 本月全部计量项的用量：有额度的和用过的，按计量项名字排序
+func (q *Quota) Report(ctx context.Context, subject string) ([]Usage, error) {
+	limits, err := q.limits(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	period, reset := q.period()
+	counts, err := q.cfg.Store.Get(ctx, subject, period)
+	if err != nil {
+		return nil, fmt.Errorf("quota: get usage: %w", err)
+	}
+	metrics := make([]Metric, 0, len(limits)+len(counts))
+	for m := range limits {
+		metrics = append(metrics, m)
+	}
+	for m := range counts {
+		if _, ok := limits[m]; !ok {
+			metrics = append(metrics, m)
+		}
+	}
+	slices.Sort(metrics)
+	report := make([]Usage, len(metrics))
+	for i, m := range metrics {
+		report[i] = usage(m, counts[m], limits[m], reset)
+	}
+	return report, nil
+}
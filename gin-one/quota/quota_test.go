@@ -0,0 +1,234 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/internal/testdb"
+)
+
+func newTestStore(t *testing.T) *GormStore {
+	t.Helper()
+	db := testdb.Open(t, &Counter{})
+	return NewGormStore(db)
+}
+
+func newTestQuota(store Store, limits Limits, now *time.Time) *Quota {
+	q := New(Config{Store: store, Limits: limits})
+	q.cfg.now = func() time.Time { return *now }
+	return q
+}
+
+func TestAddAndMonthlyReset(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	q := newTestQuota(newTestStore(t), Limits{Requests: 2}, &now)
+
+	for i := range 2 {
+		if u, err := q.Add(ctx, "t1", Requests, 1); err != nil || u.Used != int64(i+1) {
+			t.Fatalf("Add #%d = %+v, %v", i+1, u, err)
+		}
+	}
+	u, err := q.Add(ctx, "t1", Requests, 1)
+	if !errors.Is(err, ErrExceeded) || u.Used != 3 || u.Remaining != 0 {
+		t.Fatalf("Add over limit = %+v, %v", u, err)
+	}
+	if want := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC); !u.Reset.Equal(want) {
+		t.Errorf("reset = %v, want %v", u.Reset, want)
+	}
+	// 其他租户互不影响
+	if _, err := q.Add(ctx, "t2", Requests, 1); err != nil {
+		t.Errorf("other subject err = %v", err)
+	}
+
+	// 下个月清零
+	now = now.Add(2 * time.Hour)
+	if u, err := q.Add(ctx, "t1", Requests, 1); err != nil || u.Used != 1 {
+		t.Errorf("Add next month = %+v, %v", u, err)
+	}
+}
+
+func TestCheckAndReport(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	q := newTestQuota(newTestStore(t), Limits{Requests: 100, UploadBytes: 1000}, &now)
+
+	q.Add(ctx, "t1", UploadBytes, 600)
+	if _, err := q.Check(ctx, "t1", UploadBytes, 400); err != nil {
+		t.Errorf("Check exactly at limit err = %v", err)
+	}
+	if u, err := q.Check(ctx, "t1", UploadBytes, 401); !errors.Is(err, ErrExceeded) || u.Used != 600 {
+		t.Errorf("Check over limit = %+v, %v", u, err)
+	}
+
+	q.Add(ctx, "t1", "exports", 3) // 没有配置额度的计量项也出现在报告里
+	report, err := q.Report(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(report))
+	for i, u := range report {
+		got[i] = string(u.Metric) + "=" + strconv.FormatInt(u.Used, 10) + "/" + strconv.FormatInt(u.Remaining, 10)
+	}
+	if want := "exports=3/-1 requests=0/100 upload_bytes=600/400"; strings.Join(got, " ") != want {
+		t.Errorf("report = %v, want %s", got, want)
+	}
+}
+
+func TestLimitsFor(t *testing.T) {
+	ctx := context.Background()
+	q := New(Config{
+		Store: newTestStore(t),
+		LimitsFor: func(_ context.Context, subject string) (Limits, error) {
+			if subject == "pro" {
+				return Limits{Requests: 10}, nil
+			}
+			return Limits{Requests: 1}, nil
+		},
+	})
+	q.Add(ctx, "free", Requests, 1)
+	q.Add(ctx, "pro", Requests, 1)
+	if _, err := q.Add(ctx, "free", Requests, 1); !errors.Is(err, ErrExceeded) {
+		t.Errorf("free plan err = %v", err)
+	}
+	if _, err := q.Add(ctx, "pro", Requests, 1); err != nil {
+		t.Errorf("pro plan err = %v", err)
+	}
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	q := New(Config{Store: newTestStore(t), Limits: Limits{Requests: 2, UploadBytes: 10}, Status: http.StatusPaymentRequired})
+	q.cfg.now = func() time.Time { return now }
+	key := ByHeader("X-API-Key")
+
+	r := gin.New()
+	// 用量查询不计量，额度用完后也能查
+	r.GET("/quota", Handler(q, key))
+	api := r.Group("", Middleware(q, key))
+	api.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	api.POST("/upload", LimitUpload(q, key), func(c *gin.Context) {
+		io.Copy(io.Discard, c.Request.Body)
+		c.Status(http.StatusCreated)
+	})
+
+	do := func(method, path, apiKey, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("GET", "/ping", "k1", "")
+	if w.Code != 200 || w.Header().Get("X-Quota-Limit") != "2" || w.Header().Get("X-Quota-Remaining") != "1" {
+		t.Fatalf("first = %d %v", w.Code, w.Header())
+	}
+	if w.Header().Get("X-Quota-Reset") != strconv.FormatInt(time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC).Unix(), 10) {
+		t.Errorf("reset header = %s", w.Header().Get("X-Quota-Reset"))
+	}
+
+	// 上传：8 字节成功计入，再传 5 字节按 Content-Length 判断超出额度，请求体不会被读取
+	if w := do("POST", "/upload", "k2", "12345678"); w.Code != http.StatusCreated {
+		t.Fatalf("upload = %d %s", w.Code, w.Body)
+	}
+	if w := do("POST", "/upload", "k2", "12345"); w.Code != http.StatusPaymentRequired || !strings.Contains(w.Body.String(), `"metric":"upload_bytes"`) {
+		t.Fatalf("upload over quota = %d %s", w.Code, w.Body)
+	}
+
+	// 第二个请求用完额度，第三个被拒绝
+	do("GET", "/ping", "k1", "")
+	w = do("GET", "/ping", "k1", "")
+	if w.Code != http.StatusPaymentRequired || w.Header().Get("X-Quota-Remaining") != "0" {
+		t.Fatalf("over quota = %d %v", w.Code, w.Header())
+	}
+
+	// 没有 API Key 不计量
+	if w := do("GET", "/ping", "", ""); w.Code != 200 || w.Header().Get("X-Quota-Limit") != "" {
+		t.Errorf("anonymous = %d %v", w.Code, w.Header())
+	}
+
+	// k2 的两次上传计入了请求数和上传字节数；被拒绝的那次只计入请求数
+	w = do("GET", "/quota", "k2", "")
+	var body struct {
+		Data struct {
+			Subject string  `json:"subject"`
+			Usage   []Usage `json:"usage"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != 200 {
+		t.Fatalf("quota = %d %s", w.Code, w.Body)
+	}
+	if strings.Contains(body.Data.Subject, "k2") || len(body.Data.Usage) != 2 || body.Data.Usage[0].Used != 2 {
+		t.Fatalf("quota body = %+v", body.Data)
+	}
+	if u := body.Data.Usage[1]; u.Metric != UploadBytes || u.Used != 8 || u.Remaining != 2 {
+		t.Errorf("upload usage = %+v", u)
+	}
+}
+
+// fakeRedis 按脚本模拟 HINCRBY / HGETALL
+type fakeRedis struct {
+	mu   sync.Mutex
+	data map[string]map[string]int64
+}
+
+func (f *fakeRedis) Eval(_ context.Context, script string, keys []string, args ...any) (any, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	h := f.data[keys[0]]
+	switch script {
+	case addScript:
+		if h == nil {
+			h = map[string]int64{}
+			f.data[keys[0]] = h
+		}
+		h[args[0].(string)] += args[1].(int64)
+		return h[args[0].(string)], nil
+	case getScript:
+		var reply []any
+		for k, v := range h {
+			reply = append(reply, k, strconv.FormatInt(v, 10))
+		}
+		return reply, nil
+	}
+	panic("unknown script")
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeRedis{data: map[string]map[string]int64{}}
+	s := NewRedisStore(fake, "quota:")
+	s.Add(ctx, "t1", Requests, "2026-05", 1)
+	if n, err := s.Add(ctx, "t1", Requests, "2026-05", 2); err != nil || n != 3 {
+		t.Fatalf("Add = %d, %v", n, err)
+	}
+	s.Add(ctx, "t1", UploadBytes, "2026-05", 100)
+	counts, err := s.Get(ctx, "t1", "2026-05")
+	if err != nil || counts[Requests] != 3 || counts[UploadBytes] != 100 {
+		t.Errorf("Get = %v, %v", counts, err)
+	}
+	if _, ok := fake.data["quota:2026-05:t1"]; !ok {
+		t.Errorf("keys = %v", fake.data)
+	}
+	if counts, _ := s.Get(ctx, "t1", "2026-06"); len(counts) != 0 {
+		t.Errorf("next month = %v", counts)
+	}
+}
@@ -0,0 +1,158 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"go-one/rediseval"
+)
+
+// ============================================================================
+// 数据库存储
+// ============================================================================
+//
+// 每个 subject、计量项、月份一行，加法用 INSERT ... ON CONFLICT DO UPDATE 一条语句完成，
+// 不需要先查再改。每个请求都要写一次数据库，请求量大时换成 RedisStore。
+// 历史月份的行保留下来，可以直接用来出账单。
+
+// Counter 表 quota_counters 的一行
+type Counter struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Subject   string    `gorm:"size:100;not null;uniqueIndex:idx_quota_counter" json:"subject"`
+	Period    string    `gorm:"size:7;not null;uniqueIndex:idx_quota_counter" json:"period"`
+	Metric    Metric    `gorm:"size:50;not null;uniqueIndex:idx_quota_counter" json:"metric"`
+	Used      int64     `gorm:"not null;default:0" json:"used"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Counter) TableName() string {
+	return "quota_counters"
+}
+
+// GormStore 基于数据库的计数存储，表需要事先 AutoMigrate(&quota.Counter{})
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 创建数据库存储
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+// Add 实现 Store
+func (s *GormStore) Add(ctx context.Context, subject string, metric Metric, period string, n int64) (int64, error) {
+	var used int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		row := Counter{Subject: subject, Period: period, Metric: metric, Used: n}
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "subject"}, {Name: "period"}, {Name: "metric"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"used":       gorm.Expr("quota_counters.used + ?", n),
+				"updated_at": time.Now(),
+			}),
+		}).Create(&row).Error
+		if err != nil {
+			return err
+		}
+		// 事务里读到的是自己刚写入的值，不会被其他请求的加法插进来
+		return tx.Model(&Counter{}).
+			Where("subject = ? AND period = ? AND metric = ?", subject, period, metric).
+			Pluck("used", &used).Error
+	})
+	return used, err
+}
+
+// Get 实现 Store
+func (s *GormStore) Get(ctx context.Context, subject, period string) (map[Metric]int64, error) {
+	var rows []Counter
+	err := s.db.WithContext(ctx).Where("subject = ? AND period = ?", subject, period).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[Metric]int64, len(rows))
+	for _, r := range rows {
+		counts[r.Metric] = r.Used
+	}
+	return counts, nil
+}
+
+// ============================================================================
+// Redis 存储
+// ============================================================================
+//
+// 每个 subject 每个月一个 hash，字段是计量项：HINCRBY 原子加法，HGETALL 取全部。
+// 第一次写入时设置过期时间（两个月），历史用量不长期保留，需要出账单时月底导出到数据库。
+//
+// 客户端接口和 go-redis 的适配方法见 rediseval。
+
+// redisTTL hash 的过期时间，超过一个月，保证月末最后一刻的写入在查询时还在
+const redisTTL = 62 * 24 * time.Hour
+
+const (
+	addScript = `
+local v = redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[2])
+if redis.call('TTL', KEYS[1]) < 0 then
+  redis.call('EXPIRE', KEYS[1], ARGV[3])
+end
+return v
+`
+	getScript = `return redis.call('HGETALL', KEYS[1])`
+)
+
+// RedisStore 基于 Redis 的计数存储
+type RedisStore struct {
+	client rediseval.Client
+	prefix string
+}
+
+// NewRedisStore 创建 Redis 存储，所有 key 加上 prefix（如 "quota:"）
+func NewRedisStore(client rediseval.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) key(subject, period string) string {
+	return s.prefix + period + ":" + subject
+}
+
+// Add 实现 Store
+func (s *RedisStore) Add(ctx context.Context, subject string, metric Metric, period string, n int64) (int64, error) {
+	reply, err := s.client.Eval(ctx, addScript, []string{s.key(subject, period)}, string(metric), n, int64(redisTTL.Seconds()))
+	if err != nil {
+		return 0, err
+	}
+	used, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected redis reply: %v", reply)
+	}
+	return used, nil
+}
+
+// Get 实现 Store
+func (s *RedisStore) Get(ctx context.Context, subject, period string) (map[Metric]int64, error) {
+	reply, err := s.client.Eval(ctx, getScript, []string{s.key(subject, period)})
+	if err != nil {
+		return nil, err
+	}
+	// HGETALL 返回 [field1, value1, field2, value2, ...]
+	items, ok := reply.([]any)
+	if !ok || len(items)%2 != 0 {
+		return nil, fmt.Errorf("unexpected redis reply: %v", reply)
+	}
+	counts := make(map[Metric]int64, len(items)/2)
+	for i := 0; i < len(items); i += 2 {
+		field, _ := items[i].(string)
+		value, _ := items[i+1].(string)
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected redis value %q for %s: %w", value, field, err)
+		}
+		counts[Metric(field)] = n
+	}
+	return counts, nil
+}
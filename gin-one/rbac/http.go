@@ -0,0 +1,103 @@
+package rbac
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/policy"
+	"go-one/response"
+)
+
+// RequirePermission 要求当前用户拥有权限 perm（"资源:动作"），放在 JWT 认证中间件之后
+//
+// 拒绝时与 policy.Authorize 格式一致：
+//
+//	403 {"code": -1, "error": "forbidden", "reason": "permission_required", "permission": "users:delete"}
+func (e *Enforcer) RequirePermission(perm string) gin.HandlerFunc {
+	if !validPermission(perm) {
+		panic("rbac: malformed permission " + strconv.Quote(perm))
+	}
+	return func(c *gin.Context) {
+		s := policy.SubjectFromContext(c)
+		if e.CanPermission(s, perm) {
+			c.Next()
+			return
+		}
+		reason := ReasonPermissionRequired
+		if !s.Authenticated() {
+			reason = policy.ReasonUnauthenticated
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"code":       response.CodeError,
+			"error":      "forbidden",
+			"reason":     reason,
+			"permission": perm,
+			"message":    "没有权限执行此操作",
+		})
+	}
+}
+
+// RegisterAdmin 注册角色管理接口，调用方负责在 group 上加认证和权限中间件
+//
+//	GET    /roles                  当前策略（角色及权限）
+//	GET    /users/:id/roles        用户额外分配的角色
+//	PUT    /users/:id/roles/:role  分配角色
+//	DELETE /users/:id/roles/:role  收回角色
+//
+// 只管理 user_roles 中的额外角色，JWT 里的基础角色由用户资料决定
+func RegisterAdmin(group *gin.RouterGroup, e *Enforcer) {
+	group.GET("/roles", func(c *gin.Context) {
+		response.Success(c, e.Policy().Roles)
+	})
+
+	group.GET("/users/:id/roles", func(c *gin.Context) {
+		id, ok := userID(c)
+		if !ok {
+			return
+		}
+		response.Success(c, gin.H{"user_id": id, "roles": e.Assigned(id)})
+	})
+
+	group.PUT("/users/:id/roles/:role", func(c *gin.Context) {
+		id, ok := userID(c)
+		if !ok {
+			return
+		}
+		err := e.Assign(c.Request.Context(), id, c.Param("role"))
+		if errors.Is(err, ErrUnknownRole) {
+			response.Error(c, http.StatusBadRequest, "unknown_role", "角色不存在")
+			return
+		}
+		if err != nil {
+			_ = c.Error(err)
+			response.Error(c, http.StatusInternalServerError, "internal_error", "分配角色失败")
+			return
+		}
+		response.Success(c, gin.H{"user_id": id, "roles": e.Assigned(id)})
+	})
+
+	group.DELETE("/users/:id/roles/:role", func(c *gin.Context) {
+		id, ok := userID(c)
+		if !ok {
+			return
+		}
+		if err := e.Unassign(c.Request.Context(), id, c.Param("role")); err != nil {
+			_ = c.Error(err)
+			response.Error(c, http.StatusInternalServerError, "internal_error", "收回角色失败")
+			return
+		}
+		response.Success(c, gin.H{"user_id": id, "roles": e.Assigned(id)})
+	})
+}
+
+func userID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		response.Error(c, http.StatusBadRequest, "invalid_id", "用户 ID 不合法")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,293 @@
+// ============================================================================
+// Package rbac 基于角色的权限控制（Role-Based Access Control）
+// ============================================================================
+//
+// 【和 RoleMiddleware / policy 的区别】
+//
+// | 方式                     | 回答的问题                     | 变更方式         |
+// |--------------------------|--------------------------------|------------------|
+// | RoleMiddleware("admin")  | 是不是管理员？                 | 改代码           |
+// | policy.Owner             | 是不是这篇文章的作者？         | 改代码           |
+// | rbac                     | 能不能对 users 做 delete？     | 改 YAML / 数据库 |
+//
+// 接口只声明需要的权限，哪个角色有哪些权限由策略文件决定：
+//
+//	admin.DELETE("/users/:id", perms.RequirePermission("users:delete"), deleteUser)
+//
+// 【模型】
+//
+//	用户 ──(多对多)── 角色 ──(多对多)── 权限 "资源:动作"
+//
+//	roles:
+//	  user:
+//	    permissions: [posts:read, posts:write]
+//	  editor:
+//	    inherits: [user]          # 继承 user 的全部权限
+//	    permissions: [posts:delete, users:read]
+//	  admin:
+//	    permissions: ["*"]        # 所有权限
+//
+// 权限支持两种通配符：posts:* 表示文章的所有动作，* 表示所有资源的所有动作。
+//
+// 【用户的角色从哪来】
+//
+// 1. JWT 里的 role（登录时写入，作为基础角色）
+// 2. user_roles 表里额外分配的角色（管理员通过接口授予 / 收回，立即生效）
+//
+// ============================================================================
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"go-one/policy"
+)
+
+// 错误定义
+var (
+	ErrUnknownRole = errors.New("rbac: unknown role")
+	ErrInvalid     = errors.New("rbac: invalid policy")
+)
+
+// ReasonPermissionRequired 缺少权限时的拒绝原因
+const ReasonPermissionRequired = "permission_required"
+
+// Wildcard 通配符
+const Wildcard = "*"
+
+// Role 角色定义
+type Role struct {
+	Permissions []string `yaml:"permissions" json:"permissions"`
+	Inherits    []string `yaml:"inherits" json:"inherits,omitempty"`
+}
+
+// Policy 角色与权限的对应关系
+type Policy struct {
+	Roles map[string]Role `yaml:"roles" json:"roles"`
+}
+
+// Config Enforcer 配置
+type Config struct {
+	// Policy 角色权限定义，见 ParsePolicy / LoadPolicyFile / LoadPolicyDB
+	Policy *Policy
+
+	// Store 角色分配的持久化，nil 时只保存在内存中
+	Store Store
+}
+
+// Enforcer 权限判定
+//
+// 权限判定在每个请求上执行，全部在内存中完成：
+// 策略展开继承关系后缓存，角色分配启动时从 Store 加载，修改时同时写 Store 和内存。
+type Enforcer struct {
+	store Store
+
+	mu     sync.RWMutex
+	policy *Policy
+	perms  map[string]map[string]bool // 角色 → 展开继承后的权限集合
+	users  map[uint][]string          // 用户 → 额外分配的角色
+}
+
+// New 创建 Enforcer 并从 Store 加载已有的角色分配
+func New(ctx context.Context, cfg Config) (*Enforcer, error) {
+	if cfg.Policy == nil {
+		return nil, fmt.Errorf("%w: policy is required", ErrInvalid)
+	}
+	e := &Enforcer{store: cfg.Store, users: map[uint][]string{}}
+	if err := e.SetPolicy(cfg.Policy); err != nil {
+		return nil, err
+	}
+	if e.store != nil {
+		list, err := e.store.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("rbac: load assignments: %w", err)
+		}
+		for _, a := range list {
+			e.users[a.UserID] = append(e.users[a.UserID], a.Role)
+		}
+	}
+	return e, nil
+}
+
+// SetPolicy 替换策略（如配置文件热加载），策略不合法时保留旧策略
+// 已分配但新策略里不存在的角色不再有任何权限
+func (e *Enforcer) SetPolicy(p *Policy) error {
+	perms, err := expand(p)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.policy, e.perms = p, perms
+	e.mu.Unlock()
+	return nil
+}
+
+// Policy 当前策略
+func (e *Enforcer) Policy() *Policy {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.policy
+}
+
+// expand 展开继承关系，检查继承的角色是否存在、是否有环
+func expand(p *Policy) (map[string]map[string]bool, error) {
+	perms := make(map[string]map[string]bool, len(p.Roles))
+	var visit func(name string, path []string) (map[string]bool, error)
+	visit = func(name string, path []string) (map[string]bool, error) {
+		if set, ok := perms[name]; ok {
+			return set, nil
+		}
+		if slices.Contains(path, name) {
+			return nil, fmt.Errorf("%w: inheritance cycle %s", ErrInvalid, strings.Join(append(path, name), " -> "))
+		}
+		role, ok := p.Roles[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: role %q inherits unknown role %q", ErrInvalid, path[len(path)-1], name)
+		}
+		set := map[string]bool{}
+		for _, perm := range role.Permissions {
+			if !validPermission(perm) {
+				return nil, fmt.Errorf("%w: role %q has malformed permission %q", ErrInvalid, name, perm)
+			}
+			set[perm] = true
+		}
+		for _, parent := range role.Inherits {
+			inherited, err := visit(parent, append(path, name))
+			if err != nil {
+				return nil, err
+			}
+			for perm := range inherited {
+				set[perm] = true
+			}
+		}
+		perms[name] = set
+		return set, nil
+	}
+	for name := range p.Roles {
+		if _, err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return perms, nil
+}
+
+// validPermission 合法格式：* 或 资源:动作（动作可以是 *）
+func validPermission(perm string) bool {
+	if perm == Wildcard {
+		return true
+	}
+	resource, action, ok := strings.Cut(perm, ":")
+	return ok && resource != "" && resource != Wildcard && action != "" && !strings.Contains(action, ":")
+}
+
+// Roles 用户拥有的全部角色：JWT 中的基础角色 + 额外分配的角色
+func (e *Enforcer) Roles(s policy.Subject) []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.rolesLocked(s)
+}
+
+func (e *Enforcer) rolesLocked(s policy.Subject) []string {
+	roles := make([]string, 0, 1+len(e.users[s.UserID]))
+	if s.Role != "" {
+		roles = append(roles, s.Role)
+	}
+	for _, r := range e.users[s.UserID] {
+		if !slices.Contains(roles, r) {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// Can 用户能否对 resource 执行 action
+//
+//	perms.Can(policy.SubjectFromContext(c), "users", "delete")
+func (e *Enforcer) Can(s policy.Subject, resource, action string) bool {
+	if !s.Authenticated() {
+		return false
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, role := range e.rolesLocked(s) {
+		set := e.perms[role]
+		if set[Wildcard] || set[resource+":"+Wildcard] || set[resource+":"+action] {
+			return true
+		}
+	}
+	return false
+}
+
+// CanPermission 同 Can，权限写成 "资源:动作"
+func (e *Enforcer) CanPermission(s policy.Subject, perm string) bool {
+	resource, action, ok := strings.Cut(perm, ":")
+	return ok && e.Can(s, resource, action)
+}
+
+// Assign 给用户分配额外角色，重复分配不报错
+func (e *Enforcer) Assign(ctx context.Context, userID uint, role string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.policy.Roles[role]; !ok {
+		return ErrUnknownRole
+	}
+	if slices.Contains(e.users[userID], role) {
+		return nil
+	}
+	// 先写存储再改内存，存储失败时内存状态不变
+	if e.store != nil {
+		if err := e.store.Add(ctx, userID, role); err != nil {
+			return err
+		}
+	}
+	e.users[userID] = append(e.users[userID], role)
+	return nil
+}
+
+// Unassign 收回用户的额外角色，JWT 中的基础角色不受影响（需要修改用户资料并重新登录）
+func (e *Enforcer) Unassign(ctx context.Context, userID uint, role string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	i := slices.Index(e.users[userID], role)
+	if i < 0 {
+		return nil
+	}
+	if e.store != nil {
+		if err := e.store.Remove(ctx, userID, role); err != nil {
+			return err
+		}
+	}
+	e.users[userID] = slices.Delete(slices.Clone(e.users[userID]), i, i+1)
+	if len(e.users[userID]) == 0 {
+		delete(e.users, userID)
+	}
+	return nil
+}
+
+// Assigned 用户额外分配的角色
+func (e *Enforcer) Assigned(userID uint) []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return slices.Clone(e.users[userID])
+}
+
+// Permission 转成 policy.Policy，可以和 Owner 等资源级策略组合：
+//
+//	// 有 posts:delete 权限（编辑、管理员），或者是作者本人
+//	policy.Any(rbac.Permission[*Post](perms, "posts:delete"), policy.Owner(postAuthor))
+func Permission[R any](e *Enforcer, perm string) policy.Policy[R] {
+	return func(s policy.Subject, _ R) policy.Decision {
+		if !s.Authenticated() {
+			return policy.Deny(policy.ReasonUnauthenticated)
+		}
+		if !e.CanPermission(s, perm) {
+			return policy.Deny(ReasonPermissionRequired)
+		}
+		return policy.Allow()
+	}
+}
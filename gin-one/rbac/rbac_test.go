@@ -0,0 +1,274 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+	"go-one/policy"
+)
+
+const testPolicy = `
+roles:
+  user:
+    permissions: [posts:read, posts:write]
+  editor:
+    inherits: [user]
+    permissions: [posts:delete, users:read]
+  moderator:
+    permissions: ["comments:*"]
+  admin:
+    permissions: ["*"]
+`
+
+var (
+	alice = policy.Subject{UserID: 1, Role: "user"}
+	bob   = policy.Subject{UserID: 2, Role: "editor"}
+	root  = policy.Subject{UserID: 3, Role: "admin"}
+	guest = policy.Subject{}
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.Open(t, &UserRole{}, &RolePermission{})
+}
+
+func newEnforcer(t *testing.T, store Store) *Enforcer {
+	t.Helper()
+	p, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := New(context.Background(), Config{Policy: p, Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestCan(t *testing.T) {
+	e := newEnforcer(t, nil)
+
+	tests := []struct {
+		subject  policy.Subject
+		resource string
+		action   string
+		want     bool
+	}{
+		{alice, "posts", "write", true},
+		{alice, "posts", "delete", false},
+		{bob, "posts", "write", true}, // 继承自 user
+		{bob, "posts", "delete", true},
+		{bob, "users", "delete", false},
+		{root, "users", "delete", true},
+		{guest, "posts", "read", false},
+		{policy.Subject{UserID: 4, Role: "moderator"}, "comments", "delete", true},
+		{policy.Subject{UserID: 4, Role: "moderator"}, "posts", "delete", false},
+		{policy.Subject{UserID: 5, Role: "ghost"}, "posts", "read", false},
+	}
+	for _, tt := range tests {
+		if got := e.Can(tt.subject, tt.resource, tt.action); got != tt.want {
+			t.Errorf("Can(%s, %s, %s) = %v; want %v", tt.subject.Role, tt.resource, tt.action, got, tt.want)
+		}
+	}
+}
+
+func TestParsePolicyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"empty", "roles: {}", "no roles"},
+		{"unknown parent", "roles: {a: {inherits: [b]}}", `inherits unknown role "b"`},
+		{"cycle", "roles: {a: {inherits: [b]}, b: {inherits: [a]}}", "inheritance cycle"},
+		{"malformed", "roles: {a: {permissions: [posts]}}", `malformed permission "posts"`},
+		{"wildcard resource", `roles: {a: {permissions: ["*:read"]}}`, "malformed permission"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePolicy([]byte(tt.yaml))
+			if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParsePolicy() error = %v; want ErrInvalid containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssign(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	e := newEnforcer(t, NewGormStore(db))
+
+	if e.Can(alice, "posts", "delete") {
+		t.Fatal("alice can delete posts before assignment")
+	}
+	if err := e.Assign(ctx, alice.UserID, "editor"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Assign(ctx, alice.UserID, "editor"); err != nil {
+		t.Errorf("Assign twice: error = %v; want nil", err)
+	}
+	if err := e.Assign(ctx, alice.UserID, "ghost"); !errors.Is(err, ErrUnknownRole) {
+		t.Errorf("Assign unknown role: error = %v; want ErrUnknownRole", err)
+	}
+	if !e.Can(alice, "posts", "delete") {
+		t.Error("alice cannot delete posts after assignment")
+	}
+	if got := e.Roles(alice); len(got) != 2 || got[0] != "user" || got[1] != "editor" {
+		t.Errorf("Roles(alice) = %v; want [user editor]", got)
+	}
+
+	// 重启后从数据库恢复
+	restarted := newEnforcer(t, NewGormStore(db))
+	if !restarted.Can(alice, "users", "read") {
+		t.Error("assignment not restored from store")
+	}
+
+	if err := restarted.Unassign(ctx, alice.UserID, "editor"); err != nil {
+		t.Fatal(err)
+	}
+	if restarted.Can(alice, "posts", "delete") {
+		t.Error("alice can still delete posts after unassign")
+	}
+	var count int64
+	db.Model(&UserRole{}).Count(&count)
+	if count != 0 {
+		t.Errorf("user_roles has %d rows after unassign; want 0", count)
+	}
+}
+
+func TestLoadPolicyDB(t *testing.T) {
+	db := newTestDB(t)
+	db.Create(&[]RolePermission{
+		{Role: "user", Permission: "posts:read"},
+		{Role: "editor", Inherits: "user"},
+		{Role: "editor", Permission: "posts:delete"},
+	})
+
+	p, err := LoadPolicyDB(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := New(context.Background(), Config{Policy: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Can(bob, "posts", "read") || !e.Can(bob, "posts", "delete") {
+		t.Error("editor from DB policy lacks posts:read or posts:delete")
+	}
+}
+
+func TestSetPolicyKeepsOldOnError(t *testing.T) {
+	e := newEnforcer(t, nil)
+	err := e.SetPolicy(&Policy{Roles: map[string]Role{"a": {Inherits: []string{"a"}}}})
+	if !errors.Is(err, ErrInvalid) {
+		t.Fatalf("SetPolicy() error = %v; want ErrInvalid", err)
+	}
+	if !e.Can(alice, "posts", "read") {
+		t.Error("old policy lost after invalid SetPolicy")
+	}
+}
+
+func TestPermissionPolicy(t *testing.T) {
+	e := newEnforcer(t, nil)
+	type post struct{ AuthorID uint }
+	canDelete := policy.Any(Permission[*post](e, "posts:delete"), policy.Owner(func(p *post) uint { return p.AuthorID }))
+
+	p := &post{AuthorID: alice.UserID}
+	tests := []struct {
+		subject policy.Subject
+		want    policy.Decision
+	}{
+		{alice, policy.Allow()},
+		{bob, policy.Allow()},
+		{policy.Subject{UserID: 9, Role: "user"}, policy.Deny("permission_required,not_owner")},
+	}
+	for _, tt := range tests {
+		if got := canDelete(tt.subject, p); got != tt.want {
+			t.Errorf("user %d: got %+v; want %+v", tt.subject.UserID, got, tt.want)
+		}
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := newEnforcer(t, nil)
+
+	r := gin.New()
+	// 模拟 JWT 中间件：从请求头读取用户
+	r.Use(func(c *gin.Context) {
+		switch c.GetHeader("X-User") {
+		case "alice":
+			c.Set("user_id", alice.UserID)
+			c.Set("role", alice.Role)
+		case "root":
+			c.Set("user_id", root.UserID)
+			c.Set("role", root.Role)
+		}
+	})
+	r.DELETE("/posts/:id", e.RequirePermission("posts:delete"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	RegisterAdmin(r.Group("/admin", e.RequirePermission("roles:manage")), e)
+
+	do := func(method, path, user string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-User", user)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		method, path, user string
+		want               int
+		reason             string
+	}{
+		{"DELETE", "/posts/1", "", http.StatusForbidden, "unauthenticated"},
+		{"DELETE", "/posts/1", "alice", http.StatusForbidden, "permission_required"},
+		{"PUT", "/admin/users/1/roles/editor", "alice", http.StatusForbidden, "permission_required"},
+		{"PUT", "/admin/users/1/roles/ghost", "root", http.StatusBadRequest, ""},
+		{"PUT", "/admin/users/x/roles/editor", "root", http.StatusBadRequest, ""},
+		{"PUT", "/admin/users/1/roles/editor", "root", http.StatusOK, ""},
+		{"DELETE", "/posts/1", "alice", http.StatusNoContent, ""}, // 分配 editor 后立即生效
+		{"DELETE", "/admin/users/1/roles/editor", "root", http.StatusOK, ""},
+		{"DELETE", "/posts/1", "alice", http.StatusForbidden, "permission_required"},
+	}
+	for _, tt := range tests {
+		w := do(tt.method, tt.path, tt.user)
+		if w.Code != tt.want {
+			t.Errorf("%s %s as %q: status = %d; want %d (%s)", tt.method, tt.path, tt.user, w.Code, tt.want, w.Body)
+			continue
+		}
+		if tt.reason != "" {
+			var body struct{ Reason, Permission string }
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if body.Reason != tt.reason || body.Permission == "" {
+				t.Errorf("%s %s as %q: body = %s; want reason %q with permission", tt.method, tt.path, tt.user, w.Body, tt.reason)
+			}
+		}
+	}
+
+	w := do("GET", "/admin/roles", "root")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"editor"`) {
+		t.Errorf("GET /admin/roles = %d %s", w.Code, w.Body)
+	}
+}
+
+func TestRequirePermissionMalformedPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("RequirePermission(\"posts\") did not panic")
+		}
+	}()
+	newEnforcer(t, nil).RequirePermission("posts")
+}
@@ -0,0 +1,143 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ============================================================================
+// 策略加载
+// ============================================================================
+
+// ParsePolicy 解析 YAML 策略
+func ParsePolicy(data []byte) (*Policy, error) {
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if len(p.Roles) == 0 {
+		return nil, fmt.Errorf("%w: no roles defined", ErrInvalid)
+	}
+	if _, err := expand(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// LoadPolicyFile 从 YAML 文件加载策略
+func LoadPolicyFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("rbac: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// RolePermission 表 role_permissions，数据库存储策略时使用
+// 继承关系用 inherits 列表示：Permission 为空、Inherits 为父角色名
+type RolePermission struct {
+	ID         uint   `gorm:"primaryKey"`
+	Role       string `gorm:"size:50;not null;index"`
+	Permission string `gorm:"size:100"`
+	Inherits   string `gorm:"size:50"`
+}
+
+// TableName 指定表名
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// LoadPolicyDB 从 role_permissions 表加载策略，适合需要在后台页面编辑权限的场景
+func LoadPolicyDB(ctx context.Context, db *gorm.DB) (*Policy, error) {
+	var rows []RolePermission
+	if err := db.WithContext(ctx).Order("id").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("rbac: load policy: %w", err)
+	}
+	p := &Policy{Roles: map[string]Role{}}
+	for _, row := range rows {
+		role := p.Roles[row.Role]
+		if row.Permission != "" {
+			role.Permissions = append(role.Permissions, row.Permission)
+		}
+		if row.Inherits != "" {
+			role.Inherits = append(role.Inherits, row.Inherits)
+		}
+		p.Roles[row.Role] = role
+	}
+	if len(p.Roles) == 0 {
+		return nil, fmt.Errorf("%w: no roles defined", ErrInvalid)
+	}
+	if _, err := expand(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ============================================================================
+// 角色分配存储
+// ============================================================================
+
+// Assignment 一条角色分配
+type Assignment struct {
+	UserID uint
+	Role   string
+}
+
+// Store 角色分配的持久化
+type Store interface {
+	List(ctx context.Context) ([]Assignment, error)
+	Add(ctx context.Context, userID uint, role string) error
+	Remove(ctx context.Context, userID uint, role string) error
+}
+
+// UserRole 表 user_roles
+type UserRole struct {
+	UserID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	Role      string `gorm:"primaryKey;size:50"`
+	CreatedAt time.Time
+}
+
+// TableName 指定表名
+func (UserRole) TableName() string {
+	return "user_roles"
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 用 user_roles 表保存角色分配，表需要事先 AutoMigrate(&rbac.UserRole{})
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) List(ctx context.Context) ([]Assignment, error) {
+	var rows []UserRole
+	if err := s.db.WithContext(ctx).Order("user_id, role").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	list := make([]Assignment, len(rows))
+	for i, r := range rows {
+		list[i] = Assignment{UserID: r.UserID, Role: r.Role}
+	}
+	return list, nil
+}
+
+func (s *gormStore) Add(ctx context.Context, userID uint, role string) error {
+	// 多实例同时分配同一个角色时，主键冲突直接忽略
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&UserRole{UserID: userID, Role: role}).Error
+}
+
+func (s *gormStore) Remove(ctx context.Context, userID uint, role string) error {
+	return s.db.WithContext(ctx).
+		Where("user_id = ? AND role = ?", userID, role).
+		Delete(&UserRole{}).Error
+}
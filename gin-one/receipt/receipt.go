@@ -0,0 +1,165 @@
+// ============================================================================
+// Package receipt 支付回执 PDF
+// ============================================================================
+//
+// 【流程】
+//
+//	支付成功 → operation.Start 异步生成 → Render 排版 → storage.Blob 保存
+//	        → 订单资源返回 receipt_url（签名下载链接）
+//
+// 【金额】
+//
+// 金额统一用"分"（int64）表示，避免浮点误差，只在排版时格式化成 "99.00"。
+//
+// ============================================================================
+package receipt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-one/pdf"
+	"go-one/storage"
+)
+
+// ErrNoItems 回执没有任何商品行
+var ErrNoItems = errors.New("receipt: no items")
+
+// Item 商品行
+type Item struct {
+	Name      string
+	Quantity  int
+	UnitPrice int64 // 单价（分）
+}
+
+// Amount 小计（分）
+func (i Item) Amount() int64 {
+	return i.UnitPrice * int64(i.Quantity)
+}
+
+// Receipt 一张支付回执
+type Receipt struct {
+	Number        string // 回执编号
+	OrderID       string
+	Merchant      string
+	Customer      string
+	PaymentMethod string
+	Currency      string // 如 CNY
+	PaidAt        time.Time
+	Items         []Item
+	Logo          []byte // 可选，JPEG
+}
+
+// Total 合计（分）
+func (r *Receipt) Total() int64 {
+	var total int64
+	for _, it := range r.Items {
+		total += it.Amount()
+	}
+	return total
+}
+
+// Key 回执在存储中的 key
+func Key(orderID string) string {
+	return "receipts/" + orderID + ".pdf"
+}
+
+// Render 把回执排版成 PDF
+func Render(r *Receipt) ([]byte, error) {
+	if len(r.Items) == 0 {
+		return nil, ErrNoItems
+	}
+
+	doc := pdf.New()
+	doc.Title = "Receipt " + r.Number
+	page := doc.AddPage()
+
+	const (
+		left  = 50.0
+		right = pdf.A4Width - 50
+	)
+
+	y := 60.0
+	if len(r.Logo) > 0 {
+		logo, err := doc.AddJPEG(r.Logo)
+		if err != nil {
+			return nil, err
+		}
+		page.Image(logo, right-80, 40, 80, 40)
+	}
+
+	page.Text(left, y, pdf.HelveticaBold, 22, "RECEIPT")
+	y += 24
+	page.Text(left, y, pdf.Helvetica, 11, r.Merchant)
+	y += 30
+
+	// 基本信息：左列标签，右列内容
+	info := [][2]string{
+		{"Receipt No.", r.Number},
+		{"Order", r.OrderID},
+		{"Customer", r.Customer},
+		{"Paid at", r.PaidAt.UTC().Format("2006-01-02 15:04 UTC")},
+		{"Payment method", r.PaymentMethod},
+	}
+	for _, kv := range info {
+		page.Text(left, y, pdf.HelveticaBold, 10, kv[0])
+		page.Text(left+110, y, pdf.Helvetica, 10, kv[1])
+		y += 16
+	}
+	y += 14
+
+	rows := make([][]string, len(r.Items))
+	for i, it := range r.Items {
+		rows[i] = []string{
+			it.Name,
+			strconv.Itoa(it.Quantity),
+			FormatAmount(it.UnitPrice),
+			FormatAmount(it.Amount()),
+		}
+	}
+	y = page.Table(left, y, pdf.Table{
+		Columns: []pdf.Column{
+			{Title: "Item", Width: 245},
+			{Title: "Qty", Width: 50, Align: pdf.AlignRight},
+			{Title: "Unit price", Width: 100, Align: pdf.AlignRight},
+			{Title: "Amount", Width: 100, Align: pdf.AlignRight},
+		},
+		Rows: rows,
+	})
+
+	y += 24
+	page.TextRight(right, y, pdf.HelveticaBold, 12,
+		fmt.Sprintf("Total: %s %s", r.Currency, FormatAmount(r.Total())))
+
+	page.Line(left, pdf.A4Height-60, right, pdf.A4Height-60, 0.5)
+	page.Text(left, pdf.A4Height-45, pdf.Helvetica, 8, "This receipt was generated electronically and is valid without a signature.")
+
+	return doc.Bytes(), nil
+}
+
+// FormatAmount 把分格式化成两位小数，如 9900 → "99.00"
+func FormatAmount(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// Generate 渲染回执并保存到存储，返回存储 key
+// 适合作为 operation.Func 的主体，存储写入失败时由 operation 负责重试
+func Generate(ctx context.Context, blob storage.Blob, r *Receipt) (string, error) {
+	data, err := Render(r)
+	if err != nil {
+		return "", err
+	}
+	key := Key(r.OrderID)
+	if err := blob.Put(ctx, key, bytes.NewReader(data), "application/pdf"); err != nil {
+		return "", err
+	}
+	return key, nil
+}
@@ -0,0 +1,75 @@
+package receipt
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"go-one/storage"
+)
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		cents int64
+		want  string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{9900, "99.00"},
+		{123456, "1234.56"},
+		{-150, "-1.50"},
+	}
+	for _, tt := range tests {
+		if got := FormatAmount(tt.cents); got != tt.want {
+			t.Errorf("FormatAmount(%d) = %q; want %q", tt.cents, got, tt.want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	ctx := context.Background()
+	blob, err := storage.NewLocal(t.TempDir(), "/files", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Receipt{
+		Number:        "R-0001",
+		OrderID:       "ord_1",
+		Merchant:      "Gin Shop",
+		Customer:      "alice",
+		PaymentMethod: "credit_card",
+		Currency:      "CNY",
+		PaidAt:        time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC),
+		Items: []Item{
+			{Name: "Go Book", Quantity: 2, UnitPrice: 4950},
+			{Name: "Sticker", Quantity: 1, UnitPrice: 100},
+		},
+	}
+	if r.Total() != 10000 {
+		t.Fatalf("Total = %d; want 10000", r.Total())
+	}
+
+	key, err := Generate(ctx, blob, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := blob.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(rc)
+
+	for _, want := range []string{"%PDF-1.4", "(R-0001)", "(Total: CNY 100.00)", "(99.00)"} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("receipt PDF missing %q", want)
+		}
+	}
+
+	if _, err := Render(&Receipt{}); err != ErrNoItems {
+		t.Errorf("Render(empty) err = %v; want ErrNoItems", err)
+	}
+}
@@ -0,0 +1,33 @@
+// ============================================================================
+// Package rediseval 执行 Lua 脚本的最小 Redis 客户端接口
+// ============================================================================
+//
+// 需要原子地"读取 → 计算 → 写回"的 Redis 存储（限流、幂等键、分布式锁、用量计数）
+// 都只用 Eval，共用这里的 Client，而不是各自声明一份。
+//
+// 【为什么用 Lua 脚本？】
+//
+// 状态读出来在 Go 里算完再写回，中间别的实例可能已经改过。
+// Redis 单线程执行 Lua 脚本，脚本里的多条命令天然是原子的。
+//
+// 【不直接依赖 go-redis】
+//
+// 只要求客户端实现 Eval，用 go-redis 时这样适配：
+//
+//	type redisAdapter struct{ rdb *goredis.Client }
+//
+//	func (a redisAdapter) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+//		return a.rdb.Eval(ctx, script, keys, args...).Result()
+//	}
+//
+// 测试里用一个实现 Eval 的假客户端即可，不需要真的 Redis。
+//
+// ============================================================================
+package rediseval
+
+import "context"
+
+// Client 执行 Lua 脚本的最小接口
+type Client interface {
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
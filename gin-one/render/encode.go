@@ -0,0 +1,97 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"go-one/serializer"
+)
+
+// encoder 编码 serializer.View 的结果：对象保持字段顺序，时间按选项格式化，其余叶子交给 encoding/json
+type encoder struct {
+	opts *Options
+	buf  *bytes.Buffer
+}
+
+func (e *encoder) value(v any) error {
+	switch v := v.(type) {
+	case nil:
+		e.buf.WriteString("null")
+		return nil
+	case serializer.Object:
+		e.buf.WriteByte('{')
+		for i, f := range v {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			if err := e.member(f.Key, f.Value); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('}')
+		return nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		e.buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			if err := e.member(k, v[k]); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('}')
+		return nil
+	case []any:
+		if v == nil {
+			e.buf.WriteString("null")
+			return nil
+		}
+		e.buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			if err := e.value(item); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte(']')
+		return nil
+	case time.Time:
+		if e.opts.Location != nil {
+			v = v.In(e.opts.Location)
+		}
+		return e.leaf(v.Format(e.opts.TimeFormat))
+	default:
+		return e.leaf(v)
+	}
+}
+
+func (e *encoder) member(key string, v any) error {
+	if err := e.leaf(key); err != nil {
+		return err
+	}
+	e.buf.WriteByte(':')
+	return e.value(v)
+}
+
+// leaf 用 encoding/json 编码单个值，去掉 Encoder 追加的换行
+func (e *encoder) leaf(v any) error {
+	enc := json.NewEncoder(e.buf)
+	enc.SetEscapeHTML(e.opts.EscapeHTML)
+	n := e.buf.Len()
+	if err := enc.Encode(v); err != nil {
+		e.buf.Truncate(n)
+		return err
+	}
+	e.buf.Truncate(e.buf.Len() - 1)
+	return nil
+}
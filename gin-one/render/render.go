@@ -0,0 +1,193 @@
+// ============================================================================
+// Package render 统一的 JSON 输出层：不 panic、可流式输出（JSON 数组 / NDJSON）、按调用方隐藏字段、统一时间格式
+// ============================================================================
+//
+// 【为什么不直接用 c.JSON】
+//
+// | 问题                         | c.JSON                                   | render                                   |
+// |------------------------------|------------------------------------------|------------------------------------------|
+// | 编码失败（NaN、循环引用）    | 状态码已写出，客户端收到空的 200         | 先编码到缓冲区，失败返回 500 统一错误    |
+// | MarshalJSON 里 panic         | 交给 recovery 中间件                     | 就地恢复，记日志，返回 500               |
+// | 大数组                       | 整个数组编码进内存再写                   | Stream / NDJSON 逐条编码、定期 Flush     |
+// | HTML 转义                    | 总是把 <>& 转义为 \u003c 等          | Options.EscapeHTML 控制                  |
+// | 时间格式                     | 各字段跟着 time.Time 的时区和纳秒走      | 统一按 TimeFormat / Location 输出        |
+// | 按调用方隐藏字段             | 每个接口手写一个 DTO                     | 字段上写 view:"admin,self"               |
+//
+// 【字段过滤】
+//
+// 编码前先用 serializer.View 按调用方过滤 view 标签（规则见 serializer 包），
+// 调用方默认取 policy.SubjectFromContext（JWT 中间件写入的 user_id / role），Options.Subject 可以改。
+// render 只负责编码：时间格式、HTML 转义、缩进。不支持 json 标签的 ",string" 选项。
+//
+// 【流式输出】
+//
+//	render.Stream(r, c, render.Rows[User](db.Model(&User{}).Order("id")))
+//
+// 输出和 response.Success 一样的信封 {"code":0,"message":"success","data":[...]}，
+// 逐条编码写出，每 FlushEvery 条 Flush 一次，内存占用和总条数无关。
+//
+// 第一条之前出错还能返回 500；写出第一条之后状态码已经发出，再出错只能停止输出，
+// 响应缺少结尾的 ]} 是一个不完整的 JSON，客户端解析失败就知道数据不全，
+// 比补上 ]} 让客户端以为拿到了完整列表更安全。
+//
+// 【NDJSON】
+//
+//	render.NDJSON(r, c, render.Rows[User](q))
+//
+// 输出 application/x-ndjson：每行一条记录，没有外层信封。客户端读一行处理一行
+// （jq -c、bufio.Scanner、流式 fetch），百万条记录两端都不用把整个列表放进内存。
+// 每 FlushEvery 条或每 FlushInterval 时间 Flush 一次。
+//
+// 中途出错时已经不能改状态码，最后补一行 {"code":-1,...,"error":"stream_aborted"}：
+// 每行都是完整的 JSON，少了结尾客户端看不出来，所以要显式告诉它数据不完整。
+// 记录本身不要有顶层的 code 字段，避免和这一行混淆。
+//
+// ============================================================================
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/policy"
+	"go-one/pools"
+	"go-one/response"
+	"go-one/serializer"
+)
+
+// Options 编码选项
+type Options struct {
+	// EscapeHTML 是否把 <>& 转义为 \u003c 等，默认不转义（API 响应不会嵌进 HTML）
+	EscapeHTML bool
+	// Indent 缩进，为空时输出紧凑 JSON
+	Indent string
+
+	// TimeFormat time.Time 的输出格式，默认 time.RFC3339
+	TimeFormat string
+	// Location 输出前转换到的时区，nil 表示保持原时区
+	Location *time.Location
+
+	// Subject 取当前调用方，用于 view 标签，默认 policy.SubjectFromContext
+	Subject func(*gin.Context) policy.Subject
+
+	// FlushEvery Stream / NDJSON 每写多少条 Flush 一次，默认 100
+	FlushEvery int
+	// FlushInterval 距上次 Flush 超过这个时间也 Flush 一次，默认 1 秒；
+	// 每行都要查关联数据的慢查询里，客户端不用等攒够 FlushEvery 条才收到数据
+	FlushInterval time.Duration
+
+	// Logger 记录编码失败，默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Renderer 按 Options 编码并写出响应，并发安全
+type Renderer struct {
+	opts Options
+}
+
+// New 创建 Renderer
+func New(opts Options) *Renderer {
+	if opts.TimeFormat == "" {
+		opts.TimeFormat = time.RFC3339
+	}
+	if opts.Subject == nil {
+		opts.Subject = policy.SubjectFromContext
+	}
+	if opts.FlushEvery <= 0 {
+		opts.FlushEvery = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return &Renderer{opts: opts}
+}
+
+// Default 使用默认选项的 Renderer
+var Default = New(Options{})
+
+// Marshal 按选项编码 v，s 为零值时按未登录调用方过滤
+func (r *Renderer) Marshal(v any, s policy.Subject) ([]byte, error) {
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	if err := r.encode(buf, v, s); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// JSON 编码 v 并写出，编码失败返回 500
+func (r *Renderer) JSON(c *gin.Context, status int, v any) {
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	if err := r.encode(buf, v, r.opts.Subject(c)); err != nil {
+		r.fail(c, err)
+		return
+	}
+	c.Data(status, "application/json; charset=utf-8", buf.Bytes())
+}
+
+// Success 与 response.Success 相同的信封
+func (r *Renderer) Success(c *gin.Context, data any) {
+	r.JSON(c, http.StatusOK, response.Response{Code: response.CodeOK, Message: "success", Data: data})
+}
+
+func (r *Renderer) fail(c *gin.Context, err error) {
+	r.opts.Logger.Error("render: encode response failed",
+		slog.String("method", c.Request.Method), slog.String("path", c.Request.URL.Path), slog.Any("error", err))
+	_ = c.Error(err)
+	response.Error(c, http.StatusInternalServerError, "render_failed", "响应编码失败")
+}
+
+// encode 编码到 buf，MarshalJSON 等方法里的 panic 转为错误
+func (r *Renderer) encode(buf *bytes.Buffer, v any, s policy.Subject) error {
+	if err := r.encodeCompact(buf, v, s); err != nil {
+		return err
+	}
+	if r.opts.Indent == "" {
+		return nil
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", r.opts.Indent); err != nil {
+		return err
+	}
+	buf.Reset()
+	buf.Write(out.Bytes())
+	return nil
+}
+
+// encodeCompact 不管 Options.Indent，总是输出一行；NDJSON 每条记录必须在一行里
+func (r *Renderer) encodeCompact(buf *bytes.Buffer, v any, s policy.Subject) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("render: panic while encoding: %v", p)
+		}
+	}()
+	tree, err := serializer.View(v, s)
+	if err != nil {
+		return err
+	}
+	e := &encoder{opts: &r.opts, buf: buf}
+	return e.value(tree)
+}
+
+// ============================================================================
+// 缓冲区池
+// ============================================================================
+
+// 偶尔的大响应不放回池里，避免池子长期持有大块内存
+var bufPool = pools.New(
+	func() *bytes.Buffer { return new(bytes.Buffer) },
+	func(buf *bytes.Buffer) bool {
+		buf.Reset()
+		return buf.Cap() <= 64<<10
+	},
+)
@@ -0,0 +1,309 @@
+package render
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"iter"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/internal/testdb"
+	"go-one/policy"
+	"go-one/serializer"
+)
+
+type Base struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Profile struct {
+	Bio   string `json:"bio"`
+	Phone string `json:"phone,omitempty" view:"admin,support"`
+}
+
+type User struct {
+	Base
+	Name     string            `json:"name"`
+	Email    string            `json:"email" view:"admin"`
+	Password string            `json:"-"`
+	Note     string            `json:"note,omitempty"`
+	Profile  *Profile          `json:"profile"`
+	Friends  []User            `json:"friends,omitempty"`
+	Meta     map[string]any    `json:"meta,omitempty"`
+	Raw      json.RawMessage   `json:"raw,omitempty"`
+	Tags     map[int]string    `json:"tags,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	private  string
+}
+
+var shanghai = time.FixedZone("CST", 8*3600)
+
+func testUser() User {
+	return User{
+		Base:    Base{ID: 1, CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 999, time.UTC)},
+		Name:    "<alice>",
+		Email:   "alice@example.com",
+		Profile: &Profile{Bio: "hi", Phone: "138"},
+		Friends: []User{{Base: Base{ID: 2}, Name: "bob", Email: "bob@example.com"}},
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	r := New(Options{})
+	tests := []struct {
+		name string
+		r    *Renderer
+		role string
+		v    any
+		want string
+	}{
+		{"anonymous hides role fields", r, "", testUser(),
+			`{"id":1,"created_at":"2026-01-02T03:04:05Z","name":"<alice>","profile":{"bio":"hi"},"friends":[{"id":2,"created_at":"0001-01-01T00:00:00Z","name":"bob","profile":null}]}`},
+		{"admin sees everything", r, "admin", testUser(),
+			`{"id":1,"created_at":"2026-01-02T03:04:05Z","name":"<alice>","email":"alice@example.com","profile":{"bio":"hi","phone":"138"},"friends":[{"id":2,"created_at":"0001-01-01T00:00:00Z","name":"bob","email":"bob@example.com","profile":null}]}`},
+		{"support sees phone only", r, "support", User{Profile: &Profile{Phone: "138"}},
+			`{"id":0,"created_at":"0001-01-01T00:00:00Z","name":"","profile":{"bio":"","phone":"138"}}`},
+		{"escape html and time zone",
+			New(Options{EscapeHTML: true, Location: shanghai, TimeFormat: "2006-01-02 15:04:05"}), "",
+			map[string]any{"t": time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), "s": "<b>"},
+			`{"s":"\u003cb\u003e","t":"2026-01-02 11:04:05"}`},
+		{"marshalers and special maps", r, "",
+			User{Raw: json.RawMessage(`{"x": 1}`), Tags: map[int]string{2: "b", 1: "a"}, Meta: map[string]any{"when": &time.Time{}}},
+			`{"id":0,"created_at":"0001-01-01T00:00:00Z","name":"","profile":null,"meta":{"when":"0001-01-01T00:00:00Z"},"raw":{"x":1},"tags":{"1":"a","2":"b"}}`},
+		{"nil and scalars", r, "", []any{nil, 1.5, true, []byte("hi"), [2]int{1, 2}, (*User)(nil)},
+			`[null,1.5,true,"aGk=",[1,2],null]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.r.Marshal(tt.v, policy.Subject{Role: tt.role})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// shadow 外层同名字段覆盖匿名结构体里的字段
+type shadow struct {
+	Base
+	ID string `json:"id"`
+}
+
+type panicky struct{}
+
+func (panicky) MarshalJSON() ([]byte, error) { panic("boom") }
+
+type node struct {
+	Next *node `json:"next"`
+}
+
+func TestMarshalEdgeCases(t *testing.T) {
+	got, err := Default.Marshal(shadow{Base: Base{ID: 1}, ID: "outer"}, policy.Subject{})
+	if err != nil || string(got) != `{"created_at":"0001-01-01T00:00:00Z","id":"outer"}` {
+		t.Errorf("shadow = %s, %v", got, err)
+	}
+
+	n := &node{}
+	n.Next = n
+	if _, err := Default.Marshal(n, policy.Subject{}); !errors.Is(err, serializer.ErrTooDeep) {
+		t.Errorf("cycle err = %v, want serializer.ErrTooDeep", err)
+	}
+	if _, err := Default.Marshal(panicky{}, policy.Subject{}); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("panic err = %v", err)
+	}
+	if _, err := Default.Marshal(math.NaN(), policy.Subject{}); err == nil {
+		t.Error("NaN should fail")
+	}
+
+	indented, _ := New(Options{Indent: "  "}).Marshal(map[string]int{"a": 1}, policy.Subject{})
+	if string(indented) != "{\n  \"a\": 1\n}" {
+		t.Errorf("indent = %q", indented)
+	}
+}
+
+func serve(h gin.HandlerFunc, role string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		c.Set("role", role)
+		h(c)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	return w
+}
+
+func TestJSON(t *testing.T) {
+	w := serve(func(c *gin.Context) { Default.Success(c, testUser()) }, "admin")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"email":"alice@example.com"`) ||
+		!strings.HasPrefix(w.Body.String(), `{"code":0,"message":"success","data":{`) {
+		t.Errorf("success = %d %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("content type = %q", ct)
+	}
+
+	// 编码失败：不是空的 200，而是 500 统一错误
+	w = serve(func(c *gin.Context) { Default.Success(c, panicky{}) }, "")
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"error":"render_failed"`) {
+		t.Errorf("failure = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func seqOf(items []int, failAt int) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		for i, v := range items {
+			if i == failAt {
+				yield(0, errors.New("db gone"))
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	r := New(Options{FlushEvery: 2})
+	tests := []struct {
+		name     string
+		seq      iter.Seq2[int, error]
+		wantCode int
+		wantBody string
+	}{
+		{"items", seqOf([]int{1, 2, 3}, -1), 200, `{"code":0,"message":"success","data":[1,2,3]}`},
+		{"empty", seqOf(nil, -1), 200, `{"code":0,"message":"success","data":[]}`},
+		{"error before first item", seqOf([]int{1}, 0), 500, ""},
+		{"error mid stream leaves json incomplete", seqOf([]int{1, 2, 3}, 2), 200, `{"code":0,"message":"success","data":[1,2`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(func(c *gin.Context) { Stream(r, c, tt.seq) }, "")
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, body %s", w.Code, w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	r := New(Options{FlushEvery: 2, Indent: "  "}) // Indent 不影响 NDJSON，每条一行
+	tests := []struct {
+		name     string
+		seq      iter.Seq2[int, error]
+		wantCode int
+		wantBody string
+	}{
+		{"items", seqOf([]int{1, 2, 3}, -1), 200, "1\n2\n3\n"},
+		{"empty", seqOf(nil, -1), 200, ""},
+		{"error before first item", seqOf([]int{1}, 0), 500, `{"code":-1,"message":"响应编码失败","error":"render_failed"}`},
+		{"error mid stream", seqOf([]int{1, 2, 3}, 2), 200, "1\n2\n" + ndjsonAborted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(func(c *gin.Context) { NDJSON(r, c, tt.seq) }, "")
+			if w.Code != tt.wantCode || w.Body.String() != tt.wantBody {
+				t.Fatalf("got %d %q, want %d %q", w.Code, w.Body.String(), tt.wantCode, tt.wantBody)
+			}
+			if ct := w.Header().Get("Content-Type"); tt.wantCode == 200 && ct != NDJSONType {
+				t.Errorf("content type = %q", ct)
+			}
+		})
+	}
+
+	w := serve(func(c *gin.Context) {
+		NDJSON(r, c, func(yield func(User, error) bool) { yield(testUser(), nil) })
+	}, "")
+	if lines := strings.Split(w.Body.String(), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[0], `{"id":1,`) {
+		t.Errorf("body = %q", w.Body.String())
+	}
+}
+
+// flushCounter 统计 Flush 次数
+type flushCounter struct {
+	gin.ResponseWriter
+	n int
+}
+
+func (f *flushCounter) Flush() { f.n++ }
+
+func TestFlusher(t *testing.T) {
+	w := &flushCounter{}
+	f := &flusher{w: w, every: 3, interval: time.Hour, last: time.Now()}
+	for range 7 {
+		f.wrote()
+	}
+	if w.n != 2 {
+		t.Errorf("flushes by count = %d, want 2", w.n)
+	}
+
+	// 写得慢时按时间 Flush
+	f.last = time.Now().Add(-2 * time.Hour)
+	if !f.wrote() || w.n != 3 {
+		t.Errorf("flushes by interval = %d, want 3", w.n)
+	}
+}
+
+func TestRows(t *testing.T) {
+	db := testdb.Open(t)
+
+	type Account struct {
+		ID    uint   `json:"id"`
+		Email string `json:"email" view:"admin"`
+	}
+	if err := db.AutoMigrate(&Account{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 250 {
+		db.Create(&Account{Email: strings.Repeat("x", i%5) + "@example.com"})
+	}
+
+	w := serve(func(c *gin.Context) { Stream(Default, c, Rows[Account](db.Model(&Account{}).Order("id"))) }, "")
+	var resp struct {
+		Data []map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v\n%s", err, w.Body.String())
+	}
+	if len(resp.Data) != 250 || resp.Data[249]["id"] != float64(250) {
+		t.Fatalf("got %d rows", len(resp.Data))
+	}
+	if _, ok := resp.Data[0]["email"]; ok {
+		t.Error("email should be hidden from anonymous callers")
+	}
+
+	w = serve(func(c *gin.Context) { NDJSON(Default, c, Rows[Account](db.Model(&Account{}).Order("id"))) }, "admin")
+	sc := bufio.NewScanner(w.Body)
+	n := 0
+	for sc.Scan() {
+		var a Account
+		if err := json.Unmarshal(sc.Bytes(), &a); err != nil || a.ID != uint(n+1) || a.Email == "" {
+			t.Fatalf("line %d = %s, %v", n, sc.Bytes(), err)
+		}
+		n++
+	}
+	if n != 250 {
+		t.Errorf("ndjson lines = %d", n)
+	}
+
+	// 查询本身出错：还没写出任何内容，返回 500
+	w = serve(func(c *gin.Context) { Stream(Default, c, Rows[Account](db.Table("missing"))) }, "")
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("missing table = %d", w.Code)
+	}
+}
@@ -0,0 +1,172 @@
+package render
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	streamPrefix = `{"code":0,"message":"success","data":[`
+	streamSuffix = `]}`
+)
+
+// Stream 逐条编码 seq 输出为 {"code":0,"message":"success","data":[...]}，出错时的行为见包注释
+func Stream[T any](r *Renderer, c *gin.Context, seq iter.Seq2[T, error]) {
+	subject := r.opts.Subject(c)
+	ctx := c.Request.Context()
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	f := r.newFlusher(c)
+
+	n := 0
+	for item, err := range seq {
+		if err == nil {
+			buf.Reset()
+			err = r.encode(buf, item, subject)
+		}
+		if err != nil {
+			if n == 0 {
+				r.fail(c, err)
+				return
+			}
+			r.opts.Logger.Error("render: stream aborted",
+				slog.String("path", c.Request.URL.Path), slog.Int("written", n), slog.Any("error", err))
+			_ = c.Error(err)
+			return // 不写结尾，让客户端发现响应不完整
+		}
+
+		if n == 0 {
+			c.Header("Content-Type", "application/json; charset=utf-8")
+			c.Status(http.StatusOK)
+			_, _ = c.Writer.WriteString(streamPrefix)
+		} else {
+			_, _ = c.Writer.WriteString(",")
+		}
+		if _, err := c.Writer.Write(buf.Bytes()); err != nil {
+			return // 客户端断开
+		}
+		n++
+		if f.wrote() && ctx.Err() != nil {
+			return
+		}
+	}
+
+	if n == 0 {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(streamPrefix+streamSuffix))
+		return
+	}
+	_, _ = c.Writer.WriteString(streamSuffix)
+}
+
+// NDJSONType NDJSON 响应的 Content-Type，也用于按 Accept 协商
+const NDJSONType = "application/x-ndjson"
+
+// ndjsonAborted 中途出错时的最后一行，和 response.Error 的格式相同
+const ndjsonAborted = `{"code":-1,"message":"输出中断，数据不完整","error":"stream_aborted"}` + "\n"
+
+// NDJSON 逐条编码 seq，每条一行输出为 application/x-ndjson，出错时的行为见包注释
+func NDJSON[T any](r *Renderer, c *gin.Context, seq iter.Seq2[T, error]) {
+	subject := r.opts.Subject(c)
+	ctx := c.Request.Context()
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	f := r.newFlusher(c)
+
+	n := 0
+	for item, err := range seq {
+		if err == nil {
+			buf.Reset()
+			err = r.encodeCompact(buf, item, subject)
+		}
+		if err != nil {
+			if n == 0 {
+				r.fail(c, err)
+				return
+			}
+			r.opts.Logger.Error("render: ndjson stream aborted",
+				slog.String("path", c.Request.URL.Path), slog.Int("written", n), slog.Any("error", err))
+			_ = c.Error(err)
+			_, _ = c.Writer.WriteString(ndjsonAborted)
+			return
+		}
+
+		if n == 0 {
+			c.Header("Content-Type", NDJSONType)
+			c.Status(http.StatusOK)
+		}
+		buf.WriteByte('\n')
+		if _, err := c.Writer.Write(buf.Bytes()); err != nil {
+			return // 客户端断开
+		}
+		n++
+		if f.wrote() && ctx.Err() != nil {
+			return
+		}
+	}
+
+	if n == 0 {
+		c.Data(http.StatusOK, NDJSONType, nil)
+	}
+}
+
+// flusher 每 FlushEvery 条或距上次 Flush 超过 FlushInterval 时 Flush
+type flusher struct {
+	w        gin.ResponseWriter
+	every    int
+	interval time.Duration
+	n        int
+	last     time.Time
+}
+
+func (r *Renderer) newFlusher(c *gin.Context) *flusher {
+	return &flusher{w: c.Writer, every: r.opts.FlushEvery, interval: r.opts.FlushInterval, last: time.Now()}
+}
+
+// wrote 写完一条后调用，返回这次是否 Flush 了
+func (f *flusher) wrote() bool {
+	f.n++
+	if f.n%f.every != 0 && time.Since(f.last) < f.interval {
+		return false
+	}
+	f.w.Flush()
+	f.last = time.Now()
+	return true
+}
+
+// Rows 用游标逐行读取查询结果，不把整个结果集加载进内存
+//
+//	render.Stream(render.Default, c, render.Rows[User](db.WithContext(ctx).Model(&User{}).Order("id")))
+//	render.NDJSON(render.Default, c, render.Rows[User](db.WithContext(ctx).Model(&User{}).Order("id")))
+//
+// 遍历期间占用一个数据库连接，SQLite 单连接时不要在循环里再查询
+func Rows[T any](tx *gorm.DB) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		rows, err := tx.Rows()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var item T
+			if err := tx.ScanRows(rows, &item); err != nil {
+				yield(zero, fmt.Errorf("render: scan row: %w", err))
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"go-one/cache/redis"
+	"go-one/diff"
+	"go-one/model"
+)
+
+// ============================================================================
+// 缓存装饰器
+// ============================================================================
+//
+// 装饰器实现同一个接口，service 不知道缓存的存在：
+//
+//	users := repository.NewCachedUserRepository(repository.NewUserRepository(db),
+//	    redis.New[model.User](client, redis.Config{Prefix: "user:v1:", TTL: 5 * time.Minute}))
+//	svc := service.NewUserService(users, passwords)
+//
+// | 方法                    | 缓存行为                                   |
+// |-------------------------|--------------------------------------------|
+// | Get                     | cache-aside，未命中查库并写回              |
+// | Update / Anonymize      | 数据库成功后删除缓存                       |
+// | AttachTags / DetachTags | 数据库成功后删除缓存（文章缓存里带着标签） |
+// | 其他                    | 直接透传给被装饰的仓储                     |
+//
+// 绕过仓储的写操作（如 trash 恢复、直接 DB.Model(...).Update）要自己调用 Invalidate。
+//
+// 【仍然可能读到旧数据的情况】
+//
+// 读请求未命中、从数据库读到旧值 → 写请求更新数据库并删除缓存 → 读请求把旧值写回缓存。
+// 这个窗口很小，旧值最多存活一个 TTL；对一致性要求高的字段（余额、库存）不要走缓存。
+// 文章缓存里带着作者信息，作者改名后文章里的作者名同样要等 TTL 过期才更新。
+//
+
+// Invalidator 带缓存的仓储，绕过它修改了缓存内容时调用 Invalidate
+//
+// 例如评论的钩子直接更新 posts.comment_count，文章缓存里的评论数要删掉重新读
+type Invalidator interface {
+	Invalidate(ctx context.Context, id uint)
+}
+
+func cacheID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// invalidate 删除缓存失败只记日志：数据库已经更新成功，不能因为缓存让请求失败
+func invalidate[V any](ctx context.Context, c *redis.Cache[V], id uint) {
+	if err := c.Delete(ctx, cacheID(id)); err != nil {
+		slog.WarnContext(ctx, "cache invalidate failed", "id", id, "error", err)
+	}
+}
+
+// CachedUserRepository 带缓存的用户仓储
+type CachedUserRepository struct {
+	UserRepository
+	cache *redis.Cache[model.User]
+}
+
+// NewCachedUserRepository 给 inner 加上缓存
+func NewCachedUserRepository(inner UserRepository, c *redis.Cache[model.User]) *CachedUserRepository {
+	return &CachedUserRepository{UserRepository: inner, cache: c}
+}
+
+// Get 先查缓存，ErrNotFound 不缓存
+func (r *CachedUserRepository) Get(ctx context.Context, id uint) (*model.User, error) {
+	return r.cache.GetOrLoad(ctx, cacheID(id), func(ctx context.Context) (*model.User, error) {
+		return r.UserRepository.Get(ctx, id)
+	})
+}
+
+// Update 有变化时删除缓存
+func (r *CachedUserRepository) Update(ctx context.Context, id, version uint, fields map[string]any) (*model.User, diff.Changes, error) {
+	user, changes, err := r.UserRepository.Update(ctx, id, version, fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(changes) > 0 {
+		invalidate(ctx, r.cache, id)
+	}
+	return user, changes, nil
+}
+
+// Anonymize 注销后删除缓存，否则已注销用户的个人信息还能从缓存里读到
+func (r *CachedUserRepository) Anonymize(ctx context.Context, id uint) error {
+	if err := r.UserRepository.Anonymize(ctx, id); err != nil {
+		return err
+	}
+	invalidate(ctx, r.cache, id)
+	return nil
+}
+
+// Invalidate 删除用户缓存，给绕过仓储的写操作使用
+func (r *CachedUserRepository) Invalidate(ctx context.Context, id uint) {
+	invalidate(ctx, r.cache, id)
+}
+
+// CachedPostRepository 带缓存的文章仓储
+type CachedPostRepository struct {
+	PostRepository
+	cache *redis.Cache[model.Post]
+}
+
+// NewCachedPostRepository 给 inner 加上缓存
+func NewCachedPostRepository(inner PostRepository, c *redis.Cache[model.Post]) *CachedPostRepository {
+	return &CachedPostRepository{PostRepository: inner, cache: c}
+}
+
+// Get 先查缓存，ErrNotFound 不缓存
+func (r *CachedPostRepository) Get(ctx context.Context, id uint) (*model.Post, error) {
+	return r.cache.GetOrLoad(ctx, cacheID(id), func(ctx context.Context) (*model.Post, error) {
+		return r.PostRepository.Get(ctx, id)
+	})
+}
+
+// AttachTags 加标签后删除文章缓存
+func (r *CachedPostRepository) AttachTags(ctx context.Context, postID uint, names []string) ([]model.Tag, error) {
+	tags, err := r.PostRepository.AttachTags(ctx, postID, names)
+	if err != nil {
+		return nil, err
+	}
+	invalidate(ctx, r.cache, postID)
+	return tags, nil
+}
+
+// DetachTags 去标签后删除文章缓存
+func (r *CachedPostRepository) DetachTags(ctx context.Context, postID uint, names []string) ([]model.Tag, error) {
+	tags, err := r.PostRepository.DetachTags(ctx, postID, names)
+	if err != nil {
+		return nil, err
+	}
+	invalidate(ctx, r.cache, postID)
+	return tags, nil
+}
+
+// Invalidate 删除文章缓存，文章被修改或删除后调用
+func (r *CachedPostRepository) Invalidate(ctx context.Context, id uint) {
+	invalidate(ctx, r.cache, id)
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-one/cache/redis"
+	"go-one/model"
+)
+
+func TestCachedUserRepository(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	alice := model.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+	db.Create(&alice)
+
+	client := redis.NewMemoryClient(0)
+	repo := NewCachedUserRepository(NewUserRepository(db), redis.New[model.User](client, redis.Config{Prefix: "user:"}))
+
+	if _, err := repo.Get(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	// 绕过仓储直接改库：缓存里仍是旧值
+	db.Model(&model.User{}).Where("id = ?", alice.ID).Update("age", 30)
+	if got, _ := repo.Get(ctx, alice.ID); got.Age != 0 {
+		t.Fatalf("age = %d; want cached 0", got.Age)
+	}
+	repo.Invalidate(ctx, alice.ID)
+	if got, _ := repo.Get(ctx, alice.ID); got.Age != 30 {
+		t.Fatalf("age after Invalidate = %d; want 30", got.Age)
+	}
+
+	// 通过仓储更新自动失效
+	if _, _, err := repo.Update(ctx, alice.ID, 0, map[string]any{"age": 31}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.Get(ctx, alice.ID); got.Age != 31 {
+		t.Fatalf("age after Update = %d; want 31", got.Age)
+	}
+
+	// 注销后不能再从缓存读到
+	if err := repo.Anonymize(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(ctx, alice.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Anonymize err = %v; want ErrNotFound", err)
+	}
+	if _, err := client.Get(ctx, "user:1"); !errors.Is(err, redis.ErrMiss) {
+		t.Fatalf("not found was cached: %v", err)
+	}
+}
+
+func TestCachedPostRepository(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	alice := model.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+	db.Create(&alice)
+	post := model.Post{Title: "hello", UserID: alice.ID}
+	db.Create(&post)
+
+	repo := NewCachedPostRepository(NewPostRepository(db),
+		redis.New[model.Post](redis.NewMemoryClient(0), redis.Config{Codec: redis.Msgpack}))
+
+	got, err := repo.Get(ctx, post.ID)
+	if err != nil || got.User.Username != "alice" {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	db.Model(&model.Post{}).Where("id = ?", post.ID).Update("title", "edited")
+	if got, _ := repo.Get(ctx, post.ID); got.Title != "hello" {
+		t.Fatalf("title = %q; want cached hello", got.Title)
+	}
+	repo.Invalidate(ctx, post.ID)
+	got, _ = repo.Get(ctx, post.ID)
+	if got.Title != "edited" || got.User.Username != "alice" {
+		t.Fatalf("after Invalidate = %+v", got)
+	}
+}
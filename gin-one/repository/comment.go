@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"go-one/model"
+)
+
+// CommentRepository 评论数据访问
+//
+// posts.comment_count 由 model.Comment 的钩子维护，这里的写操作都经过模型，计数不会漏
+type CommentRepository interface {
+	// Create 创建评论，文章评论数加 1
+	Create(ctx context.Context, comment *model.Comment) error
+	// Get 评论详情，不含已删除的
+	Get(ctx context.Context, id uint) (*model.Comment, error)
+	// ListByPost 文章的全部评论，含已删除的（组装楼中楼时占位），按创建顺序
+	ListByPost(ctx context.Context, postID uint) ([]model.Comment, error)
+	// Delete 软删除评论，文章评论数减 1；回复保留
+	Delete(ctx context.Context, id uint) error
+}
+
+type commentRepository struct {
+	db *gorm.DB
+}
+
+// NewCommentRepository 创建评论仓储
+func NewCommentRepository(db *gorm.DB) CommentRepository {
+	return &commentRepository{db: db}
+}
+
+func (r *commentRepository) Create(ctx context.Context, comment *model.Comment) error {
+	return translate(r.db.WithContext(ctx).Create(comment).Error)
+}
+
+func (r *commentRepository) Get(ctx context.Context, id uint) (*model.Comment, error) {
+	var comment model.Comment
+	if err := r.db.WithContext(ctx).First(&comment, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &comment, nil
+}
+
+func (r *commentRepository) ListByPost(ctx context.Context, postID uint) ([]model.Comment, error) {
+	comments := []model.Comment{}
+	err := r.db.WithContext(ctx).Unscoped().Where("post_id = ?", postID).Order("id").Find(&comments).Error
+	return comments, err
+}
+
+func (r *commentRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 先读出整条评论，钩子要用 PostID
+		var comment model.Comment
+		if err := tx.First(&comment, id).Error; err != nil {
+			return translate(err)
+		}
+		return tx.Delete(&comment).Error
+	})
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-one/model"
+)
+
+func TestCommentCount(t *testing.T) {
+	db := newTestDB(t)
+	posts := NewPostRepository(db)
+	repo := NewCommentRepository(db)
+	ctx := context.Background()
+
+	alice := &model.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+	NewUserRepository(db).Create(ctx, alice)
+	post := &model.Post{Title: "first", UserID: alice.ID}
+	posts.Create(ctx, post, nil)
+
+	count := func() int64 {
+		got, err := posts.Get(ctx, post.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.CommentCount
+	}
+
+	root := &model.Comment{PostID: post.ID, UserID: alice.ID, Body: "root"}
+	if err := repo.Create(ctx, root); err != nil {
+		t.Fatal(err)
+	}
+	reply := &model.Comment{PostID: post.ID, UserID: alice.ID, ParentID: &root.ID, Body: "reply"}
+	repo.Create(ctx, reply)
+	if n := count(); n != 2 {
+		t.Errorf("comment_count = %d; want 2", n)
+	}
+
+	if err := repo.Delete(ctx, root.ID); err != nil {
+		t.Fatal(err)
+	}
+	// 钩子只在真的删掉一行时减：并发删除时两边都可能读到评论，第二次 UPDATE 影响 0 行
+	if err := db.Delete(root).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 1 {
+		t.Errorf("comment_count after delete = %d; want 1", n)
+	}
+	if err := repo.Delete(ctx, root.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete(deleted) error = %v; want ErrNotFound", err)
+	}
+	if _, err := repo.Get(ctx, root.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(deleted) error = %v; want ErrNotFound", err)
+	}
+
+	// 已删除的评论仍然列出，给楼中楼占位
+	list, err := repo.ListByPost(ctx, post.ID)
+	if err != nil || len(list) != 2 || list[0].ID != root.ID || !list[0].DeletedAt.Valid || list[1].DeletedAt.Valid {
+		t.Errorf("ListByPost = %+v, %v; want deleted root then reply", list, err)
+	}
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"go-one/model"
+	"go-one/pagination"
+)
+
+// PostRepository 文章数据访问
+type PostRepository interface {
+	// Create 创建文章，tags 中不存在的标签自动创建，和文章在同一个事务里
+	Create(ctx context.Context, post *model.Post, tags []string) error
+	// Get 文章详情，带作者和标签
+	Get(ctx context.Context, id uint) (*model.Post, error)
+	// List 页码分页，最新的在前，带作者和标签；tag 非空时只返回该标签的文章；返回当前页和总数
+	List(ctx context.Context, tag string, offset, limit int) ([]model.Post, int64, error)
+	// Scroll 游标分页，最新的在前，带作者和标签；tag 非空时只返回该标签的文章
+	Scroll(ctx context.Context, tag string, after *pagination.Cursor, limit int) (pagination.Page[model.Post], error)
+	// Recent 最近 limit 篇文章，带作者和标签；tag 非空时只返回该标签的文章
+	Recent(ctx context.Context, tag string, limit int) ([]model.Post, error)
+
+	// AttachTags 给文章加上标签，不存在的标签自动创建，已有的忽略；返回文章现在的全部标签
+	AttachTags(ctx context.Context, postID uint, names []string) ([]model.Tag, error)
+	// DetachTags 去掉文章的标签（只删 post_tags 里的关联，标签本身保留）；返回文章现在的全部标签
+	DetachTags(ctx context.Context, postID uint, names []string) ([]model.Tag, error)
+	// TagCounts 按文章数从多到少列出标签，最多 limit 个；没有文章的标签也列出，文章数为 0
+	TagCounts(ctx context.Context, limit int) ([]model.TagCount, error)
+}
+
+type postRepository struct {
+	db *gorm.DB
+}
+
+// NewPostRepository 创建文章仓储
+func NewPostRepository(db *gorm.DB) PostRepository {
+	return &postRepository{db: db}
+}
+
+// withDeleted 预加载条件：包含已软删除的记录
+// 作者可能已注销（软删除），用 Unscoped 预加载才能显示 deleted_user_<id>
+func withDeleted(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// taggedWith 只保留带标签 tag 的文章，tag 为空时不过滤
+//
+// 用 IN 子查询而不是 JOIN：JOIN 之后一篇文章可能出现多行，分页和计数都要再去重
+func taggedWith(db *gorm.DB, tag string) *gorm.DB {
+	if tag == "" {
+		return db
+	}
+	return db.Where("posts.id IN (?)",
+		db.Session(&gorm.Session{NewDB: true}).Table("post_tags").Select("post_tags.post_id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id").
+			Where("tags.name = ?", tag))
+}
+
+// ensureTags 按名字取标签，不存在的创建
+func ensureTags(tx *gorm.DB, names []string) ([]model.Tag, error) {
+	tags := make([]model.Tag, 0, len(names))
+	for _, name := range names {
+		tag := model.Tag{Name: name}
+		// 并发创建同名标签时忽略冲突，再查一次拿到 ID
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tag).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("name = ?", name).Take(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+func (r *postRepository) Create(ctx context.Context, post *model.Post, tags []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		list, err := ensureTags(tx, tags)
+		if err != nil {
+			return err
+		}
+		post.Tags = append(post.Tags, list...)
+		return translate(tx.Omit("User").Create(post).Error)
+	})
+}
+
+func (r *postRepository) Get(ctx context.Context, id uint) (*model.Post, error) {
+	var post model.Post
+	if err := r.db.WithContext(ctx).Preload("User", withDeleted).Preload("Tags").First(&post, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &post, nil
+}
+
+func (r *postRepository) List(ctx context.Context, tag string, offset, limit int) ([]model.Post, int64, error) {
+	db := taggedWith(r.db.WithContext(ctx).Model(&model.Post{}), tag)
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	// Preload 对整页文章各执行一次查询（作者一次、post_tags + tags 一次），不会每篇文章查一次
+	var posts []model.Post
+	err := db.Preload("User", withDeleted).Preload("Tags").
+		Order("created_at DESC, id DESC").Offset(offset).Limit(limit).
+		Find(&posts).Error
+	return posts, total, err
+}
+
+// postCursor 文章的游标位置
+func postCursor(p model.Post) pagination.Cursor { return pagination.Of(p.Model) }
+
+func (r *postRepository) Scroll(ctx context.Context, tag string, after *pagination.Cursor, limit int) (pagination.Page[model.Post], error) {
+	var posts []model.Post
+	db := taggedWith(r.db.WithContext(ctx), tag).Preload("User", withDeleted).Preload("Tags")
+	if err := pagination.Apply(db, after, limit, pagination.Desc).Find(&posts).Error; err != nil {
+		return pagination.Page[model.Post]{}, err
+	}
+	return pagination.NewPage(posts, limit, postCursor), nil
+}
+
+func (r *postRepository) Recent(ctx context.Context, tag string, limit int) ([]model.Post, error) {
+	query := taggedWith(r.db.WithContext(ctx), tag).Preload("User", withDeleted).Preload("Tags").Order("created_at DESC").Limit(limit)
+	var posts []model.Post
+	err := query.Find(&posts).Error
+	return posts, err
+}
+
+// postTags 文章现在的全部标签，按名字排序
+func postTags(tx *gorm.DB, postID uint) ([]model.Tag, error) {
+	tags := []model.Tag{}
+	err := tx.Model(&model.Post{Model: gorm.Model{ID: postID}}).Order("tags.name").Association("Tags").Find(&tags)
+	return tags, err
+}
+
+func (r *postRepository) AttachTags(ctx context.Context, postID uint, names []string) ([]model.Tag, error) {
+	var tags []model.Tag
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var post model.Post
+		if err := tx.Select("id").First(&post, postID).Error; err != nil {
+			return translate(err)
+		}
+		list, err := ensureTags(tx, names)
+		if err != nil {
+			return err
+		}
+		// 直接写中间表，已有的关联忽略；Association.Append 还会顺带 upsert 标签和更新文章
+		links := make([]map[string]any, len(list))
+		for i, t := range list {
+			links[i] = map[string]any{"post_id": postID, "tag_id": t.ID}
+		}
+		if len(links) > 0 {
+			if err := tx.Table("post_tags").Clauses(clause.OnConflict{DoNothing: true}).Create(links).Error; err != nil {
+				return err
+			}
+		}
+		tags, err = postTags(tx, postID)
+		return err
+	})
+	return tags, err
+}
+
+func (r *postRepository) DetachTags(ctx context.Context, postID uint, names []string) ([]model.Tag, error) {
+	db := r.db.WithContext(ctx)
+	var post model.Post
+	if err := db.Select("id").First(&post, postID).Error; err != nil {
+		return nil, translate(err)
+	}
+	if len(names) > 0 {
+		ids := db.Model(&model.Tag{}).Select("id").Where("name IN ?", names)
+		if err := db.Exec("DELETE FROM post_tags WHERE post_id = ? AND tag_id IN (?)", postID, ids).Error; err != nil {
+			return nil, err
+		}
+	}
+	return postTags(db, postID)
+}
+
+func (r *postRepository) TagCounts(ctx context.Context, limit int) ([]model.TagCount, error) {
+	// 一条聚合查询：已删除（软删除）的文章不算
+	//
+	//	SELECT tags.id, tags.name, COUNT(posts.id) AS posts FROM tags
+	//	LEFT JOIN post_tags ON post_tags.tag_id = tags.id
+	//	LEFT JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL
+	//	GROUP BY tags.id, tags.name ORDER BY posts DESC, tags.name LIMIT ?
+	counts := []model.TagCount{}
+	err := r.db.WithContext(ctx).Table("tags").
+		Select("tags.id, tags.name, COUNT(posts.id) AS posts").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("LEFT JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL").
+		Group("tags.id, tags.name").
+		Order("posts DESC, tags.name").
+		Limit(limit).
+		Scan(&counts).Error
+	return counts, err
+}
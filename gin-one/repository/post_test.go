@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"go-one/model"
+	"go-one/pagination"
+)
+
+func TestPostRepository(t *testing.T) {
+	db := newTestDB(t)
+	users := NewUserRepository(db)
+	repo := NewPostRepository(db)
+	ctx := context.Background()
+
+	alice := &model.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+	users.Create(ctx, alice)
+
+	p1 := &model.Post{Title: "first", UserID: alice.ID}
+	if err := repo.Create(ctx, p1, []string{"go", "gorm"}); err != nil {
+		t.Fatal(err)
+	}
+	p2 := &model.Post{Title: "second", UserID: alice.ID}
+	if err := repo.Create(ctx, p2, []string{"go"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同名标签只创建一次
+	var tags int64
+	db.Model(&model.Tag{}).Count(&tags)
+	if tags != 2 {
+		t.Errorf("tags = %d; want 2", tags)
+	}
+
+	got, err := repo.Get(ctx, p1.ID)
+	if err != nil || got.User.Username != "alice" {
+		t.Errorf("Get() = %+v, %v; want post with author alice", got, err)
+	}
+
+	// 作者注销后文章仍然能显示匿名作者
+	if err := users.Anonymize(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	list, total, err := repo.List(ctx, "", 0, 10)
+	if err != nil || total != 2 || len(list) != 2 || list[0].User.Username != AnonymizedUsername(alice.ID) {
+		t.Errorf("List() = %+v, %d, %v; want 2 posts by %s", list, total, err, AnonymizedUsername(alice.ID))
+	}
+	if list[0].ID != p2.ID {
+		t.Errorf("List()[0] = post %d; want newest post %d first", list[0].ID, p2.ID)
+	}
+
+	// 游标分页：每页 1 条，最新的在前
+	page, err := repo.Scroll(ctx, "", nil, 1)
+	if err != nil || len(page.Items) != 1 || page.Items[0].ID != p2.ID || !page.HasMore {
+		t.Fatalf("Scroll(first) = %+v, %v; want post %d with more", page, err, p2.ID)
+	}
+	after, _ := pagination.Decode(page.NextCursor)
+	page, err = repo.Scroll(ctx, "", after, 1)
+	if err != nil || len(page.Items) != 1 || page.Items[0].ID != p1.ID || page.HasMore {
+		t.Errorf("Scroll(second) = %+v, %v; want last post %d", page, err, p1.ID)
+	}
+
+	tests := []struct {
+		tag  string
+		want int
+	}{
+		{"", 2},
+		{"gorm", 1},
+		{"rust", 0},
+	}
+	for _, tt := range tests {
+		posts, err := repo.Recent(ctx, tt.tag, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(posts) != tt.want {
+			t.Errorf("Recent(%q) = %d posts; want %d", tt.tag, len(posts), tt.want)
+		}
+		for _, p := range posts {
+			if len(p.Tags) == 0 {
+				t.Errorf("Recent(%q): post %d has no preloaded tags", tt.tag, p.ID)
+			}
+		}
+	}
+}
+
+func tagNames(tags []model.Tag) []string {
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.Name
+	}
+	return names
+}
+
+func TestPostTags(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostRepository(db)
+	ctx := context.Background()
+
+	alice := &model.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+	NewUserRepository(db).Create(ctx, alice)
+	p1 := &model.Post{Title: "first", UserID: alice.ID}
+	p2 := &model.Post{Title: "second", UserID: alice.ID}
+	repo.Create(ctx, p1, []string{"go"})
+	repo.Create(ctx, p2, nil)
+
+	// 已有的关联忽略，不存在的标签自动创建
+	tags, err := repo.AttachTags(ctx, p1.ID, []string{"sql", "go", "gorm"})
+	if err != nil || !slices.Equal(tagNames(tags), []string{"go", "gorm", "sql"}) {
+		t.Fatalf("AttachTags = %v, %v; want go, gorm, sql", tagNames(tags), err)
+	}
+	repo.AttachTags(ctx, p2.ID, []string{"go"})
+	tags, err = repo.DetachTags(ctx, p1.ID, []string{"sql", "rust"})
+	if err != nil || !slices.Equal(tagNames(tags), []string{"go", "gorm"}) {
+		t.Errorf("DetachTags = %v, %v; want go, gorm", tagNames(tags), err)
+	}
+	if _, err := repo.AttachTags(ctx, 99, []string{"go"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AttachTags(missing post) error = %v; want ErrNotFound", err)
+	}
+	if _, err := repo.DetachTags(ctx, 99, []string{"go"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DetachTags(missing post) error = %v; want ErrNotFound", err)
+	}
+
+	// 按标签过滤：页码分页的总数和游标分页一致
+	list, total, err := repo.List(ctx, "gorm", 0, 10)
+	if err != nil || total != 1 || len(list) != 1 || list[0].ID != p1.ID {
+		t.Errorf("List(gorm) = %d posts, total %d, %v; want post %d", len(list), total, err, p1.ID)
+	}
+	page, err := repo.Scroll(ctx, "go", nil, 10)
+	if err != nil || len(page.Items) != 2 || page.HasMore {
+		t.Errorf("Scroll(go) = %+v, %v; want 2 posts", page, err)
+	}
+	if got, _ := repo.Get(ctx, p1.ID); !slices.Equal(tagNames(got.Tags), []string{"go", "gorm"}) {
+		t.Errorf("Get().Tags = %v; want go, gorm", tagNames(got.Tags))
+	}
+
+	// 一条聚合查询；删除的文章不算，没有文章的标签数为 0
+	db.Delete(&model.Post{}, p2.ID)
+	counts, err := repo.TagCounts(ctx, 10)
+	want := []model.TagCount{{Name: "go", Posts: 1}, {Name: "gorm", Posts: 1}, {Name: "sql", Posts: 0}}
+	if err != nil || len(counts) != len(want) {
+		t.Fatalf("TagCounts = %+v, %v", counts, err)
+	}
+	for i, c := range counts {
+		if c.Name != want[i].Name || c.Posts != want[i].Posts || c.ID == 0 {
+			t.Errorf("TagCounts[%d] = %+v; want %+v", i, c, want[i])
+		}
+	}
+}
+
+// TestListQueries 列表的查询次数和文章数无关：作者和标签都是整页批量预加载，没有 N+1
+func TestListQueries(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostRepository(db)
+	users := NewUserRepository(db)
+	ctx := context.Background()
+
+	var queries int
+	db.Callback().Query().After("gorm:query").Register("test:count", func(tx *gorm.DB) {
+		if !tx.DryRun { // 拼 IN 子查询时也会走一遍回调，但不会执行
+			queries++
+		}
+	})
+
+	// count、posts、users、post_tags、tags 各一次
+	for _, name := range []string{"alice", "bob", "carol"} {
+		u := &model.User{Username: name, Email: name + "@example.com", Password: "hash"}
+		users.Create(ctx, u)
+		repo.Create(ctx, &model.Post{Title: name, UserID: u.ID}, []string{"go", name})
+
+		queries = 0
+		list, _, err := repo.List(ctx, "go", 0, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if queries != 5 {
+			t.Errorf("List(%d posts) = %d queries; want 5", len(list), queries)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// ============================================================================
+// Package repository 数据访问层
+// ============================================================================
+//
+// 把 SQL / GORM 细节收拢在这里，Handler 只调用语义化的方法，
+// 每个方法都接收 context，便于超时控制和链路追踪。
+//
+// 模型定义在 model 包（users / posts / tags），与 examples/4_1_gorm_integration.go 共用。
+//
+// 【分层】
+//
+//	Handler  ──→  service（业务规则：密码哈希、作者是否存在）
+//	                 │ 依赖接口，测试时可以换成内存实现
+//	                 ↓
+//	         repository（只管读写数据库）  ──→  *gorm.DB
+//
+// 【错误约定】
+//
+// | 错误                   | 含义                                                       |
+// |------------------------|------------------------------------------------------------|
+// | ErrNotFound            | 记录不存在或已被软删除                                     |
+// | ErrDuplicate           | 违反唯一索引（需要 gorm.Config{TranslateError: true}）     |
+// | *optlock.ConflictError | 版本号不匹配，errors.Is(err, optlock.ErrConflict) 成立     |
+//
+// 其他错误原样返回（包一层说明），调用方按 500 处理。
+//
+// ============================================================================
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"go-one/audit"
+	"go-one/diff"
+	"go-one/model"
+	"go-one/optlock"
+	"go-one/pagination"
+)
+
+// 错误定义
+var (
+	ErrNotFound  = errors.New("repository: record not found")
+	ErrDuplicate = errors.New("repository: duplicate key")
+)
+
+// translate 把 GORM 错误转换为仓储层错误
+func translate(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	}
+	return err
+}
+
+// UserFilter 用户列表查询条件
+type UserFilter struct {
+	Status  string
+	Keyword string // 匹配用户名或邮箱
+	Offset  int    // 只用于 List
+	Limit   int
+}
+
+// UserRepository 用户数据访问
+type UserRepository interface {
+	Create(ctx context.Context, user *model.User) error
+	// CreateBatch 在一个事务里插入多个用户，用户名或邮箱已存在的行跳过，
+	// 返回与 users 等长的结果，created[i] 为 false 表示第 i 个被跳过
+	CreateBatch(ctx context.Context, users []*model.User) (created []bool, err error)
+	Get(ctx context.Context, id uint) (*model.User, error)
+	Exists(ctx context.Context, id uint) (bool, error)
+	// List 页码分页，返回当前页和满足条件的总数
+	List(ctx context.Context, f UserFilter) ([]model.User, int64, error)
+	// Scroll 游标分页，按注册时间从早到晚，after 为 nil 时从头开始
+	Scroll(ctx context.Context, f UserFilter, after *pagination.Cursor) (pagination.Page[model.User], error)
+	// Update 只更新 fields（列名 → 新值）中和当前值不同的列，返回更新后的用户和实际的变化；
+	// 没有任何变化时不执行 UPDATE，updated_at 和 version 保持不变。
+	// version 不为 0 时必须等于当前版本号，否则返回 *optlock.ConflictError
+	Update(ctx context.Context, id, version uint, fields map[string]any) (*model.User, diff.Changes, error)
+	// Anonymize 注销用户：抹去个人信息并软删除，保留其文章/评论
+	Anonymize(ctx context.Context, id uint) error
+}
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository 创建用户仓储
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	return translate(r.db.WithContext(ctx).Create(user).Error)
+}
+
+func (r *userRepository) CreateBatch(ctx context.Context, users []*model.User) ([]bool, error) {
+	created := make([]bool, len(users))
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, u := range users {
+			// 逐条 ON CONFLICT DO NOTHING：批量 INSERT 冲突时无法知道是哪一行被跳过，
+			// 而且 PostgreSQL 事务里的一条语句报错后整个事务都不能再用
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(u)
+			if res.Error != nil {
+				return res.Error
+			}
+			created[i] = res.RowsAffected > 0
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, translate(err)
+	}
+	return created, nil
+}
+
+func (r *userRepository) Get(ctx context.Context, id uint) (*model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) Exists(ctx context.Context, id uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// likeEscaper 转义 LIKE 通配符，用户输入的 % 和 _ 按普通字符匹配
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *userRepository) filter(ctx context.Context, f UserFilter) *gorm.DB {
+	db := r.db.WithContext(ctx).Model(&model.User{})
+	if f.Status != "" {
+		db = db.Where("status = ?", f.Status)
+	}
+	if f.Keyword != "" {
+		kw := "%" + likeEscaper.Replace(f.Keyword) + "%"
+		db = db.Where(`(username LIKE ? ESCAPE '\' OR email LIKE ? ESCAPE '\')`, kw, kw)
+	}
+	return db
+}
+
+func (r *userRepository) List(ctx context.Context, f UserFilter) ([]model.User, int64, error) {
+	db := r.filter(ctx, f)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []model.User
+	if err := db.Order("created_at, id").Offset(f.Offset).Limit(f.Limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// userCursor 用户的游标位置
+func userCursor(u model.User) pagination.Cursor { return pagination.Of(u.Model) }
+
+func (r *userRepository) Scroll(ctx context.Context, f UserFilter, after *pagination.Cursor) (pagination.Page[model.User], error) {
+	var users []model.User
+	// 游标模式不统计总数：COUNT(*) 在大表上和 OFFSET 一样慢
+	if err := pagination.Apply(r.filter(ctx, f), after, f.Limit, pagination.Asc).Find(&users).Error; err != nil {
+		return pagination.Page[model.User]{}, err
+	}
+	return pagination.NewPage(users, f.Limit, userCursor), nil
+}
+
+func (r *userRepository) Update(ctx context.Context, id, version uint, fields map[string]any) (*model.User, diff.Changes, error) {
+	var user model.User
+	var changes diff.Changes
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, id).Error; err != nil {
+			return err
+		}
+		if err := optlock.Check(&user, version); err != nil {
+			return err
+		}
+		var err error
+		changes, err = diff.Updates(&user, fields, diff.Options{Name: r.column})
+		if err != nil || len(changes) == 0 {
+			return err
+		}
+		// 条件更新用刚读到的版本号：没带 version 的请求也不会覆盖 First 之后别人的修改
+		if err := optlock.Update(tx, &user, changes.Map()); err != nil {
+			return err
+		}
+		// 重新查询返回最新数据（默认值、钩子修改过的字段）
+		return tx.First(&user, id).Error
+	})
+	if err != nil {
+		return nil, nil, translate(err)
+	}
+	return &user, changes, nil
+}
+
+// column diff 按数据库列名匹配 fields 的键（model.User 没有用 gorm:"column:" 改名）
+func (r *userRepository) column(f reflect.StructField) string {
+	return r.db.NamingStrategy.ColumnName("", f.Name)
+}
+
+// ============================================================================
+// 注销匿名化
+// ============================================================================
+//
+// 【为什么不直接删除？】
+//
+// 物理删除用户会让 posts.user_id 指向不存在的行（或被外键级联删掉文章）。
+// 匿名化保留这一行，只抹掉能识别个人的信息：
+//
+//	username  alice             → deleted_user_42
+//	email     alice@example.com → sha256 摘要（仍唯一，但不可逆）
+//	password  <hash>            → 空（无法再登录）
+//	status    active            → deleted
+//	deleted_at NULL             → 当前时间（普通查询看不到它）
+//
+// 文章仍然能通过 Unscoped 关联到这个"已注销用户"。
+//
+
+// AnonymizedUsername 匿名化后的用户名
+func AnonymizedUsername(id uint) string {
+	return "deleted_user_" + strconv.FormatUint(uint64(id), 10)
+}
+
+// HashEmail 邮箱摘要：先规范化（去空格、小写），同一邮箱总是得到相同结果
+func HashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *userRepository) Anonymize(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user struct {
+			ID       uint
+			Username string
+			Email    string
+		}
+		// 只处理未删除的用户，已注销的用户不能再次注销
+		err := tx.Table("users").
+			Select("id", "username", "email").
+			Where("id = ? AND deleted_at IS NULL", id).
+			Take(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		email := ""
+		if user.Email != "" {
+			email = HashEmail(user.Email)
+		}
+		err = tx.Table("users").Where("id = ?", id).Updates(map[string]any{
+			"username":   AnonymizedUsername(id),
+			"email":      email,
+			"password":   "",
+			"status":     "deleted",
+			"deleted_at": time.Now(),
+			"updated_at": time.Now(),
+		}).Error
+		if err != nil {
+			return fmt.Errorf("anonymize user %d: %w", id, err)
+		}
+
+		// 审计日志不记录原始用户名和邮箱，否则匿名化就白做了
+		return audit.Record(ctx, tx, "anonymize", "users", strconv.FormatUint(uint64(id), 10), map[string]any{
+			"fields": []string{"username", "email", "password", "status", "deleted_at"},
+		})
+	})
+}
@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"go-one/audit"
+	"go-one/diff"
+	"go-one/internal/testdb"
+	"go-one/model"
+	"go-one/optlock"
+	"go-one/pagination"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.ForeignKeys(t, &model.User{}, &model.Post{}, &model.Tag{}, &model.Comment{}, &audit.Log{})
+}
+
+func TestAnonymize(t *testing.T) {
+	db := newTestDB(t)
+	ctx := audit.WithActor(context.Background(), "admin")
+
+	alice := model.User{Username: "alice", Email: "Alice@Example.com", Password: "hash"}
+	bob := model.User{Username: "bob", Email: "bob@example.com", Password: "hash"}
+	db.Create(&alice)
+	db.Create(&bob)
+	db.Create(&[]model.Post{{Title: "a1", UserID: alice.ID}, {Title: "a2", UserID: alice.ID}, {Title: "b1", UserID: bob.ID}})
+
+	repo := NewUserRepository(db)
+	if err := repo.Anonymize(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// 普通查询看不到已注销用户
+	var count int64
+	db.Model(&model.User{}).Where("id = ?", alice.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("anonymized user visible in scoped query")
+	}
+
+	var got model.User
+	if err := db.Unscoped().First(&got, alice.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Username != AnonymizedUsername(alice.ID) || got.Email != HashEmail("alice@example.com") ||
+		got.Password != "" || got.Status != "deleted" || !got.DeletedAt.Valid {
+		t.Errorf("user = %+v; want anonymized and soft-deleted", got)
+	}
+
+	// 文章全部保留
+	var posts int64
+	db.Model(&model.Post{}).Where("user_id = ?", alice.ID).Count(&posts)
+	if posts != 2 {
+		t.Errorf("alice posts = %d; want 2", posts)
+	}
+
+	// 没有任何文章指向不存在的用户
+	var orphans int64
+	db.Raw(`SELECT COUNT(*) FROM posts LEFT JOIN users ON users.id = posts.user_id WHERE users.id IS NULL`).Scan(&orphans)
+	if orphans != 0 {
+		t.Errorf("orphaned posts = %d; want 0", orphans)
+	}
+	var fkViolations []map[string]any
+	db.Raw("PRAGMA foreign_key_check").Scan(&fkViolations)
+	if len(fkViolations) != 0 {
+		t.Errorf("foreign_key_check = %v; want none", fkViolations)
+	}
+
+	// 其他用户不受影响
+	var other model.User
+	db.First(&other, bob.ID)
+	if other.Username != "bob" {
+		t.Errorf("bob username = %q; want bob", other.Username)
+	}
+
+	// 审计日志：记录操作者，不包含原始个人信息
+	var logs []audit.Log
+	db.Find(&logs)
+	if len(logs) != 1 || logs[0].Actor != "admin" || logs[0].Action != "anonymize" || logs[0].EntityID != "1" {
+		t.Fatalf("audit logs = %+v; want one anonymize entry by admin", logs)
+	}
+	for _, pii := range []string{"alice", "Alice@Example.com"} {
+		if strings.Contains(logs[0].Changes, pii) {
+			t.Errorf("audit changes %q leak %q", logs[0].Changes, pii)
+		}
+	}
+}
+
+func TestAnonymizeNotFound(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	if err := repo.Anonymize(ctx, 99); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing user err = %v; want ErrNotFound", err)
+	}
+
+	u := model.User{Username: "carol", Email: "carol@example.com", Password: "hash"}
+	db.Create(&u)
+	repo.Anonymize(ctx, u.ID)
+	if err := repo.Anonymize(ctx, u.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second anonymize err = %v; want ErrNotFound", err)
+	}
+
+	var logs int64
+	db.Model(&audit.Log{}).Count(&logs)
+	if logs != 1 {
+		t.Errorf("audit logs = %d; want 1 (failed attempts roll back)", logs)
+	}
+}
+
+func TestUserCRUD(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	alice := &model.User{Username: "alice", Email: "alice@example.com", Password: "hash", Age: 30}
+	if err := repo.Create(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	dup := &model.User{Username: "alice", Email: "other@example.com", Password: "hash"}
+	if err := repo.Create(ctx, dup); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("duplicate username: err = %v; want ErrDuplicate", err)
+	}
+
+	got, err := repo.Get(ctx, alice.ID)
+	if err != nil || got.Username != "alice" || got.Status != "active" {
+		t.Errorf("Get() = %+v, %v; want alice with default status", got, err)
+	}
+	if _, err := repo.Get(ctx, 99); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(99) err = %v; want ErrNotFound", err)
+	}
+
+	// 零值也要写入；用户名没变，不在变化列表里
+	updated, changes, err := repo.Update(ctx, alice.ID, 0, map[string]any{"age": 0, "status": "banned", "username": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Age != 0 || updated.Status != "banned" {
+		t.Errorf("Update() = age %d status %q; want 0 banned", updated.Age, updated.Status)
+	}
+	if got := changes.Fields(); len(got) != 2 || got[0] != "age" || got[1] != "status" {
+		t.Errorf("Update() changes = %v; want age, status", changes)
+	}
+	if c, _ := changes.Get("age"); c.Old != 30 || c.New != 0 {
+		t.Errorf("age change = %+v; want 30 → 0", c)
+	}
+
+	// 值都没变：不执行 UPDATE，updated_at 不刷新
+	again, changes, err := repo.Update(ctx, alice.ID, 0, map[string]any{"age": 0, "status": "banned"})
+	if err != nil || len(changes) != 0 || !again.UpdatedAt.Equal(updated.UpdatedAt) {
+		t.Errorf("no-op Update() = %v, %v; updated_at %v → %v", changes, err, updated.UpdatedAt, again.UpdatedAt)
+	}
+	if _, _, err := repo.Update(ctx, alice.ID, 0, map[string]any{"nickname": "x"}); !errors.Is(err, diff.ErrUnknownField) {
+		t.Errorf("Update(nickname) err = %v; want ErrUnknownField", err)
+	}
+	if _, _, err := repo.Update(ctx, 99, 0, map[string]any{"age": 1}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update(99) err = %v; want ErrNotFound", err)
+	}
+
+	// 乐观锁：创建时 version=1，更新一次变成 2，no-op 不加；带旧版本号更新返回冲突，数据不变
+	if alice.Version != 1 || updated.Version != 2 || again.Version != 2 {
+		t.Errorf("versions = %d, %d, %d; want 1, 2, 2", alice.Version, updated.Version, again.Version)
+	}
+	_, _, err = repo.Update(ctx, alice.ID, 1, map[string]any{"age": 40})
+	var conflict *optlock.ConflictError
+	if !errors.As(err, &conflict) || conflict.Expected != 1 || conflict.Current != 2 {
+		t.Errorf("stale Update() err = %v; want ConflictError 1 → 2", err)
+	}
+	if got, _ := repo.Get(ctx, alice.ID); got.Age != 0 {
+		t.Errorf("stale Update() wrote age %d", got.Age)
+	}
+	if u, _, err := repo.Update(ctx, alice.ID, 2, map[string]any{"age": 40}); err != nil || u.Version != 3 {
+		t.Errorf("Update(version 2) = %v, %v; want version 3", u, err)
+	}
+
+	ok, err := repo.Exists(ctx, alice.ID)
+	if err != nil || !ok {
+		t.Errorf("Exists(alice) = %v, %v; want true", ok, err)
+	}
+}
+
+func TestUserCreateBatch(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &model.User{Username: "alice", Email: "alice@example.com", Password: "hash"}); err != nil {
+		t.Fatal(err)
+	}
+	batch := []*model.User{
+		{Username: "bob", Email: "bob@example.com", Password: "hash"},
+		{Username: "alice", Email: "alice2@example.com", Password: "hash"}, // 用户名已存在
+		{Username: "carol", Email: "bob@example.com", Password: "hash"},    // 邮箱和同批的 bob 重复
+		{Username: "dave", Email: "dave@example.com", Password: "hash"},
+	}
+	created, err := repo.CreateBatch(ctx, batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []bool{true, false, false, true}; !reflect.DeepEqual(created, want) {
+		t.Fatalf("created = %v; want %v", created, want)
+	}
+	if batch[0].ID == 0 || batch[3].ID == 0 {
+		t.Errorf("created rows have no ID: %d, %d", batch[0].ID, batch[3].ID)
+	}
+	var n int64
+	db.Model(&model.User{}).Count(&n)
+	if n != 3 {
+		t.Errorf("users = %d; want 3", n)
+	}
+}
+
+func TestUserList(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	for _, u := range []model.User{
+		{Username: "alice", Email: "alice@example.com", Status: "active"},
+		{Username: "alan", Email: "alan@example.com", Status: "banned"},
+		{Username: "bob_1", Email: "bob@example.com", Status: "active"},
+		{Username: "bobx1", Email: "bobx@example.com", Status: "active"},
+	} {
+		u.Password = "hash"
+		if err := repo.Create(ctx, &u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		filter    UserFilter
+		wantNames []string
+		wantTotal int64
+	}{
+		{"all", UserFilter{Limit: 10}, []string{"alice", "alan", "bob_1", "bobx1"}, 4},
+		{"page 2", UserFilter{Offset: 2, Limit: 1}, []string{"bob_1"}, 4},
+		{"status", UserFilter{Status: "banned", Limit: 10}, []string{"alan"}, 1},
+		{"keyword", UserFilter{Keyword: "al", Limit: 10}, []string{"alice", "alan"}, 2},
+		{"underscore is literal", UserFilter{Keyword: "b_1", Limit: 10}, []string{"bob_1"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, total, err := repo.List(ctx, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, u := range users {
+				names = append(names, u.Username)
+			}
+			if total != tt.wantTotal || strings.Join(names, ",") != strings.Join(tt.wantNames, ",") {
+				t.Errorf("List() = %v (total %d); want %v (total %d)", names, total, tt.wantNames, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestUserScroll(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	for _, name := range []string{"u1", "u2", "u3", "x4", "u5"} {
+		repo.Create(ctx, &model.User{Username: name, Email: name + "@example.com", Password: "hash"})
+	}
+
+	var names []string
+	var after *pagination.Cursor
+	for {
+		page, err := repo.Scroll(ctx, UserFilter{Keyword: "u", Limit: 2}, after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, u := range page.Items {
+			names = append(names, u.Username)
+		}
+		if !page.HasMore {
+			break
+		}
+		after, _ = pagination.Decode(page.NextCursor)
+	}
+	if got := strings.Join(names, ","); got != "u1,u2,u3,u5" {
+		t.Errorf("scrolled users = %s; want u1,u2,u3,u5", got)
+	}
+}
+
+func TestUserRepositoryContext(t *testing.T) {
+	repo := NewUserRepository(newTestDB(t))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := repo.Get(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() with canceled context err = %v; want context.Canceled", err)
+	}
+}
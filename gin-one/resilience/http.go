@@ -0,0 +1,66 @@
+package resilience
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// Registry 按名字登记各依赖的 Guard，统一输出统计
+type Registry struct {
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	guards map[string]*Guard
+}
+
+// NewRegistry 创建登记表，logger 是 New 出来的 Guard 的默认 Logger，nil 时用 slog.Default()
+func NewRegistry(logger *slog.Logger) *Registry {
+	return &Registry{logger: logger, guards: make(map[string]*Guard)}
+}
+
+// New 创建 Guard 并登记；同名的 Guard 已存在时直接返回它（cfg 被忽略），
+// 这样两处代码调用同一个依赖时共用并发上限和熔断状态
+func (r *Registry) New(cfg Config) *Guard {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if g, ok := r.guards[cfg.Name]; ok {
+		return g
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = r.logger
+	}
+	g := New(cfg)
+	r.guards[cfg.Name] = g
+	return g
+}
+
+// Stats 所有依赖的统计，按名字排序
+func (r *Registry) Stats() []Stats {
+	r.mu.Lock()
+	guards := make([]*Guard, 0, len(r.guards))
+	for _, g := range r.guards {
+		guards = append(guards, g)
+	}
+	r.mu.Unlock()
+
+	stats := make([]Stats, len(guards))
+	for i, g := range guards {
+		stats[i] = g.Stats()
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// Handler 返回各依赖的熔断状态、并发数和计数，挂在管理员路由下：
+//
+//	{"code":0,"data":[{"name":"smtp","state":"closed","in_flight":0,"max_concurrent":4,"calls":12,...}]}
+func Handler(r *Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response.Success(c, r.Stats())
+	}
+}
@@ -0,0 +1,236 @@
+// ============================================================================
+// Package resilience 下游调用的保护：熔断、舱壁（并发上限）和超时，按依赖分别配置
+// ============================================================================
+//
+// 【为什么要三样一起用】
+//
+// 邮件服务、Webhook 接收方、第三方 API 都可能变慢或挂掉，只加其中一样都不够：
+//
+// | 手段           | 防住的问题                                  | 单独使用的缺口                             |
+// |----------------|---------------------------------------------|--------------------------------------------|
+// | 超时           | 一次调用卡住不返回                          | 下游持续超时时，每个请求仍要等满超时时间   |
+// | 熔断           | 下游已经挂了还继续打，失败还要等超时        | 熔断前的慢调用仍会占满 goroutine 和连接    |
+// | 舱壁（并发上限）| 一个慢依赖占光连接池，拖垮不相关的接口     | 不会主动放弃已经挂掉的依赖                 |
+//
+// 熔断器复用 go-learning/httpclient 的 Breaker（closed / open / half-open），
+// 这里加上舱壁、超时和统计，并且不限于 HTTP：任何 func(ctx) error 都能包。
+//
+// 【一次调用的顺序】
+//
+//	Do(ctx, fn)
+//	  ├── 并发槽位已满       → 等 MaxWait，仍没有 → ErrBulkheadFull（RejectedFull +1）
+//	  ├── 熔断打开           → ErrCircuitOpen（RejectedOpen +1），立即归还槽位
+//	  ├── fn(带 Timeout 的 ctx)
+//	  │     ├── 超时         → ErrTimeout，算失败
+//	  │     ├── 调用方取消   → 不算失败（不是下游的问题）
+//	  │     └── 其他错误     → IsFailure 判断是否算失败
+//	  └── 结果记入熔断器，释放槽位
+//
+// ErrCircuitOpen 和 ErrBulkheadFull 都表示"请求没有发出"，IsRejected 判断；
+// 调用方可以据此降级或推迟（webhooks 把投递推迟一会儿，不算一次尝试）。
+//
+// 【用法】
+//
+//	guards := resilience.NewRegistry(nil)
+//	mail := guards.New(resilience.Config{Name: "smtp", MaxConcurrent: 4, Timeout: 10 * time.Second})
+//	err := mail.Do(ctx, func(ctx context.Context) error { return smtp.Send(ctx, msg) })
+//
+//	// HTTP 客户端：Transport 包一层，5xx 也算失败
+//	client := &http.Client{Transport: resilience.Transport(guards.New(resilience.Config{Name: "github"}), nil)}
+//
+//	// 各依赖的状态和计数
+//	admin.GET("/resilience", resilience.Handler(guards))
+//
+// ============================================================================
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"go-learning/httpclient"
+)
+
+// 错误定义
+var (
+	// ErrCircuitOpen 熔断打开，调用没有执行；与 httpclient.ErrCircuitOpen 是同一个值
+	ErrCircuitOpen = httpclient.ErrCircuitOpen
+
+	ErrBulkheadFull = errors.New("resilience: too many concurrent calls")
+	ErrTimeout      = errors.New("resilience: call timed out")
+)
+
+// IsRejected 调用是否被熔断或舱壁拒绝（没有发出）
+func IsRejected(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrBulkheadFull)
+}
+
+// Config 一个依赖的保护配置
+type Config struct {
+	// Name 依赖名，出现在日志、错误和统计里，必填
+	Name string
+
+	// MaxConcurrent 同时进行的调用数上限，默认 10
+	MaxConcurrent int
+
+	// MaxWait 槽位满时最多等多久，默认 0：立即返回 ErrBulkheadFull
+	MaxWait time.Duration
+
+	// Timeout 单次调用的超时，默认 10 秒；调用方 ctx 的截止时间更早时以它为准
+	Timeout time.Duration
+
+	// Breaker 熔断器配置，默认连续失败 5 次熔断、30 秒后试探；OnStateChange 之外还会记日志
+	Breaker httpclient.BreakerConfig
+
+	// IsFailure 哪些错误计入熔断，默认除调用方取消（context.Canceled）外的所有错误
+	// 参数校验失败、404 之类"下游正常但请求不对"的错误应该返回 false
+	IsFailure func(error) bool
+
+	// Logger 记录熔断状态变化，默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Stats 统计，计数从创建起累计
+type Stats struct {
+	Name          string `json:"name"`
+	State         string `json:"state"`          // closed / open / half-open
+	InFlight      int    `json:"in_flight"`      // 正在进行的调用
+	MaxConcurrent int    `json:"max_concurrent"` // 舱壁上限
+	Calls         uint64 `json:"calls"`          // 实际执行的调用
+	Failures      uint64 `json:"failures"`       // 计入熔断的失败（含超时）
+	Timeouts      uint64 `json:"timeouts"`
+	RejectedOpen  uint64 `json:"rejected_open"` // 熔断拒绝
+	RejectedFull  uint64 `json:"rejected_full"` // 舱壁拒绝
+}
+
+// Guard 一个依赖的熔断 + 舱壁 + 超时，可以并发使用
+type Guard struct {
+	cfg     Config
+	breaker *httpclient.Breaker
+	slots   chan struct{}
+
+	calls, failures, timeouts, rejectedOpen, rejectedFull atomic.Uint64
+}
+
+// New 创建 Guard；同一个依赖的所有调用共用一个 Guard，需要统计接口时用 Registry.New
+func New(cfg Config) *Guard {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 10
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool { return !errors.Is(err, context.Canceled) }
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	onChange := cfg.Breaker.OnStateChange
+	cfg.Breaker.OnStateChange = func(from, to httpclient.State) {
+		cfg.Logger.Warn("resilience: circuit state changed", "dependency", cfg.Name, "from", from.String(), "to", to.String())
+		if onChange != nil {
+			onChange(from, to)
+		}
+	}
+	return &Guard{
+		cfg:     cfg,
+		breaker: httpclient.NewBreaker(cfg.Breaker),
+		slots:   make(chan struct{}, cfg.MaxConcurrent),
+	}
+}
+
+// Name 依赖名
+func (g *Guard) Name() string { return g.cfg.Name }
+
+// Do 在熔断、舱壁和超时的保护下执行 fn，fn 必须使用传入的 ctx
+func (g *Guard) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := g.enter(ctx); err != nil {
+		return err
+	}
+	callCtx, cancel := context.WithTimeout(ctx, g.cfg.Timeout)
+	defer cancel()
+	return g.exit(ctx, callCtx, fn(callCtx), false)
+}
+
+// Call Do 的泛型版本，返回 fn 的结果
+func Call[T any](ctx context.Context, g *Guard, fn func(ctx context.Context) (T, error)) (T, error) {
+	var v T
+	err := g.Do(ctx, func(ctx context.Context) error {
+		var err error
+		v, err = fn(ctx)
+		return err
+	})
+	return v, err
+}
+
+// enter 先占槽位再问熔断器：熔断器放行后一定要 Record，槽位满时还没问过它，不会留下记录
+func (g *Guard) enter(ctx context.Context) error {
+	if err := g.acquire(ctx); err != nil {
+		return err
+	}
+	if err := g.breaker.Allow(); err != nil {
+		<-g.slots
+		g.rejectedOpen.Add(1)
+		return fmt.Errorf("%s: %w", g.cfg.Name, err)
+	}
+	g.calls.Add(1)
+	return nil
+}
+
+func (g *Guard) acquire(ctx context.Context) error {
+	select {
+	case g.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if g.cfg.MaxWait > 0 {
+		timer := time.NewTimer(g.cfg.MaxWait)
+		defer timer.Stop()
+		select {
+		case g.slots <- struct{}{}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	g.rejectedFull.Add(1)
+	return fmt.Errorf("%s: %w", g.cfg.Name, ErrBulkheadFull)
+}
+
+// exit 释放槽位并记录结果；failed 为 true 时不看 err 直接算失败（Transport 的 5xx）
+// 调用方 ctx 还没结束而 callCtx 超时了，说明是 Timeout 触发的，转成 ErrTimeout
+func (g *Guard) exit(ctx, callCtx context.Context, err error, failed bool) error {
+	<-g.slots
+	if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		g.timeouts.Add(1)
+		failed = true
+		err = fmt.Errorf("%s: %w after %s: %w", g.cfg.Name, ErrTimeout, g.cfg.Timeout, err)
+	}
+	failed = failed || (err != nil && g.cfg.IsFailure(err))
+	if failed {
+		g.failures.Add(1)
+	}
+	g.breaker.Record(!failed)
+	return err
+}
+
+// Stats 当前状态和累计计数
+func (g *Guard) Stats() Stats {
+	return Stats{
+		Name:          g.cfg.Name,
+		State:         g.breaker.State().String(),
+		InFlight:      len(g.slots),
+		MaxConcurrent: g.cfg.MaxConcurrent,
+		Calls:         g.calls.Load(),
+		Failures:      g.failures.Load(),
+		Timeouts:      g.timeouts.Load(),
+		RejectedOpen:  g.rejectedOpen.Load(),
+		RejectedFull:  g.rejectedFull.Load(),
+	}
+}
@@ -0,0 +1,236 @@
+package resilience
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-learning/httpclient"
+)
+
+var (
+	errDown     = errors.New("down")
+	errNotFound = errors.New("not found")
+)
+
+func newGuard(cfg Config) *Guard {
+	if cfg.Name == "" {
+		cfg.Name = "test"
+	}
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	return New(cfg)
+}
+
+func fail(err error) func(context.Context) error {
+	return func(context.Context) error { return err }
+}
+
+func TestBreaker(t *testing.T) {
+	var changes []string
+	g := newGuard(Config{Breaker: httpclient.BreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      20 * time.Millisecond,
+		OnStateChange:    func(from, to httpclient.State) { changes = append(changes, to.String()) },
+	}})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := g.Do(ctx, fail(errDown)); !errors.Is(err, errDown) {
+			t.Fatalf("call %d: err = %v; want errDown", i, err)
+		}
+	}
+	err := g.Do(ctx, fail(nil))
+	if !errors.Is(err, ErrCircuitOpen) || !IsRejected(err) {
+		t.Fatalf("after threshold: err = %v; want ErrCircuitOpen", err)
+	}
+
+	// 冷却结束后放行一次试探，成功后关闭
+	time.Sleep(30 * time.Millisecond)
+	if err := g.Do(ctx, fail(nil)); err != nil {
+		t.Fatalf("probe: err = %v", err)
+	}
+	got := g.Stats()
+	if got.State != "closed" || got.Calls != 3 || got.Failures != 2 || got.RejectedOpen != 1 || got.InFlight != 0 {
+		t.Errorf("Stats = %+v", got)
+	}
+	if len(changes) != 3 || changes[0] != "open" || changes[2] != "closed" {
+		t.Errorf("state changes = %v; want open, half-open, closed", changes)
+	}
+}
+
+func TestIsFailure(t *testing.T) {
+	g := newGuard(Config{
+		Breaker:   httpclient.BreakerConfig{FailureThreshold: 1},
+		IsFailure: func(err error) bool { return !errors.Is(err, errNotFound) },
+	})
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := g.Do(ctx, fail(errNotFound)); !errors.Is(err, errNotFound) {
+			t.Fatalf("err = %v", err)
+		}
+	}
+	if s := g.Stats(); s.State != "closed" || s.Failures != 0 {
+		t.Errorf("Stats = %+v; not-found must not open the circuit", s)
+	}
+
+	// 调用方自己取消不算下游失败
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	g2 := newGuard(Config{Breaker: httpclient.BreakerConfig{FailureThreshold: 1}})
+	if err := g2.Do(cctx, func(ctx context.Context) error { return ctx.Err() }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v; want Canceled", err)
+	}
+	if s := g2.Stats(); s.State != "closed" {
+		t.Errorf("caller cancel opened the circuit: %+v", s)
+	}
+}
+
+func TestBulkhead(t *testing.T) {
+	g := newGuard(Config{MaxConcurrent: 1})
+	ctx := context.Background()
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- g.Do(ctx, func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	err := g.Do(ctx, fail(nil))
+	if !errors.Is(err, ErrBulkheadFull) || !IsRejected(err) {
+		t.Fatalf("second call: err = %v; want ErrBulkheadFull", err)
+	}
+	if s := g.Stats(); s.InFlight != 1 || s.RejectedFull != 1 {
+		t.Errorf("Stats = %+v", s)
+	}
+
+	// MaxWait：等到第一个调用结束后拿到槽位
+	waiting := newGuard(Config{MaxConcurrent: 1, MaxWait: time.Second})
+	waiting.slots <- struct{}{}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-waiting.slots
+	}()
+	if err := waiting.Do(ctx, fail(nil)); err != nil {
+		t.Errorf("MaxWait: err = %v; want nil", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	g := newGuard(Config{Timeout: 10 * time.Millisecond})
+	v, err := Call(context.Background(), g, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) || v != 0 {
+		t.Fatalf("Call = %d, %v; want ErrTimeout wrapping DeadlineExceeded", v, err)
+	}
+	if s := g.Stats(); s.Timeouts != 1 || s.Failures != 1 {
+		t.Errorf("Stats = %+v", s)
+	}
+
+	v, err = Call(context.Background(), g, func(context.Context) (int, error) { return 42, nil })
+	if v != 42 || err != nil {
+		t.Errorf("Call = %d, %v; want 42", v, err)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(int(status.Load()))
+		io.WriteString(w, "body")
+	}))
+	defer srv.Close()
+
+	g := newGuard(Config{Timeout: 50 * time.Millisecond, Breaker: httpclient.BreakerConfig{FailureThreshold: 2}})
+	client := &http.Client{Transport: Transport(g, nil)}
+
+	// 4xx 不算失败
+	status.Store(http.StatusNotFound)
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if s := g.Stats(); s.Failures != 0 {
+		t.Errorf("404 counted as failure: %+v", s)
+	}
+
+	// 5xx 照常返回，计入熔断；响应体在超时前可以读
+	status.Store(http.StatusInternalServerError)
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil || resp.StatusCode != 500 {
+			t.Fatalf("get %d: %v, %v", i, resp, err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(b) != "body" {
+			t.Errorf("body = %q", b)
+		}
+	}
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v; want ErrCircuitOpen through *url.Error", err)
+	}
+
+	slow := newGuard(Config{Timeout: 20 * time.Millisecond})
+	client = &http.Client{Transport: Transport(slow, nil)}
+	if _, err := client.Get(srv.URL + "/slow"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("slow: err = %v; want ErrTimeout", err)
+	}
+	if s := slow.Stats(); s.Timeouts != 1 || s.InFlight != 0 {
+		t.Errorf("slow Stats = %+v", s)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg := NewRegistry(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	smtp := reg.New(Config{Name: "smtp", MaxConcurrent: 4})
+	if again := reg.New(Config{Name: "smtp"}); again != smtp {
+		t.Error("same name returned a different Guard")
+	}
+	reg.New(Config{Name: "github"})
+	_ = smtp.Do(context.Background(), fail(errDown))
+
+	r := gin.New()
+	r.GET("/resilience", Handler(reg))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/resilience", nil))
+
+	var body struct {
+		Data []Stats `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data) != 2 || body.Data[0].Name != "github" || body.Data[1].Name != "smtp" {
+		t.Fatalf("stats = %+v; want github, smtp", body.Data)
+	}
+	if s := body.Data[1]; s.MaxConcurrent != 4 || s.Calls != 1 || s.Failures != 1 {
+		t.Errorf("smtp = %+v", s)
+	}
+}
@@ -0,0 +1,57 @@
+package resilience
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Transport 用 g 保护出站 HTTP 请求的 RoundTripper，base 为 nil 时用 http.DefaultTransport
+//
+// 与 Do 的区别：
+//   - 5xx 响应照常返回给调用方，但计入熔断（下游出错了）；4xx 不算
+//   - 超时覆盖读取响应体，响应体 Close 时才取消，和 http.Client.Timeout 一样
+//   - 并发槽位在收到响应头时释放，读响应体不占槽位
+//
+// 被拒绝时 RoundTrip 返回的错误包着 ErrCircuitOpen / ErrBulkheadFull，
+// http.Client 会再包一层 *url.Error，errors.Is 和 IsRejected 仍然能判断。
+func Transport(g *Guard, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{g: g, base: base}
+}
+
+type transport struct {
+	g    *Guard
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if err := t.g.enter(ctx); err != nil {
+		return nil, err
+	}
+	callCtx, cancel := context.WithTimeout(ctx, t.g.cfg.Timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(callCtx))
+	if err != nil {
+		err = t.g.exit(ctx, callCtx, err, false)
+		cancel()
+		return nil, err
+	}
+	t.g.exit(ctx, callCtx, nil, resp.StatusCode >= 500)
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody 响应体关闭时取消超时 ctx
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
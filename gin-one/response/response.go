@@ -0,0 +1,72 @@
+// ============================================================================
+// Package response 统一响应格式
+// ============================================================================
+//
+// 与 examples/1_3_request_response.go 中演示的结构一致，抽出来供中间件和各示例复用：
+//
+//	成功: {"code": 0,  "message": "success", "data": {...}}
+//	失败: {"code": -1, "message": "用户不存在", "error": "user_not_found"}
+//
+// code 是业务状态码，HTTP 状态码仍然按语义设置（400/404/500...），
+// 前端先看 HTTP 状态码判断大类，再用 error 字段做精细处理。
+//
+// 请求经过 i18n.Middleware 且协商到其他语言时，message 换成目录里 errors.<error> 的翻译，
+// 目录里没有这个错误码时保持原文。
+//
+// ============================================================================
+package response
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/i18n"
+)
+
+// 业务状态码
+const (
+	CodeOK    = 0
+	CodeError = -1
+)
+
+// Response 统一响应结构
+type Response struct {
+	Code    int    `json:"code"`            // 业务状态码
+	Message string `json:"message"`         // 提示信息
+	Data    any    `json:"data,omitempty"`  // 数据
+	Error   string `json:"error,omitempty"` // 错误码，如 user_not_found
+}
+
+// Success 成功响应
+func Success(c *gin.Context, data any) {
+	c.JSON(http.StatusOK, Response{
+		Code:    CodeOK,
+		Message: "success",
+		Data:    data,
+	})
+}
+
+// Error 错误响应
+func Error(c *gin.Context, httpCode int, errCode, message string) {
+	c.JSON(httpCode, Response{
+		Code:    CodeError,
+		Message: Localize(c, errCode, message),
+		Error:   errCode,
+	})
+}
+
+// Abort 错误响应并中止后续 Handler，中间件中使用
+func Abort(c *gin.Context, httpCode int, errCode, message string) {
+	c.AbortWithStatusJSON(httpCode, Response{
+		Code:    CodeError,
+		Message: Localize(c, errCode, message),
+		Error:   errCode,
+	})
+}
+
+// Localize 错误提示的翻译（errors.<errCode>），args 同 i18n.Localizer.Translate；
+// 自己拼错误响应的中间件用它和 Error / Abort 保持一致
+func Localize(c *gin.Context, errCode, message string, args ...any) string {
+	return i18n.FromContext(c).Translate("errors."+errCode, message, args...)
+}
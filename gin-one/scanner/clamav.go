@@ -0,0 +1,151 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ClamAVConfig clamd 连接配置
+type ClamAVConfig struct {
+	Addr      string        // clamd 的 TCP 地址，clamd.conf 里的 TCPSocket，默认 127.0.0.1:3310
+	Timeout   time.Duration // 单次扫描的总超时，默认 30 秒
+	ChunkSize int           // INSTREAM 每块的大小，默认 64KB
+}
+
+// ClamAV clamd TCP 客户端
+//
+// 使用 INSTREAM 命令，文件内容通过连接发送，clamd 不需要访问应用的文件系统：
+//
+//	→ zINSTREAM\0
+//	→ <4 字节大端长度><数据> ... <0000>
+//	← stream: OK\0                     干净
+//	← stream: Eicar-Signature FOUND\0  感染
+//	← INSTREAM size limit exceeded. ERROR\0
+//
+// 每次扫描一个新连接，clamd 默认最多 10 个并发连接（MaxThreads）。
+type ClamAV struct {
+	addr    string
+	timeout time.Duration
+	chunk   int
+}
+
+// NewClamAV 创建 clamd 客户端，不会立即连接
+func NewClamAV(cfg ClamAVConfig) *ClamAV {
+	if cfg.Addr == "" {
+		cfg.Addr = "127.0.0.1:3310"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = 64 << 10
+	}
+	return &ClamAV{addr: cfg.Addr, timeout: cfg.Timeout, chunk: cfg.ChunkSize}
+}
+
+// Scan 实现 Scanner
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (Verdict, error) {
+	reply, err := c.command(ctx, "zINSTREAM", func(w io.Writer) error {
+		return c.stream(w, r)
+	})
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return Verdict{Clean: true, Engine: "clamav"}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return Verdict{Signature: strings.TrimSuffix(reply, " FOUND"), Engine: "clamav"}, nil
+	default:
+		// "... ERROR" 或其他意外响应
+		return Verdict{}, fmt.Errorf("%w: %s", ErrScan, reply)
+	}
+}
+
+// Ping 检查 clamd 是否可用，可以注册到 health：h.Register("clamav", health.Ping(av))
+func (c *ClamAV) Ping(ctx context.Context) error {
+	reply, err := c.command(ctx, "zPING", nil)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("%w: unexpected ping reply %q", ErrScan, reply)
+	}
+	return nil
+}
+
+// stream 按 INSTREAM 格式分块发送，最后发送长度为 0 的块表示结束
+func (c *ClamAV) stream(w io.Writer, r io.Reader) error {
+	buf := make([]byte, 4+c.chunk)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := w.Write(buf[:4+n]); werr != nil {
+				return fmt.Errorf("%w: %v", ErrUnavailable, werr)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("scanner: read file: %w", err)
+		}
+	}
+	if _, err := w.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// command 发送一条 z 开头（\0 结尾）的命令，返回去掉结尾 \0 的响应
+func (c *ClamAV) command(ctx context.Context, cmd string, body func(io.Writer) error) (string, error) {
+	d := net.Dialer{Timeout: c.timeout}
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(c.timeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	conn.SetDeadline(deadline)
+	// 请求被取消时让阻塞中的读写立即返回
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	if _, err := conn.Write([]byte(cmd + "\x00")); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	var werr error
+	if body != nil {
+		werr = body(conn)
+	}
+	if werr != nil {
+		// 超过 StreamMaxLength 时 clamd 先回复错误再断开，写入会失败，
+		// 这时仍然尝试读响应拿到真正的原因；读文件出错时 clamd 还在等数据，不要一直等
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	reply = strings.TrimSpace(strings.TrimSuffix(reply, "\x00"))
+	if reply == "" {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if werr != nil {
+			return "", werr
+		}
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return reply, nil
+}
@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"go-one/storage"
+)
+
+// Config Guard 配置
+type Config struct {
+	Logger *slog.Logger // 默认 slog.Default()
+}
+
+// Guard 上传后扫描，感染文件移到隔离区
+type Guard struct {
+	scanner    Scanner
+	quarantine storage.Blob
+	logger     *slog.Logger
+	now        func() time.Time
+}
+
+// NewGuard 创建 Guard，quarantine 是隔离区，不要给它注册下载路由
+func NewGuard(s Scanner, quarantine storage.Blob, cfg Config) *Guard {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Guard{scanner: s, quarantine: quarantine, logger: cfg.Logger, now: time.Now}
+}
+
+// record 隔离区里和文件放在一起的 .verdict.json
+type record struct {
+	Key           string    `json:"key"`
+	Signature     string    `json:"signature"`
+	Engine        string    `json:"engine"`
+	QuarantinedAt time.Time `json:"quarantined_at"`
+}
+
+// Check 扫描已经写入 b 的 key
+//
+//   - 干净：返回 Verdict，文件保持原样
+//   - 感染：复制到隔离区，从 b 删除，返回 Verdict 和 ErrInfected
+//   - 扫描失败：从 b 删除，返回错误（fail closed）
+func (g *Guard) Check(ctx context.Context, b storage.Blob, key string) (Verdict, error) {
+	rc, err := b.Get(ctx, key)
+	if err != nil {
+		return Verdict{}, err
+	}
+	v, err := g.scanner.Scan(ctx, rc)
+	rc.Close()
+	if err != nil {
+		g.discard(b, key)
+		return Verdict{}, err
+	}
+	if v.Clean {
+		return v, nil
+	}
+
+	if rc, err := b.Get(ctx, key); err != nil {
+		g.logger.Error("quarantine: read infected file", "key", key, "error", err)
+	} else {
+		err = g.isolate(ctx, key, rc, v)
+		rc.Close()
+		if err != nil {
+			g.logger.Error("quarantine: write", "key", key, "error", err)
+		}
+	}
+	// 隔离失败也要删除，不能让感染文件留在可下载的位置
+	g.discard(b, key)
+	return v, ErrInfected
+}
+
+// CheckFile 扫描还没有写入存储的文件，感染时以 key 为名放进隔离区
+//
+// 返回时 f 已经回到开头，干净的文件可以直接交给 blob.Put 或 files.Store.Save。
+func (g *Guard) CheckFile(ctx context.Context, f io.ReadSeeker, key string) (Verdict, error) {
+	v, err := g.scanner.Scan(ctx, f)
+	if _, serr := f.Seek(0, io.SeekStart); err == nil && serr != nil {
+		err = serr
+	}
+	if err != nil {
+		return Verdict{}, err
+	}
+	if v.Clean {
+		return v, nil
+	}
+	if err := g.isolate(ctx, key, f, v); err != nil {
+		g.logger.Error("quarantine: write", "key", key, "error", err)
+	}
+	return v, ErrInfected
+}
+
+// isolate 把感染文件和扫描记录写入隔离区
+func (g *Guard) isolate(ctx context.Context, key string, r io.Reader, v Verdict) error {
+	if err := g.quarantine.Put(ctx, key, r, "application/octet-stream"); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(record{Key: key, Signature: v.Signature, Engine: v.Engine, QuarantinedAt: g.now()})
+	if err != nil {
+		return err
+	}
+	if err := g.quarantine.Put(ctx, key+".verdict.json", bytes.NewReader(meta), "application/json"); err != nil {
+		return fmt.Errorf("write verdict: %w", err)
+	}
+	g.logger.Warn("file quarantined", "key", key, "signature", v.Signature, "engine", v.Engine)
+	return nil
+}
+
+// discard 删除上传的文件；请求可能已经取消，用独立的 context
+func (g *Guard) discard(b storage.Blob, key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := b.Delete(ctx, key); err != nil {
+		g.logger.Error("quarantine: delete upload", "key", key, "error", err)
+	}
+}
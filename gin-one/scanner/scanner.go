@@ -0,0 +1,85 @@
+// ============================================================================
+// Package scanner 上传文件病毒扫描
+// ============================================================================
+//
+// 【流程】
+//
+//	上传 → blob.Put(key) → Guard.Check(key) ──干净──→ 返回 key / 下载链接
+//	                                      └──感染──→ 复制到隔离区，从 blob 删除，422
+//
+// key 在响应之前没有交给客户端，所以扫描完成之前文件不会被下载。
+// 需要先扫描再落盘的场景（如按内容去重，写入后还要建数据库记录）用 CheckFile，
+// 直接扫描还没有写入存储的临时文件。
+//
+// 【扫描器】
+//
+// | scanner.driver | 实现     | 说明                                           |
+// |----------------|----------|------------------------------------------------|
+// | none（默认）   | Nop      | 不扫描，所有文件都判定为干净                   |
+// | clamav         | ClamAV   | 通过 TCP 把文件流发给 clamd（INSTREAM 命令）   |
+//
+// 【扫描失败怎么办？】
+//
+// clamd 连不上、超时、文件超过 clamd 的 StreamMaxLength 时，无法判断文件是否安全。
+// Guard 按"不安全"处理（fail closed）：删除已上传的文件并返回错误，客户端稍后重传。
+// 宁可上传失败，也不能放过一个没扫描的文件。
+//
+// 【隔离区】
+//
+// 感染文件写到单独的 Blob（默认本地 ./quarantine 目录，不注册任何下载路由），
+// 旁边是同名的 .verdict.json，记录原 key、病毒名和时间，方便人工复核。
+//
+// ============================================================================
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go-one/config"
+)
+
+// 错误定义
+var (
+	// ErrInfected 文件被判定为恶意，已移到隔离区
+	ErrInfected = errors.New("scanner: file infected")
+	// ErrUnavailable 连不上扫描服务
+	ErrUnavailable = errors.New("scanner: unavailable")
+	// ErrScan 扫描服务返回错误（如文件超过大小限制）
+	ErrScan = errors.New("scanner: scan failed")
+)
+
+// Verdict 扫描结果
+type Verdict struct {
+	Clean     bool   `json:"clean"`
+	Signature string `json:"signature,omitempty"` // 命中的病毒特征名，如 Win.Test.EICAR_HDB-1
+	Engine    string `json:"engine"`
+}
+
+// Scanner 扫描器接口
+type Scanner interface {
+	// Scan 读完 r 并返回结果；感染不是错误，通过 Verdict.Clean 区分
+	Scan(ctx context.Context, r io.Reader) (Verdict, error)
+}
+
+// Nop 不扫描，所有文件都判定为干净
+type Nop struct{}
+
+// Scan 实现 Scanner
+func (Nop) Scan(context.Context, io.Reader) (Verdict, error) {
+	return Verdict{Clean: true, Engine: "none"}, nil
+}
+
+// Open 按配置创建扫描器
+func Open(c config.ScannerConfig) (Scanner, error) {
+	switch c.Driver {
+	case "", "none":
+		return Nop{}, nil
+	case "clamav":
+		return NewClamAV(ClamAVConfig{Addr: c.ClamAV.Addr, Timeout: c.ClamAV.Timeout}), nil
+	default:
+		return nil, fmt.Errorf("scanner: unknown driver %q", c.Driver)
+	}
+}
@@ -0,0 +1,257 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go-one/config"
+	"go-one/storage"
+)
+
+const eicar = "EICAR-STANDARD-ANTIVIRUS-TEST-FILE"
+
+// fakeClamd 按 clamd 协议应答：内容包含 eicar 判定为感染，超过 limit 字节回复 size limit 错误
+func fakeClamd(t *testing.T, limit int) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveClamd(conn, limit)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveClamd(conn net.Conn, limit int) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	cmd, err := br.ReadString(0)
+	if err != nil {
+		return
+	}
+	switch cmd {
+	case "zPING\x00":
+		conn.Write([]byte("PONG\x00"))
+	case "zINSTREAM\x00":
+		var data []byte
+		for {
+			var n uint32
+			if binary.Read(br, binary.BigEndian, &n) != nil {
+				return
+			}
+			if n == 0 {
+				break
+			}
+			chunk := make([]byte, n)
+			if _, err := io.ReadFull(br, chunk); err != nil {
+				return
+			}
+			data = append(data, chunk...)
+			if len(data) > limit {
+				conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+				return
+			}
+		}
+		if bytes.Contains(data, []byte(eicar)) {
+			conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+		} else {
+			conn.Write([]byte("stream: OK\x00"))
+		}
+	default:
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+	}
+}
+
+func TestClamAV(t *testing.T) {
+	addr := fakeClamd(t, 1000)
+	// 块很小，病毒特征会被拆到多个块里
+	av := NewClamAV(ClamAVConfig{Addr: addr, Timeout: 5 * time.Second, ChunkSize: 7})
+
+	tests := []struct {
+		name    string
+		content string
+		want    Verdict
+		err     error
+	}{
+		{"clean", "hello world", Verdict{Clean: true, Engine: "clamav"}, nil},
+		{"empty", "", Verdict{Clean: true, Engine: "clamav"}, nil},
+		{"infected", "prefix " + eicar + " suffix", Verdict{Signature: "Eicar-Test-Signature", Engine: "clamav"}, nil},
+		{"too large", strings.Repeat("x", 5000), Verdict{}, ErrScan},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := av.Scan(context.Background(), strings.NewReader(tt.content))
+			if !errors.Is(err, tt.err) || v != tt.want {
+				t.Fatalf("Scan = %+v, %v; want %+v, %v", v, err, tt.want, tt.err)
+			}
+		})
+	}
+
+	if err := av.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	// 没有 clamd 在监听
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	down := ln.Addr().String()
+	ln.Close()
+	if _, err := NewClamAV(ClamAVConfig{Addr: down}).Scan(context.Background(), strings.NewReader("x")); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Scan with clamd down err = %v; want ErrUnavailable", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   any
+		err    bool
+	}{
+		{"", Nop{}, false},
+		{"none", Nop{}, false},
+		{"clamav", &ClamAV{}, false},
+		{"virustotal", nil, true},
+	}
+	for _, tt := range tests {
+		s, err := Open(config.ScannerConfig{Driver: tt.driver})
+		if (err != nil) != tt.err {
+			t.Errorf("Open(%q) err = %v", tt.driver, err)
+			continue
+		}
+		switch tt.want.(type) {
+		case Nop:
+			if _, ok := s.(Nop); !ok {
+				t.Errorf("Open(%q) = %T; want Nop", tt.driver, s)
+			}
+		case *ClamAV:
+			if _, ok := s.(*ClamAV); !ok {
+				t.Errorf("Open(%q) = %T; want *ClamAV", tt.driver, s)
+			}
+		}
+	}
+}
+
+// failing 扫描服务不可用
+type failing struct{}
+
+func (failing) Scan(context.Context, io.Reader) (Verdict, error) {
+	return Verdict{}, ErrUnavailable
+}
+
+func newGuard(t *testing.T, s Scanner) (*Guard, storage.Blob, storage.Blob) {
+	t.Helper()
+	uploads, err := storage.NewLocal(t.TempDir(), "/files", []byte("k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	quarantine, err := storage.NewLocal(t.TempDir(), "/quarantine", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGuard(s, quarantine, Config{})
+	g.now = func() time.Time { return time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC) }
+	return g, uploads, quarantine
+}
+
+func exists(b storage.Blob, key string) bool {
+	rc, err := b.Get(context.Background(), key)
+	if err == nil {
+		rc.Close()
+	}
+	return err == nil
+}
+
+func TestGuardCheck(t *testing.T) {
+	ctx := context.Background()
+	g, uploads, quarantine := newGuard(t, NewClamAV(ClamAVConfig{Addr: fakeClamd(t, 1<<20)}))
+
+	tests := []struct {
+		name           string
+		scanner        Scanner
+		content        string
+		err            error
+		kept, isolated bool
+	}{
+		{"clean", nil, "hello", nil, true, false},
+		{"infected", nil, "x" + eicar, ErrInfected, false, true},
+		{"scanner down", failing{}, "hello", ErrUnavailable, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := g
+			if tt.scanner != nil {
+				guard = NewGuard(tt.scanner, quarantine, Config{})
+			}
+			key := "docs/" + strings.ReplaceAll(tt.name, " ", "_") + ".txt"
+			uploads.Put(ctx, key, strings.NewReader(tt.content), "")
+
+			_, err := guard.Check(ctx, uploads, key)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Check err = %v; want %v", err, tt.err)
+			}
+			if exists(uploads, key) != tt.kept {
+				t.Errorf("upload kept = %v; want %v", !tt.kept, tt.kept)
+			}
+			if exists(quarantine, key) != tt.isolated {
+				t.Errorf("quarantined = %v; want %v", !tt.isolated, tt.isolated)
+			}
+		})
+	}
+
+	// 隔离记录
+	rc, err := quarantine.Get(ctx, "docs/infected.txt.verdict.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	var rec record
+	if err := json.NewDecoder(rc).Decode(&rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Key != "docs/infected.txt" || rec.Signature != "Eicar-Test-Signature" || rec.Engine != "clamav" || rec.QuarantinedAt.IsZero() {
+		t.Fatalf("record = %+v", rec)
+	}
+}
+
+func TestGuardCheckFile(t *testing.T) {
+	ctx := context.Background()
+	g, _, quarantine := newGuard(t, NewClamAV(ClamAVConfig{Addr: fakeClamd(t, 1<<20)}))
+
+	// 干净文件扫描后回到开头，可以继续读
+	f := strings.NewReader("hello")
+	if v, err := g.CheckFile(ctx, f, "a.txt"); err != nil || !v.Clean {
+		t.Fatalf("CheckFile = %+v, %v", v, err)
+	}
+	if b, _ := io.ReadAll(f); string(b) != "hello" {
+		t.Fatalf("file not rewound, read %q", b)
+	}
+
+	v, err := g.CheckFile(ctx, strings.NewReader(eicar), "b.txt")
+	if !errors.Is(err, ErrInfected) || v.Signature != "Eicar-Test-Signature" {
+		t.Fatalf("CheckFile = %+v, %v; want ErrInfected", v, err)
+	}
+	rc, err := quarantine.Get(ctx, "b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if b, _ := io.ReadAll(rc); string(b) != eicar {
+		t.Fatalf("quarantined content = %q", b)
+	}
+}
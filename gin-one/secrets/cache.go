@@ -0,0 +1,204 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"go-learning/clock"
+)
+
+// CacheConfig 缓存配置
+type CacheConfig struct {
+	// TTL 缓存有效期，过期后下一次 Get 重新读取，默认 5 分钟
+	TTL time.Duration
+	// Clock 默认 clock.Real，测试时注入 clock.Fake
+	Clock clock.Clock
+	// Logger 记录刷新失败和密钥变化（不记录密钥的值），默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Cache 给 Provider 加上缓存和变化回调，可以并发使用
+type Cache struct {
+	p      Provider
+	cfg    CacheConfig
+	clock  clock.Clock
+	logger *slog.Logger
+	group  singleflight.Group // 同一个名字同时过期时只读取一次
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	hooks   map[string][]func(old, cur []byte)
+}
+
+type entry struct {
+	value     []byte
+	fetchedAt time.Time
+}
+
+// NewCache 创建缓存
+func NewCache(p Provider, cfg CacheConfig) *Cache {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 5 * time.Minute
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Cache{
+		p:       p,
+		cfg:     cfg,
+		clock:   clock.OrReal(cfg.Clock),
+		logger:  cfg.Logger,
+		entries: map[string]*entry{},
+		hooks:   map[string][]func(old, cur []byte){},
+	}
+}
+
+// Get 返回缓存的值，过期时重新读取；重新读取失败但有旧值时返回旧值
+func (c *Cache) Get(ctx context.Context, name string) ([]byte, error) {
+	c.mu.Lock()
+	e, ok := c.entries[name]
+	c.mu.Unlock()
+	if ok && c.clock.Since(e.fetchedAt) < c.cfg.TTL {
+		return e.value, nil
+	}
+	v, err := c.fetch(ctx, name)
+	if err != nil && v != nil {
+		// 密钥来源暂时不可用：继续用旧值，下一次 Get 再试
+		c.logger.Warn("secrets: refresh failed, using cached value", "name", name, "error", err)
+		return v, nil
+	}
+	return v, err
+}
+
+// fetch 从 Provider 读取并更新缓存，值变了时调用回调；失败时返回旧值（没有时为 nil）和错误
+func (c *Cache) fetch(ctx context.Context, name string) ([]byte, error) {
+	v, err, _ := c.group.Do(name, func() (any, error) {
+		v, err := c.p.Get(ctx, name)
+
+		c.mu.Lock()
+		old, ok := c.entries[name]
+		if err != nil {
+			c.mu.Unlock()
+			if ok {
+				return old.value, err
+			}
+			return []byte(nil), err
+		}
+		c.entries[name] = &entry{value: v, fetchedAt: c.clock.Now()}
+		hooks := c.hooks[name]
+		c.mu.Unlock()
+
+		if ok && !bytes.Equal(old.value, v) {
+			c.logger.Info("secrets: value changed", "name", name)
+			for _, fn := range hooks {
+				fn(old.value, v)
+			}
+		}
+		return v, nil
+	})
+	return v.([]byte), err
+}
+
+// OnChange 注册变化回调，刷新后值和上一次不同时调用；回调在刷新的 goroutine 里同步执行
+func (c *Cache) OnChange(name string, fn func(old, cur []byte)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks[name] = append(c.hooks[name], fn)
+}
+
+// Refresh 立即重新读取所有读过的密钥，不管是否过期；返回各个密钥的读取错误
+func (c *Cache) Refresh(ctx context.Context) error {
+	c.mu.Lock()
+	names := make([]string, 0, len(c.entries))
+	for name := range c.entries {
+		names = append(names, name)
+	}
+	c.mu.Unlock()
+
+	var errs []error
+	for _, name := range names {
+		// 失败时缓存里保留旧值，这里只收集错误
+		if _, err := c.fetch(ctx, name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run 每隔 interval 刷新一次，阻塞直到 ctx 取消
+//
+// 不依赖 Get 触发过期：没有请求的时候密钥也会更新，OnChange 回调及时执行。
+func (c *Cache) Run(ctx context.Context, interval time.Duration) {
+	ticker := c.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if err := c.Refresh(ctx); err != nil {
+				c.logger.Warn("secrets: refresh failed", "error", err)
+			}
+		}
+	}
+}
+
+// ============================================================================
+// 轮换中的密钥
+// ============================================================================
+
+// Rotating 当前密钥和轮换前的密钥，签名用 Current，验证时 Verification 里的都接受
+type Rotating struct {
+	grace time.Duration
+	clock clock.Clock
+
+	mu        sync.RWMutex
+	current   []byte
+	previous  []byte
+	rotatedAt time.Time
+}
+
+// Fixed 不会轮换的密钥，没有配置外部来源时使用
+func Fixed(value []byte) *Rotating {
+	return &Rotating{current: value, clock: clock.Real}
+}
+
+// Watch 读取密钥并跟踪变化；变化后旧密钥在 grace 内仍出现在 Verification 里
+func (c *Cache) Watch(ctx context.Context, name string, grace time.Duration) (*Rotating, error) {
+	v, err := c.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	r := &Rotating{grace: grace, clock: c.clock, current: v}
+	c.OnChange(name, func(old, cur []byte) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.previous, r.current, r.rotatedAt = old, cur, r.clock.Now()
+	})
+	return r, nil
+}
+
+// Current 签名用的密钥
+func (r *Rotating) Current() []byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// Verification 验证时接受的密钥：当前密钥在前，加上 grace 内的旧密钥
+func (r *Rotating) Verification() [][]byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := [][]byte{r.current}
+	if r.previous != nil && r.clock.Since(r.rotatedAt) < r.grace {
+		keys = append(keys, r.previous)
+	}
+	return keys
+}
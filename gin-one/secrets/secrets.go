@@ -0,0 +1,145 @@
+// ============================================================================
+// Package secrets 密钥来源：环境变量、挂载文件、Vault，带缓存和轮换回调
+// ============================================================================
+//
+// 【为什么不直接用 config？】
+//
+// config 在启动时把 jwt.secret、database.password 读进结构体，之后就不变了：
+// 密钥轮换要改环境变量再重启所有实例。这里把"密钥从哪来"抽象成 Provider，
+// Cache 定期重新读取，值变了就调用回调，JWT 密钥换掉不用重启。
+//
+// | Provider | 名字 jwt.secret 对应                           | 典型场景                         |
+// |----------|------------------------------------------------|----------------------------------|
+// | Env      | 环境变量 APP_JWT_SECRET                        | 本地开发、CI                     |
+// | File     | 文件 <Dir>/jwt.secret，去掉末尾换行            | Kubernetes Secret 卷、Docker secrets |
+// | Vault    | KV v2：<Mount>/data/<Path>/jwt 里的 secret 字段 | HashiCorp Vault 及兼容的服务     |
+// | Chain    | 按顺序找，第一个找到的为准                     | Vault 优先，没有时退回环境变量   |
+//
+// 环境变量在进程启动后不会变，Env 适合不需要轮换的场景；
+// Kubernetes 更新 Secret 后会原子替换卷里的文件，File 下一次读取就是新值。
+//
+// 【缓存】
+//
+//	sc := secrets.NewCache(vault, secrets.CacheConfig{TTL: 5 * time.Minute})
+//	go sc.Run(ctx, time.Minute)            // 定期刷新读过的密钥，值变了调用 OnChange 回调
+//
+// | 情况                     | 行为                                                  |
+// |--------------------------|-------------------------------------------------------|
+// | 缓存未过期               | 直接返回，不访问 Vault                                |
+// | 过期后重新读取失败       | 返回旧值并记录日志：Vault 短暂不可用不影响签发 Token  |
+// | 第一次读取就失败         | 返回错误，启动失败                                    |
+//
+// 【JWT 密钥轮换】
+//
+//	keys, err := sc.Watch(ctx, "jwt.secret", accessTTL)
+//	token.SignedString(keys.Current())   // 用新密钥签发
+//	for _, k := range keys.Verification() { set.Keys = append(set.Keys, k) }  // jwt.VerificationKeySet，新旧密钥都能验证
+//
+// 密钥变化后，旧密钥在 grace（通常等于 Access Token 有效期）内仍可验证：
+// 轮换之前签发的 Token 不会立刻失效，过了 grace 旧密钥作废。
+//
+// 数据库密码只在建立连接池时读取一次；轮换数据库密码要让新旧密码同时有效一段时间并重启实例。
+//
+// ============================================================================
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 错误定义
+var (
+	ErrNotFound    = errors.New("secrets: not found")
+	ErrInvalidName = errors.New("secrets: invalid name")
+)
+
+// Provider 按名字读取密钥，名字形如 jwt.secret、database.password；没有这个密钥时返回 ErrNotFound
+type Provider interface {
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+// Env 从环境变量读取：Prefix + "_" + 名字大写，"." 换成 "_"，与 config 包的规则相同
+type Env struct {
+	// Prefix 默认 APP
+	Prefix string
+}
+
+// Get 环境变量没有设置或为空时返回 ErrNotFound
+func (e Env) Get(_ context.Context, name string) ([]byte, error) {
+	prefix := e.Prefix
+	if prefix == "" {
+		prefix = "APP"
+	}
+	key := prefix + "_" + strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+	v := os.Getenv(key)
+	if v == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return []byte(v), nil
+}
+
+// File 从目录下与名字同名的文件读取，每次调用都重新读文件
+type File struct {
+	Dir string
+}
+
+// Get 文件不存在时返回 ErrNotFound；名字里不能有路径分隔符，防止读到目录外的文件
+func (f File) Get(_ context.Context, name string) ([]byte, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	b, err := os.ReadFile(filepath.Join(f.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// echo "secret" > file 和 kubectl create secret --from-file 都可能带上换行
+	return []byte(strings.TrimRight(string(b), "\r\n")), nil
+}
+
+// Chain 依次查找，返回第一个找到的值；某个 Provider 出错（不是 ErrNotFound）时直接返回错误，
+// 不会因为 Vault 暂时不可用就悄悄用上环境变量里的旧值
+type Chain []Provider
+
+// Get 都没有时返回 ErrNotFound
+func (c Chain) Get(ctx context.Context, name string) ([]byte, error) {
+	for _, p := range c {
+		v, err := p.Get(ctx, name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return v, err
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Static 固定的名字 → 值，测试或没有配置外部来源时使用
+type Static map[string]string
+
+// Get 没有这个名字或值为空时返回 ErrNotFound
+func (s Static) Get(_ context.Context, name string) ([]byte, error) {
+	v := s[name]
+	if v == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return []byte(v), nil
+}
+
+// GetOr 读取字符串形式的密钥，没有这个密钥时返回 fallback（通常是 config 里的值）
+func GetOr(ctx context.Context, p Provider, name, fallback string) (string, error) {
+	v, err := p.Get(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return fallback, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(v), nil
+}
@@ -0,0 +1,255 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-learning/clock"
+	"go-learning/httpclient"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestProviders(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("APP_JWT_SECRET", "from-env")
+	t.Setenv("DEMO_DATABASE_PASSWORD", "demo-env")
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "jwt.secret"), []byte("from-file\n"), 0o600)
+	os.WriteFile(filepath.Join(dir, "..secret"), []byte("dots"), 0o600)
+
+	tests := []struct {
+		name string
+		p    Provider
+		key  string
+		want string
+		err  error
+	}{
+		{"env", Env{}, "jwt.secret", "from-env", nil},
+		{"env prefix", Env{Prefix: "DEMO"}, "database.password", "demo-env", nil},
+		{"env missing", Env{}, "database.password", "", ErrNotFound},
+		{"file trims newline", File{Dir: dir}, "jwt.secret", "from-file", nil},
+		{"file missing", File{Dir: dir}, "database.password", "", ErrNotFound},
+		{"file traversal", File{Dir: dir}, "../etc/passwd", "", ErrInvalidName},
+		{"file dotdot", File{Dir: dir}, "..", "", ErrInvalidName},
+		{"chain first found", Chain{File{Dir: dir}, Env{}}, "jwt.secret", "from-file", nil},
+		{"chain falls through", Chain{Static{}, Env{}}, "jwt.secret", "from-env", nil},
+		{"chain none", Chain{Static{}, File{Dir: dir}}, "oauth.secret", "", ErrNotFound},
+		{"chain stops on error", Chain{File{Dir: dir}, Env{}}, "a/b", "", ErrInvalidName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.p.Get(ctx, tt.key)
+			if !errors.Is(err, tt.err) || string(v) != tt.want {
+				t.Errorf("Get(%s) = %q, %v; want %q, %v", tt.key, v, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+// fakeVault KV v2 读取接口，data 为 路径 → 字段
+func fakeVault(t *testing.T, data map[string]map[string]any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"errors": []string{"permission denied"}})
+			return
+		}
+		fields, ok := data[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": fields, "metadata": map[string]any{"version": 1}}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVault(t *testing.T) {
+	ctx := context.Background()
+	srv := fakeVault(t, map[string]map[string]any{
+		"/v1/kv/data/app/prod/jwt":      {"secret": "from-vault"},
+		"/v1/kv/data/app/prod/database": {"password": "db-pass", "port": 5432},
+	})
+	client := httpclient.New(httpclient.Config{MaxRetries: -1})
+	v, err := NewVault(VaultConfig{Addr: srv.URL + "/", Token: "s.token", Mount: "/kv/", Path: "app/prod", Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"jwt.secret": "from-vault", "database.password": "db-pass"} {
+		if got, err := v.Get(ctx, name); err != nil || string(got) != want {
+			t.Errorf("Get(%s) = %q, %v", name, got, err)
+		}
+	}
+	for name, want := range map[string]error{
+		"jwt.missing":   ErrNotFound,
+		"oauth.secret":  ErrNotFound,
+		"secret":        ErrInvalidName,
+		"jwt.":          ErrInvalidName,
+		"database.port": nil,
+	} {
+		_, err := v.Get(ctx, name)
+		if want == nil && (err == nil || !strings.Contains(err.Error(), "want string")) || want != nil && !errors.Is(err, want) {
+			t.Errorf("Get(%s) err = %v; want %v", name, err, want)
+		}
+	}
+
+	bad, _ := NewVault(VaultConfig{Addr: srv.URL, Token: "wrong", Client: client})
+	if _, err := bad.Get(ctx, "jwt.secret"); err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("bad token err = %v", err)
+	}
+	for _, cfg := range []VaultConfig{{Token: "x"}, {Addr: srv.URL}, {Addr: "vault:8200", Token: "x"}} {
+		if _, err := NewVault(cfg); err == nil {
+			t.Errorf("NewVault(%+v) accepted", cfg)
+		}
+	}
+}
+
+// flaky 可以切换值和错误的 Provider，记录调用次数
+type flaky struct {
+	mu    sync.Mutex
+	value string
+	err   error
+	calls int
+}
+
+func (f *flaky) set(value string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.value, f.err = value, err
+}
+
+func (f *flaky) Get(context.Context, string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []byte(f.value), nil
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	p := &flaky{value: "v1"}
+	c := NewCache(p, CacheConfig{TTL: time.Minute, Clock: clk, Logger: discard})
+
+	var changes []string
+	c.OnChange("jwt.secret", func(old, cur []byte) { changes = append(changes, string(old)+"→"+string(cur)) })
+
+	for range 3 {
+		if v, err := c.Get(ctx, "jwt.secret"); err != nil || string(v) != "v1" {
+			t.Fatalf("Get = %q, %v", v, err)
+		}
+	}
+	if p.calls != 1 {
+		t.Errorf("calls = %d; want 1 (cached)", p.calls)
+	}
+
+	// 过期后重新读取，值变了调用回调
+	p.set("v2", nil)
+	clk.Advance(time.Minute)
+	if v, _ := c.Get(ctx, "jwt.secret"); string(v) != "v2" || !slices.Equal(changes, []string{"v1→v2"}) {
+		t.Errorf("after TTL = %q, changes %v", v, changes)
+	}
+
+	// 来源不可用：返回旧值；值没变不调用回调
+	p.set("", errors.New("vault: connection refused"))
+	clk.Advance(time.Minute)
+	if v, err := c.Get(ctx, "jwt.secret"); err != nil || string(v) != "v2" {
+		t.Errorf("stale = %q, %v", v, err)
+	}
+	if err := c.Refresh(ctx); err == nil || !strings.Contains(err.Error(), "jwt.secret: vault: connection refused") {
+		t.Errorf("Refresh err = %v", err)
+	}
+	// 没读到过的密钥直接返回错误
+	if _, err := c.Get(ctx, "database.password"); err == nil {
+		t.Error("first read error swallowed")
+	}
+
+	p.set("v2", nil)
+	if err := c.Refresh(ctx); err != nil || len(changes) != 1 {
+		t.Errorf("Refresh = %v, changes %v", err, changes)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clk := clock.NewFake(time.Now())
+	p := &flaky{value: "v1"}
+	c := NewCache(p, CacheConfig{TTL: time.Hour, Clock: clk, Logger: discard})
+	changed := make(chan string, 1)
+	c.OnChange("jwt.secret", func(_, cur []byte) { changed <- string(cur) })
+	c.Get(ctx, "jwt.secret")
+
+	// 没有请求触发 Get，Run 也会在间隔到了之后发现新值
+	go c.Run(ctx, time.Minute)
+	clk.BlockUntil(1)
+	p.set("v2", nil)
+	clk.Advance(time.Minute)
+	select {
+	case v := <-changed:
+		if v != "v2" {
+			t.Errorf("changed to %q", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not refresh")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	p := &flaky{value: "old-key"}
+	c := NewCache(p, CacheConfig{Clock: clk, Logger: discard})
+
+	keys, err := c.Watch(ctx, "jwt.secret", 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.set("new-key", nil)
+	c.Refresh(ctx)
+	if string(keys.Current()) != "new-key" {
+		t.Errorf("Current = %s", keys.Current())
+	}
+	// grace 内旧密钥仍可验证，过了 grace 作废
+	got := func() []string {
+		var out []string
+		for _, k := range keys.Verification() {
+			out = append(out, string(k))
+		}
+		return out
+	}
+	if v := got(); !slices.Equal(v, []string{"new-key", "old-key"}) {
+		t.Errorf("Verification = %v", v)
+	}
+	clk.Advance(2 * time.Hour)
+	if v := got(); !slices.Equal(v, []string{"new-key"}) {
+		t.Errorf("after grace = %v", v)
+	}
+
+	if _, err := NewCache(Static{}, CacheConfig{}).Watch(ctx, "jwt.secret", time.Hour); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing secret: %v", err)
+	}
+	if fixed := Fixed([]byte("k")); string(fixed.Current()) != "k" || len(fixed.Verification()) != 1 {
+		t.Error("Fixed")
+	}
+}
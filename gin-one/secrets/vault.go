@@ -0,0 +1,115 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-learning/httpclient"
+)
+
+// VaultConfig HashiCorp Vault KV v2 的连接参数
+type VaultConfig struct {
+	// Addr Vault 地址，如 https://vault.internal:8200
+	Addr string
+	// Token 访问令牌，只需要 read 权限；用环境变量注入
+	Token string
+	// Mount KV 引擎的挂载路径，默认 secret
+	Mount string
+	// Path 本应用的路径前缀，如 app/prod；为空时名字直接对应 <Mount>/data/<名字前半部分>
+	Path string
+	// Namespace Vault 企业版的命名空间，放在 X-Vault-Namespace 请求头
+	Namespace string
+	// Client 默认超时 5 秒、失败重试和熔断的 httpclient
+	Client *httpclient.Client
+}
+
+// Vault 从 KV v2 读取密钥
+//
+// 名字按最后一个 "." 拆成路径和字段：jwt.secret → GET /v1/<Mount>/data/<Path>/jwt 的 data.secret，
+// 一个路径下可以放同一组件的多个字段（database 下放 user、password）。
+//
+//	vault kv put secret/app/prod/jwt secret=$(openssl rand -hex 32)
+type Vault struct {
+	cfg VaultConfig
+}
+
+// NewVault 创建 Vault Provider，Addr 和 Token 必填
+func NewVault(cfg VaultConfig) (*Vault, error) {
+	if cfg.Addr == "" || cfg.Token == "" {
+		return nil, errors.New("secrets: vault addr and token are required")
+	}
+	if u, err := url.Parse(cfg.Addr); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("secrets: vault addr %q must be http(s)://host:port", cfg.Addr)
+	}
+	cfg.Addr = strings.TrimRight(cfg.Addr, "/")
+	if cfg.Mount == "" {
+		cfg.Mount = "secret"
+	}
+	cfg.Mount = strings.Trim(cfg.Mount, "/")
+	cfg.Path = strings.Trim(cfg.Path, "/")
+	if cfg.Client == nil {
+		cfg.Client = httpclient.New(httpclient.Config{Timeout: 5 * time.Second})
+	}
+	return &Vault{cfg: cfg}, nil
+}
+
+// vaultResponse KV v2 读取接口的响应，只取用到的部分
+type vaultResponse struct {
+	Data struct {
+		Data map[string]any `json:"data"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+// Get 路径不存在（404）或字段不存在时返回 ErrNotFound，其他错误原样返回
+func (v *Vault) Get(ctx context.Context, name string) ([]byte, error) {
+	i := strings.LastIndex(name, ".")
+	if i <= 0 || i == len(name)-1 {
+		return nil, fmt.Errorf("%w: %q, want <path>.<field>", ErrInvalidName, name)
+	}
+	path, field := strings.ReplaceAll(name[:i], ".", "/"), name[i+1:]
+	if v.cfg.Path != "" {
+		path = v.cfg.Path + "/" + path
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.cfg.Addr+"/v1/"+v.cfg.Mount+"/data/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.cfg.Token)
+	if v.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.Namespace)
+	}
+	resp, err := v.cfg.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: vault %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	var body vaultResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("secrets: vault %s: %w", path, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: vault %s", ErrNotFound, path)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("secrets: vault %s: status %d %s", path, resp.StatusCode, strings.Join(body.Errors, "; "))
+	}
+	raw, ok := body.Data.Data[field]
+	if !ok {
+		return nil, fmt.Errorf("%w: vault %s field %s", ErrNotFound, path, field)
+	}
+	s, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("secrets: vault %s field %s is %T, want string", path, field, raw)
+	}
+	return []byte(s), nil
+}
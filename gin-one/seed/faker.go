@@ -0,0 +1,91 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Faker
+// ============================================================================
+//
+// 够用的假数据生成器：性能测试关心的是数量和分布，不是数据像不像真的，
+// 不为此引入 gofakeit 之类的依赖。种子相同时生成的序列相同，测试可以断言具体值。
+//
+// 需要唯一的字段（用户名、邮箱）用行号 i 拼出来，不靠随机数去重：
+//
+//	func fakeUser(f *seed.Faker, i int) map[string]any {
+//		return map[string]any{
+//			"username": f.Username(i),
+//			"email":    f.Email(i),
+//			"password": hash, // 预先算好一个哈希，1 万次 argon2id 要跑好几分钟
+//			"age":      f.IntRange(18, 80),
+//		}
+//	}
+
+var (
+	firstNames = []string{"alice", "bob", "carol", "dave", "erin", "frank", "grace", "heidi", "ivan", "judy", "mallory", "oscar", "peggy", "trent", "victor", "wendy"}
+	words      = []string{"gin", "golang", "router", "middleware", "context", "handler", "database", "cache", "queue", "request", "response", "server", "client", "token", "session", "schema", "index", "query", "batch", "stream"}
+	domains    = []string{"example.com", "example.org", "example.net"}
+)
+
+// Faker 确定性的假数据生成器，不是并发安全的
+type Faker struct {
+	r *rand.Rand
+}
+
+// NewFaker 用固定种子创建生成器
+func NewFaker(seed uint64) *Faker {
+	return &Faker{r: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// IntRange [lo, hi] 之间的随机整数
+func (f *Faker) IntRange(lo, hi int) int {
+	return lo + f.r.IntN(hi-lo+1)
+}
+
+// Pick 随机取一个元素
+func Pick[T any](f *Faker, items []T) T {
+	return items[f.r.IntN(len(items))]
+}
+
+// Bool 随机布尔值，为 true 的概率是 p
+func (f *Faker) Bool(p float64) bool {
+	return f.r.Float64() < p
+}
+
+// Username 第 i 个用户名，如 carol_42，i 不同时一定不同
+func (f *Faker) Username(i int) string {
+	return fmt.Sprintf("%s_%d", Pick(f, firstNames), i)
+}
+
+// Email 第 i 个邮箱，i 不同时一定不同
+func (f *Faker) Email(i int) string {
+	return fmt.Sprintf("%s%d@%s", Pick(f, firstNames), i, Pick(f, domains))
+}
+
+// Sentence n 个单词的句子，首字母大写
+func (f *Faker) Sentence(n int) string {
+	ws := make([]string, n)
+	for i := range ws {
+		ws[i] = Pick(f, words)
+	}
+	s := strings.Join(ws, " ")
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
+
+// Paragraph n 个句子的段落，每句 5~12 个单词
+func (f *Faker) Paragraph(n int) string {
+	ss := make([]string, n)
+	for i := range ss {
+		ss[i] = f.Sentence(f.IntRange(5, 12))
+	}
+	return strings.Join(ss, " ")
+}
+
+// Time base 之前 within 以内的随机时间，用于分散 created_at
+func (f *Faker) Time(base time.Time, within time.Duration) time.Time {
+	return base.Add(-time.Duration(f.r.Int64N(int64(within))))
+}
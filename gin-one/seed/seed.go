@@ -0,0 +1,373 @@
+// ============================================================================
+// Package seed 测试 / 开发数据：YAML、JSON fixture 导入和批量生成假数据
+// ============================================================================
+//
+// 【fixture 格式】
+//
+// 顶层键是表名，值是行的列表，列名和数据库列名（或 Go 字段名）一致：
+//
+//	users:
+//	  - username: alice          # 没写 id 时按出现顺序编号：1、2、3...
+//	    email: alice@example.com
+//	    password: secret123      # Table.Prepare 里换成哈希
+//	posts:
+//	  - title: Hello
+//	    user_id: 1               # 引用上面 alice 的 id，每次导入都一样
+//
+// JSON 是 YAML 的子集，同样的结构写成 .json 文件也可以。
+//
+// 【依赖顺序】
+//
+// Table.DependsOn 声明外键依赖，Load 按拓扑顺序写入（users 在 posts 之前），
+// 和 fixture 文件里键的顺序、文件的顺序无关；全部在一个事务里，任何一行失败都不留半套数据。
+//
+// 【固定 ID】
+//
+// 测试里直接写 GET /users/1，不用先查 alice 的 id。已经存在的 id 整行覆盖，
+// 重复执行 seed 不会报主键冲突。PostgreSQL 写入指定 id 后自增序列不会跟着走，
+// Load 结束时把序列调到最大 id，之后正常插入的行不会撞上 fixture 的 id。
+//
+// 【批量生成】
+//
+// 性能测试要的是数量，不是具体内容：Table.Generate 用 Faker 生成一行，
+// Generate(ctx, "users", 10000) 每 1000 行一批插入。Faker 的种子固定，空库上每次生成的数据相同。
+//
+// 【用法】
+//
+//	s := seed.New(db,
+//	    seed.Table{Name: "users", Model: &model.User{}, Prepare: hashPassword, Generate: fakeUser},
+//	    seed.Table{Name: "posts", Model: &model.Post{}, DependsOn: []string{"users"}},
+//	)
+//	err := s.LoadFiles(ctx, "fixtures/")                 // 目录下所有 .yaml / .yml / .json
+//	err = s.Run(ctx, []string{"fixtures/", "users=10000"}) // cmd 的 seed 子命令参数
+//
+// ============================================================================
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 错误定义
+var (
+	ErrUnknownTable = errors.New("seed: unknown table")
+	ErrNoGenerator  = errors.New("seed: table has no generator")
+	ErrInvalid      = errors.New("seed: invalid fixture")
+)
+
+// batchSize 批量生成时每批插入的行数
+const batchSize = 1000
+
+// Fixtures 表名 → 行，一行是列名 → 值
+type Fixtures map[string][]map[string]any
+
+// Table 一张可以导入数据的表
+type Table struct {
+	Name string
+
+	// Model 决定表名和列（如 &model.User{}），CreatedAt 等自动填充；
+	// 为 nil 时直接写 Name 表，用于多对多中间表（post_tags）
+	Model any
+
+	// DependsOn 外键引用的表，这些表先写入
+	DependsOn []string
+
+	// Prepare fixture 的每一行写入前调用，如把明文密码换成哈希；批量生成的行不调用
+	Prepare func(row map[string]any) error
+
+	// Generate 批量生成第 i 行（从 0 开始），没有时这张表不能批量生成
+	Generate func(f *Faker, i int) map[string]any
+}
+
+// Seeder 按依赖顺序导入 fixture、批量生成数据
+type Seeder struct {
+	db     *gorm.DB
+	tables map[string]*Table
+	order  []string // 拓扑顺序，依赖在前
+}
+
+// New 注册表并确定写入顺序；依赖了没注册的表或依赖有环时 panic（属于代码错误）
+func New(db *gorm.DB, tables ...Table) *Seeder {
+	s := &Seeder{db: db, tables: make(map[string]*Table, len(tables))}
+	for i := range tables {
+		s.tables[tables[i].Name] = &tables[i]
+	}
+	// 深度优先拓扑排序，同一层按注册顺序
+	state := map[string]int{} // 0 未访问，1 访问中，2 已完成
+	var visit func(name string, path []string)
+	visit = func(name string, path []string) {
+		switch state[name] {
+		case 1:
+			panic(fmt.Sprintf("seed: dependency cycle %s", strings.Join(append(path, name), " -> ")))
+		case 2:
+			return
+		}
+		t, ok := s.tables[name]
+		if !ok {
+			panic(fmt.Sprintf("seed: %s depends on unregistered table %s", path[len(path)-1], name))
+		}
+		state[name] = 1
+		for _, dep := range t.DependsOn {
+			visit(dep, append(path, name))
+		}
+		state[name] = 2
+		s.order = append(s.order, name)
+	}
+	for _, t := range tables {
+		visit(t.Name, nil)
+	}
+	return s
+}
+
+// ============================================================================
+// fixture
+// ============================================================================
+
+// Parse 解析 YAML 或 JSON 格式的 fixture
+func Parse(data []byte) (Fixtures, error) {
+	var f Fixtures
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return f, nil
+}
+
+// ReadFiles 读取并合并 fixture 文件，目录读取其中的 .yaml / .yml / .json（按文件名排序）
+// 同一张表出现在多个文件里时，按文件顺序拼接
+func ReadFiles(paths ...string) (Fixtures, error) {
+	all := Fixtures{}
+	for _, p := range paths {
+		files := []string{p}
+		if info, err := os.Stat(p); err != nil {
+			return nil, fmt.Errorf("seed: %w", err)
+		} else if info.IsDir() {
+			entries, err := os.ReadDir(p)
+			if err != nil {
+				return nil, fmt.Errorf("seed: %w", err)
+			}
+			files = files[:0]
+			for _, e := range entries {
+				switch filepath.Ext(e.Name()) {
+				case ".yaml", ".yml", ".json":
+					files = append(files, filepath.Join(p, e.Name()))
+				}
+			}
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("seed: %w", err)
+			}
+			f, err := Parse(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			for name, rows := range f {
+				all[name] = append(all[name], rows...)
+			}
+		}
+	}
+	return all, nil
+}
+
+// LoadFiles 读取 fixture 文件并写入数据库，见 ReadFiles 和 Load
+func (s *Seeder) LoadFiles(ctx context.Context, paths ...string) error {
+	f, err := ReadFiles(paths...)
+	if err != nil {
+		return err
+	}
+	return s.Load(ctx, f)
+}
+
+// Load 在一个事务里按依赖顺序写入 fixture，没有 id 的行按表内顺序编号为 1、2、3...
+func (s *Seeder) Load(ctx context.Context, f Fixtures) error {
+	for name := range f {
+		if _, ok := s.tables[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownTable, name)
+		}
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, name := range s.order {
+			rows := f[name]
+			if len(rows) == 0 {
+				continue
+			}
+			t := s.tables[name]
+			for i, row := range rows {
+				if t.Model != nil {
+					if _, ok := row["id"]; !ok {
+						row["id"] = i + 1
+					}
+				}
+				if t.Prepare != nil {
+					if err := t.Prepare(row); err != nil {
+						return fmt.Errorf("%w: %s[%d]: %v", ErrInvalid, name, i, err)
+					}
+				}
+			}
+			// 有模型的表按主键整行覆盖；中间表没有别的列，已经存在就跳过
+			conflict := clause.OnConflict{UpdateAll: true}
+			if t.Model == nil {
+				conflict = clause.OnConflict{DoNothing: true}
+			}
+			if err := insert(tx, t, rows, conflict); err != nil {
+				return fmt.Errorf("seed: load %s: %w", name, err)
+			}
+			if t.Model != nil && tx.Dialector.Name() == "postgres" {
+				if err := resetSequence(tx, t); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// insert 有 Model 时转换成 []*Model 再写（表名、自动时间戳、默认值都按模型），否则直接写 Name 表
+func insert(db *gorm.DB, t *Table, rows []map[string]any, clauses ...clause.Expression) error {
+	if t.Model == nil {
+		return db.Table(t.Name).Clauses(clauses...).Create(rows).Error
+	}
+	models, err := decode(db, t, rows)
+	if err != nil {
+		return err
+	}
+	return db.Clauses(clauses...).Create(models).Error
+}
+
+// decode 把行转换成 []*Model：列名按 GORM 的映射找字段，类型由 field.Set 转换（int → uint 等）
+// 直接用 map 创建时 GORM 无法把数据库生成的 id 写回 map，而且拼错的列名会被悄悄忽略
+func decode(tx *gorm.DB, t *Table, rows []map[string]any) (any, error) {
+	sch, err := schemaOf(tx, t)
+	if err != nil {
+		return nil, err
+	}
+	models := reflect.MakeSlice(reflect.SliceOf(reflect.PointerTo(sch.ModelType)), len(rows), len(rows))
+	for i, row := range rows {
+		v := reflect.New(sch.ModelType)
+		for col, value := range row {
+			field := sch.LookUpField(col)
+			if field == nil {
+				return nil, fmt.Errorf("%w: %s[%d]: unknown column %s", ErrInvalid, t.Name, i, col)
+			}
+			if err := field.Set(tx.Statement.Context, v.Elem(), value); err != nil {
+				return nil, fmt.Errorf("%w: %s[%d].%s: %v", ErrInvalid, t.Name, i, col, err)
+			}
+		}
+		models.Index(i).Set(v)
+	}
+	return models.Interface(), nil
+}
+
+func schemaOf(tx *gorm.DB, t *Table) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(t.Model); err != nil {
+		return nil, fmt.Errorf("seed: %s: %w", t.Name, err)
+	}
+	return stmt.Schema, nil
+}
+
+// resetSequence 把 PostgreSQL 的自增序列调到当前最大 id
+func resetSequence(tx *gorm.DB, t *Table) error {
+	sch, err := schemaOf(tx, t)
+	if err != nil {
+		return err
+	}
+	err = tx.Exec("SELECT setval(pg_get_serial_sequence(?, 'id'), (SELECT COALESCE(MAX(id), 1) FROM "+
+		tx.Statement.Quote(sch.Table)+"))", sch.Table).Error
+	if err != nil {
+		return fmt.Errorf("seed: reset %s id sequence: %w", sch.Table, err)
+	}
+	return nil
+}
+
+// ============================================================================
+// 批量生成
+// ============================================================================
+
+// Generate 用 Table.Generate 再生成 n 行，每 batchSize 行一批插入；id 由数据库分配
+//
+// 传给 Table.Generate 的 i 从表里已有的行数开始，用 i 拼出的用户名、邮箱在重复执行时也不会冲突。
+// 每一批单独提交，中途失败时已提交的批次保留（性能测试数据不需要全有或全无）。
+func (s *Seeder) Generate(ctx context.Context, name string, n int) error {
+	t, ok := s.tables[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTable, name)
+	}
+	if t.Generate == nil {
+		return fmt.Errorf("%w: %s", ErrNoGenerator, name)
+	}
+	db := s.db.WithContext(ctx)
+	var offset int64
+	q := db.Table(t.Name)
+	if t.Model != nil {
+		q = db.Model(t.Model).Unscoped()
+	}
+	if err := q.Count(&offset).Error; err != nil {
+		return fmt.Errorf("seed: count %s: %w", name, err)
+	}
+	f := NewFaker(uint64(offset))
+	for start := 0; start < n; start += batchSize {
+		rows := make([]map[string]any, min(batchSize, n-start))
+		for i := range rows {
+			rows[i] = t.Generate(f, int(offset)+start+i)
+		}
+		if err := insert(db, t, rows); err != nil {
+			return fmt.Errorf("seed: generate %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Run 执行 seed 子命令的参数：文件或目录按 LoadFiles 导入，"表名=数量" 按 Generate 生成
+//
+//	app seed fixtures/ users=10000 posts=50000
+//
+// 先导入全部 fixture，再按依赖顺序批量生成，和参数的顺序无关。
+func (s *Seeder) Run(ctx context.Context, args []string) error {
+	var files []string
+	counts := map[string]int{}
+	for _, arg := range args {
+		name, n, ok := strings.Cut(arg, "=")
+		if !ok {
+			files = append(files, arg)
+			continue
+		}
+		count, err := strconv.Atoi(n)
+		if err != nil || count < 0 {
+			return fmt.Errorf("%w: %s: count must be a non-negative integer", ErrInvalid, arg)
+		}
+		counts[name] = count
+	}
+	for name := range counts {
+		if !slices.Contains(s.order, name) {
+			return fmt.Errorf("%w: %s", ErrUnknownTable, name)
+		}
+	}
+	if len(files) > 0 {
+		if err := s.LoadFiles(ctx, files...); err != nil {
+			return err
+		}
+	}
+	for _, name := range s.order {
+		if n, ok := counts[name]; ok {
+			if err := s.Generate(ctx, name, n); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
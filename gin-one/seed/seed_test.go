@@ -0,0 +1,178 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+	"go-one/model"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.Open(t, &model.User{}, &model.Post{}, &model.Tag{})
+}
+
+func newTestSeeder(db *gorm.DB) *Seeder {
+	// 故意倒着注册，顺序由 DependsOn 决定
+	return New(db,
+		Table{Name: "post_tags", DependsOn: []string{"posts", "tags"}},
+		Table{Name: "posts", Model: &model.Post{}, DependsOn: []string{"users"}},
+		Table{Name: "tags", Model: &model.Tag{}},
+		Table{
+			Name:  "users",
+			Model: &model.User{},
+			Prepare: func(row map[string]any) error {
+				pw, ok := row["password"].(string)
+				if !ok {
+					return errors.New("password is required")
+				}
+				row["password"] = "hashed:" + pw
+				return nil
+			},
+			Generate: func(f *Faker, i int) map[string]any {
+				return map[string]any{"username": f.Username(i), "email": f.Email(i), "password": "hashed", "age": f.IntRange(18, 80)}
+			},
+		},
+	)
+}
+
+const fixtureYAML = `
+posts:
+  - title: Hello
+    user_id: 2
+  - id: 10
+    title: Second
+    user_id: 1
+post_tags:
+  - {post_id: 1, tag_id: 1}
+users:
+  - username: alice
+    email: alice@example.com
+    password: secret
+  - username: bob
+    email: bob@example.com
+    password: secret
+`
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(fixtureYAML), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"tags": [{"name": "go"}]}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a fixture"), 0o644)
+	s := newTestSeeder(db)
+
+	// 重复导入同一份 fixture，按 id 覆盖，不报主键冲突
+	for range 2 {
+		if err := s.LoadFiles(ctx, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var users []model.User
+	db.Order("id").Find(&users)
+	if len(users) != 2 || users[0].ID != 1 || users[0].Username != "alice" || users[1].Password != "hashed:secret" {
+		t.Fatalf("users = %+v", users)
+	}
+	var post model.Post
+	if err := db.Preload("User").Preload("Tags").First(&post, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if post.User.Username != "bob" || len(post.Tags) != 1 || post.Tags[0].Name != "go" || post.CreatedAt.IsZero() {
+		t.Errorf("post 1 = %+v", post)
+	}
+	var ids []uint
+	db.Model(&model.Post{}).Order("id").Pluck("id", &ids)
+	if len(ids) != 2 || ids[1] != 10 {
+		t.Errorf("post ids = %v", ids)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	s := newTestSeeder(db)
+
+	if err := s.Load(ctx, Fixtures{"comments": {{"body": "x"}}}); !errors.Is(err, ErrUnknownTable) {
+		t.Errorf("unknown table err = %v", err)
+	}
+	// Prepare 失败时整个事务回滚，前面的表也不留数据
+	f, err := Parse([]byte("tags: [{name: go}]\nusers: [{username: carol}]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load(ctx, f); !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), "users[0]") {
+		t.Errorf("prepare err = %v", err)
+	}
+	var n int64
+	if db.Model(&model.Tag{}).Count(&n); n != 0 {
+		t.Errorf("tags after rollback = %d", n)
+	}
+	if _, err := Parse([]byte("users: 1")); !errors.Is(err, ErrInvalid) {
+		t.Errorf("parse err = %v", err)
+	}
+}
+
+func TestNewPanics(t *testing.T) {
+	for name, tables := range map[string][]Table{
+		"cycle":        {{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}},
+		"unregistered": {{Name: "posts", DependsOn: []string{"users"}}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: no panic", name)
+				}
+			}()
+			New(nil, tables...)
+		}()
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	s := newTestSeeder(db)
+
+	if err := s.Run(ctx, []string{"users=2500"}); err != nil {
+		t.Fatal(err)
+	}
+	// 再生成一批：行号接着已有的行数，用户名不冲突
+	if err := s.Generate(ctx, "users", 10); err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	if db.Model(&model.User{}).Count(&n); n != 2510 {
+		t.Errorf("users = %d", n)
+	}
+
+	// 种子固定，另一个空库生成的数据相同
+	other := newTestDB(t)
+	if err := newTestSeeder(other).Generate(ctx, "users", 1); err != nil {
+		t.Fatal(err)
+	}
+	var a, b model.User
+	db.First(&a)
+	other.First(&b)
+	if a.Username != b.Username || a.Age != b.Age || !strings.HasSuffix(a.Username, "_0") {
+		t.Errorf("not deterministic: %s/%d vs %s/%d", a.Username, a.Age, b.Username, b.Age)
+	}
+
+	if err := s.Run(ctx, []string{"comments=1"}); !errors.Is(err, ErrUnknownTable) {
+		t.Errorf("unknown table err = %v", err)
+	}
+	if err := s.Run(ctx, []string{"posts=1"}); !errors.Is(err, ErrNoGenerator) {
+		t.Errorf("no generator err = %v", err)
+	}
+	if err := s.Run(ctx, []string{"users=-1"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("negative count err = %v", err)
+	}
+}
@@ -0,0 +1,374 @@
+// ============================================================================
+// Package serializer 序列化分组：同一个结构体按调用方身份输出不同字段
+// ============================================================================
+//
+// 【问题】
+//
+// 用户资料给管理员看要有邮箱、手机号、最后登录 IP，给本人看要有邮箱和手机号，
+// 给其他人看只有昵称和头像。每种身份写一个 DTO，字段一改三个地方都要改。
+//
+// 【view 标签】
+//
+//	type User struct {
+//	    ID      uint   `json:"id"`
+//	    Name    string `json:"name"`                         // 所有人
+//	    Email   string `json:"email" view:"admin,self"`      // 管理员和本人
+//	    LoginIP string `json:"login_ip" view:"admin"`        // 只有管理员
+//	}
+//
+//	func (u User) OwnerID() uint { return u.ID }
+//
+// | 调用方                   | 看到的字段                   |
+// |--------------------------|------------------------------|
+// | 未登录 / 其他用户        | id、name                     |
+// | 本人（OwnerID 等于自己） | id、name、email              |
+// | admin                    | id、name、email、login_ip    |
+//
+// view 里除了 self 都是角色名，和 policy.Subject.Role 比较。
+//
+// 【self 怎么判断】
+//
+// 结构体实现 Owner 接口时，OwnerID() 等于调用方 UserID 即为 self；
+// 没实现 Owner 的嵌套结构体沿用外层的判断（User.Profile.Phone 跟着 User 走），
+// 切片里每个元素分别判断：用户列表里只有自己那一行带 email。
+//
+// 【输出】
+//
+// View 把值转换成 Object / []any / map[string]any 组成的树，字段顺序和声明顺序一致，
+// 可以直接交给 c.JSON、response.Success 或 render。字段名、omitempty、"-"、
+// 匿名结构体字段提升遵循 json 标签；实现了 json.Marshaler / encoding.TextMarshaler 的类型
+// （time.Time、json.RawMessage、gorm.DeletedAt）原样保留，由编码器处理。
+//
+//	serializer.Success(c, user) // 按 JWT 中间件写入的 user_id / role 过滤后套上统一信封
+//
+// ============================================================================
+package serializer
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/policy"
+	"go-one/response"
+)
+
+var (
+	// ErrTooDeep 嵌套超过 MaxDepth，通常是循环引用
+	ErrTooDeep = errors.New("serializer: value nested too deep")
+)
+
+// MaxDepth View 允许的最大嵌套层数
+const MaxDepth = 64
+
+// Self view 标签中表示资源所有者的特殊值
+const Self = "self"
+
+// Owner 资源所有者，用于判断 view:"self"
+type Owner interface {
+	OwnerID() uint
+}
+
+// Field Object 中的一个字段
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Object 保持字段顺序的 JSON 对象
+type Object []Field
+
+// Get 按字段名取值，不存在时 ok 为 false
+func (o Object) Get(key string) (any, bool) {
+	for _, f := range o {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// MarshalJSON 按字段顺序编码
+func (o Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(f.Key)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// View 按调用方身份过滤 v 中带 view 标签的字段
+func View(v any, s policy.Subject) (any, error) {
+	w := walker{subject: s}
+	return w.value(reflect.ValueOf(v), false, 0)
+}
+
+// Success 过滤后用 response.Success 输出，失败时返回 500
+func Success(c *gin.Context, data any) {
+	out, err := View(data, policy.SubjectFromContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		response.Error(c, http.StatusInternalServerError, "serialize_failed", "响应序列化失败")
+		return
+	}
+	response.Success(c, out)
+}
+
+// ============================================================================
+// 反射遍历
+// ============================================================================
+
+var (
+	marshalerType     = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+type walker struct {
+	subject policy.Subject
+}
+
+func (w *walker) value(v reflect.Value, self bool, depth int) (any, error) {
+	if depth > MaxDepth {
+		return nil, ErrTooDeep
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+	}
+	t := v.Type()
+	if t.Kind() != reflect.Interface && t.Kind() != reflect.Pointer && isMarshaler(t) {
+		if v.CanAddr() && !t.Implements(marshalerType) && !t.Implements(textMarshalerType) {
+			return v.Addr().Interface(), nil // 指针接收者的 MarshalJSON
+		}
+		return v.Interface(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return w.value(v.Elem(), self, depth+1)
+	case reflect.Struct:
+		return w.structValue(v, self, depth)
+	case reflect.Map:
+		if v.IsNil() || t.Key().Kind() != reflect.String {
+			return v.Interface(), nil // 非字符串键的 map 交给编码器
+		}
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			val, err := w.value(iter.Value(), self, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out[iter.Key().String()] = val
+		}
+		return out, nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return []any(nil), nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), nil // []byte 编码为 base64
+		}
+		fallthrough
+	case reflect.Array:
+		out := make([]any, v.Len())
+		for i := range v.Len() {
+			val, err := w.value(v.Index(i), self, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = val
+		}
+		return out, nil
+	default:
+		return v.Interface(), nil
+	}
+}
+
+func (w *walker) structValue(v reflect.Value, self bool, depth int) (any, error) {
+	if owner, ok := asOwner(v); ok {
+		self = w.subject.Authenticated() && owner.OwnerID() == w.subject.UserID
+	}
+	fields := cachedFields(v.Type())
+	out := make(Object, 0, len(fields))
+	for _, f := range fields {
+		if !w.visible(f.views, self) {
+			continue
+		}
+		fv, err := v.FieldByIndexErr(f.index)
+		if err != nil {
+			continue // 经过 nil 的匿名指针字段，encoding/json 同样跳过
+		}
+		if f.omitEmpty && isEmpty(fv) {
+			continue
+		}
+		val, err := w.value(fv, self, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Field{Key: f.name, Value: val})
+	}
+	return out, nil
+}
+
+func (w *walker) visible(views []string, self bool) bool {
+	if len(views) == 0 {
+		return true
+	}
+	for _, view := range views {
+		if view == Self && self || view != Self && view == w.subject.Role {
+			return true
+		}
+	}
+	return false
+}
+
+// asOwner 值接收者和指针接收者的 OwnerID 都认
+func asOwner(v reflect.Value) (Owner, bool) {
+	if v.CanInterface() {
+		if o, ok := v.Interface().(Owner); ok {
+			return o, true
+		}
+	}
+	if v.CanAddr() && v.Addr().CanInterface() {
+		if o, ok := v.Addr().Interface().(Owner); ok {
+			return o, true
+		}
+	}
+	return nil, false
+}
+
+// isMarshaler 类型自己实现了 JSON / 文本编码，不展开字段
+func isMarshaler(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return t.Implements(marshalerType) || pt.Implements(marshalerType) ||
+		t.Implements(textMarshalerType) || pt.Implements(textMarshalerType)
+}
+
+// isEmpty 与 encoding/json 的 omitempty 规则一致
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// ============================================================================
+// 字段解析，按类型缓存
+// ============================================================================
+
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	views     []string
+}
+
+var fieldCache sync.Map // reflect.Type → []field
+
+func cachedFields(t reflect.Type) []field {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]field)
+	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t, nil, nil))
+	return f.([]field)
+}
+
+// typeFields 展开匿名结构体字段；同名字段层级浅的优先，同一层级先出现的优先
+func typeFields(t reflect.Type, index []int, visited []reflect.Type) []field {
+	if slices.Contains(visited, t) {
+		return nil
+	}
+	visited = append(visited, t)
+
+	var fields []field
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		idx := append(index[:len(index):len(index)], i)
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct && !isMarshaler(ft) {
+			fields = append(fields, typeFields(ft, idx, visited)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		f := field{name: name, index: idx, omitEmpty: hasOption(opts, "omitempty")}
+		if views := sf.Tag.Get("view"); views != "" {
+			for _, view := range strings.Split(views, ",") {
+				f.views = append(f.views, strings.TrimSpace(view))
+			}
+		}
+		fields = append(fields, f)
+	}
+
+	// 稳定排序后按名字去重：层级浅的在前
+	slices.SortStableFunc(fields, func(a, b field) int { return len(a.index) - len(b.index) })
+	seen := make(map[string]bool, len(fields))
+	out := fields[:0]
+	for _, f := range fields {
+		if !seen[f.name] {
+			seen[f.name] = true
+			out = append(out, f)
+		}
+	}
+	// 恢复声明顺序
+	slices.SortStableFunc(out, func(a, b field) int { return slices.Compare(a.index, b.index) })
+	return out
+}
+
+func hasOption(opts, want string) bool {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if o == want {
+			return true
+		}
+	}
+	return false
+}
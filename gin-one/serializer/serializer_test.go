@@ -0,0 +1,147 @@
+package serializer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/policy"
+)
+
+type Profile struct {
+	Bio   string `json:"bio"`
+	Phone string `json:"phone,omitempty" view:"admin,self"`
+}
+
+type User struct {
+	ID       uint      `json:"id"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email" view:"admin,self"`
+	LoginIP  string    `json:"login_ip" view:"admin"`
+	Password string    `json:"-"`
+	Profile  *Profile  `json:"profile,omitempty"`
+	Posts    []Post    `json:"posts,omitempty"`
+	Joined   time.Time `json:"joined"`
+}
+
+func (u User) OwnerID() uint { return u.ID }
+
+// Post 指针接收者实现 Owner
+type Post struct {
+	AuthorID uint   `json:"author_id"`
+	Title    string `json:"title"`
+	Draft    string `json:"draft,omitempty" view:"self"`
+}
+
+func (p *Post) OwnerID() uint { return p.AuthorID }
+
+func marshal(t *testing.T, v any, s policy.Subject) string {
+	t.Helper()
+	out, err := View(v, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestView(t *testing.T) {
+	joined := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	alice := User{
+		ID: 1, Name: "alice", Email: "a@example.com", LoginIP: "10.0.0.1", Password: "x", Joined: joined,
+		Profile: &Profile{Bio: "hi", Phone: "138"},
+		Posts:   []Post{{AuthorID: 1, Title: "mine", Draft: "wip"}, {AuthorID: 2, Title: "co-written", Draft: "theirs"}},
+	}
+
+	tests := []struct {
+		name    string
+		subject policy.Subject
+		want    string
+	}{
+		{"anonymous", policy.Subject{},
+			`{"id":1,"name":"alice","profile":{"bio":"hi"},"posts":[{"author_id":1,"title":"mine"},{"author_id":2,"title":"co-written"}],"joined":"2026-01-02T00:00:00Z"}`},
+		{"other user", policy.Subject{UserID: 2, Role: "user"},
+			`{"id":1,"name":"alice","profile":{"bio":"hi"},"posts":[{"author_id":1,"title":"mine"},{"author_id":2,"title":"co-written","draft":"theirs"}],"joined":"2026-01-02T00:00:00Z"}`},
+		{"self", policy.Subject{UserID: 1, Role: "user"},
+			`{"id":1,"name":"alice","email":"a@example.com","profile":{"bio":"hi","phone":"138"},"posts":[{"author_id":1,"title":"mine","draft":"wip"},{"author_id":2,"title":"co-written"}],"joined":"2026-01-02T00:00:00Z"}`},
+		{"admin", policy.Subject{UserID: 9, Role: "admin"},
+			`{"id":1,"name":"alice","email":"a@example.com","login_ip":"10.0.0.1","profile":{"bio":"hi","phone":"138"},"posts":[{"author_id":1,"title":"mine"},{"author_id":2,"title":"co-written"}],"joined":"2026-01-02T00:00:00Z"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := marshal(t, alice, tt.subject); got != tt.want {
+				t.Errorf("\n got %s\nwant %s", got, tt.want)
+			}
+			// 指针和值结果一致
+			if got := marshal(t, &alice, tt.subject); got != tt.want {
+				t.Errorf("pointer\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestViewCollections(t *testing.T) {
+	users := []User{{ID: 1, Name: "alice", Email: "a@example.com"}, {ID: 2, Name: "bob", Email: "b@example.com"}}
+	self := policy.Subject{UserID: 2}
+
+	// 列表里只有自己那一行带 email
+	got := marshal(t, map[string]any{"items": users, "total": 2}, self)
+	want := `{"items":[{"id":1,"name":"alice","joined":"0001-01-01T00:00:00Z"},{"id":2,"name":"bob","email":"b@example.com","joined":"0001-01-01T00:00:00Z"}],"total":2}`
+	if got != want {
+		t.Errorf("\n got %s\nwant %s", got, want)
+	}
+
+	// 非字符串键的 map、[]byte、nil 切片原样交给编码器
+	got = marshal(t, []any{map[int]string{1: "a"}, []byte("hi"), []User(nil), json.RawMessage(`{"k":1}`)}, self)
+	if want := `[{"1":"a"},"aGk=",null,{"k":1}]`; got != want {
+		t.Errorf("\n got %s\nwant %s", got, want)
+	}
+}
+
+type node struct {
+	Next *node `json:"next"`
+}
+
+func TestViewTooDeep(t *testing.T) {
+	n := &node{}
+	n.Next = n
+	if _, err := View(n, policy.Subject{}); !errors.Is(err, ErrTooDeep) {
+		t.Errorf("err = %v, want ErrTooDeep", err)
+	}
+}
+
+func TestObject(t *testing.T) {
+	o := Object{{Key: "z", Value: 1}, {Key: "a", Value: "<b>"}}
+	data, err := json.Marshal(o)
+	if err != nil || string(data) != `{"z":1,"a":"\u003cb\u003e"}` {
+		t.Errorf("marshal = %s, %v", data, err)
+	}
+	if v, ok := o.Get("a"); !ok || v != "<b>" {
+		t.Errorf("Get = %v, %v", v, ok)
+	}
+}
+
+func TestSuccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/users/:id", func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Set("role", "user")
+		Success(c, User{ID: 1, Name: "alice", Email: "a@example.com", LoginIP: "10.0.0.1"})
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/users/1", nil))
+
+	want := `{"code":0,"message":"success","data":{"id":1,"name":"alice","email":"a@example.com","joined":"0001-01-01T00:00:00Z"}}`
+	if w.Code != http.StatusOK || w.Body.String() != want {
+		t.Errorf("got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,385 @@
+// ============================================================================
+// Package server HTTP 服务生命周期管理
+// ============================================================================
+//
+// 把 examples/5_3_graceful_shutdown.go 里手写的流程封装起来，所有示例共用：
+//
+//	收到 SIGINT/SIGTERM
+//	  → 1. 标记未就绪（/ready 返回 503，负载均衡停止转发新流量）
+//	  → 2. 等待 ShutdownDelay（给负载均衡摘流的时间）
+//	  → 3. http.Server.Shutdown：不再接受新连接，执行 OnDrain 注册的函数，
+//	       等待进行中的请求完成（最多 DrainTimeout）
+//	  → 4. 按注册的逆序执行关闭钩子（先关后开：日志最先注册、最后关闭）
+//
+// 第 3 步之后 keep-alive 连接上仍可能到达新请求，DrainTimeout 到了强制断开时 handler 也可能还在执行；
+// middleware/drain 的 Tracker 拒绝这些新请求，并在关闭数据库之前等进行中的请求结束。
+//
+// 【用法】
+//
+//	srv := server.New(r, server.Config{Addr: ":8080"})
+//	srv.OnShutdown("database", func(ctx context.Context) error { return sqlDB.Close() })
+//	r.GET("/ready", srv.ReadinessHandler())
+//	if err := srv.Run(); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// 不需要钩子时直接：server.Run(r, ":8080")
+//
+// 【HTTPS 与 HTTP/2】（见 tls.go）
+//
+// | 配置                                 | 主端口（Addr）             | 明文端口（TLS.HTTPAddr，默认 :80）     |
+// |--------------------------------------|----------------------------|----------------------------------------|
+// | 都不配                               | HTTP/1.1                   | 不监听                                 |
+// | TLS.CertFile + KeyFile               | HTTPS，ALPN 协商 HTTP/2    | 301 / 308 跳转到 HTTPS                 |
+// | TLS.AutocertDomains                  | HTTPS，证书自动申请和续期  | ACME HTTP-01 验证 + 跳转               |
+// | HTTP2: h2c（不配 TLS）               | 明文 HTTP/1.1 + HTTP/2     | 不监听                                 |
+//
+// 两个端口各自的读 / 写 / 空闲超时：明文端口只处理跳转和验证，超时更短。
+// TLS 在 Nginx / 负载均衡终止时不用配这里，跳转和 HSTS 交给 middleware/secure。
+//
+//	srv := server.New(r, server.FromConfig(cfg.Server))  // server.tls.* / server.http2 来自配置
+//
+// ============================================================================
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/config"
+)
+
+// Config 服务配置，零值字段使用默认值
+type Config struct {
+	Addr string // 默认 :8080
+
+	ReadTimeout       time.Duration // 默认 10s
+	ReadHeaderTimeout time.Duration // 默认 5s，防 Slowloris
+	WriteTimeout      time.Duration // 默认 30s
+	IdleTimeout       time.Duration // 默认 60s
+
+	// TLS 为空时只提供明文 HTTP
+	TLS TLSConfig
+	// HTTP2 默认 HTTP2Auto
+	HTTP2 HTTP2Mode
+
+	// ShutdownDelay 标记未就绪后、开始关闭前的等待时间，K8s 中建议 5~10s
+	ShutdownDelay time.Duration
+	// DrainTimeout 等待进行中请求完成的最长时间，默认 30s
+	DrainTimeout time.Duration
+	// HookTimeout 所有关闭钩子的总超时，默认 10s
+	HookTimeout time.Duration
+
+	// Signals 触发关闭的信号，默认 SIGINT、SIGTERM
+	Signals []os.Signal
+
+	// Logger 默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Hook 关闭钩子
+type Hook func(ctx context.Context) error
+
+type namedHook struct {
+	name string
+	fn   Hook
+}
+
+// Server 带生命周期管理的 HTTP 服务
+type Server struct {
+	cfg   Config
+	http  *http.Server
+	ready atomic.Bool
+
+	mu       sync.Mutex
+	hooks    []namedHook
+	addr     net.Addr
+	redirect *http.Server // TLS.HTTPAddr 上的明文服务，未启用 HTTPS 时为 nil
+	httpAddr net.Addr
+}
+
+// New 创建服务
+func New(handler http.Handler, cfg Config) *Server {
+	if cfg.Addr == "" {
+		cfg.Addr = ":8080"
+	}
+	if cfg.ReadTimeout == 0 {
+		cfg.ReadTimeout = 10 * time.Second
+	}
+	if cfg.ReadHeaderTimeout == 0 {
+		cfg.ReadHeaderTimeout = 5 * time.Second
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = 30 * time.Second
+	}
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = 60 * time.Second
+	}
+	if cfg.DrainTimeout == 0 {
+		cfg.DrainTimeout = 30 * time.Second
+	}
+	if cfg.HookTimeout == 0 {
+		cfg.HookTimeout = 10 * time.Second
+	}
+	if len(cfg.Signals) == 0 {
+		cfg.Signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	if cfg.HTTP2 == "" {
+		cfg.HTTP2 = HTTP2Auto
+	}
+	if cfg.TLS.HTTPAddr == "" {
+		cfg.TLS.HTTPAddr = ":80"
+	}
+	if cfg.TLS.AutocertCacheDir == "" {
+		cfg.TLS.AutocertCacheDir = "./certs"
+	}
+	if cfg.TLS.MinVersion == 0 {
+		cfg.TLS.MinVersion = tls.VersionTLS12
+	}
+	t := &cfg.TLS.HTTPTimeouts
+	if t.Read == 0 {
+		t.Read = 5 * time.Second
+	}
+	if t.ReadHeader == 0 {
+		t.ReadHeader = 5 * time.Second
+	}
+	if t.Write == 0 {
+		t.Write = 10 * time.Second
+	}
+	if t.Idle == 0 {
+		t.Idle = 30 * time.Second
+	}
+
+	return &Server{
+		cfg: cfg,
+		http: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    1 << 20,
+			Protocols:         protocols(cfg.HTTP2),
+		},
+	}
+}
+
+// FromConfig 从 config.ServerConfig 转换，关闭相关的字段（ShutdownDelay 等）和 Logger 由调用方补充
+func FromConfig(c config.ServerConfig) Config {
+	return Config{
+		Addr:         c.Addr,
+		ReadTimeout:  c.ReadTimeout,
+		WriteTimeout: c.WriteTimeout,
+		IdleTimeout:  c.IdleTimeout,
+		HTTP2:        HTTP2Mode(c.HTTP2),
+		TLS: TLSConfig{
+			CertFile:         c.TLS.CertFile,
+			KeyFile:          c.TLS.KeyFile,
+			AutocertDomains:  c.TLS.AutocertDomains,
+			AutocertCacheDir: c.TLS.AutocertCacheDir,
+			AutocertEmail:    c.TLS.AutocertEmail,
+			HTTPAddr:         c.TLS.HTTPAddr,
+			HTTPTimeouts: Timeouts{
+				Read:  c.TLS.HTTPReadTimeout,
+				Write: c.TLS.HTTPWriteTimeout,
+				Idle:  c.TLS.HTTPIdleTimeout,
+			},
+		},
+	}
+}
+
+// Run 使用默认配置启动服务，直到收到退出信号
+func Run(handler http.Handler, addr string) error {
+	return New(handler, Config{Addr: addr}).Run()
+}
+
+// OnShutdown 注册关闭钩子，在 HTTP 请求全部完成后按注册的逆序执行
+func (s *Server) OnShutdown(name string, fn Hook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, namedHook{name: name, fn: fn})
+}
+
+// OnDrain 注册开始关闭时调用的函数，在等待进行中的请求之前、在单独的 goroutine 里执行
+//
+// SSE 这类长连接请求不会自己结束，Shutdown 会一直等到 DrainTimeout，
+// 在这里通知它们退出：srv.OnDrain(broker.Close)
+func (s *Server) OnDrain(fn func()) {
+	s.http.RegisterOnShutdown(fn)
+}
+
+// Ready 是否就绪
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
+
+// SetReady 手动切换就绪状态，如依赖的数据库断开时设为 false
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// Addr 实际监听地址（Addr 为 ":0" 时可用于获取随机端口），启动前返回 nil
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addr
+}
+
+// HTTPAddr 启用 HTTPS 时明文端口的实际监听地址，没有监听时返回 nil
+func (s *Server) HTTPAddr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.httpAddr
+}
+
+// ReadinessHandler 就绪探针接口
+func (s *Server) ReadinessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.Ready() {
+			c.JSON(http.StatusOK, gin.H{"status": "ready"})
+			return
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready"})
+	}
+}
+
+// Run 启动服务，阻塞直到收到退出信号并完成关闭
+func (s *Server) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), s.cfg.Signals...)
+	defer stop()
+	return s.RunContext(ctx)
+}
+
+// RunContext 启动服务，ctx 取消时优雅关闭
+func (s *Server) RunContext(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("server: listen %s: %w", s.cfg.Addr, err)
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve 在指定 listener 上提供服务，ctx 取消时优雅关闭
+// 配置了 TLS 时 ln 上提供 HTTPS，并另外监听 TLS.HTTPAddr
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	serve := s.http.Serve
+	if s.cfg.TLS.Enabled() {
+		if err := s.listenTLS(ln); err != nil {
+			ln.Close()
+			return err
+		}
+		serve = func(ln net.Listener) error { return s.http.ServeTLS(ln, "", "") }
+	}
+	s.mu.Lock()
+	s.addr = ln.Addr()
+	s.mu.Unlock()
+
+	log := s.cfg.Logger
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- serve(ln)
+	}()
+	s.SetReady(true)
+	log.Info("server started", slog.String("addr", ln.Addr().String()),
+		slog.Bool("tls", s.cfg.TLS.Enabled()), slog.String("http2", string(s.cfg.HTTP2)))
+
+	select {
+	case err := <-errCh:
+		// 没收到信号就退出，说明服务本身出错
+		s.SetReady(false)
+		if s.redirect != nil {
+			s.redirect.Close()
+		}
+		return errors.Join(err, s.runHooks())
+	case <-ctx.Done():
+	}
+
+	log.Info("shutting down", slog.Duration("delay", s.cfg.ShutdownDelay), slog.Duration("drain_timeout", s.cfg.DrainTimeout))
+	s.SetReady(false)
+	if s.cfg.ShutdownDelay > 0 {
+		time.Sleep(s.cfg.ShutdownDelay)
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), s.cfg.DrainTimeout)
+	defer cancel()
+	var errs []error
+	if s.redirect != nil {
+		// 明文端口只有跳转和验证请求，直接关闭
+		s.redirect.Close()
+	}
+	if err := s.http.Shutdown(drainCtx); err != nil {
+		// 超时后强制关闭剩余连接
+		errs = append(errs, fmt.Errorf("server: drain: %w", err))
+		s.http.Close()
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, err)
+	}
+
+	errs = append(errs, s.runHooks())
+	log.Info("server stopped")
+	return errors.Join(errs...)
+}
+
+// listenTLS 准备证书，按需启动明文端口（跳转 + ACME 验证）
+func (s *Server) listenTLS(ln net.Listener) error {
+	tlsCfg, httpHandler, err := s.tlsSetup(portOf(ln.Addr()))
+	if err != nil {
+		return fmt.Errorf("server: tls: %w", err)
+	}
+	s.http.TLSConfig = tlsCfg
+	if s.cfg.TLS.HTTPAddr == "-" {
+		return nil
+	}
+	httpLn, err := net.Listen("tcp", s.cfg.TLS.HTTPAddr)
+	if err != nil {
+		return fmt.Errorf("server: listen %s: %w", s.cfg.TLS.HTTPAddr, err)
+	}
+	srv := newHTTPServer(httpHandler, s.cfg.TLS.HTTPTimeouts)
+	s.mu.Lock()
+	s.redirect, s.httpAddr = srv, httpLn.Addr()
+	s.mu.Unlock()
+	go func() {
+		if err := srv.Serve(httpLn); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.cfg.Logger.Error("http redirect listener stopped", slog.Any("error", err))
+		}
+	}()
+	return nil
+}
+
+// runHooks 逆序执行关闭钩子，单个钩子失败不影响后续钩子
+func (s *Server) runHooks() error {
+	s.mu.Lock()
+	hooks := append([]namedHook(nil), s.hooks...)
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.HookTimeout)
+	defer cancel()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		if err := h.fn(ctx); err != nil {
+			s.cfg.Logger.Error("shutdown hook failed", slog.String("hook", h.name), slog.Any("error", err))
+			errs = append(errs, fmt.Errorf("server: hook %s: %w", h.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,351 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	started := make(chan struct{})
+	r.GET("/slow", func(c *gin.Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+
+	srv := New(r, Config{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	r.GET("/ready", srv.ReadinessHandler())
+
+	var order []string
+	srv.OnShutdown("logs", func(context.Context) error { order = append(order, "logs"); return nil })
+	srv.OnShutdown("db", func(context.Context) error { order = append(order, "db"); return errors.New("close failed") })
+	srv.OnShutdown("cache", func(context.Context) error { order = append(order, "cache"); return nil })
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+
+	base := "http://" + ln.Addr().String()
+	waitReady(t, base)
+
+	// 慢请求进行中触发关闭，请求应该正常完成
+	type result struct {
+		body string
+		err  error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err != nil {
+			slow <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		slow <- result{body: string(b)}
+	}()
+	<-started
+	cancel()
+
+	if res := <-slow; res.err != nil || res.body != "done" {
+		t.Errorf("in-flight request = %q, %v; want done", res.body, res.err)
+	}
+
+	err = <-done
+	if err == nil || !strings.Contains(err.Error(), "hook db: close failed") {
+		t.Errorf("Serve err = %v; want hook db error", err)
+	}
+	if want := []string{"cache", "db", "logs"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("hook order = %v; want %v", order, want)
+	}
+	if srv.Ready() {
+		t.Error("server still ready after shutdown")
+	}
+	if _, err := http.Get(base + "/ready"); err == nil {
+		t.Error("server still accepting connections")
+	}
+}
+
+func TestNotReadyDuringShutdownDelay(t *testing.T) {
+	srv := New(http.NotFoundHandler(), Config{
+		ShutdownDelay: 50 * time.Millisecond,
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+
+	for !srv.Ready() {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+
+	// 延迟期间已经未就绪，但仍然可以处理请求
+	if srv.Ready() {
+		t.Error("ready during shutdown delay")
+	}
+	resp, err := http.Get("http://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatalf("request during shutdown delay: %v", err)
+	}
+	resp.Body.Close()
+
+	if err := <-done; err != nil {
+		t.Errorf("Serve err = %v; want nil", err)
+	}
+}
+
+func TestOnDrain(t *testing.T) {
+	// 长连接请求只有收到通知才会结束
+	stop := make(chan struct{})
+	streaming := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		close(streaming)
+		<-stop
+	})
+	srv := New(handler, Config{
+		DrainTimeout: 5 * time.Second,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	srv.OnDrain(func() { close(stop) })
+
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	<-streaming
+
+	start := time.Now()
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Serve err = %v; want nil", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("shutdown took %v; OnDrain should end the stream immediately", elapsed)
+	}
+}
+
+func waitReady(t *testing.T, base string) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		resp, err := http.Get(base + "/ready")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("server not ready")
+}
+
+// writeCert 生成 127.0.0.1 的自签名证书，返回证书和私钥文件路径
+func writeCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+// start 在随机端口启动服务，返回停止函数
+func start(t *testing.T, srv *Server) (stop func()) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+	for !srv.Ready() {
+		select {
+		case err := <-done:
+			t.Fatalf("Serve: %v", err)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve err = %v", err)
+		}
+	}
+}
+
+var protoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, r.Proto) })
+
+func TestTLS(t *testing.T) {
+	certFile, keyFile := writeCert(t)
+	for _, tt := range []struct {
+		mode  HTTP2Mode
+		proto string
+	}{
+		{HTTP2Auto, "HTTP/2.0"},
+		{HTTP2Off, "HTTP/1.1"},
+	} {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			srv := New(protoHandler, Config{
+				HTTP2:  tt.mode,
+				TLS:    TLSConfig{CertFile: certFile, KeyFile: keyFile, HTTPAddr: "127.0.0.1:0"},
+				Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			})
+			stop := start(t, srv)
+			defer stop()
+
+			client := &http.Client{
+				Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, ForceAttemptHTTP2: true},
+				// 不跟随跳转，检查 Location
+				CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+			}
+			resp, err := client.Get("https://" + srv.Addr().String() + "/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(b) != tt.proto || resp.TLS == nil || resp.TLS.Version < tls.VersionTLS12 {
+				t.Errorf("proto = %s, tls = %v; want %s over TLS 1.2+", b, resp.TLS != nil, tt.proto)
+			}
+
+			// 明文端口跳转到 HTTPS 端口的同一路径
+			_, port, _ := net.SplitHostPort(srv.Addr().String())
+			resp, err = client.Post("http://"+srv.HTTPAddr().String()+"/a?b=1", "text/plain", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if want := "https://127.0.0.1:" + port + "/a?b=1"; resp.StatusCode != http.StatusPermanentRedirect || resp.Header.Get("Location") != want {
+				t.Errorf("redirect = %d %s; want 308 %s", resp.StatusCode, resp.Header.Get("Location"), want)
+			}
+		})
+	}
+}
+
+func TestH2C(t *testing.T) {
+	srv := New(protoHandler, Config{HTTP2: HTTP2H2C, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	stop := start(t, srv)
+	defer stop()
+	if srv.HTTPAddr() != nil {
+		t.Error("plain server opened a redirect listener")
+	}
+
+	// prior knowledge：客户端直接用 HTTP/2 帧说话，不经过 Upgrade
+	protos := new(http.Protocols)
+	protos.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protos}}
+	resp, err := client.Get("http://" + srv.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != "HTTP/2.0" {
+		t.Errorf("proto = %s; want HTTP/2.0", b)
+	}
+	// 普通 HTTP/1.1 客户端照常可用
+	resp, err = http.Get("http://" + srv.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != "HTTP/1.1" {
+		t.Errorf("proto = %s; want HTTP/1.1", b)
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	certFile, keyFile := writeCert(t)
+	for name, cfg := range map[string]TLSConfig{
+		"both cert and autocert": {CertFile: certFile, KeyFile: keyFile, AutocertDomains: []string{"example.com"}},
+		"cert without key":       {CertFile: certFile},
+		"missing file":           {CertFile: certFile + ".missing", KeyFile: keyFile},
+	} {
+		srv := New(protoHandler, Config{TLS: cfg, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+		ln, _ := net.Listen("tcp", "127.0.0.1:0")
+		if err := srv.Serve(context.Background(), ln); err == nil || !strings.HasPrefix(err.Error(), "server: tls:") {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestAutocertHTTPHandler(t *testing.T) {
+	srv := New(protoHandler, Config{
+		TLS:    TLSConfig{AutocertDomains: []string{"app.example.com"}, AutocertCacheDir: t.TempDir()},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	tlsCfg, handler, err := srv.tlsSetup("443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsCfg.GetCertificate == nil || !slices.Contains(tlsCfg.NextProtos, "acme-tls/1") || tlsCfg.MinVersion != tls.VersionTLS12 {
+		t.Errorf("tls config = %+v", tlsCfg)
+	}
+	// 普通请求跳转，默认端口 443 不写进 Location
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://app.example.com/login", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://app.example.com/login" {
+		t.Errorf("redirect = %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// HTTP2Mode HTTP/2 支持方式
+type HTTP2Mode string
+
+const (
+	// HTTP2Auto HTTPS 上通过 ALPN 自动协商 HTTP/2，明文只用 HTTP/1.1（默认）
+	HTTP2Auto HTTP2Mode = "auto"
+	// HTTP2Off 只用 HTTP/1.1，排查代理兼容问题时用
+	HTTP2Off HTTP2Mode = "off"
+	// HTTP2H2C 明文端口也接受 HTTP/2（h2c，prior knowledge），TLS 在前面的代理终止、
+	// 代理到应用也想用 HTTP/2 时用（Envoy、内网 gRPC 网关）；不要直接暴露到公网
+	HTTP2H2C HTTP2Mode = "h2c"
+)
+
+// Timeouts 一个监听地址的超时，零值字段用默认值
+type Timeouts struct {
+	Read       time.Duration
+	ReadHeader time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+}
+
+// TLSConfig HTTPS 配置：证书文件和自动证书二选一，都为空时只提供明文 HTTP
+type TLSConfig struct {
+	// CertFile / KeyFile PEM 格式的证书链和私钥
+	CertFile string
+	KeyFile  string
+
+	// AutocertDomains 通过 ACME（Let's Encrypt）自动申请和续期证书的域名，
+	// 只给这些域名签发，其他 SNI 的握手直接失败
+	AutocertDomains []string
+	// AutocertCacheDir 证书缓存目录，默认 ./certs；重启后复用，不会每次重新申请触发限额
+	AutocertCacheDir string
+	// AutocertEmail 证书快过期或出问题时 CA 联系的邮箱，可以为空
+	AutocertEmail string
+
+	// HTTPAddr 明文监听地址，负责 HTTP → HTTPS 跳转和 ACME 的 HTTP-01 验证，
+	// 默认 :80；"-" 表示不监听（前面的代理已经做了跳转）
+	HTTPAddr string
+	// HTTPTimeouts 明文监听的超时，只处理跳转和验证请求，默认比主端口短：读 5s、写 10s、空闲 30s
+	HTTPTimeouts Timeouts
+
+	// MinVersion 默认 TLS 1.2
+	MinVersion uint16
+}
+
+// Enabled 是否启用 HTTPS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || len(c.AutocertDomains) > 0
+}
+
+func (c TLSConfig) validate() error {
+	if c.CertFile != "" && len(c.AutocertDomains) > 0 {
+		return errors.New("server: tls cert_file and autocert_domains are mutually exclusive")
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("server: tls cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// protocols 按 HTTP2 模式设置 http.Server.Protocols（Go 1.24 起标准库直接支持 h2c）
+func protocols(mode HTTP2Mode) *http.Protocols {
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	switch mode {
+	case HTTP2Off:
+	case HTTP2H2C:
+		p.SetHTTP2(true)
+		p.SetUnencryptedHTTP2(true)
+	default:
+		p.SetHTTP2(true)
+	}
+	return p
+}
+
+// tlsSetup 加载证书或创建 autocert.Manager；返回的 handler 用于明文端口（跳转 + ACME 验证）
+func (s *Server) tlsSetup(httpsPort string) (*tls.Config, http.Handler, error) {
+	cfg := s.cfg.TLS
+	if err := cfg.validate(); err != nil {
+		return nil, nil, err
+	}
+	redirect := redirectHandler(httpsPort)
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &tls.Config{MinVersion: cfg.MinVersion, Certificates: []tls.Certificate{cert}}, redirect, nil
+	}
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.AutocertDomains...),
+		Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+		Email:      cfg.AutocertEmail,
+	}
+	// m.TLSConfig 带上 TLS-ALPN-01 验证需要的 acme-tls/1 协议
+	tlsCfg := m.TLSConfig()
+	tlsCfg.MinVersion = cfg.MinVersion
+	return tlsCfg, m.HTTPHandler(redirect), nil
+}
+
+// redirectHandler 明文请求跳转到 HTTPS 的同一路径：GET / HEAD 用 301，其他方法 308 保持方法和请求体
+func redirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		code := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+	})
+}
+
+// newHTTPServer 创建明文端口的 http.Server
+func newHTTPServer(handler http.Handler, t Timeouts) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadTimeout:       t.Read,
+		ReadHeaderTimeout: t.ReadHeader,
+		WriteTimeout:      t.Write,
+		IdleTimeout:       t.Idle,
+		MaxHeaderBytes:    1 << 16,
+	}
+}
+
+// portOf 监听地址的端口，如 [::]:8443 → 8443
+func portOf(addr net.Addr) string {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return strconv.Itoa(tcp.Port)
+	}
+	_, port, _ := net.SplitHostPort(addr.String())
+	return port
+}
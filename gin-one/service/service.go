@@ -0,0 +1,520 @@
+// ============================================================================
+// Package service 业务逻辑层
+// ============================================================================
+//
+// Handler 只负责 HTTP（绑定参数、选择状态码），业务规则写在这里：
+//
+// | 规则                       | 位置                         |
+// |----------------------------|------------------------------|
+// | 密码只保存哈希             | UserService.Create           |
+// | 只能更新允许修改的字段     | UserService.Update           |
+// | 文章作者必须存在           | PostService.Create           |
+// | 标签名去掉首尾空格、去重   | PostService.tagNames         |
+// | 回复的评论必须在同一篇文章 | CommentService.Create        |
+//
+// 【构造函数注入】
+//
+// Service 只依赖 repository 接口，不直接碰 *gorm.DB：
+//
+//	users := service.NewUserService(repository.NewUserRepository(db), passwords)
+//
+// 单元测试传入内存实现即可，不需要数据库，见 service_test.go。
+//
+// ============================================================================
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go-one/diff"
+	"go-one/model"
+	"go-one/optlock"
+	"go-one/pagination"
+	"go-one/repository"
+)
+
+// 错误定义，Handler 据此选择 HTTP 状态码
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrPostNotFound = errors.New("post not found")
+	ErrUserExists   = errors.New("username or email already exists")
+
+	ErrCommentNotFound = errors.New("comment not found")
+	// ErrInvalidParent 回复的评论不存在、已删除或者不属于这篇文章
+	ErrInvalidParent = errors.New("parent comment not found in this post")
+
+	// ErrVersionConflict 更新时版本号不匹配；错误链里有 *optlock.ConflictError，可以取出当前版本号
+	ErrVersionConflict = errors.New("user was modified by someone else")
+)
+
+// PasswordHasher 密码哈希，*password.Service 实现了该接口
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+}
+
+// ============================================================================
+// 用户
+// ============================================================================
+
+// UserService 用户业务
+type UserService struct {
+	users     repository.UserRepository
+	passwords PasswordHasher
+}
+
+// NewUserService 创建用户服务
+func NewUserService(users repository.UserRepository, passwords PasswordHasher) *UserService {
+	return &UserService{users: users, passwords: passwords}
+}
+
+// CreateUserInput 创建用户参数，Password 为明文
+type CreateUserInput struct {
+	Username string
+	Email    string
+	Password string
+	Age      int
+}
+
+// Create 创建用户，只保存密码哈希
+func (s *UserService) Create(ctx context.Context, in CreateUserInput) (*model.User, error) {
+	hash, err := s.passwords.Hash(in.Password)
+	if err != nil {
+		return nil, fmt.Errorf("hash password: %w", err)
+	}
+	user := &model.User{
+		Username: in.Username,
+		Email:    in.Email,
+		Password: hash,
+		Age:      in.Age,
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrUserExists
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// CreateBatch 批量创建用户，逐个哈希密码后在一个事务里写入
+// 返回与 in 等长的结果，用户名或邮箱已存在的位置为 nil
+func (s *UserService) CreateBatch(ctx context.Context, in []CreateUserInput) ([]*model.User, error) {
+	users := make([]*model.User, len(in))
+	for i, u := range in {
+		hash, err := s.passwords.Hash(u.Password)
+		if err != nil {
+			return nil, fmt.Errorf("hash password: %w", err)
+		}
+		users[i] = &model.User{Username: u.Username, Email: u.Email, Password: hash, Age: u.Age}
+	}
+	created, err := s.users.CreateBatch(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+	for i, ok := range created {
+		if !ok {
+			users[i] = nil
+		}
+	}
+	return users, nil
+}
+
+// Get 获取用户
+func (s *UserService) Get(ctx context.Context, id uint) (*model.User, error) {
+	user, err := s.users.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// ListUsersInput 用户列表参数，Page 从 1 开始（游标模式忽略 Page）
+type ListUsersInput struct {
+	Page     int
+	PageSize int
+	Status   string
+	Keyword  string
+}
+
+func (in *ListUsersInput) normalize() {
+	if in.Page < 1 {
+		in.Page = 1
+	}
+	if in.PageSize < 1 || in.PageSize > pagination.MaxSize {
+		in.PageSize = pagination.DefaultSize
+	}
+}
+
+// List 页码分页查询用户，返回当前页和总数
+func (s *UserService) List(ctx context.Context, in ListUsersInput) ([]model.User, int64, error) {
+	in.normalize()
+	return s.users.List(ctx, repository.UserFilter{
+		Status:  in.Status,
+		Keyword: in.Keyword,
+		Offset:  (in.Page - 1) * in.PageSize,
+		Limit:   in.PageSize,
+	})
+}
+
+// Scroll 游标分页查询用户，after 为 nil 时返回第一页
+func (s *UserService) Scroll(ctx context.Context, in ListUsersInput, after *pagination.Cursor) (pagination.Page[model.User], error) {
+	in.normalize()
+	return s.users.Scroll(ctx, repository.UserFilter{
+		Status:  in.Status,
+		Keyword: in.Keyword,
+		Limit:   in.PageSize,
+	}, after)
+}
+
+// UpdateUserInput 更新用户参数，nil 表示不修改
+type UpdateUserInput struct {
+	Username *string
+	Email    *string
+	Age      *int
+	Status   *string
+
+	// Version 客户端读取时拿到的版本号，不为 nil 时和当前版本号不一致返回 ErrVersionConflict
+	Version *uint
+}
+
+// Update 只更新传入的字段；密码不在这里修改
+// 返回实际变化的字段，传入的值和当前值相同的字段不在其中，全都相同时不写数据库
+func (s *UserService) Update(ctx context.Context, id uint, in UpdateUserInput) (*model.User, diff.Changes, error) {
+	// 用 map 而不是结构体更新，Age=0 这样的零值也能写入
+	fields := make(map[string]any)
+	if in.Username != nil {
+		fields["username"] = *in.Username
+	}
+	if in.Email != nil {
+		fields["email"] = *in.Email
+	}
+	if in.Age != nil {
+		fields["age"] = *in.Age
+	}
+	if in.Status != nil {
+		fields["status"] = *in.Status
+	}
+
+	var version uint
+	if in.Version != nil {
+		version = *in.Version
+	}
+
+	user, changes, err := s.users.Update(ctx, id, version, fields)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return nil, nil, ErrUserNotFound
+	case errors.Is(err, repository.ErrDuplicate):
+		return nil, nil, ErrUserExists
+	case errors.Is(err, optlock.ErrConflict):
+		return nil, nil, fmt.Errorf("%w: %w", ErrVersionConflict, err)
+	}
+	return user, changes, err
+}
+
+// Delete 注销用户（匿名化 + 软删除），文章保留
+func (s *UserService) Delete(ctx context.Context, id uint) error {
+	err := s.users.Anonymize(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// ============================================================================
+// 文章
+// ============================================================================
+
+// PostService 文章业务
+type PostService struct {
+	posts repository.PostRepository
+	users repository.UserRepository
+}
+
+// NewPostService 创建文章服务
+func NewPostService(posts repository.PostRepository, users repository.UserRepository) *PostService {
+	return &PostService{posts: posts, users: users}
+}
+
+// CreatePostInput 创建文章参数
+type CreatePostInput struct {
+	Title   string
+	Content string
+	UserID  uint
+	Tags    []string
+}
+
+// Create 创建文章，作者必须存在
+func (s *PostService) Create(ctx context.Context, in CreatePostInput) (*model.Post, error) {
+	ok, err := s.users.Exists(ctx, in.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	post := &model.Post{Title: in.Title, Content: in.Content, UserID: in.UserID}
+	if err := s.posts.Create(ctx, post, tagNames(in.Tags)); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// Get 文章详情
+func (s *PostService) Get(ctx context.Context, id uint) (*model.Post, error) {
+	post, err := s.posts.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrPostNotFound
+	}
+	return post, err
+}
+
+// List 页码分页，最新的在前，返回当前页和总数；tag 非空时只看该标签的文章
+func (s *PostService) List(ctx context.Context, tag string, page, size int) ([]model.Post, int64, error) {
+	return s.posts.List(ctx, strings.TrimSpace(tag), (page-1)*size, size)
+}
+
+// Scroll 游标分页，最新的在前；tag 非空时只看该标签的文章
+func (s *PostService) Scroll(ctx context.Context, tag string, after *pagination.Cursor, size int) (pagination.Page[model.Post], error) {
+	return s.posts.Scroll(ctx, strings.TrimSpace(tag), after, size)
+}
+
+// Recent 最近的文章，用于订阅源
+func (s *PostService) Recent(ctx context.Context, tag string, limit int) ([]model.Post, error) {
+	return s.posts.Recent(ctx, tag, limit)
+}
+
+// AttachTags 给文章加标签，返回文章现在的全部标签
+func (s *PostService) AttachTags(ctx context.Context, id uint, names []string) ([]model.Tag, error) {
+	tags, err := s.posts.AttachTags(ctx, id, tagNames(names))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrPostNotFound
+	}
+	return tags, err
+}
+
+// DetachTags 去掉文章的标签，返回文章现在的全部标签
+func (s *PostService) DetachTags(ctx context.Context, id uint, names []string) ([]model.Tag, error) {
+	tags, err := s.posts.DetachTags(ctx, id, tagNames(names))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrPostNotFound
+	}
+	return tags, err
+}
+
+// PopularTags 文章数最多的 limit 个标签
+func (s *PostService) PopularTags(ctx context.Context, limit int) ([]model.TagCount, error) {
+	return s.posts.TagCounts(ctx, limit)
+}
+
+// tagNames 去掉首尾空格，丢弃空名字和重复的名字，保持原来的顺序
+func tagNames(names []string) []string {
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name != "" && !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// ============================================================================
+// 评论
+// ============================================================================
+
+// 楼中楼展开的层数，顶层评论是第 1 层
+const (
+	DefaultCommentDepth = 3
+	MaxCommentDepth     = 10
+)
+
+// DeletedComment 已删除评论的占位内容
+const DeletedComment = "[deleted]"
+
+// CommentService 评论业务
+type CommentService struct {
+	comments repository.CommentRepository
+	posts    repository.PostRepository
+	users    repository.UserRepository
+}
+
+// NewCommentService 创建评论服务
+//
+// posts 实现了 repository.Invalidator（带缓存）时，评论增删后删除文章缓存，缓存里的评论数不会过时
+func NewCommentService(comments repository.CommentRepository, posts repository.PostRepository, users repository.UserRepository) *CommentService {
+	return &CommentService{comments: comments, posts: posts, users: users}
+}
+
+// CreateCommentInput 创建评论参数，ParentID 为空是顶层评论
+type CreateCommentInput struct {
+	PostID   uint
+	UserID   uint
+	ParentID *uint
+	Body     string
+}
+
+// Create 发表评论：文章和作者必须存在，回复的评论必须在同一篇文章里且没有删除
+func (s *CommentService) Create(ctx context.Context, in CreateCommentInput) (*model.Comment, error) {
+	if err := s.postExists(ctx, in.PostID); err != nil {
+		return nil, err
+	}
+	ok, err := s.users.Exists(ctx, in.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	if in.ParentID != nil {
+		parent, err := s.comments.Get(ctx, *in.ParentID)
+		if errors.Is(err, repository.ErrNotFound) || err == nil && parent.PostID != in.PostID {
+			return nil, ErrInvalidParent
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	comment := &model.Comment{PostID: in.PostID, UserID: in.UserID, ParentID: in.ParentID, Body: in.Body}
+	if err := s.comments.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+	s.invalidatePost(ctx, in.PostID)
+	return comment, nil
+}
+
+// Delete 删除评论（软删除），回复保留，楼中楼里显示为 [deleted]
+func (s *CommentService) Delete(ctx context.Context, id uint) error {
+	comment, err := s.comments.Get(ctx, id)
+	if err == nil {
+		err = s.comments.Delete(ctx, id)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrCommentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	s.invalidatePost(ctx, comment.PostID)
+	return nil
+}
+
+// Thread 文章的评论树，depth 是展开的层数（<= 0 用默认值，最多 MaxCommentDepth）
+func (s *CommentService) Thread(ctx context.Context, postID uint, depth int) ([]*CommentNode, error) {
+	if err := s.postExists(ctx, postID); err != nil {
+		return nil, err
+	}
+	comments, err := s.comments.ListByPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildThread(comments, depth), nil
+}
+
+func (s *CommentService) postExists(ctx context.Context, postID uint) error {
+	_, err := s.posts.Get(ctx, postID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrPostNotFound
+	}
+	return err
+}
+
+func (s *CommentService) invalidatePost(ctx context.Context, postID uint) {
+	if c, ok := s.posts.(repository.Invalidator); ok {
+		c.Invalidate(ctx, postID)
+	}
+}
+
+// CommentNode 评论树里的一条评论
+type CommentNode struct {
+	ID       uint  `json:"id"`
+	UserID   uint  `json:"user_id,omitempty"` // 已删除的评论不显示作者
+	ParentID *uint `json:"parent_id"`
+	// ReplyTo 超过展开层数、被挂到上一层时，原本回复的评论
+	ReplyTo   *uint          `json:"reply_to,omitempty"`
+	Body      string         `json:"body"`
+	Deleted   bool           `json:"deleted"`
+	CreatedAt time.Time      `json:"created_at"`
+	Replies   []*CommentNode `json:"replies"`
+}
+
+// BuildThread 把一篇文章的评论组装成树，顶层评论和每层回复都按创建顺序
+//
+// 在 Go 里用 map 组装，不用递归 SQL（WITH RECURSIVE 各数据库写法不同，也不好限制层数）：
+//
+//	1 ─┬─ 2 ─── 4 ─── 6        depth = 2 时：    1 ─┬─ 2
+//	  └─ 3                                          ├─ 3
+//	5                                               ├─ 4  reply_to 2
+//	                                                └─ 6  reply_to 4
+//	                                             5
+//
+// - 第 depth 层以下的回复挂到第 depth 层所在的那一层，ReplyTo 记录原本回复的评论
+// - 已删除的评论内容换成 [deleted]、去掉作者，仍有回复（包括拍平的）时占位，没有回复的不显示
+// - comments 必须按 ID 升序（回复一定在它回复的评论之后），父评论不在列表里的当作顶层评论
+func BuildThread(comments []model.Comment, depth int) []*CommentNode {
+	if depth <= 0 {
+		depth = DefaultCommentDepth
+	}
+	depth = min(depth, MaxCommentDepth)
+
+	type entry struct {
+		node      *CommentNode
+		level     int
+		container *CommentNode // 挂在谁的 Replies 下，nil 是顶层
+		keep      bool
+		replies   int // 保留下来的直接回复数（含被拍平到别处的）
+	}
+	entries := make([]entry, len(comments))
+	index := make(map[uint]int, len(comments))
+
+	for i, c := range comments {
+		e := entry{level: 1, node: &CommentNode{
+			ID: c.ID, UserID: c.UserID, ParentID: c.ParentID, Body: c.Body,
+			CreatedAt: c.CreatedAt, Replies: []*CommentNode{},
+		}}
+		if c.DeletedAt.Valid {
+			e.node.UserID, e.node.Body, e.node.Deleted = 0, DeletedComment, true
+		}
+		if c.ParentID != nil {
+			if j, ok := index[*c.ParentID]; ok {
+				parent := entries[j]
+				if parent.level < depth {
+					e.level, e.container = parent.level+1, parent.node
+				} else {
+					e.level, e.container, e.node.ReplyTo = parent.level, parent.container, c.ParentID
+				}
+			}
+		}
+		index[c.ID] = i
+		entries[i] = e
+	}
+
+	// 从后往前：回复一定排在它回复的评论后面，先决定回复留不留，再决定已删除的评论要不要占位
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := &entries[i]
+		e.keep = !e.node.Deleted || e.replies > 0
+		if e.keep && e.node.ParentID != nil {
+			if j, ok := index[*e.node.ParentID]; ok {
+				entries[j].replies++
+			}
+		}
+	}
+
+	roots := []*CommentNode{}
+	for _, e := range entries {
+		switch {
+		case !e.keep:
+		case e.container == nil:
+			roots = append(roots, e.node)
+		default:
+			e.container.Replies = append(e.container.Replies, e.node)
+		}
+	}
+	return roots
+}
@@ -0,0 +1,464 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go-one/diff"
+	"go-one/model"
+	"go-one/optlock"
+	"go-one/pagination"
+	"go-one/repository"
+)
+
+// fakeUsers 内存版 UserRepository，只实现测试需要的行为
+type fakeUsers struct {
+	byID    map[uint]*model.User
+	updated map[string]any
+}
+
+func newFakeUsers(users ...*model.User) *fakeUsers {
+	f := &fakeUsers{byID: map[uint]*model.User{}}
+	for _, u := range users {
+		f.byID[u.ID] = u
+	}
+	return f
+}
+
+func (f *fakeUsers) Create(_ context.Context, u *model.User) error {
+	for _, other := range f.byID {
+		if other.Username == u.Username || other.Email == u.Email {
+			return repository.ErrDuplicate
+		}
+	}
+	u.ID = uint(len(f.byID) + 1)
+	f.byID[u.ID] = u
+	return nil
+}
+
+func (f *fakeUsers) CreateBatch(ctx context.Context, users []*model.User) ([]bool, error) {
+	created := make([]bool, len(users))
+	for i, u := range users {
+		created[i] = f.Create(ctx, u) == nil
+	}
+	return created, nil
+}
+
+func (f *fakeUsers) Get(_ context.Context, id uint) (*model.User, error) {
+	if u, ok := f.byID[id]; ok {
+		return u, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *fakeUsers) Exists(_ context.Context, id uint) (bool, error) {
+	_, ok := f.byID[id]
+	return ok, nil
+}
+
+func (f *fakeUsers) List(_ context.Context, filter repository.UserFilter) ([]model.User, int64, error) {
+	return nil, int64(filter.Offset*1000 + filter.Limit), nil // 把分页参数编码进 total 方便断言
+}
+
+func (f *fakeUsers) Scroll(_ context.Context, filter repository.UserFilter, _ *pagination.Cursor) (pagination.Page[model.User], error) {
+	return pagination.Page[model.User]{HasMore: filter.Limit == pagination.DefaultSize}, nil
+}
+
+func (f *fakeUsers) Update(_ context.Context, id, version uint, fields map[string]any) (*model.User, diff.Changes, error) {
+	u, ok := f.byID[id]
+	if !ok {
+		return nil, nil, repository.ErrNotFound
+	}
+	if err := optlock.Check(u, version); err != nil {
+		return nil, nil, err
+	}
+	f.updated = fields
+	return u, nil, nil
+}
+
+func (f *fakeUsers) Anonymize(_ context.Context, id uint) error {
+	if _, ok := f.byID[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(f.byID, id)
+	return nil
+}
+
+type fakePosts struct {
+	created []*model.Post
+}
+
+func (f *fakePosts) Create(_ context.Context, p *model.Post, tags []string) error {
+	for _, name := range tags {
+		p.Tags = append(p.Tags, model.Tag{Name: name})
+	}
+	f.created = append(f.created, p)
+	return nil
+}
+
+func (f *fakePosts) Get(_ context.Context, id uint) (*model.Post, error) {
+	for _, p := range f.created {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *fakePosts) List(context.Context, string, int, int) ([]model.Post, int64, error) {
+	return nil, 0, nil
+}
+
+func (f *fakePosts) Scroll(context.Context, string, *pagination.Cursor, int) (pagination.Page[model.Post], error) {
+	return pagination.Page[model.Post]{}, nil
+}
+
+func (f *fakePosts) Recent(context.Context, string, int) ([]model.Post, error) { return nil, nil }
+
+func (f *fakePosts) AttachTags(_ context.Context, id uint, names []string) ([]model.Tag, error) {
+	for _, p := range f.created {
+		if p.ID == id {
+			for _, name := range names {
+				p.Tags = append(p.Tags, model.Tag{Name: name})
+			}
+			return p.Tags, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *fakePosts) DetachTags(context.Context, uint, []string) ([]model.Tag, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (f *fakePosts) TagCounts(context.Context, int) ([]model.TagCount, error) { return nil, nil }
+
+func userWithID(id uint, name string) *model.User {
+	u := &model.User{Username: name}
+	u.ID = id
+	return u
+}
+
+type prefixHasher struct{}
+
+func (prefixHasher) Hash(pw string) (string, error) {
+	if pw == "" {
+		return "", errors.New("empty password")
+	}
+	return "hashed:" + pw, nil
+}
+
+func TestUserServiceCreate(t *testing.T) {
+	ctx := context.Background()
+	users := newFakeUsers()
+	svc := NewUserService(users, prefixHasher{})
+
+	u, err := svc.Create(ctx, CreateUserInput{Username: "alice", Email: "a@example.com", Password: "secret1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Password != "hashed:secret1" {
+		t.Errorf("Password = %q; want hashed value", u.Password)
+	}
+
+	_, err = svc.Create(ctx, CreateUserInput{Username: "alice", Email: "b@example.com", Password: "x"})
+	if !errors.Is(err, ErrUserExists) {
+		t.Errorf("duplicate: error = %v; want ErrUserExists", err)
+	}
+	_, err = svc.Create(ctx, CreateUserInput{Username: "bob", Email: "bob@example.com"})
+	if err == nil || !strings.Contains(err.Error(), "hash password") {
+		t.Errorf("hash failure: error = %v; want wrapped hash error", err)
+	}
+}
+
+func TestUserServiceCreateBatch(t *testing.T) {
+	ctx := context.Background()
+	users := newFakeUsers(userWithID(1, "alice"))
+	svc := NewUserService(users, prefixHasher{})
+
+	got, err := svc.CreateBatch(ctx, []CreateUserInput{
+		{Username: "bob", Email: "b@example.com", Password: "secret1"},
+		{Username: "alice", Email: "other@example.com", Password: "secret1"}, // 用户名已存在
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] == nil || got[1] != nil {
+		t.Fatalf("CreateBatch() = %v; want [bob, nil]", got)
+	}
+	if got[0].Password != "hashed:secret1" {
+		t.Errorf("Password = %q; want hashed value", got[0].Password)
+	}
+
+	// 哈希失败时整批都不写入
+	if _, err := svc.CreateBatch(ctx, []CreateUserInput{{Username: "carol", Email: "c@example.com"}}); err == nil {
+		t.Error("hash failure: want error")
+	}
+	if len(users.byID) != 2 {
+		t.Errorf("users = %d; want 2", len(users.byID))
+	}
+}
+
+func TestUserServiceList(t *testing.T) {
+	svc := NewUserService(newFakeUsers(), prefixHasher{})
+	tests := []struct {
+		page, size int
+		want       int64 // offset*1000 + limit
+	}{
+		{1, 10, 10},
+		{3, 20, 40*1000 + 20},
+		{0, 0, 10},              // 非法页码按第 1 页、默认 10 条
+		{2, 1000, 10*1000 + 10}, // 超过上限按默认值
+	}
+	for _, tt := range tests {
+		_, got, _ := svc.List(context.Background(), ListUsersInput{Page: tt.page, PageSize: tt.size})
+		if got != tt.want {
+			t.Errorf("List(page=%d, size=%d) offset/limit = %d; want %d", tt.page, tt.size, got, tt.want)
+		}
+	}
+
+	// 游标模式同样限制每页条数
+	p, _ := svc.Scroll(context.Background(), ListUsersInput{PageSize: 1000}, nil)
+	if !p.HasMore {
+		t.Error("Scroll(size=1000) did not fall back to the default page size")
+	}
+}
+
+func TestUserServiceUpdate(t *testing.T) {
+	users := newFakeUsers(userWithID(1, "alice"))
+	svc := NewUserService(users, prefixHasher{})
+	ctx := context.Background()
+
+	age := 0
+	if _, _, err := svc.Update(ctx, 1, UpdateUserInput{Age: &age}); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := users.updated["age"]; !ok || v != 0 || len(users.updated) != 1 {
+		t.Errorf("updated fields = %v; want only age=0", users.updated)
+	}
+	if _, _, err := svc.Update(ctx, 2, UpdateUserInput{}); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("missing user: error = %v; want ErrUserNotFound", err)
+	}
+
+	users.byID[1].Version = 3
+	stale := uint(2)
+	_, _, err := svc.Update(ctx, 1, UpdateUserInput{Age: &age, Version: &stale})
+	var conflict *optlock.ConflictError
+	if !errors.Is(err, ErrVersionConflict) || !errors.As(err, &conflict) || conflict.Current != 3 {
+		t.Errorf("stale version: error = %v; want ErrVersionConflict with current 3", err)
+	}
+	if err := svc.Delete(ctx, 2); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Delete missing user: error = %v; want ErrUserNotFound", err)
+	}
+}
+
+func TestPostServiceCreate(t *testing.T) {
+	users := newFakeUsers(userWithID(1, "alice"))
+	posts := &fakePosts{}
+	svc := NewPostService(posts, users)
+	ctx := context.Background()
+
+	_, err := svc.Create(ctx, CreatePostInput{Title: "hi", UserID: 9})
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("unknown author: error = %v; want ErrUserNotFound", err)
+	}
+	if len(posts.created) != 0 {
+		t.Errorf("post created for unknown author")
+	}
+
+	// 标签名去掉首尾空格，空的和重复的丢掉
+	p, err := svc.Create(ctx, CreatePostInput{Title: "hi", UserID: 1, Tags: []string{"go", " go ", ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.UserID != 1 || len(p.Tags) != 1 {
+		t.Errorf("post = %+v; want author 1 with 1 tag", p)
+	}
+	if _, err := svc.Get(ctx, 1); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Get missing post: error = %v; want ErrPostNotFound", err)
+	}
+
+	p.ID = 1
+	tags, err := svc.AttachTags(ctx, 1, []string{" gorm", "gorm "})
+	if err != nil || len(tags) != 2 || tags[1].Name != "gorm" {
+		t.Errorf("AttachTags = %+v, %v; want go, gorm", tags, err)
+	}
+	if _, err := svc.DetachTags(ctx, 2, []string{"go"}); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("DetachTags missing post: error = %v; want ErrPostNotFound", err)
+	}
+}
+
+// fakeComments 内存版 CommentRepository
+type fakeComments struct {
+	all []model.Comment
+}
+
+func (f *fakeComments) Create(_ context.Context, c *model.Comment) error {
+	c.ID = uint(len(f.all) + 1)
+	f.all = append(f.all, *c)
+	return nil
+}
+
+func (f *fakeComments) Get(_ context.Context, id uint) (*model.Comment, error) {
+	if id == 0 || int(id) > len(f.all) || f.all[id-1].DeletedAt.Valid {
+		return nil, repository.ErrNotFound
+	}
+	c := f.all[id-1]
+	return &c, nil
+}
+
+func (f *fakeComments) ListByPost(_ context.Context, postID uint) ([]model.Comment, error) {
+	var out []model.Comment
+	for _, c := range f.all {
+		if c.PostID == postID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeComments) Delete(ctx context.Context, id uint) error {
+	if _, err := f.Get(ctx, id); err != nil {
+		return err
+	}
+	f.all[id-1].DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return nil
+}
+
+// invalidatingPosts 带 Invalidate 的文章仓储，记录被删除缓存的文章
+type invalidatingPosts struct {
+	*fakePosts
+	invalidated []uint
+}
+
+func (p *invalidatingPosts) Invalidate(_ context.Context, id uint) {
+	p.invalidated = append(p.invalidated, id)
+}
+
+func TestCommentService(t *testing.T) {
+	post := &model.Post{Title: "hi", UserID: 1}
+	post.ID = 1
+	other := &model.Post{Title: "other", UserID: 1}
+	other.ID = 2
+	posts := &invalidatingPosts{fakePosts: &fakePosts{created: []*model.Post{post, other}}}
+	comments := &fakeComments{}
+	svc := NewCommentService(comments, posts, newFakeUsers(userWithID(1, "alice")))
+	ctx := context.Background()
+
+	root, err := svc.Create(ctx, CreateCommentInput{PostID: 1, UserID: 1, Body: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	elsewhere, _ := svc.Create(ctx, CreateCommentInput{PostID: 2, UserID: 1, Body: "elsewhere"})
+
+	tests := []struct {
+		name string
+		in   CreateCommentInput
+		want error
+	}{
+		{"reply", CreateCommentInput{PostID: 1, UserID: 1, ParentID: &root.ID, Body: "reply"}, nil},
+		{"unknown post", CreateCommentInput{PostID: 9, UserID: 1, Body: "x"}, ErrPostNotFound},
+		{"unknown author", CreateCommentInput{PostID: 1, UserID: 9, Body: "x"}, ErrUserNotFound},
+		{"parent in another post", CreateCommentInput{PostID: 1, UserID: 1, ParentID: &elsewhere.ID, Body: "x"}, ErrInvalidParent},
+	}
+	for _, tt := range tests {
+		if _, err := svc.Create(ctx, tt.in); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v; want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if err := svc.Delete(ctx, root.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Delete(ctx, root.ID); !errors.Is(err, ErrCommentNotFound) {
+		t.Errorf("delete twice: error = %v; want ErrCommentNotFound", err)
+	}
+	parent := root.ID
+	if _, err := svc.Create(ctx, CreateCommentInput{PostID: 1, UserID: 1, ParentID: &parent, Body: "x"}); !errors.Is(err, ErrInvalidParent) {
+		t.Errorf("reply to deleted comment: error = %v; want ErrInvalidParent", err)
+	}
+	if want := []uint{1, 2, 1, 1}; !slices.Equal(posts.invalidated, want) {
+		t.Errorf("invalidated posts = %v; want %v", posts.invalidated, want)
+	}
+
+	thread, err := svc.Thread(ctx, 1, 0)
+	if err != nil || len(thread) != 1 || !thread[0].Deleted || len(thread[0].Replies) != 1 {
+		t.Errorf("Thread = %+v, %v; want deleted root with 1 reply", thread, err)
+	}
+	if _, err := svc.Thread(ctx, 9, 0); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Thread(unknown post) error = %v; want ErrPostNotFound", err)
+	}
+}
+
+func TestBuildThread(t *testing.T) {
+	ref := func(id uint) *uint { return &id }
+	comment := func(id uint, parent *uint, deleted bool) model.Comment {
+		c := model.Comment{PostID: 1, UserID: 7, ParentID: parent, Body: "body"}
+		c.ID = id
+		c.DeletedAt.Valid = deleted
+		return c
+	}
+	// 1 ─┬─ 2 ─── 4 ─── 6
+	//    ├─ 3（已删除，没有回复）
+	//    └─ 8（已删除）── 9
+	// 5（已删除，没有回复）
+	// 7（父评论不在列表里）
+	comments := []model.Comment{
+		comment(1, nil, false),
+		comment(2, ref(1), false),
+		comment(3, ref(1), true),
+		comment(4, ref(2), false),
+		comment(5, nil, true),
+		comment(6, ref(4), false),
+		comment(7, ref(99), false),
+		comment(8, ref(1), true),
+		comment(9, ref(8), false),
+	}
+
+	// shape 把树写成 "1(2(4(6)) 8(9)) 7"，拍平的回复带上原本回复的评论，如 "4^2"
+	var shape func(nodes []*CommentNode) string
+	shape = func(nodes []*CommentNode) string {
+		parts := make([]string, len(nodes))
+		for i, n := range nodes {
+			parts[i] = fmt.Sprint(n.ID)
+			if n.ReplyTo != nil {
+				parts[i] += fmt.Sprintf("^%d", *n.ReplyTo)
+			}
+			if len(n.Replies) > 0 {
+				parts[i] += "(" + shape(n.Replies) + ")"
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+
+	tests := []struct {
+		depth int
+		want  string
+	}{
+		{0, "1(2(4 6^4) 8(9)) 7"}, // 默认 3 层
+		{1, "1 2^1 4^2 6^4 7 8^1 9^8"},
+		{2, "1(2 4^2 6^4 8 9^8) 7"},
+		{4, "1(2(4(6)) 8(9)) 7"},
+		{100, "1(2(4(6)) 8(9)) 7"},
+	}
+	for _, tt := range tests {
+		if got := shape(BuildThread(comments, tt.depth)); got != tt.want {
+			t.Errorf("BuildThread(depth %d) = %s; want %s", tt.depth, got, tt.want)
+		}
+	}
+
+	deleted := BuildThread(comments, 0)[0].Replies[1]
+	if !deleted.Deleted || deleted.Body != DeletedComment || deleted.UserID != 0 {
+		t.Errorf("deleted comment = %+v; want placeholder without author", deleted)
+	}
+	if got := BuildThread(nil, 0); got == nil || len(got) != 0 {
+		t.Errorf("BuildThread(nil) = %v; want empty slice", got)
+	}
+}
@@ -0,0 +1,285 @@
+// ============================================================================
+// Package sse Server-Sent Events 通知推送
+// ============================================================================
+//
+// 【SSE vs WebSocket】
+//
+// | 对比项     | SSE                                  | WebSocket                      |
+// |------------|--------------------------------------|--------------------------------|
+// | 方向       | 只有服务端 → 客户端                  | 双向                           |
+// | 协议       | 普通 HTTP 响应，代理、网关都认识     | 升级后是另一个协议             |
+// | 断线重连   | 浏览器自动重连，带上 Last-Event-ID   | 自己实现                       |
+// | 适合       | 通知、进度、行情                     | 聊天、协同编辑、游戏           |
+//
+// 【格式】
+//
+// 响应 Content-Type: text/event-stream，每个事件以空行结束：
+//
+//	retry: 3000                  客户端断线后 3 秒重连
+//
+//	id: 42
+//	event: order.paid
+//	data: {"order_id":1001}
+//
+//	: ping                       冒号开头是注释，用作心跳
+//
+// 【断线补发】
+//
+// Broker 用环形缓冲区保存最近 BufferSize 个事件。浏览器重连时自动带上
+// Last-Event-ID，Broker 补发这之后的事件，客户端不会漏消息：
+//
+// | Last-Event-ID              | 处理                                             |
+// |----------------------------|--------------------------------------------------|
+// | 没有                       | 新连接，只推送之后的事件                         |
+// | 还在缓冲区范围内           | 补发之后的事件                                   |
+// | 已经被挤出缓冲区           | 发送 reset 事件，客户端应该重新拉取完整状态      |
+// | 比最新 ID 还大（服务重启） | 同上，发送 reset                                 |
+//
+// 事件 ID 只在一个进程内递增，多实例部署需要共享的事件存储（如 Redis Stream）。
+//
+// 【按用户推送】
+//
+// 订阅时用 Config.Authenticate（和 ws 包相同，可以直接用 ws.JWT）认证，
+// PublishTo(userID, ...) 只推给这个用户的连接；Publish 推给所有人。
+// EventSource 不能设置请求头，token 放在查询参数里：new EventSource("/events?token=eyJ...")
+//
+// 【心跳】
+//
+// Nginx 等代理默认 60 秒没有数据就断开连接，Broker 每 Heartbeat 发一行注释保持连接。
+// 响应带 X-Accel-Buffering: no，避免 Nginx 缓冲事件。
+//
+// 【慢客户端】
+//
+// 每个订阅者有一个队列（QueueSize），满了就断开这个连接。
+// 浏览器会自动重连并带上 Last-Event-ID，从缓冲区补发，只要没有落后太多就不会丢事件。
+//
+// ============================================================================
+package sse
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"go-one/ws"
+)
+
+// 错误定义
+var (
+	// ErrUnauthorized 订阅时认证失败
+	ErrUnauthorized = errors.New("sse: unauthorized")
+	// ErrClosed Broker 已关闭
+	ErrClosed = errors.New("sse: broker closed")
+	// ErrInvalidType 事件类型包含换行
+	ErrInvalidType = errors.New("sse: invalid event type")
+)
+
+// Config Broker 配置
+type Config struct {
+	// Authenticate 订阅时认证，nil 表示允许匿名订阅（只能收到 Publish 的广播事件）
+	Authenticate ws.Authenticator
+
+	BufferSize int           // 补发用的环形缓冲区大小，默认 1024
+	QueueSize  int           // 每个订阅者的队列长度，默认 64
+	Heartbeat  time.Duration // 心跳间隔，默认 15 秒
+	Retry      time.Duration // 告诉客户端断线后多久重连，默认 3 秒
+
+	// Logger 默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Event 一个事件
+type Event struct {
+	ID     uint64
+	Type   string // event 字段，空表示默认的 message 事件
+	Data   []byte
+	UserID string // 接收者，空表示所有人
+}
+
+// subscriber 一个 /events 连接
+type subscriber struct {
+	userID string
+	ch     chan Event // Broker 删除订阅者时关闭
+}
+
+// Broker 事件分发
+type Broker struct {
+	cfg    Config
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	seq    uint64
+	ring   []Event // 最近的事件，按 ID 递增
+	subs   map[*subscriber]struct{}
+	closed bool
+}
+
+// NewBroker 创建 Broker
+func NewBroker(cfg Config) *Broker {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1024
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 64
+	}
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = 15 * time.Second
+	}
+	if cfg.Retry <= 0 {
+		cfg.Retry = 3 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Broker{
+		cfg:    cfg,
+		logger: cfg.Logger,
+		ring:   make([]Event, 0, cfg.BufferSize),
+		subs:   make(map[*subscriber]struct{}),
+	}
+}
+
+// Publish 推送给所有订阅者，返回事件 ID
+//
+// data 是 string 或 []byte 时原样发送，其他类型编码为 JSON。
+func (b *Broker) Publish(typ string, data any) (uint64, error) {
+	return b.PublishTo("", typ, data)
+}
+
+// PublishTo 只推送给 userID 的连接（同一个用户可能打开了多个页面）
+func (b *Broker) PublishTo(userID, typ string, data any) (uint64, error) {
+	if strings.ContainsAny(typ, "\r\n") {
+		return 0, ErrInvalidType
+	}
+	payload, err := encode(data)
+	if err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, ErrClosed
+	}
+	b.seq++
+	ev := Event{ID: b.seq, Type: typ, Data: payload, UserID: userID}
+	if len(b.ring) == cap(b.ring) {
+		// 挤掉最旧的一个；copy 代替环形下标，缓冲区只有几千个元素，开销可以忽略
+		copy(b.ring, b.ring[1:])
+		b.ring = b.ring[:len(b.ring)-1]
+	}
+	b.ring = append(b.ring, ev)
+
+	for s := range b.subs {
+		if !s.wants(ev) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			// 队列满：断开，客户端重连后从缓冲区补发
+			b.logger.Warn("sse: slow subscriber disconnected", "user", s.userID, "queue", cap(s.ch))
+			b.drop(s)
+		}
+	}
+	return ev.ID, nil
+}
+
+func encode(data any) ([]byte, error) {
+	switch v := data.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+func (s *subscriber) wants(ev Event) bool {
+	return ev.UserID == "" || ev.UserID == s.userID
+}
+
+// subscribe 注册订阅者并取出需要补发的事件，在同一把锁里完成，中间不会漏掉事件
+//
+// lastID 为 0 表示新连接；reset 为 true 表示补发不完整，客户端需要重新拉取状态
+func (b *Broker) subscribe(userID string, lastID uint64) (s *subscriber, replay []Event, reset bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, nil, false, ErrClosed
+	}
+
+	s = &subscriber{userID: userID, ch: make(chan Event, b.cfg.QueueSize)}
+	b.subs[s] = struct{}{}
+
+	if lastID == 0 || lastID == b.seq {
+		return s, nil, false, nil
+	}
+	// 服务重启过（ID 从头开始），或者要补发的事件已经被挤出缓冲区
+	if lastID > b.seq || len(b.ring) == 0 || lastID+1 < b.ring[0].ID {
+		return s, nil, true, nil
+	}
+	for _, ev := range b.ring {
+		if ev.ID > lastID && s.wants(ev) {
+			replay = append(replay, ev)
+		}
+	}
+	return s, replay, false, nil
+}
+
+// lastSeq 当前最新的事件 ID
+func (b *Broker) lastSeq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+func (b *Broker) unsubscribe(s *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drop(s)
+}
+
+// drop 删除订阅者并关闭它的队列，调用方持有锁
+func (b *Broker) drop(s *subscriber) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Subscribers 当前订阅者数量
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Online 用户是否至少有一个订阅连接
+func (b *Broker) Online(userID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		if s.userID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Close 断开所有订阅者并拒绝新的订阅和事件
+//
+// http.Server.Shutdown 会等待所有进行中的请求，SSE 请求不会自己结束，
+// 要在关闭开始时调用：srv.OnDrain(broker.Close)
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		b.drop(s)
+	}
+}
@@ -0,0 +1,145 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// Handler 返回订阅事件的 gin handler，请求会一直保持到客户端断开或 Broker 关闭
+//
+//	r.GET("/events", broker.Handler())
+func (b *Broker) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := b.Serve(c.Writer, c.Request)
+		switch {
+		case errors.Is(err, ErrUnauthorized):
+			response.Error(c, http.StatusUnauthorized, "unauthorized", err.Error())
+		case errors.Is(err, ErrClosed) && !c.Writer.Written():
+			response.Error(c, http.StatusServiceUnavailable, "unavailable", "服务正在关闭，请重连")
+		}
+	}
+}
+
+// Serve 认证、补发错过的事件，然后持续推送直到客户端断开
+//
+// 开始推送之前失败时返回错误且没有写响应（ErrUnauthorized、ErrClosed）；
+// 客户端断开、被当作慢客户端断开、Broker 关闭都返回 nil。
+func (b *Broker) Serve(w http.ResponseWriter, r *http.Request) error {
+	var userID string
+	if b.cfg.Authenticate != nil {
+		id, err := b.cfg.Authenticate(r)
+		if err != nil {
+			return errors.Join(ErrUnauthorized, err)
+		}
+		userID = id.UserID
+	}
+
+	lastID, known := lastEventID(r)
+	s, replay, reset, err := b.subscribe(userID, lastID)
+	if err != nil {
+		return err
+	}
+	defer b.unsubscribe(s)
+
+	// 服务端的 WriteTimeout 是给普通请求设的，对长连接不适用
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("retry: " + strconv.FormatInt(b.cfg.Retry.Milliseconds(), 10) + "\n\n")
+	if reset || !known {
+		// 带上当前 ID，客户端下次重连从这里开始，不会反复收到 reset
+		writeEvent(bw, Event{ID: b.lastSeq(), Type: "reset"})
+	}
+	for _, ev := range replay {
+		writeEvent(bw, ev)
+	}
+	if err := flush(bw, rc); err != nil {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(b.cfg.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case ev, ok := <-s.ch:
+			if !ok {
+				return nil // 慢客户端或 Broker 关闭
+			}
+			writeEvent(bw, ev)
+			// 一次把队列里已有的事件都写进缓冲，减少 Flush 次数
+			for drained := false; !drained; {
+				select {
+				case ev, ok := <-s.ch:
+					if !ok {
+						flush(bw, rc)
+						return nil
+					}
+					writeEvent(bw, ev)
+				default:
+					drained = true
+				}
+			}
+		case <-heartbeat.C:
+			bw.WriteString(": ping\n\n")
+		case <-r.Context().Done():
+			return nil
+		}
+		if err := flush(bw, rc); err != nil {
+			return nil // 客户端已经断开
+		}
+	}
+}
+
+// lastEventID 浏览器重连时带 Last-Event-ID 头；有些 EventSource polyfill 只能用查询参数
+// known 为 false 表示带了 ID 但格式不对，按需要 reset 处理
+func lastEventID(r *http.Request) (id uint64, known bool) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	if v == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	return id, err == nil
+}
+
+// writeEvent 按 text/event-stream 格式写一个事件，data 里的每一行单独一个 data 字段
+func writeEvent(w *bufio.Writer, ev Event) {
+	w.WriteString("id: " + strconv.FormatUint(ev.ID, 10) + "\n")
+	if ev.Type != "" {
+		w.WriteString("event: " + ev.Type + "\n")
+	}
+	if len(ev.Data) == 0 {
+		w.WriteString("data\n")
+	}
+	for line := range bytes.Lines(ev.Data) {
+		w.WriteString("data: ")
+		w.Write(bytes.TrimRight(line, "\r\n"))
+		w.WriteByte('\n')
+	}
+	w.WriteByte('\n')
+}
+
+func flush(bw *bufio.Writer, rc *http.ResponseController) error {
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
@@ -0,0 +1,292 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"go-one/ws"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func userToken(t *testing.T, id uint) string {
+	t.Helper()
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": id, "username": "u", "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+// newServer 启动挂着 broker 的测试服务器，返回 /events 地址
+func newServer(t *testing.T, cfg Config) (*Broker, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	if cfg.Authenticate == nil {
+		cfg.Authenticate = ws.JWT(secret)
+	}
+	b := NewBroker(cfg)
+	r := gin.New()
+	r.GET("/events", b.Handler())
+	srv := httptest.NewServer(r)
+	t.Cleanup(func() {
+		b.Close()
+		srv.Close()
+	})
+	return b, srv.URL + "/events"
+}
+
+// stream 一个 /events 连接，按事件读取
+type stream struct {
+	resp *http.Response
+	r    *bufio.Reader
+}
+
+func subscribe(t *testing.T, url, token string, header http.Header) *stream {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url+sep+"token="+token, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	s := &stream{resp: resp, r: bufio.NewReader(resp.Body)}
+	if got := s.next(t); got != "retry: 3000" {
+		t.Fatalf("first block = %q", got)
+	}
+	return s
+}
+
+// next 读一个以空行结束的块，多行用 | 连接
+func (s *stream) next(t *testing.T) string {
+	t.Helper()
+	type result struct {
+		block string
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var lines []string
+		for {
+			line, err := s.r.ReadString('\n')
+			if err != nil {
+				done <- result{err: err}
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				done <- result{block: strings.Join(lines, "|")}
+				return
+			}
+			lines = append(lines, line)
+		}
+	}()
+	select {
+	case res := <-done:
+		if res.err != nil {
+			t.Fatalf("read: %v", res.err)
+		}
+		return res.block
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for event")
+		return ""
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	_, url := newServer(t, Config{})
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status = %d; want 401", resp.StatusCode)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	b, url := newServer(t, Config{})
+	alice := subscribe(t, url, userToken(t, 1), nil)
+	bob := subscribe(t, url, userToken(t, 2), nil)
+	waitFor(t, func() bool { return b.Subscribers() == 2 })
+	if !b.Online("1") || !b.Online("2") || b.Online("3") {
+		t.Fatal("Online: want users 1 and 2 online, 3 offline")
+	}
+
+	if resp := alice.resp.Header.Get("Content-Type"); resp != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", resp)
+	}
+
+	b.Publish("notice", "line1\nline2")
+	b.PublishTo("2", "dm", map[string]int{"n": 1})
+	b.Publish("", "plain")
+
+	if got := alice.next(t); got != "id: 1|event: notice|data: line1|data: line2" {
+		t.Fatalf("alice got %q", got)
+	}
+	// alice 收不到发给 bob 的事件
+	if got := alice.next(t); got != "id: 3|data: plain" {
+		t.Fatalf("alice got %q", got)
+	}
+	for _, want := range []string{
+		"id: 1|event: notice|data: line1|data: line2",
+		`id: 2|event: dm|data: {"n":1}`,
+		"id: 3|data: plain",
+	} {
+		if got := bob.next(t); got != want {
+			t.Fatalf("bob got %q; want %q", got, want)
+		}
+	}
+
+	if _, err := b.Publish("bad\ntype", "x"); !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("err = %v; want ErrInvalidType", err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string // 追加在 /events 后面
+		last string // Last-Event-ID 头
+		want []string
+	}{
+		{"in buffer", "", "4", []string{"id: 5|event: n|data: 5"}},
+		{"query param", "?last_event_id=4", "", []string{"id: 5|event: n|data: 5"}},
+		{"up to date", "", "6", nil},
+		{"evicted", "", "1", []string{"id: 6|event: reset|data"}},
+		{"server restarted", "", "99", []string{"id: 6|event: reset|data"}},
+		{"malformed", "", "abc", []string{"id: 6|event: reset|data"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, url := newServer(t, Config{BufferSize: 3})
+			for i := range 5 {
+				b.Publish("n", i+1) // ID 1..5
+			}
+			b.PublishTo("2", "dm", "for bob") // ID 6，缓冲区里剩 4、5、6
+
+			var header http.Header
+			if tt.last != "" {
+				header = http.Header{"Last-Event-ID": {tt.last}}
+			}
+			s := subscribe(t, url+tt.url, userToken(t, 1), header)
+			for _, want := range tt.want {
+				if got := s.next(t); got != want {
+					t.Fatalf("got %q; want %q", got, want)
+				}
+			}
+			// 补发之后是实时事件
+			b.Publish("live", "x")
+			if got := s.next(t); got != "id: 7|event: live|data: x" {
+				t.Fatalf("got %q", got)
+			}
+		})
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	_, url := newServer(t, Config{Heartbeat: 20 * time.Millisecond})
+	s := subscribe(t, url, userToken(t, 1), nil)
+	if got := s.next(t); got != ": ping" {
+		t.Fatalf("got %q; want heartbeat", got)
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	b := NewBroker(Config{QueueSize: 2})
+	slow, _, _, _ := b.subscribe("1", 0)
+	for range 3 {
+		b.Publish("n", "x")
+	}
+	if b.Subscribers() != 0 {
+		t.Fatal("slow subscriber not dropped")
+	}
+	// 队列里的事件还在，读完后 channel 已关闭
+	n := 0
+	for range slow.ch {
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("queued %d; want 2", n)
+	}
+}
+
+func TestClose(t *testing.T) {
+	b, url := newServer(t, Config{})
+	s := subscribe(t, url, userToken(t, 1), nil)
+	waitFor(t, func() bool { return b.Subscribers() == 1 })
+
+	b.Close()
+	if _, err := s.r.ReadString('\n'); err == nil {
+		t.Fatal("stream still open after Close")
+	}
+	if _, err := b.Publish("n", "x"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Publish err = %v; want ErrClosed", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, url+"?token="+userToken(t, 1), nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status after close = %d; want 503", resp.StatusCode)
+	}
+}
+
+// 服务端的 WriteTimeout 不应该断开长连接
+func TestWriteTimeout(t *testing.T) {
+	b := NewBroker(Config{Authenticate: ws.JWT(secret)})
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.Serve(w, r)
+	}))
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	t.Cleanup(func() {
+		b.Close()
+		srv.Close()
+	})
+
+	s := subscribe(t, srv.URL, userToken(t, 1), nil)
+	time.Sleep(150 * time.Millisecond)
+	b.Publish("n", "late")
+	if got := s.next(t); got != "id: 1|event: n|data: late" {
+		t.Fatalf("got %q", got)
+	}
+}
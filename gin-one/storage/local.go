@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/pools"
+)
+
+// ============================================================================
+// 本地磁盘存储
+// ============================================================================
+//
+// 【签名算法】
+//
+//	sig = hex(HMAC-SHA256(secret, key + "\n" + expires))
+//
+// 下载时用同样的方法重新计算并做常量时间比较，
+// 签名里包含 key 和过期时间，篡改任意一个都会校验失败。
+//
+
+// ErrBadSignature 签名无效或链接已过期
+var ErrBadSignature = errors.New("storage: invalid or expired signature")
+
+// Local 把对象保存在本地目录中
+type Local struct {
+	dir     string
+	baseURL string // 下载路由前缀，如 /files
+	secret  []byte
+	now     func() time.Time
+}
+
+// NewLocal 创建本地存储，dir 不存在时自动创建
+func NewLocal(dir, baseURL string, secret []byte) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		secret:  secret,
+		now:     time.Now,
+	}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	if err := ValidKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put 实现 Blob：先写临时文件再重命名，读取方不会看到写了一半的文件
+func (l *Local) Put(_ context.Context, key string, r io.Reader, _ string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // 重命名成功后这里什么也不做
+
+	if _, err := pools.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get 实现 Blob
+func (l *Local) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Stat 实现 Ranger，ETag 由大小和修改时间生成（和 nginx 的做法一样），文件被覆盖后会变
+func (l *Local) Stat(_ context.Context, key string) (Info, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return Info{}, err
+	}
+	fi, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Info{}, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	return Info{
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		ETag:    fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()),
+	}, nil
+}
+
+// GetRange 实现 Ranger
+func (l *Local) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	rc, err := l.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	f := rc.(*os.File)
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, length), f}, nil
+}
+
+// Delete 实现 Blob
+func (l *Local) Delete(_ context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL 实现 Blob
+func (l *Local) SignedURL(_ context.Context, key string, ttl time.Duration) (string, error) {
+	if err := ValidKey(key); err != nil {
+		return "", err
+	}
+	expires := strconv.FormatInt(l.now().Add(ttl).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("sig", l.sign(key, expires))
+	return l.baseURL + "/" + key + "?" + q.Encode(), nil
+}
+
+// Verify 校验签名和过期时间
+func (l *Local) Verify(key, expires, sig string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || l.now().Unix() > exp {
+		return ErrBadSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(l.sign(key, expires))) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+func (l *Local) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Handler 处理 SignedURL 生成的下载链接，路由需要以 *key 结尾：
+//
+//	r.GET("/files/*key", local.Handler())
+func (l *Local) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimPrefix(c.Param("key"), "/")
+		if err := l.Verify(key, c.Query("expires"), c.Query("sig")); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden", "message": "链接无效或已过期"})
+			return
+		}
+
+		path, err := l.path(key)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_key", "message": err.Error()})
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "文件不存在"})
+			return
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal", "message": err.Error()})
+			return
+		}
+		// ServeContent 会根据扩展名设置 Content-Type，并处理 Range / If-Modified-Since
+		http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), f)
+	}
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want error
+	}{
+		{"a.txt", nil},
+		{"receipts/2024/ord_1.pdf", nil},
+		{"", ErrInvalidKey},
+		{"/etc/passwd", ErrInvalidKey},
+		{"../secret", ErrInvalidKey},
+		{"a/../../b", ErrInvalidKey},
+		{"a//b", ErrInvalidKey},
+		{`a\b`, ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		if got := ValidKey(tt.key); got != tt.want {
+			t.Errorf("ValidKey(%q) = %v; want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestLocalPutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewLocal(t.TempDir(), "/files", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Put(ctx, "a/b.txt", strings.NewReader("hello"), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := l.Get(ctx, "a/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(body) != "hello" {
+		t.Errorf("Get = %q; want hello", body)
+	}
+
+	if err := l.Delete(ctx, "a/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Get(ctx, "a/b.txt"); err != ErrNotFound {
+		t.Errorf("Get after Delete err = %v; want ErrNotFound", err)
+	}
+	if err := l.Delete(ctx, "a/b.txt"); err != nil {
+		t.Errorf("Delete missing key err = %v; want nil", err)
+	}
+}
+
+func TestSignedURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	l, _ := NewLocal(t.TempDir(), "/files", []byte("secret"))
+	l.now = func() time.Time { return now }
+	l.Put(ctx, "r/1.pdf", strings.NewReader("%PDF"), "application/pdf")
+
+	r := gin.New()
+	r.GET("/files/*key", l.Handler())
+
+	signed, err := l.SignedURL(ctx, "r/1.pdf", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		at   time.Time
+		want int
+	}{
+		{"valid", signed, now, http.StatusOK},
+		{"expired", signed, now.Add(2 * time.Minute), http.StatusForbidden},
+		{"tampered key", strings.Replace(signed, "1.pdf", "2.pdf", 1), now, http.StatusForbidden},
+		{"missing sig", "/files/r/1.pdf", now, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l.now = func() time.Time { return tt.at }
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d; want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package testutil_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"go-one/app"
+	"go-one/grpcapi"
+	"go-one/grpcapi/userpb"
+	"go-one/model"
+	"go-one/policy"
+	"go-one/response"
+	"go-one/testutil"
+)
+
+// userRoutes 7_1_grpc_service.go 的用户接口：HTTP 网关转发到内存里的 gRPC 服务，
+// 网关和服务端用同一个 Access Token 认证；注销用户另外要求 admin 角色
+func userRoutes(t *testing.T) func(r *gin.Engine, a *app.Application) {
+	return func(r *gin.Engine, a *app.Application) {
+		srv := grpcapi.NewServer(a.Services.Users, grpcapi.Config{
+			Authenticate: grpcapi.JWT(testutil.Secret),
+			Public:       []string{userpb.UserService_CreateUser_FullMethodName},
+			Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		})
+		lis := bufconn.Listen(1 << 20)
+		go srv.Serve(lis)
+		t.Cleanup(srv.Stop)
+
+		conn, err := grpc.NewClient("passthrough:///bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		// 网关前面也做一次认证，rbac、policy 的中间件才有用户和角色可看
+		v1 := r.Group("/v1", testutil.Auth(), func(c *gin.Context) {
+			if c.Request.Method == http.MethodDelete && policy.SubjectFromContext(c).Role != "admin" {
+				response.Abort(c, http.StatusForbidden, "forbidden", "需要管理员权限")
+			}
+		})
+		grpcapi.RegisterGateway(v1, userpb.NewUserServiceClient(conn))
+	}
+}
+
+func TestUserCRUD(t *testing.T) {
+	h := testutil.New(t, userRoutes(t))
+
+	h.Do(testutil.POST("/v1/users", map[string]any{"username": "alice", "email": "alice@example.com", "password": "secret123", "age": 30}).WithJWT("admin")).
+		Status(http.StatusOK).
+		JSON("data.id", "1"). // protojson 把 64 位整数编码成字符串
+		Golden("user_create")
+	h.Do(testutil.POST("/v1/users", map[string]any{"username": "bob", "email": "bob@example.com", "password": "secret123"}).WithJWT("admin")).
+		Status(http.StatusOK)
+
+	h.Do(testutil.GET("/v1/users/1")).Status(http.StatusUnauthorized)
+	h.Do(testutil.GET("/v1/users/1").WithJWT("user")).
+		Status(http.StatusOK).
+		JSON("data.username", "alice").
+		JSON("data.status", "active")
+
+	h.Do(testutil.GET("/v1/users?page=1&page_size=10").WithJWT("user")).
+		Status(http.StatusOK).
+		JSON("data.total_size", "2").
+		JSON("data.users.1.username", "bob").
+		Golden("user_list")
+
+	var updated struct {
+		Age  int    `json:"age"`
+		Name string `json:"username"`
+	}
+	h.Do(testutil.PATCH("/v1/users/1", map[string]any{"age": 31}).WithJWT("user")).
+		Status(http.StatusOK).
+		Data(&updated)
+	if updated.Age != 31 || updated.Name != "alice" {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	// 注销只允许管理员
+	h.Do(testutil.DELETE("/v1/users/2").WithJWT("user")).
+		Status(http.StatusForbidden).
+		JSON("error", "forbidden")
+	h.Do(testutil.DELETE("/v1/users/2").WithJWT("admin")).Status(http.StatusOK)
+	h.Do(testutil.GET("/v1/users/2").WithJWT("admin")).
+		Status(http.StatusNotFound).
+		Golden("user_not_found")
+
+	var bob model.User
+	if err := h.DB.Unscoped().First(&bob, 2).Error; err != nil || bob.Status != "deleted" || bob.Email == "bob@example.com" {
+		t.Errorf("bob = %+v, %v", bob, err)
+	}
+}
+
+func TestUserCreateErrors(t *testing.T) {
+	h := testutil.New(t, userRoutes(t))
+	post := func(body any) *testutil.Request {
+		return testutil.POST("/v1/users", body).WithJWT("admin")
+	}
+
+	// 上一个测试的 alice 已经回滚，可以再注册一次
+	h.Do(post(map[string]any{"username": "alice", "email": "alice@example.com", "password": "secret123"})).
+		Status(http.StatusOK).
+		JSON("data.id", "1")
+	h.Do(post(map[string]any{"username": "alice", "email": "other@example.com", "password": "secret123"})).
+		Status(http.StatusConflict).
+		JSON("error", "already_exists")
+
+	h.Do(post(map[string]any{"username": "x", "email": "not-an-email", "password": "1"})).
+		Status(http.StatusBadRequest).
+		Golden("user_create_invalid")
+	h.Do(post("{")).
+		Status(http.StatusBadRequest).
+		JSON("message", "invalid JSON body")
+}
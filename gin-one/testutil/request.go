@@ -0,0 +1,165 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Secret 测试 token 的签名密钥，Auth 和 grpcapi.JWT(testutil.Secret) 用它校验
+var Secret = []byte("testutil-secret")
+
+// Claims 和 5_1_jwt_auth.go 的 CustomClaims 相同的载荷
+type Claims struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// Token 用 Secret 签发一小时有效的 Access Token
+func Token(userID uint, username, role string) string {
+	now := time.Now()
+	claims := Claims{
+		UserID:   userID,
+		Username: username,
+		Role:     role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "gin-app",
+			Subject:   "access_token",
+		},
+	}
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(Secret)
+	if err != nil {
+		panic(err) // HMAC 签名只在密钥类型不对时失败
+	}
+	return s
+}
+
+// Auth 校验 Token 签发的 token，和示例里的 JWTAuthMiddleware 一样在 Context 中设置
+// user_id、username、role、claims，rbac、policy 等包的中间件可以直接放在它后面
+func Auth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		claims := &Claims{}
+		if ok {
+			_, err := jwt.ParseWithClaims(raw, claims, func(*jwt.Token) (any, error) { return Secret, nil },
+				jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+			ok = err == nil && claims.Subject == "access_token"
+		}
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "Invalid token"})
+			return
+		}
+		c.Set("user_id", claims.UserID)
+		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
+// Request 测试请求的构造器，方法都返回自身，可以链式调用
+type Request struct {
+	method string
+	target string
+	header http.Header
+	body   io.Reader
+	err    error // 请求体编码失败，Do 时报告
+}
+
+// NewRequest 创建请求，target 可以带查询参数
+func NewRequest(method, target string) *Request {
+	return &Request{method: method, target: target, header: http.Header{}}
+}
+
+// GET 创建 GET 请求
+func GET(target string) *Request { return NewRequest(http.MethodGet, target) }
+
+// DELETE 创建 DELETE 请求
+func DELETE(target string) *Request { return NewRequest(http.MethodDelete, target) }
+
+// POST 创建请求体为 JSON 的 POST 请求
+func POST(target string, body any) *Request {
+	return NewRequest(http.MethodPost, target).JSON(body)
+}
+
+// PUT 创建请求体为 JSON 的 PUT 请求
+func PUT(target string, body any) *Request {
+	return NewRequest(http.MethodPut, target).JSON(body)
+}
+
+// PATCH 创建请求体为 JSON 的 PATCH 请求
+func PATCH(target string, body any) *Request {
+	return NewRequest(http.MethodPatch, target).JSON(body)
+}
+
+// JSON 把 body 编码为请求体；string 和 []byte 原样发送，用来构造格式错误的 JSON
+func (r *Request) JSON(body any) *Request {
+	switch b := body.(type) {
+	case string:
+		r.body = strings.NewReader(b)
+	case []byte:
+		r.body = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(body)
+		r.body, r.err = bytes.NewReader(data), err
+	}
+	r.header.Set("Content-Type", "application/json")
+	return r
+}
+
+// Form 以 application/x-www-form-urlencoded 发送表单
+func (r *Request) Form(values url.Values) *Request {
+	r.body = strings.NewReader(values.Encode())
+	r.header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+// Header 设置请求头
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// WithToken 带上 Authorization: Bearer <token>
+func (r *Request) WithToken(token string) *Request {
+	return r.Header("Authorization", "Bearer "+token)
+}
+
+// WithJWT 以角色 role 的用户 1 认证，用户名和角色相同
+func (r *Request) WithJWT(role string) *Request {
+	return r.WithUser(1, role, role)
+}
+
+// WithUser 以指定用户认证，测试资源所有者等需要区分用户的场景
+func (r *Request) WithUser(userID uint, username, role string) *Request {
+	return r.WithToken(Token(userID, username, role))
+}
+
+// Do 用任意 http.Handler 处理请求，不需要 Harness 时使用
+func (r *Request) Do(t testing.TB, handler http.Handler) *Response {
+	t.Helper()
+	if r.err != nil {
+		t.Fatalf("testutil: encode request body: %v", r.err)
+	}
+	req := httptest.NewRequest(r.method, r.target, r.body)
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return &Response{ResponseRecorder: w, t: t, req: req}
+}
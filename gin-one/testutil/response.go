@@ -0,0 +1,166 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// update 为 true 时 Golden 重写 golden 文件而不是比较
+var update = flag.Bool("update", false, "testutil: rewrite golden files under testdata/")
+
+// Response 响应和绑定的 testing.TB，断言方法失败时调用 t.Errorf，返回自身可以链式调用
+type Response struct {
+	*httptest.ResponseRecorder
+
+	t   testing.TB
+	req *http.Request
+}
+
+// Status 断言 HTTP 状态码，不一致时打印响应体，后续断言大多没有意义所以直接结束测试
+func (r *Response) Status(want int) *Response {
+	r.t.Helper()
+	if r.Code != want {
+		r.t.Fatalf("%s %s: status = %d, want %d\n%s", r.req.Method, r.req.URL, r.Code, want, r.Body)
+	}
+	return r
+}
+
+// Decode 把响应体解码到 v
+func (r *Response) Decode(v any) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body.Bytes(), v); err != nil {
+		r.t.Fatalf("%s %s: decode body: %v\n%s", r.req.Method, r.req.URL, err, r.Body)
+	}
+	return r
+}
+
+// Data 把 response.Success 的 data 字段解码到 v
+func (r *Response) Data(v any) *Response {
+	r.t.Helper()
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	r.Decode(&body)
+	if err := json.Unmarshal(body.Data, v); err != nil {
+		r.t.Fatalf("%s %s: decode data: %v\n%s", r.req.Method, r.req.URL, err, r.Body)
+	}
+	return r
+}
+
+// JSON 断言响应体中 path 处的值等于 want
+//
+// path 用点分隔，数组用下标："data.users.0.username"；空字符串表示整个响应体。
+// want 先编码成 JSON 再比较，所以 1 和 1.0、struct 和 map 都能直接比
+func (r *Response) JSON(path string, want any) *Response {
+	r.t.Helper()
+	var body any
+	r.Decode(&body)
+	got, err := lookup(body, path)
+	if err != nil {
+		r.t.Errorf("%s %s: %s: %v\n%s", r.req.Method, r.req.URL, path, err, r.Body)
+		return r
+	}
+	if !EqualJSON(got, want) {
+		g, _ := json.Marshal(got)
+		w, _ := json.Marshal(want)
+		r.t.Errorf("%s %s: %s = %s, want %s", r.req.Method, r.req.URL, path, g, w)
+	}
+	return r
+}
+
+// Golden 和 testdata/<name>.golden 比较响应体，见 Golden 函数
+func (r *Response) Golden(name string) *Response {
+	r.t.Helper()
+	Golden(r.t, name, r.Body.Bytes())
+	return r
+}
+
+// EqualJSON a、b 编码成 JSON 后是否相同
+func EqualJSON(a, b any) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+func normalize(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	json.Unmarshal(data, &out)
+	return out
+}
+
+// lookup 按点分隔的路径取值
+func lookup(v any, path string) (any, error) {
+	if path == "" {
+		return v, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("no key %q", key)
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("index %q out of range [0, %d)", key, len(node))
+			}
+			v = node[i]
+		default:
+			return nil, fmt.Errorf("cannot index %T with %q", v, key)
+		}
+	}
+	return v, nil
+}
+
+// timestamps RFC 3339 时间，golden 文件里替换成 <time>
+var timestamps = regexp.MustCompile(`"\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})"`)
+
+// Golden 和 testdata/<name>.golden 比较 got，go test -update 时改为写入
+//
+// JSON 先按两个空格缩进格式化，时间戳替换成 "<time>"，每次运行都变的值不会造成差异；
+// 其他格式原样比较
+func Golden(t testing.TB, name string, got []byte) {
+	t.Helper()
+	got = canonical(got)
+	file := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s mismatch (run go test -update if the change is intended)\ngot:\n%s\nwant:\n%s", file, got, want)
+	}
+}
+
+func canonical(body []byte) []byte {
+	var buf bytes.Buffer
+	if json.Indent(&buf, body, "", "  ") != nil {
+		return body
+	}
+	buf.WriteByte('\n')
+	return timestamps.ReplaceAll(buf.Bytes(), []byte(`"<time>"`))
+}
@@ -0,0 +1,89 @@
+package testutil
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/app"
+	"go-one/model"
+	"go-one/response"
+)
+
+func TestTxRollback(t *testing.T) {
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			tx := Tx(t)
+			var n int64
+			if tx.Model(&model.Tag{}).Count(&n); n != 0 {
+				t.Fatalf("tags left by previous test = %d", n)
+			}
+			tag := model.Tag{Name: "go"}
+			if err := tx.Create(&tag).Error; err != nil || tag.ID != 1 {
+				t.Fatalf("tag = %+v, %v", tag, err)
+			}
+		})
+	}
+}
+
+func TestAuthAndAssertions(t *testing.T) {
+	h := New(t, func(r *gin.Engine, _ *app.Application) {
+		r.GET("/me", Auth(), func(c *gin.Context) {
+			response.Success(c, gin.H{"user_id": c.GetUint("user_id"), "role": c.GetString("role"), "tags": []string{"a", "b"}})
+		})
+	})
+
+	h.Do(GET("/me")).Status(http.StatusUnauthorized)
+	h.Do(GET("/me").WithToken("garbage")).Status(http.StatusUnauthorized)
+	h.Do(GET("/me").WithUser(7, "bob", "editor")).
+		Status(http.StatusOK).
+		JSON("data", map[string]any{"user_id": 7, "role": "editor", "tags": []string{"a", "b"}}).
+		JSON("data.tags.1", "b")
+
+	res := h.Do(GET("/me").WithJWT("admin"))
+	var data struct {
+		UserID uint   `json:"user_id"`
+		Role   string `json:"role"`
+	}
+	if res.Data(&data); data.UserID != 1 || data.Role != "admin" {
+		t.Errorf("data = %+v", data)
+	}
+	if _, err := lookup(map[string]any{"a": []any{}}, "a.0"); err == nil {
+		t.Error("lookup out of range: no error")
+	}
+}
+
+func TestGolden(t *testing.T) {
+	t.Chdir(t.TempDir())
+	body := []byte(`{"code":0,"data":{"id":1,"create_time":"2026-10-15T08:00:00.123456Z"}}`)
+
+	defer func(old bool) { *update = old }(*update)
+	*update = true
+	Golden(t, "user", body)
+	*update = false
+
+	data, _ := os.ReadFile(filepath.Join("testdata", "user.golden"))
+	want := "{\n  \"code\": 0,\n  \"data\": {\n    \"id\": 1,\n    \"create_time\": \"<time>\"\n  }\n}\n"
+	if string(data) != want {
+		t.Errorf("golden =\n%s", data)
+	}
+	// 时间戳不同也算一致
+	Golden(t, "user", []byte(`{"code":0,"data":{"id":1,"create_time":"2027-01-01T00:00:00+08:00"}}`))
+
+	ft := &fakeT{TB: t}
+	Golden(ft, "user", []byte(`{"code":0,"data":{"id":2}}`))
+	if !ft.failed {
+		t.Error("mismatch not reported")
+	}
+}
+
+// fakeT 记录 Errorf，不让外层测试失败
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (f *fakeT) Errorf(string, ...any) { f.failed = true }
@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// GORM 插件：每条 SQL 一个 span
+// ============================================================================
+//
+//	db.Use(tracing.GormPlugin{})
+//	db.WithContext(c.Request.Context()).First(&user, id) // 成为请求 span 的子 span
+//
+// 记录的 SQL 不带参数值（WHERE email = ?），避免把个人信息写进追踪后端。
+// record not found 是正常的业务结果，不标记为错误。
+//
+// ============================================================================
+
+// spanKey 回调之间传递 span 的键
+const spanKey = "tracing:span"
+
+// GormPlugin GORM 追踪插件
+type GormPlugin struct {
+	// WithoutQuery 为 true 时不记录 SQL 语句，只记录操作和表名
+	WithoutQuery bool
+}
+
+// Name 实现 gorm.Plugin
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize 实现 gorm.Plugin，在每种操作的最前和最后注册回调
+// 排在所有回调外面，事务和其他插件（如审计）的耗时也算在 span 里
+func (p GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	steps := []error{
+		cb.Create().Before("*").Register("tracing:before_create", p.before("create")),
+		cb.Create().After("*").Register("tracing:after_create", p.after),
+		cb.Query().Before("*").Register("tracing:before_query", p.before("query")),
+		cb.Query().After("*").Register("tracing:after_query", p.after),
+		cb.Update().Before("*").Register("tracing:before_update", p.before("update")),
+		cb.Update().After("*").Register("tracing:after_update", p.after),
+		cb.Delete().Before("*").Register("tracing:before_delete", p.before("delete")),
+		cb.Delete().After("*").Register("tracing:after_delete", p.after),
+		cb.Row().Before("*").Register("tracing:before_row", p.before("row")),
+		cb.Row().After("*").Register("tracing:after_row", p.after),
+		cb.Raw().Before("*").Register("tracing:before_raw", p.before("raw")),
+		cb.Raw().After("*").Register("tracing:after_raw", p.after),
+	}
+	for _, err := range steps {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p GormPlugin) before(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := tracer().Start(db.Statement.Context, "gorm."+op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNameKey.String(db.Dialector.Name()),
+				semconv.DBOperationName(op),
+			),
+		)
+		// 回调里再发起的查询（如审计插件读旧值）成为这个 span 的子 span
+		db.Statement.Context = ctx
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func (p GormPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := v.(trace.Span)
+	defer span.End()
+
+	// 查询是返回的行数，增删改是影响的行数
+	attrs := []attribute.KeyValue{attribute.Int64("db.rows_affected", db.RowsAffected)}
+	if table := db.Statement.Table; table != "" {
+		attrs = append(attrs, semconv.DBCollectionName(table))
+	}
+	if !p.WithoutQuery {
+		if sql := db.Statement.SQL.String(); sql != "" {
+			attrs = append(attrs, semconv.DBQueryText(sql))
+		}
+	}
+	span.SetAttributes(attrs...)
+
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
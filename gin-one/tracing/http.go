@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceIDHeader 响应头中的 trace ID，客户端报错时附上它就能查到整条链路
+const TraceIDHeader = "X-Trace-Id"
+
+// Middleware 为每个请求创建 server span
+//
+// span 名称用路由模板（"GET /users/:id"）而不是实际路径，否则每个 ID 一个名字，
+// 追踪后端没法按接口聚合。没有匹配到路由的请求统一叫 "GET unmatched"。
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := c.Request
+		// 读取上游的 traceparent，没有时开始一条新的链路
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+		route := c.FullPath()
+		name := req.Method + " " + route
+		if route == "" {
+			name = req.Method + " unmatched"
+		}
+		ctx, span := tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(req.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(req.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+			),
+		)
+		defer span.End()
+
+		if sc := span.SpanContext(); sc.HasTraceID() {
+			c.Header(TraceIDHeader, sc.TraceID().String())
+		}
+		c.Request = req.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		// 4xx 是客户端的问题，server span 只把 5xx 标为错误
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+	}
+}
+
+// Transport 为出站请求创建 client span，并把 traceparent 写入请求头
+// base 为 nil 时使用 http.DefaultTransport；span 在收到响应头时结束，不含读取响应体的时间
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := tracer().Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(redactURL(req)),
+			semconv.ServerAddress(req.URL.Hostname()),
+		),
+	)
+
+	// RoundTripper 不能修改调用方的请求，复制一份再写请求头
+	out := req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(out.Header))
+
+	resp, err := t.base.RoundTrip(out)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	// 对调用方来说 4xx 也是失败
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", resp.StatusCode))
+	}
+	span.End()
+	return resp, nil
+}
+
+// redactURL 去掉 URL 中的用户名密码和查询参数（可能带 token）
+func redactURL(req *http.Request) string {
+	u := *req.URL
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
@@ -0,0 +1,149 @@
+// ============================================================================
+// Package tracing OpenTelemetry 链路追踪：HTTP 入口、GORM、出站 HTTP 请求
+// ============================================================================
+//
+// 【一条链路长什么样】
+//
+//	service A                                     service B
+//	GET /orders/:id ─────────────────────────┐    GET /users/:id ──────────┐
+//	  ├─ gorm.query orders   (2ms)           │      └─ gorm.query users    │
+//	  └─ HTTP GET (client) ──traceparent──▶  │ ─────────────────────────── │
+//	                                         └── 同一个 trace_id ──────────┘
+//
+// | 环节        | 本包提供                   | span 名称 / 属性                              |
+// |-------------|----------------------------|-----------------------------------------------|
+// | HTTP 入口   | Middleware()               | "GET /users/:id"，http.route、状态码          |
+// | 数据库      | db.Use(tracing.GormPlugin{}) | "gorm.query"，表名、SQL（不含参数值）、行数   |
+// | 出站请求    | Transport(base)            | "HTTP GET"，url.full、状态码，注入 traceparent |
+//
+// 【上下文怎么传】
+//
+// 进程内靠 context.Context：Middleware 把 span 放进 c.Request.Context()，
+// 之后 db.WithContext(ctx)、http.NewRequestWithContext(ctx, ...) 都会成为它的子 span。
+// 漏传 ctx（用 context.Background()）链路就会断开，变成一条新的 trace。
+//
+// 跨进程靠 W3C traceparent 请求头：Transport 发出请求时写入，对方的 Middleware 读取。
+//
+// 【用法】
+//
+//	shutdown, err := tracing.Init(ctx, tracing.Config{ServiceName: "user-service"})
+//	defer shutdown(context.Background()) // 退出前把缓冲的 span 发出去
+//
+//	r.Use(tracing.Middleware())
+//	db.Use(tracing.GormPlugin{})
+//	client := &http.Client{Transport: tracing.Transport(nil)}
+//
+// go-with-ai-one 的 httpclient 同样通过 Config.Transport 接入，每次重试都是一个单独的子 span。
+//
+// 【导出到哪里】
+//
+// OTLP/HTTP 协议，Jaeger、Tempo、OpenTelemetry Collector 都能直接接收：
+//
+//	docker run -p 16686:16686 -p 4318:4318 jaegertracing/all-in-one
+//	OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318 go run examples/4_1_gorm_integration.go
+//
+// 没有配置 Endpoint 和 OTEL_EXPORTER_OTLP_ENDPOINT 时不导出，
+// 但 span 照常创建，响应头 X-Trace-Id 仍可用于关联日志。
+//
+// ============================================================================
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation 本包创建的 tracer 名称
+const instrumentation = "go-one/tracing"
+
+// Config 追踪配置
+type Config struct {
+	// ServiceName 服务名，链路图上每个节点的名字，必填
+	ServiceName string
+
+	// ServiceVersion 服务版本，可选
+	ServiceVersion string
+
+	// Endpoint OTLP/HTTP 地址，如 "http://localhost:4318"
+	// 为空时读取环境变量 OTEL_EXPORTER_OTLP_ENDPOINT，都没有则不导出
+	Endpoint string
+
+	// SampleRatio 采样比例 (0, 1]，默认 1（全部采样）
+	// 上游已经决定采样的请求（traceparent 的 sampled 标志）跟随上游，保证一条链路要么完整要么没有
+	SampleRatio float64
+
+	// Exporter 自定义导出器，设置后忽略 Endpoint；测试用 tracetest.NewInMemoryExporter()
+	Exporter sdktrace.SpanExporter
+}
+
+// Init 创建 TracerProvider 并设置为全局，返回退出时调用的 shutdown
+//
+// shutdown 会把缓冲中的 span 导出，调用方应在关闭数据库等资源之前调用。
+func Init(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	if cfg.ServiceName == "" {
+		panic("tracing: ServiceName is required")
+	}
+	if cfg.SampleRatio <= 0 || cfg.SampleRatio > 1 {
+		cfg.SampleRatio = 1
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(cfg.ServiceVersion),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: resource: %w", err)
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	}
+	exporter := cfg.Exporter
+	if exporter == nil && (cfg.Endpoint != "" || os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "") {
+		var otlpOpts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			otlpOpts = append(otlpOpts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		if exporter, err = otlptracehttp.New(ctx, otlpOpts...); err != nil {
+			return nil, fmt.Errorf("tracing: otlp exporter: %w", err)
+		}
+	}
+	if exporter != nil {
+		// 批量异步导出，导出慢或失败不影响请求
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, // traceparent / tracestate
+		propagation.Baggage{},
+	))
+	return tp.Shutdown, nil
+}
+
+// tracer 每次从全局 provider 取，Init 在中间件创建之后调用也能生效
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// TraceID 返回 ctx 中当前 span 的 trace ID，没有时返回空字符串
+// 写进日志后，可以从日志直接跳到链路详情
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
@@ -0,0 +1,231 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setup 用内存导出器初始化全局 provider，返回读取已结束 span 的函数
+func setup(t *testing.T) func() tracetest.SpanStubs {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown, err := Init(context.Background(), Config{ServiceName: "test", Exporter: exporter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = shutdown(context.Background()) })
+	return func() tracetest.SpanStubs {
+		// WithBatcher 异步导出，ForceFlush 后才能读到
+		_ = otel.GetTracerProvider().(*sdktrace.TracerProvider).ForceFlush(context.Background())
+		return exporter.GetSpans()
+	}
+}
+
+// find 返回最后一个同名 span
+func find(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := len(spans) - 1; i >= 0; i-- {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func attr(s *tracetest.SpanStub, key string) attribute.Value {
+	for _, kv := range s.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		path       string
+		header     string // traceparent
+		wantSpan   string
+		wantStatus codes.Code
+		wantTrace  string // 非空时要求继承上游的 trace ID
+	}{
+		{"route template as name", "/users/42", "", "GET /users/:id", codes.Unset, ""},
+		{"5xx marked as error", "/fail", "", "GET /fail", codes.Error, ""},
+		{"4xx not an error", "/users/0", "", "GET /users/:id", codes.Unset, ""},
+		{"unmatched route", "/nope", "", "GET unmatched", codes.Unset, ""},
+		{
+			"continues upstream trace", "/users/1",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"GET /users/:id", codes.Unset, "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := setup(t)
+			r := gin.New()
+			r.Use(Middleware())
+			r.GET("/users/:id", func(c *gin.Context) {
+				if c.Param("id") == "0" {
+					c.Status(http.StatusNotFound)
+					return
+				}
+				c.String(http.StatusOK, TraceID(c.Request.Context()))
+			})
+			r.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("traceparent", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			s := find(spans(), tt.wantSpan)
+			if s == nil {
+				t.Fatalf("span %q not found", tt.wantSpan)
+			}
+			if s.SpanKind != trace.SpanKindServer || s.Status.Code != tt.wantStatus {
+				t.Errorf("kind = %v, status = %v; want server, %v", s.SpanKind, s.Status.Code, tt.wantStatus)
+			}
+			if got := attr(s, "http.response.status_code").AsInt64(); got != int64(rec.Code) {
+				t.Errorf("status attribute = %d; want %d", got, rec.Code)
+			}
+			traceID := s.SpanContext.TraceID().String()
+			if rec.Header().Get(TraceIDHeader) != traceID {
+				t.Errorf("%s = %q; want %q", TraceIDHeader, rec.Header().Get(TraceIDHeader), traceID)
+			}
+			if tt.wantTrace != "" && (traceID != tt.wantTrace || !s.Parent.IsRemote()) {
+				t.Errorf("trace = %s, parent remote = %v; want child of %s", traceID, s.Parent.IsRemote(), tt.wantTrace)
+			}
+		})
+	}
+}
+
+func TestTransportPropagation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	spans := setup(t)
+
+	// 下游服务
+	downstream := gin.New()
+	downstream.Use(Middleware())
+	downstream.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	srvB := httptest.NewServer(downstream)
+	defer srvB.Close()
+
+	// 上游服务：处理请求时调用下游
+	client := &http.Client{Transport: Transport(nil)}
+	upstream := gin.New()
+	upstream.Use(Middleware())
+	upstream.GET("/orders/:id", func(c *gin.Context) {
+		req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, srvB.URL+"/users/7?token=secret", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			c.Status(http.StatusBadGateway)
+			return
+		}
+		resp.Body.Close()
+		if req.Header.Get("traceparent") != "" {
+			t.Error("Transport must not modify the caller's request")
+		}
+		c.Status(http.StatusOK)
+	})
+	upstream.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+
+	got := spans()
+	order, call, user := find(got, "GET /orders/:id"), find(got, "HTTP GET"), find(got, "GET /users/:id")
+	if order == nil || call == nil || user == nil {
+		t.Fatalf("spans = %v", names(got))
+	}
+	// 三个 span 在同一条链路上：order → client call → user
+	if call.Parent.SpanID() != order.SpanContext.SpanID() || user.Parent.SpanID() != call.SpanContext.SpanID() {
+		t.Error("spans are not linked parent → child")
+	}
+	if user.SpanContext.TraceID() != order.SpanContext.TraceID() {
+		t.Error("downstream span has a different trace ID")
+	}
+	if u := attr(call, "url.full").AsString(); strings.Contains(u, "secret") {
+		t.Errorf("url.full = %q; query should be redacted", u)
+	}
+}
+
+func TestGormPlugin(t *testing.T) {
+	spans := setup(t)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	type Item struct {
+		ID   uint
+		Name string
+	}
+	_ = db.AutoMigrate(&Item{})
+
+	ctx, parent := tracer().Start(context.Background(), "request")
+	db.WithContext(ctx).Create(&Item{Name: "a"})
+	var item Item
+	db.WithContext(ctx).Where("name = ?", "secret-value").First(&item) // not found
+	db.WithContext(ctx).Exec("SELECT * FROM missing_table")
+	parent.End()
+
+	got := spans()
+	tests := []struct {
+		name       string
+		wantStatus codes.Code
+		wantTable  string
+	}{
+		{"gorm.create", codes.Unset, "items"},
+		{"gorm.query", codes.Unset, "items"},
+		{"gorm.raw", codes.Error, ""},
+	}
+	for _, tt := range tests {
+		s := find(got, tt.name)
+		if s == nil {
+			t.Errorf("span %q not found in %v", tt.name, names(got))
+			continue
+		}
+		if s.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s: not a child of the request span", tt.name)
+		}
+		if s.Status.Code != tt.wantStatus {
+			t.Errorf("%s: status = %v; want %v", tt.name, s.Status.Code, tt.wantStatus)
+		}
+		if table := attr(s, "db.collection.name").AsString(); table != tt.wantTable {
+			t.Errorf("%s: table = %q; want %q", tt.name, table, tt.wantTable)
+		}
+	}
+	if q := attr(find(got, "gorm.query"), "db.query.text").AsString(); !strings.Contains(q, "name = ?") {
+		t.Errorf("query text = %q; want placeholders, not values", q)
+	}
+}
+
+func TestTraceIDEmpty(t *testing.T) {
+	if id := TraceID(context.Background()); id != "" {
+		t.Errorf("TraceID = %q; want empty", id)
+	}
+}
+
+func names(spans tracetest.SpanStubs) []string {
+	var out []string
+	for _, s := range spans {
+		out = append(out, s.Name)
+	}
+	return out
+}
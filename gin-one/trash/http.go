@@ -0,0 +1,83 @@
+package trash
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/pagination"
+	"go-one/response"
+)
+
+// Register 在 group 上注册回收站接口，调用方负责加管理员权限中间件
+//
+//	GET    /trash?page=1&page_size=10  回收站列表
+//	POST   /:id/restore                恢复
+//	DELETE /:id/purge                  彻底删除
+//
+// group 上已有 GET /:id 也没关系，gin 优先匹配静态路径 /trash。
+func Register[T any](group *gin.RouterGroup, b *Bin[T]) {
+	group.GET("/trash", func(c *gin.Context) {
+		req, err := pagination.FromQuery(c)
+		if err != nil || req.Mode != pagination.ModeOffset {
+			response.Error(c, http.StatusBadRequest, "invalid_pagination", "回收站只支持 page / page_size 分页")
+			return
+		}
+		rows, total, err := b.List(c.Request.Context(), req.Offset(), req.Size)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "查询回收站失败")
+			return
+		}
+		response.Success(c, gin.H{"items": rows, "total": total, "page": req.Page, "size": req.Size})
+	})
+
+	group.POST("/:id/restore", func(c *gin.Context) {
+		id, ok := recordID(c)
+		if !ok {
+			return
+		}
+		row, err := b.Restore(c.Request.Context(), id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, row)
+	})
+
+	group.DELETE("/:id/purge", func(c *gin.Context) {
+		id, ok := recordID(c)
+		if !ok {
+			return
+		}
+		if err := b.Purge(c.Request.Context(), id); err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, gin.H{"id": id, "purged": true})
+	})
+}
+
+func recordID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		response.Error(c, http.StatusBadRequest, "invalid_id", "ID 不合法")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// abort 按错误类型选择状态码，钩子返回的其他错误按 500 处理
+func abort(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		response.Error(c, http.StatusNotFound, "not_in_trash", "回收站中没有该记录")
+	case errors.Is(err, ErrConflict):
+		response.Error(c, http.StatusConflict, "restore_conflict", "已存在相同唯一键的记录，无法恢复")
+	case errors.Is(err, ErrInUse):
+		response.Error(c, http.StatusConflict, "in_use", "记录仍被其他数据引用，无法彻底删除")
+	default:
+		response.Error(c, http.StatusInternalServerError, "internal_error", "操作失败")
+	}
+}
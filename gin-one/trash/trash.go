@@ -0,0 +1,139 @@
+// ============================================================================
+// Package trash 回收站：查看、恢复、彻底删除软删除的记录
+// ============================================================================
+//
+// 【软删除回顾】
+//
+// 嵌入 gorm.Model 的模型调用 Delete 时只设置 deleted_at，
+// 之后所有查询自动加 WHERE deleted_at IS NULL，记录"看不见"但还在表里。
+// 要操作这些记录必须用 Unscoped：
+//
+// | 操作     | SQL                                                     | 方法        |
+// |----------|---------------------------------------------------------|-------------|
+// | 回收站   | SELECT ... WHERE deleted_at IS NOT NULL                 | Bin.List    |
+// | 恢复     | UPDATE ... SET deleted_at = NULL WHERE id = ?           | Bin.Restore |
+// | 彻底删除 | DELETE FROM ... WHERE id = ? AND deleted_at IS NOT NULL | Bin.Purge   |
+//
+// 恢复和彻底删除都只作用于已在回收站里的记录：
+// 不能用 purge 接口绕过软删除直接物理删除正常数据。
+//
+// 【用法】
+//
+//	users := trash.New[model.User](db, trash.Config[model.User]{})
+//	trash.Register(r.Group("/users"), users)
+//	// GET /users/trash  POST /users/:id/restore  DELETE /users/:id/purge
+//
+// 模型需要有 id 主键和 deleted_at 列（嵌入 gorm.Model 即可）。
+// 通过模型执行更新和删除，audit.Plugin 会照常记录审计日志。
+//
+// ============================================================================
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// 错误定义
+var (
+	ErrNotFound = errors.New("trash: record not found in trash")
+	ErrConflict = errors.New("trash: restore conflicts with an existing record")
+	ErrInUse    = errors.New("trash: record is still referenced")
+)
+
+// Config 回收站配置，钩子和恢复 / 删除在同一个事务里执行，返回错误即取消操作
+type Config[T any] struct {
+	// BeforeRestore 恢复前调用，可以顺带修改其他字段（如状态改回 active）
+	BeforeRestore func(tx *gorm.DB, row *T) error
+	// BeforePurge 彻底删除前调用，通常用来检查是否还被引用，被引用时返回 ErrInUse
+	BeforePurge func(tx *gorm.DB, row *T) error
+}
+
+// Bin 某个模型的回收站
+type Bin[T any] struct {
+	db  *gorm.DB
+	cfg Config[T]
+}
+
+// New 创建回收站
+func New[T any](db *gorm.DB, cfg Config[T]) *Bin[T] {
+	return &Bin[T]{db: db, cfg: cfg}
+}
+
+func trashed(db *gorm.DB) *gorm.DB {
+	return db.Unscoped().Where("deleted_at IS NOT NULL")
+}
+
+// List 回收站列表，最近删除的在前，返回当前页和总数
+func (b *Bin[T]) List(ctx context.Context, offset, limit int) ([]T, int64, error) {
+	db := trashed(b.db.WithContext(ctx).Model(new(T)))
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	rows := []T{}
+	err := db.Order("deleted_at DESC, id DESC").Offset(offset).Limit(limit).Find(&rows).Error
+	return rows, total, err
+}
+
+// take 在 tx 中取出回收站里的记录
+func take[T any](tx *gorm.DB, id uint) (*T, error) {
+	row := new(T)
+	err := trashed(tx).Where("id = ?", id).Take(row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return row, err
+}
+
+// Restore 恢复记录，返回恢复后的数据
+func (b *Bin[T]) Restore(ctx context.Context, id uint) (*T, error) {
+	var row *T
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if row, err = take[T](tx, id); err != nil {
+			return err
+		}
+		if b.cfg.BeforeRestore != nil {
+			if err := b.cfg.BeforeRestore(tx, row); err != nil {
+				return err
+			}
+		}
+		// 必须 Unscoped，否则条件里带 deleted_at IS NULL，一行也更新不到
+		err = tx.Unscoped().Model(row).Update("deleted_at", nil).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrConflict
+		}
+		if err != nil {
+			return fmt.Errorf("restore %d: %w", id, err)
+		}
+		return tx.Where("id = ?", id).Take(row).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// Purge 彻底删除回收站里的记录，不可恢复
+func (b *Bin[T]) Purge(ctx context.Context, id uint) error {
+	return b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		row, err := take[T](tx, id)
+		if err != nil {
+			return err
+		}
+		if b.cfg.BeforePurge != nil {
+			if err := b.cfg.BeforePurge(tx, row); err != nil {
+				return err
+			}
+		}
+		err = tx.Unscoped().Delete(row).Error
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return ErrInUse
+		}
+		return err
+	})
+}
@@ -0,0 +1,164 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/internal/testdb"
+)
+
+type author struct {
+	gorm.Model
+	Name   string `gorm:"uniqueIndex"`
+	Status string
+	Books  []book
+}
+
+type book struct {
+	ID       uint
+	AuthorID uint
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.ForeignKeys(t, &author{}, &book{})
+}
+
+// seed 创建 alice(1)、bob(2，有一本书)、carol(3)，并软删除 bob 和 carol
+func seed(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	for _, name := range []string{"alice", "bob", "carol"} {
+		db.Create(&author{Name: name, Status: "active"})
+	}
+	db.Create(&book{AuthorID: 2})
+	db.Model(&author{}).Where("id IN ?", []uint{2, 3}).Update("status", "deleted")
+	db.Delete(&author{}, []uint{2, 3})
+}
+
+func TestList(t *testing.T) {
+	db := newTestDB(t)
+	seed(t, db)
+	bin := New(db, Config[author]{})
+
+	rows, total, err := bin.List(context.Background(), 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(rows) != 1 {
+		t.Errorf("List = %d rows, total %d; want 1, 2", len(rows), total)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	db := newTestDB(t)
+	seed(t, db)
+	bin := New(db, Config[author]{
+		BeforeRestore: func(tx *gorm.DB, a *author) error {
+			return tx.Unscoped().Model(a).Update("status", "active").Error
+		},
+	})
+
+	tests := []struct {
+		name    string
+		id      uint
+		wantErr error
+	}{
+		{"trashed", 3, nil},
+		{"already restored", 3, ErrNotFound},
+		{"not deleted", 1, ErrNotFound},
+		{"missing", 99, ErrNotFound},
+	}
+	for _, tt := range tests {
+		got, err := bin.Restore(context.Background(), tt.id)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Restore(%d) error = %v; want %v", tt.name, tt.id, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (got.DeletedAt.Valid || got.Status != "active") {
+			t.Errorf("%s: Restore(%d) = %+v; want undeleted and active", tt.name, tt.id, got)
+		}
+	}
+
+	var count int64
+	db.Model(&author{}).Count(&count)
+	if count != 2 {
+		t.Errorf("visible authors = %d; want 2", count)
+	}
+}
+
+func TestRestoreHookError(t *testing.T) {
+	db := newTestDB(t)
+	seed(t, db)
+	refuse := errors.New("refused")
+	bin := New(db, Config[author]{
+		BeforeRestore: func(*gorm.DB, *author) error { return refuse },
+	})
+	if _, err := bin.Restore(context.Background(), 3); !errors.Is(err, refuse) {
+		t.Errorf("Restore error = %v; want %v", err, refuse)
+	}
+	if _, err := take[author](db, 3); err != nil {
+		t.Errorf("record left trash after refused restore: %v", err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	db := newTestDB(t)
+	seed(t, db)
+	bin := New(db, Config[author]{})
+
+	tests := []struct {
+		name    string
+		id      uint
+		wantErr error
+	}{
+		{"not deleted", 1, ErrNotFound},
+		{"referenced by foreign key", 2, ErrInUse},
+		{"trashed", 3, nil},
+		{"already purged", 3, ErrNotFound},
+	}
+	for _, tt := range tests {
+		if err := bin.Purge(context.Background(), tt.id); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Purge(%d) error = %v; want %v", tt.name, tt.id, err, tt.wantErr)
+		}
+	}
+
+	var count int64
+	db.Unscoped().Model(&author{}).Count(&count)
+	if count != 2 {
+		t.Errorf("authors left = %d; want 2", count)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+	seed(t, db)
+	r := gin.New()
+	Register(r.Group("/authors"), New(db, Config[author]{}))
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/authors/trash?page=1&page_size=1", http.StatusOK},
+		{"GET", "/authors/trash?cursor=", http.StatusBadRequest},
+		{"POST", "/authors/abc/restore", http.StatusBadRequest},
+		{"POST", "/authors/1/restore", http.StatusNotFound},
+		{"POST", "/authors/3/restore", http.StatusOK},
+		{"DELETE", "/authors/2/purge", http.StatusConflict},
+		{"DELETE", "/authors/3/purge", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s = %d; want %d", tt.method, tt.path, w.Code, tt.want)
+		}
+	}
+}
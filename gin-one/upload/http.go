@@ -0,0 +1,110 @@
+package upload
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+)
+
+// sessionView 接口返回的会话，附带缺失的分片
+type sessionView struct {
+	*Session
+	Missing []int `json:"missing"`
+}
+
+func view(s *Session) sessionView {
+	return sessionView{Session: s, Missing: s.Missing()}
+}
+
+// Register 在 group 上注册分片上传接口，通常挂在 /upload/sessions
+//
+//	POST   ""                 创建会话
+//	GET    /:id               查询进度
+//	PUT    /:id/chunks/:n     上传分片
+//	POST   /:id/complete      合并并校验
+//	DELETE /:id               放弃上传
+func Register(group *gin.RouterGroup, m *Manager) {
+	group.POST("", func(c *gin.Context) {
+		var req CreateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		s, err := m.Create(c.Request.Context(), req)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, response.Response{Code: response.CodeOK, Message: "success", Data: view(s)})
+	})
+
+	group.GET("/:id", func(c *gin.Context) {
+		s, err := m.Get(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, view(s))
+	})
+
+	group.PUT("/:id/chunks/:n", func(c *gin.Context) {
+		n, err := strconv.Atoi(c.Param("n"))
+		if err != nil {
+			abort(c, ErrChunkOutOfRange)
+			return
+		}
+		s, err := m.PutChunk(c.Request.Context(), c.Param("id"), n, c.Request.Body)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, gin.H{"chunk": n, "sha256": s.Chunks[n], "missing": s.Missing()})
+	})
+
+	group.POST("/:id/complete", func(c *gin.Context) {
+		s, err := m.Complete(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, view(s))
+	})
+
+	group.DELETE("/:id", func(c *gin.Context) {
+		if err := m.Abort(c.Request.Context(), c.Param("id")); err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, gin.H{"id": c.Param("id"), "aborted": true})
+	})
+}
+
+// abort 按错误类型选择状态码
+func abort(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		response.Error(c, http.StatusNotFound, "session_not_found", "上传会话不存在")
+	case errors.Is(err, ErrExpired):
+		response.Error(c, http.StatusGone, "session_expired", "上传会话已过期，请重新创建")
+	case errors.Is(err, ErrCompleted):
+		response.Error(c, http.StatusConflict, "session_completed", "上传已完成")
+	case errors.Is(err, ErrTooLarge):
+		response.Error(c, http.StatusRequestEntityTooLarge, "file_too_large", "文件超过大小上限")
+	case errors.Is(err, ErrInvalidRequest):
+		response.Error(c, http.StatusBadRequest, "invalid_request", err.Error())
+	case errors.Is(err, ErrChunkOutOfRange):
+		response.Error(c, http.StatusBadRequest, "chunk_out_of_range", "分片序号超出范围")
+	case errors.Is(err, ErrChunkSize):
+		response.Error(c, http.StatusBadRequest, "chunk_size_mismatch", err.Error())
+	case errors.Is(err, ErrIncomplete):
+		response.Error(c, http.StatusConflict, "missing_chunks", err.Error())
+	case errors.Is(err, ErrChecksumMismatch):
+		response.Error(c, http.StatusUnprocessableEntity, "checksum_mismatch", "文件 SHA-256 与声明的不一致")
+	default:
+		response.Error(c, http.StatusInternalServerError, "internal_error", "上传失败")
+	}
+}
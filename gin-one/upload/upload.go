@@ -0,0 +1,452 @@
+// ============================================================================
+// Package upload 分片上传与断点续传
+// ============================================================================
+//
+// 【为什么要分片？】
+//
+// 几百 MB 的文件一次 POST 上传，网络断一下就得从头再来；
+// 分片上传把文件切成固定大小的块逐个上传，中断后只补传缺失的块。
+//
+//	POST   /upload/sessions                     创建会话 {filename, size, sha256}
+//	PUT    /upload/sessions/:id/chunks/:n       上传第 n 块（从 0 开始），请求体是原始字节
+//	GET    /upload/sessions/:id                 查询进度，missing 列出还没传的块
+//	POST   /upload/sessions/:id/complete        合并分片并校验 SHA-256
+//	DELETE /upload/sessions/:id                 放弃上传
+//
+// 【状态保存在哪？】
+//
+// 会话信息和分片都写进 storage.Blob，不依赖进程内存：
+//
+// | key                              | 内容                  |
+// |----------------------------------|-----------------------|
+// | <Prefix>/<id>/session.json       | Session（JSON）       |
+// | <Prefix>/<id>/chunks/<n>         | 第 n 块原始字节       |
+// | <KeyPrefix>/2006/01/02/<id><ext> | 合并后的文件          |
+//
+// 进程重启、换一台实例（S3 后端）都能接着传。
+// 同一个会话的状态更新在进程内加锁串行化；多实例同时写同一个会话没有保护，
+// 客户端应该把一个会话的分片发给同一个实例，或者换成数据库保存会话。
+//
+// 【合并与校验】
+//
+// Complete 先按顺序读一遍所有分片计算 SHA-256，和创建会话时声明的值比较，
+// 一致后再读第二遍写入最终 key：校验失败时最终 key 上不会出现错误的内容。
+// 每个分片上传时也会记录自己的 SHA-256，客户端重传前可以先比较，跳过已经传对的块。
+//
+// 【过期清理】
+//
+// Blob 接口没有列举功能，过期会话的分片不会被主动删除；
+// S3 / MinIO 给 Prefix 配置生命周期规则（如 2 天后删除）即可。
+//
+// ============================================================================
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-one/pools"
+	"go-one/storage"
+)
+
+// 错误定义
+var (
+	ErrNotFound         = errors.New("upload: session not found")
+	ErrExpired          = errors.New("upload: session expired")
+	ErrCompleted        = errors.New("upload: session already completed")
+	ErrTooLarge         = errors.New("upload: file too large")
+	ErrInvalidRequest   = errors.New("upload: invalid request")
+	ErrChunkOutOfRange  = errors.New("upload: chunk index out of range")
+	ErrChunkSize        = errors.New("upload: chunk size mismatch")
+	ErrIncomplete       = errors.New("upload: missing chunks")
+	ErrChecksumMismatch = errors.New("upload: sha256 mismatch")
+)
+
+// Config 分片上传配置
+type Config struct {
+	ChunkSize int64         // 分片大小，默认 5MB；最后一块可以更小
+	MaxSize   int64         // 单个文件上限，默认 5GB
+	TTL       time.Duration // 会话有效期，从创建时算起，默认 24h
+	Prefix    string        // 会话和分片的 key 前缀，默认 upload-sessions
+	KeyPrefix string        // 合并后文件的 key 前缀，默认 files
+}
+
+// Session 上传会话，保存为 <Prefix>/<id>/session.json
+type Session struct {
+	ID          string    `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type,omitempty"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	ChunkSize   int64     `json:"chunk_size"`
+	TotalChunks int       `json:"total_chunks"`
+	Chunks      []string  `json:"chunks"` // 每块的 SHA-256，空字符串表示还没上传
+	Key         string    `json:"key"`    // 合并后文件的 key
+	Completed   bool      `json:"completed"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Missing 还没上传的分片序号
+func (s *Session) Missing() []int {
+	missing := []int{}
+	for i, sum := range s.Chunks {
+		if sum == "" {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// chunkLen 第 n 块应有的字节数
+func (s *Session) chunkLen(n int) int64 {
+	if n == s.TotalChunks-1 {
+		return s.Size - int64(n)*s.ChunkSize
+	}
+	return s.ChunkSize
+}
+
+// CreateRequest 创建会话的参数
+type CreateRequest struct {
+	Filename    string `json:"filename" binding:"required"`
+	Size        int64  `json:"size" binding:"required,gt=0"`
+	SHA256      string `json:"sha256" binding:"required,len=64,hexadecimal"`
+	ContentType string `json:"content_type"`
+}
+
+// Manager 管理上传会话
+type Manager struct {
+	blob storage.Blob
+	cfg  Config
+	now  func() time.Time
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex // 按会话加锁，不同会话互不影响
+}
+
+// NewManager 创建管理器，会话和分片都保存在 blob 中
+func NewManager(blob storage.Blob, cfg Config) *Manager {
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = 5 << 20
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 5 << 30
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "upload-sessions"
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "files"
+	}
+	return &Manager{blob: blob, cfg: cfg, now: time.Now, locks: make(map[string]*sync.Mutex)}
+}
+
+// Config 返回生效的配置（已填充默认值）
+func (m *Manager) Config() Config {
+	return m.cfg
+}
+
+func (m *Manager) sessionKey(id string) string {
+	return path.Join(m.cfg.Prefix, id, "session.json")
+}
+
+func (m *Manager) chunkKey(id string, n int) string {
+	return path.Join(m.cfg.Prefix, id, "chunks", strconv.Itoa(n))
+}
+
+// lock 锁住一个会话，返回解锁函数
+func (m *Manager) lock(id string) func() {
+	m.mu.Lock()
+	l, ok := m.locks[id]
+	if !ok {
+		l = &sync.Mutex{}
+		m.locks[id] = l
+	}
+	m.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// Create 创建上传会话
+func (m *Manager) Create(ctx context.Context, req CreateRequest) (*Session, error) {
+	if req.Size <= 0 || req.Filename == "" {
+		return nil, ErrInvalidRequest
+	}
+	if req.Size > m.cfg.MaxSize {
+		return nil, ErrTooLarge
+	}
+	sum, err := hex.DecodeString(req.SHA256)
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("%w: sha256 must be 64 hex characters", ErrInvalidRequest)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := m.now()
+	total := int((req.Size + m.cfg.ChunkSize - 1) / m.cfg.ChunkSize)
+	s := &Session{
+		ID:          id,
+		Filename:    path.Base(strings.ReplaceAll(req.Filename, "\\", "/")),
+		ContentType: req.ContentType,
+		Size:        req.Size,
+		SHA256:      strings.ToLower(req.SHA256),
+		ChunkSize:   m.cfg.ChunkSize,
+		TotalChunks: total,
+		Chunks:      make([]string, total),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(m.cfg.TTL),
+	}
+	s.Key = path.Join(m.cfg.KeyPrefix, now.Format("2006/01/02"), id+safeExt(s.Filename))
+
+	if err := m.save(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get 读取会话，用于断点续传时查询缺失的分片
+func (m *Manager) Get(ctx context.Context, id string) (*Session, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	rc, err := m.blob.Get(ctx, m.sessionKey(id))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var s Session
+	if err := json.NewDecoder(rc).Decode(&s); err != nil {
+		return nil, fmt.Errorf("upload: decode session %s: %w", id, err)
+	}
+	return &s, nil
+}
+
+// active 读取未过期、未完成的会话
+func (m *Manager) active(ctx context.Context, id string) (*Session, error) {
+	s, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Completed {
+		return s, ErrCompleted
+	}
+	if m.now().After(s.ExpiresAt) {
+		return s, ErrExpired
+	}
+	return s, nil
+}
+
+func (m *Manager) save(ctx context.Context, s *Session) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return m.blob.Put(ctx, m.sessionKey(s.ID), bytes.NewReader(b), "application/json")
+}
+
+// PutChunk 上传第 n 块，重复上传同一块会覆盖
+//
+// 分片最多 ChunkSize 字节，整块读进内存后校验长度再写入存储，
+// 长度不对的分片不会覆盖已经传好的内容。
+func (m *Manager) PutChunk(ctx context.Context, id string, n int, r io.Reader) (*Session, error) {
+	s, err := m.active(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n >= s.TotalChunks {
+		return nil, ErrChunkOutOfRange
+	}
+
+	want := s.chunkLen(n)
+	data, err := io.ReadAll(io.LimitReader(r, want+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != want {
+		return nil, fmt.Errorf("%w: chunk %d has %d bytes, want %d", ErrChunkSize, n, len(data), want)
+	}
+	if err := m.blob.Put(ctx, m.chunkKey(id, n), bytes.NewReader(data), "application/octet-stream"); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+
+	// 写分片可以并发，更新会话要串行：重新读一次，避免覆盖其他分片的记录
+	defer m.lock(id)()
+	if s, err = m.active(ctx, id); err != nil {
+		return nil, err
+	}
+	s.Chunks[n] = hex.EncodeToString(sum[:])
+	if err := m.save(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Complete 合并分片，整体 SHA-256 与创建时声明的一致才写入最终 key
+//
+// 已经完成的会话再次调用直接返回，客户端没收到响应时可以放心重试。
+func (m *Manager) Complete(ctx context.Context, id string) (*Session, error) {
+	defer m.lock(id)()
+
+	s, err := m.active(ctx, id)
+	if errors.Is(err, ErrCompleted) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if missing := s.Missing(); len(missing) > 0 {
+		return s, fmt.Errorf("%w: %v", ErrIncomplete, missing)
+	}
+
+	// 第一遍：校验
+	h := sha256.New()
+	src := m.chunks(ctx, s)
+	_, err = pools.Copy(h, src)
+	src.Close()
+	if err != nil {
+		return nil, err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != s.SHA256 {
+		return s, fmt.Errorf("%w: got %s", ErrChecksumMismatch, got)
+	}
+
+	// 第二遍：写入最终 key
+	src = m.chunks(ctx, s)
+	err = m.blob.Put(ctx, s.Key, src, s.ContentType)
+	src.Close()
+	if err != nil {
+		return nil, err
+	}
+	s.Completed = true
+	if err := m.save(ctx, s); err != nil {
+		return nil, err
+	}
+	m.deleteChunks(ctx, s)
+	return s, nil
+}
+
+// Abort 放弃上传，删除分片和会话
+func (m *Manager) Abort(ctx context.Context, id string) error {
+	defer m.lock(id)()
+
+	s, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	m.deleteChunks(ctx, s)
+	if err := m.blob.Delete(ctx, m.sessionKey(id)); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	delete(m.locks, id)
+	m.mu.Unlock()
+	return nil
+}
+
+// deleteChunks 尽力删除分片，失败的留给存储的生命周期规则清理
+func (m *Manager) deleteChunks(ctx context.Context, s *Session) {
+	for n := range s.TotalChunks {
+		m.blob.Delete(ctx, m.chunkKey(s.ID, n))
+	}
+}
+
+// chunks 按顺序读取所有分片，读完一块再打开下一块
+func (m *Manager) chunks(ctx context.Context, s *Session) io.ReadCloser {
+	return &chunkReader{ctx: ctx, m: m, s: s}
+}
+
+type chunkReader struct {
+	ctx context.Context
+	m   *Manager
+	s   *Session
+	n   int
+	cur io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if r.n >= r.s.TotalChunks {
+				return 0, io.EOF
+			}
+			rc, err := r.m.blob.Get(r.ctx, r.m.chunkKey(r.s.ID, r.n))
+			if err != nil {
+				return 0, fmt.Errorf("upload: read chunk %d: %w", r.n, err)
+			}
+			r.cur = rc
+		}
+		n, err := r.cur.Read(p)
+		if err == io.EOF {
+			r.cur.Close()
+			r.cur = nil
+			r.n++
+			if n == 0 {
+				continue
+			}
+			return n, nil
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.cur == nil {
+		return nil
+	}
+	err := r.cur.Close()
+	r.cur = nil
+	return err
+}
+
+// safeExt 扩展名来自用户输入，只保留小写字母和数字，否则不要扩展名
+func safeExt(filename string) string {
+	ext := strings.ToLower(path.Ext(filename))
+	if len(ext) < 2 || len(ext) > 10 {
+		return ""
+	}
+	for _, r := range ext[1:] {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validID 会话 ID 会拼进 key，只接受 newID 生成的格式
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
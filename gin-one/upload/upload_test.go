@@ -0,0 +1,193 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"go-one/storage"
+)
+
+func newBlob(t *testing.T) storage.Blob {
+	t.Helper()
+	b, err := storage.NewLocal(t.TempDir(), "/files", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func sum(data []byte) string {
+	s := sha256.Sum256(data)
+	return hex.EncodeToString(s[:])
+}
+
+func readAll(t *testing.T, b storage.Blob, key string) []byte {
+	t.Helper()
+	rc, err := b.Get(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(rc)
+	return data
+}
+
+func TestUploadResume(t *testing.T) {
+	ctx := context.Background()
+	blob := newBlob(t)
+	data := []byte(strings.Repeat("0123456789", 25)) // 250 字节，分 3 块：100 100 50
+	cfg := Config{ChunkSize: 100}
+
+	m := NewManager(blob, cfg)
+	s, err := m.Create(ctx, CreateRequest{Filename: "../video.MP4", Size: int64(len(data)), SHA256: sum(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.TotalChunks != 3 || s.Filename != "video.MP4" || !strings.HasSuffix(s.Key, s.ID+".mp4") {
+		t.Fatalf("session = %+v", s)
+	}
+
+	// 乱序上传两块后"进程重启"
+	if _, err := m.PutChunk(ctx, s.ID, 2, bytes.NewReader(data[200:])); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.PutChunk(ctx, s.ID, 0, bytes.NewReader(data[:100])); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Complete(ctx, s.ID); !errors.Is(err, ErrIncomplete) {
+		t.Fatalf("Complete with missing chunk err = %v; want ErrIncomplete", err)
+	}
+
+	m = NewManager(blob, cfg)
+	got, err := m.Get(ctx, s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing := got.Missing(); len(missing) != 1 || missing[0] != 1 {
+		t.Fatalf("missing after restart = %v; want [1]", missing)
+	}
+	if got.Chunks[0] != sum(data[:100]) {
+		t.Fatalf("chunk 0 sha = %s", got.Chunks[0])
+	}
+
+	if _, err := m.PutChunk(ctx, s.ID, 1, bytes.NewReader(data[100:200])); err != nil {
+		t.Fatal(err)
+	}
+	done, err := m.Complete(ctx, s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(readAll(t, blob, done.Key), data) {
+		t.Fatal("assembled file differs from original")
+	}
+	if _, err := blob.Get(ctx, m.chunkKey(s.ID, 0)); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("chunk not deleted after complete: %v", err)
+	}
+
+	// 重复 Complete 返回同样的结果，不能再传分片
+	if again, err := m.Complete(ctx, s.ID); err != nil || again.Key != done.Key {
+		t.Fatalf("second Complete = %+v, %v", again, err)
+	}
+	if _, err := m.PutChunk(ctx, s.ID, 0, bytes.NewReader(data[:100])); !errors.Is(err, ErrCompleted) {
+		t.Fatalf("PutChunk after complete err = %v; want ErrCompleted", err)
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	ctx := context.Background()
+	data := []byte(strings.Repeat("x", 150))
+	good := CreateRequest{Filename: "a.bin", Size: 150, SHA256: sum(data)}
+
+	tests := []struct {
+		name string
+		run  func(m *Manager, id string) error
+		want error
+	}{
+		{"chunk too short", func(m *Manager, id string) error {
+			_, err := m.PutChunk(ctx, id, 0, strings.NewReader("short"))
+			return err
+		}, ErrChunkSize},
+		{"chunk too long", func(m *Manager, id string) error {
+			_, err := m.PutChunk(ctx, id, 1, bytes.NewReader(data[:100]))
+			return err
+		}, ErrChunkSize},
+		{"chunk out of range", func(m *Manager, id string) error {
+			_, err := m.PutChunk(ctx, id, 2, strings.NewReader("x"))
+			return err
+		}, ErrChunkOutOfRange},
+		{"checksum mismatch", func(m *Manager, id string) error {
+			m.PutChunk(ctx, id, 0, bytes.NewReader(data[:100]))
+			m.PutChunk(ctx, id, 1, strings.NewReader(strings.Repeat("y", 50)))
+			_, err := m.Complete(ctx, id)
+			return err
+		}, ErrChecksumMismatch},
+		{"expired", func(m *Manager, id string) error {
+			m.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+			_, err := m.PutChunk(ctx, id, 0, bytes.NewReader(data[:100]))
+			return err
+		}, ErrExpired},
+		{"aborted", func(m *Manager, id string) error {
+			if err := m.Abort(ctx, id); err != nil {
+				return err
+			}
+			_, err := m.Get(ctx, id)
+			return err
+		}, ErrNotFound},
+		{"bad id", func(m *Manager, _ string) error {
+			_, err := m.Get(ctx, "../../etc")
+			return err
+		}, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(newBlob(t), Config{ChunkSize: 100})
+			s, err := m.Create(ctx, good)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.run(m, s.ID); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v; want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	m := NewManager(newBlob(t), Config{MaxSize: 1000})
+	tests := []struct {
+		name string
+		req  CreateRequest
+		want error
+	}{
+		{"too large", CreateRequest{Filename: "a", Size: 1001, SHA256: sum(nil)}, ErrTooLarge},
+		{"bad sha", CreateRequest{Filename: "a", Size: 10, SHA256: "abc"}, ErrInvalidRequest},
+		{"empty", CreateRequest{Filename: "a", SHA256: sum(nil)}, ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		if _, err := m.Create(context.Background(), tt.req); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v; want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestSafeExt(t *testing.T) {
+	tests := map[string]string{
+		"a.JPG":             ".jpg",
+		"archive.tar.gz":    ".gz",
+		"noext":             "",
+		"evil.p%2f":         "",
+		"weird.verylongext": "",
+	}
+	for in, want := range tests {
+		if got := safeExt(in); got != want {
+			t.Errorf("safeExt(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go-one/eventbus"
+	"go-one/jobs"
+	"go-one/resilience"
+)
+
+// ============================================================================
+// Dispatcher：事件扇出到端点，任务队列负责投递和重试
+// ============================================================================
+//
+// 【重试】
+//
+// 投递失败时 handler 返回错误，由 jobs worker 按它的 Backoff / MaxBackoff 指数退避重试；
+// Delivery.Attempts 记录真正发出的次数，达到 MaxAttempts 后标记 failed，
+// 可以在修好接收方之后调用 Redeliver 重新投递。
+//
+// 熔断打开时任务推迟到冷却结束（重新入队），不算一次尝试。
+//
+// 【Guard】
+//
+// 端点熔断按端点分别计数；Config.Guard 保护的是"所有出站 Webhook"这个整体：
+// 并发上限防止大量投递占满连接和 worker，连接失败、超时计入 Guard 的熔断
+// （出口网络或代理挂了时所有端点一起失败），接收方返回的非 2xx 只算端点自己的失败。
+// Guard 拒绝时（IsRejected）投递推迟 rejectedDelay 后重新入队，同样不算一次尝试。
+//
+// ============================================================================
+
+// Config Dispatcher 配置
+type Config struct {
+	// Client 发送请求的客户端，默认 10s 超时且不跟随重定向（3xx 按失败处理）
+	Client *http.Client
+
+	// MaxAttempts 每个投递最多发送次数（含第一次），默认 8
+	MaxAttempts int
+
+	// FailureThreshold 端点连续失败多少次后熔断，默认 5
+	FailureThreshold int
+
+	// Cooldown 熔断打开的时长，之后放行一次试探投递，默认 1 分钟
+	Cooldown time.Duration
+
+	// MaxResponseBody 投递日志保存的响应体长度上限，默认 1KB
+	MaxResponseBody int
+
+	// Queue 投递任务所在的队列，默认 jobs.DefaultQueue；worker 的 Queues 要包含它
+	Queue string
+
+	// UserAgent 默认 "go-one-webhooks/1.0"
+	UserAgent string
+
+	// Logger 默认 slog.Default()
+	Logger *slog.Logger
+
+	// Guard 所有投递共用的并发上限、超时和熔断，nil 表示不限制
+	Guard *resilience.Guard
+}
+
+// rejectedDelay Guard 拒绝投递后多久再试
+const rejectedDelay = 5 * time.Second
+
+// deliverJob 投递任务的 payload，内容在 webhook_deliveries 表里
+type deliverJob struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+// Envelope 发给接收方的请求体
+type Envelope struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Dispatcher 把事件发给订阅了它的端点
+type Dispatcher struct {
+	db     *gorm.DB
+	worker *jobs.Worker
+	cfg    Config
+	task   jobs.Task[deliverJob]
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// New 创建 Dispatcher 并在 worker 上注册投递任务，必须在 worker.Run 之前调用
+// 表需要事先 AutoMigrate(&webhooks.Endpoint{}, &webhooks.Delivery{})
+func New(db *gorm.DB, worker *jobs.Worker, cfg Config) *Dispatcher {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{
+			Timeout: 10 * time.Second,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = time.Minute
+	}
+	if cfg.MaxResponseBody <= 0 {
+		cfg.MaxResponseBody = 1 << 10
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = "go-one-webhooks/1.0"
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	d := &Dispatcher{
+		db:     db,
+		worker: worker,
+		cfg:    cfg,
+		// 次数由 Delivery.Attempts 控制，任务本身留一次余量给记录结果失败的情况
+		task:   jobs.Task[deliverJob]{Name: "webhooks.deliver", Queue: cfg.Queue, MaxAttempts: cfg.MaxAttempts + 1},
+		logger: cfg.Logger,
+		now:    time.Now,
+	}
+	jobs.Handle(worker, d.task, d.deliver)
+	return d
+}
+
+// Publish 为订阅了 event 的每个启用端点创建投递记录并入队，返回投递数
+// 没有端点订阅时什么都不做
+func (d *Dispatcher) Publish(ctx context.Context, event string, data any) (int, error) {
+	id, err := newEventID()
+	if err != nil {
+		return 0, err
+	}
+	payload, err := json.Marshal(Envelope{ID: id, Type: event, CreatedAt: d.now().UTC(), Data: data})
+	if err != nil {
+		return 0, fmt.Errorf("webhooks: marshal %s: %w", event, err)
+	}
+
+	var eps []Endpoint
+	if err := d.db.WithContext(ctx).Where("active = ?", true).Find(&eps).Error; err != nil {
+		return 0, err
+	}
+	n := 0
+	err = d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, ep := range eps {
+			if !ep.Subscribed(event) {
+				continue
+			}
+			delivery := &Delivery{EndpointID: ep.ID, EventID: id, Event: event, Payload: string(payload), Status: StatusPending}
+			if err := tx.Create(delivery).Error; err != nil {
+				return err
+			}
+			if _, err := d.task.Enqueue(ctx, tx, deliverJob{DeliveryID: delivery.ID}); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		d.worker.Notify()
+	}
+	return n, nil
+}
+
+// Redeliver 把投递记录重置为 pending 并重新入队，次数从 0 开始
+// 已经成功的投递也可以重发，接收方按 X-Webhook-Id 去重
+func (d *Dispatcher) Redeliver(ctx context.Context, id uint) (*Delivery, error) {
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Delivery{}).Where("id = ?", id).Updates(map[string]any{
+			"status":     StatusPending,
+			"attempts":   0,
+			"last_error": "",
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		_, err := d.task.Enqueue(ctx, tx, deliverJob{DeliveryID: id})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.worker.Notify()
+	return GetDelivery(ctx, d.db, id)
+}
+
+// Subscribe 把事件总线上的主题转发为 Webhook，事件类型就是主题名，返回取消订阅的函数
+//
+//	webhooks.Subscribe(hooks, bus, UserCreated) // 订阅了 "user.created" 的端点会收到
+func Subscribe[T any](d *Dispatcher, bus *eventbus.Bus, topic eventbus.Topic[T]) (unsubscribe func()) {
+	opts := eventbus.Options{Name: "webhooks", Policy: eventbus.PolicyRetry}
+	return eventbus.Subscribe(bus, topic, opts, func(ctx context.Context, event T) error {
+		_, err := d.Publish(ctx, topic.Name(), event)
+		return err
+	})
+}
+
+// ============================================================================
+// 投递
+// ============================================================================
+
+// deliver 投递任务的 handler
+func (d *Dispatcher) deliver(ctx context.Context, job deliverJob) error {
+	delivery, err := GetDelivery(ctx, d.db, job.DeliveryID)
+	if errors.Is(err, ErrNotFound) {
+		return jobs.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status != StatusPending {
+		return nil // 任务重复执行，已经有结果了
+	}
+
+	ep, err := GetEndpoint(ctx, d.db, delivery.EndpointID)
+	if errors.Is(err, ErrNotFound) {
+		return d.finish(ctx, delivery, StatusFailed, "endpoint deleted")
+	}
+	if err != nil {
+		return err
+	}
+	if !ep.Active {
+		return d.finish(ctx, delivery, StatusFailed, "endpoint disabled")
+	}
+	if wait, err := d.breaker(ctx, ep); err != nil || !wait.IsZero() {
+		if err != nil {
+			return err
+		}
+		_, err := d.task.EnqueueAt(ctx, d.db, job, wait)
+		return err
+	}
+
+	start := d.now()
+	status, body, sendErr := d.send(ctx, ep, delivery)
+	if resilience.IsRejected(sendErr) {
+		_, err := d.task.EnqueueAt(ctx, d.db, job, d.now().Add(rejectedDelay))
+		return err
+	}
+	delivery.Attempts++
+	updates := map[string]any{
+		"attempts":        delivery.Attempts,
+		"response_status": status,
+		"response_body":   body,
+		"duration_ms":     d.now().Sub(start).Milliseconds(),
+		"last_error":      "",
+	}
+
+	if sendErr == nil {
+		now := d.now()
+		updates["status"] = StatusSucceeded
+		updates["delivered_at"] = &now
+		if err := d.update(ctx, delivery, updates); err != nil {
+			return err
+		}
+		return d.recordSuccess(ctx, ep)
+	}
+
+	updates["last_error"] = truncate(sendErr.Error(), 500)
+	if status == http.StatusGone {
+		// 接收方明确表示不再需要：停用端点，不再重试
+		updates["status"] = StatusFailed
+		if err := d.update(ctx, delivery, updates); err != nil {
+			return err
+		}
+		d.logger.Warn("webhooks: endpoint returned 410, disabling", "endpoint", ep.ID, "url", ep.URL)
+		return SetActive(ctx, d.db, ep.ID, false)
+	}
+	if delivery.Attempts >= d.cfg.MaxAttempts {
+		updates["status"] = StatusFailed
+	}
+	if err := d.update(ctx, delivery, updates); err != nil {
+		return err
+	}
+	if err := d.recordFailure(ctx, ep); err != nil {
+		d.logger.Error("webhooks: record endpoint failure", "endpoint", ep.ID, "error", err)
+	}
+	if delivery.Attempts >= d.cfg.MaxAttempts {
+		d.logger.Error("webhooks: delivery failed", "delivery", delivery.ID, "endpoint", ep.ID,
+			"event", delivery.Event, "attempts", delivery.Attempts, "error", sendErr)
+		return nil
+	}
+	return sendErr // worker 退避后重试
+}
+
+// send 发送请求，返回状态码和截断的响应体；非 2xx 返回错误
+func (d *Dispatcher) send(ctx context.Context, ep *Endpoint, delivery *Delivery) (int, string, error) {
+	var (
+		status  int
+		snippet []byte
+	)
+	// 只有请求本身出错（连接失败、超时）才让 Guard 记一次失败，非 2xx 在外面判断
+	err := d.guard(ctx, func(ctx context.Context) error {
+		body := []byte(delivery.Payload)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", d.cfg.UserAgent)
+		req.Header.Set(HeaderID, delivery.EventID)
+		req.Header.Set(HeaderEvent, delivery.Event)
+		req.Header.Set(HeaderSignature, Sign(ep.Secret, d.now(), body))
+
+		resp, err := d.cfg.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		status = resp.StatusCode
+		snippet, _ = io.ReadAll(io.LimitReader(resp.Body, int64(d.cfg.MaxResponseBody)))
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // 读完剩余部分以便复用连接
+		return nil
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	if status < 200 || status > 299 {
+		return status, string(snippet), fmt.Errorf("endpoint responded %d %s", status, http.StatusText(status))
+	}
+	return status, string(snippet), nil
+}
+
+// guard 有 Guard 时在它的保护下执行 fn
+func (d *Dispatcher) guard(ctx context.Context, fn func(ctx context.Context) error) error {
+	if d.cfg.Guard == nil {
+		return fn(ctx)
+	}
+	return d.cfg.Guard.Do(ctx, fn)
+}
+
+// breaker 熔断判断：返回非零时间表示熔断打开，投递推迟到该时间
+//
+// 冷却结束后用条件更新把冷却时间再延长一轮，抢到的投递作为试探发出，
+// 其他投递看到的仍是打开状态，不会在端点恢复前一起涌过去。
+func (d *Dispatcher) breaker(ctx context.Context, ep *Endpoint) (time.Time, error) {
+	if ep.DisabledUntil == nil {
+		return time.Time{}, nil
+	}
+	now := d.now()
+	if now.Before(*ep.DisabledUntil) {
+		return *ep.DisabledUntil, nil
+	}
+	next := now.Add(d.cfg.Cooldown)
+	res := d.db.WithContext(ctx).Model(&Endpoint{}).
+		Where("id = ? AND disabled_until = ?", ep.ID, *ep.DisabledUntil).
+		Update("disabled_until", next)
+	if res.Error != nil {
+		return time.Time{}, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return next, nil // 别的投递正在试探
+	}
+	return time.Time{}, nil
+}
+
+func (d *Dispatcher) recordSuccess(ctx context.Context, ep *Endpoint) error {
+	if ep.ConsecutiveFailures == 0 && ep.DisabledUntil == nil {
+		return nil
+	}
+	return d.db.WithContext(ctx).Model(&Endpoint{}).Where("id = ?", ep.ID).
+		Updates(map[string]any{"consecutive_failures": 0, "disabled_until": nil}).Error
+}
+
+func (d *Dispatcher) recordFailure(ctx context.Context, ep *Endpoint) error {
+	updates := map[string]any{"consecutive_failures": gorm.Expr("consecutive_failures + 1")}
+	if ep.ConsecutiveFailures+1 >= d.cfg.FailureThreshold {
+		updates["disabled_until"] = d.now().Add(d.cfg.Cooldown)
+		d.logger.Warn("webhooks: circuit open", "endpoint", ep.ID, "url", ep.URL, "until", updates["disabled_until"])
+	}
+	return d.db.WithContext(ctx).Model(&Endpoint{}).Where("id = ?", ep.ID).Updates(updates).Error
+}
+
+// finish 不发送请求直接结束投递
+func (d *Dispatcher) finish(ctx context.Context, delivery *Delivery, status, reason string) error {
+	return d.update(ctx, delivery, map[string]any{"status": status, "last_error": reason})
+}
+
+func (d *Dispatcher) update(ctx context.Context, delivery *Delivery, updates map[string]any) error {
+	// 记录结果不受请求超时影响
+	return d.db.WithContext(context.WithoutCancel(ctx)).Model(&Delivery{}).Where("id = ?", delivery.ID).Updates(updates).Error
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
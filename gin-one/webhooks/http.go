@@ -0,0 +1,197 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/pagination"
+	"go-one/response"
+)
+
+// endpointView 接口返回的端点：事件展开为数组，密钥只在创建和轮换时返回
+type endpointView struct {
+	*Endpoint
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+func viewOf(ep *Endpoint, withSecret bool) endpointView {
+	v := endpointView{Endpoint: ep, Events: ep.EventList()}
+	if withSecret {
+		v.Secret = ep.Secret
+	}
+	return v
+}
+
+type createRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Events      []string `json:"events" binding:"required"`
+	Description string   `json:"description" binding:"max=200"`
+}
+
+// Register 在 group 上注册 Webhook 管理接口，调用方负责加管理员权限中间件
+//
+//	GET    /endpoints                          端点列表
+//	POST   /endpoints                          登记端点，响应里带密钥（只返回这一次）
+//	GET    /endpoints/:id                      端点详情和熔断状态
+//	POST   /endpoints/:id/enable               启用并清除熔断状态
+//	POST   /endpoints/:id/disable              停用
+//	POST   /endpoints/:id/rotate-secret        轮换密钥
+//	DELETE /endpoints/:id                      删除
+//	GET    /deliveries?endpoint_id=&event=&status=&page=1&page_size=10   投递日志
+//	GET    /deliveries/:id                     投递详情（请求体、响应状态和响应体）
+//	POST   /deliveries/:id/redeliver           重新投递
+func Register(group *gin.RouterGroup, d *Dispatcher) {
+	db := d.db
+
+	group.GET("/endpoints", func(c *gin.Context) {
+		eps, err := ListEndpoints(c.Request.Context(), db)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "查询端点失败")
+			return
+		}
+		views := make([]endpointView, len(eps))
+		for i := range eps {
+			views[i] = viewOf(&eps[i], false)
+		}
+		response.Success(c, views)
+	})
+
+	group.POST("/endpoints", func(c *gin.Context) {
+		var req createRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		ep, err := CreateEndpoint(c.Request.Context(), db, req.URL, req.Events, req.Description)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, viewOf(ep, true))
+	})
+
+	group.GET("/endpoints/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		ep, err := GetEndpoint(c.Request.Context(), db, id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, viewOf(ep, false))
+	})
+
+	for path, active := range map[string]bool{"/endpoints/:id/enable": true, "/endpoints/:id/disable": false} {
+		group.POST(path, func(c *gin.Context) {
+			id, ok := paramID(c)
+			if !ok {
+				return
+			}
+			if err := SetActive(c.Request.Context(), db, id, active); err != nil {
+				abort(c, err)
+				return
+			}
+			response.Success(c, gin.H{"id": id, "active": active})
+		})
+	}
+
+	group.POST("/endpoints/:id/rotate-secret", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		ep, err := RotateSecret(c.Request.Context(), db, id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, viewOf(ep, true))
+	})
+
+	group.DELETE("/endpoints/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		if err := DeleteEndpoint(c.Request.Context(), db, id); err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, gin.H{"id": id, "deleted": true})
+	})
+
+	group.GET("/deliveries", func(c *gin.Context) {
+		req, err := pagination.FromQuery(c)
+		if err != nil || req.Mode != pagination.ModeOffset {
+			response.Error(c, http.StatusBadRequest, "invalid_pagination", "投递日志只支持 page / page_size 分页")
+			return
+		}
+		f := DeliveryFilter{Event: c.Query("event"), Status: c.Query("status")}
+		if s := c.Query("endpoint_id"); s != "" {
+			id, err := strconv.ParseUint(s, 10, 32)
+			if err != nil {
+				response.Error(c, http.StatusBadRequest, "invalid_id", "endpoint_id 不合法")
+				return
+			}
+			f.EndpointID = uint(id)
+		}
+		rows, total, err := ListDeliveries(c.Request.Context(), db, f, req.Offset(), req.Size)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "internal_error", "查询投递日志失败")
+			return
+		}
+		response.Success(c, gin.H{"items": rows, "total": total, "page": req.Page, "size": req.Size})
+	})
+
+	group.GET("/deliveries/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		delivery, err := GetDelivery(c.Request.Context(), db, id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, delivery)
+	})
+
+	group.POST("/deliveries/:id/redeliver", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		delivery, err := d.Redeliver(c.Request.Context(), id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response.Success(c, delivery)
+	})
+}
+
+func paramID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		response.Error(c, http.StatusBadRequest, "invalid_id", "ID 不合法")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func abort(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		response.Error(c, http.StatusNotFound, "not_found", "端点或投递记录不存在")
+	case errors.Is(err, ErrInvalidURL), errors.Is(err, ErrNoEvents):
+		response.Error(c, http.StatusBadRequest, "invalid_endpoint", err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, "internal_error", "操作失败")
+	}
+}
@@ -0,0 +1,249 @@
+// ============================================================================
+// Package inbound 接收第三方 Webhook：校验签名、防重放、解析成具体类型后分发给 handler
+// ============================================================================
+//
+// 【处理流程】
+//
+//	POST /webhooks/github
+//	  │ 读取请求体（MaxBodySize，超出 413）
+//	  ▼
+//	Provider.Verify ──失败──▶ 401（签名不对 / 时间戳超出窗口）
+//	  │ 必须先验签再解析：没验签的 JSON 不可信
+//	  ▼
+//	Provider.Identify ──▶ 事件类型 + 事件 ID
+//	  │ 没有注册该类型 ──▶ 200 ignored（返回非 2xx 对方会一直重试）
+//	  ▼
+//	Store.Claim(事件 ID) ──已处理过──▶ 200 duplicate
+//	  ▼
+//	解析成 T，调用 handler ──出错──▶ 释放事件 ID，500（对方稍后重试）
+//	  ▼
+//	200 received
+//
+// 【防重放】
+//
+// | 提供方        | 签名头                 | 时间戳             | 去重用的 ID             |
+// |---------------|------------------------|--------------------|-------------------------|
+// | GitHub        | X-Hub-Signature-256    | 无                 | X-GitHub-Delivery       |
+// | Stripe        | Stripe-Signature       | t=，超出窗口拒绝   | 请求体 id               |
+// | Standard      | X-Webhook-Signature    | t=，超出窗口拒绝   | X-Webhook-Id            |
+//
+// 时间窗口挡住"截获后很久再发"，去重挡住窗口内的重发；GitHub 签名不含时间戳，只能靠去重。
+// Standard 是本项目 webhooks 包发出的格式。
+//
+// 【用法】
+//
+//	rcv := inbound.New(inbound.Config{Provider: inbound.GitHub(secret)})
+//	inbound.On(rcv, "push", func(ctx context.Context, e inbound.Event[PushEvent]) error {
+//	    // e.Payload 已经是 PushEvent
+//	})
+//	r.POST("/webhooks/github", rcv.Handle)
+//
+// 默认的 MemoryStore 只在本进程去重，多实例部署时用 Redis 等共享存储实现 Store。
+//
+// ============================================================================
+package inbound
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/response"
+	"go-one/webhooks"
+)
+
+// 错误定义；签名相关的错误和 webhooks 包是同一个值，errors.Is 两边都能匹配
+var (
+	ErrMissingSignature = errors.New("inbound: missing signature header")
+	ErrInvalidSignature = webhooks.ErrInvalidSignature
+	ErrExpired          = webhooks.ErrExpired
+	ErrMissingEvent     = errors.New("inbound: missing event type")
+)
+
+// Event 解析后的事件，T 是 handler 声明的 payload 类型
+type Event[T any] struct {
+	ID       string      // 事件 ID，提供方没有给出时为空
+	Type     string      // 事件类型，如 "push"、"invoice.paid"
+	Provider string      // Provider.Name
+	Payload  T           // 解析后的 payload
+	Raw      []byte      // 原始请求体（已验签）
+	Header   http.Header // 原始请求头
+}
+
+// Config 接收器配置
+type Config struct {
+	// Provider 提供方的签名和事件格式，必填
+	Provider Provider
+
+	// Store 事件 ID 去重，默认 NewMemoryStore()
+	Store Store
+
+	// DedupTTL 事件 ID 保存多久，要大于对方的最长重试周期，默认 72 小时
+	DedupTTL time.Duration
+
+	// MaxBodySize 请求体上限，默认 1MB
+	MaxBodySize int64
+
+	// Logger 默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// handlerFunc 解析 payload 并调用用户的 handler
+type handlerFunc func(ctx context.Context, e Event[json.RawMessage]) error
+
+// Receiver 一个提供方的 Webhook 入口
+type Receiver struct {
+	cfg      Config
+	handlers map[string]handlerFunc
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+// New 创建接收器；Provider 没有 Verify 或 Identify 时 panic
+func New(cfg Config) *Receiver {
+	if cfg.Provider.Verify == nil || cfg.Provider.Identify == nil {
+		panic("inbound: Config.Provider must have Verify and Identify")
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.DedupTTL <= 0 {
+		cfg.DedupTTL = 72 * time.Hour
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 1 << 20
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Receiver{
+		cfg:      cfg,
+		handlers: make(map[string]handlerFunc),
+		logger:   cfg.Logger,
+		now:      time.Now,
+	}
+}
+
+// decodeError payload 和 handler 声明的类型不匹配，重试也没用，返回 400
+type decodeError struct{ err error }
+
+func (e *decodeError) Error() string { return e.err.Error() }
+func (e *decodeError) Unwrap() error { return e.err }
+
+// On 注册事件类型的 handler，必须在开始接收请求之前调用；同一类型重复注册时 panic
+//
+// handler 返回错误时接收器响应 500，对方会重试同一个事件，所以 handler 要能承受重复执行到一半的情况。
+func On[T any](r *Receiver, eventType string, fn func(ctx context.Context, e Event[T]) error) {
+	if _, ok := r.handlers[eventType]; ok {
+		panic("inbound: duplicate handler for " + eventType)
+	}
+	r.handlers[eventType] = func(ctx context.Context, raw Event[json.RawMessage]) error {
+		e := Event[T]{ID: raw.ID, Type: raw.Type, Provider: raw.Provider, Raw: raw.Raw, Header: raw.Header}
+		if err := json.Unmarshal(raw.Payload, &e.Payload); err != nil {
+			return &decodeError{fmt.Errorf("decode %s payload: %w", raw.Type, err)}
+		}
+		return fn(ctx, e)
+	}
+}
+
+// Handle gin handler：r.POST("/webhooks/github", rcv.Handle)
+func (r *Receiver) Handle(c *gin.Context) {
+	p := r.cfg.Provider
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, r.cfg.MaxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.Error(c, http.StatusRequestEntityTooLarge, "payload_too_large", "请求体过大")
+			return
+		}
+		response.Error(c, http.StatusBadRequest, "invalid_body", "读取请求体失败")
+		return
+	}
+
+	if err := p.Verify(c.Request.Header, body, r.now()); err != nil {
+		r.logger.Warn("inbound: rejected webhook", "provider", p.Name, "client_ip", c.ClientIP(), "error", err)
+		if errors.Is(err, ErrExpired) {
+			response.Error(c, http.StatusUnauthorized, "signature_expired", "签名时间戳超出允许范围")
+			return
+		}
+		response.Error(c, http.StatusUnauthorized, "invalid_signature", "签名校验失败")
+		return
+	}
+
+	typ, id, err := p.Identify(c.Request.Header, body)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid_payload", err.Error())
+		return
+	}
+	h, ok := r.handlers[typ]
+	if !ok {
+		response.Success(c, gin.H{"id": id, "type": typ, "ignored": true})
+		return
+	}
+
+	ctx := c.Request.Context()
+	key := p.Name + ":" + id
+	if id != "" {
+		claimed, err := r.cfg.Store.Claim(ctx, key, r.cfg.DedupTTL)
+		if err != nil {
+			r.logger.Error("inbound: dedup store", "provider", p.Name, "id", id, "error", err)
+			response.Error(c, http.StatusInternalServerError, "internal_error", "处理失败")
+			return
+		}
+		if !claimed {
+			response.Success(c, gin.H{"id": id, "type": typ, "duplicate": true})
+			return
+		}
+	}
+
+	payload := json.RawMessage(body)
+	if p.Payload != nil {
+		if payload, err = p.Payload(body); err != nil {
+			r.release(ctx, id, key)
+			response.Error(c, http.StatusBadRequest, "invalid_payload", err.Error())
+			return
+		}
+	}
+	err = r.call(ctx, h, Event[json.RawMessage]{ID: id, Type: typ, Provider: p.Name, Payload: payload, Raw: body, Header: c.Request.Header})
+	if err != nil {
+		r.release(ctx, id, key)
+		var decode *decodeError
+		if errors.As(err, &decode) {
+			response.Error(c, http.StatusBadRequest, "invalid_payload", err.Error())
+			return
+		}
+		r.logger.Error("inbound: handler failed", "provider", p.Name, "type", typ, "id", id, "error", err)
+		response.Error(c, http.StatusInternalServerError, "handler_failed", "处理失败，请稍后重试")
+		return
+	}
+	response.Success(c, gin.H{"id": id, "type": typ, "received": true})
+}
+
+// call 调用 handler，panic 按失败处理，让对方重试
+func (r *Receiver) call(ctx context.Context, h handlerFunc, e Event[json.RawMessage]) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			r.logger.Error("inbound: handler panic", "provider", e.Provider, "type", e.Type, "panic", v, "stack", string(debug.Stack()))
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return h(ctx, e)
+}
+
+// release 处理失败时删除去重记录，对方重试时还能再处理
+func (r *Receiver) release(ctx context.Context, id, key string) {
+	if id == "" {
+		return
+	}
+	if err := r.cfg.Store.Release(context.WithoutCancel(ctx), key); err != nil {
+		r.logger.Error("inbound: release dedup key", "key", key, "error", err)
+	}
+}
@@ -0,0 +1,188 @@
+package inbound
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-one/webhooks"
+)
+
+type push struct {
+	Ref     string `json:"ref"`
+	Commits []struct {
+		ID string `json:"id"`
+	} `json:"commits"`
+}
+
+func newRouter(rcv *Receiver) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	rcv.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := gin.New()
+	r.POST("/hook", rcv.Handle)
+	return r
+}
+
+func post(r http.Handler, body string, header map[string]string) (int, string) {
+	req := httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code, w.Body.String()
+}
+
+func githubSig(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGitHub(t *testing.T) {
+	rcv := New(Config{Provider: GitHub("s3cret")})
+	var got []Event[push]
+	fail := true
+	On(rcv, "push", func(_ context.Context, e Event[push]) error {
+		if fail {
+			fail = false
+			return errors.New("database down")
+		}
+		got = append(got, e)
+		return nil
+	})
+	r := newRouter(rcv)
+
+	body := `{"ref":"refs/heads/main","commits":[{"id":"abc"}]}`
+	headers := func(event, delivery, sig string) map[string]string {
+		return map[string]string{"X-GitHub-Event": event, "X-GitHub-Delivery": delivery, "X-Hub-Signature-256": sig}
+	}
+	valid := headers("push", "d-1", githubSig("s3cret", body))
+
+	tests := []struct {
+		name   string
+		body   string
+		header map[string]string
+		code   int
+		want   string
+	}{
+		{"missing signature", body, headers("push", "d-1", ""), 401, "invalid_signature"},
+		{"wrong secret", body, headers("push", "d-1", githubSig("other", body)), 401, "invalid_signature"},
+		{"tampered body", body + " ", valid, 401, "invalid_signature"},
+		{"handler error", body, valid, 500, "handler_failed"},
+		{"retry after error", body, valid, 200, `"received":true`},
+		{"duplicate delivery", body, valid, 200, `"duplicate":true`},
+		{"unhandled event", `{}`, headers("issues", "d-2", githubSig("s3cret", `{}`)), 200, `"ignored":true`},
+		{"wrong payload type", `{"ref":1}`, headers("push", "d-3", githubSig("s3cret", `{"ref":1}`)), 400, "invalid_payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := post(r, tt.body, tt.header)
+			if code != tt.code || !strings.Contains(resp, tt.want) {
+				t.Errorf("got %d %s, want %d %s", code, resp, tt.code, tt.want)
+			}
+		})
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("handler ran %d times, want 1", len(got))
+	}
+	if e := got[0]; e.ID != "d-1" || e.Provider != "github" || e.Payload.Ref != "refs/heads/main" || e.Payload.Commits[0].ID != "abc" {
+		t.Errorf("event = %+v", e)
+	}
+	// 解析失败的事件 ID 被释放，修好后重发可以处理
+	if ok, _ := rcv.cfg.Store.Claim(context.Background(), "github:d-3", time.Hour); !ok {
+		t.Error("d-3 still claimed after decode error")
+	}
+}
+
+type invoice struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount_paid"`
+}
+
+func TestStripe(t *testing.T) {
+	now := time.Unix(1767225600, 0)
+	rcv := New(Config{Provider: Stripe("whsec_x", 0)})
+	rcv.now = func() time.Time { return now }
+	var paid invoice
+	On(rcv, "invoice.paid", func(_ context.Context, e Event[invoice]) error {
+		paid = e.Payload
+		return nil
+	})
+	r := newRouter(rcv)
+
+	body := `{"id":"evt_1","type":"invoice.paid","data":{"object":{"id":"in_1","amount_paid":990}}}`
+	code, resp := post(r, body, map[string]string{"Stripe-Signature": webhooks.Sign("whsec_x", now.Add(-time.Minute), []byte(body))})
+	if code != 200 || paid.ID != "in_1" || paid.Amount != 990 {
+		t.Errorf("got %d %s, invoice %+v", code, resp, paid)
+	}
+
+	// 超出 5 分钟窗口的旧请求
+	old := webhooks.Sign("whsec_x", now.Add(-10*time.Minute), []byte(body))
+	if code, resp := post(r, body, map[string]string{"Stripe-Signature": old}); code != 401 || !strings.Contains(resp, "signature_expired") {
+		t.Errorf("replay got %d %s", code, resp)
+	}
+	// 签名正确但缺少 type
+	noType := `{"id":"evt_2"}`
+	if code, _ := post(r, noType, map[string]string{"Stripe-Signature": webhooks.Sign("whsec_x", now, []byte(noType))}); code != 400 {
+		t.Errorf("missing type got %d", code)
+	}
+}
+
+func TestStandardFromWebhooks(t *testing.T) {
+	rcv := New(Config{Provider: Standard("whsec_y", time.Minute), MaxBodySize: 256})
+	var data map[string]any
+	On(rcv, "user.created", func(_ context.Context, e Event[map[string]any]) error {
+		data = e.Payload
+		return nil
+	})
+	r := newRouter(rcv)
+
+	body, _ := json.Marshal(webhooks.Envelope{ID: "evt_9", Type: "user.created", Data: map[string]any{"username": "carol"}})
+	header := map[string]string{
+		webhooks.HeaderID:        "evt_9",
+		webhooks.HeaderEvent:     "user.created",
+		webhooks.HeaderSignature: webhooks.Sign("whsec_y", time.Now(), body),
+	}
+	if code, resp := post(r, string(body), header); code != 200 || data["username"] != "carol" {
+		t.Errorf("got %d %s, data %v", code, resp, data)
+	}
+	if code, _ := post(r, strings.Repeat("x", 300), header); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body got %d", code)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if ok, _ := s.Claim(ctx, "a", time.Minute); !ok {
+		t.Fatal("first claim failed")
+	}
+	if ok, _ := s.Claim(ctx, "a", time.Minute); ok {
+		t.Error("second claim succeeded")
+	}
+	now = now.Add(2 * time.Minute)
+	if ok, _ := s.Claim(ctx, "a", time.Minute); !ok {
+		t.Error("claim after ttl failed")
+	}
+	s.Release(ctx, "a")
+	if ok, _ := s.Claim(ctx, "a", time.Minute); !ok {
+		t.Error("claim after release failed")
+	}
+}
@@ -0,0 +1,134 @@
+package inbound
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-one/jsonscan"
+	"go-one/webhooks"
+)
+
+// Provider 一个提供方的签名方式和事件格式
+type Provider struct {
+	// Name 提供方名称，用于日志和去重键的前缀
+	Name string
+
+	// Verify 校验签名（和时间戳），now 为当前时间
+	Verify func(h http.Header, body []byte, now time.Time) error
+
+	// Identify 从已验签的请求中取出事件类型和事件 ID；ID 为空时不去重
+	Identify func(h http.Header, body []byte) (eventType, id string, err error)
+
+	// Payload 取出要解析成 T 的部分，nil 表示整个请求体
+	Payload func(body []byte) (json.RawMessage, error)
+}
+
+// DefaultTolerance 带时间戳的签名允许的时钟偏差
+const DefaultTolerance = 5 * time.Minute
+
+// GitHub X-Hub-Signature-256: sha256=hex(HMAC-SHA256(secret, body))
+// 事件类型来自 X-GitHub-Event，去重 ID 来自 X-GitHub-Delivery（同一次投递重发时不变）
+func GitHub(secret string) Provider {
+	return Provider{
+		Name: "github",
+		Verify: func(h http.Header, body []byte, _ time.Time) error {
+			sig, ok := strings.CutPrefix(h.Get("X-Hub-Signature-256"), "sha256=")
+			if !ok {
+				return ErrMissingSignature
+			}
+			got, err := hex.DecodeString(sig)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(body)
+			if !hmac.Equal(got, mac.Sum(nil)) {
+				return ErrInvalidSignature
+			}
+			return nil
+		},
+		Identify: func(h http.Header, _ []byte) (string, string, error) {
+			typ := h.Get("X-GitHub-Event")
+			if typ == "" {
+				return "", "", ErrMissingEvent
+			}
+			return typ, h.Get("X-GitHub-Delivery"), nil
+		},
+	}
+}
+
+// Stripe Stripe-Signature: t=<unix>,v1=hex(HMAC-SHA256(secret, "<unix>." + body))
+// 请求体是 {"id":"evt_...","type":"invoice.paid","data":{"object":{...}}}，T 对应 data.object
+func Stripe(secret string, tolerance time.Duration) Provider {
+	return timestamped("stripe", "Stripe-Signature", secret, tolerance,
+		func(_ http.Header, body []byte) (string, string, error) {
+			// 事件体可能有几十 KB，分发只需要 type 和 id，不做完整解析
+			typ, err := jsonscan.GetString(body, "type")
+			if errors.Is(err, jsonscan.ErrNotFound) || (err == nil && typ == "") {
+				return "", "", ErrMissingEvent
+			}
+			if err != nil {
+				return "", "", err
+			}
+			id, err := jsonscan.GetString(body, "id")
+			if err != nil && !errors.Is(err, jsonscan.ErrNotFound) {
+				return "", "", err
+			}
+			return typ, id, nil
+		},
+		rawField("data", "object"))
+}
+
+// Standard 本项目 webhooks 包发出的格式：签名同 Stripe，事件类型和 ID 在请求头，T 对应请求体的 data
+func Standard(secret string, tolerance time.Duration) Provider {
+	return timestamped("standard", webhooks.HeaderSignature, secret, tolerance,
+		func(h http.Header, _ []byte) (string, string, error) {
+			typ := h.Get(webhooks.HeaderEvent)
+			if typ == "" {
+				return "", "", ErrMissingEvent
+			}
+			return typ, h.Get(webhooks.HeaderID), nil
+		},
+		rawField("data"))
+}
+
+// rawField 取出 path 处的原始 JSON 交给 handler 解析；不存在时返回 nil，由解析 T 时报错
+func rawField(path ...string) func([]byte) (json.RawMessage, error) {
+	return func(body []byte) (json.RawMessage, error) {
+		raw, err := jsonscan.Get(body, path...)
+		if errors.Is(err, jsonscan.ErrNotFound) {
+			return nil, nil
+		}
+		return raw, err
+	}
+}
+
+// timestamped t=,v1= 格式的签名，校验复用 webhooks.Verify
+func timestamped(name, header, secret string, tolerance time.Duration,
+	identify func(http.Header, []byte) (string, string, error), payload func([]byte) (json.RawMessage, error)) Provider {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	return Provider{
+		Name: name,
+		Verify: func(h http.Header, body []byte, now time.Time) error {
+			sig := h.Get(header)
+			if sig == "" {
+				return ErrMissingSignature
+			}
+			err := webhooks.Verify(secret, sig, body, now, tolerance)
+			if err != nil && !errors.Is(err, ErrExpired) {
+				return ErrInvalidSignature
+			}
+			return err
+		},
+		Identify: identify,
+		Payload:  payload,
+	}
+}
@@ -0,0 +1,57 @@
+package inbound
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store 已处理事件 ID 的存储，用于去重
+type Store interface {
+	// Claim 记录 key，保存 ttl；key 已存在且未过期时返回 false
+	// 必须是原子操作：并发的两个重复请求只能有一个返回 true（Redis 用 SET NX PX）
+	Claim(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+	// Release 删除 key，handler 失败后调用，对方重试时可以再次 Claim
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryStore 进程内的 Store，只适合单实例
+type MemoryStore struct {
+	mu     sync.Mutex
+	seen   map[string]time.Time // key -> 过期时间
+	claims int
+	now    func() time.Time
+}
+
+// NewMemoryStore 创建 MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{seen: make(map[string]time.Time), now: time.Now}
+}
+
+// Claim 实现 Store；每 256 次调用顺带清理一次过期的 key
+func (s *MemoryStore) Claim(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.claims++; s.claims%256 == 0 {
+		for k, exp := range s.seen {
+			if !now.Before(exp) {
+				delete(s.seen, k)
+			}
+		}
+	}
+	if exp, ok := s.seen[key]; ok && now.Before(exp) {
+		return false, nil
+	}
+	s.seen[key] = now.Add(ttl)
+	return true, nil
+}
+
+// Release 实现 Store
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.seen, key)
+	return nil
+}
@@ -0,0 +1,365 @@
+// ============================================================================
+// Package webhooks 出站 Webhook：管理员登记接收地址和订阅的事件，领域事件签名后经任务队列投递
+// ============================================================================
+//
+// 【整体流程】
+//
+//	eventbus.Publish ──▶ Subscribe 注册的订阅者
+//	                        │ Publish：按事件类型找到订阅了它的启用端点
+//	                        ▼
+//	                 webhook_deliveries（每个端点一行）+ jobs 表（同一事务）
+//	                        │ worker 领取 "webhooks.deliver" 任务
+//	                        ▼
+//	                 POST 端点 URL（HMAC-SHA256 签名）
+//	                        ├── 2xx ──▶ succeeded，端点连续失败数清零
+//	                        ├── 其他 ──▶ 任务队列指数退避重试，用完次数后 failed
+//	                        └── 410 ──▶ 接收方明确不再需要，停用端点
+//
+// 总线是"最多一次"，但订阅者写库成功后投递就是"至少一次"：
+// 接收方要按 X-Webhook-Id 去重。
+//
+// 【签名】
+//
+//	X-Webhook-Id:        evt_4f1c...（同一事件重试时不变）
+//	X-Webhook-Event:     user.created
+//	X-Webhook-Signature: t=1767225600,v1=hex(HMAC-SHA256(secret, "1767225600." + body))
+//
+// 时间戳参与签名，接收方校验时间窗口（如 5 分钟）即可拒绝重放；
+// 格式和 Stripe 相同，Verify 是参考实现。
+//
+// 【按端点熔断】
+//
+// | 状态   | 条件                                        | 投递行为                                |
+// |--------|---------------------------------------------|-----------------------------------------|
+// | 关闭   | 连续失败 < FailureThreshold                 | 正常发送                                |
+// | 打开   | 连续失败 ≥ FailureThreshold，冷却时间内     | 不发送，任务推迟到冷却结束，不消耗次数  |
+// | 半开   | 冷却结束                                    | 放行下一次投递，成功清零，失败重新打开  |
+//
+// 一个挂掉的端点不会用满所有投递的重试次数，也不会占住 worker 等超时。
+//
+// 【安全】
+//
+// URL 由管理员填写，服务端会向它发请求（SSRF）：生产环境应只允许 https，
+// 并用 Config.Client 的 Transport 拒绝内网地址。
+//
+// ============================================================================
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 错误定义
+var (
+	ErrNotFound         = errors.New("webhooks: not found")
+	ErrInvalidURL       = errors.New("webhooks: endpoint URL must be an absolute http(s) URL")
+	ErrNoEvents         = errors.New("webhooks: endpoint must subscribe to at least one event")
+	ErrInvalidSignature = errors.New("webhooks: invalid signature")
+	ErrExpired          = errors.New("webhooks: signature timestamp outside tolerance")
+)
+
+// 请求头
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// AllEvents 订阅所有事件
+const AllEvents = "*"
+
+// 投递状态
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Endpoint 表 webhook_endpoints：一个接收地址
+type Endpoint struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	URL         string `gorm:"size:500;not null" json:"url"`
+	Secret      string `gorm:"size:100;not null" json:"-"`  // 只在创建和轮换时返回一次
+	Events      string `gorm:"size:1000;not null" json:"-"` // 逗号分隔，AllEvents 表示全部
+	Description string `gorm:"size:200" json:"description,omitempty"`
+	Active      bool   `gorm:"not null;default:true" json:"active"`
+
+	// 熔断状态
+	ConsecutiveFailures int        `gorm:"not null;default:0" json:"consecutive_failures"`
+	DisabledUntil       *time.Time `json:"disabled_until,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Endpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+// EventList 订阅的事件
+func (e Endpoint) EventList() []string {
+	if e.Events == "" {
+		return nil
+	}
+	return strings.Split(e.Events, ",")
+}
+
+// Subscribed 端点是否订阅了事件 event
+func (e Endpoint) Subscribed(event string) bool {
+	events := e.EventList()
+	return slices.Contains(events, AllEvents) || slices.Contains(events, event)
+}
+
+// Delivery 表 webhook_deliveries：一个事件发往一个端点的投递记录，也是投递日志
+type Delivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	EndpointID     uint       `gorm:"not null;index" json:"endpoint_id"`
+	EventID        string     `gorm:"size:40;not null;index" json:"event_id"`
+	Event          string     `gorm:"size:100;not null" json:"event"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`
+	Status         string     `gorm:"size:20;not null;index" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	ResponseStatus int        `json:"response_status,omitempty"` // 最后一次尝试的状态码，网络错误时为 0
+	ResponseBody   string     `gorm:"size:1000" json:"response_body,omitempty"`
+	LastError      string     `gorm:"size:500" json:"last_error,omitempty"`
+	DurationMs     int64      `json:"duration_ms"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// ============================================================================
+// 签名
+// ============================================================================
+
+// Sign 计算 X-Webhook-Signature 的值
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac(secret, ts, body))
+}
+
+// Verify 校验签名：格式、HMAC（恒定时间比较）和时间戳是否在 now ± tolerance 内
+// 接收方轮换密钥期间可以带多个 v1，任意一个匹配即通过
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts string
+	var sigs [][]byte
+	for part := range strings.SplitSeq(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			if sig, err := hex.DecodeString(v); err == nil {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrInvalidSignature
+	}
+	want := mac(secret, ts, body)
+	if !slices.ContainsFunc(sigs, func(sig []byte) bool { return hmac.Equal(sig, want) }) {
+		return ErrInvalidSignature
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+		return ErrExpired
+	}
+	return nil
+}
+
+func mac(secret, ts string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// ============================================================================
+// 端点管理
+// ============================================================================
+
+// CreateEndpoint 登记端点，生成随机密钥；返回的 Endpoint.Secret 要交给接收方保存
+func CreateEndpoint(ctx context.Context, db *gorm.DB, rawURL string, events []string, description string) (*Endpoint, error) {
+	if err := validateURL(rawURL); err != nil {
+		return nil, err
+	}
+	list, err := normalizeEvents(events)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	ep := &Endpoint{URL: rawURL, Secret: secret, Events: list, Description: description, Active: true}
+	if err := db.WithContext(ctx).Create(ep).Error; err != nil {
+		return nil, err
+	}
+	return ep, nil
+}
+
+// ListEndpoints 所有端点，按 ID 排序
+func ListEndpoints(ctx context.Context, db *gorm.DB) ([]Endpoint, error) {
+	var eps []Endpoint
+	err := db.WithContext(ctx).Order("id").Find(&eps).Error
+	return eps, err
+}
+
+// GetEndpoint 按 ID 查询端点
+func GetEndpoint(ctx context.Context, db *gorm.DB, id uint) (*Endpoint, error) {
+	var ep Endpoint
+	err := db.WithContext(ctx).Where("id = ?", id).Take(&ep).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ep, nil
+}
+
+// SetActive 启用或停用端点；启用时同时清除熔断状态
+func SetActive(ctx context.Context, db *gorm.DB, id uint, active bool) error {
+	updates := map[string]any{"active": active}
+	if active {
+		updates["consecutive_failures"] = 0
+		updates["disabled_until"] = nil
+	}
+	res := db.WithContext(ctx).Model(&Endpoint{}).Where("id = ?", id).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RotateSecret 生成新密钥，返回更新后的端点；之后的投递用新密钥签名
+func RotateSecret(ctx context.Context, db *gorm.DB, id uint) (*Endpoint, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	res := db.WithContext(ctx).Model(&Endpoint{}).Where("id = ?", id).Update("secret", secret)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return GetEndpoint(ctx, db, id)
+}
+
+// DeleteEndpoint 删除端点，投递记录保留；还没执行的投递任务会因端点不存在而放弃
+func DeleteEndpoint(ctx context.Context, db *gorm.DB, id uint) error {
+	res := db.WithContext(ctx).Where("id = ?", id).Delete(&Endpoint{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeliveryFilter ListDeliveries 的筛选条件，零值表示不筛选
+type DeliveryFilter struct {
+	EndpointID uint
+	Event      string
+	Status     string
+}
+
+// ListDeliveries 投递日志，最新的在前
+func ListDeliveries(ctx context.Context, db *gorm.DB, f DeliveryFilter, offset, limit int) ([]Delivery, int64, error) {
+	q := db.WithContext(ctx).Model(&Delivery{})
+	if f.EndpointID != 0 {
+		q = q.Where("endpoint_id = ?", f.EndpointID)
+	}
+	if f.Event != "" {
+		q = q.Where("event = ?", f.Event)
+	}
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var rows []Delivery
+	err := q.Order("id DESC").Offset(offset).Limit(limit).Find(&rows).Error
+	return rows, total, err
+}
+
+// GetDelivery 按 ID 查询投递记录
+func GetDelivery(ctx context.Context, db *gorm.DB, id uint) (*Delivery, error) {
+	var d Delivery
+	err := db.WithContext(ctx).Where("id = ?", id).Take(&d).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+// normalizeEvents 去空白、去重、排序后用逗号拼接
+func normalizeEvents(events []string) (string, error) {
+	var list []string
+	for _, e := range events {
+		if e = strings.TrimSpace(e); e != "" && !strings.Contains(e, ",") {
+			list = append(list, e)
+		}
+	}
+	if len(list) == 0 {
+		return "", ErrNoEvents
+	}
+	slices.Sort(list)
+	return strings.Join(slices.Compact(list), ","), nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func newEventID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("webhooks: event id: %w", err)
+	}
+	return "evt_" + hex.EncodeToString(b), nil
+}
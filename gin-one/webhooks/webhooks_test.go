@@ -0,0 +1,372 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"go-one/eventbus"
+	"go-one/internal/testdb"
+	"go-one/jobs"
+	"go-one/resilience"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.Open(t, &Endpoint{}, &Delivery{}, &jobs.Job{}, &jobs.DeadJob{})
+}
+
+func newTestDispatcher(t *testing.T, db *gorm.DB, cfg Config) (*Dispatcher, *jobs.Worker) {
+	t.Helper()
+	quiet := slog.New(slog.NewTextHandler(io.Discard, nil))
+	w := jobs.NewWorker(db, jobs.Config{Backoff: time.Millisecond, MaxBackoff: time.Millisecond, Logger: quiet})
+	cfg.Logger = quiet
+	return New(db, w, cfg), w
+}
+
+// drain 反复领取任务直到没有到期的任务
+func drain(t *testing.T, w *jobs.Worker) {
+	t.Helper()
+	for range 50 {
+		n, err := w.RunOnce(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			time.Sleep(5 * time.Millisecond) // 等退避到期
+			if n, _ = w.RunOnce(context.Background()); n == 0 {
+				return
+			}
+		}
+	}
+}
+
+// receiver 记录收到的请求，按 status 返回
+type receiver struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	status   atomic.Int32
+}
+
+func newReceiver(t *testing.T, status int) (*receiver, *httptest.Server) {
+	rc := &receiver{}
+	rc.status.Store(int32(status))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		rc.requests = append(rc.requests, r)
+		rc.bodies = append(rc.bodies, body)
+		rc.mu.Unlock()
+		w.WriteHeader(int(rc.status.Load()))
+		io.WriteString(w, "ack")
+	}))
+	t.Cleanup(srv.Close)
+	return rc, srv
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1767225600, 0)
+	body := []byte(`{"id":"evt_1"}`)
+	header := Sign("whsec_a", now, body)
+
+	if err := Verify("whsec_a", header, body, now.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Errorf("valid signature: %v", err)
+	}
+	if err := Verify("whsec_a", header, []byte(`{"id":"evt_2"}`), now, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered body err = %v", err)
+	}
+	if err := Verify("whsec_b", header, body, now, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("wrong secret err = %v", err)
+	}
+	if err := Verify("whsec_a", header, body, now.Add(10*time.Minute), 5*time.Minute); !errors.Is(err, ErrExpired) {
+		t.Errorf("replayed err = %v", err)
+	}
+	if err := Verify("whsec_a", "v1=abc", body, now, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("missing timestamp err = %v", err)
+	}
+	// 轮换期间带两个签名
+	rotated := header + ",v1=" + strings.Repeat("0", 64)
+	if err := Verify("whsec_a", rotated, body, now, 5*time.Minute); err != nil {
+		t.Errorf("multiple v1: %v", err)
+	}
+}
+
+type userCreated struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestPublishViaEventBus(t *testing.T) {
+	db := newTestDB(t)
+	d, w := newTestDispatcher(t, db, Config{})
+	rc, srv := newReceiver(t, http.StatusOK)
+	ctx := context.Background()
+
+	users, err := CreateEndpoint(ctx, db, srv.URL+"/users", []string{"user.created"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateEndpoint(ctx, db, srv.URL+"/posts", []string{"post.created"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	bus := eventbus.New(eventbus.Config{})
+	topic := eventbus.NewTopic[userCreated]("user.created")
+	Subscribe(d, bus, topic)
+	if err := eventbus.Publish(ctx, bus, topic, userCreated{ID: 7, Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(ctx); err != nil { // 等订阅者写完投递记录
+		t.Fatal(err)
+	}
+	drain(t, w)
+
+	if rc.count() != 1 {
+		t.Fatalf("received %d requests, want 1 (only the user.created endpoint)", rc.count())
+	}
+	req, body := rc.requests[0], rc.bodies[0]
+	if req.URL.Path != "/users" || req.Header.Get(HeaderEvent) != "user.created" || !strings.HasPrefix(req.Header.Get(HeaderID), "evt_") {
+		t.Errorf("request %s headers %v", req.URL.Path, req.Header)
+	}
+	if err := Verify(users.Secret, req.Header.Get(HeaderSignature), body, time.Now(), time.Minute); err != nil {
+		t.Errorf("signature: %v", err)
+	}
+	var env struct {
+		ID   string      `json:"id"`
+		Type string      `json:"type"`
+		Data userCreated `json:"data"`
+	}
+	if err := json.Unmarshal(body, &env); err != nil || env.Data.Name != "alice" || env.ID != req.Header.Get(HeaderID) {
+		t.Errorf("body %s: %v", body, err)
+	}
+
+	rows, total, err := ListDeliveries(ctx, db, DeliveryFilter{EndpointID: users.ID}, 0, 10)
+	if err != nil || total != 1 {
+		t.Fatalf("deliveries = %d, %v", total, err)
+	}
+	if got := rows[0]; got.Status != StatusSucceeded || got.Attempts != 1 || got.ResponseStatus != 200 || got.ResponseBody != "ack" || got.DeliveredAt == nil {
+		t.Errorf("delivery = %+v", got)
+	}
+}
+
+func TestRetryThenFail(t *testing.T) {
+	db := newTestDB(t)
+	d, w := newTestDispatcher(t, db, Config{MaxAttempts: 3, FailureThreshold: 100})
+	rc, srv := newReceiver(t, http.StatusInternalServerError)
+	ctx := context.Background()
+
+	ep, _ := CreateEndpoint(ctx, db, srv.URL, []string{AllEvents}, "")
+	if n, err := d.Publish(ctx, "order.paid", map[string]int{"id": 1}); err != nil || n != 1 {
+		t.Fatalf("Publish = %d, %v", n, err)
+	}
+	drain(t, w)
+
+	if rc.count() != 3 {
+		t.Errorf("received %d requests, want 3", rc.count())
+	}
+	rows, _, _ := ListDeliveries(ctx, db, DeliveryFilter{Status: StatusFailed}, 0, 10)
+	if len(rows) != 1 || rows[0].Attempts != 3 || rows[0].ResponseStatus != 500 || rows[0].LastError == "" {
+		t.Fatalf("failed deliveries = %+v", rows)
+	}
+	got, _ := GetEndpoint(ctx, db, ep.ID)
+	if got.ConsecutiveFailures != 3 {
+		t.Errorf("consecutive failures = %d", got.ConsecutiveFailures)
+	}
+
+	// 接收方修好后重新投递
+	rc.status.Store(http.StatusNoContent)
+	if _, err := d.Redeliver(ctx, rows[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	drain(t, w)
+	delivery, _ := GetDelivery(ctx, db, rows[0].ID)
+	if delivery.Status != StatusSucceeded || delivery.Attempts != 1 {
+		t.Errorf("redelivered = %+v", delivery)
+	}
+	if got, _ := GetEndpoint(ctx, db, ep.ID); got.ConsecutiveFailures != 0 {
+		t.Errorf("failures not reset: %d", got.ConsecutiveFailures)
+	}
+	if _, err := d.Redeliver(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("redeliver missing err = %v", err)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	db := newTestDB(t)
+	d, w := newTestDispatcher(t, db, Config{MaxAttempts: 1, FailureThreshold: 2, Cooldown: time.Hour})
+	now := time.Now()
+	d.now = func() time.Time { return now }
+	rc, srv := newReceiver(t, http.StatusBadGateway)
+	ctx := context.Background()
+
+	ep, _ := CreateEndpoint(ctx, db, srv.URL, []string{"ping"}, "")
+	for range 3 {
+		d.Publish(ctx, "ping", nil)
+	}
+	drain(t, w)
+
+	// 前两次失败打开熔断，第三次没有发出，推迟到冷却结束
+	if rc.count() != 2 {
+		t.Fatalf("received %d requests, want 2", rc.count())
+	}
+	got, _ := GetEndpoint(ctx, db, ep.ID)
+	if got.DisabledUntil == nil || !got.DisabledUntil.Equal(now.Add(time.Hour)) {
+		t.Fatalf("disabled_until = %v", got.DisabledUntil)
+	}
+	pending, _, _ := ListDeliveries(ctx, db, DeliveryFilter{Status: StatusPending}, 0, 10)
+	if len(pending) != 1 || pending[0].Attempts != 0 {
+		t.Fatalf("pending = %+v", pending)
+	}
+	var deferred jobs.Job
+	db.Take(&deferred)
+	if !deferred.RunAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("deferred run_at = %v", deferred.RunAt)
+	}
+
+	// 冷却结束，试探投递成功后熔断关闭
+	now = now.Add(time.Hour + time.Second)
+	rc.status.Store(http.StatusOK)
+	db.Model(&jobs.Job{}).Where("id = ?", deferred.ID).Update("run_at", time.Now())
+	drain(t, w)
+	if rc.count() != 3 {
+		t.Errorf("received %d requests after cooldown, want 3", rc.count())
+	}
+	got, _ = GetEndpoint(ctx, db, ep.ID)
+	if got.ConsecutiveFailures != 0 || got.DisabledUntil != nil {
+		t.Errorf("breaker not reset: %+v", got)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	db := newTestDB(t)
+	quiet := slog.New(slog.NewTextHandler(io.Discard, nil))
+	guard := resilience.New(resilience.Config{Name: "webhooks", MaxConcurrent: 1, Logger: quiet})
+	d, w := newTestDispatcher(t, db, Config{Guard: guard, MaxAttempts: 1})
+	now := time.Now()
+	d.now = func() time.Time { return now }
+	rc, srv := newReceiver(t, http.StatusBadGateway)
+	ctx := context.Background()
+	CreateEndpoint(ctx, db, srv.URL, []string{"ping"}, "")
+
+	// 唯一的槽位被占着：投递推迟，不算一次尝试
+	busy, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		guard.Do(ctx, func(context.Context) error {
+			close(busy)
+			<-release
+			return nil
+		})
+	}()
+	<-busy
+	d.Publish(ctx, "ping", nil)
+	if _, err := w.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if rc.count() != 0 {
+		t.Fatalf("sent %d requests while bulkhead full", rc.count())
+	}
+	pending, _, _ := ListDeliveries(ctx, db, DeliveryFilter{Status: StatusPending}, 0, 10)
+	if len(pending) != 1 || pending[0].Attempts != 0 {
+		t.Fatalf("pending = %+v", pending)
+	}
+	var deferred jobs.Job
+	db.Take(&deferred)
+	if !deferred.RunAt.Equal(now.Add(rejectedDelay)) {
+		t.Errorf("deferred run_at = %v; want now + %s", deferred.RunAt, rejectedDelay)
+	}
+
+	// 槽位释放后照常投递；接收方的 502 只算端点的失败，不计入 Guard
+	close(release)
+	<-done
+	db.Model(&jobs.Job{}).Where("id = ?", deferred.ID).Update("run_at", time.Now())
+	drain(t, w)
+	if rc.count() != 1 {
+		t.Fatalf("received %d requests, want 1", rc.count())
+	}
+	if s := guard.Stats(); s.Failures != 0 || s.RejectedFull != 1 {
+		t.Errorf("guard stats = %+v", s)
+	}
+}
+
+func TestGoneDisablesEndpoint(t *testing.T) {
+	db := newTestDB(t)
+	d, w := newTestDispatcher(t, db, Config{})
+	rc, srv := newReceiver(t, http.StatusGone)
+	ctx := context.Background()
+
+	ep, _ := CreateEndpoint(ctx, db, srv.URL, []string{"ping"}, "")
+	d.Publish(ctx, "ping", nil)
+	drain(t, w)
+	if rc.count() != 1 {
+		t.Errorf("received %d requests, want 1", rc.count())
+	}
+	if got, _ := GetEndpoint(ctx, db, ep.ID); got.Active {
+		t.Error("endpoint still active after 410")
+	}
+	// 停用后不再创建投递
+	if n, _ := d.Publish(ctx, "ping", nil); n != 0 {
+		t.Errorf("published to %d disabled endpoints", n)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+	d, _ := newTestDispatcher(t, db, Config{})
+	r := gin.New()
+	Register(r.Group("/webhooks"), d)
+
+	do := func(method, path, body string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		var resp map[string]any
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := do("POST", "/webhooks/endpoints", `{"url":"https://hooks.example.com/in","events":["user.created","user.created"," post.created"]}`)
+	data, _ := resp["data"].(map[string]any)
+	if code != http.StatusOK || !strings.HasPrefix(data["secret"].(string), "whsec_") {
+		t.Fatalf("create = %d %v", code, resp)
+	}
+	if events := data["events"].([]any); len(events) != 2 || events[0] != "post.created" {
+		t.Errorf("events = %v", events)
+	}
+
+	code, resp = do("GET", "/webhooks/endpoints", "")
+	if list := resp["data"].([]any); code != http.StatusOK || len(list) != 1 || list[0].(map[string]any)["secret"] != nil {
+		t.Errorf("list = %d %v", code, resp)
+	}
+
+	for _, body := range []string{`{"url":"ftp://x","events":["a"]}`, `{"url":"https://x","events":[" "]}`} {
+		if code, _ := do("POST", "/webhooks/endpoints", body); code != http.StatusBadRequest {
+			t.Errorf("create %s = %d, want 400", body, code)
+		}
+	}
+	if code, _ := do("POST", "/webhooks/endpoints/9/enable", ""); code != http.StatusNotFound {
+		t.Errorf("enable missing = %d", code)
+	}
+	if code, _ := do("GET", "/webhooks/deliveries?page=1&page_size=10&status=failed", ""); code != http.StatusOK {
+		t.Errorf("deliveries = %d", code)
+	}
+}
@@ -0,0 +1,99 @@
+package ws
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// bearerProtocol 用 Sec-WebSocket-Protocol 传 token 时的子协议名：["bearer", "<token>"]
+const bearerProtocol = "bearer"
+
+// Identity 连接的身份
+type Identity struct {
+	UserID string
+	Name   string
+}
+
+// Authenticator 握手时认证，返回错误时拒绝升级
+type Authenticator func(r *http.Request) (Identity, error)
+
+// Token 依次从 Authorization 头、Sec-WebSocket-Protocol、查询参数 token 中取 token
+func Token(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if tok, ok := strings.CutPrefix(h, "Bearer "); ok {
+			return strings.TrimSpace(tok)
+		}
+	}
+	// Sec-WebSocket-Protocol: bearer, eyJ...
+	protocols := websocketProtocols(r)
+	for i, p := range protocols {
+		if p == bearerProtocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+	return r.URL.Query().Get("token")
+}
+
+func websocketProtocols(r *http.Request) []string {
+	var out []string
+	for _, h := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(h, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				out = append(out, p)
+			}
+		}
+	}
+	return out
+}
+
+// JWT 用 HS256 密钥校验 token，和 5_1_jwt_auth.go 签发的 Access Token 兼容，见 ParseJWT
+func JWT(secret []byte) Authenticator {
+	return func(r *http.Request) (Identity, error) {
+		return ParseJWT(secret, Token(r))
+	}
+}
+
+// ParseJWT 校验 token 并取出身份：用户 ID 取 user_id（没有时取 sub），名称取 username
+//
+// 不依赖 *http.Request，gRPC 等其他入口也可以用同一套规则认证
+func ParseJWT(secret []byte, raw string) (Identity, error) {
+	if raw == "" {
+		return Identity{}, errors.New("token required")
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(*jwt.Token) (any, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return Identity{}, err
+	}
+
+	id := Identity{UserID: claimString(claims["user_id"])}
+	if id.UserID == "" {
+		id.UserID = claimString(claims["sub"])
+	}
+	if id.UserID == "" {
+		return Identity{}, errors.New("token has no user id")
+	}
+	id.Name = claimString(claims["username"])
+	return id, nil
+}
+
+// claimString JSON 数字解码为 float64，转成不带小数点的字符串
+func claimString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,161 @@
+package ws
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Conn 一个 WebSocket 连接
+type Conn struct {
+	ID       string   // 随机生成，用于日志和区分同一用户的多个连接
+	Identity Identity // 握手时认证得到的身份，匿名连接为零值
+
+	hub  *Hub
+	ws   *websocket.Conn
+	send chan []byte
+
+	done      chan struct{} // 关闭后写循环发送关闭帧并断开
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+	written   chan struct{} // 写循环退出
+
+	rooms map[string]struct{} // 由 hub.mu 保护
+}
+
+func (h *Hub) newConn(ws *websocket.Conn, id Identity) *Conn {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return &Conn{
+		ID:       hex.EncodeToString(b),
+		Identity: id,
+		hub:      h,
+		ws:       ws,
+		send:     make(chan []byte, h.cfg.SendQueue),
+		done:     make(chan struct{}),
+		written:  make(chan struct{}),
+		rooms:    make(map[string]struct{}),
+	}
+}
+
+// Send 把消息放入发送队列，不会阻塞
+//
+// 队列已满时断开连接并返回 ErrQueueFull，见包文档【背压】。
+func (c *Conn) Send(msg []byte) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+	select {
+	case c.send <- msg:
+		return nil
+	default:
+		c.hub.logger.Warn("ws: slow consumer disconnected", "conn", c.ID, "user", c.Identity.UserID, "queue", cap(c.send))
+		c.closeWith(websocket.CloseTryAgainLater, "send queue full")
+		return ErrQueueFull
+	}
+}
+
+// Close 正常关闭连接（1000）
+func (c *Conn) Close() {
+	c.closeWith(websocket.CloseNormalClosure, "")
+}
+
+// Rooms 连接当前所在的房间，按名称排序
+func (c *Conn) Rooms() []string {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	rooms := make([]string, 0, len(c.rooms))
+	for r := range c.rooms {
+		rooms = append(rooms, r)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// closeWith 记录关闭码并通知写循环，只有第一次调用生效
+func (c *Conn) closeWith(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode, c.closeText = code, text
+		close(c.done)
+	})
+}
+
+// run 运行读写循环，连接断开后从 Hub 删除
+func (c *Conn) run() {
+	go c.writeLoop()
+	c.readLoop()
+	c.closeWith(websocket.CloseNormalClosure, "")
+	c.hub.remove(c)
+	<-c.written
+}
+
+func (c *Conn) readLoop() {
+	cfg := c.hub.cfg
+	c.ws.SetReadLimit(cfg.MaxMessageSize)
+	c.ws.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+	})
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			select {
+			case <-c.done:
+				// 服务端主动关闭，读失败是预期的
+			default:
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+					c.hub.logger.Debug("ws: read", "conn", c.ID, "error", err)
+				}
+			}
+			return
+		}
+		// 收到任何数据都说明连接还活着
+		c.ws.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+		if cfg.OnMessage != nil {
+			cfg.OnMessage(c, data)
+		}
+	}
+}
+
+// writeLoop 唯一写连接的 goroutine
+func (c *Conn) writeLoop() {
+	cfg := c.hub.cfg
+	ticker := time.NewTicker(cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.ws.Close() // 读循环随之返回
+		close(c.written)
+	}()
+
+	for {
+		select {
+		case msg := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+			if err := c.ws.WriteMessage(websocket.TextMessage, msg); err != nil {
+				c.closeWith(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			c.ws.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+			if err := c.ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.closeWith(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.done:
+			// 1006 只用于本地表示异常断开，不能出现在关闭帧里
+			if c.closeCode != websocket.CloseAbnormalClosure {
+				msg := websocket.FormatCloseMessage(c.closeCode, c.closeText)
+				c.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(cfg.WriteTimeout))
+			}
+			return
+		}
+	}
+}
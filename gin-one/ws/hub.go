@@ -0,0 +1,352 @@
+// ============================================================================
+// Package ws WebSocket 连接管理：Hub、房间、JWT 握手
+// ============================================================================
+//
+// 【结构】
+//
+//	           ┌──────── Hub ────────┐
+//	握手 ──→   │ conns   所有连接     │   Broadcast(room, msg) → 房间里每个连接的发送队列
+//	(JWT)      │ rooms   房间 → 连接  │   SendToUser(id, msg)  → 这个用户的所有连接（多个标签页）
+//	           │ users   用户 → 连接  │
+//	           └─────────────────────┘
+//	每个连接两个 goroutine：
+//	  读循环  ReadMessage → Config.OnMessage，同时处理 pong
+//	  写循环  发送队列 → WriteMessage，定时发 ping
+//
+// gorilla/websocket 的连接不支持并发写，所有写操作都在写循环里完成，
+// 其他 goroutine 只往发送队列里放消息。
+//
+// 【背压】
+//
+// 每个连接的发送队列有上限（默认 256 条）。队列满说明客户端读得比服务端写得慢
+// （网络差或者客户端卡住），这时 Send 返回 ErrQueueFull 并断开这个连接：
+//
+// | 做法               | 问题                                                 |
+// |--------------------|------------------------------------------------------|
+// | 阻塞等待           | 一个慢客户端拖住整个房间的广播                       |
+// | 无限队列           | 慢客户端的积压消息把内存吃光                         |
+// | 丢弃消息           | 客户端悄悄漏掉消息，还以为自己是最新的               |
+// | 断开（这里的做法） | 客户端重连后重新拉取状态，行为明确                   |
+//
+// 【心跳】
+//
+// 服务端每 PingInterval 发一次 ping，浏览器自动回 pong；
+// PongTimeout 内没有收到任何数据（包括 pong）就认为连接已断开。
+// 没有心跳的话，客户端断网（不是正常关闭）后服务端要等 TCP 超时才能发现，可能是几十分钟。
+//
+// 【认证】
+//
+// 浏览器的 WebSocket API 不能设置 Authorization 头，token 可以放在：
+//
+//	new WebSocket("ws://host/ws?token=eyJ...")                  // 查询参数，会出现在访问日志里
+//	new WebSocket("ws://host/ws", ["bearer", "eyJ..."])         // Sec-WebSocket-Protocol
+//
+// 认证在升级之前完成，失败直接返回 401，不会建立 WebSocket 连接。
+//
+// 【优雅关闭】
+//
+// http.Server.Shutdown 不会等待已经升级的连接，关闭时调用 Hub.Close
+// 给所有客户端发送 1001 Going Away，客户端据此重连到其他实例。
+//
+// ============================================================================
+package ws
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"go-one/response"
+)
+
+// 错误定义
+var (
+	// ErrQueueFull 发送队列已满，连接已被断开
+	ErrQueueFull = errors.New("ws: send queue full")
+	// ErrClosed 连接已关闭
+	ErrClosed = errors.New("ws: connection closed")
+	// ErrUnauthorized 握手时认证失败
+	ErrUnauthorized = errors.New("ws: unauthorized")
+)
+
+// Config Hub 配置
+type Config struct {
+	// Authenticate 握手时认证，返回错误时拒绝升级；nil 表示允许匿名连接
+	Authenticate Authenticator
+
+	// OnMessage 收到客户端消息时调用，在该连接的读循环里执行，
+	// 同一个连接的消息按顺序处理，耗时操作要自己开 goroutine
+	OnMessage func(c *Conn, data []byte)
+
+	// OnConnect / OnDisconnect 连接建立、断开时调用，可以为 nil；
+	// OnDisconnect 调用时连接还没有离开房间，可以据此通知房间里的其他人
+	OnConnect    func(c *Conn)
+	OnDisconnect func(c *Conn)
+
+	SendQueue      int           // 每个连接的发送队列长度，默认 256
+	WriteTimeout   time.Duration // 单条消息的写超时，默认 10 秒
+	PongTimeout    time.Duration // 多久收不到数据认为连接已断开，默认 60 秒
+	PingInterval   time.Duration // ping 间隔，默认 PongTimeout 的 9/10
+	MaxMessageSize int64         // 客户端消息大小上限，默认 64KB，超过时断开
+
+	// CheckOrigin 校验 Origin 头，默认只允许同源；跨域前端需要显式放行
+	CheckOrigin func(r *http.Request) bool
+
+	// Logger 默认 slog.Default()
+	Logger *slog.Logger
+}
+
+// Hub 管理所有连接和房间
+type Hub struct {
+	cfg      Config
+	upgrader websocket.Upgrader
+	logger   *slog.Logger
+
+	mu     sync.RWMutex
+	conns  map[*Conn]struct{}
+	rooms  map[string]map[*Conn]struct{}
+	users  map[string]map[*Conn]struct{}
+	closed bool
+}
+
+// NewHub 创建 Hub
+func NewHub(cfg Config) *Hub {
+	if cfg.SendQueue <= 0 {
+		cfg.SendQueue = 256
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+	if cfg.PongTimeout <= 0 {
+		cfg.PongTimeout = 60 * time.Second
+	}
+	if cfg.PingInterval <= 0 || cfg.PingInterval >= cfg.PongTimeout {
+		cfg.PingInterval = cfg.PongTimeout * 9 / 10
+	}
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = 64 << 10
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Hub{
+		cfg: cfg,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: cfg.CheckOrigin, // nil 时 gorilla 只允许同源
+			// 客户端用 Sec-WebSocket-Protocol 传 token 时，必须回应一个它提供的子协议
+			Subprotocols: []string{bearerProtocol},
+		},
+		logger: cfg.Logger,
+		conns:  make(map[*Conn]struct{}),
+		rooms:  make(map[string]map[*Conn]struct{}),
+		users:  make(map[string]map[*Conn]struct{}),
+	}
+}
+
+// Handler 返回升级 WebSocket 的 gin handler，请求会一直阻塞到连接断开
+//
+//	r.GET("/ws", hub.Handler())
+func (h *Hub) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := h.Serve(c.Writer, c.Request)
+		if errors.Is(err, ErrUnauthorized) {
+			response.Error(c, http.StatusUnauthorized, "unauthorized", err.Error())
+			return
+		}
+		if err != nil && !c.Writer.Written() {
+			response.Error(c, http.StatusServiceUnavailable, "unavailable", err.Error())
+		}
+	}
+}
+
+// Serve 认证并升级连接，然后运行读写循环直到连接断开
+//
+// 升级之前失败时返回错误且没有写响应（ErrUnauthorized、ErrClosed），由调用方决定格式；
+// 升级失败时 gorilla 已经写了 400 响应。
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request) error {
+	var id Identity
+	if h.cfg.Authenticate != nil {
+		var err error
+		if id, err = h.cfg.Authenticate(r); err != nil {
+			return errors.Join(ErrUnauthorized, err)
+		}
+	}
+	h.mu.RLock()
+	closed := h.closed
+	h.mu.RUnlock()
+	if closed {
+		return ErrClosed
+	}
+
+	ws, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+	c := h.newConn(ws, id)
+	if !h.add(c) {
+		c.closeWith(websocket.CloseGoingAway, "server shutting down")
+		c.run()
+		return nil
+	}
+	if h.cfg.OnConnect != nil {
+		h.cfg.OnConnect(c)
+	}
+	c.run()
+	return nil
+}
+
+// Join 让连接加入房间，重复加入没有影响
+func (h *Hub) Join(c *Conn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.conns[c]; !ok {
+		return
+	}
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[*Conn]struct{})
+	}
+	h.rooms[room][c] = struct{}{}
+	c.rooms[room] = struct{}{}
+}
+
+// Leave 让连接离开房间
+func (h *Hub) Leave(c *Conn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leave(c, room)
+}
+
+func (h *Hub) leave(c *Conn, room string) {
+	delete(c.rooms, room)
+	if members := h.rooms[room]; members != nil {
+		delete(members, c)
+		if len(members) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// Broadcast 发给房间里的所有连接，except 不为 nil 时跳过它（通常是发送者自己），
+// 返回成功放入队列的连接数
+func (h *Hub) Broadcast(room string, msg []byte, except *Conn) int {
+	return h.sendAll(h.members(h.rooms[room], except), msg)
+}
+
+// SendToUser 发给某个用户的所有连接（同一个用户可能开了多个标签页），返回成功放入队列的连接数
+func (h *Hub) SendToUser(userID string, msg []byte) int {
+	return h.sendAll(h.members(h.users[userID], nil), msg)
+}
+
+// BroadcastAll 发给所有连接，如系统公告
+func (h *Hub) BroadcastAll(msg []byte) int {
+	return h.sendAll(h.members(h.conns, nil), msg)
+}
+
+// members 在读锁内复制一份成员列表，发送时不持有锁：
+// Send 队列满时会断开连接，断开需要写锁
+func (h *Hub) members(set map[*Conn]struct{}, except *Conn) []*Conn {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	list := make([]*Conn, 0, len(set))
+	for c := range set {
+		if c != except {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+func (h *Hub) sendAll(conns []*Conn, msg []byte) int {
+	n := 0
+	for _, c := range conns {
+		if c.Send(msg) == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Count 当前连接数
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// RoomSize 房间里的连接数
+func (h *Hub) RoomSize(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Online 用户是否至少有一个连接
+func (h *Hub) Online(userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.users[userID]) > 0
+}
+
+// Close 拒绝新连接，给所有连接发送 1001 Going Away 后关闭
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	conns := make([]*Conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range conns {
+		c.closeWith(websocket.CloseGoingAway, "server shutting down")
+	}
+}
+
+func (h *Hub) add(c *Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	h.conns[c] = struct{}{}
+	if uid := c.Identity.UserID; uid != "" {
+		if h.users[uid] == nil {
+			h.users[uid] = make(map[*Conn]struct{})
+		}
+		h.users[uid][c] = struct{}{}
+	}
+	return true
+}
+
+// remove 连接断开时从所有索引中删除，只执行一次
+//
+// OnDisconnect 在删除之前调用，回调里还能通过 Rooms 拿到连接所在的房间
+func (h *Hub) remove(c *Conn) {
+	h.mu.RLock()
+	_, ok := h.conns[c]
+	h.mu.RUnlock()
+	if !ok {
+		return
+	}
+	if h.cfg.OnDisconnect != nil {
+		h.cfg.OnDisconnect(c)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, c)
+	for room := range c.rooms {
+		h.leave(c, room)
+	}
+	if uid := c.Identity.UserID; uid != "" {
+		delete(h.users[uid], c)
+		if len(h.users[uid]) == 0 {
+			delete(h.users, uid)
+		}
+	}
+}
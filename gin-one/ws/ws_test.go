@@ -0,0 +1,247 @@
+package ws
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func userToken(t *testing.T, id uint, name string) string {
+	return sign(t, jwt.MapClaims{"user_id": id, "username": name, "exp": time.Now().Add(time.Hour).Unix()})
+}
+
+// newServer 启动挂着 hub 的测试服务器，返回 ws:// 地址
+func newServer(t *testing.T, cfg Config) (*Hub, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	if cfg.Authenticate == nil {
+		cfg.Authenticate = JWT(secret)
+	}
+	hub := NewHub(cfg)
+	r := gin.New()
+	r.GET("/ws", hub.Handler())
+	srv := httptest.NewServer(r)
+	t.Cleanup(func() {
+		hub.Close()
+		srv.Close()
+	})
+	return hub, "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+}
+
+func dial(t *testing.T, url, token string) *websocket.Conn {
+	t.Helper()
+	c, resp, err := websocket.DefaultDialer.Dial(url+"?token="+token, nil)
+	if err != nil {
+		t.Fatalf("dial: %v (resp %v)", err, resp)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func read(t *testing.T, c *websocket.Conn) string {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, msg, err := c.ReadMessage()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return string(msg)
+}
+
+// waitFor 连接注册是异步的（升级完成后才加入 hub）
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	_, url := newServer(t, Config{})
+
+	tests := []struct {
+		name   string
+		url    string
+		header http.Header
+		status int
+	}{
+		{"no token", url, nil, http.StatusUnauthorized},
+		{"bad signature", url + "?token=" + sign(t, jwt.MapClaims{"user_id": 1}) + "x", nil, http.StatusUnauthorized},
+		{"expired", url + "?token=" + sign(t, jwt.MapClaims{"user_id": 1, "exp": time.Now().Add(-time.Minute).Unix()}), nil, http.StatusUnauthorized},
+		{"query token", url + "?token=" + userToken(t, 1, "alice"), nil, http.StatusSwitchingProtocols},
+		{"authorization header", url, http.Header{"Authorization": {"Bearer " + userToken(t, 1, "alice")}}, http.StatusSwitchingProtocols},
+		{"subprotocol", url, http.Header{"Sec-WebSocket-Protocol": {"bearer, " + userToken(t, 1, "alice")}}, http.StatusSwitchingProtocols},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, resp, err := websocket.DefaultDialer.Dial(tt.url, tt.header)
+			if resp == nil {
+				t.Fatalf("dial: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d; want %d", resp.StatusCode, tt.status)
+			}
+			if c != nil {
+				c.Close()
+			}
+		})
+	}
+}
+
+func TestRoomsAndDirect(t *testing.T) {
+	var hub *Hub
+	hub, url := newServer(t, Config{
+		// 协议：join:<room> / say:<room>:<text> / dm:<user>:<text>
+		OnMessage: func(c *Conn, data []byte) {
+			parts := strings.SplitN(string(data), ":", 3)
+			switch parts[0] {
+			case "join":
+				hub.Join(c, parts[1])
+			case "leave":
+				hub.Leave(c, parts[1])
+			case "say":
+				hub.Broadcast(parts[1], []byte(c.Identity.Name+": "+parts[2]), c)
+			case "dm":
+				hub.SendToUser(parts[1], []byte("dm from "+c.Identity.Name+": "+parts[2]))
+			}
+		},
+	})
+
+	alice := dial(t, url, userToken(t, 1, "alice"))
+	bob := dial(t, url, userToken(t, 2, "bob"))
+	bob2 := dial(t, url, userToken(t, 2, "bob")) // bob 的第二个标签页
+	carol := dial(t, url, userToken(t, 3, "carol"))
+	waitFor(t, func() bool { return hub.Count() == 4 })
+
+	for _, c := range []*websocket.Conn{alice, bob, bob2} {
+		c.WriteMessage(websocket.TextMessage, []byte("join:go"))
+	}
+	waitFor(t, func() bool { return hub.RoomSize("go") == 3 })
+
+	// 广播不发给自己，也不发给房间外的 carol
+	alice.WriteMessage(websocket.TextMessage, []byte("say:go:hello"))
+	if got := read(t, bob); got != "alice: hello" {
+		t.Fatalf("bob got %q", got)
+	}
+	if got := read(t, bob2); got != "alice: hello" {
+		t.Fatalf("bob2 got %q", got)
+	}
+
+	// 私信发给用户的所有连接
+	carol.WriteMessage(websocket.TextMessage, []byte("dm:2:hi bob"))
+	for _, c := range []*websocket.Conn{bob, bob2} {
+		if got := read(t, c); got != "dm from carol: hi bob" {
+			t.Fatalf("dm got %q", got)
+		}
+	}
+	if !hub.Online("2") || hub.Online("9") {
+		t.Fatal("Online mismatch")
+	}
+
+	// 断开后从房间和用户索引中删除
+	bob2.Close()
+	waitFor(t, func() bool { return hub.RoomSize("go") == 2 })
+	bob.Close()
+	waitFor(t, func() bool { return !hub.Online("2") })
+
+	// carol 没有收到房间消息，下一条是 BroadcastAll
+	hub.BroadcastAll([]byte("announcement"))
+	if got := read(t, carol); got != "announcement" {
+		t.Fatalf("carol got %q", got)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	hub := NewHub(Config{SendQueue: 2})
+	c := hub.newConn(nil, Identity{UserID: "1"})
+	hub.add(c)
+
+	// 没有写循环消费，队列满后断开
+	for i := range 2 {
+		if err := c.Send([]byte("x")); err != nil {
+			t.Fatalf("Send %d: %v", i, err)
+		}
+	}
+	if err := c.Send([]byte("x")); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Send err = %v; want ErrQueueFull", err)
+	}
+	if err := c.Send([]byte("x")); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Send after close err = %v; want ErrClosed", err)
+	}
+	if c.closeCode != websocket.CloseTryAgainLater {
+		t.Fatalf("close code = %d", c.closeCode)
+	}
+	// 广播跳过已经断开的连接
+	if n := hub.SendToUser("1", []byte("x")); n != 0 {
+		t.Fatalf("SendToUser delivered to %d", n)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	hub, url := newServer(t, Config{PongTimeout: 200 * time.Millisecond, PingInterval: 50 * time.Millisecond})
+
+	// gorilla 客户端默认自动回复 pong，只要在读就能保持连接
+	alive := dial(t, url, userToken(t, 1, "alice"))
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// 不读的客户端不会回复 pong，超时后被断开
+	dial(t, url, userToken(t, 2, "bob"))
+	waitFor(t, func() bool { return hub.Count() == 2 })
+
+	waitFor(t, func() bool { return !hub.Online("2") })
+	time.Sleep(300 * time.Millisecond)
+	if !hub.Online("1") {
+		t.Fatal("connection answering pings was dropped")
+	}
+}
+
+func TestClose(t *testing.T) {
+	disconnected := make(chan string, 1)
+	hub, url := newServer(t, Config{OnDisconnect: func(c *Conn) { disconnected <- c.Identity.UserID }})
+	c := dial(t, url, userToken(t, 1, "alice"))
+	waitFor(t, func() bool { return hub.Count() == 1 })
+
+	hub.Close()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := c.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("read err = %v; want 1001 going away", err)
+	}
+	if uid := <-disconnected; uid != "1" {
+		t.Fatalf("OnDisconnect user = %q", uid)
+	}
+
+	// 关闭后拒绝新连接
+	_, resp, _ := websocket.DefaultDialer.Dial(url+"?token="+userToken(t, 1, "alice"), nil)
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("dial after close: %v", resp)
+	}
+}
@@ -36,11 +36,16 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strconv"
+	"strings"
+
+	"go-learning/errtrace"
+	"go-learning/multierr"
 )
 
 // ============================================================================
@@ -58,6 +63,11 @@ import (
 // - 需要携带额外字段（如错误码、字段名）
 // - 需要程序化地检查错误类型
 // - 需要实现特定的错误行为
+//
+// 【Web 服务中的错误分类】
+// 自定义错误类型再带上「类别」（NotFound / Conflict / Invalid ...），
+// 就能由中间件统一映射成 HTTP 状态码，handler 不用自己拼错误响应。
+// 完整实现见 gin-one/apperr：*Error 实现 Is / Unwrap，包装后仍能用 errors.Is 判断
 // ============================================================================
 
 // ValidationError: 简单的自定义错误
@@ -369,6 +379,42 @@ func main() {
 		fmt.Printf("处理失败: %v\n", err)
 	}
 
+	// 【带路径的多错误】
+	// errors.Join 只知道"有哪些错误"，批量接口还要告诉调用方"错在哪一行、哪个字段"
+	// multierr.Errors 给每个错误带上路径，并能输出 JSON 数组给前端逐行标红
+	// 完整实现见 multierr/
+	fmt.Println("\n--- 带路径的多错误（multierr 包）---")
+
+	err = validateRows([]userRow{
+		{Name: "alice", Email: "alice@example.com", Age: 20},
+		{Name: "", Email: "bob", Age: 30},
+		{Name: "carol", Email: "carol@example.com", Age: -1},
+	})
+	fmt.Printf("校验结果:\n%v\n", err)
+	if errors.Is(err, ErrEmptyField) {
+		fmt.Println("errors.Is: 有字段为空（穿透多错误找到成员）")
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		fmt.Printf("errors.As: 第一个 ValidationError, field=%s\n", verr.Field)
+	}
+	if data, jerr := json.Marshal(err); jerr == nil {
+		fmt.Printf("JSON: %s\n", data)
+	}
+
+	// 【带调用栈的错误】
+	// 错误消息只说了"什么错了"，errtrace 在创建错误时记录"在哪错的"
+	// %v 只输出消息，%+v 额外输出调用栈；完整实现见 errtrace/
+	fmt.Println("\n--- 带调用栈的错误（errtrace 包）---")
+
+	err = loadConfig("missing.yaml")
+	fmt.Printf("%%v:  %v\n", err)
+	fmt.Printf("出错位置: %s\n", errtrace.Source(err))
+	fmt.Printf("%%+v: %+v\n", err)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Println("errors.Is: 包装后仍能匹配 os.ErrNotExist")
+	}
+
 	// ========================================================================
 	// 【错误处理模式】
 	// ========================================================================
@@ -681,6 +727,50 @@ func processItems(items []string) error {
 	return nil
 }
 
+// userRow 批量导入的一行
+type userRow struct {
+	Name  string
+	Email string
+	Age   int
+}
+
+// ErrEmptyField 必填字段为空
+var ErrEmptyField = errors.New("field is empty")
+
+// validateRows: 逐行校验，收集所有行的错误，演示 multierr
+// 【路径】
+// rows[1].name: field is empty
+// rows[1].email: validation error on field 'email': must contain @
+//
+// 【注意】
+// 返回 errs.Err() 而不是 &errs：没有错误时前者是真正的 nil
+func validateRows(rows []userRow) error {
+	var errs multierr.Errors
+	for i, row := range rows {
+		path := multierr.Index("rows", i)
+		if row.Name == "" {
+			errs.Add(multierr.Field(path, "name"), ErrEmptyField)
+		}
+		if !strings.Contains(row.Email, "@") {
+			errs.Add(multierr.Field(path, "email"), &ValidationError{Field: "email", Message: "must contain @"})
+		}
+		if row.Age < 0 {
+			errs.Add(multierr.Field(path, "age"), &ValidationError{Field: "age", Message: "must be >= 0"})
+		}
+	}
+	return errs.Err()
+}
+
+// loadConfig: 读取配置文件失败时附带调用栈，演示 errtrace
+// 【注意】
+// 只在错误第一次出现的地方 Wrap，上层再 Wrap 不会重复记录
+func loadConfig(path string) error {
+	if _, err := os.ReadFile(path); err != nil {
+		return errtrace.Wrap(err)
+	}
+	return nil
+}
+
 // doSomething: 模拟可能失败的操作
 func doSomething() error {
 	return errors.New("operation failed")
@@ -184,6 +184,8 @@ func PrintAll[T Stringer](items []T) {
 // - 栈只需要存储和取出元素
 // - 不需要比较或排序元素
 // - any 提供最大的灵活性
+//
+// 更多泛型容器（Set、OrderedMap、Deque）见 collections 包
 type Stack[T any] struct {
 	items []T // 使用切片存储元素
 }
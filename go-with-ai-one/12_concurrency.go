@@ -41,6 +41,9 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"go-learning/conc"
+	"go-learning/concurrent"
 )
 
 func main() {
@@ -386,18 +389,24 @@ func main() {
 	// ========================================================================
 	fmt.Println("\n--- sync.Mutex ---")
 
-	counter := &SafeCounter{}
+	// 【设计模式】mutex 和它保护的数据放在一起声明，谁访问数据谁先加锁
+	var (
+		mu    sync.Mutex // 互斥锁
+		count int        // 被保护的数据
+	)
 
 	var wg2 sync.WaitGroup
 	for i := 0; i < 1000; i++ {
 		wg2.Add(1)
 		go func() {
 			defer wg2.Done()
-			counter.Increment() // 安全地增加计数
+			mu.Lock()
+			defer mu.Unlock() // defer 确保解锁
+			count++
 		}()
 	}
 	wg2.Wait()
-	fmt.Printf("安全计数器: %d\n", counter.Value())
+	fmt.Printf("安全计数器: %d\n", count)
 
 	// ========================================================================
 	// 【sync.RWMutex】
@@ -424,9 +433,16 @@ func main() {
 	// ========================================================================
 	fmt.Println("\n--- sync.RWMutex ---")
 
-	cache := &Cache{data: make(map[string]string)}
-	cache.Set("key1", "value1")                       // 使用写锁
-	fmt.Printf("cache.Get(\"key1\") = %s\n", cache.Get("key1")) // 使用读锁
+	var rw sync.RWMutex
+	data := make(map[string]string)
+
+	rw.Lock() // 写锁：独占
+	data["key1"] = "value1"
+	rw.Unlock()
+
+	rw.RLock() // 读锁：多个读者可以同时持有
+	fmt.Printf("data[\"key1\"] = %s\n", data["key1"])
+	rw.RUnlock()
 
 	// ========================================================================
 	// 【sync.Once】
@@ -500,6 +516,43 @@ func main() {
 	wg4.Wait()
 	fmt.Printf("原子计数器: %d\n", atomicCounter)
 
+	// ========================================================================
+	// 【并发安全的容器：concurrent 包】
+	// ========================================================================
+	// 上面的 mutex + 数据、RWMutex + map 每次都要手写加锁，漏一处就是数据竞争。
+	// concurrent 包把常用的几种封装成类型，锁（或 atomic、channel）藏在方法里：
+	//
+	// | 类型                  | 替代                         |
+	// |-----------------------|------------------------------|
+	// | concurrent.Map[K, V]  | sync.Map（带类型，不用断言） |
+	// | concurrent.Counter    | 单个 atomic / mutex 计数器   |
+	// | concurrent.Queue[T]   | 多生产者时不好关闭的 channel |
+	//
+	// 详见 concurrent/doc.go，用 go test -race ./concurrent 验证
+	// ========================================================================
+	fmt.Println("\n--- concurrent 包 ---")
+
+	var sessions concurrent.Map[string, int]
+	sessions.Store("alice", 1)
+	if id, ok := sessions.Load("alice"); ok {
+		fmt.Printf("sessions.Load(\"alice\") = %d\n", id) // id 已经是 int
+	}
+
+	requests := concurrent.NewCounter(0)
+	queue := concurrent.NewQueue[int](10)
+	var wg5 sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg5.Add(1)
+		go func(i int) {
+			defer wg5.Done()
+			requests.Inc()
+			queue.TryPush(i) // 满了就丢弃，不阻塞
+		}(i)
+	}
+	wg5.Wait()
+	queue.Close()
+	fmt.Printf("请求数: %d，队列中: %d（容量 %d）\n", requests.Value(), queue.Len(), queue.Cap())
+
 	// ========================================================================
 	// 【Context】
 	// ========================================================================
@@ -579,6 +632,8 @@ func main() {
 	// 1. 扇出/扇入
 	fmt.Println("扇出/扇入模式:")
 	fanOutFanIn()
+	fmt.Println("扇出（conc.ForEach）:")
+	fanOutWithConc()
 
 	// 2. Worker Pool
 	fmt.Println("\nWorker Pool 模式:")
@@ -663,64 +718,6 @@ func square(in <-chan int) <-chan int {
 	return out
 }
 
-// ============================================================================
-// 【线程安全的计数器】
-// ============================================================================
-// 使用 Mutex 保护共享数据
-// 【设计模式】
-// - 将 mutex 和它保护的数据放在同一个结构体中
-// - 提供方法封装锁的操作
-// - 使用 defer 确保解锁
-// ============================================================================
-
-// SafeCounter: 线程安全的计数器
-type SafeCounter struct {
-	mu    sync.Mutex // 互斥锁
-	count int        // 被保护的数据
-}
-
-// Increment: 安全地增加计数
-func (c *SafeCounter) Increment() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.count++
-}
-
-// Value: 安全地读取计数
-func (c *SafeCounter) Value() int {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.count
-}
-
-// ============================================================================
-// 【带读写锁的缓存】
-// ============================================================================
-// 使用 RWMutex 优化读多写少的场景
-// - Get 用读锁，允许并发读
-// - Set 用写锁，独占写入
-// ============================================================================
-
-// Cache: 带读写锁的缓存
-type Cache struct {
-	mu   sync.RWMutex      // 读写锁
-	data map[string]string // 被保护的数据
-}
-
-// Get: 读取缓存（使用读锁）
-func (c *Cache) Get(key string) string {
-	c.mu.RLock() // 读锁
-	defer c.mu.RUnlock()
-	return c.data[key]
-}
-
-// Set: 写入缓存（使用写锁）
-func (c *Cache) Set(key, value string) {
-	c.mu.Lock() // 写锁
-	defer c.mu.Unlock()
-	c.data[key] = value
-}
-
 // ============================================================================
 // 【Context Worker】
 // ============================================================================
@@ -777,6 +774,30 @@ func fanOutFanIn() {
 	fmt.Println()
 }
 
+// fanOutWithConc: 用 conc.ForEach 完成同样的扇出
+//
+// 【和 channel 版本对比】
+// | 需求             | channel 版本                     | conc.ForEach               |
+// |------------------|----------------------------------|----------------------------|
+// | 控制并发数       | 启动几个 square 就是几个         | 第三个参数                 |
+// | 结果顺序         | 谁先算完谁先输出                 | 按下标写入，和输入顺序一致 |
+// | 某个任务出错     | 要再加一个 error channel         | 返回第一个错误，其他被取消 |
+// | 某个任务 panic   | 整个程序崩溃                     | 转成 *conc.PanicError      |
+func fanOutWithConc() {
+	nums := []int{1, 2, 3, 4, 5}
+	squares := make([]int, len(nums))
+
+	err := conc.ForEach(context.Background(), nums, 2, func(ctx context.Context, i, n int) error {
+		squares[i] = n * n // 每个 goroutine 只写自己的下标，不需要加锁
+		return nil
+	})
+	if err != nil {
+		fmt.Println("出错:", err)
+		return
+	}
+	fmt.Println(squares)
+}
+
 // merge: 合并多个 channel 为一个
 // 【实现要点】
 // - 为每个输入 channel 启动一个 goroutine
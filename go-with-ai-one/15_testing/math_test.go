@@ -38,6 +38,7 @@
 package testing_demo
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -346,20 +347,21 @@ func TestWithHelper(t *testing.T) {
 // 【Output 注释】
 // // Output: 期望的输出
 // 如果实际输出与注释不符，测试失败
+// 【注意】只捕获标准输出，必须用 fmt.Println；内置的 println 写到标准错误，测试会失败
 // ============================================================================
 
 // ExampleAdd: Add 函数的示例
 // 这会出现在 godoc 文档中
 func ExampleAdd() {
 	result := Add(2, 3)
-	println(result)
+	fmt.Println(result)
 	// Output: 5
 }
 
 // ExampleReverseString: ReverseString 函数的示例
 func ExampleReverseString() {
 	result := ReverseString("Hello")
-	println(result)
+	fmt.Println(result)
 	// Output: olleH
 }
 
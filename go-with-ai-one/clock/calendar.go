@@ -0,0 +1,134 @@
+package clock
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDate 节假日或补班日不是 2006-01-02 格式
+var ErrDate = errors.New("clock: invalid date")
+
+// CalendarConfig 工作日历的配置
+type CalendarConfig struct {
+	// Location 判断"哪一天"用的时区，默认 UTC；同一个时间点在上海已经是周一，在纽约还是周日
+	Location *time.Location
+
+	// Weekend 休息的星期，默认周六、周日
+	Weekend []time.Weekday
+
+	// Holidays 节假日，格式 2006-01-02
+	Holidays []string
+
+	// Workdays 调休补班的日期（落在周末但要上班），格式 2006-01-02；优先于 Weekend 和 Holidays
+	Workdays []string
+}
+
+// date 忽略时分秒和时区的日期
+type date struct {
+	y int
+	m time.Month
+	d int
+}
+
+// Calendar 工作日历，创建后只读，可以并发使用
+type Calendar struct {
+	loc      *time.Location
+	weekend  [7]bool
+	holidays map[date]bool
+	workdays map[date]bool
+}
+
+// NewCalendar 按配置创建工作日历
+func NewCalendar(cfg CalendarConfig) (*Calendar, error) {
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	if cfg.Weekend == nil {
+		cfg.Weekend = []time.Weekday{time.Saturday, time.Sunday}
+	}
+	c := &Calendar{loc: cfg.Location, holidays: map[date]bool{}, workdays: map[date]bool{}}
+	for _, d := range cfg.Weekend {
+		c.weekend[d] = true
+	}
+	for _, list := range []struct {
+		days []string
+		into map[date]bool
+	}{{cfg.Holidays, c.holidays}, {cfg.Workdays, c.workdays}} {
+		for _, s := range list.days {
+			t, err := time.Parse(time.DateOnly, s)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q", ErrDate, s)
+			}
+			list.into[dateOf(t)] = true
+		}
+	}
+	return c, nil
+}
+
+func dateOf(t time.Time) date {
+	y, m, d := t.Date()
+	return date{y, m, d}
+}
+
+// IsBusinessDay t 所在的那一天（按 Calendar 的时区）是否上班
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	t = t.In(c.loc)
+	d := dateOf(t)
+	if c.workdays[d] {
+		return true
+	}
+	return !c.weekend[t.Weekday()] && !c.holidays[d]
+}
+
+// AddBusinessDays 向后（n < 0 时向前）数 n 个工作日，保留时分秒；
+// n 为 0 时返回 t 本身，即使 t 不是工作日
+//
+//	周五 15:00 + 1 → 下周一 15:00
+//	周六 10:00 + 1 → 下周一 10:00（周末不算第 0 天）
+func (c *Calendar) AddBusinessDays(t time.Time, n int) time.Time {
+	t = t.In(c.loc)
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		// AddDate 而不是 Add(24h)：夏令时切换那天只有 23 或 25 小时
+		t = t.AddDate(0, 0, step)
+		if c.IsBusinessDay(t) {
+			n--
+		}
+	}
+	return t
+}
+
+// NextBusinessDay t 是工作日时返回 t，否则返回之后第一个工作日的 0 点
+func (c *Calendar) NextBusinessDay(t time.Time) time.Time {
+	t = t.In(c.loc)
+	if c.IsBusinessDay(t) {
+		return t
+	}
+	day := StartOfDay(t, c.loc)
+	for !c.IsBusinessDay(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// BusinessDaysBetween from 之后到 to（含）之间的工作日天数，to 早于 from 时为负数
+//
+//	周五 → 下周一：1；周一 → 周一：0
+func (c *Calendar) BusinessDaysBetween(from, to time.Time) int {
+	sign := 1
+	if to.Before(from) {
+		from, to, sign = to, from, -1
+	}
+	from, to = StartOfDay(from, c.loc), StartOfDay(to, c.loc)
+	n := 0
+	for d := from.AddDate(0, 0, 1); !d.After(to); d = d.AddDate(0, 0, 1) {
+		if c.IsBusinessDay(d) {
+			n++
+		}
+	}
+	return sign * n
+}
@@ -0,0 +1,87 @@
+// ============================================================================
+// clock - 可注入的时钟：真实时钟 / 测试用的假时钟、工作日计算、按用户时区换算
+// ============================================================================
+// 运行测试: go test -v ./clock
+//
+// stdlib.Time 把 now 作为参数传入，让输出可以测试。长期运行的代码（Token 过期、
+// 定时任务）不止读一次时间，还要等待：time.NewTicker、time.After 在测试里
+// 只能真的等下去。Clock 把这些都收进一个接口，生产代码用 Real，测试用 Fake：
+//
+//	type Store struct{ clock clock.Clock }
+//
+//	clk := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+//	s := &Store{clock: clk}
+//	clk.Advance(time.Hour) // Token 立即过期，不用 sleep
+//
+// 【Fake 的语义】
+//
+// | 操作                | 行为                                                         |
+// |---------------------|--------------------------------------------------------------|
+// | Now                 | 返回当前的假时间，只有 Advance / Set 会改变它                |
+// | NewTimer / After    | 假时间到达截止时间时向通道发送一次                           |
+// | NewTicker           | 每经过一个周期发送一次；通道容量 1，没人读时多余的 tick 丢弃 |
+// | Advance(d)          | 按截止时间先后依次触发，触发时 Now 等于那个截止时间          |
+// | BlockUntil(n)       | 等到至少有 n 个定时器在等待，再 Advance 就不会错过           |
+//
+// 测试里被测代码通常在另一个 goroutine 中创建 Ticker，先 BlockUntil(1)
+// 确认它已经在等待，再 Advance，否则 Advance 可能发生在 NewTicker 之前。
+//
+// 【工作日和时区】
+//
+// Calendar 按周末、节假日和调休补班日判断工作日，AddBusinessDays 计算"3 个工作日后"；
+// LoadLocation 缓存 time.LoadLocation 的结果，UserLocation 把用户保存的时区名
+// 换成 *time.Location，无效时退回默认时区。
+// ============================================================================
+package clock
+
+import "time"
+
+// Clock 读取当前时间和创建定时器，生产代码用 Real，测试用 NewFake
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer 对应 *time.Timer；C 是方法而不是字段，假时钟才能实现
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker 对应 *time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// Real 真实时钟，直接调用 time 包
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// OrReal c 为 nil 时返回 Real，给 Config.Clock 之类的可选字段设默认值
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+	return c
+}
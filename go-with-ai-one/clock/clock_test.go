@@ -0,0 +1,240 @@
+package clock
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fired 通道里是否已经有值，不阻塞
+func fired(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case t := <-c:
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFakeTimer(t *testing.T) {
+	f := NewFake(epoch)
+	tm := f.NewTimer(time.Minute)
+
+	f.Advance(59 * time.Second)
+	if _, ok := fired(tm.C()); ok {
+		t.Fatal("fired before deadline")
+	}
+	f.Advance(time.Second)
+	got, ok := fired(tm.C())
+	if !ok || !got.Equal(epoch.Add(time.Minute)) {
+		t.Fatalf("fired = %v, %v; want %v", got, ok, epoch.Add(time.Minute))
+	}
+	if f.Waiters() != 0 {
+		t.Errorf("Waiters = %d after fire; want 0", f.Waiters())
+	}
+
+	// Reset 从当前假时间重新计时
+	if tm.Reset(time.Second) {
+		t.Error("Reset of fired timer = true; want false")
+	}
+	if !tm.Stop() {
+		t.Error("Stop of pending timer = false; want true")
+	}
+	f.Advance(time.Hour)
+	if _, ok := fired(tm.C()); ok {
+		t.Error("stopped timer fired")
+	}
+
+	if _, ok := fired(f.After(0)); !ok {
+		t.Error("After(0) did not fire immediately")
+	}
+	if d := f.Since(epoch); d != time.Hour+time.Minute {
+		t.Errorf("Since = %v; want 1h1m", d)
+	}
+}
+
+func TestFakeTickerOrder(t *testing.T) {
+	f := NewFake(epoch)
+	tk := f.NewTicker(10 * time.Second)
+	defer tk.Stop()
+	tm := f.NewTimer(15 * time.Second)
+
+	// 一次 Advance 跨过多个截止时间：按先后触发，ticker 通道容量 1，多余的 tick 丢弃
+	f.Advance(25 * time.Second)
+	if got, _ := fired(tk.C()); !got.Equal(epoch.Add(10 * time.Second)) {
+		t.Errorf("first tick = %v; want +10s (later ticks dropped)", got)
+	}
+	if got, _ := fired(tm.C()); !got.Equal(epoch.Add(15 * time.Second)) {
+		t.Errorf("timer = %v; want +15s", got)
+	}
+	if !f.Now().Equal(epoch.Add(25 * time.Second)) {
+		t.Errorf("Now = %v; want +25s", f.Now())
+	}
+
+	f.Advance(5 * time.Second)
+	if got, ok := fired(tk.C()); !ok || !got.Equal(epoch.Add(30*time.Second)) {
+		t.Errorf("tick = %v, %v; want +30s", got, ok)
+	}
+
+	tk.Reset(time.Minute)
+	f.Advance(59 * time.Second)
+	if _, ok := fired(tk.C()); ok {
+		t.Error("tick before new period")
+	}
+	f.Advance(time.Second)
+	if _, ok := fired(tk.C()); !ok {
+		t.Error("no tick after new period")
+	}
+}
+
+func TestFakeSetBackward(t *testing.T) {
+	f := NewFake(epoch)
+	tm := f.NewTimer(time.Minute)
+	f.Set(epoch.Add(-time.Hour))
+	if _, ok := fired(tm.C()); ok {
+		t.Error("Set backward fired a timer")
+	}
+	f.Set(epoch.Add(time.Minute))
+	if _, ok := fired(tm.C()); !ok {
+		t.Error("Set forward past deadline did not fire")
+	}
+}
+
+func TestFakeBlockUntil(t *testing.T) {
+	f := NewFake(epoch)
+	ticks := make(chan time.Time)
+	go func() {
+		tk := f.NewTicker(time.Second)
+		defer tk.Stop()
+		ticks <- <-tk.C()
+	}()
+
+	// 不等 goroutine 建好 ticker 就 Advance，tick 可能丢失
+	f.BlockUntil(1)
+	f.Advance(time.Second)
+	select {
+	case got := <-ticks:
+		if !got.Equal(epoch.Add(time.Second)) {
+			t.Errorf("tick = %v; want +1s", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("tick not received")
+	}
+}
+
+func TestReal(t *testing.T) {
+	if OrReal(nil) != Real {
+		t.Error("OrReal(nil) != Real")
+	}
+	f := NewFake(epoch)
+	if OrReal(f) != Clock(f) {
+		t.Error("OrReal(f) != f")
+	}
+	tm := Real.NewTimer(time.Millisecond)
+	select {
+	case <-tm.C():
+	case <-time.After(time.Second):
+		t.Fatal("real timer did not fire")
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	// 2026-10-01 ~ 10-07 国庆，10-10（周六）补班
+	cal, err := NewCalendar(CalendarConfig{
+		Holidays: []string{"2026-10-01", "2026-10-02", "2026-10-05", "2026-10-06", "2026-10-07"},
+		Workdays: []string{"2026-10-10"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(d int, hour int) time.Time { return time.Date(2026, 10, d, hour, 0, 0, 0, time.UTC) }
+
+	for _, tt := range []struct {
+		d    int
+		want bool
+	}{{1, false}, {3, false}, {8, true}, {10, true}, {11, false}, {12, true}} {
+		if got := cal.IsBusinessDay(day(tt.d, 12)); got != tt.want {
+			t.Errorf("IsBusinessDay(10-%02d) = %v; want %v", tt.d, got, tt.want)
+		}
+	}
+
+	add := []struct {
+		from, n, want int
+	}{
+		{8, 1, 9},   // 周四 → 周五
+		{9, 1, 10},  // 周五 → 周六补班
+		{10, 1, 12}, // 补班 → 周一
+		{3, 1, 8},   // 假期中 → 第一个工作日
+		{12, -2, 9}, // 向前数
+		{3, 0, 3},   // n = 0 原样返回
+	}
+	for _, tt := range add {
+		if got := cal.AddBusinessDays(day(tt.from, 15), tt.n); !got.Equal(day(tt.want, 15)) {
+			t.Errorf("AddBusinessDays(10-%02d, %d) = %v; want 10-%02d 15:00", tt.from, tt.n, got, tt.want)
+		}
+	}
+
+	if got := cal.NextBusinessDay(day(4, 15)); !got.Equal(day(8, 0)) {
+		t.Errorf("NextBusinessDay(holiday) = %v; want 10-08 00:00", got)
+	}
+	if got := cal.NextBusinessDay(day(9, 15)); !got.Equal(day(9, 15)) {
+		t.Errorf("NextBusinessDay(workday) = %v; want unchanged", got)
+	}
+	if got := cal.BusinessDaysBetween(day(1, 9), day(12, 9)); got != 4 {
+		t.Errorf("BusinessDaysBetween = %d; want 4 (8, 9, 10, 12)", got)
+	}
+	if got := cal.BusinessDaysBetween(day(12, 9), day(1, 9)); got != -4 {
+		t.Errorf("BusinessDaysBetween reversed = %d; want -4", got)
+	}
+
+	if _, err := NewCalendar(CalendarConfig{Holidays: []string{"10/01/2026"}}); !errors.Is(err, ErrDate) {
+		t.Errorf("bad date err = %v; want ErrDate", err)
+	}
+}
+
+func TestCalendarLocation(t *testing.T) {
+	sh, err := LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("tzdata not available:", err)
+	}
+	cal, _ := NewCalendar(CalendarConfig{Location: sh})
+	// UTC 周日 20:00 在上海已经是周一 04:00
+	sunday := time.Date(2026, 10, 11, 20, 0, 0, 0, time.UTC)
+	if !cal.IsBusinessDay(sunday) {
+		t.Error("Sunday 20:00 UTC should be Monday in Shanghai")
+	}
+}
+
+func TestZone(t *testing.T) {
+	ny, err := LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata not available:", err)
+	}
+	again, _ := LoadLocation("America/New_York")
+	if again != ny {
+		t.Error("LoadLocation not cached")
+	}
+	for _, name := range []string{"", "Local", "Mars/Olympus"} {
+		if _, err := LoadLocation(name); !errors.Is(err, ErrZone) {
+			t.Errorf("LoadLocation(%q) err = %v; want ErrZone", name, err)
+		}
+	}
+
+	if got := UserLocation("bogus", nil); got != time.UTC {
+		t.Errorf("UserLocation(bogus, nil) = %v; want UTC", got)
+	}
+	if got := UserLocation("", ny); got != ny {
+		t.Errorf("UserLocation(\"\", ny) = %v; want fallback", got)
+	}
+
+	// 2026-11-01 纽约夏令时结束，这一天有 25 小时
+	from, to := DayRange(time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC), ny)
+	if from.Hour() != 0 || to.Sub(from) != 25*time.Hour {
+		t.Errorf("DayRange = [%v, %v); want 25h day starting 00:00", from, to)
+	}
+	if got := StartOfDay(time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC), ny); got.Day() != 31 {
+		t.Errorf("StartOfDay = %v; want 12-31 in New York", got)
+	}
+}
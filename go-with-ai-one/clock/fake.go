@@ -0,0 +1,172 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake 测试用的时钟，时间只在 Advance / Set 时前进，可以并发使用
+type Fake struct {
+	mu      sync.Mutex
+	changed *sync.Cond // 等待中的定时器数量变化时广播，BlockUntil 用
+	now     time.Time
+	timers  []*fakeTimer // 等待中的定时器和 ticker
+}
+
+// NewFake 创建停在 t 的假时钟
+func NewFake(t time.Time) *Fake {
+	f := &Fake{now: t}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now 当前的假时间
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since 距 t 经过的假时间
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// After 等价于 NewTimer(d).C()
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// NewTimer 假时间经过 d 后触发一次；d <= 0 时立即触发
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{f: f, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// NewTicker 假时间每经过 d 触发一次，d <= 0 时 panic（与 time.NewTicker 一致）
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	t := &fakeTimer{f: f, c: make(chan time.Time, 1), period: d}
+	t.Reset(d)
+	return fakeTicker{t}
+}
+
+// Advance 时间前进 d，途中到期的定时器按截止时间先后触发
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.advanceLocked(f.now.Add(d))
+}
+
+// Set 把时间设为 t；t 早于当前时间时只改时间，不触发任何定时器
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t.Before(f.now) {
+		f.now = t
+		return
+	}
+	f.advanceLocked(t)
+}
+
+func (f *Fake) advanceLocked(target time.Time) {
+	for {
+		sort.SliceStable(f.timers, func(i, j int) bool { return f.timers[i].when.Before(f.timers[j].when) })
+		if len(f.timers) == 0 || f.timers[0].when.After(target) {
+			break
+		}
+		t := f.timers[0]
+		f.now = t.when
+		select {
+		case t.c <- t.when:
+		default: // 和 time.Ticker 一样，没人读时丢弃
+		}
+		if t.period > 0 {
+			t.when = t.when.Add(t.period)
+		} else {
+			f.removeLocked(t)
+		}
+	}
+	f.now = target
+}
+
+// Waiters 等待中的定时器和 ticker 数量
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// BlockUntil 阻塞到至少有 n 个定时器在等待
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.changed.Wait()
+	}
+}
+
+func (f *Fake) addLocked(t *fakeTimer) bool {
+	for _, x := range f.timers {
+		if x == t {
+			return true
+		}
+	}
+	f.timers = append(f.timers, t)
+	f.changed.Broadcast()
+	return false
+}
+
+func (f *Fake) removeLocked(t *fakeTimer) bool {
+	for i, x := range f.timers {
+		if x == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			f.changed.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// fakeTimer Timer 和 Ticker 的共同实现，period > 0 时是 ticker
+type fakeTimer struct {
+	f      *Fake
+	c      chan time.Time
+	when   time.Time
+	period time.Duration
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+// Stop 返回定时器停止前是否还在等待
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	return t.f.removeLocked(t)
+}
+
+// Reset 从当前假时间起重新计时，返回重置前是否还在等待；
+// Ticker 的 Reset 同时修改周期
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	if t.period > 0 && d > 0 {
+		t.period = d
+	}
+	t.when = t.f.now.Add(d)
+	active := t.f.addLocked(t)
+	if d <= 0 {
+		t.f.advanceLocked(t.f.now) // 立即到期
+	}
+	return active
+}
+
+// fakeTicker Ticker 的 Stop / Reset 没有返回值
+type fakeTicker struct{ *fakeTimer }
+
+func (t fakeTicker) Stop()                 { t.fakeTimer.Stop() }
+func (t fakeTicker) Reset(d time.Duration) { t.fakeTimer.Reset(d) }
@@ -0,0 +1,62 @@
+package clock
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrZone 不认识的时区名
+var ErrZone = errors.New("clock: unknown time zone")
+
+// zones time.LoadLocation 每次都要读时区数据库，按名字缓存
+var zones sync.Map // string → *time.Location
+
+// LoadLocation 带缓存的 time.LoadLocation，名字是 IANA 时区（Asia/Shanghai），
+// 空字符串和 "Local" 不接受：用户保存的时区不能依赖服务器本地时区
+func LoadLocation(name string) (*time.Location, error) {
+	if v, ok := zones.Load(name); ok {
+		return v.(*time.Location), nil
+	}
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("%w: %q", ErrZone, name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrZone, name)
+	}
+	zones.Store(name, loc)
+	return loc, nil
+}
+
+// UserLocation 用户偏好的时区；name 为空或无效时返回 fallback（nil 时为 UTC）
+//
+// 保存时用 LoadLocation 校验，读取时用这个函数：数据库里的旧值失效（时区被 IANA 改名）
+// 也不会让接口报错
+func UserLocation(name string, fallback *time.Location) *time.Location {
+	if loc, err := LoadLocation(name); err == nil {
+		return loc
+	}
+	if fallback == nil {
+		return time.UTC
+	}
+	return fallback
+}
+
+// StartOfDay t 在 loc 时区里那一天的 0 点
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// DayRange 用户所在时区里 t 那一天的 [开始, 结束)，查询"今天的订单"用：
+//
+//	from, to := clock.DayRange(clk.Now(), clock.UserLocation(user.Timezone, nil))
+//	db.Where("created_at >= ? AND created_at < ?", from, to)
+//
+// 结束是第二天 0 点而不是开始加 24 小时，夏令时切换那天也正确
+func DayRange(t time.Time, loc *time.Location) (from, to time.Time) {
+	from = StartOfDay(t, loc)
+	return from, from.AddDate(0, 0, 1)
+}
@@ -0,0 +1,197 @@
+package collections
+
+import "testing"
+
+// ============================================================================
+// 基准测试：与直接使用 map / slice 对比
+// ============================================================================
+// go test -bench . -benchmem ./collections
+//
+// 每组 Baseline 是不用本包时的常见写法，看封装的代价或收益。
+// ============================================================================
+
+const benchN = 1024
+
+// Set 添加 + 查找 vs map[int]struct{}
+
+func BenchmarkSetAddContains(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s := NewSet[int]()
+		for j := 0; j < benchN; j++ {
+			s.Add(j)
+		}
+		for j := 0; j < benchN; j++ {
+			_ = s.Contains(j)
+		}
+	}
+}
+
+func BenchmarkSetAddContainsBaseline(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m := make(map[int]struct{})
+		for j := 0; j < benchN; j++ {
+			m[j] = struct{}{}
+		}
+		for j := 0; j < benchN; j++ {
+			_ = m[j]
+		}
+	}
+}
+
+// 交集 vs 手写双重循环
+
+func BenchmarkSetIntersection(b *testing.B) {
+	x, y := NewSet[int](), NewSet[int]()
+	for j := 0; j < benchN; j++ {
+		x.Add(j)
+		y.Add(j * 2)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = x.Intersection(y)
+	}
+}
+
+func BenchmarkSetIntersectionBaseline(b *testing.B) {
+	var x, y []int
+	for j := 0; j < benchN; j++ {
+		x = append(x, j)
+		y = append(y, j*2)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out []int
+		for _, a := range x {
+			for _, c := range y { // O(n*m)，这正是要用 Set 的原因
+				if a == c {
+					out = append(out, a)
+					break
+				}
+			}
+		}
+		_ = out
+	}
+}
+
+// OrderedMap 插入 + 按顺序遍历 vs map + 键切片
+
+func BenchmarkOrderedMapSetRange(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m := NewOrderedMap[int, int]()
+		for j := 0; j < benchN; j++ {
+			m.Set(j, j)
+		}
+		sum := 0
+		m.Range(func(_, v int) bool {
+			sum += v
+			return true
+		})
+	}
+}
+
+func BenchmarkOrderedMapSetRangeBaseline(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m := make(map[int]int)
+		var keys []int
+		for j := 0; j < benchN; j++ {
+			if _, ok := m[j]; !ok {
+				keys = append(keys, j)
+			}
+			m[j] = j
+		}
+		sum := 0
+		for _, k := range keys {
+			sum += m[k]
+		}
+	}
+}
+
+// 删除：链表 O(1) vs 在键切片里查找后删除 O(n)
+
+func BenchmarkOrderedMapDelete(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		m := NewOrderedMap[int, int]()
+		for j := 0; j < benchN; j++ {
+			m.Set(j, j)
+		}
+		b.StartTimer()
+		for j := 0; j < benchN; j += 2 {
+			m.Delete(j)
+		}
+	}
+}
+
+func BenchmarkOrderedMapDeleteBaseline(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		m := make(map[int]int)
+		keys := make([]int, 0, benchN)
+		for j := 0; j < benchN; j++ {
+			m[j] = j
+			keys = append(keys, j)
+		}
+		b.StartTimer()
+		for j := 0; j < benchN; j += 2 {
+			delete(m, j)
+			for k, key := range keys {
+				if key == j {
+					keys = append(keys[:k], keys[k+1:]...)
+					break
+				}
+			}
+		}
+	}
+}
+
+// Deque 作为 FIFO 队列：稳定状态下反复入队出队 vs 切片 append + s[1:]
+
+func BenchmarkDequeQueue(b *testing.B) {
+	b.ReportAllocs()
+	d := NewDeque[int](benchN)
+	for j := 0; j < benchN; j++ {
+		d.PushBack(j)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.PushBack(i)
+		d.PopFront()
+	}
+}
+
+func BenchmarkDequeQueueBaseline(b *testing.B) {
+	b.ReportAllocs()
+	q := make([]int, 0, benchN)
+	for j := 0; j < benchN; j++ {
+		q = append(q, j)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q = append(q, i) // 前面出队腾出的空间用不上，容量不够时整体搬迁
+		q = q[1:]
+	}
+}
+
+// 队头插入：Deque O(1) vs 切片整体后移 O(n)
+
+func BenchmarkDequePushFront(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var d Deque[int]
+		for j := 0; j < benchN; j++ {
+			d.PushFront(j)
+		}
+	}
+}
+
+func BenchmarkDequePushFrontBaseline(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var s []int
+		for j := 0; j < benchN; j++ {
+			s = append([]int{j}, s...)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package collections
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// sorted Set.Items 顺序不固定，比较前先排序
+func sorted(s *Set[int]) []int {
+	items := s.Items()
+	slices.Sort(items)
+	return items
+}
+
+// ============================================================================
+// Set
+// ============================================================================
+
+func TestSetBasic(t *testing.T) {
+	var s Set[string] // 零值可用
+	if s.Contains("a") || s.Len() != 0 {
+		t.Fatal("zero Set should be empty")
+	}
+	s.Remove("a") // nil map 上删除不 panic
+	s.Add("a", "b", "a")
+	if s.Len() != 2 || !s.Contains("a") || !s.Contains("b") {
+		t.Errorf("after Add: %v", s.Items())
+	}
+	s.Remove("a", "x")
+	if s.Len() != 1 || s.Contains("a") {
+		t.Errorf("after Remove: %v", s.Items())
+	}
+	s.Clear()
+	if s.Len() != 0 {
+		t.Errorf("after Clear: %v", s.Items())
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b       []int
+		union      []int
+		intersect  []int
+		difference []int // a - b
+		subset     bool  // a ⊆ b
+		equal      bool
+	}{
+		{"disjoint", []int{1, 2}, []int{3, 4}, []int{1, 2, 3, 4}, []int{}, []int{1, 2}, false, false},
+		{"overlap", []int{1, 2, 3}, []int{2, 3, 4}, []int{1, 2, 3, 4}, []int{2, 3}, []int{1}, false, false},
+		{"subset", []int{2}, []int{1, 2, 3}, []int{1, 2, 3}, []int{2}, []int{}, true, false},
+		{"superset", []int{1, 2, 3}, []int{2}, []int{1, 2, 3}, []int{2}, []int{1, 3}, false, false},
+		{"same size not subset", []int{1, 2}, []int{2, 3}, []int{1, 2, 3}, []int{2}, []int{1}, false, false},
+		{"equal", []int{1, 2}, []int{2, 1}, []int{1, 2}, []int{1, 2}, []int{}, true, true},
+		{"both empty", nil, nil, []int{}, []int{}, []int{}, true, true},
+		{"empty a", nil, []int{1}, []int{1}, []int{}, []int{}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := NewSet(tt.a...), NewSet(tt.b...)
+			if got := sorted(a.Union(b)); !reflect.DeepEqual(got, tt.union) {
+				t.Errorf("Union = %v; want %v", got, tt.union)
+			}
+			if got := sorted(a.Intersection(b)); !reflect.DeepEqual(got, tt.intersect) {
+				t.Errorf("Intersection = %v; want %v", got, tt.intersect)
+			}
+			if got := sorted(b.Intersection(a)); !reflect.DeepEqual(got, tt.intersect) {
+				t.Errorf("Intersection not symmetric: %v; want %v", got, tt.intersect)
+			}
+			if got := sorted(a.Difference(b)); !reflect.DeepEqual(got, tt.difference) {
+				t.Errorf("Difference = %v; want %v", got, tt.difference)
+			}
+			if got := a.IsSubset(b); got != tt.subset {
+				t.Errorf("IsSubset = %v; want %v", got, tt.subset)
+			}
+			if got := a.Equal(b); got != tt.equal {
+				t.Errorf("Equal = %v; want %v", got, tt.equal)
+			}
+			// 运算不修改原集合
+			if a.Len() != len(NewSet(tt.a...).Items()) || b.Len() != len(NewSet(tt.b...).Items()) {
+				t.Error("operands were modified")
+			}
+		})
+	}
+}
+
+func TestSetCloneAndRange(t *testing.T) {
+	s := NewSet(1, 2, 3)
+	c := s.Clone()
+	c.Add(4)
+	if s.Contains(4) {
+		t.Error("Clone shares storage with the original")
+	}
+
+	sum := 0
+	s.Range(func(item int) bool {
+		sum += item
+		return true
+	})
+	if sum != 6 {
+		t.Errorf("Range sum = %d; want 6", sum)
+	}
+
+	visited := 0
+	s.Range(func(int) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("Range visited %d after returning false; want 1", visited)
+	}
+}
+
+// ============================================================================
+// OrderedMap
+// ============================================================================
+
+func TestOrderedMap(t *testing.T) {
+	type op struct {
+		kind  string // set / delete
+		key   string
+		value int
+		want  bool // Set: updated；Delete: existed
+	}
+	tests := []struct {
+		name       string
+		ops        []op
+		wantKeys   []string
+		wantValues []int
+	}{
+		{"insertion order", []op{{"set", "c", 3, false}, {"set", "a", 1, false}, {"set", "b", 2, false}},
+			[]string{"c", "a", "b"}, []int{3, 1, 2}},
+		{"update keeps position", []op{{"set", "a", 1, false}, {"set", "b", 2, false}, {"set", "a", 10, true}},
+			[]string{"a", "b"}, []int{10, 2}},
+		{"delete middle", []op{{"set", "a", 1, false}, {"set", "b", 2, false}, {"set", "c", 3, false}, {"delete", "b", 0, true}},
+			[]string{"a", "c"}, []int{1, 3}},
+		{"delete head and tail", []op{{"set", "a", 1, false}, {"set", "b", 2, false}, {"set", "c", 3, false}, {"delete", "a", 0, true}, {"delete", "c", 0, true}},
+			[]string{"b"}, []int{2}},
+		{"delete missing", []op{{"set", "a", 1, false}, {"delete", "x", 0, false}},
+			[]string{"a"}, []int{1}},
+		{"re-insert moves to end", []op{{"set", "a", 1, false}, {"set", "b", 2, false}, {"delete", "a", 0, true}, {"set", "a", 3, false}},
+			[]string{"b", "a"}, []int{2, 3}},
+		{"delete all", []op{{"set", "a", 1, false}, {"delete", "a", 0, true}},
+			[]string{}, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewOrderedMap[string, int]()
+			for _, o := range tt.ops {
+				var got bool
+				if o.kind == "set" {
+					got = m.Set(o.key, o.value)
+				} else {
+					got = m.Delete(o.key)
+				}
+				if got != o.want {
+					t.Errorf("%s(%q) = %v; want %v", o.kind, o.key, got, o.want)
+				}
+			}
+			if keys := m.Keys(); !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("Keys = %v; want %v", keys, tt.wantKeys)
+			}
+			if values := m.Values(); !reflect.DeepEqual(values, tt.wantValues) {
+				t.Errorf("Values = %v; want %v", values, tt.wantValues)
+			}
+			if m.Len() != len(tt.wantKeys) {
+				t.Errorf("Len = %d; want %d", m.Len(), len(tt.wantKeys))
+			}
+
+			// Oldest / Newest 与 Keys 的首尾一致
+			oldest, _, okOld := m.Oldest()
+			newest, _, okNew := m.Newest()
+			if n := len(tt.wantKeys); n == 0 {
+				if okOld || okNew {
+					t.Error("Oldest/Newest on empty map should return ok=false")
+				}
+			} else if oldest != tt.wantKeys[0] || newest != tt.wantKeys[n-1] {
+				t.Errorf("Oldest, Newest = %q, %q; want %q, %q", oldest, newest, tt.wantKeys[0], tt.wantKeys[n-1])
+			}
+		})
+	}
+}
+
+func TestOrderedMapGet(t *testing.T) {
+	m := NewOrderedMap[string, int]()
+	m.Set("a", 1)
+	if v, ok := m.Get("a"); !ok || v != 1 || !m.Has("a") {
+		t.Errorf("Get(a) = %d, %v", v, ok)
+	}
+	if v, ok := m.Get("x"); ok || v != 0 || m.Has("x") {
+		t.Errorf("Get(x) = %d, %v; want zero value, false", v, ok)
+	}
+}
+
+func TestOrderedMapRange(t *testing.T) {
+	newMap := func() *OrderedMap[int, string] {
+		m := NewOrderedMap[int, string]()
+		for i, s := range []string{"a", "b", "c", "d"} {
+			m.Set(i, s)
+		}
+		return m
+	}
+	tests := []struct {
+		name     string
+		fn       func(m *OrderedMap[int, string], k int) bool
+		want     []int // 遍历到的键
+		wantKeys []int // 遍历后剩下的键
+	}{
+		{"all", func(*OrderedMap[int, string], int) bool { return true },
+			[]int{0, 1, 2, 3}, []int{0, 1, 2, 3}},
+		{"stop early", func(_ *OrderedMap[int, string], k int) bool { return k < 1 },
+			[]int{0, 1}, []int{0, 1, 2, 3}},
+		{"delete current", func(m *OrderedMap[int, string], k int) bool { m.Delete(k); return true },
+			[]int{0, 1, 2, 3}, []int{}},
+		{"append during range", func(m *OrderedMap[int, string], k int) bool {
+			if k == 3 {
+				m.Set(4, "e")
+			}
+			return true
+		}, []int{0, 1, 2, 3, 4}, []int{0, 1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMap()
+			got := []int{}
+			m.Range(func(k int, _ string) bool {
+				got = append(got, k)
+				return tt.fn(m, k)
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("visited %v; want %v", got, tt.want)
+			}
+			if keys := m.Keys(); !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("Keys after Range = %v; want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+// ============================================================================
+// Deque
+// ============================================================================
+
+func TestDeque(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  string // F=PushFront B=PushBack f=PopFront b=PopBack，数字依次作为 Push 的值
+		want []int
+	}{
+		{"queue", "BBBf", []int{1, 2}},
+		{"stack", "BBBb", []int{0, 1}},
+		{"push front reverses", "FFF", []int{2, 1, 0}},
+		{"mixed", "BFBFfb", []int{1, 0}},
+		{"pop empty", "fbB", []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d Deque[int]
+			for i, op := range tt.ops {
+				switch op {
+				case 'F':
+					d.PushFront(i)
+				case 'B':
+					d.PushBack(i)
+				case 'f':
+					d.PopFront()
+				case 'b':
+					d.PopBack()
+				}
+			}
+			if got := d.Items(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Items = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDequeEmpty(t *testing.T) {
+	var d Deque[string]
+	if _, ok := d.PopFront(); ok {
+		t.Error("PopFront on empty deque returned ok")
+	}
+	if _, ok := d.PopBack(); ok {
+		t.Error("PopBack on empty deque returned ok")
+	}
+	if _, ok := d.Front(); ok {
+		t.Error("Front on empty deque returned ok")
+	}
+	if _, ok := d.Back(); ok {
+		t.Error("Back on empty deque returned ok")
+	}
+	if len(d.Items()) != 0 {
+		t.Error("Items on empty deque not empty")
+	}
+}
+
+// TestDequeWrapAndResize 和切片实现的参照队列对比，覆盖环绕、扩容、缩容
+func TestDequeWrapAndResize(t *testing.T) {
+	d := NewDeque[int](0)
+	var ref []int
+	check := func(step string) {
+		t.Helper()
+		if !reflect.DeepEqual(d.Items(), append([]int{}, ref...)) {
+			t.Fatalf("%s: Items = %v; want %v", step, d.Items(), ref)
+		}
+		if d.Len() != len(ref) {
+			t.Fatalf("%s: Len = %d; want %d", step, d.Len(), len(ref))
+		}
+		for i := range ref {
+			if d.At(i) != ref[i] {
+				t.Fatalf("%s: At(%d) = %d; want %d", step, i, d.At(i), ref[i])
+			}
+		}
+		if len(ref) > 0 {
+			if f, _ := d.Front(); f != ref[0] {
+				t.Fatalf("%s: Front = %d; want %d", step, f, ref[0])
+			}
+			if b, _ := d.Back(); b != ref[len(ref)-1] {
+				t.Fatalf("%s: Back = %d; want %d", step, b, ref[len(ref)-1])
+			}
+		}
+	}
+
+	// 先让 head 移到中间，再两端交替入队，元素跨过数组末尾
+	for i := 0; i < 6; i++ {
+		d.PushBack(i)
+		ref = append(ref, i)
+	}
+	for i := 0; i < 4; i++ {
+		d.PopFront()
+		ref = ref[1:]
+	}
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			d.PushBack(100 + i)
+			ref = append(ref, 100+i)
+		} else {
+			d.PushFront(100 + i)
+			ref = append([]int{100 + i}, ref...)
+		}
+		check("grow")
+	}
+	grown := len(d.buf)
+
+	// 出队到只剩几个，缓冲区应该缩回去
+	for len(ref) > 3 {
+		if len(ref)%2 == 0 {
+			v, _ := d.PopFront()
+			if v != ref[0] {
+				t.Fatalf("PopFront = %d; want %d", v, ref[0])
+			}
+			ref = ref[1:]
+		} else {
+			v, _ := d.PopBack()
+			if v != ref[len(ref)-1] {
+				t.Fatalf("PopBack = %d; want %d", v, ref[len(ref)-1])
+			}
+			ref = ref[:len(ref)-1]
+		}
+		check("shrink")
+	}
+	if len(d.buf) >= grown || len(d.buf) < minDequeCap {
+		t.Errorf("buffer cap = %d after shrinking from %d", len(d.buf), grown)
+	}
+
+	d.Clear()
+	ref = nil
+	check("clear")
+}
+
+func TestNewDequeCapacity(t *testing.T) {
+	tests := []struct{ capacity, want int }{
+		{0, minDequeCap},
+		{8, 8},
+		{9, 16},
+		{100, 128},
+	}
+	for _, tt := range tests {
+		if got := len(NewDeque[int](tt.capacity).buf); got != tt.want {
+			t.Errorf("NewDeque(%d) cap = %d; want %d", tt.capacity, got, tt.want)
+		}
+	}
+}
+
+func TestDequeAtOutOfRange(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("At(-1) did not panic")
+		}
+	}()
+	d := NewDeque[int](0)
+	d.PushBack(1)
+	d.At(-1)
+}
+
+// TestDequeReleasesReferences 出队后缓冲区里不再引用元素
+func TestDequeReleasesReferences(t *testing.T) {
+	var d Deque[*int]
+	for i := 0; i < 4; i++ {
+		d.PushBack(new(int))
+	}
+	d.PopFront()
+	d.PopBack()
+	nonNil := 0
+	for _, p := range d.buf {
+		if p != nil {
+			nonNil++
+		}
+	}
+	if nonNil != d.Len() {
+		t.Errorf("buffer holds %d pointers; want %d", nonNil, d.Len())
+	}
+}
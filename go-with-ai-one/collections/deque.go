@@ -0,0 +1,157 @@
+package collections
+
+// minDequeCap 缓冲区最小容量，必须是 2 的幂
+const minDequeCap = 8
+
+// Deque 双端队列，底层是环形缓冲区
+//
+// 【环形缓冲区】
+//
+//	buf:  [ d | e | _ | _ | _ | a | b | c ]     容量 8，长度 5
+//	                ▲           ▲
+//	              tail         head
+//
+// head 指向第一个元素，元素依次排到数组末尾后绕回开头。
+// 容量保持 2 的幂，下标取模可以用 & (cap-1) 代替 %。
+// 满了扩容为两倍；元素少于容量的 1/4 时缩容为一半，避免长期占着峰值时的内存。
+//
+// 零值可以直接使用。
+type Deque[T any] struct {
+	buf   []T
+	head  int // 第一个元素的下标
+	count int
+}
+
+// NewDeque 创建至少能容纳 capacity 个元素而不扩容的 Deque
+func NewDeque[T any](capacity int) *Deque[T] {
+	c := minDequeCap
+	for c < capacity {
+		c <<= 1
+	}
+	return &Deque[T]{buf: make([]T, c)}
+}
+
+// Len 元素个数
+func (d *Deque[T]) Len() int {
+	return d.count
+}
+
+// PushBack 在队尾添加元素
+func (d *Deque[T]) PushBack(item T) {
+	d.grow()
+	d.buf[d.index(d.count)] = item
+	d.count++
+}
+
+// PushFront 在队头添加元素
+func (d *Deque[T]) PushFront(item T) {
+	d.grow()
+	d.head = d.index(len(d.buf) - 1) // head 前移一位（环绕）
+	d.buf[d.head] = item
+	d.count++
+}
+
+// PopFront 移除并返回队头元素；为空时返回零值和 false
+func (d *Deque[T]) PopFront() (T, bool) {
+	var zero T
+	if d.count == 0 {
+		return zero, false
+	}
+	item := d.buf[d.head]
+	d.buf[d.head] = zero // 清掉引用，否则元素是指针时无法被 GC
+	d.head = d.index(1)
+	d.count--
+	d.shrink()
+	return item, true
+}
+
+// PopBack 移除并返回队尾元素；为空时返回零值和 false
+func (d *Deque[T]) PopBack() (T, bool) {
+	var zero T
+	if d.count == 0 {
+		return zero, false
+	}
+	i := d.index(d.count - 1)
+	item := d.buf[i]
+	d.buf[i] = zero
+	d.count--
+	d.shrink()
+	return item, true
+}
+
+// Front 查看队头元素（不移除）
+func (d *Deque[T]) Front() (T, bool) {
+	if d.count == 0 {
+		var zero T
+		return zero, false
+	}
+	return d.buf[d.head], true
+}
+
+// Back 查看队尾元素（不移除）
+func (d *Deque[T]) Back() (T, bool) {
+	if d.count == 0 {
+		var zero T
+		return zero, false
+	}
+	return d.buf[d.index(d.count-1)], true
+}
+
+// At 第 i 个元素（0 是队头），越界时 panic，和切片下标一致
+func (d *Deque[T]) At(i int) T {
+	if i < 0 || i >= d.count {
+		panic("collections: Deque index out of range")
+	}
+	return d.buf[d.index(i)]
+}
+
+// Clear 删除所有元素，保留当前容量
+func (d *Deque[T]) Clear() {
+	clear(d.buf)
+	d.head, d.count = 0, 0
+}
+
+// Items 从队头到队尾的所有元素
+func (d *Deque[T]) Items() []T {
+	items := make([]T, d.count)
+	d.copyTo(items)
+	return items
+}
+
+// index 第 i 个元素在 buf 中的下标
+func (d *Deque[T]) index(i int) int {
+	return (d.head + i) & (len(d.buf) - 1)
+}
+
+// grow 缓冲区满时扩容为两倍（零值 Deque 第一次分配 minDequeCap）
+func (d *Deque[T]) grow() {
+	if d.count < len(d.buf) {
+		return
+	}
+	d.resize(max(len(d.buf)*2, minDequeCap))
+}
+
+// shrink 元素少于容量的 1/4 时缩容为一半
+// 用 1/4 而不是 1/2 作为阈值：在临界点反复 push / pop 时不会每次都扩缩容
+func (d *Deque[T]) shrink() {
+	if len(d.buf) > minDequeCap && d.count <= len(d.buf)/4 {
+		d.resize(len(d.buf) / 2)
+	}
+}
+
+// resize 把元素按顺序搬到新缓冲区的开头
+func (d *Deque[T]) resize(n int) {
+	buf := make([]T, n)
+	d.copyTo(buf)
+	d.buf = buf
+	d.head = 0
+}
+
+// copyTo 按顺序复制所有元素：元素可能分成 [head, end) 和 [0, rest) 两段
+func (d *Deque[T]) copyTo(dst []T) {
+	if d.count == 0 {
+		return
+	}
+	n := copy(dst, d.buf[d.head:min(d.head+d.count, len(d.buf))])
+	copy(dst[n:], d.buf[:d.count-n])
+}
@@ -0,0 +1,30 @@
+// ============================================================================
+// collections - 泛型容器（11_generics.go 中 Stack 的延续）
+// ============================================================================
+// 运行测试: go test -v -cover ./collections
+// 基准测试: go test -bench . -benchmem ./collections
+//
+// 【提供的容器】
+// | 类型                   | 底层结构              | 解决的问题                                 |
+// |------------------------|-----------------------|--------------------------------------------|
+// | Set[T comparable]      | map[T]struct{}        | 去重、并集 / 交集 / 差集                   |
+// | OrderedMap[K, V]       | map + 双向链表        | map 遍历顺序随机，需要按插入顺序输出       |
+// | Deque[T any]           | 环形缓冲区            | 两端 O(1) 入队出队，出队后内存可以复用     |
+//
+// 【和直接用 map / slice 比】
+// Set 只是给 map[T]struct{} 加上集合运算，性能与裸 map 相同。
+// OrderedMap 每个元素多一个链表节点，换来稳定的遍历顺序和 O(1) 删除；
+// 用 "map + 键切片" 实现时删除要在切片里线性查找。
+// Deque 对比 "append 入队 + s = s[1:] 出队"：切片出队后前面的空间不能复用，
+// 底层数组只能靠下一次 append 扩容时整体搬走；环形缓冲区在原数组里循环使用。
+// 具体数字见 bench_test.go。
+//
+// 【遍历】
+// go.mod 是 go 1.21，还没有 range-over-func（1.23 起），
+// 所以遍历用 sync.Map 风格的 Range(func(...) bool)，返回 false 停止；
+// 需要切片时用 Items / Keys / Values（返回副本，修改不影响容器）。
+//
+// 【并发】
+// 和内置 map、slice 一样都不是并发安全的，多个 goroutine 使用时调用方加锁。
+// ============================================================================
+package collections
@@ -0,0 +1,135 @@
+package collections
+
+// OrderedMap 按插入顺序遍历的 map
+//
+// 【结构】
+//
+//	index: map[K]*entry ──┐ O(1) 查找
+//	                      ▼
+//	head ⇄ entry ⇄ entry ⇄ entry ⇄ tail   双向链表记录顺序，O(1) 删除
+//
+// 更新已存在的键只改值，不改变位置；删除后重新 Set 会排到最后。
+// 零值不能直接使用，用 NewOrderedMap 创建。
+type OrderedMap[K comparable, V any] struct {
+	index      map[K]*entry[K, V]
+	head, tail *entry[K, V]
+}
+
+type entry[K comparable, V any] struct {
+	key        K
+	value      V
+	prev, next *entry[K, V]
+}
+
+// NewOrderedMap 创建空的 OrderedMap
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{index: make(map[K]*entry[K, V])}
+}
+
+// Set 设置键值；键已存在时只更新值，返回 true
+func (m *OrderedMap[K, V]) Set(key K, value V) (updated bool) {
+	if e, ok := m.index[key]; ok {
+		e.value = value
+		return true
+	}
+	e := &entry[K, V]{key: key, value: value, prev: m.tail}
+	if m.tail == nil {
+		m.head = e
+	} else {
+		m.tail.next = e
+	}
+	m.tail = e
+	m.index[key] = e
+	return false
+}
+
+// Get 读取键对应的值，不存在时返回零值和 false
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	if e, ok := m.index[key]; ok {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Has 是否包含键
+func (m *OrderedMap[K, V]) Has(key K) bool {
+	_, ok := m.index[key]
+	return ok
+}
+
+// Delete 删除键，返回键是否存在
+func (m *OrderedMap[K, V]) Delete(key K) bool {
+	e, ok := m.index[key]
+	if !ok {
+		return false
+	}
+	delete(m.index, key)
+	if e.prev == nil {
+		m.head = e.next
+	} else {
+		e.prev.next = e.next
+	}
+	if e.next == nil {
+		m.tail = e.prev
+	} else {
+		e.next.prev = e.prev
+	}
+	e.prev, e.next = nil, nil // 断开引用，便于 GC
+	return true
+}
+
+// Len 键值对个数
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.index)
+}
+
+// Oldest 最早插入的键值对；为空时 ok 为 false
+func (m *OrderedMap[K, V]) Oldest() (key K, value V, ok bool) {
+	if m.head == nil {
+		return key, value, false
+	}
+	return m.head.key, m.head.value, true
+}
+
+// Newest 最后插入的键值对；为空时 ok 为 false
+func (m *OrderedMap[K, V]) Newest() (key K, value V, ok bool) {
+	if m.tail == nil {
+		return key, value, false
+	}
+	return m.tail.key, m.tail.value, true
+}
+
+// Range 按插入顺序遍历，fn 返回 false 时停止
+//
+// 遍历过程中可以删除当前键；新增的键也会被遍历到（排在最后）。
+func (m *OrderedMap[K, V]) Range(fn func(key K, value V) bool) {
+	for e := m.head; e != nil; {
+		next := e.next // 先记下，fn 里可能删除 e
+		if !fn(e.key, e.value) {
+			return
+		}
+		if next == nil {
+			next = e.next // e 原本是最后一个，fn 里追加了新键
+		}
+		e = next
+	}
+}
+
+// Keys 按插入顺序返回所有键
+func (m *OrderedMap[K, V]) Keys() []K {
+	keys := make([]K, 0, len(m.index))
+	for e := m.head; e != nil; e = e.next {
+		keys = append(keys, e.key)
+	}
+	return keys
+}
+
+// Values 按插入顺序返回所有值
+func (m *OrderedMap[K, V]) Values() []V {
+	values := make([]V, 0, len(m.index))
+	for e := m.head; e != nil; e = e.next {
+		values = append(values, e.value)
+	}
+	return values
+}
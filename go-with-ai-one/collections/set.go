@@ -0,0 +1,134 @@
+package collections
+
+// Set 无序集合
+//
+// 零值可以直接使用：
+//
+//	var s collections.Set[string]
+//	s.Add("go")
+//
+// 集合运算（Union、Intersection、Difference）返回新集合，不修改参与运算的集合。
+type Set[T comparable] struct {
+	m map[T]struct{} // struct{} 不占内存，比 map[T]bool 省空间
+}
+
+// NewSet 创建包含 items 的集合，重复元素只保留一个
+func NewSet[T comparable](items ...T) *Set[T] {
+	s := &Set[T]{m: make(map[T]struct{}, len(items))}
+	s.Add(items...)
+	return s
+}
+
+// Add 添加元素，已存在的元素忽略
+func (s *Set[T]) Add(items ...T) {
+	if s.m == nil {
+		s.m = make(map[T]struct{}, len(items))
+	}
+	for _, item := range items {
+		s.m[item] = struct{}{}
+	}
+}
+
+// Remove 删除元素，不存在时什么也不做
+func (s *Set[T]) Remove(items ...T) {
+	for _, item := range items {
+		delete(s.m, item) // 对 nil map 调用 delete 是安全的
+	}
+}
+
+// Contains 是否包含 item
+func (s *Set[T]) Contains(item T) bool {
+	_, ok := s.m[item]
+	return ok
+}
+
+// Len 元素个数
+func (s *Set[T]) Len() int {
+	return len(s.m)
+}
+
+// Clear 删除所有元素
+func (s *Set[T]) Clear() {
+	clear(s.m)
+}
+
+// Items 所有元素，顺序不固定
+func (s *Set[T]) Items() []T {
+	items := make([]T, 0, len(s.m))
+	for item := range s.m {
+		items = append(items, item)
+	}
+	return items
+}
+
+// Range 遍历所有元素，fn 返回 false 时停止；顺序不固定
+func (s *Set[T]) Range(fn func(item T) bool) {
+	for item := range s.m {
+		if !fn(item) {
+			return
+		}
+	}
+}
+
+// Clone 复制一个集合
+func (s *Set[T]) Clone() *Set[T] {
+	c := &Set[T]{m: make(map[T]struct{}, len(s.m))}
+	for item := range s.m {
+		c.m[item] = struct{}{}
+	}
+	return c
+}
+
+// Union 并集：s ∪ other
+func (s *Set[T]) Union(other *Set[T]) *Set[T] {
+	result := s.Clone()
+	for item := range other.m {
+		result.m[item] = struct{}{}
+	}
+	return result
+}
+
+// Intersection 交集：s ∩ other
+func (s *Set[T]) Intersection(other *Set[T]) *Set[T] {
+	// 遍历较小的集合，另一个只做查找
+	small, large := s, other
+	if small.Len() > large.Len() {
+		small, large = large, small
+	}
+	result := &Set[T]{m: make(map[T]struct{})}
+	for item := range small.m {
+		if large.Contains(item) {
+			result.m[item] = struct{}{}
+		}
+	}
+	return result
+}
+
+// Difference 差集：在 s 中但不在 other 中的元素
+func (s *Set[T]) Difference(other *Set[T]) *Set[T] {
+	result := &Set[T]{m: make(map[T]struct{})}
+	for item := range s.m {
+		if !other.Contains(item) {
+			result.m[item] = struct{}{}
+		}
+	}
+	return result
+}
+
+// IsSubset s 的每个元素是否都在 other 中
+func (s *Set[T]) IsSubset(other *Set[T]) bool {
+	if s.Len() > other.Len() {
+		return false
+	}
+	for item := range s.m {
+		if !other.Contains(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal 两个集合的元素是否完全相同
+func (s *Set[T]) Equal(other *Set[T]) bool {
+	return s.Len() == other.Len() && s.IsSubset(other)
+}
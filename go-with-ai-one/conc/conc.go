@@ -0,0 +1,184 @@
+// ============================================================================
+// conc - 结构化并发：限制并发数、出错取消、panic 转 error
+// ============================================================================
+// 运行测试: go test -race -v ./conc
+//
+// 【手写 WaitGroup 的问题】
+// | 问题                       | 后果                                   | Group 的做法                 |
+// |----------------------------|----------------------------------------|------------------------------|
+// | 错误只能自己收集           | 每处都要 mutex + 切片，或者干脆忽略    | Wait 返回第一个错误          |
+// | 一个失败了其他还在跑       | 请求早该返回了，资源还在消耗           | 第一个错误取消 ctx           |
+// | 每个元素开一个 goroutine   | 下游连接池、文件句柄被打满             | SetLimit(n) 最多 n 个同时运行|
+// | goroutine 里 panic         | 整个进程崩溃，外层的 recover 接不住    | 转成 *PanicError 返回        |
+//
+// 【用法】
+//
+//	g, ctx := conc.WithContext(ctx)
+//	g.SetLimit(4)
+//	for _, url := range urls {
+//	    url := url // go.mod 是 1.21，循环变量在各轮之间共享
+//	    g.Go(func() error { return fetch(ctx, url) })
+//	}
+//	if err := g.Wait(); err != nil { ... }
+//
+// 处理切片时用 ForEach，不用自己写循环：
+//
+//	err := conc.ForEach(ctx, files, 4, func(ctx context.Context, i int, f File) error {
+//	    results[i] = process(ctx, f) // 每个 goroutine 只写自己的下标，不需要加锁
+//	    return nil
+//	})
+//
+// 和 golang.org/x/sync/errgroup 的 API 一致，多了 panic 转换和 ForEach。
+// ============================================================================
+package conc
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError goroutine 中的 panic 被转换成的错误
+type PanicError struct {
+	Value any    // recover() 的返回值
+	Stack []byte // panic 时的调用栈，排查问题时打到日志里
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("conc: panic: %v", e.Value)
+}
+
+// Unwrap panic 的值本身是 error 时（如 panic(err)），errors.Is / As 可以匹配到它
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Group 一组 goroutine，等待全部结束并返回第一个错误
+//
+// 零值可以直接使用，但不会在出错时取消任何东西；需要取消时用 WithContext 创建。
+// Group 使用后不能复制。
+type Group struct {
+	cancel func(error)
+	wg     sync.WaitGroup
+	sem    chan struct{} // 并发上限；nil 表示不限制
+
+	errOnce sync.Once
+	err     error
+}
+
+// WithContext 创建 Group 和派生的 ctx
+// 任意一个函数返回错误（或 panic）、或者 Wait 返回时，ctx 被取消；
+// context.Cause(ctx) 可以拿到导致取消的错误
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// SetLimit 最多同时运行 n 个函数，n < 0 表示不限制
+//
+// 必须在调用 Go 之前设置；有函数正在运行时修改会 panic。
+func (g *Group) SetLimit(n int) {
+	if n == 0 {
+		panic("conc: limit must not be zero")
+	}
+	if len(g.sem) != 0 {
+		panic(fmt.Errorf("conc: modify limit while %d goroutines are still active", len(g.sem)))
+	}
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go 在新的 goroutine 中运行 f；达到并发上限时阻塞，直到有函数结束
+func (g *Group) Go(f func() error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.start(f)
+}
+
+// TryGo 未达到并发上限时启动 f 并返回 true，否则不启动、返回 false
+func (g *Group) TryGo(f func() error) bool {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	g.start(f)
+	return true
+}
+
+// Wait 等待所有函数结束，返回第一个错误
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(g.err)
+	}
+	return g.err
+}
+
+func (g *Group) start(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.done()
+		if err := run(f); err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				if g.cancel != nil {
+					g.cancel(err)
+				}
+			})
+		}
+	}()
+}
+
+func (g *Group) done() {
+	if g.sem != nil {
+		<-g.sem
+	}
+	g.wg.Done()
+}
+
+// run 调用 f，把 panic 转换成 *PanicError
+func run(f func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return f()
+}
+
+// ForEach 并发处理 items 中的每个元素，最多 limit 个同时运行（limit <= 0 表示不限制）
+//
+// fn 收到元素的下标，可以直接写入预先分配好的结果切片的对应位置。
+// 第一个错误出现后 ctx 被取消，还没开始的元素不再处理；返回第一个错误。
+func ForEach[T any](ctx context.Context, items []T, limit int, fn func(ctx context.Context, i int, item T) error) error {
+	parent := ctx
+	g, ctx := WithContext(ctx)
+	if limit > 0 {
+		g.SetLimit(limit)
+	}
+	for i, item := range items {
+		if ctx.Err() != nil {
+			break // 已经有函数失败，或者调用方取消了
+		}
+		i, item := i, item
+		g.Go(func() error {
+			return fn(ctx, i, item)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	// 调用方取消时可能没有函数返回错误，但后面的元素没有处理
+	return parent.Err()
+}
@@ -0,0 +1,253 @@
+package conc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errBoom = errors.New("boom")
+
+func TestGroupWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		fns     []func() error
+		wantErr error
+	}{
+		{"all succeed", []func() error{
+			func() error { return nil },
+			func() error { return nil },
+		}, nil},
+		{"first error wins", []func() error{
+			func() error { return errBoom },
+			func() error { time.Sleep(20 * time.Millisecond); return errors.New("later") },
+		}, errBoom},
+		{"panic with error value", []func() error{
+			func() error { panic(errBoom) },
+		}, errBoom},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g Group // 零值可用
+			for _, fn := range tt.fns {
+				g.Go(fn)
+			}
+			if err := g.Wait(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Wait = %v; want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPanicError(t *testing.T) {
+	var g Group
+	g.Go(func() error { panic("bad index") })
+	err := g.Wait()
+
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Wait = %v; want *PanicError", err)
+	}
+	if pe.Value != "bad index" || !strings.Contains(err.Error(), "bad index") {
+		t.Errorf("PanicError = %v", pe)
+	}
+	if !strings.Contains(string(pe.Stack), "conc_test.go") {
+		t.Error("stack does not point at the panicking function")
+	}
+	if pe.Unwrap() != nil {
+		t.Error("Unwrap of a non-error panic value should be nil")
+	}
+}
+
+func TestWithContextCancel(t *testing.T) {
+	g, ctx := WithContext(context.Background())
+	g.Go(func() error { return errBoom })
+	g.Go(func() error {
+		<-ctx.Done() // 另一个函数失败后收到取消
+		return ctx.Err()
+	})
+	if err := g.Wait(); !errors.Is(err, errBoom) {
+		t.Errorf("Wait = %v; want %v", err, errBoom)
+	}
+	if cause := context.Cause(ctx); !errors.Is(cause, errBoom) {
+		t.Errorf("Cause = %v; want %v", cause, errBoom)
+	}
+
+	// 全部成功时 Wait 返回后 ctx 也被取消，防止泄漏
+	g, ctx = WithContext(context.Background())
+	g.Go(func() error { return nil })
+	if err := g.Wait(); err != nil || ctx.Err() == nil {
+		t.Errorf("Wait = %v, ctx.Err = %v; want nil, canceled", err, ctx.Err())
+	}
+}
+
+// track 记录同时运行的函数个数的最大值
+type track struct {
+	running, peak atomic.Int32
+}
+
+func (tr *track) do() {
+	n := tr.running.Add(1)
+	for {
+		p := tr.peak.Load()
+		if n <= p || tr.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	tr.running.Add(-1)
+}
+
+func TestSetLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		wantPeak int32 // 0 表示只要求大于 1
+	}{
+		{"limit 1 is serial", 1, 1},
+		{"limit 3", 3, 3},
+		{"unlimited", -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g Group
+			g.SetLimit(tt.limit)
+			var tr track
+			for i := 0; i < 10; i++ {
+				g.Go(func() error { tr.do(); return nil })
+			}
+			_ = g.Wait()
+			peak := tr.peak.Load()
+			if (tt.wantPeak != 0 && peak != tt.wantPeak) || (tt.wantPeak == 0 && peak <= 1) {
+				t.Errorf("peak concurrency = %d; want %d", peak, tt.wantPeak)
+			}
+		})
+	}
+}
+
+func TestSetLimitPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(g *Group)
+	}{
+		{"zero", func(g *Group) { g.SetLimit(0) }},
+		{"while running", func(g *Group) {
+			g.SetLimit(1)
+			block := make(chan struct{})
+			defer close(block)
+			g.Go(func() error { <-block; return nil })
+			g.SetLimit(2)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g Group
+			defer func() {
+				if recover() == nil {
+					t.Error("SetLimit did not panic")
+				}
+				_ = g.Wait()
+			}()
+			tt.fn(&g)
+		})
+	}
+}
+
+func TestTryGo(t *testing.T) {
+	var g Group
+	g.SetLimit(1)
+	block := make(chan struct{})
+	if !g.TryGo(func() error { <-block; return nil }) {
+		t.Fatal("first TryGo should start")
+	}
+	if g.TryGo(func() error { return nil }) {
+		t.Error("TryGo over the limit should not start")
+	}
+	close(block)
+	_ = g.Wait()
+	if !g.TryGo(func() error { return nil }) {
+		t.Error("TryGo after Wait should start")
+	}
+	_ = g.Wait()
+
+	var unlimited Group
+	if !unlimited.TryGo(func() error { return nil }) {
+		t.Error("TryGo without limit should always start")
+	}
+	_ = unlimited.Wait()
+}
+
+func TestForEach(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+
+	t.Run("results by index", func(t *testing.T) {
+		squares := make([]int, len(items))
+		var tr track
+		err := ForEach(context.Background(), items, 2, func(_ context.Context, i, n int) error {
+			tr.do()
+			squares[i] = n * n
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, n := range items {
+			if squares[i] != n*n {
+				t.Errorf("squares[%d] = %d; want %d", i, squares[i], n*n)
+			}
+		}
+		if tr.peak.Load() > 2 {
+			t.Errorf("peak concurrency = %d; want <= 2", tr.peak.Load())
+		}
+	})
+
+	t.Run("stops after first error", func(t *testing.T) {
+		var started atomic.Int32
+		err := ForEach(context.Background(), items, 1, func(ctx context.Context, _ int, n int) error {
+			started.Add(1)
+			if n == 2 {
+				return errBoom
+			}
+			return nil
+		})
+		if !errors.Is(err, errBoom) {
+			t.Errorf("err = %v; want %v", err, errBoom)
+		}
+		if n := started.Load(); n >= int32(len(items)) {
+			t.Errorf("started %d items; later items should be skipped", n)
+		}
+	})
+
+	t.Run("caller cancels", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		err := ForEach(ctx, items, 1, func(_ context.Context, i int, _ int) error {
+			if i == 1 {
+				cancel()
+			}
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v; want context.Canceled", err)
+		}
+	})
+
+	t.Run("unlimited and empty", func(t *testing.T) {
+		var sum atomic.Int64
+		err := ForEach(context.Background(), items, 0, func(_ context.Context, _ int, n int) error {
+			sum.Add(int64(n))
+			return nil
+		})
+		if err != nil || sum.Load() != 36 {
+			t.Errorf("err = %v, sum = %d; want nil, 36", err, sum.Load())
+		}
+		if err := ForEach(context.Background(), []int(nil), 2, func(context.Context, int, int) error {
+			t.Error("fn called for empty slice")
+			return nil
+		}); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,342 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 这些测试要配合 -race 运行：go test -race ./concurrent
+
+// ============================================================================
+// Map
+// ============================================================================
+
+func TestMap(t *testing.T) {
+	var m Map[string, int]
+
+	if v, ok := m.Load("a"); ok || v != 0 {
+		t.Errorf("Load on empty map = %d, %v", v, ok)
+	}
+	m.Store("a", 1)
+	if v, ok := m.Load("a"); !ok || v != 1 {
+		t.Errorf("Load(a) = %d, %v; want 1, true", v, ok)
+	}
+
+	tests := []struct {
+		name       string
+		op         func() (int, bool)
+		wantValue  int
+		wantLoaded bool
+		wantAfter  int // 操作后 Load("a") 的值，-1 表示不存在
+	}{
+		{"LoadOrStore existing", func() (int, bool) { return m.LoadOrStore("a", 9) }, 1, true, 1},
+		{"Swap existing", func() (int, bool) { return m.Swap("a", 2) }, 1, true, 2},
+		{"CompareAndSwap mismatch", func() (int, bool) { return 0, m.CompareAndSwap("a", 1, 3) }, 0, false, 2},
+		{"CompareAndSwap match", func() (int, bool) { return 0, m.CompareAndSwap("a", 2, 3) }, 0, true, 3},
+		{"CompareAndDelete mismatch", func() (int, bool) { return 0, m.CompareAndDelete("a", 2) }, 0, false, 3},
+		{"LoadAndDelete existing", func() (int, bool) { return m.LoadAndDelete("a") }, 3, true, -1},
+		{"LoadAndDelete missing", func() (int, bool) { return m.LoadAndDelete("a") }, 0, false, -1},
+		{"Swap missing", func() (int, bool) { return m.Swap("a", 4) }, 0, false, 4},
+		{"CompareAndDelete match", func() (int, bool) { return 0, m.CompareAndDelete("a", 4) }, 0, true, -1},
+		{"LoadOrStore missing", func() (int, bool) { return m.LoadOrStore("a", 5) }, 5, false, 5},
+	}
+	for _, tt := range tests {
+		v, loaded := tt.op()
+		if v != tt.wantValue || loaded != tt.wantLoaded {
+			t.Errorf("%s = %d, %v; want %d, %v", tt.name, v, loaded, tt.wantValue, tt.wantLoaded)
+		}
+		after, ok := m.Load("a")
+		if (tt.wantAfter == -1 && ok) || (tt.wantAfter != -1 && after != tt.wantAfter) {
+			t.Errorf("%s: Load(a) = %d, %v afterwards; want %d", tt.name, after, ok, tt.wantAfter)
+		}
+	}
+
+	m.Delete("a")
+	if m.Len() != 0 {
+		t.Errorf("Len after Delete = %d", m.Len())
+	}
+}
+
+func TestMapRange(t *testing.T) {
+	var m Map[int, string]
+	for i, s := range []string{"a", "b", "c"} {
+		m.Store(i, s)
+	}
+	if m.Len() != 3 {
+		t.Errorf("Len = %d; want 3", m.Len())
+	}
+
+	var keys []int
+	m.Range(func(k int, v string) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Ints(keys)
+	if len(keys) != 3 || keys[0] != 0 || keys[2] != 2 {
+		t.Errorf("Range keys = %v", keys)
+	}
+
+	visited := 0
+	m.Range(func(int, string) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("Range visited %d after returning false; want 1", visited)
+	}
+}
+
+// TestMapLoadOrStoreRace 多个 goroutine 同时初始化同一个键，所有人拿到同一个值
+func TestMapLoadOrStoreRace(t *testing.T) {
+	var m Map[string, *int]
+	const n = 50
+	results := make([]*int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v := i
+			results[i], _ = m.LoadOrStore("key", &v)
+		}(i)
+	}
+	wg.Wait()
+	for i := 1; i < n; i++ {
+		if results[i] != results[0] {
+			t.Fatalf("goroutine %d got a different value", i)
+		}
+	}
+}
+
+// ============================================================================
+// Counter
+// ============================================================================
+
+func TestCounter(t *testing.T) {
+	tests := []struct {
+		name       string
+		shards     int
+		wantShards int
+	}{
+		{"default to GOMAXPROCS", 0, 0},
+		{"single shard", 1, 1},
+		{"round up to power of two", 5, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCounter(tt.shards)
+			n := len(c.shards)
+			if n&(n-1) != 0 || (tt.wantShards != 0 && n != tt.wantShards) {
+				t.Fatalf("shards = %d; want power of two %d", n, tt.wantShards)
+			}
+
+			const goroutines, perG = 16, 1000
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < perG; i++ {
+						c.Inc()
+					}
+					c.Add(-10)
+				}()
+			}
+			wg.Wait()
+
+			want := int64(goroutines * (perG - 10))
+			if got := c.Value(); got != want {
+				t.Errorf("Value = %d; want %d", got, want)
+			}
+			if got := c.Reset(); got != want {
+				t.Errorf("Reset = %d; want %d", got, want)
+			}
+			if got := c.Value(); got != 0 {
+				t.Errorf("Value after Reset = %d", got)
+			}
+		})
+	}
+}
+
+// ============================================================================
+// Queue
+// ============================================================================
+
+func TestQueueNonBlocking(t *testing.T) {
+	q := NewQueue[int](2)
+	steps := []struct {
+		name string
+		do   func() bool
+		want bool
+	}{
+		{"pop empty", func() bool { _, ok := q.TryPop(); return ok }, false},
+		{"push 1", func() bool { return q.TryPush(1) }, true},
+		{"push 2", func() bool { return q.TryPush(2) }, true},
+		{"push full", func() bool { return q.TryPush(3) }, false},
+		{"pop 1", func() bool { v, ok := q.TryPop(); return ok && v == 1 }, true},
+		{"close then push", func() bool { q.Close(); return q.TryPush(4) }, false},
+		{"pop remaining after close", func() bool { v, ok := q.TryPop(); return ok && v == 2 }, true},
+		{"pop drained", func() bool { _, ok := q.TryPop(); return ok }, false},
+	}
+	for _, s := range steps {
+		if got := s.do(); got != s.want {
+			t.Fatalf("%s = %v; want %v", s.name, got, s.want)
+		}
+	}
+	if q.Cap() != 2 || q.Len() != 0 {
+		t.Errorf("Cap, Len = %d, %d; want 2, 0", q.Cap(), q.Len())
+	}
+}
+
+func TestQueueBlocking(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(q *Queue[int]) // 队列容量 1
+		run     func(ctx context.Context, q *Queue[int]) error
+		wantErr error
+	}{
+		{"push to full times out",
+			func(q *Queue[int]) { q.TryPush(1) },
+			func(ctx context.Context, q *Queue[int]) error { return q.Push(ctx, 2) },
+			context.DeadlineExceeded},
+		{"pop from empty times out",
+			func(*Queue[int]) {},
+			func(ctx context.Context, q *Queue[int]) error { _, err := q.Pop(ctx); return err },
+			context.DeadlineExceeded},
+		{"push after close",
+			func(q *Queue[int]) { q.Close() },
+			func(ctx context.Context, q *Queue[int]) error { return q.Push(ctx, 1) },
+			ErrClosed},
+		{"pop after close drains first",
+			func(q *Queue[int]) { q.TryPush(1); q.Close() },
+			func(ctx context.Context, q *Queue[int]) error {
+				if v, err := q.Pop(ctx); err != nil || v != 1 {
+					return errors.New("remaining item not returned")
+				}
+				_, err := q.Pop(ctx)
+				return err
+			},
+			ErrClosed},
+		{"blocked push woken by close",
+			func(q *Queue[int]) {
+				q.TryPush(1)
+				time.AfterFunc(10*time.Millisecond, q.Close)
+			},
+			func(ctx context.Context, q *Queue[int]) error { return q.Push(ctx, 2) },
+			ErrClosed},
+		{"blocked pop woken by close",
+			func(q *Queue[int]) { time.AfterFunc(10*time.Millisecond, q.Close) },
+			func(ctx context.Context, q *Queue[int]) error { _, err := q.Pop(ctx); return err },
+			ErrClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQueue[int](1)
+			tt.prepare(q)
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if err := tt.run(ctx, q); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v; want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestQueueMPMC 多个生产者、多个消费者，每个元素恰好被取到一次
+func TestQueueMPMC(t *testing.T) {
+	const producers, consumers, perProducer = 4, 4, 500
+	q := NewQueue[int](8) // 容量远小于总量，生产者会被背压阻塞
+	ctx := context.Background()
+
+	var seen [producers * perProducer]atomic.Int32
+	var consumed sync.WaitGroup
+	for c := 0; c < consumers; c++ {
+		consumed.Add(1)
+		go func() {
+			defer consumed.Done()
+			for {
+				v, err := q.Pop(ctx)
+				if errors.Is(err, ErrClosed) {
+					return
+				}
+				seen[v].Add(1)
+			}
+		}()
+	}
+
+	var produced sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		produced.Add(1)
+		go func(p int) {
+			defer produced.Done()
+			for i := 0; i < perProducer; i++ {
+				if err := q.Push(ctx, p*perProducer+i); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(p)
+	}
+
+	produced.Wait()
+	q.Close()
+	q.Close() // 重复关闭不 panic
+	consumed.Wait()
+
+	for v := range seen {
+		if n := seen[v].Load(); n != 1 {
+			t.Fatalf("item %d consumed %d times", v, n)
+		}
+	}
+}
+
+func TestNewQueuePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewQueue(0) did not panic")
+		}
+	}()
+	NewQueue[int](0)
+}
+
+// ============================================================================
+// 基准测试：Counter vs 单个 atomic.Int64 vs Mutex
+// ============================================================================
+// go test -bench Counter -cpu 1,4,8 ./concurrent
+// 核数越多，单个 atomic 的争用越严重，分片计数器的优势越明显
+// ============================================================================
+
+func BenchmarkCounterSharded(b *testing.B) {
+	c := NewCounter(0)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Inc()
+		}
+	})
+}
+
+func BenchmarkCounterAtomic(b *testing.B) {
+	var c atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Add(1)
+		}
+	})
+}
+
+func BenchmarkCounterMutex(b *testing.B) {
+	var mu sync.Mutex
+	var c int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			mu.Lock()
+			c++
+			mu.Unlock()
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package concurrent
+
+import (
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+)
+
+// cacheLine CPU 缓存行大小；x86 和大多数 ARM 是 64 字节
+const cacheLine = 64
+
+// shard 一个分片独占一条缓存行
+//
+// 【伪共享】
+// 两个 atomic.Int64 挨在一起时落在同一条缓存行，
+// CPU A 改第一个、CPU B 改第二个，缓存行仍然在两个核之间来回失效，
+// 看起来互不相干，实际和抢同一个变量一样慢。填充到 64 字节就避免了。
+type shard struct {
+	n atomic.Int64
+	_ [cacheLine - 8]byte
+}
+
+// Counter 分片计数器：写入分散到多个分片，读取时求和
+//
+// 适合写多读少：每个请求 Add 一次，监控每隔几秒 Value 一次。
+// Value 逐个读取分片，期间的并发 Add 可能只被计入一部分，
+// 所以它不是某一时刻的精确快照；需要精确读数（如用作 ID）时用 atomic.Int64。
+//
+// 零值不能使用，用 NewCounter 创建。
+type Counter struct {
+	shards []shard
+	mask   uint32
+}
+
+// NewCounter 创建计数器，shards 向上取整到 2 的幂；<= 0 时按 GOMAXPROCS 决定
+func NewCounter(shards int) *Counter {
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+	return &Counter{shards: make([]shard, n), mask: uint32(n - 1)}
+}
+
+// Add 加上 delta（可以是负数）
+//
+// Go 拿不到当前 goroutine 运行在哪个 CPU 上，这里随机选一个分片。
+// 包级的 rand 函数在未设置种子时是无锁的，不会重新引入争用。
+func (c *Counter) Add(delta int64) {
+	c.shards[rand.Uint32()&c.mask].n.Add(delta)
+}
+
+// Inc 加 1
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Value 所有分片之和
+func (c *Counter) Value() int64 {
+	var sum int64
+	for i := range c.shards {
+		sum += c.shards[i].n.Load()
+	}
+	return sum
+}
+
+// Reset 清零并返回清零前的值，用于"每个周期上报一次增量"
+func (c *Counter) Reset() int64 {
+	var sum int64
+	for i := range c.shards {
+		sum += c.shards[i].n.Swap(0)
+	}
+	return sum
+}
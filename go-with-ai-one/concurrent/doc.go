@@ -0,0 +1,30 @@
+// ============================================================================
+// concurrent - 并发安全的泛型数据结构（12_concurrency.go 的 SafeCounter / Cache 的正式版）
+// ============================================================================
+// 运行测试: go test -race -v ./concurrent
+// 基准测试: go test -bench . -benchmem ./concurrent
+//
+// 【提供的类型】
+// | 类型        | 底层实现             | 适用场景                                 |
+// |-------------|----------------------|------------------------------------------|
+// | Map[K, V]   | sync.Map + 类型参数  | 读多写少、键集合基本稳定                 |
+// | Counter     | 分片 atomic.Int64    | 大量 goroutine 同时自增（请求数、字节数）|
+// | Queue[T]    | 带缓冲的 channel     | 有界的多生产者多消费者队列，背压和关闭   |
+//
+// 【为什么不直接用】
+// sync.Map：Load 返回 any，每次都要类型断言，存错类型编译期发现不了。
+// 一个 atomic.Int64：所有 CPU 抢同一条缓存行，核数越多越慢；
+// Counter 把计数分散到多个缓存行，读取时再求和。
+// 裸 channel：关闭后再发送会 panic，多个生产者时谁来关闭是个难题；
+// Queue 的 Close 可以在任意 goroutine 调用，之后的 Push 返回 ErrClosed。
+//
+// 【选择指南】
+// | 场景                         | 推荐                          |
+// |------------------------------|-------------------------------|
+// | 读多写少，键很少变化         | Map                           |
+// | 读写都频繁、需要遍历或计数   | map + sync.RWMutex            |
+// | 计数器，读取不频繁           | Counter                       |
+// | 计数器，每次都要读准确值     | atomic.Int64                  |
+// | 生产者比消费者快，需要限流   | Queue（满时 Push 阻塞）       |
+// ============================================================================
+package concurrent
@@ -0,0 +1,92 @@
+package concurrent
+
+import "sync"
+
+// Map 类型安全的 sync.Map
+//
+// 方法和 sync.Map 一一对应，只是参数和返回值带了类型。
+// 零值可以直接使用；和 sync.Map 一样，使用后不能复制。
+//
+//	var sessions concurrent.Map[string, *Session]
+//	sessions.Store(id, s)
+//	s, ok := sessions.Load(id) // s 已经是 *Session，不用断言
+type Map[K comparable, V any] struct {
+	m sync.Map
+}
+
+// Load 读取键对应的值，不存在时返回零值和 false
+func (m *Map[K, V]) Load(key K) (value V, ok bool) {
+	v, ok := m.m.Load(key)
+	if !ok {
+		return value, false
+	}
+	return v.(V), true
+}
+
+// Store 写入键值
+func (m *Map[K, V]) Store(key K, value V) {
+	m.m.Store(key, value)
+}
+
+// LoadOrStore 键存在时返回已有的值（loaded 为 true），否则写入 value 并返回它
+//
+// 多个 goroutine 同时初始化同一个键时，只有一个的值会被保留，
+// 其他 goroutine 拿到的都是这个值。
+func (m *Map[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	v, loaded := m.m.LoadOrStore(key, value)
+	return v.(V), loaded
+}
+
+// LoadAndDelete 删除键并返回删除前的值
+func (m *Map[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	v, loaded := m.m.LoadAndDelete(key)
+	if !loaded {
+		return value, false
+	}
+	return v.(V), true
+}
+
+// Delete 删除键
+func (m *Map[K, V]) Delete(key K) {
+	m.m.Delete(key)
+}
+
+// Swap 写入新值并返回旧值
+func (m *Map[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	v, loaded := m.m.Swap(key, value)
+	if !loaded {
+		return previous, false
+	}
+	return v.(V), true
+}
+
+// CompareAndSwap 当前值等于 old 时替换为 new
+// V 不可比较（如切片、map）时 panic，和 sync.Map 一致
+func (m *Map[K, V]) CompareAndSwap(key K, old, new V) bool {
+	return m.m.CompareAndSwap(key, old, new)
+}
+
+// CompareAndDelete 当前值等于 old 时删除
+func (m *Map[K, V]) CompareAndDelete(key K, old V) bool {
+	return m.m.CompareAndDelete(key, old)
+}
+
+// Range 遍历所有键值，fn 返回 false 时停止
+//
+// 和 sync.Map.Range 一样不是快照：遍历期间其他 goroutine 的写入可能看得到也可能看不到，
+// 但每个键最多遍历一次。
+func (m *Map[K, V]) Range(fn func(key K, value V) bool) {
+	m.m.Range(func(k, v any) bool {
+		return fn(k.(K), v.(V))
+	})
+}
+
+// Len 键值对个数，需要遍历整个 map，O(n)
+func (m *Map[K, V]) Len() int {
+	n := 0
+	m.m.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
@@ -0,0 +1,128 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// 错误定义
+var (
+	// ErrClosed 队列已关闭：Push 不再接受元素，Pop 在取完剩余元素后返回它
+	ErrClosed = errors.New("concurrent: queue is closed")
+)
+
+// Queue 有界的多生产者多消费者（MPMC）队列
+//
+// 【API】
+// | 方法       | 队列满 / 空时              | 关闭后                         |
+// |------------|----------------------------|--------------------------------|
+// | Push       | 阻塞，直到有空位或 ctx 结束 | 返回 ErrClosed                 |
+// | TryPush    | 立即返回 false             | 返回 false                     |
+// | Pop        | 阻塞，直到有元素或 ctx 结束 | 先取完剩余元素，再返回 ErrClosed |
+// | TryPop     | 立即返回 false             | 先取完剩余元素，再返回 false   |
+//
+// 【为什么不直接关闭 channel】
+// 多个生产者时，关闭 channel 后还在发送的生产者会 panic。
+// Queue 从不关闭数据 channel，而是关闭单独的 done channel 作为信号，
+// 所以 Close 可以在任何 goroutine、任何时候调用，重复调用也没问题。
+//
+// 与 Close 同时进行的 Push 可能成功也可能返回 ErrClosed；成功放入的元素仍会被 Pop 取到。
+type Queue[T any] struct {
+	items chan T
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewQueue 创建容量为 capacity 的队列，capacity 必须大于 0
+func NewQueue[T any](capacity int) *Queue[T] {
+	if capacity <= 0 {
+		panic("concurrent: queue capacity must be positive")
+	}
+	return &Queue[T]{
+		items: make(chan T, capacity),
+		done:  make(chan struct{}),
+	}
+}
+
+// Push 放入元素，队列满时阻塞
+// ctx 结束时返回 ctx.Err()，队列关闭时返回 ErrClosed
+func (q *Queue[T]) Push(ctx context.Context, item T) error {
+	// select 在多个分支都就绪时随机选择，先单独检查一次：Close 返回后调用的 Push 一定失败
+	if q.closed() {
+		return ErrClosed
+	}
+	select {
+	case q.items <- item:
+		return nil
+	case <-q.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryPush 不阻塞地放入元素，队列满或已关闭时返回 false
+func (q *Queue[T]) TryPush(item T) bool {
+	if q.closed() {
+		return false
+	}
+	select {
+	case q.items <- item:
+		return true
+	default:
+		return false
+	}
+}
+
+// Pop 取出元素，队列空时阻塞
+// 队列关闭后先取完剩余元素，再返回 ErrClosed；ctx 结束时返回 ctx.Err()
+func (q *Queue[T]) Pop(ctx context.Context) (T, error) {
+	var zero T
+	select {
+	case item := <-q.items:
+		return item, nil
+	case <-q.done:
+		if item, ok := q.TryPop(); ok {
+			return item, nil
+		}
+		return zero, ErrClosed
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// TryPop 不阻塞地取出元素，队列空时返回 false
+func (q *Queue[T]) TryPop() (T, bool) {
+	select {
+	case item := <-q.items:
+		return item, true
+	default:
+		var zero T
+		return zero, false
+	}
+}
+
+// Close 关闭队列，可以重复调用
+func (q *Queue[T]) Close() {
+	q.once.Do(func() { close(q.done) })
+}
+
+// Len 当前元素个数
+func (q *Queue[T]) Len() int {
+	return len(q.items)
+}
+
+// Cap 容量
+func (q *Queue[T]) Cap() int {
+	return cap(q.items)
+}
+
+func (q *Queue[T]) closed() bool {
+	select {
+	case <-q.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,187 @@
+// ============================================================================
+// errtrace - 创建错误时记录调用栈，%+v 打印出错位置
+// ============================================================================
+// 运行测试: go test -v -cover ./errtrace
+//
+// 【为什么需要】
+// 线上日志里只有 "record not found" 或 "connection reset by peer"，
+// 不知道是哪个函数、哪一行返回的；fmt.Errorf 一层层加上下文能缓解，但总有漏掉的。
+// errtrace 在错误产生的地方记录调用栈，日志中间件再把出错位置写进结构化日志。
+//
+// 【用法】
+//
+//	if err := db.QueryRow(...).Scan(&u); err != nil {
+//	    return errtrace.Wrap(err) // 消息不变，附带调用栈
+//	}
+//	return errtrace.New("quota exceeded")
+//	return errtrace.Errorf("load user %d: %w", id, err)
+//
+//	fmt.Printf("%v\n", err)  // load user 1: record not found
+//	fmt.Printf("%+v\n", err) // 消息 + 每行一个 "函数\n\t文件:行号"
+//	errtrace.StackOf(err)    // []Frame，用于结构化日志
+//
+// 【成本】
+// 记录调用栈（runtime.Callers）不到 1µs（见 BenchmarkWrap），只保存程序计数器，
+// 函数名、文件名等到真正打印时才解析。
+// 不要在 io.EOF 这类预期内、会频繁出现的错误上使用。
+//
+// 【只记录一次】
+// 错误链中已经有调用栈时 Wrap / Errorf 不再记录：最深处的调用栈最有用，
+// 每层都记录只会让日志变长。
+//
+// 【panic】
+// 在 defer 中 recover 后调用时，调用栈从 panic 发生的位置开始，
+// 而不是 recover 所在的函数（见 gin-one/middleware/recovery）。
+// ============================================================================
+package errtrace
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxDepth 最多记录的栈帧数
+const maxDepth = 32
+
+// Frame 调用栈中的一帧
+type Frame struct {
+	Function string // 完整函数名，如 go-one/service.(*UserService).Create
+	File     string
+	Line     int
+}
+
+// String 文件:行号 函数名
+func (f Frame) String() string {
+	return f.File + ":" + strconv.Itoa(f.Line) + " " + f.Function
+}
+
+// StackTracer 带调用栈的错误
+type StackTracer interface {
+	StackTrace() []Frame
+}
+
+// New 创建带调用栈的错误
+func New(message string) error {
+	return &withStack{err: errors.New(message), pcs: callers()}
+}
+
+// Errorf 与 fmt.Errorf 相同（支持 %w），并附带调用栈
+// %w 包装的错误已经带调用栈时不再记录
+func Errorf(format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	if hasStack(err) {
+		return err
+	}
+	return &withStack{err: err, pcs: callers()}
+}
+
+// Wrap 给 err 附带调用栈，消息不变；err 为 nil 或已带调用栈时原样返回
+func Wrap(err error) error {
+	if err == nil || hasStack(err) {
+		return err
+	}
+	return &withStack{err: err, pcs: callers()}
+}
+
+// StackOf 错误链中第一个调用栈，没有时返回 nil
+func StackOf(err error) []Frame {
+	var st StackTracer
+	if errors.As(err, &st) {
+		return st.StackTrace()
+	}
+	return nil
+}
+
+// Source 出错位置（调用栈第一帧），如 "service/user.go:42"，没有调用栈时返回 ""
+// 文件名只保留最后两级目录，便于在日志里阅读
+func Source(err error) string {
+	frames := StackOf(err)
+	if len(frames) == 0 {
+		return ""
+	}
+	return shortFile(frames[0].File) + ":" + strconv.Itoa(frames[0].Line)
+}
+
+func hasStack(err error) bool {
+	var st StackTracer
+	return errors.As(err, &st)
+}
+
+type withStack struct {
+	err error
+	pcs []uintptr
+}
+
+func (e *withStack) Error() string { return e.err.Error() }
+func (e *withStack) Unwrap() error { return e.err }
+
+// StackTrace 解析调用栈，省略 runtime 包内部的帧
+func (e *withStack) StackTrace() []Frame {
+	frames := runtime.CallersFrames(e.pcs)
+	out := make([]Frame, 0, len(e.pcs))
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "runtime.") {
+			out = append(out, Frame{Function: f.Function, File: f.File, Line: f.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return out
+}
+
+// Format %s %v 只输出消息，%+v 额外输出调用栈，%q 输出带引号的消息
+func (e *withStack) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			_, _ = io.WriteString(s, e.Error())
+			for _, f := range e.StackTrace() {
+				_, _ = fmt.Fprintf(s, "\n%s\n\t%s:%d", f.Function, f.File, f.Line)
+			}
+			return
+		}
+		fallthrough
+	case 's':
+		_, _ = io.WriteString(s, e.Error())
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+// callers 记录调用 New / Errorf / Wrap 的位置开始的调用栈
+// 在 panic 过程中（defer 里）调用时，从 panic 发生处开始
+func callers() []uintptr {
+	var pcs [maxDepth + 8]uintptr
+	n := runtime.Callers(3, pcs[:]) // 跳过 runtime.Callers、callers 和 New/Errorf/Wrap
+	stack := pcs[:n]
+
+	// 按程序计数器逐个查找，不用 CallersFrames：内联会让帧数和 pcs 的下标对不上
+	for i, pc := range stack {
+		if fn := runtime.FuncForPC(pc - 1); fn != nil && fn.Name() == "runtime.gopanic" {
+			stack = stack[i+1:]
+			break
+		}
+	}
+	if len(stack) > maxDepth {
+		stack = stack[:maxDepth]
+	}
+	return append([]uintptr(nil), stack...)
+}
+
+// shortFile 只保留路径的最后两级：/home/x/go-one/service/service.go → service/service.go
+func shortFile(path string) string {
+	i := strings.LastIndexByte(path, '/')
+	if i <= 0 {
+		return path
+	}
+	if j := strings.LastIndexByte(path[:i], '/'); j >= 0 {
+		return path[j+1:]
+	}
+	return path
+}
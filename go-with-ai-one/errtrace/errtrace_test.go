@@ -0,0 +1,154 @@
+package errtrace
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// 测试通过函数名判断调用栈的第一帧是否指向出错的位置
+
+func loadUser() error { return Wrap(io.ErrUnexpectedEOF) }
+
+func newQuota() error { return New("quota exceeded") }
+
+func formatLoad(id int) error { return Errorf("load user %d: %w", id, io.EOF) }
+
+func TestStackPointsAtCreation(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantFn  string
+		wantMsg string
+	}{
+		{"Wrap", loadUser(), "errtrace.loadUser", "unexpected EOF"},
+		{"New", newQuota(), "errtrace.newQuota", "quota exceeded"},
+		{"Errorf", formatLoad(7), "errtrace.formatLoad", "load user 7: EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Error() != tt.wantMsg {
+				t.Errorf("Error() = %q; want %q", tt.err.Error(), tt.wantMsg)
+			}
+			frames := StackOf(tt.err)
+			if len(frames) == 0 {
+				t.Fatal("no stack recorded")
+			}
+			if !strings.HasSuffix(frames[0].Function, tt.wantFn) {
+				t.Errorf("first frame = %s; want %s", frames[0], tt.wantFn)
+			}
+			if !strings.HasSuffix(frames[0].File, "errtrace_test.go") || frames[0].Line == 0 {
+				t.Errorf("first frame location = %s:%d", frames[0].File, frames[0].Line)
+			}
+			if src := Source(tt.err); !strings.HasPrefix(src, "errtrace/errtrace_test.go:") {
+				t.Errorf("Source = %q", src)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	err := loadUser()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Error("Wrap broke errors.Is")
+	}
+	if errors.Is(formatLoad(1), io.EOF) == false {
+		t.Error("Errorf %w broke errors.Is")
+	}
+
+	// 已经带调用栈时不再记录，第一帧仍然是最初出错的位置
+	outer := fmt.Errorf("handler: %w", err)
+	for _, e := range []error{Wrap(err), Wrap(outer), Errorf("retry: %w", err)} {
+		if !strings.HasSuffix(StackOf(e)[0].Function, "errtrace.loadUser") {
+			t.Errorf("stack was re-recorded: %s", StackOf(e)[0])
+		}
+	}
+	if Wrap(outer) != outer {
+		t.Error("Wrap should return an error that already has a stack unchanged")
+	}
+
+	if Wrap(nil) != nil {
+		t.Error("Wrap(nil) should be nil")
+	}
+	if StackOf(io.EOF) != nil || Source(io.EOF) != "" {
+		t.Error("plain error should have no stack")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	err := loadUser()
+	tests := []struct {
+		format string
+		check  func(s string) bool
+	}{
+		{"%v", func(s string) bool { return s == "unexpected EOF" }},
+		{"%s", func(s string) bool { return s == "unexpected EOF" }},
+		{"%q", func(s string) bool { return s == `"unexpected EOF"` }},
+		{"%+v", func(s string) bool {
+			lines := strings.Split(s, "\n")
+			return lines[0] == "unexpected EOF" &&
+				strings.HasSuffix(lines[1], "errtrace.loadUser") &&
+				strings.HasPrefix(lines[2], "\t") && strings.Contains(lines[2], "errtrace_test.go:")
+		}},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, err); !tt.check(got) {
+			t.Errorf("Sprintf(%q) = %q", tt.format, got)
+		}
+	}
+}
+
+// explode 故意触发空指针 panic
+func explode() {
+	var m map[string]int
+	var p *struct{ n int }
+	m["x"] = p.n
+}
+
+func TestPanicStack(t *testing.T) {
+	var err error
+	func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = Errorf("panic: %v", rec)
+			}
+		}()
+		explode()
+	}()
+
+	frames := StackOf(err)
+	if len(frames) == 0 {
+		t.Fatal("no stack recorded")
+	}
+	// 从 panic 的位置开始，而不是 recover 所在的匿名函数
+	if !strings.HasSuffix(frames[0].Function, "errtrace.explode") {
+		t.Errorf("first frame = %s; want explode", frames[0])
+	}
+	for _, f := range frames {
+		if strings.HasPrefix(f.Function, "runtime.") {
+			t.Errorf("runtime frame not filtered: %s", f)
+		}
+	}
+}
+
+func TestShortFile(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"/home/x/go-one/service/service.go", "service/service.go"},
+		{"/main.go", "/main.go"},
+		{"main.go", "main.go"},
+		{"a/b.go", "a/b.go"},
+	}
+	for _, tt := range tests {
+		if got := shortFile(tt.in); got != tt.want {
+			t.Errorf("shortFile(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkWrap(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = Wrap(io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,164 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// 【熔断器】
+// ============================================================================
+// 下游服务挂了之后，每个请求都要等到超时才失败，调用方的 goroutine 和连接会被占满。
+// 熔断器在连续失败达到阈值后直接拒绝请求（快速失败），过一段时间再放少量请求试探。
+//
+// 【三种状态】
+//
+//	          连续失败 >= FailureThreshold
+//	Closed ─────────────────────────────────▶ Open
+//	  ▲                                        │
+//	  │ 试探成功                               │ 经过 OpenTimeout
+//	  │                                        ▼
+//	  └────────────────────────────────── HalfOpen
+//	                   试探失败 → 回到 Open
+//
+// | 状态      | 请求                           | 转换条件                          |
+// |-----------|--------------------------------|-----------------------------------|
+// | Closed    | 全部放行                       | 连续失败达到阈值 → Open           |
+// | Open      | 全部拒绝，返回 ErrCircuitOpen  | 经过 OpenTimeout → HalfOpen       |
+// | HalfOpen  | 最多放行 HalfOpenMax 个        | 成功 → Closed；失败 → Open        |
+// ============================================================================
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig 熔断器配置
+type BreakerConfig struct {
+	// FailureThreshold 连续失败多少次后熔断，默认 5
+	FailureThreshold int
+
+	// OpenTimeout 熔断后多久进入半开状态，默认 30 秒
+	OpenTimeout time.Duration
+
+	// HalfOpenMax 半开状态同时放行的试探请求数，默认 1
+	HalfOpenMax int
+
+	// OnStateChange 状态变化时调用（在锁内调用，不要做耗时操作）
+	OnStateChange func(from, to State)
+}
+
+// Breaker 熔断器，可以在多个 Client 之间共享
+type Breaker struct {
+	cfg BreakerConfig
+	now func() time.Time // 测试时替换
+
+	mu       sync.Mutex
+	state    State
+	failures int       // Closed 状态下的连续失败次数
+	openedAt time.Time // 进入 Open 的时间
+	probes   int       // HalfOpen 状态下正在进行的试探请求数
+}
+
+// NewBreaker 创建熔断器
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenMax <= 0 {
+		cfg.HalfOpenMax = 1
+	}
+	return &Breaker{cfg: cfg, now: time.Now}
+}
+
+// Allow 请求前调用，熔断时返回 ErrCircuitOpen
+// 返回 nil 时必须在请求结束后调用 Record
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.cfg.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.setState(StateHalfOpen)
+		fallthrough
+	case StateHalfOpen:
+		if b.probes >= b.cfg.HalfOpenMax {
+			return ErrCircuitOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Record 记录一次请求的结果
+func (b *Breaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.open()
+		}
+	case StateHalfOpen:
+		b.probes--
+		if success {
+			b.failures = 0
+			b.setState(StateClosed)
+		} else {
+			b.open()
+		}
+	}
+	// Open 状态下的结果来自熔断前发出的请求，忽略
+}
+
+// State 当前状态；Open 已经超过 OpenTimeout 时仍返回 Open，下一次 Allow 才转为 HalfOpen
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Breaker) open() {
+	b.openedAt = b.now()
+	b.probes = 0
+	b.setState(StateOpen)
+}
+
+func (b *Breaker) setState(to State) {
+	if b.state == to {
+		return
+	}
+	from := b.state
+	b.state = to
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(from, to)
+	}
+}
@@ -0,0 +1,310 @@
+// ============================================================================
+// httpclient - 生产可用的 HTTP 客户端（13_stdlib.go 中 http.Get 的进阶版）
+// ============================================================================
+// 运行测试: go test -v ./httpclient
+//
+// 【http.Get 的问题】
+// | 问题                       | 后果                                   | 本包的做法                      |
+// |----------------------------|----------------------------------------|---------------------------------|
+// | http.DefaultClient 无超时  | 下游不响应时 goroutine 永远阻塞        | 每次尝试一个 context 超时       |
+// | 网络抖动直接失败           | 偶发的 502/503 变成用户看到的错误      | 幂等请求指数退避重试            |
+// | 下游挂掉后仍然每次都请求   | 请求堆积，拖垮自己（雪崩）             | 熔断器快速失败                  |
+// | 没有日志                   | 出问题时不知道请求了什么、耗时多少     | OnRequest / OnResponse 钩子     |
+//
+// 【什么请求会重试】
+//  1. 方法幂等：GET、HEAD、OPTIONS、TRACE、PUT、DELETE，
+//     或者带了 Idempotency-Key 请求头（服务端保证只执行一次）
+//  2. 网络错误、单次尝试超时，或状态码 429 / 502 / 503 / 504
+//  3. 请求体可以重放（http.NewRequest 传入 bytes.Reader、strings.Reader 等会自动设置 GetBody）
+//
+// POST 默认不重试：请求可能已经到达服务端并执行了，重试会重复下单、重复扣款。
+//
+// 【退避】
+// 重试循环交给 retry 包：第 n 次重试等待 rand[0, min(MaxDelay, BaseDelay * 2^(n-1))]（全抖动），
+// 避免大量客户端在同一时刻一起重试。响应带 Retry-After 时按它等待（同样不超过 MaxDelay）。
+//
+// 【用法】
+//
+//	client := httpclient.New(httpclient.Config{Timeout: 3 * time.Second})
+//	resp, err := client.Get(ctx, "http://user-service/users/1")
+//	if errors.Is(err, httpclient.ErrCircuitOpen) {
+//	    // 下游熔断中，走降级逻辑
+//	}
+//
+// 每个下游服务一个 Client（各自的熔断器），不要所有服务共用一个。
+// ============================================================================
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-learning/retry"
+)
+
+// 错误定义
+var (
+	// ErrCircuitOpen 熔断器打开，请求没有发出
+	ErrCircuitOpen = errors.New("httpclient: circuit breaker is open")
+)
+
+// Config 客户端配置
+type Config struct {
+	// Timeout 单次尝试的超时（包括读取响应体），默认 10 秒
+	// 整个调用（含重试）的截止时间由调用方的 ctx 控制
+	Timeout time.Duration
+
+	// MaxRetries 最多重试次数，默认 2（最多发出 3 次）；设为 -1 不重试
+	MaxRetries int
+
+	// BaseDelay 第一次重试前的等待时间，默认 100ms
+	BaseDelay time.Duration
+
+	// MaxDelay 单次等待的上限，默认 2 秒
+	MaxDelay time.Duration
+
+	// Breaker 熔断器，默认 NewBreaker(BreakerConfig{})
+	Breaker *Breaker
+
+	// Transport 默认 http.DefaultTransport，测试时可以替换
+	Transport http.RoundTripper
+
+	// OnRequest 每次尝试发出前调用，attempt 从 1 开始；可以用来加请求头、打日志
+	OnRequest func(req *http.Request, attempt int)
+
+	// OnResponse 每次尝试结束后调用，LogResponse 提供了一个 slog 实现
+	OnResponse func(a Attempt)
+}
+
+// Attempt 一次尝试的结果
+type Attempt struct {
+	Request  *http.Request
+	Response *http.Response // 出错时为 nil；不要读取 Body
+	Err      error
+	Duration time.Duration
+	Number   int  // 第几次尝试，从 1 开始
+	Retry    bool // 是否会重试
+}
+
+// Client 带超时、重试和熔断的 HTTP 客户端，可以并发使用
+type Client struct {
+	cfg     Config
+	http    *http.Client
+	breaker *Breaker
+}
+
+// New 创建客户端
+func New(cfg Config) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 2
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 100 * time.Millisecond
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 2 * time.Second
+	}
+	if cfg.Breaker == nil {
+		cfg.Breaker = NewBreaker(BreakerConfig{})
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+	return &Client{
+		cfg: cfg,
+		// 超时由每次尝试的 context 控制，这里不设置 Timeout
+		http:    &http.Client{Transport: cfg.Transport},
+		breaker: cfg.Breaker,
+	}
+}
+
+// Get 发送 GET 请求
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Do 发送请求，按配置重试；返回的 Response 与 http.Client.Do 一样需要关闭 Body
+//
+// 重试用完后返回最后一次的响应（如 503）或错误，由调用方决定如何处理。
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	retryable := isIdempotent(req) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+	maxAttempts := c.cfg.MaxRetries + 1
+	if !retryable {
+		maxAttempts = 1
+	}
+
+	// 每次尝试的结果，fn 和 OnAttempt 之间传递
+	var (
+		resp    *http.Response
+		err     error
+		elapsed time.Duration
+		sent    bool // 熔断器拒绝时请求没有发出
+	)
+	attempt := 0
+	result := retry.Do(req.Context(), func(ctx context.Context) error {
+		attempt++
+		resp, err, sent = nil, nil, false
+		if err := c.breaker.Allow(); err != nil {
+			return retry.Permanent(err)
+		}
+		sent = true
+		resp, elapsed, err = c.try(req, attempt)
+		c.breaker.Record(err == nil && resp.StatusCode < http.StatusInternalServerError)
+		if err != nil {
+			return err // 网络错误、单次尝试超时都可以重试
+		}
+		if !retryableStatus(resp.StatusCode) {
+			return nil
+		}
+		statusErr := &statusError{code: resp.StatusCode}
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return retry.After(statusErr, d)
+		}
+		return statusErr
+	}, retry.Options{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   c.cfg.BaseDelay,
+		MaxDelay:    c.cfg.MaxDelay,
+		OnAttempt: func(a retry.Attempt) {
+			if sent && c.cfg.OnResponse != nil {
+				c.cfg.OnResponse(Attempt{Request: req, Response: resp, Err: err, Duration: elapsed, Number: a.Number, Retry: a.Retry})
+			}
+			if a.Retry && resp != nil {
+				// 读完再关闭，连接才能放回连接池复用
+				_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+				resp.Body.Close()
+			}
+		},
+	})
+
+	var statusErr *statusError
+	if errors.As(result, &statusErr) {
+		return resp, nil // 可重试的状态码但不再重试：把最后一次的响应交给调用方
+	}
+	if result != nil {
+		return nil, result // 网络错误、熔断、ctx 结束
+	}
+	return resp, nil
+}
+
+// statusError 可重试的状态码，只在重试循环内部使用
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return "httpclient: retryable status " + strconv.Itoa(e.code)
+}
+
+// try 发出一次请求，超时覆盖到响应体读完为止
+func (c *Client) try(req *http.Request, attempt int) (*http.Response, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), c.cfg.Timeout)
+	r := req.Clone(ctx)
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, 0, err
+		}
+		r.Body = body
+	}
+	if c.cfg.OnRequest != nil {
+		c.cfg.OnRequest(r, attempt)
+	}
+
+	start := time.Now()
+	resp, err := c.http.Do(r)
+	elapsed := time.Since(start)
+	if err != nil {
+		cancel()
+		return nil, elapsed, err
+	}
+	// 调用方关闭 Body 时才取消 context，否则读响应体时会被提前取消
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, elapsed, nil
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// retryableStatus 下游过载或网关问题，稍后重试可能成功
+// 调用方取消（ctx 结束）时 retry.Do 不会再重试
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter 解析 Retry-After：秒数或 HTTP 日期
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// LogResponse 返回一个用 slog 记录每次尝试的 OnResponse 钩子
+//
+//	client := httpclient.New(httpclient.Config{OnResponse: httpclient.LogResponse(slog.Default())})
+func LogResponse(logger *slog.Logger) func(Attempt) {
+	return func(a Attempt) {
+		attrs := []any{
+			slog.String("method", a.Request.Method),
+			slog.String("url", a.Request.URL.Redacted()),
+			slog.Int("attempt", a.Number),
+			slog.Duration("duration", a.Duration),
+			slog.Bool("retry", a.Retry),
+		}
+		level := slog.LevelInfo
+		switch {
+		case a.Err != nil:
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String("error", a.Err.Error()))
+		case a.Response.StatusCode >= http.StatusInternalServerError:
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.Int("status", a.Response.StatusCode))
+		default:
+			attrs = append(attrs, slog.Int("status", a.Response.StatusCode))
+		}
+		logger.Log(a.Request.Context(), level, "http request", attrs...)
+	}
+}
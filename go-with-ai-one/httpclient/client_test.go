@@ -0,0 +1,306 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// server 按顺序返回 statuses 中的状态码，用完后一直返回最后一个；记录收到的请求体
+type server struct {
+	*httptest.Server
+	calls  atomic.Int32
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newServer(t *testing.T, statuses ...int) *server {
+	s := &server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(s.calls.Add(1))
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+		status := statuses[min(n, len(statuses))-1]
+		w.WriteHeader(status)
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func fastConfig() Config {
+	return Config{BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		header     string // Idempotency-Key
+		statuses   []int
+		maxRetries int
+		wantStatus int
+		wantCalls  int32
+	}{
+		{"success no retry", "GET", "", []int{200}, 0, 200, 1},
+		{"retry 503 then ok", "GET", "", []int{503, 200}, 0, 200, 2},
+		{"retry 429 and 502", "GET", "", []int{429, 502, 200}, 0, 200, 3},
+		{"retries exhausted returns last", "GET", "", []int{503}, 0, 503, 3},
+		{"500 not retried", "GET", "", []int{500, 200}, 0, 500, 1},
+		{"404 not retried", "GET", "", []int{404, 200}, 0, 404, 1},
+		{"post not retried", "POST", "", []int{503, 200}, 0, 503, 1},
+		{"post with idempotency key", "POST", "k1", []int{503, 200}, 0, 200, 2},
+		{"put retried", "PUT", "", []int{503, 200}, 0, 200, 2},
+		{"retries disabled", "GET", "", []int{503, 200}, -1, 503, 1},
+		{"custom max retries", "GET", "", []int{503}, 4, 503, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t, tt.statuses...)
+			cfg := fastConfig()
+			cfg.MaxRetries = tt.maxRetries
+			c := New(cfg)
+
+			req, _ := http.NewRequest(tt.method, s.URL, strings.NewReader("payload"))
+			if tt.header != "" {
+				req.Header.Set("Idempotency-Key", tt.header)
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d; want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := s.calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d; want %d", got, tt.wantCalls)
+			}
+			// 每次重试都重放了完整的请求体
+			for i, b := range s.bodies {
+				if b != "payload" {
+					t.Errorf("attempt %d body = %q", i+1, b)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			select { // 前两次不响应
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		io.WriteString(w, "slow body")
+	}))
+	defer close(release)
+	defer s.Close()
+
+	cfg := fastConfig()
+	cfg.Timeout = 50 * time.Millisecond
+	resp, err := New(cfg).Get(context.Background(), s.URL)
+	if err != nil {
+		t.Fatalf("third attempt should succeed: %v", err)
+	}
+	// 单次超时的 context 在 Body 关闭前不能被取消，否则这里读不到数据
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "slow body" || calls.Load() != 3 {
+		t.Errorf("body = %q, err = %v, calls = %d", body, err, calls.Load())
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	s := newServer(t, 503)
+	cfg := fastConfig()
+	cfg.BaseDelay, cfg.MaxDelay = time.Hour, time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := New(cfg).Get(ctx, s.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v; want deadline exceeded", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("backoff should stop when ctx is done")
+	}
+}
+
+func TestHooks(t *testing.T) {
+	s := newServer(t, 503, 200)
+	var requests []int
+	var attempts []Attempt
+	cfg := fastConfig()
+	cfg.OnRequest = func(req *http.Request, attempt int) {
+		req.Header.Set("X-Attempt", "set")
+		requests = append(requests, attempt)
+	}
+	cfg.OnResponse = func(a Attempt) { attempts = append(attempts, a) }
+
+	resp, err := New(cfg).Get(context.Background(), s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(requests) != 2 || requests[0] != 1 || requests[1] != 2 {
+		t.Errorf("OnRequest attempts = %v", requests)
+	}
+	if len(attempts) != 2 || !attempts[0].Retry || attempts[1].Retry ||
+		attempts[0].Response.StatusCode != 503 || attempts[1].Number != 2 {
+		t.Errorf("OnResponse attempts = %+v", attempts)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0, true}, // 过去的时间
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRetryAfterCapped(t *testing.T) {
+	var calls atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	var retries []Attempt
+	cfg := fastConfig()
+	cfg.OnResponse = func(a Attempt) { retries = append(retries, a) }
+	start := time.Now()
+	resp, err := New(cfg).Get(context.Background(), s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// Retry-After: 30 被限制到 MaxDelay（5ms），而不是真的等 30 秒
+	if elapsed := time.Since(start); elapsed > time.Second || resp.StatusCode != 200 || calls.Load() != 2 {
+		t.Errorf("status = %d, calls = %d, elapsed = %v", resp.StatusCode, calls.Load(), elapsed)
+	}
+	if len(retries) != 2 || !retries[0].Retry || retries[0].Err != nil {
+		t.Errorf("attempts = %+v; want a retried 503 without transport error", retries)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var changes []string
+	b := NewBreaker(BreakerConfig{
+		FailureThreshold: 3,
+		OpenTimeout:      time.Minute,
+		OnStateChange:    func(from, to State) { changes = append(changes, from.String()+"->"+to.String()) },
+	})
+	b.now = func() time.Time { return now }
+
+	steps := []struct {
+		name      string
+		advance   time.Duration
+		allow     bool // Allow 是否放行
+		success   bool // 放行后记录的结果
+		wantState State
+	}{
+		{"failure 1", 0, true, false, StateClosed},
+		{"success resets count", 0, true, true, StateClosed},
+		{"failure 1", 0, true, false, StateClosed},
+		{"failure 2", 0, true, false, StateClosed},
+		{"failure 3 opens", 0, true, false, StateOpen},
+		{"rejected while open", 30 * time.Second, false, false, StateOpen},
+		{"probe after timeout fails", 31 * time.Second, true, false, StateOpen},
+		{"rejected again", time.Second, false, false, StateOpen},
+		{"probe succeeds closes", time.Minute, true, true, StateClosed},
+	}
+	for i, s := range steps {
+		now = now.Add(s.advance)
+		err := b.Allow()
+		if (err == nil) != s.allow {
+			t.Fatalf("step %d (%s): Allow() = %v; want allowed=%v", i, s.name, err, s.allow)
+		}
+		if err == nil {
+			b.Record(s.success)
+		}
+		if got := b.State(); got != s.wantState {
+			t.Fatalf("step %d (%s): state = %v; want %v", i, s.name, got, s.wantState)
+		}
+	}
+
+	want := "closed->open,open->half-open,half-open->open,open->half-open,half-open->closed"
+	if got := strings.Join(changes, ","); got != want {
+		t.Errorf("state changes = %s; want %s", got, want)
+	}
+}
+
+func TestBreakerHalfOpenLimit(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second, HalfOpenMax: 2})
+	b.now = func() time.Time { return now }
+
+	_ = b.Allow()
+	b.Record(false)
+	now = now.Add(2 * time.Second)
+
+	if b.Allow() != nil || b.Allow() != nil {
+		t.Fatal("two probes should be allowed")
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("third probe = %v; want ErrCircuitOpen", err)
+	}
+}
+
+func TestClientCircuitOpen(t *testing.T) {
+	s := newServer(t, 500)
+	cfg := fastConfig()
+	cfg.Breaker = NewBreaker(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Hour})
+	c := New(cfg)
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(context.Background(), s.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := c.Get(context.Background(), s.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("err = %v; want ErrCircuitOpen", err)
+	}
+	if got := s.calls.Load(); got != 2 {
+		t.Errorf("calls = %d; open circuit should not reach the server", got)
+	}
+}
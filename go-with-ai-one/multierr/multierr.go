@@ -0,0 +1,174 @@
+// ============================================================================
+// multierr - 带路径的多错误收集（10_errors.go 中 errors.Join 的延续）
+// ============================================================================
+// 运行测试: go test -v -cover ./multierr
+//
+// 【errors.Join 不够用的地方】
+// 批量导入 100 行，第 3 行邮箱格式错、第 17 行用户名重复：
+//
+// | 需求                         | errors.Join             | multierr.Errors                          |
+// |------------------------------|-------------------------|------------------------------------------|
+// | 知道是哪一行、哪个字段       | 只能拼进错误字符串里    | 每个错误带路径 items[3].email            |
+// | 返回给前端逐行标红           | 只有一个换行分隔的字符串| MarshalJSON 输出 [{path, error}] 数组    |
+// | errors.Is / As 检查某个成员  | 支持                    | 支持（Unwrap() []error）                 |
+// | 嵌套（行里的多个字段错误）   | 嵌套后路径丢失          | Add 时展开，路径前缀自动拼接             |
+//
+// 【用法】
+//
+//	var errs multierr.Errors
+//	for i, item := range req.Items {
+//	    path := multierr.Index("items", i)
+//	    if item.Email == "" {
+//	        errs.Add(multierr.Field(path, "email"), ErrRequired)
+//	    }
+//	    if err := save(item); err != nil {
+//	        errs.Add(path, err)
+//	    }
+//	}
+//	return errs.Err() // 没有错误时返回 nil
+//
+// 【nil 接口陷阱】
+// 不要直接 return &errs 或返回 *Errors 类型的变量：
+// 即使没有错误，非 nil 的 *Errors 装进 error 接口后 err != nil 也为 true。
+// 一律通过 Err() 返回，它在没有错误时返回真正的 nil。
+//
+// 【并发】
+// Errors 不是并发安全的。并发收集时每个 goroutine 写自己的下标，
+// 结束后在一个 goroutine 里统一 Add（见 conc.ForEach 的用法）。
+// ============================================================================
+package multierr
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// PathError 带路径的单个错误
+type PathError struct {
+	Path string // 如 items[3].email，为空表示整体错误
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *PathError) Unwrap() error { return e.Err }
+
+// MarshalJSON 输出 {"path": "items[3].email", "error": "..."}
+func (e *PathError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Path  string `json:"path"`
+		Error string `json:"error"`
+	}{e.Path, e.Err.Error()})
+}
+
+// Errors 按添加顺序收集的多个 *PathError，零值可以直接使用
+type Errors struct {
+	list []*PathError
+}
+
+// Add 添加一个错误，err 为 nil 时忽略
+//
+// err 的错误链中有 *Errors 时展开它的成员，成员路径拼到 path 后面：
+// Add("items[3]", 含 "email" 错误的 *Errors) 得到路径 items[3].email。
+func (m *Errors) Add(path string, err error) {
+	if err == nil {
+		return
+	}
+	var nested *Errors
+	if errors.As(err, &nested) {
+		for _, e := range nested.list {
+			m.list = append(m.list, &PathError{Path: join(path, e.Path), Err: e.Err})
+		}
+		return
+	}
+	m.list = append(m.list, &PathError{Path: path, Err: err})
+}
+
+// Len 错误个数
+func (m *Errors) Len() int { return len(m.list) }
+
+// Errors 所有错误，按添加顺序；返回副本
+func (m *Errors) Errors() []*PathError {
+	return append([]*PathError(nil), m.list...)
+}
+
+// Err 没有错误时返回 nil，否则返回 m
+func (m *Errors) Err() error {
+	if len(m.list) == 0 {
+		return nil
+	}
+	return m
+}
+
+// Error 一行一个错误，第一行是总数：
+//
+//	2 errors:
+//	items[0].email: required
+//	items[3]: duplicate username
+func (m *Errors) Error() string {
+	switch len(m.list) {
+	case 0:
+		return "no errors"
+	case 1:
+		return m.list[0].Error()
+	}
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(len(m.list)))
+	b.WriteString(" errors:")
+	for _, e := range m.list {
+		b.WriteByte('\n')
+		b.WriteString(e.Error())
+	}
+	return b.String()
+}
+
+// Unwrap 返回全部成员，errors.Is / As 会逐个检查（Go 1.20+）
+func (m *Errors) Unwrap() []error {
+	errs := make([]error, len(m.list))
+	for i, e := range m.list {
+		errs[i] = e
+	}
+	return errs
+}
+
+// MarshalJSON 输出 [{"path": ..., "error": ...}, ...]，没有错误时输出 []
+func (m *Errors) MarshalJSON() ([]byte, error) {
+	if m.list == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(m.list)
+}
+
+// ============================================================================
+// 路径
+// ============================================================================
+
+// Index 数组下标路径：Index("items", 3) = "items[3]"，Index("", 3) = "[3]"
+func Index(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+// Field 字段路径：Field("items[3]", "email") = "items[3].email"，Field("", "email") = "email"
+func Field(path, name string) string {
+	return join(path, name)
+}
+
+// join 拼接父路径和子路径，子路径以 [ 开头时不加点
+func join(parent, child string) string {
+	switch {
+	case parent == "":
+		return child
+	case child == "":
+		return parent
+	case child[0] == '[':
+		return parent + child
+	}
+	return parent + "." + child
+}
@@ -0,0 +1,151 @@
+package multierr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+var (
+	errRequired  = errors.New("required")
+	errDuplicate = errors.New("duplicate username")
+)
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{Index("items", 3), "items[3]"},
+		{Index("", 0), "[0]"},
+		{Field(Index("items", 3), "email"), "items[3].email"},
+		{Field("", "email"), "email"},
+		{Field("user", ""), "user"},
+		{Index(Index("matrix", 1), 2), "matrix[1][2]"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("path = %q; want %q", tt.got, tt.want)
+		}
+	}
+}
+
+func TestErrNil(t *testing.T) {
+	var errs Errors
+	errs.Add("ignored", nil)
+	if err := errs.Err(); err != nil {
+		t.Fatalf("Err() with no errors = %v; want nil", err)
+	}
+	if errs.Len() != 0 || errs.Error() != "no errors" {
+		t.Errorf("Len, Error = %d, %q", errs.Len(), errs.Error())
+	}
+}
+
+func TestAdd(t *testing.T) {
+	// 一行里的字段错误
+	var row Errors
+	row.Add("email", errRequired)
+	row.Add("age", fmt.Errorf("must be >= 0: %w", errRequired))
+
+	tests := []struct {
+		name      string
+		add       func(m *Errors)
+		wantPaths []string
+	}{
+		{"flat", func(m *Errors) {
+			m.Add(Field(Index("items", 0), "email"), errRequired)
+			m.Add(Index("items", 3), errDuplicate)
+		}, []string{"items[0].email", "items[3]"}},
+		{"nested rows are flattened", func(m *Errors) {
+			m.Add(Index("items", 2), row.Err())
+		}, []string{"items[2].email", "items[2].age"}},
+		{"nested through fmt wrapping", func(m *Errors) {
+			m.Add("user", fmt.Errorf("validate: %w", row.Err()))
+		}, []string{"user.email", "user.age"}},
+		{"nested index path", func(m *Errors) {
+			var inner Errors
+			inner.Add(Index("", 1), errRequired)
+			m.Add("tags", inner.Err())
+		}, []string{"tags[1]"}},
+		{"empty path", func(m *Errors) {
+			m.Add("", errDuplicate)
+		}, []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Errors
+			tt.add(&m)
+			got := m.Errors()
+			if len(got) != len(tt.wantPaths) {
+				t.Fatalf("got %d errors; want %d: %v", len(got), len(tt.wantPaths), &m)
+			}
+			for i, e := range got {
+				if e.Path != tt.wantPaths[i] {
+					t.Errorf("errors[%d].Path = %q; want %q", i, e.Path, tt.wantPaths[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIsAs(t *testing.T) {
+	var errs Errors
+	errs.Add("config", &fs.PathError{Op: "open", Path: "app.yaml", Err: fs.ErrNotExist})
+	errs.Add(Index("items", 3), errDuplicate)
+	err := fmt.Errorf("import: %w", errs.Err())
+
+	if !errors.Is(err, errDuplicate) || !errors.Is(err, fs.ErrNotExist) {
+		t.Error("errors.Is should match any member")
+	}
+	if errors.Is(err, errRequired) {
+		t.Error("errors.Is matched an error that was never added")
+	}
+	var pe *fs.PathError
+	if !errors.As(err, &pe) || pe.Path != "app.yaml" {
+		t.Errorf("errors.As = %v", pe)
+	}
+	var first *PathError
+	if !errors.As(err, &first) || first.Path != "config" {
+		t.Errorf("errors.As(*PathError) = %v", first)
+	}
+}
+
+func TestError(t *testing.T) {
+	var errs Errors
+	errs.Add("items[0].email", errRequired)
+	if got := errs.Error(); got != "items[0].email: required" {
+		t.Errorf("single Error() = %q", got)
+	}
+	errs.Add("", errDuplicate)
+	want := "2 errors:\nitems[0].email: required\nduplicate username"
+	if got := errs.Error(); got != want {
+		t.Errorf("Error() = %q; want %q", got, want)
+	}
+
+	// Errors 返回副本
+	errs.Errors()[0] = nil
+	if errs.Errors()[0] == nil {
+		t.Error("Errors() exposed the internal slice")
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	var empty Errors
+	b, err := json.Marshal(&empty)
+	if err != nil || string(b) != "[]" {
+		t.Errorf("empty = %s, %v; want []", b, err)
+	}
+
+	var errs Errors
+	errs.Add("items[3].email", errRequired)
+	errs.Add("items[5]", errDuplicate)
+	b, err = json.Marshal(map[string]any{"errors": &errs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"errors":[{"path":"items[3].email","error":"required"},{"path":"items[5]","error":"duplicate username"}]}`
+	if string(b) != want {
+		t.Errorf("json = %s\nwant   %s", b, want)
+	}
+}
@@ -0,0 +1,223 @@
+// ============================================================================
+// retry - 指数退避重试：全抖动、次数和总时长预算、按错误类型决定是否重试
+// ============================================================================
+// 运行测试: go test -v ./retry
+//
+// 【用法】
+//
+//	err := retry.Do(ctx, func(ctx context.Context) error {
+//	    return callPaymentService(ctx)
+//	}, retry.Options{
+//	    MaxAttempts: 5,
+//	    MaxElapsed:  10 * time.Second,
+//	    Retryable:   retry.IfIs(ErrUnavailable, io.ErrUnexpectedEOF),
+//	})
+//
+// 需要返回值时用 DoValue：
+//
+//	db, err := retry.DoValue(ctx, connect, retry.Options{MaxAttempts: 3})
+//
+// 【退避：全抖动（full jitter）】
+// 第 n 次重试前等待 rand[0, min(MaxDelay, BaseDelay * 2^(n-1))]。
+//
+// | 策略           | 第 3 次等待（Base 100ms） | 问题                                   |
+// |----------------|---------------------------|----------------------------------------|
+// | 固定间隔       | 100ms                     | 下游恢复前一直以同样的频率打它         |
+// | 纯指数         | 400ms                     | 同时失败的客户端同时重试，又一起失败   |
+// | 全抖动         | 0 ~ 400ms 随机            | 重试在时间上均匀散开，总等待也最短     |
+//
+// 【什么时候停】
+// 任一条件满足即停止，返回最后一次的错误：
+// 成功；错误不可重试（Retryable 返回 false，或用 Permanent 包装）；
+// 次数用完（MaxAttempts）；总时长预算用完（MaxElapsed，包括下一次等待）；
+// ctx 结束（在等待中结束时返回 ctx.Err()）。
+//
+// 【幂等】
+// 重试意味着 fn 可能执行多次。只重试幂等操作，
+// 或者让下游按请求 ID 去重（见 gin-one/middleware/idempotency）。
+// ============================================================================
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Options 重试配置，零值字段使用默认值
+type Options struct {
+	// MaxAttempts 最多尝试次数（含第一次），默认 3；1 表示不重试
+	MaxAttempts int
+
+	// MaxElapsed 从第一次尝试开始的总时长预算，0 表示不限制
+	// 剩余预算不够下一次等待时直接停止，不会等到一半再放弃
+	MaxElapsed time.Duration
+
+	// BaseDelay 第一次重试前等待时间的上限，默认 100ms
+	BaseDelay time.Duration
+
+	// MaxDelay 单次等待的上限，默认 10s
+	MaxDelay time.Duration
+
+	// Retryable 判断错误是否值得重试，nil 表示除 Permanent 外的错误都重试
+	// 用 IfIs / IfAs 按 errors.Is / errors.As 分类
+	Retryable func(error) bool
+
+	// OnAttempt 每次尝试结束后调用（包括成功），用于打日志、打点、释放这次尝试的资源
+	OnAttempt func(a Attempt)
+}
+
+// Attempt 一次尝试的结果
+type Attempt struct {
+	Number  int           // 第几次尝试，从 1 开始
+	Err     error         // 这次尝试的错误，成功时为 nil
+	Elapsed time.Duration // 从第一次尝试开始到现在
+	Retry   bool          // 是否会重试
+	Delay   time.Duration // 重试前的等待时间，不重试时为 0
+}
+
+// Do 调用 fn，失败时按 opts 重试，返回 nil 或最后一次的错误
+func Do(ctx context.Context, fn func(ctx context.Context) error, opts Options) error {
+	_, err := DoValue(ctx, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}, opts)
+	return err
+}
+
+// DoValue 和 Do 相同，返回 fn 最后一次的返回值
+func DoValue[T any](ctx context.Context, fn func(ctx context.Context) (T, error), opts Options) (T, error) {
+	opts = opts.withDefaults()
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		v, err := fn(ctx)
+		a := Attempt{Number: attempt, Err: err, Elapsed: time.Since(start)}
+		if err != nil && opts.shouldRetry(ctx, err, attempt) {
+			a.Delay = opts.delay(attempt, err)
+			a.Retry = opts.MaxElapsed <= 0 || a.Elapsed+a.Delay < opts.MaxElapsed
+		}
+		if !a.Retry {
+			a.Delay = 0
+		}
+		if opts.OnAttempt != nil {
+			opts.OnAttempt(a)
+		}
+		if !a.Retry {
+			return v, unwrapPermanent(err)
+		}
+
+		timer := time.NewTimer(a.Delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return v, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 3
+	}
+	if o.BaseDelay <= 0 {
+		o.BaseDelay = 100 * time.Millisecond
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = 10 * time.Second
+	}
+	return o
+}
+
+// shouldRetry 调用方取消（ctx 结束）时 fn 返回的错误多半就是 ctx 的错误，不重试
+func (o Options) shouldRetry(ctx context.Context, err error, attempt int) bool {
+	if attempt >= o.MaxAttempts || ctx.Err() != nil {
+		return false
+	}
+	var p *permanentError
+	if errors.As(err, &p) {
+		return false
+	}
+	return o.Retryable == nil || o.Retryable(err)
+}
+
+// delay 第 attempt 次尝试失败后的等待时间
+func (o Options) delay(attempt int, err error) time.Duration {
+	var a *afterError
+	if errors.As(err, &a) {
+		return min(a.delay, o.MaxDelay)
+	}
+	return Backoff(attempt, o.BaseDelay, o.MaxDelay)
+}
+
+// Backoff 全抖动退避：rand[0, min(maxDelay, base * 2^(attempt-1))]
+func Backoff(attempt int, base, maxDelay time.Duration) time.Duration {
+	d := base << (attempt - 1)
+	if d <= 0 || d > maxDelay || attempt > 62 { // 左移溢出时 d 为负或回绕
+		d = maxDelay
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// ============================================================================
+// 错误分类
+// ============================================================================
+
+// Permanent 包装不应该重试的错误，如参数错误、权限不足
+// Do 返回时去掉包装，调用方拿到的是原始错误
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func unwrapPermanent(err error) error {
+	if p, ok := err.(*permanentError); ok {
+		return p.err
+	}
+	return err
+}
+
+// After 包装错误并指定下一次重试前的等待时间（不超过 MaxDelay），
+// 用于服务端明确告诉了何时重试的情况，如 HTTP 的 Retry-After
+func After(err error, delay time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &afterError{err: err, delay: max(delay, 0)}
+}
+
+type afterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *afterError) Error() string { return e.err.Error() }
+func (e *afterError) Unwrap() error { return e.err }
+
+// IfIs 错误匹配（errors.Is）任意一个 targets 时重试
+func IfIs(targets ...error) func(error) bool {
+	return func(err error) bool {
+		for _, target := range targets {
+			if errors.Is(err, target) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IfAs 错误链中有 E 类型的错误时重试，如 IfAs[*net.OpError]()
+func IfAs[E error]() func(error) bool {
+	return func(err error) bool {
+		var target E
+		return errors.As(err, &target)
+	}
+}
@@ -0,0 +1,215 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+	"time"
+)
+
+var (
+	errTemporary = errors.New("temporary")
+	errFatal     = errors.New("fatal")
+)
+
+// fast 测试用的短等待
+func fast(o Options) Options {
+	o.BaseDelay, o.MaxDelay = time.Millisecond, 2*time.Millisecond
+	return o
+}
+
+func TestDo(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error // 第 n 次尝试返回 errs[n-1]，用完后返回 nil
+		opts      Options
+		wantErr   error
+		wantCalls int
+	}{
+		{"success first try", nil, Options{}, nil, 1},
+		{"success after retries", []error{errTemporary, errTemporary}, Options{}, nil, 3},
+		{"attempts exhausted", []error{errTemporary, errTemporary, errTemporary, errTemporary}, Options{}, errTemporary, 3},
+		{"max attempts 1 disables retry", []error{errTemporary}, Options{MaxAttempts: 1}, errTemporary, 1},
+		{"custom max attempts", []error{errTemporary, errTemporary, errTemporary, errTemporary}, Options{MaxAttempts: 5}, nil, 5},
+		{"permanent stops and unwraps", []error{Permanent(errFatal)}, Options{}, errFatal, 1},
+		{"wrapped permanent stops", []error{fmt.Errorf("op: %w", Permanent(errFatal))}, Options{}, errFatal, 1},
+		{"retryable by errors.Is", []error{fmt.Errorf("read: %w", errTemporary)}, Options{Retryable: IfIs(errTemporary)}, nil, 2},
+		{"not retryable by errors.Is", []error{errFatal}, Options{Retryable: IfIs(errTemporary)}, errFatal, 1},
+		{"retryable by errors.As", []error{&fs.PathError{Op: "open", Err: errTemporary}}, Options{Retryable: IfAs[*fs.PathError]()}, nil, 2},
+		{"not retryable by errors.As", []error{errTemporary}, Options{Retryable: IfAs[*fs.PathError]()}, errTemporary, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := Do(context.Background(), func(context.Context) error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			}, fast(tt.opts))
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("err = %v; want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d; want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoValue(t *testing.T) {
+	calls := 0
+	v, err := DoValue(context.Background(), func(context.Context) (string, error) {
+		calls++
+		if calls < 2 {
+			return "", errTemporary
+		}
+		return "ok", nil
+	}, fast(Options{}))
+	if err != nil || v != "ok" {
+		t.Errorf("DoValue = %q, %v; want ok, nil", v, err)
+	}
+}
+
+func TestOnAttempt(t *testing.T) {
+	var got []Attempt
+	_ = Do(context.Background(), func(context.Context) error { return errTemporary },
+		fast(Options{MaxAttempts: 3, OnAttempt: func(a Attempt) { got = append(got, a) }}))
+
+	if len(got) != 3 {
+		t.Fatalf("OnAttempt called %d times; want 3", len(got))
+	}
+	for i, a := range got {
+		last := i == len(got)-1
+		if a.Number != i+1 || a.Retry == last || !errors.Is(a.Err, errTemporary) {
+			t.Errorf("attempt %d = %+v", i+1, a)
+		}
+		if a.Delay > 2*time.Millisecond || (last && a.Delay != 0) {
+			t.Errorf("attempt %d delay = %v", i+1, a.Delay)
+		}
+		if i > 0 && a.Elapsed < got[i-1].Elapsed {
+			t.Errorf("elapsed went backwards: %v < %v", a.Elapsed, got[i-1].Elapsed)
+		}
+	}
+}
+
+func TestMaxElapsed(t *testing.T) {
+	calls := 0
+	start := time.Now()
+	err := Do(context.Background(), func(context.Context) error {
+		calls++
+		return errTemporary
+	}, Options{
+		MaxAttempts: 100,
+		MaxElapsed:  30 * time.Millisecond,
+		BaseDelay:   10 * time.Millisecond,
+		MaxDelay:    10 * time.Millisecond,
+	})
+	if !errors.Is(err, errTemporary) {
+		t.Errorf("err = %v; want last error", err)
+	}
+	// 预算不够下一次等待时提前停止，不会超出预算
+	if elapsed := time.Since(start); elapsed > 60*time.Millisecond {
+		t.Errorf("took %v; want to stop within the 30ms budget", elapsed)
+	}
+	if calls >= 100 {
+		t.Errorf("calls = %d; budget should stop retries", calls)
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	t.Run("during wait", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := Do(ctx, func(context.Context) error { return errTemporary },
+			Options{MaxAttempts: 10, BaseDelay: time.Hour, MaxDelay: time.Hour, Retryable: func(error) bool { return true }})
+		// 全抖动可能抽到很短的等待，但总会在 ctx 超时后停止
+		if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, errTemporary) {
+			t.Errorf("err = %v", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Error("Do did not return when ctx ended")
+		}
+	})
+
+	t.Run("fn returns ctx error", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := Do(ctx, func(ctx context.Context) error {
+			calls++
+			cancel()
+			return ctx.Err()
+		}, fast(Options{}))
+		if !errors.Is(err, context.Canceled) || calls != 1 {
+			t.Errorf("err = %v, calls = %d; want Canceled after 1 call", err, calls)
+		}
+	})
+}
+
+func TestAfter(t *testing.T) {
+	if After(nil, time.Second) != nil || Permanent(nil) != nil {
+		t.Fatal("wrapping nil should return nil")
+	}
+
+	var delays []time.Duration
+	calls := 0
+	err := Do(context.Background(), func(context.Context) error {
+		calls++
+		switch calls {
+		case 1:
+			return After(errTemporary, 3*time.Millisecond)
+		case 2:
+			return After(errTemporary, time.Hour) // 超过 MaxDelay 被截断
+		}
+		return nil
+	}, Options{MaxDelay: 5 * time.Millisecond, OnAttempt: func(a Attempt) {
+		if a.Retry {
+			delays = append(delays, a.Delay)
+		}
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delays) != 2 || delays[0] != 3*time.Millisecond || delays[1] != 5*time.Millisecond {
+		t.Errorf("delays = %v; want [3ms 5ms]", delays)
+	}
+	if e := After(errTemporary, 0); !errors.Is(e, errTemporary) || e.Error() != "temporary" {
+		t.Errorf("After does not wrap transparently: %v", e)
+	}
+	if e := Permanent(errFatal); e.Error() != "fatal" {
+		t.Errorf("Permanent().Error() = %q", e.Error())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base, maxDelay := 100*time.Millisecond, time.Second
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},  // 1.6s 被限制到 maxDelay
+		{80, time.Second}, // 左移溢出
+	}
+	for _, tt := range tests {
+		var seenLow, seenHigh bool
+		for i := 0; i < 500; i++ {
+			d := Backoff(tt.attempt, base, maxDelay)
+			if d < 0 || d > tt.ceiling {
+				t.Fatalf("Backoff(%d) = %v; want within [0, %v]", tt.attempt, d, tt.ceiling)
+			}
+			seenLow = seenLow || d < tt.ceiling/2
+			seenHigh = seenHigh || d >= tt.ceiling/2
+		}
+		// 全抖动：整个区间都会出现，而不是集中在上半段
+		if !seenLow || !seenHigh {
+			t.Errorf("Backoff(%d) not spread over [0, %v]", tt.attempt, tt.ceiling)
+		}
+	}
+}
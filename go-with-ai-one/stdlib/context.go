@@ -0,0 +1,71 @@
+package stdlib
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// 【context 包】
+// ============================================================================
+// context 包定义了 Context 类型，用于在 goroutine 之间传递截止日期、
+// 取消信号和请求范围的值
+//
+// 【创建 Context】
+// Background() - 根 context
+// TODO() - 占位 context
+// WithCancel(parent) - 可取消的 context
+// WithTimeout(parent, d) - 带超时的 context
+// WithDeadline(parent, t) - 带截止时间的 context
+// WithValue(parent, k, v) - 携带值的 context
+//
+// 【使用规则】
+// 1. Context 应该作为函数的第一个参数
+// 2. 不要存储 Context
+// 3. 不要传递 nil Context
+// 4. WithValue 的 key 使用自定义类型，避免不同包之间冲突
+// ============================================================================
+
+// ctxKey 自定义 key 类型，其他包即使用同样的字符串也不会冲突
+type ctxKey string
+
+// Context 演示取消、超时和传值
+func Context() {
+	fmt.Println("\n--- context 包 ---")
+
+	// Background 和 TODO
+	fmt.Println("context.Background() - 根 context")
+	fmt.Println("context.TODO() - 占位符 context")
+
+	// WithCancel
+	// 【done channel】等待 goroutine 打印完再继续，输出顺序才是确定的
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func(ctx context.Context) {
+		defer close(done)
+		<-ctx.Done()
+		fmt.Printf("WithCancel: 收到取消信号 (%v)\n", ctx.Err())
+	}(ctx)
+	cancel() // 调用 cancel 取消 context
+	<-done
+
+	// WithTimeout
+	// 【特点】指定时间后自动取消
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel2() // 即使超时，也要调用 cancel 释放资源
+	select {
+	case <-time.After(time.Second):
+		fmt.Println("WithTimeout: 操作完成")
+	case <-ctx2.Done():
+		fmt.Printf("WithTimeout: %v\n", ctx2.Err())
+	}
+
+	// WithValue
+	// 【用途】传递请求范围的值（如用户 ID、请求 ID）
+	// 【注意】不要用于传递可选参数，只用于跨 API 边界的请求数据
+	ctx3 := context.WithValue(context.Background(), ctxKey("userID"), 123)
+	fmt.Printf("WithValue: userID=%v\n", ctx3.Value(ctxKey("userID")))
+	// key 类型不同就取不到值
+	fmt.Printf("WithValue: 字符串 key=%v\n", ctx3.Value("userID"))
+}
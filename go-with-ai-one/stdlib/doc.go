@@ -0,0 +1,36 @@
+// ============================================================================
+// stdlib - 常用标准库（13_stdlib.go 的可测试版本）
+// ============================================================================
+// 运行: go test -v ./stdlib
+// 演示: go run 13_stdlib.go
+//
+// 【为什么拆成包】
+// 13_stdlib.go 原来只能 go run 看输出，示例代码写错了也没人发现。
+// 每个主题现在是一个导出函数，配套的 Example 函数带 // Output: 注释，
+// go test 会比对实际输出，教学内容本身就是可执行、可验证的文档。
+//
+// 【文件组织】
+// | 文件         | 函数      | 主题                       |
+// |--------------|-----------|----------------------------|
+// | fmt.go       | Fmt       | 格式化输出、格式化动词     |
+// | strings.go   | Strings   | 查找、替换、分割、Builder  |
+// | strconv.go   | Strconv   | 字符串与基本类型互转       |
+// | time.go      | Time      | 格式化、解析、计算、定时器 |
+// | os.go        | OS        | 环境变量、文件与目录       |
+// | io.go        | IO        | Reader/Writer、bufio       |
+// | filepath.go  | Filepath  | 路径拆分与拼接             |
+// | json.go      | JSON      | 序列化、反序列化、标签     |
+// | regexp.go    | Regexp    | 匹配、查找、替换           |
+// | sort.go      | Sort      | 基本排序、自定义排序、二分 |
+// | context.go   | Context   | 取消、超时、传值           |
+// | log.go       | Log       | 自定义 Logger              |
+// | flag.go      | Flag      | 命令行参数解析             |
+// | http.go      | HTTP      | 服务器与客户端             |
+// | rand.go      | Rand      | 伪随机数                   |
+//
+// 【可验证输出】
+// Example 的输出必须每次相同，所以依赖当前时间、随机数的演示改为由参数传入：
+// Time(now) 传入固定时间，Rand(seed) 传入固定种子；
+// 13_stdlib.go 运行时传入 time.Now() 和随机种子，效果与原来一致。
+// ============================================================================
+package stdlib
@@ -0,0 +1,250 @@
+package stdlib_test
+
+import (
+	"time"
+
+	"go-learning/stdlib"
+)
+
+// ============================================================================
+// 【示例测试】
+// ============================================================================
+// 每个 Example 调用一个主题函数，go test 比对 // Output: 注释和实际输出。
+// 修改了 stdlib 里的演示代码却忘了更新这里的输出，测试就会失败。
+//
+// 使用外部测试包 stdlib_test，只能访问导出的函数，和读者的使用方式一致。
+// ============================================================================
+
+func ExampleFmt() {
+	stdlib.Fmt()
+	// Output:
+	// --- fmt 包 ---
+	// Print: 不换行 Println: 换行
+	// Printf: name=Gopher, age=10, score=95.5
+	// Sprintf: name=Gopher, age=10
+	//
+	// 常用格式化动词:
+	//   %v  通用: map[a:1]
+	//   %+v 带字段名: {Name:Go}
+	//   %#v Go语法: []int{1, 2}
+	//   %T  类型: float64
+	//   %d  十进制: 42
+	//   %b  二进制: 101010
+	//   %o  八进制: 52
+	//   %x  十六进制: 2a
+	//   %f  浮点数: 3.141590
+	//   %.2f 精度: 3.14
+	//   %e  科学计数: 1.234568e+05
+	//   %s  字符串: hello
+	//   %q  带引号: "hello"
+	//   %p  指针: 0x...
+}
+
+func ExampleStrings() {
+	stdlib.Strings()
+	// Output:
+	// --- strings 包 ---
+	// 原字符串: Hello, World!
+	// Contains: true
+	// HasPrefix: true
+	// HasSuffix: true
+	// Index: 7
+	// ToUpper: HELLO, WORLD!
+	// ToLower: hello, world!
+	// Replace: Hello, Go!
+	// Split: [Hello World!]
+	// Join: a-b-c
+	// TrimSpace: [hello]
+	// Repeat: GoGoGo
+	// Count: 3
+	// Builder: Hello, Builder!
+}
+
+func ExampleStrconv() {
+	stdlib.Strconv()
+	// Output:
+	// --- strconv 包 ---
+	// Atoi: "42" -> 42
+	// Atoi 错误: strconv.Atoi: parsing "abc": invalid syntax
+	// ParseFloat: "3.14" -> 3.140000
+	// ParseBool: "true" -> true
+	// Itoa: 42 -> "42"
+	// FormatFloat: 3.14159 -> "3.14"
+	// FormatBool: true -> "true"
+	// Quote: "Hello\tWorld"
+}
+
+func ExampleTime() {
+	// 传入固定时间，输出才能被验证
+	stdlib.Time(time.Date(2024, 3, 15, 14, 30, 0, 0, time.UTC))
+	// Output:
+	// --- time 包 ---
+	// 当前时间: 2024-03-15 14:30:00 +0000 UTC
+	// 格式化: 2024-03-15 14:30:00
+	// RFC3339: 2024-03-15T14:30:00Z
+	// 年月日: 2024-3-15
+	// 时分秒: 14:30:0
+	// 星期: Friday
+	// 解析: 2024-03-15 00:00:00 +0000 UTC
+	// 明天: 2024-03-16
+	// 昨天: 2024-03-14
+	// now.Before(tomorrow): true
+	// now.After(yesterday): true
+	// 距离 2024-03-15: 14h30m0s
+	// Duration: 2h30m0s
+	// 耗时 >= 10ms: true
+	// Timer 触发
+}
+
+func ExampleOS() {
+	stdlib.OS()
+	// Output:
+	// --- os 包 ---
+	// Getenv: hello
+	// LookupEnv 不存在的变量: false
+	// ReadFile: Hello, os!
+	// Stat: name=hello.txt size=10 dir=false
+	// ReadDir: a (dir=true)
+	// ReadDir: hello.txt (dir=false)
+	// Rename 后原文件不存在: true
+}
+
+func ExampleIO() {
+	stdlib.IO()
+	// Output:
+	// --- io 包 ---
+	// Buffer 内容: Hello, World!
+	// 读取 5 字节: Hello
+	// io.Copy 结果: Copy this text
+	// bufio.ReadString: "line1\n"
+	// Scanner: line1
+	// Scanner: line2
+	// Scanner: line3
+}
+
+func ExampleFilepath() {
+	stdlib.Filepath()
+	// Output:
+	// --- filepath 包 ---
+	// 原路径: /home/user/documents/file.txt
+	// Dir: /home/user/documents
+	// Base: file.txt
+	// Ext: .txt
+	// Join: home/user/file.txt
+	// Split: /home/user/documents/ file.txt
+	// Clean: /a/c/d
+	// Rel: documents/file.txt
+	// Match: true
+}
+
+func ExampleJSON() {
+	stdlib.JSON()
+	// Output:
+	// --- encoding/json 包 ---
+	// Marshal: {"name":"Alice","age":25,"hobbies":["reading","coding"]}
+	// MarshalIndent:
+	// {
+	//   "name": "Alice",
+	//   "age": 25,
+	//   "hobbies": [
+	//     "reading",
+	//     "coding"
+	//   ]
+	// }
+	// Unmarshal: {Name:Bob Age:30 Email: Hobbies:[music]}
+	// Unmarshal to map: map[age:30 hobbies:[music] name:Bob]
+	// age 的类型: float64
+	// 语法错误: invalid character '}' looking for beginning of value
+}
+
+func ExampleRegexp() {
+	stdlib.Regexp()
+	// Output:
+	// --- regexp 包 ---
+	// 原文本: abc123def456
+	// FindString: 123
+	// FindAllString: [123 456]
+	// MatchString: true
+	// ReplaceAllString: abc#def#
+	// FindStringSubmatch: ["2024-03-15" "2024" "03" "15"]
+	// 邮箱验证 'test@example.com': true
+	// 邮箱验证 'not-an-email': false
+}
+
+func ExampleSort() {
+	stdlib.Sort()
+	// Output:
+	// --- sort 包 ---
+	// Ints: [1 2 5 8 9]
+	// Strings: [apple banana cherry]
+	// Float64s: [1.41 2.72 3.14]
+	// 自定义排序: [{Bob 25} {Alice 30} {Charlie 35}]
+	// IsSorted: true
+	// SearchInts(5): 索引 2
+}
+
+func ExampleContext() {
+	stdlib.Context()
+	// Output:
+	// --- context 包 ---
+	// context.Background() - 根 context
+	// context.TODO() - 占位符 context
+	// WithCancel: 收到取消信号 (context canceled)
+	// WithTimeout: context deadline exceeded
+	// WithValue: userID=123
+	// WithValue: 字符串 key=<nil>
+}
+
+func ExampleLog() {
+	stdlib.Log()
+	// Output:
+	// --- log 包 ---
+	// 日志输出: [INFO] 自定义日志
+	// 日志输出: [WARN] 磁盘剩余 10%
+	//
+	// 日志标志:
+	//   log.Ldate      - 日期
+	//   log.Ltime      - 时间
+	//   log.Lmicroseconds - 微秒
+	//   log.Llongfile  - 完整文件路径
+	//   log.Lshortfile - 文件名和行号
+	//   log.LUTC       - UTC 时间
+}
+
+func ExampleFlag() {
+	stdlib.Flag([]string{"-name=Gopher", "-age", "10", "-v", "file.txt", "-x"})
+	// Output:
+	// --- flag 包 ---
+	// 参数: ["-name=Gopher" "-age" "10" "-v" "file.txt" "-x"]
+	// name=Gopher age=10 verbose=true
+	// Args: ["file.txt" "-x"]
+}
+
+func ExampleFlag_invalid() {
+	stdlib.Flag([]string{"-age=abc"})
+	// Output:
+	// --- flag 包 ---
+	// 参数: ["-age=abc"]
+	// 解析失败: invalid value "abc" for flag -age: parse error
+}
+
+func ExampleHTTP() {
+	stdlib.HTTP()
+	// Output:
+	// --- net/http 包 ---
+	// 状态码: 200
+	// Content-Type: text/plain; charset=utf-8
+	// 响应: Hello, Gopher!
+	// 未注册路径: 404 Not Found
+}
+
+func ExampleRand() {
+	// 固定种子，每次运行得到相同的序列
+	stdlib.Rand(42)
+	// Output:
+	// --- math/rand 包 ---
+	// 随机整数: 5
+	// 随机浮点数: 0.0660
+	// 打乱后: [2 3 5 1 4]
+	// 随机字符串: tuezptne
+}
@@ -0,0 +1,54 @@
+package stdlib
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// ============================================================================
+// 【filepath 包】
+// ============================================================================
+// filepath 包提供文件路径操作，兼容不同操作系统
+//
+// 【常用函数】
+// Dir(path) - 目录部分
+// Base(path) - 文件名部分
+// Ext(path) - 扩展名
+// Join(elem...) - 连接路径
+// Split(path) - 分割为目录和文件名
+// Clean(path) - 清理路径
+// Abs(path) - 绝对路径
+// Rel(basepath, targpath) - 相对路径
+// Match(pattern, name) - 模式匹配
+// Walk(root, fn) - 遍历目录树
+// ============================================================================
+
+// Filepath 演示路径拆分、拼接和清理
+// 【注意】输出使用 / 分隔符，在 Windows 上 Join 的结果会是 \
+func Filepath() {
+	fmt.Println("\n--- filepath 包 ---")
+
+	path := "/home/user/documents/file.txt"
+
+	fmt.Printf("原路径: %s\n", path)
+	fmt.Printf("Dir: %s\n", filepath.Dir(path))   // /home/user/documents
+	fmt.Printf("Base: %s\n", filepath.Base(path)) // file.txt
+	fmt.Printf("Ext: %s\n", filepath.Ext(path))   // .txt
+	fmt.Printf("Join: %s\n", filepath.Join("home", "user", "file.txt"))
+
+	// 【Split】返回两个值，不能直接作为 Printf 的一个参数
+	dir, file := filepath.Split(path)
+	fmt.Printf("Split: %s %s\n", dir, file)
+
+	// 路径清理
+	// 【Clean】规范化路径，处理 . 和 ..
+	fmt.Printf("Clean: %s\n", filepath.Clean("/a/b/../c/./d")) // /a/c/d
+
+	// 相对路径
+	rel, _ := filepath.Rel("/home/user", path)
+	fmt.Printf("Rel: %s\n", rel)
+
+	// 模式匹配
+	matched, _ := filepath.Match("*.txt", "file.txt")
+	fmt.Printf("Match: %v\n", matched)
+}
@@ -0,0 +1,59 @@
+package stdlib
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// ============================================================================
+// 【flag 包】
+// ============================================================================
+// flag 包提供命令行参数解析
+//
+// 【定义标志】
+// String(name, default, usage) - 字符串标志
+// Int(name, default, usage) - 整数标志
+// Bool(name, default, usage) - 布尔标志
+// Duration(name, default, usage) - 时间段标志
+//
+// 【解析】
+// Parse() - 解析命令行参数
+// Args() - 非标志参数
+//
+// 【使用模式】
+// 1. 定义标志（返回指针）
+// 2. 调用 flag.Parse()
+// 3. 使用 *flag 获取值
+//
+// 【FlagSet】
+// flag.String 等函数注册在全局 flag.CommandLine 上，只能解析 os.Args；
+// 自己创建 FlagSet 可以解析任意参数列表，子命令和测试都用这种方式
+// ============================================================================
+
+// Flag 用独立的 FlagSet 解析 args
+func Flag(args []string) {
+	fmt.Println("\n--- flag 包 ---")
+
+	fs := flag.NewFlagSet("demo", flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // 不打印用法，错误由返回值处理
+
+	// 定义标志
+	name := fs.String("name", "default", "用户名")
+	age := fs.Int("age", 0, "年龄")
+	verbose := fs.Bool("v", false, "详细模式")
+
+	// 解析
+	fmt.Printf("参数: %q\n", args)
+	if err := fs.Parse(args); err != nil {
+		fmt.Printf("解析失败: %v\n", err)
+		return
+	}
+
+	// 使用
+	fmt.Printf("name=%s age=%d verbose=%v\n", *name, *age, *verbose)
+
+	// 非标志参数
+	// 【注意】遇到第一个非标志参数后停止解析，后面的 -x 也会被当作普通参数
+	fmt.Printf("Args: %q\n", fs.Args())
+}
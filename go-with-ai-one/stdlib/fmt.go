@@ -0,0 +1,82 @@
+package stdlib
+
+import "fmt"
+
+// ============================================================================
+// 【fmt 包】
+// ============================================================================
+// fmt 包实现了格式化 I/O
+//
+// 【Print 系列】
+// Print, Println, Printf - 输出到标准输出
+// Sprint, Sprintln, Sprintf - 返回字符串
+// Fprint, Fprintln, Fprintf - 输出到 io.Writer
+//
+// 【Scan 系列】
+// Scan, Scanln, Scanf - 从标准输入读取
+// Sscan, Sscanln, Sscanf - 从字符串读取
+// Fscan, Fscanln, Fscanf - 从 io.Reader 读取
+// ============================================================================
+
+// Fmt 演示 Print 系列和常用格式化动词
+func Fmt() {
+	fmt.Println("--- fmt 包 ---")
+
+	// 格式化输出
+	name := "Gopher"
+	age := 10
+	score := 95.5
+
+	// Print 系列
+	// 【区别】
+	// Print: 不换行，参数间无空格
+	// Println: 换行，参数间有空格
+	// Printf: 格式化输出
+	fmt.Print("Print: 不换行")
+	fmt.Println(" Println: 换行")
+	fmt.Printf("Printf: name=%s, age=%d, score=%.1f\n", name, age, score)
+
+	// Sprint 系列（返回字符串）
+	// 【用途】
+	// - 构建字符串
+	// - 不直接输出，而是保存结果
+	str := fmt.Sprintf("name=%s, age=%d", name, age)
+	fmt.Printf("Sprintf: %s\n", str)
+
+	// 常用格式化动词
+	// 【格式化动词表】
+	// | 动词  | 说明                           | 示例                    |
+	// |-------|--------------------------------|-------------------------|
+	// | %v    | 默认格式                       | {name: "Go"}            |
+	// | %+v   | 结构体带字段名                 | {Name:Go}               |
+	// | %#v   | Go 语法表示                    | main.Person{Name:"Go"}  |
+	// | %T    | 类型                           | main.Person             |
+	// | %d    | 十进制整数                     | 42                      |
+	// | %b    | 二进制                         | 101010                  |
+	// | %o    | 八进制                         | 52                      |
+	// | %x    | 十六进制（小写）               | 2a                      |
+	// | %X    | 十六进制（大写）               | 2A                      |
+	// | %f    | 浮点数                         | 3.141593                |
+	// | %.2f  | 浮点数（2 位小数）             | 3.14                    |
+	// | %e    | 科学计数法                     | 3.141593e+00            |
+	// | %s    | 字符串                         | hello                   |
+	// | %q    | 带引号字符串                   | "hello"                 |
+	// | %p    | 指针                           | 0xc0000...              |
+	// | %%    | 百分号                         | %                       |
+	fmt.Println("\n常用格式化动词:")
+	fmt.Printf("  %%v  通用: %v\n", map[string]int{"a": 1})
+	fmt.Printf("  %%+v 带字段名: %+v\n", struct{ Name string }{"Go"})
+	fmt.Printf("  %%#v Go语法: %#v\n", []int{1, 2})
+	fmt.Printf("  %%T  类型: %T\n", 3.14)
+	fmt.Printf("  %%d  十进制: %d\n", 42)
+	fmt.Printf("  %%b  二进制: %b\n", 42)
+	fmt.Printf("  %%o  八进制: %o\n", 42)
+	fmt.Printf("  %%x  十六进制: %x\n", 42)
+	fmt.Printf("  %%f  浮点数: %f\n", 3.14159)
+	fmt.Printf("  %%.2f 精度: %.2f\n", 3.14159)
+	fmt.Printf("  %%e  科学计数: %e\n", 123456.789)
+	fmt.Printf("  %%s  字符串: %s\n", "hello")
+	fmt.Printf("  %%q  带引号: %q\n", "hello")
+	// 指针地址每次运行都不同，这里只打印前缀
+	fmt.Printf("  %%p  指针: %s...\n", fmt.Sprintf("%p", &name)[:2])
+}
@@ -0,0 +1,77 @@
+package stdlib
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+)
+
+// ============================================================================
+// 【net/http 包】
+// ============================================================================
+// http 包提供 HTTP 客户端和服务器实现
+//
+// 【服务器】
+// HandleFunc(pattern, handler) - 注册处理函数
+// ListenAndServe(addr, handler) - 启动服务器
+// ListenAndServeTLS(addr, certFile, keyFile, handler) - HTTPS 服务器
+//
+// 【客户端】
+// Get(url) - GET 请求
+// Post(url, contentType, body) - POST 请求
+// Client.Do(req) - 自定义请求
+//
+// 【常用类型】
+// Request - 请求
+// Response - 响应
+// ResponseWriter - 响应写入器
+// Handler - 处理器接口
+// ServeMux - 路由器
+//
+// 【httptest】
+// httptest.NewServer 在随机端口启动真实的服务器，适合演示和测试
+//
+// 【生产环境的客户端】
+// http.Get 用的 http.DefaultClient 没有超时，下游不响应时会一直阻塞。
+// 调用其他服务时用 httpclient 包：单次超时、幂等请求退避重试、熔断、日志钩子。
+// ============================================================================
+
+// HTTP 启动一个本地服务器并用客户端请求它
+func HTTP() {
+	fmt.Println("\n--- net/http 包 ---")
+
+	// 服务器
+	// 【Go 1.22+】ServeMux 支持在模式中写方法和路径参数："GET /hello/{name}"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			name = "World"
+		}
+		fmt.Fprintf(w, "Hello, %s!", name)
+	})
+	// 生产环境: http.ListenAndServe(":8080", mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// 客户端
+	// 【重要】必须关闭 resp.Body，否则连接无法复用
+	resp, err := http.Get(srv.URL + "/hello?name=Gopher")
+	if err != nil {
+		fmt.Println("请求失败:", err)
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Printf("状态码: %d\n", resp.StatusCode)
+	fmt.Printf("Content-Type: %s\n", resp.Header.Get("Content-Type"))
+	fmt.Printf("响应: %s\n", body)
+
+	// 未注册的路径返回 404
+	resp2, err := http.Get(srv.URL + "/missing")
+	if err == nil {
+		resp2.Body.Close()
+		fmt.Printf("未注册路径: %s\n", resp2.Status)
+	}
+}
@@ -0,0 +1,74 @@
+package stdlib
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ============================================================================
+// 【io 包】
+// ============================================================================
+// io 包提供 I/O 原语的基本接口
+//
+// 【核心接口】
+// io.Reader - Read(p []byte) (n int, err error)
+// io.Writer - Write(p []byte) (n int, err error)
+// io.Closer - Close() error
+// io.Seeker - Seek(offset int64, whence int) (int64, error)
+//
+// 【常用函数】
+// io.Copy(dst, src) - 复制数据
+// io.ReadAll(r) - 读取所有数据（Go 1.16+）
+// io.WriteString(w, s) - 写入字符串
+//
+// 【bufio 包】
+// 带缓冲的 I/O，提高性能
+// bufio.NewReader(r) - 带缓冲的 Reader
+// bufio.NewWriter(w) - 带缓冲的 Writer
+// bufio.NewScanner(r) - 按行读取
+// ============================================================================
+
+// IO 演示 bytes.Buffer、io.Copy 和 bufio
+func IO() {
+	fmt.Println("\n--- io 包 ---")
+
+	// bytes.Buffer 实现了 io.Reader 和 io.Writer
+	// 【bytes.Buffer】
+	// - 可读可写的字节缓冲区
+	// - 常用于测试和构建数据
+	var buf bytes.Buffer
+
+	// 写入
+	buf.WriteString("Hello, ")
+	buf.Write([]byte("World!"))
+	fmt.Printf("Buffer 内容: %s\n", buf.String())
+
+	// 读取
+	data := make([]byte, 5)
+	buf.Read(data)
+	fmt.Printf("读取 5 字节: %s\n", data)
+
+	// io.Copy
+	// 【用途】从 Reader 复制到 Writer
+	src := strings.NewReader("Copy this text")
+	var dst bytes.Buffer
+	io.Copy(&dst, src)
+	fmt.Printf("io.Copy 结果: %s\n", dst.String())
+
+	// bufio
+	// 【bufio.Reader】
+	// - 带缓冲，减少系统调用
+	// - 提供便捷的读取方法
+	reader := bufio.NewReader(strings.NewReader("line1\nline2\nline3"))
+	line, _ := reader.ReadString('\n') // 读取到换行符（包含换行符）
+	fmt.Printf("bufio.ReadString: %q\n", line)
+
+	// 【bufio.Scanner】按行读取，自动去掉换行符
+	scanner := bufio.NewScanner(strings.NewReader("line1\nline2\nline3"))
+	for scanner.Scan() {
+		fmt.Printf("Scanner: %s\n", scanner.Text())
+	}
+}
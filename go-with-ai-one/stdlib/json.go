@@ -0,0 +1,76 @@
+package stdlib
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ============================================================================
+// 【encoding/json 包】
+// ============================================================================
+// json 包提供 JSON 编码和解码
+//
+// 【序列化（Go -> JSON）】
+// Marshal(v) - 序列化为 []byte
+// MarshalIndent(v, prefix, indent) - 带缩进的序列化
+// Encoder.Encode(v) - 流式编码
+//
+// 【反序列化（JSON -> Go）】
+// Unmarshal(data, v) - 反序列化到变量
+// Decoder.Decode(v) - 流式解码
+//
+// 【结构体标签】
+// `json:"name"` - 指定 JSON 字段名
+// `json:"name,omitempty"` - 空值时省略
+// `json:"-"` - 忽略此字段
+// `json:"name,string"` - 数字以字符串形式编码
+// ============================================================================
+
+// JSON 演示结构体与 JSON 的互相转换
+func JSON() {
+	fmt.Println("\n--- encoding/json 包 ---")
+
+	// 定义结构体，使用 JSON 标签
+	type Person struct {
+		Name    string   `json:"name"`            // 指定 JSON 字段名
+		Age     int      `json:"age"`             //
+		Email   string   `json:"email,omitempty"` // 空值时省略
+		Hobbies []string `json:"hobbies"`         //
+	}
+
+	// 序列化
+	person := Person{
+		Name:    "Alice",
+		Age:     25,
+		Hobbies: []string{"reading", "coding"},
+		// Email 为空，会被省略
+	}
+
+	// 【Marshal】返回紧凑的 JSON
+	jsonData, _ := json.Marshal(person)
+	fmt.Printf("Marshal: %s\n", jsonData)
+
+	// 【MarshalIndent】返回格式化的 JSON
+	prettyJSON, _ := json.MarshalIndent(person, "", "  ")
+	fmt.Printf("MarshalIndent:\n%s\n", prettyJSON)
+
+	// 反序列化
+	jsonStr := `{"name":"Bob","age":30,"hobbies":["music"]}`
+	var p2 Person
+	// 【Unmarshal】第二个参数必须是指针
+	json.Unmarshal([]byte(jsonStr), &p2)
+	fmt.Printf("Unmarshal: %+v\n", p2)
+
+	// 解析到 map
+	// 【动态 JSON】不知道结构时，可以解析到 map[string]interface{}
+	// 【注意】JSON 数字解析到 interface{} 时是 float64
+	var m map[string]interface{}
+	json.Unmarshal([]byte(jsonStr), &m)
+	fmt.Printf("Unmarshal to map: %v\n", m)
+	fmt.Printf("age 的类型: %T\n", m["age"])
+
+	// 错误处理
+	// 【语法错误】返回 *json.SyntaxError，包含出错位置
+	err := json.Unmarshal([]byte(`{"name":}`), &p2)
+	fmt.Printf("语法错误: %v\n", err)
+}
@@ -0,0 +1,62 @@
+package stdlib
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+)
+
+// ============================================================================
+// 【log 包】
+// ============================================================================
+// log 包提供简单的日志功能
+//
+// 【基本函数】
+// Print, Println, Printf - 普通日志
+// Fatal, Fatalln, Fatalf - 日志后 os.Exit(1)
+// Panic, Panicln, Panicf - 日志后 panic
+//
+// 【自定义 Logger】
+// New(out, prefix, flag) - 创建 Logger
+//
+// 【日志标志】
+// Ldate - 日期
+// Ltime - 时间
+// Lmicroseconds - 微秒
+// Llongfile - 完整文件路径
+// Lshortfile - 文件名和行号
+// LUTC - UTC 时间
+// Lmsgprefix - 前缀放在消息前面，而不是行首
+// LstdFlags - Ldate | Ltime
+//
+// 【结构化日志】Go 1.21+ 推荐使用 log/slog
+// ============================================================================
+
+// Log 演示自定义 Logger 的前缀和标志
+func Log() {
+	fmt.Println("\n--- log 包 ---")
+
+	// 自定义 logger
+	// 【参数】(输出目标, 前缀, 标志)
+	// 标志为 0 时不输出日期时间，输出内容可预测，适合测试
+	var buf bytes.Buffer
+	logger := log.New(&buf, "[INFO] ", 0)
+	logger.Println("自定义日志")
+	fmt.Printf("日志输出: %s", buf.String())
+
+	// Lmsgprefix：前缀紧挨消息
+	buf.Reset()
+	logger.SetFlags(log.Lmsgprefix)
+	logger.SetPrefix("[WARN] ")
+	logger.Printf("磁盘剩余 %d%%", 10)
+	fmt.Printf("日志输出: %s", buf.String())
+
+	// 日志标志
+	fmt.Println("\n日志标志:")
+	fmt.Println("  log.Ldate      - 日期")
+	fmt.Println("  log.Ltime      - 时间")
+	fmt.Println("  log.Lmicroseconds - 微秒")
+	fmt.Println("  log.Llongfile  - 完整文件路径")
+	fmt.Println("  log.Lshortfile - 文件名和行号")
+	fmt.Println("  log.LUTC       - UTC 时间")
+}
@@ -0,0 +1,78 @@
+package stdlib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ============================================================================
+// 【os 包】
+// ============================================================================
+// os 包提供操作系统功能的平台无关接口
+//
+// 【环境变量】
+// Getenv(key) - 获取环境变量
+// LookupEnv(key) - 获取环境变量，并返回是否存在
+// Setenv(key, value) - 设置环境变量
+// Environ() - 所有环境变量
+//
+// 【文件操作】
+// Create(name) - 创建文件
+// Open(name) - 打开文件（只读）
+// OpenFile(name, flag, perm) - 打开文件（指定模式）
+// ReadFile(name) - 读取整个文件（Go 1.16+）
+// WriteFile(name, data, perm) - 写入整个文件（Go 1.16+）
+// Remove(name) - 删除文件
+// Rename(old, new) - 重命名
+//
+// 【目录操作】
+// Mkdir(name, perm) - 创建目录
+// MkdirAll(path, perm) - 递归创建目录
+// MkdirTemp(dir, pattern) - 创建临时目录
+// ReadDir(name) - 读取目录（Go 1.16+）
+// ============================================================================
+
+// OS 演示环境变量和文件操作，所有文件都写在临时目录里，结束时删除
+func OS() {
+	fmt.Println("\n--- os 包 ---")
+
+	// 环境变量
+	// 【LookupEnv】能区分「未设置」和「设置为空字符串」，Getenv 做不到
+	os.Setenv("STDLIB_DEMO", "hello")
+	defer os.Unsetenv("STDLIB_DEMO")
+	fmt.Printf("Getenv: %s\n", os.Getenv("STDLIB_DEMO"))
+	_, ok := os.LookupEnv("STDLIB_DEMO_MISSING")
+	fmt.Printf("LookupEnv 不存在的变量: %v\n", ok)
+
+	// 临时目录
+	// 【MkdirTemp】在系统临时目录下创建唯一的目录，用完记得删除
+	dir, err := os.MkdirTemp("", "stdlib-demo-")
+	if err != nil {
+		fmt.Println("MkdirTemp 失败:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// 写入和读取整个文件
+	name := filepath.Join(dir, "hello.txt")
+	os.WriteFile(name, []byte("Hello, os!"), 0o644)
+	data, _ := os.ReadFile(name)
+	fmt.Printf("ReadFile: %s\n", data)
+
+	// 文件信息
+	info, _ := os.Stat(name)
+	fmt.Printf("Stat: name=%s size=%d dir=%v\n", info.Name(), info.Size(), info.IsDir())
+
+	// 递归创建目录并列出内容
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0o755)
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		fmt.Printf("ReadDir: %s (dir=%v)\n", e.Name(), e.IsDir())
+	}
+
+	// 重命名和删除
+	os.Rename(name, filepath.Join(dir, "renamed.txt"))
+	_, err = os.Stat(name)
+	fmt.Printf("Rename 后原文件不存在: %v\n", os.IsNotExist(err))
+}
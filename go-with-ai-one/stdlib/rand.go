@@ -0,0 +1,60 @@
+package stdlib
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// ============================================================================
+// 【math/rand 包】
+// ============================================================================
+// rand 包提供伪随机数生成
+//
+// 【Go 1.20+ 变化】
+// 不再需要手动设置种子，默认使用随机种子
+//
+// 【常用函数】
+// Int() - 随机 int
+// Intn(n) - [0, n) 的随机 int
+// Int63() - 随机 int64
+// Float64() - [0.0, 1.0) 的随机 float64
+// Shuffle(n, swap) - 随机打乱
+//
+// 【固定种子】
+// rand.New(rand.NewSource(seed)) 创建独立的生成器，
+// 同一个种子每次产生完全相同的序列，便于复现问题和编写测试
+//
+// 【注意】
+// - math/rand 不是加密安全的
+// - 加密用途请使用 crypto/rand
+// - *rand.Rand 不是并发安全的，全局函数（rand.Intn 等）是
+// ============================================================================
+
+// Rand 用 seed 创建生成器演示随机数
+func Rand(seed int64) {
+	fmt.Println("\n--- math/rand 包 ---")
+
+	r := rand.New(rand.NewSource(seed))
+
+	// 【Intn】返回 [0, n) 的随机整数
+	fmt.Printf("随机整数: %d\n", r.Intn(100))
+
+	// 【Float64】返回 [0.0, 1.0) 的随机浮点数
+	fmt.Printf("随机浮点数: %.4f\n", r.Float64())
+
+	// 随机打乱切片
+	// 【Shuffle】第一个参数是长度，第二个是交换函数
+	nums := []int{1, 2, 3, 4, 5}
+	r.Shuffle(len(nums), func(i, j int) {
+		nums[i], nums[j] = nums[j], nums[i]
+	})
+	fmt.Printf("打乱后: %v\n", nums)
+
+	// 生成随机字符串
+	const charset = "abcdefghijklmnopqrstuvwxyz"
+	result := make([]byte, 8)
+	for i := range result {
+		result[i] = charset[r.Intn(len(charset))]
+	}
+	fmt.Printf("随机字符串: %s\n", string(result))
+}
@@ -0,0 +1,66 @@
+package stdlib
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// ============================================================================
+// 【regexp 包】
+// ============================================================================
+// regexp 包提供正则表达式功能
+//
+// 【编译正则】
+// Compile(expr) - 编译，返回错误
+// MustCompile(expr) - 编译，失败则 panic
+//
+// 【匹配方法】
+// MatchString(s) - 是否匹配
+// FindString(s) - 第一个匹配
+// FindAllString(s, n) - 所有匹配（n=-1 表示全部）
+// FindStringSubmatch(s) - 带捕获组的匹配
+//
+// 【替换方法】
+// ReplaceAllString(s, repl) - 替换所有匹配
+// ReplaceAllStringFunc(s, f) - 用函数替换
+//
+// 【常用正则语法】
+// .  - 任意字符
+// *  - 0 或多个
+// +  - 1 或多个
+// ?  - 0 或 1 个
+// \d - 数字
+// \w - 字母数字下划线
+// \s - 空白字符
+// [] - 字符类
+// () - 捕获组
+// ============================================================================
+
+// 静态正则在包级别编译一次，不要在每次调用时重复编译
+var emailRe = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// Regexp 演示匹配、查找、替换和捕获组
+func Regexp() {
+	fmt.Println("\n--- regexp 包 ---")
+
+	// 编译正则表达式
+	// 【MustCompile】失败会 panic，适合静态正则
+	re := regexp.MustCompile(`\d+`)
+
+	text := "abc123def456"
+	fmt.Printf("原文本: %s\n", text)
+	fmt.Printf("FindString: %s\n", re.FindString(text))                  // 第一个匹配
+	fmt.Printf("FindAllString: %v\n", re.FindAllString(text, -1))        // 所有匹配
+	fmt.Printf("MatchString: %v\n", re.MatchString(text))                // 是否匹配
+	fmt.Printf("ReplaceAllString: %s\n", re.ReplaceAllString(text, "#")) // 替换
+
+	// 捕获组
+	// 【FindStringSubmatch】返回 [完整匹配, 第1组, 第2组, ...]
+	dateRe := regexp.MustCompile(`(\d{4})-(\d{2})-(\d{2})`)
+	fmt.Printf("FindStringSubmatch: %q\n", dateRe.FindStringSubmatch("发布于 2024-03-15"))
+
+	// 邮箱验证
+	// 【实用正则示例】
+	fmt.Printf("邮箱验证 'test@example.com': %v\n", emailRe.MatchString("test@example.com"))
+	fmt.Printf("邮箱验证 'not-an-email': %v\n", emailRe.MatchString("not-an-email"))
+}
@@ -0,0 +1,75 @@
+package stdlib
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ============================================================================
+// 【sort 包】
+// ============================================================================
+// sort 包提供排序功能
+//
+// 【基本排序】
+// Ints(x) - 整数排序
+// Float64s(x) - 浮点数排序
+// Strings(x) - 字符串排序
+//
+// 【自定义排序】
+// Slice(x, less) - 使用 less 函数排序
+// SliceStable(x, less) - 稳定排序
+//
+// 【搜索】
+// SearchInts(a, x) - 二分查找（需要已排序）
+// Search(n, f) - 通用二分查找
+//
+// 【检查】
+// IntsAreSorted(x) - 是否已排序
+//
+// 【Go 1.21+】slices.Sort / slices.SortFunc 是泛型版本，新代码优先使用
+// ============================================================================
+
+// Sort 演示基本排序、自定义排序和二分查找
+func Sort() {
+	fmt.Println("\n--- sort 包 ---")
+
+	// 整数排序
+	ints := []int{5, 2, 8, 1, 9}
+	sort.Ints(ints)
+	fmt.Printf("Ints: %v\n", ints)
+
+	// 字符串排序
+	strs := []string{"banana", "apple", "cherry"}
+	sort.Strings(strs)
+	fmt.Printf("Strings: %v\n", strs)
+
+	// 浮点数排序
+	floats := []float64{3.14, 1.41, 2.72}
+	sort.Float64s(floats)
+	fmt.Printf("Float64s: %v\n", floats)
+
+	// 自定义排序
+	// 【sort.Slice】第二个参数是 less 函数
+	// less(i, j) 返回 true 表示 i 应该在 j 前面
+	type Person struct {
+		Name string
+		Age  int
+	}
+	people := []Person{
+		{"Alice", 30},
+		{"Bob", 25},
+		{"Charlie", 35},
+	}
+	sort.Slice(people, func(i, j int) bool {
+		return people[i].Age < people[j].Age // 按年龄升序
+	})
+	fmt.Printf("自定义排序: %v\n", people)
+
+	// 检查是否已排序
+	fmt.Printf("IsSorted: %v\n", sort.IntsAreSorted(ints))
+
+	// 二分查找
+	// 【注意】切片必须已排序
+	idx := sort.SearchInts(ints, 5)
+	fmt.Printf("SearchInts(5): 索引 %d\n", idx)
+}
@@ -0,0 +1,68 @@
+package stdlib
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ============================================================================
+// 【strconv 包】
+// ============================================================================
+// strconv 包提供字符串与基本类型之间的转换
+//
+// 【字符串 -> 数字】
+// Atoi(s) - 字符串转 int
+// ParseInt(s, base, bitSize) - 字符串转整数（指定进制和位数）
+// ParseFloat(s, bitSize) - 字符串转浮点数
+// ParseBool(s) - 字符串转布尔
+//
+// 【数字 -> 字符串】
+// Itoa(i) - int 转字符串
+// FormatInt(i, base) - 整数转字符串（指定进制）
+// FormatFloat(f, fmt, prec, bitSize) - 浮点数转字符串
+// FormatBool(b) - 布尔转字符串
+//
+// 【Quote 系列】
+// Quote(s) - 添加 Go 字符串引号
+// QuoteRune(r) - 添加 Go 字符引号
+// Unquote(s) - 移除引号
+// ============================================================================
+
+// Strconv 演示字符串与数字、布尔之间的转换
+func Strconv() {
+	fmt.Println("\n--- strconv 包 ---")
+
+	// 字符串转数字
+	// 【Atoi】= ParseInt(s, 10, 0) 的简写
+	// 返回 (int, error)
+	i, _ := strconv.Atoi("42")
+	fmt.Printf("Atoi: \"42\" -> %d\n", i)
+
+	// 转换失败时返回 *strconv.NumError
+	_, err := strconv.Atoi("abc")
+	fmt.Printf("Atoi 错误: %v\n", err)
+
+	// ParseFloat: 第二个参数是位数（32 或 64）
+	f, _ := strconv.ParseFloat("3.14", 64)
+	fmt.Printf("ParseFloat: \"3.14\" -> %f\n", f)
+
+	// ParseBool: 接受 "1", "t", "T", "TRUE", "true", "True" 为 true
+	b, _ := strconv.ParseBool("true")
+	fmt.Printf("ParseBool: \"true\" -> %v\n", b)
+
+	// 数字转字符串
+	// 【Itoa】= FormatInt(int64(i), 10) 的简写
+	str := strconv.Itoa(42)
+	fmt.Printf("Itoa: 42 -> %q\n", str)
+
+	// FormatFloat: 参数为 (value, format, precision, bitSize)
+	// format: 'f'=小数, 'e'=科学计数法, 'g'=自动选择
+	str = strconv.FormatFloat(3.14159, 'f', 2, 64)
+	fmt.Printf("FormatFloat: 3.14159 -> %q\n", str)
+
+	str = strconv.FormatBool(true)
+	fmt.Printf("FormatBool: true -> %q\n", str)
+
+	// Quote: 为字符串添加 Go 语法的引号，转义特殊字符
+	fmt.Printf("Quote: %s\n", strconv.Quote("Hello\tWorld"))
+}
@@ -0,0 +1,67 @@
+package stdlib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// 【strings 包】
+// ============================================================================
+// strings 包提供字符串操作函数
+//
+// 【常用函数分类】
+// 查找：Contains, ContainsAny, HasPrefix, HasSuffix, Index, LastIndex
+// 转换：ToUpper, ToLower, Title
+// 修改：Replace, ReplaceAll, Trim, TrimSpace, TrimPrefix, TrimSuffix
+// 分割：Split, SplitN, Fields
+// 连接：Join, Repeat
+// 比较：Compare, EqualFold（忽略大小写）
+//
+// 【strings.Builder】
+// 高效的字符串构建器，避免多次字符串拼接的性能问题
+// ============================================================================
+
+// Strings 演示查找、转换、分割和 Builder
+func Strings() {
+	fmt.Println("\n--- strings 包 ---")
+
+	s := "Hello, World!"
+
+	fmt.Printf("原字符串: %s\n", s)
+
+	// 查找
+	fmt.Printf("Contains: %v\n", strings.Contains(s, "World"))   // 包含
+	fmt.Printf("HasPrefix: %v\n", strings.HasPrefix(s, "Hello")) // 前缀
+	fmt.Printf("HasSuffix: %v\n", strings.HasSuffix(s, "!"))     // 后缀
+	fmt.Printf("Index: %d\n", strings.Index(s, "World"))         // 位置
+
+	// 转换
+	fmt.Printf("ToUpper: %s\n", strings.ToUpper(s)) // 大写
+	fmt.Printf("ToLower: %s\n", strings.ToLower(s)) // 小写
+
+	// 修改
+	fmt.Printf("Replace: %s\n", strings.Replace(s, "World", "Go", 1)) // 替换
+
+	// 分割和连接
+	fmt.Printf("Split: %v\n", strings.Split(s, ", "))                    // 分割
+	fmt.Printf("Join: %s\n", strings.Join([]string{"a", "b", "c"}, "-")) // 连接
+
+	// 清理
+	fmt.Printf("TrimSpace: [%s]\n", strings.TrimSpace("  hello  ")) // 去空白
+
+	// 其他
+	fmt.Printf("Repeat: %s\n", strings.Repeat("Go", 3)) // 重复
+	fmt.Printf("Count: %d\n", strings.Count(s, "l"))    // 计数
+
+	// Builder（高效字符串构建）
+	// 【为什么使用 Builder】
+	// - 字符串是不可变的，+ 拼接会产生新字符串
+	// - Builder 内部使用 []byte，减少内存分配
+	// - 适合大量字符串拼接场景
+	var builder strings.Builder
+	builder.WriteString("Hello")
+	builder.WriteString(", ")
+	builder.WriteString("Builder!")
+	fmt.Printf("Builder: %s\n", builder.String())
+}
@@ -0,0 +1,92 @@
+package stdlib
+
+import (
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// 【time 包】
+// ============================================================================
+// time 包提供时间的测量和显示功能
+//
+// 【核心类型】
+// Time: 时间点
+// Duration: 时间段
+// Location: 时区
+//
+// 【Go 的时间格式化】
+// Go 使用特殊的参考时间: 2006-01-02 15:04:05 MST
+// 这是 Go 诞生的时间（2006年1月2日15:04:05）
+// 记忆：1月2日下午3点4分5秒 -> 01/02 03:04:05 -> 1 2 3 4 5
+//
+// 【常用格式】
+// time.RFC3339: "2006-01-02T15:04:05Z07:00"
+// time.RFC822:  "02 Jan 06 15:04 MST"
+// time.Kitchen: "3:04PM"
+// ============================================================================
+
+// Time 以 now 为当前时间演示格式化、解析、计算和定时器
+// 【为什么传入 now】直接调用 time.Now() 的函数输出每次不同，无法测试；
+// 把时间作为参数传入是让代码可测试的常用手法；需要定时器、Ticker 的代码
+// 用 clock 包的 Clock 接口，测试里换成 clock.NewFake
+func Time(now time.Time) {
+	fmt.Println("\n--- time 包 ---")
+
+	// 当前时间
+	fmt.Printf("当前时间: %v\n", now)
+
+	// 格式化（Go 使用特殊的参考时间: 2006-01-02 15:04:05）
+	// 【重要】不是随意的日期，而是 Go 的诞生时间
+	// 记忆：1月2日下午3点4分5秒2006年
+	fmt.Printf("格式化: %s\n", now.Format("2006-01-02 15:04:05"))
+	fmt.Printf("RFC3339: %s\n", now.Format(time.RFC3339))
+
+	// 时间组件
+	fmt.Printf("年月日: %d-%d-%d\n", now.Year(), now.Month(), now.Day())
+	fmt.Printf("时分秒: %d:%d:%d\n", now.Hour(), now.Minute(), now.Second())
+	fmt.Printf("星期: %s\n", now.Weekday())
+
+	// 解析时间
+	// 【Parse】第一个参数是格式，第二个参数是要解析的字符串
+	t, _ := time.Parse("2006-01-02", "2024-03-15")
+	fmt.Printf("解析: %v\n", t)
+
+	// 时间计算
+	// 【Add】添加 Duration
+	tomorrow := now.Add(24 * time.Hour)
+	fmt.Printf("明天: %s\n", tomorrow.Format("2006-01-02"))
+
+	// 【AddDate】添加年/月/日
+	yesterday := now.AddDate(0, 0, -1)
+	fmt.Printf("昨天: %s\n", yesterday.Format("2006-01-02"))
+
+	// 时间比较
+	fmt.Printf("now.Before(tomorrow): %v\n", now.Before(tomorrow))
+	fmt.Printf("now.After(yesterday): %v\n", now.After(yesterday))
+
+	// 【Sub】两个时间点相减得到 Duration
+	fmt.Printf("距离 2024-03-15: %v\n", now.Sub(t))
+
+	// Duration
+	// 【Duration 常量】
+	// time.Nanosecond, time.Microsecond, time.Millisecond
+	// time.Second, time.Minute, time.Hour
+	duration := 2*time.Hour + 30*time.Minute
+	fmt.Printf("Duration: %v\n", duration)
+
+	// 计时
+	// 【time.Since】= time.Now().Sub(start)
+	// 实际耗时每次不同，这里只验证下限
+	start := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	elapsed := time.Since(start)
+	fmt.Printf("耗时 >= 10ms: %v\n", elapsed >= 10*time.Millisecond)
+
+	// 定时器
+	// 【time.NewTimer】创建单次定时器
+	// timer.C 是一个 channel，到时后发送当前时间
+	timer := time.NewTimer(50 * time.Millisecond)
+	<-timer.C
+	fmt.Println("Timer 触发")
+}